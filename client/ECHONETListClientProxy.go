@@ -4,6 +4,7 @@ import (
 	"echonet-list/echonet_lite"
 	"echonet-list/echonet_lite/handler"
 	"fmt"
+	"time"
 )
 
 // ECHONETListClientProxy は、ECHONETListClientのlocal proxy
@@ -17,6 +18,13 @@ func NewECHONETListClientProxy(handler *handler.ECHONETLiteHandler) ECHONETListC
 	}
 }
 
+// propertyWatchBufferSize is the number of property changes buffered for each watcher
+const propertyWatchBufferSize = 100
+
+func (c *ECHONETListClientProxy) WatchPropertyChanges() (<-chan PropertyChangeNotification, func()) {
+	return c.handler.WatchPropertyChanges(propertyWatchBufferSize)
+}
+
 func (c *ECHONETListClientProxy) Close() error {
 	return nil
 }
@@ -45,6 +53,14 @@ func (c *ECHONETListClientProxy) DebugSetOffline(target string, offline bool) er
 	return c.handler.DebugSetOffline(target, offline)
 }
 
+func (c *ECHONETListClientProxy) DebugPendingRequests() ([]PendingRequest, error) {
+	return c.handler.DebugPendingRequests(), nil
+}
+
+func (c *ECHONETListClientProxy) DebugCancelRequest(id uint64) error {
+	return c.handler.DebugCancelRequest(id)
+}
+
 func (c *ECHONETListClientProxy) IsOfflineDevice(device IPAndEOJ) bool {
 	return c.handler.IsOffline(device)
 }
@@ -85,8 +101,12 @@ func (c *ECHONETListClientProxy) GetProperties(device IPAndEOJ, EPCs []EPCType,
 	return c.handler.GetProperties(device, EPCs, skipValidation)
 }
 
-func (c *ECHONETListClientProxy) SetProperties(device IPAndEOJ, properties Properties) (DeviceAndProperties, error) {
-	return c.handler.SetProperties(device, properties)
+func (c *ECHONETListClientProxy) SetProperties(device IPAndEOJ, properties Properties, skipValidationEPCs []EPCType) (DeviceAndProperties, error) {
+	return c.handler.SetProperties(device, properties, skipValidationEPCs)
+}
+
+func (c *ECHONETListClientProxy) SetGetProperties(device IPAndEOJ, properties Properties, EPCs []EPCType) (SetGetResult, error) {
+	return c.handler.SetGetProperties(device, properties, EPCs)
 }
 
 func (c *ECHONETListClientProxy) GetDeviceHistory(device IPAndEOJ, opts DeviceHistoryOptions) ([]DeviceHistoryEntry, error) {
@@ -143,6 +163,86 @@ func (c *ECHONETListClientProxy) GetIDString(device IPAndEOJ) IDString {
 	return c.handler.GetIDString(device)
 }
 
+func (c *ECHONETListClientProxy) CleanupDevices(unseenDays int, dryRun bool) ([]StaleDevice, error) {
+	return c.handler.CleanupStaleDevices(time.Duration(unseenDays)*24*time.Hour, dryRun)
+}
+
+func (c *ECHONETListClientProxy) RefreshPropertyMap(device IPAndEOJ) (PropertyMapChange, error) {
+	return c.handler.RefreshPropertyMap(device)
+}
+
+// SceneManager インターフェースの実装
+
+func (c *ECHONETListClientProxy) SceneList(sceneName *string) []SceneActionsPair {
+	return c.handler.SceneList(sceneName)
+}
+
+func (c *ECHONETListClientProxy) SceneAdd(sceneName string, actions []SceneAction) error {
+	return c.handler.SceneAdd(sceneName, actions)
+}
+
+func (c *ECHONETListClientProxy) SceneDelete(sceneName string) error {
+	return c.handler.SceneDelete(sceneName)
+}
+
+func (c *ECHONETListClientProxy) GetScene(sceneName string) ([]SceneAction, bool) {
+	return c.handler.GetScene(sceneName)
+}
+
+func (c *ECHONETListClientProxy) SceneSetSequence(sceneName string, sequence SceneSequence) error {
+	return c.handler.SceneSetSequence(sceneName, sequence)
+}
+
+func (c *ECHONETListClientProxy) GetSceneSequence(sceneName string) SceneSequence {
+	return c.handler.GetSceneSequence(sceneName)
+}
+
+func (c *ECHONETListClientProxy) SceneRun(sceneName string) ([]SceneRunResult, error) {
+	return c.handler.SceneRun(sceneName)
+}
+
+// ScheduleManager インターフェースの実装
+
+func (c *ECHONETListClientProxy) ScheduleList() []Schedule {
+	return c.handler.ScheduleList()
+}
+
+func (c *ECHONETListClientProxy) ScheduleSet(schedule Schedule) error {
+	return c.handler.ScheduleSet(schedule)
+}
+
+func (c *ECHONETListClientProxy) ScheduleDelete(name string) error {
+	return c.handler.ScheduleDelete(name)
+}
+
+func (c *ECHONETListClientProxy) ScheduleSetDisabled(name string, disabled bool) error {
+	return c.handler.ScheduleSetDisabled(name, disabled)
+}
+
+func (c *ECHONETListClientProxy) ScheduleNextRun(schedule Schedule, after time.Time) time.Time {
+	return c.handler.ScheduleNextRun(schedule, after)
+}
+
+// LocalDeviceManager インターフェースの実装
+
+func (c *ECHONETListClientProxy) LocalPropertyMaps() ([]LocalPropertyMap, error) {
+	return c.handler.LocalPropertyMaps(), nil
+}
+
+func (c *ECHONETListClientProxy) SetLocalPropertyMap(eoj EOJ, mapType PropertyMapType, epcs []EPCType) error {
+	return c.handler.SetLocalPropertyMap(eoj, mapType, epcs)
+}
+
+// PollExclusionManager インターフェースの実装（定期更新はサーバーが行うため、スタンドアロンモードでは使えない）
+
+func (c *ECHONETListClientProxy) PollExclusions() ([]string, error) {
+	return nil, fmt.Errorf("periodic updates are not available in standalone mode")
+}
+
+func (c *ECHONETListClientProxy) SetPollExcluded(target string, excluded bool) error {
+	return fmt.Errorf("periodic updates are not available in standalone mode")
+}
+
 // LocationSettingsManager インターフェースの実装
 
 func (c *ECHONETListClientProxy) GetLocationSettings() (map[string]string, []string) {
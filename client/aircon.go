@@ -0,0 +1,200 @@
+package client
+
+import (
+	"echonet-list/echonet_lite"
+	"fmt"
+	"strings"
+)
+
+// AirConditionerMode is the operation mode of a home air conditioner (EPC 0xB0).
+// The values are the aliases of the property, so they can also be used with the set command.
+type AirConditionerMode string
+
+const (
+	AirConditionerModeAuto  AirConditionerMode = "auto"
+	AirConditionerModeCool  AirConditionerMode = "cooling"
+	AirConditionerModeHeat  AirConditionerMode = "heating"
+	AirConditionerModeDry   AirConditionerMode = "dry"
+	AirConditionerModeFan   AirConditionerMode = "fan"
+	AirConditionerModeOther AirConditionerMode = "other"
+)
+
+// ParseAirConditionerMode parses an operation mode: an alias of EPC 0xB0, or one of the short forms cool, heat and dehumidify.
+func ParseAirConditionerMode(s string) (AirConditionerMode, error) {
+	switch strings.ToLower(s) {
+	case "cool":
+		return AirConditionerModeCool, nil
+	case "heat":
+		return AirConditionerModeHeat, nil
+	case "dehumidify":
+		return AirConditionerModeDry, nil
+	}
+	mode := AirConditionerMode(strings.ToLower(s))
+	if _, err := mode.edt(); err != nil {
+		return "", err
+	}
+	return mode, nil
+}
+
+// edt returns the EDT of EPC 0xB0 for the mode
+func (m AirConditionerMode) edt() ([]byte, error) {
+	if desc, ok := echonet_lite.GetPropertyDesc(echonet_lite.HomeAirConditioner_ClassCode, echonet_lite.EPC_HAC_OperationModeSetting); ok {
+		if edt, ok := desc.Aliases[string(m)]; ok {
+			return edt, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation mode: %s (auto, cool, heat, dry or fan)", m)
+}
+
+// airConditionerModeFromEDT returns the mode of an EDT of EPC 0xB0
+func airConditionerModeFromEDT(edt []byte) (AirConditionerMode, bool) {
+	if desc, ok := echonet_lite.GetPropertyDesc(echonet_lite.HomeAirConditioner_ClassCode, echonet_lite.EPC_HAC_OperationModeSetting); ok {
+		if alias := desc.EDTToString(edt); alias != "" {
+			return AirConditionerMode(alias), true
+		}
+	}
+	return "", false
+}
+
+// Range of the temperature setting (EPC 0xB3)
+const (
+	MinAirConditionerTemperature = 0
+	MaxAirConditionerTemperature = 50
+)
+
+// AirConditionerState is the state of a home air conditioner read at once by AirConditioner.State.
+// The values a device does not report, or reports as unknown, are nil.
+type AirConditionerState struct {
+	Power              *bool
+	Mode               *AirConditionerMode
+	TargetTemperature  *int // ℃
+	RoomTemperature    *int // ℃
+	RoomHumidity       *int // %
+	OutsideTemperature *int // ℃
+}
+
+// AirConditioner operates a home air conditioner (class 0x0130) with typed values,
+// translating them to the EPC and EDT of the device so that everyday operations need no raw hex.
+type AirConditioner struct {
+	typedDevice[AirConditionerState]
+}
+
+// NewAirConditioner returns the air conditioner helper of a device. The device must be a home air conditioner.
+func NewAirConditioner(c DeviceManager, device IPAndEOJ) (*AirConditioner, error) {
+	d, err := newTypedDevice(c, device, DecodeAirConditionerState, "a home air conditioner", echonet_lite.HomeAirConditioner_ClassCode)
+	if err != nil {
+		return nil, err
+	}
+	return &AirConditioner{d}, nil
+}
+
+// SetPower turns the air conditioner on or off (EPC 0x80)
+func (a *AirConditioner) SetPower(on bool) error {
+	edt := byte(0x31)
+	if on {
+		edt = 0x30
+	}
+	return a.set(Property{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{edt}})
+}
+
+// SetMode sets the operation mode (EPC 0xB0)
+func (a *AirConditioner) SetMode(mode AirConditionerMode) error {
+	edt, err := mode.edt()
+	if err != nil {
+		return err
+	}
+	return a.set(Property{EPC: echonet_lite.EPC_HAC_OperationModeSetting, EDT: edt})
+}
+
+// SetTargetTemperature sets the temperature setting (EPC 0xB3) in ℃
+func (a *AirConditioner) SetTargetTemperature(celsius int) error {
+	if celsius < MinAirConditionerTemperature || celsius > MaxAirConditionerTemperature {
+		return fmt.Errorf("temperature must be between %d and %d: %d", MinAirConditionerTemperature, MaxAirConditionerTemperature, celsius)
+	}
+	return a.set(Property{EPC: echonet_lite.EPC_HAC_TemperatureSetting, EDT: []byte{byte(celsius)}})
+}
+
+// GetMode reads the operation mode (EPC 0xB0)
+func (a *AirConditioner) GetMode() (AirConditionerMode, error) {
+	state, err := a.read(echonet_lite.EPC_HAC_OperationModeSetting)
+	if err != nil {
+		return "", err
+	}
+	if state.Mode == nil {
+		return "", fmt.Errorf("%v did not report the operation mode", a.Device)
+	}
+	return *state.Mode, nil
+}
+
+// GetTargetTemperature reads the temperature setting (EPC 0xB3) in ℃
+func (a *AirConditioner) GetTargetTemperature() (int, error) {
+	state, err := a.read(echonet_lite.EPC_HAC_TemperatureSetting)
+	if err != nil {
+		return 0, err
+	}
+	if state.TargetTemperature == nil {
+		return 0, fmt.Errorf("%v did not report the temperature setting", a.Device)
+	}
+	return *state.TargetTemperature, nil
+}
+
+// GetRoomTemperature reads the measured room temperature (EPC 0xBB) in ℃
+func (a *AirConditioner) GetRoomTemperature() (int, error) {
+	state, err := a.read(echonet_lite.EPC_HAC_CurrentRoomTemperature)
+	if err != nil {
+		return 0, err
+	}
+	if state.RoomTemperature == nil {
+		return 0, fmt.Errorf("%v did not report the room temperature", a.Device)
+	}
+	return *state.RoomTemperature, nil
+}
+
+// State reads the power, mode, temperature setting and measured values at once
+func (a *AirConditioner) State() (AirConditionerState, error) {
+	return a.read(
+		echonet_lite.EPCOperationStatus,
+		echonet_lite.EPC_HAC_OperationModeSetting,
+		echonet_lite.EPC_HAC_TemperatureSetting,
+		echonet_lite.EPC_HAC_CurrentRoomTemperature,
+		echonet_lite.EPC_HAC_CurrentRoomHumidity,
+		echonet_lite.EPC_HAC_CurrentOutsideTemperature,
+	)
+}
+
+// DecodeAirConditionerState decodes the properties of a home air conditioner.
+// Missing properties and the values meaning unknown, overflow or underflow are left nil.
+func DecodeAirConditionerState(properties Properties) AirConditionerState {
+	var state AirConditionerState
+	if p, ok := properties.FindEPC(echonet_lite.EPCOperationStatus); ok && len(p.EDT) == 1 && (p.EDT[0] == 0x30 || p.EDT[0] == 0x31) {
+		on := p.EDT[0] == 0x30
+		state.Power = &on
+	}
+	if p, ok := properties.FindEPC(echonet_lite.EPC_HAC_OperationModeSetting); ok {
+		if mode, ok := airConditionerModeFromEDT(p.EDT); ok {
+			state.Mode = &mode
+		}
+	}
+	// The temperature setting and the humidity are unsigned; 0xFD and above mean unknown, underflow or overflow
+	unsigned := func(epc EPCType, max int) *int {
+		if p, ok := properties.FindEPC(epc); ok && len(p.EDT) == 1 && int(p.EDT[0]) <= max {
+			value := int(p.EDT[0])
+			return &value
+		}
+		return nil
+	}
+	// Measured temperatures are signed; 0x7E, 0x7F and 0x80 mean N/A, overflow and underflow
+	signed := func(epc EPCType) *int {
+		if p, ok := properties.FindEPC(epc); ok && len(p.EDT) == 1 {
+			if value := int(int8(p.EDT[0])); value >= -127 && value <= 125 {
+				return &value
+			}
+		}
+		return nil
+	}
+	state.TargetTemperature = unsigned(echonet_lite.EPC_HAC_TemperatureSetting, MaxAirConditionerTemperature)
+	state.RoomHumidity = unsigned(echonet_lite.EPC_HAC_CurrentRoomHumidity, 100)
+	state.RoomTemperature = signed(echonet_lite.EPC_HAC_CurrentRoomTemperature)
+	state.OutsideTemperature = signed(echonet_lite.EPC_HAC_CurrentOutsideTemperature)
+	return state
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"echonet-list/echonet_lite"
+	"net"
+	"testing"
+)
+
+// propertyStub keeps the properties of one device and records the properties set
+type propertyStub struct {
+	DeviceManager
+	properties Properties
+	set        []Properties
+}
+
+func (s *propertyStub) SetProperties(device IPAndEOJ, properties Properties, _ []EPCType) (DeviceAndProperties, error) {
+	s.set = append(s.set, properties)
+	for _, p := range properties {
+		s.properties = s.properties.UpdateProperty(p)
+	}
+	return DeviceAndProperties{Device: device, Properties: properties}, nil
+}
+
+func (s *propertyStub) GetProperties(device IPAndEOJ, epcs []EPCType, _ bool) (DeviceAndProperties, error) {
+	var result Properties
+	for _, epc := range epcs {
+		if p, ok := s.properties.FindEPC(epc); ok {
+			result = append(result, p)
+		}
+	}
+	return DeviceAndProperties{Device: device, Properties: result}, nil
+}
+
+func TestParseAirConditionerMode(t *testing.T) {
+	tests := map[string]AirConditionerMode{
+		"cool":    AirConditionerModeCool,
+		"cooling": AirConditionerModeCool,
+		"Heat":    AirConditionerModeHeat,
+		"dry":     AirConditionerModeDry,
+		"fan":     AirConditionerModeFan,
+		"auto":    AirConditionerModeAuto,
+	}
+	for input, want := range tests {
+		if got, err := ParseAirConditionerMode(input); err != nil || got != want {
+			t.Errorf("ParseAirConditionerMode(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseAirConditionerMode("turbo"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func TestAirConditioner(t *testing.T) {
+	device := IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	stub := &propertyStub{properties: Properties{
+		{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x31}},
+		{EPC: echonet_lite.EPC_HAC_CurrentRoomTemperature, EDT: []byte{0xFB}},    // -5℃
+		{EPC: echonet_lite.EPC_HAC_CurrentOutsideTemperature, EDT: []byte{0x7E}}, // N/A
+	}}
+
+	if _, err := NewAirConditioner(stub, IPAndEOJ{IP: device.IP, EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)}); err == nil {
+		t.Error("expected an error for a device that is not an air conditioner")
+	}
+	aircon, err := NewAirConditioner(stub, device)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := aircon.SetMode(AirConditionerModeCool); err != nil {
+		t.Fatal(err)
+	}
+	if err := aircon.SetTargetTemperature(26); err != nil {
+		t.Fatal(err)
+	}
+	if err := aircon.SetPower(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := aircon.SetTargetTemperature(51); err == nil {
+		t.Error("expected an error for a temperature out of range")
+	}
+	want := []Property{
+		{EPC: echonet_lite.EPC_HAC_OperationModeSetting, EDT: []byte{0x42}},
+		{EPC: echonet_lite.EPC_HAC_TemperatureSetting, EDT: []byte{26}},
+		{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}},
+	}
+	if len(stub.set) != len(want) {
+		t.Fatalf("set %d times, want %d", len(stub.set), len(want))
+	}
+	for i, p := range want {
+		if got := stub.set[i]; len(got) != 1 || got[0].EPC != p.EPC || string(got[0].EDT) != string(p.EDT) {
+			t.Errorf("set #%d = %v, want %v", i, got, p)
+		}
+	}
+
+	if mode, err := aircon.GetMode(); err != nil || mode != AirConditionerModeCool {
+		t.Errorf("GetMode() = %q, %v", mode, err)
+	}
+	if temperature, err := aircon.GetTargetTemperature(); err != nil || temperature != 26 {
+		t.Errorf("GetTargetTemperature() = %d, %v", temperature, err)
+	}
+	if temperature, err := aircon.GetRoomTemperature(); err != nil || temperature != -5 {
+		t.Errorf("GetRoomTemperature() = %d, %v", temperature, err)
+	}
+
+	state, err := aircon.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Power == nil || !*state.Power || state.OutsideTemperature != nil || state.RoomHumidity != nil {
+		t.Errorf("unexpected state: %+v", state)
+	}
+}
@@ -20,11 +20,23 @@ func MakeEOJ(class EOJClassCode, instance EOJInstanceCode) EOJ {
 type FilterCriteria = handler.FilterCriteria
 type AliasIDStringPair = handler.AliasIDStringPair
 type GroupDevicePair = handler.GroupDevicePair
+type SceneAction = handler.SceneAction
+type SceneActionsPair = handler.SceneActionsPair
+type SceneRunResult = handler.SceneRunResult
+type SceneSequence = handler.SceneSequence
+type Schedule = handler.Schedule
 type DeviceSpecifier = handler.DeviceSpecifier
 type EPCType = echonet_lite.EPCType
 type Property = echonet_lite.Property
 type Properties = echonet_lite.Properties
 type DeviceAndProperties = handler.DeviceAndProperties
+type SetGetResult = handler.SetGetResult
+type PendingRequest = handler.PendingRequest
+type StaleDevice = handler.StaleDevice
+type LocalPropertyMap = handler.LocalPropertyMap
+type PropertyMapType = handler.PropertyMapType
+type PropertyMapChange = handler.PropertyMapChange
+type PropertyChangeNotification = handler.PropertyChangeNotification
 
 type PropertyDesc = echonet_lite.PropertyDesc
 type PropertyDescription = echonet_lite.PropertyDescription
@@ -32,6 +44,8 @@ type PropertyDescription = echonet_lite.PropertyDescription
 type DeviceHistoryOptions struct {
 	Limit        int
 	SettableOnly *bool
+	Since        time.Time // ゼロ値なら期間を限定しない
+	EPC          EPCType   // 0 以外ならそのプロパティの履歴のみ
 }
 
 type DeviceHistoryEntry struct {
@@ -48,6 +62,11 @@ type ECHONETListClient interface {
 	DeviceManager
 	PropertyDescProvider
 	GroupManager
+	SceneManager
+	ScheduleManager
 	LocationSettingsManager
+	LocalDeviceManager
+	PollExclusionManager
+	PropertyChangeWatcher
 	Close() error
 }
@@ -1,9 +1,13 @@
 package client
 
+import "time"
+
 type Debugger interface {
 	IsDebug() bool
 	SetDebug(debug bool)
 	DebugSetOffline(target string, offline bool) error
+	DebugPendingRequests() ([]PendingRequest, error)
+	DebugCancelRequest(id uint64) error
 	IsOfflineDevice(device IPAndEOJ) bool
 }
 
@@ -21,11 +25,16 @@ type DeviceManager interface {
 	UpdateProperties(criteria FilterCriteria, force bool) error
 	GetDevices(deviceSpec DeviceSpecifier) []IPAndEOJ
 	ListDevices(criteria FilterCriteria) []DeviceAndProperties
-	GetProperties(device IPAndEOJ, EPCs []EPCType, skipValidation bool) (DeviceAndProperties, error)
-	SetProperties(device IPAndEOJ, properties Properties) (DeviceAndProperties, error)
+	GetProperties(device IPAndEOJ, EPCs []EPCType, skipValidation bool) (DeviceAndProperties, error) // empty EPCs: all readable properties
+	SetProperties(device IPAndEOJ, properties Properties, skipValidationEPCs []EPCType) (DeviceAndProperties, error)
+	SetGetProperties(device IPAndEOJ, properties Properties, EPCs []EPCType) (SetGetResult, error)
 	GetDeviceHistory(device IPAndEOJ, opts DeviceHistoryOptions) ([]DeviceHistoryEntry, error)
 	FindDeviceByIDString(id IDString) *IPAndEOJ
 	GetIDString(device IPAndEOJ) IDString
+	// CleanupDevices removes the devices not updated for unseenDays days, or only lists them if dryRun is true.
+	CleanupDevices(unseenDays int, dryRun bool) ([]StaleDevice, error)
+	// RefreshPropertyMap reads the property maps of a device again, then every property of the new Get property map.
+	RefreshPropertyMap(device IPAndEOJ) (PropertyMapChange, error)
 }
 
 type PropertyDescProvider interface {
@@ -44,9 +53,49 @@ type GroupManager interface {
 	GetDevicesByGroup(groupName string) ([]IDString, bool)
 }
 
+type SceneManager interface {
+	SceneList(sceneName *string) []SceneActionsPair
+	SceneAdd(sceneName string, actions []SceneAction) error
+	SceneDelete(sceneName string) error
+	GetScene(sceneName string) ([]SceneAction, bool)
+	SceneSetSequence(sceneName string, sequence SceneSequence) error
+	GetSceneSequence(sceneName string) SceneSequence
+	SceneRun(sceneName string) ([]SceneRunResult, error)
+}
+
+type ScheduleManager interface {
+	ScheduleList() []Schedule
+	ScheduleSet(schedule Schedule) error
+	ScheduleDelete(name string) error
+	ScheduleSetDisabled(name string, disabled bool) error
+	// ScheduleNextRun は、スケジュールの after より後の次回実行時刻を返す（ない場合はゼロ値）
+	// 日の出・日の入りのスケジュールはサーバーの地点で計算する
+	ScheduleNextRun(schedule Schedule, after time.Time) time.Time
+}
+
 type LocationSettingsManager interface {
 	GetLocationSettings() (aliases map[string]string, order []string)
 	LocationAliasAdd(alias, value string) error
 	LocationAliasDelete(alias string) error
 	SetLocationOrder(order []string) error
 }
+
+type LocalDeviceManager interface {
+	// LocalPropertyMaps returns the property maps of the devices of our own node.
+	LocalPropertyMaps() ([]LocalPropertyMap, error)
+	// SetLocalPropertyMap replaces a property map of a device of our own node, or restores the default if epcs is nil.
+	SetLocalPropertyMap(eoj EOJ, mapType PropertyMapType, epcs []EPCType) error
+}
+
+type PollExclusionManager interface {
+	// PollExclusions returns the class codes and device patterns that are not polled by the periodic updater of the server.
+	PollExclusions() ([]string, error)
+	// SetPollExcluded stops polling a class code ("0279") or device pattern, or polls it again. INF notifications are still accepted.
+	SetPollExcluded(target string, excluded bool) error
+}
+
+type PropertyChangeWatcher interface {
+	// WatchPropertyChanges returns a channel of property changes and a function that stops watching and closes it.
+	// Changes are dropped for a watcher that does not keep up.
+	WatchPropertyChanges() (<-chan PropertyChangeNotification, func())
+}
@@ -0,0 +1,206 @@
+package client
+
+import (
+	"echonet-list/echonet_lite"
+	"fmt"
+	"strings"
+)
+
+// LightColor is the light color setting of general lighting (EPC 0xB1), the color temperature of the light.
+// The values are the aliases of the property, so they can also be used with the set command.
+type LightColor string
+
+const (
+	LightColorIncandescent  LightColor = "incandescent"   // about 2700K
+	LightColorWhite         LightColor = "white"          // about 4200K
+	LightColorDaylightWhite LightColor = "daylight_white" // about 5000K
+	LightColorDaylight      LightColor = "daylight"       // about 6500K
+	LightColorOther         LightColor = "other"
+)
+
+// ParseLightColor parses a light color: an alias of EPC 0xB1, or one of the short forms warm and cool.
+func ParseLightColor(s string) (LightColor, error) {
+	switch strings.ToLower(s) {
+	case "warm":
+		return LightColorIncandescent, nil
+	case "cool":
+		return LightColorDaylight, nil
+	}
+	color := LightColor(strings.ToLower(strings.ReplaceAll(s, "-", "_")))
+	if _, err := color.edt(echonet_lite.GeneralLighting_ClassCode); err != nil {
+		return "", err
+	}
+	return color, nil
+}
+
+// edt returns the EDT of EPC 0xB1 of the class for the color
+func (c LightColor) edt(classCode EOJClassCode) ([]byte, error) {
+	desc, ok := echonet_lite.GetPropertyDesc(classCode, echonet_lite.EPC_GL_LightColor)
+	if !ok || desc.Aliases == nil {
+		return nil, fmt.Errorf("%v does not have the light color setting", classCode)
+	}
+	if edt, ok := desc.Aliases[string(c)]; ok {
+		return edt, nil
+	}
+	return nil, fmt.Errorf("unknown light color: %s (incandescent, white, daylight_white or daylight)", c)
+}
+
+// lightColorFromEDT returns the color of an EDT of EPC 0xB1
+func lightColorFromEDT(classCode EOJClassCode, edt []byte) (LightColor, bool) {
+	if desc, ok := echonet_lite.GetPropertyDesc(classCode, echonet_lite.EPC_GL_LightColor); ok && desc.Aliases != nil {
+		if alias := desc.EDTToString(edt); alias != "" {
+			return LightColor(alias), true
+		}
+	}
+	return "", false
+}
+
+// LightingState is the state of a light read at once by Lighting.State.
+// The values a device does not report are nil.
+type LightingState struct {
+	Power      *bool
+	Brightness *int // %
+	Color      *LightColor
+}
+
+// Lighting operates general lighting (class 0x0290) and single function lighting (class 0x0291) with typed values.
+// The ranges of the values are taken from the property descriptions of the class of the device.
+type Lighting struct {
+	typedDevice[LightingState]
+}
+
+// IsLightingClass reports whether the class is supported by Lighting
+func IsLightingClass(classCode EOJClassCode) bool {
+	return classCode == echonet_lite.GeneralLighting_ClassCode || classCode == echonet_lite.SingleFunctionLighting_ClassCode
+}
+
+// NewLighting returns the lighting helper of a device. The device must be general or single function lighting.
+func NewLighting(c DeviceManager, device IPAndEOJ) (*Lighting, error) {
+	decode := func(properties Properties) LightingState {
+		return DecodeLightingState(device.EOJ.ClassCode(), properties)
+	}
+	d, err := newTypedDevice(c, device, decode, "general or single function lighting",
+		echonet_lite.GeneralLighting_ClassCode, echonet_lite.SingleFunctionLighting_ClassCode)
+	if err != nil {
+		return nil, err
+	}
+	return &Lighting{d}, nil
+}
+
+// illuminanceDesc returns the description of the illuminance level (EPC 0xB0, the same EPC in both classes) of the class
+func illuminanceDesc(classCode EOJClassCode) (echonet_lite.NumberDesc, error) {
+	desc, ok := echonet_lite.GetPropertyDesc(classCode, echonet_lite.EPC_GL_Illuminance)
+	if !ok {
+		return echonet_lite.NumberDesc{}, fmt.Errorf("%v does not have the illuminance level", classCode)
+	}
+	number, ok := desc.Decoder.(echonet_lite.NumberDesc)
+	if !ok {
+		return echonet_lite.NumberDesc{}, fmt.Errorf("%v: the illuminance level is not a number", classCode)
+	}
+	return number, nil
+}
+
+// BrightnessRange returns the range of the illuminance level (EPC 0xB0) of the class in %
+func BrightnessRange(classCode EOJClassCode) (lowest, highest int, err error) {
+	number, err := illuminanceDesc(classCode)
+	if err != nil {
+		return 0, 0, err
+	}
+	return number.Min, number.Max, nil
+}
+
+// SupportsLightColor reports whether the class has the light color setting (EPC 0xB1)
+func SupportsLightColor(classCode EOJClassCode) bool {
+	desc, ok := echonet_lite.GetPropertyDesc(classCode, echonet_lite.EPC_GL_LightColor)
+	return ok && desc.Aliases != nil
+}
+
+// SetPower turns the light on or off (EPC 0x80)
+func (l *Lighting) SetPower(on bool) error {
+	edt := byte(0x31)
+	if on {
+		edt = 0x30
+	}
+	return l.set(Property{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{edt}})
+}
+
+// SetBrightness sets the illuminance level (EPC 0xB0) in %
+func (l *Lighting) SetBrightness(percent int) error {
+	number, err := illuminanceDesc(l.Device.EOJ.ClassCode())
+	if err != nil {
+		return err
+	}
+	edt, ok := number.FromInt(percent)
+	if !ok {
+		return fmt.Errorf("brightness must be between %d and %d: %d", number.Min, number.Max, percent)
+	}
+	return l.set(Property{EPC: echonet_lite.EPC_GL_Illuminance, EDT: edt})
+}
+
+// SetColorTemperature sets the light color setting (EPC 0xB1). Single function lighting does not have it.
+func (l *Lighting) SetColorTemperature(color LightColor) error {
+	edt, err := color.edt(l.Device.EOJ.ClassCode())
+	if err != nil {
+		return err
+	}
+	return l.set(Property{EPC: echonet_lite.EPC_GL_LightColor, EDT: edt})
+}
+
+// GetBrightness reads the illuminance level (EPC 0xB0) in %
+func (l *Lighting) GetBrightness() (int, error) {
+	state, err := l.read(echonet_lite.EPC_GL_Illuminance)
+	if err != nil {
+		return 0, err
+	}
+	if state.Brightness == nil {
+		return 0, fmt.Errorf("%v did not report the illuminance level", l.Device)
+	}
+	return *state.Brightness, nil
+}
+
+// GetColorTemperature reads the light color setting (EPC 0xB1)
+func (l *Lighting) GetColorTemperature() (LightColor, error) {
+	if !SupportsLightColor(l.Device.EOJ.ClassCode()) {
+		return "", fmt.Errorf("%v does not have the light color setting", l.Device)
+	}
+	state, err := l.read(echonet_lite.EPC_GL_LightColor)
+	if err != nil {
+		return "", err
+	}
+	if state.Color == nil {
+		return "", fmt.Errorf("%v did not report the light color", l.Device)
+	}
+	return *state.Color, nil
+}
+
+// State reads the power, brightness and, for general lighting, the light color at once
+func (l *Lighting) State() (LightingState, error) {
+	epcs := []EPCType{echonet_lite.EPCOperationStatus, echonet_lite.EPC_GL_Illuminance}
+	if SupportsLightColor(l.Device.EOJ.ClassCode()) {
+		epcs = append(epcs, echonet_lite.EPC_GL_LightColor)
+	}
+	return l.read(epcs...)
+}
+
+// DecodeLightingState decodes the properties of a light of the class.
+// Missing properties and values out of the range of the class are left nil.
+func DecodeLightingState(classCode EOJClassCode, properties Properties) LightingState {
+	var state LightingState
+	if p, ok := properties.FindEPC(echonet_lite.EPCOperationStatus); ok && len(p.EDT) == 1 && (p.EDT[0] == 0x30 || p.EDT[0] == 0x31) {
+		on := p.EDT[0] == 0x30
+		state.Power = &on
+	}
+	if p, ok := properties.FindEPC(echonet_lite.EPC_GL_Illuminance); ok {
+		if number, err := illuminanceDesc(classCode); err == nil {
+			if value, _, ok := number.ToInt(p.EDT); ok {
+				state.Brightness = &value
+			}
+		}
+	}
+	if p, ok := properties.FindEPC(echonet_lite.EPC_GL_LightColor); ok {
+		if color, ok := lightColorFromEDT(classCode, p.EDT); ok {
+			state.Color = &color
+		}
+	}
+	return state
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"echonet-list/echonet_lite"
+	"net"
+	"testing"
+)
+
+func TestParseLightColor(t *testing.T) {
+	tests := map[string]LightColor{
+		"incandescent":   LightColorIncandescent,
+		"warm":           LightColorIncandescent,
+		"White":          LightColorWhite,
+		"daylight-white": LightColorDaylightWhite,
+		"daylight_white": LightColorDaylightWhite,
+		"cool":           LightColorDaylight,
+	}
+	for input, want := range tests {
+		if got, err := ParseLightColor(input); err != nil || got != want {
+			t.Errorf("ParseLightColor(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseLightColor("purple"); err == nil {
+		t.Error("expected an error for an unknown color")
+	}
+}
+
+func TestLighting(t *testing.T) {
+	ip := net.ParseIP("192.168.1.20")
+	device := IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.GeneralLighting_ClassCode, 1)}
+	stub := &propertyStub{properties: Properties{
+		{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x31}},
+	}}
+
+	if _, err := NewLighting(stub, IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}); err == nil {
+		t.Error("expected an error for a device that is not a light")
+	}
+	light, err := NewLighting(stub, device)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := light.SetBrightness(40); err != nil {
+		t.Fatal(err)
+	}
+	if err := light.SetColorTemperature(LightColorDaylightWhite); err != nil {
+		t.Fatal(err)
+	}
+	if err := light.SetPower(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := light.SetBrightness(101); err == nil {
+		t.Error("expected an error for a brightness out of range")
+	}
+	want := []Property{
+		{EPC: echonet_lite.EPC_GL_Illuminance, EDT: []byte{40}},
+		{EPC: echonet_lite.EPC_GL_LightColor, EDT: []byte{0x43}},
+		{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}},
+	}
+	if len(stub.set) != len(want) {
+		t.Fatalf("set %d times, want %d", len(stub.set), len(want))
+	}
+	for i, p := range want {
+		if got := stub.set[i]; len(got) != 1 || got[0].EPC != p.EPC || string(got[0].EDT) != string(p.EDT) {
+			t.Errorf("set #%d = %v, want %v", i, got, p)
+		}
+	}
+
+	if brightness, err := light.GetBrightness(); err != nil || brightness != 40 {
+		t.Errorf("GetBrightness() = %d, %v", brightness, err)
+	}
+	if color, err := light.GetColorTemperature(); err != nil || color != LightColorDaylightWhite {
+		t.Errorf("GetColorTemperature() = %q, %v", color, err)
+	}
+	state, err := light.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Power == nil || !*state.Power || state.Brightness == nil || *state.Brightness != 40 || state.Color == nil {
+		t.Errorf("unexpected state: %+v", state)
+	}
+}
+
+func TestLighting_SingleFunction(t *testing.T) {
+	device := IPAndEOJ{IP: net.ParseIP("192.168.1.21"), EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)}
+	stub := &propertyStub{}
+	light, err := NewLighting(stub, device)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lowest, highest, err := BrightnessRange(echonet_lite.SingleFunctionLighting_ClassCode); err != nil || lowest != 0 || highest != 100 {
+		t.Errorf("BrightnessRange() = %d, %d, %v", lowest, highest, err)
+	}
+	if err := light.SetBrightness(70); err != nil {
+		t.Fatal(err)
+	}
+	// Single function lighting has no light color setting
+	if err := light.SetColorTemperature(LightColorWhite); err == nil {
+		t.Error("expected an error for the light color of single function lighting")
+	}
+	if _, err := light.GetColorTemperature(); err == nil {
+		t.Error("expected an error for the light color of single function lighting")
+	}
+	if len(stub.set) != 1 {
+		t.Errorf("set %d times, want 1", len(stub.set))
+	}
+
+	// A value out of range is treated as not reported
+	state := DecodeLightingState(echonet_lite.SingleFunctionLighting_ClassCode, Properties{{EPC: echonet_lite.EPC_SF_Illuminance, EDT: []byte{0xFF}}})
+	if state.Brightness != nil {
+		t.Errorf("unexpected brightness: %d", *state.Brightness)
+	}
+}
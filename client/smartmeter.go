@@ -0,0 +1,135 @@
+package client
+
+import (
+	"echonet-list/echonet_lite"
+	"fmt"
+	"time"
+)
+
+// SmartMeterState is the state of a low-voltage smart electric energy meter read at once by SmartMeter.State.
+// Cumulative amounts are converted to kWh with the coefficient (EPC 0xD3) and the unit (EPC 0xE1) of the meter.
+// The values a meter does not report are nil.
+type SmartMeterState struct {
+	InstantaneousPower *int     // W, negative while selling power
+	CurrentR           *float64 // A, R phase
+	CurrentT           *float64 // A, T phase; nil for single-phase two-wire meters
+	CumulativeKWh      *float64 // normal direction (bought)
+	ReverseKWh         *float64 // reverse direction (sold)
+	FixedTimeKWh       *float64 // normal direction at the last fixed time (every 30 minutes)
+	FixedTimeAt        time.Time
+}
+
+// SmartMeter reads a low-voltage smart electric energy meter (class 0x0288) with typed values.
+type SmartMeter struct {
+	typedDevice[SmartMeterState]
+}
+
+// NewSmartMeter returns the smart meter helper of a device. The device must be a low-voltage smart electric energy meter.
+func NewSmartMeter(c DeviceManager, device IPAndEOJ) (*SmartMeter, error) {
+	d, err := newTypedDevice(c, device, DecodeSmartMeterState, "a low-voltage smart electric energy meter", echonet_lite.LowVoltageSmartMeter_ClassCode)
+	if err != nil {
+		return nil, err
+	}
+	return &SmartMeter{d}, nil
+}
+
+// smartMeterStateEPCs are the properties read by State
+var smartMeterStateEPCs = []EPCType{
+	echonet_lite.EPC_SM_Coefficient,
+	echonet_lite.EPC_SM_CumulativeEnergyUnit,
+	echonet_lite.EPC_SM_InstantaneousPower,
+	echonet_lite.EPC_SM_InstantaneousCurrent,
+	echonet_lite.EPC_SM_CumulativeEnergy,
+	echonet_lite.EPC_SM_CumulativeEnergyReverse,
+	echonet_lite.EPC_SM_FixedTimeCumulativeEnergy,
+}
+
+// State reads the instantaneous power and current and the cumulative amounts of energy at once
+func (m *SmartMeter) State() (SmartMeterState, error) {
+	return m.read(smartMeterStateEPCs...)
+}
+
+// GetInstantaneousPower reads the instantaneous power (EPC 0xE7) in W
+func (m *SmartMeter) GetInstantaneousPower() (int, error) {
+	state, err := m.read(echonet_lite.EPC_SM_InstantaneousPower)
+	if err != nil {
+		return 0, err
+	}
+	if state.InstantaneousPower == nil {
+		return 0, fmt.Errorf("%v did not report the instantaneous power", m.Device)
+	}
+	return *state.InstantaneousPower, nil
+}
+
+// GetCumulativeKWh reads the cumulative amount of energy in the normal direction (EPC 0xE0) in kWh
+func (m *SmartMeter) GetCumulativeKWh() (float64, error) {
+	state, err := m.read(echonet_lite.EPC_SM_Coefficient, echonet_lite.EPC_SM_CumulativeEnergyUnit, echonet_lite.EPC_SM_CumulativeEnergy)
+	if err != nil {
+		return 0, err
+	}
+	if state.CumulativeKWh == nil {
+		return 0, fmt.Errorf("%v did not report the cumulative amount of energy or its unit", m.Device)
+	}
+	return *state.CumulativeKWh, nil
+}
+
+// DecodeSmartMeterState decodes the properties of a smart meter.
+// Cumulative amounts are left nil without the unit (EPC 0xE1). A meter without the coefficient (EPC 0xD3) uses 1.
+func DecodeSmartMeterState(properties Properties) SmartMeterState {
+	var state SmartMeterState
+	number := func(epc EPCType) (int, bool) {
+		p, ok := properties.FindEPC(epc)
+		if !ok {
+			return 0, false
+		}
+		desc, ok := echonet_lite.GetPropertyDesc(echonet_lite.LowVoltageSmartMeter_ClassCode, epc)
+		if !ok {
+			return 0, false
+		}
+		converter, ok := desc.Decoder.(echonet_lite.PropertyIntConverter)
+		if !ok {
+			return 0, false
+		}
+		value, _, ok := converter.ToInt(p.EDT)
+		return value, ok
+	}
+
+	if power, ok := number(echonet_lite.EPC_SM_InstantaneousPower); ok {
+		state.InstantaneousPower = &power
+	}
+	if p, ok := properties.FindEPC(echonet_lite.EPC_SM_InstantaneousCurrent); ok {
+		if r, t, ok := echonet_lite.SM_DecodeInstantaneousCurrent(p.EDT); ok {
+			state.CurrentR = &r
+			state.CurrentT = t
+		}
+	}
+
+	unit, ok := properties.FindEPC(echonet_lite.EPC_SM_CumulativeEnergyUnit)
+	if !ok {
+		return state
+	}
+	coefficient := 1
+	if value, ok := number(echonet_lite.EPC_SM_Coefficient); ok {
+		coefficient = value
+	}
+	kWh := func(value int) *float64 {
+		if kWh, ok := echonet_lite.SM_CumulativeEnergyKWh(value, coefficient, unit.EDT); ok {
+			return &kWh
+		}
+		return nil
+	}
+	if value, ok := number(echonet_lite.EPC_SM_CumulativeEnergy); ok {
+		state.CumulativeKWh = kWh(value)
+	}
+	if value, ok := number(echonet_lite.EPC_SM_CumulativeEnergyReverse); ok {
+		state.ReverseKWh = kWh(value)
+	}
+	if p, ok := properties.FindEPC(echonet_lite.EPC_SM_FixedTimeCumulativeEnergy); ok {
+		if at, value, ok := echonet_lite.SM_DecodeFixedTimeEnergy(p.EDT); ok {
+			if state.FixedTimeKWh = kWh(value); state.FixedTimeKWh != nil {
+				state.FixedTimeAt = at
+			}
+		}
+	}
+	return state
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"echonet-list/echonet_lite"
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSmartMeter(t *testing.T) {
+	ip := net.ParseIP("192.168.1.30")
+	device := IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.LowVoltageSmartMeter_ClassCode, 1)}
+	stub := &propertyStub{properties: Properties{
+		{EPC: echonet_lite.EPC_SM_Coefficient, EDT: []byte{0x00, 0x00, 0x00, 0x0A}},                                              // x10
+		{EPC: echonet_lite.EPC_SM_CumulativeEnergyUnit, EDT: []byte{0x02}},                                                       // 0.01kWh
+		{EPC: echonet_lite.EPC_SM_CumulativeEnergy, EDT: []byte{0x00, 0x01, 0xE2, 0x40}},                                         // 123456
+		{EPC: echonet_lite.EPC_SM_CumulativeEnergyReverse, EDT: []byte{0x00, 0x00, 0x03, 0xE8}},                                  // 1000
+		{EPC: echonet_lite.EPC_SM_InstantaneousPower, EDT: []byte{0xFF, 0xFF, 0xFE, 0x0C}},                                       // -500W
+		{EPC: echonet_lite.EPC_SM_InstantaneousCurrent, EDT: []byte{0x00, 0x7B, 0x7F, 0xFE}},                                     // R 12.3A, no T phase
+		{EPC: echonet_lite.EPC_SM_FixedTimeCumulativeEnergy, EDT: []byte{0x07, 0xEA, 10, 16, 12, 30, 0, 0x00, 0x01, 0xE2, 0x3A}}, // 123450
+	}}
+
+	if _, err := NewSmartMeter(stub, IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}); err == nil {
+		t.Error("expected an error for a device that is not a smart meter")
+	}
+	meter, err := NewSmartMeter(stub, device)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	near := func(got *float64, want float64) bool {
+		return got != nil && math.Abs(*got-want) < 1e-6
+	}
+	state, err := meter.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.InstantaneousPower == nil || *state.InstantaneousPower != -500 {
+		t.Errorf("unexpected instantaneous power: %v", state.InstantaneousPower)
+	}
+	if !near(state.CurrentR, 12.3) || state.CurrentT != nil {
+		t.Errorf("unexpected currents: %v, %v", state.CurrentR, state.CurrentT)
+	}
+	if !near(state.CumulativeKWh, 12345.6) {
+		t.Errorf("unexpected cumulative energy: %v", state.CumulativeKWh)
+	}
+	if !near(state.ReverseKWh, 100) {
+		t.Errorf("unexpected reverse cumulative energy: %v", state.ReverseKWh)
+	}
+	if !near(state.FixedTimeKWh, 12345) || !state.FixedTimeAt.Equal(time.Date(2026, 10, 16, 12, 30, 0, 0, time.Local)) {
+		t.Errorf("unexpected fixed-time energy: %v at %v", state.FixedTimeKWh, state.FixedTimeAt)
+	}
+
+	if power, err := meter.GetInstantaneousPower(); err != nil || power != -500 {
+		t.Errorf("GetInstantaneousPower() = %d, %v", power, err)
+	}
+	if kWh, err := meter.GetCumulativeKWh(); err != nil || math.Abs(kWh-12345.6) > 1e-6 {
+		t.Errorf("GetCumulativeKWh() = %v, %v", kWh, err)
+	}
+}
+
+func TestDecodeSmartMeterState_WithoutUnit(t *testing.T) {
+	// Cumulative amounts cannot be converted without the unit
+	state := DecodeSmartMeterState(Properties{
+		{EPC: echonet_lite.EPC_SM_CumulativeEnergy, EDT: []byte{0x00, 0x00, 0x00, 0x64}},
+	})
+	if state.CumulativeKWh != nil {
+		t.Errorf("unexpected cumulative energy: %v", *state.CumulativeKWh)
+	}
+
+	// Without the coefficient, the coefficient is 1
+	state = DecodeSmartMeterState(Properties{
+		{EPC: echonet_lite.EPC_SM_CumulativeEnergyUnit, EDT: []byte{0x01}},
+		{EPC: echonet_lite.EPC_SM_CumulativeEnergy, EDT: []byte{0x00, 0x00, 0x00, 0x64}},
+	})
+	if state.CumulativeKWh == nil || math.Abs(*state.CumulativeKWh-10) > 1e-9 {
+		t.Errorf("unexpected cumulative energy: %v", state.CumulativeKWh)
+	}
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"echonet-list/echonet_lite"
+	"fmt"
+)
+
+// SolarPowerState is the state of a household solar power generation read at once by SolarPower.State.
+// The values a device does not report are nil.
+type SolarPowerState struct {
+	GenerationPower *int     // W
+	GeneratedKWh    *float64 // cumulative generated energy
+	SoldKWh         *float64 // cumulative sold energy
+	RatedOutput     *int     // W
+	OutputLimit     *int     // %
+}
+
+// SolarPower reads a household solar power generation (class 0x0279) with typed values.
+type SolarPower struct {
+	typedDevice[SolarPowerState]
+}
+
+// NewSolarPower returns the solar power helper of a device. The device must be a household solar power generation.
+func NewSolarPower(c DeviceManager, device IPAndEOJ) (*SolarPower, error) {
+	d, err := newTypedDevice(c, device, DecodeSolarPowerState, "a household solar power generation", echonet_lite.HomeSolarPower_ClassCode)
+	if err != nil {
+		return nil, err
+	}
+	return &SolarPower{d}, nil
+}
+
+// solarPowerStateEPCs are the properties read by State
+var solarPowerStateEPCs = []EPCType{
+	echonet_lite.EPC_PV_InstantaneousGeneration,
+	echonet_lite.EPC_PV_CumulativeGeneration,
+	echonet_lite.EPC_PV_CumulativeSold,
+	echonet_lite.EPC_PV_RatedGenerationOutput,
+	echonet_lite.EPC_PV_OutputLimit,
+}
+
+// State reads the generation power and the cumulative amounts of energy at once
+func (s *SolarPower) State() (SolarPowerState, error) {
+	return s.read(solarPowerStateEPCs...)
+}
+
+// GetGenerationPower reads the instantaneous generation power (EPC 0xE0) in W
+func (s *SolarPower) GetGenerationPower() (int, error) {
+	state, err := s.read(echonet_lite.EPC_PV_InstantaneousGeneration)
+	if err != nil {
+		return 0, err
+	}
+	if state.GenerationPower == nil {
+		return 0, fmt.Errorf("%v did not report the generation power", s.Device)
+	}
+	return *state.GenerationPower, nil
+}
+
+// DecodeSolarPowerState decodes the properties of a household solar power generation.
+// Missing properties and values out of range are left nil.
+func DecodeSolarPowerState(properties Properties) SolarPowerState {
+	number := func(epc EPCType) *int {
+		return decodeNumber(echonet_lite.HomeSolarPower_ClassCode, properties, epc)
+	}
+	return SolarPowerState{
+		GenerationPower: number(echonet_lite.EPC_PV_InstantaneousGeneration),
+		GeneratedKWh:    whToKWh(number(echonet_lite.EPC_PV_CumulativeGeneration)),
+		SoldKWh:         whToKWh(number(echonet_lite.EPC_PV_CumulativeSold)),
+		RatedOutput:     number(echonet_lite.EPC_PV_RatedGenerationOutput),
+		OutputLimit:     number(echonet_lite.EPC_PV_OutputLimit),
+	}
+}
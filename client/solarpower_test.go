@@ -0,0 +1,46 @@
+package client
+
+import (
+	"echonet-list/echonet_lite"
+	"math"
+	"net"
+	"testing"
+)
+
+func TestSolarPower(t *testing.T) {
+	ip := net.ParseIP("192.168.1.51")
+	device := IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeSolarPower_ClassCode, 1)}
+	stub := &propertyStub{properties: Properties{
+		{EPC: echonet_lite.EPC_PV_InstantaneousGeneration, EDT: []byte{0x0B, 0xB8}},          // 3000W
+		{EPC: echonet_lite.EPC_PV_CumulativeGeneration, EDT: []byte{0x00, 0x98, 0x96, 0x80}}, // 10000kWh
+		{EPC: echonet_lite.EPC_PV_CumulativeSold, EDT: []byte{0x00, 0x00, 0x04, 0xD2}},       // 1.234kWh
+	}}
+
+	if _, err := NewSolarPower(stub, IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.StorageBattery_ClassCode, 1)}); err == nil {
+		t.Error("expected an error for a device that is not a solar power generation")
+	}
+	solar, err := NewSolarPower(stub, device)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := solar.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.GenerationPower == nil || *state.GenerationPower != 3000 {
+		t.Errorf("unexpected generation power: %v", state.GenerationPower)
+	}
+	if state.GeneratedKWh == nil || math.Abs(*state.GeneratedKWh-10000) > 1e-9 {
+		t.Errorf("unexpected generated energy: %v", state.GeneratedKWh)
+	}
+	if state.SoldKWh == nil || math.Abs(*state.SoldKWh-1.234) > 1e-9 {
+		t.Errorf("unexpected sold energy: %v", state.SoldKWh)
+	}
+	if state.RatedOutput != nil || state.OutputLimit != nil {
+		t.Errorf("unexpected state: %+v", state)
+	}
+	if power, err := solar.GetGenerationPower(); err != nil || power != 3000 {
+		t.Errorf("GetGenerationPower() = %d, %v", power, err)
+	}
+}
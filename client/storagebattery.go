@@ -0,0 +1,139 @@
+package client
+
+import (
+	"echonet-list/echonet_lite"
+	"fmt"
+)
+
+// BatteryOperationMode is the operation mode of a storage battery (EPC 0xDA) and its working status (EPC 0xCF).
+// The values are the aliases of EPC 0xDA, so they can also be used with the set command.
+type BatteryOperationMode string
+
+const (
+	BatteryOperationModeRapidCharging BatteryOperationMode = "rapid_charging"
+	BatteryOperationModeCharging      BatteryOperationMode = "charging"
+	BatteryOperationModeDischarging   BatteryOperationMode = "discharging"
+	BatteryOperationModeStandby       BatteryOperationMode = "standby"
+	BatteryOperationModeAuto          BatteryOperationMode = "auto"
+)
+
+// StorageBatteryState is the state of a storage battery read at once by StorageBattery.State.
+// The values a battery does not report are nil.
+type StorageBatteryState struct {
+	OperationMode *BatteryOperationMode // the mode set
+	WorkingStatus *BatteryOperationMode // what the battery is actually doing
+	ChargingPower *int                  // W, negative while discharging
+	SOC           *int                  // %, remaining capacity
+	RemainingWh   *int                  // Wh, remaining stored electricity
+	RatedWh       *int                  // Wh, rated capacity
+	ChargedKWh    *float64              // cumulative charged energy
+	DischargedKWh *float64              // cumulative discharged energy
+	StateOfHealth *int                  // %
+}
+
+// StorageBattery reads and operates a storage battery (class 0x027D) with typed values.
+type StorageBattery struct {
+	typedDevice[StorageBatteryState]
+}
+
+// NewStorageBattery returns the storage battery helper of a device. The device must be a storage battery.
+func NewStorageBattery(c DeviceManager, device IPAndEOJ) (*StorageBattery, error) {
+	d, err := newTypedDevice(c, device, DecodeStorageBatteryState, "a storage battery", echonet_lite.StorageBattery_ClassCode)
+	if err != nil {
+		return nil, err
+	}
+	return &StorageBattery{d}, nil
+}
+
+// storageBatteryStateEPCs are the properties read by State
+var storageBatteryStateEPCs = []EPCType{
+	echonet_lite.EPC_SB_OperationMode,
+	echonet_lite.EPC_SB_WorkingOperationStatus,
+	echonet_lite.EPC_SB_InstantaneousChargingPower,
+	echonet_lite.EPC_SB_RemainingCapacityPercentage,
+	echonet_lite.EPC_SB_RemainingStoredElectricity,
+	echonet_lite.EPC_SB_RatedEnergy,
+	echonet_lite.EPC_SB_CumulativeCharging,
+	echonet_lite.EPC_SB_CumulativeDischarging,
+	echonet_lite.EPC_SB_BatteryStateOfHealth,
+}
+
+// State reads the operation mode, the charging power, the remaining capacity and the cumulative amounts at once
+func (b *StorageBattery) State() (StorageBatteryState, error) {
+	return b.read(storageBatteryStateEPCs...)
+}
+
+// SetOperationMode sets the operation mode (EPC 0xDA)
+func (b *StorageBattery) SetOperationMode(mode BatteryOperationMode) error {
+	desc, ok := echonet_lite.GetPropertyDesc(echonet_lite.StorageBattery_ClassCode, echonet_lite.EPC_SB_OperationMode)
+	if !ok {
+		return fmt.Errorf("unknown property: %02X", byte(echonet_lite.EPC_SB_OperationMode))
+	}
+	edt, ok := desc.Aliases[string(mode)]
+	if !ok {
+		return fmt.Errorf("unknown operation mode: %s", mode)
+	}
+	return b.set(Property{EPC: echonet_lite.EPC_SB_OperationMode, EDT: edt})
+}
+
+// DecodeStorageBatteryState decodes the properties of a storage battery.
+// Missing properties and values out of range are left nil.
+func DecodeStorageBatteryState(properties Properties) StorageBatteryState {
+	var state StorageBatteryState
+	number := func(epc EPCType) *int {
+		return decodeNumber(echonet_lite.StorageBattery_ClassCode, properties, epc)
+	}
+	mode := func(epc EPCType) *BatteryOperationMode {
+		p, ok := properties.FindEPC(epc)
+		if !ok {
+			return nil
+		}
+		desc, ok := echonet_lite.GetPropertyDesc(echonet_lite.StorageBattery_ClassCode, epc)
+		if !ok {
+			return nil
+		}
+		if value := desc.EDTToString(p.EDT); value != "" {
+			mode := BatteryOperationMode(value)
+			return &mode
+		}
+		return nil
+	}
+
+	state.OperationMode = mode(echonet_lite.EPC_SB_OperationMode)
+	state.WorkingStatus = mode(echonet_lite.EPC_SB_WorkingOperationStatus)
+	state.ChargingPower = number(echonet_lite.EPC_SB_InstantaneousChargingPower)
+	state.SOC = number(echonet_lite.EPC_SB_RemainingCapacityPercentage)
+	state.RemainingWh = number(echonet_lite.EPC_SB_RemainingStoredElectricity)
+	state.RatedWh = number(echonet_lite.EPC_SB_RatedEnergy)
+	state.ChargedKWh = whToKWh(number(echonet_lite.EPC_SB_CumulativeCharging))
+	state.DischargedKWh = whToKWh(number(echonet_lite.EPC_SB_CumulativeDischarging))
+	state.StateOfHealth = number(echonet_lite.EPC_SB_BatteryStateOfHealth)
+	return state
+}
+
+// decodeNumber decodes a numeric property of a class with its property description
+func decodeNumber(classCode echonet_lite.EOJClassCode, properties Properties, epc EPCType) *int {
+	p, ok := properties.FindEPC(epc)
+	if !ok {
+		return nil
+	}
+	desc, ok := echonet_lite.GetPropertyDesc(classCode, epc)
+	if !ok {
+		return nil
+	}
+	if converter, ok := desc.Decoder.(echonet_lite.PropertyIntConverter); ok {
+		if value, _, ok := converter.ToInt(p.EDT); ok {
+			return &value
+		}
+	}
+	return nil
+}
+
+// whToKWh converts an amount of energy in Wh to kWh
+func whToKWh(wh *int) *float64 {
+	if wh == nil {
+		return nil
+	}
+	kWh := float64(*wh) / 1000
+	return &kWh
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"echonet-list/echonet_lite"
+	"math"
+	"net"
+	"testing"
+)
+
+func TestStorageBattery(t *testing.T) {
+	ip := net.ParseIP("192.168.1.50")
+	device := IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.StorageBattery_ClassCode, 1)}
+	stub := &propertyStub{properties: Properties{
+		{EPC: echonet_lite.EPC_SB_OperationMode, EDT: []byte{0x46}},
+		{EPC: echonet_lite.EPC_SB_WorkingOperationStatus, EDT: []byte{0x43}},
+		{EPC: echonet_lite.EPC_SB_InstantaneousChargingPower, EDT: []byte{0xFF, 0xFF, 0xFC, 0x18}}, // -1000W
+		{EPC: echonet_lite.EPC_SB_RemainingCapacityPercentage, EDT: []byte{80}},
+		{EPC: echonet_lite.EPC_SB_RemainingStoredElectricity, EDT: []byte{0x00, 0x00, 0x1F, 0x40}}, // 8000Wh
+		{EPC: echonet_lite.EPC_SB_CumulativeCharging, EDT: []byte{0x00, 0x01, 0xE2, 0x40}},         // 123.456kWh
+		{EPC: echonet_lite.EPC_SB_BatteryStateOfHealth, EDT: []byte{0xFF}},                         // out of range
+	}}
+
+	if _, err := NewStorageBattery(stub, IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeSolarPower_ClassCode, 1)}); err == nil {
+		t.Error("expected an error for a device that is not a storage battery")
+	}
+	battery, err := NewStorageBattery(stub, device)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := battery.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.OperationMode == nil || *state.OperationMode != BatteryOperationModeAuto {
+		t.Errorf("unexpected operation mode: %v", state.OperationMode)
+	}
+	if state.WorkingStatus == nil || *state.WorkingStatus != BatteryOperationModeDischarging {
+		t.Errorf("unexpected working status: %v", state.WorkingStatus)
+	}
+	if state.ChargingPower == nil || *state.ChargingPower != -1000 {
+		t.Errorf("unexpected charging power: %v", state.ChargingPower)
+	}
+	if state.SOC == nil || *state.SOC != 80 || state.RemainingWh == nil || *state.RemainingWh != 8000 {
+		t.Errorf("unexpected remaining capacity: %v, %v", state.SOC, state.RemainingWh)
+	}
+	if state.ChargedKWh == nil || math.Abs(*state.ChargedKWh-123.456) > 1e-9 || state.DischargedKWh != nil {
+		t.Errorf("unexpected cumulative energy: %v, %v", state.ChargedKWh, state.DischargedKWh)
+	}
+	if state.StateOfHealth != nil || state.RatedWh != nil {
+		t.Errorf("unexpected state: %+v", state)
+	}
+
+	if err := battery.SetOperationMode(BatteryOperationModeCharging); err != nil {
+		t.Fatal(err)
+	}
+	if err := battery.SetOperationMode("turbo"); err == nil {
+		t.Error("expected an error for an unknown operation mode")
+	}
+	if len(stub.set) != 1 || len(stub.set[0]) != 1 || stub.set[0][0].EPC != echonet_lite.EPC_SB_OperationMode || string(stub.set[0][0].EDT) != "\x42" {
+		t.Errorf("unexpected set: %v", stub.set)
+	}
+}
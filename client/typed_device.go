@@ -0,0 +1,49 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+)
+
+// typedDevice is the part shared by the typed helpers of the device classes, such as AirConditioner and SmartMeter:
+// it sets and reads the properties of one device and decodes them to the state type S of the class.
+// It only uses DeviceManager, so the helpers work with both the local handler and the WebSocket client.
+type typedDevice[S any] struct {
+	client DeviceManager
+	Device IPAndEOJ
+	decode func(Properties) S
+}
+
+// newTypedDevice checks that the device is of one of the classes and returns its typedDevice.
+// kind names the classes in the error, e.g. "a home air conditioner".
+func newTypedDevice[S any](c DeviceManager, device IPAndEOJ, decode func(Properties) S, kind string, classCodes ...EOJClassCode) (typedDevice[S], error) {
+	if !slices.Contains(classCodes, device.EOJ.ClassCode()) {
+		return typedDevice[S]{}, fmt.Errorf("%v is not %s", device, kind)
+	}
+	return typedDevice[S]{client: c, Device: device, decode: decode}, nil
+}
+
+// set sets properties of the device
+func (d *typedDevice[S]) set(properties ...Property) error {
+	_, err := d.client.SetProperties(d.Device, properties, nil)
+	return err
+}
+
+// get reads properties from the device
+func (d *typedDevice[S]) get(epcs ...EPCType) (Properties, error) {
+	result, err := d.client.GetProperties(d.Device, epcs, false)
+	if err != nil {
+		return nil, err
+	}
+	return result.Properties, nil
+}
+
+// read reads properties from the device and decodes them
+func (d *typedDevice[S]) read(epcs ...EPCType) (S, error) {
+	properties, err := d.get(epcs...)
+	if err != nil {
+		var zero S
+		return zero, err
+	}
+	return d.decode(properties), nil
+}
@@ -0,0 +1,247 @@
+package client
+
+import (
+	"echonet-list/echonet_lite"
+	"fmt"
+	"strings"
+)
+
+// WaterHeatingMode is the automatic water heating setting of an electric water heater (EPC 0xB0).
+// The values are the aliases of the property, so they can also be used with the set command.
+type WaterHeatingMode string
+
+const (
+	WaterHeatingModeAuto       WaterHeatingMode = "auto_heating"        // heat up automatically, usually at night
+	WaterHeatingModeManual     WaterHeatingMode = "manual_heating"      // heat up now
+	WaterHeatingModeManualStop WaterHeatingMode = "manual_heating_stop" // do not heat up
+)
+
+// ParseWaterHeatingMode parses a water heating mode: an alias of EPC 0xB0, or one of the short forms auto, boost and stop.
+func ParseWaterHeatingMode(s string) (WaterHeatingMode, error) {
+	switch strings.ToLower(s) {
+	case "auto":
+		return WaterHeatingModeAuto, nil
+	case "boost", "manual":
+		return WaterHeatingModeManual, nil
+	case "stop":
+		return WaterHeatingModeManualStop, nil
+	}
+	mode := WaterHeatingMode(strings.ToLower(s))
+	if _, err := waterHeaterAliasEDT(echonet_lite.EPC_EWH_AutomaticHeating, string(mode)); err != nil {
+		return "", fmt.Errorf("unknown water heating mode: %s (auto, boost or stop)", s)
+	}
+	return mode, nil
+}
+
+// WaterHeaterTankMode is the tank operation mode of an electric water heater (EPC 0xB6), the amount of water heated up.
+type WaterHeaterTankMode string
+
+const (
+	WaterHeaterTankModeStandard WaterHeaterTankMode = "standard"
+	WaterHeaterTankModeSaving   WaterHeaterTankMode = "saving"
+	WaterHeaterTankModeExtra    WaterHeaterTankMode = "extra"
+)
+
+// Range of the temperature settings (EPC 0xB3, 0xD1 and 0xD3)
+const (
+	MinWaterHeaterTemperature = 0
+	MaxWaterHeaterTemperature = 100
+)
+
+// waterHeaterAliasEDT returns the EDT of an alias of a property of the electric water heater
+func waterHeaterAliasEDT(epc EPCType, alias string) ([]byte, error) {
+	if desc, ok := echonet_lite.GetPropertyDesc(echonet_lite.ElectricWaterHeater_ClassCode, epc); ok {
+		if edt, ok := desc.Aliases[alias]; ok {
+			return edt, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown value of EPC %02X: %s", byte(epc), alias)
+}
+
+// waterHeaterAlias returns the alias of an EDT of a property of the electric water heater
+func waterHeaterAlias(epc EPCType, edt []byte) (string, bool) {
+	if desc, ok := echonet_lite.GetPropertyDesc(echonet_lite.ElectricWaterHeater_ClassCode, epc); ok {
+		if alias := desc.EDTToString(edt); alias != "" {
+			return alias, true
+		}
+	}
+	return "", false
+}
+
+// WaterHeaterState is the state of an electric water heater read at once by WaterHeater.State.
+// The values a device does not report are nil.
+type WaterHeaterState struct {
+	HeatingMode        *WaterHeatingMode
+	Heating            *bool // heating up the water now
+	HeatingTemperature *int  // ℃
+	TankMode           *WaterHeaterTankMode
+	DaytimeReheating   *bool // heating up during the day is permitted
+	TankTemperature    *int  // ℃
+	SupplyTemperature  *int  // ℃
+	BathTemperature    *int  // ℃
+	RemainingHotWater  *int  // L
+	TankCapacity       *int  // L
+}
+
+// WaterHeater operates an electric water heater such as an EcoCute (class 0x026B) with typed values.
+type WaterHeater struct {
+	typedDevice[WaterHeaterState]
+}
+
+// NewWaterHeater returns the water heater helper of a device. The device must be an electric water heater.
+func NewWaterHeater(c DeviceManager, device IPAndEOJ) (*WaterHeater, error) {
+	d, err := newTypedDevice(c, device, DecodeWaterHeaterState, "an electric water heater", echonet_lite.ElectricWaterHeater_ClassCode)
+	if err != nil {
+		return nil, err
+	}
+	return &WaterHeater{d}, nil
+}
+
+// setAlias sets a property of the water heater to an alias
+func (w *WaterHeater) setAlias(epc EPCType, alias string) error {
+	edt, err := waterHeaterAliasEDT(epc, alias)
+	if err != nil {
+		return err
+	}
+	return w.set(Property{EPC: epc, EDT: edt})
+}
+
+// setTemperature sets a temperature setting in ℃
+func (w *WaterHeater) setTemperature(epc EPCType, celsius int) error {
+	if celsius < MinWaterHeaterTemperature || celsius > MaxWaterHeaterTemperature {
+		return fmt.Errorf("temperature must be between %d and %d: %d", MinWaterHeaterTemperature, MaxWaterHeaterTemperature, celsius)
+	}
+	return w.set(Property{EPC: epc, EDT: []byte{byte(celsius)}})
+}
+
+// SetHeatingMode sets the automatic water heating setting (EPC 0xB0)
+func (w *WaterHeater) SetHeatingMode(mode WaterHeatingMode) error {
+	return w.setAlias(echonet_lite.EPC_EWH_AutomaticHeating, string(mode))
+}
+
+// SetDaytimeReheating permits or prohibits heating up during the day (EPC 0xC0)
+func (w *WaterHeater) SetDaytimeReheating(permitted bool) error {
+	alias := "no_daytime_reheating"
+	if permitted {
+		alias = "daytime_reheating"
+	}
+	return w.setAlias(echonet_lite.EPC_EWH_DaytimeReheating, alias)
+}
+
+// BoostNow heats up the water now, during the day: it permits daytime reheating (EPC 0xC0)
+// and starts manual water heating (EPC 0xB0) in one request.
+// Set the heating mode back to WaterHeatingModeAuto to return to the usual schedule.
+func (w *WaterHeater) BoostNow() error {
+	permit, err := waterHeaterAliasEDT(echonet_lite.EPC_EWH_DaytimeReheating, "daytime_reheating")
+	if err != nil {
+		return err
+	}
+	manual, err := waterHeaterAliasEDT(echonet_lite.EPC_EWH_AutomaticHeating, string(WaterHeatingModeManual))
+	if err != nil {
+		return err
+	}
+	return w.set(
+		Property{EPC: echonet_lite.EPC_EWH_DaytimeReheating, EDT: permit},
+		Property{EPC: echonet_lite.EPC_EWH_AutomaticHeating, EDT: manual},
+	)
+}
+
+// SetTankMode sets the tank operation mode (EPC 0xB6)
+func (w *WaterHeater) SetTankMode(mode WaterHeaterTankMode) error {
+	return w.setAlias(echonet_lite.EPC_EWH_TankOperationMode, string(mode))
+}
+
+// SetHeatingTemperature sets the water heating temperature (EPC 0xB3) in ℃
+func (w *WaterHeater) SetHeatingTemperature(celsius int) error {
+	return w.setTemperature(echonet_lite.EPC_EWH_HeatingTemperature, celsius)
+}
+
+// SetSupplyTemperature sets the temperature of supplied water (EPC 0xD1) in ℃
+func (w *WaterHeater) SetSupplyTemperature(celsius int) error {
+	return w.setTemperature(echonet_lite.EPC_EWH_SupplyTemperature, celsius)
+}
+
+// SetBathTemperature sets the bath water temperature (EPC 0xD3) in ℃
+func (w *WaterHeater) SetBathTemperature(celsius int) error {
+	return w.setTemperature(echonet_lite.EPC_EWH_BathTemperature, celsius)
+}
+
+// GetRemainingHotWater reads the amount of hot water remaining in the tank (EPC 0xE1) in L
+func (w *WaterHeater) GetRemainingHotWater() (int, error) {
+	state, err := w.read(echonet_lite.EPC_EWH_RemainingHotWater)
+	if err != nil {
+		return 0, err
+	}
+	if state.RemainingHotWater == nil {
+		return 0, fmt.Errorf("%v did not report the remaining hot water", w.Device)
+	}
+	return *state.RemainingHotWater, nil
+}
+
+// State reads the heating settings, the temperatures and the remaining hot water at once
+func (w *WaterHeater) State() (WaterHeaterState, error) {
+	return w.read(
+		echonet_lite.EPC_EWH_AutomaticHeating,
+		echonet_lite.EPC_EWH_HeatingStatus,
+		echonet_lite.EPC_EWH_HeatingTemperature,
+		echonet_lite.EPC_EWH_TankOperationMode,
+		echonet_lite.EPC_EWH_DaytimeReheating,
+		echonet_lite.EPC_EWH_TankTemperature,
+		echonet_lite.EPC_EWH_SupplyTemperature,
+		echonet_lite.EPC_EWH_BathTemperature,
+		echonet_lite.EPC_EWH_RemainingHotWater,
+		echonet_lite.EPC_EWH_TankCapacity,
+	)
+}
+
+// DecodeWaterHeaterState decodes the properties of an electric water heater.
+// Missing properties and values out of range are left nil.
+func DecodeWaterHeaterState(properties Properties) WaterHeaterState {
+	var state WaterHeaterState
+	alias := func(epc EPCType) (string, bool) {
+		if p, ok := properties.FindEPC(epc); ok {
+			return waterHeaterAlias(epc, p.EDT)
+		}
+		return "", false
+	}
+	number := func(epc EPCType) *int {
+		p, ok := properties.FindEPC(epc)
+		if !ok {
+			return nil
+		}
+		desc, ok := echonet_lite.GetPropertyDesc(echonet_lite.ElectricWaterHeater_ClassCode, epc)
+		if !ok {
+			return nil
+		}
+		if converter, ok := desc.Decoder.(echonet_lite.PropertyIntConverter); ok {
+			if value, _, ok := converter.ToInt(p.EDT); ok {
+				return &value
+			}
+		}
+		return nil
+	}
+
+	if value, ok := alias(echonet_lite.EPC_EWH_AutomaticHeating); ok {
+		mode := WaterHeatingMode(value)
+		state.HeatingMode = &mode
+	}
+	if value, ok := alias(echonet_lite.EPC_EWH_HeatingStatus); ok {
+		heating := value == "heating"
+		state.Heating = &heating
+	}
+	if value, ok := alias(echonet_lite.EPC_EWH_TankOperationMode); ok {
+		mode := WaterHeaterTankMode(value)
+		state.TankMode = &mode
+	}
+	if value, ok := alias(echonet_lite.EPC_EWH_DaytimeReheating); ok {
+		permitted := value == "daytime_reheating"
+		state.DaytimeReheating = &permitted
+	}
+	state.HeatingTemperature = number(echonet_lite.EPC_EWH_HeatingTemperature)
+	state.TankTemperature = number(echonet_lite.EPC_EWH_TankTemperature)
+	state.SupplyTemperature = number(echonet_lite.EPC_EWH_SupplyTemperature)
+	state.BathTemperature = number(echonet_lite.EPC_EWH_BathTemperature)
+	state.RemainingHotWater = number(echonet_lite.EPC_EWH_RemainingHotWater)
+	state.TankCapacity = number(echonet_lite.EPC_EWH_TankCapacity)
+	return state
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"echonet-list/echonet_lite"
+	"net"
+	"testing"
+)
+
+func TestParseWaterHeatingMode(t *testing.T) {
+	tests := map[string]WaterHeatingMode{
+		"auto":           WaterHeatingModeAuto,
+		"auto_heating":   WaterHeatingModeAuto,
+		"Boost":          WaterHeatingModeManual,
+		"manual_heating": WaterHeatingModeManual,
+		"stop":           WaterHeatingModeManualStop,
+	}
+	for input, want := range tests {
+		if got, err := ParseWaterHeatingMode(input); err != nil || got != want {
+			t.Errorf("ParseWaterHeatingMode(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseWaterHeatingMode("turbo"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func TestWaterHeater(t *testing.T) {
+	ip := net.ParseIP("192.168.1.40")
+	device := IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.ElectricWaterHeater_ClassCode, 1)}
+	stub := &propertyStub{properties: Properties{
+		{EPC: echonet_lite.EPC_EWH_AutomaticHeating, EDT: []byte{0x41}},
+		{EPC: echonet_lite.EPC_EWH_HeatingStatus, EDT: []byte{0x42}},
+		{EPC: echonet_lite.EPC_EWH_DaytimeReheating, EDT: []byte{0x42}},
+		{EPC: echonet_lite.EPC_EWH_TankTemperature, EDT: []byte{65}},
+		{EPC: echonet_lite.EPC_EWH_RemainingHotWater, EDT: []byte{0x01, 0x2C}}, // 300L
+		{EPC: echonet_lite.EPC_EWH_TankCapacity, EDT: []byte{0x01, 0xCC}},      // 460L
+	}}
+
+	if _, err := NewWaterHeater(stub, IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}); err == nil {
+		t.Error("expected an error for a device that is not a water heater")
+	}
+	heater, err := NewWaterHeater(stub, device)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := heater.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.HeatingMode == nil || *state.HeatingMode != WaterHeatingModeAuto || state.Heating == nil || *state.Heating {
+		t.Errorf("unexpected heating state: %+v", state)
+	}
+	if state.DaytimeReheating == nil || *state.DaytimeReheating {
+		t.Errorf("unexpected daytime reheating: %v", state.DaytimeReheating)
+	}
+	if state.TankTemperature == nil || *state.TankTemperature != 65 || state.TankCapacity == nil || *state.TankCapacity != 460 {
+		t.Errorf("unexpected tank state: %+v", state)
+	}
+	if remaining, err := heater.GetRemainingHotWater(); err != nil || remaining != 300 {
+		t.Errorf("GetRemainingHotWater() = %d, %v", remaining, err)
+	}
+
+	// BoostNow permits daytime reheating and starts heating in one request
+	if err := heater.BoostNow(); err != nil {
+		t.Fatal(err)
+	}
+	if err := heater.SetSupplyTemperature(42); err != nil {
+		t.Fatal(err)
+	}
+	if err := heater.SetSupplyTemperature(101); err == nil {
+		t.Error("expected an error for a temperature out of range")
+	}
+	if err := heater.SetTankMode("turbo"); err == nil {
+		t.Error("expected an error for an unknown tank mode")
+	}
+	want := []Properties{
+		{{EPC: echonet_lite.EPC_EWH_DaytimeReheating, EDT: []byte{0x41}}, {EPC: echonet_lite.EPC_EWH_AutomaticHeating, EDT: []byte{0x42}}},
+		{{EPC: echonet_lite.EPC_EWH_SupplyTemperature, EDT: []byte{42}}},
+	}
+	if len(stub.set) != len(want) {
+		t.Fatalf("set %d times, want %d", len(stub.set), len(want))
+	}
+	for i, properties := range want {
+		got := stub.set[i]
+		if len(got) != len(properties) {
+			t.Errorf("set #%d = %v, want %v", i, got, properties)
+			continue
+		}
+		for j, p := range properties {
+			if got[j].EPC != p.EPC || string(got[j].EDT) != string(p.EDT) {
+				t.Errorf("set #%d = %v, want %v", i, got, properties)
+			}
+		}
+	}
+
+	state, err = heater.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.HeatingMode == nil || *state.HeatingMode != WaterHeatingModeManual || state.DaytimeReheating == nil || !*state.DaytimeReheating {
+		t.Errorf("unexpected state after boost: %+v", state)
+	}
+}
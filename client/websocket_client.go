@@ -7,8 +7,10 @@ import (
 	"echonet-list/echonet_lite/handler"
 	"echonet-list/protocol"
 	"fmt"
+	"log/slog"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -37,10 +39,22 @@ type WebSocketClient struct {
 	locationAliases       map[string]string
 	locationOrder         []string
 	locationSettingsMutex sync.RWMutex
+	scheduleNextRuns      map[string]time.Time // next run times computed by the server in the last ScheduleList
+	scheduleNextRunsMutex sync.RWMutex
 	requestID             int
 	requestIDMutex        sync.Mutex
 	responseCh            map[string]chan *protocol.Message
 	responseChMutex       sync.Mutex
+	propertyWatchers      map[chan PropertyChangeNotification]struct{}
+	propertyWatchersMutex sync.Mutex
+	initialState          chan struct{} // closed when the first initial_state message is received
+	initialStateOnce      sync.Once
+	disconnected          atomic.Bool   // true while reconnecting after the connection was lost
+	resyncPending         atomic.Bool   // true until the initial_state after a reconnection is applied
+	lost                  chan struct{} // closed when the current connection is lost
+	reconnectMutex        sync.Mutex    // protects lost
+	minReconnectDelay     time.Duration // first reconnection delay (reconnectMinDelay if zero)
+	maxReconnectDelay     time.Duration // maximum reconnection delay (reconnectMaxDelay if zero)
 }
 
 // NewWebSocketClient creates a new WebSocket client
@@ -55,17 +69,19 @@ func NewWebSocketClient(ctx context.Context, serverURL string, debug bool) (*Web
 	}
 
 	client := &WebSocketClient{
-		ctx:             clientCtx,
-		cancel:          cancel,
-		transport:       transport,
-		debug:           debug,
-		devices:         make(map[string]WebSocketDeviceAndProperties),
-		lastSeenTimes:   make(map[string]time.Time),
-		aliases:         make(map[string]IDString),
-		groups:          make([]GroupDevicePair, 0),
-		locationAliases: make(map[string]string),
-		locationOrder:   make([]string, 0),
-		responseCh:      make(map[string]chan *protocol.Message),
+		ctx:              clientCtx,
+		cancel:           cancel,
+		transport:        transport,
+		debug:            debug,
+		devices:          make(map[string]WebSocketDeviceAndProperties),
+		lastSeenTimes:    make(map[string]time.Time),
+		aliases:          make(map[string]IDString),
+		groups:           make([]GroupDevicePair, 0),
+		locationAliases:  make(map[string]string),
+		locationOrder:    make([]string, 0),
+		responseCh:       make(map[string]chan *protocol.Message),
+		propertyWatchers: make(map[chan PropertyChangeNotification]struct{}),
+		initialState:     make(chan struct{}),
 	}
 
 	return client, nil
@@ -84,9 +100,25 @@ func (c *WebSocketClient) Connect() error {
 	return nil
 }
 
+// WaitInitialState waits until the initial_state message has been received after Connect,
+// so that the device list is available before running a command non-interactively.
+func (c *WebSocketClient) WaitInitialState(timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-c.initialState:
+		return nil
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	case <-timer.C:
+		return fmt.Errorf("timed out waiting for the initial state from the server")
+	}
+}
+
 // Close closes the WebSocket connection
 func (c *WebSocketClient) Close() error {
 	c.cancel()
+	c.closePropertyWatchers()
 	return c.transport.Close()
 }
 
@@ -387,7 +419,8 @@ func (c *WebSocketClient) GetDevicesByGroup(groupName string) ([]IDString, bool)
 	return groups[0].Devices, true
 }
 
-// listenForMessages listens for messages from the WebSocket server
+// listenForMessages listens for messages from the WebSocket server.
+// When the connection is lost, it reconnects with exponential backoff until the client is closed.
 func (c *WebSocketClient) listenForMessages() {
 	for {
 		select {
@@ -397,17 +430,22 @@ func (c *WebSocketClient) listenForMessages() {
 			// Read a message using the transport
 			_, message, err := c.transport.ReadMessage()
 			if err != nil {
-				if c.debug {
-					fmt.Printf("Error reading message: %v\n", err)
+				if c.ctx.Err() != nil {
+					return
+				}
+				slog.Warn("WebSocketClient: connection to the server lost, reconnecting", "err", err)
+				c.handleDisconnect()
+				if !c.reconnect() {
+					return
 				}
-				return
+				continue
 			}
 
 			// Parse the message
 			msg, err := protocol.ParseMessage(message)
 			if err != nil {
 				if c.debug {
-					fmt.Printf("Error parsing message: %v\n", err)
+					slog.Debug("WebSocketClient: Error parsing message", "err", err)
 				}
 				continue
 			}
@@ -429,8 +467,22 @@ func (c *WebSocketClient) listenForMessages() {
 	}
 }
 
+// requestTimeout is how long sendRequest waits for a response
+const requestTimeout = 10 * time.Second
+
 // sendRequest sends a request to the WebSocket server and waits for a response
 func (c *WebSocketClient) sendRequest(msgType protocol.MessageType, payload interface{}) (*protocol.Message, error) {
+	return c.sendRequestWithTimeout(msgType, payload, requestTimeout)
+}
+
+// sendRequestWithTimeout sends a request and waits for a response up to timeout.
+// Used for requests that may legitimately take longer than requestTimeout.
+func (c *WebSocketClient) sendRequestWithTimeout(msgType protocol.MessageType, payload interface{}, timeout time.Duration) (*protocol.Message, error) {
+	// Fail fast instead of waiting for the timeout while reconnecting
+	if c.disconnected.Load() {
+		return nil, errConnectionLost
+	}
+
 	// Generate a request ID
 	c.requestIDMutex.Lock()
 	c.requestID++
@@ -450,6 +502,7 @@ func (c *WebSocketClient) sendRequest(msgType protocol.MessageType, payload inte
 	}
 
 	// Send the message using the transport
+	lost := c.connectionLost()
 	if err := c.transport.WriteMessage(websocket.TextMessage, data); err != nil {
 		return nil, fmt.Errorf("error sending message: %v", err)
 	}
@@ -458,8 +511,10 @@ func (c *WebSocketClient) sendRequest(msgType protocol.MessageType, payload inte
 	select {
 	case response := <-responseCh:
 		return response, nil
-	case <-time.After(10 * time.Second):
+	case <-time.After(timeout):
 		return nil, fmt.Errorf("timeout waiting for response")
+	case <-lost:
+		return nil, errConnectionLost
 	case <-c.ctx.Done():
 		return nil, fmt.Errorf("context canceled")
 	}
@@ -3,13 +3,14 @@ package client
 import (
 	"context"
 	"net/url"
+	"sync"
 
 	"github.com/gorilla/websocket"
 )
 
 // WebSocketClientTransport はWebSocketクライアントのネットワーク層を抽象化するインターフェース
 type WebSocketClientTransport interface {
-	// Connect はWebSocketサーバーに接続する
+	// Connect はWebSocketサーバーに接続する。切断後に再接続するときにも呼ばれる
 	Connect() error
 
 	// Close は接続を閉じる
@@ -30,6 +31,8 @@ type DefaultWebSocketClientTransport struct {
 	ctx     context.Context
 	url     string
 	conn    *websocket.Conn
+	connMu  sync.RWMutex // conn の差し替えを保護する
+	writeMu sync.Mutex   // websocket.Conn は同時に1つしか書き込めないため、書き込みを直列化する
 	dialer  *websocket.Dialer
 	isDebug bool
 }
@@ -51,41 +54,61 @@ func NewDefaultWebSocketClientTransport(ctx context.Context, serverURL string, d
 }
 
 // Connect はWebSocketサーバーに接続する
+// 既に接続がある場合は、新しい接続に差し替えて古い接続を閉じる
 func (t *DefaultWebSocketClientTransport) Connect() error {
-	conn, _, err := t.dialer.Dial(t.url, nil)
+	conn, _, err := t.dialer.DialContext(t.ctx, t.url, nil)
 	if err != nil {
 		return err
 	}
+	t.connMu.Lock()
+	old := t.conn
 	t.conn = conn
+	t.connMu.Unlock()
+	if old != nil {
+		_ = old.Close()
+	}
 	return nil
 }
 
 // Close は接続を閉じる
 func (t *DefaultWebSocketClientTransport) Close() error {
-	if t.conn != nil {
-		return t.conn.Close()
+	t.connMu.RLock()
+	conn := t.conn
+	t.connMu.RUnlock()
+	if conn != nil {
+		return conn.Close()
 	}
 	return nil
 }
 
 // ReadMessage はWebSocketサーバーからメッセージを読み込む
 func (t *DefaultWebSocketClientTransport) ReadMessage() (messageType int, p []byte, err error) {
-	if t.conn == nil {
+	t.connMu.RLock()
+	conn := t.conn
+	t.connMu.RUnlock()
+	if conn == nil {
 		return 0, nil, websocket.ErrCloseSent
 	}
-	return t.conn.ReadMessage()
+	return conn.ReadMessage()
 }
 
 // WriteMessage はWebSocketサーバーにメッセージを送信する
 func (t *DefaultWebSocketClientTransport) WriteMessage(messageType int, data []byte) error {
-	if t.conn == nil {
+	t.connMu.RLock()
+	conn := t.conn
+	t.connMu.RUnlock()
+	if conn == nil {
 		return websocket.ErrCloseSent
 	}
-	return t.conn.WriteMessage(messageType, data)
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	return conn.WriteMessage(messageType, data)
 }
 
 // IsConnected は接続が確立されているかどうかを返す
 func (t *DefaultWebSocketClientTransport) IsConnected() bool {
+	t.connMu.RLock()
+	defer t.connMu.RUnlock()
 	return t.conn != nil
 }
 
@@ -6,6 +6,9 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"sort"
+	"time"
 )
 
 // Discover sends a discover_devices message to the server
@@ -16,7 +19,7 @@ func (c *WebSocketClient) Discover() error {
 	// Send the message
 	_, err := c.sendRequest(protocol.MessageTypeDiscoverDevices, payload)
 	if err != nil {
-		fmt.Printf("Error discovering devices: %v\n", err)
+		slog.Error("Error discovering devices", "err", err)
 	}
 	return err
 }
@@ -47,7 +50,7 @@ func (c *WebSocketClient) UpdateProperties(criteria FilterCriteria, force bool)
 	// Send the message
 	_, err := c.sendRequest(protocol.MessageTypeUpdateProperties, payload)
 	if err != nil {
-		fmt.Printf("Error updating properties: %v\n", err)
+		slog.Error("Error updating properties", "err", err)
 	}
 	return err
 }
@@ -73,6 +76,7 @@ func (c *WebSocketClient) GetProperties(device IPAndEOJ, EPCs []EPCType, skipVal
 	payload := protocol.GetPropertiesPayload{
 		Targets: []string{device.Specifier()},
 		EPCs:    epcs,
+		All:     len(EPCs) == 0,
 	}
 
 	// Send the message
@@ -95,27 +99,47 @@ func (c *WebSocketClient) GetProperties(device IPAndEOJ, EPCs []EPCType, skipVal
 	}
 
 	// Parse the device data
-	var deviceData protocol.Device
+	var result protocol.SetPropertiesResult
 	if resultPayload.Data != nil {
-		if err := json.Unmarshal(resultPayload.Data, &deviceData); err != nil {
+		if err := json.Unmarshal(resultPayload.Data, &result); err != nil {
 			return DeviceAndProperties{}, fmt.Errorf("error parsing device data: %v", err)
 		}
 	}
 
 	// Convert protocol.Device to echonet_lite types using DeviceFromProtocol
-	ipAndEOJ, props, err := protocol.DeviceFromProtocol(deviceData)
+	ipAndEOJ, props, err := protocol.DeviceFromProtocol(result.Device)
 	if err != nil {
 		return DeviceAndProperties{}, fmt.Errorf("error converting device: %v", err)
 	}
 
 	return DeviceAndProperties{
 		Device:     ipAndEOJ,
-		Properties: props,
+		Properties: sortByAppliedOrder(props, result.AppliedOrder),
 	}, nil
 }
 
-// SetProperties sets properties on a device
-func (c *WebSocketClient) SetProperties(device IPAndEOJ, properties Properties) (DeviceAndProperties, error) {
+// sortByAppliedOrder puts the properties in the order the server set them; properties not in order keep their place after them
+func sortByAppliedOrder(properties Properties, order []string) Properties {
+	if len(order) == 0 {
+		return properties
+	}
+	rank := make(map[string]int, len(order))
+	for i, epc := range order {
+		rank[epc] = i
+	}
+	position := func(prop Property) int {
+		if i, ok := rank[prop.EPC.String()]; ok {
+			return i
+		}
+		return len(order)
+	}
+	sort.SliceStable(properties, func(i, j int) bool { return position(properties[i]) < position(properties[j]) })
+	return properties
+}
+
+// SetProperties sets properties on a device.
+// The EPCs in skipValidationEPCs are not checked against the Set property map of the device.
+func (c *WebSocketClient) SetProperties(device IPAndEOJ, properties Properties, skipValidationEPCs []EPCType) (DeviceAndProperties, error) {
 	// Create the payload
 	propsMap := make(protocol.PropertyMap)
 	for _, prop := range properties {
@@ -129,6 +153,9 @@ func (c *WebSocketClient) SetProperties(device IPAndEOJ, properties Properties)
 		Target:     device.Specifier(),
 		Properties: propsMap,
 	}
+	for _, epc := range skipValidationEPCs {
+		payload.SkipValidationEPCs = append(payload.SkipValidationEPCs, fmt.Sprintf("%02X", byte(epc)))
+	}
 
 	// Send the message
 	response, err := c.sendRequest(protocol.MessageTypeSetProperties, payload)
@@ -169,6 +196,63 @@ func (c *WebSocketClient) SetProperties(device IPAndEOJ, properties Properties)
 	}, nil
 }
 
+// SetGetProperties sets and reads properties of a device in one SetGet frame
+func (c *WebSocketClient) SetGetProperties(device IPAndEOJ, properties Properties, EPCs []EPCType) (SetGetResult, error) {
+	// Create the payload
+	propsMap := make(protocol.PropertyMap)
+	for _, prop := range properties {
+		propsMap.Set(prop.EPC, protocol.PropertyData{
+			EDT: base64.StdEncoding.EncodeToString(prop.EDT),
+		})
+	}
+	epcs := make([]string, 0, len(EPCs))
+	for _, epc := range EPCs {
+		epcs = append(epcs, fmt.Sprintf("%02X", byte(epc)))
+	}
+
+	payload := protocol.SetGetPropertiesPayload{
+		Target:     device.Specifier(),
+		Properties: propsMap,
+		EPCs:       epcs,
+	}
+
+	// Send the message
+	response, err := c.sendRequest(protocol.MessageTypeSetGetProperties, payload)
+	if err != nil {
+		return SetGetResult{}, err
+	}
+
+	// Parse the response
+	var resultPayload protocol.CommandResultPayload
+	if err := protocol.ParsePayload(response, &resultPayload); err != nil {
+		return SetGetResult{}, fmt.Errorf("error parsing response: %v", err)
+	}
+
+	if !resultPayload.Success {
+		if resultPayload.Error != nil {
+			return SetGetResult{}, fmt.Errorf("error setting and getting properties: %s: %s", resultPayload.Error.Code, resultPayload.Error.Message)
+		}
+		return SetGetResult{}, fmt.Errorf("error setting and getting properties: unknown error")
+	}
+
+	var data protocol.SetGetPropertiesResult
+	if err := json.Unmarshal(resultPayload.Data, &data); err != nil {
+		return SetGetResult{}, fmt.Errorf("error parsing set_get_properties result: %v", err)
+	}
+
+	// Convert the property maps using DeviceFromProtocol, which resolves string and number values by class
+	_, setProps, err := protocol.DeviceFromProtocol(protocol.Device{IP: data.IP, EOJ: data.EOJ, Properties: data.Set})
+	if err != nil {
+		return SetGetResult{}, fmt.Errorf("error converting set properties: %v", err)
+	}
+	_, getProps, err := protocol.DeviceFromProtocol(protocol.Device{IP: data.IP, EOJ: data.EOJ, Properties: data.Get})
+	if err != nil {
+		return SetGetResult{}, fmt.Errorf("error converting get properties: %v", err)
+	}
+
+	return SetGetResult{Device: device, Set: setProps, Get: getProps}, nil
+}
+
 // AliasSet sets an alias for a device
 func (c *WebSocketClient) AliasSet(alias *string, criteria FilterCriteria) error {
 	if alias == nil {
@@ -324,3 +408,420 @@ func (c *WebSocketClient) GroupDelete(groupName string) error {
 
 	return nil
 }
+
+// sceneClassCode returns the class code of a device referenced by a scene, if the device is known
+func (c *WebSocketClient) sceneClassCode(id IDString) (EOJClassCode, bool) {
+	device := c.FindDeviceByIDString(id)
+	if device == nil {
+		return 0, false
+	}
+	return device.EOJ.ClassCode(), true
+}
+
+// SceneList returns a list of scenes
+func (c *WebSocketClient) SceneList(sceneName *string) []SceneActionsPair {
+	payload := protocol.ManageScenePayload{
+		Action: protocol.SceneActionList,
+	}
+	if sceneName != nil {
+		payload.Scene = *sceneName
+	}
+
+	response, err := c.sendRequest(protocol.MessageTypeManageScene, payload)
+	if err != nil {
+		slog.Error("Error listing scenes", "err", err)
+		return []SceneActionsPair{}
+	}
+
+	var resultPayload protocol.CommandResultPayload
+	if err := protocol.ParsePayload(response, &resultPayload); err != nil || !resultPayload.Success || resultPayload.Data == nil {
+		return []SceneActionsPair{}
+	}
+
+	var scenes map[string][]protocol.SceneDeviceProperties
+	if err := json.Unmarshal(resultPayload.Data, &scenes); err != nil {
+		slog.Error("Error parsing scene data", "err", err)
+		return []SceneActionsPair{}
+	}
+
+	names := make([]string, 0, len(scenes))
+	for name := range scenes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]SceneActionsPair, 0, len(names))
+	for _, name := range names {
+		actions, err := protocol.SceneActionsFromProtocol(scenes[name])
+		if err != nil {
+			slog.Error("Error parsing scene", "name", name, "err", err)
+			continue
+		}
+		result = append(result, SceneActionsPair{Scene: name, Actions: actions})
+	}
+	return result
+}
+
+// GetScene gets the actions of a scene
+func (c *WebSocketClient) GetScene(sceneName string) ([]SceneAction, bool) {
+	if err := handler.ValidateSceneName(sceneName); err != nil {
+		return nil, false
+	}
+	scenes := c.SceneList(&sceneName)
+	if len(scenes) == 0 {
+		return nil, false
+	}
+	return scenes[0].Actions, true
+}
+
+// SceneAdd adds actions to a scene
+func (c *WebSocketClient) SceneAdd(sceneName string, actions []SceneAction) error {
+	// Validate the scene name
+	if err := handler.ValidateSceneName(sceneName); err != nil {
+		return err
+	}
+
+	// Create the payload
+	payload := protocol.ManageScenePayload{
+		Action:  protocol.SceneActionAdd,
+		Scene:   sceneName,
+		Actions: protocol.SceneActionsToProtocol(actions, c.sceneClassCode),
+	}
+
+	// Send the message
+	response, err := c.sendRequest(protocol.MessageTypeManageScene, payload)
+	if err != nil {
+		return fmt.Errorf("error adding actions to scene: %v", err)
+	}
+
+	// Parse the response
+	var resultPayload protocol.CommandResultPayload
+	if err := protocol.ParsePayload(response, &resultPayload); err != nil {
+		return fmt.Errorf("error parsing response: %v", err)
+	}
+
+	if !resultPayload.Success {
+		if resultPayload.Error != nil {
+			return fmt.Errorf("error adding actions to scene: %s: %s", resultPayload.Error.Code, resultPayload.Error.Message)
+		}
+		return fmt.Errorf("error adding actions to scene: unknown error")
+	}
+
+	return nil
+}
+
+// SceneDelete deletes a scene
+func (c *WebSocketClient) SceneDelete(sceneName string) error {
+	// Validate the scene name
+	if err := handler.ValidateSceneName(sceneName); err != nil {
+		return err
+	}
+
+	// Create the payload
+	payload := protocol.ManageScenePayload{
+		Action: protocol.SceneActionDelete,
+		Scene:  sceneName,
+	}
+
+	// Send the message
+	response, err := c.sendRequest(protocol.MessageTypeManageScene, payload)
+	if err != nil {
+		return fmt.Errorf("error deleting scene: %v", err)
+	}
+
+	// Parse the response
+	var resultPayload protocol.CommandResultPayload
+	if err := protocol.ParsePayload(response, &resultPayload); err != nil {
+		return fmt.Errorf("error parsing response: %v", err)
+	}
+
+	if !resultPayload.Success {
+		if resultPayload.Error != nil {
+			return fmt.Errorf("error deleting scene: %s: %s", resultPayload.Error.Code, resultPayload.Error.Message)
+		}
+		return fmt.Errorf("error deleting scene: unknown error")
+	}
+
+	return nil
+}
+
+// SceneSetSequence sets the device order and delay used when running a scene
+func (c *WebSocketClient) SceneSetSequence(sceneName string, sequence SceneSequence) error {
+	if err := handler.ValidateSceneName(sceneName); err != nil {
+		return err
+	}
+
+	payload := protocol.ManageScenePayload{
+		Action:   protocol.SceneActionSetSequence,
+		Scene:    sceneName,
+		Sequence: &sequence,
+	}
+	response, err := c.sendRequest(protocol.MessageTypeManageScene, payload)
+	if err != nil {
+		return fmt.Errorf("error setting scene sequence: %v", err)
+	}
+
+	var resultPayload protocol.CommandResultPayload
+	if err := protocol.ParsePayload(response, &resultPayload); err != nil {
+		return fmt.Errorf("error parsing response: %v", err)
+	}
+	if !resultPayload.Success {
+		if resultPayload.Error != nil {
+			return fmt.Errorf("error setting scene sequence: %s: %s", resultPayload.Error.Code, resultPayload.Error.Message)
+		}
+		return fmt.Errorf("error setting scene sequence: unknown error")
+	}
+	return nil
+}
+
+// GetSceneSequence gets the device order and delay of a scene (zero value if not set or on error)
+func (c *WebSocketClient) GetSceneSequence(sceneName string) SceneSequence {
+	payload := protocol.ManageScenePayload{
+		Action: protocol.SceneActionGetSequence,
+		Scene:  sceneName,
+	}
+	response, err := c.sendRequest(protocol.MessageTypeManageScene, payload)
+	if err != nil {
+		slog.Error("Error getting scene sequence", "err", err)
+		return SceneSequence{}
+	}
+
+	var resultPayload protocol.CommandResultPayload
+	if err := protocol.ParsePayload(response, &resultPayload); err != nil || !resultPayload.Success || resultPayload.Data == nil {
+		return SceneSequence{}
+	}
+	var sequence SceneSequence
+	if err := json.Unmarshal(resultPayload.Data, &sequence); err != nil {
+		slog.Error("Error parsing scene sequence", "err", err)
+		return SceneSequence{}
+	}
+	return sequence
+}
+
+// sceneRunTimeout is how long SceneRun waits for the result.
+// A scene with a sequence sets its devices one by one with delays, which can take much longer than other requests.
+const sceneRunTimeout = 5 * time.Minute
+
+// SceneRun runs a scene on the server
+func (c *WebSocketClient) SceneRun(sceneName string) ([]SceneRunResult, error) {
+	// Send the message
+	response, err := c.sendRequestWithTimeout(protocol.MessageTypeRunScene, protocol.RunScenePayload{Scene: sceneName}, sceneRunTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("error running scene: %v", err)
+	}
+
+	// Parse the response
+	var resultPayload protocol.CommandResultPayload
+	if err := protocol.ParsePayload(response, &resultPayload); err != nil {
+		return nil, fmt.Errorf("error parsing response: %v", err)
+	}
+
+	if !resultPayload.Success {
+		if resultPayload.Error != nil {
+			return nil, fmt.Errorf("error running scene: %s: %s", resultPayload.Error.Code, resultPayload.Error.Message)
+		}
+		return nil, fmt.Errorf("error running scene: unknown error")
+	}
+
+	var runResponse protocol.RunSceneResponse
+	if err := json.Unmarshal(resultPayload.Data, &runResponse); err != nil {
+		return nil, fmt.Errorf("error parsing scene result: %v", err)
+	}
+
+	results := make([]SceneRunResult, 0, len(runResponse.Results))
+	for _, r := range runResponse.Results {
+		result := SceneRunResult{ID: r.Device}
+		if device := c.FindDeviceByIDString(r.Device); device != nil {
+			result.Device = *device
+		}
+		if !r.Success {
+			result.Err = fmt.Errorf("%s", r.Error)
+		} else {
+			actions, err := protocol.SceneActionsFromProtocol([]protocol.SceneDeviceProperties{{Device: r.Device, Properties: r.Properties}})
+			if err != nil {
+				return nil, fmt.Errorf("error parsing scene result: %v", err)
+			}
+			result.Result.Device = result.Device
+			for _, action := range actions {
+				result.Result.Properties = append(result.Result.Properties, Property{EPC: action.EPC, EDT: action.EDT})
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// CleanupDevices sends a cleanup_devices message to the server
+func (c *WebSocketClient) CleanupDevices(unseenDays int, dryRun bool) ([]StaleDevice, error) {
+	response, err := c.sendRequest(protocol.MessageTypeCleanupDevices, protocol.CleanupDevicesPayload{UnseenDays: unseenDays, DryRun: dryRun})
+	if err != nil {
+		return nil, fmt.Errorf("error cleaning up devices: %v", err)
+	}
+
+	var resultPayload protocol.CommandResultPayload
+	if err := protocol.ParsePayload(response, &resultPayload); err != nil {
+		return nil, fmt.Errorf("error parsing response: %v", err)
+	}
+	if !resultPayload.Success {
+		if resultPayload.Error != nil {
+			return nil, fmt.Errorf("error cleaning up devices: %s: %s", resultPayload.Error.Code, resultPayload.Error.Message)
+		}
+		return nil, fmt.Errorf("error cleaning up devices: unknown error")
+	}
+
+	var cleanupResponse protocol.CleanupDevicesResponse
+	if err := json.Unmarshal(resultPayload.Data, &cleanupResponse); err != nil {
+		return nil, fmt.Errorf("error parsing cleanup result: %v", err)
+	}
+	return protocol.StaleDevicesFromProtocol(cleanupResponse.Devices)
+}
+
+// RefreshPropertyMap sends a refresh_property_map message to the server
+func (c *WebSocketClient) RefreshPropertyMap(device IPAndEOJ) (PropertyMapChange, error) {
+	response, err := c.sendRequest(protocol.MessageTypeRefreshPropertyMap, protocol.RefreshPropertyMapPayload{Target: device.Specifier()})
+	if err != nil {
+		return PropertyMapChange{}, fmt.Errorf("error refreshing property map: %v", err)
+	}
+
+	var resultPayload protocol.CommandResultPayload
+	if err := protocol.ParsePayload(response, &resultPayload); err != nil {
+		return PropertyMapChange{}, fmt.Errorf("error parsing response: %v", err)
+	}
+	if !resultPayload.Success {
+		if resultPayload.Error != nil {
+			return PropertyMapChange{}, fmt.Errorf("error refreshing property map: %s: %s", resultPayload.Error.Code, resultPayload.Error.Message)
+		}
+		return PropertyMapChange{}, fmt.Errorf("error refreshing property map: unknown error")
+	}
+
+	var refreshResponse protocol.RefreshPropertyMapResponse
+	if err := json.Unmarshal(resultPayload.Data, &refreshResponse); err != nil {
+		return PropertyMapChange{}, fmt.Errorf("error parsing refresh result: %v", err)
+	}
+	var change PropertyMapChange
+	for _, s := range refreshResponse.Added {
+		epc, err := handler.ParseEPCString(s)
+		if err != nil {
+			return PropertyMapChange{}, fmt.Errorf("invalid EPC in response: %v", err)
+		}
+		change.Added = append(change.Added, epc)
+	}
+	for _, s := range refreshResponse.Removed {
+		epc, err := handler.ParseEPCString(s)
+		if err != nil {
+			return PropertyMapChange{}, fmt.Errorf("invalid EPC in response: %v", err)
+		}
+		change.Removed = append(change.Removed, epc)
+	}
+	return change, nil
+}
+
+// ScheduleList returns the schedules registered on the server
+func (c *WebSocketClient) ScheduleList() []Schedule {
+	response, err := c.sendRequest(protocol.MessageTypeManageSchedule, protocol.ManageSchedulePayload{
+		Action: protocol.ScheduleActionList,
+	})
+	if err != nil {
+		slog.Error("Error listing schedules", "err", err)
+		return []Schedule{}
+	}
+
+	var resultPayload protocol.CommandResultPayload
+	if err := protocol.ParsePayload(response, &resultPayload); err != nil || !resultPayload.Success || resultPayload.Data == nil {
+		return []Schedule{}
+	}
+
+	var schedules []protocol.ScheduleData
+	if err := json.Unmarshal(resultPayload.Data, &schedules); err != nil {
+		slog.Error("Error parsing schedule data", "err", err)
+		return []Schedule{}
+	}
+
+	result := make([]Schedule, 0, len(schedules))
+	nextRuns := make(map[string]time.Time, len(schedules))
+	for _, data := range schedules {
+		schedule, err := protocol.ScheduleFromProtocol(data)
+		if err != nil {
+			slog.Error("Error parsing schedule", "name", data.Name, "err", err)
+			continue
+		}
+		result = append(result, schedule)
+		if data.NextRun != nil {
+			nextRuns[data.Name] = *data.NextRun
+		}
+	}
+	c.scheduleNextRunsMutex.Lock()
+	c.scheduleNextRuns = nextRuns
+	c.scheduleNextRunsMutex.Unlock()
+	return result
+}
+
+// ScheduleSet adds or replaces a schedule
+func (c *WebSocketClient) ScheduleSet(schedule Schedule) error {
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+
+	data := protocol.ScheduleToProtocol(schedule, c.sceneClassCode, time.Time{})
+	return c.sendManageSchedule(protocol.ManageSchedulePayload{
+		Action:   protocol.ScheduleActionSet,
+		Schedule: &data,
+	}, "setting schedule")
+}
+
+// ScheduleNextRun returns the next run time of a schedule after the given time.
+// The time computed by the server in the last ScheduleList is used while it is still ahead,
+// since sunrise and sunset schedules depend on the location configured on the server.
+func (c *WebSocketClient) ScheduleNextRun(schedule Schedule, after time.Time) time.Time {
+	c.scheduleNextRunsMutex.RLock()
+	next, ok := c.scheduleNextRuns[schedule.Name]
+	c.scheduleNextRunsMutex.RUnlock()
+	if ok && !schedule.Disabled && next.After(after) {
+		return next
+	}
+	return schedule.NextRun(after, nil)
+}
+
+// ScheduleDelete deletes a schedule
+func (c *WebSocketClient) ScheduleDelete(name string) error {
+	return c.sendManageSchedule(protocol.ManageSchedulePayload{
+		Action: protocol.ScheduleActionDelete,
+		Name:   name,
+	}, "deleting schedule")
+}
+
+// ScheduleSetDisabled enables or disables a schedule
+func (c *WebSocketClient) ScheduleSetDisabled(name string, disabled bool) error {
+	action := protocol.ScheduleActionEnable
+	if disabled {
+		action = protocol.ScheduleActionDisable
+	}
+	return c.sendManageSchedule(protocol.ManageSchedulePayload{
+		Action: action,
+		Name:   name,
+	}, "updating schedule")
+}
+
+// sendManageSchedule sends a manage_schedule request that returns no data
+func (c *WebSocketClient) sendManageSchedule(payload protocol.ManageSchedulePayload, operation string) error {
+	// Send the message
+	response, err := c.sendRequest(protocol.MessageTypeManageSchedule, payload)
+	if err != nil {
+		return fmt.Errorf("error %s: %v", operation, err)
+	}
+
+	// Parse the response
+	var resultPayload protocol.CommandResultPayload
+	if err := protocol.ParsePayload(response, &resultPayload); err != nil {
+		return fmt.Errorf("error parsing response: %v", err)
+	}
+
+	if !resultPayload.Success {
+		if resultPayload.Error != nil {
+			return fmt.Errorf("error %s: %s: %s", operation, resultPayload.Error.Code, resultPayload.Error.Message)
+		}
+		return fmt.Errorf("error %s: unknown error", operation)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"echonet-list/protocol"
+)
+
+// DebugPendingRequests returns the Get/Set requests the server is waiting for a response to
+func (c *WebSocketClient) DebugPendingRequests() ([]PendingRequest, error) {
+	data, err := c.sendDebugPendingRequests(protocol.DebugPendingRequestsPayload{Action: "list"})
+	if err != nil {
+		return nil, err
+	}
+	var response protocol.PendingRequestsResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("error parsing pending requests: %v", err)
+	}
+	requests := make([]PendingRequest, 0, len(response.Requests))
+	for _, req := range response.Requests {
+		converted, err := protocol.PendingRequestFromProtocol(req)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pending request in response: %v", err)
+		}
+		requests = append(requests, converted)
+	}
+	return requests, nil
+}
+
+// DebugCancelRequest cancels a request that is waiting for the device's response
+func (c *WebSocketClient) DebugCancelRequest(id uint64) error {
+	_, err := c.sendDebugPendingRequests(protocol.DebugPendingRequestsPayload{Action: "cancel", ID: id})
+	return err
+}
+
+func (c *WebSocketClient) sendDebugPendingRequests(payload protocol.DebugPendingRequestsPayload) (json.RawMessage, error) {
+	response, err := c.sendRequest(protocol.MessageTypeDebugPendingRequests, payload)
+	if err != nil {
+		return nil, err
+	}
+	var resultPayload protocol.CommandResultPayload
+	if err := protocol.ParsePayload(response, &resultPayload); err != nil {
+		return nil, fmt.Errorf("error parsing response: %v", err)
+	}
+	if !resultPayload.Success {
+		if resultPayload.Error != nil {
+			return nil, fmt.Errorf("%s: %s", resultPayload.Error.Code, resultPayload.Error.Message)
+		}
+		return nil, fmt.Errorf("debug_pending_requests failed: unknown error")
+	}
+	return resultPayload.Data, nil
+}
@@ -23,6 +23,15 @@ func (c *WebSocketClient) GetDeviceHistory(device IPAndEOJ, opts DeviceHistoryOp
 		payload.SettableOnly = opts.SettableOnly
 	}
 
+	if !opts.Since.IsZero() {
+		since := opts.Since
+		payload.Since = &since
+	}
+
+	if opts.EPC != 0 {
+		payload.EPC = fmt.Sprintf("%02X", byte(opts.EPC))
+	}
+
 	response, err := c.sendRequest(protocol.MessageTypeGetDeviceHistory, payload)
 	if err != nil {
 		return nil, err
@@ -105,6 +105,8 @@ func TestGetDeviceHistorySuccess(t *testing.T) {
 	opts := DeviceHistoryOptions{
 		Limit:        limit,
 		SettableOnly: &settableOnly,
+		Since:        testTime.Add(-time.Hour),
+		EPC:          0x80,
 	}
 
 	entries, err := client.GetDeviceHistory(device, opts)
@@ -121,6 +123,12 @@ func TestGetDeviceHistorySuccess(t *testing.T) {
 	if capturedPayload.SettableOnly == nil || *capturedPayload.SettableOnly != settableOnly {
 		t.Fatalf("expected settableOnly false, got %v", capturedPayload.SettableOnly)
 	}
+	if capturedPayload.Since == nil || !capturedPayload.Since.Equal(opts.Since) {
+		t.Fatalf("expected since %v, got %v", opts.Since, capturedPayload.Since)
+	}
+	if capturedPayload.EPC != "80" {
+		t.Fatalf("expected EPC 80, got %q", capturedPayload.EPC)
+	}
 
 	if len(entries) != 1 {
 		t.Fatalf("expected 1 entry, got %d", len(entries))
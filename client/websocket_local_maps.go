@@ -0,0 +1,61 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"echonet-list/protocol"
+)
+
+// LocalPropertyMaps returns the property maps of the devices of the server's own node
+func (c *WebSocketClient) LocalPropertyMaps() ([]LocalPropertyMap, error) {
+	data, err := c.sendManageLocalPropertyMaps(protocol.ManageLocalPropertyMapsPayload{Action: "list"})
+	if err != nil {
+		return nil, err
+	}
+	var response protocol.LocalPropertyMapsResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("error parsing property maps: %v", err)
+	}
+	maps := make([]LocalPropertyMap, 0, len(response.Maps))
+	for _, m := range response.Maps {
+		converted, err := protocol.LocalPropertyMapFromProtocol(m)
+		if err != nil {
+			return nil, fmt.Errorf("invalid property map in response: %v", err)
+		}
+		maps = append(maps, converted)
+	}
+	return maps, nil
+}
+
+// SetLocalPropertyMap replaces a property map of a device of the server's own node, or restores the default if epcs is nil
+func (c *WebSocketClient) SetLocalPropertyMap(eoj EOJ, mapType PropertyMapType, epcs []EPCType) error {
+	payload := protocol.ManageLocalPropertyMapsPayload{Action: "reset", EOJ: eoj.Specifier(), Map: mapType.String()}
+	if epcs != nil {
+		payload.Action = "set"
+		payload.EPCs = make([]string, 0, len(epcs))
+		for _, epc := range epcs {
+			payload.EPCs = append(payload.EPCs, epc.String())
+		}
+	}
+	_, err := c.sendManageLocalPropertyMaps(payload)
+	return err
+}
+
+func (c *WebSocketClient) sendManageLocalPropertyMaps(payload protocol.ManageLocalPropertyMapsPayload) (json.RawMessage, error) {
+	response, err := c.sendRequest(protocol.MessageTypeManageLocalPropertyMaps, payload)
+	if err != nil {
+		return nil, err
+	}
+	var resultPayload protocol.CommandResultPayload
+	if err := protocol.ParsePayload(response, &resultPayload); err != nil {
+		return nil, fmt.Errorf("error parsing response: %v", err)
+	}
+	if !resultPayload.Success {
+		if resultPayload.Error != nil {
+			return nil, fmt.Errorf("%s: %s", resultPayload.Error.Code, resultPayload.Error.Message)
+		}
+		return nil, fmt.Errorf("manage_local_property_maps failed: unknown error")
+	}
+	return resultPayload.Data, nil
+}
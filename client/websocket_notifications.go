@@ -25,6 +25,8 @@ func (c *WebSocketClient) handleNotification(msg *protocol.Message) {
 		c.handleGroupChanged(msg)
 	case protocol.MessageTypePropertyChanged:
 		c.handlePropertyChanged(msg)
+	case protocol.MessageTypePropertiesChanged:
+		c.handlePropertiesChanged(msg)
 	case protocol.MessageTypeTimeoutNotification:
 		c.handleTimeoutNotification(msg)
 	case protocol.MessageTypeDeviceOffline:
@@ -50,6 +52,7 @@ func (c *WebSocketClient) handleInitialState(msg *protocol.Message) {
 	c.devicesMutex.Lock()
 	c.lastSeenMutex.Lock()
 
+	previous := c.devices
 	c.devices = make(map[string]WebSocketDeviceAndProperties)
 	c.lastSeenTimes = make(map[string]time.Time)
 
@@ -77,6 +80,12 @@ func (c *WebSocketClient) handleInitialState(msg *protocol.Message) {
 		c.lastSeenTimes[ipAndEOJ.Specifier()] = device.LastSeen
 	}
 
+	var changed map[string]changedProperties
+	resync := c.resyncPending.CompareAndSwap(true, false)
+	if resync {
+		changed = diffDevices(previous, c.devices)
+	}
+
 	c.lastSeenMutex.Unlock()
 	c.devicesMutex.Unlock()
 
@@ -110,6 +119,14 @@ func (c *WebSocketClient) handleInitialState(msg *protocol.Message) {
 		c.locationOrder = append(c.locationOrder, payload.LocationSettings.Order...)
 	}
 	c.locationSettingsMutex.Unlock()
+
+	if c.initialState != nil {
+		c.initialStateOnce.Do(func() { close(c.initialState) })
+	}
+
+	if resync {
+		c.finishResync(changed)
+	}
 }
 
 // handleDeviceAdded handles a device_added message
@@ -298,6 +315,46 @@ func (c *WebSocketClient) handlePropertyChanged(msg *protocol.Message) {
 		}
 	}
 	c.devicesMutex.Unlock()
+
+	c.notifyPropertyWatchers(ipAndEOJ, Properties{{EPC: epc, EDT: edt}})
+}
+
+// handlePropertiesChanged handles a properties_changed message
+func (c *WebSocketClient) handlePropertiesChanged(msg *protocol.Message) {
+	var payload protocol.PropertiesChangedPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		slog.Error("WebSocketClient.handlePropertiesChanged: Error parsing properties_changed payload", "err", err)
+		return
+	}
+
+	ipAndEOJ, properties, err := protocol.DeviceFromProtocol(protocol.Device{
+		IP:         payload.IP,
+		EOJ:        payload.EOJ,
+		Properties: payload.Properties,
+	})
+	if err != nil {
+		slog.Error("WebSocketClient.handlePropertiesChanged: Error converting properties", "err", err)
+		return
+	}
+
+	// Update the properties
+	c.devicesMutex.Lock()
+	key := ipAndEOJ.Specifier()
+	if deviceProps, ok := c.devices[key]; ok {
+		for _, prop := range properties {
+			deviceProps.DeviceAndProperties.Properties = deviceProps.DeviceAndProperties.Properties.UpdateProperty(prop)
+		}
+		c.devices[key] = deviceProps
+		if c.debug {
+			slog.Info("WebSocketClient.handlePropertiesChanged: プロパティ更新",
+				"device", ipAndEOJ.String(),
+				"count", len(properties),
+			)
+		}
+	}
+	c.devicesMutex.Unlock()
+
+	c.notifyPropertyWatchers(ipAndEOJ, properties)
 }
 
 // handleTimeoutNotification handles a timeout_notification message
@@ -267,3 +267,42 @@ func TestGetDevices_ByIPIncludesOfflineDevice(t *testing.T) {
 		t.Errorf("期待されるIP: 192.168.1.100, 実際: %s", devices[0].IP.String())
 	}
 }
+
+func TestHandlePropertiesChanged(t *testing.T) {
+	client := &WebSocketClient{
+		devices: make(map[string]WebSocketDeviceAndProperties),
+	}
+
+	device := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.101"), EOJ: MakeEOJ(0x0130, 0x01)}
+	client.devices[device.Specifier()] = WebSocketDeviceAndProperties{
+		DeviceAndProperties: handler.DeviceAndProperties{
+			Device:     device,
+			Properties: Properties{{EPC: 0x80, EDT: []byte{0x31}}},
+		},
+	}
+
+	payload := protocol.PropertiesChangedPayload{
+		IP:  "192.168.1.101",
+		EOJ: "0130:1",
+		Properties: protocol.PropertyMap{
+			"80": {EDT: "MA=="}, // 0x30
+			"B3": {EDT: "GQ=="}, // 0x19
+		},
+	}
+	payloadBytes, _ := json.Marshal(payload)
+	client.handleNotification(&protocol.Message{
+		Type:    protocol.MessageTypePropertiesChanged,
+		Payload: json.RawMessage(payloadBytes),
+	})
+
+	props := client.devices[device.Specifier()].Properties
+	if len(props) != 2 {
+		t.Fatalf("プロパティ数が不正: %v", props)
+	}
+	if p, ok := props.FindEPC(0x80); !ok || p.EDT[0] != 0x30 {
+		t.Errorf("EPC 0x80 が更新されていない: %v", props)
+	}
+	if p, ok := props.FindEPC(0xB3); !ok || p.EDT[0] != 0x19 {
+		t.Errorf("EPC 0xB3 が追加されていない: %v", props)
+	}
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"echonet-list/protocol"
+)
+
+// PollExclusions returns the class codes and device patterns that the server does not poll periodically
+func (c *WebSocketClient) PollExclusions() ([]string, error) {
+	return c.sendManagePollExclusions(protocol.ManagePollExclusionsPayload{Action: "list"})
+}
+
+// SetPollExcluded stops the server polling a class code or device pattern, or makes it poll the target again
+func (c *WebSocketClient) SetPollExcluded(target string, excluded bool) error {
+	payload := protocol.ManagePollExclusionsPayload{Action: "remove", Target: target}
+	if excluded {
+		payload.Action = "add"
+	}
+	_, err := c.sendManagePollExclusions(payload)
+	return err
+}
+
+func (c *WebSocketClient) sendManagePollExclusions(payload protocol.ManagePollExclusionsPayload) ([]string, error) {
+	response, err := c.sendRequest(protocol.MessageTypeManagePollExclusions, payload)
+	if err != nil {
+		return nil, err
+	}
+	var resultPayload protocol.CommandResultPayload
+	if err := protocol.ParsePayload(response, &resultPayload); err != nil {
+		return nil, fmt.Errorf("error parsing response: %v", err)
+	}
+	if !resultPayload.Success {
+		if resultPayload.Error != nil {
+			return nil, fmt.Errorf("%s: %s", resultPayload.Error.Code, resultPayload.Error.Message)
+		}
+		return nil, fmt.Errorf("manage_poll_exclusions failed: unknown error")
+	}
+	var result protocol.PollExclusionsResponse
+	if err := json.Unmarshal(resultPayload.Data, &result); err != nil {
+		return nil, fmt.Errorf("error parsing poll exclusions: %v", err)
+	}
+	return result.Exclusions, nil
+}
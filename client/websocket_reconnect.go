@@ -0,0 +1,122 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Delays between reconnection attempts after the connection to the server is lost.
+// The delay doubles after each failed attempt up to reconnectMaxDelay.
+const (
+	reconnectMinDelay = 500 * time.Millisecond
+	reconnectMaxDelay = 30 * time.Second
+)
+
+// errConnectionLost is returned by requests that were waiting for a response when the connection was lost
+var errConnectionLost = fmt.Errorf("connection to the server was lost")
+
+// IsConnected reports whether the client is connected to the server.
+// It is false while the client is reconnecting after the connection was lost.
+func (c *WebSocketClient) IsConnected() bool {
+	return c.transport.IsConnected() && !c.disconnected.Load()
+}
+
+// connectionLost returns a channel that is closed when the current connection is lost
+func (c *WebSocketClient) connectionLost() <-chan struct{} {
+	c.reconnectMutex.Lock()
+	defer c.reconnectMutex.Unlock()
+	if c.lost == nil {
+		c.lost = make(chan struct{})
+	}
+	return c.lost
+}
+
+// handleDisconnect marks the connection as lost and fails the requests waiting for a response
+func (c *WebSocketClient) handleDisconnect() {
+	c.disconnected.Store(true)
+	c.reconnectMutex.Lock()
+	if c.lost != nil {
+		close(c.lost)
+	}
+	c.lost = make(chan struct{})
+	c.reconnectMutex.Unlock()
+
+	c.responseChMutex.Lock()
+	for id := range c.responseCh {
+		delete(c.responseCh, id)
+	}
+	c.responseChMutex.Unlock()
+}
+
+// reconnect connects to the server again, waiting with exponential backoff between attempts.
+// It returns false if the client was closed before the connection was restored.
+func (c *WebSocketClient) reconnect() bool {
+	delay, maxDelay := c.minReconnectDelay, c.maxReconnectDelay
+	if delay <= 0 {
+		delay = reconnectMinDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = reconnectMaxDelay
+	}
+	for attempt := 1; ; attempt++ {
+		timer := time.NewTimer(delay)
+		select {
+		case <-c.ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+
+		err := c.transport.Connect()
+		if err == nil {
+			// The server sends initial_state to every new connection; handleInitialState completes the resync
+			c.resyncPending.Store(true)
+			c.disconnected.Store(false)
+			slog.Info("WebSocketClient: reconnected to the server", "attempt", attempt)
+			return true
+		}
+		if c.ctx.Err() != nil {
+			return false
+		}
+		delay = min(delay*2, maxDelay)
+		slog.Warn("WebSocketClient: reconnection failed", "attempt", attempt, "retryIn", delay, "err", err)
+	}
+}
+
+// finishResync notifies the watchers of the property values that changed while disconnected.
+// Nothing else has to be restored on the server: it keeps no subscriptions per connection,
+// sends the notifications to every connection, and Connect dials the same URL, including its token.
+func (c *WebSocketClient) finishResync(changed map[string]changedProperties) {
+	for _, change := range changed {
+		c.notifyPropertyWatchers(change.device, change.properties)
+	}
+}
+
+// changedProperties is the properties of a device whose values differ from the previous state
+type changedProperties struct {
+	device     IPAndEOJ
+	properties Properties
+}
+
+// diffDevices returns the properties in current that are new or have a different value from previous
+func diffDevices(previous, current map[string]WebSocketDeviceAndProperties) map[string]changedProperties {
+	changed := make(map[string]changedProperties)
+	for key, device := range current {
+		old, existed := previous[key]
+		var props Properties
+		for _, prop := range device.Properties {
+			if existed {
+				if oldProp, ok := old.Properties.FindEPC(prop.EPC); ok && bytes.Equal(oldProp.EDT, prop.EDT) {
+					continue
+				}
+			}
+			props = append(props, prop)
+		}
+		if len(props) > 0 {
+			changed[key] = changedProperties{device: device.Device, properties: props}
+		}
+	}
+	return changed
+}
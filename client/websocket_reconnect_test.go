@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// reconnectTransport is a transport whose connection can be dropped by the test
+type reconnectTransport struct {
+	mu       sync.Mutex
+	messages chan []byte // messages read by the client; closed when the connection is dropped
+	connects int
+	failures int // number of Connect calls to fail after a drop
+	onWrite  func(data []byte)
+	onAccept func(t *reconnectTransport) // called after each successful Connect
+}
+
+func (r *reconnectTransport) Connect() error {
+	r.mu.Lock()
+	r.connects++
+	if r.connects > 1 && r.failures > 0 {
+		r.failures--
+		r.mu.Unlock()
+		return errors.New("connection refused")
+	}
+	r.messages = make(chan []byte, 10)
+	onAccept := r.onAccept
+	r.mu.Unlock()
+	if onAccept != nil {
+		onAccept(r)
+	}
+	return nil
+}
+
+func (r *reconnectTransport) Close() error { return nil }
+
+func (r *reconnectTransport) ReadMessage() (int, []byte, error) {
+	r.mu.Lock()
+	ch := r.messages
+	r.mu.Unlock()
+	data, ok := <-ch
+	if !ok {
+		return 0, nil, errors.New("connection reset")
+	}
+	return 1, data, nil
+}
+
+func (r *reconnectTransport) WriteMessage(messageType int, data []byte) error {
+	if r.onWrite != nil {
+		r.onWrite(data)
+	}
+	return nil
+}
+
+func (r *reconnectTransport) IsConnected() bool { return true }
+
+func (r *reconnectTransport) send(t *testing.T, msgType protocol.MessageType, payload interface{}) {
+	t.Helper()
+	data, err := protocol.CreateMessage(msgType, payload, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.mu.Lock()
+	r.messages <- data
+	r.mu.Unlock()
+}
+
+func (r *reconnectTransport) drop() {
+	r.mu.Lock()
+	close(r.messages)
+	r.mu.Unlock()
+}
+
+func initialStateWith(device IPAndEOJ, edt string) protocol.InitialStatePayload {
+	return protocol.InitialStatePayload{
+		Devices: map[string]protocol.Device{
+			device.Specifier(): {
+				IP:         device.IP.String(),
+				EOJ:        device.EOJ.Specifier(),
+				Properties: map[string]protocol.PropertyData{"80": {EDT: edt}},
+			},
+		},
+		Aliases: map[string]handler.IDString{},
+		Groups:  map[string][]handler.IDString{},
+	}
+}
+
+func TestWebSocketClient_ReconnectAndResync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	device := IPAndEOJ{IP: net.ParseIP("192.168.1.101"), EOJ: MakeEOJ(0x0130, 0x01)}
+	transport := &reconnectTransport{failures: 2}
+	// 0x30 before the drop; 0x31 and a new alias and group after the reconnection
+	before := initialStateWith(device, "MA==")
+	after := initialStateWith(device, "MQ==")
+	after.Aliases["light"] = "id-light"
+	after.Groups["@living"] = []handler.IDString{"id-light"}
+	states := []protocol.InitialStatePayload{before, after}
+	transport.onAccept = func(r *reconnectTransport) {
+		// the server sends initial_state to every new connection
+		r.send(t, protocol.MessageTypeInitialState, states[0])
+		states = states[1:]
+	}
+
+	client := &WebSocketClient{
+		ctx:               ctx,
+		cancel:            cancel,
+		transport:         transport,
+		devices:           make(map[string]WebSocketDeviceAndProperties),
+		lastSeenTimes:     make(map[string]time.Time),
+		responseCh:        make(map[string]chan *protocol.Message),
+		propertyWatchers:  make(map[chan PropertyChangeNotification]struct{}),
+		initialState:      make(chan struct{}),
+		minReconnectDelay: time.Millisecond,
+		maxReconnectDelay: 4 * time.Millisecond,
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.WaitInitialState(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	watch, stop := client.WatchPropertyChanges()
+	defer stop()
+
+	// A request waiting for a response fails as soon as the connection is lost
+	written := make(chan struct{})
+	transport.onWrite = func([]byte) { close(written) }
+	result := make(chan error, 1)
+	go func() {
+		_, err := client.sendRequest(protocol.MessageTypeGetProperties, nil)
+		result <- err
+	}()
+	<-written
+	transport.drop()
+	select {
+	case err := <-result:
+		if !errors.Is(err, errConnectionLost) {
+			t.Errorf("request error = %v, want %v", err, errConnectionLost)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request was not failed when the connection was lost")
+	}
+
+	// The property changed while disconnected is taken from the new initial_state and notified to the watchers
+	select {
+	case n := <-watch:
+		if n.Property.EPC != 0x80 || len(n.Property.EDT) != 1 || n.Property.EDT[0] != 0x31 {
+			t.Errorf("unexpected notification: %+v", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("changed property was not notified after the resync")
+	}
+	if !client.IsConnected() {
+		t.Error("IsConnected() = false after reconnecting")
+	}
+	transport.mu.Lock()
+	if transport.connects != 4 {
+		t.Errorf("Connect called %d times, want 4 (initial, 2 failures, success)", transport.connects)
+	}
+	transport.mu.Unlock()
+
+	// The aliases and groups are replaced with those of the new initial_state
+	if aliases := client.AliasList(); len(aliases) != 1 || aliases[0].Alias != "light" || aliases[0].ID != "id-light" {
+		t.Errorf("aliases were not resynced: %+v", aliases)
+	}
+	if groups := client.GroupList(nil); len(groups) != 1 || groups[0].Group != "@living" {
+		t.Errorf("groups were not resynced: %+v", groups)
+	}
+	client.devicesMutex.RLock()
+	props := client.devices[device.Specifier()].Properties
+	client.devicesMutex.RUnlock()
+	if p, ok := props.FindEPC(0x80); !ok || p.EDT[0] != 0x31 {
+		t.Errorf("devices were not resynced: %v", props)
+	}
+}
+
+func TestDiffDevices(t *testing.T) {
+	device := IPAndEOJ{IP: net.ParseIP("192.168.1.101"), EOJ: MakeEOJ(0x0130, 0x01)}
+	entry := func(props Properties) map[string]WebSocketDeviceAndProperties {
+		return map[string]WebSocketDeviceAndProperties{
+			device.Specifier(): {DeviceAndProperties: handler.DeviceAndProperties{Device: device, Properties: props}},
+		}
+	}
+	previous := entry(Properties{{EPC: 0x80, EDT: []byte{0x30}}, {EPC: 0xB0, EDT: []byte{0x41}}})
+	current := entry(Properties{{EPC: 0x80, EDT: []byte{0x31}}, {EPC: 0xB0, EDT: []byte{0x41}}, {EPC: 0xB3, EDT: []byte{0x19}}})
+
+	changed := diffDevices(previous, current)
+	props := changed[device.Specifier()].properties
+	if len(changed) != 1 || len(props) != 2 || props[0].EPC != 0x80 || props[1].EPC != 0xB3 {
+		t.Errorf("unexpected changes: %+v", changed)
+	}
+	if changed := diffDevices(current, current); len(changed) != 0 {
+		t.Errorf("expected no changes, got %+v", changed)
+	}
+}
+
+func TestSendRequest_FailsWhileDisconnected(t *testing.T) {
+	client := &WebSocketClient{
+		ctx:        context.Background(),
+		transport:  &reconnectTransport{},
+		responseCh: make(map[string]chan *protocol.Message),
+	}
+	client.disconnected.Store(true)
+	if _, err := client.sendRequest(protocol.MessageTypeGetProperties, nil); !errors.Is(err, errConnectionLost) {
+		t.Errorf("error = %v, want %v", err, errConnectionLost)
+	}
+}
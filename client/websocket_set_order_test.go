@@ -0,0 +1,14 @@
+package client
+
+import "testing"
+
+func TestSortByAppliedOrder(t *testing.T) {
+	props := Properties{{EPC: 0x80}, {EPC: 0xB0}, {EPC: 0xB3}, {EPC: 0xA0}}
+	got := sortByAppliedOrder(props, []string{"B0", "80", "B3"})
+	want := []EPCType{0xB0, 0x80, 0xB3, 0xA0}
+	for i, prop := range got {
+		if prop.EPC != want[i] {
+			t.Fatalf("order = %v, want %v", got, want)
+		}
+	}
+}
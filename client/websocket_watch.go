@@ -0,0 +1,53 @@
+package client
+
+import "sync"
+
+// WatchPropertyChanges returns a channel that receives the property changes notified by the server
+func (c *WebSocketClient) WatchPropertyChanges() (<-chan PropertyChangeNotification, func()) {
+	ch := make(chan PropertyChangeNotification, propertyWatchBufferSize)
+	c.propertyWatchersMutex.Lock()
+	if c.propertyWatchers == nil {
+		// already closed
+		c.propertyWatchersMutex.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	c.propertyWatchers[ch] = struct{}{}
+	c.propertyWatchersMutex.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			c.propertyWatchersMutex.Lock()
+			defer c.propertyWatchersMutex.Unlock()
+			if _, ok := c.propertyWatchers[ch]; ok {
+				delete(c.propertyWatchers, ch)
+				close(ch)
+			}
+		})
+	}
+}
+
+// notifyPropertyWatchers sends property changes to the watchers, dropping them for watchers that do not keep up
+func (c *WebSocketClient) notifyPropertyWatchers(device IPAndEOJ, properties Properties) {
+	c.propertyWatchersMutex.Lock()
+	defer c.propertyWatchersMutex.Unlock()
+	for watcher := range c.propertyWatchers {
+		for _, prop := range properties {
+			select {
+			case watcher <- PropertyChangeNotification{Device: device, Property: prop}:
+			default:
+			}
+		}
+	}
+}
+
+// closePropertyWatchers closes the channels of all watchers
+func (c *WebSocketClient) closePropertyWatchers() {
+	c.propertyWatchersMutex.Lock()
+	defer c.propertyWatchersMutex.Unlock()
+	for watcher := range c.propertyWatchers {
+		close(watcher)
+	}
+	c.propertyWatchers = nil
+}
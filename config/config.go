@@ -1,9 +1,17 @@
 package config
 
 import (
+	"encoding/base64"
 	"flag"
+	"fmt"
+	"log/slog"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 )
@@ -54,30 +62,155 @@ func getDefaultDaemonLogFile() string {
 	}
 }
 
+// RateLimitConfig は1つのメッセージタイプのリクエスト数の上限を表す
+type RateLimitConfig struct {
+	Rate  float64 `toml:"rate"`  // 1秒あたりのリクエスト数（0 で無制限）
+	Burst int     `toml:"burst"` // 連続して受け付けるリクエスト数
+}
+
+// AccessTokenConfig は1つのアクセストークンと、そのトークンで扱えるデバイスを表す
+// デバイスは "*"、"@グループ名"、エイリアス、IDString、"IP EOJ" または IP アドレスで指定する
+type AccessTokenConfig struct {
+	Name    string   `toml:"name"`    // ログに表示するトークンの名前
+	Token   string   `toml:"token"`   // クライアントが送るトークン
+	Read    []string `toml:"read"`    // 状態を読めるデバイス
+	Control []string `toml:"control"` // プロパティを設定できるデバイス（読み取りも許可される）
+	Admin   bool     `toml:"admin"`   // エイリアス・グループ・シーン・スケジュールの編集や検出を含む全操作を許可する
+}
+
+// AlertRuleConfig は数値プロパティが閾値を超えたときに上げるアラートを表す
+type AlertRuleConfig struct {
+	Name       string  `toml:"name"`       // アラートの名前
+	Device     string  `toml:"device"`     // "*"、"@グループ名"、エイリアス、IDString、"IP EOJ" または IP アドレス
+	EPC        string  `toml:"epc"`        // 対象のプロパティ（例: "E7"）
+	Comparator string  `toml:"comparator"` // ">"、">="、"<" または "<="
+	Threshold  float64 `toml:"threshold"`  // 閾値（プロパティの数値の単位、例: W や ℃）
+	Hysteresis float64 `toml:"hysteresis"` // 解除までに閾値から戻る幅
+	Duration   string  `toml:"duration"`   // 条件がこの時間続いたらアラートを上げる（例: "5m"）
+	Webhook    string  `toml:"webhook"`    // アラートを POST する URL（省略時は [alerts] の webhook）
+}
+
+// RetryPolicyConfig は応答のない要求を再送する回数と間隔を表す
+type RetryPolicyConfig struct {
+	MaxRetries int    `toml:"max_retries"` // 最大再送回数（0 で上位の設定を使う）
+	Interval   string `toml:"interval"`    // 最初の再送までの間隔（例: "3s"）。以降は倍になっていく（空で上位の設定を使う）
+}
+
 // Config はアプリケーション全体の設定を表す
 type Config struct {
-	Debug bool `toml:"debug"`
-	Log   struct {
+	Debug    bool   `toml:"debug"`
+	Timezone string `toml:"timezone"` // IANA time zone name (e.g. "Asia/Tokyo"); empty uses the system time zone
+	Locale   string `toml:"locale"`   // Language of property and class names in the console and logs ("en" or "ja"); empty is English
+	// Directory of the data and log files given as relative paths; empty is $XDG_DATA_HOME/echonet-list, or /var/lib/echonet-list in daemon mode
+	DataDir string `toml:"data_dir"`
+	// How long the server waits for in-flight ECHONET Lite requests on shutdown (e.g. "10s"; "0" does not wait)
+	ShutdownTimeout string `toml:"shutdown_timeout"`
+
+	Log struct {
 		Filename string `toml:"filename"`
+		Level    string `toml:"level"` // "debug", "info", "warn" or "error"; empty is "debug" when debug is enabled, otherwise "info"
 	} `toml:"log"`
 	History struct {
-		PerDeviceSettableLimit    int `toml:"per_device_settable_limit"`     // Limit for settable properties
-		PerDeviceNonSettableLimit int `toml:"per_device_non_settable_limit"` // Limit for non-settable properties
+		Enabled                   bool `toml:"enabled"`                       // false disables the history subsystem entirely
+		PerDeviceSettableLimit    int  `toml:"per_device_settable_limit"`     // Limit for settable properties
+		PerDeviceNonSettableLimit int  `toml:"per_device_non_settable_limit"` // Limit for non-settable properties
+
+		Backend     string `toml:"backend"`      // "memory" (default) or "journal"
+		JournalFile string `toml:"journal_file"` // Journal file used by the "journal" backend
+		Retention   string `toml:"retention"`    // How long the journal keeps entries, e.g. "720h" ("0" keeps forever)
 	} `toml:"history"`
+	// Soft memory caps for in-memory stores
+	Memory struct {
+		HistorySoftLimitMB  int    `toml:"history_soft_limit_mb"` // 0 = no limit; oldest notifications are evicted first
+		DevicesSoftLimitMB  int    `toml:"devices_soft_limit_mb"` // 0 = no limit; a warning is logged when exceeded
+		EvictOfflineDevices bool   `toml:"evict_offline_devices"` // Remove least recently updated offline devices when over the devices limit
+		CheckInterval       string `toml:"check_interval"`        // e.g., "1m"
+	} `toml:"memory"`
+	// Checksum verification of persisted files with automatic restore from backups
+	Integrity struct {
+		Enabled       bool   `toml:"enabled"`        // Record checksums and keep backups of devices, aliases, groups and history files
+		CheckInterval string `toml:"check_interval"` // e.g., "1h"
+		Backups       int    `toml:"backups"`        // Number of backups kept per file
+	} `toml:"integrity"`
+	// Batched saving of the devices file and automatic removal of devices that are no longer seen
+	Persistence struct {
+		SaveInterval    string `toml:"save_interval"`     // e.g., "1m"; changes are saved together at this interval ("0" saves every change)
+		PruneUnseenDays int    `toml:"prune_unseen_days"` // Remove devices not updated for this many days (0 keeps them)
+	} `toml:"persistence"`
+	// Active liveness checks of nodes that have been quiet
+	Liveness struct {
+		Enabled     bool   `toml:"enabled"`      // Ping the NodeProfile of quiet nodes and mark them offline when they do not answer
+		Interval    string `toml:"interval"`     // e.g., "5m"; a node is pinged after this long without any response
+		MaxInterval string `toml:"max_interval"` // e.g., "1h"; upper limit of the doubling interval for nodes that do not answer
+	} `toml:"liveness"`
+	// AES-GCM encryption of the devices and history files
+	Encryption struct {
+		Enabled    bool     `toml:"enabled"`
+		Key        string   `toml:"key"`         // Base64 encoded 32-byte key
+		KeyFile    string   `toml:"key_file"`    // File containing the base64 encoded key
+		KeyCommand []string `toml:"key_command"` // Command that prints the base64 encoded key (e.g. a keyring lookup)
+	} `toml:"encryption"`
 	WebSocket struct {
 		Enabled                bool   `toml:"enabled"`
 		PeriodicUpdateInterval string `toml:"periodic_update_interval"` // e.g., "1m", "30s", "0" to disable
 		ForcedUpdateInterval   string `toml:"forced_update_interval"`   // e.g., "30m", "1h", "0" to disable force updates
+		ForcedUpdateSchedule   string `toml:"forced_update_schedule"`   // cron spec of forced updates, e.g., "30 3 * * *"; overrides forced_update_interval
+		PropertyChangeWindow   string `toml:"property_change_window"`   // e.g., "50ms", "0" to send every change separately
+		SendQueueSize          int    `toml:"send_queue_size"`          // Messages buffered per client before the slow client policy applies
+		SlowClientPolicy       string `toml:"slow_client_policy"`       // "disconnect" or "drop_oldest"
+		// Per-client request limits keyed by message type (e.g. "set_properties"); entries override the defaults
+		RateLimit map[string]RateLimitConfig `toml:"rate_limit"`
+		// Minimum change of a numeric property that is broadcast and recorded, keyed by EPC ("BB") or class code and EPC ("0011:E0")
+		ChangeThresholds map[string]float64 `toml:"change_thresholds"`
+		// Periodic update intervals keyed by class code ("0288") or device pattern (alias, "@group", "IP EOJ"); override periodic_update_interval
+		PollIntervals map[string]string `toml:"poll_intervals"`
+		// Device classes ("0279") and device patterns that are never polled; their INF notifications are still accepted
+		PollExclude []string `toml:"poll_exclude"`
 	} `toml:"websocket"`
+	// Per-token device access control for WebSocket and REST clients
+	Access struct {
+		Enabled bool                `toml:"enabled"` // false lets every client read and control every device
+		Tokens  []AccessTokenConfig `toml:"tokens"`
+	} `toml:"access"`
+	// Threshold alerts on numeric properties, broadcast as alert_raised/alert_cleared and posted to webhooks
+	Alerts struct {
+		Webhook string            `toml:"webhook"` // Default webhook URL of the rules without their own
+		Rules   []AlertRuleConfig `toml:"rules"`
+	} `toml:"alerts"`
+	// Export of numeric property values in InfluxDB line protocol, e.g. for Grafana dashboards
+	InfluxDB struct {
+		Enabled     bool   `toml:"enabled"`
+		URL         string `toml:"url"`         // e.g., "http://localhost:8086"; written with /api/v2/write
+		Org         string `toml:"org"`         // Organization (ignored by InfluxDB 1.x)
+		Bucket      string `toml:"bucket"`      // Bucket, or "database/retention-policy" for InfluxDB 1.x
+		Token       string `toml:"token"`       // API token, or "user:password" for InfluxDB 1.x
+		UDPAddr     string `toml:"udp_addr"`    // e.g., "localhost:8089"; sends line protocol over UDP
+		Measurement string `toml:"measurement"` // Measurement name
+		Interval    string `toml:"interval"`    // e.g., "1m"; also writes every numeric property at this interval, "0" for changes only
+	} `toml:"influxdb"`
 	TLS struct {
 		Enabled  bool   `toml:"enabled"`
 		CertFile string `toml:"cert_file"`
 		KeyFile  string `toml:"key_file"`
+		// Certificates obtained and renewed automatically with ACME (Let's Encrypt); used instead of cert_file/key_file when domains is set
+		ACME struct {
+			Domains           []string `toml:"domains"`             // Domain names to obtain a certificate for
+			Email             string   `toml:"email"`               // Contact address for expiry notices (optional)
+			CacheDir          string   `toml:"cache_dir"`           // Where certificates and the account key are stored
+			HTTPChallengeAddr string   `toml:"http_challenge_addr"` // Address answering HTTP-01 challenges; empty uses TLS-ALPN-01 only
+			DirectoryURL      string   `toml:"directory_url"`       // ACME directory; empty is Let's Encrypt production
+		} `toml:"acme"`
 	} `toml:"tls"`
 	WebSocketClient struct {
-		Enabled bool   `toml:"enabled"`
-		Addr    string `toml:"addr"`
+		Enabled  bool   `toml:"enabled"`
+		Addr     string `toml:"addr"`
+		Discover bool   `toml:"discover"` // Find the server with mDNS instead of connecting to addr
 	} `toml:"websocket_client"`
+	// mDNS (Bonjour) advertisement of the WebSocket server as _echonet-list._tcp, found by clients with -discover
+	MDNS struct {
+		Enabled  bool   `toml:"enabled"`
+		Instance string `toml:"instance"` // Service instance name shown to clients; empty uses the host name
+	} `toml:"mdns"`
 
 	// Daemon mode settings
 	Daemon struct {
@@ -89,20 +222,90 @@ type Config struct {
 		Host    string `toml:"host"`
 		Port    int    `toml:"port"`
 		WebRoot string `toml:"web_root"`
+		ELWA    bool   `toml:"elwa"` // ECHONET Lite Web API (/elapi/v1/) を提供する
 	} `toml:"http_server"`
 
 	// Network monitoring settings
 	Network struct {
-		MonitorEnabled bool `toml:"monitor_enabled"`
+		MonitorEnabled  bool     `toml:"monitor_enabled"`
+		Interfaces      []string `toml:"interfaces"`        // 検出に使うインターフェース名（複数指定時は並列に検出）
+		ReplyAddress    string   `toml:"reply_address"`     // 送信元として使うIPv4アドレス（NAT/ブリッジ環境向け）
+		IPVersion       string   `toml:"ip_version"`        // 通信に使うIPのバージョン（"ipv4", "ipv6", "dual"）
+		PreferIPVersion string   `toml:"prefer_ip_version"` // デュアルスタックで同じノードが両方から応答した場合に採用するバージョン（"ipv4", "ipv6"）
+		// マルチキャストグループへの参加とマルチキャスト送信に使うインターフェース名（空の場合はOSが選択）
+		MulticastInterfaces []string `toml:"multicast_interfaces"`
+		// interfaces での検出に、マルチキャストの代わりに各インターフェースのブロードキャストアドレスを使う
+		DiscoveryBroadcast bool `toml:"discovery_broadcast"`
+		// 要求の送信の制限（0の場合は制限なし）
+		MaxPacketsPerSecond float64 `toml:"max_packets_per_second"` // 1秒あたりに送信する要求パケットの上限（再送を含む）
+		PacketBurst         int     `toml:"packet_burst"`           // 間隔を空けずに続けて送信できるパケット数
+		MaxInFlight         int     `toml:"max_in_flight"`          // 同時に応答を待つ要求の上限
+		// 1つの要求フレームの上限（0の場合は制限なし）。超える Get/Set は複数のフレームに分けて送信する
+		MaxPropertiesPerFrame int `toml:"max_properties_per_frame"` // 1フレームに含めるプロパティ数の上限（OPCの上限は255）
+		MaxFrameSize          int `toml:"max_frame_size"`           // 1フレームの大きさの上限（バイト数）
+		// 仕様に厳密に従うモード（認証試験向け）。false の場合は仕様に従わない機器とも通信できるよう寛容に扱う
+		Strict bool `toml:"strict"`
 	} `toml:"network"`
 
+	// Retransmission of requests that get no response
+	Retry struct {
+		MaxRetries int    `toml:"max_retries"`
+		Interval   string `toml:"interval"`
+		// Overrides keyed by class code ("03B7") or IP address ("192.168.1.20"); an IP address takes priority over a class
+		Overrides map[string]RetryPolicyConfig `toml:"overrides"`
+	} `toml:"retry"`
+
+	// Demo mode: serve simulated devices instead of talking to the ECHONET Lite network
+	Demo struct {
+		Enabled bool `toml:"enabled"` // Simulated devices and generated history; no data files are read or written
+	} `toml:"demo"`
+
+	// Wi-SUN (Route B) transport for reading a smart electric energy meter through a serial adapter
+	WiSUN struct {
+		Enabled        bool   `toml:"enabled"`          // Use the Wi-SUN adapter instead of UDP
+		Device         string `toml:"device"`           // Serial device of the adapter (e.g. /dev/ttyUSB0)
+		BaudRate       int    `toml:"baud_rate"`        // Baud rate of the serial port
+		RouteBID       string `toml:"route_b_id"`       // Route B authentication ID issued by the power company
+		RouteBPassword string `toml:"route_b_password"` // Route B password issued by the power company
+	} `toml:"wisun"`
+
+	// Location of the site where sunrise and sunset are computed for schedules and the weather device
+	Location struct {
+		Latitude  *float64 `toml:"latitude"`  // Degrees north (negative for south); unset disables sunrise and sunset
+		Longitude *float64 `toml:"longitude"` // Degrees east (negative for west)
+	} `toml:"location"`
+
+	// Current weather from an online provider, served as a virtual device usable in alert rules and dashboards
+	Weather struct {
+		Enabled   bool    `toml:"enabled"`
+		Provider  string  `toml:"provider"`  // "open-meteo" or "jma"
+		Latitude  float64 `toml:"latitude"`  // Location for open-meteo
+		Longitude float64 `toml:"longitude"` // Location for open-meteo
+		Station   string  `toml:"station"`   // AMeDAS station number for jma, e.g. "44132" (Tokyo)
+		Interval  string  `toml:"interval"`  // e.g. "10m"; how often the weather is fetched
+		IP        string  `toml:"ip"`        // Address of the virtual weather node
+	} `toml:"weather"`
+
 	// Data file paths
 	DataFiles struct {
-		DevicesFile string `toml:"devices_file"`
-		AliasesFile string `toml:"aliases_file"`
-		GroupsFile  string `toml:"groups_file"`
-		HistoryFile string `toml:"history_file"`
+		DevicesFile           string `toml:"devices_file"`
+		AliasesFile           string `toml:"aliases_file"`
+		GroupsFile            string `toml:"groups_file"`
+		ScenesFile            string `toml:"scenes_file"`
+		SchedulesFile         string `toml:"schedules_file"`
+		LocationsFile         string `toml:"locations_file"`
+		MetadataFile          string `toml:"metadata_file"`
+		PreferencesFile       string `toml:"preferences_file"`
+		PropertyOverridesFile string `toml:"property_overrides_file"`
+		LocalPropertyMapsFile string `toml:"local_property_maps_file"`
+		StatsFile             string `toml:"stats_file"`
+		HistoryFile           string `toml:"history_file"`
 	} `toml:"data_files"`
+
+	// Named profiles ([profiles.<name>]) selected with -profile; a profile overrides any of the settings above
+	Profiles map[string]toml.Primitive `toml:"profiles"`
+	// Name of the selected profile (empty when no profile is selected)
+	Profile string `toml:"-"`
 }
 
 // NewConfig はデフォルト設定を持つConfigを作成する
@@ -110,11 +313,31 @@ func NewConfig() *Config {
 	cfg := &Config{
 		Debug: false,
 	}
+	cfg.ShutdownTimeout = "10s"
 	cfg.Log.Filename = "echonet-list.log"
+	cfg.History.Enabled = true
 	cfg.History.PerDeviceSettableLimit = 200    // Default for settable properties
 	cfg.History.PerDeviceNonSettableLimit = 100 // Default for non-settable properties
+	cfg.History.Backend = "memory"
+	cfg.History.JournalFile = "history.jsonl"
+	cfg.History.Retention = "720h" // Default to 30 days
+	cfg.Integrity.Enabled = false
+	cfg.Integrity.CheckInterval = "1h"
+	cfg.Integrity.Backups = 3
+	cfg.Persistence.SaveInterval = "0"
+	cfg.Persistence.PruneUnseenDays = 0
+	cfg.Liveness.Enabled = false
+	cfg.Liveness.Interval = "5m"
+	cfg.Liveness.MaxInterval = "1h"
+	cfg.InfluxDB.Measurement = "echonet"
+	cfg.InfluxDB.Interval = "0"
 	cfg.WebSocket.PeriodicUpdateInterval = "1m" // Default to 1 minute
 	cfg.WebSocket.ForcedUpdateInterval = "30m"  // Default to 30 minutes
+	cfg.WebSocket.PropertyChangeWindow = "50ms" // Default to 50 milliseconds
+	cfg.WebSocket.SendQueueSize = 256
+	cfg.WebSocket.SlowClientPolicy = "disconnect"
+	cfg.TLS.ACME.CacheDir = "certs/acme"
+	cfg.TLS.ACME.HTTPChallengeAddr = ":80"
 	cfg.WebSocketClient.Addr = "ws://localhost:8080/ws"
 	// Default daemon settings
 	cfg.Daemon.Enabled = false
@@ -127,11 +350,40 @@ func NewConfig() *Config {
 
 	// Default network monitoring settings
 	cfg.Network.MonitorEnabled = true
+	cfg.Network.IPVersion = "ipv4"
+	cfg.Network.PreferIPVersion = "ipv4"
+	cfg.Network.MaxPacketsPerSecond = 20
+	cfg.Network.PacketBurst = 10
+	cfg.Network.MaxInFlight = 16
+	cfg.Network.MaxFrameSize = 1472
+
+	// Default retry settings
+	cfg.Retry.MaxRetries = 7
+	cfg.Retry.Interval = "3s"
+
+	// Default Wi-SUN settings
+	cfg.WiSUN.Enabled = false
+	cfg.WiSUN.Device = "/dev/ttyUSB0"
+	cfg.WiSUN.BaudRate = 115200
+
+	// Default weather settings
+	cfg.Weather.Enabled = false
+	cfg.Weather.Provider = "open-meteo"
+	cfg.Weather.Interval = "10m"
+	cfg.Weather.IP = "192.0.2.254"
 
 	// Default data file paths (empty means use default locations)
 	cfg.DataFiles.DevicesFile = ""
 	cfg.DataFiles.AliasesFile = ""
 	cfg.DataFiles.GroupsFile = ""
+	cfg.DataFiles.ScenesFile = ""
+	cfg.DataFiles.SchedulesFile = ""
+	cfg.DataFiles.LocationsFile = ""
+	cfg.DataFiles.MetadataFile = ""
+	cfg.DataFiles.PreferencesFile = ""
+	cfg.DataFiles.PropertyOverridesFile = ""
+	cfg.DataFiles.LocalPropertyMapsFile = ""
+	cfg.DataFiles.StatsFile = ""
 	cfg.DataFiles.HistoryFile = "history.json" // Default history file (set to empty string to disable)
 
 	return cfg
@@ -142,7 +394,8 @@ func NewConfig() *Config {
 // 1. 指定されたパスの設定ファイル（指定がある場合）
 // 2. カレントディレクトリのデフォルト設定ファイル（存在する場合）
 // 3. デフォルト設定
-func LoadConfig(configPath string) (*Config, error) {
+// profile を指定すると、設定ファイルの [profiles.<profile>] に書かれた設定で上書きする
+func LoadConfig(configPath string, profile string) (*Config, error) {
 	config := NewConfig()
 
 	// 設定ファイルパスの解決
@@ -151,6 +404,8 @@ func LoadConfig(configPath string) (*Config, error) {
 		// 指定がなければデフォルトファイルを探す
 		if _, err := os.Stat(DefaultConfigFile); err == nil {
 			filePath = DefaultConfigFile
+		} else if profile != "" {
+			return nil, fmt.Errorf("profile %q is specified but no config file is found", profile)
 		} else {
 			// デフォルトファイルもなければ、デフォルト設定をそのまま返す
 			return config, nil
@@ -158,13 +413,241 @@ func LoadConfig(configPath string) (*Config, error) {
 	}
 
 	// 設定ファイルが指定または存在する場合は読み込む
-	if _, err := toml.DecodeFile(filePath, config); err != nil {
+	md, err := toml.DecodeFile(filePath, config)
+	if err != nil {
 		return nil, err
 	}
 
+	if profile != "" {
+		if err := config.applyProfile(md, profile); err != nil {
+			return nil, fmt.Errorf("%s: %w", filePath, err)
+		}
+	}
+
 	return config, nil
 }
 
+// applyProfile は、プロファイルに書かれた設定で現在の設定を上書きする
+// プロファイルに書かれていない項目は、設定ファイルの共通部分の値のまま残る
+func (c *Config) applyProfile(md toml.MetaData, profile string) error {
+	primitive, ok := c.Profiles[profile]
+	if !ok {
+		names := make([]string, 0, len(c.Profiles))
+		for name := range c.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("profile %q is not defined (available: %v)", profile, names)
+	}
+	profiles := c.Profiles
+	if err := md.PrimitiveDecode(primitive, c); err != nil {
+		return fmt.Errorf("profile %q: %w", profile, err)
+	}
+	// プロファイルの中にプロファイルは定義できない
+	c.Profiles = profiles
+	c.Profile = profile
+	return nil
+}
+
+// HistoryRetention は history.retention を time.Duration に変換する
+// 空文字の場合は 0（無期限）を返す
+func (c *Config) HistoryRetention() (time.Duration, error) {
+	if c.History.Retention == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.History.Retention)
+	if err != nil {
+		return 0, fmt.Errorf("invalid history.retention %q: %w", c.History.Retention, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid history.retention %q: must not be negative", c.History.Retention)
+	}
+	return d, nil
+}
+
+// MemoryCheckInterval は memory.check_interval を time.Duration に変換する
+// 空文字の場合は 0（デフォルト間隔）を返す
+func (c *Config) MemoryCheckInterval() (time.Duration, error) {
+	if c.Memory.CheckInterval == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.Memory.CheckInterval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory.check_interval %q: %w", c.Memory.CheckInterval, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid memory.check_interval %q: must not be negative", c.Memory.CheckInterval)
+	}
+	return d, nil
+}
+
+// IntegrityCheckInterval は integrity.check_interval を time.Duration に変換する
+// 空文字の場合は 0（デフォルト間隔）を返す
+func (c *Config) IntegrityCheckInterval() (time.Duration, error) {
+	if c.Integrity.CheckInterval == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.Integrity.CheckInterval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integrity.check_interval %q: %w", c.Integrity.CheckInterval, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid integrity.check_interval %q: must not be negative", c.Integrity.CheckInterval)
+	}
+	return d, nil
+}
+
+// LogLevel は log.level を slog.Level に変換する
+// 空文字の場合は、デバッグモードなら Debug、そうでなければ Info を返す
+func (c *Config) LogLevel() (slog.Level, error) {
+	switch strings.ToLower(c.Log.Level) {
+	case "":
+		if c.Debug {
+			return slog.LevelDebug, nil
+		}
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid log.level %q: must be debug, info, warn or error", c.Log.Level)
+}
+
+// ShutdownTimeoutDuration は shutdown_timeout を time.Duration に変換する
+// 空文字の場合は 0（待たない）を返す
+func (c *Config) ShutdownTimeoutDuration() (time.Duration, error) {
+	if c.ShutdownTimeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.ShutdownTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid shutdown_timeout %q: %w", c.ShutdownTimeout, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid shutdown_timeout %q: must not be negative", c.ShutdownTimeout)
+	}
+	return d, nil
+}
+
+// InfluxDBInterval は influxdb.interval を time.Duration に変換する（空文字の場合は 0）
+func (c *Config) InfluxDBInterval() (time.Duration, error) {
+	if c.InfluxDB.Interval == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.InfluxDB.Interval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid influxdb.interval %q: %w", c.InfluxDB.Interval, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid influxdb.interval %q: must not be negative", c.InfluxDB.Interval)
+	}
+	return d, nil
+}
+
+// WeatherInterval は weather.interval を time.Duration に変換する（空文字の場合は 0）
+func (c *Config) WeatherInterval() (time.Duration, error) {
+	if c.Weather.Interval == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.Weather.Interval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid weather.interval %q: %w", c.Weather.Interval, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid weather.interval %q: must not be negative", c.Weather.Interval)
+	}
+	return d, nil
+}
+
+// LivenessIntervals は liveness.interval と liveness.max_interval を time.Duration に変換する
+// 空文字の場合は 0（デフォルト間隔）を返す
+func (c *Config) LivenessIntervals() (time.Duration, time.Duration, error) {
+	var durations [2]time.Duration
+	for i, field := range []struct{ name, value string }{
+		{"liveness.interval", c.Liveness.Interval},
+		{"liveness.max_interval", c.Liveness.MaxInterval},
+	} {
+		if field.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(field.value)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid %s %q: %w", field.name, field.value, err)
+		}
+		if d < 0 {
+			return 0, 0, fmt.Errorf("invalid %s %q: must not be negative", field.name, field.value)
+		}
+		durations[i] = d
+	}
+	return durations[0], durations[1], nil
+}
+
+// PersistenceDurations は persistence.save_interval と persistence.prune_unseen_days を time.Duration に変換する
+// 空文字と 0 の場合は 0（変更のたびに保存する、削除しない）を返す
+func (c *Config) PersistenceDurations() (saveInterval, pruneAfter time.Duration, err error) {
+	if c.Persistence.SaveInterval != "" {
+		saveInterval, err = time.ParseDuration(c.Persistence.SaveInterval)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid persistence.save_interval %q: %w", c.Persistence.SaveInterval, err)
+		}
+		if saveInterval < 0 {
+			return 0, 0, fmt.Errorf("invalid persistence.save_interval %q: must not be negative", c.Persistence.SaveInterval)
+		}
+	}
+	if c.Persistence.PruneUnseenDays < 0 {
+		return 0, 0, fmt.Errorf("invalid persistence.prune_unseen_days %d: must not be negative", c.Persistence.PruneUnseenDays)
+	}
+	pruneAfter = time.Duration(c.Persistence.PruneUnseenDays) * 24 * time.Hour
+	return saveInterval, pruneAfter, nil
+}
+
+// EncryptionKey は encryption の設定から保存ファイルの暗号化に使う鍵を取得する
+// 鍵は key、key_file、key_command のいずれか1つで base64 で指定する。無効な場合は nil を返す
+func (c *Config) EncryptionKey() ([]byte, error) {
+	if !c.Encryption.Enabled {
+		return nil, nil
+	}
+	sources := 0
+	for _, set := range []bool{c.Encryption.Key != "", c.Encryption.KeyFile != "", len(c.Encryption.KeyCommand) > 0} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, fmt.Errorf("encryption: exactly one of key, key_file and key_command must be set")
+	}
+
+	encoded := c.Encryption.Key
+	switch {
+	case c.Encryption.KeyFile != "":
+		data, err := os.ReadFile(c.Encryption.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("encryption: failed to read key_file: %w", err)
+		}
+		encoded = string(data)
+	case len(c.Encryption.KeyCommand) > 0:
+		out, err := exec.Command(c.Encryption.KeyCommand[0], c.Encryption.KeyCommand[1:]...).Output()
+		if err != nil {
+			return nil, fmt.Errorf("encryption: key_command failed: %w", err)
+		}
+		encoded = string(out)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("encryption: key is not valid base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption: key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
 // ApplyCommandLineArgs はコマンドライン引数で指定された値を設定に適用する
 func (c *Config) ApplyCommandLineArgs(args CommandLineArgs) {
 	// コマンドライン引数で指定された値で上書き
@@ -172,7 +655,10 @@ func (c *Config) ApplyCommandLineArgs(args CommandLineArgs) {
 		c.Debug = args.Debug
 	}
 	if args.LogFilenameSpecified {
-		c.Log.Filename = args.LogFilename
+		c.Log.Filename = absPath(args.LogFilename)
+	}
+	if args.LogLevelSpecified {
+		c.Log.Level = args.LogLevel
 	}
 	// websocket
 	if args.WebSocketEnabledSpecified {
@@ -196,6 +682,15 @@ func (c *Config) ApplyCommandLineArgs(args CommandLineArgs) {
 	if args.WebSocketClientAddrSpecified {
 		c.WebSocketClient.Addr = args.WebSocketClientAddr
 	}
+	// -discover はクライアントモードを含む
+	if args.DiscoverSpecified && args.Discover {
+		c.WebSocketClient.Enabled = true
+		c.WebSocketClient.Discover = true
+	}
+	// mDNS
+	if args.MDNSSpecified {
+		c.MDNS.Enabled = args.MDNS
+	}
 	// ws-both フラグの特殊処理
 	if args.WebSocketBothSpecified && args.WebSocketBoth {
 		c.WebSocket.Enabled = true
@@ -228,15 +723,35 @@ func (c *Config) ApplyCommandLineArgs(args CommandLineArgs) {
 	}
 
 	// Data file paths
+	// コマンドラインで指定した相対パスは、データディレクトリではなくカレントディレクトリからのパスとして扱う
+	if args.DataDirSpecified {
+		c.DataDir = absPath(args.DataDir)
+	}
 	if args.DevicesFileSpecified {
-		c.DataFiles.DevicesFile = args.DevicesFile
+		c.DataFiles.DevicesFile = absPath(args.DevicesFile)
 	}
 	if args.AliasesFileSpecified {
-		c.DataFiles.AliasesFile = args.AliasesFile
+		c.DataFiles.AliasesFile = absPath(args.AliasesFile)
 	}
 	if args.GroupsFileSpecified {
-		c.DataFiles.GroupsFile = args.GroupsFile
+		c.DataFiles.GroupsFile = absPath(args.GroupsFile)
+	}
+
+	// Demo mode
+	if args.DemoSpecified {
+		c.Demo.Enabled = args.Demo
+	}
+}
+
+// absPath は、パスをカレントディレクトリからの絶対パスにする。空の場合や変換できない場合はそのまま返す
+func absPath(path string) string {
+	if path == "" {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
 	}
+	return path
 }
 
 // CommandLineArgs はコマンドライン引数からの値を保持する
@@ -244,6 +759,7 @@ type CommandLineArgs struct {
 	// 設定ファイル (メタ設定)
 	ConfigFile      string
 	ConfigSpecified bool
+	Profile         string // 設定ファイルから選ぶプロファイル名
 
 	// 一般設定
 	Debug          bool
@@ -252,6 +768,8 @@ type CommandLineArgs struct {
 	// ログ設定
 	LogFilename          string
 	LogFilenameSpecified bool
+	LogLevel             string
+	LogLevelSpecified    bool
 
 	// WebSocketサーバー設定
 	WebSocketEnabled          bool
@@ -270,6 +788,12 @@ type CommandLineArgs struct {
 	WebSocketClientEnabledSpecified bool
 	WebSocketClientAddr             string
 	WebSocketClientAddrSpecified    bool
+	Discover                        bool
+	DiscoverSpecified               bool
+
+	// mDNS
+	MDNS          bool
+	MDNSSpecified bool
 
 	// 特殊フラグ
 	WebSocketBoth          bool
@@ -291,12 +815,22 @@ type CommandLineArgs struct {
 	HTTPServerWebRootSpecified bool
 
 	// Data file paths
+	DataDir              string
+	DataDirSpecified     bool
 	DevicesFile          string
 	DevicesFileSpecified bool
 	AliasesFile          string
 	AliasesFileSpecified bool
 	GroupsFile           string
 	GroupsFileSpecified  bool
+
+	// デモモード
+	Demo          bool
+	DemoSpecified bool
+
+	// コンソールの出力 (設定ファイルには対応しない)
+	JSONOutput bool     // get/devices/discover の結果を JSON で出力する
+	Command    []string // フラグの後に指定されたコマンド。指定された場合はそれだけを実行して終了する
 }
 
 // ParseCommandLineArgs はコマンドライン引数をパースする
@@ -305,9 +839,11 @@ func ParseCommandLineArgs() CommandLineArgs {
 
 	// フラグの定義
 	configFileFlag := flag.String("config", "", "TOML設定ファイルのパスを指定する")
+	profileFlag := flag.String("profile", "", "設定ファイルの [profiles.<名前>] から使うプロファイルを指定する")
 
 	debugFlag := flag.Bool("debug", false, "デバッグモードを有効にする")
 	logFilenameFlag := flag.String("log", "echonet-list.log", "ログファイル名を指定する")
+	logLevelFlag := flag.String("loglevel", "", "ログレベルを指定する（debug, info, warn, error）")
 
 	websocketFlag := flag.Bool("websocket", false, "WebSocketサーバーモードを有効にする")
 
@@ -317,6 +853,8 @@ func ParseCommandLineArgs() CommandLineArgs {
 
 	wsClientFlag := flag.Bool("ws-client", false, "WebSocketクライアントモードを有効にする")
 	wsClientAddrFlag := flag.String("ws-client-addr", "ws://localhost:8080/ws", "WebSocketクライアントの接続先アドレスを指定する")
+	discoverFlag := flag.Bool("discover", false, "mDNS で WebSocket サーバーを探して接続する（-ws-client を含む）")
+	mdnsFlag := flag.Bool("mdns", false, "WebSocketサーバーを mDNS (Bonjour) で広告する")
 
 	wsBothFlag := flag.Bool("ws-both", false, "WebSocketサーバーとクライアントの両方を有効にする（テスト用）")
 	daemonFlag := flag.Bool("daemon", false, "デーモンモードを有効にする")
@@ -327,9 +865,13 @@ func ParseCommandLineArgs() CommandLineArgs {
 	httpPortFlag := flag.Int("http-port", 8080, "HTTPサーバーのポートを指定する")
 	httpWebRootFlag := flag.String("http-webroot", "web/bundle", "HTTPサーバーのWebルートディレクトリを指定する")
 
-	devicesFileFlag := flag.String("devices-file", "", "devices.jsonファイルのパスを指定する（デフォルト: devices.json）")
-	aliasesFileFlag := flag.String("aliases-file", "", "aliases.jsonファイルのパスを指定する（デフォルト: aliases.json）")
-	groupsFileFlag := flag.String("groups-file", "", "groups.jsonファイルのパスを指定する（デフォルト: groups.json）")
+	dataDirFlag := flag.String("data-dir", "", "データファイルとログファイルを置くディレクトリを指定する（デフォルト: $XDG_DATA_HOME/echonet-list、デーモンモードでは /var/lib/echonet-list）")
+	devicesFileFlag := flag.String("devices-file", "", "devices.jsonファイルのパスを指定する（デフォルト: データディレクトリの devices.json）")
+	aliasesFileFlag := flag.String("aliases-file", "", "aliases.jsonファイルのパスを指定する（デフォルト: データディレクトリの aliases.json）")
+	groupsFileFlag := flag.String("groups-file", "", "groups.jsonファイルのパスを指定する（デフォルト: データディレクトリの groups.json）")
+
+	demoFlag := flag.Bool("demo", false, "デモモードを有効にする（実機の代わりに模擬デバイスを使い、データファイルを読み書きしない）")
+	jsonFlag := flag.Bool("json", false, "get/devices/discover コマンドの結果を JSON で出力する")
 
 	// コマンドライン引数を解析
 	flag.Parse()
@@ -364,12 +906,15 @@ func ParseCommandLineArgs() CommandLineArgs {
 	// 値と指定有無の設定
 	args.ConfigFile = *configFileFlag
 	args.ConfigSpecified = argsMap["config"]
+	args.Profile = *profileFlag
 
 	args.Debug = *debugFlag
 	args.DebugSpecified = argsMap["debug"]
 
 	args.LogFilename = *logFilenameFlag
 	args.LogFilenameSpecified = argsMap["log"]
+	args.LogLevel = *logLevelFlag
+	args.LogLevelSpecified = argsMap["loglevel"]
 
 	args.WebSocketEnabled = *websocketFlag
 	args.WebSocketEnabledSpecified = argsMap["websocket"]
@@ -388,6 +933,11 @@ func ParseCommandLineArgs() CommandLineArgs {
 
 	args.WebSocketClientAddr = *wsClientAddrFlag
 	args.WebSocketClientAddrSpecified = argsMap["ws-client-addr"]
+	args.Discover = *discoverFlag
+	args.DiscoverSpecified = argsMap["discover"]
+
+	args.MDNS = *mdnsFlag
+	args.MDNSSpecified = argsMap["mdns"]
 
 	args.WebSocketBoth = *wsBothFlag
 	args.WebSocketBothSpecified = argsMap["ws-both"]
@@ -405,6 +955,8 @@ func ParseCommandLineArgs() CommandLineArgs {
 	args.HTTPServerWebRoot = *httpWebRootFlag
 	args.HTTPServerWebRootSpecified = argsMap["http-webroot"]
 
+	args.DataDir = *dataDirFlag
+	args.DataDirSpecified = argsMap["data-dir"]
 	args.DevicesFile = *devicesFileFlag
 	args.DevicesFileSpecified = argsMap["devices-file"]
 	args.AliasesFile = *aliasesFileFlag
@@ -412,5 +964,11 @@ func ParseCommandLineArgs() CommandLineArgs {
 	args.GroupsFile = *groupsFileFlag
 	args.GroupsFileSpecified = argsMap["groups-file"]
 
+	args.Demo = *demoFlag
+	args.DemoSpecified = argsMap["demo"]
+
+	args.JSONOutput = *jsonFlag
+	args.Command = flag.Args()
+
 	return args
 }
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const profileTestConfig = `
+debug = false
+
+[http_server]
+enabled = true
+port = 8080
+
+[network]
+interfaces = ["eth0"]
+
+[data_files]
+devices_file = "devices.json"
+
+[profiles.lab.http_server]
+port = 8081
+
+[profiles.lab.network]
+interfaces = ["eth1"]
+
+[profiles.lab.data_files]
+devices_file = "lab/devices.json"
+history_file = "lab/history.json"
+
+[profiles.demo]
+debug = true
+
+[profiles.demo.demo]
+enabled = true
+`
+
+func writeTestConfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(profileTestConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig_WithoutProfile(t *testing.T) {
+	cfg, err := LoadConfig(writeTestConfig(t), "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Profile != "" || cfg.HTTPServer.Port != 8080 || cfg.DataFiles.DevicesFile != "devices.json" {
+		t.Errorf("unexpected config: profile=%q port=%d devices=%q", cfg.Profile, cfg.HTTPServer.Port, cfg.DataFiles.DevicesFile)
+	}
+	if len(cfg.Profiles) != 2 {
+		t.Errorf("expected 2 profiles, got %d", len(cfg.Profiles))
+	}
+}
+
+func TestLoadConfig_Profile(t *testing.T) {
+	cfg, err := LoadConfig(writeTestConfig(t), "lab")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Profile != "lab" {
+		t.Errorf("Profile = %q, want lab", cfg.Profile)
+	}
+	// Settings in the profile override the common ones
+	if cfg.HTTPServer.Port != 8081 || cfg.DataFiles.DevicesFile != "lab/devices.json" || cfg.DataFiles.HistoryFile != "lab/history.json" {
+		t.Errorf("profile settings were not applied: port=%d devices=%q history=%q", cfg.HTTPServer.Port, cfg.DataFiles.DevicesFile, cfg.DataFiles.HistoryFile)
+	}
+	if len(cfg.Network.Interfaces) != 1 || cfg.Network.Interfaces[0] != "eth1" {
+		t.Errorf("Interfaces = %v, want [eth1]", cfg.Network.Interfaces)
+	}
+	// Settings not in the profile keep the common values
+	if !cfg.HTTPServer.Enabled || cfg.Demo.Enabled || cfg.Debug {
+		t.Errorf("common settings changed: http=%v demo=%v debug=%v", cfg.HTTPServer.Enabled, cfg.Demo.Enabled, cfg.Debug)
+	}
+
+	cfg, err = LoadConfig(writeTestConfig(t), "demo")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if !cfg.Demo.Enabled || !cfg.Debug || cfg.HTTPServer.Port != 8080 {
+		t.Errorf("demo profile was not applied: demo=%v debug=%v port=%d", cfg.Demo.Enabled, cfg.Debug, cfg.HTTPServer.Port)
+	}
+}
+
+func TestLoadConfig_UnknownProfile(t *testing.T) {
+	_, err := LoadConfig(writeTestConfig(t), "home")
+	if err == nil || !strings.Contains(err.Error(), "[demo lab]") {
+		t.Errorf("expected an error listing the available profiles, got %v", err)
+	}
+
+	t.Chdir(t.TempDir())
+	if _, err := LoadConfig("", "lab"); err == nil {
+		t.Error("expected an error when no config file is found")
+	}
+}
+
+func TestConfig_EncryptionKey(t *testing.T) {
+	const encoded = "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+
+	cfg := NewConfig()
+	if key, err := cfg.EncryptionKey(); key != nil || err != nil {
+		t.Fatalf("disabled encryption returned key=%v err=%v", key, err)
+	}
+
+	cfg.Encryption.Enabled = true
+	if _, err := cfg.EncryptionKey(); err == nil {
+		t.Error("expected an error when no key source is set")
+	}
+
+	// The key file may end with a newline
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte(encoded+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Encryption.KeyFile = keyFile
+	key, err := cfg.EncryptionKey()
+	if err != nil || len(key) != 32 || key[31] != 31 {
+		t.Fatalf("EncryptionKey from key_file = %v, %v", key, err)
+	}
+
+	cfg.Encryption.Key = encoded
+	if _, err := cfg.EncryptionKey(); err == nil {
+		t.Error("expected an error when several key sources are set")
+	}
+
+	cfg.Encryption.KeyFile = ""
+	cfg.Encryption.Key = "c2hvcnQ="
+	if _, err := cfg.EncryptionKey(); err == nil {
+		t.Error("expected an error for a key that is not 32 bytes")
+	}
+}
+
+func TestConfig_LogLevel(t *testing.T) {
+	cfg := NewConfig()
+	if level, err := cfg.LogLevel(); err != nil || level != slog.LevelInfo {
+		t.Errorf("default LogLevel = %v, %v; want INFO", level, err)
+	}
+
+	// Without log.level, debug mode writes debug logs
+	cfg.Debug = true
+	if level, err := cfg.LogLevel(); err != nil || level != slog.LevelDebug {
+		t.Errorf("LogLevel in debug mode = %v, %v; want DEBUG", level, err)
+	}
+
+	// log.level overrides the debug mode, e.g. -debug -loglevel warn
+	cfg.ApplyCommandLineArgs(CommandLineArgs{LogLevel: "WARN", LogLevelSpecified: true})
+	if level, err := cfg.LogLevel(); err != nil || level != slog.LevelWarn {
+		t.Errorf("LogLevel with -loglevel WARN = %v, %v; want WARN", level, err)
+	}
+
+	cfg.Log.Level = "verbose"
+	if _, err := cfg.LogLevel(); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestConfig_Discover(t *testing.T) {
+	cfg := NewConfig()
+	if cfg.WebSocketClient.Discover || cfg.MDNS.Enabled {
+		t.Error("mDNS should be disabled by default")
+	}
+
+	// -discover implies the WebSocket client mode
+	cfg.ApplyCommandLineArgs(CommandLineArgs{Discover: true, DiscoverSpecified: true, MDNS: true, MDNSSpecified: true})
+	if !cfg.WebSocketClient.Enabled || !cfg.WebSocketClient.Discover || !cfg.MDNS.Enabled {
+		t.Errorf("unexpected settings: client=%v discover=%v mdns=%v", cfg.WebSocketClient.Enabled, cfg.WebSocketClient.Discover, cfg.MDNS.Enabled)
+	}
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// dataDirName はデータディレクトリの名前
+const dataDirName = "echonet-list"
+
+// getDefaultDataDir はデフォルトのデータディレクトリを返す
+// デーモンモードでは /var/lib/echonet-list、それ以外では $XDG_DATA_HOME/echonet-list（未設定の場合は ~/.local/share/echonet-list）
+func getDefaultDataDir(daemon bool) string {
+	if daemon {
+		switch runtime.GOOS {
+		case "linux":
+			return "/var/lib/echonet-list"
+		case "darwin":
+			return "/usr/local/var/lib/echonet-list"
+		}
+	}
+	// XDG Base Directory の仕様では、相対パスの XDG_DATA_HOME は無視する
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, dataDirName)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "share", dataDirName)
+	}
+	// ホームディレクトリがわからない場合はカレントディレクトリ
+	return "."
+}
+
+// dataFile はデータディレクトリに置くファイルの設定項目とデフォルトのファイル名
+type dataFile struct {
+	path        *string
+	defaultName string // 空の場合、設定が空のときはファイルを使わない
+}
+
+// dataFiles はデータディレクトリに置くファイルの一覧を返す
+// デフォルトのファイル名は handler パッケージのデフォルトと同じ
+func (c *Config) dataFiles() []dataFile {
+	return []dataFile{
+		{&c.DataFiles.DevicesFile, "devices.json"},
+		{&c.DataFiles.AliasesFile, "aliases.json"},
+		{&c.DataFiles.GroupsFile, "groups.json"},
+		{&c.DataFiles.ScenesFile, "scenes.json"},
+		{&c.DataFiles.SchedulesFile, "schedules.json"},
+		{&c.DataFiles.LocationsFile, "location_settings.json"},
+		{&c.DataFiles.MetadataFile, "device_metadata.json"},
+		{&c.DataFiles.PreferencesFile, "client_preferences.json"},
+		{&c.DataFiles.PropertyOverridesFile, "property-descriptions-override.json"},
+		{&c.DataFiles.LocalPropertyMapsFile, "local_property_maps.json"},
+		{&c.DataFiles.StatsFile, "property_stats.json"},
+		{&c.DataFiles.HistoryFile, ""}, // 空の場合は履歴を保存しない
+		{&c.History.JournalFile, "history.jsonl"},
+	}
+}
+
+// ResolveDataDir は、データファイルとログファイルの相対パスをデータディレクトリからのパスにする
+// data_dir が空の場合はデフォルトのデータディレクトリを使う。絶対パスで指定されたファイルはそのまま使う
+// 戻り値は、移行の対象となるカレントディレクトリの相対パスと、データディレクトリでのパスの組
+func (c *Config) ResolveDataDir() map[string]string {
+	if c.DataDir == "" {
+		c.DataDir = getDefaultDataDir(c.Daemon.Enabled)
+	}
+	legacy := make(map[string]string)
+	for _, f := range c.dataFiles() {
+		name := *f.path
+		if name == "" {
+			name = f.defaultName
+		}
+		if name == "" || filepath.IsAbs(name) {
+			continue
+		}
+		*f.path = filepath.Join(c.DataDir, name)
+		legacy[name] = *f.path
+	}
+	if c.Log.Filename != "" && !filepath.IsAbs(c.Log.Filename) {
+		c.Log.Filename = filepath.Join(c.DataDir, c.Log.Filename)
+	}
+	return legacy
+}
+
+// PrepareDataDir は、データディレクトリを作成し、カレントディレクトリに残っているデータファイルを移す
+// legacy は ResolveDataDir の戻り値。データディレクトリに同じファイルがある場合は移さない
+// チェックサムとバックアップ（<ファイル名>.sha256、<ファイル名>.bak1 など）も一緒に移す
+// 戻り値は移したファイルのデータディレクトリでのパス
+func (c *Config) PrepareDataDir(legacy map[string]string) ([]string, error) {
+	if err := os.MkdirAll(c.DataDir, 0755); err != nil {
+		return nil, fmt.Errorf("data_dir: %w", err)
+	}
+	if sameDir(c.DataDir, ".") {
+		return nil, nil
+	}
+
+	var migrated []string
+	for name, dest := range legacy {
+		if _, err := os.Stat(dest); err == nil {
+			continue
+		}
+		if _, err := os.Stat(name); err != nil {
+			continue
+		}
+		sidecars, _ := filepath.Glob(name + ".*")
+		for _, src := range append([]string{name}, sidecars...) {
+			// ロックファイルは実行中のインスタンスのものかもしれないため移さない
+			if strings.HasSuffix(src, ".lock") {
+				continue
+			}
+			to := dest + strings.TrimPrefix(src, name)
+			if err := moveFile(src, to); err != nil {
+				return migrated, fmt.Errorf("%s を %s に移せません: %w", src, to, err)
+			}
+			migrated = append(migrated, to)
+		}
+	}
+	sort.Strings(migrated)
+	return migrated, nil
+}
+
+// sameDir は、2つのパスが同じディレクトリかどうかを返す
+func sameDir(a, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
+}
+
+// moveFile はファイルを移す。別のファイルシステムへは、コピーしてから元のファイルを削除する
+func moveFile(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dest); !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(dest)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(dest)
+		return err
+	}
+	return os.Remove(src)
+}
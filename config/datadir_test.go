@@ -0,0 +1,125 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestGetDefaultDataDir(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/xdg/data")
+	if dir := getDefaultDataDir(false); dir != filepath.Join("/xdg/data", "echonet-list") {
+		t.Errorf("XDG_DATA_HOME を使っていない: %s", dir)
+	}
+
+	// 相対パスの XDG_DATA_HOME は無視する
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "relative")
+	if runtime.GOOS != "windows" {
+		if dir := getDefaultDataDir(false); dir != filepath.Join(home, ".local", "share", "echonet-list") {
+			t.Errorf("~/.local/share を使っていない: %s", dir)
+		}
+	}
+
+	if runtime.GOOS == "linux" {
+		if dir := getDefaultDataDir(true); dir != "/var/lib/echonet-list" {
+			t.Errorf("デーモンモードのデータディレクトリが不正: %s", dir)
+		}
+	}
+}
+
+func TestConfig_ResolveDataDir(t *testing.T) {
+	cfg := NewConfig()
+	cfg.DataDir = "/data"
+	cfg.DataFiles.AliasesFile = "/etc/echonet-list/aliases.json"
+	cfg.DataFiles.GroupsFile = "lab/groups.json"
+
+	legacy := cfg.ResolveDataDir()
+
+	if cfg.DataFiles.DevicesFile != filepath.Join("/data", "devices.json") {
+		t.Errorf("DevicesFile = %s", cfg.DataFiles.DevicesFile)
+	}
+	if cfg.DataFiles.AliasesFile != "/etc/echonet-list/aliases.json" {
+		t.Errorf("絶対パスが変わった: %s", cfg.DataFiles.AliasesFile)
+	}
+	if cfg.DataFiles.GroupsFile != filepath.Join("/data", "lab", "groups.json") {
+		t.Errorf("GroupsFile = %s", cfg.DataFiles.GroupsFile)
+	}
+	if cfg.DataFiles.HistoryFile != filepath.Join("/data", "history.json") || cfg.History.JournalFile != filepath.Join("/data", "history.jsonl") {
+		t.Errorf("履歴ファイルが不正: %s, %s", cfg.DataFiles.HistoryFile, cfg.History.JournalFile)
+	}
+	if cfg.Log.Filename != filepath.Join("/data", "echonet-list.log") {
+		t.Errorf("Log.Filename = %s", cfg.Log.Filename)
+	}
+	if legacy["devices.json"] != cfg.DataFiles.DevicesFile || legacy["lab/groups.json"] != cfg.DataFiles.GroupsFile {
+		t.Errorf("移行の対象が不正: %v", legacy)
+	}
+	if _, ok := legacy["/etc/echonet-list/aliases.json"]; ok {
+		t.Error("絶対パスのファイルが移行の対象になった")
+	}
+
+	// 保存しない設定の履歴ファイルは空のまま
+	cfg = NewConfig()
+	cfg.DataDir = "/data"
+	cfg.DataFiles.HistoryFile = ""
+	cfg.ResolveDataDir()
+	if cfg.DataFiles.HistoryFile != "" {
+		t.Errorf("空の HistoryFile が設定された: %s", cfg.DataFiles.HistoryFile)
+	}
+}
+
+func TestConfig_PrepareDataDir(t *testing.T) {
+	work := t.TempDir()
+	t.Chdir(work)
+	for _, name := range []string{"devices.json", "devices.json.sha256", "devices.json.bak1", "devices.json.lock", "aliases.json"} {
+		if err := os.WriteFile(name, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := NewConfig()
+	cfg.DataDir = filepath.Join(t.TempDir(), "data")
+	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// データディレクトリにすでにあるファイルは上書きしない
+	if err := os.WriteFile(filepath.Join(cfg.DataDir, "aliases.json"), []byte("current"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, err := cfg.PrepareDataDir(cfg.ResolveDataDir())
+	if err != nil {
+		t.Fatalf("PrepareDataDir failed: %v", err)
+	}
+	want := []string{
+		filepath.Join(cfg.DataDir, "devices.json"),
+		filepath.Join(cfg.DataDir, "devices.json.bak1"),
+		filepath.Join(cfg.DataDir, "devices.json.sha256"),
+	}
+	if !slices.Equal(migrated, want) {
+		t.Errorf("移したファイル %v, 期待値 %v", migrated, want)
+	}
+	if data, err := os.ReadFile(filepath.Join(cfg.DataDir, "devices.json")); err != nil || string(data) != "devices.json" {
+		t.Errorf("devices.json が移されていない: %q, %v", data, err)
+	}
+	if _, err := os.Stat("devices.json"); !os.IsNotExist(err) {
+		t.Error("移した devices.json が残っている")
+	}
+	if _, err := os.Stat("devices.json.lock"); err != nil {
+		t.Error("ロックファイルが移された")
+	}
+	if data, _ := os.ReadFile(filepath.Join(cfg.DataDir, "aliases.json")); string(data) != "current" {
+		t.Errorf("データディレクトリの aliases.json が上書きされた: %q", data)
+	}
+	if _, err := os.Stat("aliases.json"); err != nil {
+		t.Error("移さなかった aliases.json が消えた")
+	}
+
+	// 2回目は何も移さない
+	if migrated, err := cfg.PrepareDataDir(map[string]string{"devices.json": cfg.DataFiles.DevicesFile}); err != nil || len(migrated) != 0 {
+		t.Errorf("2回目に移したファイル %v, %v", migrated, err)
+	}
+}
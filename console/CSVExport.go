@@ -0,0 +1,80 @@
+package console
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"echonet-list/client"
+	"echonet-list/echonet_lite"
+	"echonet-list/protocol"
+)
+
+// processExportCSVCommand は、デバイス一覧または履歴を CSV ファイルに書き出す
+func (p *CommandProcessor) processExportCSVCommand(cmd *Command) error {
+	devices := p.handler.ListDevices(client.FilterCriteria{Device: cmd.DeviceSpec})
+	if len(devices) == 0 {
+		return fmt.Errorf("デバイスが見つかりません")
+	}
+
+	var buf bytes.Buffer
+	rows := 0
+	switch cmd.ExportKind {
+	case protocol.CSVExportDevices:
+		protoDevices := make([]protocol.Device, 0, len(devices))
+		aliases := make(map[string]string)
+		for _, device := range devices {
+			protoDevices = append(protoDevices, protocol.DeviceToProtocol(device.Device, device.Properties, time.Time{}, p.handler.IsOfflineDevice(device.Device)))
+			if names := p.handler.GetAliases(device.Device); len(names) > 0 {
+				aliases[device.Device.Specifier()] = names[0]
+			}
+			rows += len(device.Properties)
+		}
+		if err := protocol.WriteDevicesCSV(&buf, protoDevices, aliases, echonet_lite.DisplayLanguage()); err != nil {
+			return err
+		}
+
+	case protocol.CSVExportHistory:
+		var entries []protocol.CSVHistoryEntry
+		for _, device := range devices {
+			history, err := p.handler.GetDeviceHistory(device.Device, cmd.HistoryOptions)
+			if err != nil {
+				return err
+			}
+			csvDevice := protocol.CSVDevice{IP: device.Device.IP.String(), EOJ: device.Device.EOJ.Specifier()}
+			if names := p.handler.GetAliases(device.Device); len(names) > 0 {
+				csvDevice.Alias = names[0]
+			}
+			for _, entry := range history {
+				epc := ""
+				if entry.EPC != 0 {
+					epc = entry.EPC.String()
+				}
+				entries = append(entries, protocol.CSVHistoryEntry{
+					Device: csvDevice,
+					Entry:  protocol.HistoryEntry{Timestamp: entry.Timestamp, EPC: epc, Value: entry.Value, Origin: entry.Origin, Settable: entry.Settable},
+				})
+			}
+		}
+		// 履歴は新しい順に返されるため、古い順に並べ替える
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Entry.Timestamp.Before(entries[j].Entry.Timestamp)
+		})
+		rows = len(entries)
+		if err := protocol.WriteHistoryCSV(&buf, entries, echonet_lite.DisplayLanguage()); err != nil {
+			return err
+		}
+	}
+
+	filename := cmd.OutputFile
+	if filename == "" {
+		filename = protocol.CSVFilename(cmd.ExportKind, time.Now())
+	}
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("CSV ファイルの書き込みに失敗しました: %w", err)
+	}
+	fmt.Printf("%d 行を %s に書き出しました\n", rows, filename)
+	return nil
+}
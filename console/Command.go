@@ -3,11 +3,13 @@ package console
 import (
 	"echonet-list/client"
 	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
 	"fmt"
 	"net"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/exp/slices"
 )
@@ -24,9 +26,22 @@ const (
 	CmdSet
 	CmdGet
 	CmdHistory
+	CmdExportCSV
+	CmdWatch
 	CmdDebug
 	CmdDebugOffline
+	CmdPending
+	CmdPendingCancel
+	CmdVersions
+	CmdLocalMapList
+	CmdLocalMapChange
+	CmdPollExcludeList
+	CmdPollExcludeChange
 	CmdUpdate
+	CmdCleanup
+	CmdRefreshPropertyMap
+	CmdAircon
+	CmdLight
 	CmdAliasSet
 	CmdAliasGet
 	CmdAliasDelete
@@ -35,6 +50,16 @@ const (
 	CmdGroupRemove
 	CmdGroupDelete
 	CmdGroupList
+	CmdSceneAdd
+	CmdSceneDelete
+	CmdSceneList
+	CmdSceneRun
+	CmdSceneSequence
+	CmdScheduleAdd
+	CmdScheduleDelete
+	CmdScheduleEnable
+	CmdScheduleDisable
+	CmdScheduleList
 	CmdLocationList
 	CmdLocationAliasList
 	CmdLocationAliasAdd
@@ -60,6 +85,10 @@ type Command struct {
 	DeviceSpecs    []client.DeviceSpecifier    // 複数デバイス指定子（グループ追加・削除用）
 	DeviceAlias    *string                     // エイリアス
 	GroupName      *string                     // グループ名（グループ操作用およびフィルタリング用）
+	SceneName      *string                     // シーン名（シーン操作用）
+	SceneDelay     time.Duration               // scene sequence コマンドのデバイスの間隔（0の場合は順次実行を解除）
+	ScheduleName   *string                     // スケジュール名（スケジュール操作用）
+	ScheduleCron   string                      // schedule add コマンドの cron 形式の指定
 	EPCs           []client.EPCType            // devicesコマンドのEPCフィルター用。空の場合は全EPCを表示
 	PropMode       PropertyMode                // プロパティ表示モード
 	Properties     client.Properties           // set/devicesコマンドのプロパティリスト
@@ -67,11 +96,39 @@ type Command struct {
 	DebugMode      *string                     // debugコマンドのモード ("on" または "off")
 	RawValue       *string                     // location alias add コマンドの生値
 	ForceUpdate    bool                        // updateコマンドの強制更新フラグ
+	AllProperties  bool                        // getコマンドで取得可能なすべてのプロパティを取得するか（-all）
 	HistoryOptions client.DeviceHistoryOptions // historyコマンドのオプション
+	ExportKind     protocol.CSVExportKind      // export-csv コマンドで書き出す表
+	OutputFile     string                      // export-csv コマンドの出力先ファイル（空の場合は自動で名前を付ける）
+	RequestID      uint64                      // pending cancel コマンドで取り消す要求の番号
+	UnseenDays     int                         // cleanup コマンドの対象（この日数以上更新のないデバイス）
+	LocalEOJ       client.EOJ                  // localmap コマンドの対象の自ノードのデバイス
+	LocalMapType   client.PropertyMapType      // localmap コマンドで変更するプロパティマップ
+	LocalMapAction string                      // localmap コマンドの操作（"set", "add", "remove", "reset"）
+	PollTarget     string                      // poll-exclude コマンドの対象（クラスコードまたはデバイス指定）
+	PollExcluded   bool                        // poll-exclude コマンドで除外するか（false の場合は除外をやめる）
+	Confirmed      bool                        // cleanup コマンドで削除を確認済みか（-y）
+	Aircon         AirconOptions               // aircon コマンドの操作
+	Light          LightOptions                // light コマンドの操作
+	Output         OutputFormat                // get/devices/discover コマンドの出力形式
 	Done           chan struct{}               // コマンド実行完了を通知するチャネル
 	Error          error                       // コマンド実行中に発生したエラー
 }
 
+// AirconOptions は、aircon コマンドの操作（指定のないものは変更しない）
+type AirconOptions struct {
+	Power       *bool                     // 電源の入/切
+	Mode        client.AirConditionerMode // 運転モード
+	Temperature *int                      // 温度設定値（℃）
+}
+
+// LightOptions は、light コマンドの操作（指定のないものは変更しない）
+type LightOptions struct {
+	Power      *bool             // 電源の入/切
+	Brightness *int              // 照度レベル（%）
+	Color      client.LightColor // 光色（色温度）
+}
+
 // GetIPAddress は、コマンドのIPアドレスを取得する
 func (c *Command) GetIPAddress() *net.IP {
 	return c.DeviceSpec.IP
@@ -160,6 +217,21 @@ func parseEPC(epcStr string) (client.EPCType, error) {
 	return client.EPCType(epc64), nil
 }
 
+// history の -since の値をパースする。期間（例: 24h）なら now からさかのぼった時刻、それ以外は RFC3339 の時刻として扱う
+func parseHistorySince(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("-since の期間には正の値を指定してください: %s", value)
+		}
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("-since には期間（例: 24h）または RFC3339 の時刻（例: 2024-05-01T12:00:00Z）を指定してください: %s", value)
+	}
+	return t, nil
+}
+
 func parseHexBytes(hexStr string) ([]byte, error) {
 	if len(hexStr)%2 != 0 {
 		return nil, fmt.Errorf("hex string must be a multiple of 2 characters: %s", hexStr)
@@ -176,7 +248,7 @@ func parseHexBytes(hexStr string) ([]byte, error) {
 }
 
 // プロパティ文字列をパースする
-// propertyStr: プロパティ文字列（"EPC:EDT" 形式または "alias" 形式）
+// propertyStr: プロパティ文字列（"EPC:EDT" 形式、"プロパティ名:値" 形式または "alias" 形式）
 // classCode: クラスコード
 // debug: デバッグフラグ
 // 戻り値: パースされたプロパティとエラー
@@ -184,10 +256,14 @@ func (p CommandParser) parsePropertyString(propertyStr string, classCode client.
 	// EPC:EDT の形式をパース
 	propParts := strings.Split(propertyStr, ":")
 	if len(propParts) == 2 {
-		// EPCのパース
+		// EPCのパース（2桁の16進数の代わりにプロパティ名も使える）
 		epc, err := parseEPC(propParts[0])
 		if err != nil {
-			return client.Property{}, err
+			byName, ok := findEPCByName(p.propertyDescProvider, classCode, propParts[0])
+			if !ok {
+				return client.Property{}, err
+			}
+			epc = byName
 		}
 
 		var edt []byte
@@ -3,12 +3,13 @@ package console
 import (
 	"context"
 	"echonet-list/client"
+	"echonet-list/echonet_lite"
 	"echonet-list/echonet_lite/handler"
 	"echonet-list/protocol"
 	"encoding/base64"
-	"encoding/hex"
 	"errors"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 	"time"
@@ -23,6 +24,8 @@ type CommandProcessor struct {
 	done    chan struct{}
 	ctx     context.Context    // コンテキスト
 	cancel  context.CancelFunc // コンテキストのキャンセル関数
+
+	jsonOutput bool // -o の指定がないコマンドの結果を JSON で出力する
 }
 
 // NewCommandProcessor は、CommandProcessor の新しいインスタンスを作成する
@@ -39,6 +42,11 @@ func NewCommandProcessor(ctx context.Context, handler client.ECHONETListClient)
 	}
 }
 
+// SetJSONOutput は、-o の指定がないコマンドの結果を JSON で出力するかどうかを設定する
+func (p *CommandProcessor) SetJSONOutput(enabled bool) {
+	p.jsonOutput = enabled
+}
+
 // Start は、コマンド処理を開始する
 func (p *CommandProcessor) Start() {
 	go p.processCommands()
@@ -96,7 +104,7 @@ func (p *CommandProcessor) processCommands() {
 			close(cmd.Done) // 終了コマンドの場合は即座に完了を通知して終了
 			return
 		case CmdDiscover:
-			cmd.Error = p.handler.Discover()
+			cmd.Error = p.processDiscoverCommand(cmd)
 		case CmdDevices:
 			cmd.Error = p.processDevicesCommand(cmd)
 
@@ -110,8 +118,33 @@ func (p *CommandProcessor) processCommands() {
 			cmd.Error = p.processDebugCommand(cmd)
 		case CmdDebugOffline:
 			cmd.Error = p.processDebugOfflineCommand(cmd)
+		case CmdPending:
+			cmd.Error = p.processPendingCommand(cmd)
+		case CmdPendingCancel:
+			cmd.Error = p.handler.DebugCancelRequest(cmd.RequestID)
+			if cmd.Error == nil {
+				fmt.Printf("要求 #%d を取り消しました\n", cmd.RequestID)
+			}
+		case CmdVersions:
+			cmd.Error = p.processVersionsCommand(cmd)
+		case CmdLocalMapList:
+			cmd.Error = p.processLocalMapListCommand()
+		case CmdLocalMapChange:
+			cmd.Error = p.processLocalMapChangeCommand(cmd)
+		case CmdPollExcludeList:
+			cmd.Error = p.processPollExcludeListCommand()
+		case CmdPollExcludeChange:
+			cmd.Error = p.processPollExcludeChangeCommand(cmd)
 		case CmdUpdate:
 			cmd.Error = p.processUpdateCommand(cmd)
+		case CmdCleanup:
+			cmd.Error = p.processCleanupCommand(cmd)
+		case CmdRefreshPropertyMap:
+			cmd.Error = p.processRefreshPropertyMapCommand(cmd)
+		case CmdAircon:
+			cmd.Error = p.processAirconCommand(cmd)
+		case CmdLight:
+			cmd.Error = p.processLightCommand(cmd)
 		case CmdAliasList:
 			aliases := p.handler.AliasList()
 			for _, alias := range aliases {
@@ -147,8 +180,44 @@ func (p *CommandProcessor) processCommands() {
 			}
 		case CmdGroupList:
 			cmd.Error = p.processGroupListCommand(cmd)
+		case CmdSceneAdd:
+			cmd.Error = p.processSceneAddCommand(cmd)
+		case CmdSceneDelete:
+			cmd.Error = p.handler.SceneDelete(*cmd.SceneName)
+			if cmd.Error == nil {
+				fmt.Printf("シーン %s を削除しました\n", *cmd.SceneName)
+			}
+		case CmdSceneList:
+			cmd.Error = p.processSceneListCommand(cmd)
+		case CmdSceneRun:
+			cmd.Error = p.processSceneRunCommand(cmd)
+		case CmdSceneSequence:
+			cmd.Error = p.processSceneSequenceCommand(cmd)
+		case CmdScheduleAdd:
+			cmd.Error = p.processScheduleAddCommand(cmd)
+		case CmdScheduleDelete:
+			cmd.Error = p.handler.ScheduleDelete(*cmd.ScheduleName)
+			if cmd.Error == nil {
+				fmt.Printf("スケジュール %s を削除しました\n", *cmd.ScheduleName)
+			}
+		case CmdScheduleEnable, CmdScheduleDisable:
+			disabled := cmd.Type == CmdScheduleDisable
+			cmd.Error = p.handler.ScheduleSetDisabled(*cmd.ScheduleName, disabled)
+			if cmd.Error == nil {
+				if disabled {
+					fmt.Printf("スケジュール %s を無効にしました\n", *cmd.ScheduleName)
+				} else {
+					fmt.Printf("スケジュール %s を有効にしました\n", *cmd.ScheduleName)
+				}
+			}
+		case CmdScheduleList:
+			cmd.Error = p.processScheduleListCommand()
 		case CmdHistory:
 			cmd.Error = p.processHistoryCommand(cmd)
+		case CmdExportCSV:
+			cmd.Error = p.processExportCSVCommand(cmd)
+		case CmdWatch:
+			cmd.Error = p.processWatchCommand(cmd)
 		case CmdLocationList:
 			cmd.Error = p.processLocationListCommand()
 		case CmdLocationAliasList:
@@ -221,11 +290,8 @@ func sortProperties(p client.Properties) client.Properties {
 	return p
 }
 
-// displayDevice は、デバイスとそのプロパティを表示する
-func (p *CommandProcessor) displayDevice(cmd *Command, device client.IPAndEOJ, properties client.Properties) bool {
-	classCode := device.EOJ.ClassCode()
-
-	// プロパティ表示モードに応じてフィルタリング
+// filterProperties は、プロパティ表示モードに応じて表示するプロパティを選ぶ
+func (p *CommandProcessor) filterProperties(cmd *Command, classCode client.EOJClassCode, properties client.Properties) client.Properties {
 	filteredProps := make(client.Properties, 0, len(properties))
 
 	for _, prop := range properties {
@@ -250,7 +316,13 @@ func (p *CommandProcessor) displayDevice(cmd *Command, device client.IPAndEOJ, p
 		}
 		filteredProps = append(filteredProps, prop)
 	}
+	return filteredProps
+}
 
+// displayDevice は、デバイスとそのプロパティを表示する
+func (p *CommandProcessor) displayDevice(cmd *Command, device client.IPAndEOJ, properties client.Properties) bool {
+	classCode := device.EOJ.ClassCode()
+	filteredProps := p.filterProperties(cmd, classCode, properties)
 	if len(filteredProps) == 0 {
 		return false
 	}
@@ -273,6 +345,13 @@ func (p *CommandProcessor) processDevicesCommand(cmd *Command) error {
 	}
 	result := p.handler.ListDevices(criteria)
 
+	if p.isJSONOutput(cmd) {
+		if cmd.GroupByEPC != nil {
+			return errors.New("-group-by は JSON 出力では使用できません")
+		}
+		return p.printDevicesJSON(cmd, result)
+	}
+
 	// グループ化が指定されている場合
 	if cmd.GroupByEPC != nil {
 		return p.processDevicesWithGrouping(cmd, result)
@@ -289,6 +368,30 @@ func (p *CommandProcessor) processDevicesCommand(cmd *Command) error {
 	return nil
 }
 
+// printDevicesJSON は、表示するプロパティのあるデバイスを JSON の配列で出力する
+func (p *CommandProcessor) printDevicesJSON(cmd *Command, devices []client.DeviceAndProperties) error {
+	result := make([]jsonDevice, 0, len(devices))
+	for _, d := range devices {
+		filteredProps := p.filterProperties(cmd, d.Device.EOJ.ClassCode(), d.Properties)
+		if len(filteredProps) == 0 {
+			continue
+		}
+		result = append(result, p.toJSONDevice(d.Device, filteredProps))
+	}
+	return printJSON(result)
+}
+
+// processDiscoverCommand は、デバイスの検出を行う。JSON 出力の場合は検出後のデバイス一覧を出力する
+func (p *CommandProcessor) processDiscoverCommand(cmd *Command) error {
+	if err := p.handler.Discover(); err != nil {
+		return err
+	}
+	if !p.isJSONOutput(cmd) {
+		return nil
+	}
+	return p.printDevicesJSON(&Command{PropMode: PropAll}, p.handler.ListDevices(client.FilterCriteria{}))
+}
+
 // processDevicesWithGrouping は、指定されたEPCでデバイスをグループ化して表示する
 func (p *CommandProcessor) processDevicesWithGrouping(cmd *Command, devices []client.DeviceAndProperties) error {
 	// グループ化するEPC
@@ -408,14 +511,17 @@ func (p *CommandProcessor) processGetCommand(cmd *Command) error {
 		devices = append(devices, *device)
 	}
 
-	if len(cmd.EPCs) == 0 {
-		return errors.New("get コマンドには少なくとも1つのEPCが必要です")
+	if len(cmd.EPCs) == 0 && !cmd.AllProperties {
+		return errors.New("get コマンドには少なくとも1つのEPCか -all が必要です")
 	}
 
 	var lastError error
+	var jsonResults []jsonDevice
 	for _, device := range devices {
 		result, err := p.handler.GetProperties(device, cmd.EPCs, skipValidation)
-		if err == nil {
+		if err == nil && p.isJSONOutput(cmd) {
+			jsonResults = append(jsonResults, p.toJSONDevice(result.Device, result.Properties))
+		} else if err == nil {
 			fmt.Printf("プロパティ取得成功: %v\n", result.Device)
 			classCode := result.Device.EOJ.ClassCode()
 			for _, p := range result.Properties {
@@ -423,12 +529,20 @@ func (p *CommandProcessor) processGetCommand(cmd *Command) error {
 				fmt.Printf("  %v\n", propStr)
 			}
 		} else {
-			if lastError != nil {
+			if lastError != nil && p.isJSONOutput(cmd) {
+				fmt.Fprintln(os.Stderr, lastError) // JSON 出力を壊さないよう標準エラーへ
+			} else if lastError != nil {
 				fmt.Println(lastError)
 			}
 			lastError = err
 		}
 	}
+	if p.isJSONOutput(cmd) && jsonResults != nil {
+		// 取得できたデバイスの結果は、他のデバイスがエラーでも出力する
+		if err := printJSON(jsonResults); err != nil {
+			return err
+		}
+	}
 	return lastError
 }
 
@@ -452,7 +566,7 @@ func (p *CommandProcessor) processSetCommand(cmd *Command) error {
 
 	var lastError error
 	for _, device := range devices {
-		result, err := p.handler.SetProperties(device, cmd.Properties)
+		result, err := p.handler.SetProperties(device, cmd.Properties, nil)
 		if err == nil {
 			fmt.Printf("プロパティ設定成功: %v\n", result.Device)
 			classCode := result.Device.EOJ.ClassCode()
@@ -492,8 +606,6 @@ func (p *CommandProcessor) processDebugCommand(cmd *Command) error {
 	return nil
 }
 
-const historyDisplayLanguage = ""
-
 func (p *CommandProcessor) processHistoryCommand(cmd *Command) error {
 	device, err := p.getSingleDevice(cmd.DeviceSpec)
 	if err != nil {
@@ -511,7 +623,10 @@ func (p *CommandProcessor) processHistoryCommand(cmd *Command) error {
 		return nil
 	}
 
+	// 日本語のプロパティ名でも列が揃うよう、表示幅で揃える
+	table := newTextTable("TIME", "PROPERTY", "VALUE", "ORIGIN", "SETTABLE")
 	classCode := device.EOJ.ClassCode()
+	lang := echonet_lite.DisplayLanguage()
 	for _, entry := range entries {
 		timestamp := entry.Timestamp.Local().Format(time.RFC3339)
 
@@ -522,26 +637,25 @@ func (p *CommandProcessor) processHistoryCommand(cmd *Command) error {
 			// Display event entries differently
 			eventDescription := "Event"
 			if entry.Origin == protocol.HistoryOriginOnline {
-				if historyDisplayLanguage == "ja" {
+				if lang == "ja" {
 					eventDescription = "デバイスがオンラインになりました"
 				} else {
 					eventDescription = "Device came online"
 				}
 			} else if entry.Origin == protocol.HistoryOriginOffline {
-				if historyDisplayLanguage == "ja" {
+				if lang == "ja" {
 					eventDescription = "デバイスがオフラインになりました"
 				} else {
 					eventDescription = "Device went offline"
 				}
 			}
 
-			fmt.Printf("%s | %s | origin=%s\n",
-				timestamp, eventDescription, entry.Origin)
+			table.AddRow(timestamp, eventDescription, "", string(entry.Origin), "")
 		} else {
 			// Display property change entries
 			propLabel := fmt.Sprintf("EPC 0x%02X", byte(entry.EPC))
 			if desc, ok := p.handler.GetPropertyDesc(classCode, entry.EPC); ok && desc != nil {
-				propLabel = fmt.Sprintf("%s (0x%02X)", desc.GetName(historyDisplayLanguage), byte(entry.EPC))
+				propLabel = fmt.Sprintf("%s (0x%02X)", desc.GetName(lang), byte(entry.EPC))
 			}
 
 			valueStr := entry.Value.String
@@ -549,8 +663,9 @@ func (p *CommandProcessor) processHistoryCommand(cmd *Command) error {
 				valueStr = fmt.Sprintf("%d", *entry.Value.Number)
 			}
 			if valueStr == "" && entry.Value.EDT != "" {
+				// サーバーが文字列にしなかった値も、クライアントのプロパティ定義でデコードする（デコードできなければ16進数）
 				if decoded, err := base64.StdEncoding.DecodeString(entry.Value.EDT); err == nil {
-					valueStr = strings.ToUpper(hex.EncodeToString(decoded))
+					valueStr = client.Property{EPC: entry.EPC, EDT: decoded}.EDTString(classCode)
 				} else {
 					valueStr = entry.Value.EDT
 				}
@@ -564,10 +679,10 @@ func (p *CommandProcessor) processHistoryCommand(cmd *Command) error {
 				settableLabel = "settable"
 			}
 
-			fmt.Printf("%s | %s | value=%s | origin=%s | %s\n",
-				timestamp, propLabel, valueStr, entry.Origin, settableLabel)
+			table.AddRow(timestamp, propLabel, valueStr, string(entry.Origin), settableLabel)
 		}
 	}
+	table.Print(os.Stdout)
 
 	fmt.Printf("(%d entries)\n", len(entries))
 	return nil
@@ -611,6 +726,445 @@ func (p *CommandProcessor) processDebugOfflineCommand(cmd *Command) error {
 	return nil
 }
 
+// processCleanupCommand は、長い間更新のないデバイスを表示し、-y が指定された場合は削除する
+func (p *CommandProcessor) processCleanupCommand(cmd *Command) error {
+	devices, err := p.handler.CleanupDevices(cmd.UnseenDays, !cmd.Confirmed)
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		fmt.Printf("%d 日以上更新のないデバイスはありません\n", cmd.UnseenDays)
+		return nil
+	}
+
+	if cmd.Confirmed {
+		fmt.Printf("%d 台のデバイスを削除しました\n", len(devices))
+	} else {
+		fmt.Printf("%d 日以上更新のないデバイスが %d 台あります\n", cmd.UnseenDays, len(devices))
+	}
+	now := time.Now()
+	for _, d := range devices {
+		name := d.Device.String()
+		if aliases := p.handler.GetAliases(d.Device); len(aliases) > 0 {
+			name += " (" + strings.Join(aliases, ", ") + ")"
+		}
+		fmt.Printf("  %s 最終更新 %s（%d 日前）\n", name, d.LastSeen.Local().Format("2006-01-02 15:04"), int(now.Sub(d.LastSeen).Hours()/24))
+	}
+	if !cmd.Confirmed {
+		fmt.Printf("削除するには cleanup %d -y を実行してください\n", cmd.UnseenDays)
+	}
+	return nil
+}
+
+// processRefreshPropertyMapCommand は、デバイスのプロパティマップを取得し直し、Get プロパティマップの変化を表示する
+func (p *CommandProcessor) processRefreshPropertyMapCommand(cmd *Command) error {
+	device, err := p.getSingleDevice(cmd.DeviceSpec)
+	if err != nil {
+		return err
+	}
+
+	change, err := p.handler.RefreshPropertyMap(*device)
+	if err != nil {
+		return fmt.Errorf("プロパティマップの取得に失敗しました: %v", err)
+	}
+	if len(change.Added) == 0 && len(change.Removed) == 0 {
+		fmt.Printf("%v: プロパティマップに変化はありません\n", *device)
+		return nil
+	}
+
+	fmt.Printf("%v: プロパティマップが変化しました\n", *device)
+	classCode := device.EOJ.ClassCode()
+	for _, epc := range change.Added {
+		fmt.Printf("  + %s\n", epc.StringForClass(classCode))
+	}
+	for _, epc := range change.Removed {
+		fmt.Printf("  - %s\n", epc.StringForClass(classCode))
+	}
+	return nil
+}
+
+// processAirconCommand は、エアコンの運転モード・温度設定値・電源を設定し、状態を表示する
+// 運転モードと温度設定値を先に設定してから電源を入れる
+func (p *CommandProcessor) processAirconCommand(cmd *Command) error {
+	device, err := p.getSingleDevice(cmd.DeviceSpec)
+	if err != nil {
+		return err
+	}
+	aircon, err := client.NewAirConditioner(p.handler, *device)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Aircon.Mode != "" {
+		if err := aircon.SetMode(cmd.Aircon.Mode); err != nil {
+			return fmt.Errorf("運転モードの設定に失敗しました: %v", err)
+		}
+	}
+	if cmd.Aircon.Temperature != nil {
+		if err := aircon.SetTargetTemperature(*cmd.Aircon.Temperature); err != nil {
+			return fmt.Errorf("温度設定値の設定に失敗しました: %v", err)
+		}
+	}
+	if cmd.Aircon.Power != nil {
+		if err := aircon.SetPower(*cmd.Aircon.Power); err != nil {
+			return fmt.Errorf("電源の設定に失敗しました: %v", err)
+		}
+	}
+
+	state, err := aircon.State()
+	if err != nil {
+		return fmt.Errorf("状態の取得に失敗しました: %v", err)
+	}
+	fmt.Println(formatAirconState(*device, state))
+	return nil
+}
+
+// formatAirconState は、エアコンの状態を1行で表す。報告されなかった値は - とする
+func formatAirconState(device client.IPAndEOJ, state client.AirConditionerState) string {
+	value := func(v *int, unit string) string {
+		if v == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%d%s", *v, unit)
+	}
+	power := "-"
+	if state.Power != nil {
+		power = "off"
+		if *state.Power {
+			power = "on"
+		}
+	}
+	mode := "-"
+	if state.Mode != nil {
+		mode = string(*state.Mode)
+		if desc, ok := echonet_lite.GetPropertyDesc(echonet_lite.HomeAirConditioner_ClassCode, echonet_lite.EPC_HAC_OperationModeSetting); ok {
+			if translated, ok := desc.GetAliasTranslations(echonet_lite.DisplayLanguage())[mode]; ok {
+				mode = translated
+			}
+		}
+	}
+	return fmt.Sprintf("%v: 電源 %s, 運転モード %s, 温度設定値 %s, 室温 %s, 湿度 %s, 外気温 %s",
+		device, power, mode,
+		value(state.TargetTemperature, "℃"), value(state.RoomTemperature, "℃"),
+		value(state.RoomHumidity, "%"), value(state.OutsideTemperature, "℃"))
+}
+
+// processLightCommand は、照明の照度レベル・光色・電源を設定し、状態を表示する
+// クラスコードを省略した場合は、一般照明と単機能照明の中から対象のデバイスを探す
+func (p *CommandProcessor) processLightCommand(cmd *Command) error {
+	device, err := p.getSingleLighting(cmd.DeviceSpec)
+	if err != nil {
+		return err
+	}
+	light, err := client.NewLighting(p.handler, *device)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Light.Brightness != nil {
+		if err := light.SetBrightness(*cmd.Light.Brightness); err != nil {
+			return fmt.Errorf("照度レベルの設定に失敗しました: %v", err)
+		}
+	}
+	if cmd.Light.Color != "" {
+		if err := light.SetColorTemperature(cmd.Light.Color); err != nil {
+			return fmt.Errorf("光色の設定に失敗しました: %v", err)
+		}
+	}
+	if cmd.Light.Power != nil {
+		if err := light.SetPower(*cmd.Light.Power); err != nil {
+			return fmt.Errorf("電源の設定に失敗しました: %v", err)
+		}
+	}
+
+	state, err := light.State()
+	if err != nil {
+		return fmt.Errorf("状態の取得に失敗しました: %v", err)
+	}
+	fmt.Println(formatLightingState(*device, state))
+	return nil
+}
+
+// getSingleLighting は、照明のデバイスを1つだけ返す。クラスコードがない場合は照明のクラスに限って探す
+func (p *CommandProcessor) getSingleLighting(deviceSpec client.DeviceSpecifier) (*client.IPAndEOJ, error) {
+	if deviceSpec.ClassCode != nil {
+		return p.getSingleDevice(deviceSpec)
+	}
+	var filtered []client.IPAndEOJ
+	for _, classCode := range []client.EOJClassCode{echonet_lite.GeneralLighting_ClassCode, echonet_lite.SingleFunctionLighting_ClassCode} {
+		spec := deviceSpec
+		spec.ClassCode = &classCode
+		filtered = append(filtered, p.handler.GetDevices(spec)...)
+	}
+	switch len(filtered) {
+	case 0:
+		return nil, fmt.Errorf("照明のデバイス（0290, 0291）が見つかりません")
+	case 1:
+		return &filtered[0], nil
+	}
+	errMsg := []string{"照明のデバイスが複数見つかりました。エイリアスまたはIPアドレスを指定してください"}
+	for _, device := range filtered {
+		errMsg = append(errMsg, fmt.Sprintf("  %v", device))
+	}
+	return nil, errors.New(strings.Join(errMsg, "\n"))
+}
+
+// formatLightingState は、照明の状態を1行で表す。報告されなかった値は - とする
+func formatLightingState(device client.IPAndEOJ, state client.LightingState) string {
+	power := "-"
+	if state.Power != nil {
+		power = "off"
+		if *state.Power {
+			power = "on"
+		}
+	}
+	brightness := "-"
+	if state.Brightness != nil {
+		brightness = fmt.Sprintf("%d%%", *state.Brightness)
+	}
+	line := fmt.Sprintf("%v: 電源 %s, 照度レベル %s", device, power, brightness)
+	if client.SupportsLightColor(device.EOJ.ClassCode()) {
+		color := "-"
+		if state.Color != nil {
+			color = string(*state.Color)
+			if desc, ok := echonet_lite.GetPropertyDesc(device.EOJ.ClassCode(), echonet_lite.EPC_GL_LightColor); ok {
+				if translated, ok := desc.GetAliasTranslations(echonet_lite.DisplayLanguage())[color]; ok {
+					color = translated
+				}
+			}
+		}
+		line += fmt.Sprintf(", 光色 %s", color)
+	}
+	return line
+}
+
+// processLocalMapListCommand は、自ノードのデバイスのプロパティマップを表示する
+func (p *CommandProcessor) processLocalMapListCommand() error {
+	maps, err := p.handler.LocalPropertyMaps()
+	if err != nil {
+		return fmt.Errorf("プロパティマップの取得に失敗しました: %v", err)
+	}
+	for _, m := range maps {
+		fmt.Println(m.EOJ)
+		for _, t := range []client.PropertyMapType{handler.GetPropertyMap, handler.SetPropertyMap, handler.StatusAnnouncementPropertyMap} {
+			propertyMap := m.Get
+			switch t {
+			case handler.SetPropertyMap:
+				propertyMap = m.Set
+			case handler.StatusAnnouncementPropertyMap:
+				propertyMap = m.Announce
+			}
+			epcs := propertyMap.EPCs()
+			sort.Slice(epcs, func(i, j int) bool { return epcs[i] < epcs[j] })
+			names := make([]string, 0, len(epcs))
+			for _, epc := range epcs {
+				names = append(names, epc.StringForClass(m.EOJ.ClassCode()))
+			}
+			changed := ""
+			if slices.Contains(m.Overridden, t) {
+				changed = "（変更）"
+			}
+			fmt.Printf("  %-8s%s: %s\n", t, changed, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// processLocalMapChangeCommand は、自ノードのデバイスのプロパティマップを変更する
+func (p *CommandProcessor) processLocalMapChangeCommand(cmd *Command) error {
+	var epcs []client.EPCType
+	switch cmd.LocalMapAction {
+	case "set":
+		epcs = append([]client.EPCType{}, cmd.EPCs...)
+	case "add", "remove":
+		maps, err := p.handler.LocalPropertyMaps()
+		if err != nil {
+			return fmt.Errorf("プロパティマップの取得に失敗しました: %v", err)
+		}
+		index := slices.IndexFunc(maps, func(m client.LocalPropertyMap) bool { return m.EOJ == cmd.LocalEOJ })
+		if index < 0 {
+			return fmt.Errorf("自ノードにデバイス %v がありません", cmd.LocalEOJ)
+		}
+		current := maps[index].Get
+		switch cmd.LocalMapType {
+		case handler.SetPropertyMap:
+			current = maps[index].Set
+		case handler.StatusAnnouncementPropertyMap:
+			current = maps[index].Announce
+		}
+		propertyMap := make(handler.PropertyMap)
+		for _, epc := range current.EPCs() {
+			propertyMap.Set(epc)
+		}
+		for _, epc := range cmd.EPCs {
+			if cmd.LocalMapAction == "add" {
+				propertyMap.Set(epc)
+			} else {
+				propertyMap.Delete(epc)
+			}
+		}
+		epcs = propertyMap.EPCs()
+	}
+	if err := p.handler.SetLocalPropertyMap(cmd.LocalEOJ, cmd.LocalMapType, epcs); err != nil {
+		return err
+	}
+	if epcs == nil {
+		fmt.Printf("%v の %v プロパティマップを初期状態に戻しました\n", cmd.LocalEOJ, cmd.LocalMapType)
+	} else {
+		fmt.Printf("%v の %v プロパティマップを変更しました\n", cmd.LocalEOJ, cmd.LocalMapType)
+	}
+	return nil
+}
+
+// processPollExcludeListCommand は、定期更新から除外しているクラスとデバイスを表示する
+func (p *CommandProcessor) processPollExcludeListCommand() error {
+	exclusions, err := p.handler.PollExclusions()
+	if err != nil {
+		return fmt.Errorf("定期更新の除外の取得に失敗しました: %v", err)
+	}
+	if len(exclusions) == 0 {
+		fmt.Println("定期更新から除外しているクラス・デバイスはありません")
+		return nil
+	}
+	for _, target := range exclusions {
+		if classCode, err := handler.ParseEOJClassCodeString(target); err == nil && len(target) == 4 {
+			fmt.Printf("%s (%s)\n", target, classCode)
+		} else {
+			fmt.Println(target)
+		}
+	}
+	return nil
+}
+
+// processPollExcludeChangeCommand は、クラスまたはデバイスを定期更新から除外する、または除外をやめる
+func (p *CommandProcessor) processPollExcludeChangeCommand(cmd *Command) error {
+	if err := p.handler.SetPollExcluded(cmd.PollTarget, cmd.PollExcluded); err != nil {
+		return err
+	}
+	if cmd.PollExcluded {
+		fmt.Printf("%s を定期更新から除外しました（INF 通知は引き続き受け付けます）\n", cmd.PollTarget)
+	} else {
+		fmt.Printf("%s を定期更新の対象に戻しました\n", cmd.PollTarget)
+	}
+	return nil
+}
+
+// processPendingCommand は、応答を待っている Get/Set 要求をデバイスごとに表示する
+func (p *CommandProcessor) processPendingCommand(cmd *Command) error {
+	requests, err := p.handler.DebugPendingRequests()
+	if err != nil {
+		return fmt.Errorf("応答待ちの要求の取得に失敗しました: %v", err)
+	}
+
+	// デバイスが指定された場合は、一致するデバイスへの要求だけを表示する
+	if cmd.DeviceSpec.IP != nil || cmd.DeviceSpec.ClassCode != nil {
+		targets := make(map[string]bool)
+		for _, device := range p.handler.GetDevices(cmd.DeviceSpec) {
+			targets[device.Key()] = true
+		}
+		var filtered []client.PendingRequest
+		for _, req := range requests {
+			if targets[req.Device.Key()] {
+				filtered = append(filtered, req)
+			}
+		}
+		requests = filtered
+	}
+
+	if len(requests) == 0 {
+		fmt.Println("応答待ちの要求はありません")
+		return nil
+	}
+
+	byDevice := make(map[string][]client.PendingRequest)
+	var devices []client.IPAndEOJ
+	for _, req := range requests {
+		key := req.Device.Key()
+		if _, ok := byDevice[key]; !ok {
+			devices = append(devices, req.Device)
+		}
+		byDevice[key] = append(byDevice[key], req)
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Compare(devices[j]) < 0
+	})
+
+	now := time.Now()
+	for _, device := range devices {
+		aliases := p.handler.GetAliases(device)
+		if len(aliases) > 0 {
+			fmt.Printf("%v (%s)\n", device, strings.Join(aliases, ", "))
+		} else {
+			fmt.Printf("%v\n", device)
+		}
+		for _, req := range byDevice[device.Key()] {
+			epcs := make([]string, 0, len(req.EPCs))
+			for _, epc := range req.EPCs {
+				epcs = append(epcs, epc.String())
+			}
+			line := fmt.Sprintf("  #%d %v [%s] %s 再送 %d/%d 経過 %v",
+				req.ID, req.ESV, strings.Join(epcs, " "), req.State, req.Retries, req.MaxRetries,
+				now.Sub(req.StartTime).Round(100*time.Millisecond))
+			if !req.NextRetry.IsZero() {
+				line += fmt.Sprintf(" 次の再送まで %v", max(req.NextRetry.Sub(now), 0).Round(100*time.Millisecond))
+			}
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
+
+// processVersionsCommand は、デバイスごとの規格Version情報とメーカ・商品コードを表で表示する
+func (p *CommandProcessor) processVersionsCommand(cmd *Command) error {
+	versions := protocol.MakeDeviceVersions(p.handler.ListDevices(client.FilterCriteria{Device: cmd.DeviceSpec}))
+	if p.isJSONOutput(cmd) {
+		return printJSON(protocol.VersionsResponse{Devices: versions})
+	}
+	if len(versions) == 0 {
+		fmt.Println("デバイスが見つかりません")
+		return nil
+	}
+
+	orDash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	table := newTextTable("DEVICE", "ALIAS", "RELEASE", "MANUFACTURER", "PRODUCT")
+	counts := make(map[string]int)
+	for _, v := range versions {
+		device, err := handler.ParseDeviceIdentifier(v.Target)
+		alias := ""
+		if err == nil {
+			alias = strings.Join(p.handler.GetAliases(device), ", ")
+		}
+		release := "-"
+		if v.Release != "" {
+			release = fmt.Sprintf("%s Rev.%d", v.Release, v.Revision)
+		}
+		manufacturer := orDash(v.ManufacturerJa)
+		if manufacturer == "-" {
+			manufacturer = orDash(v.ManufacturerCode)
+		}
+		table.AddRow(v.Target+" "+v.ClassName, orDash(alias), release, manufacturer, orDash(v.ProductCode))
+		counts[orDash(v.Release)]++
+	}
+	table.Print(os.Stdout)
+
+	releases := make([]string, 0, len(counts))
+	for release := range counts {
+		releases = append(releases, release)
+	}
+	sort.Strings(releases)
+	summary := make([]string, 0, len(releases))
+	for _, release := range releases {
+		summary = append(summary, fmt.Sprintf("%s: %d", release, counts[release]))
+	}
+	fmt.Printf("Release別: %s\n", strings.Join(summary, ", "))
+	return nil
+}
+
 func (p *CommandProcessor) processUpdateCommand(cmd *Command) error {
 	// グループが指定されている場合
 	if cmd.GroupName != nil {
@@ -736,6 +1290,183 @@ func (p *CommandProcessor) processGroupListCommand(cmd *Command) error {
 	return nil
 }
 
+// sceneActionsFromCommand は、コマンドのデバイス（またはグループ）とプロパティからシーンのアクションを作成する
+func (p *CommandProcessor) sceneActionsFromCommand(cmd *Command) ([]client.SceneAction, error) {
+	devices, err := p.getGroupDevices(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if devices == nil {
+		// 通常のデバイス指定の場合
+		device, err := p.getSingleDevice(cmd.DeviceSpec)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, *device)
+	}
+
+	actions := make([]client.SceneAction, 0, len(devices)*len(cmd.Properties))
+	for _, device := range devices {
+		ids := p.handler.GetIDString(device)
+		if ids == "" {
+			return nil, fmt.Errorf("デバイスのIDが取得できません: %v", device)
+		}
+		for _, prop := range cmd.Properties {
+			actions = append(actions, client.SceneAction{Device: ids, EPC: prop.EPC, EDT: prop.EDT})
+		}
+	}
+	return actions, nil
+}
+
+func (p *CommandProcessor) processSceneAddCommand(cmd *Command) error {
+	actions, err := p.sceneActionsFromCommand(cmd)
+	if err != nil {
+		return err
+	}
+
+	err = p.handler.SceneAdd(*cmd.SceneName, actions)
+	if err == nil {
+		fmt.Printf("シーン %s にプロパティを追加しました\n", *cmd.SceneName)
+	}
+	return err
+}
+
+func (p *CommandProcessor) processSceneListCommand(cmd *Command) error {
+	scenes := p.handler.SceneList(cmd.SceneName)
+	if len(scenes) == 0 {
+		if cmd.SceneName != nil {
+			return fmt.Errorf("シーン %s が見つかりません", *cmd.SceneName)
+		}
+		fmt.Println("シーンが登録されていません")
+		return nil
+	}
+
+	for _, scene := range scenes {
+		fmt.Printf("%s: %d プロパティ\n", scene.Scene, len(scene.Actions))
+		if !scene.Sequence.IsZero() {
+			fmt.Printf("  順次実行: 間隔 %v\n", scene.Sequence.Delay())
+		}
+		for _, action := range scene.Actions {
+			device := p.handler.FindDeviceByIDString(action.Device)
+			prop := client.Property{EPC: action.EPC, EDT: action.EDT}
+			if device == nil {
+				fmt.Printf("  %s (未検出): %v\n", action.Device, prop.String(0))
+				continue
+			}
+			fmt.Printf("  %v: %v\n", *device, prop.String(device.EOJ.ClassCode()))
+		}
+	}
+	return nil
+}
+
+// processSceneSequenceCommand は、シーン実行時にデバイスを設定する間隔を設定または解除する
+// WebSocket API で設定したデバイスの順序は、間隔を変更しても保持する
+func (p *CommandProcessor) processSceneSequenceCommand(cmd *Command) error {
+	if _, ok := p.handler.GetScene(*cmd.SceneName); !ok {
+		return fmt.Errorf("シーン %s が見つかりません", *cmd.SceneName)
+	}
+
+	var sequence client.SceneSequence
+	if cmd.SceneDelay > 0 {
+		sequence = p.handler.GetSceneSequence(*cmd.SceneName)
+		sequence.DelayMs = int(cmd.SceneDelay / time.Millisecond)
+	}
+	if err := p.handler.SceneSetSequence(*cmd.SceneName, sequence); err != nil {
+		return err
+	}
+	if sequence.IsZero() {
+		fmt.Printf("シーン %s のデバイスを並列に設定します\n", *cmd.SceneName)
+	} else {
+		fmt.Printf("シーン %s のデバイスを %v の間隔で順に設定します\n", *cmd.SceneName, sequence.Delay())
+	}
+	return nil
+}
+
+func (p *CommandProcessor) processSceneRunCommand(cmd *Command) error {
+	results, err := p.handler.SceneRun(*cmd.SceneName)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Printf("プロパティ設定失敗: %s: %v\n", result.ID, result.Err)
+			continue
+		}
+		fmt.Printf("プロパティ設定成功: %v\n", result.Result.Device)
+		classCode := result.Result.Device.EOJ.ClassCode()
+		for _, prop := range result.Result.Properties {
+			fmt.Printf("  %v\n", prop.String(classCode))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("シーン %s: %d/%d デバイスの設定に失敗しました", *cmd.SceneName, failed, len(results))
+	}
+	fmt.Printf("シーン %s を実行しました\n", *cmd.SceneName)
+	return nil
+}
+
+func (p *CommandProcessor) processScheduleAddCommand(cmd *Command) error {
+	schedule := client.Schedule{
+		Name: *cmd.ScheduleName,
+		Cron: cmd.ScheduleCron,
+	}
+	if cmd.SceneName != nil {
+		if _, ok := p.handler.GetScene(*cmd.SceneName); !ok {
+			return fmt.Errorf("シーン %s が見つかりません", *cmd.SceneName)
+		}
+		schedule.Scene = *cmd.SceneName
+	} else {
+		actions, err := p.sceneActionsFromCommand(cmd)
+		if err != nil {
+			return err
+		}
+		schedule.Actions = actions
+	}
+
+	if err := p.handler.ScheduleSet(schedule); err != nil {
+		return err
+	}
+	fmt.Printf("スケジュール %s を登録しました\n", schedule.Name)
+	return nil
+}
+
+func (p *CommandProcessor) processScheduleListCommand() error {
+	schedules := p.handler.ScheduleList()
+	if len(schedules) == 0 {
+		fmt.Println("スケジュールが登録されていません")
+		return nil
+	}
+
+	now := time.Now()
+	for _, schedule := range schedules {
+		next := "無効"
+		if !schedule.Disabled {
+			if t := p.handler.ScheduleNextRun(schedule, now); !t.IsZero() {
+				next = "次回 " + t.Format("2006-01-02 15:04")
+			} else {
+				next = "次回なし"
+			}
+		}
+		fmt.Printf("%s: %s (%s)\n", schedule.Name, schedule.Cron, next)
+		if schedule.Scene != "" {
+			fmt.Printf("  シーン %s\n", schedule.Scene)
+		}
+		for _, action := range schedule.Actions {
+			device := p.handler.FindDeviceByIDString(action.Device)
+			prop := client.Property{EPC: action.EPC, EDT: action.EDT}
+			if device == nil {
+				fmt.Printf("  %s (未検出): %v\n", action.Device, prop.String(0))
+				continue
+			}
+			fmt.Printf("  %v: %v\n", *device, prop.String(device.EOJ.ClassCode()))
+		}
+	}
+	return nil
+}
+
 // processLocationListCommand は、設置場所の一覧を表示する
 func (p *CommandProcessor) processLocationListCommand() error {
 	aliases, order := p.handler.GetLocationSettings()
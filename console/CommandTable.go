@@ -2,11 +2,14 @@ package console
 
 import (
 	"echonet-list/client"
+	"echonet-list/echonet_lite"
 	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
 	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/c-bata/go-prompt"
 	"golang.org/x/exp/slices"
@@ -27,23 +30,42 @@ type CommandDefinition struct {
 // コマンドの使用法に変化があったときは、README.md も更新すること
 const defaultHistoryLimit = 50
 
+// defaultCSVHistoryLimit は、export-csv history でデバイスごとに取得する履歴件数の既定値
+const defaultCSVHistoryLimit = 1000
+
 var CommandTable = []CommandDefinition{
 	{
 		Name:    "discover",
 		Summary: "ECHONET Lite デバイスの検出",
-		Syntax:  "discover",
+		Syntax:  "discover [-o json]",
 		Description: []string{
 			"ネットワーク上のECHONET Liteデバイスを検出します。",
+			"-o json: 検出後のデバイス一覧を JSON で出力",
+		},
+		GetCandidatesFunc: func(c client.ECHONETListClient, d prompt.Document) []prompt.Suggest {
+			if suggestions := getOutputOptionCandidates(d); len(suggestions) > 0 {
+				return suggestions
+			}
+			return []prompt.Suggest{{Text: "-o", Description: "出力形式を指定"}}
 		},
 		ParseFunc: func(p CommandParser, parts []string, debug bool) (*Command, error) {
-			return newCommand(CmdDiscover), nil
+			parts, output, err := parseOutputOption(parts)
+			if err != nil {
+				return nil, err
+			}
+			if len(parts) > 1 {
+				return nil, &InvalidArgument{Argument: parts[1]}
+			}
+			cmd := newCommand(CmdDiscover)
+			cmd.Output = output
+			return cmd, nil
 		},
 	},
 	{
 		Name:    "devices",
 		Aliases: []string{"list"},
 		Summary: "検出されたECHONET Liteデバイスの一覧表示",
-		Syntax:  "devices, list [ipAddress] [classCode[:instanceCode]] [-all|-props] [epc1 epc2...] [-group-by epc]",
+		Syntax:  "devices, list [ipAddress] [classCode[:instanceCode]] [-all|-props] [epc1 epc2...] [-group-by epc] [-o json]",
 		Description: []string{
 			"ipAddress: IPアドレスでフィルター（例: 192.168.0.212 または IPv6アドレス）",
 			"classCode: クラスコード（4桁の16進数、例: 0130）",
@@ -52,6 +74,7 @@ var CommandTable = []CommandDefinition{
 			"-props: 既知のEPCのみを表示",
 			"epc: 2桁の16進数で指定（例: 80）。複数指定可能",
 			"-group-by epc: 指定したEPCの値でデバイスをグループ化して表示（例: -group-by 80）",
+			"-o json: 結果を JSON で出力（-group-by とは併用不可）",
 			"※-all, -props, epc は最後に指定されたものが有効になります",
 		},
 		GetCandidatesFunc: func(c client.ECHONETListClient, d prompt.Document) []prompt.Suggest {
@@ -60,11 +83,15 @@ var CommandTable = []CommandDefinition{
 				// EPCを要求するが、サジェストはしない
 				return []prompt.Suggest{}
 			}
+			if suggestions := getOutputOptionCandidates(d); len(suggestions) > 0 {
+				return suggestions
+			}
 
 			suggestions := []prompt.Suggest{
 				{Text: "-all", Description: "全てのEPCを表示"},
 				{Text: "-props", Description: "既知のEPCのみを表示"},
 				{Text: "-group-by", Description: "指定EPCでグループ化"},
+				{Text: "-o", Description: "出力形式を指定"},
 			}
 			suggestions = append(suggestions, getDeviceCandidates(c)...)
 			suggestions = append(suggestions, getPropertyAliasCandidates(c)...)
@@ -72,6 +99,11 @@ var CommandTable = []CommandDefinition{
 		},
 		ParseFunc: func(p CommandParser, parts []string, debug bool) (*Command, error) {
 			cmd := newCommand(CmdDevices)
+			parts, output, err := parseOutputOption(parts)
+			if err != nil {
+				return nil, err
+			}
+			cmd.Output = output
 
 			// デバイス識別子のパース
 			deviceSpec, groupName, argIndex, err := p.parseDeviceSpecifierOrGroup(parts, 1, false)
@@ -131,13 +163,15 @@ var CommandTable = []CommandDefinition{
 	{
 		Name:    "get",
 		Summary: "プロパティ値の取得",
-		Syntax:  "get [ipAddress] classCode[:instanceCode] epc1 [epc2...] [-skip-validation]",
+		Syntax:  "get [ipAddress] classCode[:instanceCode] (epc1 [epc2...] | -all) [-skip-validation] [-o json]",
 		Description: []string{
 			"ipAddress: 対象デバイスのIPアドレス（省略可能、省略時はクラスコードに一致するデバイスが1つだけの場合に自動選択）",
 			"classCode: クラスコード（4桁の16進数、必須）",
 			"instanceCode: インスタンスコード（1-255の数字、省略時は1）",
 			"epc: 取得するプロパティのEPC（2桁の16進数、例: 80）。複数指定可能",
+			"-all: GetPropertyMap を読み、取得可能なすべてのプロパティを取得（EPCとは同時に指定できない）",
 			"-skip-validation: デバイスの存在チェックをスキップ（タイムアウト動作確認用）",
+			"-o json: 結果を JSON で出力",
 		},
 		GetCandidatesFunc: func(c client.ECHONETListClient, d prompt.Document) []prompt.Suggest {
 			words := splitWords(d.TextBeforeCursor())
@@ -145,14 +179,24 @@ var CommandTable = []CommandDefinition{
 
 			if wordCount <= 2 { // コマンド名 or デバイス指定子
 				return getDeviceCandidates(c)
+			} else if suggestions := getOutputOptionCandidates(d); len(suggestions) > 0 {
+				return suggestions
 			} else { // EPC or プロパティエイリアス or オプション
 				suggestions := getPropertyAliasCandidates(c)
-				suggestions = append(suggestions, prompt.Suggest{Text: "-skip-validation"})
+				suggestions = append(suggestions,
+					prompt.Suggest{Text: "-all", Description: "取得可能なすべてのプロパティを取得"},
+					prompt.Suggest{Text: "-skip-validation"},
+					prompt.Suggest{Text: "-o", Description: "出力形式を指定"})
 				return suggestions
 			}
 		},
 		ParseFunc: func(p CommandParser, parts []string, debug bool) (*Command, error) {
 			cmd := newCommand(CmdGet)
+			parts, output, err := parseOutputOption(parts)
+			if err != nil {
+				return nil, err
+			}
+			cmd.Output = output
 
 			// デバイス識別子またはグループ名のパース
 			deviceSpec, groupName, argIndex, err := p.parseDeviceSpecifierOrGroup(parts, 1, true)
@@ -163,21 +207,27 @@ var CommandTable = []CommandDefinition{
 			cmd.GroupName = groupName
 
 			// EPCのパース
-			if argIndex >= len(parts) {
-				return nil, fmt.Errorf("get コマンドには少なくとも1つのEPCが必要です")
-			}
-
 			for i := argIndex; i < len(parts); i++ {
 				if parts[i] == "-skip-validation" {
 					cmd.DebugMode = &parts[i]
 					continue
 				}
+				if parts[i] == "-all" {
+					cmd.AllProperties = true
+					continue
+				}
 				epc, err := parseEPC(parts[i])
 				if err != nil {
 					return nil, err
 				}
 				cmd.EPCs = append(cmd.EPCs, epc)
 			}
+			if cmd.AllProperties && len(cmd.EPCs) > 0 {
+				return nil, fmt.Errorf("get コマンドの -all とEPCは同時に指定できません")
+			}
+			if !cmd.AllProperties && len(cmd.EPCs) == 0 {
+				return nil, fmt.Errorf("get コマンドには少なくとも1つのEPCか -all が必要です")
+			}
 
 			return cmd, nil
 		},
@@ -185,18 +235,19 @@ var CommandTable = []CommandDefinition{
 	{
 		Name:    "history",
 		Summary: "デバイスの履歴を表示",
-		Syntax:  "history [ipAddress] classCode[:instanceCode] [-limit N] [-since RFC3339] [-all]",
+		Syntax:  "history [ipAddress] classCode[:instanceCode] [epc] [-limit N] [-since duration|RFC3339] [-all]",
 		Description: []string{
-			"デバイスの操作履歴を新しい順に表示します。",
+			"デバイスの操作履歴を新しい順に表で表示します。",
 			"ipAddress/classCode[:instanceCode]: 対象デバイスの指定（エイリアス指定も可）",
+			"epc: 指定したプロパティの履歴のみ表示（2桁の16進数、例: BB。書き込み不可のプロパティも含む）",
 			fmt.Sprintf("-limit N: 取得する履歴件数の上限（既定 %d）", defaultHistoryLimit),
-			"-since RFC3339: 指定した時刻以降の履歴のみ表示（例: 2024-05-01T12:00:00Z）",
+			"-since duration|RFC3339: 指定した期間内（例: 24h）または時刻以降（例: 2024-05-01T12:00:00Z）の履歴のみ表示",
 			"-all: Set Property Map に含まれない通知も表示（既定では書き込み可能なプロパティのみ）",
 		},
 		GetCandidatesFunc: func(c client.ECHONETListClient, d prompt.Document) []prompt.Suggest {
 			suggestions := []prompt.Suggest{
 				{Text: "-limit", Description: "取得件数の上限を指定"},
-				{Text: "-since", Description: "この時刻以降の履歴を取得"},
+				{Text: "-since", Description: "この期間内（例: 24h）または時刻以降の履歴を取得"},
 				{Text: "-all", Description: "すべての履歴（センサー値など）を含める"},
 			}
 			suggestions = append(suggestions, getDeviceCandidates(c)...)
@@ -218,8 +269,28 @@ var CommandTable = []CommandDefinition{
 				return nil, fmt.Errorf("history コマンドにはデバイスの指定が必要です")
 			}
 
+			// オプションでない引数は EPC として扱う
+			if argIndex < len(parts) && !strings.HasPrefix(parts[argIndex], "-") {
+				epc, err := parseEPC(parts[argIndex])
+				if err != nil {
+					return nil, err
+				}
+				cmd.HistoryOptions.EPC = epc
+				argIndex++
+			}
+
 			for argIndex < len(parts) {
 				switch parts[argIndex] {
+				case "-since":
+					if argIndex+1 >= len(parts) {
+						return nil, fmt.Errorf("-since オプションには期間または時刻が必要です")
+					}
+					since, err := parseHistorySince(parts[argIndex+1], time.Now())
+					if err != nil {
+						return nil, err
+					}
+					cmd.HistoryOptions.Since = since
+					argIndex += 2
 				case "-limit":
 					if argIndex+1 >= len(parts) {
 						return nil, fmt.Errorf("-limit オプションには数値が必要です")
@@ -242,6 +313,112 @@ var CommandTable = []CommandDefinition{
 			if cmd.HistoryOptions.Limit == 0 {
 				cmd.HistoryOptions.Limit = defaultHistoryLimit
 			}
+			// プロパティを指定した場合は、センサー値などの書き込み不可のプロパティも対象にする
+			if cmd.HistoryOptions.EPC != 0 && cmd.HistoryOptions.SettableOnly == nil {
+				settable := false
+				cmd.HistoryOptions.SettableOnly = &settable
+			}
+			return cmd, nil
+		},
+	},
+	{
+		Name:    "export-csv",
+		Summary: "デバイス一覧または履歴を CSV ファイルに書き出す",
+		Syntax:  "export-csv devices|history [ipAddress] [classCode[:instanceCode]] [-limit N] [-file name]",
+		Description: []string{
+			"Excel などで分析できるよう、UTF-8（BOM付き）の CSV ファイルに書き出します。",
+			"devices: デバイスごとの現在のプロパティ値（1行に1プロパティ）",
+			"history: デバイスの履歴を古い順に（1行に1件）",
+			"ipAddress/classCode[:instanceCode]: 対象デバイスの指定（エイリアス指定も可。省略時はすべてのデバイス）",
+			fmt.Sprintf("-limit N: history でデバイスごとに取得する件数の上限（既定 %d）", defaultCSVHistoryLimit),
+			"-file name: 出力先のファイル名（省略時は echonet-devices-20060102-150405.csv のような名前）",
+		},
+		GetCandidatesFunc: func(c client.ECHONETListClient, d prompt.Document) []prompt.Suggest {
+			suggestions := []prompt.Suggest{
+				{Text: "devices", Description: "現在のプロパティ値を書き出す"},
+				{Text: "history", Description: "履歴を書き出す"},
+				{Text: "-limit", Description: "取得件数の上限を指定"},
+				{Text: "-file", Description: "出力先のファイル名を指定"},
+			}
+			return append(suggestions, getDeviceCandidates(c)...)
+		},
+		ParseFunc: func(p CommandParser, parts []string, debug bool) (*Command, error) {
+			cmd := newCommand(CmdExportCSV)
+			if len(parts) < 2 {
+				return nil, fmt.Errorf("export-csv コマンドには devices または history の指定が必要です")
+			}
+			switch protocol.CSVExportKind(parts[1]) {
+			case protocol.CSVExportDevices, protocol.CSVExportHistory:
+				cmd.ExportKind = protocol.CSVExportKind(parts[1])
+			default:
+				return nil, fmt.Errorf("export-csv コマンドには devices または history を指定してください: %s", parts[1])
+			}
+
+			deviceSpec, groupName, argIndex, err := p.parseDeviceSpecifierOrGroup(parts, 2, false)
+			if err != nil {
+				return nil, err
+			}
+			if groupName != nil {
+				return nil, fmt.Errorf("export-csv コマンドはグループ指定に対応していません")
+			}
+			cmd.DeviceSpec = deviceSpec
+
+			settable := false
+			cmd.HistoryOptions = client.DeviceHistoryOptions{Limit: defaultCSVHistoryLimit, SettableOnly: &settable}
+			for argIndex < len(parts) {
+				switch parts[argIndex] {
+				case "-limit":
+					if argIndex+1 >= len(parts) {
+						return nil, fmt.Errorf("-limit オプションには数値が必要です")
+					}
+					value, err := strconv.Atoi(parts[argIndex+1])
+					if err != nil || value <= 0 {
+						return nil, fmt.Errorf("-limit には1以上の整数を指定してください")
+					}
+					cmd.HistoryOptions.Limit = value
+					argIndex += 2
+				case "-file":
+					if argIndex+1 >= len(parts) {
+						return nil, fmt.Errorf("-file オプションにはファイル名が必要です")
+					}
+					cmd.OutputFile = parts[argIndex+1]
+					argIndex += 2
+				default:
+					return nil, &InvalidArgument{Argument: parts[argIndex]}
+				}
+			}
+			return cmd, nil
+		},
+	},
+	{
+		Name:    "watch",
+		Summary: "プロパティの変化をリアルタイムに表示",
+		Syntax:  "watch [ipAddress] [classCode[:instanceCode]] [epc1 epc2...]",
+		Description: []string{
+			"デバイスから通知されたプロパティの変化を、値を解釈して表示し続けます。Enter, q, Ctrl-C で終了します。",
+			"ipAddress/classCode[:instanceCode]: 表示するデバイスの指定（エイリアス、@グループ名も可。省略時はすべてのデバイス）",
+			"epc: 表示するプロパティを2桁の16進数で指定（例: 80）。複数指定可能",
+		},
+		GetCandidatesFunc: func(c client.ECHONETListClient, d prompt.Document) []prompt.Suggest {
+			return getDeviceCandidates(c)
+		},
+		ParseFunc: func(p CommandParser, parts []string, debug bool) (*Command, error) {
+			cmd := newCommand(CmdWatch)
+
+			deviceSpec, groupName, argIndex, err := p.parseDeviceSpecifierOrGroup(parts, 1, false)
+			if err != nil {
+				return nil, err
+			}
+			cmd.DeviceSpec = deviceSpec
+			cmd.GroupName = groupName
+
+			for _, part := range parts[argIndex:] {
+				epc, err := parseEPC(part)
+				if err != nil {
+					return nil, &InvalidArgument{Argument: part}
+				}
+				cmd.EPCs = append(cmd.EPCs, epc)
+			}
 			return cmd, nil
 		},
 	},
@@ -255,8 +432,8 @@ var CommandTable = []CommandDefinition{
 			"instanceCode: インスタンスコード（1-255の数字、省略時は1）",
 			"property: 以下のいずれかの形式",
 			"  - EPC:EDT（例: 80:30）",
-			"    EPC: 2桁の16進数",
-			"    EDT: 2桁の16進数の倍数またはエイリアス名",
+			"    EPC: 2桁の16進数またはプロパティ名（例: temperature-setting、一意に決まれば temperature のように前方一致でも可）",
+			"    EDT: 2桁の16進数の倍数、エイリアス名または値（例: 25）",
 			"  - EPC（例: 80）- 利用可能なエイリアスを表示",
 			"  - エイリアス名（例: on）- 対応するEPC:EDTに自動展開",
 			"  - 80:on（OperationStatus{true}と同等）",
@@ -269,8 +446,18 @@ var CommandTable = []CommandDefinition{
 
 			if wordCount <= 2 { // コマンド名 or デバイス指定子
 				return getDeviceCandidates(c)
-			} else { // プロパティ指定 (EPC:EDT or Alias)
-				lastWord := words[len(words)-1]
+			}
+
+			// 入力済みのデバイス指定子から対象のクラスを決める
+			parser := NewCommandParser(c, c, c)
+			deviceSpec, groupName, _, err := parser.parseDeviceSpecifierOrGroup(words[:wordCount-1], 1, true)
+			if err != nil {
+				// IPアドレスの次のクラスコードなど、まだデバイス指定子の途中
+				return getDeviceCandidates(c)
+			}
+			if groupName != nil || deviceSpec.ClassCode == nil {
+				// クラスが決まらない場合は、全クラスのエイリアスを候補にする
+				lastWord := words[wordCount-1]
 				parts := strings.Split(lastWord, ":")
 				if len(parts) == 2 {
 					if epc, err := parseEPC(parts[0]); err == nil {
@@ -280,6 +467,8 @@ var CommandTable = []CommandDefinition{
 				}
 				return getPropertyAliasCandidates(c)
 			}
+			// プロパティ指定 (EPC:EDT, 名前:値 or Alias)
+			return getClassPropertyCandidates(c, *deviceSpec.ClassCode, words[wordCount-1])
 		},
 		ParseFunc: func(p CommandParser, parts []string, debug bool) (*Command, error) {
 			cmd := newCommand(CmdSet)
@@ -376,6 +565,226 @@ var CommandTable = []CommandDefinition{
 			return cmd, nil
 		},
 	},
+	{
+		Name:    "cleanup",
+		Summary: "長い間更新のないデバイスを削除",
+		Syntax:  "cleanup <days> [-y]",
+		Description: []string{
+			"days: この日数以上プロパティが更新されていないデバイスを対象にする",
+			"引数が days だけの場合: 削除せずに対象のデバイスを表示（dry run）",
+			"-y: 表示されたデバイスを削除する",
+			"エイリアスやグループの設定は残るため、同じデバイスが再び見つかれば元の名前で使えます",
+			"例: cleanup 30",
+			"例: cleanup 30 -y",
+		},
+		GetCandidatesFunc: func(c client.ECHONETListClient, d prompt.Document) []prompt.Suggest {
+			if len(splitWords(d.TextBeforeCursor())) == 3 {
+				return []prompt.Suggest{{Text: "-y", Description: "対象のデバイスを削除する"}}
+			}
+			return nil
+		},
+		ParseFunc: func(p CommandParser, parts []string, debug bool) (*Command, error) {
+			cmd := newCommand(CmdCleanup)
+			for _, arg := range parts[1:] {
+				if arg == "-y" {
+					cmd.Confirmed = true
+					continue
+				}
+				if cmd.UnseenDays != 0 {
+					return nil, fmt.Errorf("不明な引数です: %s", arg)
+				}
+				days, err := strconv.Atoi(strings.TrimSuffix(arg, "d"))
+				if err != nil || days <= 0 {
+					return nil, fmt.Errorf("日数は正の整数で指定してください: %s", arg)
+				}
+				cmd.UnseenDays = days
+			}
+			if cmd.UnseenDays == 0 {
+				return nil, fmt.Errorf("cleanup コマンドには日数が必要です")
+			}
+			return cmd, nil
+		},
+	},
+	{
+		Name:    "refresh-propertymap",
+		Summary: "デバイスのプロパティマップを取得し直す",
+		Syntax:  "refresh-propertymap [ipAddress] classCode[:instanceCode]",
+		Description: []string{
+			"プロパティマップ（9D, 9E, 9F）を取得し直し、新しい Get プロパティマップのすべてのプロパティを取得します。",
+			"ファームウェアの更新などでプロパティが増減した機器に使います。",
+			"プロパティマップにないプロパティが通知された場合は、自動的に取得し直します。",
+			"ipAddress/classCode[:instanceCode]: 対象デバイスの指定（エイリアス指定も可）",
+		},
+		GetCandidatesFunc: func(c client.ECHONETListClient, d prompt.Document) []prompt.Suggest {
+			if len(splitWords(d.TextBeforeCursor())) <= 2 {
+				return getDeviceCandidates(c)
+			}
+			return nil
+		},
+		ParseFunc: func(p CommandParser, parts []string, debug bool) (*Command, error) {
+			cmd := newCommand(CmdRefreshPropertyMap)
+
+			deviceSpec, groupName, argIndex, err := p.parseDeviceSpecifierOrGroup(parts, 1, true)
+			if err != nil {
+				return nil, err
+			}
+			if groupName != nil {
+				return nil, fmt.Errorf("refresh-propertymap コマンドはグループ指定に対応していません")
+			}
+			if argIndex < len(parts) {
+				return nil, &InvalidArgument{Argument: parts[argIndex]}
+			}
+			cmd.DeviceSpec = deviceSpec
+			return cmd, nil
+		},
+	},
+	{
+		Name:    "aircon",
+		Summary: "エアコンの状態の表示と操作",
+		Syntax:  "aircon [ipAddress] [0130[:instanceCode]] [on|off] [auto|cool|heat|dry|fan] [temperature]",
+		Description: []string{
+			"家庭用エアコン（0130）の電源・運転モード・温度設定値を、EPCやEDTを指定せずに操作します。",
+			"操作のあとに、電源・運転モード・温度設定値・室温・湿度・外気温を表示します。",
+			"ipAddress/classCode[:instanceCode]: 対象デバイスの指定（エイリアス指定も可、クラスコードを省略すると 0130）",
+			"on/off: 電源を入/切する",
+			"auto/cool/heat/dry/fan: 運転モードを設定する",
+			fmt.Sprintf("temperature: 温度設定値（%d-%d℃、例: 26）", client.MinAirConditionerTemperature, client.MaxAirConditionerTemperature),
+			"例: aircon ac - エイリアス ac のエアコンの状態を表示",
+			"例: aircon ac cool 26 on - 冷房 26℃ で運転",
+		},
+		GetCandidatesFunc: func(c client.ECHONETListClient, d prompt.Document) []prompt.Suggest {
+			suggestions := []prompt.Suggest{
+				{Text: "on", Description: "電源を入れる"},
+				{Text: "off", Description: "電源を切る"},
+				{Text: "auto", Description: "自動運転"},
+				{Text: "cool", Description: "冷房"},
+				{Text: "heat", Description: "暖房"},
+				{Text: "dry", Description: "除湿"},
+				{Text: "fan", Description: "送風"},
+			}
+			if len(splitWords(d.TextBeforeCursor())) <= 2 {
+				suggestions = append(suggestions, getDeviceCandidates(c)...)
+			}
+			return suggestions
+		},
+		ParseFunc: func(p CommandParser, parts []string, debug bool) (*Command, error) {
+			cmd := newCommand(CmdAircon)
+
+			deviceSpec, groupName, argIndex, err := p.parseDeviceSpecifierOrGroup(parts, 1, false)
+			if err != nil {
+				return nil, err
+			}
+			if groupName != nil {
+				return nil, fmt.Errorf("aircon コマンドはグループ指定に対応していません")
+			}
+			if deviceSpec.ClassCode == nil {
+				classCode := echonet_lite.HomeAirConditioner_ClassCode
+				deviceSpec.ClassCode = &classCode
+			} else if *deviceSpec.ClassCode != echonet_lite.HomeAirConditioner_ClassCode {
+				return nil, fmt.Errorf("aircon コマンドは家庭用エアコン（0130）のみ対象です")
+			}
+			cmd.DeviceSpec = deviceSpec
+
+			for _, arg := range parts[argIndex:] {
+				switch arg {
+				case "on", "off":
+					on := arg == "on"
+					cmd.Aircon.Power = &on
+					continue
+				}
+				if temperature, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSuffix(arg, "℃"), "C")); err == nil {
+					if temperature < client.MinAirConditionerTemperature || temperature > client.MaxAirConditionerTemperature {
+						return nil, fmt.Errorf("温度設定値は %d から %d の範囲で指定してください: %s", client.MinAirConditionerTemperature, client.MaxAirConditionerTemperature, arg)
+					}
+					cmd.Aircon.Temperature = &temperature
+					continue
+				}
+				mode, err := client.ParseAirConditionerMode(arg)
+				if err != nil {
+					return nil, &InvalidArgument{Argument: arg}
+				}
+				cmd.Aircon.Mode = mode
+			}
+			return cmd, nil
+		},
+	},
+	{
+		Name:    "light",
+		Summary: "照明の状態の表示と操作",
+		Syntax:  "light [ipAddress] [0290|0291[:instanceCode]] [on|off] [brightness%] [incandescent|white|daylight_white|daylight]",
+		Description: []string{
+			"一般照明（0290）と単機能照明（0291）の電源・照度レベル・光色を、EPCやEDTを指定せずに操作します。",
+			"操作のあとに、電源・照度レベル・光色（一般照明のみ）を表示します。",
+			"ipAddress/classCode[:instanceCode]: 対象デバイスの指定（エイリアス指定も可、クラスコードを省略すると一般照明と単機能照明から探す）",
+			"on/off: 電源を入/切する",
+			"brightness%: 照度レベル（範囲はクラスのプロパティ定義による、例: 50%）",
+			"incandescent/white/daylight_white/daylight: 光色（色温度）を設定する（warm は電球色、cool は昼光色）。単機能照明は対応していません",
+			"例: light desk - エイリアス desk の照明の状態を表示",
+			"例: light living 30% warm on - 電球色・照度30%で点灯",
+		},
+		GetCandidatesFunc: func(c client.ECHONETListClient, d prompt.Document) []prompt.Suggest {
+			suggestions := []prompt.Suggest{
+				{Text: "on", Description: "電源を入れる"},
+				{Text: "off", Description: "電源を切る"},
+				{Text: "incandescent", Description: "電球色"},
+				{Text: "white", Description: "白色"},
+				{Text: "daylight_white", Description: "昼白色"},
+				{Text: "daylight", Description: "昼光色"},
+			}
+			if len(splitWords(d.TextBeforeCursor())) <= 2 {
+				suggestions = append(suggestions, getDeviceCandidates(c)...)
+			}
+			return suggestions
+		},
+		ParseFunc: func(p CommandParser, parts []string, debug bool) (*Command, error) {
+			cmd := newCommand(CmdLight)
+
+			deviceSpec, groupName, argIndex, err := p.parseDeviceSpecifierOrGroup(parts, 1, false)
+			if err != nil {
+				return nil, err
+			}
+			if groupName != nil {
+				return nil, fmt.Errorf("light コマンドはグループ指定に対応していません")
+			}
+			classCode := echonet_lite.GeneralLighting_ClassCode
+			if deviceSpec.ClassCode != nil {
+				if !client.IsLightingClass(*deviceSpec.ClassCode) {
+					return nil, fmt.Errorf("light コマンドは一般照明（0290）と単機能照明（0291）のみ対象です")
+				}
+				classCode = *deviceSpec.ClassCode
+			}
+			cmd.DeviceSpec = deviceSpec
+
+			for _, arg := range parts[argIndex:] {
+				switch arg {
+				case "on", "off":
+					on := arg == "on"
+					cmd.Light.Power = &on
+					continue
+				}
+				if brightness, err := strconv.Atoi(strings.TrimSuffix(arg, "%")); err == nil {
+					lowest, highest, err := client.BrightnessRange(classCode)
+					if err != nil {
+						return nil, err
+					}
+					if brightness < lowest || brightness > highest {
+						return nil, fmt.Errorf("照度レベルは %d から %d の範囲で指定してください: %s", lowest, highest, arg)
+					}
+					cmd.Light.Brightness = &brightness
+					continue
+				}
+				color, err := client.ParseLightColor(arg)
+				if err != nil {
+					return nil, &InvalidArgument{Argument: arg}
+				}
+				if deviceSpec.ClassCode != nil && !client.SupportsLightColor(classCode) {
+					return nil, fmt.Errorf("クラス %v は光色の設定に対応していません", classCode)
+				}
+				cmd.Light.Color = color
+			}
+			return cmd, nil
+		},
+	},
 	{
 		Name:    "alias",
 		Summary: "デバイスエイリアスの管理",
@@ -484,6 +893,7 @@ var CommandTable = []CommandDefinition{
 			"引数なし: 現在のデバッグモードを表示",
 			"on: デバッグモードを有効にする",
 			"off: デバッグモードを無効にする",
+			"送受信したパケットなどの詳細は、ログレベルが debug のときにログファイルに出力される",
 		},
 		GetCandidatesFunc: func(c client.ECHONETListClient, d prompt.Document) []prompt.Suggest {
 			words := splitWords(d.TextBeforeCursor())
@@ -569,6 +979,245 @@ var CommandTable = []CommandDefinition{
 			return cmd, nil
 		},
 	},
+	{
+		Name:    "pending",
+		Summary: "応答待ちの要求の表示と取り消し（デバッグ用）",
+		Syntax:  "pending [ipAddress] [classCode[:instanceCode]] | pending cancel <id>",
+		Description: []string{
+			"引数なし: 応答を待っている Get/Set 要求をデバイスごとに表示",
+			"ipAddress, classCode, instanceCode: 指定したデバイスへの要求だけを表示",
+			"表示内容: 番号、ESV、EPC、状態（sending: 送信中, waiting: 応答待ち, retrying: 再送後の応答待ち）、再送回数、経過時間、次の再送までの時間",
+			"cancel <id>: 指定した番号の要求の再送をやめて取り消す（呼び出し元にはエラーが返る）",
+			"例: pending 0130",
+			"例: pending cancel 12",
+		},
+		GetCandidatesFunc: func(c client.ECHONETListClient, d prompt.Document) []prompt.Suggest {
+			words := splitWords(d.TextBeforeCursor())
+			if len(words) == 2 {
+				return append([]prompt.Suggest{{Text: "cancel", Description: "要求の取り消し"}}, getDeviceCandidates(c)...)
+			}
+			if len(words) == 3 && words[1] == "cancel" {
+				requests, _ := c.DebugPendingRequests()
+				suggestions := make([]prompt.Suggest, 0, len(requests))
+				for _, req := range requests {
+					suggestions = append(suggestions, prompt.Suggest{
+						Text:        strconv.FormatUint(req.ID, 10),
+						Description: fmt.Sprintf("%v %v %s", req.Device, req.ESV, req.State),
+					})
+				}
+				return suggestions
+			}
+			return nil
+		},
+		ParseFunc: func(p CommandParser, parts []string, debug bool) (*Command, error) {
+			if len(parts) >= 2 && parts[1] == "cancel" {
+				if len(parts) != 3 {
+					return nil, fmt.Errorf("pending cancel コマンドには要求の番号が1つ必要です")
+				}
+				id, err := strconv.ParseUint(strings.TrimPrefix(parts[2], "#"), 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("無効な要求の番号です: %s", parts[2])
+				}
+				cmd := newCommand(CmdPendingCancel)
+				cmd.RequestID = id
+				return cmd, nil
+			}
+
+			cmd := newCommand(CmdPending)
+			deviceSpec, groupName, argIndex, err := p.parseDeviceSpecifierOrGroup(parts, 1, false)
+			if err != nil {
+				return nil, err
+			}
+			if groupName != nil {
+				return nil, fmt.Errorf("pending コマンドはグループ指定に対応していません")
+			}
+			if argIndex < len(parts) {
+				return nil, fmt.Errorf("不明な引数です: %s", parts[argIndex])
+			}
+			cmd.DeviceSpec = deviceSpec
+			return cmd, nil
+		},
+	},
+	{
+		Name:    "versions",
+		Summary: "デバイスの規格Versionとメーカ・商品コードの一覧",
+		Syntax:  "versions [ipAddress] [classCode[:instanceCode]] [-o json]",
+		Description: []string{
+			"引数なし: 全デバイス（ノードプロファイルを除く）の規格Version情報(EPC 0x82)、メーカコード(EPC 0x8A)、商品コード(EPC 0x8C)を表で表示",
+			"ipAddress, classCode, instanceCode: 指定したデバイスだけを表示",
+			"最後にReleaseごとのデバイス数を表示する。取得済みのプロパティを表示するので、値がない場合は update で再取得する",
+			"-o json: JSON で出力",
+			"例: versions",
+			"例: versions 0130 -o json",
+		},
+		GetCandidatesFunc: func(c client.ECHONETListClient, d prompt.Document) []prompt.Suggest {
+			if suggestions := getOutputOptionCandidates(d); len(suggestions) > 0 {
+				return suggestions
+			}
+			return append(getDeviceCandidates(c), prompt.Suggest{Text: "-o", Description: "出力形式を指定"})
+		},
+		ParseFunc: func(p CommandParser, parts []string, debug bool) (*Command, error) {
+			parts, output, err := parseOutputOption(parts)
+			if err != nil {
+				return nil, err
+			}
+			cmd := newCommand(CmdVersions)
+			cmd.Output = output
+			deviceSpec, groupName, argIndex, err := p.parseDeviceSpecifierOrGroup(parts, 1, false)
+			if err != nil {
+				return nil, err
+			}
+			if groupName != nil {
+				return nil, fmt.Errorf("versions コマンドはグループ指定に対応していません")
+			}
+			if argIndex < len(parts) {
+				return nil, fmt.Errorf("不明な引数です: %s", parts[argIndex])
+			}
+			cmd.DeviceSpec = deviceSpec
+			return cmd, nil
+		},
+	},
+	{
+		Name:    "localmap",
+		Summary: "自ノードのデバイスのプロパティマップの表示と変更（テスト用）",
+		Syntax:  "localmap [list] | localmap set|add|remove <classCode:instanceCode> get|set|announce <EPC>... | localmap reset <classCode:instanceCode> get|set|announce",
+		Description: []string{
+			"引数なし: 自ノードのデバイス（ノードプロファイル、コントローラー）の Get/Set/状態通知プロパティマップを表示",
+			"set: プロパティマップを指定したEPCだけにする（EPCを省略すると空にする）",
+			"add, remove: プロパティマップにEPCを追加・削除する",
+			"reset: プロパティマップを初期状態に戻す",
+			"他のコントローラーが自ノードのプロパティマップにどう反応するかを確かめるために使う",
+			"変更は保存され、再起動後も有効になる。Get プロパティマップにないプロパティの Get には不可応答を返す",
+			"例: localmap",
+			"例: localmap remove 05FF:1 announce 81",
+			"例: localmap reset 05FF:1 announce",
+		},
+		GetCandidatesFunc: func(c client.ECHONETListClient, d prompt.Document) []prompt.Suggest {
+			words := splitWords(d.TextBeforeCursor())
+			switch len(words) {
+			case 2:
+				return []prompt.Suggest{
+					{Text: "list", Description: "プロパティマップを表示"},
+					{Text: "set", Description: "プロパティマップを置き換える"},
+					{Text: "add", Description: "プロパティマップにEPCを追加"},
+					{Text: "remove", Description: "プロパティマップからEPCを削除"},
+					{Text: "reset", Description: "プロパティマップを初期状態に戻す"},
+				}
+			case 3:
+				maps, _ := c.LocalPropertyMaps()
+				suggestions := make([]prompt.Suggest, 0, len(maps))
+				for _, m := range maps {
+					suggestions = append(suggestions, prompt.Suggest{Text: m.EOJ.Specifier(), Description: m.EOJ.ClassCode().String()})
+				}
+				return suggestions
+			case 4:
+				return []prompt.Suggest{
+					{Text: "get", Description: "Get プロパティマップ"},
+					{Text: "set", Description: "Set プロパティマップ"},
+					{Text: "announce", Description: "状態通知プロパティマップ"},
+				}
+			}
+			return nil
+		},
+		ParseFunc: func(p CommandParser, parts []string, debug bool) (*Command, error) {
+			if len(parts) == 1 || (len(parts) == 2 && parts[1] == "list") {
+				return newCommand(CmdLocalMapList), nil
+			}
+			action := parts[1]
+			switch action {
+			case "set", "add", "remove", "reset":
+			default:
+				return nil, fmt.Errorf("localmap コマンドの操作は list, set, add, remove, reset のいずれかです: %s", action)
+			}
+			if len(parts) < 4 {
+				return nil, fmt.Errorf("localmap %s コマンドにはデバイス（classCode:instanceCode）とプロパティマップの種類が必要です", action)
+			}
+			eoj, err := handler.ParseEOJString(parts[2])
+			if err != nil {
+				return nil, err
+			}
+			mapType, err := handler.ParsePropertyMapType(parts[3])
+			if err != nil {
+				return nil, err
+			}
+			cmd := newCommand(CmdLocalMapChange)
+			cmd.LocalEOJ = eoj
+			cmd.LocalMapType = mapType
+			cmd.LocalMapAction = action
+			if action == "reset" {
+				if len(parts) > 4 {
+					return nil, fmt.Errorf("不明な引数です: %s", parts[4])
+				}
+				return cmd, nil
+			}
+			if action != "set" && len(parts) == 4 {
+				return nil, fmt.Errorf("localmap %s コマンドにはEPCが必要です", action)
+			}
+			for _, s := range parts[4:] {
+				epc, err := parseEPC(s)
+				if err != nil {
+					return nil, err
+				}
+				cmd.EPCs = append(cmd.EPCs, epc)
+			}
+			return cmd, nil
+		},
+	},
+	{
+		Name:    "poll-exclude",
+		Summary: "定期更新から除外するクラス・デバイスの表示と変更",
+		Syntax:  "poll-exclude [list] | poll-exclude add|remove <classCode|device>",
+		Description: []string{
+			"引数なし: 定期更新から除外しているクラス・デバイスを表示",
+			"add: クラスまたはデバイスを定期更新（強制更新を含む）から除外する。INF 通知は引き続き受け付ける",
+			"remove: 除外をやめて定期更新の対象に戻す",
+			"classCode: 4桁のクラスコード（例: 0279）",
+			"device: エイリアス、@グループ名、IDString、\"IP EOJ\" または IP アドレス",
+			"変更はサーバーを再起動するまで有効。常に除外する場合は設定ファイルの websocket.poll_exclude に書く",
+			"WebSocket クライアントモードでのみ使える（定期更新はサーバーが行う）",
+			"例: poll-exclude add 0279",
+			"例: poll-exclude remove 192.168.1.50",
+		},
+		GetCandidatesFunc: func(c client.ECHONETListClient, d prompt.Document) []prompt.Suggest {
+			words := splitWords(d.TextBeforeCursor())
+			switch len(words) {
+			case 2:
+				return []prompt.Suggest{
+					{Text: "list", Description: "除外しているクラス・デバイスを表示"},
+					{Text: "add", Description: "定期更新から除外する"},
+					{Text: "remove", Description: "定期更新の対象に戻す"},
+				}
+			case 3:
+				if words[1] == "remove" {
+					exclusions, _ := c.PollExclusions()
+					suggestions := make([]prompt.Suggest, 0, len(exclusions))
+					for _, target := range exclusions {
+						suggestions = append(suggestions, prompt.Suggest{Text: target})
+					}
+					return suggestions
+				}
+				return getDeviceCandidates(c)
+			}
+			return nil
+		},
+		ParseFunc: func(p CommandParser, parts []string, debug bool) (*Command, error) {
+			if len(parts) == 1 || (len(parts) == 2 && parts[1] == "list") {
+				return newCommand(CmdPollExcludeList), nil
+			}
+			action := parts[1]
+			if action != "add" && action != "remove" {
+				return nil, fmt.Errorf("poll-exclude コマンドの操作は list, add, remove のいずれかです: %s", action)
+			}
+			if len(parts) < 3 {
+				return nil, fmt.Errorf("poll-exclude %s コマンドにはクラスコードまたはデバイスが必要です", action)
+			}
+			cmd := newCommand(CmdPollExcludeChange)
+			// "IP EOJ" の形式のデバイス指定は2語になる
+			cmd.PollTarget = strings.Join(parts[2:], " ")
+			cmd.PollExcluded = action == "add"
+			return cmd, nil
+		},
+	},
 	{
 		Name:    "help",
 		Summary: "ヘルプを表示",
@@ -721,6 +1370,270 @@ var CommandTable = []CommandDefinition{
 			return cmd, nil
 		},
 	},
+	{
+		Name:    "scene",
+		Summary: "シーン（複数デバイスのプロパティ設定）の管理と実行",
+		Syntax:  "scene add|delete|list|run [sceneName] [ipAddress] [classCode[:instanceCode]] [property1 property2...] | scene sequence <sceneName> <delay|off>",
+		Description: []string{
+			"add: シーンを作成し、デバイスに設定するプロパティを追加します（同じデバイス・EPCは上書き）",
+			"delete: シーンを削除します",
+			"list: シーンの一覧または詳細を表示します",
+			"run: シーンを実行し、登録されたプロパティを各デバイスに設定します",
+			"sequence: 実行時にデバイスを1台ずつ、指定した間隔（例: 2s, 500ms。最大1分）を空けて設定します",
+			"  突入電流を避けるため、同じ回路の機器を一斉に起動しないようにします。off で並列の設定に戻します",
+			"sceneName: シーン名（@で始めることはできません）",
+			"デバイスとプロパティの指定方法は set コマンドと同じです（@groupName も指定可能）",
+			"例: scene add おやすみ 192.168.0.3 0130:1 off",
+			"例: scene add おやすみ @livingroom 80:31",
+			"例: scene delete おやすみ",
+			"例: scene list",
+			"例: scene run おやすみ",
+			"例: scene sequence おはよう 2s",
+		},
+		GetCandidatesFunc: func(c client.ECHONETListClient, d prompt.Document) []prompt.Suggest {
+			words := splitWords(d.TextBeforeCursor())
+			wordCount := len(words)
+
+			switch {
+			case wordCount == 2: // サブコマンド
+				return []prompt.Suggest{
+					{Text: "add", Description: "シーン作成/プロパティ追加"},
+					{Text: "delete", Description: "シーン削除"},
+					{Text: "list", Description: "シーン一覧/詳細表示"},
+					{Text: "run", Description: "シーン実行"},
+					{Text: "sequence", Description: "デバイスを順に設定する間隔"},
+				}
+			case wordCount == 3: // シーン名
+				return getSceneCandidates(c)
+			case wordCount == 4 && words[1] == "add": // デバイス指定子
+				return getDeviceCandidates(c)
+			case wordCount >= 5 && words[1] == "add": // プロパティ指定
+				return getPropertyAliasCandidates(c)
+			}
+			return []prompt.Suggest{}
+		},
+		ParseFunc: func(p CommandParser, parts []string, debug bool) (*Command, error) {
+			if len(parts) < 2 {
+				return nil, fmt.Errorf("scene コマンドにはサブコマンドが必要です")
+			}
+
+			var cmd *Command
+
+			switch parts[1] {
+			case "add":
+				if len(parts) < 3 {
+					return nil, fmt.Errorf("scene add コマンドにはシーン名が必要です")
+				}
+				sceneName := parts[2]
+				if err := handler.ValidateSceneName(sceneName); err != nil {
+					return nil, err
+				}
+
+				cmd = newCommand(CmdSceneAdd)
+				cmd.SceneName = &sceneName
+
+				// デバイス識別子またはグループ名のパース
+				deviceSpec, groupName, argIndex, err := p.parseDeviceSpecifierOrGroup(parts, 3, true)
+				if err != nil {
+					return nil, err
+				}
+				cmd.DeviceSpec = deviceSpec
+				cmd.GroupName = groupName
+
+				// プロパティのパース
+				for i := argIndex; i < len(parts); i++ {
+					prop, err := p.parsePropertyString(parts[i], cmd.GetClassCode(), debug)
+					if err != nil {
+						return nil, err
+					}
+					cmd.Properties = append(cmd.Properties, prop)
+				}
+				if len(cmd.Properties) == 0 {
+					return nil, fmt.Errorf("scene add コマンドには少なくとも1つのプロパティが必要です")
+				}
+
+			case "delete", "run":
+				if len(parts) != 3 {
+					return nil, fmt.Errorf("scene %s コマンドにはシーン名のみが必要です", parts[1])
+				}
+				sceneName := parts[2]
+				if err := handler.ValidateSceneName(sceneName); err != nil {
+					return nil, err
+				}
+
+				if parts[1] == "delete" {
+					cmd = newCommand(CmdSceneDelete)
+				} else {
+					cmd = newCommand(CmdSceneRun)
+				}
+				cmd.SceneName = &sceneName
+
+			case "sequence":
+				if len(parts) != 4 {
+					return nil, fmt.Errorf("scene sequence コマンドにはシーン名と間隔（または off）が必要です")
+				}
+				sceneName := parts[2]
+				if err := handler.ValidateSceneName(sceneName); err != nil {
+					return nil, err
+				}
+
+				cmd = newCommand(CmdSceneSequence)
+				cmd.SceneName = &sceneName
+				if parts[3] != "off" {
+					delay, err := time.ParseDuration(parts[3])
+					if err != nil || delay <= 0 || delay > handler.MaxSceneDelayMs*time.Millisecond {
+						return nil, fmt.Errorf("間隔は 1ms〜1m の範囲で指定してください（例: 2s）: %s", parts[3])
+					}
+					cmd.SceneDelay = delay
+				}
+
+			case "list":
+				cmd = newCommand(CmdSceneList)
+				if len(parts) > 2 {
+					sceneName := parts[2]
+					cmd.SceneName = &sceneName
+				}
+
+			default:
+				return nil, fmt.Errorf("不明なサブコマンド: %s", parts[1])
+			}
+
+			return cmd, nil
+		},
+	},
+	{
+		Name:    "schedule",
+		Summary: "決まった時刻にプロパティを設定するスケジュールの管理",
+		Syntax:  "schedule add <name> <min> <hour> <day> <month> <weekday> (-scene <sceneName> | [ipAddress] [classCode[:instanceCode]] property1 [property2...]) | schedule delete|enable|disable <name> | schedule list",
+		Description: []string{
+			"add: スケジュールを登録します（同じ名前のスケジュールは置き換え）",
+			"delete: スケジュールを削除します",
+			"enable/disable: スケジュールを有効/無効にします",
+			"list: スケジュールの一覧と次回実行時刻を表示します",
+			"時刻は cron 形式（分 時 日 月 曜日）で指定します。曜日は 0(日)〜6(土)",
+			"  各フィールドは *, 数値, 範囲 a-b, リスト a,b, ステップ */n が使えます",
+			"  5フィールドの代わりに @hourly, @daily, @weekly, @monthly, @yearly も使えます",
+			"  @sunrise, @sunset で日の出・日の入りの時刻に実行します（設定ファイルの location で地点を指定）",
+			"  @sunset-30m のようにオフセットを付けたり、@sunrise 1-5 のように曜日を続けたりできます",
+			"-scene でシーンを実行するか、set コマンドと同じ形式でデバイスとプロパティを指定します（@groupName も指定可能）",
+			"例: schedule add wakeup 0 7 * * 1-5 192.168.0.3 0130:1 on 80:30",
+			"例: schedule add goodnight 30 23 * * * -scene おやすみ",
+			"例: schedule add porch @sunset-15m 1-5 -scene 玄関灯",
+			"例: schedule disable wakeup",
+			"例: schedule list",
+		},
+		GetCandidatesFunc: func(c client.ECHONETListClient, d prompt.Document) []prompt.Suggest {
+			words := splitWords(d.TextBeforeCursor())
+			wordCount := len(words)
+
+			switch {
+			case wordCount == 2: // サブコマンド
+				return []prompt.Suggest{
+					{Text: "add", Description: "スケジュール登録"},
+					{Text: "delete", Description: "スケジュール削除"},
+					{Text: "enable", Description: "スケジュールを有効にする"},
+					{Text: "disable", Description: "スケジュールを無効にする"},
+					{Text: "list", Description: "スケジュール一覧表示"},
+				}
+			case wordCount == 3 && words[1] != "list": // スケジュール名
+				return getScheduleCandidates(c)
+			case wordCount >= 4 && words[1] == "add" && words[wordCount-2] == "-scene":
+				return getSceneCandidates(c)
+			}
+			return []prompt.Suggest{}
+		},
+		ParseFunc: func(p CommandParser, parts []string, debug bool) (*Command, error) {
+			if len(parts) < 2 {
+				return nil, fmt.Errorf("schedule コマンドにはサブコマンドが必要です")
+			}
+
+			var cmd *Command
+
+			switch parts[1] {
+			case "add":
+				if len(parts) < 4 {
+					return nil, fmt.Errorf("schedule add コマンドにはスケジュール名と時刻の指定が必要です")
+				}
+				scheduleName := parts[2]
+				cmd = newCommand(CmdScheduleAdd)
+				cmd.ScheduleName = &scheduleName
+
+				// 時刻の指定（@daily などの別名は1語、日の出・日の入りは曜日を付けて2語にもできる、それ以外は5語）
+				argIndex := 4
+				if strings.HasPrefix(parts[3], "@") {
+					cmd.ScheduleCron = parts[3]
+					if trigger, err := handler.ParseScheduleTrigger(parts[3]); err == nil && trigger.NeedsCoordinates() && len(parts) > 4 {
+						if _, err := handler.ParseScheduleTrigger(parts[3] + " " + parts[4]); err == nil {
+							cmd.ScheduleCron = parts[3] + " " + parts[4]
+							argIndex = 5
+						}
+					}
+				} else {
+					if len(parts) < 8 {
+						return nil, fmt.Errorf("時刻は「分 時 日 月 曜日」の5つで指定してください")
+					}
+					cmd.ScheduleCron = strings.Join(parts[3:8], " ")
+					argIndex = 8
+				}
+				if _, err := handler.ParseScheduleTrigger(cmd.ScheduleCron); err != nil {
+					return nil, err
+				}
+
+				if argIndex < len(parts) && parts[argIndex] == "-scene" {
+					if len(parts) != argIndex+2 {
+						return nil, fmt.Errorf("-scene にはシーン名のみを指定してください")
+					}
+					sceneName := parts[argIndex+1]
+					cmd.SceneName = &sceneName
+					break
+				}
+
+				// デバイス識別子またはグループ名のパース
+				deviceSpec, groupName, argIndex, err := p.parseDeviceSpecifierOrGroup(parts, argIndex, true)
+				if err != nil {
+					return nil, err
+				}
+				cmd.DeviceSpec = deviceSpec
+				cmd.GroupName = groupName
+
+				// プロパティのパース
+				for i := argIndex; i < len(parts); i++ {
+					prop, err := p.parsePropertyString(parts[i], cmd.GetClassCode(), debug)
+					if err != nil {
+						return nil, err
+					}
+					cmd.Properties = append(cmd.Properties, prop)
+				}
+				if len(cmd.Properties) == 0 {
+					return nil, fmt.Errorf("schedule add コマンドには -scene か少なくとも1つのプロパティが必要です")
+				}
+
+			case "delete", "enable", "disable":
+				if len(parts) != 3 {
+					return nil, fmt.Errorf("schedule %s コマンドにはスケジュール名のみが必要です", parts[1])
+				}
+				scheduleName := parts[2]
+
+				switch parts[1] {
+				case "delete":
+					cmd = newCommand(CmdScheduleDelete)
+				case "enable":
+					cmd = newCommand(CmdScheduleEnable)
+				default:
+					cmd = newCommand(CmdScheduleDisable)
+				}
+				cmd.ScheduleName = &scheduleName
+
+			case "list":
+				cmd = newCommand(CmdScheduleList)
+
+			default:
+				return nil, fmt.Errorf("不明なサブコマンド: %s", parts[1])
+			}
+
+			return cmd, nil
+		},
+	},
 	{
 		Name:    "location",
 		Summary: "設置場所のエイリアスと表示順の管理",
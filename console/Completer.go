@@ -2,6 +2,9 @@ package console
 
 import (
 	"echonet-list/client"
+	"echonet-list/echonet_lite"
+	"sort"
+	"strings"
 
 	"github.com/c-bata/go-prompt"
 )
@@ -94,6 +97,66 @@ func getPropertyAliasCandidatesForEPC(c client.ECHONETListClient, epc client.EPC
 	return suggests
 }
 
+// getClassPropertyCandidates は、クラスが決まったデバイスに設定するプロパティの候補を返す
+// word に ':' がなければプロパティエイリアス（例: on）と "EPC:"・"プロパティ名:"（例: temperature-setting:）、
+// ':' があればその EPC に使える値のエイリアス（例: b0:auto、operation-mode:auto）
+func getClassPropertyCandidates(c client.ECHONETListClient, classCode client.EOJClassCode, word string) []prompt.Suggest {
+	if prefix, _, found := strings.Cut(word, ":"); found {
+		epc, err := parseEPC(prefix)
+		if err != nil {
+			var ok bool
+			if epc, ok = findEPCByName(c, classCode, prefix); !ok {
+				return []prompt.Suggest{}
+			}
+		}
+		desc, ok := c.GetPropertyDesc(classCode, epc)
+		if !ok {
+			return []prompt.Suggest{}
+		}
+		suggests := make([]prompt.Suggest, 0, len(desc.Aliases))
+		for alias, edt := range desc.Aliases {
+			prop := client.Property{EPC: epc, EDT: edt}
+			suggests = append(suggests, prompt.Suggest{
+				Text:        prefix + ":" + alias,
+				Description: prop.String(classCode),
+			})
+		}
+		sortSuggests(suggests)
+		return suggests
+	}
+
+	aliases := c.AvailablePropertyAliases(classCode)
+	names := propertyNames(c, classCode)
+	suggests := make([]prompt.Suggest, 0, len(aliases)+len(names)*2)
+	for alias, desc := range aliases {
+		prop, _ := c.FindPropertyAlias(classCode, alias)
+		suggests = append(suggests, prompt.Suggest{
+			Text:        alias,
+			Description: prop.String(desc.ClassCode),
+		})
+	}
+	sortSuggests(suggests)
+	lang := echonet_lite.DisplayLanguage()
+	for _, name := range names {
+		suggests = append(suggests, prompt.Suggest{
+			Text:        name.name + ":",
+			Description: name.epc.String() + " " + name.desc.GetName(lang),
+		})
+	}
+	for _, name := range names {
+		suggests = append(suggests, prompt.Suggest{
+			Text:        name.epc.String() + ":",
+			Description: name.desc.GetName(lang),
+		})
+	}
+	return suggests
+}
+
+// sortSuggests は、map から作った候補の順序を一定にする
+func sortSuggests(suggests []prompt.Suggest) {
+	sort.Slice(suggests, func(i, j int) bool { return suggests[i].Text < suggests[j].Text })
+}
+
 // getGroupCandidates はグループ名の候補を返す
 func getGroupCandidates(c client.ECHONETListClient) []prompt.Suggest {
 	groups := c.GroupList(nil)
@@ -106,6 +169,31 @@ func getGroupCandidates(c client.ECHONETListClient) []prompt.Suggest {
 	return suggests
 }
 
+// getSceneCandidates はシーン名の候補を返す
+func getSceneCandidates(c client.ECHONETListClient) []prompt.Suggest {
+	scenes := c.SceneList(nil)
+	suggests := make([]prompt.Suggest, 0, len(scenes))
+	for _, scene := range scenes {
+		suggests = append(suggests, prompt.Suggest{
+			Text: scene.Scene,
+		})
+	}
+	return suggests
+}
+
+// getScheduleCandidates はスケジュール名の候補を返す
+func getScheduleCandidates(c client.ECHONETListClient) []prompt.Suggest {
+	schedules := c.ScheduleList()
+	suggests := make([]prompt.Suggest, 0, len(schedules))
+	for _, schedule := range schedules {
+		suggests = append(suggests, prompt.Suggest{
+			Text:        schedule.Name,
+			Description: schedule.Cron,
+		})
+	}
+	return suggests
+}
+
 // getLocationAliasCandidates はロケーションエイリアスの候補を返す
 func getLocationAliasCandidates(c client.ECHONETListClient) []prompt.Suggest {
 	aliases, _ := c.GetLocationSettings()
@@ -166,3 +254,15 @@ func splitWords(line string) []string {
 
 	return words
 }
+
+// getOutputOptionCandidates は、-o の次の単語なら出力形式の候補を返す
+func getOutputOptionCandidates(d prompt.Document) []prompt.Suggest {
+	words := splitWords(d.TextBeforeCursor())
+	if len(words) < 2 || words[len(words)-2] != "-o" {
+		return nil
+	}
+	return []prompt.Suggest{
+		{Text: string(OutputJSON), Description: "JSON で出力"},
+		{Text: string(OutputText), Description: "テキストで出力"},
+	}
+}
@@ -12,7 +12,8 @@ import (
 	"golang.org/x/term"
 )
 
-func ConsoleProcess(ctx context.Context, c client.ECHONETListClient) {
+// ConsoleProcess は、対話式のコンソールを実行する。jsonOutput が true の場合、get/devices/discover の結果を JSON で出力する
+func ConsoleProcess(ctx context.Context, c client.ECHONETListClient, jsonOutput bool) {
 	// 現在の端末状態を保存
 	orig, _ := term.GetState(int(os.Stdin.Fd()))
 	defer term.Restore(int(os.Stdin.Fd()), orig)
@@ -23,6 +24,7 @@ func ConsoleProcess(ctx context.Context, c client.ECHONETListClient) {
 
 	// コマンドプロセッサの作成と開始
 	processor := NewCommandProcessor(ctx, c)
+	processor.SetJSONOutput(jsonOutput)
 	processor.Start()
 	defer processor.Stop()
 
@@ -128,6 +130,23 @@ func ConsoleProcess(ctx context.Context, c client.ECHONETListClient) {
 	saveHistory(historyFilePath, initialHistory)
 }
 
+// RunCommand は、1つのコマンドを実行して終了する。シェルのパイプラインやスクリプトから使う
+func RunCommand(ctx context.Context, c client.ECHONETListClient, line string, jsonOutput bool) error {
+	cmd, err := NewCommandParser(c, c, c).ParseCommand(line, c.IsDebug())
+	if err != nil {
+		return err
+	}
+	if cmd == nil || cmd.Type == CmdQuit {
+		return nil
+	}
+
+	processor := NewCommandProcessor(ctx, c)
+	processor.SetJSONOutput(jsonOutput)
+	processor.Start()
+	defer processor.Stop()
+	return processor.SendCommand(cmd)
+}
+
 // findCommandDefinition は CommandTable からコマンド定義を検索するヘルパー関数
 // (重複定義を避けるため、ConsoleProcess.go 内に保持)
 func findCommandDefinition(name string) *CommandDefinition {
@@ -0,0 +1,74 @@
+package console
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"echonet-list/client"
+	"echonet-list/protocol"
+)
+
+// OutputFormat は、コマンド結果の出力形式
+type OutputFormat string
+
+const (
+	OutputDefault OutputFormat = ""     // 既定（-json フラグに従う）
+	OutputText    OutputFormat = "text" // 人間向けのテキスト表示
+	OutputJSON    OutputFormat = "json" // スクリプト向けの JSON
+)
+
+// parseOutputOption は、引数から -o json / -o text / -json を取り除き、指定された出力形式を返す
+func parseOutputOption(parts []string) ([]string, OutputFormat, error) {
+	format := OutputDefault
+	rest := make([]string, 0, len(parts))
+	for i := 0; i < len(parts); i++ {
+		switch parts[i] {
+		case "-json":
+			format = OutputJSON
+		case "-o":
+			if i+1 >= len(parts) {
+				return nil, format, fmt.Errorf("-o オプションには出力形式（json または text）が必要です")
+			}
+			switch OutputFormat(parts[i+1]) {
+			case OutputJSON, OutputText:
+				format = OutputFormat(parts[i+1])
+			default:
+				return nil, format, fmt.Errorf("不明な出力形式です: %s（json または text）", parts[i+1])
+			}
+			i++
+		default:
+			rest = append(rest, parts[i])
+		}
+	}
+	return rest, format, nil
+}
+
+// jsonDevice は、JSON 出力するデバイス。REST API と同じ形式にエイリアスを加えたもの
+type jsonDevice struct {
+	protocol.Device
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// isJSONOutput は、コマンドの結果を JSON で出力するかどうかを返す
+func (p *CommandProcessor) isJSONOutput(cmd *Command) bool {
+	if cmd.Output == OutputDefault {
+		return p.jsonOutput
+	}
+	return cmd.Output == OutputJSON
+}
+
+func (p *CommandProcessor) toJSONDevice(device client.IPAndEOJ, properties client.Properties) jsonDevice {
+	return jsonDevice{
+		Device:  protocol.DeviceToProtocol(device, properties, time.Time{}, p.handler.IsOfflineDevice(device)),
+		Aliases: p.handler.GetAliases(device),
+	}
+}
+
+// printJSON は、値を JSON として標準出力に書き出す
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
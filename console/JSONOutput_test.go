@@ -0,0 +1,126 @@
+package console
+
+import (
+	"encoding/json"
+	"testing"
+
+	"echonet-list/client"
+	"echonet-list/echonet_lite"
+)
+
+// jsonClientStub はエイリアス aircon のエアコンのプロパティを返すクライアント
+type jsonClientStub struct {
+	completionClientStub
+}
+
+var jsonAirconProperties = client.Properties{
+	{EPC: 0x80, EDT: []byte{0x30}},
+	{EPC: 0xB3, EDT: []byte{25}},
+}
+
+func (jsonClientStub) GetAliases(client.IPAndEOJ) []string { return []string{"aircon"} }
+func (jsonClientStub) ListDevices(client.FilterCriteria) []client.DeviceAndProperties {
+	return []client.DeviceAndProperties{{Device: completionAircon, Properties: jsonAirconProperties}}
+}
+func (jsonClientStub) GetProperties(device client.IPAndEOJ, epcs []client.EPCType, _ bool) (client.DeviceAndProperties, error) {
+	var props client.Properties
+	for _, epc := range epcs {
+		if prop, ok := jsonAirconProperties.FindEPC(epc); ok {
+			props = append(props, prop)
+		}
+	}
+	return client.DeviceAndProperties{Device: device, Properties: props}, nil
+}
+
+func TestParseOutputOption(t *testing.T) {
+	parser := NewCommandParser(tablePropertyDescProvider{}, stubAliasManager{}, stubGroupManager{})
+
+	tests := []struct {
+		input string
+		want  OutputFormat
+	}{
+		{"devices 0130 -all", OutputDefault},
+		{"devices -o json 0130 -all", OutputJSON},
+		{"get 192.168.1.10 0130:1 80 -o text", OutputText},
+		{"discover -json", OutputJSON},
+	}
+	for _, tt := range tests {
+		cmd, err := parser.ParseCommand(tt.input, false)
+		if err != nil {
+			t.Fatalf("ParseCommand(%q) returned error: %v", tt.input, err)
+		}
+		if cmd.Output != tt.want {
+			t.Errorf("ParseCommand(%q).Output = %q, want %q", tt.input, cmd.Output, tt.want)
+		}
+	}
+
+	// -o の後の引数はデバイス指定やEPCとして扱わない
+	cmd, _ := parser.ParseCommand("get 192.168.1.10 0130:1 80 -o json", false)
+	if len(cmd.EPCs) != 1 || cmd.EPCs[0] != 0x80 {
+		t.Errorf("unexpected EPCs: %v", cmd.EPCs)
+	}
+
+	for _, input := range []string{"devices -o", "get 192.168.1.10 0130:1 80 -o yaml", "discover now"} {
+		if _, err := parser.ParseCommand(input, false); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestJSONOutput(t *testing.T) {
+	stub := jsonClientStub{completionClientStub{historyClientStub: &historyClientStub{devices: []client.IPAndEOJ{completionAircon}}}}
+	processor := &CommandProcessor{handler: stub}
+
+	decode := func(output string) []jsonDevice {
+		t.Helper()
+		var devices []jsonDevice
+		if err := json.Unmarshal([]byte(output), &devices); err != nil {
+			t.Fatalf("output is not a JSON array: %v\n%s", err, output)
+		}
+		return devices
+	}
+
+	// -o json の devices は REST API と同じ形式にエイリアスを加えたもの
+	output := captureOutput(func() {
+		if err := processor.processDevicesCommand(&Command{Type: CmdDevices, PropMode: PropAll, Output: OutputJSON}); err != nil {
+			t.Fatalf("processDevicesCommand returned error: %v", err)
+		}
+	})
+	devices := decode(output)
+	if len(devices) != 1 || devices[0].IP != "192.168.1.10" || devices[0].EOJ != "0130:1" || len(devices[0].Aliases) != 1 || devices[0].Aliases[0] != "aircon" {
+		t.Fatalf("unexpected devices: %s", output)
+	}
+	if got := devices[0].Properties["80"].String; got != "on" {
+		t.Errorf("expected 80 to be on, got %q", got)
+	}
+	if got := devices[0].Properties["B3"].Number; got == nil || *got != 25 {
+		t.Errorf("expected B3 to be 25, got %v", got)
+	}
+
+	// -json フラグの既定値と get のEPCの絞り込み
+	processor.SetJSONOutput(true)
+	class := echonet_lite.HomeAirConditioner_ClassCode
+	output = captureOutput(func() {
+		cmd := &Command{Type: CmdGet, DeviceSpec: client.DeviceSpecifier{ClassCode: &class}, EPCs: []client.EPCType{0xB3}}
+		if err := processor.processGetCommand(cmd); err != nil {
+			t.Fatalf("processGetCommand returned error: %v", err)
+		}
+	})
+	devices = decode(output)
+	if len(devices) != 1 || len(devices[0].Properties) != 1 {
+		t.Fatalf("unexpected get result: %s", output)
+	}
+
+	// -o text は -json フラグより優先される
+	output = captureOutput(func() {
+		_ = processor.processDevicesCommand(&Command{Type: CmdDevices, PropMode: PropAll, Output: OutputText})
+	})
+	if output == "" || output[0] == '[' {
+		t.Errorf("expected text output, got %s", output)
+	}
+
+	epc := client.EPCType(0x80)
+	if err := processor.processDevicesCommand(&Command{Type: CmdDevices, GroupByEPC: &epc}); err == nil {
+		t.Error("expected an error for -group-by with JSON output")
+	}
+}
@@ -0,0 +1,58 @@
+package console
+
+import (
+	"echonet-list/client"
+	"strings"
+	"unicode"
+)
+
+// propertyName は、コマンドでEPCの代わりに使えるプロパティ名
+type propertyName struct {
+	name string // 例: "temperature-setting"
+	epc  client.EPCType
+	desc *client.PropertyDesc
+}
+
+// propertyNameKeyword は、プロパティの英語の短縮名をコマンドで使える名前にする
+// 例: "Room temperature" → "room-temperature"
+func propertyNameKeyword(desc *client.PropertyDesc) string {
+	words := strings.FieldsFunc(strings.ToLower(desc.GetShortName("")), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+// propertyNames は、クラスで定義されているプロパティの名前を EPC の順に返す
+// クラスコードが 0 の場合は、全クラス共通のプロパティのみ
+func propertyNames(provider client.PropertyDescProvider, classCode client.EOJClassCode) []propertyName {
+	var names []propertyName
+	for epc := 0x80; epc <= 0xFF; epc++ {
+		desc, ok := provider.GetPropertyDesc(classCode, client.EPCType(epc))
+		if !ok {
+			continue
+		}
+		if name := propertyNameKeyword(desc); name != "" {
+			names = append(names, propertyName{name: name, epc: client.EPCType(epc), desc: desc})
+		}
+	}
+	return names
+}
+
+// findEPCByName は、プロパティ名から EPC を探す
+// 完全に一致する名前がなければ、前方一致する名前が1つだけの場合にその EPC を返す（例: "temperature" → "temperature-setting"）
+func findEPCByName(provider client.PropertyDescProvider, classCode client.EOJClassCode, name string) (client.EPCType, bool) {
+	name = strings.ToLower(name)
+	var found []client.EPCType
+	for _, n := range propertyNames(provider, classCode) {
+		if n.name == name {
+			return n.epc, true
+		}
+		if strings.HasPrefix(n.name, name) {
+			found = append(found, n.epc)
+		}
+	}
+	if len(found) == 1 {
+		return found[0], true
+	}
+	return 0, false
+}
@@ -0,0 +1,133 @@
+package console
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"echonet-list/client"
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+
+	"github.com/c-bata/go-prompt"
+	"golang.org/x/exp/slices"
+)
+
+// tablePropertyDescProvider は実際のプロパティテーブルを返すスタブ
+type tablePropertyDescProvider struct {
+	stubPropertyDescProvider
+}
+
+func (tablePropertyDescProvider) GetPropertyDesc(classCode client.EOJClassCode, epc client.EPCType) (*client.PropertyDesc, bool) {
+	return echonet_lite.GetPropertyDesc(classCode, epc)
+}
+func (tablePropertyDescProvider) FindPropertyAlias(classCode client.EOJClassCode, alias string) (client.Property, bool) {
+	return handler.FindPropertyAlias(classCode, alias)
+}
+
+func TestFindEPCByName(t *testing.T) {
+	provider := tablePropertyDescProvider{}
+	tests := []struct {
+		name  string
+		want  client.EPCType
+		found bool
+	}{
+		{"temperature-setting", 0xB3, true},
+		{"Room-Temperature", 0xBB, true},
+		{"temperature", 0xB3, true}, // 前方一致が1つだけ
+		{"operation", 0, false},     // operation-status と operation-mode-setting
+		{"unknown", 0, false},
+	}
+	for _, tt := range tests {
+		epc, found := findEPCByName(provider, echonet_lite.HomeAirConditioner_ClassCode, tt.name)
+		if found != tt.found || epc != tt.want {
+			t.Errorf("findEPCByName(%q) = %s, %v, want %s, %v", tt.name, epc, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestParseSetCommandWithPropertyName(t *testing.T) {
+	parser := NewCommandParser(tablePropertyDescProvider{}, stubAliasManager{}, stubGroupManager{})
+
+	cmd, err := parser.ParseCommand("set 192.168.1.10 0130:1 temperature:25 operation-mode-setting:auto 80:on", false)
+	if err != nil {
+		t.Fatalf("ParseCommand returned error: %v", err)
+	}
+	want := client.Properties{
+		{EPC: 0xB3, EDT: []byte{25}},
+		{EPC: 0xB0, EDT: []byte{0x41}},
+		{EPC: 0x80, EDT: []byte{0x30}},
+	}
+	if len(cmd.Properties) != len(want) {
+		t.Fatalf("expected %d properties, got %v", len(want), cmd.Properties)
+	}
+	for i, prop := range cmd.Properties {
+		if prop.EPC != want[i].EPC || !bytes.Equal(prop.EDT, want[i].EDT) {
+			t.Errorf("property %d = %s:%X, want %s:%X", i, prop.EPC, prop.EDT, want[i].EPC, want[i].EDT)
+		}
+	}
+
+	if _, err := parser.ParseCommand("set 192.168.1.10 0130:1 operation:on", false); err == nil {
+		t.Error("expected an error for an ambiguous property name")
+	}
+}
+
+// completionClientStub はエイリアス aircon のエアコンがあるクライアント
+type completionClientStub struct {
+	*historyClientStub
+}
+
+var completionAircon = client.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+
+func (completionClientStub) GetDeviceByAlias(alias string) (client.IPAndEOJ, bool) {
+	return completionAircon, alias == "aircon"
+}
+func (completionClientStub) GetPropertyDesc(classCode client.EOJClassCode, epc client.EPCType) (*client.PropertyDesc, bool) {
+	return echonet_lite.GetPropertyDesc(classCode, epc)
+}
+func (completionClientStub) FindPropertyAlias(classCode client.EOJClassCode, alias string) (client.Property, bool) {
+	return handler.FindPropertyAlias(classCode, alias)
+}
+func (completionClientStub) AvailablePropertyAliases(classCode client.EOJClassCode) map[string]client.PropertyDescription {
+	return handler.AvailablePropertyAliases(classCode)
+}
+
+func TestSetCommandCompletion(t *testing.T) {
+	c := completionClientStub{historyClientStub: &historyClientStub{devices: []client.IPAndEOJ{completionAircon}}}
+	def := findCommandDefinition("set")
+
+	complete := func(line string) []string {
+		t.Helper()
+		buf := prompt.NewBuffer()
+		buf.InsertText(line, false, true)
+		d := *buf.Document()
+		var texts []string
+		for _, s := range prompt.FilterHasPrefix(def.GetCandidatesFunc(c, d), d.GetWordBeforeCursor(), true) {
+			texts = append(texts, s.Text)
+		}
+		return texts
+	}
+
+	// デバイスのクラスのエイリアスとプロパティ名
+	got := complete("set aircon o")
+	for _, want := range []string{"on", "off", "operation-status:", "operation-mode-setting:", "outside-temperature:"} {
+		if !slices.Contains(got, want) {
+			t.Errorf("set aircon o: %q not in %v", want, got)
+		}
+	}
+	if got := complete("set 192.168.1.10 0130:1 temp"); !slices.Equal(got, []string{"temperature-setting:"}) {
+		t.Errorf("unexpected candidates for a property name: %v", got)
+	}
+
+	// プロパティ名または EPC の後は値のエイリアス
+	for _, prefix := range []string{"operation-mode-setting", "B0"} {
+		if got := complete("set aircon " + prefix + ":a"); !slices.Equal(got, []string{prefix + ":auto"}) {
+			t.Errorf("unexpected value candidates for %s: %v", prefix, got)
+		}
+	}
+
+	// IPアドレスの次はデバイス指定子
+	if got := complete("set 192.168.1.10 01"); !slices.Contains(got, "0130:1") {
+		t.Errorf("expected EOJ candidates after an IP address, got %v", got)
+	}
+}
@@ -0,0 +1,64 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// textTable は、列を揃えて表を表示するためのヘルパー
+// 日本語などの全角文字を含んでも揃うよう、文字数ではなく端末上の表示幅で揃える
+type textTable struct {
+	header []string
+	rows   [][]string
+}
+
+func newTextTable(header ...string) *textTable {
+	return &textTable{header: header}
+}
+
+// AddRow は行を追加する（列数はヘッダーと同じであること）
+func (t *textTable) AddRow(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// Print は、ヘッダーと区切り線に続けて各行を出力する
+func (t *textTable) Print(w io.Writer) {
+	widths := make([]int, len(t.header))
+	for _, row := range append([][]string{t.header}, t.rows...) {
+		for i, cell := range row {
+			widths[i] = max(widths[i], runewidth.StringWidth(cell))
+		}
+	}
+
+	printRow := func(cells []string) {
+		// 行末に空白や区切りを残さないよう、末尾の空のセルは出力しない
+		for len(cells) > 0 && cells[len(cells)-1] == "" {
+			cells = cells[:len(cells)-1]
+		}
+		var b strings.Builder
+		for i, cell := range cells {
+			if i > 0 {
+				b.WriteString(" | ")
+			}
+			if i == len(cells)-1 {
+				b.WriteString(cell)
+			} else {
+				b.WriteString(runewidth.FillRight(cell, widths[i]))
+			}
+		}
+		fmt.Fprintln(w, b.String())
+	}
+
+	printRow(t.header)
+	separators := make([]string, len(widths))
+	for i, width := range widths {
+		separators[i] = strings.Repeat("-", width)
+	}
+	fmt.Fprintln(w, strings.Join(separators, "-+-"))
+	for _, row := range t.rows {
+		printRow(row)
+	}
+}
@@ -0,0 +1,124 @@
+package console
+
+import (
+	"echonet-list/client"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/term"
+)
+
+// watchFilter は、watch コマンドで表示するプロパティ変化の条件
+type watchFilter struct {
+	spec    client.DeviceSpecifier // 対象デバイスの指定（空の場合はすべてのデバイス）
+	devices []client.IPAndEOJ      // グループ指定時の対象デバイス（nil の場合は spec で判定する）
+	epcs    []client.EPCType       // 表示するEPC（空の場合はすべて）
+}
+
+// match は、プロパティ変化が表示の対象かどうかを返す
+func (f watchFilter) match(change client.PropertyChangeNotification) bool {
+	device := change.Device
+	if f.devices != nil {
+		if !slices.ContainsFunc(f.devices, func(d client.IPAndEOJ) bool { return d.Key() == device.Key() }) {
+			return false
+		}
+	} else {
+		if f.spec.IP != nil && !f.spec.IP.Equal(device.IP) {
+			return false
+		}
+		if f.spec.ClassCode != nil && *f.spec.ClassCode != device.EOJ.ClassCode() {
+			return false
+		}
+		if f.spec.InstanceCode != nil && *f.spec.InstanceCode != device.EOJ.InstanceCode() {
+			return false
+		}
+	}
+	return len(f.epcs) == 0 || slices.Contains(f.epcs, change.Property.EPC)
+}
+
+// processWatchCommand は、プロパティの変化を Enter・q・Ctrl-C が押されるまで表示し続ける
+func (p *CommandProcessor) processWatchCommand(cmd *Command) error {
+	devices, err := p.getGroupDevices(cmd)
+	if err != nil {
+		return err
+	}
+	filter := watchFilter{spec: cmd.DeviceSpec, devices: devices, epcs: cmd.EPCs}
+
+	changes, stopWatching := p.handler.WatchPropertyChanges()
+	defer stopWatching()
+
+	stop, newline, restore := watchStopKeys()
+	defer restore()
+
+	fmt.Print("プロパティの変化を表示しています（Enter, q, Ctrl-C で終了）", newline)
+	p.printPropertyChanges(os.Stdout, newline, changes, stop, filter)
+	return nil
+}
+
+// printPropertyChanges は、stop が閉じられるまで、filter に一致するプロパティ変化を1行ずつ出力する
+func (p *CommandProcessor) printPropertyChanges(w io.Writer, newline string, changes <-chan client.PropertyChangeNotification, stop <-chan struct{}, filter watchFilter) {
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-stop:
+			return
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			if filter.match(change) {
+				fmt.Fprint(w, p.formatPropertyChange(time.Now(), change), newline)
+			}
+		}
+	}
+}
+
+// formatPropertyChange は、プロパティ変化を「時刻 デバイス (エイリアス) EPC(名前):値」の形式にする
+func (p *CommandProcessor) formatPropertyChange(at time.Time, change client.PropertyChangeNotification) string {
+	var sb strings.Builder
+	sb.WriteString(at.Local().Format("15:04:05.000"))
+	sb.WriteString(" ")
+	sb.WriteString(change.Device.String())
+	if aliases := p.handler.GetAliases(change.Device); len(aliases) > 0 {
+		fmt.Fprintf(&sb, " (%s)", strings.Join(aliases, ", "))
+	}
+	sb.WriteString(" ")
+	sb.WriteString(change.Property.String(change.Device.EOJ.ClassCode()))
+	return sb.String()
+}
+
+// watchStopKeys は、Enter・q・Ctrl-C のいずれかが押されると閉じるチャンネルを返す
+// Ctrl-C でプログラム全体が終了しないように端末を raw モードにするため、その間の改行には newline を使う
+// 端末でない場合は、チャンネルは閉じられない（プログラムの終了時に watch も終了する）
+func watchStopKeys() (stop <-chan struct{}, newline string, restore func()) {
+	ch := make(chan struct{})
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return ch, "\n", func() {}
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return ch, "\n", func() {}
+	}
+
+	// 終了キーを読んだら読み込みをやめ、それ以降の入力はプロンプトに任せる
+	go func() {
+		defer close(ch)
+		buf := make([]byte, 1)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				return
+			}
+			if n == 1 && (buf[0] == '\r' || buf[0] == '\n' || buf[0] == 'q' || buf[0] == 0x03) {
+				return
+			}
+		}
+	}()
+	return ch, "\r\n", func() { _ = term.Restore(fd, state) }
+}
@@ -0,0 +1,83 @@
+package console
+
+import (
+	"strings"
+	"testing"
+
+	"echonet-list/client"
+	"echonet-list/echonet_lite"
+)
+
+// airconClientStub は1台のエアコンのプロパティを保持するクライアント
+type airconClientStub struct {
+	*historyClientStub
+	properties client.Properties
+}
+
+func (s *airconClientStub) SetProperties(device client.IPAndEOJ, properties client.Properties, _ []client.EPCType) (client.DeviceAndProperties, error) {
+	for _, p := range properties {
+		s.properties = s.properties.UpdateProperty(p)
+	}
+	return client.DeviceAndProperties{Device: device, Properties: properties}, nil
+}
+
+func (s *airconClientStub) GetProperties(device client.IPAndEOJ, epcs []client.EPCType, _ bool) (client.DeviceAndProperties, error) {
+	var result client.Properties
+	for _, epc := range epcs {
+		if p, ok := s.properties.FindEPC(epc); ok {
+			result = append(result, p)
+		}
+	}
+	return client.DeviceAndProperties{Device: device, Properties: result}, nil
+}
+
+func TestParseAirconCommand(t *testing.T) {
+	parser := NewCommandParser(stubPropertyDescProvider{}, stubAliasManager{}, stubGroupManager{})
+
+	cmd, err := parser.ParseCommand("aircon 192.168.1.10 cool 26 on", false)
+	if err != nil {
+		t.Fatalf("ParseCommand returned error: %v", err)
+	}
+	if cmd.Type != CmdAircon || cmd.GetClassCode() != echonet_lite.HomeAirConditioner_ClassCode {
+		t.Errorf("unexpected command: %+v", cmd)
+	}
+	if cmd.Aircon.Mode != client.AirConditionerModeCool || cmd.Aircon.Temperature == nil || *cmd.Aircon.Temperature != 26 || cmd.Aircon.Power == nil || !*cmd.Aircon.Power {
+		t.Errorf("unexpected options: %+v", cmd.Aircon)
+	}
+
+	cmd, err = parser.ParseCommand("aircon", false)
+	if err != nil || cmd.Aircon.Power != nil || cmd.Aircon.Mode != "" || cmd.Aircon.Temperature != nil {
+		t.Errorf("aircon without arguments must only show the state: %+v, %v", cmd, err)
+	}
+
+	for _, input := range []string{"aircon 0291 on", "aircon turbo", "aircon 60", "aircon @living on"} {
+		if _, err := parser.ParseCommand(input, false); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestProcessAirconCommand(t *testing.T) {
+	stub := &airconClientStub{
+		historyClientStub: &historyClientStub{devices: []client.IPAndEOJ{completionAircon}},
+		properties: client.Properties{
+			{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x31}},
+			{EPC: echonet_lite.EPC_HAC_CurrentRoomTemperature, EDT: []byte{28}},
+		},
+	}
+	processor := &CommandProcessor{handler: stub}
+
+	on := true
+	temperature := 25
+	output := captureOutput(func() {
+		cmd := &Command{Type: CmdAircon, Aircon: AirconOptions{Power: &on, Mode: client.AirConditionerModeHeat, Temperature: &temperature}}
+		if err := processor.processAirconCommand(cmd); err != nil {
+			t.Fatalf("processAirconCommand returned error: %v", err)
+		}
+	})
+	for _, want := range []string{"電源 on", "温度設定値 25℃", "室温 28℃", "湿度 -"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package console
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"echonet-list/client"
+)
+
+// cleanupClientStub は古いデバイスを返し、削除の指定を記録するクライアント
+type cleanupClientStub struct {
+	*historyClientStub
+	stale      []client.StaleDevice
+	unseenDays int
+	dryRun     bool
+}
+
+func (s *cleanupClientStub) CleanupDevices(unseenDays int, dryRun bool) ([]client.StaleDevice, error) {
+	s.unseenDays = unseenDays
+	s.dryRun = dryRun
+	return s.stale, nil
+}
+
+func TestParseCleanupCommand(t *testing.T) {
+	parser := NewCommandParser(stubPropertyDescProvider{}, stubAliasManager{}, stubGroupManager{})
+
+	tests := []struct {
+		input     string
+		days      int
+		confirmed bool
+	}{
+		{"cleanup 30", 30, false},
+		{"cleanup 30d -y", 30, true},
+		{"cleanup -y 7", 7, true},
+	}
+	for _, tt := range tests {
+		cmd, err := parser.ParseCommand(tt.input, false)
+		if err != nil {
+			t.Fatalf("ParseCommand(%q) returned error: %v", tt.input, err)
+		}
+		if cmd.Type != CmdCleanup || cmd.UnseenDays != tt.days || cmd.Confirmed != tt.confirmed {
+			t.Errorf("ParseCommand(%q) = %+v", tt.input, cmd)
+		}
+	}
+
+	for _, input := range []string{"cleanup", "cleanup -y", "cleanup 0", "cleanup abc", "cleanup 30 40"} {
+		if _, err := parser.ParseCommand(input, false); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestProcessCleanupCommand(t *testing.T) {
+	stub := &cleanupClientStub{
+		historyClientStub: &historyClientStub{},
+		stale:             []client.StaleDevice{{Device: completionAircon, LastSeen: time.Now().Add(-45 * 24 * time.Hour)}},
+	}
+	processor := &CommandProcessor{handler: stub}
+
+	// -y がなければ dry run で対象を表示し、削除の方法を案内する
+	output := captureOutput(func() {
+		if err := processor.processCleanupCommand(&Command{Type: CmdCleanup, UnseenDays: 30}); err != nil {
+			t.Fatalf("processCleanupCommand returned error: %v", err)
+		}
+	})
+	if !stub.dryRun || stub.unseenDays != 30 {
+		t.Errorf("expected a dry run for 30 days, got dryRun=%v days=%d", stub.dryRun, stub.unseenDays)
+	}
+	for _, want := range []string{"192.168.1.10", "45 日前", "cleanup 30 -y"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	output = captureOutput(func() {
+		_ = processor.processCleanupCommand(&Command{Type: CmdCleanup, UnseenDays: 30, Confirmed: true})
+	})
+	if stub.dryRun || !strings.Contains(output, "1 台のデバイスを削除しました") {
+		t.Errorf("expected the devices to be removed, got dryRun=%v:\n%s", stub.dryRun, output)
+	}
+}
@@ -0,0 +1,25 @@
+package console
+
+import "testing"
+
+func TestParseGetCommandAll(t *testing.T) {
+	parser := NewCommandParser(tablePropertyDescProvider{}, stubAliasManager{}, stubGroupManager{})
+
+	// -all はEPCを指定せず、取得可能なすべてのプロパティを取得する
+	cmd, err := parser.ParseCommand("get 192.168.1.10 0130:1 -all", false)
+	if err != nil {
+		t.Fatalf("ParseCommand returned error: %v", err)
+	}
+	if !cmd.AllProperties || len(cmd.EPCs) != 0 {
+		t.Errorf("unexpected command: AllProperties=%v EPCs=%v", cmd.AllProperties, cmd.EPCs)
+	}
+
+	for _, input := range []string{
+		"get 192.168.1.10 0130:1",         // EPCも -all もない
+		"get 192.168.1.10 0130:1 80 -all", // 両方を指定
+	} {
+		if _, err := parser.ParseCommand(input, false); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
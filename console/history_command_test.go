@@ -77,6 +77,51 @@ func TestParseHistoryCommand(t *testing.T) {
 	}
 }
 
+func TestParseHistoryCommandWithEPCAndSince(t *testing.T) {
+	parser := NewCommandParser(stubPropertyDescProvider{}, stubAliasManager{}, stubGroupManager{})
+
+	before := time.Now()
+	cmd, err := parser.ParseCommand("history 192.168.1.20 0130:1 BB -since 24h", false)
+	if err != nil {
+		t.Fatalf("ParseCommand returned error: %v", err)
+	}
+	if cmd.HistoryOptions.EPC != 0xBB {
+		t.Fatalf("expected EPC 0xBB, got 0x%02X", byte(cmd.HistoryOptions.EPC))
+	}
+	if cmd.HistoryOptions.Limit != defaultHistoryLimit {
+		t.Fatalf("expected default limit, got %d", cmd.HistoryOptions.Limit)
+	}
+	// プロパティ指定時は書き込み不可のプロパティも対象にする
+	if cmd.HistoryOptions.SettableOnly == nil || *cmd.HistoryOptions.SettableOnly {
+		t.Fatalf("expected settableOnly=false, got %v", cmd.HistoryOptions.SettableOnly)
+	}
+	if since := cmd.HistoryOptions.Since; since.Before(before.Add(-24*time.Hour)) || since.After(time.Now().Add(-24*time.Hour)) {
+		t.Fatalf("expected since 24h ago, got %v", since)
+	}
+
+	cmd, err = parser.ParseCommand("history 192.168.1.20 0130:1 -since 2024-05-01T12:00:00Z", false)
+	if err != nil {
+		t.Fatalf("ParseCommand returned error: %v", err)
+	}
+	if !cmd.HistoryOptions.Since.Equal(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected since: %v", cmd.HistoryOptions.Since)
+	}
+	if cmd.HistoryOptions.EPC != 0 || cmd.HistoryOptions.SettableOnly != nil {
+		t.Fatalf("expected no EPC filter and default settableOnly, got %+v", cmd.HistoryOptions)
+	}
+
+	for _, line := range []string{
+		"history 192.168.1.20 0130:1 XYZ",
+		"history 192.168.1.20 0130:1 -since yesterday",
+		"history 192.168.1.20 0130:1 -since -1h",
+		"history 192.168.1.20 0130:1 -since",
+	} {
+		if _, err := parser.ParseCommand(line, false); err == nil {
+			t.Errorf("%q: expected an error", line)
+		}
+	}
+}
+
 type historyClientStub struct {
 	devices        []client.IPAndEOJ
 	historyEntries []client.DeviceHistoryEntry
@@ -84,15 +129,17 @@ type historyClientStub struct {
 	lastOptions    client.DeviceHistoryOptions
 }
 
-func (s *historyClientStub) IsDebug() bool                                 { return false }
-func (s *historyClientStub) SetDebug(bool)                                 {}
-func (s *historyClientStub) DebugSetOffline(string, bool) error            { return nil }
-func (s *historyClientStub) IsOfflineDevice(client.IPAndEOJ) bool          { return false }
-func (s *historyClientStub) AliasList() []client.AliasIDStringPair         { return nil }
-func (s *historyClientStub) AliasSet(*string, client.FilterCriteria) error { return nil }
-func (s *historyClientStub) AliasDelete(*string) error                     { return nil }
-func (s *historyClientStub) AliasGet(*string) (*client.IPAndEOJ, error)    { return nil, nil }
-func (s *historyClientStub) GetAliases(client.IPAndEOJ) []string           { return nil }
+func (s *historyClientStub) IsDebug() bool                                          { return false }
+func (s *historyClientStub) SetDebug(bool)                                          {}
+func (s *historyClientStub) DebugSetOffline(string, bool) error                     { return nil }
+func (s *historyClientStub) DebugPendingRequests() ([]client.PendingRequest, error) { return nil, nil }
+func (s *historyClientStub) DebugCancelRequest(uint64) error                        { return nil }
+func (s *historyClientStub) IsOfflineDevice(client.IPAndEOJ) bool                   { return false }
+func (s *historyClientStub) AliasList() []client.AliasIDStringPair                  { return nil }
+func (s *historyClientStub) AliasSet(*string, client.FilterCriteria) error          { return nil }
+func (s *historyClientStub) AliasDelete(*string) error                              { return nil }
+func (s *historyClientStub) AliasGet(*string) (*client.IPAndEOJ, error)             { return nil, nil }
+func (s *historyClientStub) GetAliases(client.IPAndEOJ) []string                    { return nil }
 func (s *historyClientStub) GetDeviceByAlias(string) (client.IPAndEOJ, bool) {
 	return client.IPAndEOJ{}, false
 }
@@ -107,16 +154,23 @@ func (s *historyClientStub) ListDevices(client.FilterCriteria) []client.DeviceAn
 func (s *historyClientStub) GetProperties(client.IPAndEOJ, []client.EPCType, bool) (client.DeviceAndProperties, error) {
 	return client.DeviceAndProperties{}, nil
 }
-func (s *historyClientStub) SetProperties(client.IPAndEOJ, client.Properties) (client.DeviceAndProperties, error) {
+func (s *historyClientStub) SetProperties(client.IPAndEOJ, client.Properties, []client.EPCType) (client.DeviceAndProperties, error) {
 	return client.DeviceAndProperties{}, nil
 }
+func (s *historyClientStub) SetGetProperties(client.IPAndEOJ, client.Properties, []client.EPCType) (client.SetGetResult, error) {
+	return client.SetGetResult{}, nil
+}
 func (s *historyClientStub) GetDeviceHistory(device client.IPAndEOJ, opts client.DeviceHistoryOptions) ([]client.DeviceHistoryEntry, error) {
 	s.lastDevice = &device
 	s.lastOptions = opts
 	return append([]client.DeviceHistoryEntry(nil), s.historyEntries...), nil
 }
-func (s *historyClientStub) FindDeviceByIDString(client.IDString) *client.IPAndEOJ { return nil }
-func (s *historyClientStub) GetIDString(client.IPAndEOJ) client.IDString           { return "" }
+func (s *historyClientStub) FindDeviceByIDString(client.IDString) *client.IPAndEOJ  { return nil }
+func (s *historyClientStub) GetIDString(client.IPAndEOJ) client.IDString            { return "" }
+func (s *historyClientStub) CleanupDevices(int, bool) ([]client.StaleDevice, error) { return nil, nil }
+func (s *historyClientStub) RefreshPropertyMap(client.IPAndEOJ) (client.PropertyMapChange, error) {
+	return client.PropertyMapChange{}, nil
+}
 func (s *historyClientStub) GetAllPropertyAliases() map[string]client.PropertyDescription {
 	return nil
 }
@@ -133,12 +187,39 @@ func (s *historyClientStub) FindPropertyAlias(client.EOJClassCode, string) (clie
 func (s *historyClientStub) AvailablePropertyAliases(client.EOJClassCode) map[string]client.PropertyDescription {
 	return nil
 }
-func (s *historyClientStub) GroupList(*string) []client.GroupDevicePair         { return nil }
-func (s *historyClientStub) GroupAdd(string, []client.IDString) error           { return nil }
-func (s *historyClientStub) GroupRemove(string, []client.IDString) error        { return nil }
-func (s *historyClientStub) GroupDelete(string) error                           { return nil }
-func (s *historyClientStub) GetDevicesByGroup(string) ([]client.IDString, bool) { return nil, false }
-func (s *historyClientStub) Close() error                                       { return nil }
+func (s *historyClientStub) GroupList(*string) []client.GroupDevicePair          { return nil }
+func (s *historyClientStub) GroupAdd(string, []client.IDString) error            { return nil }
+func (s *historyClientStub) GroupRemove(string, []client.IDString) error         { return nil }
+func (s *historyClientStub) GroupDelete(string) error                            { return nil }
+func (s *historyClientStub) GetDevicesByGroup(string) ([]client.IDString, bool)  { return nil, false }
+func (s *historyClientStub) SceneList(*string) []client.SceneActionsPair         { return nil }
+func (s *historyClientStub) SceneAdd(string, []client.SceneAction) error         { return nil }
+func (s *historyClientStub) SceneDelete(string) error                            { return nil }
+func (s *historyClientStub) GetScene(string) ([]client.SceneAction, bool)        { return nil, false }
+func (s *historyClientStub) SceneSetSequence(string, client.SceneSequence) error { return nil }
+func (s *historyClientStub) GetSceneSequence(string) client.SceneSequence {
+	return client.SceneSequence{}
+}
+func (s *historyClientStub) SceneRun(string) ([]client.SceneRunResult, error) { return nil, nil }
+func (s *historyClientStub) ScheduleList() []client.Schedule                  { return nil }
+func (s *historyClientStub) ScheduleSet(client.Schedule) error                { return nil }
+func (s *historyClientStub) ScheduleDelete(string) error                      { return nil }
+func (s *historyClientStub) ScheduleSetDisabled(string, bool) error           { return nil }
+func (s *historyClientStub) LocalPropertyMaps() ([]client.LocalPropertyMap, error) {
+	return nil, nil
+}
+func (s *historyClientStub) SetLocalPropertyMap(client.EOJ, client.PropertyMapType, []client.EPCType) error {
+	return nil
+}
+func (s *historyClientStub) PollExclusions() ([]string, error)  { return nil, nil }
+func (s *historyClientStub) SetPollExcluded(string, bool) error { return nil }
+func (s *historyClientStub) ScheduleNextRun(client.Schedule, time.Time) time.Time {
+	return time.Time{}
+}
+func (s *historyClientStub) Close() error { return nil }
+func (s *historyClientStub) WatchPropertyChanges() (<-chan client.PropertyChangeNotification, func()) {
+	return make(chan client.PropertyChangeNotification), func() {}
+}
 func (s *historyClientStub) GetLocationSettings() (map[string]string, []string) { return nil, nil }
 func (s *historyClientStub) LocationAliasAdd(string, string) error              { return nil }
 func (s *historyClientStub) LocationAliasDelete(string) error                   { return nil }
@@ -159,6 +240,19 @@ func TestProcessHistoryCommand(t *testing.T) {
 				Value:     protocol.PropertyData{String: "on"},
 				Origin:    protocol.HistoryOriginSet,
 			},
+			{
+				// 文字列のない値はクライアントのプロパティ定義でデコードする
+				Timestamp: time.Date(2024, 5, 1, 12, 5, 0, 0, time.UTC),
+				EPC:       0xB0,
+				Value:     protocol.PropertyData{EDT: "Qg=="},
+				Origin:    protocol.HistoryOriginNotification,
+			},
+			{
+				Timestamp: time.Date(2024, 5, 1, 12, 10, 0, 0, time.UTC),
+				EPC:       0xF0,
+				Value:     protocol.PropertyData{EDT: "AQI="},
+				Origin:    protocol.HistoryOriginNotification,
+			},
 		},
 	}
 
@@ -191,9 +285,15 @@ func TestProcessHistoryCommand(t *testing.T) {
 	if !strings.Contains(output, "History for") {
 		t.Fatalf("expected output to contain history header, got: %s", output)
 	}
-	if !strings.Contains(output, "value=on") {
+	if !strings.Contains(output, "TIME") || !strings.Contains(output, "| SETTABLE") {
+		t.Fatalf("expected output to contain the table header, got: %s", output)
+	}
+	if !strings.Contains(output, "| on ") {
 		t.Fatalf("expected output to contain value string, got: %s", output)
 	}
+	if !strings.Contains(output, "| cooling ") || !strings.Contains(output, "| 0102 ") {
+		t.Fatalf("expected EDT-only values to be decoded or shown in hex, got: %s", output)
+	}
 
 	if stub.lastOptions.SettableOnly != nil {
 		t.Fatalf("expected default settableOnly to be nil")
@@ -272,33 +372,35 @@ func TestProcessHistoryCommandWithOnlineOfflineEvents(t *testing.T) {
 	}
 
 	// Verify property change entry is still displayed correctly
-	if !strings.Contains(output, "value=on") {
+	if !strings.Contains(output, "| on ") {
 		t.Fatalf("expected output to contain property value, got: %s", output)
 	}
 
-	// Verify event entries do not show "value=" field
+	// Verify event entries leave the value column empty
 	lines := strings.Split(output, "\n")
 	onlineEventFound := false
 	offlineEventFound := false
 	for _, line := range lines {
-		if strings.Contains(line, "online") && strings.Contains(line, "origin=online") {
+		cells := strings.Split(line, " | ")
+		if len(cells) < 4 {
+			continue
+		}
+		if strings.Contains(cells[1], "online") && strings.TrimSpace(cells[3]) == "online" {
 			onlineEventFound = true
-			// Event entries should not display "value=" field
-			if strings.Contains(line, "value=") {
-				t.Fatalf("online event should not display value field, got: %s", line)
+			if strings.TrimSpace(cells[2]) != "" {
+				t.Fatalf("online event should not display a value, got: %s", line)
 			}
 		}
-		if strings.Contains(line, "offline") && strings.Contains(line, "origin=offline") {
+		if strings.Contains(cells[1], "offline") && strings.TrimSpace(cells[3]) == "offline" {
 			offlineEventFound = true
-			// Event entries should not display "value=" field
-			if strings.Contains(line, "value=") {
-				t.Fatalf("offline event should not display value field, got: %s", line)
+			if strings.TrimSpace(cells[2]) != "" {
+				t.Fatalf("offline event should not display a value, got: %s", line)
 			}
 		}
 	}
 
 	if !onlineEventFound {
-		t.Fatalf("expected to find online event entry in output")
+		t.Fatalf("expected to find online event entry in output, got: %s", output)
 	}
 	if !offlineEventFound {
 		t.Fatalf("expected to find offline event entry in output")
@@ -0,0 +1,83 @@
+package console
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"echonet-list/client"
+	"echonet-list/echonet_lite"
+)
+
+// lightClientStub は照明のプロパティを保持し、クラスコードでデバイスを絞り込むクライアント
+type lightClientStub struct {
+	*airconClientStub
+}
+
+func (s *lightClientStub) GetDevices(spec client.DeviceSpecifier) []client.IPAndEOJ {
+	var result []client.IPAndEOJ
+	for _, device := range s.devices {
+		if spec.ClassCode == nil || device.EOJ.ClassCode() == *spec.ClassCode {
+			result = append(result, device)
+		}
+	}
+	return result
+}
+
+var completionLight = client.IPAndEOJ{IP: net.ParseIP("192.168.1.20"), EOJ: echonet_lite.MakeEOJ(echonet_lite.GeneralLighting_ClassCode, 1)}
+
+func TestParseLightCommand(t *testing.T) {
+	parser := NewCommandParser(stubPropertyDescProvider{}, stubAliasManager{}, stubGroupManager{})
+
+	cmd, err := parser.ParseCommand("light 192.168.1.20 30% warm on", false)
+	if err != nil {
+		t.Fatalf("ParseCommand returned error: %v", err)
+	}
+	if cmd.Type != CmdLight || cmd.DeviceSpec.ClassCode != nil {
+		t.Errorf("unexpected command: %+v", cmd)
+	}
+	if cmd.Light.Color != client.LightColorIncandescent || cmd.Light.Brightness == nil || *cmd.Light.Brightness != 30 || cmd.Light.Power == nil || !*cmd.Light.Power {
+		t.Errorf("unexpected options: %+v", cmd.Light)
+	}
+
+	cmd, err = parser.ParseCommand("light 0291 80", false)
+	if err != nil || cmd.GetClassCode() != echonet_lite.SingleFunctionLighting_ClassCode || *cmd.Light.Brightness != 80 {
+		t.Errorf("unexpected command: %+v, %v", cmd, err)
+	}
+
+	for _, input := range []string{"light 0130 on", "light purple", "light 101%", "light 0291 white", "light @living on"} {
+		if _, err := parser.ParseCommand(input, false); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestProcessLightCommand(t *testing.T) {
+	stub := &lightClientStub{&airconClientStub{
+		historyClientStub: &historyClientStub{devices: []client.IPAndEOJ{completionAircon, completionLight}},
+		properties: client.Properties{
+			{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x31}},
+		},
+	}}
+	processor := &CommandProcessor{handler: stub}
+
+	on := true
+	brightness := 60
+	output := captureOutput(func() {
+		cmd := &Command{Type: CmdLight, Light: LightOptions{Power: &on, Brightness: &brightness, Color: client.LightColorWhite}}
+		if err := processor.processLightCommand(cmd); err != nil {
+			t.Fatalf("processLightCommand returned error: %v", err)
+		}
+	})
+	for _, want := range []string{completionLight.String(), "電源 on", "照度レベル 60%", "光色 white"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	// 照明が複数ある場合はエラー
+	stub.devices = append(stub.devices, client.IPAndEOJ{IP: net.ParseIP("192.168.1.21"), EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)})
+	if err := processor.processLightCommand(&Command{Type: CmdLight}); err == nil {
+		t.Error("expected an error for more than one light")
+	}
+}
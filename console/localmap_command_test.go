@@ -0,0 +1,102 @@
+package console
+
+import (
+	"strings"
+	"testing"
+
+	"echonet-list/client"
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+
+	"golang.org/x/exp/slices"
+)
+
+// localMapClientStub は自ノードのプロパティマップを保持するクライアント
+type localMapClientStub struct {
+	*historyClientStub
+	maps    []client.LocalPropertyMap
+	setEOJ  client.EOJ
+	setType client.PropertyMapType
+	setEPCs []client.EPCType
+}
+
+func (s *localMapClientStub) LocalPropertyMaps() ([]client.LocalPropertyMap, error) {
+	return s.maps, nil
+}
+
+func (s *localMapClientStub) SetLocalPropertyMap(eoj client.EOJ, mapType client.PropertyMapType, epcs []client.EPCType) error {
+	s.setEOJ, s.setType, s.setEPCs = eoj, mapType, epcs
+	return nil
+}
+
+func TestParseLocalMapCommand(t *testing.T) {
+	parser := NewCommandParser(stubPropertyDescProvider{}, stubAliasManager{}, stubGroupManager{})
+
+	cmd, err := parser.ParseCommand("localmap", false)
+	if err != nil || cmd.Type != CmdLocalMapList {
+		t.Fatalf("unexpected command: %+v, %v", cmd, err)
+	}
+
+	cmd, err = parser.ParseCommand("localmap remove 05FF:1 announce 81", false)
+	if err != nil {
+		t.Fatalf("ParseCommand returned error: %v", err)
+	}
+	if cmd.Type != CmdLocalMapChange || cmd.LocalMapAction != "remove" || cmd.LocalMapType != handler.StatusAnnouncementPropertyMap ||
+		cmd.LocalEOJ != echonet_lite.MakeEOJ(echonet_lite.Controller_ClassCode, 1) || len(cmd.EPCs) != 1 || cmd.EPCs[0] != 0x81 {
+		t.Fatalf("unexpected command: %+v", cmd)
+	}
+
+	for _, input := range []string{"localmap drop 05FF:1 get", "localmap set 05FF:1", "localmap set 05FF get", "localmap set 05FF:1 inf", "localmap add 05FF:1 get", "localmap reset 05FF:1 get 80"} {
+		if _, err := parser.ParseCommand(input, false); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestProcessLocalMapCommands(t *testing.T) {
+	controller := echonet_lite.MakeEOJ(echonet_lite.Controller_ClassCode, 1)
+	announce := make(handler.PropertyMap)
+	announce.Set(echonet_lite.EPCInstallationLocation)
+	stub := &localMapClientStub{
+		historyClientStub: &historyClientStub{},
+		maps: []client.LocalPropertyMap{{
+			EOJ:        controller,
+			Get:        handler.PropertyMap{echonet_lite.EPCOperationStatus: {}, echonet_lite.EPCInstallationLocation: {}},
+			Set:        handler.PropertyMap{echonet_lite.EPCInstallationLocation: {}},
+			Announce:   announce,
+			Overridden: []client.PropertyMapType{handler.SetPropertyMap},
+		}},
+	}
+	processor := &CommandProcessor{handler: stub}
+
+	output := captureOutput(func() {
+		if err := processor.processLocalMapListCommand(); err != nil {
+			t.Fatalf("processLocalMapListCommand returned error: %v", err)
+		}
+	})
+	for _, want := range []string{"05FF", "set     （変更）: 81", "announce: 81"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	// add は現在のプロパティマップにEPCを追加する
+	captureOutput(func() {
+		err := processor.processLocalMapChangeCommand(&Command{LocalEOJ: controller, LocalMapType: handler.StatusAnnouncementPropertyMap, LocalMapAction: "add", EPCs: []client.EPCType{echonet_lite.EPCOperationStatus}})
+		if err != nil {
+			t.Fatalf("processLocalMapChangeCommand returned error: %v", err)
+		}
+	})
+	slices.Sort(stub.setEPCs)
+	if stub.setType != handler.StatusAnnouncementPropertyMap || !slices.Equal(stub.setEPCs, []client.EPCType{echonet_lite.EPCOperationStatus, echonet_lite.EPCInstallationLocation}) {
+		t.Errorf("unexpected change: %v %v", stub.setType, stub.setEPCs)
+	}
+
+	// reset は nil で初期状態に戻す
+	captureOutput(func() {
+		_ = processor.processLocalMapChangeCommand(&Command{LocalEOJ: controller, LocalMapType: handler.SetPropertyMap, LocalMapAction: "reset"})
+	})
+	if stub.setEPCs != nil {
+		t.Errorf("reset passed %v, want nil", stub.setEPCs)
+	}
+}
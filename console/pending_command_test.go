@@ -0,0 +1,98 @@
+package console
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"echonet-list/client"
+	"echonet-list/echonet_lite"
+)
+
+// pendingClientStub は応答待ちの要求を返し、取り消された番号を記録するクライアント
+type pendingClientStub struct {
+	*historyClientStub
+	requests  []client.PendingRequest
+	cancelled []uint64
+}
+
+func (s *pendingClientStub) DebugPendingRequests() ([]client.PendingRequest, error) {
+	return s.requests, nil
+}
+
+func (s *pendingClientStub) DebugCancelRequest(id uint64) error {
+	s.cancelled = append(s.cancelled, id)
+	return nil
+}
+
+func TestParsePendingCommand(t *testing.T) {
+	parser := NewCommandParser(stubPropertyDescProvider{}, stubAliasManager{}, stubGroupManager{})
+
+	cmd, err := parser.ParseCommand("pending 192.168.1.10 0130", false)
+	if err != nil {
+		t.Fatalf("ParseCommand returned error: %v", err)
+	}
+	if cmd.Type != CmdPending || cmd.DeviceSpec.IP == nil || cmd.DeviceSpec.ClassCode == nil {
+		t.Fatalf("unexpected command: %+v", cmd)
+	}
+
+	cmd, err = parser.ParseCommand("pending cancel 12", false)
+	if err != nil {
+		t.Fatalf("ParseCommand returned error: %v", err)
+	}
+	if cmd.Type != CmdPendingCancel || cmd.RequestID != 12 {
+		t.Fatalf("unexpected command: %+v", cmd)
+	}
+
+	for _, input := range []string{"pending cancel", "pending cancel abc", "pending cancel 1 2", "pending @group"} {
+		if _, err := parser.ParseCommand(input, false); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestProcessPendingCommand(t *testing.T) {
+	other := client.IPAndEOJ{IP: net.ParseIP("192.168.1.20"), EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)}
+	now := time.Now()
+	stub := &pendingClientStub{
+		historyClientStub: &historyClientStub{devices: []client.IPAndEOJ{completionAircon}},
+		requests: []client.PendingRequest{
+			{ID: 3, Device: completionAircon, ESV: echonet_lite.ESVSetC, EPCs: []client.EPCType{0x80, 0xB3}, State: "retrying", Retries: 2, MaxRetries: 3, StartTime: now.Add(-10 * time.Second), NextRetry: now.Add(4 * time.Second)},
+			{ID: 4, Device: other, ESV: echonet_lite.ESVGet, EPCs: []client.EPCType{0x80}, State: "sending", MaxRetries: 3, StartTime: now},
+		},
+	}
+	processor := &CommandProcessor{handler: stub}
+
+	// 全デバイスの要求がデバイスごとに表示される
+	output := captureOutput(func() {
+		if err := processor.processPendingCommand(&Command{Type: CmdPending}); err != nil {
+			t.Fatalf("processPendingCommand returned error: %v", err)
+		}
+	})
+	for _, want := range []string{"192.168.1.10", "#3 SetC [80 B3] retrying 再送 2/3", "次の再送まで", "192.168.1.20", "#4 Get [80] sending 再送 0/3"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	if strings.Count(output, "次の再送まで") != 1 {
+		t.Errorf("next retry should be shown only for waiting requests, got:\n%s", output)
+	}
+
+	// デバイスを指定すると、そのデバイスへの要求だけを表示する
+	class := echonet_lite.HomeAirConditioner_ClassCode
+	output = captureOutput(func() {
+		_ = processor.processPendingCommand(&Command{Type: CmdPending, DeviceSpec: client.DeviceSpecifier{ClassCode: &class}})
+	})
+	if !strings.Contains(output, "#3") || strings.Contains(output, "#4") {
+		t.Errorf("unexpected filtered output:\n%s", output)
+	}
+
+	stub.requests = nil
+	output = captureOutput(func() {
+		_ = processor.processPendingCommand(&Command{Type: CmdPending})
+	})
+	if !strings.Contains(output, "応答待ちの要求はありません") {
+		t.Errorf("unexpected output for no requests:\n%s", output)
+	}
+}
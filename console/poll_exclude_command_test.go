@@ -0,0 +1,75 @@
+package console
+
+import (
+	"strings"
+	"testing"
+)
+
+// pollExcludeClientStub は定期更新の除外を記録するクライアント
+type pollExcludeClientStub struct {
+	*historyClientStub
+	exclusions []string
+	target     string
+	excluded   bool
+}
+
+func (s *pollExcludeClientStub) PollExclusions() ([]string, error) {
+	return s.exclusions, nil
+}
+
+func (s *pollExcludeClientStub) SetPollExcluded(target string, excluded bool) error {
+	s.target, s.excluded = target, excluded
+	return nil
+}
+
+func TestParsePollExcludeCommand(t *testing.T) {
+	parser := NewCommandParser(stubPropertyDescProvider{}, stubAliasManager{}, stubGroupManager{})
+
+	cmd, err := parser.ParseCommand("poll-exclude", false)
+	if err != nil || cmd.Type != CmdPollExcludeList {
+		t.Fatalf("unexpected command: %+v, %v", cmd, err)
+	}
+
+	cmd, err = parser.ParseCommand("poll-exclude add 192.168.1.50 0279:1", false)
+	if err != nil {
+		t.Fatalf("ParseCommand returned error: %v", err)
+	}
+	if cmd.Type != CmdPollExcludeChange || !cmd.PollExcluded || cmd.PollTarget != "192.168.1.50 0279:1" {
+		t.Fatalf("unexpected command: %+v", cmd)
+	}
+
+	cmd, err = parser.ParseCommand("poll-exclude remove 0279", false)
+	if err != nil || cmd.PollExcluded || cmd.PollTarget != "0279" {
+		t.Fatalf("unexpected command: %+v, %v", cmd, err)
+	}
+
+	for _, input := range []string{"poll-exclude add", "poll-exclude drop 0279"} {
+		if _, err := parser.ParseCommand(input, false); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestProcessPollExcludeCommands(t *testing.T) {
+	stub := &pollExcludeClientStub{historyClientStub: &historyClientStub{}, exclusions: []string{"0279", "solar"}}
+	processor := &CommandProcessor{handler: stub}
+
+	output := captureOutput(func() {
+		if err := processor.processPollExcludeListCommand(); err != nil {
+			t.Fatalf("processPollExcludeListCommand returned error: %v", err)
+		}
+	})
+	if !strings.Contains(output, "0279 (") || !strings.Contains(output, "solar") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+
+	cmd := &Command{PollTarget: "solar", PollExcluded: false}
+	captureOutput(func() {
+		if err := processor.processPollExcludeChangeCommand(cmd); err != nil {
+			t.Fatalf("processPollExcludeChangeCommand returned error: %v", err)
+		}
+	})
+	if stub.target != "solar" || stub.excluded {
+		t.Errorf("unexpected change: %q, %v", stub.target, stub.excluded)
+	}
+}
@@ -0,0 +1,39 @@
+package console
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSceneSequenceCommand(t *testing.T) {
+	parser := NewCommandParser(tablePropertyDescProvider{}, stubAliasManager{}, stubGroupManager{})
+
+	cmd, err := parser.ParseCommand("scene sequence おはよう 1500ms", false)
+	if err != nil {
+		t.Fatalf("ParseCommand returned error: %v", err)
+	}
+	if cmd.Type != CmdSceneSequence || *cmd.SceneName != "おはよう" || cmd.SceneDelay != 1500*time.Millisecond {
+		t.Errorf("unexpected command: %+v", cmd)
+	}
+
+	// off は順次実行を解除する
+	cmd, err = parser.ParseCommand("scene sequence おはよう off", false)
+	if err != nil {
+		t.Fatalf("ParseCommand returned error: %v", err)
+	}
+	if cmd.SceneDelay != 0 {
+		t.Errorf("SceneDelay = %v, want 0", cmd.SceneDelay)
+	}
+
+	for _, input := range []string{
+		"scene sequence おはよう",       // 間隔がない
+		"scene sequence おはよう 0s",    // 0 は off で指定する
+		"scene sequence おはよう 2m",    // 上限を超える
+		"scene sequence おはよう later", // 間隔として解釈できない
+		"scene sequence @group 2s",  // シーン名として使えない
+	} {
+		if _, err := parser.ParseCommand(input, false); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package console
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"echonet-list/client"
+	"echonet-list/echonet_lite"
+)
+
+// versionsClientStub はキャッシュ済みのプロパティ付きのデバイスを返すクライアント
+type versionsClientStub struct {
+	*historyClientStub
+	listed []client.DeviceAndProperties
+}
+
+func (s *versionsClientStub) ListDevices(client.FilterCriteria) []client.DeviceAndProperties {
+	return s.listed
+}
+
+func TestParseVersionsCommand(t *testing.T) {
+	parser := NewCommandParser(stubPropertyDescProvider{}, stubAliasManager{}, stubGroupManager{})
+
+	cmd, err := parser.ParseCommand("versions 192.168.1.10 0130 -o json", false)
+	if err != nil {
+		t.Fatalf("ParseCommand returned error: %v", err)
+	}
+	if cmd.Type != CmdVersions || cmd.DeviceSpec.IP == nil || cmd.DeviceSpec.ClassCode == nil || cmd.Output != OutputJSON {
+		t.Fatalf("unexpected command: %+v", cmd)
+	}
+
+	for _, input := range []string{"versions @group", "versions 0130 extra", "versions -o xml"} {
+		if _, err := parser.ParseCommand(input, false); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestProcessVersionsCommand(t *testing.T) {
+	light := client.IPAndEOJ{IP: net.ParseIP("192.168.1.20"), EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)}
+	stub := &versionsClientStub{
+		historyClientStub: &historyClientStub{},
+		listed: []client.DeviceAndProperties{
+			{Device: completionAircon, Properties: client.Properties{
+				{EPC: echonet_lite.EPCStandardVersion, EDT: []byte{0, 0, 'R', 1}},
+				{EPC: echonet_lite.EPCManufacturerCode, EDT: []byte{0x00, 0x00, 0x0b}},
+				{EPC: echonet_lite.EPCProductCode, EDT: []byte("RAS-X40")},
+			}},
+			{Device: light},
+		},
+	}
+	processor := &CommandProcessor{handler: stub}
+
+	// 取得できなかった値は - で表示し、最後にReleaseごとのデバイス数を表示する
+	output := captureOutput(func() {
+		if err := processor.processVersionsCommand(&Command{Type: CmdVersions}); err != nil {
+			t.Fatalf("processVersionsCommand returned error: %v", err)
+		}
+	})
+	for _, want := range []string{"192.168.1.10 0130:1", "R Rev.1", "パナソニック", "RAS-X40", "192.168.1.20 0291:1", "Release別: -: 1, R: 1"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	output = captureOutput(func() {
+		_ = processor.processVersionsCommand(&Command{Type: CmdVersions, Output: OutputJSON})
+	})
+	if !strings.Contains(output, `"standardVersion": "Release R Rev.1"`) || !strings.Contains(output, `"target": "192.168.1.20 0291:1"`) {
+		t.Errorf("unexpected JSON output:\n%s", output)
+	}
+}
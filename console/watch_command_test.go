@@ -0,0 +1,65 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"echonet-list/client"
+	"echonet-list/echonet_lite"
+)
+
+func TestParseWatchCommand(t *testing.T) {
+	parser := NewCommandParser(stubPropertyDescProvider{}, stubAliasManager{}, stubGroupManager{})
+
+	cmd, err := parser.ParseCommand("watch 192.168.1.20 0130 80 B3", false)
+	if err != nil {
+		t.Fatalf("ParseCommand returned error: %v", err)
+	}
+	if cmd.Type != CmdWatch {
+		t.Fatalf("expected CmdWatch, got %v", cmd.Type)
+	}
+	if cmd.DeviceSpec.IP == nil || cmd.DeviceSpec.ClassCode == nil || *cmd.DeviceSpec.ClassCode != 0x0130 {
+		t.Errorf("unexpected device spec: %v", cmd.DeviceSpec)
+	}
+	if len(cmd.EPCs) != 2 || cmd.EPCs[0] != 0x80 || cmd.EPCs[1] != 0xB3 {
+		t.Errorf("unexpected EPCs: %v", cmd.EPCs)
+	}
+
+	// 引数なしはすべてのデバイスを表示する
+	cmd, err = parser.ParseCommand("watch", false)
+	if err != nil || cmd.DeviceSpec.ClassCode != nil || len(cmd.EPCs) != 0 {
+		t.Errorf("unexpected result for watch without arguments: %+v %v", cmd, err)
+	}
+
+	if _, err := parser.ParseCommand("watch 0130 not-an-epc", false); err == nil {
+		t.Error("expected error for invalid EPC")
+	}
+}
+
+func TestPrintPropertyChanges(t *testing.T) {
+	aircon := client.IPAndEOJ{IP: parseIP(t, "192.168.1.20"), EOJ: echonet_lite.MakeEOJ(0x0130, 1)}
+	light := client.IPAndEOJ{IP: parseIP(t, "192.168.1.21"), EOJ: echonet_lite.MakeEOJ(0x0291, 1)}
+	classCode := client.EOJClassCode(0x0130)
+
+	processor := &CommandProcessor{handler: &historyClientStub{}, ctx: context.Background()}
+	changes := make(chan client.PropertyChangeNotification, 3)
+	changes <- client.PropertyChangeNotification{Device: aircon, Property: echonet_lite.Property{EPC: 0x80, EDT: []byte{0x30}}}
+	changes <- client.PropertyChangeNotification{Device: light, Property: echonet_lite.Property{EPC: 0x80, EDT: []byte{0x31}}}
+	changes <- client.PropertyChangeNotification{Device: aircon, Property: echonet_lite.Property{EPC: 0xBB, EDT: []byte{0x19}}}
+	close(changes)
+
+	var out bytes.Buffer
+	filter := watchFilter{spec: client.DeviceSpecifier{ClassCode: &classCode}, epcs: []client.EPCType{0x80}}
+	processor.printPropertyChanges(&out, "\n", changes, make(chan struct{}), filter)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line, got %q", out.String())
+	}
+	// 値は16進数ではなく解釈した形で表示する
+	if !strings.Contains(lines[0], aircon.String()) || !strings.Contains(lines[0], "on") {
+		t.Errorf("unexpected output: %q", lines[0])
+	}
+}
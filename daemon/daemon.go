@@ -0,0 +1,206 @@
+// Package daemon は、デーモンモードのライフサイクル（PIDファイルの作成・削除とシグナルの配線）を扱う
+// OS の機能は System インターフェースを通して使うため、テストではシグナルやプロセスの生存確認を差し替えられる
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// System は、デーモンのライフサイクルが使う OS の機能
+type System interface {
+	// Getpid は自分のプロセスIDを返す
+	Getpid() int
+	// ProcessAlive は、プロセスIDのプロセスが動いているかどうかを返す
+	ProcessAlive(pid int) bool
+	// Notify は signal.Notify と同じく、シグナルを c に送るように登録する
+	Notify(c chan<- os.Signal, sig ...os.Signal)
+	// Stop は signal.Stop と同じく、c へのシグナルの送信をやめる
+	Stop(c chan<- os.Signal)
+}
+
+// OS は、実際の OS を使う System
+type OS struct{}
+
+func (OS) Getpid() int                                 { return os.Getpid() }
+func (OS) ProcessAlive(pid int) bool                   { return processAlive(pid) }
+func (OS) Notify(c chan<- os.Signal, sig ...os.Signal) { signal.Notify(c, sig...) }
+func (OS) Stop(c chan<- os.Signal)                     { signal.Stop(c) }
+
+// AlreadyRunningError は、PIDファイルに書かれたプロセスがまだ動いていて起動できない場合のエラー
+type AlreadyRunningError struct {
+	PIDFile string // PIDファイルのパス
+	PID     int    // 動いているプロセスのID
+}
+
+func (e *AlreadyRunningError) Error() string {
+	return fmt.Sprintf("別のプロセス (PID %d) が既に起動しています (PIDファイル: %s)", e.PID, e.PIDFile)
+}
+
+// ShutdownSignals は、終了のきっかけにするシグナルを返す
+// デーモンモードでは SIGHUP をログローテーションに使うため含めず、コンソールUIモードでは SIGHUP でも終了する
+func ShutdownSignals(daemonMode bool) []os.Signal {
+	signals := []os.Signal{os.Interrupt, syscall.SIGTERM}
+	if !daemonMode {
+		signals = append(signals, syscall.SIGHUP)
+	}
+	return signals
+}
+
+// Lifecycle は、デーモンのPIDファイルとシグナルを扱う
+type Lifecycle struct {
+	sys     System
+	pidFile string
+
+	mu      sync.Mutex
+	written bool // PIDファイルを書き込んだかどうか
+}
+
+// New は Lifecycle を作る。sys が nil の場合は実際の OS を使う
+func New(sys System, pidFile string) *Lifecycle {
+	if sys == nil {
+		sys = OS{}
+	}
+	return &Lifecycle{sys: sys, pidFile: pidFile}
+}
+
+// PIDFile は、PIDファイルのパスを返す
+func (l *Lifecycle) PIDFile() string {
+	return l.pidFile
+}
+
+// Pid は、自分のプロセスIDを返す
+func (l *Lifecycle) Pid() int {
+	return l.sys.Getpid()
+}
+
+// WritePIDFile は、PIDファイルに自分のプロセスIDを書き込む。ディレクトリがない場合は作る
+// PIDファイルが既にあり、書かれたプロセスが動いている場合は *AlreadyRunningError を返す
+// 書かれたプロセスが終了している（異常終了で残った）場合や内容が読めない場合は、PIDファイルを引き継いで
+// 残っていたプロセスIDを stalePID として返す（プロセスIDが読めなかった場合は 0）
+func (l *Lifecycle) WritePIDFile() (stalePID int, err error) {
+	if err := os.MkdirAll(filepath.Dir(l.pidFile), 0755); err != nil {
+		return 0, fmt.Errorf("PIDファイルのディレクトリ作成に失敗しました: %w", err)
+	}
+	pid := l.sys.Getpid()
+
+	// 同時に起動した別のプロセスと取り合わないように、まず新しいファイルとして作る
+	file, err := os.OpenFile(l.pidFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		recorded, ok := readPIDFile(l.pidFile)
+		if ok && recorded != pid && l.sys.ProcessAlive(recorded) {
+			return 0, &AlreadyRunningError{PIDFile: l.pidFile, PID: recorded}
+		}
+		if ok {
+			stalePID = recorded
+		}
+		// 残っていたファイルは削除せずに上書きする（ディレクトリに書き込み権限がなく、ファイルだけ用意されている場合があるため）
+		file, err = os.OpenFile(l.pidFile, os.O_WRONLY|os.O_TRUNC, 0644)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("PIDファイルの作成に失敗しました: %w", err)
+	}
+	_, err = file.WriteString(strconv.Itoa(pid) + "\n")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("PIDファイルの書き込みに失敗しました: %w", err)
+	}
+	l.mu.Lock()
+	l.written = true
+	l.mu.Unlock()
+	return stalePID, nil
+}
+
+// RemovePIDFile は、WritePIDFile で書き込んだPIDファイルを削除する
+// 他のプロセスが引き継いだPIDファイル（自分のプロセスIDでないもの）は削除しない
+func (l *Lifecycle) RemovePIDFile() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.written {
+		return nil
+	}
+	l.written = false
+	if recorded, ok := readPIDFile(l.pidFile); ok && recorded != l.sys.Getpid() {
+		return nil
+	}
+	if err := os.Remove(l.pidFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// readPIDFile は、PIDファイルに書かれたプロセスIDを読む。読めない場合は false
+func readPIDFile(path string) (int, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+	return pid, true
+}
+
+// NotifyContext は signal.NotifyContext と同じく、シグナルを受信するとキャンセルされるコンテキストを返す
+func (l *Lifecycle) NotifyContext(parent context.Context, signals ...os.Signal) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	ch := make(chan os.Signal, 1)
+	l.sys.Notify(ch, signals...)
+	go func() {
+		select {
+		case <-ch:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		l.sys.Stop(ch)
+		cancel()
+	}
+}
+
+// OnSignal は、シグナルを受信するたびに fn を呼ぶ。返す関数を呼ぶと止まる
+// fn で panic が起きても、シグナルの受信は続ける
+func (l *Lifecycle) OnSignal(sig os.Signal, fn func()) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	l.sys.Notify(ch, sig)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				callRecovering(fn)
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.sys.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// callRecovering は fn を呼び、panic を標準エラーに出して握りつぶす
+func callRecovering(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "シグナル処理でpanicが発生しました: %v\n", r)
+		}
+	}()
+	fn()
+}
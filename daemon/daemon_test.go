@@ -0,0 +1,228 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeSystem は、プロセスの生存とシグナルを差し替える System
+type fakeSystem struct {
+	pid   int
+	alive map[int]bool
+
+	mu       sync.Mutex
+	channels map[chan<- os.Signal][]os.Signal
+}
+
+func newFakeSystem(pid int, alive ...int) *fakeSystem {
+	s := &fakeSystem{pid: pid, alive: map[int]bool{pid: true}, channels: make(map[chan<- os.Signal][]os.Signal)}
+	for _, p := range alive {
+		s.alive[p] = true
+	}
+	return s
+}
+
+func (s *fakeSystem) Getpid() int               { return s.pid }
+func (s *fakeSystem) ProcessAlive(pid int) bool { return s.alive[pid] }
+
+func (s *fakeSystem) Notify(c chan<- os.Signal, sig ...os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.channels[c] = append(s.channels[c], sig...)
+}
+
+func (s *fakeSystem) Stop(c chan<- os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.channels, c)
+}
+
+// send は、シグナルを登録されたチャンネルに送り、送った数を返す
+func (s *fakeSystem) send(sig os.Signal) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sent := 0
+	for c, signals := range s.channels {
+		if slices.Contains(signals, sig) {
+			c <- sig
+			sent++
+		}
+	}
+	return sent
+}
+
+func readPID(t *testing.T, path string) int {
+	t.Helper()
+	pid, ok := readPIDFile(path)
+	if !ok {
+		t.Fatalf("PIDファイルが読めません: %s", path)
+	}
+	return pid
+}
+
+func TestWritePIDFile(t *testing.T) {
+	// ディレクトリがなければ作る
+	path := filepath.Join(t.TempDir(), "run", "echonet-list.pid")
+	l := New(newFakeSystem(100), path)
+	stale, err := l.WritePIDFile()
+	if err != nil || stale != 0 {
+		t.Fatalf("WritePIDFile() = %d, %v", stale, err)
+	}
+	if pid := readPID(t, path); pid != 100 {
+		t.Errorf("PIDファイルの内容 = %d, want 100", pid)
+	}
+
+	if err := l.RemovePIDFile(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("PIDファイルが削除されていません: %v", err)
+	}
+}
+
+func TestWritePIDFile_AlreadyRunning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "echonet-list.pid")
+	if err := os.WriteFile(path, []byte("200\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l := New(newFakeSystem(100, 200), path)
+	_, err := l.WritePIDFile()
+	var running *AlreadyRunningError
+	if !errors.As(err, &running) || running.PID != 200 {
+		t.Fatalf("expected AlreadyRunningError for PID 200, got %v", err)
+	}
+
+	// 起動しなかった場合は、他のプロセスのPIDファイルを削除しない
+	if err := l.RemovePIDFile(); err != nil {
+		t.Fatal(err)
+	}
+	if pid := readPID(t, path); pid != 200 {
+		t.Errorf("PIDファイルの内容 = %d, want 200", pid)
+	}
+}
+
+func TestWritePIDFile_Stale(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		stale   int
+	}{
+		"終了したプロセス": {content: "200\n", stale: 200},
+		"読めない内容":   {content: "garbage", stale: 0},
+		"空のファイル":   {content: "", stale: 0},
+		"自分のPID":   {content: "100\n", stale: 100},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "echonet-list.pid")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			l := New(newFakeSystem(100), path)
+			stale, err := l.WritePIDFile()
+			if err != nil || stale != tt.stale {
+				t.Fatalf("WritePIDFile() = %d, %v; want %d", stale, err, tt.stale)
+			}
+			if pid := readPID(t, path); pid != 100 {
+				t.Errorf("PIDファイルの内容 = %d, want 100", pid)
+			}
+		})
+	}
+}
+
+func TestRemovePIDFile_TakenOver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "echonet-list.pid")
+	l := New(newFakeSystem(100), path)
+	if _, err := l.WritePIDFile(); err != nil {
+		t.Fatal(err)
+	}
+	// 他のプロセスが引き継いだPIDファイルは削除しない
+	if err := os.WriteFile(path, []byte("300\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.RemovePIDFile(); err != nil {
+		t.Fatal(err)
+	}
+	if pid := readPID(t, path); pid != 300 {
+		t.Errorf("PIDファイルの内容 = %d, want 300", pid)
+	}
+}
+
+func TestNotifyContext(t *testing.T) {
+	sys := newFakeSystem(100)
+	l := New(sys, "")
+	ctx, stop := l.NotifyContext(context.Background(), ShutdownSignals(true)...)
+	defer stop()
+
+	// デーモンモードでは SIGHUP で終了しない
+	if sent := sys.send(syscall.SIGHUP); sent != 0 {
+		t.Errorf("SIGHUP was delivered to %d channels", sent)
+	}
+	if sent := sys.send(syscall.SIGTERM); sent != 1 {
+		t.Fatalf("SIGTERM was delivered to %d channels", sent)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("コンテキストがキャンセルされません")
+	}
+
+	stop()
+	if sent := sys.send(syscall.SIGTERM); sent != 0 {
+		t.Errorf("SIGTERM was delivered to %d channels after stop", sent)
+	}
+}
+
+func TestShutdownSignals(t *testing.T) {
+	if slices.Contains(ShutdownSignals(true), os.Signal(syscall.SIGHUP)) {
+		t.Error("デーモンモードでは SIGHUP で終了しない")
+	}
+	if !slices.Contains(ShutdownSignals(false), os.Signal(syscall.SIGHUP)) {
+		t.Error("コンソールUIモードでは SIGHUP でも終了する")
+	}
+}
+
+func TestOnSignal(t *testing.T) {
+	sys := newFakeSystem(100)
+	l := New(sys, "")
+	called := make(chan struct{}, 2)
+	first := true
+	stop := l.OnSignal(syscall.SIGHUP, func() {
+		called <- struct{}{}
+		if first {
+			first = false
+			panic("rotate failed")
+		}
+	})
+
+	// panic の後も受信を続ける
+	for i := 0; i < 2; i++ {
+		sys.send(syscall.SIGHUP)
+		select {
+		case <-called:
+		case <-time.After(time.Second):
+			t.Fatalf("シグナル %d 回目で fn が呼ばれません", i+1)
+		}
+	}
+
+	stop()
+	stop()
+	if sent := sys.send(syscall.SIGHUP); sent != 0 {
+		t.Errorf("SIGHUP was delivered to %d channels after stop", sent)
+	}
+}
+
+func TestProcessAlive(t *testing.T) {
+	if !(OS{}).ProcessAlive(os.Getpid()) {
+		t.Error("自分のプロセスが動いていないと判定されました")
+	}
+	if (OS{}).ProcessAlive(0) {
+		t.Error("PID 0 が動いていると判定されました")
+	}
+}
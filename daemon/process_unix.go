@@ -0,0 +1,18 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive は、シグナル 0 を送ってプロセスが存在するかどうかを調べる
+// 権限がなく送れない場合（EPERM）も、プロセスは存在する
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package daemon
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// stillActive は、終了していないプロセスの GetExitCodeProcess の値 (STILL_ACTIVE)
+const stillActive = 259
+
+// processAlive は、プロセスを開いて終了コードを調べ、プロセスが動いているかどうかを返す
+// 権限がなく開けない場合（ERROR_ACCESS_DENIED）も、プロセスは存在する
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return errors.Is(err, windows.ERROR_ACCESS_DENIED)
+	}
+	defer windows.CloseHandle(h)
+	var code uint32
+	if err := windows.GetExitCodeProcess(h, &code); err != nil {
+		return true
+	}
+	return code == stillActive
+}
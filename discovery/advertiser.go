@@ -0,0 +1,279 @@
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// announceCount と announceInterval は、起動時にレコードを告知する回数と間隔 (RFC 6762 8.3節)
+const (
+	announceCount    = 2
+	announceInterval = time.Second
+)
+
+// Advertiser は、サービスを mDNS で広告し、問い合わせに応答する
+type Advertiser struct {
+	service Service
+	names   names
+	conn    *net.UDPConn
+
+	closeOnce sync.Once
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Advertise は、サービスの広告を始める。Close を呼ぶまで問い合わせに応答する
+func Advertise(service Service) (*Advertiser, error) {
+	service, err := service.normalize()
+	if err != nil {
+		return nil, err
+	}
+	n, err := serviceNames(service)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsAddr)
+	if err != nil {
+		return nil, fmt.Errorf("mDNS のマルチキャストグループに参加できません: %w", err)
+	}
+	a := &Advertiser{service: service, names: n, conn: conn, done: make(chan struct{})}
+	a.wg.Add(2)
+	go a.serve()
+	go a.announce()
+	return a, nil
+}
+
+// Service は、広告しているサービスを返す（空の項目はデフォルト値で埋めたもの）
+func (a *Advertiser) Service() Service {
+	return a.service
+}
+
+// Close は、広告を終える。TTL 0 のレコードを送って、キャッシュから消すように伝える
+func (a *Advertiser) Close() error {
+	var err error
+	a.closeOnce.Do(func() {
+		close(a.done)
+		if packet, packErr := a.allRecords(0); packErr == nil {
+			_, _ = a.conn.WriteToUDP(packet, mdnsAddr)
+		}
+		err = a.conn.Close()
+		a.wg.Wait()
+	})
+	return err
+}
+
+// announce は、起動時に全てのレコードを告知する
+func (a *Advertiser) announce() {
+	defer a.wg.Done()
+	for i := 0; i < announceCount; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(announceInterval):
+			case <-a.done:
+				return
+			}
+		}
+		packet, err := a.allRecords(recordTTL)
+		if err != nil {
+			slog.Warn("mDNS の告知を作れません", "err", err)
+			return
+		}
+		if _, err := a.conn.WriteToUDP(packet, mdnsAddr); err != nil {
+			slog.Warn("mDNS の告知に失敗しました", "err", err)
+		}
+	}
+}
+
+// serve は、問い合わせを受けて応答する
+func (a *Advertiser) serve() {
+	defer a.wg.Done()
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := a.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			select {
+			case <-a.done:
+				return
+			default:
+			}
+			continue
+		}
+		// 5353 以外のポートからの問い合わせは、送信元にユニキャストで応答する (RFC 6762 6.7節)
+		legacy := from.Port != mdnsAddr.Port
+		packet, ok := a.respond(buf[:n], legacy)
+		if !ok {
+			continue
+		}
+		to := mdnsAddr
+		if legacy {
+			to = from
+		}
+		if _, err := a.conn.WriteToUDP(packet, to); err != nil {
+			slog.Debug("mDNS の応答に失敗しました", "to", to, "err", err)
+		}
+	}
+}
+
+// respond は、問い合わせへの応答を作る。答えるレコードがない場合は false
+func (a *Advertiser) respond(query []byte, legacy bool) ([]byte, bool) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(query); err != nil || msg.Header.Response || len(msg.Questions) == 0 {
+		return nil, false
+	}
+	var answers, additionals []dnsmessage.Resource
+	for _, q := range msg.Questions {
+		an, ad := a.answer(q)
+		answers = append(answers, an...)
+		additionals = append(additionals, ad...)
+	}
+	if len(answers) == 0 {
+		return nil, false
+	}
+	additionals = withoutDuplicates(additionals, answers)
+
+	header := dnsmessage.Header{Response: true, Authoritative: true}
+	var questions []dnsmessage.Question
+	if legacy {
+		// レガシーユニキャストの応答は、通常の DNS の応答と同じく ID と質問を返し、キャッシュフラッシュビットを立てない
+		header.ID = msg.Header.ID
+		questions = msg.Questions
+		for i := range questions {
+			questions[i].Class &^= cacheFlush
+		}
+		answers = legacyResources(answers)
+		additionals = legacyResources(additionals)
+	}
+	packet, err := (&dnsmessage.Message{Header: header, Questions: questions, Answers: answers, Additionals: additionals}).Pack()
+	if err != nil {
+		return nil, false
+	}
+	return packet, true
+}
+
+// answer は、1つの質問に答えるレコードと、一緒に送る追加のレコードを返す
+func (a *Advertiser) answer(q dnsmessage.Question) (answers, additionals []dnsmessage.Resource) {
+	// 質問のクラスの最上位ビットはユニキャスト応答の要求 (QU) なので除く
+	if q.Class&^cacheFlush != dnsmessage.ClassINET && q.Class&^cacheFlush != dnsmessage.ClassANY {
+		return nil, nil
+	}
+	wants := func(t dnsmessage.Type) bool { return q.Type == t || q.Type == dnsmessage.TypeALL }
+
+	switch {
+	case sameName(q.Name, a.names.services) && wants(dnsmessage.TypePTR):
+		answers = append(answers, a.servicesPTR(recordTTL))
+	case sameName(q.Name, a.names.service) && wants(dnsmessage.TypePTR):
+		answers = append(answers, a.ptr(recordTTL))
+		additionals = append(additionals, a.srv(recordTTL), a.txt(recordTTL))
+		additionals = append(additionals, a.addresses(hostTTL)...)
+	case sameName(q.Name, a.names.instance):
+		if wants(dnsmessage.TypeSRV) {
+			answers = append(answers, a.srv(recordTTL))
+			additionals = append(additionals, a.addresses(hostTTL)...)
+		}
+		if wants(dnsmessage.TypeTXT) {
+			answers = append(answers, a.txt(recordTTL))
+		}
+	case sameName(q.Name, a.names.host) && wants(dnsmessage.TypeA):
+		answers = append(answers, a.addresses(hostTTL)...)
+	}
+	return answers, additionals
+}
+
+// allRecords は、告知と終了時の通知に使う、全てのレコードを載せた応答を作る
+func (a *Advertiser) allRecords(ttl uint32) ([]byte, error) {
+	addrTTL := min(ttl, hostTTL)
+	answers := []dnsmessage.Resource{a.ptr(ttl), a.srv(ttl), a.txt(ttl)}
+	answers = append(answers, a.addresses(addrTTL)...)
+	if ttl > 0 {
+		answers = append(answers, a.servicesPTR(ttl))
+	}
+	return (&dnsmessage.Message{Header: dnsmessage.Header{Response: true, Authoritative: true}, Answers: answers}).Pack()
+}
+
+func (a *Advertiser) servicesPTR(ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: a.names.services, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.PTRResource{PTR: a.names.service},
+	}
+}
+
+func (a *Advertiser) ptr(ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: a.names.service, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.PTRResource{PTR: a.names.instance},
+	}
+}
+
+func (a *Advertiser) srv(ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: a.names.instance, Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET | cacheFlush, TTL: ttl},
+		Body:   &dnsmessage.SRVResource{Target: a.names.host, Port: uint16(a.service.Port)},
+	}
+}
+
+func (a *Advertiser) txt(ttl uint32) dnsmessage.Resource {
+	tls := "0"
+	if a.service.TLS {
+		tls = "1"
+	}
+	txt := []string{txtPath + "=" + a.service.Path, txtTLS + "=" + tls}
+	if a.service.TLS && a.service.TLSHost != "" {
+		txt = append(txt, txtHost+"="+a.service.TLSHost)
+	}
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: a.names.instance, Type: dnsmessage.TypeTXT, Class: dnsmessage.ClassINET | cacheFlush, TTL: ttl},
+		Body:   &dnsmessage.TXTResource{TXT: txt},
+	}
+}
+
+func (a *Advertiser) addresses(ttl uint32) []dnsmessage.Resource {
+	var records []dnsmessage.Resource
+	for _, ip := range a.service.IPs {
+		ip4 := ip.To4()
+		if ip4 == nil {
+			continue
+		}
+		records = append(records, dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: a.names.host, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET | cacheFlush, TTL: ttl},
+			Body:   &dnsmessage.AResource{A: [4]byte(ip4)},
+		})
+	}
+	return records
+}
+
+// legacyResources は、レガシーユニキャストの応答向けにキャッシュフラッシュビットを外し、TTL を短くする
+func legacyResources(records []dnsmessage.Resource) []dnsmessage.Resource {
+	for i := range records {
+		records[i].Header.Class &^= cacheFlush
+		records[i].Header.TTL = min(records[i].Header.TTL, legacyTTL)
+	}
+	return records
+}
+
+// withoutDuplicates は、answers に含まれるレコードと重複するものを additionals から除く
+func withoutDuplicates(additionals, answers []dnsmessage.Resource) []dnsmessage.Resource {
+	seen := make(map[string]bool)
+	key := func(r dnsmessage.Resource) string {
+		return fmt.Sprintf("%s/%v/%s", r.Header.Name, r.Header.Type, r.Body.GoString())
+	}
+	for _, r := range answers {
+		seen[key(r)] = true
+	}
+	var result []dnsmessage.Resource
+	for _, r := range additionals {
+		if k := key(r); !seen[k] {
+			seen[k] = true
+			result = append(result, r)
+		}
+	}
+	return result
+}
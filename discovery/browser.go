@@ -0,0 +1,201 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// queryInterval は、Browse が問い合わせを送り直す間隔
+const queryInterval = time.Second
+
+// Browse は、mDNS でサーバーを探し、timeout の間に見つかったものをインスタンス名の順に返す
+// 見つからなかった場合は空のスライスを返す
+func Browse(ctx context.Context, timeout time.Duration) ([]Entry, error) {
+	query, err := browseQuery()
+	if err != nil {
+		return nil, err
+	}
+	// 5353 以外のポートから問い合わせると、応答は送信元にユニキャストで返ってくる（レガシーユニキャスト）
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+	if err != nil {
+		return nil, fmt.Errorf("mDNS の問い合わせ用のソケットを開けません: %w", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		_ = conn.SetReadDeadline(time.Now())
+	}()
+
+	c := newCollector()
+	if _, err := conn.WriteToUDP(query, mdnsAddr); err != nil {
+		return nil, fmt.Errorf("mDNS の問い合わせに失敗しました: %w", err)
+	}
+	nextQuery := time.Now().Add(queryInterval)
+	buf := make([]byte, 9000)
+	for {
+		_ = conn.SetReadDeadline(earliest(ctx, nextQuery))
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				return nil, err
+			}
+			if ctx.Err() != nil {
+				return c.entries(), nil
+			}
+			// 取りこぼしに備えて問い合わせを送り直す
+			_, _ = conn.WriteToUDP(query, mdnsAddr)
+			nextQuery = time.Now().Add(queryInterval)
+			continue
+		}
+		c.add(buf[:n], from.IP)
+	}
+}
+
+// earliest は、t とコンテキストの期限の早い方を返す
+func earliest(ctx context.Context, t time.Time) time.Time {
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(t) {
+		return deadline
+	}
+	return t
+}
+
+// browseQuery は、サービスタイプの PTR レコードの問い合わせを作る
+func browseQuery() ([]byte, error) {
+	name, err := dnsmessage.NewName(ServiceType + "." + domain)
+	if err != nil {
+		return nil, err
+	}
+	msg := dnsmessage.Message{
+		Questions: []dnsmessage.Question{{Name: name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET}},
+	}
+	return msg.Pack()
+}
+
+// srvRecord は、SRV レコードの内容
+type srvRecord struct {
+	target string
+	port   int
+}
+
+// collector は、応答のレコードを集めてサーバーの一覧を作る
+type collector struct {
+	instances map[string]bool      // PTR レコードで見つかったインスタンス名（小文字）
+	names     map[string]string    // インスタンス名（小文字）から元の名前
+	srv       map[string]srvRecord // インスタンス名（小文字）から SRV レコード
+	txt       map[string][]string  // インスタンス名（小文字）から TXT レコード
+	addrs     map[string][]net.IP  // ホスト名（小文字）からアドレス
+	from      map[string]net.IP    // インスタンス名（小文字）から応答の送信元
+	removed   map[string]bool      // TTL 0 で取り消されたインスタンス名（小文字）
+}
+
+func newCollector() *collector {
+	return &collector{
+		instances: make(map[string]bool),
+		names:     make(map[string]string),
+		srv:       make(map[string]srvRecord),
+		txt:       make(map[string][]string),
+		addrs:     make(map[string][]net.IP),
+		from:      make(map[string]net.IP),
+		removed:   make(map[string]bool),
+	}
+}
+
+// add は、応答のパケットのレコードを取り込む
+func (c *collector) add(packet []byte, from net.IP) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(packet); err != nil || !msg.Header.Response {
+		return
+	}
+	suffix := strings.ToLower("." + ServiceType + "." + domain)
+	records := append(append(msg.Answers, msg.Authorities...), msg.Additionals...)
+	for _, r := range records {
+		name := strings.ToLower(r.Header.Name.String())
+		switch body := r.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if name != strings.TrimPrefix(suffix, ".") {
+				continue
+			}
+			instance := strings.ToLower(body.PTR.String())
+			if !strings.HasSuffix(instance, suffix) {
+				continue
+			}
+			if r.Header.TTL == 0 {
+				c.removed[instance] = true
+				continue
+			}
+			delete(c.removed, instance)
+			c.instances[instance] = true
+			c.names[instance] = body.PTR.String()
+			c.from[instance] = from
+		case *dnsmessage.SRVResource:
+			c.srv[name] = srvRecord{target: strings.ToLower(body.Target.String()), port: int(body.Port)}
+		case *dnsmessage.TXTResource:
+			c.txt[name] = body.TXT
+		case *dnsmessage.AResource:
+			c.addIP(name, net.IP(body.A[:]))
+		case *dnsmessage.AAAAResource:
+			c.addIP(name, net.IP(body.AAAA[:]))
+		}
+	}
+}
+
+func (c *collector) addIP(host string, ip net.IP) {
+	for _, known := range c.addrs[host] {
+		if known.Equal(ip) {
+			return
+		}
+	}
+	c.addrs[host] = append(c.addrs[host], ip)
+}
+
+// entries は、SRV レコードまで揃ったサーバーの一覧を返す
+// アドレスのレコードがない場合は、応答の送信元のアドレスを使う
+func (c *collector) entries() []Entry {
+	suffix := "." + ServiceType + "." + domain
+	entries := []Entry{}
+	for instance := range c.instances {
+		if c.removed[instance] {
+			continue
+		}
+		srv, ok := c.srv[instance]
+		if !ok {
+			continue
+		}
+		name := c.names[instance]
+		entry := Entry{
+			Instance: name[:len(name)-len(suffix)],
+			Host:     strings.TrimSuffix(srv.target, "."),
+			Port:     srv.port,
+			IPs:      c.addrs[srv.target],
+			Text:     parseTXT(c.txt[instance]),
+		}
+		if len(entry.IPs) == 0 && c.from[instance] != nil {
+			entry.IPs = []net.IP{c.from[instance]}
+		}
+		entries = append(entries, entry)
+	}
+	sortEntries(entries)
+	return entries
+}
+
+// parseTXT は、key=value 形式の TXT レコードを読む
+func parseTXT(txt []string) map[string]string {
+	values := make(map[string]string, len(txt))
+	for _, s := range txt {
+		key, value, _ := strings.Cut(s, "=")
+		if key != "" {
+			values[strings.ToLower(key)] = value
+		}
+	}
+	return values
+}
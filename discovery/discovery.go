@@ -0,0 +1,209 @@
+// Package discovery は、WebSocket サーバーを mDNS (DNS-SD, Bonjour) で広告し、クライアントから探す
+// サービスタイプは _echonet-list._tcp で、TXT レコードに WebSocket のパスと TLS の有無を載せる
+// IPv4 のマルチキャスト (224.0.0.251:5353) だけを使う
+package discovery
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// ServiceType は、WebSocket サーバーを広告するサービスタイプ
+	ServiceType = "_echonet-list._tcp"
+	// domain は mDNS のドメイン
+	domain = "local."
+	// servicesName は、サービスタイプの一覧を問い合わせる名前 (RFC 6763 9章)
+	servicesName = "_services._dns-sd._udp.local."
+
+	// recordTTL は、レコードの TTL（秒）。ホスト名とアドレスは RFC 6762 の推奨に従って短くする
+	recordTTL = 4500
+	hostTTL   = 120
+	// legacyTTL は、5353 以外のポートからの問い合わせ（レガシーユニキャスト）への応答の TTL の上限
+	legacyTTL = 10
+
+	// cacheFlush は、一意なレコードのクラスに立てるキャッシュフラッシュビット
+	cacheFlush = 0x8000
+)
+
+// mdnsAddr は mDNS のマルチキャストアドレス
+var mdnsAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// TXT レコードのキー
+const (
+	txtPath = "path" // WebSocket のパス（例: /ws）
+	txtTLS  = "tls"  // TLS が有効なら "1"
+	txtHost = "host" // TLS 証明書のホスト名（mDNS のホスト名と異なる場合）
+)
+
+// Service は、広告するサービス
+type Service struct {
+	Instance string   // サービスインスタンス名（空の場合はホスト名）
+	Host     string   // ホスト名（.local を除く。空の場合は os.Hostname の最初のラベル）
+	Port     int      // WebSocket サーバーのポート
+	Path     string   // WebSocket のパス（空の場合は /ws）
+	TLS      bool     // wss:// で接続する
+	TLSHost  string   // TLS 証明書のホスト名（空の場合は mDNS のホスト名 <Host>.local で接続する）
+	IPs      []net.IP // 広告するアドレス（空の場合はループバック以外のインターフェースの IPv4 アドレス）
+}
+
+// normalize は、空の項目をデフォルト値で埋めた Service を返す
+func (s Service) normalize() (Service, error) {
+	if s.Port <= 0 || s.Port > 65535 {
+		return s, fmt.Errorf("ポート番号が不正です: %d", s.Port)
+	}
+	if s.Host == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return s, fmt.Errorf("ホスト名を取得できません: %w", err)
+		}
+		s.Host = hostname
+	}
+	// ドメイン付きのホスト名は最初のラベルだけを使う
+	s.Host = sanitizeLabel(strings.SplitN(strings.TrimSuffix(s.Host, "."), ".", 2)[0])
+	if s.Host == "" {
+		return s, fmt.Errorf("ホスト名が空です")
+	}
+	if s.Instance == "" {
+		s.Instance = s.Host
+	}
+	s.Instance = sanitizeLabel(s.Instance)
+	if s.Path == "" {
+		s.Path = "/ws"
+	}
+	if len(s.IPs) == 0 {
+		s.IPs = interfaceIPv4s()
+	}
+	if len(s.IPs) == 0 {
+		return s, fmt.Errorf("広告できる IPv4 アドレスがありません")
+	}
+	return s, nil
+}
+
+// sanitizeLabel は、DNS の1つのラベルとして使えるように、ドットを - に置き換えて63バイトに切り詰める
+func sanitizeLabel(s string) string {
+	s = strings.TrimSpace(strings.ReplaceAll(s, ".", "-"))
+	for len(s) > 63 {
+		// UTF-8 の途中で切らないように1文字ずつ削る
+		r := []rune(s)
+		s = string(r[:len(r)-1])
+	}
+	return s
+}
+
+// interfaceIPv4s は、マルチキャストが使える、ループバック以外の稼働中のインターフェースの IPv4 アドレスを返す
+func interfaceIPv4s() []net.IP {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var ips []net.IP
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				if ip4 := ipNet.IP.To4(); ip4 != nil && !ip4.IsLinkLocalUnicast() {
+					ips = append(ips, ip4)
+				}
+			}
+		}
+	}
+	return ips
+}
+
+// names は、サービスの DNS 名
+type names struct {
+	service  dnsmessage.Name // _echonet-list._tcp.local.
+	instance dnsmessage.Name // <instance>._echonet-list._tcp.local.
+	host     dnsmessage.Name // <host>.local.
+	services dnsmessage.Name // _services._dns-sd._udp.local.
+}
+
+func serviceNames(s Service) (names, error) {
+	var n names
+	var err error
+	if n.service, err = dnsmessage.NewName(ServiceType + "." + domain); err != nil {
+		return n, err
+	}
+	if n.instance, err = dnsmessage.NewName(s.Instance + "." + ServiceType + "." + domain); err != nil {
+		return n, fmt.Errorf("インスタンス名が不正です: %w", err)
+	}
+	if n.host, err = dnsmessage.NewName(s.Host + "." + domain); err != nil {
+		return n, fmt.Errorf("ホスト名が不正です: %w", err)
+	}
+	if n.services, err = dnsmessage.NewName(servicesName); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+// sameName は、DNS 名を大文字・小文字を区別せずに比べる
+func sameName(a, b dnsmessage.Name) bool {
+	return strings.EqualFold(a.String(), b.String())
+}
+
+// Entry は、見つかったサーバー
+type Entry struct {
+	Instance string            // サービスインスタンス名
+	Host     string            // ホスト名（例: raspberrypi.local）
+	Port     int               // ポート
+	IPs      []net.IP          // アドレス
+	Text     map[string]string // TXT レコード
+}
+
+// TLS は、サーバーが wss:// で待ち受けているかどうかを返す
+func (e Entry) TLS() bool {
+	return e.Text[txtTLS] == "1"
+}
+
+// URL は、サーバーの WebSocket の URL を返す
+// TLS の場合は証明書と合うように証明書のホスト名（なければ mDNS のホスト名）を、そうでない場合は名前解決の要らない IPv4 アドレスを使う
+func (e Entry) URL() string {
+	scheme := "ws"
+	if e.TLS() {
+		scheme = "wss"
+	}
+	path := e.Text[txtPath]
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	host := e.Host
+	if e.TLS() && e.Text[txtHost] != "" {
+		host = e.Text[txtHost]
+	}
+	if !e.TLS() || host == "" {
+		if ip := e.preferredIP(); ip != nil {
+			host = ip.String()
+		}
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, fmt.Sprint(e.Port)), path)
+}
+
+// preferredIP は、IPv4 アドレスを優先してアドレスを1つ返す
+func (e Entry) preferredIP() net.IP {
+	for _, ip := range e.IPs {
+		if ip.To4() != nil {
+			return ip
+		}
+	}
+	if len(e.IPs) > 0 {
+		return e.IPs[0]
+	}
+	return nil
+}
+
+// sortEntries は、インスタンス名の順に並べる
+func sortEntries(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Instance < entries[j].Instance })
+}
@@ -0,0 +1,160 @@
+package discovery
+
+import (
+	"net"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// newTestAdvertiser は、ソケットを開かずに応答だけを作る Advertiser を返す
+func newTestAdvertiser(t *testing.T, service Service) *Advertiser {
+	t.Helper()
+	service, err := service.normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := serviceNames(service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Advertiser{service: service, names: n}
+}
+
+func packQuery(t *testing.T, id uint16, name string, qtype dnsmessage.Type) []byte {
+	t.Helper()
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packet, err := msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return packet
+}
+
+func TestNormalize(t *testing.T) {
+	service, err := Service{Host: "pi.example.com", Instance: "Living.Room", Port: 8080, IPs: []net.IP{net.ParseIP("192.168.1.2")}}.normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if service.Host != "pi" || service.Instance != "Living-Room" || service.Path != "/ws" {
+		t.Errorf("unexpected service: %+v", service)
+	}
+
+	if _, err := (Service{Host: "pi", Port: 0}).normalize(); err == nil {
+		t.Error("expected an error for port 0")
+	}
+}
+
+func TestBrowseRoundTrip(t *testing.T) {
+	a := newTestAdvertiser(t, Service{
+		Instance: "Living Room",
+		Host:     "pi",
+		Port:     8443,
+		TLS:      true,
+		IPs:      []net.IP{net.ParseIP("192.168.1.2")},
+	})
+	query, err := browseQuery()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, legacy := range []bool{false, true} {
+		response, ok := a.respond(query, legacy)
+		if !ok {
+			t.Fatalf("legacy=%v: no response", legacy)
+		}
+		c := newCollector()
+		c.add(response, net.ParseIP("192.168.1.2"))
+		entries := c.entries()
+		if len(entries) != 1 {
+			t.Fatalf("legacy=%v: entries = %+v", legacy, entries)
+		}
+		e := entries[0]
+		if e.Instance != "Living Room" || e.Host != "pi.local" || e.Port != 8443 || !e.TLS() {
+			t.Errorf("legacy=%v: unexpected entry %+v", legacy, e)
+		}
+		// TLS では証明書と合うようにホスト名を使う
+		if url := e.URL(); url != "wss://pi.local:8443/ws" {
+			t.Errorf("legacy=%v: URL() = %s", legacy, url)
+		}
+	}
+}
+
+func TestRespond_LegacyUnicast(t *testing.T) {
+	a := newTestAdvertiser(t, Service{Host: "pi", Port: 8080, IPs: []net.IP{net.ParseIP("192.168.1.2")}})
+	response, ok := a.respond(packQuery(t, 42, "pi.local.", dnsmessage.TypeA), true)
+	if !ok {
+		t.Fatal("no response")
+	}
+	var msg dnsmessage.Message
+	if err := msg.Unpack(response); err != nil {
+		t.Fatal(err)
+	}
+	// レガシーユニキャストの応答は ID と質問を返し、キャッシュフラッシュビットを立てず、TTL が短い
+	if msg.Header.ID != 42 || len(msg.Questions) != 1 || len(msg.Answers) != 1 {
+		t.Fatalf("unexpected response: %+v", msg)
+	}
+	answer := msg.Answers[0]
+	if answer.Header.Class != dnsmessage.ClassINET || answer.Header.TTL > legacyTTL {
+		t.Errorf("unexpected answer header: %+v", answer.Header)
+	}
+	if a, ok := answer.Body.(*dnsmessage.AResource); !ok || net.IP(a.A[:]).String() != "192.168.1.2" {
+		t.Errorf("unexpected answer: %v", answer.Body)
+	}
+}
+
+func TestRespond_Unrelated(t *testing.T) {
+	a := newTestAdvertiser(t, Service{Host: "pi", Port: 8080, IPs: []net.IP{net.ParseIP("192.168.1.2")}})
+	for _, name := range []string{"_http._tcp.local.", "other.local."} {
+		if _, ok := a.respond(packQuery(t, 0, name, dnsmessage.TypePTR), false); ok {
+			t.Errorf("unexpected response to %s", name)
+		}
+	}
+	// サービスタイプの一覧の問い合わせには答える
+	if _, ok := a.respond(packQuery(t, 0, servicesName, dnsmessage.TypePTR), false); !ok {
+		t.Error("no response to the service type enumeration")
+	}
+}
+
+func TestCollector_Goodbye(t *testing.T) {
+	a := newTestAdvertiser(t, Service{Host: "pi", Port: 8080, IPs: []net.IP{net.ParseIP("192.168.1.2")}})
+	announce, err := a.allRecords(recordTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	goodbye, err := a.allRecords(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newCollector()
+	c.add(announce, net.ParseIP("192.168.1.2"))
+	if entries := c.entries(); len(entries) != 1 || entries[0].URL() != "ws://192.168.1.2:8080/ws" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	c.add(goodbye, net.ParseIP("192.168.1.2"))
+	if entries := c.entries(); len(entries) != 0 {
+		t.Errorf("expected no entries after the goodbye, got %+v", entries)
+	}
+}
+
+func TestEntryURL(t *testing.T) {
+	tests := []struct {
+		entry Entry
+		want  string
+	}{
+		{Entry{Host: "pi.local", Port: 8080, IPs: []net.IP{net.ParseIP("fe80::1"), net.ParseIP("192.168.1.2")}, Text: map[string]string{"path": "/ws"}}, "ws://192.168.1.2:8080/ws"},
+		{Entry{Host: "pi.local", Port: 8080, IPs: []net.IP{net.ParseIP("2001:db8::1")}, Text: map[string]string{"path": "ws"}}, "ws://[2001:db8::1]:8080/ws"},
+		{Entry{Host: "pi.local", Port: 8080, Text: map[string]string{"path": "/ws"}}, "ws://pi.local:8080/ws"},
+		{Entry{Host: "", Port: 443, IPs: []net.IP{net.ParseIP("192.168.1.2")}, Text: map[string]string{"path": "/ws", "tls": "1"}}, "wss://192.168.1.2:443/ws"},
+		// 証明書のホスト名が広告されていれば、それを使う
+		{Entry{Host: "pi.local", Port: 443, Text: map[string]string{"path": "/ws", "tls": "1", "host": "home.example.com"}}, "wss://home.example.com:443/ws"},
+	}
+	for _, tt := range tests {
+		if got := tt.entry.URL(); got != tt.want {
+			t.Errorf("URL() = %s, want %s", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package echonet_lite
+
+// ClassMetadata は、クラスの表示用情報（クラス名とアイコンの候補）
+type ClassMetadata struct {
+	Name   string // 英語のクラス名
+	NameJa string // 日本語のクラス名
+	Icon   string // UIで使うアイコン識別子の候補
+}
+
+// classMetadataTable は、プロパティテーブルを持たないクラスも含めたクラスの表示用情報
+// プロパティテーブルがあるクラスの名前は GetClassMetadata で PropertyTable の説明が優先される
+var classMetadataTable = map[EOJClassCode]ClassMetadata{
+	// センサ関連機器
+	0x0001: {Name: "Gas Leak Sensor", NameJa: "ガス漏れセンサ", Icon: "sensor"},
+	0x0007: {Name: "Human Detection Sensor", NameJa: "人体検知センサ", Icon: "motion-sensor"},
+	0x0011: {Name: "Temperature Sensor", NameJa: "温度センサ", Icon: "thermometer"},
+	0x0012: {Name: "Humidity Sensor", NameJa: "湿度センサ", Icon: "humidity"},
+	0x001b: {Name: "CO2 Sensor", NameJa: "CO2センサ", Icon: "sensor"},
+	0x0022: {Name: "Electric Energy Sensor", NameJa: "電力量センサ", Icon: "meter"},
+
+	// 仮想デバイス
+	Weather_ClassCode: {Name: "Weather", NameJa: "天気", Icon: "weather"},
+
+	// 空調関連機器
+	HomeAirConditioner_ClassCode: {Name: "Home Air Conditioner", NameJa: "家庭用エアコン", Icon: "air-conditioner"},
+	0x0133:                       {Name: "Ventilation Fan", NameJa: "換気扇", Icon: "fan"},
+	0x0134:                       {Name: "Air Conditioner Ventilation Fan", NameJa: "空調換気扇", Icon: "fan"},
+	0x0135:                       {Name: "Air Cleaner", NameJa: "空気清浄機", Icon: "air-purifier"},
+	0x0139:                       {Name: "Humidifier", NameJa: "加湿器", Icon: "humidity"},
+	0x013a:                       {Name: "Electric Heater", NameJa: "電気暖房器", Icon: "heater"},
+
+	// 住宅・設備関連機器
+	0x0260:                           {Name: "Electrically Operated Blind", NameJa: "電動ブラインド", Icon: "blinds"},
+	0x0263:                           {Name: "Electrically Operated Shutter", NameJa: "電動雨戸・シャッター", Icon: "shutter"},
+	ElectricWaterHeater_ClassCode:    {Name: "Electric Water Heater", NameJa: "電気式給湯器", Icon: "water-heater"},
+	0x026f:                           {Name: "Electric Lock", NameJa: "電気錠", Icon: "lock"},
+	0x0272:                           {Name: "Instantaneous Water Heater", NameJa: "瞬間式給湯器", Icon: "water-heater"},
+	0x0273:                           {Name: "Bathroom Heater Dryer", NameJa: "浴室暖房乾燥機", Icon: "heater"},
+	HomeSolarPower_ClassCode:         {Name: "Household Solar Power Generation", NameJa: "住宅用太陽光発電", Icon: "solar-panel"},
+	FloorHeating_ClassCode:           {Name: "Floor Heating", NameJa: "床暖房", Icon: "floor-heating"},
+	0x027c:                           {Name: "Fuel Cell", NameJa: "燃料電池", Icon: "fuel-cell"},
+	StorageBattery_ClassCode:         {Name: "Storage Battery", NameJa: "蓄電池", Icon: "battery"},
+	0x027e:                           {Name: "Electric Vehicle Charger/Discharger", NameJa: "電気自動車充放電器", Icon: "ev-charger"},
+	0x0280:                           {Name: "Electric Energy Meter", NameJa: "電力量メータ", Icon: "meter"},
+	0x0281:                           {Name: "Water Flow Meter", NameJa: "水流量メータ", Icon: "meter"},
+	0x0282:                           {Name: "Gas Meter", NameJa: "ガスメータ", Icon: "meter"},
+	0x0287:                           {Name: "Power Distribution Board Metering", NameJa: "分電盤メータリング", Icon: "meter"},
+	LowVoltageSmartMeter_ClassCode:   {Name: "Low-Voltage Smart Electric Energy Meter", NameJa: "低圧スマート電力量メータ", Icon: "meter"},
+	GeneralLighting_ClassCode:        {Name: "General Lighting", NameJa: "一般照明", Icon: "lightbulb"},
+	SingleFunctionLighting_ClassCode: {Name: "Single Function Lighting", NameJa: "単機能照明", Icon: "lightbulb"},
+	0x02a1:                           {Name: "Electric Vehicle Charger", NameJa: "電気自動車充電器", Icon: "ev-charger"},
+	LightingSystem_ClassCode:         {Name: "Lighting System", NameJa: "照明システム", Icon: "lightbulb"},
+	0x02a5:                           {Name: "Multiple Input PCS", NameJa: "マルチ入力PCS", Icon: "battery"},
+
+	// 調理・家事関連機器
+	Refrigerator_ClassCode: {Name: "Refrigerator", NameJa: "冷凍冷蔵庫", Icon: "refrigerator"},
+	0x03b8:                 {Name: "Combination Microwave Oven", NameJa: "オーブンレンジ", Icon: "microwave"},
+	0x03b9:                 {Name: "Cooking Heater", NameJa: "クッキングヒータ", Icon: "cooktop"},
+	0x03bb:                 {Name: "Rice Cooker", NameJa: "炊飯器", Icon: "rice-cooker"},
+	0x03c5:                 {Name: "Washer and Dryer", NameJa: "洗濯乾燥機", Icon: "washing-machine"},
+
+	// 管理・操作関連機器
+	0x05fd:               {Name: "Switch", NameJa: "スイッチ", Icon: "switch"},
+	Controller_ClassCode: {Name: "Controller", NameJa: "コントローラ", Icon: "controller"},
+
+	// プロファイル
+	NodeProfile_ClassCode: {Name: "Node Profile", NameJa: "ノードプロファイル", Icon: "node"},
+}
+
+// classGroupMetadata は、テーブルにないクラスに使うクラスグループ毎の表示用情報
+var classGroupMetadata = map[ClassGroupCodeType]ClassMetadata{
+	0x00: {Name: "Sensor-related device", NameJa: "センサ関連機器", Icon: "sensor"},
+	0x01: {Name: "Air conditioner-related device", NameJa: "空調関連機器", Icon: "air-conditioner"},
+	0x02: {Name: "Housing/facility-related device", NameJa: "住宅・設備関連機器", Icon: "home"},
+	0x03: {Name: "Cooking/housework-related device", NameJa: "調理・家事関連機器", Icon: "kitchen"},
+	0x04: {Name: "Health-related device", NameJa: "健康関連機器", Icon: "health"},
+	0x05: {Name: "Management/control-related device", NameJa: "管理・操作関連機器", Icon: "controller"},
+	0x06: {Name: "Audiovisual-related device", NameJa: "AV関連機器", Icon: "tv"},
+	0x07: {Name: "Network-related device", NameJa: "ネットワーク関連機器", Icon: "network"},
+	0x0e: {Name: "Profile", NameJa: "プロファイル", Icon: "node"},
+}
+
+// GetClassMetadata は、クラスの表示用情報を返す
+// クラスが未知の場合はクラスグループの情報を返し、それもない場合は ok=false を返す
+func GetClassMetadata(c EOJClassCode) (ClassMetadata, bool) {
+	meta, ok := classMetadataTable[c]
+	if !ok {
+		meta, ok = classGroupMetadata[c.ClassGroupCode()]
+	}
+	if table, found := PropertyTables[c]; found {
+		meta.Name = table.Description
+		meta.NameJa = table.GetDescription("ja")
+		ok = true
+	}
+	return meta, ok
+}
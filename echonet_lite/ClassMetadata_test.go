@@ -0,0 +1,48 @@
+package echonet_lite
+
+import "testing"
+
+func TestGetClassMetadata(t *testing.T) {
+	tests := []struct {
+		name      string
+		classCode EOJClassCode
+		want      ClassMetadata
+		wantOK    bool
+	}{
+		{
+			name:      "class with property table uses its description",
+			classCode: SingleFunctionLighting_ClassCode,
+			want:      ClassMetadata{Name: "Single Function Lighting", NameJa: "単機能照明", Icon: "lightbulb"},
+			wantOK:    true,
+		},
+		{
+			name:      "class without property table",
+			classCode: 0x0288,
+			want:      ClassMetadata{Name: "Low-Voltage Smart Electric Energy Meter", NameJa: "低圧スマート電力量メータ", Icon: "meter"},
+			wantOK:    true,
+		},
+		{
+			name:      "unknown class falls back to class group",
+			classCode: 0x01ff,
+			want:      ClassMetadata{Name: "Air conditioner-related device", NameJa: "空調関連機器", Icon: "air-conditioner"},
+			wantOK:    true,
+		},
+		{
+			name:      "unknown class group",
+			classCode: 0x0aff,
+			wantOK:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := GetClassMetadata(tt.classCode)
+			if ok != tt.wantOK {
+				t.Fatalf("GetClassMetadata(%04X) ok = %v, want %v", uint16(tt.classCode), ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("GetClassMetadata(%04X) = %+v, want %+v", uint16(tt.classCode), got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package echonet_lite
+
+import "fmt"
+
+// 認証試験のように仕様への適合を確かめる場合に使う、ECHONET Lite 規格の電文の規定
+// 通常の受信（ParseECHONETLiteMessage）は、規定に従わない機器とも通信できるよう寛容に扱う
+
+// ParseECHONETLiteMessageStrict は、ParseECHONETLiteMessage に加えて、
+// 最後のプロパティの後に余分なバイトがないことと、Validate の規定を満たすことを確認する
+func ParseECHONETLiteMessageStrict(data []byte) (*ECHONETLiteMessage, error) {
+	msg, end, err := parseMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	if end != len(data) {
+		return nil, fmt.Errorf("電文の後に余分なデータがあります: %d バイト", len(data)-end)
+	}
+	if err := msg.Validate(); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// pdcRule は、ESV ごとのプロパティのデータ長（PDC）の規定
+type pdcRule int
+
+const (
+	pdcAny      pdcRule = iota // 規定なし（不可応答など）
+	pdcZero                    // PDC は 0（読み出し要求、書き込み応答）
+	pdcNonZero                 // PDC は 1 以上（書き込み要求、読み出し応答、通知）
+	pdcRequired                // OPC が 1 以上で、PDC は 1 以上
+)
+
+// esvPDCRules は、ESV ごとの Properties と SetGetProperties の PDC の規定
+var esvPDCRules = map[ESVType][2]pdcRule{
+	ESVSetI:        {pdcRequired, pdcAny},
+	ESVSetC:        {pdcRequired, pdcAny},
+	ESVGet:         {pdcZero, pdcAny},
+	ESVINF_REQ:     {pdcZero, pdcAny},
+	ESVSetGet:      {pdcNonZero, pdcZero},
+	ESVSet_Res:     {pdcZero, pdcAny},
+	ESVGet_Res:     {pdcNonZero, pdcAny},
+	ESVINF:         {pdcRequired, pdcAny},
+	ESVINFC:        {pdcRequired, pdcAny},
+	ESVINFC_Res:    {pdcZero, pdcAny},
+	ESVSetGet_Res:  {pdcZero, pdcNonZero},
+	ESVSetI_SNA:    {pdcAny, pdcAny},
+	ESVSetC_SNA:    {pdcAny, pdcAny},
+	ESVGet_SNA:     {pdcAny, pdcAny},
+	ESVINF_REQ_SNA: {pdcAny, pdcAny},
+	ESVSetGet_SNA:  {pdcAny, pdcAny},
+}
+
+// Validate は、電文が ECHONET Lite 規格の規定を満たすかどうかを確認する
+//   - EHD が ECHONET Lite 電文形式1（0x1081）であること
+//   - ESV が規定されたものであること
+//   - 要求と通知の OPC が 1 以上であること（SetGet は書き込みと読み出しの合計）
+//   - 読み出し要求と書き込み応答の PDC が 0、書き込み要求と読み出し応答と通知の PDC が 1 以上であること
+func (m *ECHONETLiteMessage) Validate() error {
+	if m.EHD != EHD_ECHONETLite {
+		return fmt.Errorf("EHDが不正です: %v", m.EHD)
+	}
+	rules, ok := esvPDCRules[m.ESV]
+	if !ok {
+		return fmt.Errorf("ESVが不正です: %v", m.ESV)
+	}
+	if m.ESV == ESVSetGet && len(m.Properties)+len(m.SetGetProperties) == 0 {
+		return fmt.Errorf("%v のOPCが0です", m.ESV)
+	}
+	for i, properties := range []Properties{m.Properties, m.SetGetProperties} {
+		if err := validatePDC(m.ESV, rules[i], properties); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePDC(esv ESVType, rule pdcRule, properties Properties) error {
+	if rule == pdcRequired && len(properties) == 0 {
+		return fmt.Errorf("%v のOPCが0です", esv)
+	}
+	for _, p := range properties {
+		switch {
+		case rule == pdcZero && len(p.EDT) != 0:
+			return fmt.Errorf("%v のEPC %v のPDCは0でなければなりません: %d", esv, p.EPC, len(p.EDT))
+		case (rule == pdcNonZero || rule == pdcRequired) && len(p.EDT) == 0:
+			return fmt.Errorf("%v のEPC %v のPDCが0です", esv, p.EPC)
+		}
+	}
+	return nil
+}
+
+// ValidateResponse は、応答が要求と同じ数のプロパティを同じ順に含むことを確認する
+// 不可応答も、処理できなかったプロパティを含めて要求のすべてのプロパティを返す
+func ValidateResponse(request, response *ECHONETLiteMessage) error {
+	if err := matchEPCs(request.Properties, response.Properties); err != nil {
+		return fmt.Errorf("%v に対する %v: %w", request.ESV, response.ESV, err)
+	}
+	if request.ESV.ISSetGet() {
+		if err := matchEPCs(request.SetGetProperties, response.SetGetProperties); err != nil {
+			return fmt.Errorf("%v に対する %v の読み出し: %w", request.ESV, response.ESV, err)
+		}
+	}
+	return nil
+}
+
+func matchEPCs(request, response Properties) error {
+	if len(request) != len(response) {
+		return fmt.Errorf("OPCが要求と一致しません: 要求 %d, 応答 %d", len(request), len(response))
+	}
+	for i := range request {
+		if request[i].EPC != response[i].EPC {
+			return fmt.Errorf("%d番目のEPCが要求と一致しません: 要求 %v, 応答 %v", i+1, request[i].EPC, response[i].EPC)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package echonet_lite
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseECHONETLiteMessageStrict(t *testing.T) {
+	get := &ECHONETLiteMessage{
+		TID:        1,
+		SEOJ:       MakeEOJ(Controller_ClassCode, 1),
+		DEOJ:       MakeEOJ(HomeAirConditioner_ClassCode, 1),
+		ESV:        ESVGet,
+		Properties: Properties{{EPC: EPCOperationStatus}},
+	}
+	if _, err := ParseECHONETLiteMessageStrict(get.Encode()); err != nil {
+		t.Fatalf("valid Get was rejected: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data func() []byte
+		want string
+	}{
+		{"trailing bytes", func() []byte { return append(get.Encode(), 0x00) }, "余分なデータ"},
+		{"unknown EHD", func() []byte { data := get.Encode(); data[0] = 0x10; data[1] = 0x82; return data }, "EHD"},
+		{"unknown ESV", func() []byte { data := get.Encode(); data[10] = 0x65; return data }, "ESV"},
+		{"Get with EDT", func() []byte {
+			m := *get
+			m.Properties = Properties{{EPC: EPCOperationStatus, EDT: []byte{0x30}}}
+			return m.Encode()
+		}, "PDCは0"},
+		{"SetC without properties", func() []byte {
+			m := *get
+			m.ESV = ESVSetC
+			m.Properties = nil
+			return m.Encode()
+		}, "OPCが0"},
+		{"SetC without EDT", func() []byte { m := *get; m.ESV = ESVSetC; return m.Encode() }, "PDCが0"},
+		{"truncated SetGet", func() []byte {
+			m := *get
+			m.ESV = ESVSetGet
+			m.Properties = Properties{{EPC: EPCOperationStatus, EDT: []byte{0x30}}}
+			data := m.Encode()
+			return data[:len(data)-1] // OPCGet がない
+		}, "OPC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.data()
+			_, err := ParseECHONETLiteMessageStrict(data)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// Lenient parsing accepts trailing bytes and unknown EHD
+	if _, err := ParseECHONETLiteMessage(append(get.Encode(), 0x00)); err != nil {
+		t.Errorf("lenient parsing rejected trailing bytes: %v", err)
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	request := &ECHONETLiteMessage{ESV: ESVGet, Properties: Properties{{EPC: 0x80}, {EPC: 0xB0}}}
+	ok := &ECHONETLiteMessage{ESV: ESVGet_Res, Properties: Properties{{EPC: 0x80, EDT: []byte{0x30}}, {EPC: 0xB0, EDT: []byte{0x41}}}}
+	if err := ValidateResponse(request, ok); err != nil {
+		t.Errorf("matching response was rejected: %v", err)
+	}
+	missing := &ECHONETLiteMessage{ESV: ESVGet_SNA, Properties: Properties{{EPC: 0x80, EDT: []byte{0x30}}}}
+	if err := ValidateResponse(request, missing); err == nil {
+		t.Error("response with fewer properties was accepted")
+	}
+	reordered := &ECHONETLiteMessage{ESV: ESVGet_Res, Properties: Properties{{EPC: 0xB0, EDT: []byte{0x41}}, {EPC: 0x80, EDT: []byte{0x30}}}}
+	if err := ValidateResponse(request, reordered); err == nil {
+		t.Error("response in a different order was accepted")
+	}
+}
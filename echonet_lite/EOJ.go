@@ -49,7 +49,7 @@ func (e EOJ) Encode() []byte {
 func (c EOJClassCode) String() string {
 	var s string
 	if p, ok := PropertyTables[c]; ok {
-		s = p.Description
+		s = p.GetDescription(DisplayLanguage())
 	} else {
 		switch c.ClassGroupCode() {
 		case 0x00:
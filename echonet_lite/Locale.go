@@ -0,0 +1,59 @@
+package echonet_lite
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// SupportedLanguages は、プロパティ名・クラス名を表示できる言語
+// "en" はプロパティテーブルの Name/Description、それ以外は翻訳を使う
+var SupportedLanguages = []string{"en", "ja"}
+
+// displayLanguage は、コンソールやログに表示するプロパティ名・クラス名の言語（"" は英語）
+var displayLanguage atomic.Value
+
+// NormalizeLanguage は、"ja-JP" や "en_US.UTF-8"、Accept-Language のような言語指定から言語コードを取り出す
+// 空文字列はそのまま返す
+func NormalizeLanguage(lang string) string {
+	lang, _, _ = strings.Cut(lang, ",")
+	lang, _, _ = strings.Cut(lang, ";")
+	lang, _, _ = strings.Cut(lang, ".")
+	lang = strings.TrimSpace(lang)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	return strings.ToLower(lang)
+}
+
+// ParseLanguage は、言語指定を正規化し、表示できる言語かどうかを確かめる
+func ParseLanguage(lang string) (string, error) {
+	normalized := NormalizeLanguage(lang)
+	if normalized == "" {
+		return "", nil
+	}
+	for _, supported := range SupportedLanguages {
+		if normalized == supported {
+			return normalized, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported language: %q (%s)", lang, strings.Join(SupportedLanguages, ", "))
+}
+
+// SetDisplayLanguage は、コンソールやログに表示するプロパティ名・クラス名の言語を設定する
+func SetDisplayLanguage(lang string) error {
+	normalized, err := ParseLanguage(lang)
+	if err != nil {
+		return err
+	}
+	displayLanguage.Store(normalized)
+	return nil
+}
+
+// DisplayLanguage は、コンソールやログに表示するプロパティ名・クラス名の言語を返す（"" は英語）
+func DisplayLanguage() string {
+	if lang, ok := displayLanguage.Load().(string); ok {
+		return lang
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package echonet_lite
+
+import "testing"
+
+func TestNormalizeLanguage(t *testing.T) {
+	tests := map[string]string{
+		"":                "",
+		"ja":              "ja",
+		"ja-JP":           "ja",
+		"en_US.UTF-8":     "en",
+		"JA":              "ja",
+		"ja,en-US;q=0.8":  "ja",
+		" en-GB ; q=0.5 ": "en",
+	}
+	for input, want := range tests {
+		if got := NormalizeLanguage(input); got != want {
+			t.Errorf("NormalizeLanguage(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestSetDisplayLanguage(t *testing.T) {
+	t.Cleanup(func() { _ = SetDisplayLanguage("") })
+
+	aircon := MakeEOJ(HomeAirConditioner_ClassCode, 1)
+	if got := EPCOperationStatus.StringForClass(HomeAirConditioner_ClassCode); got != "80(Operation status)" {
+		t.Errorf("default StringForClass = %q", got)
+	}
+	if got := aircon.String(); got != "0130[Home Air Conditioner]:1" {
+		t.Errorf("default EOJ.String = %q", got)
+	}
+
+	if err := SetDisplayLanguage("ja-JP"); err != nil {
+		t.Fatal(err)
+	}
+	if got := DisplayLanguage(); got != "ja" {
+		t.Errorf("DisplayLanguage = %q, want ja", got)
+	}
+	if got := EPCOperationStatus.StringForClass(HomeAirConditioner_ClassCode); got != "80(動作状態)" {
+		t.Errorf("StringForClass in ja = %q", got)
+	}
+	if got := (Property{EPC: EPCOperationStatus}).EPCString(HomeAirConditioner_ClassCode); got != "80(動作状態)" {
+		t.Errorf("EPCString in ja = %q", got)
+	}
+	if got := aircon.String(); got != "0130[家庭用エアコン]:1" {
+		t.Errorf("EOJ.String in ja = %q", got)
+	}
+
+	if err := SetDisplayLanguage("fr"); err == nil {
+		t.Error("unsupported language was accepted")
+	}
+	if got := DisplayLanguage(); got != "ja" {
+		t.Errorf("DisplayLanguage changed by an unsupported language: %q", got)
+	}
+}
@@ -0,0 +1,69 @@
+package echonet_lite
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"echonet-list/echonet_lite/utils"
+)
+
+// Manufacturer は、メーカコード（EPC 0x8A）の表示用情報
+type Manufacturer struct {
+	Name   string // 英語のメーカ名
+	NameJa string // 日本語のメーカ名
+}
+
+// manufacturerTable は、ECHONET コンソーシアム（JEMA）のメーカコード一覧のうち、家庭でよく見かけるメーカ
+// 一覧にないメーカは、メーカコードの16進表記だけを表示する
+var manufacturerTable = map[uint32]Manufacturer{
+	0x000005: {Name: "Sharp", NameJa: "シャープ"},
+	0x000006: {Name: "Mitsubishi Electric", NameJa: "三菱電機"},
+	0x000008: {Name: "Daikin", NameJa: "ダイキン工業"},
+	0x00000b: {Name: "Panasonic", NameJa: "パナソニック"},
+	0x000016: {Name: "Toshiba", NameJa: "東芝"},
+	0xffffff: {Name: "Experimental", NameJa: "試験用"},
+}
+
+// LookupManufacturer は、メーカコード（3バイト）のメーカを返す
+func LookupManufacturer(code []byte) (Manufacturer, bool) {
+	if len(code) != 3 {
+		return Manufacturer{}, false
+	}
+	m, ok := manufacturerTable[utils.BytesToUint32(code)]
+	return m, ok
+}
+
+// DeviceInfo は、スーパークラスのプロパティから読み取ったデバイスの製品情報
+type DeviceInfo struct {
+	ManufacturerCode string       // メーカコードの16進表記（例: "00000B"）
+	Manufacturer     Manufacturer // メーカ（一覧にない場合はゼロ値）
+	ProductCode      string       // 商品コード（EPC 0x8C、末尾の空白・NULを除いたもの）
+	ProductionDate   string       // 製造年月日（EPC 0x8E、"2006-01-02" 形式）
+}
+
+// GetDeviceInfo は、メーカコード・商品コード・製造年月日をデコードする
+// デバイスが持たないプロパティや、不正な値のプロパティは空文字列になる
+func (ps Properties) GetDeviceInfo() DeviceInfo {
+	var info DeviceInfo
+	if p, ok := ps.FindEPC(EPCManufacturerCode); ok && len(p.EDT) == 3 {
+		info.ManufacturerCode = fmt.Sprintf("%X", p.EDT)
+		info.Manufacturer, _ = LookupManufacturer(p.EDT)
+	}
+	if p, ok := ps.FindEPC(EPCProductCode); ok {
+		code := p.EDT
+		if i := bytes.IndexByte(code, 0); i >= 0 {
+			code = code[:i]
+		}
+		info.ProductCode = strings.TrimSpace(string(code))
+	}
+	if p, ok := ps.FindEPC(EPCProductionDate); ok && len(p.EDT) == 4 {
+		year := int(p.EDT[0])<<8 | int(p.EDT[1])
+		month, day := int(p.EDT[2]), int(p.EDT[3])
+		// 製造年月日を持たない機器は 0 を返すことがある
+		if year > 0 && month >= 1 && month <= 12 && day >= 1 && day <= 31 {
+			info.ProductionDate = fmt.Sprintf("%04d-%02d-%02d", year, month, day)
+		}
+	}
+	return info
+}
@@ -0,0 +1,37 @@
+package echonet_lite
+
+import "testing"
+
+func TestGetDeviceInfo(t *testing.T) {
+	props := Properties{
+		{EPC: EPCManufacturerCode, EDT: []byte{0x00, 0x00, 0x0b}},
+		{EPC: EPCProductCode, EDT: []byte("CS-X409C2   ")},
+		{EPC: EPCProductionDate, EDT: []byte{0x07, 0xe7, 0x04, 0x01}},
+	}
+	info := props.GetDeviceInfo()
+	want := DeviceInfo{
+		ManufacturerCode: "00000B",
+		Manufacturer:     Manufacturer{Name: "Panasonic", NameJa: "パナソニック"},
+		ProductCode:      "CS-X409C2",
+		ProductionDate:   "2023-04-01",
+	}
+	if info != want {
+		t.Errorf("GetDeviceInfo() = %+v, want %+v", info, want)
+	}
+
+	// 一覧にないメーカはコードだけ、NUL 埋めの商品コードと 0 の製造年月日
+	props = Properties{
+		{EPC: EPCManufacturerCode, EDT: []byte{0x00, 0x12, 0x34}},
+		{EPC: EPCProductCode, EDT: []byte{'A', 'B', 'C', 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{EPC: EPCProductionDate, EDT: []byte{0, 0, 0, 0}},
+	}
+	info = props.GetDeviceInfo()
+	want = DeviceInfo{ManufacturerCode: "001234", ProductCode: "ABC"}
+	if info != want {
+		t.Errorf("GetDeviceInfo() = %+v, want %+v", info, want)
+	}
+
+	if info := (Properties{}).GetDeviceInfo(); info != (DeviceInfo{}) {
+		t.Errorf("expected empty info, got %+v", info)
+	}
+}
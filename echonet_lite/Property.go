@@ -70,7 +70,7 @@ func (pt PropertyTable) AvailableAliases() map[string]PropertyDescription {
 			aliases[alias] = PropertyDescription{
 				ClassCode: pt.ClassCode,
 				EPC:       epc,
-				Name:      desc.Name,
+				Name:      desc.GetName(DisplayLanguage()),
 				EDT:       desc.Aliases[alias],
 			}
 		}
@@ -135,7 +135,7 @@ func (e EPCType) String() string {
 
 func (e EPCType) StringForClass(c EOJClassCode) string {
 	if info, ok := GetPropertyDesc(c, e); ok {
-		return fmt.Sprintf("%s(%s)", e.String(), info.Name)
+		return fmt.Sprintf("%s(%s)", e.String(), info.GetName(DisplayLanguage()))
 	}
 	return e.String()
 }
@@ -143,7 +143,7 @@ func (e EPCType) StringForClass(c EOJClassCode) string {
 func (p Property) EPCString(c EOJClassCode) string {
 	EPC := p.EPC.String()
 	if info, ok := GetPropertyDesc(c, p.EPC); ok {
-		EPC = fmt.Sprintf("%s(%s)", EPC, info.Name)
+		EPC = fmt.Sprintf("%s(%s)", EPC, info.GetName(DisplayLanguage()))
 	}
 	return EPC
 }
@@ -0,0 +1,185 @@
+package echonet_lite
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// PropertyOverridesFileName は、プロパティの説明を追加・上書きするユーザーの設定ファイルのデフォルト名
+const PropertyOverridesFileName = "property-descriptions-override.json"
+
+// MinVendorEPC は、追加・上書きできる EPC の下限。0xF0-0xFF はメーカー独自のプロパティに使われる
+const MinVendorEPC EPCType = 0xF0
+
+// NumberOverride は、数値プロパティの変換を表す（NumberDesc と同じ意味）
+type NumberOverride struct {
+	Min    int    `json:"min"`
+	Max    int    `json:"max"`
+	Offset int    `json:"offset,omitempty"`
+	Unit   string `json:"unit,omitempty"`
+	EDTLen int    `json:"edtLen,omitempty"` // 1-4。0 のときは 1
+}
+
+// PropertyDescOverride は、プロパティの説明の追加・上書きの内容
+// 指定した項目だけを上書きし、省略した項目は元の説明のまま
+type PropertyDescOverride struct {
+	Name                  string                       `json:"name,omitempty"`
+	NameTranslations      map[string]string            `json:"nameTranslations,omitempty"`
+	ShortName             string                       `json:"shortName,omitempty"`
+	ShortNameTranslations map[string]string            `json:"shortNameTranslations,omitempty"`
+	Aliases               map[string]string            `json:"aliases,omitempty"` // エイリアス -> EDT（16進数）
+	AliasTranslations     map[string]map[string]string `json:"aliasTranslations,omitempty"`
+	Number                *NumberOverride              `json:"number,omitempty"`
+}
+
+// PropertyDescOverrides は、クラスコード（16進数4桁）ごと、EPC（16進数2桁）ごとの上書き内容
+type PropertyDescOverrides map[string]map[string]PropertyDescOverride
+
+// LoadPropertyDescOverrides は、ファイルからプロパティの説明の上書き内容を読み込む
+// ファイルが存在しない場合は nil を返す
+func LoadPropertyDescOverrides(filename string) (PropertyDescOverrides, error) {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var overrides PropertyDescOverrides
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	return overrides, nil
+}
+
+// ApplyPropertyDescOverrides は、上書き内容を PropertyTables に反映し、反映した EPC の数を返す
+// 1つでも不正な内容があれば何も反映せずにエラーを返す
+// PropertyTables を書き換えるため、起動時にほかの処理が始まる前に呼ぶこと
+func ApplyPropertyDescOverrides(overrides PropertyDescOverrides) (int, error) {
+	tables := make(map[EOJClassCode]PropertyTable)
+	count := 0
+	for _, classKey := range slices.Sorted(maps.Keys(overrides)) {
+		c, err := strconv.ParseUint(classKey, 16, 16)
+		if err != nil || len(classKey) != 4 {
+			return 0, fmt.Errorf("invalid class code: %q (must be 4 hexadecimal digits)", classKey)
+		}
+		classCode := EOJClassCode(c)
+		table, err := overriddenTable(classCode, overrides[classKey])
+		if err != nil {
+			return 0, fmt.Errorf("class %s: %w", classKey, err)
+		}
+		tables[classCode] = table
+		count += len(overrides[classKey])
+	}
+
+	for classCode, table := range tables {
+		PropertyTables[classCode] = table
+	}
+	return count, nil
+}
+
+// overriddenTable は、クラスのプロパティテーブルに上書き内容を反映したコピーを返す
+// クラスのテーブルがない場合は新しく作る
+func overriddenTable(classCode EOJClassCode, overrides map[string]PropertyDescOverride) (PropertyTable, error) {
+	table, ok := PropertyTables[classCode]
+	if !ok {
+		table = PropertyTable{ClassCode: classCode, Description: fmt.Sprintf("Class %04X", uint16(classCode))}
+		if meta, ok := GetClassMetadata(classCode); ok {
+			table.Description = meta.Name
+			table.DescriptionTranslations = map[string]string{"ja": meta.NameJa}
+		}
+	}
+	table.EPCDesc = maps.Clone(table.EPCDesc)
+	if table.EPCDesc == nil {
+		table.EPCDesc = make(map[EPCType]PropertyDesc)
+	}
+
+	var overridden []EPCType
+	for _, epcKey := range slices.Sorted(maps.Keys(overrides)) {
+		e, err := strconv.ParseUint(epcKey, 16, 8)
+		if err != nil || len(epcKey) != 2 {
+			return table, fmt.Errorf("invalid EPC: %q (must be 2 hexadecimal digits)", epcKey)
+		}
+		epc := EPCType(e)
+		if epc < MinVendorEPC {
+			return table, fmt.Errorf("EPC %s: only vendor-specific EPCs (F0-FF) can be overridden", epcKey)
+		}
+		desc, err := overrides[epcKey].apply(table.EPCDesc[epc])
+		if err != nil {
+			return table, fmt.Errorf("EPC %s: %w", epcKey, err)
+		}
+		table.EPCDesc[epc] = desc
+		overridden = append(overridden, epc)
+	}
+
+	// set コマンドでエイリアスから EPC を決めるため、エイリアスはクラスの中と共通プロパティとで一意でなければならない
+	for _, epc := range overridden {
+		for alias := range table.EPCDesc[epc].Aliases {
+			for other, desc := range table.EPCDesc {
+				if _, ok := desc.Aliases[alias]; ok && other != epc {
+					return table, fmt.Errorf("alias %q is used by both EPC %02X and %02X", alias, byte(epc), byte(other))
+				}
+			}
+			if classCode != NodeProfile_ClassCode {
+				if _, ok := ProfileSuperClass_PropertyTable.FindAlias(alias); ok {
+					return table, fmt.Errorf("alias %q of EPC %02X is reserved by the super class", alias, byte(epc))
+				}
+			}
+		}
+	}
+	return table, nil
+}
+
+// apply は、プロパティの説明に上書き内容を反映したものを返す
+func (o PropertyDescOverride) apply(desc PropertyDesc) (PropertyDesc, error) {
+	if o.Name != "" {
+		desc.Name = o.Name
+	}
+	if desc.Name == "" {
+		return desc, fmt.Errorf("name is required for a new property")
+	}
+	if o.NameTranslations != nil {
+		desc.NameTranslations = o.NameTranslations
+	}
+	if o.ShortName != "" {
+		desc.ShortName = o.ShortName
+	}
+	if o.ShortNameTranslations != nil {
+		desc.ShortNameTranslations = o.ShortNameTranslations
+	}
+	if o.Aliases != nil {
+		desc.Aliases = make(map[string][]byte, len(o.Aliases))
+		for alias, edtHex := range o.Aliases {
+			edt, err := hex.DecodeString(edtHex)
+			if alias == "" || err != nil || len(edt) == 0 {
+				return desc, fmt.Errorf("invalid alias %q: %q (EDT must be hexadecimal)", alias, edtHex)
+			}
+			desc.Aliases[alias] = edt
+		}
+	}
+	if o.AliasTranslations != nil {
+		for lang, translations := range o.AliasTranslations {
+			for alias := range translations {
+				if _, ok := desc.Aliases[alias]; !ok {
+					return desc, fmt.Errorf("translation of unknown alias %q (%s)", alias, lang)
+				}
+			}
+		}
+		desc.AliasTranslations = o.AliasTranslations
+	}
+	if n := o.Number; n != nil {
+		if n.Min > n.Max {
+			return desc, fmt.Errorf("number: min %d is greater than max %d", n.Min, n.Max)
+		}
+		if n.EDTLen < 0 || n.EDTLen > 4 {
+			return desc, fmt.Errorf("number: edtLen must be between 1 and 4: %d", n.EDTLen)
+		}
+		desc.Decoder = NumberDesc{Min: n.Min, Max: n.Max, Offset: n.Offset, Unit: n.Unit, EDTLen: n.EDTLen}
+	}
+	return desc, nil
+}
@@ -0,0 +1,130 @@
+package echonet_lite
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// restorePropertyTables は、テストで書き換えた PropertyTables をテストの終了時に元に戻す
+func restorePropertyTables(t *testing.T) {
+	t.Helper()
+	saved := maps.Clone(PropertyTables)
+	t.Cleanup(func() {
+		for c := range PropertyTables {
+			delete(PropertyTables, c)
+		}
+		maps.Copy(PropertyTables, saved)
+	})
+}
+
+func TestApplyPropertyDescOverrides(t *testing.T) {
+	restorePropertyTables(t)
+
+	filename := filepath.Join(t.TempDir(), PropertyOverridesFileName)
+	content := `{
+  "0130": {
+    "F1": {
+      "name": "Vendor airflow mode",
+      "nameTranslations": {"ja": "独自風量モード"},
+      "aliases": {"breeze": "41", "storm": "42"},
+      "aliasTranslations": {"ja": {"breeze": "そよ風"}}
+    },
+    "F2": {
+      "name": "Vendor filter usage",
+      "number": {"min": 0, "max": 1000, "unit": "h", "edtLen": 2}
+    }
+  },
+  "0aff": {
+    "F0": {"name": "Vendor counter", "number": {"min": -100, "max": 100}}
+  }
+}`
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	overrides, err := LoadPropertyDescOverrides(filename)
+	if err != nil {
+		t.Fatalf("LoadPropertyDescOverrides に失敗: %v", err)
+	}
+	count, err := ApplyPropertyDescOverrides(overrides)
+	if err != nil || count != 3 {
+		t.Fatalf("ApplyPropertyDescOverrides() = %d, %v", count, err)
+	}
+
+	// 追加したエイリアスで表示・設定できる
+	desc, ok := GetPropertyDesc(HomeAirConditioner_ClassCode, 0xF1)
+	if !ok || desc.GetName("ja") != "独自風量モード" {
+		t.Fatalf("F1 の説明が追加されていない: %+v", desc)
+	}
+	if got := desc.EDTToString([]byte{0x42}); got != "storm" {
+		t.Errorf("EDTToString = %q, want storm", got)
+	}
+	if prop, ok := PropertyTables.FindAlias(HomeAirConditioner_ClassCode, "breeze"); !ok || prop.EPC != 0xF1 || prop.EDT[0] != 0x41 {
+		t.Errorf("FindAlias(breeze) = %v, %v", prop, ok)
+	}
+
+	// 数値の変換
+	desc, ok = GetPropertyDesc(HomeAirConditioner_ClassCode, 0xF2)
+	if !ok {
+		t.Fatal("F2 の説明が追加されていない")
+	}
+	if got := desc.EDTToString([]byte{0x01, 0xF4}); got != "500h" {
+		t.Errorf("EDTToString = %q, want 500h", got)
+	}
+
+	// 元のプロパティはそのまま
+	if _, ok := GetPropertyDesc(HomeAirConditioner_ClassCode, EPC_HAC_OperationModeSetting); !ok {
+		t.Error("元のプロパティが失われた")
+	}
+
+	// テーブルのないクラスは新しく作られる
+	desc, ok = GetPropertyDesc(0x0aff, 0xF0)
+	if !ok || desc.EDTToString([]byte{0xF6}) != "-10" {
+		t.Errorf("0AFF の F0 が追加されていない: %+v", desc)
+	}
+	if table := PropertyTables[0x0aff]; table.Description == "" {
+		t.Error("新しいクラスの説明が空")
+	}
+}
+
+func TestApplyPropertyDescOverrides_Invalid(t *testing.T) {
+	restorePropertyTables(t)
+
+	for name, overrides := range map[string]PropertyDescOverrides{
+		"不正なクラスコード":    {"130": {"F1": {Name: "x"}}},
+		"不正な EPC":      {"0130": {"G1": {Name: "x"}}},
+		"標準の EPC":      {"0130": {"80": {Name: "x"}}},
+		"名前のない新しい EPC": {"0130": {"F1": {ShortName: "x"}}},
+		"不正な EDT":      {"0130": {"F1": {Name: "x", Aliases: map[string]string{"a": "zz"}}}},
+		"共通のエイリアス":     {"0130": {"F1": {Name: "x", Aliases: map[string]string{"on": "41"}}}},
+		"重複するエイリアス": {"0130": {
+			"F1": {Name: "x", Aliases: map[string]string{"same": "41"}},
+			"F2": {Name: "y", Aliases: map[string]string{"same": "42"}},
+		}},
+		"未知のエイリアスの翻訳":     {"0130": {"F1": {Name: "x", AliasTranslations: map[string]map[string]string{"ja": {"a": "あ"}}}}},
+		"min が max より大きい": {"0130": {"F1": {Name: "x", Number: &NumberOverride{Min: 10, Max: 0}}}},
+		"長すぎる EDT":        {"0130": {"F1": {Name: "x", Number: &NumberOverride{Max: 10, EDTLen: 5}}}},
+	} {
+		if _, err := ApplyPropertyDescOverrides(overrides); err == nil {
+			t.Errorf("%s: エラーにならない", name)
+		}
+	}
+
+	// エラーの場合は何も反映されない
+	overrides := PropertyDescOverrides{
+		"0291": {"F1": {Name: "valid"}},
+		"0130": {"80": {Name: "invalid"}},
+	}
+	if _, err := ApplyPropertyDescOverrides(overrides); err == nil {
+		t.Fatal("エラーにならない")
+	}
+	if _, ok := GetPropertyDesc(SingleFunctionLighting_ClassCode, 0xF1); ok {
+		t.Error("エラーなのに反映された")
+	}
+
+	// ファイルがない場合は何もしない
+	if overrides, err := LoadPropertyDescOverrides(filepath.Join(t.TempDir(), "missing.json")); err != nil || overrides != nil {
+		t.Errorf("LoadPropertyDescOverrides() = %v, %v", overrides, err)
+	}
+}
@@ -0,0 +1,55 @@
+package echonet_lite
+
+// SetOrderEPCs は、クラスごとに他のプロパティより先に設定するEPCの一覧（先頭から順に設定する）
+// 動作状態が OFF の間は運転モードや温度設定を受け付けない機器があるため、
+// 1つの要求で複数のプロパティを設定する場合は、ここに挙げたEPCを1つずつ先に設定してから残りを設定する
+var SetOrderEPCs = map[EOJClassCode][]EPCType{
+	HomeAirConditioner_ClassCode: {
+		EPCOperationStatus,
+		EPC_HAC_OperationModeSetting, // 温度設定値の範囲は運転モードによって変わる
+	},
+	FloorHeating_ClassCode: {
+		EPCOperationStatus,
+	},
+	GeneralLighting_ClassCode: {
+		EPCOperationStatus,
+	},
+	SingleFunctionLighting_ClassCode: {
+		EPCOperationStatus,
+	},
+	LightingSystem_ClassCode: {
+		EPCOperationStatus,
+	},
+}
+
+// SetOrderStages は、設定するプロパティを送信する順に段階へ分ける
+// SetOrderEPCs のEPCはそれぞれ単独の段階になり、残りのプロパティは最後の段階にまとめる
+// 順序を考慮する必要がない場合は、properties をそのまま1つの段階として返す
+func SetOrderStages(classCode EOJClassCode, properties Properties) []Properties {
+	if len(properties) <= 1 {
+		return []Properties{properties}
+	}
+
+	var stages []Properties
+	ordered := make(map[EPCType]bool)
+	for _, epc := range SetOrderEPCs[classCode] {
+		if prop, ok := properties.FindEPC(epc); ok {
+			stages = append(stages, Properties{prop})
+			ordered[epc] = true
+		}
+	}
+	if len(stages) == 0 {
+		return []Properties{properties}
+	}
+
+	var rest Properties
+	for _, prop := range properties {
+		if !ordered[prop.EPC] {
+			rest = append(rest, prop)
+		}
+	}
+	if len(rest) > 0 {
+		stages = append(stages, rest)
+	}
+	return stages
+}
@@ -0,0 +1,43 @@
+package echonet_lite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetOrderStages(t *testing.T) {
+	epcs := func(stages []Properties) [][]EPCType {
+		result := make([][]EPCType, len(stages))
+		for i, stage := range stages {
+			for _, prop := range stage {
+				result[i] = append(result[i], prop.EPC)
+			}
+		}
+		return result
+	}
+	props := func(list ...EPCType) Properties {
+		result := make(Properties, len(list))
+		for i, epc := range list {
+			result[i] = Property{EPC: epc, EDT: []byte{0x30}}
+		}
+		return result
+	}
+
+	tests := []struct {
+		name      string
+		classCode EOJClassCode
+		props     Properties
+		want      [][]EPCType
+	}{
+		{"温度・運転モード・動作状態の順に指定したエアコン", HomeAirConditioner_ClassCode, props(0xB3, 0xB0, 0x80, 0xA0), [][]EPCType{{0x80}, {0xB0}, {0xB3, 0xA0}}},
+		{"順序のあるEPCだけのエアコン", HomeAirConditioner_ClassCode, props(0xB0, 0x80), [][]EPCType{{0x80}, {0xB0}}},
+		{"動作状態を含まない照明", SingleFunctionLighting_ClassCode, props(0xB0, 0xB1), [][]EPCType{{0xB0, 0xB1}}},
+		{"1つだけの設定", HomeAirConditioner_ClassCode, props(0xB3), [][]EPCType{{0xB3}}},
+		{"規則のないクラス", Refrigerator_ClassCode, props(0xB3, 0x80), [][]EPCType{{0xB3, 0x80}}},
+	}
+	for _, tt := range tests {
+		if got := epcs(SetOrderStages(tt.classCode, tt.props)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: stages = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
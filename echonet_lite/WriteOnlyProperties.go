@@ -0,0 +1,20 @@
+package echonet_lite
+
+// WriteOnlyEPCs は、クラスごとの書き込み専用プロパティ（アクセスルールが Set のみ）の一覧
+// 書き込み専用プロパティは GetPropertyMap に現れず、SetPropertyMap に載せない機器もあるため、
+// ここに挙げたEPCはプロパティマップによる設定時の検証を行わない
+var WriteOnlyEPCs = map[EOJClassCode][]EPCType{
+	EVChargerDischarger_ClassCode: {
+		0xCD, // 車両接続・充放電可否状態確認
+	},
+}
+
+// IsWriteOnlyEPC は、指定したクラスのEPCが書き込み専用プロパティとして登録されているかを返す
+func IsWriteOnlyEPC(classCode EOJClassCode, epc EPCType) bool {
+	for _, e := range WriteOnlyEPCs[classCode] {
+		if e == epc {
+			return true
+		}
+	}
+	return false
+}
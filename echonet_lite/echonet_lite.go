@@ -30,7 +30,7 @@ const (
 )
 
 var ECHONETLiteMulticastIPv4 = net.ParseIP("224.0.23.0")
-var ECHONETLiteMulticastIPv6 = net.ParseIP("ff02:1") // 将来IPv6 に対応したときに使う
+var ECHONETLiteMulticastIPv6 = net.ParseIP("ff02::1")
 
 type EHDType uint16
 
@@ -193,6 +193,9 @@ func (e ESVType) ISSetGet() bool {
 }
 
 func parseProperties(data []byte, pos int) (int, []Property, error) {
+	if pos >= len(data) {
+		return pos, nil, fmt.Errorf("OPCがありません")
+	}
 	OPC := data[pos]
 	pos++
 	properties := make([]Property, 0, OPC)
@@ -218,10 +221,17 @@ func parseProperties(data []byte, pos int) (int, []Property, error) {
 }
 
 // ParseECHONETLiteMessage は受信したバイト列からECHONET Liteメッセージをパースします。
+// 最後のプロパティの後の余分なバイトや、不明なEHD・ESVは許容する
 func ParseECHONETLiteMessage(data []byte) (*ECHONETLiteMessage, error) {
+	msg, _, err := parseMessage(data)
+	return msg, err
+}
+
+// parseMessage は、メッセージと、メッセージの終わりの位置を返す
+func parseMessage(data []byte) (*ECHONETLiteMessage, int, error) {
 	// 最低限、EHD(2)+TID(2)+SEOJ(3)+DEOJ(3)+ESV(1)+OPC(1)=12バイトは必要
 	if len(data) < 12 {
-		return nil, fmt.Errorf("パケットが短すぎます: %d バイト", len(data))
+		return nil, 0, fmt.Errorf("パケットが短すぎます: %d バイト", len(data))
 	}
 
 	msg := &ECHONETLiteMessage{
@@ -233,18 +243,18 @@ func ParseECHONETLiteMessage(data []byte) (*ECHONETLiteMessage, error) {
 	}
 	pos, properties, err := parseProperties(data, 11)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	msg.Properties = properties
 
 	if msg.ESV.ISSetGet() {
-		_, properties, err = parseProperties(data, pos)
+		pos, properties, err = parseProperties(data, pos)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		msg.SetGetProperties = properties
 	}
-	return msg, nil
+	return msg, pos, nil
 }
 
 type IEncodable interface {
@@ -264,6 +274,10 @@ func (m *ECHONETLiteMessage) Encode() []byte {
 	if EHD == 0 {
 		EHD = EHD_ECHONETLite
 	}
+	if m.ESV.ISSetGet() {
+		// SetGet は書き込みプロパティの後に読み出しプロパティが続く
+		return encode(EHD, m.TID, m.SEOJ, m.DEOJ, m.ESV, m.Properties, m.SetGetProperties)
+	}
 	return encode(EHD, m.TID, m.SEOJ, m.DEOJ, m.ESV, m.Properties)
 }
 
@@ -0,0 +1,35 @@
+package echonet_lite
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestECHONETLiteMessage_SetGetRoundTrip(t *testing.T) {
+	msg := &ECHONETLiteMessage{
+		TID:              0x1234,
+		SEOJ:             MakeEOJ(Controller_ClassCode, 1),
+		DEOJ:             MakeEOJ(HomeAirConditioner_ClassCode, 1),
+		ESV:              ESVSetGet,
+		Properties:       Properties{{EPC: 0x80, EDT: []byte{0x30}}},
+		SetGetProperties: Properties{{EPC: 0x80}, {EPC: 0xB0}},
+	}
+
+	data := msg.Encode()
+	// Set part: OPCSet=1, EPC=0x80, PDC=1, EDT=0x30 / Get part: OPCGet=2, 0x80 PDC=0, 0xB0 PDC=0
+	want := []byte{0x01, 0x80, 0x01, 0x30, 0x02, 0x80, 0x00, 0xB0, 0x00}
+	if !bytes.Equal(data[11:], want) {
+		t.Fatalf("Encoded properties = % X, want % X", data[11:], want)
+	}
+
+	parsed, err := ParseECHONETLiteMessage(data)
+	if err != nil {
+		t.Fatalf("ParseECHONETLiteMessage failed: %v", err)
+	}
+	if len(parsed.Properties) != 1 || !bytes.Equal(parsed.Properties[0].EDT, []byte{0x30}) {
+		t.Errorf("Set properties = %v", parsed.Properties)
+	}
+	if len(parsed.SetGetProperties) != 2 || parsed.SetGetProperties[1].EPC != 0xB0 {
+		t.Errorf("Get properties = %v", parsed.SetGetProperties)
+	}
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"echonet-list/echonet_lite"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// AnonymizedEPCs は、匿名化するプロパティ（機器や設置場所を特定できる値）
+var AnonymizedEPCs = []EPCType{
+	echonet_lite.EPCIdentificationNumber,
+	echonet_lite.EPCBusinessFacilityCode,
+	echonet_lite.EPCProductionNumber,
+}
+
+// Anonymizer は、IPアドレス・識別番号・製造番号などを仮名に置き換える
+// 同じ Anonymizer からは同じ値に同じ仮名を返すため、匿名化した後もデバイスや履歴の対応関係は保たれる
+// 仮名は秘密の鍵による HMAC から作るため、元の値を総当たりで求めることはできない
+type Anonymizer struct {
+	key []byte
+
+	mu     sync.Mutex
+	ips    map[string]net.IP // 元のIPアドレス -> 仮名
+	usedIP map[string]bool   // 割り当て済みの仮名
+}
+
+// NewAnonymizer は、鍵をランダムに生成した Anonymizer を作成する
+// 仮名はこの Anonymizer を使う間（通常はプロセスの実行中）だけ同じになる
+func NewAnonymizer() *Anonymizer {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("failed to generate anonymizer key: %v", err))
+	}
+	return NewAnonymizerWithKey(key)
+}
+
+// NewAnonymizerWithKey は、指定した鍵の Anonymizer を作成する。同じ鍵からは同じ仮名が作られる
+func NewAnonymizerWithKey(key []byte) *Anonymizer {
+	return &Anonymizer{
+		key:    key,
+		ips:    make(map[string]net.IP),
+		usedIP: make(map[string]bool),
+	}
+}
+
+// pseudonym は、kind と value から n バイトの仮名を作る
+func (a *Anonymizer) pseudonym(kind string, value []byte, n int) []byte {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write(value)
+	sum := mac.Sum(nil)
+	for len(sum) < n {
+		mac.Write(sum)
+		sum = mac.Sum(sum)
+	}
+	return sum[:n]
+}
+
+// IP は、IPアドレスの仮名を返す
+// IPv4 は 10.0.0.0/8、IPv6 は fd00::/8 の中のアドレスにする
+func (a *Anonymizer) IP(ip net.IP) net.IP {
+	if ip == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if pseudonym, ok := a.ips[ip.String()]; ok {
+		return pseudonym
+	}
+	for counter := byte(0); ; counter++ {
+		var pseudonym net.IP
+		if ip4 := ip.To4(); ip4 != nil {
+			b := a.pseudonym("ipv4", append(append([]byte{}, ip4...), counter), 3)
+			pseudonym = net.IPv4(10, b[0], b[1], b[2]).To4()
+		} else {
+			pseudonym = make(net.IP, net.IPv6len)
+			pseudonym[0] = 0xfd
+			copy(pseudonym[1:], a.pseudonym("ipv6", append(append([]byte{}, ip.To16()...), counter), net.IPv6len-1))
+		}
+		// 異なるアドレスが同じ仮名にならないようにする
+		if !a.usedIP[pseudonym.String()] {
+			a.ips[ip.String()] = pseudonym
+			a.usedIP[pseudonym.String()] = true
+			return pseudonym
+		}
+	}
+}
+
+// Device は、デバイスのIPアドレスを仮名に置き換える
+func (a *Anonymizer) Device(device IPAndEOJ) IPAndEOJ {
+	return IPAndEOJ{IP: a.IP(device.IP), EOJ: device.EOJ}
+}
+
+// Property は、AnonymizedEPCs のプロパティの値を、同じ形式の仮名に置き換える
+func (a *Anonymizer) Property(p Property) Property {
+	if len(p.EDT) == 0 {
+		return p
+	}
+	switch p.EPC {
+	case echonet_lite.EPCIdentificationNumber:
+		// メーカコードは残し、機器固有の部分を置き換える（IDString も仮名になる）
+		if id := echonet_lite.DecodeIdentificationNumber(p.EDT); id != nil {
+			anonymized := echonet_lite.IdentificationNumber{
+				ManufacturerCode: id.ManufacturerCode,
+				UniqueIdentifier: a.pseudonym("id", p.EDT, len(id.UniqueIdentifier)),
+			}
+			return *anonymized.Property()
+		}
+		return Property{EPC: p.EPC, EDT: a.pseudonym("id", p.EDT, len(p.EDT))}
+	case echonet_lite.EPCProductionNumber:
+		// 製造番号は ASCII のため、同じ長さの英数字にする
+		const digits = "0123456789ABCDEF"
+		edt := a.pseudonym("serial", p.EDT, len(p.EDT))
+		for i, b := range edt {
+			edt[i] = digits[b%16]
+		}
+		return Property{EPC: p.EPC, EDT: edt}
+	case echonet_lite.EPCBusinessFacilityCode:
+		return Property{EPC: p.EPC, EDT: a.pseudonym("facility", p.EDT, len(p.EDT))}
+	}
+	return p
+}
+
+// Properties は、各プロパティを Property で匿名化したコピーを返す
+func (a *Anonymizer) Properties(properties Properties) Properties {
+	result := make(Properties, len(properties))
+	for i, p := range properties {
+		result[i] = a.Property(p)
+	}
+	return result
+}
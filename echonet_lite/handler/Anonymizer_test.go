@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"echonet-list/echonet_lite"
+)
+
+func TestAnonymizer_IP(t *testing.T) {
+	a := NewAnonymizerWithKey([]byte("test"))
+
+	ip1 := a.IP(net.ParseIP("192.168.1.10"))
+	ip2 := a.IP(net.ParseIP("192.168.1.11"))
+	if !ip1.Equal(a.IP(net.ParseIP("192.168.1.10"))) {
+		t.Error("同じIPアドレスに同じ仮名が返されない")
+	}
+	if ip1.Equal(ip2) {
+		t.Errorf("異なるIPアドレスに同じ仮名が返された: %v", ip1)
+	}
+	if ip1.To4() == nil || ip1.To4()[0] != 10 {
+		t.Errorf("IPv4 の仮名が 10.0.0.0/8 でない: %v", ip1)
+	}
+
+	ip6 := a.IP(net.ParseIP("2001:db8::1"))
+	if ip6.To4() != nil || ip6[0] != 0xfd {
+		t.Errorf("IPv6 の仮名が fd00::/8 でない: %v", ip6)
+	}
+
+	// 鍵が同じなら別の Anonymizer でも同じ仮名になる
+	if !ip1.Equal(NewAnonymizerWithKey([]byte("test")).IP(net.ParseIP("192.168.1.10"))) {
+		t.Error("同じ鍵で同じ仮名にならない")
+	}
+}
+
+func TestAnonymizer_Property(t *testing.T) {
+	a := NewAnonymizerWithKey([]byte("test"))
+
+	id := echonet_lite.IdentificationNumber{
+		ManufacturerCode: []byte{0x00, 0x00, 0x06},
+		UniqueIdentifier: bytes.Repeat([]byte{0x12}, 13),
+	}
+	original := *id.Property()
+	anonymized := a.Property(original)
+	decoded := echonet_lite.DecodeIdentificationNumber(anonymized.EDT)
+	if decoded == nil {
+		t.Fatalf("識別番号の形式が保たれていない: %X", anonymized.EDT)
+	}
+	if !bytes.Equal(decoded.ManufacturerCode, id.ManufacturerCode) {
+		t.Errorf("メーカコードが変わった: %X", decoded.ManufacturerCode)
+	}
+	if bytes.Equal(decoded.UniqueIdentifier, id.UniqueIdentifier) {
+		t.Error("識別番号が置き換えられていない")
+	}
+	if !bytes.Equal(a.Property(original).EDT, anonymized.EDT) {
+		t.Error("同じ識別番号に同じ仮名が返されない")
+	}
+
+	serial := Property{EPC: echonet_lite.EPCProductionNumber, EDT: []byte("SN-0001234567")}
+	anonymizedSerial := a.Property(serial)
+	if len(anonymizedSerial.EDT) != len(serial.EDT) || bytes.Equal(anonymizedSerial.EDT, serial.EDT) {
+		t.Errorf("製造番号が同じ長さの仮名に置き換えられていない: %q", anonymizedSerial.EDT)
+	}
+	for _, b := range anonymizedSerial.EDT {
+		if !('0' <= b && b <= '9' || 'A' <= b && b <= 'F') {
+			t.Errorf("製造番号の仮名が英数字でない: %q", anonymizedSerial.EDT)
+			break
+		}
+	}
+
+	// 対象外のプロパティはそのまま
+	status := Property{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}}
+	properties := a.Properties(Properties{status, serial})
+	if !bytes.Equal(properties[0].EDT, status.EDT) || !bytes.Equal(properties[1].EDT, anonymizedSerial.EDT) {
+		t.Errorf("Properties の結果が不正: %v", properties)
+	}
+	if !bytes.Equal(serial.EDT, []byte("SN-0001234567")) {
+		t.Error("元のプロパティが書き換えられた")
+	}
+}
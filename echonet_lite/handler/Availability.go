@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+// DeviceAvailability は、オンライン/オフラインの履歴から求めたデバイスの稼働率
+type DeviceAvailability struct {
+	Device       IPAndEOJ
+	Window       time.Duration // 集計した期間（現在までの長さ）
+	Offline      time.Duration // 期間中にオフラインだった時間
+	OfflineCount int           // 期間中にオフラインになった回数
+}
+
+// Percent は、期間中にオンラインだった時間の割合（0〜100）を返す
+func (a DeviceAvailability) Percent() float64 {
+	if a.Window <= 0 {
+		return 100
+	}
+	return 100 * float64(a.Window-a.Offline) / float64(a.Window)
+}
+
+// isAvailabilityEvent は、履歴がオンライン/オフラインのイベントかどうかを返す
+func isAvailabilityEvent(entry DeviceHistoryEntry) bool {
+	return entry.Origin == HistoryOriginOnline || entry.Origin == HistoryOriginOffline
+}
+
+// computeAvailability は、古い順のオンライン/オフラインのイベントから、since から until までのオフライン時間と回数を求める
+// 期間の開始時点の状態は、それより前の最後のイベントで決める。前のイベントがなければ期間中の最初のイベントの逆とし、
+// イベントが1つもなければ現在の状態が期間中ずっと続いていたとみなす
+func computeAvailability(events []DeviceHistoryEntry, since, until time.Time, offlineNow bool) (time.Duration, int) {
+	first := 0
+	for first < len(events) && events[first].Timestamp.Before(since) {
+		first++
+	}
+
+	var offline bool
+	switch {
+	case first > 0:
+		offline = events[first-1].Origin == HistoryOriginOffline
+	case first < len(events):
+		offline = events[first].Origin == HistoryOriginOnline
+	default:
+		offline = offlineNow
+	}
+
+	var total time.Duration
+	count := 0
+	offlineSince := since
+	for _, event := range events[first:] {
+		if event.Timestamp.After(until) {
+			break
+		}
+		switch {
+		case event.Origin == HistoryOriginOffline && !offline:
+			offline = true
+			offlineSince = event.Timestamp
+			count++
+		case event.Origin == HistoryOriginOnline && offline:
+			offline = false
+			total += event.Timestamp.Sub(offlineSince)
+		}
+	}
+	if offline {
+		total += until.Sub(offlineSince)
+	}
+	return total, count
+}
+
+// GetDeviceAvailability は、直近 window の間のデバイスの稼働率を、履歴に記録されたオンライン/オフラインのイベントから求める
+// 履歴の保存件数を超えて古いイベントは数えられないため、長い期間を見るには journal バックエンドを使う
+func (h *ECHONETLiteHandler) GetDeviceAvailability(device IPAndEOJ, window time.Duration) DeviceAvailability {
+	now := time.Now()
+	var events []DeviceHistoryEntry
+	if h.data.DeviceHistory != nil {
+		for _, entry := range h.data.DeviceHistory.Query(device, HistoryQuery{Until: now}) {
+			if isAvailabilityEvent(entry) {
+				events = append(events, entry)
+			}
+		}
+	}
+	// Query は新しい順に返すため、古い順に並べ替える
+	slices.Reverse(events)
+
+	offline, count := computeAvailability(events, now.Add(-window), now, h.data.IsOffline(device))
+	return DeviceAvailability{Device: device, Window: window, Offline: offline, OfflineCount: count}
+}
+
+// GetAvailability は、すべてのデバイスの直近 window の間の稼働率を、低い順（不安定なデバイスが先）に返す
+func (h *ECHONETLiteHandler) GetAvailability(window time.Duration) []DeviceAvailability {
+	devices := h.data.devices.ListIPAndEOJ()
+	result := make([]DeviceAvailability, 0, len(devices))
+	for _, device := range devices {
+		result = append(result, h.GetDeviceAvailability(device, window))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Offline != result[j].Offline {
+			return result[i].Offline > result[j].Offline
+		}
+		if result[i].OfflineCount != result[j].OfflineCount {
+			return result[i].OfflineCount > result[j].OfflineCount
+		}
+		return result[i].Device.Compare(result[j].Device) < 0
+	})
+	return result
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+)
+
+func TestComputeAvailability(t *testing.T) {
+	until := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	since := until.Add(-24 * time.Hour)
+	event := func(hoursAgo int, origin HistoryOrigin) DeviceHistoryEntry {
+		return DeviceHistoryEntry{Timestamp: until.Add(-time.Duration(hoursAgo) * time.Hour), Origin: origin}
+	}
+
+	tests := []struct {
+		name        string
+		events      []DeviceHistoryEntry
+		offlineNow  bool
+		wantOffline time.Duration
+		wantCount   int
+	}{
+		{"イベントなし・オンライン", nil, false, 0, 0},
+		{"イベントなし・オフライン", nil, true, 24 * time.Hour, 0},
+		{
+			"期間中に2回オフライン",
+			[]DeviceHistoryEntry{event(20, HistoryOriginOffline), event(18, HistoryOriginOnline), event(2, HistoryOriginOffline)},
+			true, 4 * time.Hour, 2,
+		},
+		{
+			// 期間より前のイベントで開始時点の状態が決まる
+			"期間の前からオフライン",
+			[]DeviceHistoryEntry{event(30, HistoryOriginOffline), event(12, HistoryOriginOnline)},
+			false, 12 * time.Hour, 0,
+		},
+		{
+			// 前のイベントがなければ、最初のイベントの逆の状態から始まる
+			"最初のイベントがオンライン",
+			[]DeviceHistoryEntry{event(6, HistoryOriginOnline)},
+			false, 18 * time.Hour, 0,
+		},
+		{
+			// 同じ状態のイベントが続いても二重に数えない
+			"重複したオフライン",
+			[]DeviceHistoryEntry{event(10, HistoryOriginOffline), event(9, HistoryOriginOffline), event(8, HistoryOriginOnline)},
+			false, 2 * time.Hour, 1,
+		},
+	}
+	for _, tt := range tests {
+		offline, count := computeAvailability(tt.events, since, until, tt.offlineNow)
+		if offline != tt.wantOffline || count != tt.wantCount {
+			t.Errorf("%s: offline=%v count=%d, want offline=%v count=%d", tt.name, offline, count, tt.wantOffline, tt.wantCount)
+		}
+	}
+
+	a := DeviceAvailability{Window: 24 * time.Hour, Offline: 6 * time.Hour}
+	if got := a.Percent(); got != 75 {
+		t.Errorf("Percent = %v, want 75", got)
+	}
+}
+
+func TestECHONETLiteHandler_GetAvailability(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	handler, err := NewECHONETLiteHandler(ctx, ECHONETLieHandlerOptions{TestMode: true, InMemory: true})
+	if err != nil {
+		t.Fatalf("ECHONETLiteHandlerの作成に失敗: %v", err)
+	}
+	defer handler.Close()
+
+	data := handler.GetDataManagementHandler()
+	stable := testDevice(1)
+	flaky := testDevice(2)
+	now := time.Now()
+	for _, device := range []IPAndEOJ{stable, flaky} {
+		data.devices.RegisterProperties(device, Properties{{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}}}, now)
+	}
+	// プロパティの履歴は稼働率に影響しない
+	data.DeviceHistory.Record(DeviceHistoryEntry{Timestamp: now.Add(-3 * time.Hour), Device: stable, EPC: echonet_lite.EPCOperationStatus, Origin: HistoryOriginNotification})
+	data.DeviceHistory.Record(DeviceHistoryEntry{Timestamp: now.Add(-3 * time.Hour), Device: flaky, Origin: HistoryOriginOffline})
+	data.DeviceHistory.Record(DeviceHistoryEntry{Timestamp: now.Add(-time.Hour), Device: flaky, Origin: HistoryOriginOnline})
+
+	availability := handler.GetAvailability(4 * time.Hour)
+	if len(availability) != 2 || availability[0].Device.Key() != flaky.Key() {
+		t.Fatalf("不安定なデバイスが先に来ていない: %+v", availability)
+	}
+	if got := availability[0].Percent(); got < 49.9 || got > 50.1 || availability[0].OfflineCount != 1 {
+		t.Errorf("flaky の稼働率が不正: %v%%, count=%d", got, availability[0].OfflineCount)
+	}
+	if got := availability[1].Percent(); got != 100 {
+		t.Errorf("stable の稼働率 = %v, want 100", got)
+	}
+
+	// 期間を短くすると、期間より前のオフラインは数えない
+	if a := handler.GetDeviceAvailability(flaky, 30*time.Minute); a.Offline != 0 || a.OfflineCount != 0 {
+		t.Errorf("短い期間の結果が不正: %+v", a)
+	}
+}
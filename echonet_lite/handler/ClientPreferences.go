@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// MaxPreferencesKeyLength はクライアントキーの最大文字数
+	MaxPreferencesKeyLength = 64
+	// MaxPreferencesKeys は保存できるクライアントキーの最大数
+	MaxPreferencesKeys = 256
+	// MaxPreferencesLabelLength はレイアウト名、単位の項目名と値の最大文字数
+	MaxPreferencesLabelLength = 64
+	// MaxFavoriteDevices はお気に入りに登録できるデバイスの最大数
+	MaxFavoriteDevices = 256
+	// MaxPreferenceUnits は単位の設定の最大数
+	MaxPreferenceUnits = 16
+)
+
+// ClientPreferences は、クライアントの表示設定を表す
+// ブラウザのローカルストレージの代わりにサーバーに保存し、同じクライアントキーを使うブラウザで共有する
+type ClientPreferences struct {
+	Layout    string            `json:"layout,omitempty"`    // 画面のレイアウト名
+	Favorites []IDString        `json:"favorites,omitempty"` // お気に入りのデバイス（表示順）
+	Units     map[string]string `json:"units,omitempty"`     // 項目ごとの表示単位（例: "temperature": "fahrenheit"）
+}
+
+// IsEmpty は、何も設定されていないかどうかを返す
+func (p ClientPreferences) IsEmpty() bool {
+	return p.Layout == "" && len(p.Favorites) == 0 && len(p.Units) == 0
+}
+
+// Validate は、各項目の長さと数を検証する
+func (p ClientPreferences) Validate() error {
+	if utf8.RuneCountInString(p.Layout) > MaxPreferencesLabelLength {
+		return fmt.Errorf("layout は%d文字以内にしてください", MaxPreferencesLabelLength)
+	}
+	if len(p.Favorites) > MaxFavoriteDevices {
+		return fmt.Errorf("お気に入りは%d台までです", MaxFavoriteDevices)
+	}
+	for _, id := range p.Favorites {
+		if id == "" {
+			return fmt.Errorf("お気に入りのデバイスが空です")
+		}
+	}
+	if len(p.Units) > MaxPreferenceUnits {
+		return fmt.Errorf("単位の設定は%d個までです", MaxPreferenceUnits)
+	}
+	for name, unit := range p.Units {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("単位の項目名が空です")
+		}
+		if utf8.RuneCountInString(name) > MaxPreferencesLabelLength || utf8.RuneCountInString(unit) > MaxPreferencesLabelLength {
+			return fmt.Errorf("単位の項目名と値は%d文字以内にしてください: %q", MaxPreferencesLabelLength, name)
+		}
+	}
+	return nil
+}
+
+// clone は、スライスとマップを共有しないコピーを返す
+func (p ClientPreferences) clone() ClientPreferences {
+	p.Favorites = slices.Clone(p.Favorites)
+	if p.Units != nil {
+		units := make(map[string]string, len(p.Units))
+		for k, v := range p.Units {
+			units[k] = v
+		}
+		p.Units = units
+	}
+	return p
+}
+
+// ValidatePreferencesKey は、クライアントキーを検証する
+func ValidatePreferencesKey(key string) error {
+	if strings.TrimSpace(key) == "" {
+		return fmt.Errorf("クライアントキーが指定されていません")
+	}
+	if utf8.RuneCountInString(key) > MaxPreferencesKeyLength {
+		return fmt.Errorf("クライアントキーは%d文字以内にしてください", MaxPreferencesKeyLength)
+	}
+	return nil
+}
+
+// ClientPreferencesStore は、クライアントキーごとの表示設定を管理する構造体
+type ClientPreferencesStore struct {
+	preferences map[string]ClientPreferences
+	mutex       sync.RWMutex
+}
+
+// NewClientPreferencesStore は ClientPreferencesStore の新しいインスタンスを作成する
+func NewClientPreferencesStore() *ClientPreferencesStore {
+	return &ClientPreferencesStore{
+		preferences: make(map[string]ClientPreferences),
+	}
+}
+
+// LoadFromFile はファイルから表示設定を読み込む
+func (s *ClientPreferencesStore) LoadFromFile(filename string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// ファイルが存在しない場合は空のまま終了
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		s.preferences = make(map[string]ClientPreferences)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("表示設定ファイルを開けません: %v", err)
+	}
+
+	preferences := make(map[string]ClientPreferences)
+	if err := json.Unmarshal(data, &preferences); err != nil {
+		return fmt.Errorf("表示設定ファイルの解析に失敗しました: %v", err)
+	}
+	s.preferences = preferences
+	return nil
+}
+
+// SaveToFile は表示設定をファイルに保存する
+func (s *ClientPreferencesStore) SaveToFile(filename string) error {
+	s.mutex.RLock()
+	data, err := json.MarshalIndent(s.preferences, "", "  ")
+	s.mutex.RUnlock()
+	if err != nil {
+		return fmt.Errorf("表示設定のエンコードに失敗しました: %v", err)
+	}
+
+	// ディレクトリが存在しない場合は作成
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("ディレクトリの作成に失敗しました: %v", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("表示設定ファイルの書き込みに失敗しました: %v", err)
+	}
+	return nil
+}
+
+// Set はクライアントキーの表示設定を置き換える。空の表示設定を設定した場合は削除する
+func (s *ClientPreferencesStore) Set(key string, preferences ClientPreferences) error {
+	if err := ValidatePreferencesKey(key); err != nil {
+		return err
+	}
+	if err := preferences.Validate(); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if preferences.IsEmpty() {
+		delete(s.preferences, key)
+		return nil
+	}
+	if _, exists := s.preferences[key]; !exists && len(s.preferences) >= MaxPreferencesKeys {
+		return fmt.Errorf("表示設定を保存できるクライアントキーは%d個までです", MaxPreferencesKeys)
+	}
+	s.preferences[key] = preferences.clone()
+	return nil
+}
+
+// Get はクライアントキーの表示設定を返す
+func (s *ClientPreferencesStore) Get(key string) (ClientPreferences, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	preferences, ok := s.preferences[key]
+	return preferences.clone(), ok
+}
+
+// Count は表示設定を持つクライアントキーの数を返す
+func (s *ClientPreferencesStore) Count() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.preferences)
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClientPreferencesStore_SetAndPersist(t *testing.T) {
+	store := NewClientPreferencesStore()
+	preferences := ClientPreferences{
+		Layout:    "compact",
+		Favorites: []IDString{"013001:000005:01", "029101:000005:02"},
+		Units:     map[string]string{"temperature": "fahrenheit"},
+	}
+	if err := store.Set("living-tablet", preferences); err != nil {
+		t.Fatalf("Set に失敗: %v", err)
+	}
+
+	// 返された値を変更してもストアには影響しない
+	got, ok := store.Get("living-tablet")
+	if !ok || got.Layout != "compact" || len(got.Favorites) != 2 || got.Units["temperature"] != "fahrenheit" {
+		t.Fatalf("表示設定が不正: %+v", got)
+	}
+	got.Favorites[0] = "changed"
+	preferences.Units["temperature"] = "celsius"
+	if again, _ := store.Get("living-tablet"); again.Favorites[0] != "013001:000005:01" || again.Units["temperature"] != "fahrenheit" {
+		t.Errorf("ストアの表示設定が変更された: %+v", again)
+	}
+
+	filename := filepath.Join(t.TempDir(), "client_preferences.json")
+	if err := store.SaveToFile(filename); err != nil {
+		t.Fatalf("SaveToFile に失敗: %v", err)
+	}
+	loaded := NewClientPreferencesStore()
+	if err := loaded.LoadFromFile(filename); err != nil {
+		t.Fatalf("LoadFromFile に失敗: %v", err)
+	}
+	if got, ok := loaded.Get("living-tablet"); !ok || got.Layout != "compact" || got.Favorites[1] != "029101:000005:02" {
+		t.Fatalf("読み込んだ表示設定が不正: %+v", got)
+	}
+	if _, ok := loaded.Get("unknown"); ok {
+		t.Error("保存していないキーの表示設定が返された")
+	}
+
+	// 空の表示設定を設定すると削除される
+	if err := loaded.Set("living-tablet", ClientPreferences{}); err != nil {
+		t.Fatalf("Set に失敗: %v", err)
+	}
+	if loaded.Count() != 0 {
+		t.Errorf("空の表示設定が削除されていない: %d", loaded.Count())
+	}
+}
+
+func TestClientPreferencesStore_Validate(t *testing.T) {
+	store := NewClientPreferencesStore()
+	for name, tc := range map[string]struct {
+		key         string
+		preferences ClientPreferences
+	}{
+		"空のキー":      {key: " ", preferences: ClientPreferences{Layout: "grid"}},
+		"長すぎるキー":    {key: strings.Repeat("k", MaxPreferencesKeyLength+1), preferences: ClientPreferences{Layout: "grid"}},
+		"長すぎるレイアウト": {key: "k", preferences: ClientPreferences{Layout: strings.Repeat("l", MaxPreferencesLabelLength+1)}},
+		"空のお気に入り":   {key: "k", preferences: ClientPreferences{Favorites: []IDString{""}}},
+		"空の単位の項目名":  {key: "k", preferences: ClientPreferences{Units: map[string]string{"": "celsius"}}},
+	} {
+		if err := store.Set(tc.key, tc.preferences); err == nil {
+			t.Errorf("%s: エラーにならない", name)
+		}
+	}
+
+	// キーの数の上限を超えると新しいキーは保存できないが、既存のキーは更新できる
+	for i := range MaxPreferencesKeys {
+		if err := store.Set(fmt.Sprintf("client-%d", i), ClientPreferences{Layout: "grid"}); err != nil {
+			t.Fatalf("Set に失敗: %v", err)
+		}
+	}
+	if err := store.Set("one-more", ClientPreferences{Layout: "grid"}); err == nil {
+		t.Error("上限を超えたキーがエラーにならない")
+	}
+	if err := store.Set("client-0", ClientPreferences{Layout: "list"}); err != nil {
+		t.Errorf("既存のキーの更新に失敗: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSpec は、cron 形式（分 時 日 月 曜日）のスケジュール指定を表す
+// 各フィールドは "*", 数値, 範囲 "a-b", リスト "a,b", ステップ "*/n" "a-b/n" に対応する
+// 曜日は 0(日)〜6(土)、7 も日曜日として扱う
+type CronSpec struct {
+	minute uint64 // bit i が立っていれば i 分に一致
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	// 日と曜日の両方が "*" 以外の場合は、cron と同様にどちらかに一致すればよい
+	domRestricted bool
+	dowRestricted bool
+}
+
+// cronDescriptors は、よく使う指定の別名
+var cronDescriptors = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// ParseCronSpec は、cron 形式の文字列をパースする
+func ParseCronSpec(spec string) (CronSpec, error) {
+	if descriptor, ok := cronDescriptors[strings.TrimSpace(spec)]; ok {
+		spec = descriptor
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return CronSpec{}, fmt.Errorf("cron 形式は「分 時 日 月 曜日」の5フィールドが必要です: %q", spec)
+	}
+
+	var c CronSpec
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return CronSpec{}, fmt.Errorf("分の指定が不正です: %w", err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return CronSpec{}, fmt.Errorf("時の指定が不正です: %w", err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return CronSpec{}, fmt.Errorf("日の指定が不正です: %w", err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return CronSpec{}, fmt.Errorf("月の指定が不正です: %w", err)
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return CronSpec{}, fmt.Errorf("曜日の指定が不正です: %w", err)
+	}
+	// 7 は日曜日
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domRestricted = fields[2] != "*"
+	c.dowRestricted = fields[4] != "*"
+	return c, nil
+}
+
+// parseCronField は、1つのフィールドをビットセットに変換する
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangePart = part[:i]
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("ステップが不正です: %q", part)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("範囲が不正です: %q", part)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("数値が不正です: %q", part)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q は %d〜%d の範囲外です", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Matches は、指定時刻（分単位）がスケジュールに一致するかを返す
+func (c CronSpec) Matches(t time.Time) bool {
+	return c.minute&(1<<uint(t.Minute())) != 0 &&
+		c.hour&(1<<uint(t.Hour())) != 0 &&
+		c.month&(1<<uint(t.Month())) != 0 &&
+		c.dayMatches(t)
+}
+
+// Next は、after より後で最初に一致する時刻を返す
+// 5年以内に一致する時刻がない場合（2月30日など）はゼロ値を返す
+func (c CronSpec) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.Matches(t) {
+			if c.hour&(1<<uint(t.Hour())) == 0 || !c.dayMatches(t) {
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Add(time.Hour)
+				continue
+			}
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches は、日付（日・曜日）が一致するかを返す
+func (c CronSpec) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domRestricted && c.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronSpec_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"0 7 * *",
+		"60 * * * *",
+		"0 24 * * *",
+		"0 0 0 * *",
+		"0 0 * 13 *",
+		"0 0 * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@often",
+	} {
+		if _, err := ParseCronSpec(spec); err == nil {
+			t.Errorf("%q が有効と判定された", spec)
+		}
+	}
+}
+
+func TestCronSpec_Matches(t *testing.T) {
+	loc := time.UTC
+	tests := []struct {
+		spec string
+		t    time.Time
+		want bool
+	}{
+		// 2026-10-19 は月曜日、2026-10-18 は日曜日
+		{"0 7 * * 1-5", time.Date(2026, 10, 19, 7, 0, 0, 0, loc), true},
+		{"0 7 * * 1-5", time.Date(2026, 10, 18, 7, 0, 0, 0, loc), false},
+		{"0 7 * * 1-5", time.Date(2026, 10, 19, 7, 1, 0, 0, loc), false},
+		{"*/15 * * * *", time.Date(2026, 10, 19, 3, 45, 0, 0, loc), true},
+		{"*/15 * * * *", time.Date(2026, 10, 19, 3, 50, 0, 0, loc), false},
+		{"0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, loc), true},
+		{"30 6 1,15 * *", time.Date(2026, 10, 15, 6, 30, 0, 0, loc), true},
+		// 日と曜日の両方を指定した場合はどちらかに一致すればよい
+		{"0 0 1 * 1", time.Date(2026, 10, 19, 0, 0, 0, 0, loc), true},
+		{"0 0 1 * 1", time.Date(2026, 10, 1, 0, 0, 0, 0, loc), true},
+		{"0 0 1 * 1", time.Date(2026, 10, 2, 0, 0, 0, 0, loc), false},
+		{"@daily", time.Date(2026, 10, 2, 0, 0, 0, 0, loc), true},
+	}
+	for _, tt := range tests {
+		spec, err := ParseCronSpec(tt.spec)
+		if err != nil {
+			t.Fatalf("%q のパースに失敗: %v", tt.spec, err)
+		}
+		if got := spec.Matches(tt.t); got != tt.want {
+			t.Errorf("%q.Matches(%v) = %v, want %v", tt.spec, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestCronSpec_Next(t *testing.T) {
+	loc := time.UTC
+	tests := []struct {
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		// 金曜日の 07:00 ちょうどの後は、次の月曜日
+		{"0 7 * * 1-5", time.Date(2026, 10, 16, 7, 0, 0, 0, loc), time.Date(2026, 10, 19, 7, 0, 0, 0, loc)},
+		{"0 7 * * 1-5", time.Date(2026, 10, 16, 6, 59, 30, 0, loc), time.Date(2026, 10, 16, 7, 0, 0, 0, loc)},
+		{"*/20 9-10 * * *", time.Date(2026, 10, 16, 10, 45, 0, 0, loc), time.Date(2026, 10, 17, 9, 0, 0, 0, loc)},
+		{"0 0 29 2 *", time.Date(2026, 10, 16, 0, 0, 0, 0, loc), time.Date(2028, 2, 29, 0, 0, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		spec, err := ParseCronSpec(tt.spec)
+		if err != nil {
+			t.Fatalf("%q のパースに失敗: %v", tt.spec, err)
+		}
+		if got := spec.Next(tt.after); !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%v) = %v, want %v", tt.spec, tt.after, got, tt.want)
+		}
+	}
+
+	// 存在しない日付はゼロ値
+	spec, _ := ParseCronSpec("0 0 30 2 *")
+	if got := spec.Next(time.Date(2026, 1, 1, 0, 0, 0, 0, loc)); !got.IsZero() {
+		t.Errorf("2月30日の次回実行時刻がゼロ値ではない: %v", got)
+	}
+}
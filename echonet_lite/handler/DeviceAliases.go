@@ -3,6 +3,7 @@ package handler
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"regexp"
 	"sort"
@@ -206,7 +207,7 @@ func (da *DeviceAliases) SaveToFile(filename string) error {
 	}
 	defer func() {
 		if err := file.Close(); err != nil {
-			fmt.Printf("ファイルを閉じる際にエラーが発生しました: %v\n", err)
+			slog.Warn("ファイルを閉じる際にエラーが発生しました", "file", filename, "err", err)
 		}
 	}()
 
@@ -220,19 +221,27 @@ func (da *DeviceAliases) SaveToFile(filename string) error {
 
 // LoadFromFile はJSONファイルからエイリアスと IDString の対応表を読み込みます
 func (da *DeviceAliases) LoadFromFile(filename string) error {
+	_, err := da.loadFromFile(filename)
+	return err
+}
+
+// loadFromFile はJSONファイルからエイリアスを読み込み、読み込んだ結果を返します
+func (da *DeviceAliases) loadFromFile(filename string) (fileLoadStats, error) {
+	var stats fileLoadStats
 	file, err := os.Open(filename)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// ファイルが存在しない場合は何もしない
-			return nil
+			return stats, nil
 		}
-		return fmt.Errorf("failed to open file: %w", err)
+		return stats, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer func() {
 		if err := file.Close(); err != nil {
-			fmt.Printf("ファイルを閉じる際にエラーが発生しました: %v\n", err)
+			slog.Warn("ファイルを閉じる際にエラーが発生しました", "file", filename, "err", err)
 		}
 	}()
+	stats.Exists = true
 
 	da.mu.Lock()
 	defer da.mu.Unlock()
@@ -242,10 +251,11 @@ func (da *DeviceAliases) LoadFromFile(filename string) error {
 
 	decoder := json.NewDecoder(file)
 	if err := decoder.Decode(&da.aliases); err != nil {
-		return fmt.Errorf("failed to decode data: %w", err)
+		return stats, fmt.Errorf("failed to decode data: %w", err)
 	}
+	stats.Records = len(da.aliases)
 
-	return nil
+	return stats, nil
 }
 
 // Count はエイリアスの総数を返す
@@ -254,3 +264,61 @@ func (da *DeviceAliases) Count() int {
 	defer da.mu.RUnlock()
 	return len(da.aliases)
 }
+
+// decodeAliases は、エイリアスファイルの内容を読み込み、エイリアスを検証する
+func decodeAliases(data []byte) (map[string]IDString, error) {
+	aliases := make(map[string]IDString)
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return nil, fmt.Errorf("failed to decode data: %w", err)
+	}
+	for alias := range aliases {
+		if err := ValidateDeviceAlias(alias); err != nil {
+			return nil, err
+		}
+	}
+	return aliases, nil
+}
+
+// replace は、エイリアスをすべて置き換え、置き換え前との差分をエイリアス名の順に返す
+func (da *DeviceAliases) replace(aliases map[string]IDString) []AliasChange {
+	da.mu.Lock()
+	defer da.mu.Unlock()
+
+	var changes []AliasChange
+	for alias, id := range aliases {
+		if oldID, ok := da.aliases[alias]; !ok {
+			changes = append(changes, AliasChange{Type: FileChangeAdded, Alias: alias, ID: id})
+		} else if oldID != id {
+			changes = append(changes, AliasChange{Type: FileChangeUpdated, Alias: alias, ID: id})
+		}
+	}
+	for alias, id := range da.aliases {
+		if _, ok := aliases[alias]; !ok {
+			changes = append(changes, AliasChange{Type: FileChangeDeleted, Alias: alias, ID: id})
+		}
+	}
+	da.aliases = aliases
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Alias < changes[j].Alias
+	})
+	return changes
+}
+
+// reassign は、from に関連付けられたエイリアスを to に付け替え、変更をエイリアス名の順に返す
+func (da *DeviceAliases) reassign(from, to IDString) []AliasChange {
+	da.mu.Lock()
+	defer da.mu.Unlock()
+
+	var changes []AliasChange
+	for alias, id := range da.aliases {
+		if id == from {
+			da.aliases[alias] = to
+			changes = append(changes, AliasChange{Type: FileChangeUpdated, Alias: alias, ID: to})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Alias < changes[j].Alias
+	})
+	return changes
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -25,23 +26,31 @@ func NewDeviceGroups() *DeviceGroups {
 
 // LoadFromFile はファイルからグループ情報を読み込む
 func (g *DeviceGroups) LoadFromFile(filename string) error {
+	_, err := g.loadFromFile(filename)
+	return err
+}
+
+// loadFromFile はファイルからグループ情報を読み込み、読み込んだ結果を返す
+func (g *DeviceGroups) loadFromFile(filename string) (fileLoadStats, error) {
+	var stats fileLoadStats
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
 
 	// ファイルが存在しない場合は空のグループリストを作成して終了
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
 		g.groups = make(map[string][]IDString)
-		return nil
+		return stats, nil
 	}
 
 	// ファイルを開く
 	file, err := os.Open(filename)
 	if err != nil {
-		return fmt.Errorf("グループファイルを開けません: %v", err)
+		return stats, fmt.Errorf("グループファイルを開けません: %v", err)
 	}
 	defer func(file *os.File) {
 		_ = file.Close()
 	}(file)
+	stats.Exists = true
 
 	// JSONデコード用の一時構造体
 	type GroupEntry struct {
@@ -53,7 +62,7 @@ func (g *DeviceGroups) LoadFromFile(filename string) error {
 	// JSONデコード
 	decoder := json.NewDecoder(file)
 	if err := decoder.Decode(&entries); err != nil {
-		return fmt.Errorf("グループファイルの解析に失敗しました: %v", err)
+		return stats, fmt.Errorf("グループファイルの解析に失敗しました: %v", err)
 	}
 
 	// グループマップを初期化
@@ -63,8 +72,9 @@ func (g *DeviceGroups) LoadFromFile(filename string) error {
 	for _, entry := range entries {
 		g.groups[entry.Group] = entry.Devices
 	}
+	stats.Records = len(g.groups)
 
-	return nil
+	return stats, nil
 }
 
 // SaveToFile はグループ情報をファイルに保存する
@@ -318,3 +328,76 @@ func (g *DeviceGroups) Count() int {
 	defer g.mutex.RUnlock()
 	return len(g.groups)
 }
+
+// decodeGroups は、グループファイルの内容を読み込み、グループ名を検証する
+func decodeGroups(data []byte) (map[string][]IDString, error) {
+	type GroupEntry struct {
+		Group   string     `json:"group"`
+		Devices []IDString `json:"devices"`
+	}
+	var entries []GroupEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("グループファイルの解析に失敗しました: %v", err)
+	}
+	groups := make(map[string][]IDString, len(entries))
+	for _, entry := range entries {
+		if err := ValidateGroupName(entry.Group); err != nil {
+			return nil, err
+		}
+		groups[entry.Group] = entry.Devices
+	}
+	return groups, nil
+}
+
+// replace は、グループをすべて置き換え、置き換え前との差分をグループ名の順に返す
+func (g *DeviceGroups) replace(groups map[string][]IDString) []GroupChange {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	var changes []GroupChange
+	for name, devices := range groups {
+		if old, ok := g.groups[name]; !ok {
+			changes = append(changes, GroupChange{Type: FileChangeAdded, Group: name, Devices: devices})
+		} else if !slices.Equal(old, devices) {
+			changes = append(changes, GroupChange{Type: FileChangeUpdated, Group: name, Devices: devices})
+		}
+	}
+	for name := range g.groups {
+		if _, ok := groups[name]; !ok {
+			changes = append(changes, GroupChange{Type: FileChangeDeleted, Group: name})
+		}
+	}
+	g.groups = groups
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Group < changes[j].Group
+	})
+	return changes
+}
+
+// reassign は、グループの from を同じ位置で to に置き換え、変わったグループをグループ名の順に返す
+// to がすでにグループに含まれている場合は from を削除するだけにする
+func (g *DeviceGroups) reassign(from, to IDString) []GroupChange {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	var changes []GroupChange
+	for name, devices := range g.groups {
+		index := slices.Index(devices, from)
+		if index < 0 {
+			continue
+		}
+		if slices.Contains(devices, to) {
+			devices = slices.Delete(slices.Clone(devices), index, index+1)
+		} else {
+			devices = slices.Clone(devices)
+			devices[index] = to
+		}
+		g.groups[name] = devices
+		changes = append(changes, GroupChange{Type: FileChangeUpdated, Group: name, Devices: slices.Clone(devices)})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Group < changes[j].Group
+	})
+	return changes
+}
@@ -77,6 +77,27 @@ type HistoryQuery struct {
 	// where settable history (operation status, settings) would otherwise be excluded from results
 	// when the merged data is limited to the latest N entries.
 	SettableOnly bool
+	// Since and Until restrict results to entries within [Since, Until]. Zero values mean unbounded.
+	Since time.Time
+	Until time.Time
+	// EPC, if non-zero, restricts results to entries of that property (online/offline events are excluded).
+	EPC echonet_lite.EPCType
+}
+
+// matchesEPC reports whether the entry's property is within the query's property filter.
+func (q HistoryQuery) matchesEPC(epc echonet_lite.EPCType) bool {
+	return q.EPC == 0 || epc == q.EPC
+}
+
+// matchesTimeRange reports whether the timestamp is within the query's time range.
+func (q HistoryQuery) matchesTimeRange(t time.Time) bool {
+	if !q.Since.IsZero() && t.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && t.After(q.Until) {
+		return false
+	}
+	return true
 }
 
 // DeviceHistoryStore defines behaviour required from a history backend.
@@ -97,6 +118,13 @@ type HistoryOptions struct {
 	PerDeviceSettableLimit    int    // Maximum number of settable property history per device
 	PerDeviceNonSettableLimit int    // Maximum number of non-settable property history per device
 	HistoryFilePath           string // Path to history file for persistence (empty = disabled)
+	Disabled                  bool   // Disable the history store entirely (no recording, persistence or queries)
+
+	Backend         HistoryBackend // Storage backend (empty = HistoryBackendMemory)
+	JournalFilePath string         // Path to the journal file used by HistoryBackendJournal
+	Retention       time.Duration  // How long the journal keeps entries (0 = forever)
+
+	Cipher *FileCipher // Encrypts the history and journal files (nil = plain JSON)
 }
 
 // DefaultHistoryOptions returns the default options used when none are provided.
@@ -123,6 +151,7 @@ func NewMemoryDeviceHistoryStore(opts HistoryOptions) DeviceHistoryStore {
 		perDeviceLimit:         options.PerDeviceNonSettableLimit,
 		settableData:           make(map[string][]DeviceHistoryEntry),
 		nonSettableData:        make(map[string][]DeviceHistoryEntry),
+		cipher:                 opts.Cipher,
 	}
 }
 
@@ -132,6 +161,7 @@ type memoryDeviceHistoryStore struct {
 	perDeviceLimit         int
 	settableData           map[string][]DeviceHistoryEntry
 	nonSettableData        map[string][]DeviceHistoryEntry
+	cipher                 *FileCipher // encrypts the history file (nil = plain JSON)
 }
 
 func (s *memoryDeviceHistoryStore) Record(entry DeviceHistoryEntry) {
@@ -188,6 +218,14 @@ func (s *memoryDeviceHistoryStore) Query(device IPAndEOJ, query HistoryQuery) []
 	for i := len(allEntries) - 1; i >= 0; i-- {
 		entry := allEntries[i]
 
+		if !query.Since.IsZero() && entry.Timestamp.Before(query.Since) {
+			// Entries are ordered, so everything older is out of range too
+			break
+		}
+		if !query.matchesTimeRange(entry.Timestamp) || !query.matchesEPC(entry.EPC) {
+			continue
+		}
+
 		result = append(result, entry)
 		if len(result) >= limit {
 			break
@@ -212,6 +250,87 @@ func (s *memoryDeviceHistoryStore) PerDeviceTotalLimit() int {
 	return s.perDeviceSettableLimit + s.perDeviceLimit
 }
 
+// historyEntryOverhead is the approximate fixed size of a DeviceHistoryEntry in bytes,
+// excluding variable-length data such as the EDT and string value.
+const historyEntryOverhead = 160
+
+// approximateSize returns the approximate memory used by the entry in bytes.
+func (entry DeviceHistoryEntry) approximateSize() int64 {
+	return int64(historyEntryOverhead + len(entry.Device.IP) + len(entry.Value.EDT) + len(entry.Value.String))
+}
+
+// HistoryMemoryUsage reports the approximate memory used by a history store.
+type HistoryMemoryUsage struct {
+	Entries     int
+	ApproxBytes int64
+}
+
+// HistoryMemoryManager is implemented by history stores that keep entries in memory.
+// It is used to report memory usage and to enforce soft memory caps.
+type HistoryMemoryManager interface {
+	MemoryUsage() HistoryMemoryUsage
+	// EvictToSize drops the oldest entries until the store uses at most maxBytes.
+	// Non-settable entries (notifications) are evicted before settable ones (operations).
+	// It returns the number of evicted entries.
+	EvictToSize(maxBytes int64) int
+}
+
+func (s *memoryDeviceHistoryStore) MemoryUsage() HistoryMemoryUsage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.memoryUsageLocked()
+}
+
+func (s *memoryDeviceHistoryStore) memoryUsageLocked() HistoryMemoryUsage {
+	var usage HistoryMemoryUsage
+	for _, data := range []map[string][]DeviceHistoryEntry{s.settableData, s.nonSettableData} {
+		for _, entries := range data {
+			usage.Entries += len(entries)
+			for _, entry := range entries {
+				usage.ApproxBytes += entry.approximateSize()
+			}
+		}
+	}
+	return usage
+}
+
+func (s *memoryDeviceHistoryStore) EvictToSize(maxBytes int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	size := s.memoryUsageLocked().ApproxBytes
+	evicted := 0
+	for _, data := range []map[string][]DeviceHistoryEntry{s.nonSettableData, s.settableData} {
+		for size > maxBytes {
+			// Find the device whose oldest entry is the oldest overall
+			oldestKey := ""
+			var oldest time.Time
+			for key, entries := range data {
+				if len(entries) == 0 {
+					continue
+				}
+				if oldestKey == "" || entries[0].Timestamp.Before(oldest) {
+					oldestKey = key
+					oldest = entries[0].Timestamp
+				}
+			}
+			if oldestKey == "" {
+				break
+			}
+
+			entries := data[oldestKey]
+			size -= entries[0].approximateSize()
+			evicted++
+			if len(entries) == 1 {
+				delete(data, oldestKey)
+			} else {
+				data[oldestKey] = entries[1:]
+			}
+		}
+	}
+	return evicted
+}
+
 // IsDuplicateNotification checks if there's a recent Set operation for the same device, EPC, and value.
 // This is used to avoid recording duplicate history entries when a notification follows a set operation.
 func (s *memoryDeviceHistoryStore) IsDuplicateNotification(device IPAndEOJ, epc echonet_lite.EPCType, value PropertyValue, within time.Duration) bool {
@@ -354,6 +473,51 @@ type jsonIPAndEOJ struct {
 
 const currentHistoryFileVersion = 1
 
+// toJSONHistoryEntry converts a history entry into its persisted form.
+func toJSONHistoryEntry(entry DeviceHistoryEntry) jsonDeviceHistoryEntry {
+	return jsonDeviceHistoryEntry{
+		Timestamp: entry.Timestamp,
+		Device: jsonIPAndEOJ{
+			IP:  entry.Device.IP.String(),
+			EOJ: entry.Device.EOJ.Specifier(),
+		},
+		EPC:      fmt.Sprintf("0x%02X", byte(entry.EPC)),
+		Value:    entry.Value,
+		Origin:   entry.Origin,
+		Settable: entry.Settable,
+	}
+}
+
+// fromJSONHistoryEntry parses a persisted history entry.
+func fromJSONHistoryEntry(jsonEntry jsonDeviceHistoryEntry) (DeviceHistoryEntry, error) {
+	ip := net.ParseIP(jsonEntry.Device.IP)
+	if ip == nil {
+		return DeviceHistoryEntry{}, fmt.Errorf("invalid IP address: %s", jsonEntry.Device.IP)
+	}
+
+	eoj, err := ParseEOJString(jsonEntry.Device.EOJ)
+	if err != nil {
+		return DeviceHistoryEntry{}, fmt.Errorf("invalid EOJ: %w", err)
+	}
+
+	var epc echonet_lite.EPCType
+	if _, err := fmt.Sscanf(jsonEntry.EPC, "0x%02X", (*byte)(&epc)); err != nil {
+		return DeviceHistoryEntry{}, fmt.Errorf("invalid EPC %s: %w", jsonEntry.EPC, err)
+	}
+
+	return DeviceHistoryEntry{
+		Timestamp: jsonEntry.Timestamp,
+		Device: IPAndEOJ{
+			IP:  ip,
+			EOJ: eoj,
+		},
+		EPC:      epc,
+		Value:    jsonEntry.Value,
+		Origin:   jsonEntry.Origin,
+		Settable: jsonEntry.Settable,
+	}, nil
+}
+
 // SaveToFile saves the history data to a JSON file
 func (s *memoryDeviceHistoryStore) SaveToFile(filename string) error {
 	s.mu.RLock()
@@ -379,17 +543,7 @@ func (s *memoryDeviceHistoryStore) SaveToFile(filename string) error {
 
 		jsonEntries := make([]jsonDeviceHistoryEntry, 0, len(allEntries))
 		for _, entry := range allEntries {
-			jsonEntries = append(jsonEntries, jsonDeviceHistoryEntry{
-				Timestamp: entry.Timestamp,
-				Device: jsonIPAndEOJ{
-					IP:  entry.Device.IP.String(),
-					EOJ: entry.Device.EOJ.Specifier(),
-				},
-				EPC:      fmt.Sprintf("0x%02X", byte(entry.EPC)),
-				Value:    entry.Value,
-				Origin:   entry.Origin,
-				Settable: entry.Settable,
-			})
+			jsonEntries = append(jsonEntries, toJSONHistoryEntry(entry))
 		}
 		jsonData[deviceKey] = jsonEntries
 	}
@@ -404,6 +558,10 @@ func (s *memoryDeviceHistoryStore) SaveToFile(filename string) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal history data: %w", err)
 	}
+	data, err = s.cipher.Encrypt(data)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt history data: %w", err)
+	}
 
 	// Write to temporary file
 	tempFilename := filename + ".tmp"
@@ -424,26 +582,41 @@ func (s *memoryDeviceHistoryStore) SaveToFile(filename string) error {
 
 // LoadFromFile loads the history data from a JSON file with filtering
 func (s *memoryDeviceHistoryStore) LoadFromFile(filename string, filter HistoryLoadFilter) error {
+	_, err := s.loadFromFile(filename, filter)
+	return err
+}
+
+// loadFromFile loads the history data and returns what was read for the startup report
+func (s *memoryDeviceHistoryStore) loadFromFile(filename string, filter HistoryLoadFilter) (fileLoadStats, error) {
+	var stats fileLoadStats
+
 	// Check if file exists
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
 		slog.Info("History file does not exist, starting with empty history", "filename", filename)
-		return nil
+		return stats, nil
 	}
+	stats.Exists = true
 
 	// Read file
 	data, err := os.ReadFile(filename)
 	if err != nil {
-		return fmt.Errorf("failed to read history file %s: %w", filename, err)
+		return stats, fmt.Errorf("failed to read history file %s: %w", filename, err)
+	}
+	data, err = s.cipher.Decrypt(data)
+	if err != nil {
+		return stats, fmt.Errorf("failed to decrypt history file %s: %w", filename, err)
 	}
 
 	// Parse JSON
 	var fileData historyFileFormat
 	if err := json.Unmarshal(data, &fileData); err != nil {
-		return fmt.Errorf("failed to unmarshal history file %s: %w", filename, err)
+		return stats, fmt.Errorf("failed to unmarshal history file %s: %w", filename, err)
 	}
 
 	// Check version
+	stats.Version = fileData.Version
 	if fileData.Version != currentHistoryFileVersion {
+		stats.Migrated = true
 		slog.Warn("History file version mismatch, attempting to load anyway",
 			"filename", filename,
 			"fileVersion", fileData.Version,
@@ -468,53 +641,15 @@ func (s *memoryDeviceHistoryStore) LoadFromFile(filename string, filter HistoryL
 
 		// Process entries from newest to oldest
 		for i := len(jsonEntries) - 1; i >= 0; i-- {
-			jsonEntry := jsonEntries[i]
-
-			// Parse IP address
-			ip := net.ParseIP(jsonEntry.Device.IP)
-			if ip == nil {
-				slog.Warn("Invalid IP address in history entry, skipping",
-					"deviceKey", deviceKey,
-					"ip", jsonEntry.Device.IP)
-				totalFiltered++
-				continue
-			}
-
-			// Parse EOJ
-			eoj, err := ParseEOJString(jsonEntry.Device.EOJ)
+			entry, err := fromJSONHistoryEntry(jsonEntries[i])
 			if err != nil {
-				slog.Warn("Invalid EOJ in history entry, skipping",
-					"deviceKey", deviceKey,
-					"eoj", jsonEntry.Device.EOJ,
-					"error", err)
-				totalFiltered++
-				continue
-			}
-
-			// Parse EPC
-			var epc echonet_lite.EPCType
-			if _, err := fmt.Sscanf(jsonEntry.EPC, "0x%02X", (*byte)(&epc)); err != nil {
-				slog.Warn("Invalid EPC in history entry, skipping",
+				slog.Warn("Invalid history entry, skipping",
 					"deviceKey", deviceKey,
-					"epc", jsonEntry.EPC,
 					"error", err)
 				totalFiltered++
 				continue
 			}
 
-			// Create entry
-			entry := DeviceHistoryEntry{
-				Timestamp: jsonEntry.Timestamp,
-				Device: IPAndEOJ{
-					IP:  ip,
-					EOJ: eoj,
-				},
-				EPC:      epc,
-				Value:    jsonEntry.Value,
-				Origin:   jsonEntry.Origin,
-				Settable: jsonEntry.Settable,
-			}
-
 			// Separate by settable flag
 			if entry.Settable {
 				settableFiltered = append(settableFiltered, entry)
@@ -555,7 +690,9 @@ func (s *memoryDeviceHistoryStore) LoadFromFile(filename string, filter HistoryL
 		"totalFiltered", totalFiltered,
 		"deviceCount", len(allDeviceKeys))
 
-	return nil
+	stats.Records = totalLoaded
+	stats.Skipped = totalFiltered
+	return stats, nil
 }
 
 // PropertyValueFromEDT creates a PropertyValue from EDT bytes
@@ -0,0 +1,492 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"echonet-list/echonet_lite"
+)
+
+// HistoryBackend selects the implementation of DeviceHistoryStore.
+type HistoryBackend string
+
+const (
+	// HistoryBackendMemory keeps history in memory and snapshots it to a JSON file on shutdown.
+	HistoryBackendMemory HistoryBackend = "memory"
+	// HistoryBackendJournal appends every entry to a JSON Lines file as it is recorded.
+	// History survives crashes, retention is bounded only by time, and queries read from disk.
+	HistoryBackendJournal HistoryBackend = "journal"
+)
+
+// journalRecentLimit is the number of recent entries per device kept in memory for duplicate detection.
+const journalRecentLimit = 20
+
+// maxJournalLineSize is the maximum size of a single journal line.
+const maxJournalLineSize = 1 << 20
+
+// While the server runs, the journal is compacted in the background to drop entries beyond retention
+// when it has grown to twice its size after the last compaction (and at least journalCompactMinSize),
+// or when journalCompactInterval has passed since the last compaction.
+const (
+	journalCompactMinSize  = 1 << 20
+	journalCompactInterval = 24 * time.Hour
+)
+
+// journalDeviceHistoryStore is a DeviceHistoryStore backed by an append-only JSON Lines file.
+// Each Record is written immediately, so no data is lost if the process crashes.
+// The location of each line is indexed by device, so a query reads only the lines of its device, newest first.
+type journalDeviceHistoryStore struct {
+	// compactMu is held for reading by Query and for writing while the journal is rewritten.
+	// Record does not take it, so recording is not blocked while a query reads the file.
+	compactMu sync.RWMutex
+
+	mu            sync.RWMutex // protects the fields below
+	filename      string
+	file          *os.File
+	out           io.Writer                    // where Record appends; the file, replaced in tests
+	index         map[string][]journalLocation // lines of each device by IPAndEOJ.Key(), oldest first
+	size          int64                        // bytes appended to the journal
+	compactedSize int64                        // size of the journal after the last compaction
+	compactedAt   time.Time                    // time of the last compaction
+	retention     time.Duration
+	recent        *memoryDeviceHistoryStore // recent entries for IsDuplicateNotification
+	cipher        *FileCipher               // encrypts each line (nil = plain JSON Lines)
+	now           func() time.Time
+	loaded        fileLoadStats // what the initial compaction read, for the startup report
+
+	compacting atomic.Bool // a background compaction is running
+}
+
+// journalLocation is the position of one line in the journal, excluding the newline
+type journalLocation struct {
+	offset int64
+	length int
+}
+
+func newJournalRecentStore() *memoryDeviceHistoryStore {
+	return NewMemoryDeviceHistoryStore(HistoryOptions{
+		PerDeviceSettableLimit:    journalRecentLimit,
+		PerDeviceNonSettableLimit: journalRecentLimit,
+	}).(*memoryDeviceHistoryStore)
+}
+
+// NewJournalDeviceHistoryStore opens (or creates) a journal file and drops entries older than the retention period.
+// A retention of zero keeps entries forever.
+// When cipher is not nil, each line is encrypted; existing plain lines are encrypted by the initial compaction.
+func NewJournalDeviceHistoryStore(filename string, retention time.Duration, cipher *FileCipher) (DeviceHistoryStore, error) {
+	s := &journalDeviceHistoryStore{
+		filename:  filename,
+		retention: retention,
+		cipher:    cipher,
+		recent:    newJournalRecentStore(),
+		now:       time.Now,
+	}
+
+	_, statErr := os.Stat(filename)
+	s.compactMu.Lock()
+	defer s.compactMu.Unlock()
+	stats, err := s.rewrite(keepAllHistory)
+	if err != nil {
+		return nil, err
+	}
+	stats.Exists = statErr == nil
+	s.loaded = stats
+	return s, nil
+}
+
+func keepAllHistory(DeviceHistoryEntry) bool { return true }
+
+// openLocked opens the journal for appending.
+func (s *journalDeviceHistoryStore) openLocked() error {
+	f, err := os.OpenFile(s.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history journal %s: %w", s.filename, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to open history journal %s: %w", s.filename, err)
+	}
+	s.file = f
+	s.out = f
+	s.size = info.Size()
+	return nil
+}
+
+// scan calls fn for each valid entry between the offsets from and to of the journal, oldest first,
+// and returns the number of lines skipped. A negative to reads until the end of the file.
+// from and to must be at line boundaries.
+func (s *journalDeviceHistoryStore) scan(from, to int64, fn func(DeviceHistoryEntry)) (int, error) {
+	f, err := os.Open(s.filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer f.Close()
+
+	if from > 0 {
+		if _, err := f.Seek(from, io.SeekStart); err != nil {
+			return 0, err
+		}
+	}
+	var r io.Reader = f
+	if to >= 0 {
+		r = io.LimitReader(f, to-from)
+	}
+
+	skipped := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJournalLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		entry, err := s.decodeLine(line)
+		if errors.Is(err, ErrEncryptionKeyRequired) {
+			// Dropping the line would lose history when the journal is compacted
+			return skipped, err
+		}
+		if err != nil {
+			// A truncated last line after a crash is expected; skip it
+			slog.Debug("Skipping unreadable history journal line", "filename", s.filename, "error", err)
+			skipped++
+			continue
+		}
+		fn(entry)
+	}
+	return skipped, scanner.Err()
+}
+
+// decodeLine decrypts and decodes one line of the journal.
+func (s *journalDeviceHistoryStore) decodeLine(line []byte) (DeviceHistoryEntry, error) {
+	line, err := s.cipher.DecryptLine(line)
+	if err != nil {
+		return DeviceHistoryEntry{}, fmt.Errorf("history journal %s: %w", s.filename, err)
+	}
+	var jsonEntry jsonDeviceHistoryEntry
+	if err := json.Unmarshal(line, &jsonEntry); err != nil {
+		return DeviceHistoryEntry{}, err
+	}
+	return fromJSONHistoryEntry(jsonEntry)
+}
+
+// rewrite rewrites the journal keeping entries for which keep returns true and that are within retention.
+// It also rebuilds the index and the in-memory recent entries, and returns the number of entries kept and lines skipped.
+// The caller must hold compactMu for writing. The lines written so far are copied without holding mu,
+// and mu is held only to copy the lines Record appended meanwhile and to replace the file.
+func (s *journalDeviceHistoryStore) rewrite(keep func(DeviceHistoryEntry) bool) (fileLoadStats, error) {
+	var cutoff time.Time
+	if s.retention > 0 {
+		cutoff = s.now().Add(-s.retention)
+	}
+
+	tempFilename := s.filename + ".tmp"
+	temp, err := os.Create(tempFilename)
+	if err != nil {
+		return fileLoadStats{}, fmt.Errorf("failed to create temporary file %s: %w", tempFilename, err)
+	}
+	writer := bufio.NewWriter(temp)
+	recent := newJournalRecentStore()
+	index := make(map[string][]journalLocation)
+	var written int64
+
+	kept, dropped := 0, 0
+	copyEntry := func(entry DeviceHistoryEntry) {
+		if (!cutoff.IsZero() && entry.Timestamp.Before(cutoff)) || !keep(entry) {
+			dropped++
+			return
+		}
+		n, err := writeJournalLine(writer, entry, s.cipher)
+		if err == nil {
+			key := entry.Device.Key()
+			index[key] = append(index[key], journalLocation{offset: written, length: n - 1})
+			kept++
+			recent.Record(entry)
+		}
+		written += int64(n)
+	}
+
+	// Copy the lines written so far while Record keeps appending.
+	// When the journal is not open yet, read the whole file, including a truncated last line.
+	s.mu.RLock()
+	wasOpen := s.file != nil
+	offset := int64(-1)
+	if wasOpen {
+		offset = s.size
+	}
+	s.mu.RUnlock()
+	skipped, scanErr := s.scan(0, offset, copyEntry)
+
+	// Close also takes compactMu, so the journal is still open here if it was open before
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if scanErr == nil && wasOpen && s.size > offset {
+		var n int
+		n, scanErr = s.scan(offset, s.size, copyEntry)
+		skipped += n
+	}
+
+	if err := writer.Flush(); err != nil && scanErr == nil {
+		scanErr = err
+	}
+	if err := temp.Close(); err != nil && scanErr == nil {
+		scanErr = err
+	}
+	if scanErr != nil {
+		_ = os.Remove(tempFilename)
+		// Keep appending to the existing journal even if it could not be compacted
+		if s.file == nil {
+			if err := s.openLocked(); err != nil {
+				return fileLoadStats{}, err
+			}
+		}
+		return fileLoadStats{}, fmt.Errorf("failed to compact history journal %s: %w", s.filename, scanErr)
+	}
+
+	if s.file != nil {
+		_ = s.file.Close()
+		s.file = nil
+	}
+	if err := os.Rename(tempFilename, s.filename); err != nil {
+		_ = os.Remove(tempFilename)
+		if openErr := s.openLocked(); openErr != nil {
+			return fileLoadStats{}, openErr
+		}
+		return fileLoadStats{}, fmt.Errorf("failed to rename temporary file %s to %s: %w", tempFilename, s.filename, err)
+	}
+
+	s.recent = recent
+	s.index = index
+	if dropped > 0 {
+		slog.Info("History journal compacted", "filename", s.filename, "kept", kept, "dropped", dropped)
+	}
+	if err := s.openLocked(); err != nil {
+		return fileLoadStats{}, err
+	}
+	s.compactedSize = s.size
+	s.compactedAt = s.now()
+	return fileLoadStats{Records: kept, Skipped: skipped}, nil
+}
+
+// needsCompactionLocked reports whether the journal should be compacted to drop entries beyond retention.
+func (s *journalDeviceHistoryStore) needsCompactionLocked() bool {
+	if s.retention <= 0 {
+		return false
+	}
+	return s.size >= max(2*s.compactedSize, journalCompactMinSize) || s.now().Sub(s.compactedAt) >= journalCompactInterval
+}
+
+// compactInBackground compacts the journal; started by Record.
+func (s *journalDeviceHistoryStore) compactInBackground() {
+	defer s.compacting.Store(false)
+	s.compactMu.Lock()
+	defer s.compactMu.Unlock()
+	s.mu.RLock()
+	closed := s.file == nil
+	s.mu.RUnlock()
+	if closed {
+		return
+	}
+	if _, err := s.rewrite(keepAllHistory); err != nil {
+		slog.Warn("Failed to compact history journal", "filename", s.filename, "error", err)
+	}
+}
+
+// writeJournalLine writes the entry as one line and returns the number of bytes written.
+func writeJournalLine(w io.Writer, entry DeviceHistoryEntry, cipher *FileCipher) (int, error) {
+	data, err := json.Marshal(toJSONHistoryEntry(entry))
+	if err != nil {
+		return 0, err
+	}
+	data, err = cipher.EncryptLine(data)
+	if err != nil {
+		return 0, err
+	}
+	data = append(data, '\n')
+	return w.Write(data)
+}
+
+func (s *journalDeviceHistoryStore) Record(entry DeviceHistoryEntry) {
+	if entry.Device.IP == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.recent.Record(entry)
+	if s.file == nil {
+		return
+	}
+	n, err := writeJournalLine(s.out, entry, s.cipher)
+	if err != nil {
+		slog.Warn("Failed to append to history journal", "filename", s.filename, "error", err)
+		if n > 0 {
+			s.discardPartialLineLocked(n)
+		}
+		return
+	}
+	key := entry.Device.Key()
+	s.index[key] = append(s.index[key], journalLocation{offset: s.size, length: n - 1})
+	s.size += int64(n)
+	if s.needsCompactionLocked() && s.compacting.CompareAndSwap(false, true) {
+		go s.compactInBackground()
+	}
+}
+
+// discardPartialLineLocked removes the n bytes of a line whose write failed partway,
+// so that the next line does not join onto it. If the file cannot be truncated, the line is ended instead.
+func (s *journalDeviceHistoryStore) discardPartialLineLocked(n int) {
+	if err := s.file.Truncate(s.size); err == nil {
+		return
+	}
+	s.size += int64(n)
+	if m, err := s.out.Write([]byte{'\n'}); err != nil {
+		slog.Warn("Failed to end a partly written history journal line", "filename", s.filename, "error", err)
+	} else {
+		s.size += int64(m)
+	}
+}
+
+// Query reads the indexed lines of the device, newest first, until Limit entries match.
+// It reads the lines written before the call without holding the lock Record needs.
+func (s *journalDeviceHistoryStore) Query(device IPAndEOJ, query HistoryQuery) []DeviceHistoryEntry {
+	// Keep the file from being replaced by a compaction while reading it
+	s.compactMu.RLock()
+	defer s.compactMu.RUnlock()
+	s.mu.RLock()
+	// Record only appends to the slice, so the lines seen here do not change
+	locations := s.index[device.Key()]
+	s.mu.RUnlock()
+	if len(locations) == 0 {
+		return nil
+	}
+
+	f, err := os.Open(s.filename)
+	if err != nil {
+		slog.Warn("Failed to read history journal", "filename", s.filename, "error", err)
+		return nil
+	}
+	defer f.Close()
+
+	var result []DeviceHistoryEntry
+	var buf []byte
+	for i := len(locations) - 1; i >= 0; i-- {
+		loc := locations[i]
+		if cap(buf) < loc.length {
+			buf = make([]byte, loc.length)
+		}
+		line := buf[:loc.length]
+		if _, err := f.ReadAt(line, loc.offset); err != nil {
+			slog.Warn("Failed to read history journal", "filename", s.filename, "error", err)
+			break
+		}
+		entry, err := s.decodeLine(line)
+		if err != nil {
+			slog.Debug("Skipping unreadable history journal line", "filename", s.filename, "error", err)
+			continue
+		}
+		if !query.Since.IsZero() && entry.Timestamp.Before(query.Since) {
+			// Lines are appended in order, so everything older is out of range too
+			break
+		}
+		if query.SettableOnly && !entry.Settable {
+			continue
+		}
+		if !query.matchesTimeRange(entry.Timestamp) || !query.matchesEPC(entry.EPC) {
+			continue
+		}
+		result = append(result, entry)
+		if query.Limit > 0 && len(result) >= query.Limit {
+			break
+		}
+	}
+	return result
+}
+
+// Clear removes all entries of the device by rewriting the journal.
+func (s *journalDeviceHistoryStore) Clear(device IPAndEOJ) {
+	key := device.Key()
+
+	s.compactMu.Lock()
+	defer s.compactMu.Unlock()
+
+	if _, err := s.rewrite(func(entry DeviceHistoryEntry) bool { return entry.Device.Key() != key }); err != nil {
+		slog.Warn("Failed to clear device history from journal", "device", key, "error", err)
+	}
+}
+
+// PerDeviceTotalLimit returns 0 because the journal is bounded by retention, not by count.
+func (s *journalDeviceHistoryStore) PerDeviceTotalLimit() int {
+	return 0
+}
+
+func (s *journalDeviceHistoryStore) IsDuplicateNotification(device IPAndEOJ, epc echonet_lite.EPCType, value PropertyValue, within time.Duration) bool {
+	s.mu.RLock()
+	recent := s.recent
+	s.mu.RUnlock()
+	return recent.IsDuplicateNotification(device, epc, value, within)
+}
+
+// MemoryUsage reports the in-memory part of the journal, i.e. the recent entries kept for duplicate detection.
+func (s *journalDeviceHistoryStore) MemoryUsage() HistoryMemoryUsage {
+	s.mu.RLock()
+	recent := s.recent
+	s.mu.RUnlock()
+	return recent.MemoryUsage()
+}
+
+// EvictToSize evicts only from the recent entries; the journal itself lives on disk.
+func (s *journalDeviceHistoryStore) EvictToSize(maxBytes int64) int {
+	s.mu.RLock()
+	recent := s.recent
+	s.mu.RUnlock()
+	return recent.EvictToSize(maxBytes)
+}
+
+// SaveToFile compacts the journal and flushes it to disk.
+// The filename is ignored because entries are already persisted in the journal itself.
+func (s *journalDeviceHistoryStore) SaveToFile(_ string) error {
+	s.compactMu.Lock()
+	defer s.compactMu.Unlock()
+
+	if _, err := s.rewrite(keepAllHistory); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Sync()
+}
+
+// LoadFromFile does nothing; the journal is read on demand.
+func (s *journalDeviceHistoryStore) LoadFromFile(_ string, _ HistoryLoadFilter) error {
+	return nil
+}
+
+// Close closes the journal file. It waits for a running compaction or query to finish.
+func (s *journalDeviceHistoryStore) Close() error {
+	s.compactMu.Lock()
+	defer s.compactMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+)
+
+func newTestJournal(t *testing.T, filename string, retention time.Duration) *journalDeviceHistoryStore {
+	t.Helper()
+	store, err := NewJournalDeviceHistoryStore(filename, retention, nil)
+	if err != nil {
+		t.Fatalf("NewJournalDeviceHistoryStore failed: %v", err)
+	}
+	journal := store.(*journalDeviceHistoryStore)
+	t.Cleanup(func() { _ = journal.Close() })
+	return journal
+}
+
+func recordJournalEntries(store DeviceHistoryStore, device IPAndEOJ, base time.Time, count int) {
+	for i := 0; i < count; i++ {
+		store.Record(DeviceHistoryEntry{
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+			Device:    device,
+			EPC:       echonet_lite.EPCType(0x80),
+			Value:     PropertyValue{String: fmt.Sprintf("value-%d", i)},
+			Origin:    HistoryOriginNotification,
+			Settable:  i%2 == 0,
+		})
+	}
+}
+
+func TestJournalDeviceHistoryStore_RecordAndQuery(t *testing.T) {
+	store := newTestJournal(t, filepath.Join(t.TempDir(), "history.jsonl"), 0)
+	device := testDevice(1)
+	other := testDevice(2)
+	base := time.Now().Add(-time.Hour)
+
+	recordJournalEntries(store, device, base, 5)
+	recordJournalEntries(store, other, base, 2)
+
+	entries := store.Query(device, HistoryQuery{Limit: 3})
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	for i, expected := range []string{"value-4", "value-3", "value-2"} {
+		if entries[i].Value.String != expected {
+			t.Errorf("entry %d expected value %s, got %s", i, expected, entries[i].Value.String)
+		}
+	}
+
+	settable := store.Query(device, HistoryQuery{SettableOnly: true})
+	if len(settable) != 3 {
+		t.Fatalf("expected 3 settable entries, got %d", len(settable))
+	}
+
+	ranged := store.Query(device, HistoryQuery{Since: base.Add(time.Minute), Until: base.Add(2 * time.Minute)})
+	if len(ranged) != 2 || ranged[0].Value.String != "value-2" || ranged[1].Value.String != "value-1" {
+		t.Errorf("unexpected time range result: %+v", ranged)
+	}
+
+	store.Record(DeviceHistoryEntry{Timestamp: base.Add(10 * time.Minute), Device: device, EPC: 0xBB, Value: PropertyValue{String: "25"}, Origin: HistoryOriginNotification})
+	byEPC := store.Query(device, HistoryQuery{EPC: 0xBB})
+	if len(byEPC) != 1 || byEPC[0].Value.String != "25" {
+		t.Errorf("unexpected EPC filter result: %+v", byEPC)
+	}
+}
+
+func TestJournalDeviceHistoryStore_PersistsAcrossReopen(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history.jsonl")
+	device := testDevice(3)
+	base := time.Now().Add(-time.Hour)
+
+	store, err := NewJournalDeviceHistoryStore(filename, 0, nil)
+	if err != nil {
+		t.Fatalf("NewJournalDeviceHistoryStore failed: %v", err)
+	}
+	recordJournalEntries(store, device, base, 4)
+	store.Record(DeviceHistoryEntry{Timestamp: time.Now(), Device: device, EPC: 0x80, Value: PropertyValue{String: "on"}, Origin: HistoryOriginSet, Settable: true})
+	// Simulate a crash: close the file without SaveToFile
+	if err := store.(io.Closer).Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	reopened := newTestJournal(t, filename, 0)
+	entries := reopened.Query(device, HistoryQuery{})
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 entries after reopen, got %d", len(entries))
+	}
+	if entries[0].Value.String != "on" {
+		t.Errorf("expected newest entry on, got %s", entries[0].Value.String)
+	}
+	if !reopened.IsDuplicateNotification(device, echonet_lite.EPCType(0x80), PropertyValue{String: "on"}, time.Minute) {
+		t.Error("expected recent entries to be restored for duplicate detection")
+	}
+}
+
+func TestJournalDeviceHistoryStore_Retention(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history.jsonl")
+	device := testDevice(4)
+	now := time.Now()
+
+	store := newTestJournal(t, filename, 0)
+	store.Record(DeviceHistoryEntry{Timestamp: now.Add(-48 * time.Hour), Device: device, EPC: 0x80, Value: PropertyValue{String: "old"}, Origin: HistoryOriginNotification})
+	store.Record(DeviceHistoryEntry{Timestamp: now.Add(-time.Hour), Device: device, EPC: 0x80, Value: PropertyValue{String: "new"}, Origin: HistoryOriginNotification})
+	_ = store.Close()
+
+	compacted := newTestJournal(t, filename, 24*time.Hour)
+	entries := compacted.Query(device, HistoryQuery{})
+	if len(entries) != 1 || entries[0].Value.String != "new" {
+		t.Fatalf("expected only the new entry to survive retention, got %+v", entries)
+	}
+}
+
+func TestJournalDeviceHistoryStore_Clear(t *testing.T) {
+	store := newTestJournal(t, filepath.Join(t.TempDir(), "history.jsonl"), 0)
+	device := testDevice(5)
+	other := testDevice(6)
+	base := time.Now().Add(-time.Hour)
+
+	recordJournalEntries(store, device, base, 3)
+	recordJournalEntries(store, other, base, 3)
+
+	store.Clear(device)
+
+	if entries := store.Query(device, HistoryQuery{}); len(entries) != 0 {
+		t.Errorf("expected no entries after clear, got %d", len(entries))
+	}
+	if entries := store.Query(other, HistoryQuery{}); len(entries) != 3 {
+		t.Errorf("expected other device entries to remain, got %d", len(entries))
+	}
+
+	// Recording still works after the journal was rewritten
+	recordJournalEntries(store, device, base, 1)
+	if entries := store.Query(device, HistoryQuery{}); len(entries) != 1 {
+		t.Errorf("expected 1 entry after re-recording, got %d", len(entries))
+	}
+}
+
+func TestJournalDeviceHistoryStore_CompactsWhileRunning(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history.jsonl")
+	device := testDevice(7)
+	now := time.Now()
+
+	store := newTestJournal(t, filename, 24*time.Hour)
+	var nowMu sync.Mutex
+	store.now = func() time.Time {
+		nowMu.Lock()
+		defer nowMu.Unlock()
+		return now
+	}
+	recordJournalEntries(store, device, now.Add(-time.Hour), 3)
+
+	// A day later the entries above are beyond retention, and the next Record starts a compaction
+	nowMu.Lock()
+	now = now.Add(journalCompactInterval + time.Minute)
+	nowMu.Unlock()
+	store.Record(DeviceHistoryEntry{Timestamp: now, Device: device, EPC: 0x80, Value: PropertyValue{String: "new"}, Origin: HistoryOriginNotification})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for store.compacting.Load() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	entries := store.Query(device, HistoryQuery{})
+	if len(entries) != 1 || entries[0].Value.String != "new" {
+		t.Fatalf("expected only the new entry after the background compaction, got %+v", entries)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.mu.RLock()
+	size := store.size
+	store.mu.RUnlock()
+	if info.Size() != size {
+		t.Errorf("journal size %d, tracked size %d", info.Size(), size)
+	}
+}
+
+func TestJournalDeviceHistoryStore_QueryDoesNotBlockRecord(t *testing.T) {
+	store := newTestJournal(t, filepath.Join(t.TempDir(), "history.jsonl"), 0)
+	device := testDevice(8)
+	base := time.Now().Add(-time.Hour)
+	recordJournalEntries(store, device, base, 2)
+
+	// Hold the lock a running Query holds while it scans the file
+	store.compactMu.RLock()
+	done := make(chan struct{})
+	go func() {
+		recordJournalEntries(store, device, base.Add(time.Hour), 1)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Record was blocked by a running Query")
+	}
+	store.compactMu.RUnlock()
+
+	if entries := store.Query(device, HistoryQuery{}); len(entries) != 3 {
+		t.Errorf("expected 3 entries, got %d", len(entries))
+	}
+}
+
+// partialWriter writes the first limit bytes of a write and then fails
+type partialWriter struct {
+	w     io.Writer
+	limit int
+}
+
+func (p *partialWriter) Write(data []byte) (int, error) {
+	n, err := p.w.Write(data[:min(p.limit, len(data))])
+	if err != nil {
+		return n, err
+	}
+	return n, fmt.Errorf("disk full")
+}
+
+func TestJournalDeviceHistoryStore_PartialWriteDoesNotCorruptNextLine(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history.jsonl")
+	store := newTestJournal(t, filename, 0)
+	device := testDevice(9)
+	base := time.Now().Add(-time.Hour)
+	recordJournalEntries(store, device, base, 1)
+
+	// The write fails partway and leaves the beginning of a line
+	store.mu.Lock()
+	store.out = &partialWriter{w: store.file, limit: 10}
+	store.mu.Unlock()
+	recordJournalEntries(store, device, base.Add(time.Minute), 1)
+	store.mu.Lock()
+	store.out = store.file
+	store.mu.Unlock()
+	recordJournalEntries(store, device, base.Add(2*time.Minute), 1)
+
+	if entries := store.Query(device, HistoryQuery{}); len(entries) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(entries))
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := newTestJournal(t, filename, 0)
+	if reopened.loaded.Skipped != 0 {
+		t.Errorf("expected no unreadable lines, got %d", reopened.loaded.Skipped)
+	}
+	if entries := reopened.Query(device, HistoryQuery{}); len(entries) != 2 {
+		t.Errorf("expected 2 entries after reopening, got %d", len(entries))
+	}
+}
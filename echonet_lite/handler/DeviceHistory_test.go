@@ -1138,3 +1138,59 @@ func TestQueryWithSettableOnly(t *testing.T) {
 		verifySettableEntries(t, entries, echonet_lite.EPCType(0x80))
 	})
 }
+
+func TestMemoryDeviceHistoryStore_QueryTimeRange(t *testing.T) {
+	store := NewMemoryDeviceHistoryStore(HistoryOptions{PerDeviceNonSettableLimit: 10})
+	device := testDevice(7)
+	base := time.Now().Add(-time.Hour)
+
+	for i := 0; i < 5; i++ {
+		store.Record(DeviceHistoryEntry{
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+			Device:    device,
+			EPC:       echonet_lite.EPCType(0x80),
+			Value:     PropertyValue{String: fmt.Sprintf("value-%d", i)},
+			Origin:    HistoryOriginNotification,
+		})
+	}
+
+	entries := store.Query(device, HistoryQuery{
+		Since: base.Add(1 * time.Minute),
+		Until: base.Add(3 * time.Minute),
+	})
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	for i, expected := range []string{"value-3", "value-2", "value-1"} {
+		if entries[i].Value.String != expected {
+			t.Errorf("entry %d expected value %s, got %s", i, expected, entries[i].Value.String)
+		}
+	}
+}
+
+func TestMemoryDeviceHistoryStore_QueryEPC(t *testing.T) {
+	store := NewMemoryDeviceHistoryStore(HistoryOptions{PerDeviceNonSettableLimit: 10})
+	device := testDevice(8)
+	base := time.Now().Add(-time.Hour)
+
+	for i := 0; i < 6; i++ {
+		epc := echonet_lite.EPCType(0x80)
+		if i%2 == 1 {
+			epc = 0xBB
+		}
+		store.Record(DeviceHistoryEntry{
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+			Device:    device,
+			EPC:       epc,
+			Value:     PropertyValue{String: fmt.Sprintf("value-%d", i)},
+			Origin:    HistoryOriginNotification,
+		})
+	}
+	store.Record(DeviceHistoryEntry{Timestamp: base.Add(10 * time.Minute), Device: device, Origin: HistoryOriginOffline})
+
+	// The limit applies after filtering, and events are excluded
+	entries := store.Query(device, HistoryQuery{EPC: 0xBB, Limit: 2})
+	if len(entries) != 2 || entries[0].Value.String != "value-5" || entries[1].Value.String != "value-3" {
+		t.Fatalf("unexpected EPC filter result: %+v", entries)
+	}
+}
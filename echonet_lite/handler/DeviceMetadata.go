@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// MaxMetadataNotesLength はメモの最大文字数
+	MaxMetadataNotesLength = 1000
+	// MaxMetadataLabelLength は部屋・階・アイコン名・タグのキーの最大文字数
+	MaxMetadataLabelLength = 64
+	// MaxMetadataTagValueLength はタグの値の最大文字数
+	MaxMetadataTagValueLength = 256
+	// MaxMetadataTags は1台のデバイスに付けられるタグの最大数
+	MaxMetadataTags = 32
+)
+
+// DeviceMetadata は、デバイスに付けるメモやラベルを表す
+// エイリアスと同じくサーバーに保存し、すべてのクライアントで共有する
+type DeviceMetadata struct {
+	Notes string            `json:"notes,omitempty"` // 自由記述のメモ
+	Room  string            `json:"room,omitempty"`  // 設置場所の部屋
+	Floor string            `json:"floor,omitempty"` // 設置場所の階
+	Icon  string            `json:"icon,omitempty"`  // クライアントが表示に使うアイコン名
+	Tags  map[string]string `json:"tags,omitempty"`  // 任意のキーと値
+	// Pinned は、すべてのクライアントのお気に入りとして一覧の先頭に表示するかどうか
+	Pinned bool `json:"pinned,omitempty"`
+}
+
+// IsEmpty は、何も設定されていないかどうかを返す
+func (m DeviceMetadata) IsEmpty() bool {
+	return m.Notes == "" && m.Room == "" && m.Floor == "" && m.Icon == "" && len(m.Tags) == 0 && !m.Pinned
+}
+
+// Validate は、各項目の長さとタグの数を検証する
+func (m DeviceMetadata) Validate() error {
+	if utf8.RuneCountInString(m.Notes) > MaxMetadataNotesLength {
+		return fmt.Errorf("メモは%d文字以内にしてください", MaxMetadataNotesLength)
+	}
+	for name, value := range map[string]string{"room": m.Room, "floor": m.Floor, "icon": m.Icon} {
+		if utf8.RuneCountInString(value) > MaxMetadataLabelLength {
+			return fmt.Errorf("%s は%d文字以内にしてください", name, MaxMetadataLabelLength)
+		}
+	}
+	if len(m.Tags) > MaxMetadataTags {
+		return fmt.Errorf("タグは%d個までです", MaxMetadataTags)
+	}
+	for key, value := range m.Tags {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("タグのキーが空です")
+		}
+		if utf8.RuneCountInString(key) > MaxMetadataLabelLength {
+			return fmt.Errorf("タグのキー %q は%d文字以内にしてください", key, MaxMetadataLabelLength)
+		}
+		if utf8.RuneCountInString(value) > MaxMetadataTagValueLength {
+			return fmt.Errorf("タグ %q の値は%d文字以内にしてください", key, MaxMetadataTagValueLength)
+		}
+	}
+	return nil
+}
+
+// clone は、タグのマップを共有しないコピーを返す
+func (m DeviceMetadata) clone() DeviceMetadata {
+	if m.Tags != nil {
+		tags := make(map[string]string, len(m.Tags))
+		for k, v := range m.Tags {
+			tags[k] = v
+		}
+		m.Tags = tags
+	}
+	return m
+}
+
+// DeviceMetadataStore は、デバイスごとのメモやラベルを管理する構造体
+// IP アドレスが変わっても引き継がれるよう、エイリアスと同じく IDString をキーにする
+type DeviceMetadataStore struct {
+	metadata map[IDString]DeviceMetadata
+	mutex    sync.RWMutex
+}
+
+// NewDeviceMetadataStore は DeviceMetadataStore の新しいインスタンスを作成する
+func NewDeviceMetadataStore() *DeviceMetadataStore {
+	return &DeviceMetadataStore{
+		metadata: make(map[IDString]DeviceMetadata),
+	}
+}
+
+// LoadFromFile はファイルからメタデータを読み込む
+func (s *DeviceMetadataStore) LoadFromFile(filename string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// ファイルが存在しない場合は空のまま終了
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		s.metadata = make(map[IDString]DeviceMetadata)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("メタデータファイルを開けません: %v", err)
+	}
+
+	metadata := make(map[IDString]DeviceMetadata)
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return fmt.Errorf("メタデータファイルの解析に失敗しました: %v", err)
+	}
+	s.metadata = metadata
+	return nil
+}
+
+// SaveToFile はメタデータをファイルに保存する
+func (s *DeviceMetadataStore) SaveToFile(filename string) error {
+	s.mutex.RLock()
+	// マップのキーはソートされて出力されるため、ファイルの差分が安定する
+	data, err := json.MarshalIndent(s.metadata, "", "  ")
+	s.mutex.RUnlock()
+	if err != nil {
+		return fmt.Errorf("メタデータのエンコードに失敗しました: %v", err)
+	}
+
+	// ディレクトリが存在しない場合は作成
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("ディレクトリの作成に失敗しました: %v", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("メタデータファイルの書き込みに失敗しました: %v", err)
+	}
+	return nil
+}
+
+// Set はデバイスのメタデータを置き換える。空のメタデータを設定した場合は削除する
+func (s *DeviceMetadataStore) Set(id IDString, metadata DeviceMetadata) error {
+	if id == "" {
+		return fmt.Errorf("デバイスが指定されていません")
+	}
+	if err := metadata.Validate(); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if metadata.IsEmpty() {
+		delete(s.metadata, id)
+		return nil
+	}
+	s.metadata[id] = metadata.clone()
+	return nil
+}
+
+// Delete はデバイスのメタデータを削除する
+func (s *DeviceMetadataStore) Delete(id IDString) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.metadata[id]; !ok {
+		return fmt.Errorf("メタデータが存在しません: %s", id)
+	}
+	delete(s.metadata, id)
+	return nil
+}
+
+// Get はデバイスのメタデータを返す
+func (s *DeviceMetadataStore) Get(id IDString) (DeviceMetadata, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	metadata, ok := s.metadata[id]
+	return metadata.clone(), ok
+}
+
+// GetAll はすべてのデバイスのメタデータを返す
+func (s *DeviceMetadataStore) GetAll() map[IDString]DeviceMetadata {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make(map[IDString]DeviceMetadata, len(s.metadata))
+	for id, metadata := range s.metadata {
+		result[id] = metadata.clone()
+	}
+	return result
+}
+
+// Count はメタデータを持つデバイスの数を返す
+func (s *DeviceMetadataStore) Count() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.metadata)
+}
+
+// reassign は、from のメタデータを to に移す。to にすでにメタデータがある場合は from を削除するだけにする
+// メタデータを変更した場合は true を返す
+func (s *DeviceMetadataStore) reassign(from, to IDString) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	metadata, ok := s.metadata[from]
+	if !ok {
+		return false
+	}
+	delete(s.metadata, from)
+	if _, exists := s.metadata[to]; !exists {
+		s.metadata[to] = metadata
+	}
+	return true
+}
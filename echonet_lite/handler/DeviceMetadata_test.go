@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeviceMetadataStore_SetAndPersist(t *testing.T) {
+	store := NewDeviceMetadataStore()
+	const aircon IDString = "013001:000005:01"
+	metadata := DeviceMetadata{Notes: "フィルター清掃済み", Room: "リビング", Floor: "1F", Icon: "aircon", Tags: map[string]string{"model": "X"}}
+	if err := store.Set(aircon, metadata); err != nil {
+		t.Fatalf("Set に失敗: %v", err)
+	}
+
+	// 返された値を変更してもストアには影響しない
+	got, ok := store.Get(aircon)
+	if !ok || got.Room != "リビング" || got.Tags["model"] != "X" {
+		t.Fatalf("メタデータが不正: %+v", got)
+	}
+	got.Tags["model"] = "Y"
+	metadata.Tags["model"] = "Z"
+	if again, _ := store.Get(aircon); again.Tags["model"] != "X" {
+		t.Errorf("ストアのタグが変更された: %+v", again)
+	}
+
+	filename := filepath.Join(t.TempDir(), "device_metadata.json")
+	if err := store.SaveToFile(filename); err != nil {
+		t.Fatalf("SaveToFile に失敗: %v", err)
+	}
+	loaded := NewDeviceMetadataStore()
+	if err := loaded.LoadFromFile(filename); err != nil {
+		t.Fatalf("LoadFromFile に失敗: %v", err)
+	}
+	if all := loaded.GetAll(); len(all) != 1 || all[aircon].Notes != "フィルター清掃済み" || all[aircon].Floor != "1F" {
+		t.Fatalf("読み込んだメタデータが不正: %+v", all)
+	}
+
+	// 空のメタデータを設定すると削除される
+	if err := loaded.Set(aircon, DeviceMetadata{}); err != nil {
+		t.Fatalf("Set に失敗: %v", err)
+	}
+	if loaded.Count() != 0 {
+		t.Errorf("空のメタデータが削除されていない: %+v", loaded.GetAll())
+	}
+	if (DeviceMetadata{Pinned: true}).IsEmpty() {
+		t.Error("お気に入りに固定しただけのメタデータが空と判定された")
+	}
+	if err := loaded.Delete(aircon); err == nil {
+		t.Error("存在しないメタデータの削除がエラーにならない")
+	}
+}
+
+func TestDeviceMetadataStore_LoadMissingFile(t *testing.T) {
+	store := NewDeviceMetadataStore()
+	if err := store.LoadFromFile(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Fatalf("ファイルがない場合はエラーにならないはず: %v", err)
+	}
+	if store.Count() != 0 {
+		t.Errorf("メタデータが空でない: %d", store.Count())
+	}
+}
+
+func TestDeviceMetadata_Validate(t *testing.T) {
+	tooManyTags := make(map[string]string)
+	for i := 0; i <= MaxMetadataTags; i++ {
+		tooManyTags[strings.Repeat("k", i+1)] = "v"
+	}
+	invalid := map[string]DeviceMetadata{
+		"長いメモ":    {Notes: strings.Repeat("あ", MaxMetadataNotesLength+1)},
+		"長い部屋名":   {Room: strings.Repeat("a", MaxMetadataLabelLength+1)},
+		"タグが多すぎる": {Tags: tooManyTags},
+		"空のタグのキー": {Tags: map[string]string{" ": "v"}},
+		"長いタグの値":  {Tags: map[string]string{"k": strings.Repeat("v", MaxMetadataTagValueLength+1)}},
+		"長いタグのキー": {Tags: map[string]string{strings.Repeat("k", MaxMetadataLabelLength+1): "v"}},
+	}
+	store := NewDeviceMetadataStore()
+	for name, metadata := range invalid {
+		if err := store.Set("013001:000005:01", metadata); err == nil {
+			t.Errorf("%s: エラーにならない", name)
+		}
+	}
+	if err := store.Set("", DeviceMetadata{Room: "寝室"}); err == nil {
+		t.Error("デバイスの指定がない場合にエラーにならない")
+	}
+	// 日本語は文字数で数える
+	if err := store.Set("013001:000005:01", DeviceMetadata{Notes: strings.Repeat("あ", MaxMetadataNotesLength)}); err != nil {
+		t.Errorf("上限ちょうどのメモがエラーになった: %v", err)
+	}
+}
@@ -0,0 +1,445 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SceneAction は、シーン実行時に1つのデバイスへ設定するプロパティを表す
+type SceneAction struct {
+	Device IDString `json:"device"` // 対象デバイス
+	EPC    EPCType  `json:"epc"`
+	EDT    []byte   `json:"edt"`
+}
+
+// MaxSceneDelayMs は、SceneSequence.DelayMs に指定できる最大値（1分）
+const MaxSceneDelayMs = 60 * 1000
+
+// SceneSequence は、シーン実行時のデバイスの順序と間隔を表す
+// 同じ回路の機器を一斉に起動すると突入電流でブレーカーが落ちることがあるため、
+// 設定した場合はデバイスを1台ずつ順に設定する。ゼロ値の場合はすべてのデバイスを並列に設定する
+type SceneSequence struct {
+	Order   []IDString `json:"order,omitempty"`    // 先に設定するデバイス（先頭から順に。含まれないデバイスはアクションの順に後から設定する）
+	DelayMs int        `json:"delay_ms,omitempty"` // デバイスを設定する間隔（ミリ秒）
+}
+
+// IsZero は、順序と間隔のどちらも設定されていないかどうかを返す
+func (s SceneSequence) IsZero() bool {
+	return len(s.Order) == 0 && s.DelayMs == 0
+}
+
+// Delay は、デバイスを設定する間隔を返す
+func (s SceneSequence) Delay() time.Duration {
+	return time.Duration(s.DelayMs) * time.Millisecond
+}
+
+// Validate は、順序と間隔が有効かどうかを検証する
+func (s SceneSequence) Validate() error {
+	if s.DelayMs < 0 || s.DelayMs > MaxSceneDelayMs {
+		return fmt.Errorf("デバイスの間隔は 0〜%d ミリ秒で指定してください: %d", MaxSceneDelayMs, s.DelayMs)
+	}
+	seen := make(map[IDString]bool, len(s.Order))
+	for _, id := range s.Order {
+		if seen[id] {
+			return fmt.Errorf("順序にデバイスが重複しています: %s", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// SceneActionsPair は、シーン名とアクションのペアを表す
+type SceneActionsPair struct {
+	Scene    string
+	Actions  []SceneAction
+	Sequence SceneSequence
+}
+
+// DeviceScenes は、複数デバイスのプロパティをまとめて設定するシーンを管理する構造体
+type DeviceScenes struct {
+	scenes    map[string][]SceneAction // シーン名 -> アクションリスト
+	sequences map[string]SceneSequence // シーン名 -> デバイスの順序と間隔（設定されたシーンのみ）
+	mutex     sync.RWMutex
+}
+
+// NewDeviceScenes は DeviceScenes の新しいインスタンスを作成する
+func NewDeviceScenes() *DeviceScenes {
+	return &DeviceScenes{
+		scenes:    make(map[string][]SceneAction),
+		sequences: make(map[string]SceneSequence),
+	}
+}
+
+// sceneEntry は、シーンファイルの1エントリを表す
+type sceneEntry struct {
+	Scene    string         `json:"scene"`
+	Actions  []SceneAction  `json:"actions"`
+	Sequence *SceneSequence `json:"sequence,omitempty"`
+}
+
+// LoadFromFile はファイルからシーン情報を読み込む
+func (s *DeviceScenes) LoadFromFile(filename string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// ファイルが存在しない場合は空のシーンリストを作成して終了
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		s.scenes = make(map[string][]SceneAction)
+		s.sequences = make(map[string]SceneSequence)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("シーンファイルを開けません: %v", err)
+	}
+
+	var entries []sceneEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("シーンファイルの解析に失敗しました: %v", err)
+	}
+
+	s.scenes = make(map[string][]SceneAction)
+	s.sequences = make(map[string]SceneSequence)
+	for _, entry := range entries {
+		s.scenes[entry.Scene] = entry.Actions
+		if entry.Sequence != nil && !entry.Sequence.IsZero() {
+			s.sequences[entry.Scene] = *entry.Sequence
+		}
+	}
+	return nil
+}
+
+// SaveToFile はシーン情報をファイルに保存する
+func (s *DeviceScenes) SaveToFile(filename string) error {
+	s.mutex.RLock()
+	entries := make([]sceneEntry, 0, len(s.scenes))
+	for scene, actions := range s.scenes {
+		entry := sceneEntry{Scene: scene, Actions: actions}
+		if sequence, ok := s.sequences[scene]; ok {
+			entry.Sequence = &sequence
+		}
+		entries = append(entries, entry)
+	}
+	s.mutex.RUnlock()
+
+	// シーン名でソート
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Scene < entries[j].Scene
+	})
+
+	// ディレクトリが存在しない場合は作成
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("ディレクトリの作成に失敗しました: %v", err)
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("シーン情報のエンコードに失敗しました: %v", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("シーンファイルの書き込みに失敗しました: %v", err)
+	}
+	return nil
+}
+
+// ValidateSceneName はシーン名が有効かどうかを検証する
+// グループ名と区別するため、'@' で始まる名前は使えない
+func ValidateSceneName(sceneName string) error {
+	if sceneName == "" {
+		return fmt.Errorf("シーン名が空です")
+	}
+	if strings.HasPrefix(sceneName, "@") {
+		return fmt.Errorf("シーン名は '@' で始めることはできません: %s", sceneName)
+	}
+	if strings.ContainsAny(sceneName, " \t\n\r") {
+		return fmt.Errorf("シーン名に空白文字を含めることはできません: %s", sceneName)
+	}
+	return nil
+}
+
+// SceneAdd はシーンにアクションを追加する
+// シーンが存在しない場合は作成し、同じデバイス・EPCのアクションは置き換える
+func (s *DeviceScenes) SceneAdd(sceneName string, actions []SceneAction) error {
+	if err := ValidateSceneName(sceneName); err != nil {
+		return err
+	}
+	if len(actions) == 0 {
+		return fmt.Errorf("シーンに追加するアクションがありません: %s", sceneName)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existing := s.scenes[sceneName]
+	for _, action := range actions {
+		replaced := false
+		for i := range existing {
+			if existing[i].Device == action.Device && existing[i].EPC == action.EPC {
+				existing[i] = action
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			existing = append(existing, action)
+		}
+	}
+	s.scenes[sceneName] = existing
+	return nil
+}
+
+// SceneDelete はシーンを削除する
+func (s *DeviceScenes) SceneDelete(sceneName string) error {
+	if err := ValidateSceneName(sceneName); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.scenes[sceneName]; !exists {
+		return fmt.Errorf("シーンが存在しません: %s", sceneName)
+	}
+	delete(s.scenes, sceneName)
+	delete(s.sequences, sceneName)
+	return nil
+}
+
+// SceneSetSequence は、シーン実行時のデバイスの順序と間隔を設定する
+// ゼロ値を指定すると設定を解除し、すべてのデバイスを並列に設定するようにする
+func (s *DeviceScenes) SceneSetSequence(sceneName string, sequence SceneSequence) error {
+	if err := ValidateSceneName(sceneName); err != nil {
+		return err
+	}
+	if err := sequence.Validate(); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.scenes[sceneName]; !exists {
+		return fmt.Errorf("シーンが存在しません: %s", sceneName)
+	}
+	if sequence.IsZero() {
+		delete(s.sequences, sceneName)
+	} else {
+		s.sequences[sceneName] = copySceneSequence(sequence)
+	}
+	return nil
+}
+
+// GetSceneSequence は、シーン実行時のデバイスの順序と間隔を返す（設定されていない場合はゼロ値）
+func (s *DeviceScenes) GetSceneSequence(sceneName string) SceneSequence {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return copySceneSequence(s.sequences[sceneName])
+}
+
+// SceneList はシーンのリストを返す
+// sceneNameがnilの場合は全シーンを名前順に返す
+func (s *DeviceScenes) SceneList(sceneName *string) []SceneActionsPair {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]SceneActionsPair, 0)
+	if sceneName != nil {
+		if actions, exists := s.scenes[*sceneName]; exists {
+			result = append(result, SceneActionsPair{Scene: *sceneName, Actions: copySceneActions(actions), Sequence: copySceneSequence(s.sequences[*sceneName])})
+		}
+		return result
+	}
+
+	names := make([]string, 0, len(s.scenes))
+	for name := range s.scenes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result = append(result, SceneActionsPair{Scene: name, Actions: copySceneActions(s.scenes[name]), Sequence: copySceneSequence(s.sequences[name])})
+	}
+	return result
+}
+
+// GetScene はシーン名に対応するアクションのリストを返す
+func (s *DeviceScenes) GetScene(sceneName string) ([]SceneAction, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	actions, exists := s.scenes[sceneName]
+	if !exists {
+		return nil, false
+	}
+	return copySceneActions(actions), true
+}
+
+// Count はシーンの総数を返す
+func (s *DeviceScenes) Count() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.scenes)
+}
+
+func copySceneActions(actions []SceneAction) []SceneAction {
+	result := make([]SceneAction, len(actions))
+	copy(result, actions)
+	return result
+}
+
+func copySceneSequence(sequence SceneSequence) SceneSequence {
+	if sequence.Order != nil {
+		sequence.Order = append([]IDString(nil), sequence.Order...)
+	}
+	return sequence
+}
+
+// SceneRunResult は、シーン実行時の1デバイス分の結果を表す
+type SceneRunResult struct {
+	ID         IDString            // 対象デバイスのID
+	Device     IPAndEOJ            // 対象デバイス（見つからなかった場合はゼロ値）
+	Properties Properties          // 設定を要求したプロパティ
+	Result     DeviceAndProperties // 設定結果
+	Err        error               // 失敗した場合のエラー
+}
+
+// SceneRunOptions は、シーン実行時の順序と進捗の通知先を表す
+type SceneRunOptions struct {
+	Sequence SceneSequence // デバイスの順序と間隔（ゼロ値の場合は並列に設定する）
+	// Progress は、1デバイスの設定が終わるたびに呼び出される（nilの場合は通知しない）
+	// completed は終わったデバイスの数、total はデバイスの総数
+	Progress func(result SceneRunResult, completed, total int)
+}
+
+// RunSceneActions は、アクションをデバイス毎にまとめて設定する
+// opts.Sequence が設定されている場合は順序に従って1台ずつ間隔を空けて設定し、それ以外は並列に設定する
+// 結果は実行順によらず、アクションに最初に現れたデバイスの順に返す
+func RunSceneActions(
+	actions []SceneAction,
+	findDevice func(IDString) *IPAndEOJ,
+	setProperties func(IPAndEOJ, Properties) (DeviceAndProperties, error),
+	opts SceneRunOptions,
+) []SceneRunResult {
+	var results []SceneRunResult
+	index := make(map[IDString]int)
+	for _, action := range actions {
+		i, ok := index[action.Device]
+		if !ok {
+			i = len(results)
+			index[action.Device] = i
+			results = append(results, SceneRunResult{ID: action.Device})
+		}
+		results[i].Properties = append(results[i].Properties, Property{EPC: action.EPC, EDT: action.EDT})
+	}
+
+	var progressMu sync.Mutex
+	completed := 0
+	finish := func(r *SceneRunResult) {
+		if opts.Progress == nil {
+			return
+		}
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		completed++
+		opts.Progress(*r, completed, len(results))
+	}
+	resolve := func(r *SceneRunResult) bool {
+		device := findDevice(r.ID)
+		if device == nil {
+			r.Err = fmt.Errorf("デバイスが見つかりません: %s", r.ID)
+			finish(r)
+			return false
+		}
+		r.Device = *device
+		return true
+	}
+
+	if !opts.Sequence.IsZero() {
+		started := false
+		for _, i := range sceneRunOrder(results, index, opts.Sequence.Order) {
+			r := &results[i]
+			if !resolve(r) {
+				continue
+			}
+			if started {
+				time.Sleep(opts.Sequence.Delay())
+			}
+			started = true
+			r.Result, r.Err = setProperties(r.Device, r.Properties)
+			finish(r)
+		}
+		return results
+	}
+
+	var wg sync.WaitGroup
+	for i := range results {
+		if !resolve(&results[i]) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *SceneRunResult) {
+			defer wg.Done()
+			r.Result, r.Err = setProperties(r.Device, r.Properties)
+			finish(r)
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
+}
+
+// sceneRunOrder は、results を設定する順にインデックスを返す
+// order に含まれるデバイスを先に、残りをアクションに現れた順に並べる。シーンにないデバイスは無視する
+func sceneRunOrder(results []SceneRunResult, index map[IDString]int, order []IDString) []int {
+	runOrder := make([]int, 0, len(results))
+	ordered := make(map[int]bool, len(order))
+	for _, id := range order {
+		if i, ok := index[id]; ok && !ordered[i] {
+			runOrder = append(runOrder, i)
+			ordered[i] = true
+		}
+	}
+	for i := range results {
+		if !ordered[i] {
+			runOrder = append(runOrder, i)
+		}
+	}
+	return runOrder
+}
+
+// reassign は、シーンのアクションと順序の from を to に置き換える。シーンを変更した場合は true を返す
+func (s *DeviceScenes) reassign(from, to IDString) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	changed := false
+	for name, actions := range s.scenes {
+		for i := range actions {
+			if actions[i].Device == from {
+				actions[i].Device = to
+				changed = true
+			}
+		}
+		s.scenes[name] = actions
+	}
+	for name, sequence := range s.sequences {
+		if index := slices.Index(sequence.Order, from); index >= 0 {
+			order := slices.Clone(sequence.Order)
+			if slices.Contains(order, to) {
+				order = slices.Delete(order, index, index+1)
+			} else {
+				order[index] = to
+			}
+			sequence.Order = order
+			s.sequences[name] = sequence
+			changed = true
+		}
+	}
+	return changed
+}
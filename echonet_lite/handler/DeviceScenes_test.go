@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDeviceScenes_AddAndPersist(t *testing.T) {
+	scenes := NewDeviceScenes()
+	if err := scenes.SceneAdd("goodnight", []SceneAction{
+		{Device: "013001:000005:01", EPC: 0x80, EDT: []byte{0x31}},
+		{Device: "029001:000005:02", EPC: 0x80, EDT: []byte{0x31}},
+	}); err != nil {
+		t.Fatalf("SceneAdd に失敗: %v", err)
+	}
+	// 同じデバイス・EPCは置き換え、それ以外は追加される
+	if err := scenes.SceneAdd("goodnight", []SceneAction{
+		{Device: "013001:000005:01", EPC: 0x80, EDT: []byte{0x30}},
+		{Device: "013001:000005:01", EPC: 0xB0, EDT: []byte{0x42}},
+	}); err != nil {
+		t.Fatalf("SceneAdd に失敗: %v", err)
+	}
+
+	actions, ok := scenes.GetScene("goodnight")
+	if !ok || len(actions) != 3 {
+		t.Fatalf("アクション数が不正: %+v", actions)
+	}
+	if actions[0].EDT[0] != 0x30 {
+		t.Errorf("アクションが置き換えられていない: %+v", actions[0])
+	}
+
+	filename := filepath.Join(t.TempDir(), "scenes.json")
+	if err := scenes.SaveToFile(filename); err != nil {
+		t.Fatalf("SaveToFile に失敗: %v", err)
+	}
+	loaded := NewDeviceScenes()
+	if err := loaded.LoadFromFile(filename); err != nil {
+		t.Fatalf("LoadFromFile に失敗: %v", err)
+	}
+	list := loaded.SceneList(nil)
+	if len(list) != 1 || list[0].Scene != "goodnight" || len(list[0].Actions) != 3 {
+		t.Fatalf("読み込んだシーンが不正: %+v", list)
+	}
+
+	if err := loaded.SceneDelete("goodnight"); err != nil {
+		t.Fatalf("SceneDelete に失敗: %v", err)
+	}
+	if err := loaded.SceneDelete("goodnight"); err == nil {
+		t.Error("存在しないシーンの削除がエラーにならない")
+	}
+}
+
+func TestDeviceScenes_LoadMissingFile(t *testing.T) {
+	scenes := NewDeviceScenes()
+	if err := scenes.LoadFromFile(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Fatalf("ファイルがない場合はエラーにならないはず: %v", err)
+	}
+	if scenes.Count() != 0 {
+		t.Errorf("シーン数が不正: %d", scenes.Count())
+	}
+}
+
+func TestValidateSceneName(t *testing.T) {
+	for _, name := range []string{"", "@living", "good night"} {
+		if err := ValidateSceneName(name); err == nil {
+			t.Errorf("%q が有効と判定された", name)
+		}
+	}
+	if err := ValidateSceneName("おやすみ"); err != nil {
+		t.Errorf("有効なシーン名がエラーになった: %v", err)
+	}
+}
+
+func TestRunSceneActions(t *testing.T) {
+	known := map[IDString]IPAndEOJ{
+		"013001:000005:01": testDevice(1),
+		"013001:000005:02": testDevice(2),
+	}
+	actions := []SceneAction{
+		{Device: "013001:000005:02", EPC: 0x80, EDT: []byte{0x30}},
+		{Device: "013001:000005:01", EPC: 0x80, EDT: []byte{0x30}},
+		{Device: "missing", EPC: 0x80, EDT: []byte{0x30}},
+		{Device: "013001:000005:02", EPC: 0xB0, EDT: []byte{0x42}},
+	}
+
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	results := RunSceneActions(actions,
+		func(id IDString) *IPAndEOJ {
+			if device, ok := known[id]; ok {
+				return &device
+			}
+			return nil
+		},
+		func(device IPAndEOJ, props Properties) (DeviceAndProperties, error) {
+			mu.Lock()
+			calls[device.Key()] = len(props)
+			mu.Unlock()
+			if device.Key() == testDevice(1).Key() {
+				return DeviceAndProperties{}, errors.New("timeout")
+			}
+			return DeviceAndProperties{Device: device, Properties: props}, nil
+		}, SceneRunOptions{})
+
+	if len(results) != 3 {
+		t.Fatalf("結果の数が不正: %d", len(results))
+	}
+	// 結果は最初に現れたデバイスの順
+	if results[0].ID != "013001:000005:02" || results[0].Err != nil || len(results[0].Result.Properties) != 2 {
+		t.Errorf("1件目の結果が不正: %+v", results[0])
+	}
+	if results[1].ID != "013001:000005:01" || results[1].Err == nil {
+		t.Errorf("失敗したデバイスの結果が不正: %+v", results[1])
+	}
+	if results[2].ID != "missing" || results[2].Err == nil {
+		t.Errorf("未検出デバイスの結果が不正: %+v", results[2])
+	}
+	// デバイスごとに1回だけ設定される
+	if len(calls) != 2 || calls[testDevice(2).Key()] != 2 {
+		t.Errorf("設定呼び出しが不正: %v", calls)
+	}
+}
+
+func TestRunSceneActions_Sequence(t *testing.T) {
+	known := map[IDString]IPAndEOJ{
+		"013001:000005:01": testDevice(1),
+		"013001:000005:02": testDevice(2),
+		"013001:000005:03": testDevice(3),
+	}
+	actions := []SceneAction{
+		{Device: "013001:000005:01", EPC: 0x80, EDT: []byte{0x30}},
+		{Device: "013001:000005:02", EPC: 0x80, EDT: []byte{0x30}},
+		{Device: "missing", EPC: 0x80, EDT: []byte{0x30}},
+		{Device: "013001:000005:03", EPC: 0x80, EDT: []byte{0x30}},
+	}
+
+	var order []string
+	var times []time.Time
+	var progress []int
+	opts := SceneRunOptions{
+		// 03 を先に、残りはアクションの順。シーンにないデバイスは無視する
+		Sequence: SceneSequence{Order: []IDString{"013001:000005:03", "013001:000005:09"}, DelayMs: 20},
+		Progress: func(result SceneRunResult, completed, total int) {
+			if total != 4 {
+				t.Errorf("total = %d, want 4", total)
+			}
+			progress = append(progress, completed)
+		},
+	}
+	results := RunSceneActions(actions,
+		func(id IDString) *IPAndEOJ {
+			if device, ok := known[id]; ok {
+				return &device
+			}
+			return nil
+		},
+		func(device IPAndEOJ, props Properties) (DeviceAndProperties, error) {
+			order = append(order, device.Key())
+			times = append(times, time.Now())
+			return DeviceAndProperties{Device: device, Properties: props}, nil
+		}, opts)
+
+	want := []string{testDevice(3).Key(), testDevice(1).Key(), testDevice(2).Key()}
+	if len(order) != len(want) || order[0] != want[0] || order[1] != want[1] || order[2] != want[2] {
+		t.Fatalf("設定の順序が不正: %v, want %v", order, want)
+	}
+	// デバイスの間は間隔を空ける
+	for i := 1; i < len(times); i++ {
+		if d := times[i].Sub(times[i-1]); d < 20*time.Millisecond {
+			t.Errorf("間隔が短すぎる: %v", d)
+		}
+	}
+	// 結果は実行順によらずアクションの順
+	if results[0].ID != "013001:000005:01" || results[3].ID != "013001:000005:03" || results[2].Err == nil {
+		t.Errorf("結果が不正: %+v", results)
+	}
+	if len(progress) != 4 || progress[3] != 4 {
+		t.Errorf("進捗の通知が不正: %v", progress)
+	}
+}
+
+func TestDeviceScenes_Sequence(t *testing.T) {
+	scenes := NewDeviceScenes()
+	sequence := SceneSequence{Order: []IDString{"013001:000005:02"}, DelayMs: 1500}
+	if err := scenes.SceneSetSequence("おやすみ", sequence); err == nil {
+		t.Error("存在しないシーンに順序を設定できてしまった")
+	}
+	if err := scenes.SceneAdd("おやすみ", []SceneAction{{Device: "013001:000005:02", EPC: 0x80, EDT: []byte{0x31}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := scenes.SceneSetSequence("おやすみ", SceneSequence{DelayMs: MaxSceneDelayMs + 1}); err == nil {
+		t.Error("上限を超える間隔を設定できてしまった")
+	}
+	if err := scenes.SceneSetSequence("おやすみ", SceneSequence{Order: []IDString{"a", "a"}}); err == nil {
+		t.Error("重複した順序を設定できてしまった")
+	}
+	if err := scenes.SceneSetSequence("おやすみ", sequence); err != nil {
+		t.Fatal(err)
+	}
+
+	// ファイルに保存して読み込み直しても保持される
+	filename := filepath.Join(t.TempDir(), "scenes.json")
+	if err := scenes.SaveToFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewDeviceScenes()
+	if err := loaded.LoadFromFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	got := loaded.GetSceneSequence("おやすみ")
+	if got.DelayMs != 1500 || len(got.Order) != 1 || got.Order[0] != "013001:000005:02" {
+		t.Errorf("読み込んだ順序が不正: %+v", got)
+	}
+	if list := loaded.SceneList(nil); len(list) != 1 || list[0].Sequence.DelayMs != 1500 {
+		t.Errorf("シーン一覧の順序が不正: %+v", list)
+	}
+
+	// ゼロ値で解除、シーンの削除でも消える
+	if err := loaded.SceneSetSequence("おやすみ", SceneSequence{}); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.GetSceneSequence("おやすみ").IsZero() {
+		t.Error("順序が解除されていない")
+	}
+	_ = scenes.SceneDelete("おやすみ")
+	if !scenes.GetSceneSequence("おやすみ").IsZero() {
+		t.Error("削除したシーンの順序が残っている")
+	}
+}
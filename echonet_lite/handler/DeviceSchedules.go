@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schedule は、決まった時刻にプロパティを設定するスケジュールを表す
+// Scene が指定されている場合はシーンを実行し、Actions が指定されている場合はそのプロパティを設定する（両方指定も可）
+type Schedule struct {
+	Name     string        `json:"name"`
+	Cron     string        `json:"cron"` // 分 時 日 月 曜日（例: "0 7 * * 1-5"）または日の出・日の入り（例: "@sunset-30m"）
+	Scene    string        `json:"scene,omitempty"`
+	Actions  []SceneAction `json:"actions,omitempty"`
+	Disabled bool          `json:"disabled,omitempty"`
+}
+
+// Validate は、スケジュールの内容が有効かどうかを検証する
+func (s Schedule) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("スケジュール名が空です")
+	}
+	if strings.ContainsAny(s.Name, " \t\n\r") {
+		return fmt.Errorf("スケジュール名に空白文字を含めることはできません: %s", s.Name)
+	}
+	if _, err := ParseScheduleTrigger(s.Cron); err != nil {
+		return err
+	}
+	if s.Scene == "" && len(s.Actions) == 0 {
+		return fmt.Errorf("スケジュールにはシーンまたはプロパティの指定が必要です: %s", s.Name)
+	}
+	return nil
+}
+
+// NextRun は、after より後の次回実行時刻を返す（無効な場合はゼロ値）
+// 日の出・日の入りのスケジュールは site の地点で計算する（site が nil の場合はゼロ値）
+func (s Schedule) NextRun(after time.Time, site *Coordinates) time.Time {
+	if s.Disabled {
+		return time.Time{}
+	}
+	trigger, err := ParseScheduleTrigger(s.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+	return trigger.Next(after, site)
+}
+
+// DeviceSchedules は、スケジュールを管理する構造体
+type DeviceSchedules struct {
+	schedules map[string]Schedule // スケジュール名 -> スケジュール
+	site      *Coordinates        // 日の出・日の入りを計算する地点（nil の場合は日の出・日の入りのスケジュールを実行しない）
+	mutex     sync.RWMutex
+}
+
+// NewDeviceSchedules は DeviceSchedules の新しいインスタンスを作成する
+func NewDeviceSchedules() *DeviceSchedules {
+	return &DeviceSchedules{
+		schedules: make(map[string]Schedule),
+	}
+}
+
+// LoadFromFile はファイルからスケジュールを読み込む
+func (s *DeviceSchedules) LoadFromFile(filename string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// ファイルが存在しない場合は空のスケジュールリストを作成して終了
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		s.schedules = make(map[string]Schedule)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("スケジュールファイルを開けません: %v", err)
+	}
+
+	var entries []Schedule
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("スケジュールファイルの解析に失敗しました: %v", err)
+	}
+
+	s.schedules = make(map[string]Schedule)
+	for _, entry := range entries {
+		if err := entry.Validate(); err != nil {
+			return fmt.Errorf("スケジュールファイルの内容が不正です: %v", err)
+		}
+		s.schedules[entry.Name] = entry
+	}
+	return nil
+}
+
+// SaveToFile はスケジュールをファイルに保存する
+func (s *DeviceSchedules) SaveToFile(filename string) error {
+	entries := s.ScheduleList()
+
+	// ディレクトリが存在しない場合は作成
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("ディレクトリの作成に失敗しました: %v", err)
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("スケジュールのエンコードに失敗しました: %v", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("スケジュールファイルの書き込みに失敗しました: %v", err)
+	}
+	return nil
+}
+
+// SetCoordinates は、日の出・日の入りを計算する地点を設定する
+func (s *DeviceSchedules) SetCoordinates(site *Coordinates) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if site != nil {
+		copied := *site
+		site = &copied
+	}
+	s.site = site
+}
+
+// Coordinates は、日の出・日の入りを計算する地点を返す（設定されていない場合は nil）
+func (s *DeviceSchedules) Coordinates() *Coordinates {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.site == nil {
+		return nil
+	}
+	site := *s.site
+	return &site
+}
+
+// NextRun は、スケジュールの after より後の次回実行時刻を返す（ない場合はゼロ値）
+func (s *DeviceSchedules) NextRun(schedule Schedule, after time.Time) time.Time {
+	return schedule.NextRun(after, s.Coordinates())
+}
+
+// ScheduleSet はスケジュールを追加する。同じ名前のスケジュールは置き換える
+// 日の出・日の入りのスケジュールは、地点が設定されている場合のみ追加できる
+func (s *DeviceSchedules) ScheduleSet(schedule Schedule) error {
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if trigger, _ := ParseScheduleTrigger(schedule.Cron); trigger.NeedsCoordinates() && s.site == nil {
+		return fmt.Errorf("日の出・日の入りのスケジュールには地点（location の緯度・経度）の設定が必要です: %s", schedule.Name)
+	}
+
+	schedule.Actions = copySceneActions(schedule.Actions)
+	s.schedules[schedule.Name] = schedule
+	return nil
+}
+
+// ScheduleDelete はスケジュールを削除する
+func (s *DeviceSchedules) ScheduleDelete(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.schedules[name]; !exists {
+		return fmt.Errorf("スケジュールが存在しません: %s", name)
+	}
+	delete(s.schedules, name)
+	return nil
+}
+
+// ScheduleSetDisabled はスケジュールの有効・無効を切り替える
+func (s *DeviceSchedules) ScheduleSetDisabled(name string, disabled bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	schedule, exists := s.schedules[name]
+	if !exists {
+		return fmt.Errorf("スケジュールが存在しません: %s", name)
+	}
+	schedule.Disabled = disabled
+	s.schedules[name] = schedule
+	return nil
+}
+
+// ScheduleList はスケジュールを名前順に返す
+func (s *DeviceSchedules) ScheduleList() []Schedule {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]Schedule, 0, len(s.schedules))
+	for _, schedule := range s.schedules {
+		schedule.Actions = copySceneActions(schedule.Actions)
+		result = append(result, schedule)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// GetSchedule はスケジュール名に対応するスケジュールを返す
+func (s *DeviceSchedules) GetSchedule(name string) (Schedule, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	schedule, exists := s.schedules[name]
+	if !exists {
+		return Schedule{}, false
+	}
+	schedule.Actions = copySceneActions(schedule.Actions)
+	return schedule, true
+}
+
+// Count はスケジュールの総数を返す
+func (s *DeviceSchedules) Count() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.schedules)
+}
+
+// DueSchedules は、指定時刻（分単位）に実行すべき有効なスケジュールを返す
+func (s *DeviceSchedules) DueSchedules(t time.Time) []Schedule {
+	site := s.Coordinates()
+	var due []Schedule
+	for _, schedule := range s.ScheduleList() {
+		if schedule.Disabled {
+			continue
+		}
+		trigger, err := ParseScheduleTrigger(schedule.Cron)
+		if err != nil {
+			continue
+		}
+		if trigger.Matches(t, site) {
+			due = append(due, schedule)
+		}
+	}
+	return due
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDeviceSchedules_SetAndPersist(t *testing.T) {
+	schedules := NewDeviceSchedules()
+	wakeup := Schedule{
+		Name:    "wakeup",
+		Cron:    "0 7 * * 1-5",
+		Actions: []SceneAction{{Device: "013001:000005:01", EPC: 0x80, EDT: []byte{0x30}}},
+	}
+	if err := schedules.ScheduleSet(wakeup); err != nil {
+		t.Fatalf("ScheduleSet に失敗: %v", err)
+	}
+	if err := schedules.ScheduleSet(Schedule{Name: "goodnight", Cron: "@daily", Scene: "おやすみ"}); err != nil {
+		t.Fatalf("ScheduleSet に失敗: %v", err)
+	}
+	if err := schedules.ScheduleSetDisabled("goodnight", true); err != nil {
+		t.Fatalf("ScheduleSetDisabled に失敗: %v", err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "schedules.json")
+	if err := schedules.SaveToFile(filename); err != nil {
+		t.Fatalf("SaveToFile に失敗: %v", err)
+	}
+	loaded := NewDeviceSchedules()
+	if err := loaded.LoadFromFile(filename); err != nil {
+		t.Fatalf("LoadFromFile に失敗: %v", err)
+	}
+	list := loaded.ScheduleList()
+	if len(list) != 2 || list[0].Name != "goodnight" || !list[0].Disabled || list[1].Name != "wakeup" || len(list[1].Actions) != 1 {
+		t.Fatalf("読み込んだスケジュールが不正: %+v", list)
+	}
+
+	// 無効なスケジュールは実行対象にならない
+	due := loaded.DueSchedules(time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local))
+	if len(due) != 0 {
+		t.Errorf("無効なスケジュールが実行対象になった: %+v", due)
+	}
+	due = loaded.DueSchedules(time.Date(2026, 10, 19, 7, 0, 0, 0, time.Local))
+	if len(due) != 1 || due[0].Name != "wakeup" {
+		t.Errorf("実行対象のスケジュールが不正: %+v", due)
+	}
+	if next := list[0].NextRun(time.Now(), nil); !next.IsZero() {
+		t.Errorf("無効なスケジュールの次回実行時刻がゼロ値ではない: %v", next)
+	}
+
+	if err := loaded.ScheduleDelete("wakeup"); err != nil {
+		t.Fatalf("ScheduleDelete に失敗: %v", err)
+	}
+	if err := loaded.ScheduleDelete("wakeup"); err == nil {
+		t.Error("存在しないスケジュールの削除がエラーにならない")
+	}
+}
+
+func TestSchedule_Validate(t *testing.T) {
+	for _, schedule := range []Schedule{
+		{Name: "", Cron: "@daily", Scene: "s"},
+		{Name: "a b", Cron: "@daily", Scene: "s"},
+		{Name: "a", Cron: "0 25 * * *", Scene: "s"},
+		{Name: "a", Cron: "@daily"},
+	} {
+		if err := schedule.Validate(); err == nil {
+			t.Errorf("%+v が有効と判定された", schedule)
+		}
+	}
+}
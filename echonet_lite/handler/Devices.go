@@ -166,27 +166,32 @@ func (d *DeviceProperties) UnmarshalJSON(data []byte) error {
 
 // DevicesFileFormat は devices.json ファイルの新しいフォーマットを表します。
 type DevicesFileFormat struct {
-	Version int                         `json:"version"`
-	Data    map[string]DeviceProperties `json:"data"`
+	Version    int                         `json:"version"`
+	Data       map[string]DeviceProperties `json:"data"`
+	Timestamps map[string]time.Time        `json:"timestamps,omitempty"` // デバイスごとの最終更新時刻（古いデバイスの整理に使う）
+	Offline    []string                    `json:"offline,omitempty"`    // オフライン状態のデバイス（IPAndEOJ.Key()、バージョン 2 から）
 }
 
 // currentDevicesFileVersion は現在の devices.json のフォーマットバージョンです。
-const currentDevicesFileVersion = 1
+// バージョン 2 でオフライン状態を保存するようになった。バージョン 1 のファイルはオフライン状態なしで読み込む
+const currentDevicesFileVersion = 2
 
 // DeviceEventType はデバイスイベントの種類を表す型
 type DeviceEventType int
 
 const (
-	DeviceEventAdded   DeviceEventType = iota // デバイスが追加された
-	DeviceEventRemoved                        // デバイスが削除された
-	DeviceEventOffline                        // デバイスがオフラインになった
-	DeviceEventOnline                         // デバイスがオンラインに復旧した
+	DeviceEventAdded        DeviceEventType = iota // デバイスが追加された
+	DeviceEventRemoved                             // デバイスが削除された
+	DeviceEventOffline                             // デバイスがオフラインになった
+	DeviceEventOnline                              // デバイスがオンラインに復旧した
+	DeviceEventReconfigured                        // ノードがインスタンスを振り直し、以前のインスタンスを引き継いだ
 )
 
 // DeviceEvent はデバイスに関するイベントを表す構造体
 type DeviceEvent struct {
-	Device IPAndEOJ        // イベントが発生したデバイス
-	Type   DeviceEventType // イベントの種類
+	Device          IPAndEOJ               // イベントが発生したデバイス
+	Type            DeviceEventType        // イベントの種類
+	Reconfiguration *DeviceReconfiguration // DeviceEventReconfigured の場合の引き継ぎの内容
 }
 
 type DevicesImpl struct {
@@ -196,6 +201,7 @@ type DevicesImpl struct {
 	EventCh        chan DeviceEvent            // デバイスイベント通知用チャンネル
 	offlineDevices map[string]struct{}         // オフライン状態のデバイス (key: IPAndEOJ.Key())
 	saveMu         sync.Mutex                  // ファイル保存操作の排他制御用
+	cipher         *FileCipher                 // ファイルの暗号化（nil の場合は暗号化しない）
 }
 
 type Devices struct {
@@ -213,6 +219,13 @@ func NewDevices() Devices {
 	}
 }
 
+// SetFileCipher はファイルの読み書きに使う暗号化を設定する
+func (d *Devices) SetFileCipher(c *FileCipher) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cipher = c
+}
+
 // SetEventChannel はイベント通知用チャンネルを設定する
 func (d *Devices) SetEventChannel(ch chan DeviceEvent) {
 	d.mu.Lock()
@@ -627,14 +640,24 @@ func (d Devices) SaveToFile(filename string) error {
 	defer d.mu.RUnlock()
 
 	fileData := DevicesFileFormat{
-		Version: currentDevicesFileVersion,
-		Data:    d.data,
+		Version:    currentDevicesFileVersion,
+		Data:       d.data,
+		Timestamps: d.timestamps,
+	}
+	for key := range d.offlineDevices {
+		fileData.Offline = append(fileData.Offline, key)
 	}
+	// ファイルの差分が安定するようにソートする
+	sort.Strings(fileData.Offline)
 
 	jsonData, err := json.Marshal(fileData)
 	if err != nil {
 		return fmt.Errorf("failed to marshal devices data: %w", err)
 	}
+	jsonData, err = d.cipher.Encrypt(jsonData)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt devices data: %w", err)
+	}
 
 	// 一時ファイルに書き込み
 	tempFilename := filename + ".tmp"
@@ -660,62 +683,130 @@ func (d Devices) SaveToFile(filename string) error {
 
 // LoadFromFile loads the Devices data from a file, supporting both new and old JSON formats.
 func (d Devices) LoadFromFile(filename string) error {
+	_, err := d.loadFromFile(filename)
+	return err
+}
+
+// loadFromFile loads the Devices data from a file and returns what was loaded.
+// Nodes whose records cannot be decoded are skipped instead of failing the whole file.
+func (d Devices) loadFromFile(filename string) (fileLoadStats, error) {
+	var stats fileLoadStats
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
 	file, err := os.Open(filename)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil // ファイルが存在しない場合はエラーとしない
+			return stats, nil // ファイルが存在しない場合はエラーとしない
 		}
-		return fmt.Errorf("failed to open file %s: %w", filename, err)
+		return stats, fmt.Errorf("failed to open file %s: %w", filename, err)
 	}
 	defer func() {
 		if err := file.Close(); err != nil {
 			slog.Warn("Error closing file", "filename", filename, "err", err)
 		}
 	}()
+	stats.Exists = true
 
 	data, err := io.ReadAll(file)
 	if err != nil {
-		return fmt.Errorf("failed to read file %s: %w", filename, err)
+		return stats, fmt.Errorf("failed to read file %s: %w", filename, err)
+	}
+	data, err = d.cipher.Decrypt(data)
+	if err != nil {
+		return stats, fmt.Errorf("failed to decrypt file %s: %w", filename, err)
 	}
 
 	// まずバージョン情報を含むかチェックするために一時的なマップにデコード
 	var versionCheck map[string]any
 	if err := json.Unmarshal(data, &versionCheck); err != nil {
 		// JSONとしてパースできない場合はエラー
-		return fmt.Errorf("failed to parse file %s as JSON: %w", filename, err)
+		return stats, fmt.Errorf("failed to parse file %s as JSON: %w", filename, err)
 	}
 
 	if versionVal, ok := versionCheck["version"]; ok {
 		// "version" キーが存在する場合
-		if versionFloat, ok := versionVal.(float64); ok && int(versionFloat) == currentDevicesFileVersion {
-			// バージョンが一致する場合、新しいフォーマットとしてデコード
-			var fileData DevicesFileFormat
+		if versionFloat, ok := versionVal.(float64); ok && (int(versionFloat) == 1 || int(versionFloat) == currentDevicesFileVersion) {
+			// バージョン 1 以降のフォーマットとしてデコード（バージョン 1 には offline がない）
+			version := int(versionFloat)
+			var fileData struct {
+				Data       map[string]json.RawMessage `json:"data"`
+				Timestamps map[string]time.Time       `json:"timestamps,omitempty"`
+				Offline    []string                   `json:"offline,omitempty"`
+			}
 			if err := json.Unmarshal(data, &fileData); err != nil {
-				return fmt.Errorf("failed to unmarshal file %s with version %d: %w", filename, currentDevicesFileVersion, err)
+				return stats, fmt.Errorf("failed to unmarshal file %s with version %d: %w", filename, version, err)
 			}
-			d.data = fileData.Data
-			// TODO: タイムスタンプの復元ロジックが必要な場合はここに追加
-			d.timestamps = make(map[string]time.Time) // タイムスタンプは一旦リセット
-			return nil
+			stats.Version = version
+			stats.Migrated = version != currentDevicesFileVersion
+			d.data = decodeDeviceNodes(filename, fileData.Data, &stats)
+			// 最終更新時刻を復元する（記録のない古いファイルでは空のまま）
+			d.timestamps = make(map[string]time.Time)
+			for key, ts := range fileData.Timestamps {
+				d.timestamps[key] = ts
+			}
+			// オフライン状態を復元する。読み込めなかったデバイスの記録は捨てる
+			d.offlineDevices = make(map[string]struct{})
+			keys := d.deviceKeysNoLock()
+			for _, key := range fileData.Offline {
+				if _, ok := keys[key]; ok {
+					d.offlineDevices[key] = struct{}{}
+				}
+			}
+			return stats, nil
 		}
 		// バージョンが不一致の場合はエラーまたはフォールバック処理
 		// ここでは古いバージョンとして扱うことにする（下の処理に流れる）
-		fmt.Printf("Warning: File %s has version %v, expected %d. Attempting to load as old format.\n", filename, versionVal, currentDevicesFileVersion)
+		slog.Warn("Unexpected devices file version. Attempting to load as old format", "file", filename, "version", versionVal, "expected", currentDevicesFileVersion)
+		if versionFloat, ok := versionVal.(float64); ok {
+			stats.Version = int(versionFloat)
+		}
 	}
 
 	// "version" キーが存在しない、またはバージョンが不一致の場合、古いフォーマットとしてデコード
-	var oldData map[string]DeviceProperties
+	var oldData map[string]json.RawMessage
 	if err := json.Unmarshal(data, &oldData); err != nil {
-		return fmt.Errorf("failed to unmarshal file %s as old format: %w", filename, err)
+		return stats, fmt.Errorf("failed to unmarshal file %s as old format: %w", filename, err)
 	}
-	d.data = oldData
-	// TODO: タイムスタンプの復元ロジックが必要な場合はここに追加
-	d.timestamps = make(map[string]time.Time) // タイムスタンプは一旦リセット
+	stats.Migrated = true
+	d.data = decodeDeviceNodes(filename, oldData, &stats)
+	// 古いフォーマットには最終更新時刻もオフライン状態もない
+	d.timestamps = make(map[string]time.Time)
+	d.offlineDevices = make(map[string]struct{})
 
-	return nil
+	return stats, nil
+}
+
+// deviceKeysNoLock は全デバイスの IPAndEOJ.Key() 形式のキーの集合を返します（ロックなし版）
+func (d Devices) deviceKeysNoLock() map[string]struct{} {
+	keys := make(map[string]struct{})
+	for ipStr, eojs := range d.data {
+		ip := net.ParseIP(ipStr)
+		for eoj := range eojs {
+			keys[IPAndEOJ{IP: ip, EOJ: eoj}.Key()] = struct{}{}
+		}
+	}
+	return keys
+}
+
+// decodeDeviceNodes decodes the devices of each node, skipping the nodes that cannot be decoded.
+// A version key left in an old format file is not a node.
+func decodeDeviceNodes(filename string, nodes map[string]json.RawMessage, stats *fileLoadStats) map[string]DeviceProperties {
+	result := make(map[string]DeviceProperties, len(nodes))
+	for ip, raw := range nodes {
+		if ip == "version" && stats.Migrated {
+			continue
+		}
+		var props DeviceProperties
+		if err := json.Unmarshal(raw, &props); err != nil {
+			slog.Warn("Skipping unreadable devices of a node", "file", filename, "ip", ip, "err", err)
+			stats.Skipped++
+			continue
+		}
+		result[ip] = props
+		stats.Records += len(props)
+	}
+	return result
 }
 
 func (h Devices) Len() int {
@@ -870,6 +961,13 @@ func (d DeviceProperties) Set(eoj EOJ, properties ...Property) error {
 }
 
 func (d DeviceProperties) Get(eoj EOJ, epc EPCType) (Property, bool) {
+	// Get/Set プロパティマップは、保持している場合（LocalDevices で変更した場合）はその値を返す
+	if epc == echonet_lite.EPCGetPropertyMap || epc == echonet_lite.EPCSetPropertyMap {
+		if prop, ok := d[eoj][epc]; ok {
+			return prop, true
+		}
+	}
+
 	if epc == echonet_lite.EPCGetPropertyMap {
 		// GetPropertyMap は特別なプロパティで、全てのプロパティを含むプロパティマップを返す
 		propertyMap := make(PropertyMap)
@@ -935,12 +1033,21 @@ func (d DeviceProperties) GetProperties(eoj EOJ, properties Properties) (Propert
 	result := make([]Property, 0, len(properties))
 	success := true
 
+	// Get プロパティマップを変更している場合は、含まれないプロパティは取得できない
+	var getPropertyMap PropertyMap
+	if p, ok := d[eoj][echonet_lite.EPCGetPropertyMap]; ok {
+		getPropertyMap = echonet_lite.DecodePropertyMap(p.EDT)
+	}
+
 	for _, p := range properties {
 		rep := Property{
 			EPC: p.EPC,
 			EDT: []byte{}, // empty
 		}
 		prop, ok := d.Get(eoj, p.EPC)
+		if getPropertyMap != nil && !getPropertyMap.Has(p.EPC) {
+			ok = false
+		}
 		if !ok {
 			success = false
 		} else {
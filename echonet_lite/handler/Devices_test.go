@@ -787,6 +787,71 @@ func TestDevices_OfflineStatus(t *testing.T) {
 	}
 }
 
+// TestDevices_SaveLoadOfflineState は最終更新時刻とオフライン状態が再起動後も復元されることをテストします
+func TestDevices_SaveLoadOfflineState(t *testing.T) {
+	tempFile := t.TempDir() + "/devices.json"
+	ip := net.ParseIP("192.168.1.50")
+	online := IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	offline := IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 2)}
+	updated := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+
+	devices := NewDevices()
+	devices.RegisterProperty(online, Property{EPC: 0x80, EDT: []byte{0x30}}, updated)
+	devices.RegisterProperty(offline, Property{EPC: 0x80, EDT: []byte{0x31}}, updated)
+	devices.SetOffline(offline, true)
+	if err := devices.SaveToFile(tempFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+
+	loaded := NewDevices()
+	stats, err := loaded.loadFromFile(tempFile)
+	if err != nil {
+		t.Fatalf("loadFromFile failed: %v", err)
+	}
+	if stats.Version != currentDevicesFileVersion || stats.Migrated {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if !loaded.IsOffline(offline) || loaded.IsOffline(online) {
+		t.Errorf("offline state was not restored: online=%v offline=%v", loaded.IsOffline(online), loaded.IsOffline(offline))
+	}
+	if got := loaded.GetLastUpdateTime(online); !got.Equal(updated) {
+		t.Errorf("last update time = %v, want %v", got, updated)
+	}
+
+	// バージョン 1 のファイルはオフライン状態なしで読み込み、次の保存で移行する
+	v1 := []byte(`{"version":1,"data":{"192.168.1.50":{"0130:1":{"0x80":"MA=="}}},"timestamps":{"` + online.Key() + `":"2026-10-17T09:30:00Z"}}`)
+	if err := os.WriteFile(tempFile, v1, 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded = NewDevices()
+	stats, err = loaded.loadFromFile(tempFile)
+	if err != nil {
+		t.Fatalf("loadFromFile failed for version 1: %v", err)
+	}
+	if stats.Version != 1 || !stats.Migrated || stats.Records != 1 {
+		t.Errorf("unexpected stats for version 1: %+v", stats)
+	}
+	if !loaded.IsKnownDevice(online) || loaded.IsOffline(online) {
+		t.Errorf("version 1 device was not loaded as online")
+	}
+	if got := loaded.GetLastUpdateTime(online); !got.Equal(updated) {
+		t.Errorf("last update time from version 1 = %v, want %v", got, updated)
+	}
+
+	// 読み込めなかったデバイスのオフライン状態は捨てる
+	unknown := []byte(`{"version":2,"data":{"192.168.1.50":{"0130:1":{"0x80":"MA=="}}},"offline":["192.168.1.99 0130:1"]}`)
+	if err := os.WriteFile(tempFile, unknown, 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded = NewDevices()
+	if err := loaded.LoadFromFile(tempFile); err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	if loaded.IsOffline(IPAndEOJ{IP: net.ParseIP("192.168.1.99"), EOJ: online.EOJ}) {
+		t.Error("offline state of an unknown device was restored")
+	}
+}
+
 // TestDevices_SetOfflineEvents はオフライン/オンラインイベントの送信をテストします
 func TestDevices_SetOfflineEvents(t *testing.T) {
 	// イベントチャンネルを作成
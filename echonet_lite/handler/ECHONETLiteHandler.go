@@ -4,7 +4,9 @@ import (
 	"context"
 	"echonet-list/echonet_lite"
 	"echonet-list/echonet_lite/network"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"time"
@@ -17,24 +19,78 @@ type ECHONETLiteHandler struct {
 	comm             *CommunicationHandler           // 通信機能
 	data             *DataManagementHandler          // データ管理機能
 	historyFilePath  string                          // 履歴ファイルパス
+	statsFilePath    string                          // プロパティ変化統計ファイルパス
+	memoryLimits     MemoryLimits                    // インメモリストアのソフト上限
+	integrityOptions IntegrityOptions                // 保存ファイルの整合性チェックの設定
+	livenessOptions  LivenessOptions                 // 応答のないノードへの問い合わせの設定
+	pruneAfter       time.Duration                   // この期間更新のないデバイスを自動的に削除する（0 の場合は削除しない）
 	PropertyChangeCh chan PropertyChangeNotification // プロパティ変化通知用チャネル
+	FileReloadCh     chan ReloadNotification         // 外部で編集されたエイリアス・グループファイルの再読み込み通知用チャネル
+	PersistenceCh    chan PersistenceStatus          // デバイス情報の保存に失敗し始めたとき・回復したときの通知用チャネル
+	logger           *slog.Logger                    // このインスタンスのログ出力先
+	startTime        time.Time                       // 作成した時刻（最終更新時刻の記録がないデバイスはこの時刻に見えていたとみなす）
+	instanceLock     *instanceLock                   // データファイルを他のインスタンスと共有しないためのロック
+	local            *LocalDevices                   // 自ノードが所有するデバイス
+	localMapsFile    string                          // 変更したプロパティマップの保存先（空の場合は保存しない）
+	startupReport    StartupReport                   // 起動時に保存ファイルから復元した状態の要約
 }
 
 type ECHONETLieHandlerOptions struct {
 	IP                   net.IP                        // 自ノードのIPアドレス, nilの場合はワイルドカード
+	ReplyIP              net.IP                        // 送信元として使うアドレス（NAT/ブリッジ環境向け）。nilの場合はOSが選択
 	Debug                bool                          // デバッグモード
 	ManufacturerCode     string                        // echonet_lite.ManufacturerCodeEDT のキーのいずれか。省略時は Experimental
 	UniqueIdentifier     []byte                        // 13バイトのユニーク識別子, nilの場合はMACアドレスから生成
 	NetworkMonitorConfig *network.NetworkMonitorConfig // ネットワーク監視設定
+	DiscoveryInterfaces  []string                      // 並列検出に使うインターフェース名（空の場合は単一のブロードキャストで検出）
+	DiscoveryBroadcast   bool                          // インターフェースごとの検出でマルチキャストの代わりにブロードキャストを使う
+	IPVersion            network.IPVersion             // 通信に使うIPのバージョン（空の場合は IPv4）
+	PreferIPVersion      network.IPVersion             // デュアルスタックで同じノードが両方から応答した場合に採用するIPのバージョン（空の場合は IPv4）
+	Strict               bool                          // 仕様に厳密に従う（認証試験向け）。仕様に従わない電文を破棄し、SetPropertyMap にないプロパティは設定しない
 	// カスタムファイルパス（空文字の場合はデフォルトファイルを使用）
-	DevicesFile          string // デバイスファイルパス
-	AliasesFile          string // エイリアスファイルパス
-	GroupsFile           string // グループファイルパス
-	LocationSettingsFile string // ロケーション設定ファイルパス
+	DevicesFile           string // デバイスファイルパス
+	AliasesFile           string // エイリアスファイルパス
+	GroupsFile            string // グループファイルパス
+	LocationSettingsFile  string // ロケーション設定ファイルパス
+	ScenesFile            string // シーンファイルパス
+	SchedulesFile         string // スケジュールファイルパス
+	MetadataFile          string // デバイスのメタデータファイルパス
+	PreferencesFile       string // クライアントの表示設定ファイルパス
+	LocalPropertyMapsFile string // 自ノードのデバイスの変更したプロパティマップのファイルパス
+	StatsFile             string // プロパティ変化統計ファイルパス
 	// 履歴設定
 	HistoryOptions HistoryOptions // 履歴ストアのオプション
+	// インメモリストアのソフト上限（ゼロ値の場合は上限なし）
+	MemoryLimits MemoryLimits
+	// 保存ファイルのチェックサム検証とバックアップからの復元（ゼロ値の場合は無効）
+	Integrity IntegrityOptions
+	// 応答のないノードへの定期的な問い合わせ（ゼロ値の場合は無効）
+	Liveness LivenessOptions
+	// デバイス情報の保存をまとめる間隔と古いデバイスの自動削除（ゼロ値の場合は変更のたびに保存し、削除しない）
+	Persistence PersistenceOptions
+	// 要求の送信の速さと同時に応答を待つ要求の数の制限（ゼロ値の場合は制限なし）
+	OutboundLimits OutboundLimits
+	// 1つの要求フレームに含めるプロパティの上限（ゼロ値の場合は OPC の上限のみ）
+	FrameLimits FrameLimits
+	// 応答のない要求の再送設定（ゼロ値の場合は DefaultMaxRetries, DefaultRetryInterval）
+	Retry RetryOptions
+	// デバイス情報と履歴ファイルの暗号化（nilの場合は暗号化しない）
+	Cipher *FileCipher
+	// 日の出・日の入りのスケジュールを計算する地点（nilの場合は日の出・日の入りのスケジュールを使えない）
+	Coordinates *Coordinates
+	// 通信に使う接続（nilの場合はUDPで接続する）。デモモードでは模擬ネットワークを指定する
+	Connection network.Connection
+	// 実際のノードと合わせて通信する仮想ノード（nilの場合はなし）。天気の仮想デバイスなどに使う
+	VirtualNodes network.VirtualConnection
+	// ファイルの読み書きを行わない（デモモード用）。デバイス・エイリアス・履歴などはメモリ上にのみ保持する
+	InMemory bool
 	// テスト用設定（CI環境での実行時にファイルアクセスやネットワーク通信を避ける）
 	TestMode bool // テストモード（ファイル読み込みとネットワーク通信を無効化）
+	// このインスタンスのログ出力先（nilの場合は slog.Default()）
+	// 1つのプロセスで複数のインスタンスを動かす場合に、インスタンスごとに属性を付けたロガーを指定する
+	Logger *slog.Logger
+	// ECHONET Lite の UDP ポート番号（0の場合は3610）。テストなどで複数のインスタンスを動かす場合に指定する
+	Port int
 }
 
 // getFileOrDefault は、カスタムファイル名が空文字の場合にデフォルトファイル名を返す
@@ -55,10 +111,10 @@ type OfflineManager interface {
 // handleDeviceTimeout processes device timeout events based on device type
 // For NodeProfile: marks all devices with the same IP as offline
 // For other devices: marks as offline only if the NodeProfile is already offline
-func handleDeviceTimeout(device IPAndEOJ, manager OfflineManager) {
+func handleDeviceTimeout(device IPAndEOJ, manager OfflineManager, logger *slog.Logger) {
 	// NodeProfileの場合は、そのIPの全デバイスをオフラインに
 	if device.EOJ.ClassCode() == echonet_lite.NodeProfile_ClassCode {
-		slog.Info("NodeProfileタイムアウト: IPの全デバイスをオフラインに設定", "ip", device.IP)
+		logger.Info("NodeProfileタイムアウト: IPの全デバイスをオフラインに設定", "ip", device.IP)
 		manager.SetOfflineByIP(device.IP, true)
 	} else {
 		// NodeProfile以外の場合、NodeProfileがオフラインの場合のみオフラインに
@@ -67,10 +123,10 @@ func handleDeviceTimeout(device IPAndEOJ, manager OfflineManager) {
 			EOJ: echonet_lite.NodeProfileObject,
 		}
 		if manager.IsOffline(nodeProfile) {
-			slog.Info("デバイスタイムアウト: NodeProfileがオフラインのため、デバイスをオフラインに設定", "device", device.Specifier())
+			logger.Info("デバイスタイムアウト: NodeProfileがオフラインのため、デバイスをオフラインに設定", "device", device.Specifier())
 			manager.SetOffline(device, true)
 		} else {
-			slog.Info("デバイスタイムアウト: NodeProfileがオンラインのため、オフライン設定をスキップ", "device", device.Specifier())
+			logger.Info("デバイスタイムアウト: NodeProfileがオンラインのため、オフライン設定をスキップ", "device", device.Specifier())
 		}
 	}
 }
@@ -89,7 +145,7 @@ func handleDeviceOnline(device IPAndEOJ, handler *ECHONETLiteHandler) {
 		// NodeProfileがオフラインの場合、直接オンラインに設定
 		// （同じIPからINFを受信できたということは、NodeProfileも生きている）
 		if handler.IsOffline(nodeProfile) {
-			slog.Info("デバイスオンライン: NodeProfileも同時にオンラインに設定", "device", device.Specifier(), "nodeProfile", nodeProfile.Specifier())
+			handler.log().Info("デバイスオンライン: NodeProfileも同時にオンラインに設定", "device", device.Specifier(), "nodeProfile", nodeProfile.Specifier())
 			handler.GetDataManagementHandler().SetOffline(nodeProfile, false)
 		}
 	}
@@ -99,10 +155,14 @@ func handleDeviceOnline(device IPAndEOJ, handler *ECHONETLiteHandler) {
 func NewECHONETLiteHandler(ctx context.Context, options ECHONETLieHandlerOptions) (*ECHONETLiteHandler, error) {
 	// タイムアウト付きのコンテキストを作成
 	handlerCtx, cancel := context.WithCancel(ctx)
+	logger := loggerOrDefault(options.Logger)
 
 	// Controller Object
 	seoj := echonet_lite.MakeEOJ(echonet_lite.Controller_ClassCode, 1)
 
+	// ファイルの読み書きを行うかどうか
+	skipFiles := options.TestMode || options.InMemory
+
 	// デバイス情報を管理するオブジェクトを作成
 	devices := NewDevices()
 
@@ -110,74 +170,223 @@ func NewECHONETLiteHandler(ctx context.Context, options ECHONETLieHandlerOptions
 	deviceEventCh := make(chan DeviceEvent, 100)
 	// Devicesにイベントチャンネルを設定
 	devices.SetEventChannel(deviceEventCh)
+	devices.SetFileCipher(options.Cipher)
+
+	// 同じデータファイルを使う別のインスタンスがいないことを確認（テストモード・メモリ上のみの場合は省略）
+	// 2つのプロセスが devices.json などを書き込むと互いの内容を上書きしてしまうため、読み込む前にロックする
+	var lock *instanceLock
+	if !skipFiles {
+		lockPath := getFileOrDefault(options.DevicesFile, DeviceFileName) + instanceLockSuffix
+		var err error
+		lock, err = acquireInstanceLock(lockPath)
+		if err != nil {
+			cancel() // エラーの場合はコンテキストをキャンセル
+			logger.Error("データファイルのロックに失敗", "file", lockPath, "error", err)
+			return nil, err
+		}
+		// 作成に失敗した場合はロックを解放する（成功した場合はハンドラーが保持する）
+		defer func() {
+			if lock != nil {
+				_ = lock.release()
+			}
+		}()
+	}
 
-	// デバイス情報を読み込む（テストモードでは省略）
-	if !options.TestMode {
-		devicesFile := getFileOrDefault(options.DevicesFile, DeviceFileName)
-		slog.Info("デバイスファイルを使用", "file", devicesFile)
-		err := devices.LoadFromFile(devicesFile)
+	// 起動時に保存ファイルから復元した状態の要約
+	var report StartupReport
+
+	// 保存ファイルの整合性チェック（テストモード・メモリ上のみの場合は省略）
+	// 読み込む前に検証し、破損している場合はバックアップから復元する
+	var integrity *IntegrityChecker
+	if options.Integrity.Enabled && !skipFiles {
+		integrity = NewIntegrityChecker(options.Integrity.Backups, logger)
+		integrity.Register(getFileOrDefault(options.DevicesFile, DeviceFileName), options.Cipher.validateWith(validateJSON), false)
+		integrity.Register(getFileOrDefault(options.AliasesFile, DeviceAliasesFileName), func(data []byte) error {
+			_, err := decodeAliases(data)
+			return err
+		}, true)
+		integrity.Register(getFileOrDefault(options.GroupsFile, DeviceGroupsFileName), func(data []byte) error {
+			_, err := decodeGroups(data)
+			return err
+		}, true)
+	}
+
+	// デバイス情報を読み込む（テストモード・メモリ上のみの場合は省略）
+	devicesFile := ""
+	if !skipFiles {
+		devicesFile = getFileOrDefault(options.DevicesFile, DeviceFileName)
+		logger.Info("デバイスファイルを使用", "file", devicesFile)
+		restored := integrity.CheckOnLoad(devicesFile)
+		stats, err := devices.loadFromFile(devicesFile)
 		if err != nil {
 			cancel() // エラーの場合はコンテキストをキャンセル
-			slog.Error("デバイス情報の読み込みに失敗", "file", devicesFile, "error", err)
+			logger.Error("デバイス情報の読み込みに失敗", "file", devicesFile, "error", err)
 			return nil, fmt.Errorf("デバイス情報の読み込みに失敗 (file: %s): %w", devicesFile, err)
 		}
-		slog.Info("デバイス情報の読み込み完了", "file", devicesFile, "deviceCount", devices.CountAll())
+		report.add("devices", devicesFile, stats, restored, nil)
+		logger.Info("デバイス情報の読み込み完了", "file", devicesFile, "deviceCount", devices.CountAll())
 	}
 
 	aliases := NewDeviceAliases()
 
-	// エイリアス情報を読み込む（テストモードでは省略）
-	if !options.TestMode {
-		aliasesFile := getFileOrDefault(options.AliasesFile, DeviceAliasesFileName)
-		slog.Info("エイリアスファイルを使用", "file", aliasesFile)
-		err := aliases.LoadFromFile(aliasesFile)
+	// エイリアス情報を読み込む（テストモード・メモリ上のみの場合は省略）
+	aliasesFile := ""
+	if !skipFiles {
+		aliasesFile = getFileOrDefault(options.AliasesFile, DeviceAliasesFileName)
+		logger.Info("エイリアスファイルを使用", "file", aliasesFile)
+		restored := integrity.CheckOnLoad(aliasesFile)
+		stats, err := aliases.loadFromFile(aliasesFile)
 		if err != nil {
 			cancel() // エラーの場合はコンテキストをキャンセル
-			slog.Error("エイリアス情報の読み込みに失敗", "file", aliasesFile, "error", err)
+			logger.Error("エイリアス情報の読み込みに失敗", "file", aliasesFile, "error", err)
 			return nil, fmt.Errorf("エイリアス情報の読み込みに失敗 (file: %s): %w", aliasesFile, err)
 		}
-		slog.Info("エイリアス情報の読み込み完了", "file", aliasesFile, "aliasCount", aliases.Count())
+		report.add("aliases", aliasesFile, stats, restored, nil)
+		logger.Info("エイリアス情報の読み込み完了", "file", aliasesFile, "aliasCount", aliases.Count())
 	}
 
 	groups := NewDeviceGroups()
 
-	// グループ情報を読み込む（テストモードでは省略）
-	if !options.TestMode {
-		groupsFile := getFileOrDefault(options.GroupsFile, DeviceGroupsFileName)
-		slog.Info("グループファイルを使用", "file", groupsFile)
-		err := groups.LoadFromFile(groupsFile)
+	// グループ情報を読み込む（テストモード・メモリ上のみの場合は省略）
+	groupsFile := ""
+	if !skipFiles {
+		groupsFile = getFileOrDefault(options.GroupsFile, DeviceGroupsFileName)
+		logger.Info("グループファイルを使用", "file", groupsFile)
+		restored := integrity.CheckOnLoad(groupsFile)
+		stats, err := groups.loadFromFile(groupsFile)
 		if err != nil {
 			cancel() // エラーの場合はコンテキストをキャンセル
-			slog.Error("グループ情報の読み込みに失敗", "file", groupsFile, "error", err)
+			logger.Error("グループ情報の読み込みに失敗", "file", groupsFile, "error", err)
 			return nil, fmt.Errorf("グループ情報の読み込みに失敗 (file: %s): %w", groupsFile, err)
 		}
-		slog.Info("グループ情報の読み込み完了", "file", groupsFile, "groupCount", groups.Count())
+		report.add("groups", groupsFile, stats, restored, nil)
+		logger.Info("グループ情報の読み込み完了", "file", groupsFile, "groupCount", groups.Count())
 	}
 
 	locationSettings := NewLocationSettings()
 
-	// ロケーション設定を読み込む（テストモードでは省略）
-	if !options.TestMode {
+	// ロケーション設定を読み込む（テストモード・メモリ上のみの場合は省略）
+	if !skipFiles {
 		locationSettingsFile := getFileOrDefault(options.LocationSettingsFile, LocationSettingsFileName)
-		slog.Info("ロケーション設定ファイルを使用", "file", locationSettingsFile)
+		logger.Info("ロケーション設定ファイルを使用", "file", locationSettingsFile)
 		err := locationSettings.LoadFromFile(locationSettingsFile)
 		if err != nil {
 			cancel() // エラーの場合はコンテキストをキャンセル
-			slog.Error("ロケーション設定の読み込みに失敗", "file", locationSettingsFile, "error", err)
+			logger.Error("ロケーション設定の読み込みに失敗", "file", locationSettingsFile, "error", err)
 			return nil, fmt.Errorf("ロケーション設定の読み込みに失敗 (file: %s): %w", locationSettingsFile, err)
 		}
-		slog.Info("ロケーション設定の読み込み完了", "file", locationSettingsFile, "aliasCount", locationSettings.Aliases.Count())
+		logger.Info("ロケーション設定の読み込み完了", "file", locationSettingsFile, "aliasCount", locationSettings.Aliases.Count())
+	}
+
+	scenes := NewDeviceScenes()
+	scenesFile := ""
+
+	// シーン情報を読み込む（テストモード・メモリ上のみの場合は省略）
+	if !skipFiles {
+		scenesFile = getFileOrDefault(options.ScenesFile, DeviceScenesFileName)
+		logger.Info("シーンファイルを使用", "file", scenesFile)
+		if err := scenes.LoadFromFile(scenesFile); err != nil {
+			cancel() // エラーの場合はコンテキストをキャンセル
+			logger.Error("シーン情報の読み込みに失敗", "file", scenesFile, "error", err)
+			return nil, fmt.Errorf("シーン情報の読み込みに失敗 (file: %s): %w", scenesFile, err)
+		}
+		logger.Info("シーン情報の読み込み完了", "file", scenesFile, "sceneCount", scenes.Count())
+	}
+
+	schedules := NewDeviceSchedules()
+	schedules.SetCoordinates(options.Coordinates)
+	schedulesFile := ""
+
+	// スケジュールを読み込む（テストモード・メモリ上のみの場合は省略）
+	if !skipFiles {
+		schedulesFile = getFileOrDefault(options.SchedulesFile, SchedulesFileName)
+		logger.Info("スケジュールファイルを使用", "file", schedulesFile)
+		if err := schedules.LoadFromFile(schedulesFile); err != nil {
+			cancel() // エラーの場合はコンテキストをキャンセル
+			logger.Error("スケジュールの読み込みに失敗", "file", schedulesFile, "error", err)
+			return nil, fmt.Errorf("スケジュールの読み込みに失敗 (file: %s): %w", schedulesFile, err)
+		}
+		logger.Info("スケジュールの読み込み完了", "file", schedulesFile, "scheduleCount", schedules.Count())
+		if options.Coordinates == nil {
+			for _, schedule := range schedules.ScheduleList() {
+				if trigger, err := ParseScheduleTrigger(schedule.Cron); err == nil && trigger.NeedsCoordinates() {
+					logger.Warn("地点が設定されていないため、日の出・日の入りのスケジュールは実行されません", "schedule", schedule.Name, "cron", schedule.Cron)
+				}
+			}
+		}
+	}
+
+	metadata := NewDeviceMetadataStore()
+	metadataFile := ""
+
+	// デバイスのメタデータを読み込む（テストモード・メモリ上のみの場合は省略）
+	if !skipFiles {
+		metadataFile = getFileOrDefault(options.MetadataFile, MetadataFileName)
+		logger.Info("メタデータファイルを使用", "file", metadataFile)
+		if err := metadata.LoadFromFile(metadataFile); err != nil {
+			cancel() // エラーの場合はコンテキストをキャンセル
+			logger.Error("メタデータの読み込みに失敗", "file", metadataFile, "error", err)
+			return nil, fmt.Errorf("メタデータの読み込みに失敗 (file: %s): %w", metadataFile, err)
+		}
+		logger.Info("メタデータの読み込み完了", "file", metadataFile, "deviceCount", metadata.Count())
+	}
+
+	preferences := NewClientPreferencesStore()
+	preferencesFile := ""
+
+	// クライアントの表示設定を読み込む（テストモード・メモリ上のみの場合は省略）
+	if !skipFiles {
+		preferencesFile = getFileOrDefault(options.PreferencesFile, PreferencesFileName)
+		logger.Info("表示設定ファイルを使用", "file", preferencesFile)
+		if err := preferences.LoadFromFile(preferencesFile); err != nil {
+			cancel() // エラーの場合はコンテキストをキャンセル
+			logger.Error("表示設定の読み込みに失敗", "file", preferencesFile, "error", err)
+			return nil, fmt.Errorf("表示設定の読み込みに失敗 (file: %s): %w", preferencesFile, err)
+		}
+		logger.Info("表示設定の読み込み完了", "file", preferencesFile, "clientCount", preferences.Count())
+	}
+
+	// 履歴バックエンドの指定を検証（セッション作成前に行う）
+	switch options.HistoryOptions.Backend {
+	case "", HistoryBackendMemory, HistoryBackendJournal:
+	default:
+		cancel() // エラーの場合はコンテキストをキャンセル
+		return nil, fmt.Errorf("不明な履歴バックエンド: %s", options.HistoryOptions.Backend)
+	}
+
+	if err := options.Retry.Validate(); err != nil {
+		cancel()
+		return nil, err
 	}
 
 	// 自ノードのセッションを作成（テストモードでは省略）
 	var session *Session
 	var err error
-	if !options.TestMode {
-		session, err = CreateSession(handlerCtx, options.IP, seoj, options.Debug, options.NetworkMonitorConfig, devices.IsOffline)
+	if !options.TestMode && options.Connection != nil {
+		session = CreateSessionWithConnection(handlerCtx, options.Connection, seoj, options.Debug, devices.IsOffline)
+	} else if !options.TestMode {
+		session, err = CreateSession(handlerCtx, options.IP, options.Port, options.IPVersion, seoj, options.Debug, options.NetworkMonitorConfig, devices.IsOffline)
 		if err != nil {
 			cancel() // エラーの場合はコンテキストをキャンセル
 			return nil, fmt.Errorf("接続に失敗: %w", err)
 		}
+		if options.ReplyIP != nil {
+			if err := session.SetReplyAddress(options.ReplyIP); err != nil {
+				_ = session.Close()
+				cancel()
+				return nil, fmt.Errorf("応答アドレスの設定に失敗: %w", err)
+			}
+		}
+	}
+	if session != nil {
+		session.SetLogger(logger)
+		session.SetOutboundLimits(options.OutboundLimits)
+		session.SetFrameLimits(options.FrameLimits)
+		session.Strict = options.Strict
+		session.SetRetryOptions(options.Retry)
+		if options.VirtualNodes != nil {
+			session.AddVirtualNodes(options.VirtualNodes)
+		}
 	}
 
 	localDevices := make(DeviceProperties)
@@ -214,7 +423,7 @@ func NewECHONETLiteHandler(ctx context.Context, options ECHONETLieHandlerOptions
 		ManufacturerCode: manufacturerCodeEDT,
 		UniqueIdentifier: uniqueIdentifier,
 	}
-	slog.Info("ユニーク識別子", "identificationNumber", identificationNumber.String())
+	logger.Info("ユニーク識別子", "identificationNumber", identificationNumber.String())
 
 	commonProps := []Property{
 		operationStatusOn,
@@ -268,6 +477,18 @@ func NewECHONETLiteHandler(ctx context.Context, options ECHONETLieHandlerOptions
 		return nil, err
 	}
 
+	// 実行時に変更したプロパティマップを読み込む（テストモード・メモリ上のみの場合は省略）
+	local := NewLocalDevices(localDevices)
+	localPropertyMapsFile := ""
+	if !skipFiles {
+		localPropertyMapsFile = getFileOrDefault(options.LocalPropertyMapsFile, LocalPropertyMapsFileName)
+		if err := local.LoadFromFile(localPropertyMapsFile); err != nil {
+			cancel()
+			logger.Error("プロパティマップの読み込みに失敗", "file", localPropertyMapsFile, "error", err)
+			return nil, fmt.Errorf("プロパティマップの読み込みに失敗 (file: %s): %w", localPropertyMapsFile, err)
+		}
+	}
+
 	// セッションのタイムアウト通知チャンネルを作成
 	sessionTimeoutCh := make(chan SessionTimeoutEvent, 100)
 
@@ -277,42 +498,142 @@ func NewECHONETLiteHandler(ctx context.Context, options ECHONETLieHandlerOptions
 	}
 
 	// 各ハンドラを初期化
-	core := NewHandlerCore(handlerCtx, cancel, options.Debug)
+	core := NewHandlerCore(handlerCtx, cancel, options.Debug, logger)
 
-	// 履歴ストアを作成
+	// 履歴ストアを作成（無効化されている場合は作成せず、ファイルの読み書きも行わない）
 	historyOpts := options.HistoryOptions
 	if historyOpts.PerDeviceSettableLimit == 0 && historyOpts.PerDeviceNonSettableLimit == 0 {
 		// オプションが指定されていない場合はデフォルトを使用
 		historyOpts = DefaultHistoryOptions()
-		// HistoryFilePathだけは引き継ぐ
+		// ファイルパス、無効化、バックエンドの設定は引き継ぐ
 		historyOpts.HistoryFilePath = options.HistoryOptions.HistoryFilePath
+		historyOpts.Disabled = options.HistoryOptions.Disabled
+		historyOpts.Backend = options.HistoryOptions.Backend
+		historyOpts.JournalFilePath = options.HistoryOptions.JournalFilePath
+		historyOpts.Retention = options.HistoryOptions.Retention
+	}
+	historyOpts.Cipher = options.Cipher
+	var history DeviceHistoryStore
+	journal := false
+	switch {
+	case historyOpts.Disabled:
+		logger.Info("履歴機能は無効化されています")
+		historyOpts.HistoryFilePath = ""
+	case historyOpts.Backend == HistoryBackendJournal && !skipFiles:
+		// ジャーナルは記録の都度ファイルに追記するため、起動時の読み込みは不要
+		// Close時の SaveToFile でコンパクションを行うため、historyFilePath にジャーナルのパスを設定する
+		journalFile := getFileOrDefault(historyOpts.JournalFilePath, HistoryJournalFileName)
+		logger.Info("履歴ジャーナルを使用", "file", journalFile, "retention", historyOpts.Retention)
+		store, err := NewJournalDeviceHistoryStore(journalFile, historyOpts.Retention, historyOpts.Cipher)
+		if err != nil {
+			if session != nil {
+				_ = session.Close()
+			}
+			cancel()
+			return nil, fmt.Errorf("履歴ジャーナルのオープンに失敗: %w", err)
+		}
+		history = store
+		historyOpts.HistoryFilePath = journalFile
+		journal = true
+		report.add("history", journalFile, store.(*journalDeviceHistoryStore).loaded, false, nil)
+	default:
+		history = NewMemoryDeviceHistoryStore(historyOpts)
+	}
+
+	// メモリ上のみの場合は履歴ファイルの読み書きを行わない
+	if options.InMemory {
+		historyOpts.HistoryFilePath = ""
 	}
-	history := NewMemoryDeviceHistoryStore(historyOpts)
 
 	// 履歴ファイルの読み込み（テストモードでは省略、ファイルパスが指定されている場合のみ）
-	if !options.TestMode && historyOpts.HistoryFilePath != "" {
-		slog.Info("履歴ファイルを使用", "file", historyOpts.HistoryFilePath)
+	if !options.TestMode && !journal && history != nil && historyOpts.HistoryFilePath != "" {
+		logger.Info("履歴ファイルを使用", "file", historyOpts.HistoryFilePath)
+		restored := false
+		if integrity != nil {
+			integrity.Register(historyOpts.HistoryFilePath, options.Cipher.validateWith(validateJSON), false)
+			restored = integrity.CheckOnLoad(historyOpts.HistoryFilePath)
+		}
 		// ロード時のフィルター設定
 		filter := HistoryLoadFilter{
 			PerDeviceSettableLimit:    historyOpts.PerDeviceSettableLimit,
 			PerDeviceNonSettableLimit: historyOpts.PerDeviceNonSettableLimit,
 		}
-		err := history.LoadFromFile(historyOpts.HistoryFilePath, filter)
+		var stats fileLoadStats
+		var err error
+		if memory, ok := history.(*memoryDeviceHistoryStore); ok {
+			stats, err = memory.loadFromFile(historyOpts.HistoryFilePath, filter)
+		} else {
+			err = history.LoadFromFile(historyOpts.HistoryFilePath, filter)
+		}
+		if errors.Is(err, ErrEncryptionKeyRequired) {
+			// 新規作成すると暗号化された履歴を上書きしてしまうため、起動しない
+			if session != nil {
+				_ = session.Close()
+			}
+			cancel()
+			return nil, fmt.Errorf("履歴ファイルの読み込みに失敗 (file: %s): %w", historyOpts.HistoryFilePath, err)
+		}
+		report.add("history", historyOpts.HistoryFilePath, stats, restored, err)
 		if err != nil {
-			slog.Warn("履歴ファイルの読み込みに失敗（新規作成します）", "file", historyOpts.HistoryFilePath, "error", err)
+			logger.Warn("履歴ファイルの読み込みに失敗（新規作成します）", "file", historyOpts.HistoryFilePath, "error", err)
 		} else {
-			slog.Info("履歴ファイルの読み込み完了", "file", historyOpts.HistoryFilePath)
+			logger.Info("履歴ファイルの読み込み完了", "file", historyOpts.HistoryFilePath)
 		}
 	}
 
-	data := NewDataManagementHandler(devices, aliases, groups, locationSettings, history, core)
+	data := NewDataManagementHandler(devices, aliases, groups, locationSettings, history, core, logger)
+	data.SetScenes(scenes, scenesFile)
+	data.SetSchedules(schedules, schedulesFile)
+	data.SetMetadata(metadata, metadataFile)
+	data.SetPreferences(preferences, preferencesFile)
+	data.SetInMemory(options.InMemory)
+	data.SetDevicesFile(devicesFile)
+	data.SetIntegrityChecker(integrity)
+	data.SetSaveInterval(options.Persistence.SaveInterval)
+	persistenceCh := make(chan PersistenceStatus, 10)
+	data.SetPersistenceNotifications(persistenceCh)
+
+	// 外部で編集されたエイリアス・グループファイルを読み込み直す（テストモード・メモリ上のみの場合は省略）
+	fileReloadCh := make(chan ReloadNotification, 10)
+	if !skipFiles {
+		data.SetWatchedFiles(aliasesFile, groupsFile, fileReloadCh)
+		go func() {
+			ticker := time.NewTicker(FileWatchInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-handlerCtx.Done():
+					return
+				case <-ticker.C:
+					data.ReloadChangedFiles()
+				}
+			}
+		}()
+	}
+
+	// プロパティ変化統計の読み込み（テストモード・メモリ上のみの場合は省略）
+	statsFilePath := ""
+	if !skipFiles {
+		statsFilePath = getFileOrDefault(options.StatsFile, PropertyChangeStatsFileName)
+		if err := data.ChangeStats.LoadFromFile(statsFilePath); err != nil {
+			logger.Warn("プロパティ変化統計の読み込みに失敗（新規作成します）", "file", statsFilePath, "error", err)
+		}
+	}
 
 	// オフラインチェッカーを設定（重複通知防止のため）
 	core.SetOfflineChecker(data)
 
 	var comm *CommunicationHandler
 	if !options.TestMode && session != nil {
-		comm = NewCommunicationHandler(handlerCtx, session, localDevices, data, core, options.Debug)
+		comm = NewCommunicationHandler(handlerCtx, session, local, data, core, options.Debug, logger)
+		comm.discoveryInterfaces = options.DiscoveryInterfaces
+		comm.discoveryBroadcast = options.DiscoveryBroadcast
+		if options.IPVersion == network.DualStack {
+			comm.preferIPVersion = network.IPv4
+			if options.PreferIPVersion == network.IPv6 {
+				comm.preferIPVersion = network.IPv6
+			}
+		}
 		// プロパティ更新後のフック処理を設定
 		data.SetHookProcessor(comm)
 	}
@@ -329,13 +650,33 @@ func NewECHONETLiteHandler(ctx context.Context, options ECHONETLieHandlerOptions
 	}
 
 	// ECHONETLiteHandlerを作成
+	// 復元した状態の要約を記録する（ファイルを読み込まなかった場合は記録しない）
+	report.LoadedAt = time.Now()
+	if len(report.Files) > 0 {
+		report.log(logger)
+	}
+
 	handler := &ECHONETLiteHandler{
 		core:             core,
 		comm:             comm,
 		data:             data,
 		historyFilePath:  historyOpts.HistoryFilePath,
+		statsFilePath:    statsFilePath,
+		memoryLimits:     options.MemoryLimits,
+		integrityOptions: options.Integrity,
+		livenessOptions:  options.Liveness,
+		pruneAfter:       options.Persistence.PruneAfter,
 		PropertyChangeCh: core.PropertyChangeCh,
+		FileReloadCh:     fileReloadCh,
+		PersistenceCh:    persistenceCh,
+		logger:           logger,
+		startTime:        time.Now(),
+		instanceLock:     lock,
+		local:            local,
+		localMapsFile:    localPropertyMapsFile,
+		startupReport:    report,
 	}
+	lock = nil
 
 	// タイムアウト時にオフライン状態を設定するgoroutineを起動
 	// SubscribeNotifications を使用して専用チャンネルを取得
@@ -343,7 +684,7 @@ func NewECHONETLiteHandler(ctx context.Context, options ECHONETLieHandlerOptions
 	go func() {
 		for ev := range subscribedCh {
 			if ev.Type == DeviceTimeout {
-				handleDeviceTimeout(ev.Device, data)
+				handleDeviceTimeout(ev.Device, data, logger)
 			}
 			if ev.Type == DeviceOnline {
 				handleDeviceOnline(ev.Device, handler)
@@ -358,19 +699,64 @@ func NewECHONETLiteHandler(ctx context.Context, options ECHONETLieHandlerOptions
 // 履歴ファイルの保存に失敗した場合でもエラーを返さず、ログに記録するのみとする。
 // これは、履歴データの保存失敗がアプリケーションの正常終了を妨げるべきではないためである。
 func (h *ECHONETLiteHandler) Close() error {
+	// デバイス情報の保存（終了直前に受け取った応答や、まとめて保存するために待っている変更も残すため）
+	if h.data != nil && (h.data.devicesFilePath != "" || h.data.hasPendingDeviceInfo()) {
+		_ = h.data.saveDeviceInfoNow()
+	}
+	if h.data != nil {
+		h.data.stopSaveRetry()
+	}
 	// 履歴ファイルの保存（ファイルパスが指定されている場合のみ）
 	if h.historyFilePath != "" && h.data != nil && h.data.DeviceHistory != nil {
-		slog.Info("履歴ファイルを保存", "file", h.historyFilePath)
-		err := h.data.DeviceHistory.SaveToFile(h.historyFilePath)
+		h.log().Info("履歴ファイルを保存", "file", h.historyFilePath)
+		err := h.data.integrity.Save(h.historyFilePath, func() error {
+			return h.data.DeviceHistory.SaveToFile(h.historyFilePath)
+		})
 		if err != nil {
 			// 履歴保存エラーはログに記録するのみで、Close()自体は失敗させない
 			// 履歴データは重要だが、保存失敗がシステム終了を妨げるべきではない
-			slog.Error("履歴ファイルの保存に失敗", "file", h.historyFilePath, "error", err)
+			h.log().Error("履歴ファイルの保存に失敗", "file", h.historyFilePath, "error", err)
 		} else {
-			slog.Info("履歴ファイルの保存完了", "file", h.historyFilePath)
+			h.log().Info("履歴ファイルの保存完了", "file", h.historyFilePath)
 		}
 	}
-	return h.core.Close()
+	// プロパティ変化統計の保存（履歴と同様、失敗してもログに記録するのみ）
+	if h.statsFilePath != "" && h.data != nil && h.data.ChangeStats != nil {
+		if err := h.data.ChangeStats.SaveToFile(h.statsFilePath); err != nil {
+			h.log().Error("プロパティ変化統計の保存に失敗", "file", h.statsFilePath, "error", err)
+		}
+	}
+	// ファイルを保持するバックエンド（ジャーナル）はここで閉じる
+	if h.data != nil {
+		if closer, ok := h.data.DeviceHistory.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				h.log().Error("履歴ストアのクローズに失敗", "error", err)
+			}
+		}
+	}
+	err := h.core.Close()
+	// 保存が終わってから ECHONET Lite のソケットを閉じる
+	if h.comm != nil && h.comm.session != nil {
+		if closeErr := h.comm.session.Close(); closeErr != nil {
+			h.log().Info("セッションのクローズに失敗", "error", closeErr)
+		}
+	}
+	// すべての保存が終わってからロックを解放する
+	if lockErr := h.instanceLock.release(); lockErr != nil {
+		h.log().Warn("データファイルのロックの解放に失敗", "error", lockErr)
+	}
+	return err
+}
+
+// PersistenceStatus は、デバイス情報ファイルの保存の状態を返す
+func (h *ECHONETLiteHandler) PersistenceStatus() PersistenceStatus {
+	return h.data.PersistenceStatus()
+}
+
+// StartupReport は、起動時に保存ファイルから復元した状態の要約を返す
+// ファイルを読み込まなかった場合（テストモード・メモリ上のみ）は Files が空
+func (h *ECHONETLiteHandler) StartupReport() StartupReport {
+	return h.startupReport
 }
 
 // GetCore は、HandlerCoreを取得する
@@ -386,6 +772,19 @@ func (h *ECHONETLiteHandler) GetDataManagementHandler() *DataManagementHandler {
 // StartMainLoop は、メインループを開始する
 func (h *ECHONETLiteHandler) StartMainLoop() {
 	go h.comm.session.MainLoop()
+	if h.memoryLimits.Enabled() {
+		h.startMemoryMonitor(h.memoryLimits)
+	}
+	if h.data.integrity != nil {
+		h.startIntegrityMonitor(h.integrityOptions.CheckInterval)
+	}
+	if h.livenessOptions.Enabled {
+		h.startLivenessMonitor(h.livenessOptions)
+	}
+	if h.pruneAfter > 0 {
+		h.startStaleDevicePruner(h.pruneAfter)
+	}
+	h.startScheduler()
 }
 
 // SetDebug は、デバッグモードを設定する
@@ -394,6 +793,11 @@ func (h *ECHONETLiteHandler) SetDebug(debug bool) {
 	h.comm.SetDebug(debug)
 }
 
+// WatchPropertyChanges は、プロパティ変化通知を受信するチャンネルと、購読をやめる関数を返す
+func (h *ECHONETLiteHandler) WatchPropertyChanges(bufferSize int) (<-chan PropertyChangeNotification, func()) {
+	return h.core.WatchPropertyChanges(bufferSize)
+}
+
 // IsDebug は、現在のデバッグモードを返す
 func (h *ECHONETLiteHandler) IsDebug() bool {
 	if h == nil || h.core == nil {
@@ -415,6 +819,49 @@ func (h *ECHONETLiteHandler) DebugSetOffline(target string, offline bool) error
 	return nil
 }
 
+// Drain は、新しい ECHONET Lite の要求の送信を止め、応答待ちの要求（Set の途中のものを含む）が終わるまで待つ
+// 終了処理で Close の前に呼び出す。ctx が終わった場合は残っている要求の数を含むエラーを返す
+func (h *ECHONETLiteHandler) Drain(ctx context.Context) error {
+	if h.comm == nil || h.comm.session == nil {
+		// テストモードでは送信しないため待つものがない
+		return nil
+	}
+	return h.comm.session.Drain(ctx)
+}
+
+// LocalPropertyMaps は、自ノードのデバイスのプロパティマップを返す
+func (h *ECHONETLiteHandler) LocalPropertyMaps() []LocalPropertyMap {
+	return h.local.PropertyMaps()
+}
+
+// SetLocalPropertyMap は、自ノードのデバイスのプロパティマップを変更して保存する。epcs が nil の場合は初期状態に戻す
+func (h *ECHONETLiteHandler) SetLocalPropertyMap(eoj EOJ, mapType PropertyMapType, epcs []EPCType) error {
+	if err := h.local.SetPropertyMap(eoj, mapType, epcs); err != nil {
+		return err
+	}
+	if h.localMapsFile == "" {
+		return nil
+	}
+	return h.local.SaveToFile(h.localMapsFile)
+}
+
+// DebugPendingRequests は、デバイスの応答を待っている Get/Set 要求の一覧を返す
+func (h *ECHONETLiteHandler) DebugPendingRequests() []PendingRequest {
+	if h.comm == nil {
+		// テストモードではCommunicationHandlerが無いため、空の一覧を返す
+		return nil
+	}
+	return h.comm.session.PendingRequests()
+}
+
+// DebugCancelRequest は、応答を待っている要求の再送をやめて取り消す
+func (h *ECHONETLiteHandler) DebugCancelRequest(id uint64) error {
+	if h.comm == nil || !h.comm.session.CancelPendingRequest(id) {
+		return fmt.Errorf("応答待ちの要求 %d が見つかりません", id)
+	}
+	return nil
+}
+
 // IsOfflineDevice checks if a device is currently offline
 func (h *ECHONETLiteHandler) IsOfflineDevice(device IPAndEOJ) bool {
 	return h.data.IsOffline(device)
@@ -435,19 +882,33 @@ func (h *ECHONETLiteHandler) GetGetPropertyMap(device IPAndEOJ) error {
 	return h.comm.GetGetPropertyMap(device)
 }
 
+// RefreshPropertyMap は、デバイスのプロパティマップを取得し直し、Get プロパティマップのすべてのプロパティを取得する
+func (h *ECHONETLiteHandler) RefreshPropertyMap(device IPAndEOJ) (PropertyMapChange, error) {
+	if h.comm == nil {
+		return PropertyMapChange{}, fmt.Errorf("%v: 通信ハンドラがないため、プロパティマップを取得できません", device)
+	}
+	return h.comm.RefreshPropertyMap(device)
+}
+
 // Discover は、ECHONET Liteデバイスを検出する
 func (h *ECHONETLiteHandler) Discover() error {
 	return h.comm.Discover()
 }
 
-// GetProperties は、プロパティ値を取得する
+// GetProperties は、プロパティ値を取得する（EPCs が空の場合は取得可能なすべてのプロパティ）
 func (h *ECHONETLiteHandler) GetProperties(device IPAndEOJ, EPCs []EPCType, skipValidation bool) (DeviceAndProperties, error) {
 	return h.comm.GetProperties(device, EPCs, skipValidation)
 }
 
 // SetProperties は、プロパティ値を設定する
-func (h *ECHONETLiteHandler) SetProperties(device IPAndEOJ, properties Properties) (DeviceAndProperties, error) {
-	return h.comm.SetProperties(device, properties)
+// skipValidationEPCs に含まれるEPCは、SetPropertyMap による確認を行わない
+func (h *ECHONETLiteHandler) SetProperties(device IPAndEOJ, properties Properties, skipValidationEPCs []EPCType) (DeviceAndProperties, error) {
+	return h.comm.SetProperties(device, properties, skipValidationEPCs)
+}
+
+// SetGetProperties は、プロパティ値の設定と取得を1つの SetGet フレームで行う
+func (h *ECHONETLiteHandler) SetGetProperties(device IPAndEOJ, properties Properties, EPCs []EPCType) (SetGetResult, error) {
+	return h.comm.SetGetProperties(device, properties, EPCs)
 }
 
 // UpdateProperties は、フィルタリングされたデバイスのプロパティキャッシュを更新する
@@ -459,6 +920,31 @@ func (h *ECHONETLiteHandler) UpdateProperties(criteria FilterCriteria, force boo
 	return h.comm.UpdateProperties(criteria, force)
 }
 
+// UpdateDevicesWithProgress は、指定されたデバイスのプロパティキャッシュを更新し、デバイスごとの結果を progress に通知する
+func (h *ECHONETLiteHandler) UpdateDevicesWithProgress(devices []IPAndEOJ, force bool, progress UpdateProgressFunc) error {
+	if h.comm == nil {
+		// テストモードではCommunicationHandlerが無いため、何も実行しない
+		return nil
+	}
+	return h.comm.UpdateDevicesWithProgress(devices, force, progress)
+}
+
+// SetPollSchedule は、定期更新でデバイスごとに取得間隔を変える予定を設定する（nil で全デバイスを毎回取得）
+func (h *ECHONETLiteHandler) SetPollSchedule(schedule *PollSchedule) {
+	if h.comm != nil {
+		h.comm.SetPollSchedule(schedule)
+	}
+}
+
+// UpdateScheduledProperties は、定期更新の予定で取得間隔が経過したデバイスのプロパティキャッシュを更新する
+func (h *ECHONETLiteHandler) UpdateScheduledProperties(force bool) error {
+	if h.comm == nil {
+		// テストモードではCommunicationHandlerが無いため、何も実行しない
+		return nil
+	}
+	return h.comm.UpdateScheduledProperties(force)
+}
+
 // ListDevices は、検出されたデバイスの一覧を表示する
 func (h *ECHONETLiteHandler) ListDevices(criteria FilterCriteria) []DeviceAndProperties {
 	return h.data.ListDevices(criteria)
@@ -546,6 +1032,82 @@ func (h *ECHONETLiteHandler) GetDevicesByGroup(groupName string) ([]IDString, bo
 	return h.data.GetDevicesByGroup(groupName)
 }
 
+// SceneList は、シーンのリストを返す
+func (h *ECHONETLiteHandler) SceneList(sceneName *string) []SceneActionsPair {
+	return h.data.SceneList(sceneName)
+}
+
+// SceneAdd は、シーンにアクションを追加する
+func (h *ECHONETLiteHandler) SceneAdd(sceneName string, actions []SceneAction) error {
+	return h.data.SceneAdd(sceneName, actions)
+}
+
+// SceneDelete は、シーンを削除する
+func (h *ECHONETLiteHandler) SceneDelete(sceneName string) error {
+	return h.data.SceneDelete(sceneName)
+}
+
+// GetScene は、シーン名に対応するアクションのリストを返す
+func (h *ECHONETLiteHandler) GetScene(sceneName string) ([]SceneAction, bool) {
+	return h.data.GetScene(sceneName)
+}
+
+// SceneSetSequence は、シーン実行時のデバイスの順序と間隔を設定する
+func (h *ECHONETLiteHandler) SceneSetSequence(sceneName string, sequence SceneSequence) error {
+	return h.data.SceneSetSequence(sceneName, sequence)
+}
+
+// GetSceneSequence は、シーン実行時のデバイスの順序と間隔を返す（設定されていない場合はゼロ値）
+func (h *ECHONETLiteHandler) GetSceneSequence(sceneName string) SceneSequence {
+	return h.data.GetSceneSequence(sceneName)
+}
+
+// SceneRun は、シーンを実行する
+func (h *ECHONETLiteHandler) SceneRun(sceneName string) ([]SceneRunResult, error) {
+	actions, ok := h.data.GetScene(sceneName)
+	if !ok {
+		return nil, fmt.Errorf("シーンが存在しません: %s", sceneName)
+	}
+	opts := SceneRunOptions{Sequence: h.data.GetSceneSequence(sceneName)}
+	return RunSceneActions(actions, h.data.FindDeviceByIDString, h.setSceneProperties, opts), nil
+}
+
+// setSceneProperties は、シーンやスケジュールのアクションとしてプロパティを設定する
+// アクションのEPCは書き込み専用プロパティを除いてすべて SetPropertyMap で確認する
+func (h *ECHONETLiteHandler) setSceneProperties(device IPAndEOJ, properties Properties) (DeviceAndProperties, error) {
+	return h.SetProperties(device, properties, nil)
+}
+
+// ScheduleList は、スケジュールのリストを返す
+func (h *ECHONETLiteHandler) ScheduleList() []Schedule {
+	return h.data.ScheduleList()
+}
+
+// ScheduleSet は、スケジュールを追加または置き換える
+func (h *ECHONETLiteHandler) ScheduleSet(schedule Schedule) error {
+	return h.data.ScheduleSet(schedule)
+}
+
+// ScheduleNextRun は、スケジュールの after より後の次回実行時刻を返す（ない場合はゼロ値）
+func (h *ECHONETLiteHandler) ScheduleNextRun(schedule Schedule, after time.Time) time.Time {
+	return h.data.Schedules.NextRun(schedule, after)
+}
+
+// Coordinates は、日の出・日の入りを計算する地点を返す（設定されていない場合は nil）
+func (h *ECHONETLiteHandler) Coordinates() *Coordinates {
+	return h.data.Schedules.Coordinates()
+}
+
+// ScheduleDelete は、スケジュールを削除する
+func (h *ECHONETLiteHandler) ScheduleDelete(name string) error {
+	return h.data.ScheduleDelete(name)
+}
+
+// ScheduleSetDisabled は、スケジュールの有効・無効を切り替える
+func (h *ECHONETLiteHandler) ScheduleSetDisabled(name string, disabled bool) error {
+	return h.data.ScheduleSetDisabled(name, disabled)
+}
+
 // GetLocationSettings は、ロケーション設定を取得する
 func (h *ECHONETLiteHandler) GetLocationSettings() (map[string]string, []string) {
 	return h.data.GetLocationSettings()
@@ -571,6 +1133,31 @@ func (h *ECHONETLiteHandler) SetLocationOrder(order []string) error {
 	return h.data.SetLocationOrder(order)
 }
 
+// DeviceMetadataSet は、デバイスのメタデータを置き換える
+func (h *ECHONETLiteHandler) DeviceMetadataSet(id IDString, metadata DeviceMetadata) error {
+	return h.data.DeviceMetadataSet(id, metadata)
+}
+
+// DeviceMetadataDelete は、デバイスのメタデータを削除する
+func (h *ECHONETLiteHandler) DeviceMetadataDelete(id IDString) error {
+	return h.data.DeviceMetadataDelete(id)
+}
+
+// GetDeviceMetadata は、すべてのデバイスのメタデータを返す
+func (h *ECHONETLiteHandler) GetDeviceMetadata() map[IDString]DeviceMetadata {
+	return h.data.GetDeviceMetadata()
+}
+
+// ClientPreferencesSet は、クライアントキーの表示設定を置き換える
+func (h *ECHONETLiteHandler) ClientPreferencesSet(key string, preferences ClientPreferences) error {
+	return h.data.ClientPreferencesSet(key, preferences)
+}
+
+// GetClientPreferences は、クライアントキーの表示設定を返す
+func (h *ECHONETLiteHandler) GetClientPreferences(key string) (ClientPreferences, bool) {
+	return h.data.GetClientPreferences(key)
+}
+
 // FindDeviceByIDString は、IDStringからデバイスを検索する
 func (h *ECHONETLiteHandler) FindDeviceByIDString(id IDString) *IPAndEOJ {
 	return h.data.FindDeviceByIDString(id)
@@ -626,6 +1213,12 @@ func (h *ECHONETLiteHandler) RemoveDevice(device IPAndEOJ) error {
 	return h.data.RemoveDevice(device)
 }
 
+// GetPropertyChangeStats は、プロパティ変化頻度の統計を返す
+// device が nil の場合は全デバイスの統計を返す
+func (h *ECHONETLiteHandler) GetPropertyChangeStats(device *IPAndEOJ) []PropertyChangeStat {
+	return h.data.ChangeStats.Query(device)
+}
+
 // IsOffline は、指定されたデバイスがオフラインかどうかを返す
 func (h *ECHONETLiteHandler) IsOffline(device IPAndEOJ) bool {
 	return h.data.IsOffline(device)
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/simulator"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer は複数のgoroutineから書き込まれるログを保持する
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// newIsolatedHandler は、1台の照明だけを持つ模擬ネットワークに接続したハンドラを作成する
+func newIsolatedHandler(t *testing.T, ip string, logs *syncBuffer) *ECHONETLiteHandler {
+	t.Helper()
+	light := echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)
+	network := simulator.NewNetwork([]simulator.NodeSpec{{
+		IP: net.ParseIP(ip),
+		Devices: []simulator.DeviceSpec{{
+			EOJ:        light,
+			Properties: echonet_lite.Properties{{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}}},
+		}},
+	}})
+	h, err := NewECHONETLiteHandler(context.Background(), ECHONETLieHandlerOptions{
+		Connection: network,
+		InMemory:   true,
+		Logger:     slog.New(slog.NewTextHandler(logs, nil)).With("instance", ip),
+	})
+	if err != nil {
+		t.Fatalf("NewECHONETLiteHandler failed: %v", err)
+	}
+	t.Cleanup(func() { _ = h.Close() })
+	h.StartMainLoop()
+	return h
+}
+
+func TestECHONETLiteHandler_IsolatedInstances(t *testing.T) {
+	var defaultLogs, logs1, logs2 syncBuffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&defaultLogs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	h1 := newIsolatedHandler(t, "192.0.2.1", &logs1)
+	h2 := newIsolatedHandler(t, "192.0.2.2", &logs2)
+
+	for _, h := range []*ECHONETLiteHandler{h1, h2} {
+		if err := h.Discover(); err != nil {
+			t.Fatalf("Discover failed: %v", err)
+		}
+	}
+
+	// それぞれのインスタンスは自分のネットワークのノードだけを検出する
+	for _, tc := range []struct {
+		h  *ECHONETLiteHandler
+		ip string
+	}{{h1, "192.0.2.1"}, {h2, "192.0.2.2"}} {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			devices := tc.h.ListDevices(FilterCriteria{})
+			if len(devices) >= 2 {
+				for _, d := range devices {
+					if d.Device.IP.String() != tc.ip {
+						t.Errorf("instance %s discovered a device of another instance: %v", tc.ip, d.Device)
+					}
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("instance %s did not discover its devices: %v", tc.ip, devices)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	// ログはインスタンスごとのロガーに出力される
+	if !strings.Contains(logs1.String(), "instance=192.0.2.1") || strings.Contains(logs1.String(), "instance=192.0.2.2") {
+		t.Errorf("unexpected logs for instance 1:\n%s", logs1.String())
+	}
+	if !strings.Contains(logs2.String(), "instance=192.0.2.2") {
+		t.Errorf("unexpected logs for instance 2:\n%s", logs2.String())
+	}
+	if strings.Contains(defaultLogs.String(), "ユニーク識別子") {
+		t.Errorf("instance logs were written to the default logger:\n%s", defaultLogs.String())
+	}
+}
+
+func TestCreateSessionWithConnection_BroadcastIP(t *testing.T) {
+	network := simulator.NewNetwork(nil)
+	session := CreateSessionWithConnection(context.Background(), network, echonet_lite.MakeEOJ(echonet_lite.Controller_ClassCode, 1), false, nil)
+	defer session.Close()
+
+	// UDP 以外の接続ではホストのインターフェースに依存しないブロードキャストアドレスを使う
+	if !session.BroadcastIP.Equal(net.IPv4bcast) {
+		t.Errorf("BroadcastIP = %v, want %v", session.BroadcastIP, net.IPv4bcast)
+	}
+}
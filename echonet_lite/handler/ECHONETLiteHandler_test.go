@@ -21,6 +21,7 @@ func TestNewECHONETLiteHandler_ControllerInitialization(t *testing.T) {
 		Debug:            false,
 		ManufacturerCode: "Experimental",
 		UniqueIdentifier: []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d},
+		InMemory:         true, // カレントディレクトリにデータファイルやロックファイルを作らない
 	}
 
 	handler, err := NewECHONETLiteHandler(ctx, options)
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"echonet-list/echonet_lite"
+	"log/slog"
 	"net"
 	"testing"
 )
@@ -58,7 +59,7 @@ func TestHandleDeviceTimeout_NodeProfile(t *testing.T) {
 	}
 
 	// Execute
-	handleDeviceTimeout(nodeProfileDevice, mock)
+	handleDeviceTimeout(nodeProfileDevice, mock, slog.Default())
 
 	// Verify: SetOfflineByIP should be called with the IP
 	if len(mock.setOfflineByIPCalls) != 1 {
@@ -101,7 +102,7 @@ func TestHandleDeviceTimeout_NonNodeProfile_NodeProfileOffline(t *testing.T) {
 	mock.setOfflineCalls = nil
 
 	// Execute
-	handleDeviceTimeout(device, mock)
+	handleDeviceTimeout(device, mock, slog.Default())
 
 	// Verify: SetOffline should be called for the device
 	if len(mock.setOfflineCalls) != 1 {
@@ -137,7 +138,7 @@ func TestHandleDeviceTimeout_NonNodeProfile_NodeProfileOnline(t *testing.T) {
 	}
 
 	// Execute
-	handleDeviceTimeout(device, mock)
+	handleDeviceTimeout(device, mock, slog.Default())
 
 	// Verify: SetOffline should NOT be called
 	if len(mock.setOfflineCalls) != 0 {
@@ -173,7 +174,7 @@ func TestHandleDeviceTimeout_DifferentIPs(t *testing.T) {
 	mock.setOfflineCalls = nil
 
 	// Execute: Device timeout on IP2 should not be affected by IP1's NodeProfile
-	handleDeviceTimeout(device2, mock)
+	handleDeviceTimeout(device2, mock, slog.Default())
 
 	// Verify: SetOffline should NOT be called since IP2's NodeProfile is online
 	if len(mock.setOfflineCalls) != 0 {
@@ -198,7 +199,7 @@ func TestHandleDeviceTimeout_MultipleDevicesSameIP(t *testing.T) {
 	}
 
 	// Execute: NodeProfile timeout should mark all devices with same IP as offline
-	handleDeviceTimeout(nodeProfile, mock)
+	handleDeviceTimeout(nodeProfile, mock, slog.Default())
 
 	// Verify: SetOfflineByIP should be called once
 	if len(mock.setOfflineByIPCalls) != 1 {
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// EncryptionKeySize は、保存ファイルの暗号化に使う鍵のバイト数（AES-256）
+const EncryptionKeySize = 32
+
+// encryptedFileMagic は、暗号化したデータの先頭に付けるマーク
+// 平文の JSON は '{' か '[' で始まるため、暗号化されているかどうかを区別できる
+var encryptedFileMagic = []byte("ELENC\x01")
+
+// ErrEncryptionKeyRequired は、暗号化されたデータを鍵なしで読み込もうとした場合のエラー
+var ErrEncryptionKeyRequired = errors.New("the file is encrypted but no encryption key is configured")
+
+// FileCipher は、デバイス情報や履歴などの保存ファイルを AES-GCM で暗号化・復号する
+// nil の FileCipher は暗号化せずにそのまま読み書きする
+type FileCipher struct {
+	aead cipher.AEAD
+}
+
+// NewFileCipher は、32バイトの鍵から FileCipher を作成する
+func NewFileCipher(key []byte) (*FileCipher, error) {
+	if len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", EncryptionKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &FileCipher{aead: aead}, nil
+}
+
+// IsEncrypted は、data が FileCipher で暗号化されたものかを返す
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedFileMagic)
+}
+
+// Encrypt は、plain を暗号化する。FileCipher が nil の場合は plain をそのまま返す
+func (c *FileCipher) Encrypt(plain []byte) ([]byte, error) {
+	if c == nil {
+		return plain, nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	out := make([]byte, 0, len(encryptedFileMagic)+len(nonce)+len(plain)+c.aead.Overhead())
+	out = append(out, encryptedFileMagic...)
+	out = append(out, nonce...)
+	return c.aead.Seal(out, nonce, plain, encryptedFileMagic), nil
+}
+
+// Decrypt は、Encrypt で暗号化された data を復号する
+// 暗号化されていないデータはそのまま返すため、暗号化を有効にする前のファイルも読み込める
+func (c *FileCipher) Decrypt(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	if c == nil {
+		return nil, ErrEncryptionKeyRequired
+	}
+	body := data[len(encryptedFileMagic):]
+	if len(body) < c.aead.NonceSize() {
+		return nil, errors.New("encrypted data is too short")
+	}
+	nonce, sealed := body[:c.aead.NonceSize()], body[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, sealed, encryptedFileMagic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt (wrong key or corrupted data): %w", err)
+	}
+	return plain, nil
+}
+
+// EncryptLine は、1行ずつ追記するファイル（履歴ジャーナル）の1行を暗号化し、改行を含まない文字列にする
+func (c *FileCipher) EncryptLine(plain []byte) ([]byte, error) {
+	if c == nil {
+		return plain, nil
+	}
+	data, err := c.Encrypt(plain)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.AppendEncode(nil, data), nil
+}
+
+// DecryptLine は、EncryptLine で暗号化された1行を復号する。JSON の行はそのまま返す
+func (c *FileCipher) DecryptLine(line []byte) ([]byte, error) {
+	if len(line) == 0 || line[0] == '{' || line[0] == '[' {
+		return line, nil
+	}
+	data, err := base64.StdEncoding.AppendDecode(nil, line)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encrypted line: %w", err)
+	}
+	return c.Decrypt(data)
+}
+
+// validateWith は、復号してから validate で内容を確認する検証関数を返す
+// 鍵がない場合は内容を確認できないため、チェックサムだけで検証する
+func (c *FileCipher) validateWith(validate func(data []byte) error) func(data []byte) error {
+	return func(data []byte) error {
+		plain, err := c.Decrypt(data)
+		if errors.Is(err, ErrEncryptionKeyRequired) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return validate(plain)
+	}
+}
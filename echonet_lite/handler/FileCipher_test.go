@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+)
+
+func newTestCipher(t *testing.T, seed byte) *FileCipher {
+	t.Helper()
+	c, err := NewFileCipher(bytes.Repeat([]byte{seed}, EncryptionKeySize))
+	if err != nil {
+		t.Fatalf("NewFileCipher に失敗: %v", err)
+	}
+	return c
+}
+
+func TestFileCipher_EncryptDecrypt(t *testing.T) {
+	c := newTestCipher(t, 1)
+	plain := []byte(`{"version":1}`)
+
+	encrypted, err := c.Encrypt(plain)
+	if err != nil {
+		t.Fatalf("Encrypt に失敗: %v", err)
+	}
+	if !IsEncrypted(encrypted) || bytes.Contains(encrypted, plain) {
+		t.Fatalf("暗号化されていない: %q", encrypted)
+	}
+	decrypted, err := c.Decrypt(encrypted)
+	if err != nil || !bytes.Equal(decrypted, plain) {
+		t.Fatalf("復号の結果が不正: %q %v", decrypted, err)
+	}
+
+	// 暗号化を有効にする前のファイルはそのまま読み込める
+	if data, err := c.Decrypt(plain); err != nil || !bytes.Equal(data, plain) {
+		t.Errorf("平文の読み込みが不正: %q %v", data, err)
+	}
+
+	// 鍵が違う場合・鍵がない場合はエラー
+	if _, err := newTestCipher(t, 2).Decrypt(encrypted); err == nil {
+		t.Error("違う鍵で復号できてしまった")
+	}
+	var none *FileCipher
+	if _, err := none.Decrypt(encrypted); !errors.Is(err, ErrEncryptionKeyRequired) {
+		t.Errorf("鍵がない場合のエラーが不正: %v", err)
+	}
+
+	if _, err := NewFileCipher([]byte("short")); err == nil {
+		t.Error("32バイトでない鍵が受け付けられた")
+	}
+}
+
+func TestDevices_EncryptedFile(t *testing.T) {
+	c := newTestCipher(t, 1)
+	filename := filepath.Join(t.TempDir(), "devices.json")
+
+	devices := NewDevices()
+	devices.SetFileCipher(c)
+	device := testDevice(1)
+	devices.RegisterProperty(device, Property{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}}, time.Now())
+	if err := devices.SaveToFile(filename); err != nil {
+		t.Fatalf("SaveToFile に失敗: %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(data) || strings.Contains(string(data), device.IP.String()) {
+		t.Fatalf("デバイスファイルが暗号化されていない: %q", data)
+	}
+
+	loaded := NewDevices()
+	loaded.SetFileCipher(c)
+	if err := loaded.LoadFromFile(filename); err != nil {
+		t.Fatalf("LoadFromFile に失敗: %v", err)
+	}
+	if !loaded.IsKnownDevice(device) {
+		t.Error("暗号化したファイルから読み込めていない")
+	}
+
+	// 鍵なしでは読み込まない
+	if err := NewDevices().LoadFromFile(filename); !errors.Is(err, ErrEncryptionKeyRequired) {
+		t.Errorf("鍵がない場合のエラーが不正: %v", err)
+	}
+}
+
+func TestJournalDeviceHistoryStore_Encrypted(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history.jsonl")
+	device := testDevice(2)
+	base := time.Now().Add(-time.Hour)
+
+	// 暗号化前に記録された平文の行は、暗号化を有効にしたときに暗号化される
+	plain := newTestJournal(t, filename, 0)
+	recordJournalEntries(plain, device, base, 2)
+	if err := plain.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTestCipher(t, 1)
+	store, err := NewJournalDeviceHistoryStore(filename, 0, c)
+	if err != nil {
+		t.Fatalf("NewJournalDeviceHistoryStore に失敗: %v", err)
+	}
+	recordJournalEntries(store, device, base.Add(10*time.Minute), 1)
+	if entries := store.Query(device, HistoryQuery{}); len(entries) != 3 {
+		t.Fatalf("履歴の数が不正: %d", len(entries))
+	}
+	if err := store.SaveToFile(""); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.(*journalDeviceHistoryStore).Close()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "value-") || strings.Count(string(data), "\n") != 3 {
+		t.Fatalf("ジャーナルが1行ずつ暗号化されていない: %q", data)
+	}
+
+	// 鍵なしで開くと、暗号化された行を捨てずにエラーにする
+	if _, err := NewJournalDeviceHistoryStore(filename, 0, nil); !errors.Is(err, ErrEncryptionKeyRequired) {
+		t.Errorf("鍵がない場合のエラーが不正: %v", err)
+	}
+}
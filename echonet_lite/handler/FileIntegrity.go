@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultIntegrityCheckInterval = time.Hour // 保存ファイルの定期チェックの間隔のデフォルト値
+	DefaultIntegrityBackups       = 3         // 保持するバックアップ数のデフォルト値
+
+	integrityChecksumSuffix = ".sha256"  // チェックサムファイルの拡張子（sha256sum -c で確認できる形式）
+	integrityBackupSuffix   = ".bak"     // バックアップの拡張子（.bak1 が最新）
+	integrityCorruptSuffix  = ".corrupt" // 破損していたファイルの退避先の拡張子
+)
+
+// IntegrityOptions は、保存ファイルの整合性チェックの設定
+type IntegrityOptions struct {
+	Enabled       bool          // チェックサムの記録・検証とバックアップを行う
+	CheckInterval time.Duration // 定期チェックの間隔（0 の場合は DefaultIntegrityCheckInterval）
+	Backups       int           // 保持するバックアップ数（0 の場合は DefaultIntegrityBackups）
+}
+
+// IntegrityChecker は、保存ファイルのチェックサムを記録し、読み込み時と定期的に検証する
+// 破損を検出した場合は、内容が正しい最新のバックアップから復元する
+type IntegrityChecker struct {
+	backups int
+	logger  *slog.Logger
+	files   []*integrityFile // 起動時に登録し、以降は変更しない
+}
+
+// integrityFile は、整合性をチェックする1つのファイル
+// 保存と検証は mu を保持して行い、保存途中のファイルを破損と判定しないようにする
+type integrityFile struct {
+	mu       sync.Mutex
+	path     string
+	validate func(data []byte) error // ファイルの内容が読み込めるかを確認する
+	editable bool                    // 手で編集されることがある（チェックサムが違っても、内容が正しければ受け入れる）
+}
+
+// NewIntegrityChecker は、backups 個のバックアップを保持する IntegrityChecker を作成する
+func NewIntegrityChecker(backups int, logger *slog.Logger) *IntegrityChecker {
+	if backups <= 0 {
+		backups = DefaultIntegrityBackups
+	}
+	return &IntegrityChecker{backups: backups, logger: loggerOrDefault(logger)}
+}
+
+// Register は、整合性をチェックするファイルを登録する
+// validate が nil の場合は JSON として読み込めるかを確認する
+func (c *IntegrityChecker) Register(path string, validate func(data []byte) error, editable bool) {
+	if validate == nil {
+		validate = validateJSON
+	}
+	c.files = append(c.files, &integrityFile{path: path, validate: validate, editable: editable})
+}
+
+// validateJSON は、data が JSON として読み込めるかを確認する
+func validateJSON(data []byte) error {
+	if !json.Valid(data) {
+		return errors.New("invalid JSON")
+	}
+	return nil
+}
+
+func (c *IntegrityChecker) file(path string) *integrityFile {
+	if c == nil {
+		return nil
+	}
+	for _, f := range c.files {
+		if f.path == path {
+			return f
+		}
+	}
+	return nil
+}
+
+// Save は、save でファイルを保存し、保存した内容のチェックサムを記録する
+// 登録されていないファイルの場合は save を呼ぶだけ
+func (c *IntegrityChecker) Save(path string, save func() error) error {
+	f := c.file(path)
+	if f == nil {
+		return save()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := save(); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to read saved file %s: %w", f.path, err)
+	}
+	return writeChecksum(f.path, data)
+}
+
+// CheckOnLoad は、読み込み前にファイルを検証する
+// 破損している場合はバックアップから復元し、復元できない場合は破損したファイルを退避して空の状態から始める
+// 戻り値は、ファイルが破損していたかどうか
+func (c *IntegrityChecker) CheckOnLoad(path string) bool {
+	f := c.file(path)
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return c.check(f, true)
+}
+
+// CheckAll は、登録されたすべてのファイルを検証し、正しい内容をバックアップする
+func (c *IntegrityChecker) CheckAll() {
+	for _, f := range c.files {
+		f.mu.Lock()
+		c.check(f, false)
+		f.mu.Unlock()
+	}
+}
+
+// check は、ファイルを検証し、正しければバックアップし、破損していれば復元する
+// 実行中（onLoad が false）は、手で編集されることがあるファイルの読み込めない内容を編集途中とみなし、復元しない
+// 戻り値は、ファイルが破損していて復元を試みたかどうか
+func (c *IntegrityChecker) check(f *integrityFile, onLoad bool) bool {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			c.logger.Error("保存ファイルを読み込めません", "file", f.path, "error", err)
+		}
+		return false
+	}
+
+	sum, hasSum := readChecksum(f.path)
+	matches := hasSum && sum == sha256.Sum256(data)
+	validErr := f.validate(data)
+
+	switch {
+	case validErr == nil && (matches || !hasSum || f.editable):
+		if !matches {
+			// 初回、または手で編集された内容を受け入れる
+			if err := writeChecksum(f.path, data); err != nil {
+				c.logger.Warn("チェックサムの記録に失敗", "file", f.path, "error", err)
+			}
+		}
+		if err := c.backup(f.path, data); err != nil {
+			c.logger.Warn("保存ファイルのバックアップに失敗", "file", f.path, "error", err)
+		}
+	case validErr != nil && f.editable && !onLoad:
+		// 編集途中の可能性があるため、上書きしない
+	default:
+		reason := validErr
+		if reason == nil {
+			reason = errors.New("checksum mismatch")
+		}
+		c.restore(f, data, reason)
+		return true
+	}
+	return false
+}
+
+// backup は、data が最新のバックアップと異なる場合に、バックアップを1つずつずらして data を最新のバックアップにする
+func (c *IntegrityChecker) backup(path string, data []byte) error {
+	if latest, err := os.ReadFile(backupPath(path, 1)); err == nil && bytes.Equal(latest, data) {
+		return nil
+	}
+	_ = os.Remove(backupPath(path, c.backups))
+	for i := c.backups - 1; i >= 1; i-- {
+		if err := os.Rename(backupPath(path, i), backupPath(path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return writeFileAtomic(backupPath(path, 1), data)
+}
+
+// restore は、破損したファイルを退避し、内容が正しい最新のバックアップから復元する
+func (c *IntegrityChecker) restore(f *integrityFile, corrupt []byte, reason error) {
+	corruptPath := f.path + integrityCorruptSuffix
+	if err := writeFileAtomic(corruptPath, corrupt); err != nil {
+		c.logger.Error("破損したファイルの退避に失敗", "file", f.path, "error", err)
+		return
+	}
+
+	for i := 1; i <= c.backups; i++ {
+		data, err := os.ReadFile(backupPath(f.path, i))
+		if err != nil || f.validate(data) != nil {
+			continue
+		}
+		if err := writeFileAtomic(f.path, data); err != nil {
+			c.logger.Error("バックアップからの復元に失敗", "file", f.path, "backup", backupPath(f.path, i), "error", err)
+			return
+		}
+		if err := writeChecksum(f.path, data); err != nil {
+			c.logger.Warn("チェックサムの記録に失敗", "file", f.path, "error", err)
+		}
+		c.logger.Error("保存ファイルが破損していたため、バックアップから復元しました",
+			"file", f.path, "reason", reason, "backup", backupPath(f.path, i), "corrupt", corruptPath)
+		return
+	}
+
+	// 正しいバックアップがない場合は、破損したファイルを読み込まずに空の状態から始める
+	if err := os.Remove(f.path); err != nil {
+		c.logger.Error("破損したファイルの削除に失敗", "file", f.path, "error", err)
+		return
+	}
+	_ = os.Remove(f.path + integrityChecksumSuffix)
+	c.logger.Error("保存ファイルが破損しており、復元できるバックアップがありません。空の状態から始めます",
+		"file", f.path, "reason", reason, "corrupt", corruptPath)
+}
+
+// backupPath は、n 番目に新しいバックアップのパスを返す
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s%s%d", path, integrityBackupSuffix, n)
+}
+
+// readChecksum は、記録されたチェックサムを読み込む
+func readChecksum(path string) ([sha256.Size]byte, bool) {
+	var sum [sha256.Size]byte
+	data, err := os.ReadFile(path + integrityChecksumSuffix)
+	if err != nil {
+		return sum, false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return sum, false
+	}
+	decoded, err := hex.DecodeString(fields[0])
+	if err != nil || len(decoded) != sha256.Size {
+		return sum, false
+	}
+	copy(sum[:], decoded)
+	return sum, true
+}
+
+// writeChecksum は、data のチェックサムを sha256sum と同じ形式で記録する
+func writeChecksum(path string, data []byte) error {
+	sum := sha256.Sum256(data)
+	line := fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), filepath.Base(path))
+	return writeFileAtomic(path+integrityChecksumSuffix, []byte(line))
+}
+
+// writeFileAtomic は、一時ファイルに書き込んでからリネームすることで、書き込み途中のファイルを残さないようにする
+func writeFileAtomic(path string, data []byte) error {
+	tempFilename := path + ".tmp"
+	if err := os.WriteFile(tempFilename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write to temporary file %s: %w", tempFilename, err)
+	}
+	if err := os.Rename(tempFilename, path); err != nil {
+		_ = os.Remove(tempFilename)
+		return fmt.Errorf("failed to rename temporary file %s to %s: %w", tempFilename, path, err)
+	}
+	return nil
+}
+
+// startIntegrityMonitor は、定期的に保存ファイルを検証するゴルーチンを開始する
+func (h *ECHONETLiteHandler) startIntegrityMonitor(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultIntegrityCheckInterval
+	}
+	h.log().Info("保存ファイルの整合性チェックを開始", "interval", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				h.data.integrity.CheckAll()
+			case <-h.core.ctx.Done():
+				return
+			}
+		}
+	}()
+}
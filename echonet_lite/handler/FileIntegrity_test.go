@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// saveIntegrityFile は、IntegrityChecker を通してファイルを保存する
+func saveIntegrityFile(t *testing.T, c *IntegrityChecker, path, content string) {
+	t.Helper()
+	err := c.Save(path, func() error {
+		return os.WriteFile(path, []byte(content), 0644)
+	})
+	if err != nil {
+		t.Fatalf("Save に失敗: %v", err)
+	}
+}
+
+func readIntegrityFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile に失敗: %v", err)
+	}
+	return string(data)
+}
+
+func TestIntegrityChecker_RestoreCorruptFileFromBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devices.json")
+	c := NewIntegrityChecker(2, nil)
+	c.Register(path, nil, false)
+
+	// 保存した内容はチェック時にバックアップされる
+	saveIntegrityFile(t, c, path, `{"version":1}`)
+	c.CheckAll()
+	saveIntegrityFile(t, c, path, `{"version":2}`)
+	c.CheckAll()
+	if got := readIntegrityFile(t, backupPath(path, 1)); got != `{"version":2}` {
+		t.Errorf("最新のバックアップが不正: %s", got)
+	}
+	if got := readIntegrityFile(t, backupPath(path, 2)); got != `{"version":1}` {
+		t.Errorf("古いバックアップが不正: %s", got)
+	}
+
+	// 電源断で途中までしか書き込まれなかった
+	if err := os.WriteFile(path, []byte(`{"vers`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c.CheckOnLoad(path)
+	if got := readIntegrityFile(t, path); got != `{"version":2}` {
+		t.Errorf("バックアップから復元されていない: %s", got)
+	}
+	if got := readIntegrityFile(t, path+integrityCorruptSuffix); got != `{"vers` {
+		t.Errorf("破損したファイルが退避されていない: %s", got)
+	}
+
+	// JSON として正しくても、チェックサムが一致しなければ破損とみなす
+	if err := os.WriteFile(path, []byte(`{"version":3}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c.CheckAll()
+	if got := readIntegrityFile(t, path); got != `{"version":2}` {
+		t.Errorf("チェックサムの不一致が検出されていない: %s", got)
+	}
+}
+
+func TestIntegrityChecker_SkipsInvalidBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devices.json")
+	c := NewIntegrityChecker(3, nil)
+	c.Register(path, nil, false)
+
+	if err := os.WriteFile(backupPath(path, 1), []byte(`{"broken`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(backupPath(path, 2), []byte(`{"version":1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte{0, 0, 0, 0}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	c.CheckOnLoad(path)
+	if got := readIntegrityFile(t, path); got != `{"version":1}` {
+		t.Errorf("内容が正しい最新のバックアップから復元されていない: %s", got)
+	}
+}
+
+func TestIntegrityChecker_NoBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devices.json")
+	c := NewIntegrityChecker(0, nil)
+	c.Register(path, nil, false)
+
+	if err := os.WriteFile(path, []byte(`{"vers`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c.CheckOnLoad(path)
+
+	// 復元できない場合は空の状態から始められるようにファイルを退避する
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("破損したファイルが残っている: %v", err)
+	}
+	if got := readIntegrityFile(t, path+integrityCorruptSuffix); got != `{"vers` {
+		t.Errorf("破損したファイルが退避されていない: %s", got)
+	}
+}
+
+func TestIntegrityChecker_EditableFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aliases.json")
+	c := NewIntegrityChecker(0, nil)
+	c.Register(path, func(data []byte) error {
+		_, err := decodeAliases(data)
+		return err
+	}, true)
+
+	saveIntegrityFile(t, c, path, `{"living":"013001:000005:01"}`)
+	c.CheckAll()
+
+	// 手で編集された正しい内容は受け入れる
+	edited := `{"living":"013001:000005:01","kitchen":"029001:000005:02"}`
+	if err := os.WriteFile(path, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c.CheckAll()
+	if got := readIntegrityFile(t, path); got != edited {
+		t.Errorf("手で編集された内容が置き換えられた: %s", got)
+	}
+	if sum, ok := readChecksum(path); !ok || sum != sha256.Sum256([]byte(edited)) {
+		t.Error("編集された内容のチェックサムが記録されていない")
+	}
+
+	// 実行中は編集途中の内容を上書きしない
+	if err := os.WriteFile(path, []byte(`{"living":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c.CheckAll()
+	if got := readIntegrityFile(t, path); got != `{"living":` {
+		t.Errorf("編集途中の内容が置き換えられた: %s", got)
+	}
+
+	// 起動時に読み込めない場合は復元する
+	c.CheckOnLoad(path)
+	if got := readIntegrityFile(t, path); got != edited {
+		t.Errorf("バックアップから復元されていない: %s", got)
+	}
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileChangeType は、外部で編集されたファイルを読み込み直したときの変更の種類を表す型
+type FileChangeType string
+
+const (
+	FileChangeAdded   FileChangeType = "added"
+	FileChangeUpdated FileChangeType = "updated"
+	FileChangeDeleted FileChangeType = "deleted"
+)
+
+// AliasChange は、エイリアスファイルの読み込み直しで変わったエイリアス
+type AliasChange struct {
+	Type  FileChangeType
+	Alias string
+	ID    IDString // 削除の場合は削除前の ID
+}
+
+// GroupChange は、グループファイルの読み込み直しで変わったグループ
+type GroupChange struct {
+	Type    FileChangeType
+	Group   string
+	Devices []IDString // 削除の場合は nil
+}
+
+// ReloadNotification は、外部で編集されたファイルを読み込み直した結果を表す構造体
+type ReloadNotification struct {
+	File    string        // 読み込み直したファイル
+	Aliases []AliasChange // エイリアスの変更（エイリアスファイルの場合）
+	Groups  []GroupChange // グループの変更（グループファイルの場合）
+}
+
+// FileConflictError は、ファイルが外部で編集されたが読み込めないため、アプリからの変更を保存できない場合のエラー
+// 編集中のファイルを上書きしないよう、ファイルを修正するまで変更を受け付けない
+type FileConflictError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileConflictError) Error() string {
+	return fmt.Sprintf("%s was modified externally and could not be reloaded: %v", e.Path, e.Err)
+}
+
+func (e *FileConflictError) Unwrap() error {
+	return e.Err
+}
+
+// fileSnapshot は、ある時点のファイルの内容と状態
+type fileSnapshot struct {
+	data    []byte
+	modTime time.Time
+	size    int64
+}
+
+// watchedFile は、外部からの編集を検出するために、最後に読み書きしたファイルの内容を記録する
+// 読み込み直しと保存は mu を保持して行う
+type watchedFile struct {
+	mu       sync.Mutex
+	path     string
+	hash     [sha256.Size]byte // 最後に読み込み・保存した内容のハッシュ
+	modTime  time.Time         // 最後に確認したときの更新時刻（変わっていなければ内容を読まない）
+	size     int64             // 最後に確認したときのサイズ
+	rejected error             // 外部で編集された内容を読み込めなかった場合のエラー（修正されるまで保存しない）
+}
+
+// newWatchedFile は、現在のファイルの内容を既知の内容として記録した watchedFile を作成する
+func newWatchedFile(path string) *watchedFile {
+	w := &watchedFile{path: path}
+	w.record()
+	return w
+}
+
+// record は、現在のファイルの内容を既知の内容として記録する
+func (w *watchedFile) record() {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return
+	}
+	w.accept(fileSnapshot{data: data, modTime: info.ModTime(), size: info.Size()})
+}
+
+// check は、既知の内容から外部で変更されていれば、新しい内容を返す
+// ファイルが存在しない場合は変更なしとして扱う（エディターがファイルを置き換えている途中の場合があるため）
+func (w *watchedFile) check() (fileSnapshot, bool) {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return fileSnapshot{}, false
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return fileSnapshot{}, false
+	}
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return fileSnapshot{}, false
+	}
+	snapshot := fileSnapshot{data: data, modTime: info.ModTime(), size: info.Size()}
+	if sha256.Sum256(data) == w.hash {
+		// 更新時刻だけが変わった
+		w.accept(snapshot)
+		return fileSnapshot{}, false
+	}
+	return snapshot, true
+}
+
+// accept は、snapshot を既知の内容として記録する
+func (w *watchedFile) accept(snapshot fileSnapshot) {
+	w.hash = sha256.Sum256(snapshot.data)
+	w.modTime = snapshot.modTime
+	w.size = snapshot.size
+	w.rejected = nil
+}
+
+// reject は、読み込めなかった snapshot を記録する。同じ内容を繰り返し読み込まないよう、状態だけを更新する
+func (w *watchedFile) reject(snapshot fileSnapshot, err error) {
+	w.modTime = snapshot.modTime
+	w.size = snapshot.size
+	w.rejected = err
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newWatchedDataHandler は、一時ディレクトリのエイリアス・グループファイルを監視する DataManagementHandler を作成する
+func newWatchedDataHandler(t *testing.T) (*DataManagementHandler, string, string, chan ReloadNotification) {
+	t.Helper()
+	dir := t.TempDir()
+	aliasesFile := filepath.Join(dir, "aliases.json")
+	groupsFile := filepath.Join(dir, "groups.json")
+	writeWatchedFile(t, aliasesFile, `{"living":"013001:000005:01"}`)
+	writeWatchedFile(t, groupsFile, `[{"group":"@1F","devices":["013001:000005:01"]}]`)
+
+	aliases := NewDeviceAliases()
+	if err := aliases.LoadFromFile(aliasesFile); err != nil {
+		t.Fatalf("LoadFromFile に失敗: %v", err)
+	}
+	groups := NewDeviceGroups()
+	if err := groups.LoadFromFile(groupsFile); err != nil {
+		t.Fatalf("LoadFromFile に失敗: %v", err)
+	}
+	h := NewDataManagementHandler(NewDevices(), aliases, groups, NewLocationSettings(), nil, nil, nil)
+	reloadCh := make(chan ReloadNotification, 10)
+	h.SetWatchedFiles(aliasesFile, groupsFile, reloadCh)
+	return h, aliasesFile, groupsFile, reloadCh
+}
+
+func writeWatchedFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("ファイルの書き込みに失敗: %v", err)
+	}
+}
+
+func TestDataManagementHandler_ReloadExternallyEditedFiles(t *testing.T) {
+	h, aliasesFile, groupsFile, reloadCh := newWatchedDataHandler(t)
+
+	// 変更がなければ何も通知しない
+	h.ReloadChangedFiles()
+	if len(reloadCh) != 0 {
+		t.Fatalf("変更がないのに通知された: %+v", <-reloadCh)
+	}
+
+	writeWatchedFile(t, aliasesFile, `{"kitchen":"029001:000005:02","living":"013001:000005:02"}`)
+	writeWatchedFile(t, groupsFile, `[{"group":"@2F","devices":["029001:000005:02"]}]`)
+	h.ReloadChangedFiles()
+
+	if len(reloadCh) != 2 {
+		t.Fatalf("通知の数が不正: %d", len(reloadCh))
+	}
+	aliasReload := <-reloadCh
+	wantAliases := []AliasChange{
+		{Type: FileChangeAdded, Alias: "kitchen", ID: "029001:000005:02"},
+		{Type: FileChangeUpdated, Alias: "living", ID: "013001:000005:02"},
+	}
+	if aliasReload.File != aliasesFile || len(aliasReload.Aliases) != len(wantAliases) {
+		t.Fatalf("エイリアスの通知が不正: %+v", aliasReload)
+	}
+	for i, want := range wantAliases {
+		if aliasReload.Aliases[i] != want {
+			t.Errorf("Aliases[%d] = %+v, want %+v", i, aliasReload.Aliases[i], want)
+		}
+	}
+	groupReload := <-reloadCh
+	if groupReload.File != groupsFile || len(groupReload.Groups) != 2 {
+		t.Fatalf("グループの通知が不正: %+v", groupReload)
+	}
+	if g := groupReload.Groups[0]; g.Type != FileChangeDeleted || g.Group != "@1F" {
+		t.Errorf("Groups[0] が不正: %+v", g)
+	}
+	if g := groupReload.Groups[1]; g.Type != FileChangeAdded || g.Group != "@2F" || len(g.Devices) != 1 {
+		t.Errorf("Groups[1] が不正: %+v", g)
+	}
+
+	if id, ok := h.DeviceAliases.FindByAlias("kitchen"); !ok || id != "029001:000005:02" {
+		t.Errorf("読み込み直したエイリアスが反映されていない: %v %v", id, ok)
+	}
+
+	// アプリからの変更は外部での編集の上に適用され、保存しても通知しない
+	if err := h.GroupAdd("@3F", []IDString{"013001:000005:01"}); err != nil {
+		t.Fatalf("GroupAdd に失敗: %v", err)
+	}
+	h.ReloadChangedFiles()
+	if len(reloadCh) != 0 {
+		t.Fatalf("アプリからの保存が外部の編集として通知された: %+v", <-reloadCh)
+	}
+	data, err := os.ReadFile(groupsFile)
+	if err != nil {
+		t.Fatalf("ReadFile に失敗: %v", err)
+	}
+	if !strings.Contains(string(data), "@2F") || !strings.Contains(string(data), "@3F") {
+		t.Errorf("外部での編集が上書きされた: %s", data)
+	}
+}
+
+func TestDataManagementHandler_InvalidExternalEditIsNotOverwritten(t *testing.T) {
+	h, aliasesFile, _, reloadCh := newWatchedDataHandler(t)
+
+	broken := `{"living":"013001:000005:01",`
+	writeWatchedFile(t, aliasesFile, broken)
+	h.ReloadChangedFiles()
+	if len(reloadCh) != 0 {
+		t.Fatalf("読み込めないファイルが通知された: %+v", <-reloadCh)
+	}
+
+	// 修正されるまで変更を保存しない
+	living := "living"
+	err := h.AliasDelete(&living)
+	var conflict *FileConflictError
+	if !errors.As(err, &conflict) || conflict.Path != aliasesFile {
+		t.Fatalf("FileConflictError が返されない: %v", err)
+	}
+	data, err := os.ReadFile(aliasesFile)
+	if err != nil {
+		t.Fatalf("ReadFile に失敗: %v", err)
+	}
+	if string(data) != broken {
+		t.Errorf("編集中のファイルが上書きされた: %s", data)
+	}
+	if _, ok := h.DeviceAliases.FindByAlias("living"); !ok {
+		t.Error("読み込めなかった場合は以前のエイリアスを保持するはず")
+	}
+
+	// 修正されると読み込み直し、変更を受け付ける
+	writeWatchedFile(t, aliasesFile, `{"living":"013001:000005:01","bedroom":"013001:000005:03"}`)
+	if err := h.AliasDelete(&living); err != nil {
+		t.Fatalf("AliasDelete に失敗: %v", err)
+	}
+	if _, ok := h.DeviceAliases.FindByAlias("bedroom"); !ok {
+		t.Error("修正したファイルが読み込み直されていない")
+	}
+	if _, ok := h.DeviceAliases.FindByAlias("living"); ok {
+		t.Error("エイリアスが削除されていない")
+	}
+}
@@ -0,0 +1,114 @@
+package handler
+
+import "echonet-list/echonet_lite"
+
+const (
+	// maxPropertiesPerFrame は、OPC（1バイト）で表せるプロパティ数の上限
+	maxPropertiesPerFrame = 255
+	// frameHeaderSize は、EHD, TID, SEOJ, DEOJ, ESV, OPC の大きさ
+	frameHeaderSize = 12
+)
+
+// FrameLimits は、1つの要求フレームに含めるプロパティの上限（ゼロ値の場合は OPC の上限のみ）
+// プロパティの多い要求や大きな要求を受け付けない機器があるため、
+// 上限を超える Get/Set の要求は複数のフレームに分けて送信し、結果をまとめて返す
+type FrameLimits struct {
+	MaxProperties int // 1フレームに含めるプロパティ数の上限（0の場合は255）
+	MaxFrameSize  int // 1フレームの大きさの上限（バイト数。0の場合は制限なし）
+}
+
+// maxProperties は、1フレームに含めるプロパティ数の上限を返す
+func (l FrameLimits) maxProperties() int {
+	if l.MaxProperties <= 0 || l.MaxProperties > maxPropertiesPerFrame {
+		return maxPropertiesPerFrame
+	}
+	return l.MaxProperties
+}
+
+// splitProperties は、properties を順序を保ったまま上限に収まるフレームごとに分ける
+// 1つで MaxFrameSize を超えるプロパティは、単独のフレームにする
+func (l FrameLimits) splitProperties(properties Properties) []Properties {
+	maxProperties := l.maxProperties()
+	var frames []Properties
+	var frame Properties
+	size := frameHeaderSize
+	for _, p := range properties {
+		propertySize := 2 + len(p.EDT) // EPC, PDC, EDT
+		if len(frame) > 0 && (len(frame) >= maxProperties || (l.MaxFrameSize > 0 && size+propertySize > l.MaxFrameSize)) {
+			frames = append(frames, frame)
+			frame = nil
+			size = frameHeaderSize
+		}
+		frame = append(frame, p)
+		size += propertySize
+	}
+	if len(frame) > 0 || len(frames) == 0 {
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// splitEPCs は、Get の要求の EPCs を上限に収まるフレームごとに分ける
+func (l FrameLimits) splitEPCs(EPCs []EPCType) [][]EPCType {
+	properties := make(Properties, 0, len(EPCs))
+	for _, epc := range EPCs {
+		properties = append(properties, echonet_lite.Property{EPC: epc})
+	}
+	frames := l.splitProperties(properties)
+	result := make([][]EPCType, 0, len(frames))
+	for _, frame := range frames {
+		epcs := make([]EPCType, 0, len(frame))
+		for _, p := range frame {
+			epcs = append(epcs, p.EPC)
+		}
+		result = append(result, epcs)
+	}
+	return result
+}
+
+// mergeBroadcastResults は、フレームごとのブロードキャストの結果をデバイスごとにまとめる
+// いずれかのフレームに応答したデバイスは、応答したフレームのプロパティをまとめた1つの応答にする
+// すべてのフレームが Get_Res の場合のみ、まとめた応答を Get_Res とする
+func mergeBroadcastResults(frames [][]BroadcastResult) []BroadcastResult {
+	if len(frames) == 1 {
+		return frames[0]
+	}
+	var merged []BroadcastResult
+	index := make(map[string]int)
+	for _, results := range frames {
+		for _, result := range results {
+			key := result.Device.Key()
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(merged)
+				merged = append(merged, BroadcastResult{Device: result.Device, Error: result.Error})
+				i = len(merged) - 1
+			}
+			m := &merged[i]
+			if result.Response == nil {
+				if m.Response != nil {
+					m.Response.ESV = echonet_lite.ESVGet_SNA
+				} else if m.Error == nil {
+					m.Error = result.Error
+				}
+				continue
+			}
+			if m.Response == nil {
+				response := *result.Response
+				response.Properties = append(Properties(nil), result.Response.Properties...)
+				if m.Error != nil {
+					// 先のフレームには応答しなかった
+					response.ESV = echonet_lite.ESVGet_SNA
+				}
+				m.Response = &response
+				m.Error = nil
+				continue
+			}
+			if result.Response.ESV != echonet_lite.ESVGet_Res {
+				m.Response.ESV = result.Response.ESV
+			}
+			m.Response.Properties = append(m.Response.Properties, result.Response.Properties...)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"context"
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/simulator"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFrameLimits_splitProperties(t *testing.T) {
+	properties := Properties{
+		{EPC: 0x80, EDT: []byte{0x30}},
+		{EPC: 0xB0, EDT: []byte{0x41}},
+		{EPC: 0xB3, EDT: []byte{0x1A}},
+		{EPC: 0xE0, EDT: make([]byte, 20)},
+	}
+
+	tests := []struct {
+		name   string
+		limits FrameLimits
+		want   []int // フレームごとのプロパティ数
+	}{
+		{"制限なし", FrameLimits{}, []int{4}},
+		{"プロパティ数", FrameLimits{MaxProperties: 3}, []int{3, 1}},
+		{"フレームの大きさ", FrameLimits{MaxFrameSize: frameHeaderSize + 9}, []int{3, 1}},
+		{"上限を超えるプロパティは単独のフレーム", FrameLimits{MaxFrameSize: frameHeaderSize + 3}, []int{1, 1, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames := tt.limits.splitProperties(properties)
+			if len(frames) != len(tt.want) {
+				t.Fatalf("フレーム数 %d, 期待値 %d: %v", len(frames), len(tt.want), frames)
+			}
+			var epcs []EPCType
+			for i, frame := range frames {
+				if len(frame) != tt.want[i] {
+					t.Errorf("フレーム %d のプロパティ数 %d, 期待値 %d", i, len(frame), tt.want[i])
+				}
+				for _, p := range frame {
+					epcs = append(epcs, p.EPC)
+				}
+			}
+			for i, p := range properties {
+				if epcs[i] != p.EPC {
+					t.Errorf("プロパティの順序が変わった: %v", epcs)
+					break
+				}
+			}
+		})
+	}
+
+	// OPC は1バイトのため、指定がなくても255個ごとに分ける
+	epcs := make([]EPCType, 300)
+	if frames := (FrameLimits{}).splitEPCs(epcs); len(frames) != 2 || len(frames[0]) != 255 {
+		t.Errorf("OPC の上限で分けられていない: %d フレーム", len(frames))
+	}
+}
+
+func TestMergeBroadcastResults(t *testing.T) {
+	ip := net.ParseIP("192.0.2.1")
+	light1 := echonet_lite.IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)}
+	light2 := echonet_lite.IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 2)}
+	timeout := errors.New("no response received")
+
+	merged := mergeBroadcastResults([][]BroadcastResult{
+		{
+			{Device: light1, Response: &echonet_lite.ECHONETLiteMessage{ESV: echonet_lite.ESVGet_Res, Properties: Properties{{EPC: 0x80, EDT: []byte{0x30}}}}},
+			{Device: light2, Error: timeout},
+		},
+		{
+			{Device: light1, Response: &echonet_lite.ECHONETLiteMessage{ESV: echonet_lite.ESVGet_Res, Properties: Properties{{EPC: 0xB0, EDT: []byte{0x41}}}}},
+			{Device: light2, Response: &echonet_lite.ECHONETLiteMessage{ESV: echonet_lite.ESVGet_Res, Properties: Properties{{EPC: 0xB0, EDT: []byte{0x42}}}}},
+		},
+	})
+	if len(merged) != 2 {
+		t.Fatalf("結果の数 %d, 期待値 2", len(merged))
+	}
+	if r := merged[0]; r.Error != nil || r.Response.ESV != echonet_lite.ESVGet_Res || len(r.Response.Properties) != 2 {
+		t.Errorf("すべてのフレームに応答したデバイスの結果が不正: %+v", r)
+	}
+	// 一部のフレームにだけ応答したデバイスは、応答したプロパティを持つ Get_SNA にする
+	if r := merged[1]; r.Error != nil || r.Response.ESV != echonet_lite.ESVGet_SNA || len(r.Response.Properties) != 1 {
+		t.Errorf("一部のフレームに応答したデバイスの結果が不正: %+v", r)
+	}
+}
+
+// countingConnection は、送信したフレームの数を数える
+type countingConnection struct {
+	*simulator.Network
+	sent atomic.Int32
+}
+
+func (c *countingConnection) SendTo(dstIP net.IP, data []byte) (int, error) {
+	c.sent.Add(1)
+	return c.Network.SendTo(dstIP, data)
+}
+
+func TestSession_FrameLimits(t *testing.T) {
+	ip := net.ParseIP("192.0.2.1")
+	aircon := echonet_lite.IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	conn := &countingConnection{Network: simulator.NewNetwork([]simulator.NodeSpec{{
+		IP: ip,
+		Devices: []simulator.DeviceSpec{{
+			EOJ: aircon.EOJ,
+			Properties: Properties{
+				{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}},
+				{EPC: 0xB0, EDT: []byte{0x41}},
+				{EPC: 0xB3, EDT: []byte{0x1A}},
+			},
+			Settable: []EPCType{echonet_lite.EPCOperationStatus, 0xB0, 0xB3},
+		}},
+	}})}
+	session := CreateSessionWithConnection(context.Background(), conn, echonet_lite.MakeEOJ(echonet_lite.Controller_ClassCode, 1), false, nil)
+	defer session.Close()
+	session.SetFrameLimits(FrameLimits{MaxProperties: 2})
+	go session.MainLoop()
+
+	epcs := []EPCType{echonet_lite.EPCOperationStatus, 0xB0, 0xB3}
+	success, properties, failed, err := session.GetProperties(context.Background(), aircon, epcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !success || len(failed) != 0 || len(properties) != 3 || properties[2].EPC != 0xB3 {
+		t.Errorf("Get の結果が不正: success=%v properties=%v failed=%v", success, properties, failed)
+	}
+	if n := conn.sent.Load(); n != 2 {
+		t.Errorf("Get のフレーム数 %d, 期待値 2", n)
+	}
+
+	conn.sent.Store(0)
+	success, properties, failed, err = session.SetProperties(context.Background(), aircon, Properties{
+		{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x31}},
+		{EPC: 0xB0, EDT: []byte{0x42}},
+		{EPC: 0xB3, EDT: []byte{0x18}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !success || len(failed) != 0 || len(properties) != 3 {
+		t.Errorf("Set の結果が不正: success=%v properties=%v failed=%v", success, properties, failed)
+	}
+	if n := conn.sent.Load(); n != 2 {
+		t.Errorf("Set のフレーム数 %d, 期待値 2", n)
+	}
+}
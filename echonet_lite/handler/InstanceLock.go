@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// instanceLockSuffix は、デバイスファイルの横に作るロックファイルの拡張子
+const instanceLockSuffix = ".lock"
+
+// errLockHeld は、lockFile で他のプロセスがロックを保持していた場合のエラー
+var errLockHeld = errors.New("ロックは他のプロセスが保持しています")
+
+// InstanceLockedError は、別のインスタンスが同じデータファイルを使用中で起動できない場合のエラー
+type InstanceLockedError struct {
+	LockFile string // ロックファイルのパス
+	PID      int    // ロックを保持しているプロセスID（不明な場合は 0）
+}
+
+func (e *InstanceLockedError) Error() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("別のインスタンス (PID %d) が同じデータファイルを使用中です (lock: %s)", e.PID, e.LockFile)
+	}
+	return fmt.Sprintf("別のインスタンスが同じデータファイルを使用中です (lock: %s)", e.LockFile)
+}
+
+// instanceLock は、devices.json などのデータファイルを1つのプロセスだけが書き込むためのロック
+// ロックはプロセスが終了すると OS によって解放されるため、異常終了してもロックファイルが残るだけで次の起動は妨げない
+type instanceLock struct {
+	file *os.File
+}
+
+// acquireInstanceLock は、ロックファイルを排他ロックし、自分のPIDを書き込む
+// 他のプロセスがロックしている場合は待たずに *InstanceLockedError を返す
+func acquireInstanceLock(path string) (*instanceLock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("ロックファイルを開けません (file: %s): %w", path, err)
+	}
+	if err := lockFile(file); err != nil {
+		pid := readLockPID(file)
+		file.Close()
+		if errors.Is(err, errLockHeld) {
+			return nil, &InstanceLockedError{LockFile: path, PID: pid}
+		}
+		return nil, fmt.Errorf("ロックファイルをロックできません (file: %s): %w", path, err)
+	}
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &instanceLock{file: file}, nil
+}
+
+// readLockPID は、ロックファイルに書かれたPIDを読む。読めない場合は 0
+func readLockPID(file *os.File) int {
+	buf := make([]byte, 32)
+	n, _ := file.ReadAt(buf, 0)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+// release はロックを解放する。nil の場合は何もしない
+// ロックファイル自体は削除しない（削除すると、開いた直後の他のプロセスと別のファイルをロックしてしまうため）
+func (l *instanceLock) release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	_ = l.file.Truncate(0)
+	err := unlockFile(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestInstanceLock_SecondAcquireFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devices.json.lock")
+
+	first, err := acquireInstanceLock(path)
+	if err != nil {
+		t.Fatalf("最初のロックに失敗: %v", err)
+	}
+
+	// ロックファイルには保持しているプロセスのPIDが書かれる
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile に失敗: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != strconv.Itoa(os.Getpid()) {
+		t.Errorf("ロックファイルの内容 = %q, want PID %d", got, os.Getpid())
+	}
+
+	// 2つ目のロックは待たずに InstanceLockedError になる
+	_, err = acquireInstanceLock(path)
+	var locked *InstanceLockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("InstanceLockedError を期待したが %v", err)
+	}
+	if locked.PID != os.Getpid() || locked.LockFile != path {
+		t.Errorf("エラーの内容が不正: %+v", locked)
+	}
+
+	// 解放後は再びロックできる
+	if err := first.release(); err != nil {
+		t.Fatalf("release に失敗: %v", err)
+	}
+	second, err := acquireInstanceLock(path)
+	if err != nil {
+		t.Fatalf("解放後のロックに失敗: %v", err)
+	}
+	if err := second.release(); err != nil {
+		t.Errorf("release に失敗: %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package handler
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockRegion はロックするバイト範囲。PIDを書き込む先頭部分は他のプロセスから読めるよう、ファイルの末尾より先をロックする
+var lockRegion = windows.Overlapped{OffsetHigh: 1}
+
+// lockFile は LockFileEx でファイルを排他ロックする。既にロックされている場合は errLockHeld を返す
+func lockFile(file *os.File) error {
+	ol := lockRegion
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &ol)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errLockHeld
+	}
+	return err
+}
+
+func unlockFile(file *os.File) error {
+	ol := lockRegion
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &ol)
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"sync"
+	"time"
+
+	"echonet-list/echonet_lite"
+)
+
+// DefaultLivenessInterval は、LivenessOptions.Interval を省略したときの間隔
+const DefaultLivenessInterval = 5 * time.Minute
+
+// LivenessOptions は、しばらく応答のないノードを能動的に確認する設定
+// 通常、オフラインは要求の再送が上限に達したときにしか検出されないため、
+// 誰も問い合わせないデバイスはいつまでもオンラインのままになる。
+// 有効にすると、Interval の間どのデバイスからも応答のないノードの NodeProfile に自ノードインスタンスリストSを問い合わせ、
+// 応答がなければ既存のタイムアウト処理でオフラインにする（DeviceOffline が通知される）。
+type LivenessOptions struct {
+	Enabled bool
+	// この時間、ノードのどのデバイスからも応答がなければ問い合わせる（0の場合は DefaultLivenessInterval）
+	Interval time.Duration
+	// 問い合わせに失敗したノードは、失敗するごとに間隔を2倍にする。その上限（0の場合は Interval の16倍）
+	MaxInterval time.Duration
+}
+
+func (o LivenessOptions) interval() time.Duration {
+	if o.Interval <= 0 {
+		return DefaultLivenessInterval
+	}
+	return o.Interval
+}
+
+func (o LivenessOptions) maxInterval() time.Duration {
+	if o.MaxInterval <= 0 {
+		return o.interval() * 16
+	}
+	return max(o.MaxInterval, o.interval())
+}
+
+// livenessNode は、ノードごとの問い合わせの状態
+type livenessNode struct {
+	failures int       // 続けて失敗した回数
+	retryAt  time.Time // 失敗した後、次に問い合わせてよい時刻
+	pinging  bool      // 問い合わせ中
+}
+
+// livenessTracker は、どのノードに問い合わせるかを決める
+type livenessTracker struct {
+	opts  LivenessOptions
+	mu    sync.Mutex
+	nodes map[string]*livenessNode
+}
+
+func newLivenessTracker(opts LivenessOptions) *livenessTracker {
+	return &livenessTracker{opts: opts, nodes: make(map[string]*livenessNode)}
+}
+
+// backoff は、続けて failures 回失敗したノードに次に問い合わせるまでの間隔を返す
+func (t *livenessTracker) backoff(failures int) time.Duration {
+	d := t.opts.interval()
+	limit := t.opts.maxInterval()
+	for i := 1; i < failures && d < limit; i++ {
+		d *= 2
+	}
+	return min(d, limit)
+}
+
+// start は、最後に応答があった時刻が lastSeen のノードに今問い合わせるべきかを判定し、問い合わせる場合は問い合わせ中にする
+func (t *livenessTracker) start(key string, lastSeen, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	node, ok := t.nodes[key]
+	if !ok {
+		node = &livenessNode{}
+		t.nodes[key] = node
+	}
+	if node.pinging || now.Sub(lastSeen) < t.opts.interval() || now.Before(node.retryAt) {
+		return false
+	}
+	node.pinging = true
+	return true
+}
+
+// finish は、問い合わせの結果を記録する
+func (t *livenessTracker) finish(key string, ok bool, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	node := t.nodes[key]
+	if node == nil {
+		return
+	}
+	node.pinging = false
+	if ok {
+		node.failures = 0
+		node.retryAt = time.Time{}
+		return
+	}
+	node.failures++
+	node.retryAt = now.Add(t.backoff(node.failures))
+}
+
+// startLivenessMonitor は、応答のないノードを定期的に確認するゴルーチンを開始する
+func (h *ECHONETLiteHandler) startLivenessMonitor(opts LivenessOptions) {
+	tracker := newLivenessTracker(opts)
+	tick := max(opts.interval()/4, time.Second)
+	h.log().Info("ノードの死活監視を開始", "interval", opts.interval(), "maxInterval", opts.maxInterval())
+	go func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				h.checkLiveness(tracker, time.Now())
+			case <-h.core.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// nodeLastSeen は、ノードのいずれかのデバイスから最後に応答があった時刻を返す
+func (h *ECHONETLiteHandler) nodeLastSeen(nodeProfile IPAndEOJ) time.Time {
+	var lastSeen time.Time
+	for _, device := range h.data.devices.ListIPAndEOJ() {
+		if !device.IP.Equal(nodeProfile.IP) {
+			continue
+		}
+		if t := h.data.GetLastUpdateTime(device); t.After(lastSeen) {
+			lastSeen = t
+		}
+	}
+	if lastSeen.IsZero() {
+		lastSeen = h.startTime
+	}
+	return lastSeen
+}
+
+// checkLiveness は、しばらく応答のないノードの NodeProfile に問い合わせる
+// 応答がなければ Session のタイムアウト通知からノードの全デバイスがオフラインになり、
+// 応答があればインスタンスリストからオフラインだったデバイスが復帰する
+func (h *ECHONETLiteHandler) checkLiveness(tracker *livenessTracker, now time.Time) {
+	for _, device := range h.data.devices.ListIPAndEOJ() {
+		if device.EOJ != echonet_lite.NodeProfileObject {
+			continue
+		}
+		key := device.IP.String()
+		if !tracker.start(key, h.nodeLastSeen(device), now) {
+			continue
+		}
+		go func(nodeProfile IPAndEOJ) {
+			result, err := h.comm.GetProperties(nodeProfile, []EPCType{echonet_lite.EPC_NPO_SelfNodeInstanceListS}, true)
+			ok := err == nil && len(result.Properties) > 0
+			if !ok {
+				h.log().Debug("ノードの死活確認に応答なし", "ip", key, "error", err)
+			}
+			tracker.finish(key, ok, time.Now())
+		}(device)
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLivenessTracker(t *testing.T) {
+	tracker := newLivenessTracker(LivenessOptions{Enabled: true, Interval: time.Minute, MaxInterval: 5 * time.Minute})
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	const key = "192.168.1.10"
+
+	// 最近応答のあったノードには問い合わせない
+	if tracker.start(key, now.Add(-30*time.Second), now) {
+		t.Error("expected no ping for a node seen 30s ago")
+	}
+	if !tracker.start(key, now.Add(-time.Minute), now) {
+		t.Fatal("expected a ping for a node quiet for a minute")
+	}
+	// 問い合わせ中は重ねて問い合わせない
+	if tracker.start(key, now.Add(-time.Minute), now) {
+		t.Error("expected no second ping while pinging")
+	}
+
+	// 失敗するごとに間隔が2倍になり、上限で止まる
+	lastSeen := now.Add(-time.Hour)
+	for i, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+		tracker.finish(key, false, now)
+		if tracker.start(key, lastSeen, now.Add(want-time.Second)) {
+			t.Errorf("failure %d: expected no ping before %v", i+1, want)
+		}
+		now = now.Add(want)
+		if !tracker.start(key, lastSeen, now) {
+			t.Fatalf("failure %d: expected a ping after %v", i+1, want)
+		}
+	}
+
+	// 応答があれば間隔は最後の応答からの時間に戻る
+	tracker.finish(key, true, now)
+	if tracker.start(key, now, now.Add(30*time.Second)) {
+		t.Error("expected no ping right after an answer")
+	}
+	if !tracker.start(key, now, now.Add(time.Minute)) {
+		t.Error("expected a ping one interval after an answer")
+	}
+}
+
+func TestLivenessOptionsDefaults(t *testing.T) {
+	var opts LivenessOptions
+	if opts.interval() != DefaultLivenessInterval || opts.maxInterval() != 16*DefaultLivenessInterval {
+		t.Errorf("unexpected defaults: %v %v", opts.interval(), opts.maxInterval())
+	}
+	// 上限は間隔より短くならない
+	opts = LivenessOptions{Interval: time.Hour, MaxInterval: time.Minute}
+	if opts.maxInterval() != time.Hour {
+		t.Errorf("expected the max interval to be at least the interval, got %v", opts.maxInterval())
+	}
+}
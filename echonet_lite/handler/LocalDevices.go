@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"echonet-list/echonet_lite"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// String は、プロパティマップの種類の名前（"get", "set", "announce"）を返す
+func (t PropertyMapType) String() string {
+	switch t {
+	case GetPropertyMap:
+		return "get"
+	case SetPropertyMap:
+		return "set"
+	case StatusAnnouncementPropertyMap:
+		return "announce"
+	}
+	return fmt.Sprintf("PropertyMapType(%d)", int(t))
+}
+
+// EPC は、プロパティマップの種類に対応するEPCを返す
+func (t PropertyMapType) EPC() EPCType {
+	switch t {
+	case SetPropertyMap:
+		return echonet_lite.EPCSetPropertyMap
+	case StatusAnnouncementPropertyMap:
+		return echonet_lite.EPCStatusAnnouncementPropertyMap
+	}
+	return echonet_lite.EPCGetPropertyMap
+}
+
+// ParsePropertyMapType は、プロパティマップの種類の名前をパースする
+func ParsePropertyMapType(s string) (PropertyMapType, error) {
+	for _, t := range []PropertyMapType{GetPropertyMap, SetPropertyMap, StatusAnnouncementPropertyMap} {
+		if s == t.String() {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("プロパティマップの種類は get, set, announce のいずれかです: %s", s)
+}
+
+// LocalPropertyMap は、自ノードのデバイスのプロパティマップ
+type LocalPropertyMap struct {
+	EOJ        EOJ
+	Get        PropertyMap
+	Set        PropertyMap
+	Announce   PropertyMap
+	Overridden []PropertyMapType // 実行時に変更されたプロパティマップ
+}
+
+// LocalDevices は、自ノードが所有するデバイスのプロパティを管理する構造体
+// 他のコントローラーの動作を確かめるため、プロパティマップを実行時に変更できる
+type LocalDevices struct {
+	devices   DeviceProperties
+	announce  map[EOJ]Property                        // 状態通知プロパティマップの初期値
+	overrides map[EOJ]map[PropertyMapType]PropertyMap // 変更したプロパティマップ
+	mutex     sync.RWMutex
+}
+
+// NewLocalDevices は、devices を初期状態とする LocalDevices を作成する
+func NewLocalDevices(devices DeviceProperties) *LocalDevices {
+	announce := make(map[EOJ]Property)
+	for eoj, props := range devices {
+		if p, ok := props[echonet_lite.EPCStatusAnnouncementPropertyMap]; ok {
+			announce[eoj] = p
+		}
+	}
+	return &LocalDevices{
+		devices:   devices,
+		announce:  announce,
+		overrides: make(map[EOJ]map[PropertyMapType]PropertyMap),
+	}
+}
+
+func (l *LocalDevices) Get(eoj EOJ, epc EPCType) (Property, bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.devices.Get(eoj, epc)
+}
+
+func (l *LocalDevices) GetProperties(eoj EOJ, properties Properties) (Properties, bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.devices.GetProperties(eoj, properties)
+}
+
+func (l *LocalDevices) SetProperties(eoj EOJ, properties Properties) (Properties, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.devices.SetProperties(eoj, properties)
+}
+
+func (l *LocalDevices) GetInstanceList() []EOJ {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.devices.GetInstanceList()
+}
+
+func (l *LocalDevices) FindEOJ(deoj EOJ) []EOJ {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.devices.FindEOJ(deoj)
+}
+
+func (l *LocalDevices) IsAnnouncementTarget(eoj EOJ, epc EPCType) bool {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.devices.IsAnnouncementTarget(eoj, epc)
+}
+
+// PropertyMaps は、すべてのデバイスのプロパティマップを EOJ の順に返す
+func (l *LocalDevices) PropertyMaps() []LocalPropertyMap {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	result := make([]LocalPropertyMap, 0, len(l.devices))
+	for eoj := range l.devices {
+		m := LocalPropertyMap{EOJ: eoj}
+		for _, t := range []PropertyMapType{GetPropertyMap, SetPropertyMap, StatusAnnouncementPropertyMap} {
+			propertyMap := make(PropertyMap)
+			if p, ok := l.devices.Get(eoj, t.EPC()); ok {
+				if decoded := echonet_lite.DecodePropertyMap(p.EDT); decoded != nil {
+					propertyMap = decoded
+				}
+			}
+			switch t {
+			case GetPropertyMap:
+				m.Get = propertyMap
+			case SetPropertyMap:
+				m.Set = propertyMap
+			case StatusAnnouncementPropertyMap:
+				m.Announce = propertyMap
+			}
+			if _, ok := l.overrides[eoj][t]; ok {
+				m.Overridden = append(m.Overridden, t)
+			}
+		}
+		result = append(result, m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].EOJ < result[j].EOJ })
+	return result
+}
+
+// SetPropertyMap は、デバイスのプロパティマップを変更する。epcs が nil の場合は初期状態に戻す
+func (l *LocalDevices) SetPropertyMap(eoj EOJ, mapType PropertyMapType, epcs []EPCType) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.setPropertyMap(eoj, mapType, epcs)
+}
+
+func (l *LocalDevices) setPropertyMap(eoj EOJ, mapType PropertyMapType, epcs []EPCType) error {
+	if _, ok := l.devices[eoj]; !ok {
+		return fmt.Errorf("自ノードにデバイス %v がありません", eoj)
+	}
+	if mapType < GetPropertyMap || mapType > StatusAnnouncementPropertyMap {
+		return fmt.Errorf("不明なプロパティマップの種類です: %v", mapType)
+	}
+
+	if epcs == nil {
+		delete(l.overrides[eoj], mapType)
+		if len(l.overrides[eoj]) == 0 {
+			delete(l.overrides, eoj)
+		}
+		switch mapType {
+		case StatusAnnouncementPropertyMap:
+			if p, ok := l.announce[eoj]; ok {
+				l.devices[eoj][p.EPC] = p
+			} else {
+				delete(l.devices[eoj], mapType.EPC())
+			}
+		default:
+			// Get/Set プロパティマップは保持しているプロパティから計算する
+			delete(l.devices[eoj], mapType.EPC())
+		}
+		return nil
+	}
+
+	propertyMap := make(PropertyMap)
+	for _, epc := range epcs {
+		if epc < 0x80 {
+			return fmt.Errorf("プロパティマップに含められるEPCは 80〜FF です: %v", epc)
+		}
+		propertyMap.Set(epc)
+	}
+	if l.overrides[eoj] == nil {
+		l.overrides[eoj] = make(map[PropertyMapType]PropertyMap)
+	}
+	l.overrides[eoj][mapType] = propertyMap
+	l.devices[eoj][mapType.EPC()] = Property{EPC: mapType.EPC(), EDT: propertyMap.Encode()}
+	return nil
+}
+
+// localPropertyMapsEntry は、変更したプロパティマップのファイル上の形式
+type localPropertyMapsEntry struct {
+	EOJ  string              `json:"eoj"`  // "CCCC:I"
+	Maps map[string][]string `json:"maps"` // "get", "set", "announce" -> EPC（16進数2桁）
+}
+
+// LoadFromFile は、変更したプロパティマップをファイルから読み込んで適用する
+// ファイルにあって自ノードにないデバイスは無視する
+func (l *LocalDevices) LoadFromFile(filename string) error {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("プロパティマップファイルを開けません: %v", err)
+	}
+
+	var entries []localPropertyMapsEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("プロパティマップファイルの解析に失敗しました: %v", err)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, entry := range entries {
+		eoj, err := ParseEOJString(entry.EOJ)
+		if err != nil {
+			return fmt.Errorf("プロパティマップファイルの内容が不正です: %v", err)
+		}
+		if _, ok := l.devices[eoj]; !ok {
+			continue
+		}
+		for name, epcStrings := range entry.Maps {
+			mapType, err := ParsePropertyMapType(name)
+			if err != nil {
+				return fmt.Errorf("プロパティマップファイルの内容が不正です: %v", err)
+			}
+			epcs := make([]EPCType, 0, len(epcStrings))
+			for _, s := range epcStrings {
+				epc, err := ParseEPCString(s)
+				if err != nil {
+					return fmt.Errorf("プロパティマップファイルの内容が不正です: %v", err)
+				}
+				epcs = append(epcs, epc)
+			}
+			if err := l.setPropertyMap(eoj, mapType, epcs); err != nil {
+				return fmt.Errorf("プロパティマップファイルの内容が不正です: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// SaveToFile は、変更したプロパティマップをファイルに保存する
+func (l *LocalDevices) SaveToFile(filename string) error {
+	l.mutex.RLock()
+	entries := make([]localPropertyMapsEntry, 0, len(l.overrides))
+	for eoj, maps := range l.overrides {
+		entry := localPropertyMapsEntry{EOJ: eoj.Specifier(), Maps: make(map[string][]string, len(maps))}
+		for mapType, propertyMap := range maps {
+			epcs := propertyMap.EPCs()
+			sort.Slice(epcs, func(i, j int) bool { return epcs[i] < epcs[j] })
+			epcStrings := make([]string, 0, len(epcs))
+			for _, epc := range epcs {
+				epcStrings = append(epcStrings, fmt.Sprintf("%02X", byte(epc)))
+			}
+			entry.Maps[mapType.String()] = epcStrings
+		}
+		entries = append(entries, entry)
+	}
+	l.mutex.RUnlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].EOJ < entries[j].EOJ })
+
+	// ディレクトリが存在しない場合は作成
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("ディレクトリの作成に失敗しました: %v", err)
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("プロパティマップのエンコードに失敗しました: %v", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("プロパティマップファイルの書き込みに失敗しました: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"echonet-list/echonet_lite"
+	"path/filepath"
+	"testing"
+)
+
+func newTestLocalDevices(t *testing.T) (*LocalDevices, EOJ) {
+	t.Helper()
+	controller := echonet_lite.MakeEOJ(echonet_lite.Controller_ClassCode, 1)
+	announce := make(PropertyMap)
+	announce.Set(echonet_lite.EPCInstallationLocation)
+	devices := make(DeviceProperties)
+	if err := devices.Set(controller,
+		Property{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}},
+		Property{EPC: echonet_lite.EPCInstallationLocation, EDT: []byte{0x00}},
+		Property{EPC: echonet_lite.EPCStatusAnnouncementPropertyMap, EDT: announce.Encode()},
+	); err != nil {
+		t.Fatal(err)
+	}
+	return NewLocalDevices(devices), controller
+}
+
+func TestLocalDevices_SetPropertyMap(t *testing.T) {
+	local, controller := newTestLocalDevices(t)
+
+	// Get プロパティマップから外したプロパティは取得できない
+	if err := local.SetPropertyMap(controller, GetPropertyMap, []EPCType{echonet_lite.EPCInstallationLocation, echonet_lite.EPCGetPropertyMap}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := local.GetProperties(controller, Properties{{EPC: echonet_lite.EPCOperationStatus}}); ok {
+		t.Error("Get プロパティマップにないプロパティを取得できた")
+	}
+	if _, ok := local.GetProperties(controller, Properties{{EPC: echonet_lite.EPCInstallationLocation}}); !ok {
+		t.Error("Get プロパティマップにあるプロパティを取得できない")
+	}
+
+	// 状態通知プロパティマップを空にすると通知対象でなくなる
+	if err := local.SetPropertyMap(controller, StatusAnnouncementPropertyMap, []EPCType{}); err != nil {
+		t.Fatal(err)
+	}
+	if local.IsAnnouncementTarget(controller, echonet_lite.EPCInstallationLocation) {
+		t.Error("空の状態通知プロパティマップで通知対象になっている")
+	}
+
+	maps := local.PropertyMaps()
+	if len(maps) != 1 || len(maps[0].Overridden) != 2 || maps[0].Get.Has(echonet_lite.EPCOperationStatus) {
+		t.Errorf("プロパティマップが不正: %+v", maps)
+	}
+
+	// 初期状態に戻す
+	if err := local.SetPropertyMap(controller, GetPropertyMap, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.SetPropertyMap(controller, StatusAnnouncementPropertyMap, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := local.GetProperties(controller, Properties{{EPC: echonet_lite.EPCOperationStatus}}); !ok {
+		t.Error("初期状態に戻したのに取得できない")
+	}
+	if !local.IsAnnouncementTarget(controller, echonet_lite.EPCInstallationLocation) {
+		t.Error("初期状態に戻したのに通知対象でない")
+	}
+	if maps := local.PropertyMaps(); len(maps[0].Overridden) != 0 {
+		t.Errorf("初期状態に戻したのに変更が残っている: %+v", maps[0].Overridden)
+	}
+
+	if err := local.SetPropertyMap(echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1), SetPropertyMap, []EPCType{}); err == nil {
+		t.Error("自ノードにないデバイスのプロパティマップを変更できた")
+	}
+	if err := local.SetPropertyMap(controller, SetPropertyMap, []EPCType{0x10}); err == nil {
+		t.Error("範囲外のEPCを設定できた")
+	}
+}
+
+func TestLocalDevices_Persist(t *testing.T) {
+	local, controller := newTestLocalDevices(t)
+	filename := filepath.Join(t.TempDir(), LocalPropertyMapsFileName)
+
+	if err := local.SetPropertyMap(controller, SetPropertyMap, []EPCType{echonet_lite.EPCOperationStatus}); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.SaveToFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, _ := newTestLocalDevices(t)
+	if err := loaded.LoadFromFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	maps := loaded.PropertyMaps()
+	if len(maps) != 1 || len(maps[0].Set) != 1 || !maps[0].Set.Has(echonet_lite.EPCOperationStatus) {
+		t.Errorf("読み込んだ Set プロパティマップが不正: %+v", maps)
+	}
+	if _, ok := loaded.SetProperties(controller, Properties{{EPC: echonet_lite.EPCInstallationLocation, EDT: []byte{0x08}}}); ok {
+		t.Error("Set プロパティマップにないプロパティを設定できた")
+	}
+
+	// ファイルがない場合は初期状態のまま
+	if err := loaded.LoadFromFile(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("ファイルがない場合にエラー: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"regexp"
 	"sort"
@@ -374,7 +375,7 @@ func (ls *LocationSettings) SaveToFile(filename string) error {
 	}
 	defer func() {
 		if err := file.Close(); err != nil {
-			fmt.Printf("ファイルを閉じる際にエラーが発生しました: %v\n", err)
+			slog.Warn("ファイルを閉じる際にエラーが発生しました", "file", filename, "err", err)
 		}
 	}()
 
@@ -399,7 +400,7 @@ func (ls *LocationSettings) LoadFromFile(filename string) error {
 	}
 	defer func() {
 		if err := file.Close(); err != nil {
-			fmt.Printf("ファイルを閉じる際にエラーが発生しました: %v\n", err)
+			slog.Warn("ファイルを閉じる際にエラーが発生しました", "file", filename, "err", err)
 		}
 	}()
 
@@ -413,7 +414,7 @@ func (ls *LocationSettings) LoadFromFile(filename string) error {
 	for alias, value := range data.Aliases {
 		if err := ls.Aliases.Add(alias, value); err != nil {
 			// ログに警告を出すが続行
-			fmt.Printf("Warning: failed to load alias %s: %v\n", alias, err)
+			slog.Warn("Failed to load location alias", "alias", alias, "err", err)
 		}
 	}
 
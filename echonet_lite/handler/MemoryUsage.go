@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"echonet-list/echonet_lite"
+	"sort"
+	"time"
+)
+
+// メモリ使用量の概算に使う固定オーバーヘッド（バイト）
+const (
+	devicePropertyOverhead = 64  // EPCPropertyMap の1エントリあたり
+	deviceEntryOverhead    = 256 // デバイス（IP+EOJ）1件あたり
+)
+
+// DefaultMemoryCheckInterval は、メモリ上限のチェック間隔のデフォルト値
+const DefaultMemoryCheckInterval = time.Minute
+
+// DeviceMemoryUsage は、デバイス情報のメモリ使用量の概算
+type DeviceMemoryUsage struct {
+	Devices     int   // デバイス（IP+EOJ）の数
+	Properties  int   // 保持しているプロパティの総数
+	ApproxBytes int64 // 概算バイト数
+}
+
+// BufferUsage は、通知バッファ（チャンネル）の使用状況
+type BufferUsage struct {
+	Name     string
+	Length   int
+	Capacity int
+}
+
+// MemoryUsage は、インメモリストア全体のメモリ使用量の概算
+type MemoryUsage struct {
+	Devices     DeviceMemoryUsage
+	History     HistoryMemoryUsage
+	Buffers     []BufferUsage
+	ApproxBytes int64 // Devices と History の合計
+}
+
+// MemoryLimits は、インメモリストアのソフト上限の設定
+// 0 の項目は上限なしを表す
+type MemoryLimits struct {
+	HistorySoftLimitBytes int64         // 履歴の上限。超えた場合は古い通知履歴から削除する
+	DevicesSoftLimitBytes int64         // デバイス情報の上限。超えた場合は警告する
+	EvictOfflineDevices   bool          // デバイス情報が上限を超えた場合、更新の古いオフラインデバイスを削除する
+	CheckInterval         time.Duration // チェック間隔（0 の場合は DefaultMemoryCheckInterval）
+}
+
+// Enabled は、いずれかの上限が設定されているかを返す
+func (l MemoryLimits) Enabled() bool {
+	return l.HistorySoftLimitBytes > 0 || l.DevicesSoftLimitBytes > 0
+}
+
+// MemoryUsage は、デバイス情報のメモリ使用量の概算を返す
+func (d Devices) MemoryUsage() DeviceMemoryUsage {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var usage DeviceMemoryUsage
+	for ip, eojMap := range d.data {
+		for _, props := range eojMap {
+			usage.Devices++
+			usage.Properties += len(props)
+			usage.ApproxBytes += int64(deviceEntryOverhead + len(ip))
+			for _, prop := range props {
+				usage.ApproxBytes += int64(devicePropertyOverhead + len(prop.EDT))
+			}
+		}
+	}
+	return usage
+}
+
+// deviceMemorySize は、1デバイス分のメモリ使用量の概算を返す
+func (d Devices) deviceMemorySize(device IPAndEOJ) int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	props, ok := d.data[device.IP.String()][device.EOJ]
+	if !ok {
+		return 0
+	}
+	size := int64(deviceEntryOverhead + len(device.IP.String()))
+	for _, prop := range props {
+		size += int64(devicePropertyOverhead + len(prop.EDT))
+	}
+	return size
+}
+
+// BufferUsage は、通知用チャンネルの使用状況を返す
+func (c *HandlerCore) BufferUsage() []BufferUsage {
+	usage := []BufferUsage{
+		{Name: "notification", Length: len(c.NotificationCh), Capacity: cap(c.NotificationCh)},
+		{Name: "property_change", Length: len(c.PropertyChangeCh), Capacity: cap(c.PropertyChangeCh)},
+	}
+
+	c.subscribersMutex.RLock()
+	defer c.subscribersMutex.RUnlock()
+	for _, ch := range c.notificationSubscribers {
+		usage = append(usage, BufferUsage{Name: "notification_subscriber", Length: len(ch), Capacity: cap(ch)})
+	}
+	return usage
+}
+
+// MemoryUsage は、インメモリストアのメモリ使用量の概算を返す
+func (h *ECHONETLiteHandler) MemoryUsage() MemoryUsage {
+	usage := MemoryUsage{
+		Devices: h.data.devices.MemoryUsage(),
+		Buffers: h.core.BufferUsage(),
+	}
+	if manager, ok := h.data.DeviceHistory.(HistoryMemoryManager); ok {
+		usage.History = manager.MemoryUsage()
+	}
+	usage.ApproxBytes = usage.Devices.ApproxBytes + usage.History.ApproxBytes
+	return usage
+}
+
+// enforceMemoryLimits は、ソフト上限を超えているストアを警告し、設定に応じて削除を行う
+func (h *ECHONETLiteHandler) enforceMemoryLimits(limits MemoryLimits) {
+	usage := h.MemoryUsage()
+	h.log().Debug("メモリ使用量", "devices", usage.Devices.Devices, "devicesBytes", usage.Devices.ApproxBytes,
+		"historyEntries", usage.History.Entries, "historyBytes", usage.History.ApproxBytes)
+
+	if limits.HistorySoftLimitBytes > 0 && usage.History.ApproxBytes > limits.HistorySoftLimitBytes {
+		if manager, ok := h.data.DeviceHistory.(HistoryMemoryManager); ok {
+			evicted := manager.EvictToSize(limits.HistorySoftLimitBytes)
+			h.log().Warn("履歴のメモリ使用量が上限を超えたため古い履歴を削除しました",
+				"bytes", usage.History.ApproxBytes, "limit", limits.HistorySoftLimitBytes, "evicted", evicted)
+		}
+	}
+
+	if limits.DevicesSoftLimitBytes > 0 && usage.Devices.ApproxBytes > limits.DevicesSoftLimitBytes {
+		if limits.EvictOfflineDevices {
+			evicted := h.evictOfflineDevices(usage.Devices.ApproxBytes - limits.DevicesSoftLimitBytes)
+			h.log().Warn("デバイス情報のメモリ使用量が上限を超えたためオフラインデバイスを削除しました",
+				"bytes", usage.Devices.ApproxBytes, "limit", limits.DevicesSoftLimitBytes, "evicted", evicted)
+		} else {
+			h.log().Warn("デバイス情報のメモリ使用量が上限を超えています",
+				"bytes", usage.Devices.ApproxBytes, "limit", limits.DevicesSoftLimitBytes, "devices", usage.Devices.Devices)
+		}
+	}
+
+	for _, buffer := range usage.Buffers {
+		if buffer.Capacity > 0 && float64(buffer.Length)/float64(buffer.Capacity)*100 > DefaultMonitoringConfig().ChannelUsageThreshold {
+			h.log().Warn("通知バッファの使用率が高くなっています", "name", buffer.Name, "length", buffer.Length, "capacity", buffer.Capacity)
+		}
+	}
+}
+
+// evictOfflineDevices は、最終更新の古いオフラインデバイスから順に、excessBytes 分を削除する
+// 削除したデバイスの数を返す
+func (h *ECHONETLiteHandler) evictOfflineDevices(excessBytes int64) int {
+	type candidate struct {
+		device     IPAndEOJ
+		lastUpdate time.Time
+	}
+	var candidates []candidate
+	for _, device := range h.data.devices.ListIPAndEOJ() {
+		if device.EOJ.ClassCode() == echonet_lite.NodeProfile_ClassCode || !h.data.IsOffline(device) {
+			continue
+		}
+		candidates = append(candidates, candidate{device: device, lastUpdate: h.data.GetLastUpdateTime(device)})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].lastUpdate.Before(candidates[j].lastUpdate)
+	})
+
+	evicted := 0
+	for _, c := range candidates {
+		if excessBytes <= 0 {
+			break
+		}
+		size := h.data.devices.deviceMemorySize(c.device)
+		if err := h.data.RemoveDevice(c.device); err != nil {
+			h.log().Warn("オフラインデバイスの削除に失敗", "device", c.device.Specifier(), "error", err)
+			continue
+		}
+		if h.data.DeviceHistory != nil {
+			h.data.DeviceHistory.Clear(c.device)
+		}
+		excessBytes -= size
+		evicted++
+	}
+	return evicted
+}
+
+// startMemoryMonitor は、定期的にメモリ上限をチェックするゴルーチンを開始する
+func (h *ECHONETLiteHandler) startMemoryMonitor(limits MemoryLimits) {
+	interval := limits.CheckInterval
+	if interval <= 0 {
+		interval = DefaultMemoryCheckInterval
+	}
+	h.log().Info("メモリ上限の監視を開始", "interval", interval,
+		"historyLimit", limits.HistorySoftLimitBytes, "devicesLimit", limits.DevicesSoftLimitBytes)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				h.enforceMemoryLimits(limits)
+			case <-h.core.ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+)
+
+func TestMemoryDeviceHistoryStore_EvictToSize(t *testing.T) {
+	store := NewMemoryDeviceHistoryStore(HistoryOptions{PerDeviceSettableLimit: 10, PerDeviceNonSettableLimit: 10})
+	device1 := testDevice(1)
+	device2 := testDevice(2)
+	base := time.Now().Add(-time.Hour)
+
+	for i := 0; i < 4; i++ {
+		for _, device := range []IPAndEOJ{device1, device2} {
+			store.Record(DeviceHistoryEntry{Timestamp: base.Add(time.Duration(i) * time.Minute), Device: device, EPC: 0xE0, Value: PropertyValue{Number: intPtr(i)}, Origin: HistoryOriginNotification})
+		}
+	}
+	store.Record(DeviceHistoryEntry{Timestamp: base, Device: device1, EPC: 0x80, Value: PropertyValue{String: "on"}, Origin: HistoryOriginSet, Settable: true})
+
+	manager := store.(HistoryMemoryManager)
+	usage := manager.MemoryUsage()
+	if usage.Entries != 9 {
+		t.Fatalf("履歴件数が不正: got %d, want 9", usage.Entries)
+	}
+
+	// 1件あたりのサイズは同程度なので、半分程度に収まるまで削除されるはず
+	evicted := manager.EvictToSize(usage.ApproxBytes / 2)
+	if evicted == 0 {
+		t.Fatal("履歴が削除されていない")
+	}
+	after := manager.MemoryUsage()
+	if after.ApproxBytes > usage.ApproxBytes/2 {
+		t.Errorf("上限を超えたまま: got %d, limit %d", after.ApproxBytes, usage.ApproxBytes/2)
+	}
+
+	// 操作履歴（settable）は通知履歴より後に削除される
+	if entries := store.Query(device1, HistoryQuery{SettableOnly: true}); len(entries) != 1 {
+		t.Errorf("操作履歴が削除された: got %d entries", len(entries))
+	}
+	// 残った通知履歴は新しいもの
+	for _, device := range []IPAndEOJ{device1, device2} {
+		for _, entry := range store.Query(device, HistoryQuery{}) {
+			if !entry.Settable && entry.Timestamp.Before(base.Add(2*time.Minute)) {
+				t.Errorf("古い通知履歴が残っている: %v", entry.Timestamp)
+			}
+		}
+	}
+}
+
+func TestECHONETLiteHandler_EnforceMemoryLimitsEvictsOfflineDevices(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	handler, err := NewECHONETLiteHandler(ctx, ECHONETLieHandlerOptions{TestMode: true})
+	if err != nil {
+		t.Fatalf("ECHONETLiteHandlerの作成に失敗: %v", err)
+	}
+	defer handler.Close()
+
+	data := handler.GetDataManagementHandler()
+	online := testDevice(1)
+	oldOffline := testDevice(2)
+	newOffline := testDevice(3)
+	now := time.Now()
+	for i, device := range []IPAndEOJ{online, oldOffline, newOffline} {
+		data.devices.RegisterProperties(device, Properties{{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}}}, now.Add(time.Duration(i)*time.Minute))
+	}
+	data.SetOffline(oldOffline, true)
+	data.SetOffline(newOffline, true)
+
+	usage := handler.MemoryUsage()
+	if usage.Devices.Devices != 3 || usage.Devices.Properties != 3 {
+		t.Fatalf("デバイスの使用量が不正: %+v", usage.Devices)
+	}
+
+	// 1台分だけ上限を超えるように設定
+	perDevice := usage.Devices.ApproxBytes / 3
+	handler.enforceMemoryLimits(MemoryLimits{
+		DevicesSoftLimitBytes: usage.Devices.ApproxBytes - perDevice,
+		EvictOfflineDevices:   true,
+	})
+
+	if data.IsKnownDevice(oldOffline) {
+		t.Error("更新の古いオフラインデバイスが削除されていない")
+	}
+	if !data.IsKnownDevice(newOffline) || !data.IsKnownDevice(online) {
+		t.Error("削除対象外のデバイスが削除された")
+	}
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// OutboundLimits は、ECHONET Lite の要求の送信を制限する設定（ゼロ値の場合は制限なし）
+// 多数のデバイスを一度に更新すると要求がネットワークに集中し、応答の取りこぼしが増えるため、
+// 送信の速さと同時に応答を待つ要求の数を抑える
+type OutboundLimits struct {
+	PacketsPerSecond float64 // 1秒あたりに送信する要求パケットの上限（0の場合は制限なし）。再送も含む
+	Burst            int     // 間隔を空けずに続けて送信できるパケット数（0の場合は1）
+	MaxInFlight      int     // 同時に応答を待つ要求の上限（0の場合は制限なし）
+}
+
+// enabled は、いずれかの制限が設定されているかどうかを返す
+func (l OutboundLimits) enabled() bool {
+	return l.PacketsPerSecond > 0 || l.MaxInFlight > 0
+}
+
+// burst は、トークンバケットの容量を返す
+func (l OutboundLimits) burst() int {
+	if l.Burst <= 0 {
+		return 1
+	}
+	return l.Burst
+}
+
+// outboundLimiter は、要求の送信をトークンバケットで、応答待ちの要求の数をセマフォで制限する
+type outboundLimiter struct {
+	limits OutboundLimits
+	now    func() time.Time
+
+	mu     sync.Mutex
+	tokens float64   // 残りのトークン（予約済みの分だけ負になる）
+	last   time.Time // tokens を最後に補充した時刻
+
+	inFlight chan struct{} // MaxInFlight が0の場合は nil
+}
+
+func newOutboundLimiter(limits OutboundLimits) *outboundLimiter {
+	l := &outboundLimiter{
+		limits: limits,
+		now:    time.Now,
+		tokens: float64(limits.burst()),
+	}
+	if limits.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, limits.MaxInFlight)
+	}
+	return l
+}
+
+// reserve は、トークンを1つ予約し、送信まで待つ時間を返す
+func (l *outboundLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.limits.PacketsPerSecond
+		if burst := float64(l.limits.burst()); l.tokens > burst {
+			l.tokens = burst
+		}
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.limits.PacketsPerSecond * float64(time.Second))
+}
+
+// cancelReservation は、送信しなかった予約のトークンを返す
+func (l *outboundLimiter) cancelReservation() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens++
+}
+
+// wait は、パケットを1つ送信できるまで待つ
+func (l *outboundLimiter) wait(ctx context.Context) error {
+	if l.limits.PacketsPerSecond <= 0 {
+		return nil
+	}
+	delay := l.reserve()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancelReservation()
+		return context.Cause(ctx)
+	}
+}
+
+// acquire は、応答待ちの要求の枠が空くまで待って1つ確保する
+// 戻り値の関数で枠を解放すること
+func (l *outboundLimiter) acquire(ctx context.Context) (func(), error) {
+	if l.inFlight == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.inFlight <- struct{}{}:
+		return func() { <-l.inFlight }, nil
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+}
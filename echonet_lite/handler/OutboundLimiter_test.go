@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestOutboundLimiter_Reserve トークンバケットによる送信間隔のテスト
+func TestOutboundLimiter_Reserve(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newOutboundLimiter(OutboundLimits{PacketsPerSecond: 10, Burst: 2})
+	l.now = func() time.Time { return now }
+
+	// バースト分はすぐに送信できる
+	for i := 0; i < 2; i++ {
+		if d := l.reserve(); d != 0 {
+			t.Fatalf("reserve #%d = %v, want 0", i, d)
+		}
+	}
+	// それ以降は 1/10 秒ずつ待つ
+	if d := l.reserve(); d != 100*time.Millisecond {
+		t.Errorf("reserve = %v, want 100ms", d)
+	}
+	if d := l.reserve(); d != 200*time.Millisecond {
+		t.Errorf("reserve = %v, want 200ms", d)
+	}
+
+	// 時間が経つとトークンが補充されるが、バーストを超えては貯まらない
+	now = now.Add(10 * time.Second)
+	for i := 0; i < 2; i++ {
+		if d := l.reserve(); d != 0 {
+			t.Fatalf("reserve #%d after refill = %v, want 0", i, d)
+		}
+	}
+	if d := l.reserve(); d != 100*time.Millisecond {
+		t.Errorf("reserve after refill = %v, want 100ms", d)
+	}
+}
+
+// TestOutboundLimiter_WaitCancel 待っている間にコンテキストがキャンセルされた場合のテスト
+func TestOutboundLimiter_WaitCancel(t *testing.T) {
+	l := newOutboundLimiter(OutboundLimits{PacketsPerSecond: 0.1})
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatalf("first wait failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wait = %v, want deadline exceeded", err)
+	}
+	// 送信しなかった分のトークンは返される
+	l.mu.Lock()
+	tokens := l.tokens
+	l.mu.Unlock()
+	if tokens > 0.01 || tokens < -0.01 {
+		t.Errorf("tokens = %v, want about 0", tokens)
+	}
+}
+
+// TestOutboundLimiter_InFlight 応答待ちの要求の数の上限のテスト
+func TestOutboundLimiter_InFlight(t *testing.T) {
+	l := newOutboundLimiter(OutboundLimits{MaxInFlight: 2})
+	ctx := context.Background()
+
+	release1, err := l.acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release2, err := l.acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 上限に達すると空くまで待つ
+	acquired := make(chan func(), 1)
+	go func() {
+		release, err := l.acquire(ctx)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		acquired <- release
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired beyond MaxInFlight")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release1()
+	select {
+	case release3 := <-acquired:
+		release3()
+	case <-time.After(time.Second):
+		t.Fatal("slot was not released")
+	}
+	release2()
+
+	// 制限しない設定では待たない
+	unlimited := newOutboundLimiter(OutboundLimits{PacketsPerSecond: 1})
+	for i := 0; i < 10; i++ {
+		if _, err := unlimited.acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestSession_SetOutboundLimits ゼロ値では制限しないことのテスト
+func TestSession_SetOutboundLimits(t *testing.T) {
+	s := &Session{}
+	s.SetOutboundLimits(OutboundLimits{Burst: 5})
+	if s.limiter != nil {
+		t.Error("limiter must be nil when no limit is set")
+	}
+	s.SetOutboundLimits(OutboundLimits{MaxInFlight: 4})
+	if s.limiter == nil {
+		t.Fatal("limiter is not set")
+	}
+	release, err := s.acquireInFlight(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+}
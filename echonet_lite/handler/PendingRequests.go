@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"echonet-list/echonet_lite"
+)
+
+// PendingRequestState は、応答待ちの要求の状態
+type PendingRequestState string
+
+const (
+	PendingRequestSending  PendingRequestState = "sending"  // 送信中
+	PendingRequestWaiting  PendingRequestState = "waiting"  // 応答待ち（再送前）
+	PendingRequestRetrying PendingRequestState = "retrying" // タイムアウトして再送した後の応答待ち
+)
+
+// ErrRequestCancelled は、CancelPendingRequest で取り消された要求が返すエラー
+var ErrRequestCancelled = errors.New("要求は取り消されました")
+
+// PendingRequest は、デバイスへ送った Get/Set 要求のうち、応答を待っているもの
+type PendingRequest struct {
+	ID         uint64               // 取り消しに使う番号
+	Device     IPAndEOJ             // 宛先のデバイス
+	ESV        echonet_lite.ESVType // 要求の種類（Get, SetC, SetGet など）
+	EPCs       []EPCType            // 対象のEPC
+	State      PendingRequestState  // 状態
+	Retries    int                  // 再送した回数
+	MaxRetries int                  // 最大再送回数
+	StartTime  time.Time            // 最初の送信時刻
+	NextRetry  time.Time            // 応答がなければ次に再送する時刻（送信中はゼロ値）
+}
+
+type pendingRequestEntry struct {
+	info   PendingRequest
+	cancel context.CancelCauseFunc
+}
+
+// pendingRequests は、Session の応答待ちの要求の一覧
+// 応答がなく再送を繰り返している Set を観察し、取り消せるようにする
+type pendingRequests struct {
+	mu      sync.Mutex
+	nextID  uint64
+	entries map[uint64]*pendingRequestEntry
+}
+
+func newPendingRequests() *pendingRequests {
+	return &pendingRequests{entries: make(map[uint64]*pendingRequestEntry)}
+}
+
+// add は要求を登録し、番号と要求用のコンテキストを返す。nil の場合は登録しない
+func (p *pendingRequests) add(ctx context.Context, device IPAndEOJ, msg *echonet_lite.ECHONETLiteMessage, maxRetries int) (uint64, context.Context, context.CancelCauseFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	if p == nil {
+		return 0, ctx, cancel
+	}
+	epcs := make([]EPCType, 0, len(msg.Properties)+len(msg.SetGetProperties))
+	for _, prop := range msg.Properties {
+		epcs = append(epcs, prop.EPC)
+	}
+	for _, prop := range msg.SetGetProperties {
+		epcs = append(epcs, prop.EPC)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nextID++
+	p.entries[p.nextID] = &pendingRequestEntry{
+		info: PendingRequest{
+			ID:         p.nextID,
+			Device:     device,
+			ESV:        msg.ESV,
+			EPCs:       epcs,
+			State:      PendingRequestSending,
+			MaxRetries: maxRetries,
+			StartTime:  time.Now(),
+		},
+		cancel: cancel,
+	}
+	return p.nextID, ctx, cancel
+}
+
+// update は要求の状態を更新する
+func (p *pendingRequests) update(id uint64, state PendingRequestState, retries int, nextRetry time.Time) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if entry, ok := p.entries[id]; ok {
+		entry.info.State = state
+		entry.info.Retries = retries
+		entry.info.NextRetry = nextRetry
+	}
+}
+
+// remove は完了した要求を一覧から除く
+func (p *pendingRequests) remove(id uint64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.entries, id)
+}
+
+// list は応答待ちの要求を古い順に返す
+func (p *pendingRequests) list() []PendingRequest {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result := make([]PendingRequest, 0, len(p.entries))
+	for _, entry := range p.entries {
+		info := entry.info
+		info.EPCs = append([]EPCType(nil), info.EPCs...)
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// cancel は要求の再送をやめ、呼び出し元に ErrRequestCancelled を返させる
+func (p *pendingRequests) cancel(id uint64) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	entry, ok := p.entries[id]
+	p.mu.Unlock()
+	if ok {
+		entry.cancel(ErrRequestCancelled)
+	}
+	return ok
+}
+
+// PendingRequests は、応答待ちの要求の一覧を古い順に返す
+func (s *Session) PendingRequests() []PendingRequest {
+	return s.pending.list()
+}
+
+// CancelPendingRequest は、応答待ちの要求を取り消す。見つからない場合は false を返す
+func (s *Session) CancelPendingRequest(id uint64) bool {
+	return s.pending.cancel(id)
+}
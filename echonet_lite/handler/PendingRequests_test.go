@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/network"
+)
+
+// TestSession_PendingRequests 応答待ちの要求が一覧に現れ、取り消せることのテスト
+func TestSession_PendingRequests(t *testing.T) {
+	ctx := context.Background()
+	session, err := CreateSession(ctx, net.ParseIP("127.0.0.1"), 0, network.IPv4, echonet_lite.MakeEOJ(echonet_lite.Controller_ClassCode, 1), false, nil, nil)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	defer session.Close()
+
+	// 取り消しが先に効くように、再送間隔は長くしておく
+	session.MaxRetries = 3
+	session.RetryInterval = time.Minute
+
+	device := echonet_lite.IPAndEOJ{IP: net.ParseIP("192.0.2.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	msg := &echonet_lite.ECHONETLiteMessage{
+		ESV:        echonet_lite.ESVSetC,
+		Properties: echonet_lite.Properties{{EPC: 0x80, EDT: []byte{0x30}}, {EPC: 0xB3, EDT: []byte{26}}},
+	}
+
+	// 応答が来る要求は、完了後に一覧から消える
+	responseCh := make(chan *echonet_lite.ECHONETLiteMessage, 1)
+	responseCh <- &echonet_lite.ECHONETLiteMessage{ESV: echonet_lite.ESVSet_Res}
+	if _, err := session.waitForResponseWithRetry(ctx, device, msg, responseCh); err != nil {
+		t.Fatalf("waitForResponseWithRetry failed: %v", err)
+	}
+	if pending := session.PendingRequests(); len(pending) != 0 {
+		t.Fatalf("完了した要求が残っています: %v", pending)
+	}
+
+	// 応答が来ない要求
+	done := make(chan error, 1)
+	go func() {
+		_, err := session.waitForResponseWithRetry(ctx, device, msg, make(chan *echonet_lite.ECHONETLiteMessage))
+		done <- err
+	}()
+
+	var pending []PendingRequest
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		pending = session.PendingRequests()
+		if len(pending) == 1 && pending[0].State == PendingRequestWaiting {
+			break
+		}
+	}
+	if len(pending) != 1 {
+		t.Fatalf("応答待ちの要求が1件になりません: %v", pending)
+	}
+	req := pending[0]
+	if req.Device.Key() != device.Key() || req.ESV != echonet_lite.ESVSetC || len(req.EPCs) != 2 || req.EPCs[1] != 0xB3 {
+		t.Errorf("要求の内容が正しくありません: %+v", req)
+	}
+	if req.State != PendingRequestWaiting || req.MaxRetries != 3 || !req.NextRetry.After(req.StartTime) {
+		t.Errorf("要求の状態が正しくありません: %+v", req)
+	}
+
+	if session.CancelPendingRequest(req.ID + 100) {
+		t.Error("存在しない要求の取り消しが成功しました")
+	}
+	if !session.CancelPendingRequest(req.ID) {
+		t.Fatal("要求の取り消しに失敗しました")
+	}
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrRequestCancelled) {
+			t.Errorf("ErrRequestCancelled が返りませんでした: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("取り消した要求が終了しません")
+	}
+	if pending := session.PendingRequests(); len(pending) != 0 {
+		t.Errorf("取り消した要求が残っています: %v", pending)
+	}
+}
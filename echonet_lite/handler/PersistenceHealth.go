@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	saveRetryMinInterval = 5 * time.Second // 保存に失敗したときの最初の再試行までの間隔
+	saveRetryMaxInterval = 5 * time.Minute // 再試行の間隔の上限
+)
+
+// PersistenceOptions は、デバイス情報ファイルの保存の設定
+// 大きなネットワークではプロパティを受け取るたびに保存すると書き込みが多くなるため、
+// Raspberry Pi の SD カードなどでは保存をまとめて書き込みを減らす
+type PersistenceOptions struct {
+	SaveInterval time.Duration // この間隔で変更をまとめて保存する（0 の場合は変更のたびに保存する）
+	PruneAfter   time.Duration // この期間更新のないデバイスを自動的に削除する（0 の場合は削除しない）
+}
+
+// PersistenceStatus は、デバイス情報ファイルの保存の状態
+type PersistenceStatus struct {
+	Healthy      bool      // 最後の保存に成功したか（まだ保存していない場合も true）
+	LastError    string    // 最後の保存のエラー（成功している場合は空）
+	FailingSince time.Time // 保存に失敗し始めた時刻（成功している場合はゼロ値）
+	Failures     int       // 続けて失敗した回数
+	LastSaved    time.Time // 最後に保存に成功した時刻（まだ保存していない場合はゼロ値）
+	NextRetry    time.Time // 次に再試行する時刻（再試行しない場合はゼロ値）
+}
+
+// deviceInfoSaver は、デバイス情報の保存をまとめ、失敗したときは間隔を延ばしながら再試行する
+// 受信処理や複数の goroutine から同時に呼ばれても、ファイルへの書き込みは同時に1つだけ行う
+type deviceInfoSaver struct {
+	mu            sync.Mutex
+	saving        bool                     // 保存中か
+	pending       bool                     // 保存中に保存を求められたか（保存が終わったら続けて保存する）
+	status        PersistenceStatus        // 保存の状態
+	retryInterval time.Duration            // 次の再試行までの間隔
+	retryTimer    *time.Timer              // 再試行のタイマー（nil の場合は再試行しない）
+	closed        bool                     // 閉じた後は再試行しない
+	notifyCh      chan<- PersistenceStatus // 失敗し始めたときと回復したときの通知先（nil の場合は通知しない）
+	interval      time.Duration            // 変更をまとめて保存する間隔（0 の場合はすぐに保存する）
+	flushTimer    *time.Timer              // まとめた変更を保存するタイマー（nil の場合は保存を待っている変更はない）
+}
+
+// SetSaveInterval は、デバイス情報の変更をまとめて保存する間隔を設定する。0 の場合は変更のたびに保存する
+func (h *DataManagementHandler) SetSaveInterval(interval time.Duration) {
+	h.saver.mu.Lock()
+	defer h.saver.mu.Unlock()
+	h.saver.interval = interval
+}
+
+// SetPersistenceNotifications は、デバイス情報の保存に失敗し始めたときと回復したときの通知先を設定する
+func (h *DataManagementHandler) SetPersistenceNotifications(ch chan<- PersistenceStatus) {
+	h.saver.mu.Lock()
+	defer h.saver.mu.Unlock()
+	h.saver.notifyCh = ch
+}
+
+// PersistenceStatus は、デバイス情報ファイルの保存の状態を返す
+func (h *DataManagementHandler) PersistenceStatus() PersistenceStatus {
+	h.saver.mu.Lock()
+	defer h.saver.mu.Unlock()
+	status := h.saver.status
+	if status.Failures == 0 {
+		status.Healthy = true
+	}
+	return status
+}
+
+// SaveDeviceInfo は、デバイス情報をファイルに保存する
+// 保存をまとめる間隔が設定されている場合は、間隔の終わりにまとめて保存するため、待たずに nil を返す
+// 保存中に呼ばれた場合は、保存中の処理が続けて保存するため、待たずに nil を返す
+// 失敗した場合は保存の状態に記録し、間隔を延ばしながら成功するまで再試行する
+func (h *DataManagementHandler) SaveDeviceInfo() error {
+	if h.inMemory {
+		return nil
+	}
+	s := &h.saver
+	s.mu.Lock()
+	if s.interval > 0 && !s.closed {
+		if s.flushTimer == nil {
+			s.flushTimer = time.AfterFunc(s.interval, func() {
+				_ = h.FlushDeviceInfo()
+			})
+		}
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+	return h.saveDeviceInfoNow()
+}
+
+// FlushDeviceInfo は、まとめて保存するために待っている変更があれば、すぐに保存する
+func (h *DataManagementHandler) FlushDeviceInfo() error {
+	s := &h.saver
+	s.mu.Lock()
+	pending := s.flushTimer != nil
+	s.mu.Unlock()
+	if !pending {
+		return nil
+	}
+	return h.saveDeviceInfoNow()
+}
+
+// hasPendingDeviceInfo は、まとめて保存するために待っている変更があるかどうかを返す
+func (h *DataManagementHandler) hasPendingDeviceInfo() bool {
+	h.saver.mu.Lock()
+	defer h.saver.mu.Unlock()
+	return h.saver.flushTimer != nil
+}
+
+// saveDeviceInfoNow は、待っている変更も含めてデバイス情報をすぐにファイルに保存する
+func (h *DataManagementHandler) saveDeviceInfoNow() error {
+	if h.inMemory {
+		return nil
+	}
+	s := &h.saver
+	s.mu.Lock()
+	// これから書き込む内容には待っている変更も含まれる
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+	if s.saving {
+		s.pending = true
+		s.mu.Unlock()
+		return nil
+	}
+	s.saving = true
+
+	var err error
+	for {
+		s.pending = false
+		s.mu.Unlock()
+		err = h.writeDeviceInfo()
+		s.mu.Lock()
+		h.recordSaveResult(err)
+		if !s.pending {
+			break
+		}
+	}
+	s.saving = false
+	s.mu.Unlock()
+	return err
+}
+
+// writeDeviceInfo は、デバイス情報をファイルに書き込む
+func (h *DataManagementHandler) writeDeviceInfo() error {
+	filename := getFileOrDefault(h.devicesFilePath, DeviceFileName)
+	return h.integrity.Save(filename, func() error {
+		return h.devices.SaveToFile(filename)
+	})
+}
+
+// recordSaveResult は、保存の結果を記録し、失敗し始めたときと回復したときに通知する。h.saver.mu を保持して呼ぶこと
+func (h *DataManagementHandler) recordSaveResult(err error) {
+	s := &h.saver
+	now := time.Now()
+	if err == nil {
+		recovered := s.status.Failures > 0
+		s.status = PersistenceStatus{Healthy: true, LastSaved: now}
+		s.retryInterval = 0
+		if s.retryTimer != nil {
+			s.retryTimer.Stop()
+			s.retryTimer = nil
+		}
+		if recovered {
+			h.log().Info("デバイス情報の保存が回復しました")
+			h.notifyPersistence(s.status)
+		}
+		return
+	}
+
+	s.status.Healthy = false
+	s.status.LastError = err.Error()
+	s.status.Failures++
+	if s.status.Failures == 1 {
+		// 失敗が続く間は1回だけエラーとして記録・通知する
+		s.status.FailingSince = now
+		h.log().Error("デバイス情報の保存に失敗しました。成功するまで再試行します", "err", err)
+	} else {
+		h.log().Debug("デバイス情報の保存の再試行に失敗", "failures", s.status.Failures, "err", err)
+	}
+	h.scheduleSaveRetry(now)
+	if s.status.Failures == 1 {
+		h.notifyPersistence(s.status)
+	}
+}
+
+// scheduleSaveRetry は、間隔を倍にしながら保存の再試行を予約する。h.saver.mu を保持して呼ぶこと
+func (h *DataManagementHandler) scheduleSaveRetry(now time.Time) {
+	s := &h.saver
+	if s.closed {
+		return
+	}
+	if s.retryInterval == 0 {
+		s.retryInterval = saveRetryMinInterval
+	} else {
+		s.retryInterval = min(s.retryInterval*2, saveRetryMaxInterval)
+	}
+	if s.retryTimer != nil {
+		s.retryTimer.Stop()
+	}
+	s.status.NextRetry = now.Add(s.retryInterval)
+	s.retryTimer = time.AfterFunc(s.retryInterval, func() {
+		_ = h.saveDeviceInfoNow()
+	})
+}
+
+// notifyPersistence は、保存の状態を通知する。通知先が詰まっている場合は捨てる
+func (h *DataManagementHandler) notifyPersistence(status PersistenceStatus) {
+	if h.saver.notifyCh == nil {
+		return
+	}
+	select {
+	case h.saver.notifyCh <- status:
+	default:
+		h.log().Warn("保存状態の通知チャネルがブロックされています")
+	}
+}
+
+// stopSaveRetry は、予約した保存の再試行とまとめた変更の保存を取り消し、以降は再試行しない
+func (h *DataManagementHandler) stopSaveRetry() {
+	h.saver.mu.Lock()
+	defer h.saver.mu.Unlock()
+	h.saver.closed = true
+	h.saver.status.NextRetry = time.Time{}
+	if h.saver.retryTimer != nil {
+		h.saver.retryTimer.Stop()
+		h.saver.retryTimer = nil
+	}
+	if h.saver.flushTimer != nil {
+		h.saver.flushTimer.Stop()
+		h.saver.flushTimer = nil
+	}
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDataManagementHandler_SaveDeviceInfoFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	h := NewDataManagementHandler(NewDevices(), NewDeviceAliases(), NewDeviceGroups(), NewLocationSettings(), nil, nil, nil)
+	h.SetDevicesFile(filepath.Join(dir, "devices.json"))
+	notifications := make(chan PersistenceStatus, 10)
+	h.SetPersistenceNotifications(notifications)
+	defer h.stopSaveRetry()
+
+	if status := h.PersistenceStatus(); !status.Healthy {
+		t.Errorf("保存前の状態が不正: %+v", status)
+	}
+
+	// 保存先のディレクトリがないため失敗する
+	if err := h.SaveDeviceInfo(); err == nil {
+		t.Fatal("保存できないはずのファイルに保存できた")
+	}
+	status := h.PersistenceStatus()
+	if status.Healthy || status.Failures != 1 || status.LastError == "" || status.FailingSince.IsZero() || status.NextRetry.IsZero() {
+		t.Errorf("失敗後の状態が不正: %+v", status)
+	}
+	if h.saver.retryInterval != saveRetryMinInterval {
+		t.Errorf("再試行の間隔 %v, 期待値 %v", h.saver.retryInterval, saveRetryMinInterval)
+	}
+
+	// 失敗が続いても通知は1回だけで、再試行の間隔は延びる
+	_ = h.SaveDeviceInfo()
+	if status := h.PersistenceStatus(); status.Failures != 2 || !status.FailingSince.Equal(h.saver.status.FailingSince) {
+		t.Errorf("続けて失敗した後の状態が不正: %+v", status)
+	}
+	if h.saver.retryInterval != 2*saveRetryMinInterval {
+		t.Errorf("再試行の間隔 %v, 期待値 %v", h.saver.retryInterval, 2*saveRetryMinInterval)
+	}
+	if n := len(notifications); n != 1 {
+		t.Fatalf("失敗の通知 %d 回, 期待値 1", n)
+	}
+	if failed := <-notifications; failed.Healthy {
+		t.Errorf("失敗の通知が不正: %+v", failed)
+	}
+
+	// 保存できるようになれば回復し、再試行をやめる
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SaveDeviceInfo(); err != nil {
+		t.Fatalf("SaveDeviceInfo failed: %v", err)
+	}
+	status = h.PersistenceStatus()
+	if !status.Healthy || status.Failures != 0 || status.LastSaved.IsZero() || !status.NextRetry.IsZero() || h.saver.retryTimer != nil {
+		t.Errorf("回復後の状態が不正: %+v", status)
+	}
+	if recovered := <-notifications; !recovered.Healthy {
+		t.Errorf("回復の通知が不正: %+v", recovered)
+	}
+}
+
+func TestDataManagementHandler_SaveDeviceInfoConcurrent(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "devices.json")
+	h := NewDataManagementHandler(NewDevices(), NewDeviceAliases(), NewDeviceGroups(), NewLocationSettings(), nil, nil, nil)
+	h.SetDevicesFile(filename)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := h.SaveDeviceInfo(); err != nil {
+				t.Errorf("SaveDeviceInfo failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := os.Stat(filename); err != nil {
+		t.Errorf("デバイス情報が保存されていない: %v", err)
+	}
+	if h.saver.saving || h.saver.pending {
+		t.Error("保存が終わっていない")
+	}
+}
+
+func TestDataManagementHandler_SaveDeviceInfoBatched(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "devices.json")
+	h := NewDataManagementHandler(NewDevices(), NewDeviceAliases(), NewDeviceGroups(), NewLocationSettings(), nil, nil, nil)
+	h.SetDevicesFile(filename)
+	h.SetSaveInterval(time.Hour)
+	defer h.stopSaveRetry()
+
+	// 間隔の終わりまで保存しない
+	for range 3 {
+		if err := h.SaveDeviceInfo(); err != nil {
+			t.Fatalf("SaveDeviceInfo failed: %v", err)
+		}
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Fatalf("間隔の前に保存された: %v", err)
+	}
+	if !h.hasPendingDeviceInfo() {
+		t.Fatal("保存を待っている変更がない")
+	}
+
+	// FlushDeviceInfo で待っている変更をすぐに保存する
+	if err := h.FlushDeviceInfo(); err != nil {
+		t.Fatalf("FlushDeviceInfo failed: %v", err)
+	}
+	if _, err := os.Stat(filename); err != nil {
+		t.Fatalf("保存されていない: %v", err)
+	}
+	if h.hasPendingDeviceInfo() || h.PersistenceStatus().LastSaved.IsZero() {
+		t.Errorf("保存後の状態が不正: %+v", h.PersistenceStatus())
+	}
+
+	// 間隔が過ぎると保存する
+	if err := os.Remove(filename); err != nil {
+		t.Fatal(err)
+	}
+	saved := h.PersistenceStatus().LastSaved
+	h.SetSaveInterval(10 * time.Millisecond)
+	_ = h.SaveDeviceInfo()
+	deadline := time.Now().Add(5 * time.Second)
+	for h.PersistenceStatus().LastSaved.Equal(saved) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := os.Stat(filename); err != nil {
+		t.Fatalf("間隔が過ぎても保存されていない: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"sync"
+	"time"
+)
+
+// PollSchedule は、定期更新でデバイスごとに異なる間隔でプロパティを取得するための予定
+// スマートメーターは短い間隔で、空気清浄機は長い間隔で、といったように、よく変化するデバイスだけを頻繁に取得する
+type PollSchedule struct {
+	tick        time.Duration                // 定期更新を確認する間隔（最も短い取得間隔）
+	intervalFor func(IPAndEOJ) time.Duration // デバイスの取得間隔（0 以下のデバイスは定期更新しない）
+
+	mu       sync.Mutex
+	lastPoll map[string]time.Time // key: IPAndEOJ.Key()
+}
+
+// NewPollSchedule は、tick ごとに確認し、intervalFor が返す間隔でデバイスを取得する予定を作成する
+func NewPollSchedule(tick time.Duration, intervalFor func(IPAndEOJ) time.Duration) *PollSchedule {
+	return &PollSchedule{
+		tick:        tick,
+		intervalFor: intervalFor,
+		lastPoll:    make(map[string]time.Time),
+	}
+}
+
+// takeDue は、前回の取得から間隔が経過したデバイスを返し、その時刻を取得した時刻として記録する
+// タイマーの揺らぎで1回分遅れないよう、確認間隔の半分の余裕を持たせる
+// force の場合は、定期更新しないデバイスを除いて間隔に関係なくすべて返す
+func (s *PollSchedule) takeDue(devices []IPAndEOJ, now time.Time, force bool) []IPAndEOJ {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := make([]IPAndEOJ, 0, len(devices))
+	for _, device := range devices {
+		interval := s.intervalFor(device)
+		if interval <= 0 {
+			continue
+		}
+		key := device.Key()
+		if last, ok := s.lastPoll[key]; ok && !force && now.Sub(last) < interval-s.tick/2 {
+			continue
+		}
+		s.lastPoll[key] = now
+		due = append(due, device)
+	}
+	return due
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+)
+
+func TestPollSchedule_TakeDue(t *testing.T) {
+	meter := IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(0x0288, 1)}
+	cleaner := IPAndEOJ{IP: net.ParseIP("192.168.1.11"), EOJ: echonet_lite.MakeEOJ(0x0135, 1)}
+	light := IPAndEOJ{IP: net.ParseIP("192.168.1.12"), EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)}
+	devices := []IPAndEOJ{meter, cleaner, light}
+
+	schedule := NewPollSchedule(30*time.Second, func(device IPAndEOJ) time.Duration {
+		switch device.EOJ.ClassCode() {
+		case 0x0288:
+			return 30 * time.Second
+		case 0x0135:
+			return 10 * time.Minute
+		}
+		return 0 // 照明は定期更新しない
+	})
+
+	keys := func(due []IPAndEOJ) []string {
+		result := make([]string, 0, len(due))
+		for _, device := range due {
+			result = append(result, device.Key())
+		}
+		return result
+	}
+
+	start := time.Now()
+	// 初回は定期更新するデバイスをすべて取得する
+	if due := schedule.takeDue(devices, start, false); len(due) != 2 {
+		t.Fatalf("初回は2台のはずが %v", keys(due))
+	}
+	// タイマーが少し早く発火しても、スマートメーターは取得する
+	if due := schedule.takeDue(devices, start.Add(29*time.Second), false); len(due) != 1 || due[0].Key() != meter.Key() {
+		t.Errorf("スマートメーターだけのはずが %v", keys(due))
+	}
+	// 空気清浄機は10分後に取得する
+	if due := schedule.takeDue(devices, start.Add(10*time.Minute), false); len(due) != 2 {
+		t.Errorf("10分後は2台のはずが %v", keys(due))
+	}
+	// 強制更新では間隔に関係なく取得するが、定期更新しないデバイスは除く
+	if due := schedule.takeDue(devices, start.Add(10*time.Minute+time.Second), true); len(due) != 2 {
+		t.Errorf("強制更新は2台のはずが %v", keys(due))
+	}
+}
@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"echonet-list/echonet_lite"
+)
+
+const (
+	// statsBucketWidth is the granularity of the rolling counters.
+	statsBucketWidth = 5 * time.Minute
+	// statsBucketCount covers one day of buckets.
+	statsBucketCount = int(24 * time.Hour / statsBucketWidth)
+	// statsHourBuckets is the number of buckets that make up the last hour.
+	statsHourBuckets = int(time.Hour / statsBucketWidth)
+)
+
+// PropertyChangeStat is the rolling change count for one EPC of one device.
+type PropertyChangeStat struct {
+	Device     IPAndEOJ
+	EPC        echonet_lite.EPCType
+	LastHour   int
+	LastDay    int
+	LastChange time.Time
+}
+
+// changeCounter keeps per-bucket counts in a ring indexed by bucket number.
+type changeCounter struct {
+	counts     [statsBucketCount]int
+	slots      [statsBucketCount]int64 // bucket number stored in each slot, used to detect stale slots
+	lastChange time.Time
+}
+
+func (c *changeCounter) add(bucket int64, at time.Time) {
+	idx := int(bucket % int64(statsBucketCount))
+	if c.slots[idx] != bucket {
+		c.slots[idx] = bucket
+		c.counts[idx] = 0
+	}
+	c.counts[idx]++
+	if at.After(c.lastChange) {
+		c.lastChange = at
+	}
+}
+
+// sum returns the number of changes recorded in the last n buckets up to and including current.
+func (c *changeCounter) sum(current int64, n int) int {
+	total := 0
+	for b := current - int64(n) + 1; b <= current; b++ {
+		if b < 0 {
+			continue
+		}
+		idx := int(b % int64(statsBucketCount))
+		if c.slots[idx] == b {
+			total += c.counts[idx]
+		}
+	}
+	return total
+}
+
+// PropertyChangeStats maintains rolling counts of property changes per device and EPC.
+// It is used to find chatty devices and to tune debouncing and history exclusions.
+type PropertyChangeStats struct {
+	mu       sync.RWMutex
+	counters map[string]map[echonet_lite.EPCType]*changeCounter // device key -> EPC -> counter
+	devices  map[string]IPAndEOJ
+	now      func() time.Time
+}
+
+// NewPropertyChangeStats creates an empty statistics store.
+func NewPropertyChangeStats() *PropertyChangeStats {
+	return &PropertyChangeStats{
+		counters: make(map[string]map[echonet_lite.EPCType]*changeCounter),
+		devices:  make(map[string]IPAndEOJ),
+		now:      time.Now,
+	}
+}
+
+func statsBucket(t time.Time) int64 {
+	return t.UnixNano() / int64(statsBucketWidth)
+}
+
+// Record counts one change of the given EPC on the device.
+func (s *PropertyChangeStats) Record(device IPAndEOJ, epc echonet_lite.EPCType) {
+	s.recordAt(device, epc, s.now(), 1)
+}
+
+func (s *PropertyChangeStats) recordAt(device IPAndEOJ, epc echonet_lite.EPCType, at time.Time, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := device.Key()
+	byEPC, ok := s.counters[key]
+	if !ok {
+		byEPC = make(map[echonet_lite.EPCType]*changeCounter)
+		s.counters[key] = byEPC
+		s.devices[key] = device
+	}
+	counter, ok := byEPC[epc]
+	if !ok {
+		counter = &changeCounter{}
+		for i := range counter.slots {
+			counter.slots[i] = -1
+		}
+		byEPC[epc] = counter
+	}
+	bucket := statsBucket(at)
+	for i := 0; i < count; i++ {
+		counter.add(bucket, at)
+	}
+}
+
+// Remove drops all statistics for the device.
+func (s *PropertyChangeStats) Remove(device IPAndEOJ) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := device.Key()
+	delete(s.counters, key)
+	delete(s.devices, key)
+}
+
+// Query returns the statistics sorted by daily change count (most frequent first).
+// When device is nil, statistics for all devices are returned.
+// Entries without any change in the last day are omitted.
+func (s *PropertyChangeStats) Query(device *IPAndEOJ) []PropertyChangeStat {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	current := statsBucket(s.now())
+	result := make([]PropertyChangeStat, 0)
+	for key, byEPC := range s.counters {
+		if device != nil && key != device.Key() {
+			continue
+		}
+		for epc, counter := range byEPC {
+			day := counter.sum(current, statsBucketCount)
+			if day == 0 {
+				continue
+			}
+			result = append(result, PropertyChangeStat{
+				Device:     s.devices[key],
+				EPC:        epc,
+				LastHour:   counter.sum(current, statsHourBuckets),
+				LastDay:    day,
+				LastChange: counter.lastChange,
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].LastDay != result[j].LastDay {
+			return result[i].LastDay > result[j].LastDay
+		}
+		if c := result[i].Device.Compare(result[j].Device); c != 0 {
+			return c < 0
+		}
+		return result[i].EPC < result[j].EPC
+	})
+	return result
+}
+
+// statsFileFormat represents the JSON structure for persisting change statistics.
+// Buckets are stored as bucket number -> count so that counts survive restarts.
+type statsFileFormat struct {
+	Version int                                `json:"version"`
+	Data    map[string]map[string]statsBuckets `json:"data"` // device key -> EPC (hex) -> buckets
+}
+
+// statsBuckets maps a bucket number (decimal string) to its change count.
+type statsBuckets map[string]int
+
+const currentStatsFileVersion = 1
+
+// SaveToFile persists the counters that are still within the rolling window.
+func (s *PropertyChangeStats) SaveToFile(filename string) error {
+	s.mu.RLock()
+	current := statsBucket(s.now())
+	fileData := statsFileFormat{
+		Version: currentStatsFileVersion,
+		Data:    make(map[string]map[string]statsBuckets),
+	}
+	for key, byEPC := range s.counters {
+		epcs := make(map[string]statsBuckets)
+		for epc, counter := range byEPC {
+			buckets := make(statsBuckets)
+			for i, b := range counter.slots {
+				if b >= 0 && b > current-int64(statsBucketCount) && b <= current && counter.counts[i] > 0 {
+					buckets[strconv.FormatInt(b, 10)] = counter.counts[i]
+				}
+			}
+			if len(buckets) > 0 {
+				epcs[fmt.Sprintf("%02X", byte(epc))] = buckets
+			}
+		}
+		if len(epcs) > 0 {
+			fileData.Data[key] = epcs
+		}
+	}
+	s.mu.RUnlock()
+
+	data, err := json.Marshal(fileData)
+	if err != nil {
+		return fmt.Errorf("failed to marshal statistics: %w", err)
+	}
+
+	tempFilename := filename + ".tmp"
+	if err := os.WriteFile(tempFilename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write to temporary file %s: %w", tempFilename, err)
+	}
+	if err := os.Rename(tempFilename, filename); err != nil {
+		_ = os.Remove(tempFilename)
+		return fmt.Errorf("failed to rename temporary file %s to %s: %w", tempFilename, filename, err)
+	}
+	return nil
+}
+
+// LoadFromFile restores counters saved by SaveToFile. A missing file is not an error.
+func (s *PropertyChangeStats) LoadFromFile(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read statistics file %s: %w", filename, err)
+	}
+
+	var fileData statsFileFormat
+	if err := json.Unmarshal(data, &fileData); err != nil {
+		return fmt.Errorf("failed to unmarshal statistics file %s: %w", filename, err)
+	}
+	if fileData.Version != currentStatsFileVersion {
+		slog.Warn("Statistics file version mismatch", "file", filename, "expected", currentStatsFileVersion, "actual", fileData.Version)
+	}
+
+	for key, epcs := range fileData.Data {
+		device, err := ParseDeviceIdentifier(key)
+		if err != nil {
+			slog.Warn("Skipping invalid device in statistics file", "device", key, "err", err)
+			continue
+		}
+		for epcStr, buckets := range epcs {
+			epc, err := strconv.ParseUint(epcStr, 16, 8)
+			if err != nil {
+				slog.Warn("Skipping invalid EPC in statistics file", "device", key, "epc", epcStr)
+				continue
+			}
+			for bucketStr, count := range buckets {
+				bucket, err := strconv.ParseInt(bucketStr, 10, 64)
+				if err != nil || count <= 0 {
+					continue
+				}
+				at := time.Unix(0, bucket*int64(statsBucketWidth))
+				s.recordAt(device, echonet_lite.EPCType(epc), at, count)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+)
+
+func newTestStats(now *time.Time) *PropertyChangeStats {
+	s := NewPropertyChangeStats()
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func TestPropertyChangeStats_RollingWindows(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestStats(&now)
+
+	device := IPAndEOJ{IP: net.ParseIP("192.168.0.10"), EOJ: echonet_lite.MakeEOJ(0x0130, 1)}
+	s.Record(device, 0xBB)
+	s.Record(device, 0xBB)
+	s.Record(device, 0x80)
+
+	now = now.Add(2 * time.Hour)
+	s.Record(device, 0xBB)
+
+	stats := s.Query(nil)
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(stats))
+	}
+	if stats[0].EPC != 0xBB || stats[0].LastDay != 3 || stats[0].LastHour != 1 {
+		t.Errorf("unexpected first entry: %+v", stats[0])
+	}
+	if stats[1].EPC != 0x80 || stats[1].LastDay != 1 || stats[1].LastHour != 0 {
+		t.Errorf("unexpected second entry: %+v", stats[1])
+	}
+
+	// 1日経過すると古いカウントは消える
+	now = now.Add(23 * time.Hour)
+	stats = s.Query(&device)
+	if len(stats) != 1 || stats[0].LastDay != 1 {
+		t.Errorf("expected only the latest change to remain, got %+v", stats)
+	}
+}
+
+func TestPropertyChangeStats_FilterAndRemove(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestStats(&now)
+
+	a := IPAndEOJ{IP: net.ParseIP("192.168.0.10"), EOJ: echonet_lite.MakeEOJ(0x0130, 1)}
+	b := IPAndEOJ{IP: net.ParseIP("192.168.0.11"), EOJ: echonet_lite.MakeEOJ(0x0291, 1)}
+	s.Record(a, 0x80)
+	s.Record(b, 0x80)
+
+	if got := s.Query(&b); len(got) != 1 || got[0].Device.Key() != b.Key() {
+		t.Errorf("expected only device b, got %+v", got)
+	}
+
+	s.Remove(a)
+	if got := s.Query(&a); len(got) != 0 {
+		t.Errorf("expected no entries after Remove, got %+v", got)
+	}
+}
+
+func TestPropertyChangeStats_SaveAndLoad(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestStats(&now)
+
+	device := IPAndEOJ{IP: net.ParseIP("192.168.0.10"), EOJ: echonet_lite.MakeEOJ(0x0130, 1)}
+	s.Record(device, 0xBB)
+	s.Record(device, 0xBB)
+
+	filename := filepath.Join(t.TempDir(), PropertyChangeStatsFileName)
+	if err := s.SaveToFile(filename); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+
+	loaded := newTestStats(&now)
+	if err := loaded.LoadFromFile(filename); err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	stats := loaded.Query(nil)
+	if len(stats) != 1 || stats[0].LastDay != 2 || stats[0].LastHour != 2 {
+		t.Errorf("unexpected loaded stats: %+v", stats)
+	}
+
+	if err := NewPropertyChangeStats().LoadFromFile(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("missing file should not be an error: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"echonet-list/echonet_lite"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// propertyMapRefreshInterval は、INF をきっかけにプロパティマップを取得し直す最短の間隔
+// プロパティマップにないプロパティを通知し続ける機器で、通知のたびに取得し直さないようにする
+const propertyMapRefreshInterval = time.Hour
+
+// propertyMapEPCs は、取得し直すプロパティマップのEPC
+var propertyMapEPCs = []EPCType{
+	echonet_lite.EPCStatusAnnouncementPropertyMap,
+	echonet_lite.EPCSetPropertyMap,
+	echonet_lite.EPCGetPropertyMap,
+}
+
+// PropertyMapChange は、プロパティマップを取得し直したときの Get プロパティマップの変化
+type PropertyMapChange struct {
+	Added   []EPCType // 新しく加わったEPC
+	Removed []EPCType // なくなったEPC
+}
+
+// diffPropertyMaps は、Get プロパティマップの変化をEPCの順に返す
+func diffPropertyMaps(before, after PropertyMap) PropertyMapChange {
+	var change PropertyMapChange
+	for epc := range after {
+		if !before.Has(epc) {
+			change.Added = append(change.Added, epc)
+		}
+	}
+	for epc := range before {
+		if !after.Has(epc) {
+			change.Removed = append(change.Removed, epc)
+		}
+	}
+	sort.Slice(change.Added, func(i, j int) bool { return change.Added[i] < change.Added[j] })
+	sort.Slice(change.Removed, func(i, j int) bool { return change.Removed[i] < change.Removed[j] })
+	return change
+}
+
+// RefreshPropertyMap は、デバイスのプロパティマップを取得し直し、Get プロパティマップのすべてのプロパティを取得する
+// ファームウェアの更新などでプロパティが増減した機器の、キャッシュしたプロパティマップを更新するために使う
+func (h *CommunicationHandler) RefreshPropertyMap(device IPAndEOJ) (PropertyMapChange, error) {
+	h.markPropertyMapRefreshed(device)
+	before := h.dataAccessor.GetPropertyMap(device, GetPropertyMap)
+
+	success, properties, failedEPCs, err := h.session.GetProperties(h.ctx, device, propertyMapEPCs)
+	if err != nil {
+		return PropertyMapChange{}, fmt.Errorf("%v: プロパティマップの取得に失敗: %w", device, err)
+	}
+	if !success {
+		h.log().Warn("一部のプロパティマップの取得に失敗", "device", device, "failed_epcs", failedEPCs)
+	}
+	if len(properties) > 0 {
+		h.dataAccessor.RegisterProperties(device, properties)
+	}
+
+	after := h.dataAccessor.GetPropertyMap(device, GetPropertyMap)
+	if after == nil {
+		return PropertyMapChange{}, fmt.Errorf("%v: GetPropertyMapを取得できません", device)
+	}
+	change := diffPropertyMaps(before, after)
+	if len(change.Added) > 0 || len(change.Removed) > 0 {
+		h.log().Info("プロパティマップが変化", "device", device.Specifier(), "added", change.Added, "removed", change.Removed)
+	}
+
+	// 新しい Get プロパティマップのすべてのプロパティを取得する
+	if _, err := h.GetProperties(device, nil, true); err != nil {
+		return change, err
+	}
+	return change, nil
+}
+
+// hasUnmappedEPC は、キャッシュした Get プロパティマップにないプロパティが properties に含まれるかどうかを返す
+// プロパティマップをキャッシュしていない場合は false を返す
+func (h *CommunicationHandler) hasUnmappedEPC(device IPAndEOJ, properties Properties) bool {
+	propMap := h.dataAccessor.GetPropertyMap(device, GetPropertyMap)
+	if propMap == nil {
+		return false
+	}
+	for _, p := range properties {
+		if !propMap.Has(p.EPC) {
+			return true
+		}
+	}
+	return false
+}
+
+// markPropertyMapRefreshed は、デバイスのプロパティマップを取得し直した時刻を記録する
+func (h *CommunicationHandler) markPropertyMapRefreshed(device IPAndEOJ) {
+	h.propertyMapRefreshMu.Lock()
+	defer h.propertyMapRefreshMu.Unlock()
+	if h.propertyMapRefreshed == nil {
+		h.propertyMapRefreshed = make(map[string]time.Time)
+	}
+	h.propertyMapRefreshed[device.Key()] = time.Now()
+}
+
+// claimPropertyMapRefresh は、INF をきっかけにプロパティマップを取得し直してよいかどうかを返す
+// 直前に取得し直したデバイスは propertyMapRefreshInterval が経つまで取得し直さない
+func (h *CommunicationHandler) claimPropertyMapRefresh(device IPAndEOJ) bool {
+	h.propertyMapRefreshMu.Lock()
+	defer h.propertyMapRefreshMu.Unlock()
+	if h.propertyMapRefreshed == nil {
+		h.propertyMapRefreshed = make(map[string]time.Time)
+	}
+	key := device.Key()
+	if last, ok := h.propertyMapRefreshed[key]; ok && time.Since(last) < propertyMapRefreshInterval {
+		return false
+	}
+	h.propertyMapRefreshed[key] = time.Now()
+	return true
+}
+
+// refreshPropertyMapOnInf は、INF でプロパティマップにないプロパティが通知された場合に、バックグラウンドでプロパティマップを取得し直す
+// 受信処理の中で応答を待つことはできないため、別の goroutine で取得する
+func (h *CommunicationHandler) refreshPropertyMapOnInf(device IPAndEOJ, properties Properties) {
+	if !h.hasUnmappedEPC(device, properties) || !h.claimPropertyMapRefresh(device) {
+		return
+	}
+	h.log().Info("プロパティマップにないプロパティが通知されたため、プロパティマップを取得し直します", "device", device.Specifier())
+	go func() {
+		if _, err := h.RefreshPropertyMap(device); err != nil {
+			h.log().Warn("プロパティマップの再取得に失敗", "device", device.Specifier(), "err", err)
+		}
+	}()
+}
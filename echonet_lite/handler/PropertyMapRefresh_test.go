@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/simulator"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDiffPropertyMaps(t *testing.T) {
+	before := make(PropertyMap)
+	after := make(PropertyMap)
+	for _, epc := range []EPCType{0x80, 0x9F, 0xB0} {
+		before.Set(epc)
+	}
+	for _, epc := range []EPCType{0xB3, 0x80, 0x9F, 0xB1} {
+		after.Set(epc)
+	}
+
+	change := diffPropertyMaps(before, after)
+	if !slices.Equal(change.Added, []EPCType{0xB1, 0xB3}) || !slices.Equal(change.Removed, []EPCType{0xB0}) {
+		t.Errorf("変化が不正: %+v", change)
+	}
+	if change := diffPropertyMaps(nil, before); len(change.Added) != 3 || len(change.Removed) != 0 {
+		t.Errorf("キャッシュがない場合の変化が不正: %+v", change)
+	}
+}
+
+func TestCommunicationHandler_claimPropertyMapRefresh(t *testing.T) {
+	h := &CommunicationHandler{}
+	device := echonet_lite.IPAndEOJ{IP: net.ParseIP("192.0.2.1"), EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)}
+	other := echonet_lite.IPAndEOJ{IP: net.ParseIP("192.0.2.2"), EOJ: device.EOJ}
+
+	if !h.claimPropertyMapRefresh(device) {
+		t.Fatal("最初の取得し直しが許可されない")
+	}
+	if h.claimPropertyMapRefresh(device) {
+		t.Error("propertyMapRefreshInterval 以内に再び許可された")
+	}
+	if !h.claimPropertyMapRefresh(other) {
+		t.Error("別のデバイスの取得し直しが許可されない")
+	}
+
+	h.propertyMapRefreshed[device.Key()] = time.Now().Add(-propertyMapRefreshInterval)
+	if !h.claimPropertyMapRefresh(device) {
+		t.Error("propertyMapRefreshInterval が経っても許可されない")
+	}
+}
+
+func TestECHONETLiteHandler_RefreshPropertyMap(t *testing.T) {
+	ip := net.ParseIP("192.0.2.1")
+	light := echonet_lite.IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)}
+	network := simulator.NewNetwork([]simulator.NodeSpec{{
+		IP: ip,
+		Devices: []simulator.DeviceSpec{{
+			EOJ: light.EOJ,
+			Properties: echonet_lite.Properties{
+				{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}},
+				{EPC: 0xB0, EDT: []byte{0x42}},
+			},
+		}},
+	}})
+	h, err := NewECHONETLiteHandler(context.Background(), ECHONETLieHandlerOptions{
+		Connection: network,
+		InMemory:   true,
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatalf("NewECHONETLiteHandler failed: %v", err)
+	}
+	t.Cleanup(func() { _ = h.Close() })
+	h.StartMainLoop()
+
+	data := h.GetDataManagementHandler()
+	waitForGetMap := func(epc EPCType) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !data.GetPropertyMap(light, GetPropertyMap).Has(epc) {
+			if time.Now().After(deadline) {
+				t.Fatalf("Get プロパティマップに %v が登録されない", epc)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	// ファームウェア更新前の古いプロパティマップに置き換える
+	setStaleMap := func() {
+		stale := make(PropertyMap)
+		stale.Set(echonet_lite.EPCOperationStatus)
+		stale.Set(echonet_lite.EPCGetPropertyMap)
+		data.RegisterProperties(light, Properties{{EPC: echonet_lite.EPCGetPropertyMap, EDT: stale.Encode()}})
+	}
+
+	if err := h.Discover(); err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	waitForGetMap(0xB0)
+
+	setStaleMap()
+	change, err := h.RefreshPropertyMap(light)
+	if err != nil {
+		t.Fatalf("RefreshPropertyMap failed: %v", err)
+	}
+	if !slices.Contains(change.Added, 0xB0) || len(change.Removed) != 0 {
+		t.Errorf("変化が不正: %+v", change)
+	}
+	if _, ok := data.GetProperty(light, 0xB0); !ok {
+		t.Error("新しく加わったプロパティが取得されていない")
+	}
+
+	// プロパティマップにないプロパティの INF で取得し直す
+	setStaleMap()
+	h.comm.propertyMapRefreshMu.Lock()
+	h.comm.propertyMapRefreshed = nil
+	h.comm.propertyMapRefreshMu.Unlock()
+	h.comm.refreshPropertyMapOnInf(light, Properties{{EPC: 0xB0, EDT: []byte{0x41}}})
+	waitForGetMap(0xB0)
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrSessionDraining は、終了処理中のため要求を送信しなかったことを表す
+var ErrSessionDraining = errors.New("終了処理中のため要求を送信できません")
+
+// requestTracker は、応答を待っている要求の数を数え、終了時にそれらが終わるまで待つ
+// ゼロ値で使用できる
+type requestTracker struct {
+	mu       sync.Mutex
+	draining bool          // true の場合は新しい要求を受け付けない
+	active   int           // 応答を待っている要求の数
+	idle     chan struct{} // active が0になったときに閉じる（drain で待っている間のみ）
+}
+
+// begin は、要求の開始を記録する。戻り値の関数で終了を記録すること
+// 終了処理中の場合は ErrSessionDraining を返す
+func (t *requestTracker) begin() (func(), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return nil, ErrSessionDraining
+	}
+	t.active++
+	var once sync.Once
+	return func() { once.Do(t.end) }, nil
+}
+
+func (t *requestTracker) end() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active--
+	if t.active == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// isDraining は、終了処理中かどうかを返す
+func (t *requestTracker) isDraining() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.draining
+}
+
+// drain は、新しい要求を受け付けないようにし、応答を待っている要求がすべて終わるまで待つ
+func (t *requestTracker) drain(ctx context.Context) error {
+	t.mu.Lock()
+	t.draining = true
+	if t.active == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		active := t.active
+		t.mu.Unlock()
+		return fmt.Errorf("応答待ちの要求が %d 件残っています: %w", active, context.Cause(ctx))
+	}
+}
+
+// Drain は、新しい要求の送信を止め、応答を待っている要求（再送中のものを含む）がすべて終わるまで待つ
+// ctx が終わった場合は、残っている要求の数を含むエラーを返す。終了処理で Close の前に呼び出す
+func (s *Session) Drain(ctx context.Context) error {
+	return s.requests.drain(ctx)
+}
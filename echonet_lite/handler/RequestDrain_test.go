@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestRequestTracker_Drain 応答待ちの要求が終わるまで待つテスト
+func TestRequestTracker_Drain(t *testing.T) {
+	var tracker requestTracker
+	done1, err := tracker.begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done2, err := tracker.begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drained := make(chan error, 1)
+	go func() {
+		drained <- tracker.drain(context.Background())
+	}()
+
+	// 終了処理中は新しい要求を受け付けない
+	deadline := time.Now().Add(time.Second)
+	for !tracker.isDraining() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := tracker.begin(); !errors.Is(err, ErrSessionDraining) {
+		t.Errorf("begin during drain = %v, want ErrSessionDraining", err)
+	}
+
+	// 終了の記録は重複しても1回と数える
+	done1()
+	done1()
+	select {
+	case err := <-drained:
+		t.Fatalf("drain returned before all requests finished: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	done2()
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Errorf("drain failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("drain did not return after all requests finished")
+	}
+}
+
+// TestRequestTracker_DrainTimeout 要求が終わらないうちにコンテキストが終わった場合のテスト
+func TestRequestTracker_DrainTimeout(t *testing.T) {
+	var tracker requestTracker
+	done, err := tracker.begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := tracker.drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("drain = %v, want deadline exceeded", err)
+	}
+
+	// 要求がなければすぐに終わる
+	var idle requestTracker
+	if err := idle.drain(ctx); err != nil {
+		t.Errorf("drain without requests = %v", err)
+	}
+}
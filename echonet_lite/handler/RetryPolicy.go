@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"echonet-list/echonet_lite"
+)
+
+// デフォルトの再送設定
+const (
+	DefaultMaxRetries    = 7               // 指数バックオフで約2分のタイムアウト、応答の遅い冷蔵庫などに対応
+	DefaultRetryInterval = 3 * time.Second // 最初の再送までの間隔
+)
+
+// RetryPolicy は、応答のない要求を再送する回数と間隔を表す
+type RetryPolicy struct {
+	MaxRetries    int           // 最大再送回数（0の場合は上位の設定を使う）
+	RetryInterval time.Duration // 最初の再送までの間隔。以降は指数バックオフで伸びる（0の場合は上位の設定を使う）
+}
+
+// String は、デバッグ出力向けに再送設定を表す文字列を返す
+func (p RetryPolicy) String() string {
+	return fmt.Sprintf("最大%d回, 間隔%v", p.MaxRetries, p.RetryInterval)
+}
+
+// overrideWith は、o で指定されたフィールドだけを置き換えた設定を返す
+func (p RetryPolicy) overrideWith(o RetryPolicy) RetryPolicy {
+	if o.MaxRetries > 0 {
+		p.MaxRetries = o.MaxRetries
+	}
+	if o.RetryInterval > 0 {
+		p.RetryInterval = o.RetryInterval
+	}
+	return p
+}
+
+// RetryOptions は、全体と、クラスやIPアドレスごとの再送設定を表す
+// 応答の遅い冷蔵庫は長く待ち、照明はすぐに諦めるといったように、デバイスによって変えられる
+type RetryOptions struct {
+	Default RetryPolicy                               // 全体の設定（ゼロ値のフィールドは DefaultMaxRetries, DefaultRetryInterval）
+	Classes map[echonet_lite.EOJClassCode]RetryPolicy // クラスごとの設定
+	IPs     map[string]RetryPolicy                    // IPアドレス（net.IP.String() の形式）ごとの設定。クラスの設定より優先する
+}
+
+// Validate は、再送設定が有効かどうかを検証する
+func (o RetryOptions) Validate() error {
+	check := func(name string, p RetryPolicy) error {
+		if p.MaxRetries < 0 || p.RetryInterval < 0 {
+			return fmt.Errorf("%s の再送設定に負の値は指定できません", name)
+		}
+		return nil
+	}
+	if err := check("全体", o.Default); err != nil {
+		return err
+	}
+	for classCode, p := range o.Classes {
+		if err := check(classCode.String(), p); err != nil {
+			return err
+		}
+	}
+	for ip, p := range o.IPs {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("再送設定のIPアドレスが不正です: %s", ip)
+		}
+		if err := check(ip, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetRetryOptions は、再送の設定を行う。MainLoop を開始する前に呼び出すこと
+// クラスやIPアドレスごとの設定がある場合は、それぞれの実際に使われる設定をログに出力する
+func (s *Session) SetRetryOptions(opts RetryOptions) {
+	s.MaxRetries = DefaultMaxRetries
+	s.RetryInterval = DefaultRetryInterval
+	if opts.Default.MaxRetries > 0 {
+		s.MaxRetries = opts.Default.MaxRetries
+	}
+	if opts.Default.RetryInterval > 0 {
+		s.RetryInterval = opts.Default.RetryInterval
+	}
+
+	s.retryClasses = make(map[echonet_lite.EOJClassCode]RetryPolicy, len(opts.Classes))
+	for classCode, p := range opts.Classes {
+		s.retryClasses[classCode] = p
+	}
+	s.retryIPs = make(map[string]RetryPolicy, len(opts.IPs))
+	for ip, p := range opts.IPs {
+		// IPv6 の省略表記などが異なっても一致するように正規化する
+		if parsed := net.ParseIP(ip); parsed != nil {
+			ip = parsed.String()
+		}
+		s.retryIPs[ip] = p
+	}
+
+	if len(s.retryClasses) == 0 && len(s.retryIPs) == 0 {
+		return
+	}
+	def := RetryPolicy{MaxRetries: s.MaxRetries, RetryInterval: s.RetryInterval}
+	s.log().Info("再送設定", "policy", def)
+	for classCode, p := range s.retryClasses {
+		s.log().Info("クラスの再送設定", "class", classCode, "policy", def.overrideWith(p))
+	}
+	// IPアドレスの設定で省略した項目は、クラスの設定があればそちらが使われる
+	for ip, p := range s.retryIPs {
+		s.log().Info("IPアドレスの再送設定", "ip", ip, "policy", def.overrideWith(p))
+	}
+}
+
+// RetryPolicyFor は、デバイスへの要求に使う再送設定を返す
+// 全体の設定に、クラスの設定、IPアドレスの設定の順に重ねる
+func (s *Session) RetryPolicyFor(device echonet_lite.IPAndEOJ) RetryPolicy {
+	policy := RetryPolicy{MaxRetries: s.MaxRetries, RetryInterval: s.RetryInterval}
+	if p, ok := s.retryClasses[device.EOJ.ClassCode()]; ok {
+		policy = policy.overrideWith(p)
+	}
+	if device.IP != nil {
+		if p, ok := s.retryIPs[device.IP.String()]; ok {
+			policy = policy.overrideWith(p)
+		}
+	}
+	return policy
+}
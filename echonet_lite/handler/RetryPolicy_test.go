@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+)
+
+// TestSession_RetryPolicyFor クラスとIPアドレスごとの再送設定のテスト
+func TestSession_RetryPolicyFor(t *testing.T) {
+	s := &Session{}
+	s.SetRetryOptions(RetryOptions{
+		Default: RetryPolicy{MaxRetries: 5},
+		Classes: map[echonet_lite.EOJClassCode]RetryPolicy{
+			echonet_lite.Refrigerator_ClassCode:           {MaxRetries: 10, RetryInterval: 10 * time.Second},
+			echonet_lite.SingleFunctionLighting_ClassCode: {MaxRetries: 2},
+		},
+		IPs: map[string]RetryPolicy{
+			"192.168.1.20": {RetryInterval: 20 * time.Second},
+			"fe80::0001":   {MaxRetries: 1},
+		},
+	})
+
+	device := func(ip string, classCode echonet_lite.EOJClassCode) echonet_lite.IPAndEOJ {
+		return echonet_lite.IPAndEOJ{IP: net.ParseIP(ip), EOJ: echonet_lite.MakeEOJ(classCode, 1)}
+	}
+	tests := []struct {
+		name   string
+		device echonet_lite.IPAndEOJ
+		want   RetryPolicy
+	}{
+		{"全体の設定（省略した間隔は既定値）", device("192.168.1.10", echonet_lite.HomeAirConditioner_ClassCode), RetryPolicy{5, DefaultRetryInterval}},
+		{"クラスの設定", device("192.168.1.11", echonet_lite.Refrigerator_ClassCode), RetryPolicy{10, 10 * time.Second}},
+		{"クラスの設定で省略した項目は全体の設定", device("192.168.1.12", echonet_lite.SingleFunctionLighting_ClassCode), RetryPolicy{2, DefaultRetryInterval}},
+		{"IPアドレスの設定はクラスの設定に重ねる", device("192.168.1.20", echonet_lite.Refrigerator_ClassCode), RetryPolicy{10, 20 * time.Second}},
+		{"IPv6 の表記の違い", device("fe80::1", echonet_lite.HomeAirConditioner_ClassCode), RetryPolicy{1, DefaultRetryInterval}},
+	}
+	for _, tt := range tests {
+		if got := s.RetryPolicyFor(tt.device); got != tt.want {
+			t.Errorf("%s: RetryPolicyFor = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// タイムアウトのエラーにはデバイスの設定が入る
+	var maxErr ErrMaxRetriesReached
+	err := s.notifyDeviceTimeout(device("192.168.1.11", echonet_lite.Refrigerator_ClassCode), time.Minute)
+	if !errors.As(err, &maxErr) || maxErr.MaxRetries != 10 || maxErr.RetryInterval != 10*time.Second {
+		t.Errorf("notifyDeviceTimeout = %v", err)
+	}
+}
+
+func TestRetryOptions_Validate(t *testing.T) {
+	valid := RetryOptions{IPs: map[string]RetryPolicy{"192.168.1.20": {MaxRetries: 3}}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+	invalid := []RetryOptions{
+		{Default: RetryPolicy{MaxRetries: -1}},
+		{Classes: map[echonet_lite.EOJClassCode]RetryPolicy{echonet_lite.Refrigerator_ClassCode: {RetryInterval: -time.Second}}},
+		{IPs: map[string]RetryPolicy{"refrigerator": {MaxRetries: 3}}},
+	}
+	for _, opts := range invalid {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expected an error for %+v", opts)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+)
+
+// startScheduler は、毎分0秒にスケジュールを確認して実行するゴルーチンを開始する
+func (h *ECHONETLiteHandler) startScheduler() {
+	go func() {
+		for {
+			now := time.Now()
+			next := now.Truncate(time.Minute).Add(time.Minute)
+			timer := time.NewTimer(next.Sub(now))
+			select {
+			case <-timer.C:
+				h.runDueSchedules(next)
+			case <-h.core.ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// runDueSchedules は、指定時刻に一致するスケジュールをそれぞれ並列に実行する
+func (h *ECHONETLiteHandler) runDueSchedules(t time.Time) {
+	for _, schedule := range h.data.Schedules.DueSchedules(t) {
+		go func(schedule Schedule) {
+			if _, err := h.runSchedule(schedule); err != nil {
+				h.log().Warn("スケジュールの実行に失敗", "schedule", schedule.Name, "error", err)
+			}
+		}(schedule)
+	}
+}
+
+// runSchedule は、スケジュールのシーンとプロパティを設定する
+// 一部のデバイスで失敗しても他のデバイスへの設定は続行し、失敗した数をエラーとして返す
+func (h *ECHONETLiteHandler) runSchedule(schedule Schedule) ([]SceneRunResult, error) {
+	var actions []SceneAction
+	var opts SceneRunOptions
+	if schedule.Scene != "" {
+		sceneActions, ok := h.data.GetScene(schedule.Scene)
+		if !ok {
+			return nil, fmt.Errorf("スケジュール %s のシーンが存在しません: %s", schedule.Name, schedule.Scene)
+		}
+		actions = append(actions, sceneActions...)
+		opts.Sequence = h.data.GetSceneSequence(schedule.Scene)
+	}
+	actions = append(actions, schedule.Actions...)
+
+	h.log().Info("スケジュールを実行", "schedule", schedule.Name, "cron", schedule.Cron, "actions", len(actions))
+	results := RunSceneActions(actions, h.data.FindDeviceByIDString, h.setSceneProperties, opts)
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			h.log().Warn("スケジュールのプロパティ設定に失敗", "schedule", schedule.Name, "device", result.ID, "error", result.Err)
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("スケジュール %s: %d/%d デバイスの設定に失敗しました", schedule.Name, failed, len(results))
+	}
+	return results, nil
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ScheduleTrigger は、スケジュールを実行する時刻の指定（cron 形式または日の出・日の入り）
+// 日の出・日の入りの計算には地点が必要で、site が nil の場合は一致しない
+type ScheduleTrigger interface {
+	// Matches は、指定時刻（分単位）に実行するかを返す
+	Matches(t time.Time, site *Coordinates) bool
+	// Next は、after より後の次回実行時刻を返す（ない場合はゼロ値）
+	Next(after time.Time, site *Coordinates) time.Time
+	// NeedsCoordinates は、実行時刻の計算に地点が必要かどうかを返す
+	NeedsCoordinates() bool
+}
+
+// ParseScheduleTrigger は、スケジュールの時刻の指定をパースする
+// cron 形式のほかに「@sunrise[±オフセット] [曜日]」「@sunset[±オフセット] [曜日]」を受け付ける
+// 例: "@sunset-30m", "@sunrise+1h 1-5"
+func ParseScheduleTrigger(spec string) (ScheduleTrigger, error) {
+	fields := strings.Fields(spec)
+	if len(fields) > 0 && (strings.HasPrefix(fields[0], "@"+string(Sunrise)) || strings.HasPrefix(fields[0], "@"+string(Sunset))) {
+		return parseSolarTrigger(fields)
+	}
+	cron, err := ParseCronSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	return cronTrigger{spec: cron}, nil
+}
+
+// cronTrigger は、cron 形式の ScheduleTrigger
+type cronTrigger struct {
+	spec CronSpec
+}
+
+func (c cronTrigger) Matches(t time.Time, _ *Coordinates) bool { return c.spec.Matches(t) }
+
+func (c cronTrigger) Next(after time.Time, _ *Coordinates) time.Time { return c.spec.Next(after) }
+
+func (c cronTrigger) NeedsCoordinates() bool { return false }
+
+// solarTrigger は、日の出・日の入りの時刻にオフセットを加えた時刻に実行する ScheduleTrigger
+type solarTrigger struct {
+	event  SolarEvent
+	offset time.Duration
+	dow    uint64 // 日の出・日の入りの日の曜日（bit i が立っていれば曜日 i に一致）
+}
+
+// solarSearchDays は、次回実行時刻を探す日数（白夜・極夜が続く地点でも1年分）
+const solarSearchDays = 370
+
+func parseSolarTrigger(fields []string) (ScheduleTrigger, error) {
+	if len(fields) > 2 {
+		return nil, fmt.Errorf("日の出・日の入りの指定は「@sunrise[±オフセット] [曜日]」の形式です: %q", strings.Join(fields, " "))
+	}
+	trigger := solarTrigger{event: Sunrise, dow: 1<<7 - 1}
+	rest := strings.TrimPrefix(fields[0], "@"+string(Sunrise))
+	if strings.HasPrefix(fields[0], "@"+string(Sunset)) {
+		trigger.event = Sunset
+		rest = strings.TrimPrefix(fields[0], "@"+string(Sunset))
+	}
+	if rest != "" {
+		if rest[0] != '+' && rest[0] != '-' {
+			return nil, fmt.Errorf("日の出・日の入りのオフセットは +30m や -1h の形式です: %q", fields[0])
+		}
+		offset, err := time.ParseDuration(rest)
+		if err != nil {
+			return nil, fmt.Errorf("日の出・日の入りのオフセットが不正です: %q", fields[0])
+		}
+		if offset <= -12*time.Hour || offset >= 12*time.Hour {
+			return nil, fmt.Errorf("日の出・日の入りのオフセットは12時間未満です: %q", fields[0])
+		}
+		trigger.offset = offset
+	}
+	if len(fields) == 2 {
+		dow, err := parseCronField(fields[1], 0, 7)
+		if err != nil {
+			return nil, fmt.Errorf("曜日の指定が不正です: %w", err)
+		}
+		// 7 は日曜日
+		if dow&(1<<7) != 0 {
+			dow |= 1
+		}
+		trigger.dow = dow &^ (1 << 7)
+	}
+	return trigger, nil
+}
+
+// at は、day の日付の実行時刻を返す（日の出・日の入りがない日や曜日が一致しない日は false）
+func (s solarTrigger) at(day time.Time, site *Coordinates) (time.Time, bool) {
+	if s.dow&(1<<uint(day.Weekday())) == 0 {
+		return time.Time{}, false
+	}
+	t, ok := site.SunEvent(day, s.event)
+	if !ok {
+		return time.Time{}, false
+	}
+	return t.Add(s.offset).Round(time.Minute), true
+}
+
+func (s solarTrigger) Matches(t time.Time, site *Coordinates) bool {
+	if site == nil {
+		return false
+	}
+	t = t.Truncate(time.Minute)
+	// オフセットで日付をまたぐ場合があるので前後の日も確認する
+	for d := -1; d <= 1; d++ {
+		if at, ok := s.at(t.AddDate(0, 0, d), site); ok && at.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s solarTrigger) Next(after time.Time, site *Coordinates) time.Time {
+	if site == nil {
+		return time.Time{}
+	}
+	for d := -1; d <= solarSearchDays; d++ {
+		if at, ok := s.at(after.AddDate(0, 0, d), site); ok && at.After(after) {
+			return at
+		}
+	}
+	return time.Time{}
+}
+
+func (s solarTrigger) NeedsCoordinates() bool { return true }
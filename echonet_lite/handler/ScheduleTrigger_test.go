@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"testing"
+	"time"
+)
+
+var tokyo = Coordinates{Latitude: 35.68, Longitude: 139.76}
+
+func TestCoordinates_SunTimes(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	tests := []struct {
+		day             time.Time
+		sunrise, sunset string
+	}{
+		// 国立天文台の暦計算による東京の日の出・日の入り
+		{time.Date(2026, 6, 21, 12, 0, 0, 0, jst), "04:25", "19:00"},
+		{time.Date(2026, 12, 22, 0, 0, 0, 0, jst), "06:47", "16:32"},
+	}
+	within := func(got time.Time, want string) bool {
+		w, _ := time.ParseInLocation("15:04", want, jst)
+		w = time.Date(got.Year(), got.Month(), got.Day(), w.Hour(), w.Minute(), 0, 0, jst)
+		d := got.Sub(w)
+		return d > -3*time.Minute && d < 3*time.Minute
+	}
+	for _, tt := range tests {
+		sunrise, sunset, ok := tokyo.SunTimes(tt.day)
+		if !ok {
+			t.Fatalf("%v: 日の出・日の入りがない", tt.day)
+		}
+		if sunrise.YearDay() != tt.day.YearDay() || !within(sunrise, tt.sunrise) {
+			t.Errorf("%v: 日の出 %v, 期待値 %s", tt.day, sunrise, tt.sunrise)
+		}
+		if sunset.YearDay() != tt.day.YearDay() || !within(sunset, tt.sunset) {
+			t.Errorf("%v: 日の入り %v, 期待値 %s", tt.day, sunset, tt.sunset)
+		}
+	}
+
+	// 北極圏の夏至は白夜
+	if _, _, ok := (Coordinates{Latitude: 78, Longitude: 15}).SunTimes(time.Date(2026, 6, 21, 0, 0, 0, 0, time.UTC)); ok {
+		t.Error("白夜で日の出・日の入りがある")
+	}
+}
+
+func TestParseScheduleTrigger_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"@sunrise30m",
+		"@sunset+",
+		"@sunset-12h",
+		"@sunrise 8",
+		"@sunrise 1-5 extra",
+		"@noon",
+	} {
+		if _, err := ParseScheduleTrigger(spec); err == nil {
+			t.Errorf("%q が有効と判定された", spec)
+		}
+	}
+}
+
+func TestScheduleTrigger_Solar(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	day := time.Date(2026, 6, 22, 0, 0, 0, 0, jst) // 月曜日
+	sunset, ok := tokyo.SunEvent(day, Sunset)
+	if !ok {
+		t.Fatal("日の入りがない")
+	}
+	at := sunset.Add(-30 * time.Minute).Round(time.Minute)
+
+	trigger, err := ParseScheduleTrigger("@sunset-30m 1-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !trigger.NeedsCoordinates() {
+		t.Error("日の出・日の入りのスケジュールに地点が不要と判定された")
+	}
+	if !trigger.Matches(at, &tokyo) {
+		t.Errorf("%v に一致しない", at)
+	}
+	if trigger.Matches(at.Add(time.Minute), &tokyo) {
+		t.Errorf("%v に一致した", at.Add(time.Minute))
+	}
+	if trigger.Matches(at, nil) {
+		t.Error("地点がないのに一致した")
+	}
+	if next := trigger.Next(day, &tokyo); !next.Equal(at) {
+		t.Errorf("次回実行時刻 %v, 期待値 %v", next, at)
+	}
+
+	// 土日は実行しない
+	saturday := time.Date(2026, 6, 27, 0, 0, 0, 0, jst)
+	if next := trigger.Next(saturday, &tokyo); next.Weekday() != time.Monday {
+		t.Errorf("土曜日以降の次回実行時刻が月曜日ではない: %v", next)
+	}
+
+	cron, err := ParseScheduleTrigger("0 7 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cron.NeedsCoordinates() {
+		t.Error("cron 形式のスケジュールに地点が必要と判定された")
+	}
+}
+
+func TestDeviceSchedules_SolarRequiresCoordinates(t *testing.T) {
+	schedules := NewDeviceSchedules()
+	schedule := Schedule{Name: "porch", Cron: "@sunset", Scene: "玄関灯"}
+	if err := schedules.ScheduleSet(schedule); err == nil {
+		t.Error("地点がないのに日の出・日の入りのスケジュールを追加できた")
+	}
+	schedules.SetCoordinates(&tokyo)
+	if err := schedules.ScheduleSet(schedule); err != nil {
+		t.Fatal(err)
+	}
+	jst := time.FixedZone("JST", 9*60*60)
+	next := schedules.NextRun(schedule, time.Date(2026, 6, 22, 12, 0, 0, 0, jst))
+	if next.IsZero() {
+		t.Fatal("次回実行時刻がない")
+	}
+	due := schedules.DueSchedules(next)
+	if len(due) != 1 || due[0].Name != "porch" {
+		t.Errorf("日の入りに実行対象にならない: %+v", due)
+	}
+}
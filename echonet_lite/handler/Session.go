@@ -52,9 +52,6 @@ const (
 	MaxRetryInterval   = 60 * time.Second // 最大リトライ間隔
 )
 
-// ブロードキャストアドレスの設定
-var BroadcastIP = network.GetIPv4BroadcastIP()
-
 type Key struct {
 	TID echonet_lite.TIDType
 }
@@ -94,21 +91,70 @@ type Session struct {
 	infCallback     PersistentCallbackFunc
 	tid             echonet_lite.TIDType
 	eoj             echonet_lite.EOJ
-	conn            *network.UDPConnection
+	conn            network.Connection
 	MulticastIP     net.IP
-	Debug           bool
-	ctx             context.Context                   // コンテキスト
-	cancel          context.CancelFunc                // コンテキストのキャンセル関数
-	MaxRetries      int                               // 最大再送回数
-	RetryInterval   time.Duration                     // 再送間隔
-	TimeoutCh       chan SessionTimeoutEvent          // タイムアウト通知用チャンネル
-	failedEPCs      map[string][]echonet_lite.EPCType // 失敗したEPCsを保持するマップ
-	IsOfflineFunc   func(echonet_lite.IPAndEOJ) bool  // デバイスがオフラインかどうかを判定する関数（オプショナル）
-	rng             *mathrand.Rand                    // スレッドセーフな乱数生成器
+	BroadcastIP     net.IP // IPv4 の検出要求を送るブロードキャストアドレス
+	// デュアルスタック時に MulticastIP と合わせて送信するもう一方のマルチキャストアドレス
+	SecondaryMulticastIP net.IP
+	Debug                bool
+	Strict               bool                                      // 仕様に従わない電文を破棄し、要求と一致しない応答をエラーにする
+	ctx                  context.Context                           // コンテキスト
+	cancel               context.CancelFunc                        // コンテキストのキャンセル関数
+	MaxRetries           int                                       // 最大再送回数
+	RetryInterval        time.Duration                             // 再送間隔
+	TimeoutCh            chan SessionTimeoutEvent                  // タイムアウト通知用チャンネル
+	failedEPCs           map[string][]echonet_lite.EPCType         // 失敗したEPCsを保持するマップ
+	IsOfflineFunc        func(echonet_lite.IPAndEOJ) bool          // デバイスがオフラインかどうかを判定する関数（オプショナル）
+	rng                  *mathrand.Rand                            // スレッドセーフな乱数生成器
+	logger               *slog.Logger                              // このセッションのログ出力先
+	pending              *pendingRequests                          // 応答待ちの要求
+	limiter              *outboundLimiter                          // 要求の送信の制限（nilの場合は制限なし）
+	frameLimits          FrameLimits                               // 1つの要求フレームに含めるプロパティの上限
+	retryClasses         map[echonet_lite.EOJClassCode]RetryPolicy // クラスごとの再送設定
+	retryIPs             map[string]RetryPolicy                    // IPアドレスごとの再送設定
+	requests             requestTracker                            // 応答待ちの要求（終了時に待つため）
 
 	// INFメッセージ受信によるデバイス生存確認
 	aliveMu       sync.RWMutex         // lastAliveTime用の排他制御
 	lastAliveTime map[string]time.Time // デバイスキー -> 最終生存確認時刻
+
+	// 受信を無視するアドレス（デュアルスタックで優先しない側の経路など）
+	ignoredMu  sync.RWMutex
+	ignoredIPs map[string]struct{}
+}
+
+// IgnoreIP は、以後 ip から受信したパケットを無視する
+// デュアルスタックで同じノードが IPv4 と IPv6 の両方から応答する場合に、優先しない側の経路を除外するために使う
+func (s *Session) IgnoreIP(ip net.IP) {
+	s.ignoredMu.Lock()
+	defer s.ignoredMu.Unlock()
+	if s.ignoredIPs == nil {
+		s.ignoredIPs = make(map[string]struct{})
+	}
+	s.ignoredIPs[ip.String()] = struct{}{}
+}
+
+// isIgnoredIP は、ip からの受信を無視するかどうかを返す
+func (s *Session) isIgnoredIP(ip net.IP) bool {
+	s.ignoredMu.RLock()
+	defer s.ignoredMu.RUnlock()
+	_, ok := s.ignoredIPs[ip.String()]
+	return ok
+}
+
+// DiscoveryIPs は、検出要求を送る宛先アドレスを返す
+// IPv4 ではブロードキャストアドレス、IPv6 では ECHONET Lite のマルチキャストアドレスを使い、デュアルスタックでは両方を返す
+func (s *Session) DiscoveryIPs() []net.IP {
+	var result []net.IP
+	if s.MulticastIP == nil || s.MulticastIP.To4() != nil {
+		result = append(result, s.BroadcastIP)
+	} else {
+		result = append(result, s.MulticastIP)
+	}
+	if s.SecondaryMulticastIP != nil {
+		result = append(result, s.SecondaryMulticastIP)
+	}
+	return result
 }
 
 // IsLocalIP は指定されたIPアドレスが自身のローカルIPのいずれかと一致するかを確認します
@@ -116,6 +162,25 @@ func (s *Session) IsLocalIP(ip net.IP) bool {
 	return s.conn.IsLocalIP(ip)
 }
 
+// SetReplyAddress は送信元アドレスを固定する（NAT/ブリッジ環境向け）
+// MainLoop を開始する前に呼び出すこと
+func (s *Session) SetReplyAddress(ip net.IP) error {
+	return s.conn.SetReplyAddress(ip)
+}
+
+// AddVirtualNodes は、このプログラム内で動く仮想ノード（天気など）を、実際のノードと同じように通信できるようにする
+// 仮想ノード宛ての要求は仮想ノードだけに送り、検出要求などのグループ宛ての要求は仮想ノードにも送る
+// MainLoop を開始する前に呼び出すこと。Close すると仮想ノードの接続も閉じる
+func (s *Session) AddVirtualNodes(virtual network.VirtualConnection) {
+	s.conn = network.NewMergedConnection(s.conn, virtual, s.isGroupAddress)
+}
+
+// isGroupAddress は、ip がマルチキャストまたはブロードキャストのアドレスかどうかを返す
+// インターフェースごとのブロードキャストアドレスは含まないが、定期的な検出は BroadcastIP に送るため仮想ノードも検出される
+func (s *Session) isGroupAddress(ip net.IP) bool {
+	return ip.IsMulticast() || ip.Equal(net.IPv4bcast) || ip.Equal(s.BroadcastIP)
+}
+
 // makeAliveKey はデバイスの生存確認用キー文字列を生成する
 // 形式: "IP:ClassCode:InstanceCode" (例: "192.168.1.100:0291:01")
 func makeAliveKey(device echonet_lite.IPAndEOJ) string {
@@ -129,7 +194,7 @@ func (s *Session) SignalDeviceAlive(device echonet_lite.IPAndEOJ) {
 	defer s.aliveMu.Unlock()
 	key := makeAliveKey(device)
 	s.lastAliveTime[key] = time.Now()
-	slog.Info("デバイス生存確認を記録", "key", key)
+	s.log().Info("デバイス生存確認を記録", "key", key)
 }
 
 // getLastAliveTime はデバイスの最終生存確認時刻を取得する
@@ -145,14 +210,19 @@ func (s *Session) getLastAliveTime(device echonet_lite.IPAndEOJ) time.Time {
 // ジッタは基準値の±30%の範囲でランダムに決定されます
 // retryCount: 0から始まるリトライ回数（0は初回のリトライ）
 func (s *Session) calculateRetryIntervalWithJitter(retryCount int) time.Duration {
+	return s.retryIntervalWithJitter(s.RetryInterval, retryCount)
+}
+
+// retryIntervalWithJitter は、再送間隔 retryInterval を基準に calculateRetryIntervalWithJitter と同じ計算を行う
+func (s *Session) retryIntervalWithJitter(retryInterval time.Duration, retryCount int) time.Duration {
 	// 入力検証: RetryIntervalが正の値であることを確認
-	if s.RetryInterval <= 0 {
-		slog.Warn("RetryIntervalが無効な値です。デフォルト値を使用", "interval", s.RetryInterval)
-		return 3 * time.Second // デフォルト値
+	if retryInterval <= 0 {
+		s.log().Warn("RetryIntervalが無効な値です。デフォルト値を使用", "interval", retryInterval)
+		return DefaultRetryInterval
 	}
 
 	// Exponential backoffを適用: baseInterval * (BackoffMultiplier ^ retryCount)
-	baseInterval := s.RetryInterval
+	baseInterval := retryInterval
 	for i := 0; i < retryCount; i++ {
 		baseInterval = time.Duration(float64(baseInterval) * BackoffMultiplier)
 		// 最大値を超えないようにする
@@ -197,32 +267,108 @@ func (s *Session) SetTimeoutChannel(ch chan SessionTimeoutEvent) {
 	s.TimeoutCh = ch
 }
 
-func CreateSession(ctx context.Context, ip net.IP, EOJ echonet_lite.EOJ, debug bool, networkMonitorConfig *network.NetworkMonitorConfig, isOfflineFunc func(echonet_lite.IPAndEOJ) bool) (*Session, error) {
+// CreateSession は、UDP で通信するセッションを作成する
+// ipVersion が空の場合は IPv4 で通信する。デュアルスタックでは IPv4 と IPv6 のソケットを両方開き、
+// ip はそのアドレスファミリーのソケットにのみ使う
+func CreateSession(ctx context.Context, ip net.IP, port int, ipVersion network.IPVersion, EOJ echonet_lite.EOJ, debug bool, networkMonitorConfig *network.NetworkMonitorConfig, isOfflineFunc func(echonet_lite.IPAndEOJ) bool) (*Session, error) {
 	// タイムアウトなしのコンテキストを作成（キャンセルのみ可能）
 	sessionCtx, cancel := context.WithCancel(ctx)
 
-	multicastIP := echonet_lite.ECHONETLiteMulticastIPv4
+	// ip のうち、指定したアドレスファミリーのものだけを返す
+	ipFor := func(v network.IPVersion) net.IP {
+		if ip != nil && v.Matches(ip) {
+			return ip
+		}
+		return nil
+	}
 
-	conn, err := network.CreateUDPConnection(sessionCtx, ip, echonet_lite.ECHONETLitePort, multicastIP, networkMonitorConfig)
-	if err != nil {
-		cancel() // エラーの場合はコンテキストをキャンセル
-		return nil, err
+	if port == 0 {
+		port = echonet_lite.ECHONETLitePort
 	}
+
+	switch ipVersion {
+	case "", network.IPv4:
+		multicastIP := echonet_lite.ECHONETLiteMulticastIPv4
+		conn, err := network.CreateUDPConnection(sessionCtx, ip, port, multicastIP, networkMonitorConfig)
+		if err != nil {
+			cancel() // エラーの場合はコンテキストをキャンセル
+			return nil, err
+		}
+		s := newSession(sessionCtx, cancel, conn, multicastIP, EOJ, debug, isOfflineFunc)
+		s.BroadcastIP = network.GetIPv4BroadcastIPFor(ip)
+		return s, nil
+
+	case network.IPv6:
+		multicastIP := echonet_lite.ECHONETLiteMulticastIPv6
+		conn, err := network.CreateUDPConnection(sessionCtx, ip, port, multicastIP, networkMonitorConfig)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		return newSession(sessionCtx, cancel, conn, multicastIP, EOJ, debug, isOfflineFunc), nil
+
+	case network.DualStack:
+		v4, err := network.CreateUDPConnection(sessionCtx, ipFor(network.IPv4), port, echonet_lite.ECHONETLiteMulticastIPv4, networkMonitorConfig)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		v6, err := network.CreateUDPConnection(sessionCtx, ipFor(network.IPv6), port, echonet_lite.ECHONETLiteMulticastIPv6, networkMonitorConfig)
+		if err != nil {
+			_ = v4.Close()
+			cancel()
+			return nil, err
+		}
+		s := newSession(sessionCtx, cancel, network.NewDualStackConnection(v4, v6), echonet_lite.ECHONETLiteMulticastIPv4, EOJ, debug, isOfflineFunc)
+		s.SecondaryMulticastIP = echonet_lite.ECHONETLiteMulticastIPv6
+		s.BroadcastIP = network.GetIPv4BroadcastIPFor(ipFor(network.IPv4))
+		return s, nil
+
+	default:
+		cancel()
+		return nil, fmt.Errorf("unknown IP version: %q", ipVersion)
+	}
+}
+
+// CreateSessionWithConnection は、UDP の代わりに指定された接続を使うセッションを作成する
+// デモモードの模擬ネットワークなど、実際のネットワークを使わない場合に使う
+func CreateSessionWithConnection(ctx context.Context, conn network.Connection, EOJ echonet_lite.EOJ, debug bool, isOfflineFunc func(echonet_lite.IPAndEOJ) bool) *Session {
+	sessionCtx, cancel := context.WithCancel(ctx)
+	return newSession(sessionCtx, cancel, conn, echonet_lite.ECHONETLiteMulticastIPv4, EOJ, debug, isOfflineFunc)
+}
+
+func newSession(ctx context.Context, cancel context.CancelFunc, conn network.Connection, multicastIP net.IP, EOJ echonet_lite.EOJ, debug bool, isOfflineFunc func(echonet_lite.IPAndEOJ) bool) *Session {
 	return &Session{
 		dispatchTable: make(DispatchTable),
 		tid:           echonet_lite.TIDType(1),
 		eoj:           EOJ,
 		conn:          conn,
 		MulticastIP:   multicastIP,
+		BroadcastIP:   net.IPv4bcast,
 		Debug:         debug,
-		ctx:           sessionCtx,
+		ctx:           ctx,
 		cancel:        cancel,
-		MaxRetries:    7,               // デフォルトの最大再送回数（指数バックオフで約2分のタイムアウト、応答の遅い冷蔵庫などに対応）
-		RetryInterval: 3 * time.Second, // デフォルトの再送間隔
+		MaxRetries:    DefaultMaxRetries,
+		RetryInterval: DefaultRetryInterval,
 		failedEPCs:    make(map[string][]echonet_lite.EPCType),
 		IsOfflineFunc: isOfflineFunc,
 		lastAliveTime: make(map[string]time.Time),
-	}, nil
+		logger:        loggerOrDefault(nil),
+		pending:       newPendingRequests(),
+	}
+}
+
+// debugRetryPolicy は、デバッグモードのとき、要求に使う再送設定を表示する
+func (s *Session) debugRetryPolicy(device echonet_lite.IPAndEOJ, policy RetryPolicy) {
+	if s.Debug {
+		s.log().Debug("再送設定", "device", device, "policy", policy)
+	}
+}
+
+// SetLogger は、このセッションのログ出力先を設定する（nil の場合は slog.Default() に出力する）
+// MainLoop を開始する前に呼び出すこと
+func (s *Session) SetLogger(logger *slog.Logger) {
+	s.logger = loggerOrDefault(logger)
 }
 
 func (s *Session) OnInf(callback PersistentCallbackFunc) {
@@ -272,7 +418,7 @@ func (s *Session) MainLoop() {
 
 			// 接続が閉じられた場合
 			if err.Error() == "use of closed network connection" {
-				slog.Info("受信終了: 接続が閉じられました")
+				s.log().Info("受信終了: 接続が閉じられました")
 				break
 			}
 
@@ -280,12 +426,12 @@ func (s *Session) MainLoop() {
 			// net.Error.Temporary()はdeprecatedなので、特定のエラータイプで判断する
 			if errors.Is(err, net.ErrClosed) {
 				// 接続が閉じられた場合
-				slog.Info("受信終了: 接続が閉じられました")
+				s.log().Info("受信終了: 接続が閉じられました")
 				break
 			}
 
 			// エラーログを記録
-			slog.Error("データ受信中にエラーが発生", "err", err)
+			s.log().Error("データ受信中にエラーが発生", "err", err)
 			time.Sleep(100 * time.Millisecond)
 			continue
 		}
@@ -295,19 +441,27 @@ func (s *Session) MainLoop() {
 			continue
 		}
 
+		if addr != nil && s.isIgnoredIP(addr.IP) {
+			continue
+		}
+
 		if s.Debug {
 			hexDump := hex.EncodeToString(data)
-			slog.Debug("受信データ(hex)", "addr", addr, "hex", hexDump)
+			s.log().Debug("受信データ(hex)", "addr", addr, "hex", hexDump)
 		}
 
-		msg, err := echonet_lite.ParseECHONETLiteMessage(data)
+		msg, err := s.parseMessage(data)
 		if err != nil {
-			slog.Error("パケット解析エラー", "err", err)
+			if s.Strict {
+				s.log().Warn("仕様に従わない電文を破棄", "from", addr, "err", err)
+			} else {
+				s.log().Error("パケット解析エラー", "err", err)
+			}
 			continue
 		}
 
 		if s.Debug {
-			fmt.Printf("応答を受信: %s から --- %v\n", addr, msg)
+			s.log().Debug("応答を受信", "from", addr, "message", msg)
 		}
 
 		switch msg.ESV {
@@ -336,7 +490,7 @@ func (s *Session) MainLoop() {
 				}
 			}
 			if err != nil {
-				slog.Error("ディスパッチエラー", "err", err)
+				s.log().Error("ディスパッチエラー", "err", err)
 			}
 		case echonet_lite.ESVINF, echonet_lite.ESVINFC:
 			// Get the callback while holding the lock
@@ -348,17 +502,17 @@ func (s *Session) MainLoop() {
 			if callback != nil {
 				err = callback(addr.IP, msg)
 				if err != nil {
-					slog.Error("Infコールバックエラー", "err", err)
+					s.log().Error("Infコールバックエラー", "err", err)
 				}
 			}
-		case echonet_lite.ESVGet, echonet_lite.ESVSetC, echonet_lite.ESVSetI, echonet_lite.ESVINF_REQ:
+		case echonet_lite.ESVGet, echonet_lite.ESVSetC, echonet_lite.ESVSetI, echonet_lite.ESVSetGet, echonet_lite.ESVINF_REQ:
 			s.mu.RLock()
 			callback := s.receiveCallback
 			s.mu.RUnlock()
 			if callback != nil {
 				err = callback(addr.IP, msg)
 				if err != nil {
-					slog.Error("ReceiveCallbackエラー", "DEOJ", msg.DEOJ, "err", err)
+					s.log().Error("ReceiveCallbackエラー", "DEOJ", msg.DEOJ, "err", err)
 				}
 			}
 		}
@@ -390,6 +544,47 @@ func (s *Session) newTID() echonet_lite.TIDType {
 	return s.tid
 }
 
+// SetOutboundLimits は、要求の送信の速さと同時に応答を待つ要求の数を制限する
+// ゼロ値を指定すると制限しない。MainLoop を開始する前に呼び出すこと
+func (s *Session) SetOutboundLimits(limits OutboundLimits) {
+	if !limits.enabled() {
+		s.limiter = nil
+		return
+	}
+	s.limiter = newOutboundLimiter(limits)
+}
+
+// SetFrameLimits は、1つの要求フレームに含めるプロパティの上限を設定する
+// 上限を超える Get/Set の要求は複数のフレームに分けて送信する。MainLoop を開始する前に呼び出すこと
+func (s *Session) SetFrameLimits(limits FrameLimits) {
+	s.frameLimits = limits
+}
+
+// acquireInFlight は、応答待ちの要求の枠を確保する。戻り値の関数で解放すること
+func (s *Session) acquireInFlight(ctx context.Context) (func(), error) {
+	if s.limiter == nil {
+		return func() {}, nil
+	}
+	return s.limiter.acquire(ctx)
+}
+
+// sendRequestMessage は、送信の制限に従って待ってから要求を送信する
+// 応答や通知（INF）は制限しないため sendMessage を直接使う
+func (s *Session) sendRequestMessage(ctx context.Context, ip net.IP, msg *echonet_lite.ECHONETLiteMessage) error {
+	if err := s.waitToSendRequest(ctx); err != nil {
+		return err
+	}
+	return s.sendMessage(ip, msg)
+}
+
+// waitToSendRequest は、送信の制限に従って要求を送信できるまで待つ
+func (s *Session) waitToSendRequest(ctx context.Context) error {
+	if s.limiter == nil {
+		return nil
+	}
+	return s.limiter.wait(ctx)
+}
+
 func (s *Session) registerCallback(key Key, ESVs []echonet_lite.ESVType, callback CallbackFunc) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -398,11 +593,11 @@ func (s *Session) registerCallback(key Key, ESVs []echonet_lite.ESVType, callbac
 
 func (s *Session) sendMessage(ip net.IP, msg *echonet_lite.ECHONETLiteMessage) error {
 	if _, err := s.conn.SendTo(ip, msg.Encode()); err != nil {
-		slog.Error("パケット送信エラー", "err", err)
+		s.log().Error("パケット送信エラー", "err", err)
 		return err
 	}
 	if s.Debug {
-		fmt.Printf("パケットを送信: %v へ --- %v\n", ip, msg)
+		s.log().Debug("パケットを送信", "to", ip, "message", msg)
 	}
 	return nil
 }
@@ -429,9 +624,15 @@ func (s *Session) Broadcast(SEOJ echonet_lite.EOJ, ESV echonet_lite.ESVType, pro
 	}
 	ip := s.MulticastIP
 	if ip == nil {
-		ip = BroadcastIP
+		ip = s.BroadcastIP
 	}
-	return s.sendMessage(ip, msg)
+	if err := s.sendMessage(ip, msg); err != nil {
+		return err
+	}
+	if s.SecondaryMulticastIP != nil {
+		return s.sendMessage(s.SecondaryMulticastIP, msg)
+	}
+	return nil
 }
 
 // GetPropertiesCallbackFunc はプロパティ取得のコールバック関数の型。
@@ -475,10 +676,41 @@ func (s *Session) prepareStartGetProperties(device echonet_lite.IPAndEOJ, EPCs [
 }
 
 func (s *Session) StartGetProperties(device echonet_lite.IPAndEOJ, EPCs []echonet_lite.EPCType, callback GetPropertiesCallbackFunc) (Key, error) {
+	// 応答の待ち方は呼び出し側が決めるため、終了処理中に新しく送信しないことだけを保証する
+	if s.requests.isDraining() {
+		return Key{}, ErrSessionDraining
+	}
+	msg, key := s.prepareStartGetProperties(device, EPCs, callback)
+	if err := s.sendRequestMessage(s.ctx, device.IP, msg); err != nil {
+		s.UnregisterCallback(key)
+		return Key{}, err
+	}
+	return key, nil
+}
+
+// StartGetPropertiesOnInterface は、StartGetProperties と同じ要求をマルチキャストアドレス device.IP に
+// インターフェース ifName から送信する。インターフェースごとに検出するときに使う
+func (s *Session) StartGetPropertiesOnInterface(device echonet_lite.IPAndEOJ, ifName string, EPCs []echonet_lite.EPCType, callback GetPropertiesCallbackFunc) (Key, error) {
+	sender, ok := s.conn.(network.InterfaceSender)
+	if !ok {
+		return Key{}, network.ErrInterfaceSendNotSupported
+	}
+	if s.requests.isDraining() {
+		return Key{}, ErrSessionDraining
+	}
 	msg, key := s.prepareStartGetProperties(device, EPCs, callback)
-	if err := s.sendMessage(device.IP, msg); err != nil {
+	if err := s.waitToSendRequest(s.ctx); err != nil {
+		s.UnregisterCallback(key)
+		return Key{}, err
+	}
+	if _, err := sender.SendToInterface(device.IP, ifName, msg.Encode()); err != nil {
+		s.log().Error("パケット送信エラー", "interface", ifName, "err", err)
+		s.UnregisterCallback(key)
 		return Key{}, err
 	}
+	if s.Debug {
+		s.log().Debug("パケットを送信", "to", device.IP, "interface", ifName, "message", msg)
+	}
 	return key, nil
 }
 
@@ -486,6 +718,12 @@ func (s *Session) StartGetProperties(device echonet_lite.IPAndEOJ, EPCs []echone
 func (s *Session) StartGetPropertiesWithRetry(ctx1 context.Context, device echonet_lite.IPAndEOJ, EPCs []echonet_lite.EPCType, callback GetPropertiesCallbackFunc) error {
 	desc := fmt.Sprintf("StartGetPropertiesWithRetry(%v, %v)", device, EPCs)
 
+	// 応答を受け取るか再送を諦めるまでを応答待ちとする
+	done, err := s.requests.begin()
+	if err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithCancel(ctx1)
 
 	msg, key := s.prepareStartGetProperties(device, EPCs, func(device echonet_lite.IPAndEOJ, success bool, properties echonet_lite.Properties, FailedEPCs []echonet_lite.EPCType) (CallbackCompleteStatus, error) {
@@ -494,21 +732,26 @@ func (s *Session) StartGetPropertiesWithRetry(ctx1 context.Context, device echon
 		return CallbackFinished, err
 	})
 
-	err := s.sendMessage(device.IP, msg)
+	err = s.sendRequestMessage(ctx, device.IP, msg)
 	if err != nil {
 		cancel()
 		s.UnregisterCallback(key)
+		done()
 		return err
 	}
 
+	policy := s.RetryPolicyFor(device)
+	s.debugRetryPolicy(device, policy)
+
 	go func() {
+		defer done()
 		defer cancel() // ゴルーチン終了時にキャンセル
 
 		// 再送カウンタ
 		retryCount := 0
 
 		// 初回のタイマーをジッタ付きで作成
-		intervalWithJitter := s.calculateRetryIntervalWithJitter(0)
+		intervalWithJitter := s.retryIntervalWithJitter(policy.RetryInterval, 0)
 		timer := time.NewTimer(intervalWithJitter)
 		defer timer.Stop()
 
@@ -518,7 +761,7 @@ func (s *Session) StartGetPropertiesWithRetry(ctx1 context.Context, device echon
 				s.UnregisterCallback(key)
 
 				if retryCount > 0 {
-					slog.Info("リトライ後に完了", "desc", desc, "retryCount", retryCount)
+					s.log().Info("リトライ後に完了", "desc", desc, "retryCount", retryCount)
 				}
 				return
 
@@ -526,21 +769,20 @@ func (s *Session) StartGetPropertiesWithRetry(ctx1 context.Context, device echon
 				// タイムアウトした場合
 				retryCount++
 
-				if retryCount >= s.MaxRetries {
+				if retryCount >= policy.MaxRetries {
 					// 最大再送回数に達した場合
 					_ = s.notifyDeviceTimeout(device, 0)
 					return
 				}
 
 				// 次の再送間隔をジッタ付きで計算 (retryCountをパラメータとして渡す)
-				nextInterval := s.calculateRetryIntervalWithJitter(retryCount)
+				nextInterval := s.retryIntervalWithJitter(policy.RetryInterval, retryCount)
 
 				// ログ出力（ジッタ付き間隔も表示）
-				slog.Info("リクエストを再送します", "desc", desc, "retry", retryCount, "maxRetries", s.MaxRetries, "nextInterval", nextInterval)
-				// fmt.Printf("%v: リクエストを再送します (試行 %d/%d)\n", desc, retryCount, s.MaxRetries) // DEBUG
+				s.log().Info("リクエストを再送します", "desc", desc, "retry", retryCount, "maxRetries", policy.MaxRetries, "nextInterval", nextInterval)
 
 				// 再送
-				if err := s.sendMessage(device.IP, msg); err != nil {
+				if err := s.sendRequestMessage(ctx, device.IP, msg); err != nil {
 					return
 				}
 
@@ -554,11 +796,12 @@ func (s *Session) StartGetPropertiesWithRetry(ctx1 context.Context, device echon
 
 // notifyDeviceTimeout - デバイスタイムアウトを詳細情報付きで通知
 func (s *Session) notifyDeviceTimeout(device echonet_lite.IPAndEOJ, totalDuration time.Duration) error {
+	policy := s.RetryPolicyFor(device)
 	maxRetriesErr := ErrMaxRetriesReached{
-		MaxRetries:    s.MaxRetries,
+		MaxRetries:    policy.MaxRetries,
 		Device:        device,
 		TotalDuration: totalDuration,
-		RetryInterval: s.RetryInterval,
+		RetryInterval: policy.RetryInterval,
 	}
 	if s.TimeoutCh != nil {
 		select {
@@ -568,13 +811,13 @@ func (s *Session) notifyDeviceTimeout(device echonet_lite.IPAndEOJ, totalDuratio
 			Error:  maxRetriesErr,
 		}:
 			// 送信成功
-			slog.Info("デバイスタイムアウト通知を送信", "device", device.Specifier(), "totalDuration", totalDuration)
+			s.log().Info("デバイスタイムアウト通知を送信", "device", device.Specifier(), "totalDuration", totalDuration)
 		default:
 			// チャンネルがブロックされている場合は無視
-			slog.Warn("タイムアウト通知チャンネルがブロックされています", "device", device.Specifier())
+			s.log().Warn("タイムアウト通知チャンネルがブロックされています", "device", device.Specifier())
 		}
 	} else {
-		slog.Warn("タイムアウト通知チャンネルが設定されていません", "device", device.Specifier())
+		s.log().Warn("タイムアウト通知チャンネルが設定されていません", "device", device.Specifier())
 	}
 	return maxRetriesErr
 }
@@ -589,6 +832,22 @@ func (s *Session) CreateSetPropertyMessage(device echonet_lite.IPAndEOJ, propert
 	}
 }
 
+// CreateSetGetPropertyMessage は、properties の書き込みと EPCs の読み出しを1つのフレームで行う SetGet メッセージを作成する
+func (s *Session) CreateSetGetPropertyMessage(device echonet_lite.IPAndEOJ, properties echonet_lite.Properties, EPCs []echonet_lite.EPCType) *echonet_lite.ECHONETLiteMessage {
+	getProps := make(echonet_lite.Properties, 0, len(EPCs))
+	for _, epc := range EPCs {
+		getProps = append(getProps, echonet_lite.Property{EPC: epc})
+	}
+	return &echonet_lite.ECHONETLiteMessage{
+		TID:              s.newTID(),
+		SEOJ:             s.eoj,
+		DEOJ:             device.EOJ,
+		ESV:              echonet_lite.ESVSetGet,
+		Properties:       properties,
+		SetGetProperties: getProps,
+	}
+}
+
 // コールバックを登録解除する関数
 func (s *Session) UnregisterCallback(key Key) {
 	s.mu.Lock()
@@ -625,6 +884,7 @@ type deviceResponse struct {
 	responseCh chan *echonet_lite.ECHONETLiteMessage
 	responded  bool
 	response   *echonet_lite.ECHONETLiteMessage
+	err        error // 応答が要求と一致しない場合のエラー
 }
 
 // BroadcastResult はブロードキャストの結果を表す
@@ -642,25 +902,39 @@ func (s *Session) waitForResponseWithRetry(
 	msg *echonet_lite.ECHONETLiteMessage,
 	responseCh <-chan *echonet_lite.ECHONETLiteMessage,
 ) (*echonet_lite.ECHONETLiteMessage, error) {
+	policy := s.RetryPolicyFor(device)
+	s.debugRetryPolicy(device, policy)
+
+	// 応答待ちの一覧に登録し、CancelPendingRequest で取り消せるようにする
+	id, ctx, cancel := s.pending.add(ctx, device, msg, policy.MaxRetries)
+	defer func() {
+		cancel(nil)
+		s.pending.remove(id)
+	}()
+
 	// 再送カウンタと時間追跡
 	retryCount := 0
 	startTime := time.Now()
 	lastRetryTime := startTime // 最後のリトライ試行時刻（INFによるリセット判定用）
 
 	// 初回のタイマーをジッタ付きで作成
-	intervalWithJitter := s.calculateRetryIntervalWithJitter(0)
+	intervalWithJitter := s.retryIntervalWithJitter(policy.RetryInterval, 0)
 	timer := time.NewTimer(intervalWithJitter)
 	defer timer.Stop()
+	s.pending.update(id, PendingRequestWaiting, 0, startTime.Add(intervalWithJitter))
 
 	for {
 		select {
 		case <-ctx.Done():
-			// 親コンテキストがキャンセルされた場合
-			return nil, ctx.Err()
+			// 親コンテキストがキャンセルされた場合、または要求が取り消された場合（ErrRequestCancelled）
+			if errors.Is(context.Cause(ctx), ErrRequestCancelled) {
+				s.log().Info("要求が取り消されました", "device", device, "retryCount", retryCount)
+			}
+			return nil, context.Cause(ctx)
 
 		case respMsg := <-responseCh:
 			if retryCount > 0 {
-				slog.Info("リトライ後に完了", "device", device, "retryCount", retryCount)
+				s.log().Info("リトライ後に完了", "device", device, "retryCount", retryCount)
 			}
 			// 応答を受信した場合
 			return respMsg, nil
@@ -673,32 +947,34 @@ func (s *Session) waitForResponseWithRetry(
 			// 最後のリトライ以降にデバイスからINFを受信していればリトライカウントをリセット
 			lastAlive := s.getLastAliveTime(device)
 			if !lastAlive.IsZero() && lastAlive.After(lastRetryTime) {
-				slog.Info("INF受信によりリトライカウントをリセット", "device", device, "retryCount", retryCount, "lastAlive", lastAlive)
+				s.log().Info("INF受信によりリトライカウントをリセット", "device", device, "retryCount", retryCount, "lastAlive", lastAlive)
 				retryCount = 0
 			} else {
 				retryCount++
 			}
 			lastRetryTime = now
 
-			if retryCount >= s.MaxRetries {
+			if retryCount >= policy.MaxRetries {
 				// 最大再送回数に達した場合
 				totalDuration := time.Since(startTime)
 				return nil, s.notifyDeviceTimeout(device, totalDuration)
 			}
 
 			// 次の再送間隔をジッタ付きで計算 (retryCountをパラメータとして渡す)
-			nextInterval := s.calculateRetryIntervalWithJitter(retryCount)
+			nextInterval := s.retryIntervalWithJitter(policy.RetryInterval, retryCount)
 
 			// ログ出力（ジッタ付き間隔も表示）
-			slog.Info("リクエストを再送します", "device", device, "retry", retryCount+1, "maxRetries", s.MaxRetries, "nextInterval", nextInterval)
+			s.log().Info("リクエストを再送します", "device", device, "retry", retryCount+1, "maxRetries", policy.MaxRetries, "nextInterval", nextInterval)
 
 			// 再送
-			if err := s.sendMessage(device.IP, msg); err != nil {
-				return nil, fmt.Errorf("failed to resend message to device %v (retry %d/%d): %w", device, retryCount+1, s.MaxRetries, err)
+			s.pending.update(id, PendingRequestSending, retryCount, time.Time{})
+			if err := s.sendRequestMessage(ctx, device.IP, msg); err != nil {
+				return nil, fmt.Errorf("failed to resend message to device %v (retry %d/%d): %w", device, retryCount+1, policy.MaxRetries, err)
 			}
 
 			// タイマーをジッタ付き間隔でリセット
 			timer.Reset(nextInterval)
+			s.pending.update(id, PendingRequestRetrying, retryCount, now.Add(nextInterval))
 		}
 	}
 }
@@ -712,6 +988,8 @@ func (s *Session) collectBroadcastResults(deviceResponses []*deviceResponse) []B
 		}
 		if dr.responded {
 			result.Response = dr.response
+		} else if dr.err != nil {
+			result.Error = dr.err
 		} else {
 			result.Error = fmt.Errorf("no response received")
 		}
@@ -726,6 +1004,13 @@ func (s *Session) sendRequestWithContext(
 	device echonet_lite.IPAndEOJ,
 	msg *echonet_lite.ECHONETLiteMessage,
 ) (*echonet_lite.ECHONETLiteMessage, error) {
+	// 応答を受け取るか諦めるまでを応答待ちとする
+	done, err := s.requests.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	// 結果を受け取るためのチャネル
 	responseCh := make(chan *echonet_lite.ECHONETLiteMessage, 1)
 
@@ -742,13 +1027,47 @@ func (s *Session) sendRequestWithContext(
 		s.UnregisterCallback(key)
 	}()
 
+	// 応答待ちの要求の枠を確保する（応答を受け取るか諦めるまで保持する）
+	release, err := s.acquireInFlight(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	// 最初のリクエスト送信
-	if err := s.sendMessage(device.IP, msg); err != nil {
+	if err := s.sendRequestMessage(ctx, device.IP, msg); err != nil {
 		return nil, fmt.Errorf("failed to send initial message to device %v: %w", device, err)
 	}
 
 	// 応答待ちと再送処理
-	return s.waitForResponseWithRetry(ctx, device, msg, responseCh)
+	respMsg, err := s.waitForResponseWithRetry(ctx, device, msg, responseCh)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkResponse(device, msg, respMsg); err != nil {
+		return nil, err
+	}
+	return respMsg, nil
+}
+
+// parseMessage は、受信したデータをパースする。Strict の場合は仕様に従わない電文をエラーにする
+func (s *Session) parseMessage(data []byte) (*echonet_lite.ECHONETLiteMessage, error) {
+	if s.Strict {
+		return echonet_lite.ParseECHONETLiteMessageStrict(data)
+	}
+	return echonet_lite.ParseECHONETLiteMessage(data)
+}
+
+// checkResponse は、Strict の場合に、応答が要求と同じプロパティを同じ順に含むことを確認する
+func (s *Session) checkResponse(device echonet_lite.IPAndEOJ, request, response *echonet_lite.ECHONETLiteMessage) error {
+	if !s.Strict {
+		return nil
+	}
+	if err := echonet_lite.ValidateResponse(request, response); err != nil {
+		s.log().Warn("要求と一致しない応答", "device", device, "err", err)
+		return fmt.Errorf("%v: %w", device, err)
+	}
+	return nil
 }
 
 // sendRequestWithContextBroadcast は同一クラスコードの複数デバイスへブロードキャストリクエストを送信する
@@ -769,6 +1088,13 @@ func (s *Session) sendRequestWithContextBroadcast(
 		}
 	}
 
+	// 全デバイスの応答を受け取るか諦めるまでを応答待ちとする
+	done, err := s.requests.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	// ブロードキャスト用メッセージを作成（instanceCode = 0）
 	broadcastMsg := &echonet_lite.ECHONETLiteMessage{
 		TID:              msg.TID,
@@ -807,8 +1133,19 @@ func (s *Session) sendRequestWithContextBroadcast(
 		return CallbackContinue, nil
 	})
 
+	// 応答待ちの要求の枠を確保する（全デバイスの応答待ちで1つ）
+	release, err := s.acquireInFlight(ctx)
+	if err != nil {
+		s.UnregisterCallback(key)
+		for _, dr := range deviceResponses {
+			close(dr.responseCh)
+		}
+		return nil, err
+	}
+	defer release()
+
 	// ブロードキャストメッセージを送信
-	if err := s.sendMessage(devices[0].IP, broadcastMsg); err != nil {
+	if err := s.sendRequestMessage(ctx, devices[0].IP, broadcastMsg); err != nil {
 		// エラー時はコールバック登録解除とチャネルクリーンアップ
 		s.UnregisterCallback(key)
 		for _, dr := range deviceResponses {
@@ -837,6 +1174,10 @@ func (s *Session) sendRequestWithContextBroadcast(
 			// 各デバイス用の応答待ち（最初の送信は既に完了）
 			respMsg, err := s.waitForResponseWithRetry(ctx, deviceResp.device, deviceMsg, deviceResp.responseCh)
 			if err == nil {
+				if err := s.checkResponse(deviceResp.device, deviceMsg, respMsg); err != nil {
+					deviceResp.err = err
+					return
+				}
 				deviceResp.responded = true
 				deviceResp.response = respMsg
 			}
@@ -866,14 +1207,22 @@ func (s *Session) GetPropertiesBroadcast(
 		return nil, fmt.Errorf("devices list is empty")
 	}
 
-	// メッセージを作成（最初のデバイスをベースにする）
-	msg := s.CreateGetPropertyMessage(devices[0], EPCs)
+	// フレームごとにブロードキャスト送信し、結果をデバイスごとにまとめる
+	frames := s.frameLimits.splitEPCs(EPCs)
+	s.logSplitFrames(devices[0], len(frames), len(EPCs))
+	frameResults := make([][]BroadcastResult, 0, len(frames))
+	for _, frameEPCs := range frames {
+		// メッセージを作成（最初のデバイスをベースにする）
+		msg := s.CreateGetPropertyMessage(devices[0], frameEPCs)
 
-	// ブロードキャスト送信
-	results, err := s.sendRequestWithContextBroadcast(ctx, devices, msg)
-	if err != nil {
-		return nil, err
+		// ブロードキャスト送信
+		results, err := s.sendRequestWithContextBroadcast(ctx, devices, msg)
+		if err != nil {
+			return nil, err
+		}
+		frameResults = append(frameResults, results)
 	}
+	results := mergeBroadcastResults(frameResults)
 
 	// 各デバイスの結果を処理
 	for i := range results {
@@ -969,11 +1318,50 @@ func (s *Session) updateFailedEPCs(device echonet_lite.IPAndEOJ, success echonet
 	return newlyFailedForReturn // 今回新たに失敗として記録されたEPCのみを返す
 }
 
+// logSplitFrames は、要求を複数のフレームに分けて送信することをログに出力する
+func (s *Session) logSplitFrames(device echonet_lite.IPAndEOJ, frames, properties int) {
+	if frames > 1 {
+		s.log().Debug("要求を複数のフレームに分けて送信", "device", device.Specifier(), "frames", frames, "properties", properties)
+	}
+}
+
 // GetProperties - プロパティ取得
+// EPCs が FrameLimits を超える場合は、複数のフレームに分けて取得し、結果をまとめて返す
 func (s *Session) GetProperties(
 	ctx context.Context,
 	device echonet_lite.IPAndEOJ,
 	EPCs []echonet_lite.EPCType,
+) (bool, echonet_lite.Properties, []echonet_lite.EPCType, error) {
+	frames := s.frameLimits.splitEPCs(EPCs)
+	if len(frames) == 1 {
+		return s.getPropertiesFrame(ctx, device, EPCs)
+	}
+	s.logSplitFrames(device, len(frames), len(EPCs))
+
+	success := true
+	var properties echonet_lite.Properties
+	var failedEPCs []echonet_lite.EPCType
+	for i, frameEPCs := range frames {
+		frameSuccess, frameProperties, frameFailedEPCs, err := s.getPropertiesFrame(ctx, device, frameEPCs)
+		if err != nil {
+			// 残りのフレームのEPCも失敗とする
+			for _, rest := range frames[i:] {
+				failedEPCs = append(failedEPCs, rest...)
+			}
+			return false, properties, failedEPCs, err
+		}
+		success = success && frameSuccess
+		properties = append(properties, frameProperties...)
+		failedEPCs = append(failedEPCs, frameFailedEPCs...)
+	}
+	return success, properties, failedEPCs, nil
+}
+
+// getPropertiesFrame は、1つの Get フレームでプロパティを取得する
+func (s *Session) getPropertiesFrame(
+	ctx context.Context,
+	device echonet_lite.IPAndEOJ,
+	EPCs []echonet_lite.EPCType,
 ) (bool, echonet_lite.Properties, []echonet_lite.EPCType, error) {
 	// メッセージを作成
 	msg := s.CreateGetPropertyMessage(device, EPCs)
@@ -994,10 +1382,45 @@ func (s *Session) GetProperties(
 }
 
 // SetProperties - プロパティ設定
+// properties が FrameLimits を超える場合は、順序を保ったまま複数のフレームに分けて設定し、結果をまとめて返す
+// 途中のフレームでエラーになった場合は、それまでに設定できたプロパティも返す
 func (s *Session) SetProperties(
 	ctx context.Context,
 	device echonet_lite.IPAndEOJ,
 	properties echonet_lite.Properties,
+) (bool, echonet_lite.Properties, []echonet_lite.EPCType, error) {
+	frames := s.frameLimits.splitProperties(properties)
+	if len(frames) == 1 {
+		return s.setPropertiesFrame(ctx, device, properties)
+	}
+	s.logSplitFrames(device, len(frames), len(properties))
+
+	success := true
+	var successProperties echonet_lite.Properties
+	var failedEPCs []echonet_lite.EPCType
+	for i, frame := range frames {
+		frameSuccess, frameProperties, frameFailedEPCs, err := s.setPropertiesFrame(ctx, device, frame)
+		if err != nil {
+			// 残りのフレームのEPCも失敗とする
+			for _, rest := range frames[i:] {
+				for _, p := range rest {
+					failedEPCs = append(failedEPCs, p.EPC)
+				}
+			}
+			return false, successProperties, failedEPCs, err
+		}
+		success = success && frameSuccess
+		successProperties = append(successProperties, frameProperties...)
+		failedEPCs = append(failedEPCs, frameFailedEPCs...)
+	}
+	return success, successProperties, failedEPCs, nil
+}
+
+// setPropertiesFrame は、1つの SetC フレームでプロパティを設定する
+func (s *Session) setPropertiesFrame(
+	ctx context.Context,
+	device echonet_lite.IPAndEOJ,
+	properties echonet_lite.Properties,
 ) (bool, echonet_lite.Properties, []echonet_lite.EPCType, error) {
 	// メッセージを作成
 	msg := s.CreateSetPropertyMessage(device, properties)
@@ -1033,3 +1456,56 @@ func (s *Session) SetProperties(
 
 	return success, successProperties, failedEPCs, nil
 }
+
+// SetGetProperties - プロパティの書き込みと読み出しを1つの SetGet フレームで行う
+// 戻り値は、全体の成否、書き込みに成功したプロパティ、読み出したプロパティ、失敗したEPC
+func (s *Session) SetGetProperties(
+	ctx context.Context,
+	device echonet_lite.IPAndEOJ,
+	properties echonet_lite.Properties,
+	EPCs []echonet_lite.EPCType,
+) (bool, echonet_lite.Properties, echonet_lite.Properties, []echonet_lite.EPCType, error) {
+	// メッセージを作成
+	msg := s.CreateSetGetPropertyMessage(device, properties, EPCs)
+
+	// 共通処理を呼び出し
+	respMsg, err := s.sendRequestWithContext(ctx, device, msg)
+
+	// エラーチェック
+	if err != nil {
+		// タイムアウトやコンテキストキャンセルの場合
+		failedEPCs := make([]echonet_lite.EPCType, 0, len(properties)+len(EPCs))
+		for _, p := range properties {
+			failedEPCs = append(failedEPCs, p.EPC)
+		}
+		failedEPCs = append(failedEPCs, EPCs...)
+		return false, nil, nil, failedEPCs, err
+	}
+
+	// 応答を処理
+	success := respMsg.ESV == echonet_lite.ESVSetGet_Res
+
+	setProperties := make(echonet_lite.Properties, 0, len(properties))
+	failedEPCs := make([]echonet_lite.EPCType, 0)
+
+	// 書き込みは EDT == nil が成功
+	for i, p := range respMsg.Properties {
+		if p.EDT == nil && i < len(properties) {
+			setProperties = append(setProperties, properties[i])
+		} else {
+			failedEPCs = append(failedEPCs, p.EPC)
+		}
+	}
+
+	// 読み出しは EDT == nil が失敗
+	getProperties := make(echonet_lite.Properties, 0, len(respMsg.SetGetProperties))
+	for _, p := range respMsg.SetGetProperties {
+		if p.EDT != nil {
+			getProperties = append(getProperties, p)
+		} else {
+			failedEPCs = append(failedEPCs, p.EPC)
+		}
+	}
+
+	return success, setProperties, getProperties, failedEPCs, nil
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"context"
 	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/network"
 	"net"
 	"testing"
 	"time"
@@ -27,7 +28,7 @@ func TestSession_OfflineLogSuppression(t *testing.T) {
 	}
 
 	// Session作成
-	session, err := CreateSession(ctx, ip, eoj, false, nil, mockIsOffline)
+	session, err := CreateSession(ctx, ip, 0, network.IPv4, eoj, false, nil, mockIsOffline)
 	if err != nil {
 		t.Fatalf("CreateSession failed: %v", err)
 	}
@@ -83,7 +84,7 @@ func TestSession_OfflineLogSuppressionWithNilFunc(t *testing.T) {
 	}
 
 	// IsOfflineFunc=nilでSession作成
-	session, err := CreateSession(ctx, ip, eoj, false, nil, nil)
+	session, err := CreateSession(ctx, ip, 0, network.IPv4, eoj, false, nil, nil)
 	if err != nil {
 		t.Fatalf("CreateSession failed: %v", err)
 	}
@@ -120,7 +121,7 @@ func TestSession_OnlineDeviceLogging(t *testing.T) {
 	}
 
 	// Session作成
-	session, err := CreateSession(ctx, ip, eoj, false, nil, mockIsOffline)
+	session, err := CreateSession(ctx, ip, 0, network.IPv4, eoj, false, nil, mockIsOffline)
 	if err != nil {
 		t.Fatalf("CreateSession failed: %v", err)
 	}
@@ -175,7 +176,7 @@ func TestSession_IsOfflineFuncAccess(t *testing.T) {
 	}
 
 	// Session作成
-	session, err := CreateSession(ctx, ip, eoj, false, nil, mockIsOffline)
+	session, err := CreateSession(ctx, ip, 0, network.IPv4, eoj, false, nil, mockIsOffline)
 	if err != nil {
 		t.Fatalf("CreateSession failed: %v", err)
 	}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"sort"
+	"time"
+)
+
+// StaleDevice は、長い間更新されていないデバイス
+type StaleDevice struct {
+	Device   IPAndEOJ
+	LastSeen time.Time // 最後にプロパティが更新された時刻
+}
+
+// FindStaleDevices は、最後の更新から unseenFor 以上経ったデバイスを古い順に返す
+// 更新時刻の記録がないデバイス（時刻を保存していない古い devices.json から読み込み、起動後に一度も応答がないものなど）は、
+// 起動時に見えていたとみなす
+func (h *ECHONETLiteHandler) FindStaleDevices(unseenFor time.Duration) []StaleDevice {
+	now := time.Now()
+	var result []StaleDevice
+	for _, device := range h.data.devices.ListIPAndEOJ() {
+		lastSeen := h.data.GetLastUpdateTime(device)
+		if lastSeen.IsZero() {
+			lastSeen = h.startTime
+		}
+		if now.Sub(lastSeen) < unseenFor {
+			continue
+		}
+		result = append(result, StaleDevice{Device: device, LastSeen: lastSeen})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].LastSeen.Equal(result[j].LastSeen) {
+			return result[i].LastSeen.Before(result[j].LastSeen)
+		}
+		return result[i].Device.Compare(result[j].Device) < 0
+	})
+	return result
+}
+
+// CleanupStaleDevices は、最後の更新から unseenFor 以上経ったデバイスを削除し、削除したデバイスを返す
+// dryRun の場合は削除せず、対象のデバイスだけを返す
+// エイリアスやグループの設定は残すため、同じデバイスが再び見つかれば元の名前で使える
+func (h *ECHONETLiteHandler) CleanupStaleDevices(unseenFor time.Duration, dryRun bool) ([]StaleDevice, error) {
+	stale := h.FindStaleDevices(unseenFor)
+	if dryRun || len(stale) == 0 {
+		return stale, nil
+	}
+
+	removed := make([]StaleDevice, 0, len(stale))
+	for _, s := range stale {
+		if err := h.data.RemoveDevice(s.Device); err != nil {
+			h.log().Warn("古いデバイスの削除に失敗", "device", s.Device.Specifier(), "error", err)
+			continue
+		}
+		if h.data.DeviceHistory != nil {
+			h.data.DeviceHistory.Clear(s.Device)
+		}
+		removed = append(removed, s)
+	}
+	h.log().Info("古いデバイスを削除しました", "count", len(removed), "unseenFor", unseenFor)
+	_ = h.data.SaveDeviceInfo()
+	return removed, nil
+}
+
+// stalePruneCheckInterval は、古いデバイスを自動的に削除する処理の実行間隔
+const stalePruneCheckInterval = time.Hour
+
+// startStaleDevicePruner は、最後の更新から unseenFor 以上経ったデバイスを定期的に削除する
+func (h *ECHONETLiteHandler) startStaleDevicePruner(unseenFor time.Duration) {
+	h.log().Info("古いデバイスの自動削除を開始", "unseenFor", unseenFor, "interval", stalePruneCheckInterval)
+
+	go func() {
+		ticker := time.NewTicker(stalePruneCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := h.CleanupStaleDevices(unseenFor, false); err != nil {
+					h.log().Warn("古いデバイスの自動削除に失敗", "error", err)
+				}
+			case <-h.core.ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+)
+
+func TestECHONETLiteHandler_CleanupStaleDevices(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 削除後の保存でカレントディレクトリの devices.json を書き換えないよう、メモリ上のみで動かす
+	handler, err := NewECHONETLiteHandler(ctx, ECHONETLieHandlerOptions{TestMode: true, InMemory: true})
+	if err != nil {
+		t.Fatalf("ECHONETLiteHandlerの作成に失敗: %v", err)
+	}
+	defer handler.Close()
+
+	data := handler.GetDataManagementHandler()
+	recent := testDevice(1)
+	old := testDevice(2)
+	older := testDevice(3)
+	now := time.Now()
+	for _, d := range []struct {
+		device   IPAndEOJ
+		lastSeen time.Time
+	}{
+		{recent, now.Add(-time.Hour)},
+		{old, now.Add(-40 * 24 * time.Hour)},
+		{older, now.Add(-60 * 24 * time.Hour)},
+	} {
+		data.devices.RegisterProperties(d.device, Properties{{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}}}, d.lastSeen)
+	}
+
+	// dry run では削除せず、古い順に対象を返す
+	stale, err := handler.CleanupStaleDevices(30*24*time.Hour, true)
+	if err != nil {
+		t.Fatalf("CleanupStaleDevices failed: %v", err)
+	}
+	if len(stale) != 2 || stale[0].Device.Key() != older.Key() || stale[1].Device.Key() != old.Key() {
+		t.Fatalf("対象のデバイスが不正: %+v", stale)
+	}
+	if !data.IsKnownDevice(old) || !data.IsKnownDevice(older) {
+		t.Fatal("dry run でデバイスが削除された")
+	}
+
+	removed, err := handler.CleanupStaleDevices(30*24*time.Hour, false)
+	if err != nil {
+		t.Fatalf("CleanupStaleDevices failed: %v", err)
+	}
+	if len(removed) != 2 || data.IsKnownDevice(old) || data.IsKnownDevice(older) || !data.IsKnownDevice(recent) {
+		t.Errorf("削除結果が不正: removed=%+v", removed)
+	}
+	if stale := handler.FindStaleDevices(30 * 24 * time.Hour); len(stale) != 0 {
+		t.Errorf("削除後も対象が残っている: %+v", stale)
+	}
+
+	// 更新時刻の記録がないデバイスは、起動時に見えていたとみなす
+	unknown := testDevice(4)
+	data.devices.RegisterProperties(unknown, Properties{{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}}}, now)
+	data.devices.mu.Lock()
+	delete(data.devices.timestamps, unknown.Key())
+	data.devices.mu.Unlock()
+	if stale := handler.FindStaleDevices(30 * 24 * time.Hour); len(stale) != 0 {
+		t.Errorf("起動直後に記録のないデバイスが対象になった: %+v", stale)
+	}
+	handler.startTime = now.Add(-31 * 24 * time.Hour)
+	if stale := handler.FindStaleDevices(30 * 24 * time.Hour); len(stale) != 1 || stale[0].Device.Key() != unknown.Key() || !stale[0].LastSeen.Equal(handler.startTime) {
+		t.Errorf("記録のないデバイスが起動時刻で判定されない: %+v", stale)
+	}
+}
+
+// TestDevices_TimestampsSurviveReload 最終更新時刻が devices.json に保存され、再起動後の整理に使えることのテスト
+func TestDevices_TimestampsSurviveReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "devices.json")
+	device := testDevice(1)
+	lastSeen := time.Now().Add(-48 * time.Hour).Round(time.Second)
+
+	devices := NewDevices()
+	devices.RegisterProperties(device, Properties{{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}}}, lastSeen)
+	if err := devices.SaveToFile(file); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+
+	loaded := NewDevices()
+	if err := loaded.LoadFromFile(file); err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	if got := loaded.GetLastUpdateTime(device); !got.Equal(lastSeen) {
+		t.Errorf("最終更新時刻が復元されない: got %v, want %v", got, lastSeen)
+	}
+}
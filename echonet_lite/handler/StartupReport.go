@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"log/slog"
+	"time"
+)
+
+// fileLoadStats は、保存ファイルを読み込んだ結果
+type fileLoadStats struct {
+	Exists   bool // ファイルがあったか
+	Records  int  // 読み込んだ件数
+	Skipped  int  // 壊れていたため読み飛ばした件数
+	Version  int  // ファイルのフォーマットバージョン（バージョンのない古いフォーマットは 0）
+	Migrated bool // 現在のフォーマットではないファイルから読み込んだ（次の保存で現在のフォーマットになる）
+}
+
+// LoadedFile は、起動時に読み込んだ保存ファイル1つの結果
+type LoadedFile struct {
+	Kind          string // "devices", "aliases", "groups" または "history"
+	File          string // ファイルのパス
+	Exists        bool   // ファイルがあったか（ない場合は空の状態から始めた）
+	Records       int    // 読み込んだ件数（デバイス、エイリアス、グループ、履歴の件数）
+	Skipped       int    // 壊れていたため読み飛ばした件数
+	FormatVersion int    // ファイルのフォーマットバージョン（バージョンを持たないファイルは 0）
+	Migrated      bool   // 古いフォーマットから読み込んだ（次の保存で現在のフォーマットになる）
+	Restored      bool   // 破損していたため、バックアップから復元した（または空の状態から始めた）
+	Error         string // 読み込みに失敗し、空の状態から始めた場合のエラー
+}
+
+// Clean は、ファイルが問題なく読み込めたかどうかを返す
+func (f LoadedFile) Clean() bool {
+	return f.Skipped == 0 && !f.Restored && f.Error == ""
+}
+
+// StartupReport は、起動時に保存ファイルから復元した状態の要約
+// 運用者が、状態が正しく復元されたかどうかを一目で確認できるようにする
+type StartupReport struct {
+	LoadedAt time.Time    // 読み込みを終えた時刻
+	Files    []LoadedFile // 読み込んだファイル（devices, aliases, groups, history の順）
+}
+
+// Clean は、すべてのファイルが問題なく読み込めたかどうかを返す
+func (r StartupReport) Clean() bool {
+	for _, f := range r.Files {
+		if !f.Clean() {
+			return false
+		}
+	}
+	return true
+}
+
+// add は、ファイルを読み込んだ結果を追加する
+func (r *StartupReport) add(kind, file string, stats fileLoadStats, restored bool, err error) {
+	f := LoadedFile{
+		Kind:          kind,
+		File:          file,
+		Exists:        stats.Exists,
+		Records:       stats.Records,
+		Skipped:       stats.Skipped,
+		FormatVersion: stats.Version,
+		Migrated:      stats.Migrated,
+		Restored:      restored,
+	}
+	if err != nil {
+		f.Error = err.Error()
+	}
+	r.Files = append(r.Files, f)
+}
+
+// log は、読み込んだ結果をファイル毎に1行ずつ記録する。問題があったファイルは警告として記録する
+func (r StartupReport) log(logger *slog.Logger) {
+	for _, f := range r.Files {
+		args := []any{"kind", f.Kind, "file", f.File, "exists", f.Exists, "records", f.Records}
+		if f.FormatVersion != 0 {
+			args = append(args, "version", f.FormatVersion)
+		}
+		if f.Migrated {
+			args = append(args, "migrated", true)
+		}
+		if f.Clean() {
+			logger.Info("保存ファイルの復元結果", args...)
+			continue
+		}
+		args = append(args, "skipped", f.Skipped, "restored", f.Restored)
+		if f.Error != "" {
+			args = append(args, "error", f.Error)
+		}
+		logger.Warn("保存ファイルの復元結果（問題あり）", args...)
+	}
+}
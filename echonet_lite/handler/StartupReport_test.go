@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDevices_loadFromFile_SkipsCorruptNodes(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "devices.json")
+
+	devices := NewDevices()
+	now := time.Now()
+	devices.RegisterProperty(IPAndEOJ{IP: net.ParseIP("192.168.1.1"), EOJ: EOJ(0x013001)}, Property{EPC: 0x80, EDT: []byte{0x30}}, now)
+	devices.RegisterProperty(IPAndEOJ{IP: net.ParseIP("192.168.1.1"), EOJ: EOJ(0x029001)}, Property{EPC: 0x80, EDT: []byte{0x31}}, now)
+	if err := devices.SaveToFile(filename); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+
+	// 1つのノードの内容を壊す
+	var file map[string]json.RawMessage
+	data, _ := os.ReadFile(filename)
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	var nodes map[string]json.RawMessage
+	if err := json.Unmarshal(file["data"], &nodes); err != nil {
+		t.Fatal(err)
+	}
+	nodes["192.168.1.2"] = json.RawMessage(`"broken"`)
+	file["data"], _ = json.Marshal(nodes)
+	data, _ = json.Marshal(file)
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewDevices()
+	stats, err := loaded.loadFromFile(filename)
+	if err != nil {
+		t.Fatalf("壊れたノードがあると読み込みに失敗した: %v", err)
+	}
+	want := fileLoadStats{Exists: true, Records: 2, Skipped: 1, Version: currentDevicesFileVersion}
+	if stats != want {
+		t.Errorf("stats = %+v, 期待値 %+v", stats, want)
+	}
+	if loaded.CountAll() != 2 {
+		t.Errorf("読み込んだデバイスの数 %d", loaded.CountAll())
+	}
+}
+
+func TestDevices_loadFromFile_OldFormat(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "devices.json")
+	old := `{"192.168.1.200": {"0130:1": {"128": {"EPC": 128, "EDT": "MQ=="}}}}`
+	if err := os.WriteFile(filename, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := NewDevices().loadFromFile(filename)
+	if err != nil {
+		t.Fatalf("loadFromFile failed: %v", err)
+	}
+	want := fileLoadStats{Exists: true, Records: 1, Migrated: true}
+	if stats != want {
+		t.Errorf("stats = %+v, 期待値 %+v", stats, want)
+	}
+
+	// ファイルがない場合
+	stats, err = NewDevices().loadFromFile(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || stats != (fileLoadStats{}) {
+		t.Errorf("ファイルがない場合の stats = %+v, %v", stats, err)
+	}
+}
+
+func TestJournalDeviceHistoryStore_LoadedStats(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history.jsonl")
+	store := newTestJournal(t, filename, 0)
+	recordJournalEntries(store, testDevice(1), time.Now().Add(-time.Hour), 3)
+	_ = store.Close()
+
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("not json\n")
+	_ = f.Close()
+
+	reopened := newTestJournal(t, filename, 0)
+	want := fileLoadStats{Exists: true, Records: 3, Skipped: 1}
+	if reopened.loaded != want {
+		t.Errorf("loaded = %+v, 期待値 %+v", reopened.loaded, want)
+	}
+}
+
+func TestStartupReport_Log(t *testing.T) {
+	var report StartupReport
+	report.add("devices", "devices.json", fileLoadStats{Exists: true, Records: 5, Version: 1}, false, nil)
+	report.add("aliases", "aliases.json", fileLoadStats{}, false, nil)
+	if !report.Clean() {
+		t.Error("問題のない読み込みが Clean でない")
+	}
+	report.add("groups", "groups.json", fileLoadStats{Exists: true, Records: 1}, true, nil)
+	report.add("history", "history.json", fileLoadStats{Exists: true, Skipped: 2}, false, nil)
+	if report.Clean() {
+		t.Error("復元したファイルがあるのに Clean")
+	}
+
+	var logs strings.Builder
+	report.log(slog.New(slog.NewTextHandler(&logs, nil)))
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("ファイル毎に1行ではない: %q", logs.String())
+	}
+	if !strings.Contains(lines[0], "level=INFO") || !strings.Contains(lines[0], "records=5") {
+		t.Errorf("devices の行: %s", lines[0])
+	}
+	if !strings.Contains(lines[2], "level=WARN") || !strings.Contains(lines[2], "restored=true") {
+		t.Errorf("groups の行: %s", lines[2])
+	}
+	if !strings.Contains(lines[3], "level=WARN") || !strings.Contains(lines[3], "skipped=2") {
+		t.Errorf("history の行: %s", lines[3])
+	}
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Coordinates は、日の出・日の入りを計算する地点の緯度・経度（度）
+type Coordinates struct {
+	Latitude  float64 // 北緯が正
+	Longitude float64 // 東経が正
+}
+
+// Validate は、緯度・経度が範囲内かどうかを検証する
+func (c Coordinates) Validate() error {
+	if c.Latitude < -90 || c.Latitude > 90 || c.Longitude < -180 || c.Longitude > 180 {
+		return fmt.Errorf("緯度・経度が範囲外です: 緯度 %v, 経度 %v", c.Latitude, c.Longitude)
+	}
+	return nil
+}
+
+// SolarEvent は、日の出または日の入り
+type SolarEvent string
+
+const (
+	Sunrise SolarEvent = "sunrise"
+	Sunset  SolarEvent = "sunset"
+)
+
+// sunZenith は、日の出・日の入りとみなす太陽の天頂角（大気差と太陽の視半径を含む）
+const sunZenith = 90.833
+
+// SunTimes は、day の日付（day のタイムゾーン）の日の出・日の入り時刻を返す
+// 白夜・極夜で日の出・日の入りがない日は ok が false になる
+func (c Coordinates) SunTimes(day time.Time) (sunrise, sunset time.Time, ok bool) {
+	sunrise, ok1 := c.SunEvent(day, Sunrise)
+	sunset, ok2 := c.SunEvent(day, Sunset)
+	return sunrise, sunset, ok1 && ok2
+}
+
+// SunEvent は、day の日付（day のタイムゾーン）の日の出または日の入りの時刻を返す
+// 計算は Almanac for Computers (1990) の方法によるもので、誤差は数分程度
+func (c Coordinates) SunEvent(day time.Time, event SolarEvent) (time.Time, bool) {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	deg := func(rad float64) float64 { return rad * 180 / math.Pi }
+	normalize := func(v, max float64) float64 {
+		v = math.Mod(v, max)
+		if v < 0 {
+			v += max
+		}
+		return v
+	}
+
+	rising := event == Sunrise
+	lngHour := c.Longitude / 15
+	t := float64(day.YearDay())
+	if rising {
+		t += (6 - lngHour) / 24
+	} else {
+		t += (18 - lngHour) / 24
+	}
+
+	// 太陽の平均近点角と黄経
+	m := 0.9856*t - 3.289
+	l := normalize(m+1.916*math.Sin(rad(m))+0.020*math.Sin(rad(2*m))+282.634, 360)
+
+	// 赤経（黄経と同じ象限にそろえる）
+	ra := normalize(deg(math.Atan(0.91764*math.Tan(rad(l)))), 360)
+	ra += math.Floor(l/90)*90 - math.Floor(ra/90)*90
+	ra /= 15
+
+	// 赤緯
+	sinDec := 0.39782 * math.Sin(rad(l))
+	cosDec := math.Cos(math.Asin(sinDec))
+
+	// 時角
+	cosH := (math.Cos(rad(sunZenith)) - sinDec*math.Sin(rad(c.Latitude))) / (cosDec * math.Cos(rad(c.Latitude)))
+	if cosH > 1 || cosH < -1 {
+		return time.Time{}, false
+	}
+	h := deg(math.Acos(cosH))
+	if rising {
+		h = 360 - h
+	}
+	h /= 15
+
+	ut := normalize(h+ra-0.06571*t-6.622-lngHour, 24)
+	year, month, date := day.Date()
+	result := time.Date(year, month, date, 0, 0, 0, 0, time.UTC).Add(time.Duration(ut * float64(time.Hour))).In(day.Location())
+
+	// 世界時の日付が現地の日付とずれる場合（日本の日の出など）は1日ずらす
+	switch ry, rm, rd := result.Date(); {
+	case time.Date(ry, rm, rd, 0, 0, 0, 0, time.UTC).After(time.Date(year, month, date, 0, 0, 0, 0, time.UTC)):
+		result = result.Add(-24 * time.Hour)
+	case time.Date(ry, rm, rd, 0, 0, 0, 0, time.UTC).Before(time.Date(year, month, date, 0, 0, 0, 0, time.UTC)):
+		result = result.Add(24 * time.Hour)
+	}
+	return result, true
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"crypto/rand"
 	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/network"
 	"fmt"
 	"log/slog"
 	"math/big"
 	mathrand "math/rand"
 	"net"
+	"slices"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,23 +26,37 @@ type activeUpdateEntry struct {
 // CommunicationHandler は、ECHONET Lite 通信機能を担当する構造体
 type CommunicationHandler struct {
 	session         *Session                      // セッション
-	localDevices    DeviceProperties              // 自ノードが所有するデバイスのプロパティ
+	localDevices    *LocalDevices                 // 自ノードが所有するデバイスのプロパティ
 	dataAccessor    DataAccessor                  // データアクセス機能
 	notifier        NotificationRelay             // 通知中継
 	ctx             context.Context               // コンテキスト
 	Debug           bool                          // デバッグモード
 	activeUpdatesMu sync.RWMutex                  // アクティブな更新処理の排他制御
 	activeUpdates   map[string]*activeUpdateEntry // IP+EOJ別のアクティブな更新処理 (key: "IP:ClassCode:InstanceCode")
+	// 検出に使うインターフェース名。空の場合は BroadcastIP に対して検出する
+	discoveryInterfaces []string
+	// インターフェースごとの検出で、マルチキャストの代わりに各インターフェースのブロードキャストアドレスを使う
+	discoveryBroadcast bool
+	// デュアルスタック時に、同じノードが IPv4 と IPv6 の両方から応答した場合に採用するIPのバージョン
+	// 空の場合はデュアルスタックではない
+	preferIPVersion network.IPVersion
+	logger          *slog.Logger // ログ出力先
+	// 定期更新でデバイスごとに取得間隔を変える予定。nil の場合は毎回すべてのデバイスを取得する
+	pollSchedule atomic.Pointer[PollSchedule]
+	// デバイスごとにプロパティマップを取得し直した時刻 (key: device.Key())
+	propertyMapRefreshMu sync.Mutex
+	propertyMapRefreshed map[string]time.Time
 }
 
 // NewCommunicationHandler は、CommunicationHandlerの新しいインスタンスを作成する
 func NewCommunicationHandler(
 	ctx context.Context,
 	session *Session,
-	localDevices DeviceProperties,
+	localDevices *LocalDevices,
 	dataAccessor DataAccessor,
 	notifier NotificationRelay,
 	debug bool,
+	logger *slog.Logger,
 ) *CommunicationHandler {
 	h := &CommunicationHandler{
 		session:       session,
@@ -48,6 +66,7 @@ func NewCommunicationHandler(
 		ctx:           ctx,
 		Debug:         debug,
 		activeUpdates: make(map[string]*activeUpdateEntry),
+		logger:        loggerOrDefault(logger),
 	}
 
 	// バックグラウンドクリーンアップを開始
@@ -111,7 +130,7 @@ func (h *CommunicationHandler) cancelExistingUpdate(deviceKey string) {
 
 	if entry, exists := h.activeUpdates[deviceKey]; exists {
 		if entry.cancel != nil {
-			slog.Debug("既存の更新処理をキャンセル", "device", deviceKey)
+			h.log().Debug("既存の更新処理をキャンセル", "device", deviceKey)
 			entry.cancel()
 		}
 		delete(h.activeUpdates, deviceKey)
@@ -164,7 +183,7 @@ func (h *CommunicationHandler) cleanupStaleActiveUpdates() {
 				entry.cancel()
 			}
 			delete(h.activeUpdates, deviceKey)
-			slog.Debug("Cleaned up stale active update entry", "device", deviceKey, "age", now.Sub(entry.startTime))
+			h.log().Debug("Cleaned up stale active update entry", "device", deviceKey, "age", now.Sub(entry.startTime))
 		}
 	}
 }
@@ -191,9 +210,9 @@ func (h *CommunicationHandler) onReceiveMessage(ip net.IP, msg *echonet_lite.ECH
 	}
 
 	if h.Debug {
-		fmt.Printf("%v: メッセージを受信: SEOJ:%v, DEOJ:%v, ESV:%v Property: %v\n",
-			ip, msg.SEOJ, msg.DEOJ, msg.ESV,
-			msg.Properties.String(msg.DEOJ.ClassCode()),
+		h.log().Debug("メッセージを受信",
+			"ip", ip, "seoj", msg.SEOJ, "deoj", msg.DEOJ, "esv", msg.ESV,
+			"properties", msg.Properties.String(msg.DEOJ.ClassCode()),
 		)
 	}
 
@@ -214,7 +233,7 @@ func (h *CommunicationHandler) onReceiveMessage(ip net.IP, msg *echonet_lite.ECH
 			ESV = echonet_lite.ESVGet_SNA
 		}
 		if h.Debug {
-			fmt.Printf("  Getメッセージに対する応答: %v\n", responses) // DEBUG
+			h.log().Debug("Getメッセージに対する応答", "ip", ip, "esv", ESV, "properties", responses)
 		}
 		return h.session.SendResponse(ip, msg, ESV, responses, nil)
 
@@ -236,7 +255,7 @@ func (h *CommunicationHandler) onReceiveMessage(ip net.IP, msg *echonet_lite.ECH
 				}
 			}
 			if h.Debug {
-				fmt.Printf("  %vメッセージに対する応答: %v\n", msg.ESV, responses) // DEBUG
+				h.log().Debug("Setメッセージに対する応答", "ip", ip, "request", msg.ESV, "esv", ESV, "properties", responses)
 			}
 			return h.session.SendResponse(ip, msg, ESV, responses, nil)
 		}
@@ -256,7 +275,7 @@ func (h *CommunicationHandler) onReceiveMessage(ip net.IP, msg *echonet_lite.ECH
 			ESV = echonet_lite.ESVSetGet_SNA
 		}
 		if h.Debug {
-			fmt.Printf("  SetGetメッセージに対する応答: set:%v, get:%v\n", setResult, getResult) // DEBUG
+			h.log().Debug("SetGetメッセージに対する応答", "ip", ip, "esv", ESV, "set", setResult, "get", getResult)
 		}
 		return h.session.SendResponse(ip, msg, ESV, setResult, getResult)
 
@@ -269,7 +288,7 @@ func (h *CommunicationHandler) onReceiveMessage(ip net.IP, msg *echonet_lite.ECH
 		return h.session.Broadcast(msg.DEOJ, echonet_lite.ESVINF, result)
 
 	default:
-		fmt.Printf("  未対応のESV: %v\n", msg.ESV) // DEBUG
+		h.log().Debug("未対応のESV", "ip", ip, "esv", msg.ESV)
 	}
 	return nil
 }
@@ -277,20 +296,19 @@ func (h *CommunicationHandler) onReceiveMessage(ip net.IP, msg *echonet_lite.ECH
 // onInfMessage は、INFメッセージを受信したときのコールバック
 func (h *CommunicationHandler) onInfMessage(ip net.IP, msg *echonet_lite.ECHONETLiteMessage) error {
 	if msg == nil {
-		slog.Warn("無効なINFメッセージを受信しました: nil")
+		h.log().Warn("無効なINFメッセージを受信しました: nil")
 		return nil // 処理は継続
 	}
 
 	// 自分自身からのmulticastメッセージは無視する
 	if h.session.IsLocalIP(ip) {
 		if h.Debug {
-			slog.Debug("自分自身からのINFメッセージを無視", "ip", ip, "SEOJ", msg.SEOJ)
+			h.log().Debug("自分自身からのINFメッセージを無視", "ip", ip, "SEOJ", msg.SEOJ)
 		}
 		return nil
 	}
 
-	slog.Info("INFメッセージを受信", "ip", ip, "SEOJ", msg.SEOJ, "DEOJ", msg.DEOJ, "ESV", msg.ESV, "Properties", msg.Properties.String(msg.SEOJ.ClassCode()))
-	// fmt.Printf("INFメッセージを受信: %v %v, DEOJ:%v\n", ip, msg.SEOJ, msg.DEOJ) // DEBUG
+	h.log().Info("INFメッセージを受信", "ip", ip, "SEOJ", msg.SEOJ, "DEOJ", msg.DEOJ, "ESV", msg.ESV, "Properties", msg.Properties.String(msg.SEOJ.ClassCode()))
 
 	// デバイスの生存確認を記録（リトライ中のタイムアウト判定に使用）
 	sourceDevice := echonet_lite.IPAndEOJ{IP: ip, EOJ: msg.SEOJ}
@@ -318,7 +336,7 @@ func (h *CommunicationHandler) onInfMessage(ip net.IP, msg *echonet_lite.ECHONET
 			// 応答を返す
 			err := h.session.SendResponse(ip, msg, echonet_lite.ESVINFC_Res, replyProps, nil)
 			if err != nil {
-				slog.Error("INFメッセージに対する応答の送信に失敗", "err", err)
+				h.log().Error("INFメッセージに対する応答の送信に失敗", "err", err)
 			}
 		}
 	}()
@@ -330,18 +348,18 @@ func (h *CommunicationHandler) onInfMessage(ip net.IP, msg *echonet_lite.ECHONET
 			case echonet_lite.EPC_NPO_SelfNodeInstanceListS:
 				err := h.onSelfNodeInstanceListS(IPAndEOJ{IP: ip, EOJ: msg.SEOJ}, true, p)
 				if err != nil {
-					slog.Error("SelfNodeInstanceListSの処理中エラー", "err", err)
+					h.log().Error("SelfNodeInstanceListSの処理中エラー", "err", err)
 					return err
 				}
 			case echonet_lite.EPC_NPO_InstanceListNotification:
 				iln := echonet_lite.DecodeInstanceListNotification(p.EDT)
 				if iln == nil {
-					slog.Warn("InstanceListNotificationのデコードに失敗", "EDT", p.EDT)
+					h.log().Warn("InstanceListNotificationのデコードに失敗", "EDT", p.EDT)
 					return nil // 処理は継続
 				}
 				return h.onInstanceList(ip, echonet_lite.InstanceList(*iln))
 			default:
-				slog.Info("未処理のEPC", "EPC", p.EPC)
+				h.log().Info("未処理のEPC", "EPC", p.EPC)
 			}
 		}
 	} else {
@@ -349,10 +367,10 @@ func (h *CommunicationHandler) onInfMessage(ip net.IP, msg *echonet_lite.ECHONET
 
 		// IPアドレスが未登録の場合、デバイス情報を取得
 		if !h.dataAccessor.HasIP(ip) {
-			slog.Info("未登録のIPアドレスからのメッセージ", "ip", ip)
+			h.log().Info("未登録のIPアドレスからのメッセージ", "ip", ip)
 			err := h.GetSelfNodeInstanceListS(ip, false)
 			if err != nil {
-				slog.Error("SelfNodeInstanceListSの取得に失敗", "err", err)
+				h.log().Error("SelfNodeInstanceListSの取得に失敗", "err", err)
 				return err
 			}
 		}
@@ -360,23 +378,22 @@ func (h *CommunicationHandler) onInfMessage(ip net.IP, msg *echonet_lite.ECHONET
 		device := IPAndEOJ{IP: ip, EOJ: msg.SEOJ}
 
 		// 未知のデバイスの場合、プロパティマップを取得
+		// 既知のデバイスがプロパティマップにないプロパティを通知した場合は、プロパティマップを取得し直す
 		if !h.dataAccessor.IsKnownDevice(device) {
 			err := h.GetGetPropertyMap(device)
 			if err != nil {
-				slog.Error("プロパティマップの取得に失敗", "err", err)
+				h.log().Error("プロパティマップの取得に失敗", "err", err)
 				return err
 			}
+		} else {
+			h.refreshPropertyMapOnInf(device, msg.Properties)
 		}
 
 		// プロパティの通知を処理
 		if len(msg.Properties) > 0 {
 			// Propertyの通知 -> 値を更新する
 			h.dataAccessor.RegisterProperties(device, msg.Properties)
-			fmt.Printf("%s: Propertyの通知: %v %v\n",
-				time.Now().Format(time.RFC3339),
-				device,
-				msg.Properties.String(device.EOJ.ClassCode()),
-			)
+			h.log().Debug("Propertyの通知", "device", device, "properties", msg.Properties.String(device.EOJ.ClassCode()))
 
 			// デバイス情報を保存
 			h.dataAccessor.SaveDeviceInfo()
@@ -405,6 +422,30 @@ func (h *CommunicationHandler) onSelfNodeInstanceListS(device IPAndEOJ, success
 
 // onInstanceList は、インスタンスリストを受信したときのコールバック
 func (h *CommunicationHandler) onInstanceList(ip net.IP, il echonet_lite.InstanceList) error {
+	il = h.reconcileInstanceList(ip, il)
+
+	// 各デバイスのプロパティマップを取得
+	var e []error
+	for _, eoj := range il {
+		device := IPAndEOJ{IP: ip, EOJ: eoj}
+		if err := h.GetGetPropertyMap(device); err != nil {
+			e = append(e, fmt.Errorf("デバイス %v のプロパティ取得に失敗: %w", device, err))
+		}
+	}
+
+	// エラーがあれば報告（ただし処理は継続）
+	if len(e) > 0 {
+		for _, err := range e {
+			h.log().Warn("警告", "err", err)
+		}
+	}
+
+	return nil
+}
+
+// reconcileInstanceList は、ノードのインスタンスリストに合わせてデバイスを登録・削除し、NodeProfileObject を加えたリストを返す
+// リセットなどでインスタンス番号が振り直された場合は、消えたインスタンスのエイリアスなどを新しいインスタンスに引き継ぐ
+func (h *CommunicationHandler) reconcileInstanceList(ip net.IP, il echonet_lite.InstanceList) echonet_lite.InstanceList {
 	// NodeProfileObjectも追加して取得する
 	il = append(il, echonet_lite.NodeProfileObject)
 
@@ -421,22 +462,42 @@ func (h *CommunicationHandler) onInstanceList(ip net.IP, il echonet_lite.Instanc
 		newDeviceSet[device.Key()] = struct{}{}
 	}
 
-	// 3. 削除されたデバイスを検出
+	// 3. 削除されたデバイスと、新しく現れたデバイスを検出
 	var devicesToRemove []IPAndEOJ
+	existingSet := make(map[string]struct{}, len(existingDevices))
 	for _, existingDevice := range existingDevices {
 		if existingDevice.IP.Equal(ip) {
+			existingSet[existingDevice.Key()] = struct{}{}
 			if _, exists := newDeviceSet[existingDevice.Key()]; !exists {
 				devicesToRemove = append(devicesToRemove, existingDevice)
 			}
 		}
 	}
+	var addedDevices []IPAndEOJ
+	for _, eoj := range il {
+		device := IPAndEOJ{IP: ip, EOJ: eoj}
+		if _, exists := existingSet[device.Key()]; !exists {
+			addedDevices = append(addedDevices, device)
+		}
+	}
+
+	// リセットでインスタンス番号が振り直された場合は、消えたインスタンスを同じクラスの新しいインスタンスに対応付ける
+	// IDString は削除すると求められないため、削除の前に控えておく
+	reassignments := pairReassignedInstances(devicesToRemove, addedDevices)
+	previousIDs := make(map[string]IDString, len(reassignments))
+	for _, r := range reassignments {
+		previousIDs[r.Previous.Key()] = h.dataAccessor.GetIDString(r.Previous)
+	}
+	if len(devicesToRemove) > 0 || len(addedDevices) > 0 {
+		h.log().Info("インスタンスリストの不一致を検出", "ip", ip, "removed", len(devicesToRemove), "added", len(addedDevices), "reassigned", len(reassignments))
+	}
 
 	// 4. 削除されたデバイスを削除
 	for _, device := range devicesToRemove {
 		if err := h.dataAccessor.RemoveDevice(device); err != nil {
-			slog.Warn("デバイスの削除に失敗", "device", device, "err", err)
+			h.log().Warn("デバイスの削除に失敗", "device", device, "err", err)
 		} else {
-			slog.Info("デバイスを削除", "device", device)
+			h.log().Info("デバイスを削除", "device", device)
 		}
 	}
 
@@ -448,45 +509,83 @@ func (h *CommunicationHandler) onInstanceList(ip net.IP, il echonet_lite.Instanc
 		// NodeProfileが有効なデバイスとして報告している場合、
 		// オフライン状態をオンラインに復帰
 		if h.dataAccessor.IsOffline(device) {
-			slog.Info("NodeProfileからのインスタンスリストによりデバイスをオンラインに復帰",
+			h.log().Info("NodeProfileからのインスタンスリストによりデバイスをオンラインに復帰",
 				"device", device.Specifier())
 			h.dataAccessor.SetOffline(device, false)
 		}
 	}
 
+	// 6. 消えたインスタンスのエイリアスなどを新しいインスタンスに付け替えて通知
+	for _, r := range reassignments {
+		reconfiguration := &DeviceReconfiguration{
+			Previous:   r.Previous,
+			PreviousID: previousIDs[r.Previous.Key()],
+			ID:         h.dataAccessor.GetIDString(r.Device),
+		}
+		reconfiguration.Aliases, reconfiguration.Groups = h.dataAccessor.ReassignDeviceID(reconfiguration.PreviousID, reconfiguration.ID)
+		h.log().Info("インスタンスの振り直しを検出", "previous", r.Previous.Specifier(), "device", r.Device.Specifier(),
+			"aliases", len(reconfiguration.Aliases), "groups", len(reconfiguration.Groups))
+		if h.notifier != nil {
+			h.notifier.RelayDeviceEvent(DeviceEvent{Device: r.Device, Type: DeviceEventReconfigured, Reconfiguration: reconfiguration})
+		}
+	}
+
 	// デバイス情報の保存
 	h.dataAccessor.SaveDeviceInfo()
+	return il
+}
 
-	// 各デバイスのプロパティマップを取得
-	var e []error
-	for _, eoj := range il {
-		device := IPAndEOJ{IP: ip, EOJ: eoj}
-		if err := h.GetGetPropertyMap(device); err != nil {
-			e = append(e, fmt.Errorf("デバイス %v のプロパティ取得に失敗: %w", device, err))
+// instanceReassignment は、消えたインスタンスと、それを引き継ぐ新しいインスタンスの組
+type instanceReassignment struct {
+	Previous IPAndEOJ
+	Device   IPAndEOJ
+}
+
+// pairReassignedInstances は、消えたインスタンスと新しく現れたインスタンスを、クラスごとにインスタンス番号の順に対応付ける
+// 対応する相手のないインスタンスは含めない（消えたものは削除、現れたものは追加として扱う）
+func pairReassignedInstances(removed, added []IPAndEOJ) []instanceReassignment {
+	byClass := func(devices []IPAndEOJ) map[EOJClassCode][]IPAndEOJ {
+		result := make(map[EOJClassCode][]IPAndEOJ)
+		for _, device := range devices {
+			if device.EOJ.ClassCode() == echonet_lite.NodeProfile_ClassCode {
+				continue
+			}
+			result[device.EOJ.ClassCode()] = append(result[device.EOJ.ClassCode()], device)
+		}
+		for _, list := range result {
+			sort.Slice(list, func(i, j int) bool {
+				return list[i].EOJ.InstanceCode() < list[j].EOJ.InstanceCode()
+			})
 		}
+		return result
 	}
 
-	// エラーがあれば報告（ただし処理は継続）
-	if len(e) > 0 {
-		for _, err := range e {
-			slog.Warn("警告", "err", err)
+	removedByClass := byClass(removed)
+	addedByClass := byClass(added)
+	var result []instanceReassignment
+	for class, previous := range removedByClass {
+		current := addedByClass[class]
+		for i := 0; i < len(previous) && i < len(current); i++ {
+			result = append(result, instanceReassignment{Previous: previous[i], Device: current[i]})
 		}
 	}
-
-	return nil
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Device.EOJ < result[j].Device.EOJ
+	})
+	return result
 }
 
 // onGetPropertyMap は、GetPropertyMapプロパティを受信したときのコールバック
 func (h *CommunicationHandler) onGetPropertyMap(device IPAndEOJ, success bool, properties Properties, _ []EPCType) (CallbackCompleteStatus, error) {
 	if !success {
-		slog.Warn("GetPropertyMapプロパティの取得に失敗しました", "device", device)
+		h.log().Warn("GetPropertyMapプロパティの取得に失敗しました", "device", device)
 		return CallbackFinished, nil
 	}
 
 	p := properties[0]
 
 	if p.EPC != echonet_lite.EPCGetPropertyMap {
-		slog.Warn("予期しないEPC", "EPC", p.EPC, "expected", echonet_lite.EPCGetPropertyMap)
+		h.log().Warn("予期しないEPC", "EPC", p.EPC, "expected", echonet_lite.EPCGetPropertyMap)
 		return CallbackFinished, nil
 	}
 
@@ -503,7 +602,7 @@ func (h *CommunicationHandler) onGetPropertyMap(device IPAndEOJ, success bool, p
 
 	// プロパティが見つからない場合
 	if len(forGet) == 0 {
-		slog.Info("デバイスにプロパティが見つかりません", "EOJ", device.EOJ)
+		h.log().Info("デバイスにプロパティが見つかりません", "EOJ", device.EOJ)
 		return CallbackFinished, nil
 	}
 
@@ -514,7 +613,7 @@ func (h *CommunicationHandler) onGetPropertyMap(device IPAndEOJ, success bool, p
 		forGet,
 		func(device IPAndEOJ, success bool, properties Properties, failedEPCs []EPCType) (CallbackCompleteStatus, error) {
 			if !success {
-				slog.Warn("プロパティ取得に失敗", "device", device, "failedEPCs", failedEPCs)
+				h.log().Warn("プロパティ取得に失敗", "device", device, "failedEPCs", failedEPCs)
 			}
 
 			// プロパティを登録
@@ -529,7 +628,7 @@ func (h *CommunicationHandler) onGetPropertyMap(device IPAndEOJ, success bool, p
 	)
 
 	if err != nil {
-		slog.Error("プロパティ取得リクエストの送信に失敗", "err", err)
+		h.log().Error("プロパティ取得リクエストの送信に失敗", "err", err)
 	}
 
 	return CallbackFinished, err
@@ -580,27 +679,47 @@ func (h *CommunicationHandler) GetGetPropertyMap(device IPAndEOJ) error {
 
 // Discover は、ECHONET Liteデバイスを検出する
 func (h *CommunicationHandler) Discover() error {
-	slog.Info("Starting device discovery")
+	h.log().Info("Starting device discovery")
 	start := time.Now()
 
-	err := h.GetSelfNodeInstanceListS(BroadcastIP, true)
+	var err error
+	if len(h.discoveryInterfaces) > 0 || h.preferIPVersion != "" {
+		var targets []discoveryTarget
+		targets, err = h.discoveryTargets()
+		if err == nil {
+			err = h.discoverOnTargets(targets)
+		}
+	} else {
+		err = h.GetSelfNodeInstanceListS(h.session.DiscoveryIPs()[0], true)
+	}
 
 	duration := time.Since(start)
 	if err != nil {
-		slog.Error("Device discovery failed", "duration", duration, "error", err)
+		h.log().Error("Device discovery failed", "duration", duration, "error", err)
 		return err
 	}
 
-	slog.Info("Device discovery completed", "duration", duration)
+	h.log().Info("Device discovery completed", "duration", duration)
 	return nil
 }
 
 // GetProperties は、プロパティ値を取得する
 // 成功時には ip, eoj と properties を返す
+// EPCs が空の場合は、GetPropertyMap に含まれるすべてのプロパティを取得する
 func (h *CommunicationHandler) GetProperties(device IPAndEOJ, EPCs []EPCType, skipValidation bool) (DeviceAndProperties, error) {
 	// 結果を格納する変数
 	var result DeviceAndProperties
 
+	// EPCの指定がない場合は、GetPropertyMapを先に読み、取得可能なすべてのプロパティを1回の Get で取得する
+	if len(EPCs) == 0 {
+		propMap, ok := h.tryGetPropertyMap(device)
+		if !ok {
+			return DeviceAndProperties{}, fmt.Errorf("%v: GetPropertyMapを取得できません", device)
+		}
+		EPCs = propMap.EPCs()
+		skipValidation = true // GetPropertyMap から得たEPCなので確認は不要
+	}
+
 	if !skipValidation {
 		// 指定されたEPCがGetPropertyMapに含まれているか確認
 		valid, invalidEPCs, err := h.validateEPCsInPropertyMap(device, EPCs, GetPropertyMap)
@@ -619,7 +738,7 @@ func (h *CommunicationHandler) GetProperties(device IPAndEOJ, EPCs []EPCType, sk
 	)
 
 	if err != nil {
-		slog.Error("プロパティ取得に失敗", "device", device, "err", err)
+		h.log().Error("プロパティ取得に失敗", "device", device, "err", err)
 		return DeviceAndProperties{}, fmt.Errorf("%v: プロパティ取得に失敗: %w", device, err)
 	}
 
@@ -639,24 +758,22 @@ func (h *CommunicationHandler) GetProperties(device IPAndEOJ, EPCs []EPCType, sk
 
 	// 全体の成功/失敗を判定
 	if !success {
-		slog.Warn("一部のプロパティ取得に失敗", "device", device, "failed_epcs", failedEPCs)
+		h.log().Warn("一部のプロパティ取得に失敗", "device", device, "failed_epcs", failedEPCs)
 	}
 
 	return result, nil
 }
 
 // SetProperties は、プロパティ値を設定する
-func (h *CommunicationHandler) SetProperties(device IPAndEOJ, properties Properties) (DeviceAndProperties, error) {
+// skipValidationEPCs に含まれるEPCと書き込み専用プロパティは、SetPropertyMap による確認を行わない（Strict の場合はすべて確認する）
+// 複数のプロパティを設定する場合は echonet_lite.SetOrderStages の順に分けて送信し、
+// 結果の Properties は設定できたプロパティを設定した順に並べる
+func (h *CommunicationHandler) SetProperties(device IPAndEOJ, properties Properties, skipValidationEPCs []EPCType) (DeviceAndProperties, error) {
 	// 結果を格納する変数
 	var result DeviceAndProperties
 
 	// 指定されたEPCがSetPropertyMapに含まれているか確認
-	// Propertiesから各EPCを抽出
-	epcs := make([]EPCType, 0, len(properties))
-	for _, prop := range properties {
-		epcs = append(epcs, prop.EPC)
-	}
-
+	epcs := h.setEPCsToValidate(device, properties, skipValidationEPCs)
 	valid, invalidEPCs, err := h.validateEPCsInPropertyMap(device, epcs, SetPropertyMap)
 	if err != nil {
 		return DeviceAndProperties{}, err
@@ -665,15 +782,29 @@ func (h *CommunicationHandler) SetProperties(device IPAndEOJ, properties Propert
 		return DeviceAndProperties{}, fmt.Errorf("以下のEPCはSetPropertyMapに含まれていません: %v", invalidEPCs)
 	}
 
-	success, successProperties, failedEPCs, err := h.session.SetProperties(
-		h.ctx,
-		device,
-		properties,
-	)
+	success := true
+	var successProperties Properties
+	var failedEPCs []EPCType
+	for _, stage := range echonet_lite.SetOrderStages(device.EOJ.ClassCode(), properties) {
+		stageSuccess, stageProperties, stageFailedEPCs, err := h.session.SetProperties(
+			h.ctx,
+			device,
+			stage,
+		)
 
-	if err != nil {
-		slog.Error("プロパティ設定に失敗", "device", device, "err", err)
-		return DeviceAndProperties{}, fmt.Errorf("%v: プロパティ設定に失敗: %w", device, err)
+		if err != nil {
+			h.log().Error("プロパティ設定に失敗", "device", device, "err", err)
+			successProperties = append(successProperties, stageProperties...)
+			if len(successProperties) > 0 {
+				// 先の段階やフレームで設定できたプロパティは登録しておく
+				h.dataAccessor.RegisterProperties(device, successProperties)
+				h.dataAccessor.SaveDeviceInfo()
+			}
+			return DeviceAndProperties{}, fmt.Errorf("%v: プロパティ設定に失敗: %w", device, err)
+		}
+		success = success && stageSuccess
+		successProperties = append(successProperties, stageProperties...)
+		failedEPCs = append(failedEPCs, stageFailedEPCs...)
 	}
 
 	// 成功したプロパティを登録（部分的な成功の場合も含む）
@@ -692,26 +823,138 @@ func (h *CommunicationHandler) SetProperties(device IPAndEOJ, properties Propert
 
 	// 全体の成功/失敗を判定
 	if !success {
-		slog.Warn("一部のプロパティ設定に失敗", "device", device, "failed_epcs", failedEPCs)
+		h.log().Warn("一部のプロパティ設定に失敗", "device", device, "failed_epcs", failedEPCs)
 	}
 
 	return result, nil
 }
 
+// SetGetProperties は、プロパティ値の設定と取得を1つの SetGet フレームで行う
+// 設定直後の状態を他の要求に割り込まれずに読み出せるため、整合した状態を得るために SetGet を必要とする機器で使う
+func (h *CommunicationHandler) SetGetProperties(device IPAndEOJ, properties Properties, EPCs []EPCType) (SetGetResult, error) {
+	if len(properties) == 0 && len(EPCs) == 0 {
+		return SetGetResult{}, fmt.Errorf("%v: 設定・取得するプロパティが指定されていません", device)
+	}
+
+	// 設定するEPCがSetPropertyMapに、取得するEPCがGetPropertyMapに含まれているか確認
+	setEPCs := h.setEPCsToValidate(device, properties, nil)
+	valid, invalidEPCs, err := h.validateEPCsInPropertyMap(device, setEPCs, SetPropertyMap)
+	if err != nil {
+		return SetGetResult{}, err
+	}
+	if !valid {
+		return SetGetResult{}, fmt.Errorf("以下のEPCはSetPropertyMapに含まれていません: %v", invalidEPCs)
+	}
+	valid, invalidEPCs, err = h.validateEPCsInPropertyMap(device, EPCs, GetPropertyMap)
+	if err != nil {
+		return SetGetResult{}, err
+	}
+	if !valid {
+		return SetGetResult{}, fmt.Errorf("%v: 以下のEPCはGetPropertyMapに含まれていません: %v", device, invalidEPCs)
+	}
+
+	success, setProperties, getProperties, failedEPCs, err := h.session.SetGetProperties(
+		h.ctx,
+		device,
+		properties,
+		EPCs,
+	)
+
+	if err != nil {
+		h.log().Error("プロパティ設定・取得に失敗", "device", device, "err", err)
+		return SetGetResult{}, fmt.Errorf("%v: プロパティ設定・取得に失敗: %w", device, err)
+	}
+
+	// 設定・取得に成功したプロパティを登録（部分的な成功の場合も含む）
+	// 同じEPCを設定・取得した場合は、読み出した値を優先する
+	registered := make(Properties, 0, len(setProperties)+len(getProperties))
+	registered = append(registered, setProperties...)
+	registered = append(registered, getProperties...)
+	if len(registered) > 0 {
+		h.dataAccessor.RegisterProperties(device, registered)
+
+		// デバイス情報を保存
+		h.dataAccessor.SaveDeviceInfo()
+		h.dataAccessor.SetOffline(device, false)
+	}
+
+	// 全体の成功/失敗を判定
+	if !success {
+		h.log().Warn("一部のプロパティ設定・取得に失敗", "device", device, "failed_epcs", failedEPCs)
+	}
+
+	return SetGetResult{Device: device, Set: setProperties, Get: getProperties}, nil
+}
+
 // UpdateProperties は、フィルタリングされたデバイスのプロパティキャッシュを更新する
 // force が true の場合、最終更新時刻に関わらず強制的に更新する
 func (h *CommunicationHandler) UpdateProperties(criteria FilterCriteria, force bool) error {
-	start := time.Now()
-
 	// フィルタリングを実行
 	filtered := h.dataAccessor.Filter(criteria)
 
 	// フィルタリング結果が空の場合
 	if filtered.Len() == 0 {
-		slog.Warn("No devices matched criteria", "criteria", criteria)
+		h.log().Warn("No devices matched criteria", "criteria", criteria)
 		return fmt.Errorf("条件に一致するデバイスが見つかりません")
 	}
 
+	return h.updateDevices(filtered.ListIPAndEOJ(), force, nil)
+}
+
+// UpdateDevicesWithProgress は、指定されたデバイスのプロパティキャッシュを更新し、デバイスごとの結果を progress に通知する
+// progress はデバイスごとに1回、並列に呼ばれることがある
+func (h *CommunicationHandler) UpdateDevicesWithProgress(devices []IPAndEOJ, force bool, progress UpdateProgressFunc) error {
+	return h.updateDevices(devices, force, progress)
+}
+
+// SetPollSchedule は、定期更新（UpdateScheduledProperties）でデバイスごとに取得間隔を変える予定を設定する
+// nil の場合は、毎回すべてのデバイスを取得する
+func (h *CommunicationHandler) SetPollSchedule(schedule *PollSchedule) {
+	h.pollSchedule.Store(schedule)
+}
+
+// UpdateScheduledProperties は、定期更新の予定で取得間隔が経過したデバイスのプロパティを更新する
+// 予定がない場合は UpdateProperties と同じく全デバイスを更新し、取得するデバイスがない場合は何もしない
+func (h *CommunicationHandler) UpdateScheduledProperties(force bool) error {
+	devices := h.dataAccessor.Filter(FilterCriteria{}).ListIPAndEOJ()
+	if schedule := h.pollSchedule.Load(); schedule != nil {
+		devices = schedule.takeDue(devices, time.Now(), force)
+	}
+	if len(devices) == 0 {
+		return nil
+	}
+	return h.updateDevices(devices, force, nil)
+}
+
+// UpdateStatus は、プロパティ更新でのデバイスごとの結果
+type UpdateStatus string
+
+const (
+	UpdateStatusSucceeded UpdateStatus = "succeeded"
+	UpdateStatusFailed    UpdateStatus = "failed"
+	UpdateStatusSkipped   UpdateStatus = "skipped" // 最近更新した、オフライン、または更新中のデバイス
+)
+
+// UpdateResult は、プロパティ更新での1台のデバイスの結果
+type UpdateResult struct {
+	Device IPAndEOJ
+	Status UpdateStatus
+	Reason string // 失敗・スキップの理由
+}
+
+// UpdateProgressFunc は、プロパティ更新でデバイスの処理が終わるたびに呼ばれる
+type UpdateProgressFunc func(result UpdateResult)
+
+// updateDevices は、指定されたデバイスのプロパティを並列に取得する
+// progress が nil でなければ、各デバイスの結果を通知する
+func (h *CommunicationHandler) updateDevices(devices []IPAndEOJ, force bool, progress UpdateProgressFunc) error {
+	start := time.Now()
+	report := func(device IPAndEOJ, status UpdateStatus, reason string) {
+		if progress != nil {
+			progress(UpdateResult{Device: device, Status: status, Reason: reason})
+		}
+	}
+
 	// 全てのデバイスの更新完了を待つためのWaitGroup
 	var wg sync.WaitGroup
 	var errMutex sync.Mutex
@@ -730,7 +973,7 @@ func (h *CommunicationHandler) UpdateProperties(criteria FilterCriteria, force b
 
 	// デバイスをIP+classCodeでグループ化し、ブロードキャスト可能なものと個別処理が必要なものに分類
 	deviceGroups := make(map[string][]IPAndEOJ) // key: "IP:classCode"
-	for _, device := range filtered.ListIPAndEOJ() {
+	for _, device := range devices {
 		key := fmt.Sprintf("%s:%04X", device.IP.String(), uint16(device.EOJ.ClassCode()))
 		deviceGroups[key] = append(deviceGroups[key], device)
 	}
@@ -748,7 +991,7 @@ func (h *CommunicationHandler) UpdateProperties(criteria FilterCriteria, force b
 	}
 
 	if h.Debug {
-		slog.Info("Device processing strategy",
+		h.log().Info("Device processing strategy",
 			"broadcast_groups", len(broadcastGroups),
 			"individual_devices", len(individualDevices))
 	}
@@ -764,7 +1007,10 @@ func (h *CommunicationHandler) UpdateProperties(criteria FilterCriteria, force b
 			}
 		}
 		if groupActive {
-			slog.Info("ブロードキャストグループ内のデバイスが既にアクティブのためスキップ", "group_size", len(group))
+			h.log().Info("ブロードキャストグループ内のデバイスが既にアクティブのためスキップ", "group_size", len(group))
+			for _, device := range group {
+				report(device, UpdateStatusSkipped, "already updating")
+			}
 			continue
 		}
 
@@ -788,7 +1034,7 @@ func (h *CommunicationHandler) UpdateProperties(criteria FilterCriteria, force b
 				}
 			}()
 
-			h.processBroadcastGroup(ctx, devices, force, &errMutex, &firstErr)
+			h.processBroadcastGroup(ctx, devices, force, &errMutex, &firstErr, report)
 		}(group, force)
 	}
 
@@ -798,16 +1044,21 @@ func (h *CommunicationHandler) UpdateProperties(criteria FilterCriteria, force b
 		if !force {
 			lastUpdateTime := h.dataAccessor.GetLastUpdateTime(device)
 			if !lastUpdateTime.IsZero() && time.Since(lastUpdateTime) < UpdateIntervalThreshold {
-				// fmt.Printf("デバイス %v は最近更新されたためスキップします (最終更新: %v)\n", device, lastUpdateTime.Format(time.RFC3339))
+				if h.Debug {
+					h.log().Debug("最近更新されたデバイスの更新をスキップ", "device", device, "lastUpdate", lastUpdateTime.Format(time.RFC3339))
+				}
+				report(device, UpdateStatusSkipped, "recently updated")
 				continue // 更新をスキップ
 			}
 			if h.dataAccessor.IsOffline(device) && !h.isNodeProfileOnline(device.IP) {
+				report(device, UpdateStatusSkipped, "offline")
 				continue // オフラインのデバイスはスキップ（ただし、NodeProfileがオンラインの場合は更新を試行）
 			}
 		}
 
 		propMap, ok := h.tryGetPropertyMap(device)
 		if !ok {
+			report(device, UpdateStatusFailed, "property map not available")
 			continue
 		}
 
@@ -821,7 +1072,8 @@ func (h *CommunicationHandler) UpdateProperties(criteria FilterCriteria, force b
 
 		// デバイスの更新処理がアクティブかチェック
 		if h.isUpdateActive(device, force) {
-			slog.Info("デバイスの更新処理が既にアクティブのためスキップ", "device", device.Specifier())
+			h.log().Info("デバイスの更新処理が既にアクティブのためスキップ", "device", device.Specifier())
+			report(device, UpdateStatusSkipped, "already updating")
 			continue
 		}
 
@@ -840,22 +1092,22 @@ func (h *CommunicationHandler) UpdateProperties(criteria FilterCriteria, force b
 			h.markUpdateActive(device, cancel)
 			defer h.markUpdateInactive(device)
 
-			h.processIndividualDevice(ctx, device, propMap, delay, storeError)
+			h.processIndividualDevice(ctx, device, propMap, delay, storeError, report)
 		}(device, propMap, delay)
 	}
 
 	// 全てのデバイスの更新が完了するまで待つ
-	slog.Debug("Waiting for all device updates to complete")
+	h.log().Debug("Waiting for all device updates to complete")
 	wg.Wait()
 
 	duration := time.Since(start)
 	// エラーがあれば返す
 	if firstErr != nil {
-		slog.Error("UpdateProperties completed with errors", "deviceCount", filtered.Len(), "duration", duration, "first_error", firstErr)
+		h.log().Error("UpdateProperties completed with errors", "deviceCount", len(devices), "duration", duration, "first_error", firstErr)
 		return firstErr
 	}
 
-	slog.Debug("UpdateProperties completed successfully", "deviceCount", filtered.Len(), "duration", duration)
+	h.log().Debug("UpdateProperties completed successfully", "deviceCount", len(devices), "duration", duration)
 	return nil
 }
 
@@ -895,7 +1147,8 @@ func calculateRequestDelay(requestIndex int, baseDelay time.Duration) time.Durat
 }
 
 // processBroadcastGroup はブロードキャスト対象のデバイスグループを処理する
-func (h *CommunicationHandler) processBroadcastGroup(ctx context.Context, devices []IPAndEOJ, force bool, errMutex *sync.Mutex, errPtr *error) {
+// report には各デバイスの結果を通知する
+func (h *CommunicationHandler) processBroadcastGroup(ctx context.Context, devices []IPAndEOJ, force bool, errMutex *sync.Mutex, errPtr *error, report func(IPAndEOJ, UpdateStatus, string)) {
 	if len(devices) == 0 {
 		return
 	}
@@ -914,7 +1167,10 @@ func (h *CommunicationHandler) processBroadcastGroup(ctx context.Context, device
 	// キャンセルチェック
 	select {
 	case <-ctx.Done():
-		slog.Debug("ブロードキャストグループの更新処理がキャンセルされました", "device", firstDevice.Specifier(), "reason", ctx.Err())
+		h.log().Debug("ブロードキャストグループの更新処理がキャンセルされました", "device", firstDevice.Specifier(), "reason", ctx.Err())
+		for _, device := range devices {
+			report(device, UpdateStatusFailed, "cancelled")
+		}
 		return
 	default:
 	}
@@ -930,8 +1186,10 @@ func (h *CommunicationHandler) processBroadcastGroup(ctx context.Context, device
 			if sharedPropMap == nil {
 				sharedPropMap = propMap // 最初の有効なプロパティマップを使用
 			}
+		} else {
+			// tryGetPropertyMap 内でオフライン設定済み
+			report(device, UpdateStatusFailed, "property map not available")
 		}
-		// 失敗した場合は tryGetPropertyMap 内でオフライン設定済み
 	}
 
 	if len(validDevicesWithMaps) == 0 {
@@ -948,9 +1206,11 @@ func (h *CommunicationHandler) processBroadcastGroup(ctx context.Context, device
 		for _, device := range devices {
 			lastUpdateTime := h.dataAccessor.GetLastUpdateTime(device)
 			if !lastUpdateTime.IsZero() && time.Since(lastUpdateTime) < UpdateIntervalThreshold {
+				report(device, UpdateStatusSkipped, "recently updated")
 				continue // 更新をスキップ
 			}
 			if h.dataAccessor.IsOffline(device) && !h.isNodeProfileOnline(device.IP) {
+				report(device, UpdateStatusSkipped, "offline")
 				continue // オフラインのデバイスはスキップ（ただし、NodeProfileがオンラインの場合は更新を試行）
 			}
 			validDevices = append(validDevices, device)
@@ -971,15 +1231,21 @@ func (h *CommunicationHandler) processBroadcastGroup(ctx context.Context, device
 
 	if err != nil {
 		storeError(fmt.Errorf("ブロードキャスト取得に失敗: %w", err))
+		for _, device := range devices {
+			report(device, UpdateStatusFailed, err.Error())
+		}
 		return
 	}
 
 	// 各デバイスの結果を処理
+	reported := make(map[string]bool, len(devices))
 	for _, result := range results {
 		deviceName := h.dataAccessor.DeviceStringWithAlias(result.Device)
+		reported[result.Device.Key()] = true
 
 		if result.Error != nil {
 			storeError(fmt.Errorf("%v のプロパティ取得に失敗: %w", deviceName, result.Error))
+			report(result.Device, UpdateStatusFailed, result.Error.Error())
 			continue
 		}
 
@@ -998,10 +1264,17 @@ func (h *CommunicationHandler) processBroadcastGroup(ctx context.Context, device
 				h.dataAccessor.SetOffline(result.Device, false)
 			}
 		}
+		report(result.Device, UpdateStatusSucceeded, "")
+	}
+
+	for _, device := range devices {
+		if !reported[device.Key()] {
+			report(device, UpdateStatusFailed, "no response")
+		}
 	}
 
 	if h.Debug {
-		slog.Info("Broadcast group processed", "device_count", len(devices), "first_device", firstDevice.Specifier())
+		h.log().Info("Broadcast group processed", "device_count", len(devices), "first_device", firstDevice.Specifier())
 	}
 }
 
@@ -1013,7 +1286,7 @@ func (h *CommunicationHandler) tryGetPropertyMap(device IPAndEOJ) (PropertyMap,
 	}
 
 	// プロパティマップが見つからない場合は、まずGetPropertyMapリクエストを送信
-	slog.Debug("プロパティマップが見つからないため、GetPropertyMapを取得", "device", device.Specifier())
+	h.log().Debug("プロパティマップが見つからないため、GetPropertyMapを取得", "device", device.Specifier())
 
 	success, properties, _, err := h.session.GetProperties(
 		h.ctx,
@@ -1024,7 +1297,7 @@ func (h *CommunicationHandler) tryGetPropertyMap(device IPAndEOJ) (PropertyMap,
 	if err != nil || !success || len(properties) == 0 {
 		// GetPropertyMapの取得に失敗した場合は、デバイスをオフライン状態に設定
 		if !h.dataAccessor.IsOffline(device) {
-			slog.Info("GetPropertyMap取得に失敗したため、デバイスをオフライン状態に設定", "device", device.Specifier())
+			h.log().Info("GetPropertyMap取得に失敗したため、デバイスをオフライン状態に設定", "device", device.Specifier())
 			h.dataAccessor.SetOffline(device, true)
 		}
 		return nil, false
@@ -1037,7 +1310,7 @@ func (h *CommunicationHandler) tryGetPropertyMap(device IPAndEOJ) (PropertyMap,
 	// 再度プロパティマップを取得
 	propMap = h.dataAccessor.GetPropertyMap(device, GetPropertyMap)
 	if propMap == nil {
-		slog.Warn("GetPropertyMapを取得したがプロパティマップの生成に失敗", "device", device.Specifier())
+		h.log().Warn("GetPropertyMapを取得したがプロパティマップの生成に失敗", "device", device.Specifier())
 		return nil, false
 	}
 
@@ -1045,14 +1318,16 @@ func (h *CommunicationHandler) tryGetPropertyMap(device IPAndEOJ) (PropertyMap,
 }
 
 // processIndividualDevice は個別デバイスを処理する
-func (h *CommunicationHandler) processIndividualDevice(ctx context.Context, device IPAndEOJ, propMap PropertyMap, delay time.Duration, storeError func(error)) {
+// report にはデバイスの結果を通知する
+func (h *CommunicationHandler) processIndividualDevice(ctx context.Context, device IPAndEOJ, propMap PropertyMap, delay time.Duration, storeError func(error), report func(IPAndEOJ, UpdateStatus, string)) {
 	deviceName := h.dataAccessor.DeviceStringWithAlias(device)
 
 	// 同じIPアドレスのデバイスに対して遅延を追加
 	if delay > 0 {
 		select {
 		case <-ctx.Done():
-			slog.Debug("個別デバイスの更新処理がキャンセルされました", "device", deviceName, "reason", ctx.Err())
+			h.log().Debug("個別デバイスの更新処理がキャンセルされました", "device", deviceName, "reason", ctx.Err())
+			report(device, UpdateStatusFailed, "cancelled")
 			return
 		case <-time.After(delay):
 			// 遅延完了
@@ -1069,6 +1344,7 @@ func (h *CommunicationHandler) processIndividualDevice(ctx context.Context, devi
 
 	if err != nil {
 		storeError(fmt.Errorf("%v のプロパティ取得に失敗: %w", deviceName, err))
+		report(device, UpdateStatusFailed, err.Error())
 		return
 	}
 
@@ -1097,8 +1373,36 @@ func (h *CommunicationHandler) processIndividualDevice(ctx context.Context, devi
 		for i, epc := range failedEPCs {
 			epcNames[i] = epc.StringForClass(device.EOJ.ClassCode())
 		}
-		slog.Warn("プロパティ取得に失敗", "device", deviceName, "failed_epcs", epcNames)
+		h.log().Warn("プロパティ取得に失敗", "device", deviceName, "failed_epcs", epcNames)
+	}
+	// 一部のプロパティの取得に失敗しても、取得できたものは登録したので成功とする
+	report(device, UpdateStatusSucceeded, "")
+}
+
+// setEPCsToValidate は、設定するプロパティのうち SetPropertyMap で確認すべきEPCを返す
+// skipValidationEPCs に含まれるEPCと、書き込み専用として登録されたEPCは除外する
+func setEPCsToValidate(device IPAndEOJ, properties Properties, skipValidationEPCs []EPCType) []EPCType {
+	epcs := make([]EPCType, 0, len(properties))
+	for _, prop := range properties {
+		if slices.Contains(skipValidationEPCs, prop.EPC) || echonet_lite.IsWriteOnlyEPC(device.EOJ.ClassCode(), prop.EPC) {
+			continue
+		}
+		epcs = append(epcs, prop.EPC)
 	}
+	return epcs
+}
+
+// setEPCsToValidate は、SetPropertyMap で確認すべきEPCを返す
+// Strict の場合は、書き込み専用のプロパティを含むすべてのEPCを確認する
+func (h *CommunicationHandler) setEPCsToValidate(device IPAndEOJ, properties Properties, skipValidationEPCs []EPCType) []EPCType {
+	if h.session != nil && h.session.Strict {
+		epcs := make([]EPCType, 0, len(properties))
+		for _, prop := range properties {
+			epcs = append(epcs, prop.EPC)
+		}
+		return epcs
+	}
+	return setEPCsToValidate(device, properties, skipValidationEPCs)
 }
 
 // validateEPCsInPropertyMap は、指定されたEPCがプロパティマップに含まれているかを確認する
@@ -1138,11 +1442,11 @@ func (h *CommunicationHandler) sendAnnouncementForChangedProperties(eoj EOJ, pro
 	// アナウンス対象のプロパティがある場合、INF通知を送信
 	if len(announcementProps) > 0 {
 		if h.Debug {
-			slog.Debug("アナウンス対象プロパティの変更を通知", "SEOJ", eoj, "Properties", announcementProps)
+			h.log().Debug("アナウンス対象プロパティの変更を通知", "SEOJ", eoj, "Properties", announcementProps)
 		}
 		err := h.session.Broadcast(eoj, echonet_lite.ESVINF, announcementProps)
 		if err != nil {
-			slog.Error("INF通知の送信に失敗", "err", err)
+			h.log().Error("INF通知の送信に失敗", "err", err)
 		}
 	}
 }
@@ -1192,7 +1496,7 @@ func (h *CommunicationHandler) processInstanceListForOfflineRecovery(ip net.IP,
 		// NodeProfileが有効なデバイスとして報告している場合、
 		// オフライン状態をオンラインに復帰
 		if h.dataAccessor.IsOffline(device) {
-			slog.Info("NodeProfileからのインスタンスリストによりデバイスをオンラインに復帰",
+			h.log().Info("NodeProfileからのインスタンスリストによりデバイスをオンラインに復帰",
 				"device", device.Specifier())
 			h.dataAccessor.SetOffline(device, false)
 		}
@@ -1223,10 +1527,20 @@ func (h *CommunicationHandler) migrateDevicesFromOldIP(device IPAndEOJ, idEDT []
 			continue
 		}
 
+		// デュアルスタックで同じノードが IPv4 と IPv6 の両方から見つかった場合は、優先する側のみを残す
+		if h.preferIPVersion != "" && (oldIP.To4() == nil) != (device.IP.To4() == nil) {
+			if h.preferIPVersion.Matches(device.IP) {
+				h.dropDuplicateAddress(oldIP, device.IP)
+			} else {
+				h.dropDuplicateAddress(device.IP, oldIP)
+			}
+			continue
+		}
+
 		// 旧IPの NodeProfile がオフラインの場合のみ削除（オンラインなら別の物理ノードの可能性）
 		oldNodeProfile := IPAndEOJ{IP: oldIP, EOJ: echonet_lite.NodeProfileObject}
 		if !h.dataAccessor.IsOffline(oldNodeProfile) {
-			slog.Info("旧IPのNodeProfileはオンラインのためマイグレーションをスキップ",
+			h.log().Info("旧IPのNodeProfileはオンラインのためマイグレーションをスキップ",
 				"oldIP", oldIPStr, "newIP", currentIP)
 			continue
 		}
@@ -1234,7 +1548,7 @@ func (h *CommunicationHandler) migrateDevicesFromOldIP(device IPAndEOJ, idEDT []
 		// 旧IPの全デバイスを削除
 		removed := h.dataAccessor.RemoveAllDevicesByIP(oldIP)
 		if len(removed) > 0 {
-			slog.Info("IPアドレス変更によるデバイスマイグレーション: 旧IPのデバイスを削除",
+			h.log().Info("IPアドレス変更によるデバイスマイグレーション: 旧IPのデバイスを削除",
 				"oldIP", oldIPStr, "newIP", currentIP, "removedCount", len(removed))
 		}
 	}
@@ -52,8 +52,9 @@ func (m *MockDataAccessorForInstanceList) Filter(criteria FilterCriteria) Device
 	return args.Get(0).(Devices)
 }
 
-func (m *MockDataAccessorForInstanceList) SaveDeviceInfo() {
+func (m *MockDataAccessorForInstanceList) SaveDeviceInfo() error {
 	m.Called()
+	return nil
 }
 
 func (m *MockDataAccessorForInstanceList) GetPropertyMap(device IPAndEOJ, mapType PropertyMapType) PropertyMap {
@@ -115,6 +116,10 @@ func (m *MockDataAccessorForInstanceList) RemoveAllDevicesByIP(ip net.IP) []IPAn
 	return args.Get(0).([]IPAndEOJ)
 }
 
+func (m *MockDataAccessorForInstanceList) ReassignDeviceID(from, to IDString) ([]AliasChange, []GroupChange) {
+	return nil, nil
+}
+
 // processInstanceListFromPropertyWithoutSession is a test helper for processInstanceListFromProperty that doesn't need a session
 func processInstanceListFromPropertyWithoutSession(h *CommunicationHandler, device IPAndEOJ, property Property) error {
 	// プロパティからInstanceListを抽出
@@ -673,3 +678,95 @@ func TestProcessPropertyUpdateHooks_NodeProfileNonInstanceList(t *testing.T) {
 	// No mock expectations needed as nothing should be called
 	mockDataAccessor.AssertExpectations(t)
 }
+
+// recordingNotificationRelay は中継されたデバイスイベントを記録する
+type recordingNotificationRelay struct {
+	MockNotificationRelay
+	events []DeviceEvent
+}
+
+func (r *recordingNotificationRelay) RelayDeviceEvent(event DeviceEvent) {
+	r.events = append(r.events, event)
+}
+
+// TestReconcileInstanceList_Renumbered は、リセットでインスタンス番号が振り直されたときに
+// エイリアスなどが新しいインスタンスに引き継がれることを確認する
+func TestReconcileInstanceList_Renumbered(t *testing.T) {
+	ip := net.ParseIP("192.168.1.50")
+	npo := IPAndEOJ{IP: ip, EOJ: echonet_lite.NodeProfileObject}
+	oldLight := IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)}
+	newLight := IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 2)}
+	oldAircon := IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+
+	devices := NewDevices()
+	idEDT := append([]byte{0xFE, 0x00, 0x00, 0x05}, make([]byte, 13)...)
+	devices.RegisterProperty(npo, Property{EPC: echonet_lite.EPC_NPO_IDNumber, EDT: idEDT}, time.Now())
+	devices.RegisterDevice(oldLight)
+	devices.RegisterDevice(oldAircon)
+
+	data := NewDataManagementHandler(devices, NewDeviceAliases(), NewDeviceGroups(), NewLocationSettings(), nil, nil, nil)
+	data.SetInMemory(true)
+	data.SetMetadata(NewDeviceMetadataStore(), "")
+	oldID := data.GetIDString(oldLight)
+	newID := data.GetIDString(newLight)
+	assert.NotEmpty(t, oldID)
+	assert.NoError(t, data.DeviceAliases.Register("living", oldID))
+	assert.NoError(t, data.DeviceGroups.GroupAdd("@lights", []IDString{"other", oldID}))
+	assert.NoError(t, data.Metadata.Set(oldID, DeviceMetadata{Notes: "ceiling"}))
+	assert.NoError(t, data.DeviceScenes.SceneAdd("night", []SceneAction{{Device: oldID, EPC: 0x80, EDT: []byte{0x31}}}))
+
+	notifier := &recordingNotificationRelay{}
+	h := &CommunicationHandler{dataAccessor: data, notifier: notifier}
+
+	// 照明は 1 から 2 に振り直され、エアコンは消えた
+	il := h.reconcileInstanceList(ip, echonet_lite.InstanceList{newLight.EOJ})
+	assert.Len(t, il, 2, "the node profile is added")
+	assert.False(t, data.IsKnownDevice(oldLight))
+	assert.False(t, data.IsKnownDevice(oldAircon))
+	assert.True(t, data.IsKnownDevice(newLight))
+
+	if assert.Len(t, notifier.events, 1) {
+		event := notifier.events[0]
+		assert.Equal(t, DeviceEventReconfigured, event.Type)
+		assert.Equal(t, newLight.Key(), event.Device.Key())
+		if assert.NotNil(t, event.Reconfiguration) {
+			assert.Equal(t, oldLight.Key(), event.Reconfiguration.Previous.Key())
+			assert.Equal(t, oldID, event.Reconfiguration.PreviousID)
+			assert.Equal(t, newID, event.Reconfiguration.ID)
+			assert.Equal(t, []AliasChange{{Type: FileChangeUpdated, Alias: "living", ID: newID}}, event.Reconfiguration.Aliases)
+			assert.Equal(t, []GroupChange{{Type: FileChangeUpdated, Group: "@lights", Devices: []IDString{"other", newID}}}, event.Reconfiguration.Groups)
+		}
+	}
+
+	id, _ := data.DeviceAliases.FindByAlias("living")
+	assert.Equal(t, newID, id)
+	_, ok := data.Metadata.Get(oldID)
+	assert.False(t, ok)
+	metadata, _ := data.Metadata.Get(newID)
+	assert.Equal(t, "ceiling", metadata.Notes)
+	actions, _ := data.GetScene("night")
+	assert.Equal(t, newID, actions[0].Device)
+}
+
+func TestPairReassignedInstances(t *testing.T) {
+	ip := net.ParseIP("192.168.1.51")
+	dev := func(class echonet_lite.EOJClassCode, instance int) IPAndEOJ {
+		return IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(class, echonet_lite.EOJInstanceCode(instance))}
+	}
+	light := echonet_lite.SingleFunctionLighting_ClassCode
+	removed := []IPAndEOJ{dev(light, 2), dev(light, 1), dev(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	added := []IPAndEOJ{dev(light, 4), dev(light, 3), dev(light, 5)}
+
+	pairs := pairReassignedInstances(removed, added)
+	// 同じクラスの中でインスタンス番号の順に対応付け、余ったものは含めない
+	want := []instanceReassignment{
+		{Previous: dev(light, 1), Device: dev(light, 3)},
+		{Previous: dev(light, 2), Device: dev(light, 4)},
+	}
+	if assert.Len(t, pairs, len(want)) {
+		for i := range want {
+			assert.Equal(t, want[i].Previous.Key(), pairs[i].Previous.Key())
+			assert.Equal(t, want[i].Device.Key(), pairs[i].Device.Key())
+		}
+	}
+}
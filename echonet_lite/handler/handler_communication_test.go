@@ -58,7 +58,7 @@ func (m *MockSession) OnReceive(handler func(net.IP, *echonet_lite.ECHONETLiteMe
 // MockDataAccessor はテスト用のDataAccessor実装
 type MockDataAccessor struct{}
 
-func (m *MockDataAccessor) SaveDeviceInfo()                    {}
+func (m *MockDataAccessor) SaveDeviceInfo() error              { return nil }
 func (m *MockDataAccessor) IsKnownDevice(device IPAndEOJ) bool { return false }
 func (m *MockDataAccessor) HasEPCInPropertyMap(device IPAndEOJ, mapType PropertyMapType, epc EPCType) bool {
 	return false
@@ -86,6 +86,9 @@ func (m *MockDataAccessor) FindIPsWithSameNodeProfileID(idEDT []byte, excludeIP
 	return nil
 }
 func (m *MockDataAccessor) RemoveAllDevicesByIP(ip net.IP) []IPAndEOJ { return nil }
+func (m *MockDataAccessor) ReassignDeviceID(from, to IDString) ([]AliasChange, []GroupChange) {
+	return nil, nil
+}
 
 // MockNotificationRelay はテスト用のNotificationRelay実装
 type MockNotificationRelay struct{}
@@ -263,3 +266,39 @@ func TestCalculateRequestDelayDistribution(t *testing.T) {
 
 	t.Logf("Delay distribution: min=%v, avg=%v, max=%v", minDelay, avgDelay, maxDelay)
 }
+
+func TestSetEPCsToValidate(t *testing.T) {
+	light := IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)}
+	evCharger := IPAndEOJ{IP: net.ParseIP("192.168.1.11"), EOJ: echonet_lite.MakeEOJ(echonet_lite.EVChargerDischarger_ClassCode, 1)}
+	properties := Properties{{EPC: 0x80, EDT: []byte{0x30}}, {EPC: 0xCD, EDT: []byte{0x10}}}
+
+	tests := []struct {
+		name   string
+		device IPAndEOJ
+		skip   []EPCType
+		want   []EPCType
+	}{
+		{"すべて確認する", light, nil, []EPCType{0x80, 0xCD}},
+		{"指定したEPCは確認しない", light, []EPCType{0xCD}, []EPCType{0x80}},
+		{"書き込み専用プロパティは確認しない", evCharger, nil, []EPCType{0x80}},
+		{"すべて確認しない", evCharger, []EPCType{0x80}, []EPCType{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := setEPCsToValidate(tt.device, properties, tt.skip)
+			if len(got) != len(tt.want) {
+				t.Fatalf("setEPCsToValidate() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("setEPCsToValidate() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+	// Strict の場合は、指定したEPCも書き込み専用プロパティも確認する
+	strict := &CommunicationHandler{session: &Session{Strict: true}}
+	if got := strict.setEPCsToValidate(evCharger, properties, []EPCType{0x80}); len(got) != 2 {
+		t.Errorf("Strict の setEPCsToValidate() = %v, want [80 CD]", got)
+	}
+}
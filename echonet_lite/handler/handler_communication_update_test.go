@@ -18,8 +18,9 @@ type MockDataAccessorForUpdate struct {
 	deviceStrings    map[string]string
 }
 
-func (m *MockDataAccessorForUpdate) SaveDeviceInfo() {
+func (m *MockDataAccessorForUpdate) SaveDeviceInfo() error {
 	// Mock implementation
+	return nil
 }
 
 func (m *MockDataAccessorForUpdate) IsKnownDevice(device IPAndEOJ) bool {
@@ -106,6 +107,10 @@ func (m *MockDataAccessorForUpdate) RemoveAllDevicesByIP(ip net.IP) []IPAndEOJ {
 	return nil // Mock implementation
 }
 
+func (m *MockDataAccessorForUpdate) ReassignDeviceID(from, to IDString) ([]AliasChange, []GroupChange) {
+	return nil, nil
+}
+
 // TestIsNodeProfileOnline は、isNodeProfileOnlineメソッドのテストです。
 // このテストは、実装を追加する前に失敗し、実装後に成功することを確認します。
 func TestIsNodeProfileOnline(t *testing.T) {
@@ -5,12 +5,21 @@ import (
 )
 
 const (
-	DeviceFileName        = "devices.json"
-	DeviceAliasesFileName = "aliases.json"
-	DeviceGroupsFileName  = "groups.json"
+	DeviceFileName            = "devices.json"
+	DeviceAliasesFileName     = "aliases.json"
+	DeviceGroupsFileName      = "groups.json"
+	DeviceScenesFileName      = "scenes.json"
+	SchedulesFileName         = "schedules.json"
+	MetadataFileName          = "device_metadata.json"
+	PreferencesFileName       = "client_preferences.json"
+	LocalPropertyMapsFileName = "local_property_maps.json"
+
+	PropertyChangeStatsFileName = "property_stats.json" // プロパティ変化統計の保存先
+	HistoryJournalFileName      = "history.jsonl"       // 履歴ジャーナルのデフォルトの保存先
 
 	UpdateIntervalThreshold = 5 * time.Second  // プロパティ更新をスキップする閾値
 	MaxUpdateAge            = 10 * time.Minute // IP更新の最大有効期間
+	FileWatchInterval       = 2 * time.Second  // エイリアス・グループファイルの外部編集を確認する間隔
 )
 
 // NotificationType は通知の種類を表す型
@@ -22,13 +31,25 @@ const (
 	DeviceTimeout
 	DeviceOffline
 	DeviceOnline
+	DeviceReconfigured
 )
 
 // DeviceNotification はデバイスに関する通知を表す構造体
 type DeviceNotification struct {
-	Device IPAndEOJ
-	Type   NotificationType
-	Error  error // タイムアウトの場合はエラー情報
+	Device          IPAndEOJ
+	Type            NotificationType
+	Error           error                  // タイムアウトの場合はエラー情報
+	Reconfiguration *DeviceReconfiguration // DeviceReconfigured の場合の引き継ぎの内容
+}
+
+// DeviceReconfiguration は、リセットなどでノードがインスタンス番号を振り直したときに、
+// 消えたインスタンスから新しいインスタンスへ引き継いだ内容を表す構造体
+type DeviceReconfiguration struct {
+	Previous   IPAndEOJ      // 消えたインスタンス
+	PreviousID IDString      // 消えたインスタンスの IDString（識別番号が不明な場合は空）
+	ID         IDString      // 新しいインスタンスの IDString（識別番号が不明な場合は空）
+	Aliases    []AliasChange // 付け替えたエイリアス
+	Groups     []GroupChange // 付け替えたグループ
 }
 
 // PropertyChangeNotification はプロパティ変化に関する通知を表す構造体
@@ -42,3 +63,10 @@ type DeviceAndProperties struct {
 	Device     IPAndEOJ
 	Properties Properties
 }
+
+// SetGetResult は、SetGet（書き込みと読み出しを1フレームで行う要求）の結果を表す構造体
+type SetGetResult struct {
+	Device IPAndEOJ
+	Set    Properties // 書き込みに成功したプロパティ
+	Get    Properties // 読み出したプロパティ
+}
@@ -15,20 +15,25 @@ type OfflineChecker interface {
 
 // HandlerCore は、ECHONETLiteHandlerのコア機能を担当する構造体
 type HandlerCore struct {
-	ctx                     context.Context                 // コンテキスト
-	cancel                  context.CancelFunc              // コンテキストのキャンセル関数
-	NotificationCh          chan DeviceNotification         // デバイス通知用チャネル（内部用）
-	PropertyChangeCh        chan PropertyChangeNotification // プロパティ変化通知用チャネル
-	Debug                   bool                            // デバッグモード
-	OperationTracker        *OperationTracker               // 操作追跡システム
-	notificationSubscribers []chan DeviceNotification       // 通知購読者のリスト
-	subscribersMutex        sync.RWMutex                    // 購読者リストの保護
-	fanoutWg                sync.WaitGroup                  // fanoutNotifications()の終了待機用
-	offlineChecker          OfflineChecker                  // オフラインチェッカー
+	ctx                     context.Context                              // コンテキスト
+	cancel                  context.CancelFunc                           // コンテキストのキャンセル関数
+	NotificationCh          chan DeviceNotification                      // デバイス通知用チャネル（内部用）
+	PropertyChangeCh        chan PropertyChangeNotification              // プロパティ変化通知用チャネル
+	Debug                   bool                                         // デバッグモード
+	OperationTracker        *OperationTracker                            // 操作追跡システム
+	notificationSubscribers []chan DeviceNotification                    // 通知購読者のリスト
+	subscribersMutex        sync.RWMutex                                 // 購読者リストの保護
+	propertyChangeWatchers  map[chan PropertyChangeNotification]struct{} // プロパティ変化の購読者（subscribersMutex で保護）
+	fanoutWg                sync.WaitGroup                               // fanoutNotifications()の終了待機用
+	relayWg                 sync.WaitGroup                               // StartEventRelayLoop()のゴルーチンの終了待機用
+	notifyMutex             sync.RWMutex                                 // notify() と NotificationCh のクローズの排他
+	notifyClosed            bool                                         // NotificationCh を閉じたかどうか（notifyMutex で保護）
+	offlineChecker          OfflineChecker                               // オフラインチェッカー
+	logger                  *slog.Logger                                 // ログ出力先
 }
 
 // NewHandlerCore は、HandlerCoreの新しいインスタンスを作成する
-func NewHandlerCore(ctx context.Context, cancel context.CancelFunc, debug bool) *HandlerCore {
+func NewHandlerCore(ctx context.Context, cancel context.CancelFunc, debug bool, logger *slog.Logger) *HandlerCore {
 	// 通知チャンネルを作成
 	notificationCh := make(chan DeviceNotification, 100)            // バッファサイズは100に設定
 	propertyChangeCh := make(chan PropertyChangeNotification, 2000) // バッファサイズは2000に設定
@@ -45,6 +50,8 @@ func NewHandlerCore(ctx context.Context, cancel context.CancelFunc, debug bool)
 		Debug:                   debug,
 		OperationTracker:        operationTracker,
 		notificationSubscribers: make([]chan DeviceNotification, 0),
+		propertyChangeWatchers:  make(map[chan PropertyChangeNotification]struct{}),
+		logger:                  loggerOrDefault(logger),
 	}
 
 	// ファンアウト処理を開始
@@ -61,15 +68,22 @@ func (c *HandlerCore) Close() error {
 		c.OperationTracker.Stop()
 	}
 
-	// コンテキストをキャンセルしてfanoutNotifications()の終了をシグナル
+	// コンテキストをキャンセルしてイベント中継ループとfanoutNotifications()の終了をシグナル
 	if c.cancel != nil {
 		c.cancel()
 	}
 
+	// イベント中継ループが notify() を呼ばなくなるまで待つ
+	c.relayWg.Wait()
+
 	// 通知チャネルを閉じる（これによりfanoutNotifications()が終了する）
-	if c.NotificationCh != nil {
+	// 中継ループ以外から RelayDeviceEvent() が呼ばれても閉じたチャネルに送信しないよう、notifyMutex の下で閉じる
+	c.notifyMutex.Lock()
+	if c.NotificationCh != nil && !c.notifyClosed {
 		close(c.NotificationCh)
 	}
+	c.notifyClosed = true
+	c.notifyMutex.Unlock()
 
 	// fanoutNotifications()の終了を確実に待機
 	c.fanoutWg.Wait()
@@ -88,13 +102,17 @@ func (c *HandlerCore) Close() error {
 			defer func() {
 				if r := recover(); r != nil {
 					// チャネルが既にcloseされている場合は無視
-					slog.Debug("購読者チャネルは既にcloseされています", "panic", r)
+					c.log().Debug("購読者チャネルは既にcloseされています", "panic", r)
 				}
 			}()
 			close(subscriber)
 		}()
 	}
 	c.notificationSubscribers = nil
+	for watcher := range c.propertyChangeWatchers {
+		close(watcher)
+	}
+	c.propertyChangeWatchers = nil
 	c.subscribersMutex.Unlock()
 
 	return nil
@@ -116,12 +134,18 @@ func (c *HandlerCore) IsDebug() bool {
 }
 
 func (c *HandlerCore) notify(notification DeviceNotification) {
+	c.notifyMutex.RLock()
+	defer c.notifyMutex.RUnlock()
+	if c.notifyClosed {
+		// Close() 後の通知は捨てる
+		return
+	}
 	select {
 	case c.NotificationCh <- notification:
 		// 送信成功
 	default:
 		// チャンネルがブロックされている場合は無視
-		slog.Warn("HandlerCore.notify: 通知チャネルがブロックされています", "notificationType", notification.Type, "device", notification.Device.Specifier())
+		c.log().Warn("HandlerCore.notify: 通知チャネルがブロックされています", "notificationType", notification.Type, "device", notification.Device.Specifier())
 	}
 }
 
@@ -150,8 +174,14 @@ func (c *HandlerCore) RelayDeviceEvent(event DeviceEvent) {
 			Device: event.Device,
 			Type:   DeviceOnline,
 		})
+	case DeviceEventReconfigured:
+		c.notify(DeviceNotification{
+			Device:          event.Device,
+			Type:            DeviceReconfigured,
+			Reconfiguration: event.Reconfiguration,
+		})
 	default:
-		slog.Warn("未知のDeviceEventType", "eventType", event.Type, "device", event.Device.Specifier())
+		c.log().Warn("未知のDeviceEventType", "eventType", event.Type, "device", event.Device.Specifier())
 	}
 }
 
@@ -159,7 +189,7 @@ func (c *HandlerCore) RelayDeviceEvent(event DeviceEvent) {
 func (c *HandlerCore) RelaySessionTimeoutEvent(event SessionTimeoutEvent) {
 	// すでにオフラインなら通知をスキップ（重複通知防止）
 	if c.offlineChecker != nil && c.offlineChecker.IsOffline(event.Device) {
-		slog.Debug("デバイスはすでにオフラインのためタイムアウト通知をスキップ", "device", event.Device.Specifier())
+		c.log().Debug("デバイスはすでにオフラインのためタイムアウト通知をスキップ", "device", event.Device.Specifier())
 		return
 	}
 
@@ -181,13 +211,54 @@ func (c *HandlerCore) RelayPropertyChangeEvent(device IPAndEOJ, property Propert
 		// 送信成功
 	default:
 		// チャンネルがブロックされている場合は無視
-		slog.Warn("プロパティ変化通知チャネルがブロックされています")
+		c.log().Warn("プロパティ変化通知チャネルがブロックされています")
+	}
+
+	// 購読者への配信は、受信が遅い購読者がいても他に影響しないように、送れない場合は捨てる
+	c.subscribersMutex.RLock()
+	defer c.subscribersMutex.RUnlock()
+	for watcher := range c.propertyChangeWatchers {
+		select {
+		case watcher <- PropertyChangeNotification{Device: device, Property: property}:
+		default:
+		}
+	}
+}
+
+// WatchPropertyChanges は、PropertyChangeCh とは別にプロパティ変化通知を受信するチャンネルを作成する
+// コンソールの watch コマンドなど、WebSocketサーバーと並行して通知を受け取るために使う
+// 返された関数を呼ぶと購読をやめ、チャンネルを閉じる
+func (c *HandlerCore) WatchPropertyChanges(bufferSize int) (<-chan PropertyChangeNotification, func()) {
+	ch := make(chan PropertyChangeNotification, bufferSize)
+	c.subscribersMutex.Lock()
+	if c.propertyChangeWatchers == nil {
+		// Close 済み
+		c.subscribersMutex.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	c.propertyChangeWatchers[ch] = struct{}{}
+	c.subscribersMutex.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			c.subscribersMutex.Lock()
+			defer c.subscribersMutex.Unlock()
+			if _, ok := c.propertyChangeWatchers[ch]; ok {
+				delete(c.propertyChangeWatchers, ch)
+				close(ch)
+			}
+		})
 	}
 }
 
 // StartEventRelayLoop は、デバイスイベントとセッションタイムアウトイベントを通知チャンネルに中継するゴルーチンを起動する
+// Close() はこのゴルーチンの終了を待ってから通知チャンネルを閉じる
 func (c *HandlerCore) StartEventRelayLoop(deviceEventCh <-chan DeviceEvent, sessionTimeoutCh <-chan SessionTimeoutEvent) {
+	c.relayWg.Add(1)
 	go func() {
+		defer c.relayWg.Done()
 		for {
 			select {
 			case event, ok := <-deviceEventCh:
@@ -210,10 +281,10 @@ func (c *HandlerCore) StartEventRelayLoop(deviceEventCh <-chan DeviceEvent, sess
 	}()
 }
 
-// DebugLog は、デバッグモードが有効な場合にメッセージを出力する
+// DebugLog は、デバッグモードが有効な場合にメッセージを Debug レベルでログに出力する
 func (c *HandlerCore) DebugLog(format string, args ...interface{}) {
 	if c.Debug {
-		fmt.Printf(format+"\n", args...)
+		c.log().Debug(fmt.Sprintf(format, args...))
 	}
 }
 
@@ -255,7 +326,7 @@ func (c *HandlerCore) fanoutNotifications() {
 					// 送信成功
 				default:
 					// バッファがフルの購読者は切断対象
-					slog.Warn("通知購読者のバッファがフルのため切断します", "notificationType", notification.Type, "device", notification.Device.Specifier())
+					c.log().Warn("通知購読者のバッファがフルのため切断します", "notificationType", notification.Type, "device", notification.Device.Specifier())
 					close(subscriber)
 					staleSubscribers[subscriber] = struct{}{}
 				}
@@ -14,7 +14,7 @@ func TestFanoutNotifications_DisconnectsFullBufferSubscribers(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	core := NewHandlerCore(ctx, cancel, false)
+	core := NewHandlerCore(ctx, cancel, false, nil)
 	defer core.Close()
 
 	// バッファサイズ1の購読者を2つ作成
@@ -90,7 +90,7 @@ func TestFanoutNotifications_ActiveSubscribersReceiveNotifications(t *testing.T)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	core := NewHandlerCore(ctx, cancel, false)
+	core := NewHandlerCore(ctx, cancel, false, nil)
 	defer core.Close()
 
 	// バッファサイズ10の購読者を作成
@@ -127,7 +127,7 @@ func TestSubscribeNotifications_MultipleSubscribers(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	core := NewHandlerCore(ctx, cancel, false)
+	core := NewHandlerCore(ctx, cancel, false, nil)
 	defer core.Close()
 
 	// 3つの購読者を作成
@@ -188,7 +188,7 @@ func TestRelaySessionTimeoutEvent_SkipsOfflineDevices(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	core := NewHandlerCore(ctx, cancel, false)
+	core := NewHandlerCore(ctx, cancel, false, nil)
 	defer core.Close()
 
 	// モックオフラインチェッカーを設定
@@ -239,7 +239,7 @@ func TestRelaySessionTimeoutEvent_WorksWithoutOfflineChecker(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	core := NewHandlerCore(ctx, cancel, false)
+	core := NewHandlerCore(ctx, cancel, false, nil)
 	defer core.Close()
 
 	// オフラインチェッカーを設定しない（nil）
@@ -272,7 +272,7 @@ func TestRelaySessionTimeoutEvent_ConcurrentAccess(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	core := NewHandlerCore(ctx, cancel, false)
+	core := NewHandlerCore(ctx, cancel, false, nil)
 	defer core.Close()
 
 	checker := newMockOfflineChecker()
@@ -328,3 +328,37 @@ func TestRelaySessionTimeoutEvent_ConcurrentAccess(t *testing.T) {
 		}
 	}
 }
+
+func TestWatchPropertyChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	core := NewHandlerCore(ctx, cancel, false, nil)
+	defer core.Close()
+
+	watcher, stop := core.WatchPropertyChanges(1)
+	testDevice := IPAndEOJ{
+		IP:  net.ParseIP("192.168.0.1"),
+		EOJ: echonet_lite.MakeEOJ(0x0130, 1),
+	}
+
+	// PropertyChangeCh と購読者の両方に届く
+	core.RelayPropertyChangeEvent(testDevice, Property{EPC: 0x80, EDT: []byte{0x30}})
+	if n := <-core.PropertyChangeCh; n.Property.EPC != 0x80 {
+		t.Errorf("PropertyChangeCh の通知が不正: %v", n)
+	}
+
+	// 読まない購読者のバッファがいっぱいでもブロックしない
+	core.RelayPropertyChangeEvent(testDevice, Property{EPC: 0xB0, EDT: []byte{0x41}})
+	if n := <-watcher; n.Property.EPC != 0x80 {
+		t.Errorf("購読者の通知が不正: %v", n)
+	}
+
+	// 購読をやめるとチャンネルが閉じられる（2回呼んでもよい）
+	stop()
+	stop()
+	if _, ok := <-watcher; ok {
+		t.Error("購読をやめた後にチャンネルが閉じられていない")
+	}
+	core.RelayPropertyChangeEvent(testDevice, Property{EPC: 0x80, EDT: []byte{0x31}})
+}
@@ -17,22 +17,46 @@ type DataManagementHandler struct {
 	devices          Devices                     // デバイス情報
 	DeviceAliases    *DeviceAliases              // デバイスエイリアス
 	DeviceGroups     *DeviceGroups               // デバイスグループ
+	DeviceScenes     *DeviceScenes               // シーン
+	scenesFilePath   string                      // シーンファイルパス（空文字の場合は保存しない）
+	Schedules        *DeviceSchedules            // スケジュール
+	schedulesPath    string                      // スケジュールファイルパス（空文字の場合は保存しない）
+	Metadata         *DeviceMetadataStore        // デバイスのメモやラベル
+	metadataPath     string                      // メタデータファイルパス（空文字の場合は保存しない）
+	Preferences      *ClientPreferencesStore     // クライアントの表示設定
+	preferencesPath  string                      // 表示設定ファイルパス（空文字の場合は保存しない）
+	inMemory         bool                        // true の場合はデバイス・エイリアス・グループ・ロケーション設定をファイルに保存しない
 	LocationSettings *LocationSettings           // ロケーション設定
 	DeviceHistory    DeviceHistoryStore          // デバイス履歴
+	ChangeStats      *PropertyChangeStats        // プロパティ変化頻度の統計
 	propMutex        sync.RWMutex                // プロパティの排他制御用ミューテックス
 	notifier         NotificationRelay           // 通知中継
 	hookProcessor    PropertyUpdateHookProcessor // プロパティ更新後処理
+	logger           *slog.Logger                // ログ出力先
+
+	// 外部からの編集を検出するエイリアス・グループファイル（nil の場合は検出せず、デフォルトのファイルに保存する）
+	aliasesFile *watchedFile
+	groupsFile  *watchedFile
+	reloadCh    chan<- ReloadNotification // 外部で編集されたファイルを読み込み直したときの通知先
+
+	devicesFilePath string            // デバイスファイルパス（空文字の場合はデフォルトのファイル）
+	integrity       *IntegrityChecker // 保存ファイルの整合性チェック（nil の場合はチェックしない）
+	saver           deviceInfoSaver   // デバイス情報の保存と失敗時の再試行
 }
 
 // NewDataManagementHandler は、DataManagementHandlerの新しいインスタンスを作成する
-func NewDataManagementHandler(devices Devices, aliases *DeviceAliases, groups *DeviceGroups, locationSettings *LocationSettings, history DeviceHistoryStore, notifier NotificationRelay) *DataManagementHandler {
+func NewDataManagementHandler(devices Devices, aliases *DeviceAliases, groups *DeviceGroups, locationSettings *LocationSettings, history DeviceHistoryStore, notifier NotificationRelay, logger *slog.Logger) *DataManagementHandler {
 	return &DataManagementHandler{
 		devices:          devices,
 		DeviceAliases:    aliases,
 		DeviceGroups:     groups,
+		DeviceScenes:     NewDeviceScenes(),
+		Schedules:        NewDeviceSchedules(),
 		LocationSettings: locationSettings,
 		DeviceHistory:    history,
+		ChangeStats:      NewPropertyChangeStats(),
 		notifier:         notifier,
+		logger:           loggerOrDefault(logger),
 	}
 }
 
@@ -41,12 +65,19 @@ func (h *DataManagementHandler) SetHookProcessor(processor PropertyUpdateHookPro
 	h.hookProcessor = processor
 }
 
-// SaveDeviceInfo は、デバイス情報をファイルに保存する
-func (h *DataManagementHandler) SaveDeviceInfo() {
-	if err := h.devices.SaveToFile(DeviceFileName); err != nil {
-		slog.Warn("デバイス情報の保存に失敗しました", "err", err)
-		// 保存に失敗しても処理は継続
-	}
+// SetInMemory は、デバイス・エイリアス・グループ・ロケーション設定をファイルに保存しないようにする（デモモード用）
+func (h *DataManagementHandler) SetInMemory(inMemory bool) {
+	h.inMemory = inMemory
+}
+
+// SetDevicesFile は、デバイス情報の保存先を設定する
+func (h *DataManagementHandler) SetDevicesFile(filename string) {
+	h.devicesFilePath = filename
+}
+
+// SetIntegrityChecker は、保存したファイルのチェックサムを記録する IntegrityChecker を設定する
+func (h *DataManagementHandler) SetIntegrityChecker(checker *IntegrityChecker) {
+	h.integrity = checker
 }
 
 // detectAndRegisterPropertyChanges は、プロパティの変更を検出し、登録と通知を行う
@@ -79,12 +110,15 @@ func (h *DataManagementHandler) detectAndRegisterPropertyChanges(device IPAndEOJ
 	}
 
 	if len(changedProperties) > 0 {
+		for _, p := range changedProperties {
+			h.ChangeStats.Record(device, p.EPC)
+		}
 		classCode := device.EOJ.ClassCode()
 		changes := make([]string, len(changedProperties))
 		for i, p := range changedProperties {
 			changes[i] = p.StringForClass(classCode)
 		}
-		slog.Info("プロパティ更新", "device", h.DeviceStringWithAlias(device), "count", len(changedProperties), "changes", strings.Join(changes, ", "))
+		h.log().Info("プロパティ更新", "device", h.DeviceStringWithAlias(device), "count", len(changedProperties), "changes", strings.Join(changes, ", "))
 	}
 
 	// デバイスのプロパティを登録（propMutexのロックなしで呼び出し）
@@ -110,7 +144,7 @@ func (h *DataManagementHandler) RegisterProperties(device IPAndEOJ, properties P
 	// プロパティ更新後の追加処理を実行
 	if h.hookProcessor != nil {
 		if err := h.hookProcessor.ProcessPropertyUpdateHooks(device, properties); err != nil {
-			slog.Warn("プロパティ更新後の追加処理でエラー", "device", device, "err", err)
+			h.log().Warn("プロパティ更新後の追加処理でエラー", "device", device, "err", err)
 		}
 	}
 
@@ -131,9 +165,9 @@ func (h *DataManagementHandler) ListDevices(criteria FilterCriteria) []DeviceAnd
 
 	// フィルタリングが異常に遅い場合のみログ出力
 	if filterDuration > errorThreshold {
-		slog.Error("ListDevices: Filter operation took too long", "duration", filterDuration, "criteria", criteria.String())
+		h.log().Error("ListDevices: Filter operation took too long", "duration", filterDuration, "criteria", criteria.String())
 	} else if filterDuration > warnThreshold {
-		slog.Warn("ListDevices: Filter operation is slow", "duration", filterDuration, "criteria", criteria.String())
+		h.log().Warn("ListDevices: Filter operation is slow", "duration", filterDuration, "criteria", criteria.String())
 	}
 
 	// デバイスプロパティデータの取得
@@ -143,9 +177,9 @@ func (h *DataManagementHandler) ListDevices(criteria FilterCriteria) []DeviceAnd
 
 	// ListDevicePropertyDataが異常に遅い場合のみログ出力
 	if listDuration > errorThreshold {
-		slog.Error("ListDevices: ListDevicePropertyData took too long", "duration", listDuration, "deviceCount", len(temp))
+		h.log().Error("ListDevices: ListDevicePropertyData took too long", "duration", listDuration, "deviceCount", len(temp))
 	} else if listDuration > warnThreshold {
-		slog.Warn("ListDevices: ListDevicePropertyData is slow", "duration", listDuration, "deviceCount", len(temp))
+		h.log().Warn("ListDevices: ListDevicePropertyData is slow", "duration", listDuration, "deviceCount", len(temp))
 	}
 
 	// 結果の変換
@@ -166,14 +200,14 @@ func (h *DataManagementHandler) ListDevices(criteria FilterCriteria) []DeviceAnd
 
 	// 全体の処理時間が異常に長い場合のみログ出力
 	if totalDuration > errorThreshold {
-		slog.Error("ListDevices: Operation took too long",
+		h.log().Error("ListDevices: Operation took too long",
 			"totalDuration", totalDuration,
 			"filterDuration", filterDuration,
 			"listDuration", listDuration,
 			"convertDuration", convertDuration,
 			"resultCount", len(result))
 	} else if totalDuration > warnThreshold {
-		slog.Warn("ListDevices: Operation is slow",
+		h.log().Warn("ListDevices: Operation is slow",
 			"totalDuration", totalDuration,
 			"filterDuration", filterDuration,
 			"listDuration", listDuration,
@@ -184,9 +218,126 @@ func (h *DataManagementHandler) ListDevices(criteria FilterCriteria) []DeviceAnd
 	return result
 }
 
+// SetWatchedFiles は、外部からの編集を検出するエイリアス・グループファイルを設定する
+// 読み込み直した結果は reloadCh に通知する（nil の場合は通知しない）
+func (h *DataManagementHandler) SetWatchedFiles(aliasesFile, groupsFile string, reloadCh chan<- ReloadNotification) {
+	h.aliasesFile = newWatchedFile(aliasesFile)
+	h.groupsFile = newWatchedFile(groupsFile)
+	h.reloadCh = reloadCh
+}
+
+// ReloadChangedFiles は、外部で編集されたエイリアス・グループファイルを読み込み直す
+func (h *DataManagementHandler) ReloadChangedFiles() {
+	_ = h.reloadAliasFile()
+	_ = h.reloadGroupFile()
+}
+
+// reloadAliasFile は、エイリアスファイルが外部で編集されていれば読み込み直し、変更を通知する
+// 編集された内容を読み込めない場合は FileConflictError を返す
+func (h *DataManagementHandler) reloadAliasFile() error {
+	w := h.aliasesFile
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	snapshot, changed := w.check()
+	if changed {
+		aliases, err := decodeAliases(snapshot.data)
+		if err != nil {
+			h.log().Warn("外部で編集されたエイリアスファイルを読み込めません。修正されるまでエイリアスの変更は保存しません", "file", w.path, "error", err)
+			w.reject(snapshot, err)
+		} else {
+			w.accept(snapshot)
+			changes := h.DeviceAliases.replace(aliases)
+			h.log().Info("外部で編集されたエイリアスファイルを読み込み直しました", "file", w.path, "changes", len(changes))
+			if len(changes) > 0 {
+				h.notifyReload(ReloadNotification{File: w.path, Aliases: changes})
+			}
+		}
+	}
+	if w.rejected != nil {
+		return &FileConflictError{Path: w.path, Err: w.rejected}
+	}
+	return nil
+}
+
+// reloadGroupFile は、グループファイルが外部で編集されていれば読み込み直し、変更を通知する
+// 編集された内容を読み込めない場合は FileConflictError を返す
+func (h *DataManagementHandler) reloadGroupFile() error {
+	w := h.groupsFile
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	snapshot, changed := w.check()
+	if changed {
+		groups, err := decodeGroups(snapshot.data)
+		if err != nil {
+			h.log().Warn("外部で編集されたグループファイルを読み込めません。修正されるまでグループの変更は保存しません", "file", w.path, "error", err)
+			w.reject(snapshot, err)
+		} else {
+			w.accept(snapshot)
+			changes := h.DeviceGroups.replace(groups)
+			h.log().Info("外部で編集されたグループファイルを読み込み直しました", "file", w.path, "changes", len(changes))
+			if len(changes) > 0 {
+				h.notifyReload(ReloadNotification{File: w.path, Groups: changes})
+			}
+		}
+	}
+	if w.rejected != nil {
+		return &FileConflictError{Path: w.path, Err: w.rejected}
+	}
+	return nil
+}
+
+// notifyReload は、読み込み直した結果を通知する。通知先が詰まっている場合は捨てる
+func (h *DataManagementHandler) notifyReload(notification ReloadNotification) {
+	if h.reloadCh == nil {
+		return
+	}
+	select {
+	case h.reloadCh <- notification:
+	default:
+		h.log().Warn("ファイル再読み込みの通知チャネルがブロックされています", "file", notification.File)
+	}
+}
+
+// saveWatchedFile は、外部で編集されていないことを確認してから save でファイルに保存し、保存した内容を記録する
+// 前回の読み込み以降に外部で編集されていた場合は上書きせずに FileConflictError を返す
+func saveWatchedFile(w *watchedFile, save func(filename string) error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.rejected != nil {
+		return &FileConflictError{Path: w.path, Err: w.rejected}
+	}
+	if _, changed := w.check(); changed {
+		return &FileConflictError{Path: w.path, Err: errors.New("the file changed while the update was being applied")}
+	}
+	if err := save(w.path); err != nil {
+		return err
+	}
+	w.record()
+	return nil
+}
+
 // SaveAliasFile は、エイリアス情報をファイルに保存する
 func (h *DataManagementHandler) SaveAliasFile() error {
-	err := h.DeviceAliases.SaveToFile(DeviceAliasesFileName)
+	if h.inMemory {
+		return nil
+	}
+	var err error
+	if h.aliasesFile != nil {
+		err = h.integrity.Save(h.aliasesFile.path, func() error {
+			return saveWatchedFile(h.aliasesFile, h.DeviceAliases.SaveToFile)
+		})
+	} else {
+		err = h.DeviceAliases.SaveToFile(DeviceAliasesFileName)
+	}
 	if err != nil {
 		return fmt.Errorf("エイリアス情報の保存に失敗しました: %w", err)
 	}
@@ -243,6 +394,10 @@ func (h *DataManagementHandler) AliasSet(alias *string, criteria FilterCriteria)
 		return fmt.Errorf("デバイスのIDが見つかりません: %v", found)
 	}
 
+	// 外部での編集を先に取り込み、その上に変更を適用する
+	if err := h.reloadAliasFile(); err != nil {
+		return err
+	}
 	err := h.DeviceAliases.Register(*alias, ids)
 	if err != nil {
 		return fmt.Errorf("エイリアスを設定できませんでした: %w", err)
@@ -255,6 +410,9 @@ func (h *DataManagementHandler) AliasDelete(alias *string) error {
 	if alias == nil {
 		return errors.New("エイリアス名が指定されていません")
 	}
+	if err := h.reloadAliasFile(); err != nil {
+		return err
+	}
 	if err := h.DeviceAliases.DeleteByAlias(*alias); err != nil {
 		return fmt.Errorf("エイリアス %s の削除に失敗しました: %w", *alias, err)
 	}
@@ -291,7 +449,17 @@ func (h *DataManagementHandler) GetDevices(deviceSpec DeviceSpecifier) []IPAndEO
 
 // SaveGroupFile は、グループ情報をファイルに保存する
 func (h *DataManagementHandler) SaveGroupFile() error {
-	err := h.DeviceGroups.SaveToFile(DeviceGroupsFileName)
+	if h.inMemory {
+		return nil
+	}
+	var err error
+	if h.groupsFile != nil {
+		err = h.integrity.Save(h.groupsFile.path, func() error {
+			return saveWatchedFile(h.groupsFile, h.DeviceGroups.SaveToFile)
+		})
+	} else {
+		err = h.DeviceGroups.SaveToFile(DeviceGroupsFileName)
+	}
 	if err != nil {
 		return fmt.Errorf("グループ情報の保存に失敗しました: %w", err)
 	}
@@ -305,6 +473,9 @@ func (h *DataManagementHandler) GroupList(groupName *string) []GroupDevicePair {
 
 // GroupAdd は、グループにデバイスを追加する
 func (h *DataManagementHandler) GroupAdd(groupName string, devices []IDString) error {
+	if err := h.reloadGroupFile(); err != nil {
+		return err
+	}
 	err := h.DeviceGroups.GroupAdd(groupName, devices)
 	if err != nil {
 		return err
@@ -314,6 +485,9 @@ func (h *DataManagementHandler) GroupAdd(groupName string, devices []IDString) e
 
 // GroupRemove は、グループからデバイスを削除する
 func (h *DataManagementHandler) GroupRemove(groupName string, devices []IDString) error {
+	if err := h.reloadGroupFile(); err != nil {
+		return err
+	}
 	err := h.DeviceGroups.GroupRemove(groupName, devices)
 	if err != nil {
 		return err
@@ -323,6 +497,9 @@ func (h *DataManagementHandler) GroupRemove(groupName string, devices []IDString
 
 // GroupDelete は、グループを削除する
 func (h *DataManagementHandler) GroupDelete(groupName string) error {
+	if err := h.reloadGroupFile(); err != nil {
+		return err
+	}
 	err := h.DeviceGroups.GroupDelete(groupName)
 	if err != nil {
 		return err
@@ -335,6 +512,200 @@ func (h *DataManagementHandler) GetDevicesByGroup(groupName string) ([]IDString,
 	return h.DeviceGroups.GetDevicesByGroup(groupName)
 }
 
+// SetScenes は、シーンと保存先ファイルを設定する
+// filename が空文字の場合、シーンの変更はファイルに保存しない
+func (h *DataManagementHandler) SetScenes(scenes *DeviceScenes, filename string) {
+	h.DeviceScenes = scenes
+	h.scenesFilePath = filename
+}
+
+// SaveSceneFile は、シーン情報をファイルに保存する
+func (h *DataManagementHandler) SaveSceneFile() error {
+	if h.scenesFilePath == "" {
+		return nil
+	}
+	if err := h.DeviceScenes.SaveToFile(h.scenesFilePath); err != nil {
+		return fmt.Errorf("シーン情報の保存に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// SceneList は、シーンのリストを返す
+func (h *DataManagementHandler) SceneList(sceneName *string) []SceneActionsPair {
+	return h.DeviceScenes.SceneList(sceneName)
+}
+
+// SceneAdd は、シーンにアクションを追加する
+func (h *DataManagementHandler) SceneAdd(sceneName string, actions []SceneAction) error {
+	if err := h.DeviceScenes.SceneAdd(sceneName, actions); err != nil {
+		return err
+	}
+	return h.SaveSceneFile()
+}
+
+// SceneDelete は、シーンを削除する
+func (h *DataManagementHandler) SceneDelete(sceneName string) error {
+	if err := h.DeviceScenes.SceneDelete(sceneName); err != nil {
+		return err
+	}
+	return h.SaveSceneFile()
+}
+
+// SceneSetSequence は、シーン実行時のデバイスの順序と間隔を設定する
+func (h *DataManagementHandler) SceneSetSequence(sceneName string, sequence SceneSequence) error {
+	if err := h.DeviceScenes.SceneSetSequence(sceneName, sequence); err != nil {
+		return err
+	}
+	return h.SaveSceneFile()
+}
+
+// GetSceneSequence は、シーン実行時のデバイスの順序と間隔を返す
+func (h *DataManagementHandler) GetSceneSequence(sceneName string) SceneSequence {
+	return h.DeviceScenes.GetSceneSequence(sceneName)
+}
+
+// GetScene は、シーン名に対応するアクションのリストを返す
+func (h *DataManagementHandler) GetScene(sceneName string) ([]SceneAction, bool) {
+	return h.DeviceScenes.GetScene(sceneName)
+}
+
+// SetSchedules は、スケジュールと保存先ファイルを設定する
+// filename が空文字の場合、スケジュールの変更はファイルに保存しない
+func (h *DataManagementHandler) SetSchedules(schedules *DeviceSchedules, filename string) {
+	h.Schedules = schedules
+	h.schedulesPath = filename
+}
+
+// SaveScheduleFile は、スケジュールをファイルに保存する
+func (h *DataManagementHandler) SaveScheduleFile() error {
+	if h.schedulesPath == "" {
+		return nil
+	}
+	if err := h.Schedules.SaveToFile(h.schedulesPath); err != nil {
+		return fmt.Errorf("スケジュールの保存に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// ScheduleList は、スケジュールのリストを返す
+func (h *DataManagementHandler) ScheduleList() []Schedule {
+	return h.Schedules.ScheduleList()
+}
+
+// ScheduleSet は、スケジュールを追加または置き換える
+func (h *DataManagementHandler) ScheduleSet(schedule Schedule) error {
+	if err := h.Schedules.ScheduleSet(schedule); err != nil {
+		return err
+	}
+	return h.SaveScheduleFile()
+}
+
+// ScheduleDelete は、スケジュールを削除する
+func (h *DataManagementHandler) ScheduleDelete(name string) error {
+	if err := h.Schedules.ScheduleDelete(name); err != nil {
+		return err
+	}
+	return h.SaveScheduleFile()
+}
+
+// ScheduleSetDisabled は、スケジュールの有効・無効を切り替える
+func (h *DataManagementHandler) ScheduleSetDisabled(name string, disabled bool) error {
+	if err := h.Schedules.ScheduleSetDisabled(name, disabled); err != nil {
+		return err
+	}
+	return h.SaveScheduleFile()
+}
+
+// GetSchedule は、スケジュール名に対応するスケジュールを返す
+func (h *DataManagementHandler) GetSchedule(name string) (Schedule, bool) {
+	return h.Schedules.GetSchedule(name)
+}
+
+// SetMetadata は、デバイスのメタデータと保存先ファイルを設定する
+// filename が空文字の場合、メタデータの変更はファイルに保存しない
+func (h *DataManagementHandler) SetMetadata(metadata *DeviceMetadataStore, filename string) {
+	h.Metadata = metadata
+	h.metadataPath = filename
+}
+
+// SaveMetadataFile は、デバイスのメタデータをファイルに保存する
+func (h *DataManagementHandler) SaveMetadataFile() error {
+	if h.metadataPath == "" {
+		return nil
+	}
+	if err := h.Metadata.SaveToFile(h.metadataPath); err != nil {
+		return fmt.Errorf("メタデータの保存に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// DeviceMetadataSet は、デバイスのメタデータを置き換える
+func (h *DataManagementHandler) DeviceMetadataSet(id IDString, metadata DeviceMetadata) error {
+	if h.Metadata == nil {
+		return errors.New("Metadata is not initialized")
+	}
+	if err := h.Metadata.Set(id, metadata); err != nil {
+		return err
+	}
+	return h.SaveMetadataFile()
+}
+
+// DeviceMetadataDelete は、デバイスのメタデータを削除する
+func (h *DataManagementHandler) DeviceMetadataDelete(id IDString) error {
+	if h.Metadata == nil {
+		return errors.New("Metadata is not initialized")
+	}
+	if err := h.Metadata.Delete(id); err != nil {
+		return err
+	}
+	return h.SaveMetadataFile()
+}
+
+// GetDeviceMetadata は、すべてのデバイスのメタデータを返す
+func (h *DataManagementHandler) GetDeviceMetadata() map[IDString]DeviceMetadata {
+	if h.Metadata == nil {
+		return nil
+	}
+	return h.Metadata.GetAll()
+}
+
+// SetPreferences は、クライアントの表示設定と保存先ファイルを設定する
+// filename が空文字の場合、表示設定の変更はファイルに保存しない
+func (h *DataManagementHandler) SetPreferences(preferences *ClientPreferencesStore, filename string) {
+	h.Preferences = preferences
+	h.preferencesPath = filename
+}
+
+// SavePreferencesFile は、クライアントの表示設定をファイルに保存する
+func (h *DataManagementHandler) SavePreferencesFile() error {
+	if h.preferencesPath == "" {
+		return nil
+	}
+	if err := h.Preferences.SaveToFile(h.preferencesPath); err != nil {
+		return fmt.Errorf("表示設定の保存に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// ClientPreferencesSet は、クライアントキーの表示設定を置き換える
+func (h *DataManagementHandler) ClientPreferencesSet(key string, preferences ClientPreferences) error {
+	if h.Preferences == nil {
+		return errors.New("Preferences is not initialized")
+	}
+	if err := h.Preferences.Set(key, preferences); err != nil {
+		return err
+	}
+	return h.SavePreferencesFile()
+}
+
+// GetClientPreferences は、クライアントキーの表示設定を返す
+func (h *DataManagementHandler) GetClientPreferences(key string) (ClientPreferences, bool) {
+	if h.Preferences == nil {
+		return ClientPreferences{}, false
+	}
+	return h.Preferences.Get(key)
+}
+
 // FindDeviceByIDString は、IDStringからデバイスを検索する
 func (h *DataManagementHandler) FindDeviceByIDString(id IDString) *IPAndEOJ {
 	devices := h.devices.FindByIDString(id)
@@ -434,6 +805,44 @@ func (h *DataManagementHandler) RemoveAllDevicesByIP(ip net.IP) []IPAndEOJ {
 	return h.devices.RemoveAllDevicesByIP(ip)
 }
 
+// ReassignDeviceID は、from を参照するエイリアス・グループ・メタデータ・シーンを to に付け替えて保存し、
+// 付け替えたエイリアスとグループを返す。外部で編集され読み込めないファイルは付け替えない
+func (h *DataManagementHandler) ReassignDeviceID(from, to IDString) ([]AliasChange, []GroupChange) {
+	if from == "" || to == "" || from == to {
+		return nil, nil
+	}
+
+	var aliases []AliasChange
+	if err := h.reloadAliasFile(); err != nil {
+		h.log().Warn("エイリアスを付け替えられません", "from", from, "to", to, "err", err)
+	} else if aliases = h.DeviceAliases.reassign(from, to); len(aliases) > 0 {
+		if err := h.SaveAliasFile(); err != nil {
+			h.log().Warn("エイリアスの保存に失敗", "err", err)
+		}
+	}
+
+	var groups []GroupChange
+	if err := h.reloadGroupFile(); err != nil {
+		h.log().Warn("グループを付け替えられません", "from", from, "to", to, "err", err)
+	} else if groups = h.DeviceGroups.reassign(from, to); len(groups) > 0 {
+		if err := h.SaveGroupFile(); err != nil {
+			h.log().Warn("グループの保存に失敗", "err", err)
+		}
+	}
+
+	if h.Metadata != nil && h.Metadata.reassign(from, to) {
+		if err := h.SaveMetadataFile(); err != nil {
+			h.log().Warn("メタデータの保存に失敗", "err", err)
+		}
+	}
+	if h.DeviceScenes != nil && h.DeviceScenes.reassign(from, to) {
+		if err := h.SaveSceneFile(); err != nil {
+			h.log().Warn("シーンの保存に失敗", "err", err)
+		}
+	}
+	return aliases, groups
+}
+
 // RemoveDevice は、指定されたデバイスをハンドラーから削除する
 func (h *DataManagementHandler) RemoveDevice(device IPAndEOJ) error {
 	// デバイスが存在するか確認
@@ -442,12 +851,16 @@ func (h *DataManagementHandler) RemoveDevice(device IPAndEOJ) error {
 	}
 
 	// Devicesからデバイスを削除
-	return h.devices.RemoveDevice(device)
+	if err := h.devices.RemoveDevice(device); err != nil {
+		return err
+	}
+	h.ChangeStats.Remove(device)
+	return nil
 }
 
 // SaveLocationSettingsFile は、ロケーション設定をファイルに保存する
 func (h *DataManagementHandler) SaveLocationSettingsFile() error {
-	if h.LocationSettings == nil {
+	if h.LocationSettings == nil || h.inMemory {
 		return nil
 	}
 	err := h.LocationSettings.SaveToFile(LocationSettingsFileName)
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/network"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// discoveryIdleTimeout は、インターフェース毎の検出で無通信が続いたときに完了とみなす時間
+const discoveryIdleTimeout = 2 * time.Second
+
+// discoveryMerger は、複数インターフェースでの並列検出結果を統合する
+// 同じ識別番号(0x83)を持つノードが複数の経路(IP)から応答した場合、最初に応答した経路のみを採用する
+// prefer が設定されている場合は、そのIPバージョンの経路を優先する
+type discoveryMerger struct {
+	mu        sync.Mutex
+	seenIPs   map[string]struct{} // 採用済みのIP
+	idToIP    map[string]string   // 識別番号(hex) -> 採用したIP
+	duplicate map[string]string   // 除外したIP -> 採用したIP
+
+	prefer    network.IPVersion              // 優先するIPのバージョン（空の場合は最初の応答を採用）
+	onReplace func(replaced, adopted net.IP) // 採用済みの経路を優先する経路で置き換えたときに呼ばれる
+}
+
+func newDiscoveryMerger() *discoveryMerger {
+	return &discoveryMerger{
+		seenIPs:   make(map[string]struct{}),
+		idToIP:    make(map[string]string),
+		duplicate: make(map[string]string),
+	}
+}
+
+// accept は、ip からの応答を採用するかどうかを判定する
+// 同じIPからの2回目以降の応答と、採用済みのノードと同じ識別番号を持つ別IPからの応答は採用しない
+// prefer が設定されている場合、優先しないIPバージョンで採用済みのノードは、優先するIPバージョンの応答で置き換える
+func (m *discoveryMerger) accept(ip net.IP, idEDT []byte) bool {
+	replaced, ok := m.acceptLocked(ip, idEDT)
+	if replaced != nil && m.onReplace != nil {
+		m.onReplace(replaced, ip)
+	}
+	return ok
+}
+
+// acceptLocked は accept の本体。置き換えた経路があればそのIPを返す
+func (m *discoveryMerger) acceptLocked(ip net.IP, idEDT []byte) (net.IP, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ipStr := ip.String()
+	if _, ok := m.seenIPs[ipStr]; ok {
+		return nil, false
+	}
+	var replaced net.IP
+	if len(idEDT) > 0 {
+		id := hex.EncodeToString(idEDT)
+		if existing, ok := m.idToIP[id]; ok {
+			existingIP := net.ParseIP(existing)
+			if m.prefer == "" || m.prefer.Matches(existingIP) || !m.prefer.Matches(ip) {
+				m.duplicate[ipStr] = existing
+				return nil, false
+			}
+			m.duplicate[existing] = ipStr
+			replaced = existingIP
+		}
+		m.idToIP[id] = ipStr
+	}
+	m.seenIPs[ipStr] = struct{}{}
+	return replaced, true
+}
+
+// duplicates は、別経路として除外したIPと、代わりに採用したIPの組を返す
+func (m *discoveryMerger) duplicates() map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make(map[string]string, len(m.duplicate))
+	for k, v := range m.duplicate {
+		result[k] = v
+	}
+	return result
+}
+
+// discoveryTarget は、並列検出の宛先
+type discoveryTarget struct {
+	name   string // ログに表示する名前（インターフェース名など）
+	ip     net.IP // 宛先アドレス
+	ifName string // マルチキャストを送信するインターフェース名（空の場合はOSが選ぶ）
+}
+
+// discoveryTargets は、並列検出の宛先を返す
+// インターフェースが指定されている場合は各インターフェースから ECHONET Lite のマルチキャストアドレスに送る
+// discoveryBroadcast が設定されている場合は、代わりに各インターフェースのIPv4ブロードキャストアドレスを使う
+// デュアルスタックでは IPv6 のマルチキャストアドレスも加える
+func (h *CommunicationHandler) discoveryTargets() ([]discoveryTarget, error) {
+	ips := h.session.DiscoveryIPs()
+	var targets []discoveryTarget
+	if len(h.discoveryInterfaces) > 0 && ips[0].To4() != nil {
+		// 存在しない、起動していない、IPv4 アドレスを持たないインターフェースはエラーにする
+		broadcasts, err := network.GetIPv4BroadcastIPsForInterfaces(h.discoveryInterfaces)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range broadcasts {
+			if h.discoveryBroadcast {
+				targets = append(targets, discoveryTarget{name: b.Name, ip: b.Broadcast})
+			} else {
+				targets = append(targets, discoveryTarget{name: b.Name, ip: echonet_lite.ECHONETLiteMulticastIPv4, ifName: b.Name})
+			}
+		}
+		ips = ips[1:]
+	}
+	for _, ip := range ips {
+		name := "ipv4"
+		if ip.To4() == nil {
+			name = "ipv6"
+		}
+		targets = append(targets, discoveryTarget{name: name, ip: ip})
+	}
+	return targets, nil
+}
+
+// discoverOnTargets は、宛先それぞれに対して並列に検出を行う
+func (h *CommunicationHandler) discoverOnTargets(targets []discoveryTarget) error {
+	merger := newDiscoveryMerger()
+	merger.prefer = h.preferIPVersion
+	merger.onReplace = h.dropDuplicateAddress
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(targets))
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target discoveryTarget) {
+			defer wg.Done()
+			h.log().Info("インターフェースで検出を開始", "interface", target.name, "to", target.ip)
+			if err := h.discoverOnTarget(target, merger); err != nil {
+				errs[i] = fmt.Errorf("interface %s: %w", target.name, err)
+			}
+		}(i, target)
+	}
+	wg.Wait()
+
+	for ip, adopted := range merger.duplicates() {
+		h.log().Info("複数経路で検出されたノードを統合", "ignoredIP", ip, "adoptedIP", adopted)
+	}
+
+	return errors.Join(errs...)
+}
+
+// discoverOnTarget は、宛先に対して自ノードインスタンスリストSと識別番号を要求し、
+// merger で採用された応答のみを処理する
+func (h *CommunicationHandler) discoverOnTarget(target discoveryTarget, merger *discoveryMerger) error {
+	timer := time.NewTimer(discoveryIdleTimeout)
+	defer timer.Stop()
+
+	device := IPAndEOJ{IP: target.ip, EOJ: echonet_lite.NodeProfileObject}
+	EPCs := []EPCType{echonet_lite.EPC_NPO_SelfNodeInstanceListS, echonet_lite.EPC_NPO_IDNumber}
+	callback := func(ie IPAndEOJ, success bool, p Properties, _ []EPCType) (CallbackCompleteStatus, error) {
+		timer.Reset(discoveryIdleTimeout)
+
+		instanceList, ok := p.FindEPC(echonet_lite.EPC_NPO_SelfNodeInstanceListS)
+		if !ok {
+			return CallbackContinue, nil
+		}
+		var idEDT []byte
+		if id, ok := p.FindEPC(echonet_lite.EPC_NPO_IDNumber); ok {
+			idEDT = id.EDT
+		}
+		if !merger.accept(ie.IP, idEDT) {
+			return CallbackContinue, nil
+		}
+		return CallbackContinue, h.onSelfNodeInstanceListS(ie, success, instanceList)
+	}
+
+	var key Key
+	var err error
+	if target.ifName != "" {
+		key, err = h.session.StartGetPropertiesOnInterface(device, target.ifName, EPCs, callback)
+	} else {
+		key, err = h.session.StartGetProperties(device, EPCs, callback)
+	}
+	if err != nil {
+		return err
+	}
+	defer h.session.UnregisterCallback(key)
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-h.ctx.Done():
+		return h.ctx.Err()
+	}
+}
+
+// dropDuplicateAddress は、デュアルスタックで同じノードが別の経路からも見つかったときに、
+// 採用しない経路 replaced のデバイスを削除し、以後その経路からの受信を無視する
+func (h *CommunicationHandler) dropDuplicateAddress(replaced, adopted net.IP) {
+	removed := h.dataAccessor.RemoveAllDevicesByIP(replaced)
+	h.session.IgnoreIP(replaced)
+	h.log().Info("IPv4とIPv6の両方で見つかったノードを統合", "ignoredIP", replaced, "adoptedIP", adopted, "removedCount", len(removed))
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/network"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscoveryMerger_SameIDOnDifferentPaths(t *testing.T) {
+	merger := newDiscoveryMerger()
+	idEDT := []byte{0xFE, 0x00, 0x00, 0x01}
+
+	assert.True(t, merger.accept(net.ParseIP("192.168.0.10"), idEDT))
+	// 別インターフェース経由で同じノードが応答した場合は採用しない
+	assert.False(t, merger.accept(net.ParseIP("10.0.0.10"), idEDT))
+
+	assert.Equal(t, map[string]string{"10.0.0.10": "192.168.0.10"}, merger.duplicates())
+}
+
+func TestDiscoveryMerger_DifferentIDs(t *testing.T) {
+	merger := newDiscoveryMerger()
+
+	assert.True(t, merger.accept(net.ParseIP("192.168.0.10"), []byte{0x01}))
+	assert.True(t, merger.accept(net.ParseIP("10.0.0.10"), []byte{0x02}))
+	assert.Empty(t, merger.duplicates())
+}
+
+func TestDiscoveryMerger_SameIPTwice(t *testing.T) {
+	merger := newDiscoveryMerger()
+	ip := net.ParseIP("192.168.0.10")
+
+	assert.True(t, merger.accept(ip, nil))
+	// 識別番号がなくても同じIPからの2回目の応答は重複として扱う
+	assert.False(t, merger.accept(ip, nil))
+	assert.Empty(t, merger.duplicates())
+}
+
+func TestDiscoveryMerger_PreferIPVersion(t *testing.T) {
+	merger := newDiscoveryMerger()
+	merger.prefer = network.IPv6
+	var replaced, adopted net.IP
+	merger.onReplace = func(r, a net.IP) { replaced, adopted = r, a }
+	idEDT := []byte{0xFE, 0x00, 0x00, 0x01}
+
+	assert.True(t, merger.accept(net.ParseIP("192.168.0.10"), idEDT))
+	// 優先する IPv6 で応答した場合は IPv4 の経路を置き換える
+	assert.True(t, merger.accept(net.ParseIP("fe80::10"), idEDT))
+	assert.Equal(t, "192.168.0.10", replaced.String())
+	assert.Equal(t, "fe80::10", adopted.String())
+	// 置き換えた後の IPv4 の応答は採用しない
+	assert.False(t, merger.accept(net.ParseIP("10.0.0.10"), idEDT))
+	assert.Equal(t, map[string]string{"192.168.0.10": "fe80::10", "10.0.0.10": "fe80::10"}, merger.duplicates())
+}
+
+func TestDiscoveryMerger_NoPreferenceKeepsFirst(t *testing.T) {
+	merger := newDiscoveryMerger()
+	merger.onReplace = func(r, a net.IP) { t.Errorf("unexpected replace %v -> %v", r, a) }
+	idEDT := []byte{0xFE, 0x00, 0x00, 0x01}
+
+	assert.True(t, merger.accept(net.ParseIP("192.168.0.10"), idEDT))
+	assert.False(t, merger.accept(net.ParseIP("fe80::10"), idEDT))
+}
+
+func TestDiscoveryTargets_MulticastPerInterface(t *testing.T) {
+	h := &CommunicationHandler{
+		session: &Session{
+			MulticastIP: echonet_lite.ECHONETLiteMulticastIPv4,
+			BroadcastIP: net.IPv4bcast,
+		},
+		discoveryInterfaces: []string{"lo"},
+	}
+
+	targets, err := h.discoveryTargets()
+	assert.NoError(t, err)
+	// 既定では各インターフェースからマルチキャストで検出する
+	assert.Equal(t, []discoveryTarget{{name: "lo", ip: echonet_lite.ECHONETLiteMulticastIPv4, ifName: "lo"}}, targets)
+
+	// ブロードキャストは指定された場合のみ使う
+	h.discoveryBroadcast = true
+	targets, err = h.discoveryTargets()
+	assert.NoError(t, err)
+	assert.Equal(t, []discoveryTarget{{name: "lo", ip: net.IPv4(127, 255, 255, 255).To4()}}, targets)
+}
@@ -9,8 +9,8 @@ import (
 // DataAccessor は、データアクセス機能を提供するインターフェース
 // CommunicationHandlerがDataManagementHandlerの機能を利用するために使用
 type DataAccessor interface {
-	// デバイス情報の保存
-	SaveDeviceInfo()
+	// デバイス情報の保存（失敗しても再試行するため、呼び出し側はエラーを無視してよい）
+	SaveDeviceInfo() error
 
 	// デバイスの存在確認
 	IsKnownDevice(device IPAndEOJ) bool
@@ -42,6 +42,9 @@ type DataAccessor interface {
 	// IPマイグレーション
 	FindIPsWithSameNodeProfileID(idEDT []byte, excludeIP string) []string
 	RemoveAllDevicesByIP(ip net.IP) []IPAndEOJ
+
+	// インスタンスの振り直し（エイリアス・グループ・メタデータ・シーンを付け替える）
+	ReassignDeviceID(from, to IDString) ([]AliasChange, []GroupChange)
 }
 
 // NotificationRelay は、通知イベントを中継する機能を提供するインターフェース
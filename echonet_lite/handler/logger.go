@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+)
+
+// defaultLogHandler は、呼び出し時点の slog.Default() に出力する slog.Handler
+// ログファイルのローテーションで slog.SetDefault が呼ばれても、新しい出力先に追従する
+type defaultLogHandler struct{}
+
+func (defaultLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return slog.Default().Handler().Enabled(ctx, level)
+}
+
+func (defaultLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	return slog.Default().Handler().Handle(ctx, r)
+}
+
+func (defaultLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return slog.Default().Handler().WithAttrs(attrs)
+}
+
+func (defaultLogHandler) WithGroup(name string) slog.Handler {
+	return slog.Default().Handler().WithGroup(name)
+}
+
+// defaultLogger は、ロガーが指定されていない場合に使う slog.Default() への出力
+var defaultLogger = slog.New(defaultLogHandler{})
+
+// loggerOrDefault は logger を返す。nil の場合は slog.Default() に出力するロガーを返す
+func loggerOrDefault(logger *slog.Logger) *slog.Logger {
+	if logger != nil {
+		return logger
+	}
+	return defaultLogger
+}
+
+// 以下の log メソッドは各構造体のログ出力先を返す
+// テストなどでロガーを設定せずに構造体を作った場合は slog.Default() に出力する
+
+func (s *Session) log() *slog.Logger               { return loggerOrDefault(s.logger) }
+func (h *CommunicationHandler) log() *slog.Logger  { return loggerOrDefault(h.logger) }
+func (h *DataManagementHandler) log() *slog.Logger { return loggerOrDefault(h.logger) }
+func (c *HandlerCore) log() *slog.Logger           { return loggerOrDefault(c.logger) }
+func (h *ECHONETLiteHandler) log() *slog.Logger    { return loggerOrDefault(h.logger) }
@@ -0,0 +1,140 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// IPVersion は ECHONET Lite の通信に使う IP のバージョンです
+type IPVersion string
+
+const (
+	IPv4      IPVersion = "ipv4" // IPv4 のみ（デフォルト）
+	IPv6      IPVersion = "ipv6" // IPv6 のみ
+	DualStack IPVersion = "dual" // IPv4 と IPv6 の両方
+)
+
+// ParseIPVersion は設定値を IPVersion に変換します。空文字は IPv4 として扱います
+func ParseIPVersion(s string) (IPVersion, error) {
+	switch v := IPVersion(s); v {
+	case "":
+		return IPv4, nil
+	case IPv4, IPv6, DualStack:
+		return v, nil
+	default:
+		return "", fmt.Errorf("unknown IP version %q (expected %q, %q or %q)", s, IPv4, IPv6, DualStack)
+	}
+}
+
+// Matches は、ip がこのバージョンのアドレスかどうかを返します（DualStack はすべてのアドレスに一致します）
+func (v IPVersion) Matches(ip net.IP) bool {
+	switch v {
+	case IPv4:
+		return ip.To4() != nil
+	case IPv6:
+		return ip.To4() == nil
+	}
+	return true
+}
+
+// DualStackConnection は IPv4 と IPv6 の UDPConnection を1つの接続として扱います
+// 送信は宛先アドレスのファミリーに応じたソケットから行い、受信は両方のソケットから行います
+type DualStackConnection struct {
+	v4 *UDPConnection
+	v6 *UDPConnection
+
+	startOnce sync.Once
+	ctx       context.Context
+	cancel    context.CancelFunc
+	packets   chan udpPacket
+	readersWg sync.WaitGroup
+}
+
+// NewDualStackConnection は IPv4 と IPv6 の接続をまとめた接続を作成します
+// 作成した接続を Close すると、両方の接続が閉じられます
+func NewDualStackConnection(v4, v6 *UDPConnection) *DualStackConnection {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &DualStackConnection{
+		v4:      v4,
+		v6:      v6,
+		ctx:     ctx,
+		cancel:  cancel,
+		packets: make(chan udpPacket, 64),
+	}
+}
+
+// connFor は宛先アドレスのファミリーに対応する接続を返します
+func (c *DualStackConnection) connFor(ip net.IP) *UDPConnection {
+	if ip.To4() != nil {
+		return c.v4
+	}
+	return c.v6
+}
+
+// SendTo は宛先アドレスのファミリーに対応するソケットから送信します
+func (c *DualStackConnection) SendTo(dstIP net.IP, data []byte) (int, error) {
+	return c.connFor(dstIP).SendTo(dstIP, data)
+}
+
+// SendToInterface は宛先アドレスのファミリーに対応するソケットから、インターフェース ifName でマルチキャストを送信します
+func (c *DualStackConnection) SendToInterface(dstIP net.IP, ifName string, data []byte) (int, error) {
+	return c.connFor(dstIP).SendToInterface(dstIP, ifName, data)
+}
+
+// Receive は両方のソケットのいずれかでパケットを受信し、送信元アドレスとデータを返します
+func (c *DualStackConnection) Receive(ctx context.Context) ([]byte, *net.UDPAddr, error) {
+	c.startOnce.Do(func() {
+		c.readersWg.Add(2)
+		go c.readLoop(c.v4)
+		go c.readLoop(c.v6)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-c.ctx.Done():
+		return nil, nil, net.ErrClosed
+	case p := <-c.packets:
+		return p.data, p.addr, p.err
+	}
+}
+
+// readLoop は conn からの受信を packets に送り続けます。接続が閉じられると終了します
+func (c *DualStackConnection) readLoop(conn *UDPConnection) {
+	defer c.readersWg.Done()
+	for {
+		data, addr, err := conn.Receive(c.ctx)
+		if c.ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err == nil && data == nil {
+			// 自送信パケット
+			continue
+		}
+		select {
+		case c.packets <- udpPacket{data: data, addr: addr, err: err}:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// IsLocalIP は指定されたIPアドレスがいずれかのソケットのローカルIPと一致するかを確認します
+func (c *DualStackConnection) IsLocalIP(ip net.IP) bool {
+	return c.v4.IsLocalIP(ip) || c.v6.IsLocalIP(ip)
+}
+
+// SetReplyAddress は IPv4 の送信元アドレスを固定します（IPv6 には対応しません）
+func (c *DualStackConnection) SetReplyAddress(ip net.IP) error {
+	return c.connFor(ip).SetReplyAddress(ip)
+}
+
+// Close は両方のソケットを閉じます
+func (c *DualStackConnection) Close() error {
+	c.cancel()
+	err := errors.Join(c.v4.Close(), c.v6.Close())
+	c.readersWg.Wait()
+	return err
+}
@@ -0,0 +1,96 @@
+package network
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIPVersion(t *testing.T) {
+	v, err := ParseIPVersion("")
+	assert.NoError(t, err)
+	assert.Equal(t, IPv4, v)
+
+	v, err = ParseIPVersion("dual")
+	assert.NoError(t, err)
+	assert.Equal(t, DualStack, v)
+
+	_, err = ParseIPVersion("ipv5")
+	assert.Error(t, err)
+}
+
+func TestIPVersion_Matches(t *testing.T) {
+	v4 := net.ParseIP("192.168.1.10")
+	v6 := net.ParseIP("fe80::1")
+	assert.True(t, IPv4.Matches(v4))
+	assert.False(t, IPv4.Matches(v6))
+	assert.True(t, IPv6.Matches(v6))
+	assert.False(t, IPv6.Matches(v4))
+	assert.True(t, DualStack.Matches(v4))
+	assert.True(t, DualStack.Matches(v6))
+}
+
+// TestDualStackConnection_ReceiveBothFamilies verifies that packets are received from both the IPv4 and the IPv6 socket
+// and that replies go out through the socket of the destination's family.
+func TestDualStackConnection_ReceiveBothFamilies(t *testing.T) {
+	if _, err := defaultIPv6MulticastInterface(); err != nil {
+		t.Skipf("IPv6 multicast is not available: %v", err)
+	}
+	port, err := getFreePort()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	v4, err := CreateUDPConnection(ctx, net.IPv4zero, port, net.ParseIP("224.0.23.0"), nil)
+	require.NoError(t, err)
+	v6, err := CreateUDPConnection(ctx, nil, port, net.ParseIP("ff02::1"), nil)
+	if err != nil {
+		v4.Close()
+		t.Skipf("IPv6 socket is not available: %v", err)
+	}
+	conn := NewDualStackConnection(v4, v6)
+	defer conn.Close()
+
+	for _, tc := range []struct {
+		network string
+		ip      net.IP
+	}{
+		{"udp4", net.IPv4(127, 0, 0, 1)},
+		{"udp6", net.IPv6loopback},
+	} {
+		sender, err := net.ListenUDP(tc.network, &net.UDPAddr{IP: tc.ip, Port: 0})
+		if err != nil {
+			t.Skipf("%s loopback is not available: %v", tc.network, err)
+		}
+		payload := []byte("dual stack " + tc.network)
+		_, err = sender.WriteToUDP(payload, &net.UDPAddr{IP: tc.ip, Port: port})
+		require.NoError(t, err)
+
+		recvCtx, recvCancel := context.WithTimeout(ctx, 2*time.Second)
+		data, src, err := conn.Receive(recvCtx)
+		recvCancel()
+		require.NoError(t, err)
+		assert.Equal(t, payload, data)
+		assert.True(t, src.IP.Equal(tc.ip), "unexpected source %v", src)
+
+		// A reply to the sender's address goes out through the socket of the same family
+		reply := []byte("reply")
+		_ = sender.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, err = conn.connFor(src.IP).UdpConn.WriteToUDP(reply, src)
+		require.NoError(t, err)
+		buf := make([]byte, 16)
+		n, _, err := sender.ReadFromUDP(buf)
+		require.NoError(t, err)
+		assert.Equal(t, reply, buf[:n])
+		sender.Close()
+	}
+
+	require.NoError(t, conn.Close())
+	_, _, err = conn.Receive(context.Background())
+	assert.ErrorIs(t, err, net.ErrClosed)
+}
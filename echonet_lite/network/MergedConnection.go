@@ -0,0 +1,132 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+// VirtualConnection は、このプログラム内で動く仮想ノードへの接続です
+// HasNode は、ip が仮想ノードのアドレスかどうかを返します
+type VirtualConnection interface {
+	Connection
+	HasNode(ip net.IP) bool
+}
+
+// MergedConnection は、実際のネットワークへの接続に仮想ノードを加えた接続です
+// 仮想ノード宛ての送信は仮想ノードだけに渡し、グループ宛て（マルチキャスト・ブロードキャスト）の送信は両方に渡します
+// 受信は両方の接続から行います
+type MergedConnection struct {
+	primary Connection
+	virtual VirtualConnection
+	isGroup func(ip net.IP) bool
+
+	startOnce sync.Once
+	ctx       context.Context
+	cancel    context.CancelFunc
+	packets   chan udpPacket
+	readersWg sync.WaitGroup
+}
+
+// NewMergedConnection は、primary に virtual の仮想ノードを加えた接続を作成します
+// isGroup は、仮想ノードにも渡すグループ宛てのアドレスかどうかを返します
+// 作成した接続を Close すると、両方の接続が閉じられます
+func NewMergedConnection(primary Connection, virtual VirtualConnection, isGroup func(ip net.IP) bool) *MergedConnection {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &MergedConnection{
+		primary: primary,
+		virtual: virtual,
+		isGroup: isGroup,
+		ctx:     ctx,
+		cancel:  cancel,
+		packets: make(chan udpPacket, 64),
+	}
+}
+
+// SendTo は、宛先に応じて実際のネットワーク・仮想ノードの一方または両方に送信します
+func (c *MergedConnection) SendTo(dstIP net.IP, data []byte) (int, error) {
+	if c.virtual.HasNode(dstIP) {
+		return c.virtual.SendTo(dstIP, data)
+	}
+	if c.isGroup != nil && c.isGroup(dstIP) {
+		// 仮想ノードへの送信に失敗しても、実際のネットワークへの送信は続けます
+		_, virtualErr := c.virtual.SendTo(dstIP, data)
+		n, err := c.primary.SendTo(dstIP, data)
+		if err != nil {
+			return n, err
+		}
+		return n, virtualErr
+	}
+	return c.primary.SendTo(dstIP, data)
+}
+
+// SendToInterface は、実際のネットワークにはインターフェース ifName からマルチキャストを送信し、仮想ノードにも渡します
+func (c *MergedConnection) SendToInterface(dstIP net.IP, ifName string, data []byte) (int, error) {
+	sender, ok := c.primary.(InterfaceSender)
+	if !ok {
+		return 0, ErrInterfaceSendNotSupported
+	}
+	// 仮想ノードへの送信に失敗しても、実際のネットワークへの送信は続けます
+	_, virtualErr := c.virtual.SendTo(dstIP, data)
+	n, err := sender.SendToInterface(dstIP, ifName, data)
+	if err != nil {
+		return n, err
+	}
+	return n, virtualErr
+}
+
+// Receive は、両方の接続のいずれかでパケットを受信し、送信元アドレスとデータを返します
+func (c *MergedConnection) Receive(ctx context.Context) ([]byte, *net.UDPAddr, error) {
+	c.startOnce.Do(func() {
+		c.readersWg.Add(2)
+		go c.readLoop(c.primary)
+		go c.readLoop(c.virtual)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-c.ctx.Done():
+		return nil, nil, net.ErrClosed
+	case p := <-c.packets:
+		return p.data, p.addr, p.err
+	}
+}
+
+// readLoop は conn からの受信を packets に送り続けます。接続が閉じられると終了します
+func (c *MergedConnection) readLoop(conn Connection) {
+	defer c.readersWg.Done()
+	for {
+		data, addr, err := conn.Receive(c.ctx)
+		if c.ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err == nil && data == nil {
+			// 自送信パケット
+			continue
+		}
+		select {
+		case c.packets <- udpPacket{data: data, addr: addr, err: err}:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// IsLocalIP は実際のネットワークへの接続のローカルIPと一致するかを確認します
+func (c *MergedConnection) IsLocalIP(ip net.IP) bool {
+	return c.primary.IsLocalIP(ip)
+}
+
+// SetReplyAddress は実際のネットワークへの接続の送信元アドレスを固定します
+func (c *MergedConnection) SetReplyAddress(ip net.IP) error {
+	return c.primary.SetReplyAddress(ip)
+}
+
+// Close は両方の接続を閉じます
+func (c *MergedConnection) Close() error {
+	c.cancel()
+	err := errors.Join(c.primary.Close(), c.virtual.Close())
+	c.readersWg.Wait()
+	return err
+}
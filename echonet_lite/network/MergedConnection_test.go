@@ -0,0 +1,113 @@
+package network
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConnection records sent packets and returns queued packets from Receive.
+type fakeConnection struct {
+	mu      sync.Mutex
+	sent    []string
+	nodes   []net.IP
+	packets chan udpPacket
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func newFakeConnection(nodes ...net.IP) *fakeConnection {
+	return &fakeConnection{nodes: nodes, packets: make(chan udpPacket, 8), closed: make(chan struct{})}
+}
+
+func (c *fakeConnection) SendTo(dstIP net.IP, data []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, dstIP.String())
+	return len(data), nil
+}
+
+func (c *fakeConnection) Receive(ctx context.Context) ([]byte, *net.UDPAddr, error) {
+	select {
+	case p := <-c.packets:
+		return p.data, p.addr, p.err
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-c.closed:
+		return nil, nil, net.ErrClosed
+	}
+}
+
+func (c *fakeConnection) IsLocalIP(ip net.IP) bool        { return false }
+func (c *fakeConnection) SetReplyAddress(ip net.IP) error { return nil }
+
+func (c *fakeConnection) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *fakeConnection) HasNode(ip net.IP) bool {
+	for _, n := range c.nodes {
+		if n.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *fakeConnection) sentTo() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.sent...)
+}
+
+func TestMergedConnection_SendTo(t *testing.T) {
+	virtualIP := net.ParseIP("192.0.2.254")
+	primary := newFakeConnection()
+	virtual := newFakeConnection(virtualIP)
+	conn := NewMergedConnection(primary, virtual, func(ip net.IP) bool { return ip.IsMulticast() })
+	defer conn.Close()
+
+	_, err := conn.SendTo(virtualIP, []byte{1})
+	require.NoError(t, err)
+	_, err = conn.SendTo(net.ParseIP("192.168.1.10"), []byte{2})
+	require.NoError(t, err)
+	_, err = conn.SendTo(net.ParseIP("224.0.23.0"), []byte{3})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"192.168.1.10", "224.0.23.0"}, primary.sentTo())
+	assert.Equal(t, []string{"192.0.2.254", "224.0.23.0"}, virtual.sentTo())
+}
+
+func TestMergedConnection_ReceiveFromBoth(t *testing.T) {
+	primary := newFakeConnection()
+	virtual := newFakeConnection(net.ParseIP("192.0.2.254"))
+	conn := NewMergedConnection(primary, virtual, nil)
+
+	primary.packets <- udpPacket{data: []byte("real"), addr: &net.UDPAddr{IP: net.ParseIP("192.168.1.10")}}
+	virtual.packets <- udpPacket{data: []byte("virtual"), addr: &net.UDPAddr{IP: net.ParseIP("192.0.2.254")}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	received := map[string]string{}
+	for range 2 {
+		data, addr, err := conn.Receive(ctx)
+		require.NoError(t, err)
+		received[addr.IP.String()] = string(data)
+	}
+	assert.Equal(t, map[string]string{"192.168.1.10": "real", "192.0.2.254": "virtual"}, received)
+
+	require.NoError(t, conn.Close())
+	_, _, err := conn.Receive(ctx)
+	assert.ErrorIs(t, err, net.ErrClosed)
+	select {
+	case <-virtual.closed:
+	default:
+		t.Error("virtual connection was not closed")
+	}
+}
@@ -2,13 +2,37 @@ package network
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"sync"
+	"syscall"
 	"time"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
 )
 
+// Connection は ECHONET Lite のパケットを送受信する接続を表します
+// UDPConnection のほか、デモモードの模擬ネットワークが実装します
+type Connection interface {
+	SendTo(dstIP net.IP, data []byte) (int, error)
+	Receive(ctx context.Context) ([]byte, *net.UDPAddr, error)
+	IsLocalIP(ip net.IP) bool
+	SetReplyAddress(ip net.IP) error
+	Close() error
+}
+
+// InterfaceSender はマルチキャストを送信するインターフェースを指定できる接続です
+// インターフェースごとに検出するときに使います
+type InterfaceSender interface {
+	SendToInterface(dstIP net.IP, ifName string, data []byte) (int, error)
+}
+
+// ErrInterfaceSendNotSupported は接続がインターフェースを指定した送信に対応していないことを表します
+var ErrInterfaceSendNotSupported = errors.New("connection does not support sending on a specific interface")
+
 // UDPConnection は UDP ソケットを管理します
 type UDPConnection struct {
 	UdpConn        *net.UDPConn
@@ -16,8 +40,30 @@ type UDPConnection struct {
 	localIPs       []net.IP // ローカルインターフェースのIPリスト
 	Port           int
 	multicastIP    net.IP // マルチキャストIPアドレス
+	ipv6           bool   // IPv6 のソケットかどうか
+	zone           string // IPv6 でマルチキャストグループに参加したインターフェース名（リンクローカル宛の送信に使う）
 	mu             sync.RWMutex
 	networkMonitor *NetworkMonitor
+
+	// マルチキャストに使うインターフェース（NetworkMonitorConfig.Interfaces で指定）
+	interfaceNames []string        // 指定されたインターフェース名
+	multicastIfs   []net.Interface // グループに参加しているインターフェース
+	sendMu         sync.Mutex      // 複数のインターフェースへのマルチキャスト送信の排他
+
+	// 応答アドレス固定用（SetReplyAddress で設定）
+	replyConn *net.UDPConn   // 応答アドレスにバインドした送受信用ソケット
+	packets   chan udpPacket // replyConn 使用時に両ソケットから受信したパケット
+	closed    chan struct{}  // Close で閉じられる（受信goroutineの終了用）
+	readersWg sync.WaitGroup // 受信goroutineの終了待ち
+
+	dstPort int // 送信先のポート。0 の場合は Port（テストで別ポートの相手に送るために使う）
+}
+
+// udpPacket は受信goroutineから Receive に渡すパケット
+type udpPacket struct {
+	data []byte
+	addr *net.UDPAddr
+	err  error
 }
 
 // NetworkMonitor はネットワークインターフェースの監視を行います
@@ -29,22 +75,35 @@ type NetworkMonitor struct {
 	done         chan struct{} // goroutine終了通知用
 }
 
-// NetworkMonitorConfig はネットワーク監視の設定を表します
+// NetworkMonitorConfig はネットワーク監視とマルチキャストに使うインターフェースの設定を表します
 type NetworkMonitorConfig struct {
 	Enabled bool
+	// Interfaces はマルチキャストグループへの参加とマルチキャスト送信に使うインターフェース名です。
+	// 空の場合は OS が選んだインターフェースを使います。ネットワーク監視が有効な場合、
+	// インターフェースの変更を検出するとグループに参加し直します
+	Interfaces []string
 }
 
-// CreateUDPConnection は IPv4 の unicast と multicast (マルチキャスト) を受信対応します。
+// interfaceNames は設定されたマルチキャスト用のインターフェース名を返します
+func (c *NetworkMonitorConfig) interfaceNames() []string {
+	if c == nil {
+		return nil
+	}
+	return c.Interfaces
+}
+
+// CreateUDPConnection は unicast と multicast (マルチキャスト) を受信対応します。
 // ip が nil の場合はワイルドカード listen、multicastIP がブロードキャストかつIPv4の場合は broadcast として受信。
 // multicastIP が真のマルチキャストかつIPv4の場合はグループ参加。
-// ip または multicastIP がIPv6の場合はエラーになります。
+// networkMonitorConfig.Interfaces を指定した場合は、そのインターフェースでグループに参加し、マルチキャストを送信します。
+// multicastIP がIPv6の場合は IPv6 のソケットを作成し、自動選択したインターフェースでグループに参加します。
+// このとき ip はIPv6のワイルドカードアドレス（または nil）である必要があります。
 func CreateUDPConnection(ctx context.Context, ip net.IP, port int, multicastIP net.IP, networkMonitorConfig *NetworkMonitorConfig) (*UDPConnection, error) {
-	// IPv6 非対応チェック
-	if ip != nil && ip.To4() == nil {
-		return nil, fmt.Errorf("IPv6 not supported for unicast ip")
-	}
 	if multicastIP != nil && multicastIP.To4() == nil {
-		return nil, fmt.Errorf("IPv6 not supported for multicastIP")
+		return createIPv6UDPConnection(ctx, ip, port, multicastIP, networkMonitorConfig)
+	}
+	if ip != nil && ip.To4() == nil {
+		return nil, fmt.Errorf("IPv6 unicast ip requires an IPv6 multicastIP")
 	}
 
 	// IPv4 broadcast 指定時は multicastIP を無視して listen
@@ -53,6 +112,7 @@ func CreateUDPConnection(ctx context.Context, ip net.IP, port int, multicastIP n
 	}
 
 	var conn *net.UDPConn
+	var multicastIfs []net.Interface
 	var err error
 
 	if multicastIP != nil {
@@ -60,12 +120,15 @@ func CreateUDPConnection(ctx context.Context, ip net.IP, port int, multicastIP n
 		if !multicastIP.IsMulticast() {
 			return nil, fmt.Errorf("multicastIP is not a multicast address")
 		}
-		conn, err = net.ListenMulticastUDP("udp4", nil, &net.UDPAddr{IP: multicastIP, Port: port})
+		conn, multicastIfs, err = listenMulticastUDP("udp4", multicastIP, port, networkMonitorConfig.interfaceNames())
 		if err != nil {
-			return nil, fmt.Errorf("failed to ListenMulticastUDP: %w", err)
+			return nil, err
 		}
 	} else {
 		// IPv4 unicast or wildcard listen (broadcast received via WriteToUDP)
+		if len(networkMonitorConfig.interfaceNames()) > 0 {
+			slog.Warn("マルチキャストを使わないため、インターフェースの指定は無視されます", "interfaces", networkMonitorConfig.interfaceNames())
+		}
 		bindIP := ip
 		if bindIP == nil || bindIP.IsUnspecified() {
 			bindIP = net.IPv4zero
@@ -86,7 +149,7 @@ func CreateUDPConnection(ctx context.Context, ip net.IP, port int, multicastIP n
 	// ローカルのIPv4アドレスを取得
 	localIPs, err := GetLocalIPv4s()
 	if err != nil {
-		fmt.Printf("Warning: could not reliably determine local IPs for self-message filtering: %v\n", err)
+		slog.Warn("Could not reliably determine local IPs for self-message filtering", "err", err)
 		localIPs = []net.IP{} // エラー時も空スライスで続行
 	}
 	// Listen したアドレスが Unspecified でない場合、それもリストに追加する（フォールバック）
@@ -105,23 +168,186 @@ func CreateUDPConnection(ctx context.Context, ip net.IP, port int, multicastIP n
 	}
 
 	localAddr := conn.LocalAddr().(*net.UDPAddr)
+	udpConn := &UDPConnection{
+		UdpConn:      conn,
+		LocalAddr:    localAddr,
+		localIPs:     localIPs,
+		Port:         port,
+		multicastIP:  multicastIP,
+		multicastIfs: multicastIfs,
+	}
+	if multicastIP != nil {
+		udpConn.interfaceNames = networkMonitorConfig.interfaceNames()
+	}
+
+	udpConn.startNetworkMonitor(ctx, networkMonitorConfig)
+
+	return udpConn, nil
+}
+
+// createIPv6UDPConnection は IPv6 のマルチキャストグループに参加したソケットを作成します
+// ソケットはワイルドカードアドレスにバインドされ、unicast も受信します
+func createIPv6UDPConnection(ctx context.Context, ip net.IP, port int, multicastIP net.IP, networkMonitorConfig *NetworkMonitorConfig) (*UDPConnection, error) {
+	if ip != nil && !(ip.To4() == nil && ip.IsUnspecified()) {
+		return nil, fmt.Errorf("unicast ip must be the IPv6 unspecified address for IPv6 multicast: %v", ip)
+	}
+	if !multicastIP.IsMulticast() {
+		return nil, fmt.Errorf("multicastIP is not a multicast address")
+	}
+
+	names := networkMonitorConfig.interfaceNames()
+	if len(names) == 0 {
+		ifi, err := defaultIPv6MulticastInterface()
+		if err != nil {
+			return nil, err
+		}
+		names = []string{ifi.Name}
+	}
+	conn, multicastIfs, err := listenMulticastUDP("udp6", multicastIP, port, names)
+	if err != nil {
+		return nil, err
+	}
+	// リンクローカル宛の送信には最初のインターフェースを使う
+	ifi := multicastIfs[0]
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+	} else {
+		conn.SetReadDeadline(time.Time{})
+	}
+
+	localIPs, err := GetLocalIPv6s()
+	if err != nil {
+		slog.Warn("Could not reliably determine local IPs for self-message filtering", "err", err)
+		localIPs = []net.IP{}
+	}
+
+	localAddr := *conn.LocalAddr().(*net.UDPAddr)
+	localAddr.Zone = ifi.Name
 	udpConn := &UDPConnection{
 		UdpConn:     conn,
-		LocalAddr:   localAddr,
+		LocalAddr:   &localAddr,
 		localIPs:    localIPs,
 		Port:        port,
 		multicastIP: multicastIP,
+		ipv6:        true,
+		zone:        ifi.Name,
+
+		interfaceNames: networkMonitorConfig.interfaceNames(),
+		multicastIfs:   multicastIfs,
+	}
+	udpConn.startNetworkMonitor(ctx, networkMonitorConfig)
+
+	slog.Info("IPv6マルチキャストグループに参加しました", "group", multicastIP, "interfaces", names)
+	return udpConn, nil
+}
+
+// listenMulticastUDP は指定されたインターフェースでマルチキャストグループに参加したソケットを作成します。
+// names が空の場合は OS が選んだインターフェースで参加します
+func listenMulticastUDP(network string, group net.IP, port int, names []string) (*net.UDPConn, []net.Interface, error) {
+	ifis, err := GetMulticastInterfaces(names)
+	if err != nil {
+		return nil, nil, err
+	}
+	var first *net.Interface
+	if len(ifis) > 0 {
+		first = &ifis[0]
+	}
+	// 最初のインターフェースはマルチキャスト送信にも使われる
+	conn, err := net.ListenMulticastUDP(network, first, &net.UDPAddr{IP: group, Port: port})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to ListenMulticastUDP: %w", err)
+	}
+	for i := 1; i < len(ifis); i++ {
+		if err := joinMulticastGroup(conn, &ifis[i], group); err != nil {
+			_ = conn.Close()
+			return nil, nil, fmt.Errorf("failed to join %v on %s: %w", group, ifis[i].Name, err)
+		}
+	}
+	if len(ifis) > 0 {
+		slog.Info("マルチキャストに使うインターフェースを設定しました", "group", group, "interfaces", names)
+	}
+	return conn, ifis, nil
+}
+
+// joinMulticastGroup は conn をインターフェース ifi でマルチキャストグループに参加させます
+func joinMulticastGroup(conn *net.UDPConn, ifi *net.Interface, group net.IP) error {
+	if group.To4() != nil {
+		return ipv4.NewPacketConn(conn).JoinGroup(ifi, &net.UDPAddr{IP: group})
+	}
+	return ipv6.NewPacketConn(conn).JoinGroup(ifi, &net.UDPAddr{IP: group})
+}
+
+// setMulticastInterface は conn からマルチキャストを送信するインターフェースを設定します
+func setMulticastInterface(conn *net.UDPConn, ifi *net.Interface, isIPv6 bool) error {
+	if isIPv6 {
+		return ipv6.NewPacketConn(conn).SetMulticastInterface(ifi)
+	}
+	return ipv4.NewPacketConn(conn).SetMulticastInterface(ifi)
+}
+
+// MulticastInterfaces はマルチキャストグループに参加しているインターフェース名を返します。
+// インターフェースを指定していない場合は空です
+func (c *UDPConnection) MulticastInterfaces() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	names := make([]string, 0, len(c.multicastIfs))
+	for _, ifi := range c.multicastIfs {
+		names = append(names, ifi.Name)
+	}
+	return names
+}
+
+// rejoinMulticastGroups は指定されたインターフェースでマルチキャストグループに参加し直します。
+// インターフェースが再起動するとグループから外れるため、ネットワークの変更を検出したときに呼び出します
+func (c *UDPConnection) rejoinMulticastGroups() {
+	if len(c.interfaceNames) == 0 || c.multicastIP == nil {
+		return
+	}
+	var ifis []net.Interface
+	for _, name := range c.interfaceNames {
+		ifi, err := net.InterfaceByName(name)
+		if err != nil || ifi.Flags&net.FlagUp == 0 {
+			slog.Warn("マルチキャストに使うインターフェースが利用できません", "interface", name, "err", err)
+			continue
+		}
+		// 参加したままのインターフェースではエラーになるので、ログだけ残す
+		if err := joinMulticastGroup(c.UdpConn, ifi, c.multicastIP); err != nil {
+			slog.Debug("マルチキャストグループへの再参加", "interface", name, "err", err)
+		}
+		ifis = append(ifis, *ifi)
 	}
 
-	// ネットワーク監視機能を初期化
+	c.mu.Lock()
+	c.multicastIfs = ifis
+	conn := c.UdpConn
+	if c.replyConn != nil {
+		conn = c.replyConn
+	}
+	c.mu.Unlock()
+	if len(ifis) > 0 {
+		if err := setMulticastInterface(conn, &ifis[0], c.ipv6); err != nil {
+			slog.Warn("マルチキャスト送信インターフェースの設定に失敗", "interface", ifis[0].Name, "err", err)
+		}
+	}
+}
+
+// startNetworkMonitor は設定が有効な場合にネットワーク監視機能を開始します
+func (c *UDPConnection) startNetworkMonitor(ctx context.Context, networkMonitorConfig *NetworkMonitorConfig) {
 	if networkMonitorConfig != nil && networkMonitorConfig.Enabled {
-		err := udpConn.initNetworkMonitor(ctx)
+		err := c.initNetworkMonitor(ctx)
 		if err != nil {
 			slog.Warn("ネットワーク監視の初期化に失敗", "err", err)
 		}
 	}
+}
 
-	return udpConn, nil
+// localAddresses はソケットのアドレスファミリーに応じたローカルIPアドレスのリストを取得します
+func (c *UDPConnection) localAddresses() ([]net.IP, error) {
+	if c.ipv6 {
+		return GetLocalIPv6s()
+	}
+	return GetLocalIPv4s()
 }
 
 // isSelfPacket は指定されたアドレスが自身のいずれかのローカルIPとポートから送信されたものかを確認します
@@ -134,12 +360,7 @@ func (c *UDPConnection) isSelfPacket(src *net.UDPAddr) bool {
 		return false
 	}
 	// 次にIPアドレスがローカルIPリストに含まれるか確認
-	for _, localIP := range c.localIPs {
-		if src.IP.Equal(localIP) {
-			return true
-		}
-	}
-	return false
+	return c.IsLocalIP(src.IP)
 }
 
 // IsLocalIP は指定されたIPアドレスが自身のローカルIPのいずれかと一致するかを確認します
@@ -147,6 +368,13 @@ func (c *UDPConnection) IsLocalIP(ip net.IP) bool {
 	if ip == nil {
 		return false
 	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.isLocalIPLocked(ip)
+}
+
+// isLocalIPLocked は IsLocalIP の本体です。c.mu を保持した状態で呼び出してください
+func (c *UDPConnection) isLocalIPLocked(ip net.IP) bool {
 	for _, localIP := range c.localIPs {
 		if ip.Equal(localIP) {
 			return true
@@ -165,12 +393,222 @@ func (c *UDPConnection) Close() error {
 		c.stopNetworkMonitor()
 	}
 
+	if c.replyConn != nil {
+		close(c.closed)
+		_ = c.replyConn.Close()
+	}
 	return c.UdpConn.Close()
 }
 
 // SendTo は指定先にデータを送信します
+// SetReplyAddress で応答アドレスが設定されている場合は、そのアドレスから送信します
 func (c *UDPConnection) SendTo(dstIP net.IP, data []byte) (int, error) {
-	return c.UdpConn.WriteTo(data, &net.UDPAddr{IP: dstIP, Port: c.Port})
+	c.mu.RLock()
+	conn := c.UdpConn
+	if c.replyConn != nil {
+		conn = c.replyConn
+	}
+	multicastIfs := c.multicastIfs
+	port := c.Port
+	if c.dstPort != 0 {
+		port = c.dstPort
+	}
+	c.mu.RUnlock()
+	dst := &net.UDPAddr{IP: dstIP, Port: port}
+	if dstIP.IsMulticast() && len(multicastIfs) > 1 {
+		return c.sendToMulticastInterfaces(conn, dst, data, multicastIfs)
+	}
+	// リンクローカルの宛先にはグループに参加したインターフェースを指定する
+	if c.zone != "" && (dstIP.IsLinkLocalUnicast() || dstIP.IsLinkLocalMulticast() || dstIP.IsInterfaceLocalMulticast()) {
+		dst.Zone = c.zone
+	}
+	if dstIP.IsMulticast() {
+		// SendToInterface が送信インターフェースを切り替えている間は待つ
+		c.sendMu.Lock()
+		defer c.sendMu.Unlock()
+	}
+	return conn.WriteTo(data, dst)
+}
+
+// SendToInterface はマルチキャストをインターフェース ifName から送信します（IP_MULTICAST_IF を設定します）
+// 送信後は送信インターフェースを元の設定に戻します
+func (c *UDPConnection) SendToInterface(dstIP net.IP, ifName string, data []byte) (int, error) {
+	if !dstIP.IsMulticast() {
+		return 0, fmt.Errorf("not a multicast address: %v", dstIP)
+	}
+	ifi, err := net.InterfaceByName(ifName)
+	if err != nil {
+		return 0, fmt.Errorf("interface %s: %w", ifName, err)
+	}
+
+	c.mu.RLock()
+	conn := c.UdpConn
+	if c.replyConn != nil {
+		conn = c.replyConn
+	}
+	var restore *net.Interface // nil の場合は OS が選ぶ
+	if len(c.multicastIfs) > 0 {
+		first := c.multicastIfs[0]
+		restore = &first
+	}
+	port := c.Port
+	if c.dstPort != 0 {
+		port = c.dstPort
+	}
+	c.mu.RUnlock()
+
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if err := setMulticastInterface(conn, ifi, c.ipv6); err != nil {
+		return 0, fmt.Errorf("interface %s: %w", ifName, err)
+	}
+	defer func() {
+		_ = setMulticastInterface(conn, restore, c.ipv6)
+	}()
+
+	dst := &net.UDPAddr{IP: dstIP, Port: port}
+	if c.ipv6 {
+		dst.Zone = ifi.Name
+	}
+	return conn.WriteTo(data, dst)
+}
+
+// sendToMulticastInterfaces はマルチキャストを指定されたインターフェースのそれぞれから送信します。
+// 一部のインターフェースで送信できれば成功とします
+func (c *UDPConnection) sendToMulticastInterfaces(conn *net.UDPConn, dst *net.UDPAddr, data []byte, ifis []net.Interface) (int, error) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	// 送信後は最初のインターフェースに戻す
+	defer func() {
+		_ = setMulticastInterface(conn, &ifis[0], c.ipv6)
+	}()
+
+	sent := 0
+	var errs []error
+	for i := range ifis {
+		ifi := &ifis[i]
+		if err := setMulticastInterface(conn, ifi, c.ipv6); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ifi.Name, err))
+			continue
+		}
+		d := *dst
+		if c.ipv6 {
+			d.Zone = ifi.Name
+		}
+		n, err := conn.WriteTo(data, &d)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ifi.Name, err))
+			continue
+		}
+		sent = n
+	}
+	if sent == 0 && len(errs) > 0 {
+		return 0, errors.Join(errs...)
+	}
+	if len(errs) > 0 {
+		slog.Warn("一部のインターフェースでマルチキャストの送信に失敗しました", "err", errors.Join(errs...))
+	}
+	return sent, nil
+}
+
+// SetReplyAddress は送信元アドレスを固定します。
+// ブリッジ接続やVM上で経路が非対称な環境では、カーネルが選ぶ送信元アドレスに
+// デバイスが応答できないことがあるため、指定アドレスにバインドしたソケットから送信します。
+// デバイスはこのアドレスへ応答するので、このソケットでも受信を行います。
+// Receive を呼び出し始める前に設定してください。
+func (c *UDPConnection) SetReplyAddress(ip net.IP) error {
+	if c.ipv6 {
+		return fmt.Errorf("reply address is not supported for IPv6 connections")
+	}
+	if ip == nil || ip.To4() == nil {
+		return fmt.Errorf("reply address must be an IPv4 address: %v", ip)
+	}
+
+	lc := net.ListenConfig{
+		Control: func(network, address string, rc syscall.RawConn) error {
+			var sockErr error
+			if err := rc.Control(func(fd uintptr) {
+				// マルチキャスト受信用ソケットと同じポートにバインドするため
+				sockErr = setsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+			}); err != nil {
+				return err
+			}
+			return sockErr
+		},
+	}
+	addr := &net.UDPAddr{IP: ip.To4(), Port: c.Port}
+	pc, err := lc.ListenPacket(context.Background(), "udp4", addr.String())
+	if err != nil {
+		return fmt.Errorf("failed to bind reply address %v: %w", addr, err)
+	}
+	replyConn := pc.(*net.UDPConn)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.replyConn != nil {
+		_ = replyConn.Close()
+		return fmt.Errorf("reply address is already set")
+	}
+	c.replyConn = replyConn
+	if len(c.multicastIfs) > 0 {
+		if err := setMulticastInterface(replyConn, &c.multicastIfs[0], false); err != nil {
+			slog.Warn("マルチキャスト送信インターフェースの設定に失敗", "interface", c.multicastIfs[0].Name, "err", err)
+		}
+	}
+	if !c.isLocalIPLocked(ip) {
+		c.localIPs = append(c.localIPs, ip.To4())
+	}
+
+	// 以後は両ソケットを常時読み続ける
+	c.packets = make(chan udpPacket, 64)
+	c.closed = make(chan struct{})
+	c.readersWg.Add(2)
+	go c.readLoop(c.UdpConn)
+	go c.readLoop(replyConn)
+	go func(ch chan udpPacket) {
+		c.readersWg.Wait()
+		close(ch)
+	}(c.packets)
+
+	slog.Info("応答アドレスを設定しました", "addr", addr)
+	return nil
+}
+
+// readLoop は conn からの受信を packets に送り続けます。ソケットが閉じられると終了します
+func (c *UDPConnection) readLoop(conn *net.UDPConn) {
+	defer c.readersWg.Done()
+	_ = conn.SetReadDeadline(time.Time{})
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			if !c.deliver(udpPacket{err: err}) {
+				return
+			}
+			continue
+		}
+		if c.isSelfPacket(addr) {
+			continue
+		}
+		data := make([]byte, n)
+		copy(data, buf[:n])
+		if !c.deliver(udpPacket{data: data, addr: addr}) {
+			return
+		}
+	}
+}
+
+// deliver は受信したパケットを Receive に渡します。接続が閉じられた場合は false を返します
+func (c *UDPConnection) deliver(p udpPacket) bool {
+	select {
+	case c.packets <- p:
+		return true
+	case <-c.closed:
+		return false
+	}
 }
 
 // bufferPool は受信バッファのプールです
@@ -181,6 +619,21 @@ var bufferPool = sync.Pool{
 // Receive は UDP パケットを受信し、送信元アドレスとデータを返します。
 // 自送信パケットを除外し、コンテキストキャンセルに対応します。
 func (c *UDPConnection) Receive(ctx context.Context) ([]byte, *net.UDPAddr, error) {
+	c.mu.RLock()
+	packets := c.packets
+	c.mu.RUnlock()
+	if packets != nil {
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case p, ok := <-packets:
+			if !ok {
+				return nil, nil, net.ErrClosed
+			}
+			return p.data, p.addr, p.err
+		}
+	}
+
 	if deadline, ok := ctx.Deadline(); ok {
 		c.UdpConn.SetReadDeadline(deadline)
 	} else {
@@ -317,7 +770,7 @@ func (c *UDPConnection) monitorNetworkChanges() {
 		networkMonitor.interfacesMu.Unlock()
 
 		// ローカルIPアドレスを再取得
-		newLocalIPs, err := GetLocalIPv4s()
+		newLocalIPs, err := c.localAddresses()
 		if err != nil {
 			slog.Warn("ローカルIPアドレスの再取得に失敗", "err", err)
 			// エラーでも既存のIPリストを保持して継続
@@ -327,6 +780,9 @@ func (c *UDPConnection) monitorNetworkChanges() {
 			c.mu.Unlock()
 			slog.Debug("ローカルIPアドレスを更新しました", "count", len(newLocalIPs))
 		}
+
+		// インターフェースの再起動でグループから外れている場合があるため参加し直す
+		c.rejoinMulticastGroups()
 	}
 }
 
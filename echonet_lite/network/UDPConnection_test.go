@@ -11,7 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// setsockoptInt is defined in sockopt_{unix,windows}_test.go
+// setsockoptInt is defined in sockopt_{unix,windows}.go
 // to handle platform-specific syscall.SetsockoptInt signatures.
 
 // getFreePort returns an available UDP port by letting the OS assign one.
@@ -124,7 +124,9 @@ func TestUDPConnection_ReceiveMulticast(t *testing.T) {
 
 // TestUDPConnection_ReceiveMulticastIPv6 verifies that UDPConnection can receive IPv6 multicast packets.
 func TestUDPConnection_ReceiveMulticastIPv6(t *testing.T) {
-	t.Skip("IPv6 not supported")
+	if _, err := defaultIPv6MulticastInterface(); err != nil {
+		t.Skipf("IPv6 multicast is not available: %v", err)
+	}
 	const multicastIPStr = "ff02::1"
 	multicastIP := net.ParseIP(multicastIPStr)
 	require.NotNil(t, multicastIP, "invalid IPv6 multicast IP")
@@ -174,3 +176,174 @@ func TestUDPConnection_ReceiveMulticastIPv6(t *testing.T) {
 	assert.Equal(t, payload, data)
 	assert.NotNil(t, src)
 }
+
+// TestUDPConnection_SetReplyAddress verifies that packets are sent from the reply address
+// and that packets sent to the reply address are received.
+// replyTestAddress returns a local IPv4 address other than 127.0.0.1 that a socket can bind to:
+// 127.0.0.2 where the whole loopback network is local (Linux), otherwise an address of a network interface
+func replyTestAddress(t *testing.T) net.IP {
+	t.Helper()
+	candidates := []net.IP{net.IPv4(127, 0, 0, 2)}
+	if ips, err := GetLocalIPv4s(); err == nil {
+		candidates = append(candidates, ips...)
+	}
+	for _, ip := range candidates {
+		if ip.Equal(net.IPv4(127, 0, 0, 1)) {
+			continue
+		}
+		if probe, err := net.ListenUDP("udp4", &net.UDPAddr{IP: ip, Port: 0}); err == nil {
+			probe.Close()
+			return ip
+		}
+	}
+	t.Skip("no local IPv4 address other than 127.0.0.1 is available")
+	return nil
+}
+
+func TestUDPConnection_SetReplyAddress(t *testing.T) {
+	port, err := getFreePort()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := CreateUDPConnection(ctx, nil, port, net.ParseIP("224.0.23.0"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// 応答アドレスは、127.0.0.1 宛に送るときにカーネルが選ばないアドレスにする
+	replyIP := replyTestAddress(t)
+	require.NoError(t, conn.SetReplyAddress(replyIP))
+	assert.True(t, conn.IsLocalIP(replyIP))
+	assert.Error(t, conn.SetReplyAddress(replyIP), "reply address can only be set once")
+
+	peerIP := net.IPv4(127, 0, 0, 1)
+	peer, err := net.ListenUDP("udp4", &net.UDPAddr{IP: peerIP, Port: 0})
+	require.NoError(t, err)
+	defer peer.Close()
+
+	// SendTo の送信元が応答アドレスになっていること（宛先ポートを peer のポートにする）
+	conn.mu.Lock()
+	conn.dstPort = peer.LocalAddr().(*net.UDPAddr).Port
+	conn.mu.Unlock()
+	_, err = conn.SendTo(peerIP, []byte("request"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 64)
+	require.NoError(t, peer.SetReadDeadline(time.Now().Add(2*time.Second)))
+	n, src, err := peer.ReadFromUDP(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "request", string(buf[:n]))
+	assert.True(t, src.IP.Equal(replyIP), "source %v, want the reply address %v", src.IP, replyIP)
+	assert.Equal(t, port, src.Port)
+
+	// 応答アドレス宛のパケットを受信できること
+	_, err = peer.WriteToUDP([]byte("response"), &net.UDPAddr{IP: replyIP, Port: port})
+	require.NoError(t, err)
+
+	recvCtx, recvCancel := context.WithTimeout(ctx, 2*time.Second)
+	defer recvCancel()
+	var data []byte
+	data, src, err = conn.Receive(recvCtx)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("response"), data)
+	assert.Equal(t, peer.LocalAddr().(*net.UDPAddr).Port, src.Port)
+}
+
+// TestCreateUDPConnection_MulticastInterfaces verifies that the multicast group is joined on the configured interface.
+func TestCreateUDPConnection_MulticastInterfaces(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	multicastIP := net.ParseIP("224.0.23.0")
+
+	_, err := CreateUDPConnection(ctx, nil, 0, multicastIP, &NetworkMonitorConfig{Interfaces: []string{"no-such-interface0"}})
+	assert.Error(t, err, "an unknown interface must be an error")
+
+	ifaces, err := net.Interfaces()
+	require.NoError(t, err)
+	var name string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp != 0 && iface.Flags&net.FlagMulticast != 0 {
+			name = iface.Name
+			break
+		}
+	}
+	if name == "" {
+		t.Skip("no multicast-capable interface is available")
+	}
+
+	port, err := getFreePort()
+	require.NoError(t, err)
+	conn, err := CreateUDPConnection(ctx, nil, port, multicastIP, &NetworkMonitorConfig{Interfaces: []string{name}})
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, []string{name}, conn.MulticastInterfaces())
+
+	_, err = conn.SendTo(multicastIP, []byte("multicast interface test"))
+	assert.NoError(t, err)
+
+	// Joining again after a network change keeps the interface
+	conn.rejoinMulticastGroups()
+	assert.Equal(t, []string{name}, conn.MulticastInterfaces())
+}
+
+// TestUDPConnection_SendToInterface verifies that a multicast packet is sent out of the given interface.
+func TestUDPConnection_SendToInterface(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	multicastIP := net.ParseIP("224.0.23.0")
+
+	sender, err := CreateUDPConnection(ctx, nil, 0, nil, nil)
+	require.NoError(t, err)
+	defer sender.Close()
+
+	_, err = sender.SendToInterface(net.ParseIP("127.0.0.1"), "lo", []byte("unicast"))
+	assert.Error(t, err, "a unicast destination must be an error")
+	_, err = sender.SendToInterface(multicastIP, "no-such-interface0", []byte("unknown"))
+	assert.Error(t, err, "an unknown interface must be an error")
+
+	ifaces, err := net.Interfaces()
+	require.NoError(t, err)
+	var name string
+	var ifaceIPs []net.IP
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		require.NoError(t, err)
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+				ifaceIPs = append(ifaceIPs, ipnet.IP.To4())
+			}
+		}
+		if len(ifaceIPs) > 0 {
+			name = iface.Name
+			break
+		}
+	}
+	if name == "" {
+		t.Skip("no multicast-capable interface with an IPv4 address is available")
+	}
+
+	port, err := getFreePort()
+	require.NoError(t, err)
+	receiver, err := CreateUDPConnection(ctx, nil, port, multicastIP, &NetworkMonitorConfig{Interfaces: []string{name}})
+	require.NoError(t, err)
+	defer receiver.Close()
+
+	sender.mu.Lock()
+	sender.dstPort = port
+	sender.mu.Unlock()
+	payload := []byte("send to interface test")
+	_, err = sender.SendToInterface(multicastIP, name, payload)
+	require.NoError(t, err)
+
+	recvCtx, recvCancel := context.WithTimeout(ctx, 2*time.Second)
+	defer recvCancel()
+	data, src, err := receiver.Receive(recvCtx)
+	require.NoError(t, err)
+	assert.Equal(t, payload, data)
+	// The source address is the one of the interface chosen by IP_MULTICAST_IF
+	assert.Contains(t, ifaceIPs, src.IP.To4())
+}
@@ -0,0 +1,486 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WiSUNConfig は Wi-SUN（Bルート）接続の設定です
+type WiSUNConfig struct {
+	Device         string // シリアルデバイスのパス（例: /dev/ttyUSB0）
+	BaudRate       int    // ボーレート（0 の場合は 115200）
+	RouteBID       string // Bルート認証ID（32文字）
+	RouteBPassword string // Bルートパスワード（12文字）
+	Debug          bool   // アダプターとのやりとりをログに出力する
+}
+
+const (
+	// DefaultWiSUNBaudRate は BP35A1 などの Wi-SUN アダプターの標準のボーレートです
+	DefaultWiSUNBaudRate = 115200
+
+	wisunECHONETPort   = 0x0E1A           // ECHONET Lite の UDP ポート（SKSENDTO の宛先）
+	wisunMaxScanDur    = 8                // アクティブスキャンの最大の長さ（SKSCAN の DURATION）
+	wisunMinScanDur    = 4                // アクティブスキャンの最初の長さ
+	wisunCommandWait   = 5 * time.Second  // コマンドの応答待ち時間
+	wisunScanWait      = 2 * time.Minute  // アクティブスキャン1回あたりの完了待ち時間
+	wisunJoinWait      = 90 * time.Second // PANA 認証の完了待ち時間
+	wisunRejoinBackoff = 30 * time.Second // 再接続に失敗したときの待ち時間
+)
+
+// WiSUNConnection は BP35A1 互換（SKSTACK IP）の Wi-SUN アダプターを使い、
+// Bルートでスマート電力量メーターと通信する Connection です
+// 通信相手はメーター1台のみのため、ブロードキャストやマルチキャスト宛ての送信もメーターに送ります
+type WiSUNConnection struct {
+	port   io.ReadWriteCloser
+	config WiSUNConfig
+
+	cmdMu   sync.Mutex     // コマンドと応答のやりとりを直列化する
+	lines   chan string    // 受信した行（ERXUDP 以外）
+	packets chan udpPacket // 受信した ECHONET Lite のパケット
+	rejoin  chan struct{}  // PANA セッションが切れたときに通知される
+
+	meterIP net.IP      // メーターの IPv6 リンクローカルアドレス（接続後は変わらない）
+	joined  atomic.Bool // 最初の PANA 認証が完了したかどうか
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// OpenWiSUNConnection はシリアルポートを開き、メーターを探して PANA 認証を行います
+// スキャンと認証には数十秒から数分かかります
+func OpenWiSUNConnection(ctx context.Context, config WiSUNConfig) (*WiSUNConnection, error) {
+	if config.BaudRate == 0 {
+		config.BaudRate = DefaultWiSUNBaudRate
+	}
+	port, err := openSerialPort(config.Device, config.BaudRate)
+	if err != nil {
+		return nil, fmt.Errorf("シリアルポート %s を開けません: %w", config.Device, err)
+	}
+	return NewWiSUNConnection(ctx, port, config)
+}
+
+// NewWiSUNConnection は開いたシリアルポートを使って接続します
+// 失敗した場合は port を閉じます
+func NewWiSUNConnection(ctx context.Context, port io.ReadWriteCloser, config WiSUNConfig) (*WiSUNConnection, error) {
+	if config.RouteBID == "" || config.RouteBPassword == "" {
+		_ = port.Close()
+		return nil, errors.New("Bルート認証IDとパスワードが必要です")
+	}
+	connCtx, cancel := context.WithCancel(context.Background())
+	c := &WiSUNConnection{
+		port:    port,
+		config:  config,
+		lines:   make(chan string, 64),
+		packets: make(chan udpPacket, 64),
+		rejoin:  make(chan struct{}, 1),
+		ctx:     connCtx,
+		cancel:  cancel,
+	}
+	c.wg.Add(1)
+	go c.readLoop()
+
+	if err := c.connect(ctx); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	c.joined.Store(true)
+
+	c.wg.Add(1)
+	go c.rejoinLoop()
+	return c, nil
+}
+
+// MeterIP はメーターの IPv6 アドレスを返します
+func (c *WiSUNConnection) MeterIP() net.IP {
+	return c.meterIP
+}
+
+// connect はアダプターを初期化し、メーターをスキャンして PANA 認証を行います
+func (c *WiSUNConnection) connect(ctx context.Context) error {
+	c.cmdMu.Lock()
+	defer c.cmdMu.Unlock()
+
+	// エコーバックを無効にする（SKSENDTO のバイナリデータが返ってこないように）
+	if _, err := c.command(ctx, "SKSREG SFE 0"); err != nil {
+		return fmt.Errorf("Wi-SUN アダプターの初期化に失敗: %w", err)
+	}
+	// ERXUDP のデータを16進数の文字列で受け取る（ROPT/WOPT がないアダプターはそのまま）
+	if res, err := c.command(ctx, "ROPT"); err == nil && len(res) > 0 && strings.HasPrefix(res[len(res)-1], "OK 00") {
+		if _, err := c.command(ctx, "WOPT 01"); err != nil {
+			return fmt.Errorf("Wi-SUN アダプターの表示形式の設定に失敗: %w", err)
+		}
+	}
+	if _, err := c.command(ctx, "SKSETPWD C "+c.config.RouteBPassword); err != nil {
+		return fmt.Errorf("Bルートパスワードの設定に失敗: %w", err)
+	}
+	if _, err := c.command(ctx, "SKSETRBID "+c.config.RouteBID); err != nil {
+		return fmt.Errorf("Bルート認証IDの設定に失敗: %w", err)
+	}
+
+	pan, err := c.scan(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := c.command(ctx, "SKSREG S2 "+pan.channel); err != nil {
+		return fmt.Errorf("チャンネルの設定に失敗: %w", err)
+	}
+	if _, err := c.command(ctx, "SKSREG S3 "+pan.panID); err != nil {
+		return fmt.Errorf("PAN ID の設定に失敗: %w", err)
+	}
+	meterIP, err := c.linkLocalAddress(ctx, pan.addr)
+	if err != nil {
+		return err
+	}
+	c.meterIP = meterIP
+
+	if err := c.join(ctx); err != nil {
+		return err
+	}
+	slog.Info("スマート電力量メーターに接続しました", "meter", meterIP, "channel", pan.channel, "panID", pan.panID)
+	return nil
+}
+
+// wisunPAN はアクティブスキャンで見つかった PAN の情報です
+type wisunPAN struct {
+	channel string
+	panID   string
+	addr    string // メーターの MAC アドレス（16進数16桁）
+}
+
+// scan はアクティブスキャンでメーターを探します。見つからない場合はスキャン時間を延ばして繰り返します
+func (c *WiSUNConnection) scan(ctx context.Context) (*wisunPAN, error) {
+	for duration := wisunMinScanDur; duration <= wisunMaxScanDur; duration++ {
+		slog.Info("スマート電力量メーターをスキャンしています", "duration", duration)
+		if _, err := c.command(ctx, fmt.Sprintf("SKSCAN 2 FFFFFFFF %d", duration)); err != nil {
+			return nil, fmt.Errorf("アクティブスキャンに失敗: %w", err)
+		}
+		pan := &wisunPAN{}
+		err := c.waitFor(ctx, wisunScanWait, func(line string) (bool, error) {
+			if strings.HasPrefix(line, "EVENT 22") {
+				return true, nil
+			}
+			key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+			if !ok {
+				return false, nil
+			}
+			switch key {
+			case "Channel":
+				pan.channel = value
+			case "Pan ID":
+				pan.panID = value
+			case "Addr":
+				pan.addr = value
+			}
+			return false, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("アクティブスキャンに失敗: %w", err)
+		}
+		if pan.channel != "" && pan.panID != "" && pan.addr != "" {
+			return pan, nil
+		}
+	}
+	return nil, errors.New("スマート電力量メーターが見つかりません")
+}
+
+// linkLocalAddress は MAC アドレスから IPv6 リンクローカルアドレスを求めます
+// SKLL64 は OK を返さず、アドレスの行だけを返します
+func (c *WiSUNConnection) linkLocalAddress(ctx context.Context, addr string) (net.IP, error) {
+	c.drainLines()
+	if err := c.writeLine("SKLL64 " + addr); err != nil {
+		return nil, err
+	}
+	var ip net.IP
+	err := c.waitFor(ctx, wisunCommandWait, func(line string) (bool, error) {
+		if strings.HasPrefix(line, "FAIL") {
+			return false, errors.New(line)
+		}
+		ip = net.ParseIP(strings.TrimSpace(line))
+		return ip != nil, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("メーターのアドレスの取得に失敗: %w", err)
+	}
+	return ip, nil
+}
+
+// join はメーターとの PANA 認証を行います
+func (c *WiSUNConnection) join(ctx context.Context) error {
+	if _, err := c.command(ctx, "SKJOIN "+wisunAddress(c.meterIP)); err != nil {
+		return fmt.Errorf("PANA 認証の開始に失敗: %w", err)
+	}
+	err := c.waitFor(ctx, wisunJoinWait, func(line string) (bool, error) {
+		switch {
+		case strings.HasPrefix(line, "EVENT 25"):
+			return true, nil
+		case strings.HasPrefix(line, "EVENT 24"):
+			return false, errors.New("認証に失敗しました。Bルート認証IDとパスワードを確認してください")
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("PANA 認証に失敗: %w", err)
+	}
+	return nil
+}
+
+// rejoinLoop は PANA セッションが切れたときに再認証を行います
+func (c *WiSUNConnection) rejoinLoop() {
+	defer c.wg.Done()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.rejoin:
+		}
+		for {
+			slog.Info("スマート電力量メーターに再接続します", "meter", c.meterIP)
+			c.cmdMu.Lock()
+			err := c.join(c.ctx)
+			c.cmdMu.Unlock()
+			if err == nil {
+				break
+			}
+			slog.Warn("スマート電力量メーターへの再接続に失敗", "err", err)
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-time.After(wisunRejoinBackoff):
+			}
+		}
+	}
+}
+
+// command はコマンドを送信し、OK までの応答行を返します。FAIL の場合はエラーを返します
+// 呼び出し元で cmdMu を保持すること
+func (c *WiSUNConnection) command(ctx context.Context, cmd string) ([]string, error) {
+	c.drainLines()
+	if err := c.writeLine(cmd); err != nil {
+		return nil, err
+	}
+	return c.waitResponse(ctx, cmd)
+}
+
+// waitResponse は OK または FAIL までの応答行を返します
+func (c *WiSUNConnection) waitResponse(ctx context.Context, cmd string) ([]string, error) {
+	var result []string
+	err := c.waitFor(ctx, wisunCommandWait, func(line string) (bool, error) {
+		if line == cmd {
+			// エコーバック
+			return false, nil
+		}
+		result = append(result, line)
+		if strings.HasPrefix(line, "FAIL") {
+			return false, fmt.Errorf("%s: %s", strings.Fields(cmd)[0], line)
+		}
+		return line == "OK" || strings.HasPrefix(line, "OK "), nil
+	})
+	return result, err
+}
+
+// waitFor は、match が true かエラーを返すまで受信した行を渡します
+func (c *WiSUNConnection) waitFor(ctx context.Context, timeout time.Duration, match func(line string) (bool, error)) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.ctx.Done():
+			return net.ErrClosed
+		case <-timer.C:
+			return errors.New("Wi-SUN アダプターからの応答がタイムアウトしました")
+		case line := <-c.lines:
+			done, err := match(line)
+			if err != nil || done {
+				return err
+			}
+		}
+	}
+}
+
+// drainLines は、前のコマンドの応答などの読み残した行を捨てます
+func (c *WiSUNConnection) drainLines() {
+	for {
+		select {
+		case <-c.lines:
+		default:
+			return
+		}
+	}
+}
+
+func (c *WiSUNConnection) writeLine(line string) error {
+	if c.config.Debug {
+		slog.Debug("Wi-SUN 送信", "command", line)
+	}
+	_, err := io.WriteString(c.port, line+"\r\n")
+	return err
+}
+
+// readLoop はアダプターからの出力を行ごとに読み、ERXUDP は packets に、それ以外は lines に渡します
+func (c *WiSUNConnection) readLoop() {
+	defer c.wg.Done()
+	reader := bufio.NewReader(c.port)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if c.ctx.Err() == nil {
+				slog.Warn("Wi-SUN アダプターからの読み込みに失敗", "err", err)
+				c.deliver(udpPacket{err: err})
+			}
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			continue
+		}
+		if c.config.Debug {
+			slog.Debug("Wi-SUN 受信", "line", line)
+		}
+
+		switch {
+		case strings.HasPrefix(line, "ERXUDP "):
+			if p, ok := parseERXUDP(line); ok {
+				if !c.deliver(p) {
+					return
+				}
+			}
+			continue
+		case strings.HasPrefix(line, "EVENT 26"), strings.HasPrefix(line, "EVENT 27"), strings.HasPrefix(line, "EVENT 28"):
+			// セッション終了（26: メーターから, 27: 自分から, 28: タイムアウト）
+			if c.joined.Load() {
+				select {
+				case c.rejoin <- struct{}{}:
+				default:
+				}
+			}
+		}
+
+		select {
+		case c.lines <- line:
+		default:
+			// コマンドの応答を待っていないときの出力は捨てる
+		}
+	}
+}
+
+// deliver は受信パケットを Receive に渡します。接続が閉じられた場合は false を返します
+func (c *WiSUNConnection) deliver(p udpPacket) bool {
+	select {
+	case c.packets <- p:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+// parseERXUDP は ERXUDP イベントから ECHONET Lite のパケットを取り出します
+// BP35A1: ERXUDP SENDER DEST RPORT LPORT SENDERLLA SECURED DATALEN DATA
+// BP35C0 などはフィールドが増えますが、末尾の DATALEN DATA は共通です
+func parseERXUDP(line string) (udpPacket, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 9 {
+		return udpPacket{}, false
+	}
+	lport, err := strconv.ParseUint(fields[4], 16, 16)
+	if err != nil || lport != wisunECHONETPort {
+		return udpPacket{}, false
+	}
+	rport, err := strconv.ParseUint(fields[3], 16, 16)
+	if err != nil {
+		return udpPacket{}, false
+	}
+	length, err := strconv.ParseUint(fields[len(fields)-2], 16, 16)
+	if err != nil {
+		return udpPacket{}, false
+	}
+	data, err := hex.DecodeString(fields[len(fields)-1])
+	if err != nil || len(data) != int(length) {
+		return udpPacket{}, false
+	}
+	ip := net.ParseIP(fields[1])
+	if ip == nil {
+		return udpPacket{}, false
+	}
+	return udpPacket{data: data, addr: &net.UDPAddr{IP: ip, Port: int(rport)}}, true
+}
+
+// wisunAddress は SKSTACK のコマンドで使う形式（省略なしの IPv6 アドレス）に変換します
+func wisunAddress(ip net.IP) string {
+	ip16 := ip.To16()
+	parts := make([]string, 8)
+	for i := range parts {
+		parts[i] = fmt.Sprintf("%02X%02X", ip16[2*i], ip16[2*i+1])
+	}
+	return strings.Join(parts, ":")
+}
+
+// SendTo はメーターにデータを送信します
+// Bルートの通信相手はメーターのみのため、宛先がメーター以外（ブロードキャストなど）でもメーターに送ります
+func (c *WiSUNConnection) SendTo(dstIP net.IP, data []byte) (int, error) {
+	if c.ctx.Err() != nil {
+		return 0, net.ErrClosed
+	}
+	c.cmdMu.Lock()
+	defer c.cmdMu.Unlock()
+
+	c.drainLines()
+	header := fmt.Sprintf("SKSENDTO 1 %s %04X 1 %04X ", wisunAddress(c.meterIP), wisunECHONETPort, len(data))
+	if c.config.Debug {
+		slog.Debug("Wi-SUN 送信", "command", header, "data", hex.EncodeToString(data))
+	}
+	if _, err := c.port.Write(append([]byte(header), data...)); err != nil {
+		return 0, err
+	}
+	if _, err := c.waitResponse(c.ctx, "SKSENDTO"); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Receive はメーターからのパケットを受信します
+func (c *WiSUNConnection) Receive(ctx context.Context) ([]byte, *net.UDPAddr, error) {
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-c.ctx.Done():
+		return nil, nil, net.ErrClosed
+	case p := <-c.packets:
+		return p.data, p.addr, p.err
+	}
+}
+
+// IsLocalIP は常に false を返します（アダプターは自分宛ての送信を返さないため）
+func (c *WiSUNConnection) IsLocalIP(ip net.IP) bool {
+	return false
+}
+
+// SetReplyAddress は Wi-SUN では使えません
+func (c *WiSUNConnection) SetReplyAddress(ip net.IP) error {
+	return errors.New("Wi-SUN 接続では応答アドレスを設定できません")
+}
+
+// Close はシリアルポートを閉じます
+func (c *WiSUNConnection) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.cancel()
+		err = c.port.Close()
+		c.wg.Wait()
+	})
+	return err
+}
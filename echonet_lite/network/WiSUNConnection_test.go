@@ -0,0 +1,211 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fakeMeterMAC = "001C640000000001"
+	fakeMeterLLA = "FE80:0000:0000:0000:021C:6400:0000:0001"
+)
+
+// fakeSKStack emulates a BP35A1 compatible Wi-SUN adapter with a smart meter in range.
+type fakeSKStack struct {
+	conn     net.Conn
+	password string // password the meter accepts
+
+	mu      sync.Mutex
+	scans   int
+	joins   int
+	sent    [][]byte
+	sentDst []string
+}
+
+func newFakeSKStack(t *testing.T, password string) (*fakeSKStack, net.Conn) {
+	adapter, host := net.Pipe()
+	f := &fakeSKStack{conn: adapter, password: password}
+	go f.serve()
+	t.Cleanup(func() { adapter.Close() })
+	return f, host
+}
+
+func (f *fakeSKStack) write(lines ...string) {
+	for _, line := range lines {
+		if _, err := io.WriteString(f.conn, line+"\r\n"); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeSKStack) serve() {
+	r := bufio.NewReader(f.conn)
+	var buf []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		buf = append(buf, b)
+		s := string(buf)
+		// SKSENDTO is followed by binary data of the given length instead of a line ending
+		if strings.HasPrefix(s, "SKSENDTO ") && strings.Count(s, " ") == 6 {
+			fields := strings.Fields(s)
+			n, _ := strconv.ParseUint(fields[5], 16, 16)
+			data := make([]byte, n)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.sent = append(f.sent, data)
+			f.sentDst = append(f.sentDst, fields[2])
+			f.mu.Unlock()
+			f.write("EVENT 21 "+fields[2]+" 00", "OK")
+			buf = nil
+			continue
+		}
+		if strings.HasSuffix(s, "\r\n") {
+			f.handle(strings.TrimSuffix(s, "\r\n"))
+			buf = nil
+		}
+	}
+}
+
+func (f *fakeSKStack) handle(cmd string) {
+	fields := strings.Fields(cmd)
+	switch fields[0] {
+	case "ROPT":
+		f.write("OK 00")
+	case "SKSETPWD":
+		if fields[2] != f.password {
+			// The adapter accepts any password; the meter rejects it during PANA authentication
+			f.password = ""
+		}
+		f.write("OK")
+	case "SKSCAN":
+		f.mu.Lock()
+		f.scans++
+		found := f.scans > 1
+		f.mu.Unlock()
+		f.write("OK")
+		if found {
+			// The meter is only found by the second (longer) scan
+			f.write("EVENT 20 "+fakeMeterLLA, "EPANDESC", "  Channel:21", "  Channel Page:09", "  Pan ID:8888",
+				"  Addr:"+fakeMeterMAC, "  LQI:E1", "  PairID:00000001")
+		}
+		f.write("EVENT 22 FE80:0000:0000:0000:0000:0000:0000:0002")
+	case "SKLL64":
+		if fields[1] == fakeMeterMAC {
+			f.write(fakeMeterLLA)
+		} else {
+			f.write("FAIL ER06")
+		}
+	case "SKJOIN":
+		f.mu.Lock()
+		f.joins++
+		f.mu.Unlock()
+		f.write("OK", "EVENT 21 "+fields[1]+" 00")
+		if f.password == "" {
+			f.write("EVENT 24 " + fields[1])
+		} else {
+			f.write("EVENT 25 " + fields[1])
+		}
+	default:
+		f.write("OK")
+	}
+}
+
+func (f *fakeSKStack) counts() (scans, joins int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.scans, f.joins
+}
+
+func TestWiSUNConnection_ConnectSendReceive(t *testing.T) {
+	fake, host := newFakeSKStack(t, "0123456789AB")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := NewWiSUNConnection(ctx, host, WiSUNConfig{RouteBID: "00000000000000000000000000000001", RouteBPassword: "0123456789AB"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, "fe80::21c:6400:0:1", conn.MeterIP().String())
+	scans, joins := fake.counts()
+	assert.Equal(t, 2, scans, "scan should be retried with a longer duration")
+	assert.Equal(t, 1, joins)
+
+	// A broadcast is sent to the meter, the only node on Route B
+	request := []byte{0x10, 0x81, 0x00, 0x01, 0x05, 0xFF, 0x01, 0x0E, 0xF0, 0x01, 0x62, 0x01, 0xD6, 0x00}
+	n, err := conn.SendTo(net.IPv4bcast, request)
+	require.NoError(t, err)
+	assert.Equal(t, len(request), n)
+	fake.mu.Lock()
+	assert.Equal(t, [][]byte{request}, fake.sent)
+	assert.Equal(t, []string{fakeMeterLLA}, fake.sentDst)
+	fake.mu.Unlock()
+
+	fake.write(fmt.Sprintf("ERXUDP %s FE80:0000:0000:0000:021D:1290:0003:C890 0E1A 0E1A 001C640000000001 1 0004 1081000A", fakeMeterLLA))
+	recvCtx, recvCancel := context.WithTimeout(ctx, time.Second)
+	defer recvCancel()
+	data, addr, err := conn.Receive(recvCtx)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x10, 0x81, 0x00, 0x0A}, data)
+	assert.Equal(t, "fe80::21c:6400:0:1", addr.IP.String())
+	assert.Equal(t, 0x0E1A, addr.Port)
+}
+
+func TestWiSUNConnection_Rejoin(t *testing.T) {
+	fake, host := newFakeSKStack(t, "0123456789AB")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := NewWiSUNConnection(ctx, host, WiSUNConfig{RouteBID: "00000000000000000000000000000001", RouteBPassword: "0123456789AB"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The meter terminated the PANA session
+	fake.write("EVENT 26 " + fakeMeterLLA)
+	assert.Eventually(t, func() bool {
+		_, joins := fake.counts()
+		return joins == 2
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestWiSUNConnection_AuthenticationFailure(t *testing.T) {
+	_, host := newFakeSKStack(t, "0123456789AB")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := NewWiSUNConnection(ctx, host, WiSUNConfig{RouteBID: "00000000000000000000000000000001", RouteBPassword: "wrong"})
+	assert.ErrorContains(t, err, "PANA")
+
+	_, err = NewWiSUNConnection(ctx, host, WiSUNConfig{})
+	assert.Error(t, err, "credentials are required")
+}
+
+func TestParseERXUDP(t *testing.T) {
+	// BP35C0 adds RSSI and SIDE fields before DATALEN
+	p, ok := parseERXUDP("ERXUDP FE80:0000:0000:0000:021C:6400:0000:0001 FE80:0000:0000:0000:021D:1290:0003:C890 0E1A 0E1A 001C640000000001 E1 1 0 0002 1081")
+	require.True(t, ok)
+	assert.Equal(t, []byte{0x10, 0x81}, p.data)
+
+	// Packets that are not ECHONET Lite (e.g. PANA on port 716) are ignored
+	_, ok = parseERXUDP("ERXUDP FE80:0000:0000:0000:021C:6400:0000:0001 FE80:0000:0000:0000:021D:1290:0003:C890 02CC 02CC 001C640000000001 1 0002 1081")
+	assert.False(t, ok)
+
+	// DATALEN must match the data
+	_, ok = parseERXUDP("ERXUDP FE80:0000:0000:0000:021C:6400:0000:0001 FE80:0000:0000:0000:021D:1290:0003:C890 0E1A 0E1A 001C640000000001 1 0003 1081")
+	assert.False(t, ok)
+}
@@ -2,6 +2,7 @@ package network
 
 import (
 	"fmt"
+	"log/slog"
 	"net"
 )
 
@@ -13,7 +14,7 @@ func GetIPv4BroadcastIP() net.IP {
 	// すべてのネットワークインターフェースを取得
 	interfaces, err := net.Interfaces()
 	if err != nil {
-		fmt.Printf("ネットワークインターフェースの取得に失敗しました: %v\n", err)
+		slog.Warn("ネットワークインターフェースの取得に失敗しました", "err", err)
 		return defaultBroadcast
 	}
 
@@ -55,7 +56,7 @@ func GetIPv4BroadcastIP() net.IP {
 				broadcast[i] = ip4[i] | ^ipnet.Mask[i]
 			}
 
-			fmt.Printf("インターフェース %s のIPv4ブロードキャストアドレス: %v\n", iface.Name, broadcast)
+			slog.Debug("IPv4ブロードキャストアドレスを検出しました", "interface", iface.Name, "broadcast", broadcast)
 			return broadcast
 		}
 	}
@@ -64,6 +65,27 @@ func GetIPv4BroadcastIP() net.IP {
 	return defaultBroadcast
 }
 
+// GetIPv4BroadcastIPFor は、ip が割り当てられたインターフェースのIPv4ブロードキャストアドレスを返します
+// ip が未指定（nil または 0.0.0.0）の場合や見つからない場合は GetIPv4BroadcastIP の結果を返します
+func GetIPv4BroadcastIPFor(ip net.IP) net.IP {
+	ip4 := ip.To4()
+	if ip4 == nil || ip4.IsUnspecified() {
+		return GetIPv4BroadcastIP()
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return GetIPv4BroadcastIP()
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.Equal(ip4) {
+			if broadcast := calcIPv4Broadcast(ipnet); broadcast != nil {
+				return broadcast
+			}
+		}
+	}
+	return GetIPv4BroadcastIP()
+}
+
 // GetLocalUDPAddressFor は、指定された宛先IPアドレスとポートに対するローカルアドレスを取得します
 func GetLocalUDPAddressFor(ip net.IP, port int) (*net.UDPAddr, error) {
 	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: ip, Port: port})
@@ -78,6 +100,16 @@ func GetLocalUDPAddressFor(ip net.IP, port int) (*net.UDPAddr, error) {
 
 // GetLocalIPv4s はローカルマシンの非ループバックIPv4アドレスのリストを取得します
 func GetLocalIPv4s() ([]net.IP, error) {
+	return getLocalIPs(false)
+}
+
+// GetLocalIPv6s はローカルマシンの非ループバックIPv6アドレス（リンクローカルを含む）のリストを取得します
+func GetLocalIPv6s() ([]net.IP, error) {
+	return getLocalIPs(true)
+}
+
+// getLocalIPs はローカルマシンの非ループバックアドレスのうち、指定したファミリーのものを取得します
+func getLocalIPs(ipv6 bool) ([]net.IP, error) {
 	localIPs := []net.IP{}
 	ifaces, err := net.Interfaces()
 	if err != nil {
@@ -91,7 +123,7 @@ func GetLocalIPv4s() ([]net.IP, error) {
 		addrs, err := i.Addrs()
 		if err != nil {
 			// エラーが発生しても他のインターフェースの処理を続ける
-			fmt.Printf("Warning: failed to get addresses for interface %s: %v\n", i.Name, err)
+			slog.Warn("Failed to get addresses for interface", "interface", i.Name, "err", err)
 			continue
 		}
 		for _, addr := range addrs {
@@ -102,19 +134,67 @@ func GetLocalIPv4s() ([]net.IP, error) {
 			case *net.IPAddr:
 				ip = v.IP
 			}
-			// IPv4 アドレスのみを対象とする
-			if ip != nil && ip.To4() != nil {
+			// 指定したファミリーのアドレスのみを対象とする
+			if ip != nil && (ip.To4() == nil) == ipv6 {
 				localIPs = append(localIPs, ip)
 			}
 		}
 	}
 	if len(localIPs) == 0 {
 		// 適切なIPが見つからなかった場合は警告を出す
-		fmt.Println("Warning: no suitable local IPv4 addresses found.")
+		if ipv6 {
+			slog.Warn("No suitable local IPv6 addresses found")
+		} else {
+			slog.Warn("No suitable local IPv4 addresses found")
+		}
 	}
 	return localIPs, nil
 }
 
+// defaultIPv6MulticastInterface は、IPv6 マルチキャストに使うインターフェースを選びます
+// 起動していてマルチキャストに対応し、IPv6 アドレスを持つ最初の非ループバックインターフェースを返します
+func defaultIPv6MulticastInterface() (*net.Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interfaces: %w", err)
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() == nil {
+				return &iface, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no network interface with an IPv6 address is available for multicast")
+}
+
+// GetMulticastInterfaces は、指定された名前のインターフェースを返します。
+// 存在しない、起動していない、マルチキャストに対応していないインターフェースはエラーになります。
+func GetMulticastInterfaces(names []string) ([]net.Interface, error) {
+	result := make([]net.Interface, 0, len(names))
+	for _, name := range names {
+		iface, err := net.InterfaceByName(name)
+		if err != nil {
+			return nil, fmt.Errorf("interface %s: %w", name, err)
+		}
+		if iface.Flags&net.FlagUp == 0 {
+			return nil, fmt.Errorf("interface %s is down", name)
+		}
+		if iface.Flags&net.FlagMulticast == 0 {
+			return nil, fmt.Errorf("interface %s does not support multicast", name)
+		}
+		result = append(result, *iface)
+	}
+	return result, nil
+}
+
 // GetMACAddressByIP は、指定されたIPアドレスに紐づくネットワークインターフェースのMACアドレスを取得します。
 func GetMACAddressByIP(ip net.IP) ([]byte, error) {
 	interfaces, err := net.Interfaces()
@@ -137,3 +217,71 @@ func GetMACAddressByIP(ip net.IP) ([]byte, error) {
 	}
 	return nil, fmt.Errorf("MAC address not found for IP: %s", ip.String())
 }
+
+// InterfaceBroadcast はネットワークインターフェースとその IPv4 ブロードキャストアドレスの組です
+type InterfaceBroadcast struct {
+	Name      string // インターフェース名
+	IP        net.IP // インターフェースの IPv4 アドレス
+	Broadcast net.IP // ブロードキャストアドレス
+}
+
+// calcIPv4Broadcast は IPv4 ネットワークアドレスからブロードキャストアドレスを計算します。
+// IPv4 でない場合は nil を返します
+func calcIPv4Broadcast(ipnet *net.IPNet) net.IP {
+	ip4 := ipnet.IP.To4()
+	if ip4 == nil {
+		return nil
+	}
+	mask := ipnet.Mask
+	if len(mask) == net.IPv6len {
+		mask = mask[12:]
+	}
+	if len(mask) != net.IPv4len {
+		return nil
+	}
+	broadcast := net.IP(make([]byte, 4))
+	for i := range ip4 {
+		broadcast[i] = ip4[i] | ^mask[i]
+	}
+	return broadcast
+}
+
+// GetIPv4BroadcastIPsForInterfaces は、指定された名前のインターフェースごとに
+// IPv4 ブロードキャストアドレスを返します。
+// 存在しない、起動していない、IPv4 アドレスを持たないインターフェースはエラーになります。
+func GetIPv4BroadcastIPsForInterfaces(names []string) ([]InterfaceBroadcast, error) {
+	result := make([]InterfaceBroadcast, 0, len(names))
+	for _, name := range names {
+		iface, err := net.InterfaceByName(name)
+		if err != nil {
+			return nil, fmt.Errorf("interface %s: %w", name, err)
+		}
+		if iface.Flags&net.FlagUp == 0 {
+			return nil, fmt.Errorf("interface %s is down", name)
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get addresses for interface %s: %w", name, err)
+		}
+		found := false
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			if broadcast := calcIPv4Broadcast(ipnet); broadcast != nil {
+				result = append(result, InterfaceBroadcast{
+					Name:      name,
+					IP:        ipnet.IP.To4(),
+					Broadcast: broadcast,
+				})
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("interface %s has no IPv4 address", name)
+		}
+	}
+	return result, nil
+}
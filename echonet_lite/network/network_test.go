@@ -0,0 +1,33 @@
+package network
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcIPv4Broadcast(t *testing.T) {
+	tests := []struct {
+		name string
+		cidr string
+		want net.IP
+	}{
+		{"class C", "192.168.1.23/24", net.IPv4(192, 168, 1, 255).To4()},
+		{"narrow", "10.0.0.5/30", net.IPv4(10, 0, 0, 7).To4()},
+		{"IPv6", "fe80::1/64", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, ipnet, err := net.ParseCIDR(tt.cidr)
+			assert.NoError(t, err)
+			ipnet.IP = ip
+			assert.Equal(t, tt.want, calcIPv4Broadcast(ipnet))
+		})
+	}
+}
+
+func TestGetIPv4BroadcastIPsForInterfaces_UnknownInterface(t *testing.T) {
+	_, err := GetIPv4BroadcastIPsForInterfaces([]string{"no-such-interface0"})
+	assert.Error(t, err)
+}
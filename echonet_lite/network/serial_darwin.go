@@ -0,0 +1,14 @@
+package network
+
+import "golang.org/x/sys/unix"
+
+const (
+	ioctlGetTermios = unix.TIOCGETA
+	ioctlSetTermios = unix.TIOCSETA
+)
+
+func setTermiosSpeed(t *unix.Termios, baudRate int) error {
+	t.Ispeed = uint64(baudRate)
+	t.Ospeed = uint64(baudRate)
+	return nil
+}
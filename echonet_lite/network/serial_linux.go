@@ -0,0 +1,34 @@
+package network
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ioctlGetTermios = unix.TCGETS
+	ioctlSetTermios = unix.TCSETS
+)
+
+// linuxBaudRates は Linux の termios で指定できるボーレートです
+var linuxBaudRates = map[int]uint32{
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+	230400: unix.B230400,
+}
+
+func setTermiosSpeed(t *unix.Termios, baudRate int) error {
+	speed, ok := linuxBaudRates[baudRate]
+	if !ok {
+		return fmt.Errorf("unsupported baud rate: %d", baudRate)
+	}
+	t.Cflag &^= unix.CBAUD
+	t.Cflag |= speed
+	t.Ispeed = speed
+	t.Ospeed = speed
+	return nil
+}
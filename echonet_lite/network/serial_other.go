@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package network
+
+import (
+	"errors"
+	"io"
+)
+
+// openSerialPort はこのプラットフォームでは使えません
+func openSerialPort(path string, baudRate int) (io.ReadWriteCloser, error) {
+	return nil, errors.New("serial ports are not supported on this platform")
+}
@@ -0,0 +1,39 @@
+//go:build linux || darwin
+
+package network
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// openSerialPort はシリアルポートを raw モード（8N1）で開きます
+func openSerialPort(path string, baudRate int) (io.ReadWriteCloser, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	t, err := unix.IoctlGetTermios(int(f.Fd()), ioctlGetTermios)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON | unix.IXOFF
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.CSTOPB
+	t.Cflag |= unix.CS8 | unix.CREAD | unix.CLOCAL
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+	if err := setTermiosSpeed(t, baudRate); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if err := unix.IoctlSetTermios(int(f.Fd()), ioctlSetTermios, t); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -4,6 +4,7 @@ package network
 
 import "syscall"
 
+// setsockoptInt はプラットフォーム毎に異なる syscall.SetsockoptInt のシグネチャを吸収します
 func setsockoptInt(fd uintptr, level, opt, value int) error {
 	return syscall.SetsockoptInt(syscall.Handle(fd), level, opt, value)
 }
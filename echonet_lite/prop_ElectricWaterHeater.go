@@ -0,0 +1,263 @@
+package echonet_lite
+
+const (
+	// EPC
+	EPC_EWH_AutomaticHeating     EPCType = 0xB0 // 沸き上げ自動設定
+	EPC_EWH_HeatingStatus        EPCType = 0xB2 // 沸き上げ中状態
+	EPC_EWH_HeatingTemperature   EPCType = 0xB3 // 沸き上げ湯温設定値
+	EPC_EWH_TankOperationMode    EPCType = 0xB6 // 給湯器運転モード設定
+	EPC_EWH_DaytimeReheating     EPCType = 0xC0 // 昼間沸き増し許可設定
+	EPC_EWH_TankTemperature      EPCType = 0xC1 // 温水器湯温計測値
+	EPC_EWH_HotWaterSupplyStatus EPCType = 0xC3 // 給湯中状態
+	EPC_EWH_SupplyTemperature    EPCType = 0xD1 // 給湯温度設定値
+	EPC_EWH_BathTemperature      EPCType = 0xD3 // 風呂温度設定値
+	EPC_EWH_RemainingHotWater    EPCType = 0xE1 // 残湯量計測値
+	EPC_EWH_TankCapacity         EPCType = 0xE2 // タンク容量値
+	EPC_EWH_AutomaticBathMode    EPCType = 0xE3 // 風呂自動モード設定
+	EPC_EWH_BathOperationStatus  EPCType = 0xEA // 風呂動作状態監視
+)
+
+func (r PropertyRegistry) ElectricWaterHeater() PropertyTable {
+	TemperatureDesc := NumberDesc{Min: 0, Max: 100, Unit: "℃"}
+	VolumeDesc := NumberDesc{Min: 0, Max: 65533, Unit: "L", EDTLen: 2}
+
+	return PropertyTable{
+		ClassCode:   ElectricWaterHeater_ClassCode,
+		Description: "Electric Water Heater",
+		DescriptionTranslations: map[string]string{
+			"ja": "電気式給湯器",
+		},
+		EPCDesc: map[EPCType]PropertyDesc{
+			EPC_EWH_AutomaticHeating: {
+				Name: "Automatic water heating setting",
+				NameTranslations: map[string]string{
+					"ja": "沸き上げ自動設定",
+				},
+				ShortName: "Water heating",
+				ShortNameTranslations: map[string]string{
+					"ja": "沸き上げ",
+				},
+				Aliases: map[string][]byte{
+					"auto_heating":        {0x41},
+					"manual_heating":      {0x42},
+					"manual_heating_stop": {0x43},
+				},
+				AliasTranslations: map[string]map[string]string{
+					"ja": {
+						"auto_heating":        "自動沸き上げ",
+						"manual_heating":      "手動沸き上げ",
+						"manual_heating_stop": "手動沸き上げ停止",
+					},
+				},
+				Decoder: nil,
+			},
+			EPC_EWH_HeatingStatus: {
+				Name: "Water heater status",
+				NameTranslations: map[string]string{
+					"ja": "沸き上げ中状態",
+				},
+				ShortName: "Heating status",
+				ShortNameTranslations: map[string]string{
+					"ja": "沸き上げ状態",
+				},
+				Aliases: map[string][]byte{
+					"heating":     {0x41},
+					"not_heating": {0x42},
+				},
+				AliasTranslations: map[string]map[string]string{
+					"ja": {
+						"heating":     "沸き上げ中",
+						"not_heating": "非沸き上げ中",
+					},
+				},
+				Decoder: nil,
+			},
+			EPC_EWH_HeatingTemperature: {
+				Name: "Water heating temperature setting",
+				NameTranslations: map[string]string{
+					"ja": "沸き上げ湯温設定値",
+				},
+				ShortName: "Heating temperature",
+				ShortNameTranslations: map[string]string{
+					"ja": "沸き上げ湯温",
+				},
+				Aliases: nil,
+				Decoder: TemperatureDesc,
+			},
+			EPC_EWH_TankOperationMode: {
+				Name: "Tank operation mode setting",
+				NameTranslations: map[string]string{
+					"ja": "給湯器運転モード設定",
+				},
+				ShortName: "Tank mode",
+				ShortNameTranslations: map[string]string{
+					"ja": "運転モード",
+				},
+				Aliases: map[string][]byte{
+					"standard": {0x41},
+					"saving":   {0x42},
+					"extra":    {0x43},
+				},
+				AliasTranslations: map[string]map[string]string{
+					"ja": {
+						"standard": "標準",
+						"saving":   "節約",
+						"extra":    "多め",
+					},
+				},
+				Decoder: nil,
+			},
+			EPC_EWH_DaytimeReheating: {
+				Name: "Daytime reheating permission setting",
+				NameTranslations: map[string]string{
+					"ja": "昼間沸き増し許可設定",
+				},
+				ShortName: "Daytime reheating",
+				ShortNameTranslations: map[string]string{
+					"ja": "昼間沸き増し",
+				},
+				Aliases: map[string][]byte{
+					"daytime_reheating":    {0x41},
+					"no_daytime_reheating": {0x42},
+				},
+				AliasTranslations: map[string]map[string]string{
+					"ja": {
+						"daytime_reheating":    "昼間沸き増し許可",
+						"no_daytime_reheating": "昼間沸き増し禁止",
+					},
+				},
+				Decoder: nil,
+			},
+			EPC_EWH_TankTemperature: {
+				Name: "Measured temperature of water in water heater",
+				NameTranslations: map[string]string{
+					"ja": "温水器湯温計測値",
+				},
+				ShortName: "Tank temperature",
+				ShortNameTranslations: map[string]string{
+					"ja": "タンク湯温",
+				},
+				Aliases: nil,
+				Decoder: TemperatureDesc,
+			},
+			EPC_EWH_HotWaterSupplyStatus: {
+				Name: "Hot water supply status",
+				NameTranslations: map[string]string{
+					"ja": "給湯中状態",
+				},
+				ShortName: "Supply status",
+				ShortNameTranslations: map[string]string{
+					"ja": "給湯状態",
+				},
+				Aliases: map[string][]byte{
+					"supplying":     {0x41},
+					"not_supplying": {0x42},
+				},
+				AliasTranslations: map[string]map[string]string{
+					"ja": {
+						"supplying":     "給湯中",
+						"not_supplying": "非給湯中",
+					},
+				},
+				Decoder: nil,
+			},
+			EPC_EWH_SupplyTemperature: {
+				Name: "Temperature of supplied water setting",
+				NameTranslations: map[string]string{
+					"ja": "給湯温度設定値",
+				},
+				ShortName: "Supply temperature",
+				ShortNameTranslations: map[string]string{
+					"ja": "給湯温度",
+				},
+				Aliases: nil,
+				Decoder: TemperatureDesc,
+			},
+			EPC_EWH_BathTemperature: {
+				Name: "Bath water temperature setting",
+				NameTranslations: map[string]string{
+					"ja": "風呂温度設定値",
+				},
+				ShortName: "Bath temperature",
+				ShortNameTranslations: map[string]string{
+					"ja": "風呂温度",
+				},
+				Aliases: nil,
+				Decoder: TemperatureDesc,
+			},
+			EPC_EWH_RemainingHotWater: {
+				Name: "Measured amount of hot water remaining in tank",
+				NameTranslations: map[string]string{
+					"ja": "残湯量計測値",
+				},
+				ShortName: "Remaining hot water",
+				ShortNameTranslations: map[string]string{
+					"ja": "残湯量",
+				},
+				Aliases: nil,
+				Decoder: VolumeDesc,
+			},
+			EPC_EWH_TankCapacity: {
+				Name: "Tank capacity",
+				NameTranslations: map[string]string{
+					"ja": "タンク容量値",
+				},
+				ShortName: "Tank capacity",
+				ShortNameTranslations: map[string]string{
+					"ja": "タンク容量",
+				},
+				Aliases: nil,
+				Decoder: VolumeDesc,
+			},
+			EPC_EWH_AutomaticBathMode: {
+				Name: "Automatic bath water heating mode setting",
+				NameTranslations: map[string]string{
+					"ja": "風呂自動モード設定",
+				},
+				ShortName: "Auto bath",
+				ShortNameTranslations: map[string]string{
+					"ja": "風呂自動",
+				},
+				Aliases: map[string][]byte{
+					"bath_auto_on":  {0x41},
+					"bath_auto_off": {0x42},
+				},
+				AliasTranslations: map[string]map[string]string{
+					"ja": {
+						"bath_auto_on":  "風呂自動入",
+						"bath_auto_off": "風呂自動切",
+					},
+				},
+				Decoder: nil,
+			},
+			EPC_EWH_BathOperationStatus: {
+				Name: "Bath operation status monitor",
+				NameTranslations: map[string]string{
+					"ja": "風呂動作状態監視",
+				},
+				ShortName: "Bath status",
+				ShortNameTranslations: map[string]string{
+					"ja": "風呂動作状態",
+				},
+				Aliases: map[string][]byte{
+					"filling": {0x41},
+					"stopped": {0x42},
+					"keeping": {0x43},
+				},
+				AliasTranslations: map[string]map[string]string{
+					"ja": {
+						"filling": "湯張り中",
+						"stopped": "停止中",
+						"keeping": "保温中",
+					},
+				},
+				Decoder: nil,
+			},
+		},
+		DefaultEPCs: []EPCType{
+			EPC_EWH_RemainingHotWater,
+			EPC_EWH_TankTemperature,
+			EPC_EWH_HeatingStatus,
+		},
+	}
+}
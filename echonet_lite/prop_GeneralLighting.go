@@ -0,0 +1,110 @@
+package echonet_lite
+
+const (
+	// EPC
+	EPC_GL_Illuminance     EPCType = 0xb0 // 照度レベル設定
+	EPC_GL_LightColor      EPCType = 0xb1 // 光色設定
+	EPC_GL_IlluminanceStep EPCType = 0xb2 // 照度レベル段数指定設定
+	EPC_GL_LightColorStep  EPCType = 0xb3 // 光色段数指定設定
+	EPC_GL_LightingMode    EPCType = 0xb6 // 点灯モード設定
+)
+
+func (r PropertyRegistry) GeneralLighting() PropertyTable {
+	return PropertyTable{
+		ClassCode:   GeneralLighting_ClassCode,
+		Description: "General Lighting",
+		DescriptionTranslations: map[string]string{
+			"ja": "一般照明",
+		},
+		EPCDesc: map[EPCType]PropertyDesc{
+			EPC_GL_Illuminance: {
+				Name: "Illuminance level",
+				NameTranslations: map[string]string{
+					"ja": "照度レベル",
+				},
+				Aliases: nil,
+				Decoder: NumberDesc{Min: 0, Max: 100, Unit: "%"},
+			},
+			EPC_GL_LightColor: {
+				Name: "Light color setting",
+				NameTranslations: map[string]string{
+					"ja": "光色設定",
+				},
+				ShortName: "Light color",
+				ShortNameTranslations: map[string]string{
+					"ja": "光色",
+				},
+				Aliases: map[string][]byte{
+					"other":          {0x40},
+					"incandescent":   {0x41},
+					"white":          {0x42},
+					"daylight_white": {0x43},
+					"daylight":       {0x44},
+				},
+				AliasTranslations: map[string]map[string]string{
+					"ja": {
+						"other":          "その他",
+						"incandescent":   "電球色",
+						"white":          "白色",
+						"daylight_white": "昼白色",
+						"daylight":       "昼光色",
+					},
+				},
+				Decoder: nil,
+			},
+			EPC_GL_IlluminanceStep: {
+				Name: "Illuminance level step setting",
+				NameTranslations: map[string]string{
+					"ja": "照度レベル段数指定設定",
+				},
+				ShortName: "Illuminance step",
+				ShortNameTranslations: map[string]string{
+					"ja": "照度段数",
+				},
+				Aliases: nil,
+				Decoder: NumberDesc{Min: 1, Max: 255},
+			},
+			EPC_GL_LightColorStep: {
+				Name: "Light color step setting",
+				NameTranslations: map[string]string{
+					"ja": "光色段数指定設定",
+				},
+				ShortName: "Light color step",
+				ShortNameTranslations: map[string]string{
+					"ja": "光色段数",
+				},
+				Aliases: nil,
+				Decoder: NumberDesc{Min: 1, Max: 255},
+			},
+			EPC_GL_LightingMode: {
+				Name: "Lighting mode setting",
+				NameTranslations: map[string]string{
+					"ja": "点灯モード設定",
+				},
+				ShortName: "Lighting mode",
+				ShortNameTranslations: map[string]string{
+					"ja": "点灯モード",
+				},
+				Aliases: map[string][]byte{
+					"auto":   {0x41},
+					"normal": {0x42},
+					"night":  {0x43},
+					"color":  {0x45},
+				},
+				AliasTranslations: map[string]map[string]string{
+					"ja": {
+						"auto":   "自動",
+						"normal": "通常灯",
+						"night":  "常夜灯",
+						"color":  "カラー灯",
+					},
+				},
+				Decoder: nil,
+			},
+		},
+		DefaultEPCs: []EPCType{
+			EPC_GL_Illuminance,
+			EPC_GL_LightColor,
+		},
+	}
+}
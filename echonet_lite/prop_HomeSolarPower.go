@@ -0,0 +1,114 @@
+package echonet_lite
+
+const (
+	// EPC
+	EPC_PV_GridConnectionType      EPCType = 0xD0 // 系統連系状態
+	EPC_PV_InstantaneousGeneration EPCType = 0xE0 // 瞬時発電電力計測値
+	EPC_PV_CumulativeGeneration    EPCType = 0xE1 // 積算発電電力量計測値
+	EPC_PV_CumulativeSold          EPCType = 0xE3 // 積算売電電力量計測値
+	EPC_PV_OutputLimit             EPCType = 0xE5 // 発電電力制限設定1
+	EPC_PV_RatedGenerationOutput   EPCType = 0xE8 // 定格発電電力値(系統連系時)
+)
+
+func (r PropertyRegistry) HomeSolarPower() PropertyTable {
+	// 積算電力量は 0.001kWh 単位なので Wh として扱う
+	EnergyDesc := NumberDesc{Min: 0, Max: 999999999, Unit: "Wh", EDTLen: 4}
+	PowerDesc := NumberDesc{Min: 0, Max: 65533, Unit: "W", EDTLen: 2}
+
+	return PropertyTable{
+		ClassCode:   HomeSolarPower_ClassCode,
+		Description: "Household Solar Power Generation",
+		DescriptionTranslations: map[string]string{
+			"ja": "住宅用太陽光発電",
+		},
+		EPCDesc: map[EPCType]PropertyDesc{
+			EPC_PV_GridConnectionType: {
+				Name: "System-interconnected type",
+				NameTranslations: map[string]string{
+					"ja": "系統連系状態",
+				},
+				ShortName: "Grid connection",
+				ShortNameTranslations: map[string]string{
+					"ja": "系統連系",
+				},
+				Aliases: map[string][]byte{
+					"reverse_power_flow":    {0x00},
+					"independent":           {0x01},
+					"no_reverse_power_flow": {0x02},
+				},
+				AliasTranslations: map[string]map[string]string{
+					"ja": {
+						"reverse_power_flow":    "系統連系(逆潮流可)",
+						"independent":           "独立",
+						"no_reverse_power_flow": "系統連系(逆潮流不可)",
+					},
+				},
+				Decoder: nil,
+			},
+			EPC_PV_InstantaneousGeneration: {
+				Name: "Measured instantaneous amount of electricity generated",
+				NameTranslations: map[string]string{
+					"ja": "瞬時発電電力計測値",
+				},
+				ShortName: "Generation",
+				ShortNameTranslations: map[string]string{
+					"ja": "発電電力",
+				},
+				Aliases: nil,
+				Decoder: PowerDesc,
+			},
+			EPC_PV_CumulativeGeneration: {
+				Name: "Measured cumulative amount of electric energy generated",
+				NameTranslations: map[string]string{
+					"ja": "積算発電電力量計測値",
+				},
+				ShortName: "Generated",
+				ShortNameTranslations: map[string]string{
+					"ja": "積算発電量",
+				},
+				Aliases: nil,
+				Decoder: EnergyDesc,
+			},
+			EPC_PV_CumulativeSold: {
+				Name: "Measured cumulative amount of electric energy sold",
+				NameTranslations: map[string]string{
+					"ja": "積算売電電力量計測値",
+				},
+				ShortName: "Sold",
+				ShortNameTranslations: map[string]string{
+					"ja": "積算売電量",
+				},
+				Aliases: nil,
+				Decoder: EnergyDesc,
+			},
+			EPC_PV_OutputLimit: {
+				Name: "Power generation output limit setting 1",
+				NameTranslations: map[string]string{
+					"ja": "発電電力制限設定1",
+				},
+				ShortName: "Output limit",
+				ShortNameTranslations: map[string]string{
+					"ja": "出力制限",
+				},
+				Aliases: nil,
+				Decoder: NumberDesc{Min: 0, Max: 100, Unit: "%"},
+			},
+			EPC_PV_RatedGenerationOutput: {
+				Name: "Rated power generation output (system-interconnected)",
+				NameTranslations: map[string]string{
+					"ja": "定格発電電力値(系統連系時)",
+				},
+				ShortName: "Rated output",
+				ShortNameTranslations: map[string]string{
+					"ja": "定格発電電力",
+				},
+				Aliases: nil,
+				Decoder: PowerDesc,
+			},
+		},
+		DefaultEPCs: []EPCType{
+			EPC_PV_InstantaneousGeneration,
+			EPC_PV_CumulativeGeneration,
+		},
+	}
+}
@@ -0,0 +1,244 @@
+package echonet_lite
+
+import (
+	"fmt"
+	"time"
+
+	"echonet-list/echonet_lite/utils"
+)
+
+const (
+	// EPC
+	EPC_SM_Coefficient                      EPCType = 0xD3 // 係数
+	EPC_SM_EffectiveDigits                  EPCType = 0xD7 // 積算電力量有効桁数
+	EPC_SM_CumulativeEnergy                 EPCType = 0xE0 // 積算電力量計測値(正方向計測値)
+	EPC_SM_CumulativeEnergyUnit             EPCType = 0xE1 // 積算電力量単位(正方向、逆方向計測値)
+	EPC_SM_CumulativeEnergyReverse          EPCType = 0xE3 // 積算電力量計測値(逆方向計測値)
+	EPC_SM_InstantaneousPower               EPCType = 0xE7 // 瞬時電力計測値
+	EPC_SM_InstantaneousCurrent             EPCType = 0xE8 // 瞬時電流計測値
+	EPC_SM_FixedTimeCumulativeEnergy        EPCType = 0xEA // 定時積算電力量計測値(正方向計測値)
+	EPC_SM_FixedTimeCumulativeEnergyReverse EPCType = 0xEB // 定時積算電力量計測値(逆方向計測値)
+)
+
+func (r PropertyRegistry) LowVoltageSmartMeter() PropertyTable {
+	CumulativeEnergyDesc := NumberDesc{Min: 0, Max: 99999999, EDTLen: 4}
+
+	return PropertyTable{
+		ClassCode:   LowVoltageSmartMeter_ClassCode,
+		Description: "Low-Voltage Smart Electric Energy Meter",
+		DescriptionTranslations: map[string]string{
+			"ja": "低圧スマート電力量メータ",
+		},
+		EPCDesc: map[EPCType]PropertyDesc{
+			EPC_SM_Coefficient: {
+				Name: "Coefficient",
+				NameTranslations: map[string]string{
+					"ja": "係数",
+				},
+				Aliases: nil,
+				Decoder: NumberDesc{Min: 1, Max: 999999, EDTLen: 4},
+			},
+			EPC_SM_EffectiveDigits: {
+				Name: "Number of effective digits for cumulative amounts of electric energy",
+				NameTranslations: map[string]string{
+					"ja": "積算電力量有効桁数",
+				},
+				ShortName: "Effective digits",
+				ShortNameTranslations: map[string]string{
+					"ja": "有効桁数",
+				},
+				Aliases: nil,
+				Decoder: NumberDesc{Min: 1, Max: 8},
+			},
+			EPC_SM_CumulativeEnergy: {
+				Name: "Measured cumulative amount of electric energy (normal direction)",
+				NameTranslations: map[string]string{
+					"ja": "積算電力量計測値(正方向計測値)",
+				},
+				ShortName: "Cumulative energy",
+				ShortNameTranslations: map[string]string{
+					"ja": "積算電力量",
+				},
+				Aliases: nil,
+				Decoder: CumulativeEnergyDesc,
+			},
+			EPC_SM_CumulativeEnergyUnit: {
+				Name: "Unit for cumulative amounts of electric energy",
+				NameTranslations: map[string]string{
+					"ja": "積算電力量単位",
+				},
+				ShortName: "Energy unit",
+				ShortNameTranslations: map[string]string{
+					"ja": "積算電力量単位",
+				},
+				Aliases: map[string][]byte{
+					"1kWh":      {0x00},
+					"0.1kWh":    {0x01},
+					"0.01kWh":   {0x02},
+					"0.001kWh":  {0x03},
+					"0.0001kWh": {0x04},
+					"10kWh":     {0x0A},
+					"100kWh":    {0x0B},
+					"1000kWh":   {0x0C},
+					"10000kWh":  {0x0D},
+				},
+				Decoder: nil,
+			},
+			EPC_SM_CumulativeEnergyReverse: {
+				Name: "Measured cumulative amount of electric energy (reverse direction)",
+				NameTranslations: map[string]string{
+					"ja": "積算電力量計測値(逆方向計測値)",
+				},
+				ShortName: "Cumulative energy (reverse)",
+				ShortNameTranslations: map[string]string{
+					"ja": "積算電力量(逆方向)",
+				},
+				Aliases: nil,
+				Decoder: CumulativeEnergyDesc,
+			},
+			EPC_SM_InstantaneousPower: {
+				Name: "Measured instantaneous electric power",
+				NameTranslations: map[string]string{
+					"ja": "瞬時電力計測値",
+				},
+				ShortName: "Instantaneous power",
+				ShortNameTranslations: map[string]string{
+					"ja": "瞬時電力",
+				},
+				Aliases: nil,
+				Decoder: NumberDesc{Min: -2147483647, Max: 2147483645, Unit: "W", EDTLen: 4},
+			},
+			EPC_SM_InstantaneousCurrent: {
+				Name: "Measured instantaneous currents",
+				NameTranslations: map[string]string{
+					"ja": "瞬時電流計測値",
+				},
+				ShortName: "Instantaneous current",
+				ShortNameTranslations: map[string]string{
+					"ja": "瞬時電流",
+				},
+				Aliases: nil,
+				Decoder: SM_InstantaneousCurrentDesc{},
+			},
+			EPC_SM_FixedTimeCumulativeEnergy: {
+				Name: "Cumulative amount of electric energy measured at fixed time (normal direction)",
+				NameTranslations: map[string]string{
+					"ja": "定時積算電力量計測値(正方向計測値)",
+				},
+				ShortName: "Fixed-time cumulative energy",
+				ShortNameTranslations: map[string]string{
+					"ja": "定時積算電力量",
+				},
+				Aliases: nil,
+				Decoder: SM_FixedTimeEnergyDesc{},
+			},
+			EPC_SM_FixedTimeCumulativeEnergyReverse: {
+				Name: "Cumulative amount of electric energy measured at fixed time (reverse direction)",
+				NameTranslations: map[string]string{
+					"ja": "定時積算電力量計測値(逆方向計測値)",
+				},
+				ShortName: "Fixed-time cumulative energy (reverse)",
+				ShortNameTranslations: map[string]string{
+					"ja": "定時積算電力量(逆方向)",
+				},
+				Aliases: nil,
+				Decoder: SM_FixedTimeEnergyDesc{},
+			},
+		},
+		DefaultEPCs: []EPCType{
+			EPC_SM_InstantaneousPower,
+			EPC_SM_CumulativeEnergy,
+		},
+	}
+}
+
+// 積算電力量単位(0xE1)の値ごとの kWh への倍率
+var smEnergyUnits = map[byte]float64{
+	0x00: 1,
+	0x01: 0.1,
+	0x02: 0.01,
+	0x03: 0.001,
+	0x04: 0.0001,
+	0x0A: 10,
+	0x0B: 100,
+	0x0C: 1000,
+	0x0D: 10000,
+}
+
+// SM_EnergyUnitKWh は、積算電力量単位(0xE1)の EDT から、計測値1あたりの kWh を返す
+func SM_EnergyUnitKWh(EDT []byte) (float64, bool) {
+	if len(EDT) != 1 {
+		return 0, false
+	}
+	unit, ok := smEnergyUnits[EDT[0]]
+	return unit, ok
+}
+
+// SM_CumulativeEnergyKWh は、積算電力量の計測値を kWh に換算する。
+// coefficient は係数(0xD3)の値で、係数のないメータは 1 とする。unitEDT は積算電力量単位(0xE1)の EDT
+func SM_CumulativeEnergyKWh(value, coefficient int, unitEDT []byte) (float64, bool) {
+	unit, ok := SM_EnergyUnitKWh(unitEDT)
+	if !ok || coefficient < 1 {
+		return 0, false
+	}
+	return float64(value) * float64(coefficient) * unit, true
+}
+
+// SM_InstantaneousCurrentDesc は、瞬時電流計測値(0xE8)を表す。
+// R相、T相の順に 0.1A 単位の符号付き2バイト。単相2線式のT相は 0x7FFE (計測値なし)
+type SM_InstantaneousCurrentDesc struct{}
+
+func (d SM_InstantaneousCurrentDesc) ToString(EDT []byte) (string, bool) {
+	r, t, ok := SM_DecodeInstantaneousCurrent(EDT)
+	if !ok {
+		return "", false
+	}
+	if t == nil {
+		return fmt.Sprintf("R:%.1fA", r), true
+	}
+	return fmt.Sprintf("R:%.1fA T:%.1fA", r, *t), true
+}
+
+// SM_DecodeInstantaneousCurrent は、瞬時電流計測値(0xE8)の R相、T相の電流(A)を返す。T相がない場合は nil
+func SM_DecodeInstantaneousCurrent(EDT []byte) (r float64, t *float64, ok bool) {
+	if len(EDT) != 4 {
+		return 0, nil, false
+	}
+	r = float64(utils.BytesToInt32(EDT[0:2])) / 10
+	if EDT[2] == 0x7F && EDT[3] == 0xFE {
+		return r, nil, true
+	}
+	tValue := float64(utils.BytesToInt32(EDT[2:4])) / 10
+	return r, &tValue, true
+}
+
+// SM_FixedTimeEnergyDesc は、定時積算電力量計測値(0xEA, 0xEB)を表す。
+// 計測日時(年2バイト、月、日、時、分、秒)と積算電力量計測値(4バイト)の11バイト
+type SM_FixedTimeEnergyDesc struct{}
+
+func (d SM_FixedTimeEnergyDesc) ToString(EDT []byte) (string, bool) {
+	at, value, ok := SM_DecodeFixedTimeEnergy(EDT)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%s %d", at.Format("2006/01/02 15:04:05"), value), true
+}
+
+// SM_DecodeFixedTimeEnergy は、定時積算電力量計測値(0xEA, 0xEB)の計測日時と計測値を返す。
+// 日時はメータの時刻で、タイムゾーンを持たないため time.Local とする
+func SM_DecodeFixedTimeEnergy(EDT []byte) (time.Time, int, bool) {
+	if len(EDT) != 11 {
+		return time.Time{}, 0, false
+	}
+	year := int(utils.BytesToUint32(EDT[0:2]))
+	month, day, hour, minute, second := int(EDT[2]), int(EDT[3]), int(EDT[4]), int(EDT[5]), int(EDT[6])
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, 0, false
+	}
+	value := utils.BytesToUint32(EDT[7:11])
+	if value > 99999999 {
+		// 0xFFFFFFFE は計測値なし
+		return time.Time{}, 0, false
+	}
+	return time.Date(year, time.Month(month), day, hour, minute, second, 0, time.Local), int(value), true
+}
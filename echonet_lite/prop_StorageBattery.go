@@ -0,0 +1,244 @@
+package echonet_lite
+
+const (
+	// EPC
+	EPC_SB_ACChargeableAmount          EPCType = 0xA4 // AC充電可能量
+	EPC_SB_ACDischargeableAmount       EPCType = 0xA5 // AC放電可能量
+	EPC_SB_ACCumulativeCharging        EPCType = 0xA8 // AC積算充電電力量計測値
+	EPC_SB_ACCumulativeDischarging     EPCType = 0xA9 // AC積算放電電力量計測値
+	EPC_SB_WorkingOperationStatus      EPCType = 0xCF // 運転動作状態
+	EPC_SB_RatedEnergy                 EPCType = 0xD0 // 定格電力量
+	EPC_SB_InstantaneousChargingPower  EPCType = 0xD3 // 瞬時充放電電力計測値
+	EPC_SB_CumulativeDischarging       EPCType = 0xD6 // 積算放電電力量計測値
+	EPC_SB_CumulativeCharging          EPCType = 0xD8 // 積算充電電力量計測値
+	EPC_SB_OperationMode               EPCType = 0xDA // 運転モード設定
+	EPC_SB_RemainingStoredElectricity  EPCType = 0xE2 // 蓄電残量1
+	EPC_SB_RemainingCapacityPercentage EPCType = 0xE4 // 蓄電残量3
+	EPC_SB_BatteryStateOfHealth        EPCType = 0xE5 // 劣化状態
+)
+
+func (r PropertyRegistry) StorageBattery() PropertyTable {
+	// 積算電力量は 0.001kWh 単位なので Wh として扱う
+	EnergyDesc := NumberDesc{Min: 0, Max: 999999999, Unit: "Wh", EDTLen: 4}
+	PercentageDesc := NumberDesc{Min: 0, Max: 100, Unit: "%"}
+
+	return PropertyTable{
+		ClassCode:   StorageBattery_ClassCode,
+		Description: "Storage Battery",
+		DescriptionTranslations: map[string]string{
+			"ja": "蓄電池",
+		},
+		EPCDesc: map[EPCType]PropertyDesc{
+			EPC_SB_ACChargeableAmount: {
+				Name: "AC chargeable amount",
+				NameTranslations: map[string]string{
+					"ja": "AC充電可能量",
+				},
+				ShortName: "Chargeable",
+				ShortNameTranslations: map[string]string{
+					"ja": "充電可能量",
+				},
+				Aliases: nil,
+				Decoder: EnergyDesc,
+			},
+			EPC_SB_ACDischargeableAmount: {
+				Name: "AC dischargeable amount",
+				NameTranslations: map[string]string{
+					"ja": "AC放電可能量",
+				},
+				ShortName: "Dischargeable",
+				ShortNameTranslations: map[string]string{
+					"ja": "放電可能量",
+				},
+				Aliases: nil,
+				Decoder: EnergyDesc,
+			},
+			EPC_SB_ACCumulativeCharging: {
+				Name: "AC measured cumulative charging electric energy",
+				NameTranslations: map[string]string{
+					"ja": "AC積算充電電力量計測値",
+				},
+				ShortName: "AC charged",
+				ShortNameTranslations: map[string]string{
+					"ja": "AC積算充電量",
+				},
+				Aliases: nil,
+				Decoder: EnergyDesc,
+			},
+			EPC_SB_ACCumulativeDischarging: {
+				Name: "AC measured cumulative discharging electric energy",
+				NameTranslations: map[string]string{
+					"ja": "AC積算放電電力量計測値",
+				},
+				ShortName: "AC discharged",
+				ShortNameTranslations: map[string]string{
+					"ja": "AC積算放電量",
+				},
+				Aliases: nil,
+				Decoder: EnergyDesc,
+			},
+			EPC_SB_WorkingOperationStatus: {
+				Name: "Working operation status",
+				NameTranslations: map[string]string{
+					"ja": "運転動作状態",
+				},
+				ShortName: "Working status",
+				ShortNameTranslations: map[string]string{
+					"ja": "動作状態",
+				},
+				// 運転モード設定(0xDA)と同じ値だが、エイリアスの重複を避けるためデコーダで表示する
+				Aliases: nil,
+				Decoder: SB_OperationStatusDesc{},
+			},
+			EPC_SB_RatedEnergy: {
+				Name: "Rated electric energy",
+				NameTranslations: map[string]string{
+					"ja": "定格電力量",
+				},
+				ShortName: "Rated energy",
+				ShortNameTranslations: map[string]string{
+					"ja": "定格電力量",
+				},
+				Aliases: nil,
+				Decoder: EnergyDesc,
+			},
+			EPC_SB_InstantaneousChargingPower: {
+				Name: "Measured instantaneous charging/discharging electric power",
+				NameTranslations: map[string]string{
+					"ja": "瞬時充放電電力計測値",
+				},
+				ShortName: "Charging power",
+				ShortNameTranslations: map[string]string{
+					"ja": "充放電電力",
+				},
+				// 充電が正、放電が負
+				Aliases: nil,
+				Decoder: NumberDesc{Min: -999999999, Max: 999999999, Unit: "W", EDTLen: 4},
+			},
+			EPC_SB_CumulativeDischarging: {
+				Name: "Measured cumulative discharging electric energy",
+				NameTranslations: map[string]string{
+					"ja": "積算放電電力量計測値",
+				},
+				ShortName: "Discharged",
+				ShortNameTranslations: map[string]string{
+					"ja": "積算放電量",
+				},
+				Aliases: nil,
+				Decoder: EnergyDesc,
+			},
+			EPC_SB_CumulativeCharging: {
+				Name: "Measured cumulative charging electric energy",
+				NameTranslations: map[string]string{
+					"ja": "積算充電電力量計測値",
+				},
+				ShortName: "Charged",
+				ShortNameTranslations: map[string]string{
+					"ja": "積算充電量",
+				},
+				Aliases: nil,
+				Decoder: EnergyDesc,
+			},
+			EPC_SB_OperationMode: {
+				Name: "Operation mode setting",
+				NameTranslations: map[string]string{
+					"ja": "運転モード設定",
+				},
+				ShortName: "Mode",
+				ShortNameTranslations: map[string]string{
+					"ja": "運転モード",
+				},
+				Aliases: map[string][]byte{
+					"other":          {0x40},
+					"rapid_charging": {0x41},
+					"charging":       {0x42},
+					"discharging":    {0x43},
+					"standby":        {0x44},
+					"test":           {0x45},
+					"auto":           {0x46},
+					"restart":        {0x48},
+					"recalculation":  {0x49},
+				},
+				AliasTranslations: map[string]map[string]string{
+					"ja": {
+						"other":          "その他",
+						"rapid_charging": "急速充電",
+						"charging":       "充電",
+						"discharging":    "放電",
+						"standby":        "待機",
+						"test":           "テスト",
+						"auto":           "自動",
+						"restart":        "再起動",
+						"recalculation":  "実効容量再計算処理",
+					},
+				},
+				Decoder: nil,
+			},
+			EPC_SB_RemainingStoredElectricity: {
+				Name: "Remaining stored electricity 1",
+				NameTranslations: map[string]string{
+					"ja": "蓄電残量1",
+				},
+				ShortName: "Remaining energy",
+				ShortNameTranslations: map[string]string{
+					"ja": "蓄電残量",
+				},
+				Aliases: nil,
+				Decoder: EnergyDesc,
+			},
+			EPC_SB_RemainingCapacityPercentage: {
+				Name: "Remaining stored electricity 3",
+				NameTranslations: map[string]string{
+					"ja": "蓄電残量3",
+				},
+				ShortName: "SOC",
+				ShortNameTranslations: map[string]string{
+					"ja": "残量",
+				},
+				Aliases: nil,
+				Decoder: PercentageDesc,
+			},
+			EPC_SB_BatteryStateOfHealth: {
+				Name: "Battery state of health",
+				NameTranslations: map[string]string{
+					"ja": "劣化状態",
+				},
+				ShortName: "SOH",
+				ShortNameTranslations: map[string]string{
+					"ja": "劣化状態",
+				},
+				Aliases: nil,
+				Decoder: PercentageDesc,
+			},
+		},
+		DefaultEPCs: []EPCType{
+			EPC_SB_RemainingCapacityPercentage,
+			EPC_SB_InstantaneousChargingPower,
+			EPC_SB_WorkingOperationStatus,
+		},
+	}
+}
+
+// 運転動作状態(0xCF)の値ごとの名前。運転モード設定(0xDA)のエイリアスと同じ
+var sbOperationStatusNames = map[byte]string{
+	0x40: "other",
+	0x41: "rapid_charging",
+	0x42: "charging",
+	0x43: "discharging",
+	0x44: "standby",
+	0x45: "test",
+	0x46: "auto",
+	0x48: "restart",
+	0x49: "recalculation",
+}
+
+// SB_OperationStatusDesc は、運転動作状態(0xCF)を表す
+type SB_OperationStatusDesc struct{}
+
+func (d SB_OperationStatusDesc) ToString(EDT []byte) (string, bool) {
+	if len(EDT) != 1 {
+		return "", false
+	}
+	name, ok := sbOperationStatusNames[EDT[0]]
+	return name, ok
+}
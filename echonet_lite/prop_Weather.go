@@ -0,0 +1,163 @@
+package echonet_lite
+
+const (
+	// EPC
+	EPC_WE_Temperature   EPCType = 0xE0 // 気温
+	EPC_WE_Humidity      EPCType = 0xE1 // 湿度
+	EPC_WE_WindSpeed     EPCType = 0xE2 // 風速
+	EPC_WE_WindDirection EPCType = 0xE3 // 風向
+	EPC_WE_Precipitation EPCType = 0xE4 // 1時間降水量
+	EPC_WE_Condition     EPCType = 0xE5 // 天気
+	EPC_WE_Sunrise       EPCType = 0xE6 // 日の出時刻
+	EPC_WE_Sunset        EPCType = 0xE7 // 日の入り時刻
+	EPC_WE_Daylight      EPCType = 0xE8 // 昼夜
+)
+
+// 天気の値を取得できない場合の EDT（いずれの値も範囲外として数値にならない）
+var (
+	WeatherUnknownSigned1   = []byte{0x7E}       // 気温
+	WeatherUnknownUnsigned1 = []byte{0xFD}       // 湿度・風速
+	WeatherUnknownUnsigned2 = []byte{0xFF, 0xFD} // 風向・降水量
+	WeatherNoSunEvent       = []byte{0xFF, 0xFF} // 白夜・極夜で日の出・日の入りがない
+)
+
+func (r PropertyRegistry) Weather() PropertyTable {
+	signedUnknownAlias := map[string][]byte{"unknown": WeatherUnknownSigned1}
+	unsigned1UnknownAlias := map[string][]byte{"unknown": WeatherUnknownUnsigned1}
+	unsigned2UnknownAlias := map[string][]byte{"unknown": WeatherUnknownUnsigned2}
+	unknownAliasTranslations := map[string]map[string]string{
+		"ja": {"unknown": "不明"},
+	}
+	noSunEventAlias := map[string][]byte{"none": WeatherNoSunEvent}
+	noSunEventAliasTranslations := map[string]map[string]string{
+		"ja": {"none": "なし"},
+	}
+
+	return PropertyTable{
+		ClassCode:   Weather_ClassCode,
+		Description: "Weather",
+		DescriptionTranslations: map[string]string{
+			"ja": "天気",
+		},
+		EPCDesc: map[EPCType]PropertyDesc{
+			EPC_WE_Temperature: {
+				Name: "Outside temperature",
+				NameTranslations: map[string]string{
+					"ja": "気温",
+				},
+				Aliases:           signedUnknownAlias,
+				AliasTranslations: unknownAliasTranslations,
+				Decoder:           NumberDesc{Min: -127, Max: 125, Unit: "℃"},
+			},
+			EPC_WE_Humidity: {
+				Name: "Outside humidity",
+				NameTranslations: map[string]string{
+					"ja": "湿度",
+				},
+				Aliases:           unsigned1UnknownAlias,
+				AliasTranslations: unknownAliasTranslations,
+				Decoder:           NumberDesc{Min: 0, Max: 100, Unit: "%"},
+			},
+			EPC_WE_WindSpeed: {
+				Name: "Wind speed",
+				NameTranslations: map[string]string{
+					"ja": "風速",
+				},
+				Aliases:           unsigned1UnknownAlias,
+				AliasTranslations: unknownAliasTranslations,
+				Decoder:           NumberDesc{Min: 0, Max: 200, Unit: "m/s"},
+			},
+			EPC_WE_WindDirection: {
+				Name: "Wind direction",
+				NameTranslations: map[string]string{
+					"ja": "風向",
+				},
+				Aliases:           unsigned2UnknownAlias,
+				AliasTranslations: unknownAliasTranslations,
+				Decoder:           NumberDesc{Min: 0, Max: 359, Unit: "°", EDTLen: 2},
+			},
+			EPC_WE_Precipitation: {
+				Name: "Precipitation in the last hour",
+				NameTranslations: map[string]string{
+					"ja": "1時間降水量",
+				},
+				ShortName: "Precipitation",
+				ShortNameTranslations: map[string]string{
+					"ja": "降水量",
+				},
+				Aliases:           unsigned2UnknownAlias,
+				AliasTranslations: unknownAliasTranslations,
+				Decoder:           NumberDesc{Min: 0, Max: 1000, Unit: "mm", EDTLen: 2},
+			},
+			EPC_WE_Condition: {
+				Name: "Weather condition",
+				NameTranslations: map[string]string{
+					"ja": "天気",
+				},
+				Aliases: map[string][]byte{
+					"unknown":      {0x40},
+					"clear":        {0x41},
+					"cloudy":       {0x42},
+					"rain":         {0x43},
+					"snow":         {0x44},
+					"thunderstorm": {0x45},
+					"fog":          {0x46},
+				},
+				AliasTranslations: map[string]map[string]string{
+					"ja": {
+						"unknown":      "不明",
+						"clear":        "晴れ",
+						"cloudy":       "曇り",
+						"rain":         "雨",
+						"snow":         "雪",
+						"thunderstorm": "雷雨",
+						"fog":          "霧",
+					},
+				},
+				Decoder: nil,
+			},
+			EPC_WE_Sunrise: {
+				Name: "Sunrise time",
+				NameTranslations: map[string]string{
+					"ja": "日の出時刻",
+				},
+				Aliases:           noSunEventAlias,
+				AliasTranslations: noSunEventAliasTranslations,
+				Decoder:           FH_HHMMDesc{},
+			},
+			EPC_WE_Sunset: {
+				Name: "Sunset time",
+				NameTranslations: map[string]string{
+					"ja": "日の入り時刻",
+				},
+				Aliases:           noSunEventAlias,
+				AliasTranslations: noSunEventAliasTranslations,
+				Decoder:           FH_HHMMDesc{},
+			},
+			EPC_WE_Daylight: {
+				Name: "Daylight",
+				NameTranslations: map[string]string{
+					"ja": "昼夜",
+				},
+				Aliases: map[string][]byte{
+					"day":   {0x41},
+					"night": {0x42},
+				},
+				AliasTranslations: map[string]map[string]string{
+					"ja": {
+						"day":   "昼",
+						"night": "夜",
+					},
+				},
+				Decoder: nil,
+			},
+		},
+		DefaultEPCs: []EPCType{
+			EPC_WE_Temperature,
+			EPC_WE_Humidity,
+			EPC_WE_WindSpeed,
+			EPC_WE_Precipitation,
+			EPC_WE_Condition,
+		},
+	}
+}
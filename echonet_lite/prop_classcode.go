@@ -2,11 +2,20 @@ package echonet_lite
 
 const (
 	HomeAirConditioner_ClassCode     EOJClassCode = 0x0130 // 家庭用エアコン
-	ElectricWaterHeater_ClassCode    EOJClassCode = 0x026b // 電気式給湯器(エコキュート含む) (TODO)
+	ElectricWaterHeater_ClassCode    EOJClassCode = 0x026b // 電気式給湯器(エコキュート含む)
+	HomeSolarPower_ClassCode         EOJClassCode = 0x0279 // 住宅用太陽光発電
 	FloorHeating_ClassCode           EOJClassCode = 0x027b // 床暖房
+	StorageBattery_ClassCode         EOJClassCode = 0x027d // 蓄電池
+	EVChargerDischarger_ClassCode    EOJClassCode = 0x027e // 電気自動車充放電器
+	LowVoltageSmartMeter_ClassCode   EOJClassCode = 0x0288 // 低圧スマート電力量メータ
+	GeneralLighting_ClassCode        EOJClassCode = 0x0290 // 一般照明
 	SingleFunctionLighting_ClassCode EOJClassCode = 0x0291 // 単機能照明
 	LightingSystem_ClassCode         EOJClassCode = 0x02a3 // 照明システム
 	Refrigerator_ClassCode           EOJClassCode = 0x03b7 // 冷凍冷蔵庫
 	Controller_ClassCode             EOJClassCode = 0x05ff // コントローラ
 	NodeProfile_ClassCode            EOJClassCode = 0x0ef0 // ノードプロファイル
+
+	// 規格で割り当てられていないクラスコード。天気予報サービスから取得した現在の天気を
+	// このプログラム内の仮想ノードが提供するためだけに使う
+	Weather_ClassCode EOJClassCode = 0x00f0 // 天気（仮想デバイス）
 )
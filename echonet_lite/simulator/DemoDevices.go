@@ -0,0 +1,128 @@
+package simulator
+
+import (
+	"echonet-list/echonet_lite"
+	"math"
+	"net"
+	"time"
+)
+
+// 設置場所（EPC 0x81）の値
+const (
+	locationLiving  = 0x08
+	locationDining  = 0x10
+	locationKitchen = 0x18
+	locationRoom1   = 0x41
+)
+
+// 動作状態（EPC 0x80）の値
+const (
+	operationOn  = 0x30
+	operationOff = 0x31
+)
+
+// standardVersion は、機器オブジェクトの規格Version情報（Release R Rev.1）
+var standardVersion = []byte{0x00, 0x00, 'R', 0x01}
+
+// DemoNodes は、デモモードで使う模擬ノードの定義を返す
+// アドレスには実在のネットワークと重ならないよう文書用のアドレス（192.0.2.0/24）を使う
+func DemoNodes() []NodeSpec {
+	common := func(operation, location byte) echonet_lite.Properties {
+		return echonet_lite.Properties{
+			{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{operation}},
+			{EPC: echonet_lite.EPCInstallationLocation, EDT: []byte{location}},
+			{EPC: echonet_lite.EPCStandardVersion, EDT: standardVersion},
+			{EPC: echonet_lite.EPCFaultStatus, EDT: []byte{0x42}},
+		}
+	}
+
+	return []NodeSpec{
+		{
+			IP: net.ParseIP("192.0.2.10"),
+			Devices: []DeviceSpec{{
+				EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1),
+				Properties: append(common(operationOn, locationLiving),
+					echonet_lite.Property{EPC: echonet_lite.EPC_HAC_OperationModeSetting, EDT: []byte{0x42}}, // 冷房
+					echonet_lite.Property{EPC: echonet_lite.EPC_HAC_TemperatureSetting, EDT: []byte{26}},
+					echonet_lite.Property{EPC: echonet_lite.EPC_HAC_AirVolumeSetting, EDT: []byte{0x41}}, // 自動
+				),
+				Settable: []echonet_lite.EPCType{
+					echonet_lite.EPCOperationStatus,
+					echonet_lite.EPCInstallationLocation,
+					echonet_lite.EPC_HAC_OperationModeSetting,
+					echonet_lite.EPC_HAC_TemperatureSetting,
+					echonet_lite.EPC_HAC_AirVolumeSetting,
+				},
+				Announce: []echonet_lite.EPCType{
+					echonet_lite.EPCOperationStatus,
+					echonet_lite.EPCInstallationLocation,
+					echonet_lite.EPCFaultStatus,
+					echonet_lite.EPC_HAC_OperationModeSetting,
+					echonet_lite.EPC_HAC_TemperatureSetting,
+				},
+				Sensors: []Sensor{
+					{EPC: echonet_lite.EPC_HAC_CurrentRoomTemperature, Value: dailyTemperature(25, 2, 15)},
+					{EPC: echonet_lite.EPC_HAC_CurrentOutsideTemperature, Value: dailyTemperature(20, 6, 14)},
+				},
+			}},
+		},
+		{
+			IP: net.ParseIP("192.0.2.11"),
+			Devices: []DeviceSpec{
+				{
+					EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1),
+					Properties: append(common(operationOn, locationLiving),
+						echonet_lite.Property{EPC: echonet_lite.EPC_SF_Illuminance, EDT: []byte{80}},
+					),
+					Settable: []echonet_lite.EPCType{echonet_lite.EPCOperationStatus, echonet_lite.EPCInstallationLocation, echonet_lite.EPC_SF_Illuminance},
+					Announce: []echonet_lite.EPCType{echonet_lite.EPCOperationStatus, echonet_lite.EPCInstallationLocation},
+				},
+				{
+					EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 2),
+					Properties: append(common(operationOff, locationRoom1),
+						echonet_lite.Property{EPC: echonet_lite.EPC_SF_Illuminance, EDT: []byte{40}},
+					),
+					Settable: []echonet_lite.EPCType{echonet_lite.EPCOperationStatus, echonet_lite.EPCInstallationLocation, echonet_lite.EPC_SF_Illuminance},
+					Announce: []echonet_lite.EPCType{echonet_lite.EPCOperationStatus, echonet_lite.EPCInstallationLocation},
+				},
+			},
+		},
+		{
+			IP: net.ParseIP("192.0.2.12"),
+			Devices: []DeviceSpec{{
+				EOJ: echonet_lite.MakeEOJ(echonet_lite.FloorHeating_ClassCode, 1),
+				Properties: append(common(operationOff, locationDining),
+					echonet_lite.Property{EPC: echonet_lite.EPC_FH_TemperatureLevel, EDT: []byte{0x33}}, // レベル3
+				),
+				Settable: []echonet_lite.EPCType{echonet_lite.EPCOperationStatus, echonet_lite.EPCInstallationLocation, echonet_lite.EPC_FH_TemperatureLevel},
+				Announce: []echonet_lite.EPCType{echonet_lite.EPCOperationStatus, echonet_lite.EPCInstallationLocation},
+				Sensors: []Sensor{
+					{EPC: echonet_lite.EPC_FH_RoomTemperature, Value: dailyTemperature(22, 2, 15)},
+					{EPC: echonet_lite.EPC_FH_FloorTemperature, Value: dailyTemperature(21, 1, 16)},
+				},
+			}},
+		},
+		{
+			IP: net.ParseIP("192.0.2.13"),
+			Devices: []DeviceSpec{{
+				EOJ: echonet_lite.MakeEOJ(echonet_lite.Refrigerator_ClassCode, 1),
+				Properties: append(common(operationOn, locationKitchen),
+					echonet_lite.Property{EPC: echonet_lite.EPC_RF_DoorOpenStatus, EDT: []byte{0x42}}, // 閉
+					echonet_lite.Property{EPC: echonet_lite.EPC_RF_DoorOpenAlertStatus, EDT: []byte{0x42}},
+				),
+				Settable: []echonet_lite.EPCType{echonet_lite.EPCInstallationLocation},
+				Announce: []echonet_lite.EPCType{echonet_lite.EPCOperationStatus, echonet_lite.EPCInstallationLocation, echonet_lite.EPC_RF_DoorOpenStatus},
+			}},
+		},
+	}
+}
+
+// dailyTemperature は、1日周期で変化する温度（1℃単位の符号付き1バイト）を返すセンサー値関数を作る
+// warmestHour の時刻に mean+amplitude、その12時間後に mean-amplitude になり、短い周期の揺らぎを加える
+func dailyTemperature(mean, amplitude, warmestHour float64) func(time.Time) []byte {
+	return func(t time.Time) []byte {
+		hour := float64(t.Hour()) + float64(t.Minute())/60
+		v := mean + amplitude*math.Cos(2*math.Pi*(hour-warmestHour)/24) + 0.6*math.Sin(2*math.Pi*hour*5/24)
+		return []byte{byte(int8(math.Round(v)))}
+	}
+}
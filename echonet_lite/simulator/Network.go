@@ -0,0 +1,485 @@
+// Package simulator は、実機やネットワークを使わずに ECHONET Lite ノードを模擬する
+// デモモードで network.Connection の代わりに使い、Get/Set/SetGet/INF_REQ に応答する
+package simulator
+
+import (
+	"context"
+	"echonet-list/echonet_lite"
+	"net"
+	"sync"
+	"time"
+)
+
+// ResponseDelay は、要求を受けてから応答を返すまでの時間（実機の応答遅延を模擬する）
+const ResponseDelay = 20 * time.Millisecond
+
+// Sensor は、時刻によって値が変わるプロパティを表す
+// Value は同じ時刻に対して常に同じ値を返すこと（履歴の生成にも使う）
+type Sensor struct {
+	EPC   echonet_lite.EPCType
+	Value func(t time.Time) []byte
+}
+
+// DeviceSpec は、模擬デバイス（機器オブジェクト）の定義
+// 識別番号・メーカコード・プロパティマップは自動的に追加する
+type DeviceSpec struct {
+	EOJ        echonet_lite.EOJ
+	Properties echonet_lite.Properties // プロパティの初期値（すべて Get 可能）
+	Settable   []echonet_lite.EPCType  // Set 可能なプロパティ
+	Announce   []echonet_lite.EPCType  // 値が変化したときに INF で通知するプロパティ
+	Sensors    []Sensor                // 時刻によって値が変わるプロパティ
+}
+
+// NodeSpec は、模擬ノード（1つのIPアドレス）の定義
+type NodeSpec struct {
+	IP      net.IP
+	Devices []DeviceSpec
+}
+
+// object は、模擬ノード上の1つのオブジェクト
+type object struct {
+	eoj        echonet_lite.EOJ
+	properties map[echonet_lite.EPCType][]byte
+	settable   echonet_lite.PropertyMap
+	announce   echonet_lite.PropertyMap
+	sensors    []Sensor
+}
+
+// node は、模擬ノード
+type node struct {
+	ip      net.IP
+	objects []*object // 先頭はノードプロファイル
+}
+
+// packet は、Receive に渡す受信パケット
+type packet struct {
+	data []byte
+	addr *net.UDPAddr
+}
+
+// Network は、模擬ノードの集まりを network.Connection として扱う
+// SendTo で送られた要求に模擬ノードが応答し、応答や通知は Receive で受け取る
+type Network struct {
+	mu        sync.Mutex
+	nodes     []*node
+	tid       echonet_lite.TIDType // 模擬ノードが送る INF の TID
+	packets   chan packet
+	closed    chan struct{}
+	closeOnce sync.Once
+	now       func() time.Time
+}
+
+// NewNetwork は、模擬ノードから Network を作成する
+func NewNetwork(nodes []NodeSpec) *Network {
+	n := &Network{
+		packets: make(chan packet, 256),
+		closed:  make(chan struct{}),
+		now:     time.Now,
+	}
+	now := n.now()
+	for _, spec := range nodes {
+		n.nodes = append(n.nodes, newNode(spec, now))
+	}
+	return n
+}
+
+// newNode は、ノードプロファイルと機器オブジェクトのプロパティを組み立てる
+func newNode(spec NodeSpec, now time.Time) *node {
+	nd := &node{ip: spec.IP.To4()}
+
+	var instances echonet_lite.InstanceList
+	classes := echonet_lite.SelfNodeClassListS{}
+	classSeen := map[echonet_lite.EOJClassCode]bool{}
+	var devices []*object
+	for _, d := range spec.Devices {
+		obj := newObject(spec.IP, d.EOJ, d.Properties, d.Settable, d.Announce)
+		obj.sensors = d.Sensors
+		for _, sensor := range d.Sensors {
+			obj.properties[sensor.EPC] = sensor.Value(now)
+		}
+		obj.updatePropertyMaps()
+		devices = append(devices, obj)
+
+		instances = append(instances, d.EOJ)
+		if !classSeen[d.EOJ.ClassCode()] {
+			classSeen[d.EOJ.ClassCode()] = true
+			classes = append(classes, d.EOJ.ClassCode())
+		}
+	}
+
+	instanceCount := len(instances)
+	npoProps := echonet_lite.Properties{
+		{EPC: echonet_lite.EPC_NPO_OperationStatus, EDT: []byte{0x30}},
+		*echonet_lite.ECHONETLite_Version.Property(),
+		{EPC: echonet_lite.EPC_NPO_SelfNodeInstances, EDT: []byte{byte(instanceCount >> 16), byte(instanceCount >> 8), byte(instanceCount)}},
+		{EPC: echonet_lite.EPC_NPO_SelfNodeClasses, EDT: []byte{0x00, byte(len(classes) + 1)}},
+		*(*echonet_lite.InstanceListNotification)(&instances).Property(),
+		*(*echonet_lite.SelfNodeInstanceListS)(&instances).Property(),
+		*classes.Property(),
+	}
+	npo := newObject(spec.IP, echonet_lite.NodeProfileObject, npoProps, nil, []echonet_lite.EPCType{echonet_lite.EPC_NPO_OperationStatus, echonet_lite.EPC_NPO_InstanceListNotification})
+	npo.updatePropertyMaps()
+
+	nd.objects = append([]*object{npo}, devices...)
+	return nd
+}
+
+// newObject は、共通のプロパティ（識別番号・メーカコード）を持つオブジェクトを作成する
+func newObject(ip net.IP, eoj echonet_lite.EOJ, props echonet_lite.Properties, settable, announce []echonet_lite.EPCType) *object {
+	obj := &object{
+		eoj:        eoj,
+		properties: make(map[echonet_lite.EPCType][]byte),
+		settable:   make(echonet_lite.PropertyMap),
+		announce:   make(echonet_lite.PropertyMap),
+	}
+
+	// 識別番号はIPアドレスとEOJから作る（再起動しても同じ値になるようにする）
+	unique := make([]byte, 13)
+	copy(unique, ip.To4())
+	copy(unique[4:], eoj.Encode())
+	id := echonet_lite.IdentificationNumber{
+		ManufacturerCode: echonet_lite.ManufacturerCodeEDTs["Experimental"],
+		UniqueIdentifier: unique,
+	}
+	obj.properties[echonet_lite.EPCIdentificationNumber] = id.Property().EDT
+	obj.properties[echonet_lite.EPCManufacturerCode] = echonet_lite.ManufacturerCodeEDTs["Experimental"]
+
+	for _, p := range props {
+		obj.properties[p.EPC] = append([]byte(nil), p.EDT...)
+	}
+	for _, epc := range settable {
+		obj.settable.Set(epc)
+	}
+	for _, epc := range announce {
+		obj.announce.Set(epc)
+	}
+	return obj
+}
+
+// updatePropertyMaps は、現在のプロパティから Get/Set/状態通知プロパティマップを作る
+func (o *object) updatePropertyMaps() {
+	getMap := make(echonet_lite.PropertyMap)
+	getMap.Set(echonet_lite.EPCStatusAnnouncementPropertyMap)
+	getMap.Set(echonet_lite.EPCSetPropertyMap)
+	getMap.Set(echonet_lite.EPCGetPropertyMap)
+	for epc := range o.properties {
+		getMap.Set(epc)
+	}
+	o.properties[echonet_lite.EPCStatusAnnouncementPropertyMap] = o.announce.Encode()
+	o.properties[echonet_lite.EPCSetPropertyMap] = o.settable.Encode()
+	o.properties[echonet_lite.EPCGetPropertyMap] = getMap.Encode()
+}
+
+// matches は、宛先EOJがこのオブジェクトを指しているかを返す（インスタンスコード0は全インスタンス）
+func (o *object) matches(deoj echonet_lite.EOJ) bool {
+	if deoj.ClassCode() != o.eoj.ClassCode() {
+		return false
+	}
+	return deoj.InstanceCode() == 0 || deoj.InstanceCode() == o.eoj.InstanceCode()
+}
+
+// SendTo は、宛先の模擬ノードに要求を渡す
+// 模擬ノードのいずれのアドレスでもない宛先は、マルチキャスト・ブロードキャストとして全ノードに渡す
+func (n *Network) SendTo(dstIP net.IP, data []byte) (int, error) {
+	select {
+	case <-n.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+
+	msg, err := echonet_lite.ParseECHONETLiteMessage(data)
+	if err != nil {
+		return 0, err
+	}
+
+	n.mu.Lock()
+	var responses []packet
+	for _, nd := range n.targets(dstIP) {
+		for _, obj := range nd.objects {
+			if !obj.matches(msg.DEOJ) {
+				continue
+			}
+			for _, res := range n.handle(obj, msg) {
+				responses = append(responses, packet{data: res.Encode(), addr: &net.UDPAddr{IP: nd.ip, Port: echonet_lite.ECHONETLitePort}})
+			}
+		}
+	}
+	n.mu.Unlock()
+
+	if len(responses) > 0 {
+		time.AfterFunc(ResponseDelay, func() {
+			for _, p := range responses {
+				n.deliver(p)
+			}
+		})
+	}
+	return len(data), nil
+}
+
+// HasNode は、ip がいずれかの模擬ノードのアドレスかどうかを返す
+func (n *Network) HasNode(ip net.IP) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, nd := range n.nodes {
+		if nd.ip.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// targets は、宛先アドレスに対応する模擬ノードを返す。n.mu を保持した状態で呼び出すこと
+func (n *Network) targets(dstIP net.IP) []*node {
+	for _, nd := range n.nodes {
+		if nd.ip.Equal(dstIP) {
+			return []*node{nd}
+		}
+	}
+	return n.nodes
+}
+
+// handle は、1つのオブジェクトに対する要求を処理し、返すべきメッセージを返す。n.mu を保持した状態で呼び出すこと
+func (n *Network) handle(obj *object, msg *echonet_lite.ECHONETLiteMessage) []*echonet_lite.ECHONETLiteMessage {
+	reply := func(esv echonet_lite.ESVType, props, setGetProps echonet_lite.Properties) *echonet_lite.ECHONETLiteMessage {
+		return &echonet_lite.ECHONETLiteMessage{
+			TID:              msg.TID,
+			SEOJ:             obj.eoj,
+			DEOJ:             msg.SEOJ,
+			ESV:              esv,
+			Properties:       props,
+			SetGetProperties: setGetProps,
+		}
+	}
+
+	switch msg.ESV {
+	case echonet_lite.ESVGet:
+		props, ok := obj.get(msg.Properties)
+		if !ok {
+			return []*echonet_lite.ECHONETLiteMessage{reply(echonet_lite.ESVGet_SNA, props, nil)}
+		}
+		return []*echonet_lite.ECHONETLiteMessage{reply(echonet_lite.ESVGet_Res, props, nil)}
+
+	case echonet_lite.ESVINF_REQ:
+		props, ok := obj.get(msg.Properties)
+		if !ok {
+			return []*echonet_lite.ECHONETLiteMessage{reply(echonet_lite.ESVINF_REQ_SNA, props, nil)}
+		}
+		return []*echonet_lite.ECHONETLiteMessage{reply(echonet_lite.ESVINF, props, nil)}
+
+	case echonet_lite.ESVSetC, echonet_lite.ESVSetI:
+		props, changed, ok := obj.set(msg.Properties)
+		var result []*echonet_lite.ECHONETLiteMessage
+		switch {
+		case ok && msg.ESV == echonet_lite.ESVSetC:
+			result = append(result, reply(echonet_lite.ESVSet_Res, props, nil))
+		case !ok && msg.ESV == echonet_lite.ESVSetC:
+			result = append(result, reply(echonet_lite.ESVSetC_SNA, props, nil))
+		case !ok:
+			result = append(result, reply(echonet_lite.ESVSetI_SNA, props, nil))
+		}
+		if inf := n.announcement(obj, changed); inf != nil {
+			result = append(result, inf)
+		}
+		return result
+
+	case echonet_lite.ESVSetGet:
+		setProps, changed, setOK := obj.set(msg.Properties)
+		getProps, getOK := obj.get(msg.SetGetProperties)
+		esv := echonet_lite.ESVSetGet_Res
+		if !setOK || !getOK {
+			esv = echonet_lite.ESVSetGet_SNA
+		}
+		result := []*echonet_lite.ECHONETLiteMessage{reply(esv, setProps, getProps)}
+		if inf := n.announcement(obj, changed); inf != nil {
+			result = append(result, inf)
+		}
+		return result
+	}
+
+	// INF・INFC・応答など、模擬ノードが処理しない要求は無視する
+	return nil
+}
+
+// get は、要求されたプロパティの値を返す。取得できないプロパティは EDT を空にして返す
+func (o *object) get(requested echonet_lite.Properties) (echonet_lite.Properties, bool) {
+	ok := true
+	result := make(echonet_lite.Properties, 0, len(requested))
+	for _, p := range requested {
+		edt, exists := o.properties[p.EPC]
+		if !exists {
+			ok = false
+			result = append(result, echonet_lite.Property{EPC: p.EPC})
+			continue
+		}
+		result = append(result, echonet_lite.Property{EPC: p.EPC, EDT: append([]byte(nil), edt...)})
+	}
+	return result, ok
+}
+
+// set は、プロパティを書き込む
+// 書き込めたプロパティは EDT を空にし、書き込めなかったプロパティは要求の EDT をそのまま返す
+// changed には値が変化したプロパティを返す
+func (o *object) set(requested echonet_lite.Properties) (result, changed echonet_lite.Properties, ok bool) {
+	ok = true
+	result = make(echonet_lite.Properties, 0, len(requested))
+	for _, p := range requested {
+		current, exists := o.properties[p.EPC]
+		// Set プロパティマップにないもの、長さが現在値と異なるものは受け付けない
+		if !o.settable.Has(p.EPC) || (exists && len(current) != len(p.EDT)) || len(p.EDT) == 0 {
+			ok = false
+			result = append(result, p)
+			continue
+		}
+		if !exists || string(current) != string(p.EDT) {
+			o.properties[p.EPC] = append([]byte(nil), p.EDT...)
+			changed = append(changed, echonet_lite.Property{EPC: p.EPC, EDT: append([]byte(nil), p.EDT...)})
+		}
+		result = append(result, echonet_lite.Property{EPC: p.EPC})
+	}
+	return result, changed, ok
+}
+
+// announcement は、状態通知プロパティが変化した場合の INF を作る。n.mu を保持した状態で呼び出すこと
+func (n *Network) announcement(obj *object, changed echonet_lite.Properties) *echonet_lite.ECHONETLiteMessage {
+	var props echonet_lite.Properties
+	for _, p := range changed {
+		if obj.announce.Has(p.EPC) {
+			props = append(props, p)
+		}
+	}
+	if len(props) == 0 {
+		return nil
+	}
+	n.tid++
+	return &echonet_lite.ECHONETLiteMessage{
+		TID:        n.tid,
+		SEOJ:       obj.eoj,
+		DEOJ:       echonet_lite.MakeEOJ(echonet_lite.Controller_ClassCode, 1),
+		ESV:        echonet_lite.ESVINF,
+		Properties: props,
+	}
+}
+
+// Run は、interval ごとにセンサー値を更新し、状態通知プロパティが変化した場合は INF を送る
+// ctx がキャンセルされるか Close されるまで戻らない
+func (n *Network) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-n.closed:
+			return
+		case <-ticker.C:
+			n.updateSensors(n.now())
+		}
+	}
+}
+
+// UpdateSensors は、センサー値をすぐに現在の値に更新し、状態通知プロパティが変化した場合は INF を送る
+// Run の間隔を待たずに、値が変わったことを知っている呼び出し側が更新する場合に使う
+func (n *Network) UpdateSensors() {
+	n.updateSensors(n.now())
+}
+
+// updateSensors は、センサー値を時刻 t の値に更新する
+func (n *Network) updateSensors(t time.Time) {
+	n.mu.Lock()
+	var notifications []packet
+	for _, nd := range n.nodes {
+		for _, obj := range nd.objects {
+			var changed echonet_lite.Properties
+			for _, sensor := range obj.sensors {
+				value := sensor.Value(t)
+				if string(obj.properties[sensor.EPC]) != string(value) {
+					obj.properties[sensor.EPC] = value
+					changed = append(changed, echonet_lite.Property{EPC: sensor.EPC, EDT: value})
+				}
+			}
+			if inf := n.announcement(obj, changed); inf != nil {
+				notifications = append(notifications, packet{data: inf.Encode(), addr: &net.UDPAddr{IP: nd.ip, Port: echonet_lite.ECHONETLitePort}})
+			}
+		}
+	}
+	n.mu.Unlock()
+
+	for _, p := range notifications {
+		n.deliver(p)
+	}
+}
+
+// deliver は、パケットを受信キューに入れる（Close 後は捨てる）
+func (n *Network) deliver(p packet) {
+	select {
+	case n.packets <- p:
+	case <-n.closed:
+	}
+}
+
+// Receive は、模擬ノードからの応答・通知を1つ受け取る
+func (n *Network) Receive(ctx context.Context) ([]byte, *net.UDPAddr, error) {
+	select {
+	case p := <-n.packets:
+		return p.data, p.addr, nil
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-n.closed:
+		return nil, nil, net.ErrClosed
+	}
+}
+
+// IsLocalIP は常に false を返す（模擬ノードはすべて別ノードとして扱う）
+func (n *Network) IsLocalIP(ip net.IP) bool {
+	return false
+}
+
+// SetReplyAddress は何もしない（模擬ネットワークには送信元アドレスがない）
+func (n *Network) SetReplyAddress(ip net.IP) error {
+	return nil
+}
+
+// Close は、模擬ネットワークを閉じる
+func (n *Network) Close() error {
+	n.closeOnce.Do(func() {
+		close(n.closed)
+	})
+	return nil
+}
+
+// HistorySample は、過去のセンサー値の1件
+type HistorySample struct {
+	Time     time.Time
+	Device   echonet_lite.IPAndEOJ
+	Property echonet_lite.Property
+}
+
+// History は、[since, until) のセンサー値を step ごとに生成して返す
+// 値が前の時刻から変化しない場合は含めない
+func (n *Network) History(since, until time.Time, step time.Duration) []HistorySample {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var samples []HistorySample
+	for _, nd := range n.nodes {
+		for _, obj := range nd.objects {
+			device := echonet_lite.IPAndEOJ{IP: nd.ip, EOJ: obj.eoj}
+			for _, sensor := range obj.sensors {
+				var last []byte
+				for t := since; t.Before(until); t = t.Add(step) {
+					value := sensor.Value(t)
+					if last != nil && string(last) == string(value) {
+						continue
+					}
+					last = value
+					samples = append(samples, HistorySample{
+						Time:     t,
+						Device:   device,
+						Property: echonet_lite.Property{EPC: sensor.EPC, EDT: value},
+					})
+				}
+			}
+		}
+	}
+	return samples
+}
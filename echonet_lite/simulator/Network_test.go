@@ -0,0 +1,147 @@
+package simulator
+
+import (
+	"context"
+	"echonet-list/echonet_lite"
+	"net"
+	"testing"
+	"time"
+)
+
+var (
+	testLightIP = net.ParseIP("192.0.2.1")
+	testLight   = echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)
+	controller  = echonet_lite.MakeEOJ(echonet_lite.Controller_ClassCode, 1)
+)
+
+func newTestNetwork() *Network {
+	return NewNetwork([]NodeSpec{
+		{
+			IP: testLightIP,
+			Devices: []DeviceSpec{{
+				EOJ:        testLight,
+				Properties: echonet_lite.Properties{{EPC: 0x80, EDT: []byte{0x31}}},
+				Settable:   []echonet_lite.EPCType{0x80},
+				Announce:   []echonet_lite.EPCType{0x80},
+				Sensors: []Sensor{{EPC: 0xE0, Value: func(t time.Time) []byte {
+					return []byte{byte(t.Hour())}
+				}}},
+			}},
+		},
+		{IP: net.ParseIP("192.0.2.2")},
+	})
+}
+
+// request sends a message and returns every response and notification that arrives
+func request(t *testing.T, n *Network, dstIP net.IP, msg *echonet_lite.ECHONETLiteMessage) []*echonet_lite.ECHONETLiteMessage {
+	t.Helper()
+	if _, err := n.SendTo(dstIP, msg.Encode()); err != nil {
+		t.Fatalf("SendTo failed: %v", err)
+	}
+	var result []*echonet_lite.ECHONETLiteMessage
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*ResponseDelay)
+		data, _, err := n.Receive(ctx)
+		cancel()
+		if err != nil {
+			return result
+		}
+		res, err := echonet_lite.ParseECHONETLiteMessage(data)
+		if err != nil {
+			t.Fatalf("ParseECHONETLiteMessage failed: %v", err)
+		}
+		result = append(result, res)
+	}
+}
+
+func TestNetwork_Get(t *testing.T) {
+	n := newTestNetwork()
+	defer n.Close()
+
+	res := request(t, n, testLightIP, &echonet_lite.ECHONETLiteMessage{
+		TID: 1, SEOJ: controller, DEOJ: testLight, ESV: echonet_lite.ESVGet,
+		Properties: echonet_lite.Properties{{EPC: 0x80}, {EPC: echonet_lite.EPCSetPropertyMap}},
+	})
+	if len(res) != 1 || res[0].ESV != echonet_lite.ESVGet_Res || res[0].TID != 1 {
+		t.Fatalf("unexpected response: %v", res)
+	}
+	if setMap := echonet_lite.DecodePropertyMap(res[0].Properties[1].EDT); !setMap.Has(0x80) || len(setMap) != 1 {
+		t.Errorf("unexpected set property map: %v", setMap)
+	}
+
+	// Unknown properties are returned with an empty EDT in a Get_SNA
+	res = request(t, n, testLightIP, &echonet_lite.ECHONETLiteMessage{
+		TID: 2, SEOJ: controller, DEOJ: testLight, ESV: echonet_lite.ESVGet,
+		Properties: echonet_lite.Properties{{EPC: 0x80}, {EPC: 0xF0}},
+	})
+	if len(res) != 1 || res[0].ESV != echonet_lite.ESVGet_SNA || len(res[0].Properties[1].EDT) != 0 {
+		t.Fatalf("unexpected response: %v", res)
+	}
+}
+
+func TestNetwork_SetAnnouncesChanges(t *testing.T) {
+	n := newTestNetwork()
+	defer n.Close()
+
+	res := request(t, n, testLightIP, &echonet_lite.ECHONETLiteMessage{
+		TID: 1, SEOJ: controller, DEOJ: testLight, ESV: echonet_lite.ESVSetC,
+		Properties: echonet_lite.Properties{{EPC: 0x80, EDT: []byte{0x30}}},
+	})
+	if len(res) != 2 || res[0].ESV != echonet_lite.ESVSet_Res || res[1].ESV != echonet_lite.ESVINF {
+		t.Fatalf("expected Set_Res and INF, got %v", res)
+	}
+
+	// Properties missing from the Set property map are rejected
+	res = request(t, n, testLightIP, &echonet_lite.ECHONETLiteMessage{
+		TID: 2, SEOJ: controller, DEOJ: testLight, ESV: echonet_lite.ESVSetC,
+		Properties: echonet_lite.Properties{{EPC: 0xE0, EDT: []byte{0x01}}},
+	})
+	if len(res) != 1 || res[0].ESV != echonet_lite.ESVSetC_SNA {
+		t.Fatalf("expected SetC_SNA, got %v", res)
+	}
+}
+
+func TestNetwork_BroadcastReachesEveryNode(t *testing.T) {
+	n := newTestNetwork()
+	defer n.Close()
+
+	res := request(t, n, net.ParseIP("255.255.255.255"), &echonet_lite.ECHONETLiteMessage{
+		TID: 1, SEOJ: controller, DEOJ: echonet_lite.NodeProfileObject, ESV: echonet_lite.ESVGet,
+		Properties: echonet_lite.Properties{{EPC: echonet_lite.EPC_NPO_SelfNodeInstanceListS}},
+	})
+	if len(res) != 2 {
+		t.Fatalf("expected a response from each node, got %v", res)
+	}
+	list := echonet_lite.DecodeSelfNodeInstanceListS(res[0].Properties[0].EDT)
+	if list == nil || len(*list) != 1 || (*list)[0] != testLight {
+		t.Errorf("unexpected instance list: %v", list)
+	}
+}
+
+func TestNetwork_History(t *testing.T) {
+	n := newTestNetwork()
+	defer n.Close()
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	samples := n.History(since, since.Add(3*time.Hour), 30*time.Minute)
+	// The sensor value changes every hour, so unchanged samples are skipped
+	if len(samples) != 3 {
+		t.Fatalf("expected 3 samples, got %d: %v", len(samples), samples)
+	}
+	if !samples[1].Time.Equal(since.Add(time.Hour)) || samples[1].Property.EDT[0] != 1 {
+		t.Errorf("unexpected sample: %+v", samples[1])
+	}
+}
+
+func TestNetwork_Close(t *testing.T) {
+	n := newTestNetwork()
+	if err := n.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := n.Receive(context.Background()); err != net.ErrClosed {
+		t.Errorf("Receive after Close = %v, want net.ErrClosed", err)
+	}
+	if _, err := n.SendTo(testLightIP, nil); err != net.ErrClosed {
+		t.Errorf("SendTo after Close = %v, want net.ErrClosed", err)
+	}
+}
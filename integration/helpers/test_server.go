@@ -89,7 +89,11 @@ func (ts *TestServer) Start() error {
 	}
 
 	// ログマネージャーを作成
-	logManager, err := server.NewLogManager(ts.Config.Log.Filename, ts.Config.Debug)
+	logLevel, err := ts.Config.LogLevel()
+	if err != nil {
+		return fmt.Errorf("ログレベルの設定が不正です: %v", err)
+	}
+	logManager, err := server.NewLogManager(ts.Config.Log.Filename, logLevel)
 	if err != nil {
 		return fmt.Errorf("ログマネージャーの作成に失敗: %v", err)
 	}
@@ -112,9 +116,10 @@ func (ts *TestServer) Start() error {
 	ts.WSServer = wsServer
 
 	// ログブロードキャストを設定
-	if err := logManager.SetTransport(wsServer.GetTransport()); err != nil {
+	if err := logManager.SetTransport(wsServer.LogTransport()); err != nil {
 		return fmt.Errorf("ログブロードキャスト設定に失敗: %v", err)
 	}
+	wsServer.SetLogStream(logManager.Stream())
 
 	// サーバーを非同期で起動
 	readyChan := make(chan struct{})
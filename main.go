@@ -5,18 +5,25 @@ import (
 	"echonet-list/client"
 	"echonet-list/config"
 	"echonet-list/console"
+	"echonet-list/daemon"
+	"echonet-list/discovery"
+	"echonet-list/echonet_lite"
 	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
 	"echonet-list/server"
+	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
-	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
+	_ "time/tzdata" // タイムゾーン名を解決できない環境でも timezone 設定を使えるようにする
 )
 
 const (
@@ -27,56 +34,120 @@ func main() {
 	// サーバーの起動時刻を記録（UTC）
 	serverStartupTime := time.Now().UTC()
 
+	// コマンド実行モードの終了コード（defer で後始末をしてから終了する）
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
 	// コマンドライン引数のヘルプメッセージをカスタマイズ
 	flag.Usage = func() {
-		_, _ = fmt.Fprintf(os.Stderr, "使用方法: %s [オプション]\n\nオプション:\n", os.Args[0])
+		_, _ = fmt.Fprintf(os.Stderr, "使用方法: %s [オプション] [コマンド]\n\nコマンドを指定すると、それだけを実行して終了します（例: %s -ws-client -json devices）\n\nオプション:\n", os.Args[0], os.Args[0])
 		flag.PrintDefaults()
 	}
 
 	// コマンドライン引数を解析し、設定ファイルを読み込む
 	cmdArgs := config.ParseCommandLineArgs()
-	cfg, err := config.LoadConfig(cmdArgs.ConfigFile)
+	cfg, err := config.LoadConfig(cmdArgs.ConfigFile, cmdArgs.Profile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "設定ファイルの読み込みに失敗しました: %v\n", err)
 		os.Exit(1)
 	}
 
+	// コマンドを1つだけ実行する場合は、起動時のメッセージが結果に混ざらないよう標準エラーに出す
+	stdout := os.Stdout
+	oneShot := len(cmdArgs.Command) > 0
+	if oneShot {
+		os.Stdout = os.Stderr
+	}
+
 	// コマンドライン引数を設定に適用
 	cfg.ApplyCommandLineArgs(cmdArgs)
+	if cfg.Profile != "" {
+		fmt.Printf("プロファイル: %s\n", cfg.Profile)
+	}
+
+	// タイムゾーンを設定（ログ・履歴・スケジュールの時刻とプロトコルのタイムスタンプはこのタイムゾーンで扱う）
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "タイムゾーン '%s' の読み込みに失敗しました: %v\n", cfg.Timezone, err)
+			os.Exit(1)
+		}
+		time.Local = loc
+	}
+
+	// コンソールとログに表示するプロパティ名・クラス名の言語を設定
+	if err := echonet_lite.SetDisplayLanguage(cfg.Locale); err != nil {
+		fmt.Fprintf(os.Stderr, "locale の設定が不正です: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Daemon mode pre-checks and PID file handling
+	// シグナルの配線もデーモンのライフサイクルを通して行う（PIDファイルはデーモンモードのときだけ作る）
+	lifecycle := daemon.New(nil, cfg.Daemon.PIDFile)
 	if cfg.Daemon.Enabled {
 		if !cfg.WebSocket.Enabled {
 			fmt.Fprintln(os.Stderr, "デーモンモードでは WebSocket サーバーを有効にする必要があります。-websocket を指定してください。")
 			os.Exit(1)
 		}
 
-		// PIDファイルの作成
-		pid := os.Getpid()
-		pidDir := filepath.Dir(cfg.Daemon.PIDFile)
-
-		// PIDファイルのディレクトリが存在しない場合は作成を試みる
-		if _, err := os.Stat(pidDir); os.IsNotExist(err) {
-			if err := os.MkdirAll(pidDir, 0755); err != nil {
-				fmt.Fprintf(os.Stderr, "PIDファイルのディレクトリ作成に失敗しました: %v\n", err)
-				fmt.Fprintf(os.Stderr, "ヒント: sudo権限で実行するか、書き込み可能なパスを -pidfile で指定してください。\n")
+		// PIDファイルの作成（異常終了で残ったPIDファイルは引き継ぐ）
+		stalePID, err := lifecycle.WritePIDFile()
+		if err != nil {
+			var running *daemon.AlreadyRunningError
+			if errors.As(err, &running) {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				fmt.Fprintf(os.Stderr, "ヒント: 起動中のプロセスを停止するか、別のPIDファイルを -pidfile で指定してください。\n")
 				os.Exit(1)
 			}
-		}
-
-		if err := os.WriteFile(cfg.Daemon.PIDFile, []byte(fmt.Sprintf("%d\n", pid)), 0644); err != nil {
-			fmt.Fprintf(os.Stderr, "PIDファイルの作成に失敗しました: %v\n", err)
+			fmt.Fprintf(os.Stderr, "%v\n", err)
 			fmt.Fprintf(os.Stderr, "使用しようとしたパス: %s\n", cfg.Daemon.PIDFile)
+			fmt.Fprintf(os.Stderr, "ヒント: sudo権限で実行するか、書き込み可能なパスを -pidfile で指定してください。\n")
 			os.Exit(1)
 		}
-		defer os.Remove(cfg.Daemon.PIDFile)
+		defer func() { _ = lifecycle.RemovePIDFile() }()
+		if stalePID > 0 {
+			fmt.Printf("終了したプロセス (PID %d) のPIDファイルを引き継ぎました\n", stalePID)
+		}
+
+		fmt.Printf("デーモンモードで起動しました (PID: %d, PIDファイル: %s)\n", lifecycle.Pid(), lifecycle.PIDFile())
+	}
 
-		fmt.Printf("デーモンモードで起動しました (PID: %d, PIDファイル: %s)\n", pid, cfg.Daemon.PIDFile)
+	// データディレクトリを決め、相対パスのデータファイルとログファイルをその中に置く
+	// デバイスを扱うモードでは、以前の版がカレントディレクトリに保存したファイルをデータディレクトリに移す
+	// デモモードはデータファイルを読み書きしないため移さない
+	legacyFiles := cfg.ResolveDataDir()
+	usesDataFiles := cfg.WebSocket.Enabled || (!cfg.WebSocketClient.Enabled && !cfg.HTTPServer.Enabled)
+	if !usesDataFiles || cfg.Demo.Enabled {
+		legacyFiles = nil
+	}
+	migrated, err := cfg.PrepareDataDir(legacyFiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "データディレクトリの準備に失敗しました: %v\n", err)
+		os.Exit(1)
+	}
+	for _, file := range migrated {
+		fmt.Printf("データファイルを移しました: %s\n", file)
+	}
+
+	// メーカー独自のプロパティの説明を追加・上書きする（ファイルがない場合は何もしない）
+	overrides, err := echonet_lite.LoadPropertyDescOverrides(cfg.DataFiles.PropertyOverridesFile)
+	if err == nil && overrides != nil {
+		var count int
+		if count, err = echonet_lite.ApplyPropertyDescOverrides(overrides); err == nil {
+			fmt.Printf("プロパティの説明を上書きしました: %d件 (%s)\n", count, cfg.DataFiles.PropertyOverridesFile)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "プロパティの説明の上書きに失敗しました: %v\n", err)
+		os.Exit(1)
 	}
 
 	// 設定値を取得
 	logFilename := cfg.Log.Filename
-	debug := cfg.Debug
 	websocket := cfg.WebSocket.Enabled
 	wsClient := cfg.WebSocketClient.Enabled
 	if cfg.Daemon.Enabled {
@@ -85,7 +156,12 @@ func main() {
 	wsClientAddr := cfg.WebSocketClient.Addr
 
 	// ロガーのセットアップ
-	logManager, err := server.NewLogManager(logFilename, debug)
+	logLevel, err := cfg.LogLevel()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ログ設定エラー: %v\n", err)
+		os.Exit(1)
+	}
+	logManager, err := server.NewLogManager(logFilename, logLevel)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "ログ設定エラー: %v\n", err)
 		os.Exit(1)
@@ -93,7 +169,15 @@ func main() {
 
 	// デーモンモードのときにのみ、SIGHUP でlog rotate を実行
 	if cfg.Daemon.Enabled {
-		logManager.AutoRotate()
+		stopRotate := lifecycle.OnSignal(syscall.SIGHUP, func() {
+			fmt.Fprintln(os.Stderr, "SIGHUPを受信しました。ログファイルをローテーションします...")
+			if err := logManager.Rotate(); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "ログローテーションエラー: %v\n", err)
+			} else {
+				slog.Info("SIGHUPを受信しました。ログファイルをローテーションしました")
+			}
+		})
+		defer stopRotate()
 	}
 
 	// ログファイルを閉じる
@@ -102,12 +186,8 @@ func main() {
 	}()
 
 	// ルートコンテキストの作成
-	signals := []os.Signal{os.Interrupt, syscall.SIGTERM}
-	if !cfg.Daemon.Enabled {
-		// コンソールUIモードではSIGHUPでも終了する
-		signals = append(signals, syscall.SIGHUP)
-	}
-	ctx, stop := signal.NotifyContext(context.Background(), signals...)
+	// コンソールUIモードではSIGHUPでも終了する
+	ctx, stop := lifecycle.NotifyContext(context.Background(), daemon.ShutdownSignals(cfg.Daemon.Enabled)...)
 	defer stop() // プログラム終了時にコンテキストをキャンセル
 
 	var wg sync.WaitGroup
@@ -115,10 +195,19 @@ func main() {
 
 	// WebSocketサーバーモードの場合
 	if websocket {
+		// 終了時に応答待ちの要求を待てるように、サーバーはシグナルとは別のコンテキストで動かす
+		shutdownTimeout, err := cfg.ShutdownTimeoutDuration()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "設定ファイル 'shutdown_timeout' が不正です: %v\n", err)
+			os.Exit(1)
+		}
+		serverCtx, cancelServer := context.WithCancel(context.Background())
+		defer cancelServer()
+
 		// ECHONETLiteHandlerの作成
-		s, err := server.NewServer(ctx, cfg)
+		s, err := server.NewServer(serverCtx, cfg)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
+			printServerError(err)
 			os.Exit(1)
 		}
 
@@ -139,7 +228,7 @@ func main() {
 
 		// WebSocketサーバーの作成と起動
 		wsServer, err := server.NewWebSocketServer(
-			ctx,
+			serverCtx,
 			httpAddr,
 			client.NewECHONETListClientProxy(s.GetHandler()),
 			s.GetHandler(),
@@ -148,6 +237,7 @@ func main() {
 				PerDeviceSettableLimit:    cfg.History.PerDeviceSettableLimit,
 				PerDeviceNonSettableLimit: cfg.History.PerDeviceNonSettableLimit,
 				HistoryFilePath:           cfg.DataFiles.HistoryFile,
+				Disabled:                  !cfg.History.Enabled,
 			},
 		)
 		if err != nil {
@@ -156,12 +246,16 @@ func main() {
 		}
 
 		// LogManagerにWebSocketトランスポートを設定
-		if err := logManager.SetTransport(wsServer.GetTransport()); err != nil {
+		if err := logManager.SetTransport(wsServer.LogTransport()); err != nil {
 			fmt.Fprintf(os.Stderr, "ログブロードキャスト設定エラー: %v\n", err)
 		}
+		wsServer.SetLogStream(logManager.Stream())
 
-		// プログラム終了時にWebSocketサーバーを停止する
+		// プログラム終了時に、新しいコマンドの受け付けを止めて応答待ちの要求が終わるのを待ってから、WebSocketサーバーを停止する
+		// その後 s.Close でデバイス情報と履歴を保存し、ECHONET Lite のソケットを閉じる
 		defer func() {
+			wsServer.BeginShutdown()
+			drainServer(s, shutdownTimeout)
 			if err := wsServer.Stop(); err != nil {
 				fmt.Printf("WebSocketサーバーの停止に失敗しました: %v\n", err)
 			}
@@ -183,6 +277,113 @@ func main() {
 			forcedUpdateInterval = 30 * time.Minute // パース失敗時はデフォルト値
 		}
 
+		// 強制更新の時刻をパース
+		var forcedUpdateSchedule *handler.CronSpec
+		if spec := strings.TrimSpace(cfg.WebSocket.ForcedUpdateSchedule); spec != "" {
+			schedule, err := handler.ParseCronSpec(spec)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "設定ファイル 'websocket.forced_update_schedule' が不正です: %v\n", err)
+				os.Exit(1)
+			}
+			forcedUpdateSchedule = &schedule
+		}
+
+		// プロパティ変化をまとめる時間幅をパース
+		propertyChangeWindowStr := cfg.WebSocket.PropertyChangeWindow
+		propertyChangeWindow, err := time.ParseDuration(propertyChangeWindowStr)
+		if err != nil || propertyChangeWindowStr == "" {
+			fmt.Printf("警告: 設定ファイル 'websocket.property_change_window' の値 '%s' は無効です。デフォルトの%vを使用します。\n", propertyChangeWindowStr, server.DefaultPropertyChangeCoalesceWindow)
+			propertyChangeWindow = server.DefaultPropertyChangeCoalesceWindow // パース失敗時はデフォルト値
+		}
+
+		// 遅いクライアントの扱いをパース
+		slowClientPolicy, err := server.ParseSlowClientPolicy(cfg.WebSocket.SlowClientPolicy)
+		if err != nil {
+			fmt.Printf("警告: 設定ファイル 'websocket.slow_client_policy' の値 '%s' は無効です。デフォルトの%sを使用します。\n", cfg.WebSocket.SlowClientPolicy, server.SlowClientPolicyDisconnect)
+			slowClientPolicy = server.SlowClientPolicyDisconnect // パース失敗時はデフォルト値
+		}
+
+		// リクエスト数の上限（設定ファイルの指定でデフォルトを上書き）
+		rateLimits := server.DefaultRateLimits()
+		for msgType, limit := range cfg.WebSocket.RateLimit {
+			rateLimits[protocol.MessageType(msgType)] = server.RateLimit{Rate: limit.Rate, Burst: limit.Burst}
+		}
+
+		// デバイスクラス・デバイスごとの定期更新の間隔
+		pollIntervals, err := server.ParsePollIntervals(cfg.WebSocket.PollIntervals)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "設定ファイル 'websocket.poll_intervals' が不正です: %v\n", err)
+			os.Exit(1)
+		}
+		if updateInterval > 0 {
+			// 定期更新が有効なら、スマートメータなど変化の速いクラスは既定で短い間隔で更新する
+			pollIntervals = pollIntervals.WithClassDefaults()
+		}
+
+		// 定期更新しないデバイスクラス・デバイス
+		pollExclusions, err := server.ParsePollExclusions(cfg.WebSocket.PollExclude)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "設定ファイル 'websocket.poll_exclude' が不正です: %v\n", err)
+			os.Exit(1)
+		}
+
+		// 通知・記録する数値プロパティの変化の最小幅
+		changeThresholds, err := server.ParseChangeThresholds(cfg.WebSocket.ChangeThresholds)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "設定ファイル 'websocket.change_thresholds' が不正です: %v\n", err)
+			os.Exit(1)
+		}
+
+		// アクセス制御（有効な場合はトークンのないクライアントを受け付けない）
+		var accessControl *server.AccessControl
+		if cfg.Access.Enabled {
+			rules := make([]server.AccessRule, 0, len(cfg.Access.Tokens))
+			for _, token := range cfg.Access.Tokens {
+				rules = append(rules, server.AccessRule{
+					Name:    token.Name,
+					Token:   token.Token,
+					Read:    token.Read,
+					Control: token.Control,
+					Admin:   token.Admin,
+				})
+			}
+			accessControl, err = server.NewAccessControl(rules)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "アクセス制御の設定が不正です: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("アクセス制御が有効です。トークン数: %d\n", len(rules))
+		}
+
+		// 数値プロパティの閾値によるアラート
+		alertRules, err := buildAlertRules(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "アラートの設定が不正です: %v\n", err)
+			os.Exit(1)
+		}
+
+		// 数値プロパティの InfluxDB への書き出し
+		var influxDB *server.InfluxDBOptions
+		if cfg.InfluxDB.Enabled {
+			interval, err := cfg.InfluxDBInterval()
+			if err == nil {
+				influxDB = &server.InfluxDBOptions{
+					URL:         cfg.InfluxDB.URL,
+					Org:         cfg.InfluxDB.Org,
+					Bucket:      cfg.InfluxDB.Bucket,
+					Token:       cfg.InfluxDB.Token,
+					UDPAddr:     cfg.InfluxDB.UDPAddr,
+					Measurement: cfg.InfluxDB.Measurement,
+					Interval:    interval,
+				}
+				err = influxDB.Validate()
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "InfluxDB の設定が不正です: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
 		// TLSと定期更新間隔の設定を準備
 		readyChan := make(chan struct{})
 		startOptions := server.StartOptions{
@@ -190,26 +391,58 @@ func main() {
 			CertFile:               cfg.TLS.CertFile,
 			KeyFile:                cfg.TLS.KeyFile,
 			PeriodicUpdateInterval: updateInterval,
+			PollIntervals:          pollIntervals,
+			PollExclusions:         pollExclusions,
 			ForcedUpdateInterval:   forcedUpdateInterval,
+			ForcedUpdateSchedule:   forcedUpdateSchedule,
 			HTTPEnabled:            cfg.HTTPServer.Enabled,
 			HTTPWebRoot:            cfg.HTTPServer.WebRoot,
+			ELWAEnabled:            cfg.HTTPServer.ELWA,
+
+			PropertyChangeCoalesceWindow: propertyChangeWindow,
+			SendQueueSize:                cfg.WebSocket.SendQueueSize,
+			SlowClientPolicy:             slowClientPolicy,
+			RateLimits:                   rateLimits,
+			ChangeThresholds:             changeThresholds,
+			AccessControl:                accessControl,
+			AlertRules:                   alertRules,
+			InfluxDB:                     influxDB,
+		}
+		if cfg.TLS.Enabled && len(cfg.TLS.ACME.Domains) > 0 {
+			startOptions.ACME = &server.ACMEOptions{
+				Domains:           cfg.TLS.ACME.Domains,
+				Email:             cfg.TLS.ACME.Email,
+				CacheDir:          cfg.TLS.ACME.CacheDir,
+				HTTPChallengeAddr: cfg.TLS.ACME.HTTPChallengeAddr,
+				DirectoryURL:      cfg.TLS.ACME.DirectoryURL,
+			}
 		}
 
 		// 設定された定期更新間隔を表示
 		if updateInterval > 0 {
 			fmt.Printf("WebSocketサーバーの定期更新間隔: %v\n", updateInterval)
-			if forcedUpdateInterval > 0 {
+			if forcedUpdateSchedule != nil {
+				fmt.Printf("WebSocketサーバーの強制更新時刻: %s\n", cfg.WebSocket.ForcedUpdateSchedule)
+			} else if forcedUpdateInterval > 0 {
 				fmt.Printf("WebSocketサーバーの強制更新間隔: %v\n", forcedUpdateInterval)
 			} else {
 				fmt.Println("WebSocketサーバーの強制更新は無効です。")
 			}
-		} else {
+		} else if pollIntervals.IsEmpty() {
 			fmt.Println("WebSocketサーバーの定期更新は無効です。")
 		}
+		if !pollIntervals.IsEmpty() {
+			fmt.Printf("WebSocketサーバーの個別の定期更新間隔: %d 件\n", len(pollIntervals.Classes)+len(pollIntervals.Devices))
+		}
+		if excluded := pollExclusions.List(); len(excluded) > 0 {
+			fmt.Printf("WebSocketサーバーの定期更新から除外: %s\n", strings.Join(excluded, ", "))
+		}
 
 		// TLSが有効かどうかを表示
 		if cfg.TLS.Enabled {
-			if startOptions.CertFile != "" && startOptions.KeyFile != "" {
+			if startOptions.ACME != nil {
+				fmt.Printf("TLSが有効です。ACMEで証明書を自動取得します: %s\n", strings.Join(startOptions.ACME.Domains, ", "))
+			} else if startOptions.CertFile != "" && startOptions.KeyFile != "" {
 				fmt.Printf("TLSが有効です。証明書: %s, 秘密鍵: %s\n", startOptions.CertFile, startOptions.KeyFile)
 			} else {
 				fmt.Fprintln(os.Stderr, "TLSが有効ですが、証明書または秘密鍵が指定されていません。")
@@ -229,6 +462,18 @@ func main() {
 
 		fmt.Printf("統合サーバーを起動しました: %s\n", httpAddr)
 
+		// mDNS でサーバーを広告し、クライアントが -discover で見つけられるようにする
+		if cfg.MDNS.Enabled {
+			advertiser, err := advertiseServer(cfg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "警告: mDNS による広告を開始できません: %v\n", err)
+			} else {
+				service := advertiser.Service()
+				fmt.Printf("mDNS で広告しています: %s (%s.local:%d)\n", service.Instance, service.Host, service.Port)
+				defer func() { _ = advertiser.Close() }()
+			}
+		}
+
 		// WebSocketクライアントモードも有効な場合は、サーバーの Ready チャネルを待機
 		if wsClient {
 			<-readyChan
@@ -243,8 +488,16 @@ func main() {
 
 	// WebSocketクライアントモードの場合
 	if wsClient {
-		// TLSが有効な場合は、接続先アドレスを修正
-		if cfg.TLS.Enabled && cfg.TLS.CertFile != "" && cfg.TLS.KeyFile != "" {
+		// mDNS でサーバーを探す（見つかったサーバーの URL はスキームまで決まっている）
+		if cfg.WebSocketClient.Discover && !websocket {
+			addr, err := discoverServer(ctx)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			wsClientAddr = addr
+		} else if tlsEnabled(cfg) {
+			// TLSが有効な場合は、接続先アドレスを修正
 			// ws:// を wss:// に置き換え
 			if strings.HasPrefix(wsClientAddr, "ws://") {
 				wsClientAddr = "wss://" + wsClientAddr[5:]
@@ -283,10 +536,18 @@ func main() {
 
 	// スタンドアロンモードの場合
 	if !websocket && !wsClient && !cfg.HTTPServer.Enabled {
+		shutdownTimeout, err := cfg.ShutdownTimeoutDuration()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "設定ファイル 'shutdown_timeout' が不正です: %v\n", err)
+			os.Exit(1)
+		}
+		serverCtx, cancelServer := context.WithCancel(context.Background())
+		defer cancelServer()
+
 		// ECHONETLiteHandlerの作成
-		s, err := server.NewServer(ctx, cfg)
+		s, err := server.NewServer(serverCtx, cfg)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
+			printServerError(err)
 			os.Exit(1)
 		}
 
@@ -297,6 +558,7 @@ func main() {
 			fmt.Println("ネットワーク監視: 無効")
 		}
 		defer func() {
+			drainServer(s, shutdownTimeout)
 			if err := s.Close(); err != nil {
 				fmt.Printf("セッションのクローズ中にエラーが発生しました: %v\n", err)
 			}
@@ -306,9 +568,24 @@ func main() {
 		c = client.NewECHONETListClientProxy(s.GetHandler())
 	}
 
-	if !cfg.Daemon.Enabled {
+	if oneShot {
+		// コマンド実行モード（例: echonet-list -ws-client -json devices 0130）
+		if wsClientInstance != nil {
+			if err := wsClientInstance.WaitInitialState(10 * time.Second); err != nil {
+				fmt.Fprintf(os.Stderr, "エラー: %v\n", err)
+				exitCode = 1
+				return
+			}
+		}
+		os.Stdout = stdout
+		if err := console.RunCommand(ctx, c, strings.Join(cmdArgs.Command, " "), cmdArgs.JSONOutput); err != nil {
+			fmt.Fprintf(os.Stderr, "エラー: %v\n", err)
+			exitCode = 1
+		}
+		os.Stdout = os.Stderr
+	} else if !cfg.Daemon.Enabled {
 		// コンソールUIモード
-		console.ConsoleProcess(ctx, c)
+		console.ConsoleProcess(ctx, c, cmdArgs.JSONOutput)
 	} else {
 		// デーモンモード
 		// wg.Wait() または ctx.Done() を待機
@@ -319,3 +596,118 @@ func main() {
 		<-ctx.Done()
 	}
 }
+
+// drainServer は、新しい ECHONET Lite の要求の送信を止め、応答待ちの要求が終わるまで最大 timeout 待つ
+func drainServer(s *server.Server, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := s.GetHandler().Drain(ctx); err != nil {
+		fmt.Printf("応答待ちの要求の完了を待たずに終了します: %v\n", err)
+	}
+}
+
+// printServerError は、サーバーを作成できなかった理由を表示する
+// 別のインスタンスが起動中の場合は、そのサーバーに接続する方法を案内する
+func printServerError(err error) {
+	fmt.Fprintf(os.Stderr, "%v\n", err)
+	var locked *handler.InstanceLockedError
+	if errors.As(err, &locked) {
+		fmt.Fprintln(os.Stderr, "同じデータファイルで2つのサーバーを起動すると、互いの保存内容を上書きしてしまいます。")
+		fmt.Fprintln(os.Stderr, "起動中のサーバーを操作するには、-ws-client オプションでそのサーバーに接続してください（例: echonet-list -ws-client -ws-client-addr ws://localhost:8080/ws）。")
+	}
+}
+
+// tlsEnabled は、統合サーバーが TLS (wss://) で待ち受けるかどうかを返す
+func tlsEnabled(cfg *config.Config) bool {
+	return cfg.TLS.Enabled && (cfg.TLS.CertFile != "" && cfg.TLS.KeyFile != "" || len(cfg.TLS.ACME.Domains) > 0)
+}
+
+// advertiseServer は、統合サーバーを mDNS で広告する
+// localhost など、他のホストから接続できないアドレスで待ち受けている場合は広告しない
+func advertiseServer(cfg *config.Config) (*discovery.Advertiser, error) {
+	service := discovery.Service{
+		Instance: cfg.MDNS.Instance,
+		Port:     cfg.HTTPServer.Port,
+		TLS:      tlsEnabled(cfg),
+	}
+	// ACME の証明書はそのドメイン名でしか検証できないため、ドメイン名で接続してもらう
+	if service.TLS && len(cfg.TLS.ACME.Domains) > 0 {
+		service.TLSHost = cfg.TLS.ACME.Domains[0]
+	}
+	if host := cfg.HTTPServer.Host; host != "" {
+		ips, err := net.LookupIP(host)
+		if err != nil {
+			return nil, fmt.Errorf("待ち受けるアドレス %s を解決できません: %w", host, err)
+		}
+		// 0.0.0.0 などは全てのインターフェースで待ち受けるので、IPs を空にしてインターフェースのアドレスを広告する
+		if !slices.ContainsFunc(ips, net.IP.IsUnspecified) {
+			for _, ip := range ips {
+				if ip4 := ip.To4(); ip4 != nil && !ip4.IsLoopback() {
+					service.IPs = append(service.IPs, ip4)
+				}
+			}
+			if len(service.IPs) == 0 {
+				return nil, fmt.Errorf("%s は他のホストから接続できません。http_server.host にこのホストのアドレスか 0.0.0.0 を指定してください", host)
+			}
+		}
+	}
+	return discovery.Advertise(service)
+}
+
+// discoverTimeout は、-discover でサーバーを探す時間
+const discoverTimeout = 3 * time.Second
+
+// discoverServer は、mDNS でサーバーを探し、接続先の URL を返す
+// 複数見つかった場合は一覧を表示し、インスタンス名の順で最初のサーバーを使う
+func discoverServer(ctx context.Context) (string, error) {
+	fmt.Printf("mDNS でサーバーを探しています (%v)...\n", discoverTimeout)
+	entries, err := discovery.Browse(ctx, discoverTimeout)
+	if err != nil {
+		return "", fmt.Errorf("mDNS によるサーバーの検索に失敗しました: %w", err)
+	}
+	if len(entries) == 0 {
+		return "", fmt.Errorf("サーバーが見つかりませんでした。サーバーで -mdns を指定しているか、同じネットワークにいるかを確認してください")
+	}
+	if len(entries) > 1 {
+		for _, entry := range entries {
+			fmt.Printf("  %s: %s\n", entry.Instance, entry.URL())
+		}
+	}
+	fmt.Printf("サーバーを見つけました: %s (%s)\n", entries[0].Instance, entries[0].URL())
+	return entries[0].URL(), nil
+}
+
+// buildAlertRules は、設定ファイルの [alerts] からアラートのルールを作成する（ルールがなければ nil）
+func buildAlertRules(cfg *config.Config) (*server.AlertRules, error) {
+	if len(cfg.Alerts.Rules) == 0 {
+		return nil, nil
+	}
+	rules := make([]server.AlertRule, 0, len(cfg.Alerts.Rules))
+	for _, rule := range cfg.Alerts.Rules {
+		epc, err := handler.ParseEPCString(rule.EPC)
+		if err != nil {
+			return nil, fmt.Errorf("アラート %q の epc が不正です: %w", rule.Name, err)
+		}
+		var duration time.Duration
+		if rule.Duration != "" {
+			if duration, err = time.ParseDuration(rule.Duration); err != nil {
+				return nil, fmt.Errorf("アラート %q の duration が不正です: %w", rule.Name, err)
+			}
+		}
+		webhook := rule.Webhook
+		if webhook == "" {
+			webhook = cfg.Alerts.Webhook
+		}
+		rules = append(rules, server.AlertRule{
+			Name:       rule.Name,
+			Device:     rule.Device,
+			EPC:        epc,
+			Comparator: server.AlertComparator(rule.Comparator),
+			Threshold:  rule.Threshold,
+			Hysteresis: rule.Hysteresis,
+			Duration:   duration,
+			Webhook:    webhook,
+		})
+	}
+	return server.NewAlertRules(rules)
+}
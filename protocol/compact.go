@@ -0,0 +1,106 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// EncodingCompact is the value of the ?encoding= query parameter of the WebSocket URL
+// that selects the compact device encoding for initial_state
+const EncodingCompact = "compact"
+
+// CompactProperty is a property encoded as an array instead of an object with repeated keys:
+// [epc, EDT] or [epc, EDT, string] or [epc, EDT, string, number].
+// Trailing empty elements are left out.
+type CompactProperty struct {
+	EPC string
+	PropertyData
+}
+
+// MarshalJSON encodes the property as an array
+func (p CompactProperty) MarshalJSON() ([]byte, error) {
+	values := []any{p.EPC, p.EDT}
+	if p.String != "" || p.Number != nil {
+		values = append(values, p.String)
+	}
+	if p.Number != nil {
+		values = append(values, *p.Number)
+	}
+	return json.Marshal(values)
+}
+
+// UnmarshalJSON decodes a property encoded as an array
+func (p *CompactProperty) UnmarshalJSON(data []byte) error {
+	var values []json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	if len(values) < 2 || len(values) > 4 {
+		return fmt.Errorf("compact property must have 2 to 4 elements, got %d", len(values))
+	}
+	*p = CompactProperty{}
+	if err := json.Unmarshal(values[0], &p.EPC); err != nil {
+		return fmt.Errorf("invalid EPC of compact property: %w", err)
+	}
+	if err := json.Unmarshal(values[1], &p.EDT); err != nil {
+		return fmt.Errorf("invalid EDT of compact property: %w", err)
+	}
+	if len(values) > 2 {
+		if err := json.Unmarshal(values[2], &p.String); err != nil {
+			return fmt.Errorf("invalid string of compact property: %w", err)
+		}
+	}
+	if len(values) > 3 {
+		var number int
+		if err := json.Unmarshal(values[3], &number); err != nil {
+			return fmt.Errorf("invalid number of compact property: %w", err)
+		}
+		p.Number = &number
+	}
+	return nil
+}
+
+// CompactDevice is a Device whose properties are encoded as an array of CompactProperty, ordered by EPC
+type CompactDevice struct {
+	Device
+	Properties []CompactProperty `json:"properties"`
+}
+
+// ToCompactDevice converts a device to the compact encoding
+func ToCompactDevice(device Device) CompactDevice {
+	properties := make([]CompactProperty, 0, len(device.Properties))
+	for epc, data := range device.Properties {
+		properties = append(properties, CompactProperty{EPC: epc, PropertyData: data})
+	}
+	sort.Slice(properties, func(i, j int) bool { return properties[i].EPC < properties[j].EPC })
+	device.Properties = nil
+	return CompactDevice{Device: device, Properties: properties}
+}
+
+// ToDevice converts a device in the compact encoding back to a Device
+func (d CompactDevice) ToDevice() Device {
+	device := d.Device
+	device.Properties = make(map[string]PropertyData, len(d.Properties))
+	for _, p := range d.Properties {
+		device.Properties[p.EPC] = p.PropertyData
+	}
+	return device
+}
+
+// CompactInitialStatePayload is the payload of initial_state for clients that asked for the compact encoding
+type CompactInitialStatePayload struct {
+	InitialStatePayload
+	Devices  map[string]CompactDevice `json:"devices"`
+	Encoding string                   `json:"encoding"` // always EncodingCompact
+}
+
+// ToCompactInitialState converts an initial_state payload to the compact encoding
+func ToCompactInitialState(payload InitialStatePayload) CompactInitialStatePayload {
+	devices := make(map[string]CompactDevice, len(payload.Devices))
+	for key, device := range payload.Devices {
+		devices[key] = ToCompactDevice(device)
+	}
+	payload.Devices = nil
+	return CompactInitialStatePayload{InitialStatePayload: payload, Devices: devices, Encoding: EncodingCompact}
+}
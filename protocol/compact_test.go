@@ -0,0 +1,84 @@
+package protocol
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+)
+
+func TestCompactDevice(t *testing.T) {
+	device := DeviceToProtocol(
+		echonet_lite.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)},
+		echonet_lite.Properties{
+			{EPC: 0x80, EDT: []byte{0x30}},
+			{EPC: 0xB3, EDT: []byte{25}},
+			{EPC: 0x9F, EDT: []byte{0x01, 0x80}},
+		},
+		time.Time{},
+		false,
+	)
+
+	data, err := json.Marshal(ToCompactDevice(device))
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+	var raw struct {
+		Properties [][]any `json:"properties"`
+		IP         string  `json:"ip"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	// Properties are arrays ordered by EPC, and the other fields are unchanged
+	if raw.IP != "192.168.1.10" || len(raw.Properties) != 3 || raw.Properties[0][0] != "80" || raw.Properties[1][0] != "9F" {
+		t.Fatalf("unexpected compact device: %s", data)
+	}
+	if got := raw.Properties[2]; len(got) != 4 || got[2] != "25℃" || got[3] != float64(25) {
+		t.Errorf("expected [B3, EDT, \"25℃\", 25], got %v", got)
+	}
+
+	var decoded CompactDevice
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+	if got := decoded.ToDevice(); !reflect.DeepEqual(got.Properties, device.Properties) {
+		t.Errorf("round trip changed the properties: %v, want %v", got.Properties, device.Properties)
+	}
+
+	var p CompactProperty
+	for _, input := range []string{`["80"]`, `["80","MA==","on",1,2]`, `{"EDT":"MA=="}`, `["80","MA==","on","x"]`} {
+		if err := json.Unmarshal([]byte(input), &p); err == nil {
+			t.Errorf("expected an error for %s", input)
+		}
+	}
+}
+
+func TestToCompactInitialState(t *testing.T) {
+	device := Device{IP: "192.168.1.10", EOJ: "0130:1", Properties: map[string]PropertyData{"80": {EDT: "MA==", String: "on"}}}
+	payload := ToCompactInitialState(InitialStatePayload{
+		Devices: map[string]Device{"192.168.1.10 0130:1": device},
+		Aliases: map[string]handler.IDString{},
+	})
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if string(raw["encoding"]) != `"compact"` || raw["aliases"] == nil || raw["serverTimezone"] == nil {
+		t.Errorf("unexpected compact initial state: %s", data)
+	}
+	var devices map[string]struct {
+		Properties [][]string `json:"properties"`
+	}
+	if err := json.Unmarshal(raw["devices"], &devices); err != nil || len(devices["192.168.1.10 0130:1"].Properties) != 1 {
+		t.Errorf("unexpected compact devices: %s (%v)", raw["devices"], err)
+	}
+}
@@ -0,0 +1,147 @@
+package protocol
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+)
+
+// CSVExportKind selects the table exported by export_csv.
+type CSVExportKind string
+
+const (
+	CSVExportDevices CSVExportKind = "devices" // one row per property of each device
+	CSVExportHistory CSVExportKind = "history" // one row per history entry
+)
+
+// ExportCSVPayload is the payload of the export_csv message.
+// An empty target exports all devices.
+type ExportCSVPayload struct {
+	Kind   CSVExportKind `json:"kind"`
+	Target string        `json:"target,omitempty"`
+	Since  *time.Time    `json:"since,omitempty"` // history only
+	Until  *time.Time    `json:"until,omitempty"` // history only
+	Lang   string        `json:"lang,omitempty"`  // language of the property names, e.g. "ja"
+}
+
+// ExportCSVResult is returned in the data of the command_result for export_csv.
+type ExportCSVResult struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	CSV         string `json:"csv"`
+}
+
+// CSVContentType is the content type of the exported CSV files.
+const CSVContentType = "text/csv; charset=utf-8"
+
+// csvBOM makes Excel read the UTF-8 CSV correctly, including Japanese names.
+const csvBOM = "\uFEFF"
+
+// csvTimeFormat is a timestamp format that Excel recognizes as a date and time.
+const csvTimeFormat = "2006-01-02 15:04:05"
+
+// CSVDevice identifies the device of the rows of a CSV export.
+type CSVDevice struct {
+	IP    string
+	EOJ   string
+	Alias string
+}
+
+// CSVHistoryEntry is a history entry of a device for WriteHistoryCSV.
+type CSVHistoryEntry struct {
+	Device CSVDevice
+	Entry  HistoryEntry
+}
+
+// csvPropertyColumns returns the property name, value, number and EDT columns of a property.
+func csvPropertyColumns(eoj, epcString string, value PropertyData, lang string) []string {
+	name := ""
+	if epc, err := handler.ParseEPCString(epcString); err == nil {
+		if parsed, err := handler.ParseEOJString(eoj); err == nil {
+			if desc, ok := echonet_lite.GetPropertyDesc(parsed.ClassCode(), epc); ok {
+				name = desc.GetName(lang)
+			}
+		}
+	}
+	number := ""
+	if value.Number != nil {
+		number = strconv.Itoa(*value.Number)
+	}
+	edt := ""
+	if decoded, err := base64.StdEncoding.DecodeString(value.EDT); err == nil {
+		edt = fmt.Sprintf("%X", decoded)
+	}
+	text := value.String
+	if text == "" {
+		text = edt
+	}
+	return []string{name, text, number, edt}
+}
+
+// WriteDevicesCSV writes the properties of the devices, one row per property.
+// aliases maps the "IP EOJ" of a device to its alias.
+func WriteDevicesCSV(w io.Writer, devices []Device, aliases map[string]string, lang string) error {
+	if _, err := io.WriteString(w, csvBOM); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"ip", "eoj", "alias", "class", "lastSeen", "offline", "epc", "property", "value", "number", "edt"})
+	for _, device := range devices {
+		class := device.ClassName
+		if lang == "ja" && device.ClassNameJa != "" {
+			class = device.ClassNameJa
+		}
+		lastSeen := ""
+		if !device.LastSeen.IsZero() {
+			lastSeen = device.LastSeen.Local().Format(csvTimeFormat)
+		}
+		alias := aliases[device.IP+" "+device.EOJ]
+		epcs := make([]string, 0, len(device.Properties))
+		for epc := range device.Properties {
+			epcs = append(epcs, epc)
+		}
+		sort.Strings(epcs)
+		for _, epc := range epcs {
+			row := []string{device.IP, device.EOJ, alias, class, lastSeen, strconv.FormatBool(device.IsOffline), epc}
+			row = append(row, csvPropertyColumns(device.EOJ, epc, device.Properties[epc], lang)...)
+			_ = writer.Write(row)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteHistoryCSV writes history entries, one row per entry in the given order.
+// Online and offline events have an empty EPC.
+func WriteHistoryCSV(w io.Writer, entries []CSVHistoryEntry, lang string) error {
+	if _, err := io.WriteString(w, csvBOM); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"time", "ip", "eoj", "alias", "epc", "property", "value", "number", "edt", "origin"})
+	for _, e := range entries {
+		row := []string{e.Entry.Timestamp.Local().Format(csvTimeFormat), e.Device.IP, e.Device.EOJ, e.Device.Alias, e.Entry.EPC}
+		if e.Entry.EPC != "" {
+			row = append(row, csvPropertyColumns(e.Device.EOJ, e.Entry.EPC, e.Entry.Value, lang)...)
+		} else {
+			row = append(row, "", "", "", "")
+		}
+		row = append(row, string(e.Entry.Origin))
+		_ = writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// CSVFilename returns a file name such as "echonet-history-20260101-120000.csv".
+func CSVFilename(kind CSVExportKind, t time.Time) string {
+	return "echonet-" + strings.ToLower(string(kind)) + "-" + t.Local().Format("20060102-150405") + ".csv"
+}
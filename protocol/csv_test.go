@@ -0,0 +1,83 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+)
+
+func readCSV(t *testing.T, buf *bytes.Buffer) [][]string {
+	t.Helper()
+	data := buf.String()
+	if !strings.HasPrefix(data, csvBOM) {
+		t.Fatal("expected the CSV to start with a BOM")
+	}
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(data, csvBOM))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestWriteDevicesCSV(t *testing.T) {
+	device := DeviceToProtocol(
+		echonet_lite.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)},
+		echonet_lite.Properties{
+			{EPC: 0xB3, EDT: []byte{25}},
+			{EPC: 0x80, EDT: []byte{0x30}},
+		},
+		time.Time{}, false,
+	)
+
+	var buf bytes.Buffer
+	if err := WriteDevicesCSV(&buf, []Device{device}, map[string]string{"192.168.1.10 0130:1": "living"}, "ja"); err != nil {
+		t.Fatal(err)
+	}
+	records := readCSV(t, &buf)
+	if len(records) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %d", len(records))
+	}
+	// Rows are sorted by EPC
+	if got := records[1]; got[0] != "192.168.1.10" || got[2] != "living" || got[4] != "" || got[6] != "80" || got[8] != "on" || got[10] != "30" {
+		t.Errorf("unexpected 80 row: %v", got)
+	}
+	if got := records[2]; got[6] != "B3" || got[9] != "25" || got[7] == "" {
+		t.Errorf("unexpected B3 row: %v", got)
+	}
+}
+
+func TestWriteHistoryCSV(t *testing.T) {
+	ts := time.Date(2026, 10, 1, 12, 0, 0, 0, time.Local)
+	device := CSVDevice{IP: "192.168.1.10", EOJ: "0130:1"}
+	entries := []CSVHistoryEntry{
+		{Device: device, Entry: HistoryEntry{Timestamp: ts, EPC: "80", Value: PropertyData{String: "on", EDT: "MA=="}, Origin: HistoryOriginSet}},
+		{Device: device, Entry: HistoryEntry{Timestamp: ts.Add(time.Minute), Origin: HistoryOriginOffline}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHistoryCSV(&buf, entries, ""); err != nil {
+		t.Fatal(err)
+	}
+	records := readCSV(t, &buf)
+	if len(records) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %d", len(records))
+	}
+	if got := records[1]; got[0] != "2026-10-01 12:00:00" || got[4] != "80" || got[6] != "on" || got[8] != "30" || got[9] != "set" {
+		t.Errorf("unexpected property row: %v", got)
+	}
+	if got := records[2]; got[4] != "" || got[5] != "" || got[9] != string(HistoryOriginOffline) {
+		t.Errorf("unexpected event row: %v", got)
+	}
+}
+
+func TestCSVFilename(t *testing.T) {
+	got := CSVFilename(CSVExportHistory, time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local))
+	if got != "echonet-history-20260102-030405.csv" {
+		t.Errorf("unexpected filename: %s", got)
+	}
+}
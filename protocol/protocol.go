@@ -1,11 +1,18 @@
 package protocol
 
 import (
+	"bytes"
 	"echonet-list/echonet_lite"
 	"echonet-list/echonet_lite/handler"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -62,6 +69,163 @@ type DeviceHistoryResponse struct {
 	Entries []HistoryEntry `json:"entries"`
 }
 
+// PropertyStatisticsEntry is the rolling change count for one EPC of a device.
+type PropertyStatisticsEntry struct {
+	Target     string    `json:"target"`
+	EPC        string    `json:"epc"`
+	LastHour   int       `json:"lastHour"`
+	LastDay    int       `json:"lastDay"`
+	LastChange time.Time `json:"lastChange"`
+}
+
+// PropertyStatisticsResponse is the payload returned for get_property_statistics.
+type PropertyStatisticsResponse struct {
+	Entries []PropertyStatisticsEntry `json:"entries"`
+	// Availability is returned when availabilityWindow is requested, lowest availability first.
+	Availability []DeviceAvailability `json:"availability,omitempty"`
+}
+
+// DeviceAvailability is the share of a time window a device was online, computed from its online/offline history.
+type DeviceAvailability struct {
+	Target         string  `json:"target"`
+	Percent        float64 `json:"percent"`        // Share of the window the device was online (0-100)
+	OfflineCount   int     `json:"offlineCount"`   // Number of times the device went offline during the window
+	OfflineSeconds int64   `json:"offlineSeconds"` // Total time the device was offline during the window
+}
+
+// SummaryDeviceCounts holds device counts for the get_summary response.
+type SummaryDeviceCounts struct {
+	Total   int `json:"total"`
+	Online  int `json:"online"`
+	Offline int `json:"offline"`
+	Faults  int `json:"faults"`
+}
+
+// SummaryEvent is a recent history event across all devices.
+type SummaryEvent struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Target    string        `json:"target"`
+	EPC       string        `json:"epc,omitempty"` // EPC is omitted for event entries (online/offline)
+	Value     PropertyData  `json:"value"`
+	Origin    HistoryOrigin `json:"origin"`
+}
+
+// SummaryResponse is the payload returned for get_summary.
+type SummaryResponse struct {
+	Devices       SummaryDeviceCounts `json:"devices"`
+	FaultDevices  []string            `json:"faultDevices"`
+	ActiveClients int                 `json:"activeClients"`
+	TotalPower    *int                `json:"totalPower,omitempty"` // Sum of instantaneous power consumption (W), omitted when no device reports it
+	RecentEvents  []SummaryEvent      `json:"recentEvents"`
+	Persistence   PersistenceStatus   `json:"persistence"`
+	Startup       *StartupReport      `json:"startup,omitempty"` // Omitted when the server did not load any file (test or in-memory mode)
+}
+
+// StartupReport is what the server restored from its saved files at startup, returned by get_summary.
+type StartupReport struct {
+	LoadedAt time.Time    `json:"loadedAt"`
+	Clean    bool         `json:"clean"` // True when every file was loaded without skipped records, restoration or errors
+	Files    []LoadedFile `json:"files"`
+}
+
+// LoadedFile is the result of loading one saved file at startup.
+type LoadedFile struct {
+	Kind          string `json:"kind"` // "devices", "aliases", "groups" or "history"
+	File          string `json:"file"`
+	Exists        bool   `json:"exists"` // False when the server started empty because the file did not exist
+	Records       int    `json:"records"`
+	Skipped       int    `json:"skipped,omitempty"`       // Corrupt records skipped while loading
+	FormatVersion int    `json:"formatVersion,omitempty"` // Omitted for files without a format version
+	Migrated      bool   `json:"migrated,omitempty"`      // Loaded from an older format; rewritten in the current format on the next save
+	Restored      bool   `json:"restored,omitempty"`      // The file was corrupt and was restored from a backup (or reset)
+	Error         string `json:"error,omitempty"`
+}
+
+// PersistenceStatus is the state of saving the device information file, returned by get_summary.
+// While saving fails, the server retries with backoff; the changes since LastSaved are lost if it stops meanwhile.
+type PersistenceStatus struct {
+	Healthy      bool       `json:"healthy"`
+	LastError    string     `json:"lastError,omitempty"`
+	FailingSince *time.Time `json:"failingSince,omitempty"`
+	Failures     int        `json:"failures,omitempty"` // Consecutive failures
+	LastSaved    *time.Time `json:"lastSaved,omitempty"`
+	NextRetry    *time.Time `json:"nextRetry,omitempty"`
+}
+
+// DeviceVersion is the ECHONET release a device implements and its identifiers, returned by get_versions.
+// Fields the device does not report are omitted.
+type DeviceVersion struct {
+	Target           string `json:"target"`
+	ClassName        string `json:"className,omitempty"`
+	Release          string `json:"release,omitempty"`          // Release letter of the standard version (EPC 0x82), e.g. "R"
+	Revision         int    `json:"revision,omitempty"`         // Revision of the release
+	StandardVersion  string `json:"standardVersion,omitempty"`  // EPC 0x82 as text, e.g. "Release R Rev.1"
+	ManufacturerCode string `json:"manufacturerCode,omitempty"` // EPC 0x8A in hex, e.g. "00000B"
+	Manufacturer     string `json:"manufacturer,omitempty"`
+	ManufacturerJa   string `json:"manufacturerJa,omitempty"`
+	ProductCode      string `json:"productCode,omitempty"` // EPC 0x8C
+}
+
+// VersionsResponse is the payload returned for get_versions.
+type VersionsResponse struct {
+	Devices []DeviceVersion `json:"devices"`
+}
+
+// MemoryUsageDevices reports the approximate memory used by cached device data.
+type MemoryUsageDevices struct {
+	Count       int   `json:"count"`
+	Properties  int   `json:"properties"`
+	ApproxBytes int64 `json:"approxBytes"`
+}
+
+// MemoryUsageHistory reports the approximate memory used by in-memory history.
+type MemoryUsageHistory struct {
+	Entries     int   `json:"entries"`
+	ApproxBytes int64 `json:"approxBytes"`
+}
+
+// MemoryUsageBuffer reports the fill level of a notification buffer.
+type MemoryUsageBuffer struct {
+	Name     string `json:"name"`
+	Length   int    `json:"length"`
+	Capacity int    `json:"capacity"`
+}
+
+// MemoryUsageRuntime reports Go runtime memory statistics.
+type MemoryUsageRuntime struct {
+	AllocBytes uint64 `json:"allocBytes"`
+	SysBytes   uint64 `json:"sysBytes"`
+	Goroutines int    `json:"goroutines"`
+	NumGC      uint32 `json:"numGC"`
+}
+
+// MemoryUsageSendQueue reports the fill level of the send queue of one WebSocket client.
+type MemoryUsageSendQueue struct {
+	ConnID   string `json:"connId"`
+	Length   int    `json:"length"`
+	Capacity int    `json:"capacity"`
+	Dropped  int64  `json:"dropped"` // messages dropped by the drop_oldest policy
+}
+
+// MemoryUsageClients reports the send queues of WebSocket clients and how slow clients were handled.
+type MemoryUsageClients struct {
+	SlowClientPolicy string                 `json:"slowClientPolicy"`
+	EvictedClients   int64                  `json:"evictedClients"`  // clients disconnected because their send queue was full
+	DroppedMessages  int64                  `json:"droppedMessages"` // total messages dropped by the drop_oldest policy
+	SendQueues       []MemoryUsageSendQueue `json:"sendQueues"`
+}
+
+// MemoryUsageResponse is the payload returned for get_memory_usage.
+// Store sizes are estimates and do not include Go runtime overhead.
+type MemoryUsageResponse struct {
+	Devices     MemoryUsageDevices  `json:"devices"`
+	History     MemoryUsageHistory  `json:"history"`
+	Buffers     []MemoryUsageBuffer `json:"buffers"`
+	Clients     *MemoryUsageClients `json:"clients,omitempty"` // omitted when the transport does not report send queues
+	ApproxBytes int64               `json:"approxBytes"`
+	Runtime     MemoryUsageRuntime  `json:"runtime"`
+}
+
 // MessageType defines the type of message being sent between client and server
 type MessageType string
 
@@ -71,33 +235,74 @@ const (
 	MessageTypeDeviceAdded         MessageType = "device_added"
 	MessageTypeAliasChanged        MessageType = "alias_changed"
 	MessageTypeGroupChanged        MessageType = "group_changed"
+	MessageTypeSceneChanged        MessageType = "scene_changed"
+	MessageTypeSceneProgress       MessageType = "scene_progress"
+	MessageTypeScheduleChanged     MessageType = "schedule_changed"
 	MessageTypePropertyChanged     MessageType = "property_changed"
+	MessageTypePropertiesChanged   MessageType = "properties_changed"
 	MessageTypeTimeoutNotification MessageType = "timeout_notification"
 	MessageTypeDeviceOffline       MessageType = "device_offline"
 	MessageTypeDeviceOnline        MessageType = "device_online"
 	MessageTypeDeviceDeleted       MessageType = "device_deleted"
+	MessageTypeDeviceReconfigured  MessageType = "device_reconfigured"
 	MessageTypeErrorNotification   MessageType = "error_notification"
 	MessageTypeCommandResult       MessageType = "command_result"
 	MessageTypeServerHeartbeat     MessageType = "server_heartbeat"
+	MessageTypeAlertRaised         MessageType = "alert_raised"
+	MessageTypeAlertCleared        MessageType = "alert_cleared"
+	MessageTypeReplayFinished      MessageType = "replay_finished"
+	MessageTypeLogEntry            MessageType = "log_entry"
+	MessageTypeUpdateProgress      MessageType = "update_progress"
 
 	// Client -> Server message types
-	MessageTypeGetProperties          MessageType = "get_properties"
-	MessageTypeSetProperties          MessageType = "set_properties"
-	MessageTypeUpdateProperties       MessageType = "update_properties"
-	MessageTypeListDevices            MessageType = "list_devices"
-	MessageTypeManageAlias            MessageType = "manage_alias"
-	MessageTypeManageGroup            MessageType = "manage_group"
-	MessageTypeDiscoverDevices        MessageType = "discover_devices"
-	MessageTypeGetPropertyDescription MessageType = "get_property_description"
-	MessageTypeDeleteDevice           MessageType = "delete_device"
-	MessageTypeDebugSetOffline        MessageType = "debug_set_offline"
-	MessageTypeGetDeviceHistory       MessageType = "get_device_history"
+	MessageTypeGetProperties           MessageType = "get_properties"
+	MessageTypeSetProperties           MessageType = "set_properties"
+	MessageTypeSetGetProperties        MessageType = "set_get_properties"
+	MessageTypeSetGroupProperties      MessageType = "set_group_properties"
+	MessageTypeUpdateProperties        MessageType = "update_properties"
+	MessageTypeListDevices             MessageType = "list_devices"
+	MessageTypeManageAlias             MessageType = "manage_alias"
+	MessageTypeManageGroup             MessageType = "manage_group"
+	MessageTypeManageScene             MessageType = "manage_scene"
+	MessageTypeRunScene                MessageType = "run_scene"
+	MessageTypeManageSchedule          MessageType = "manage_schedule"
+	MessageTypeDiscoverDevices         MessageType = "discover_devices"
+	MessageTypeGetPropertyDescription  MessageType = "get_property_description"
+	MessageTypeDeleteDevice            MessageType = "delete_device"
+	MessageTypeCleanupDevices          MessageType = "cleanup_devices"
+	MessageTypeRefreshPropertyMap      MessageType = "refresh_property_map"
+	MessageTypeDebugSetOffline         MessageType = "debug_set_offline"
+	MessageTypeGetDeviceHistory        MessageType = "get_device_history"
+	MessageTypeGetPropertyStatistics   MessageType = "get_property_statistics"
+	MessageTypeGetSummary              MessageType = "get_summary"
+	MessageTypeGetVersions             MessageType = "get_versions"
+	MessageTypeGetMemoryUsage          MessageType = "get_memory_usage"
+	MessageTypeGetAliases              MessageType = "get_aliases"
+	MessageTypeGetGroups               MessageType = "get_groups"
+	MessageTypeDebugPendingRequests    MessageType = "debug_pending_requests"
+	MessageTypeManageLocalPropertyMaps MessageType = "manage_local_property_maps"
+	MessageTypeManagePollExclusions    MessageType = "manage_poll_exclusions"
+	MessageTypeExportCSV               MessageType = "export_csv"
+	MessageTypeReplayHistory           MessageType = "replay_history"
+	MessageTypeSubscribeLogs           MessageType = "subscribe_logs"
 
 	// Location settings message types
 	MessageTypeGetLocationSettings     MessageType = "get_location_settings"
 	MessageTypeManageLocationAlias     MessageType = "manage_location_alias"
 	MessageTypeSetLocationOrder        MessageType = "set_location_order"
 	MessageTypeLocationSettingsChanged MessageType = "location_settings_changed" // Server -> Client
+
+	// Device metadata message types
+	MessageTypeManageMetadata  MessageType = "manage_metadata"
+	MessageTypeMetadataChanged MessageType = "metadata_changed" // Server -> Client
+
+	// Client preferences message types
+	MessageTypeGetPreferences MessageType = "get_preferences"
+	MessageTypeSetPreferences MessageType = "set_preferences"
+
+	// Bulk operation message types
+	MessageTypeGetOperationStatus MessageType = "get_operation_status"
+	MessageTypeCancelOperation    MessageType = "cancel_operation"
 )
 
 // AliasChangeType defines the type of alias change
@@ -124,19 +329,24 @@ type ErrorCode string
 const (
 	ErrorCodeInvalidRequestFormat ErrorCode = "INVALID_REQUEST_FORMAT"
 	ErrorCodeInvalidParameters    ErrorCode = "INVALID_PARAMETERS"
-	ErrorCodeTargetNotFound       ErrorCode = "TARGET_NOT_FOUND" // not used
+	ErrorCodeTargetNotFound       ErrorCode = "TARGET_NOT_FOUND"
 	ErrorCodeAliasOperationFailed ErrorCode = "ALIAS_OPERATION_FAILED"
 	ErrorCodeAliasAlreadyExists   ErrorCode = "ALIAS_ALREADY_EXISTS" // not used
 	ErrorCodeInvalidAliasName     ErrorCode = "INVALID_ALIAS_NAME"   // not used
 	ErrorCodeAliasNotFound        ErrorCode = "ALIAS_NOT_FOUND"      // not used
+	ErrorCodeRateLimited          ErrorCode = "RATE_LIMITED"         // Too many requests of the same type from one client
+	ErrorCodePermissionDenied     ErrorCode = "PERMISSION_DENIED"    // The access rule of the client does not allow the request
 )
 
 // Server/Communication Related
 const (
 	ErrorCodeEchonetTimeout            ErrorCode = "ECHONET_TIMEOUT"
-	ErrorCodeEchonetDeviceError        ErrorCode = "ECHONET_DEVICE_ERROR" // not used
+	ErrorCodeEchonetDeviceError        ErrorCode = "ECHONET_DEVICE_ERROR" // The device rejected the request
 	ErrorCodeEchonetCommunicationError ErrorCode = "ECHONET_COMMUNICATION_ERROR"
 	ErrorCodeInternalServerError       ErrorCode = "INTERNAL_SERVER_ERROR"
+	ErrorCodeFeatureDisabled           ErrorCode = "FEATURE_DISABLED"   // The requested feature is disabled by server configuration
+	ErrorCodeShuttingDown              ErrorCode = "SHUTTING_DOWN"      // The server is shutting down and no longer accepts requests
+	ErrorCodePersistenceFailed         ErrorCode = "PERSISTENCE_FAILED" // The server cannot save the device information file
 )
 
 // Message is the base structure for all WebSocket messages
@@ -148,13 +358,26 @@ type Message struct {
 
 // Device represents an ECHONET Lite device
 type Device struct {
-	IP         string                  `json:"ip"`
-	EOJ        string                  `json:"eoj"`
-	Name       string                  `json:"name"`
-	ID         handler.IDString        `json:"id,omitempty"`
-	Properties map[string]PropertyData `json:"properties"`
-	LastSeen   time.Time               `json:"lastSeen"`
-	IsOffline  bool                    `json:"isOffline,omitempty"`
+	IP          string                  `json:"ip"`
+	EOJ         string                  `json:"eoj"`
+	Name        string                  `json:"name"`
+	ClassName   string                  `json:"className,omitempty"`   // English class name
+	ClassNameJa string                  `json:"classNameJa,omitempty"` // Japanese class name
+	Icon        string                  `json:"icon,omitempty"`        // Suggested icon identifier for the class
+	ID          handler.IDString        `json:"id,omitempty"`
+	Properties  map[string]PropertyData `json:"properties"`
+	LastSeen    time.Time               `json:"lastSeen"`
+	IsOffline   bool                    `json:"isOffline,omitempty"`
+	// Product information decoded from the manufacturer code (0x8A), product code (0x8C) and production date (0x8E)
+	ManufacturerCode string `json:"manufacturerCode,omitempty"` // hexadecimal, e.g. "00000B"
+	Manufacturer     string `json:"manufacturer,omitempty"`     // English name, omitted when the code is not in the list
+	ManufacturerJa   string `json:"manufacturerJa,omitempty"`   // Japanese name
+	ProductCode      string `json:"productCode,omitempty"`
+	ProductionDate   string `json:"productionDate,omitempty"` // "2006-01-02"
+	// Availability is the percentage of time online, only set when list_devices requests an availabilityWindow
+	Availability *float64 `json:"availability,omitempty"`
+	// Favorite is true for devices pinned in the metadata or, for list_devices with a preferencesKey, in the favorites of the preferences
+	Favorite bool `json:"favorite,omitempty"`
 }
 
 // Error represents an error in the WebSocket protocol
@@ -165,11 +388,51 @@ type Error struct {
 
 // InitialStatePayload is the payload for the initial_state message
 type InitialStatePayload struct {
-	Devices           map[string]Device             `json:"devices"`
-	Aliases           map[string]handler.IDString   `json:"aliases"`
-	Groups            map[string][]handler.IDString `json:"groups"`
-	LocationSettings  *LocationSettingsData         `json:"locationSettings,omitempty"`
-	ServerStartupTime time.Time                     `json:"serverStartupTime"`
+	Devices          map[string]Device             `json:"devices"`
+	Aliases          map[string]handler.IDString   `json:"aliases"`
+	Groups           map[string][]handler.IDString `json:"groups"`
+	LocationSettings *LocationSettingsData         `json:"locationSettings,omitempty"`
+	// Metadata holds the notes and labels of the devices that have any, keyed by IDString
+	Metadata          map[handler.IDString]handler.DeviceMetadata `json:"metadata,omitempty"`
+	ServerStartupTime time.Time                                   `json:"serverStartupTime"`
+	ServerTimezone    TimezoneInfo                                `json:"serverTimezone"` // time zone of the timestamps in payloads
+	// Included lists the parts sent when the client requested a partial initial state; omitted when all parts are sent
+	Included []InitialStatePart `json:"included,omitempty"`
+}
+
+// InitialStatePart names a part of the initial_state message that a client can request
+// with the ?initial= query parameter of the WebSocket URL
+type InitialStatePart string
+
+const (
+	InitialStateDevices   InitialStatePart = "devices"
+	InitialStateAliases   InitialStatePart = "aliases"
+	InitialStateGroups    InitialStatePart = "groups"
+	InitialStateLocations InitialStatePart = "locations" // locationSettings
+	InitialStateMetadata  InitialStatePart = "metadata"
+	InitialStateNone      InitialStatePart = "none" // no initial_state message at all
+)
+
+// ParseInitialStateParts parses a comma-separated list of initial_state parts, e.g. "devices,aliases".
+// An empty string means all parts (nil), and "none" returns an empty list.
+func ParseInitialStateParts(s string) ([]InitialStatePart, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	parts := []InitialStatePart{}
+	for _, name := range strings.Split(s, ",") {
+		part := InitialStatePart(strings.TrimSpace(name))
+		switch part {
+		case InitialStateDevices, InitialStateAliases, InitialStateGroups, InitialStateLocations, InitialStateMetadata:
+			if !slices.Contains(parts, part) {
+				parts = append(parts, part)
+			}
+		case InitialStateNone:
+		default:
+			return nil, fmt.Errorf("unknown initial state part: %q (devices, aliases, groups, locations, metadata or none)", name)
+		}
+	}
+	return parts, nil
 }
 
 // DeviceAddedPayload is the payload for the device_added message
@@ -190,6 +453,22 @@ type PropertyChangedPayload struct {
 	EOJ   string       `json:"eoj"`
 	EPC   string       `json:"epc"`
 	Value PropertyData `json:"value"`
+	ReplayMarker
+}
+
+// ReplayMarker marks the notifications replayed from the history by replay_history.
+// Both fields are omitted from live notifications.
+type ReplayMarker struct {
+	Replay    bool       `json:"replay,omitempty"`
+	Timestamp *time.Time `json:"timestamp,omitempty"` // original time of the replayed entry
+}
+
+// PropertiesChangedPayload is the payload for the properties_changed message.
+// It carries several property changes of one device that were coalesced into a single broadcast.
+type PropertiesChangedPayload struct {
+	IP         string      `json:"ip"`
+	EOJ        string      `json:"eoj"`
+	Properties PropertyMap `json:"properties"` // EPC (hex) -> value
 }
 
 // ServerHeartbeatPayload is the payload for the server_heartbeat message.
@@ -211,12 +490,14 @@ type TimeoutNotificationPayload struct {
 type DeviceOfflinePayload struct {
 	IP  string `json:"ip"`
 	EOJ string `json:"eoj"`
+	ReplayMarker
 }
 
 // DeviceOnlinePayload is the payload for the device_online message
 type DeviceOnlinePayload struct {
 	IP  string `json:"ip"`
 	EOJ string `json:"eoj"`
+	ReplayMarker
 }
 
 // DeviceDeletedPayload is the payload for the device_deleted message
@@ -225,6 +506,32 @@ type DeviceDeletedPayload struct {
 	EOJ string `json:"eoj"`
 }
 
+// DeviceReconfiguredPayload is the payload for the device_reconfigured message.
+// It is sent when a node renumbered its instances (e.g. after a factory reset) and the aliases,
+// groups, metadata and scenes of a vanished instance were moved to the new instance of the same class.
+type DeviceReconfiguredPayload struct {
+	IP          string           `json:"ip"`
+	PreviousEOJ string           `json:"previousEoj"`          // The vanished instance
+	EOJ         string           `json:"eoj"`                  // The instance that took it over
+	PreviousID  handler.IDString `json:"previousId,omitempty"` // Empty when the identification number is unknown
+	ID          handler.IDString `json:"id,omitempty"`
+	Aliases     []string         `json:"aliases,omitempty"` // Aliases moved to the new instance
+	Groups      []string         `json:"groups,omitempty"`  // Groups in which the device was replaced
+}
+
+// AlertPayload is the payload for the alert_raised and alert_cleared messages
+type AlertPayload struct {
+	Rule       string    `json:"rule"`
+	IP         string    `json:"ip"`
+	EOJ        string    `json:"eoj"`
+	EPC        string    `json:"epc"`
+	Value      float64   `json:"value"` // value that raised or cleared the alert
+	Comparator string    `json:"comparator"`
+	Threshold  float64   `json:"threshold"`
+	Since      time.Time `json:"since"` // when the value started to cross the threshold
+	Timestamp  time.Time `json:"timestamp"`
+}
+
 // ErrorNotificationPayload is the payload for the error_notification message
 type ErrorNotificationPayload struct {
 	Code    ErrorCode `json:"code"`
@@ -242,18 +549,139 @@ type CommandResultPayload struct {
 type GetPropertiesPayload struct {
 	Targets []string `json:"targets"`
 	EPCs    []string `json:"epcs"`
+	All     bool     `json:"all,omitempty"` // read every EPC of the Get property map instead of EPCs
 }
 
-// SetPropertiesPayload is the payload for the set_properties message
+// SetPropertiesPayload is the payload for the set_properties message.
+// The EPCs in SkipValidationEPCs are set even if they are missing from the Set property map of the device.
+// With Transaction, the properties are applied all or nothing: if the device rejects one of them,
+// the others are set back to their previous cached values, and the response data is a SetPropertiesTransactionResult.
 type SetPropertiesPayload struct {
+	Target             string                  `json:"target"`
+	Properties         map[string]PropertyData `json:"properties"`
+	SkipValidationEPCs []string                `json:"skip_validation_epcs,omitempty"`
+	Transaction        bool                    `json:"transaction,omitempty"`
+}
+
+// SetPropertiesResult is the response data for set_properties.
+// AppliedOrder lists the EPCs the device accepted, in the order they were set:
+// EPCs that some devices need first (e.g. operation status) are set before the others.
+type SetPropertiesResult struct {
+	Device
+	AppliedOrder []string `json:"appliedOrder,omitempty"`
+}
+
+// AppliedOrder returns the EPCs of properties as strings, in the same order
+func AppliedOrder(properties echonet_lite.Properties) []string {
+	order := make([]string, len(properties))
+	for i, prop := range properties {
+		order[i] = prop.EPC.String()
+	}
+	return order
+}
+
+// PropertyOutcome is what happened to one EPC of a set_properties transaction
+type PropertyOutcome string
+
+const (
+	PropertyOutcomeApplied        PropertyOutcome = "applied"         // set and kept
+	PropertyOutcomeFailed         PropertyOutcome = "failed"          // rejected by the device
+	PropertyOutcomeRolledBack     PropertyOutcome = "rolled_back"     // set, then set back to the previous value
+	PropertyOutcomeRollbackFailed PropertyOutcome = "rollback_failed" // set, but the device rejected the previous value
+	PropertyOutcomeNotRolledBack  PropertyOutcome = "not_rolled_back" // set, but no previous value was cached to set back
+)
+
+// SetPropertyOutcome is the outcome of one EPC of a set_properties transaction
+type SetPropertyOutcome struct {
+	EPC      string          `json:"epc"`
+	Outcome  PropertyOutcome `json:"outcome"`
+	Value    PropertyData    `json:"value"`              // requested value
+	Previous *PropertyData   `json:"previous,omitempty"` // cached value before the request, if any
+}
+
+// SetPropertiesTransactionResult is the response data for set_properties with transaction.
+// It is returned both when the transaction is committed and when it is rolled back (with an error).
+type SetPropertiesTransactionResult struct {
+	Committed    bool                 `json:"committed"`
+	Outcomes     []SetPropertyOutcome `json:"outcomes"`               // in EPC order
+	Device       *Device              `json:"device,omitempty"`       // device after the set, when committed
+	AppliedOrder []string             `json:"appliedOrder,omitempty"` // EPCs accepted by the device, in the order they were set
+}
+
+// SetGroupPropertiesPayload is the payload for the set_group_properties message.
+// The same properties are set on every device of Group and every device in Targets in one request,
+// e.g. to turn off all the lights. At least one of Group and Targets is required.
+type SetGroupPropertiesPayload struct {
+	Group              string                  `json:"group,omitempty"`   // group name, e.g. "@lights"
+	Targets            []string                `json:"targets,omitempty"` // device identifiers (IP EOJ format)
+	Properties         map[string]PropertyData `json:"properties"`
+	SkipValidationEPCs []string                `json:"skip_validation_epcs,omitempty"`
+	// Async returns an OperationStatusData at once; the progress is read with get_operation_status
+	Async bool `json:"async,omitempty"`
+}
+
+// SetGroupPropertiesDeviceResult is the result of setting the properties of one device
+type SetGroupPropertiesDeviceResult struct {
+	Target     string           `json:"target"`       // device identifier (IP EOJ format), or the ID of a group member that was not found
+	ID         handler.IDString `json:"id,omitempty"` // ID of the device, if known
+	Success    bool             `json:"success"`
+	Error      string           `json:"error,omitempty"`
+	Properties PropertyMap      `json:"properties,omitempty"` // properties reported by the device after the set
+}
+
+// SetGroupPropertiesResponse is the response data for set_group_properties.
+// The command succeeds even if some devices fail; check Success of each result.
+type SetGroupPropertiesResponse struct {
+	Group   string                           `json:"group,omitempty"`
+	Results []SetGroupPropertiesDeviceResult `json:"results"`
+}
+
+// SetGetPropertiesPayload is the payload for the set_get_properties message.
+// The properties are set and the EPCs are read in one ECHONET Lite SetGet frame.
+type SetGetPropertiesPayload struct {
 	Target     string                  `json:"target"`
-	Properties map[string]PropertyData `json:"properties"`
+	Properties map[string]PropertyData `json:"properties,omitempty"` // Properties to set
+	EPCs       []string                `json:"epcs,omitempty"`       // Properties to read after setting
+}
+
+// SetGetPropertiesResult is the data for the command_result message of set_get_properties
+type SetGetPropertiesResult struct {
+	IP  string      `json:"ip"`
+	EOJ string      `json:"eoj"`
+	Set PropertyMap `json:"set"` // Properties that were set successfully
+	Get PropertyMap `json:"get"` // Properties that were read
 }
 
 // UpdatePropertiesPayload is the payload for the update_properties message
 type UpdatePropertiesPayload struct {
 	Targets []string `json:"targets"`
 	Force   bool     `json:"force,omitempty"`
+	// Progress sends an update_progress notification to the requesting client as each device finishes,
+	// and returns UpdatePropertiesResult in the command_result
+	Progress bool `json:"progress,omitempty"`
+	// Async returns an OperationStatusData at once; the progress is read with get_operation_status
+	Async bool `json:"async,omitempty"`
+}
+
+// UpdateProgressPayload is the payload for the update_progress message.
+// RequestID is the requestId of the update_properties request; the message itself has no requestId,
+// so that clients do not take it for the command_result.
+type UpdateProgressPayload struct {
+	RequestID string `json:"requestId"`
+	IP        string `json:"ip"`
+	EOJ       string `json:"eoj"`
+	Status    string `json:"status"`           // "succeeded", "failed" or "skipped"
+	Reason    string `json:"reason,omitempty"` // why the device failed or was skipped
+	Completed int    `json:"completed"`        // devices finished so far, including this one
+	Total     int    `json:"total"`
+}
+
+// UpdatePropertiesResult is returned in the data of the command_result for update_properties with progress
+type UpdatePropertiesResult struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
 }
 
 // GetDeviceHistoryPayload is the payload for the get_device_history message
@@ -261,6 +689,85 @@ type GetDeviceHistoryPayload struct {
 	Target       string `json:"target"`
 	Limit        *int   `json:"limit,omitempty"`
 	SettableOnly *bool  `json:"settableOnly,omitempty"`
+	// Since and Until restrict the entries to a time range (inclusive).
+	// Old entries are only available with the journal history backend.
+	Since *time.Time `json:"since,omitempty"`
+	Until *time.Time `json:"until,omitempty"`
+	// EPC restricts the entries to one property (2 hexadecimal digits, e.g. "BB")
+	EPC string `json:"epc,omitempty"`
+}
+
+// ReplayHistoryPayload is the payload for the replay_history message.
+// The history entries from Since to Until are sent back to the requesting client as property_changed,
+// device_online and device_offline notifications with the replay marker, Speed times faster than they happened.
+type ReplayHistoryPayload struct {
+	Target       string     `json:"target,omitempty"` // empty replays all devices
+	Since        *time.Time `json:"since,omitempty"`  // required unless Stop is set
+	Until        *time.Time `json:"until,omitempty"`  // defaults to now
+	Speed        float64    `json:"speed,omitempty"`  // acceleration factor, defaults to 60
+	SettableOnly bool       `json:"settableOnly,omitempty"`
+	Stop         bool       `json:"stop,omitempty"` // stops the running replay of the client
+}
+
+// ReplayHistoryResult is returned in the data of the command_result for replay_history.
+type ReplayHistoryResult struct {
+	Entries   int  `json:"entries"`             // number of entries that will be replayed
+	Truncated bool `json:"truncated,omitempty"` // the range had more entries than a replay sends
+}
+
+// ReplayFinishedPayload is the payload for the replay_finished message.
+// It carries the requestId of the replay_history request.
+type ReplayFinishedPayload struct {
+	Entries   int  `json:"entries"` // number of entries sent
+	Cancelled bool `json:"cancelled,omitempty"`
+}
+
+// SubscribeLogsPayload is the payload for the subscribe_logs message.
+// The client receives a log_entry notification for every log record at Level or above
+// until it sends Unsubscribe or disconnects. Sending it again replaces the level.
+type SubscribeLogsPayload struct {
+	Level       string `json:"level,omitempty"`   // minimum level: "debug", "info", "warn" or "error", defaults to "info"
+	Backlog     int    `json:"backlog,omitempty"` // number of recent records at Level or above to return in the result
+	Unsubscribe bool   `json:"unsubscribe,omitempty"`
+}
+
+// SubscribeLogsResult is returned in the data of the command_result for subscribe_logs.
+type SubscribeLogsResult struct {
+	Level   string            `json:"level"`   // the minimum level of the subscription
+	Entries []LogEntryPayload `json:"entries"` // the backlog, oldest first
+}
+
+// LogEntryPayload is the payload for the log_entry message and an entry of the subscribe_logs backlog.
+// log_notification carries the same fields for warnings and errors.
+type LogEntryPayload struct {
+	Level      string                 `json:"level"` // "DEBUG", "INFO", "WARN" or "ERROR"
+	Message    string                 `json:"message"`
+	Time       string                 `json:"time"` // RFC 3339
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// GetPropertyStatisticsPayload is the payload for the get_property_statistics message.
+// An empty target returns statistics for all devices.
+type GetPropertyStatisticsPayload struct {
+	Target string `json:"target,omitempty"`
+	Limit  *int   `json:"limit,omitempty"`
+	// AvailabilityWindow also returns the availability of the devices over this window, e.g. "24h" or "7d".
+	AvailabilityWindow string `json:"availabilityWindow,omitempty"`
+	// PreferencesKey adds the favorites saved with set_preferences under this key to the pinned devices (optional)
+	PreferencesKey string `json:"preferencesKey,omitempty"`
+	// FavoritesOnly returns only the favorite devices (optional)
+	FavoritesOnly bool `json:"favoritesOnly,omitempty"`
+}
+
+// GetSummaryPayload is the payload for the get_summary message
+type GetSummaryPayload struct {
+	EventLimit *int `json:"eventLimit,omitempty"`
+}
+
+// GetVersionsPayload is the payload for the get_versions message.
+// An empty target returns the versions of all devices.
+type GetVersionsPayload struct {
+	Target string `json:"target,omitempty"`
 }
 
 // ManageAliasPayload is the payload for the manage_alias message
@@ -303,6 +810,202 @@ type ManageGroupPayload struct {
 	Devices []handler.IDString `json:"devices,omitempty"`
 }
 
+// SceneChangeType defines the type of scene change
+type SceneChangeType string
+
+const (
+	SceneChangeTypeUpdated SceneChangeType = "updated"
+	SceneChangeTypeDeleted SceneChangeType = "deleted"
+)
+
+// SceneAction defines the action to perform on a scene
+type SceneAction string
+
+const (
+	SceneActionAdd         SceneAction = "add"
+	SceneActionDelete      SceneAction = "delete"
+	SceneActionList        SceneAction = "list"
+	SceneActionSetSequence SceneAction = "set_sequence"
+	SceneActionGetSequence SceneAction = "get_sequence"
+)
+
+// SceneDeviceProperties is the set of properties a scene applies to one device
+type SceneDeviceProperties struct {
+	Device     handler.IDString `json:"device"`
+	Properties PropertyMap      `json:"properties"` // EPC (hex) -> value
+}
+
+// SceneChangedPayload is the payload for the scene_changed message
+type SceneChangedPayload struct {
+	ChangeType SceneChangeType         `json:"change_type"`
+	Scene      string                  `json:"scene"`
+	Actions    []SceneDeviceProperties `json:"actions,omitempty"`
+	Sequence   *handler.SceneSequence  `json:"sequence,omitempty"` // device order and delay, omitted when the devices are set concurrently
+}
+
+// ManageScenePayload is the payload for the manage_scene message
+type ManageScenePayload struct {
+	Action   SceneAction             `json:"action"`
+	Scene    string                  `json:"scene,omitempty"`
+	Actions  []SceneDeviceProperties `json:"actions,omitempty"`
+	Sequence *handler.SceneSequence  `json:"sequence,omitempty"` // for set_sequence; an empty sequence sets the devices concurrently again
+}
+
+// SceneProgressPayload is the payload for the scene_progress message,
+// sent each time run_scene finishes setting one device
+type SceneProgressPayload struct {
+	Scene     string           `json:"scene"`
+	Device    handler.IDString `json:"device"`
+	Success   bool             `json:"success"`
+	Error     string           `json:"error,omitempty"`
+	Completed int              `json:"completed"` // number of devices finished so far, including this one
+	Total     int              `json:"total"`
+}
+
+// RunScenePayload is the payload for the run_scene message
+type RunScenePayload struct {
+	Scene string `json:"scene"`
+}
+
+// RunSceneDeviceResult is the result of applying a scene to one device
+type RunSceneDeviceResult struct {
+	Device     handler.IDString `json:"device"`
+	Success    bool             `json:"success"`
+	Error      string           `json:"error,omitempty"`
+	Properties PropertyMap      `json:"properties,omitempty"` // properties reported by the device after the set
+}
+
+// RunSceneResponse is the response data for run_scene
+type RunSceneResponse struct {
+	Scene   string                 `json:"scene"`
+	Results []RunSceneDeviceResult `json:"results"`
+}
+
+// SceneActionsToProtocol groups scene actions by device.
+// classCodeOf resolves the class code used to render values; if it returns false only the EDT is set.
+func SceneActionsToProtocol(actions []handler.SceneAction, classCodeOf func(handler.IDString) (echonet_lite.EOJClassCode, bool)) []SceneDeviceProperties {
+	result := make([]SceneDeviceProperties, 0)
+	index := make(map[handler.IDString]int)
+	for _, action := range actions {
+		i, ok := index[action.Device]
+		if !ok {
+			i = len(result)
+			index[action.Device] = i
+			result = append(result, SceneDeviceProperties{Device: action.Device, Properties: make(PropertyMap)})
+		}
+		prop := echonet_lite.Property{EPC: action.EPC, EDT: action.EDT}
+		if classCode, ok := classCodeOf(action.Device); ok {
+			result[i].Properties.Set(action.EPC, MakePropertyData(classCode, prop))
+		} else {
+			result[i].Properties.Set(action.EPC, PropertyData{EDT: base64.StdEncoding.EncodeToString(action.EDT)})
+		}
+	}
+	return result
+}
+
+// SceneActionsFromProtocol converts per-device scene properties back to scene actions.
+// Only the EDT field is used; EPCs of each device are ordered by value.
+func SceneActionsFromProtocol(devices []SceneDeviceProperties) ([]handler.SceneAction, error) {
+	actions := make([]handler.SceneAction, 0)
+	for _, device := range devices {
+		start := len(actions)
+		for epcStr, propData := range device.Properties {
+			epc, err := handler.ParseEPCString(epcStr)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing EPC: %v", err)
+			}
+			edt, err := base64.StdEncoding.DecodeString(propData.EDT)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding EDT: %v", err)
+			}
+			actions = append(actions, handler.SceneAction{Device: device.Device, EPC: epc, EDT: edt})
+		}
+		deviceActions := actions[start:]
+		sort.Slice(deviceActions, func(i, j int) bool { return deviceActions[i].EPC < deviceActions[j].EPC })
+	}
+	return actions, nil
+}
+
+// ScheduleChangeType defines the type of schedule change
+type ScheduleChangeType string
+
+const (
+	ScheduleChangeTypeUpdated ScheduleChangeType = "updated"
+	ScheduleChangeTypeDeleted ScheduleChangeType = "deleted"
+)
+
+// ScheduleAction defines the action to perform on a schedule
+type ScheduleAction string
+
+const (
+	ScheduleActionSet     ScheduleAction = "set"
+	ScheduleActionDelete  ScheduleAction = "delete"
+	ScheduleActionEnable  ScheduleAction = "enable"
+	ScheduleActionDisable ScheduleAction = "disable"
+	ScheduleActionList    ScheduleAction = "list"
+)
+
+// ScheduleData represents a schedule that sets properties at times given by a cron expression
+type ScheduleData struct {
+	Name     string                  `json:"name"`
+	Cron     string                  `json:"cron"` // minute hour day-of-month month day-of-week, or "@sunrise"/"@sunset" with an optional offset and day-of-week
+	Scene    string                  `json:"scene,omitempty"`
+	Actions  []SceneDeviceProperties `json:"actions,omitempty"`
+	Disabled bool                    `json:"disabled,omitempty"`
+	NextRun  *time.Time              `json:"next_run,omitempty"` // set by the server; omitted when disabled
+}
+
+// ScheduleChangedPayload is the payload for the schedule_changed message
+type ScheduleChangedPayload struct {
+	ChangeType ScheduleChangeType `json:"change_type"`
+	Name       string             `json:"name"`
+	Schedule   *ScheduleData      `json:"schedule,omitempty"`
+}
+
+// ManageSchedulePayload is the payload for the manage_schedule message
+type ManageSchedulePayload struct {
+	Action   ScheduleAction `json:"action"`
+	Name     string         `json:"name,omitempty"`     // for delete, enable and disable
+	Schedule *ScheduleData  `json:"schedule,omitempty"` // for set
+}
+
+// ScheduleToProtocol converts a schedule with its next run time (zero when it will not run)
+func ScheduleToProtocol(schedule handler.Schedule, classCodeOf func(handler.IDString) (echonet_lite.EOJClassCode, bool), nextRun time.Time) ScheduleData {
+	data := ScheduleData{
+		Name:     schedule.Name,
+		Cron:     schedule.Cron,
+		Scene:    schedule.Scene,
+		Disabled: schedule.Disabled,
+	}
+	if len(schedule.Actions) > 0 {
+		data.Actions = SceneActionsToProtocol(schedule.Actions, classCodeOf)
+	}
+	if !nextRun.IsZero() {
+		next := ServerTime(nextRun)
+		data.NextRun = &next
+	}
+	return data
+}
+
+// ScheduleFromProtocol converts schedule data back to a schedule.
+// As with SceneActionsFromProtocol, only the EDT field of the actions is used.
+func ScheduleFromProtocol(data ScheduleData) (handler.Schedule, error) {
+	actions, err := SceneActionsFromProtocol(data.Actions)
+	if err != nil {
+		return handler.Schedule{}, err
+	}
+	schedule := handler.Schedule{
+		Name:     data.Name,
+		Cron:     data.Cron,
+		Scene:    data.Scene,
+		Disabled: data.Disabled,
+	}
+	if len(actions) > 0 {
+		schedule.Actions = actions
+	}
+	return schedule, nil
+}
+
 // LocationAliasAction defines the action to perform on a location alias
 type LocationAliasAction string
 
@@ -348,14 +1051,125 @@ type LocationSettingsChangedPayload struct {
 	Order      []string                   `json:"order,omitempty"` // for order changes
 }
 
+// MetadataAction defines the action to perform on the metadata of a device
+type MetadataAction string
+
+const (
+	MetadataActionSet    MetadataAction = "set"
+	MetadataActionDelete MetadataAction = "delete"
+)
+
+// MetadataChangeType defines the type of metadata change
+type MetadataChangeType string
+
+const (
+	MetadataChangeTypeUpdated MetadataChangeType = "updated"
+	MetadataChangeTypeDeleted MetadataChangeType = "deleted"
+)
+
+// ManageMetadataPayload is the payload for the manage_metadata message
+type ManageMetadataPayload struct {
+	Action   MetadataAction          `json:"action"`
+	Target   handler.IDString        `json:"target"`
+	Metadata *handler.DeviceMetadata `json:"metadata,omitempty"` // for set; replaces the whole metadata of the device
+}
+
+// MetadataChangedPayload is the payload for the metadata_changed message
+type MetadataChangedPayload struct {
+	ChangeType MetadataChangeType      `json:"change_type"`
+	Target     handler.IDString        `json:"target"`
+	Metadata   *handler.DeviceMetadata `json:"metadata,omitempty"` // for updated
+}
+
+// GetPreferencesPayload is the payload for the get_preferences message
+type GetPreferencesPayload struct {
+	Key string `json:"key"` // client key chosen by the client, e.g. a user or profile name
+}
+
+// SetPreferencesPayload is the payload for the set_preferences message
+type SetPreferencesPayload struct {
+	Key         string                    `json:"key"`
+	Preferences handler.ClientPreferences `json:"preferences"` // replaces the whole preferences of the key; empty preferences delete them
+}
+
+// PreferencesData is the response data for get_preferences and set_preferences
+type PreferencesData struct {
+	Key         string                    `json:"key"`
+	Preferences handler.ClientPreferences `json:"preferences"`
+	Saved       bool                      `json:"saved"` // false when no preferences are stored for the key
+}
+
 // DiscoverDevicesPayload is the payload for the discover_devices message
 type DiscoverDevicesPayload struct {
-	// Empty payload
+	// Async returns an OperationStatusData at once instead of waiting for the discovery
+	Async bool `json:"async,omitempty"`
+}
+
+// Status of a bulk operation
+const (
+	OperationStatusRunning   = "running"
+	OperationStatusCompleted = "completed"
+	OperationStatusFailed    = "failed"
+	OperationStatusCancelled = "cancelled"
+)
+
+// OperationPayload is the payload for the get_operation_status and cancel_operation messages
+type OperationPayload struct {
+	OperationID string `json:"operationId"`
+}
+
+// OperationTargetResult is the result of one device of a bulk operation
+type OperationTargetResult struct {
+	Target string `json:"target"`           // device identifier (IP EOJ format)
+	Status string `json:"status"`           // "succeeded", "failed", "skipped" or "cancelled"
+	Reason string `json:"reason,omitempty"` // why the device failed or was skipped
+}
+
+// OperationStatusData is the state of a bulk operation started with async.
+// It is returned by the request that starts the operation, by get_operation_status and by cancel_operation.
+type OperationStatusData struct {
+	OperationID string                  `json:"operationId"`
+	Type        MessageType             `json:"type"`   // the request that started the operation
+	Status      string                  `json:"status"` // "running", "completed", "failed" or "cancelled"
+	Cancellable bool                    `json:"cancellable"`
+	Total       int                     `json:"total"`     // devices of the operation, 0 for discover_devices
+	Completed   int                     `json:"completed"` // devices finished so far
+	Percent     float64                 `json:"percent"`   // 0 to 100
+	Results     []OperationTargetResult `json:"results"`   // in the order the devices finished
+	Error       string                  `json:"error,omitempty"`
+	StartedAt   time.Time               `json:"startedAt"`
+	FinishedAt  *time.Time              `json:"finishedAt,omitempty"`
 }
 
+// ListDevicesMode selects the shape of the list_devices response
+type ListDevicesMode string
+
+const (
+	// ListDevicesModeFlat returns a flat list of devices (default)
+	ListDevicesModeFlat ListDevicesMode = "flat"
+	// ListDevicesModeNodes groups devices by physical node (IP address)
+	ListDevicesModeNodes ListDevicesMode = "nodes"
+)
+
 // ListDevicesPayload is the payload for the list_devices message
 type ListDevicesPayload struct {
-	Targets []string `json:"targets,omitempty"` // Specific device identifiers to filter (optional)
+	Targets []string        `json:"targets,omitempty"` // Specific device identifiers to filter (optional)
+	Mode    ListDevicesMode `json:"mode,omitempty"`    // Response shape, "flat" when omitted
+	// AvailabilityWindow adds the availability of each device over this window, e.g. "24h" or "7d" (optional)
+	AvailabilityWindow string `json:"availabilityWindow,omitempty"`
+	// PreferencesKey adds the favorites saved with set_preferences under this key to the pinned devices (optional)
+	PreferencesKey string `json:"preferencesKey,omitempty"`
+	// FavoritesOnly returns only the favorite devices (optional)
+	FavoritesOnly bool `json:"favoritesOnly,omitempty"`
+}
+
+// Node represents a physical ECHONET Lite node and the device objects it hosts
+type Node struct {
+	IP          string           `json:"ip"`
+	ID          handler.IDString `json:"id,omitempty"`          // Identification of the node profile, if known
+	NodeProfile *Device          `json:"nodeProfile,omitempty"` // Node profile object (0EF0), if known
+	Devices     []Device         `json:"devices"`               // Device objects other than the node profile
+	IsOffline   bool             `json:"isOffline,omitempty"`   // True when all devices of the node are offline
 }
 
 // GetPropertyDescriptionPayload is the payload for the get_property_description message
@@ -369,12 +1183,102 @@ type DeleteDevicePayload struct {
 	Target string `json:"target"` // Device identifier (IP EOJ format)
 }
 
+// CleanupDevicesPayload is the payload for the cleanup_devices message
+type CleanupDevicesPayload struct {
+	UnseenDays int  `json:"unseenDays"`       // Devices not updated for this many days are removed
+	DryRun     bool `json:"dryRun,omitempty"` // true lists the devices without removing them
+}
+
+// StaleDevice is a device that has not been updated for a long time
+type StaleDevice struct {
+	Target   string    `json:"target"`   // Device identifier (IP EOJ format)
+	LastSeen time.Time `json:"lastSeen"` // When a property of the device was last updated
+}
+
+// CleanupDevicesResponse is the data returned for cleanup_devices
+type CleanupDevicesResponse struct {
+	Devices []StaleDevice `json:"devices"` // Removed devices, or the devices that would be removed for a dry run
+	DryRun  bool          `json:"dryRun"`
+}
+
+// RefreshPropertyMapPayload is the payload for the refresh_property_map message
+type RefreshPropertyMapPayload struct {
+	Target string `json:"target"` // Device identifier (IP EOJ format)
+}
+
+// RefreshPropertyMapResponse is the data returned for refresh_property_map.
+// Added and Removed are the EPCs (2 hexadecimal digits) that appeared in or disappeared from the Get property map.
+type RefreshPropertyMapResponse struct {
+	Target  string   `json:"target"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
 // DebugSetOfflinePayload is the payload for the debug_set_offline command
 type DebugSetOfflinePayload struct {
 	Target  string `json:"target"`  // Device identifier (IP EOJ format)
 	Offline bool   `json:"offline"` // true to set offline, false to set online
 }
 
+// DebugPendingRequestsPayload is the payload for the debug_pending_requests command
+type DebugPendingRequestsPayload struct {
+	Action string `json:"action,omitempty"` // "list" (default) or "cancel"
+	ID     uint64 `json:"id,omitempty"`     // ID of the request to cancel
+}
+
+// PendingRequest is a Get/Set request that is waiting for the device's response
+type PendingRequest struct {
+	ID          uint64     `json:"id"`
+	Target      string     `json:"target"`                // Device identifier (IP EOJ format)
+	ESV         string     `json:"esv"`                   // "Get", "SetC", "SetGet", ...
+	EPCs        []string   `json:"epcs"`                  // EPCs in hex format
+	State       string     `json:"state"`                 // "sending", "waiting" or "retrying"
+	Retries     int        `json:"retries"`               // number of resends so far
+	MaxRetries  int        `json:"maxRetries"`            // the request fails after this many timeouts
+	StartedAt   time.Time  `json:"startedAt"`             // when the request was first sent
+	NextRetryAt *time.Time `json:"nextRetryAt,omitempty"` // when it is resent if there is no response (omitted while sending)
+}
+
+// PendingRequestsResponse is the data returned for debug_pending_requests
+type PendingRequestsResponse struct {
+	Requests []PendingRequest `json:"requests"`
+}
+
+// ManageLocalPropertyMapsPayload is the payload for the manage_local_property_maps command.
+// It inspects and changes the property maps of the devices of our own node, e.g. to test how other controllers react.
+type ManageLocalPropertyMapsPayload struct {
+	Action string   `json:"action,omitempty"` // "list" (default), "set" or "reset"
+	EOJ    string   `json:"eoj,omitempty"`    // Device of our own node (CCCC:I format), for set and reset
+	Map    string   `json:"map,omitempty"`    // "get", "set" or "announce", for set and reset
+	EPCs   []string `json:"epcs,omitempty"`   // EPCs in hex format that the map contains, for set
+}
+
+// LocalPropertyMap is the property maps of a device of our own node
+type LocalPropertyMap struct {
+	EOJ        string   `json:"eoj"`                  // CCCC:I format
+	Get        []string `json:"get"`                  // EPCs in hex format
+	Set        []string `json:"set"`                  // EPCs in hex format
+	Announce   []string `json:"announce"`             // EPCs in hex format
+	Overridden []string `json:"overridden,omitempty"` // maps changed at runtime ("get", "set", "announce")
+}
+
+// LocalPropertyMapsResponse is the data returned for manage_local_property_maps
+type LocalPropertyMapsResponse struct {
+	Maps []LocalPropertyMap `json:"maps"`
+}
+
+// ManagePollExclusionsPayload is the payload for the manage_poll_exclusions command.
+// It lists and changes the device classes and devices that are not polled by the periodic updater.
+type ManagePollExclusionsPayload struct {
+	Action string `json:"action,omitempty"` // "list" (default), "add" or "remove"
+	Target string `json:"target,omitempty"` // Class code ("0279") or device pattern (alias, "@group", device ID, "IP EOJ" or IP address), for add and remove
+}
+
+// PollExclusionsResponse is the data returned for manage_poll_exclusions
+type PollExclusionsResponse struct {
+	Exclusions []string `json:"exclusions"` // Class codes and device patterns, sorted
+}
+
 // PropertyDescriptionData is the data for the command_result message when success is true
 // It's included in the 'data' field of CommandResultPayload for get_property_description requests
 type PropertyDescriptionData struct {
@@ -451,15 +1355,332 @@ func DeviceToProtocol(ipAndEOJ echonet_lite.IPAndEOJ, properties echonet_lite.Pr
 		ids = handler.MakeIDString(ipAndEOJ.EOJ, *id)
 	}
 
+	meta, _ := echonet_lite.GetClassMetadata(ipAndEOJ.EOJ.ClassCode())
+	info := properties.GetDeviceInfo()
+
 	return Device{
-		IP:         ipAndEOJ.IP.String(),
-		EOJ:        ipAndEOJ.EOJ.Specifier(),
-		Name:       ipAndEOJ.EOJ.ClassCode().String(),
-		ID:         ids,
-		Properties: protoProps,
-		LastSeen:   lastSeen,
-		IsOffline:  isOffline,
+		IP:               ipAndEOJ.IP.String(),
+		EOJ:              ipAndEOJ.EOJ.Specifier(),
+		Name:             ipAndEOJ.EOJ.ClassCode().String(),
+		ClassName:        meta.Name,
+		ClassNameJa:      meta.NameJa,
+		Icon:             meta.Icon,
+		ID:               ids,
+		Properties:       protoProps,
+		LastSeen:         ServerTime(lastSeen),
+		IsOffline:        isOffline,
+		ManufacturerCode: info.ManufacturerCode,
+		Manufacturer:     info.Manufacturer.Name,
+		ManufacturerJa:   info.Manufacturer.NameJa,
+		ProductCode:      info.ProductCode,
+		ProductionDate:   info.ProductionDate,
+	}
+}
+
+// MakeDeviceVersion reads the standard version (EPC 0x82), manufacturer code (EPC 0x8A) and product code (EPC 0x8C)
+// from the cached properties of a device object. EPC 0x82 of a node profile is the ECHONET Lite version instead,
+// so node profiles should not be passed.
+func MakeDeviceVersion(device echonet_lite.IPAndEOJ, properties echonet_lite.Properties) DeviceVersion {
+	meta, _ := echonet_lite.GetClassMetadata(device.EOJ.ClassCode())
+	info := properties.GetDeviceInfo()
+	version := DeviceVersion{
+		Target:           device.Specifier(),
+		ClassName:        meta.Name,
+		ManufacturerCode: info.ManufacturerCode,
+		Manufacturer:     info.Manufacturer.Name,
+		ManufacturerJa:   info.Manufacturer.NameJa,
+		ProductCode:      info.ProductCode,
+	}
+	if p, ok := properties.FindEPC(echonet_lite.EPCStandardVersion); ok {
+		// The release is an ASCII letter; devices without one report zeros
+		if v := echonet_lite.DecodeStandardVersion(p.EDT); v != nil && v.Release >= 'A' && v.Release <= 'Z' {
+			version.Release = string(rune(v.Release))
+			version.Revision = int(v.Revision)
+			version.StandardVersion = v.String()
+		}
+	}
+	return version
+}
+
+// MakeDeviceVersions returns the versions of the devices other than node profiles, ordered by IP address and EOJ.
+func MakeDeviceVersions(devices []handler.DeviceAndProperties) []DeviceVersion {
+	devices = slices.Clone(devices)
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Device.Compare(devices[j].Device) < 0
+	})
+	versions := make([]DeviceVersion, 0, len(devices))
+	for _, device := range devices {
+		if device.Device.EOJ.ClassCode() == echonet_lite.NodeProfile_ClassCode {
+			continue
+		}
+		versions = append(versions, MakeDeviceVersion(device.Device, device.Properties))
+	}
+	return versions
+}
+
+// GroupDevicesByNode groups devices sharing an IP address into nodes.
+// Nodes are ordered by IP address and devices keep their input order.
+func GroupDevicesByNode(devices []Device) []Node {
+	nodes := make([]Node, 0)
+	index := make(map[string]int)
+	for _, device := range devices {
+		i, ok := index[device.IP]
+		if !ok {
+			i = len(nodes)
+			index[device.IP] = i
+			nodes = append(nodes, Node{IP: device.IP, Devices: []Device{}, IsOffline: true})
+		}
+		node := &nodes[i]
+		if !device.IsOffline {
+			node.IsOffline = false
+		}
+		if ipAndEOJ, err := handler.ParseDeviceIdentifier(device.IP + " " + device.EOJ); err == nil && ipAndEOJ.EOJ.ClassCode() == echonet_lite.NodeProfile_ClassCode {
+			d := device
+			node.NodeProfile = &d
+			node.ID = device.ID
+			continue
+		}
+		node.Devices = append(node.Devices, device)
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := net.ParseIP(nodes[i].IP), net.ParseIP(nodes[j].IP)
+		if a == nil || b == nil {
+			return nodes[i].IP < nodes[j].IP
+		}
+		return bytes.Compare(a.To16(), b.To16()) < 0
+	})
+	return nodes
+}
+
+// StaleDevicesToProtocol converts the handler's stale devices to the protocol format
+func StaleDevicesToProtocol(devices []handler.StaleDevice) []StaleDevice {
+	result := make([]StaleDevice, 0, len(devices))
+	for _, d := range devices {
+		result = append(result, StaleDevice{Target: d.Device.Specifier(), LastSeen: d.LastSeen})
+	}
+	return result
+}
+
+// PersistenceStatusToProtocol converts the save state of the handler to the protocol format.
+// Zero times are omitted.
+func PersistenceStatusToProtocol(status handler.PersistenceStatus) PersistenceStatus {
+	timePtr := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		t = ServerTime(t)
+		return &t
+	}
+	return PersistenceStatus{
+		Healthy:      status.Healthy,
+		LastError:    status.LastError,
+		FailingSince: timePtr(status.FailingSince),
+		Failures:     status.Failures,
+		LastSaved:    timePtr(status.LastSaved),
+		NextRetry:    timePtr(status.NextRetry),
+	}
+}
+
+// StartupReportToProtocol converts the startup report of the handler to the protocol format.
+// It returns nil when no file was loaded.
+func StartupReportToProtocol(report handler.StartupReport) *StartupReport {
+	if len(report.Files) == 0 {
+		return nil
+	}
+	files := make([]LoadedFile, 0, len(report.Files))
+	for _, f := range report.Files {
+		files = append(files, LoadedFile{
+			Kind:          f.Kind,
+			File:          f.File,
+			Exists:        f.Exists,
+			Records:       f.Records,
+			Skipped:       f.Skipped,
+			FormatVersion: f.FormatVersion,
+			Migrated:      f.Migrated,
+			Restored:      f.Restored,
+			Error:         f.Error,
+		})
+	}
+	return &StartupReport{
+		LoadedAt: ServerTime(report.LoadedAt),
+		Clean:    report.Clean(),
+		Files:    files,
+	}
+}
+
+// ParseAvailabilityWindow parses the window of availability statistics:
+// a Go duration such as "24h", or a number of days such as "7d".
+func ParseAvailabilityWindow(s string) (time.Duration, error) {
+	var window time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid availability window: %s", s)
+		}
+		window = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid availability window: %s", s)
+		}
+		window = d
+	}
+	if window <= 0 {
+		return 0, fmt.Errorf("availability window must be greater than zero: %s", s)
+	}
+	return window, nil
+}
+
+// AvailabilityPercent rounds the availability of a device to two decimal places for the protocol.
+func AvailabilityPercent(a handler.DeviceAvailability) float64 {
+	return math.Round(a.Percent()*100) / 100
+}
+
+// AvailabilityToProtocol converts the handler's device availability to the protocol format
+func AvailabilityToProtocol(availability []handler.DeviceAvailability) []DeviceAvailability {
+	result := make([]DeviceAvailability, 0, len(availability))
+	for _, a := range availability {
+		result = append(result, DeviceAvailability{
+			Target:         a.Device.Specifier(),
+			Percent:        AvailabilityPercent(a),
+			OfflineCount:   a.OfflineCount,
+			OfflineSeconds: int64(a.Offline / time.Second),
+		})
+	}
+	return result
+}
+
+// StaleDevicesFromProtocol converts stale devices in the protocol format to the handler's type
+func StaleDevicesFromProtocol(devices []StaleDevice) ([]handler.StaleDevice, error) {
+	result := make([]handler.StaleDevice, 0, len(devices))
+	for _, d := range devices {
+		device, err := handler.ParseDeviceIdentifier(d.Target)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, handler.StaleDevice{Device: device, LastSeen: d.LastSeen})
+	}
+	return result, nil
+}
+
+// PendingRequestToProtocol converts a pending request of the handler to the protocol format
+func PendingRequestToProtocol(req handler.PendingRequest) PendingRequest {
+	epcs := make([]string, 0, len(req.EPCs))
+	for _, epc := range req.EPCs {
+		epcs = append(epcs, epc.String())
+	}
+	result := PendingRequest{
+		ID:         req.ID,
+		Target:     req.Device.Specifier(),
+		ESV:        req.ESV.String(),
+		EPCs:       epcs,
+		State:      string(req.State),
+		Retries:    req.Retries,
+		MaxRetries: req.MaxRetries,
+		StartedAt:  req.StartTime,
+	}
+	if !req.NextRetry.IsZero() {
+		nextRetry := req.NextRetry
+		result.NextRetryAt = &nextRetry
+	}
+	return result
+}
+
+// PendingRequestFromProtocol converts a pending request in the protocol format to the handler's type
+func PendingRequestFromProtocol(req PendingRequest) (handler.PendingRequest, error) {
+	device, err := handler.ParseDeviceIdentifier(req.Target)
+	if err != nil {
+		return handler.PendingRequest{}, err
+	}
+	epcs := make([]echonet_lite.EPCType, 0, len(req.EPCs))
+	for _, s := range req.EPCs {
+		epc, err := handler.ParseEPCString(s)
+		if err != nil {
+			return handler.PendingRequest{}, err
+		}
+		epcs = append(epcs, epc)
+	}
+	var esv echonet_lite.ESVType
+	for _, e := range []echonet_lite.ESVType{echonet_lite.ESVSetI, echonet_lite.ESVSetC, echonet_lite.ESVGet, echonet_lite.ESVINF_REQ, echonet_lite.ESVSetGet} {
+		if e.String() == req.ESV {
+			esv = e
+		}
+	}
+	result := handler.PendingRequest{
+		ID:         req.ID,
+		Device:     device,
+		ESV:        esv,
+		EPCs:       epcs,
+		State:      handler.PendingRequestState(req.State),
+		Retries:    req.Retries,
+		MaxRetries: req.MaxRetries,
+		StartTime:  req.StartedAt,
+	}
+	if req.NextRetryAt != nil {
+		result.NextRetry = *req.NextRetryAt
+	}
+	return result, nil
+}
+
+// LocalPropertyMapToProtocol converts the property maps of a device of our own node to the protocol format
+func LocalPropertyMapToProtocol(m handler.LocalPropertyMap) LocalPropertyMap {
+	epcStrings := func(propertyMap echonet_lite.PropertyMap) []string {
+		epcs := propertyMap.EPCs()
+		sort.Slice(epcs, func(i, j int) bool { return epcs[i] < epcs[j] })
+		result := make([]string, 0, len(epcs))
+		for _, epc := range epcs {
+			result = append(result, epc.String())
+		}
+		return result
+	}
+	result := LocalPropertyMap{
+		EOJ:      m.EOJ.Specifier(),
+		Get:      epcStrings(m.Get),
+		Set:      epcStrings(m.Set),
+		Announce: epcStrings(m.Announce),
+	}
+	for _, t := range m.Overridden {
+		result.Overridden = append(result.Overridden, t.String())
+	}
+	return result
+}
+
+// LocalPropertyMapFromProtocol converts the property maps of a device of our own node to the handler's type
+func LocalPropertyMapFromProtocol(m LocalPropertyMap) (handler.LocalPropertyMap, error) {
+	eoj, err := handler.ParseEOJString(m.EOJ)
+	if err != nil {
+		return handler.LocalPropertyMap{}, err
+	}
+	propertyMap := func(epcStrings []string) (echonet_lite.PropertyMap, error) {
+		result := make(echonet_lite.PropertyMap)
+		for _, s := range epcStrings {
+			epc, err := handler.ParseEPCString(s)
+			if err != nil {
+				return nil, err
+			}
+			result.Set(epc)
+		}
+		return result, nil
+	}
+	result := handler.LocalPropertyMap{EOJ: eoj}
+	if result.Get, err = propertyMap(m.Get); err != nil {
+		return handler.LocalPropertyMap{}, err
+	}
+	if result.Set, err = propertyMap(m.Set); err != nil {
+		return handler.LocalPropertyMap{}, err
+	}
+	if result.Announce, err = propertyMap(m.Announce); err != nil {
+		return handler.LocalPropertyMap{}, err
+	}
+	for _, name := range m.Overridden {
+		t, err := handler.ParsePropertyMapType(name)
+		if err != nil {
+			return handler.LocalPropertyMap{}, err
+		}
+		result.Overridden = append(result.Overridden, t)
 	}
+	return result, nil
 }
 
 // DeviceFromProtocol converts a protocol Device to ECHONET Lite types
@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
 	"encoding/base64"
 	"fmt"
 	"net"
@@ -33,9 +34,12 @@ func TestDeviceToProtocol(t *testing.T) {
 			lastSeen:  time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
 			isOffline: false,
 			want: Device{
-				IP:   "192.168.1.10",
-				EOJ:  "0130:1",
-				Name: "0130[Home Air Conditioner]",
+				IP:          "192.168.1.10",
+				EOJ:         "0130:1",
+				Name:        "0130[Home Air Conditioner]",
+				ClassName:   "Home Air Conditioner",
+				ClassNameJa: "家庭用エアコン",
+				Icon:        "air-conditioner",
 				Properties: PropertyMap{
 					"80": {EDT: base64.StdEncoding.EncodeToString([]byte{0x30}), String: "on"},
 					"81": {EDT: base64.StdEncoding.EncodeToString([]byte{0x01, 0x02})},
@@ -54,12 +58,15 @@ func TestDeviceToProtocol(t *testing.T) {
 			lastSeen:   time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
 			isOffline:  false,
 			want: Device{
-				IP:         "192.168.1.20",
-				EOJ:        "0EF0:1",
-				Name:       "0EF0[Node Profile]",
-				Properties: PropertyMap{},
-				LastSeen:   time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
-				IsOffline:  false,
+				IP:          "192.168.1.20",
+				EOJ:         "0EF0:1",
+				Name:        "0EF0[Node Profile]",
+				ClassName:   "Node Profile",
+				ClassNameJa: "ノードプロファイル",
+				Icon:        "node",
+				Properties:  PropertyMap{},
+				LastSeen:    time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+				IsOffline:   false,
 			},
 		},
 		{
@@ -74,9 +81,12 @@ func TestDeviceToProtocol(t *testing.T) {
 			lastSeen:  time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
 			isOffline: true,
 			want: Device{
-				IP:   "192.168.1.30",
-				EOJ:  "0130:1",
-				Name: "0130[Home Air Conditioner]",
+				IP:          "192.168.1.30",
+				EOJ:         "0130:1",
+				Name:        "0130[Home Air Conditioner]",
+				ClassName:   "Home Air Conditioner",
+				ClassNameJa: "家庭用エアコン",
+				Icon:        "air-conditioner",
 				Properties: PropertyMap{
 					"80": {EDT: base64.StdEncoding.EncodeToString([]byte{0x30}), String: "on"},
 				},
@@ -106,6 +116,12 @@ func TestDeviceToProtocol(t *testing.T) {
 				t.Errorf("DeviceToProtocol() Name = %v, want %v", got.Name, tt.want.Name)
 			}
 
+			// Check class metadata
+			if got.ClassName != tt.want.ClassName || got.ClassNameJa != tt.want.ClassNameJa || got.Icon != tt.want.Icon {
+				t.Errorf("DeviceToProtocol() class metadata = (%q, %q, %q), want (%q, %q, %q)",
+					got.ClassName, got.ClassNameJa, got.Icon, tt.want.ClassName, tt.want.ClassNameJa, tt.want.Icon)
+			}
+
 			// Check Properties
 			if !reflect.DeepEqual(got.Properties, tt.want.Properties) {
 				t.Errorf("DeviceToProtocol() Properties = %v, want %v", got.Properties, tt.want.Properties)
@@ -122,6 +138,67 @@ func TestDeviceToProtocol(t *testing.T) {
 	}
 }
 
+func TestMakePropertyData_EnergyClasses(t *testing.T) {
+	number := func(n int) *int { return &n }
+	tests := []struct {
+		name       string
+		classCode  echonet_lite.EOJClassCode
+		property   echonet_lite.Property
+		wantString string
+		wantNumber *int
+	}{
+		{
+			name:       "storage battery discharging power",
+			classCode:  echonet_lite.StorageBattery_ClassCode,
+			property:   echonet_lite.Property{EPC: echonet_lite.EPC_SB_InstantaneousChargingPower, EDT: []byte{0xFF, 0xFF, 0xFE, 0x0C}},
+			wantString: "-500W",
+			wantNumber: number(-500),
+		},
+		{
+			name:       "storage battery SOC",
+			classCode:  echonet_lite.StorageBattery_ClassCode,
+			property:   echonet_lite.Property{EPC: echonet_lite.EPC_SB_RemainingCapacityPercentage, EDT: []byte{75}},
+			wantString: "75%",
+			wantNumber: number(75),
+		},
+		{
+			name:       "storage battery working status",
+			classCode:  echonet_lite.StorageBattery_ClassCode,
+			property:   echonet_lite.Property{EPC: echonet_lite.EPC_SB_WorkingOperationStatus, EDT: []byte{0x42}},
+			wantString: "charging",
+		},
+		{
+			name:       "solar generation power",
+			classCode:  echonet_lite.HomeSolarPower_ClassCode,
+			property:   echonet_lite.Property{EPC: echonet_lite.EPC_PV_InstantaneousGeneration, EDT: []byte{0x0B, 0xB8}},
+			wantString: "3000W",
+			wantNumber: number(3000),
+		},
+		{
+			name:       "solar cumulative generation",
+			classCode:  echonet_lite.HomeSolarPower_ClassCode,
+			property:   echonet_lite.Property{EPC: echonet_lite.EPC_PV_CumulativeGeneration, EDT: []byte{0x00, 0x00, 0x04, 0xD2}},
+			wantString: "1234Wh",
+			wantNumber: number(1234),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MakePropertyData(tt.classCode, tt.property)
+			if got.String != tt.wantString {
+				t.Errorf("String = %q, want %q", got.String, tt.wantString)
+			}
+			if !reflect.DeepEqual(got.Number, tt.wantNumber) {
+				t.Errorf("Number = %v, want %v", got.Number, tt.wantNumber)
+			}
+			if got.EDT != base64.StdEncoding.EncodeToString(tt.property.EDT) {
+				t.Errorf("EDT = %q", got.EDT)
+			}
+		})
+	}
+}
+
 func TestDeviceFromProtocol(t *testing.T) {
 	// Test cases
 	tests := []struct {
@@ -317,3 +394,122 @@ func TestDeviceRoundTrip(t *testing.T) {
 		}
 	}
 }
+
+func TestGroupDevicesByNode(t *testing.T) {
+	devices := []Device{
+		{IP: "192.168.1.20", EOJ: "0291:1"},
+		{IP: "192.168.1.3", EOJ: "0130:1", IsOffline: true},
+		{IP: "192.168.1.3", EOJ: "0EF0:1", ID: "0EF001:FE0000000000000000000000000001"},
+		{IP: "192.168.1.3", EOJ: "0130:2"},
+		{IP: "192.168.1.20", EOJ: "0291:2", IsOffline: true},
+		{IP: "192.168.1.4", EOJ: "0291:1", IsOffline: true},
+	}
+
+	nodes := GroupDevicesByNode(devices)
+	if len(nodes) != 3 {
+		t.Fatalf("expected 3 nodes, got %d", len(nodes))
+	}
+
+	// Nodes are ordered numerically by IP address
+	if nodes[0].IP != "192.168.1.3" || nodes[1].IP != "192.168.1.4" || nodes[2].IP != "192.168.1.20" {
+		t.Errorf("unexpected node order: %s, %s, %s", nodes[0].IP, nodes[1].IP, nodes[2].IP)
+	}
+
+	first := nodes[0]
+	if first.NodeProfile == nil || first.NodeProfile.EOJ != "0EF0:1" {
+		t.Errorf("expected node profile to be separated, got %+v", first.NodeProfile)
+	}
+	if first.ID != "0EF001:FE0000000000000000000000000001" {
+		t.Errorf("expected node ID from node profile, got %q", first.ID)
+	}
+	if len(first.Devices) != 2 || first.Devices[0].EOJ != "0130:1" || first.Devices[1].EOJ != "0130:2" {
+		t.Errorf("unexpected devices: %+v", first.Devices)
+	}
+	if first.IsOffline {
+		t.Error("node with an online device should not be offline")
+	}
+
+	if !nodes[1].IsOffline {
+		t.Error("node whose devices are all offline should be offline")
+	}
+	if nodes[2].NodeProfile != nil || nodes[2].ID != "" {
+		t.Errorf("node without node profile should have no ID, got %+v", nodes[2])
+	}
+}
+
+func TestMakeDeviceVersions(t *testing.T) {
+	device := func(ip string, eoj echonet_lite.EOJ, props ...echonet_lite.Property) handler.DeviceAndProperties {
+		return handler.DeviceAndProperties{Device: echonet_lite.IPAndEOJ{IP: net.ParseIP(ip), EOJ: eoj}, Properties: props}
+	}
+	aircon := echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)
+	light := echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)
+	productCode := []byte("RAS-X40    \x00")
+
+	versions := MakeDeviceVersions([]handler.DeviceAndProperties{
+		device("192.168.1.20", light,
+			echonet_lite.Property{EPC: echonet_lite.EPCStandardVersion, EDT: []byte{0, 0, 0, 0}}),
+		device("192.168.1.10", echonet_lite.NodeProfileObject,
+			echonet_lite.Property{EPC: echonet_lite.EPCStandardVersion, EDT: []byte{1, 14, 1, 0}}),
+		device("192.168.1.10", aircon,
+			echonet_lite.Property{EPC: echonet_lite.EPCStandardVersion, EDT: []byte{0, 0, 'R', 1}},
+			echonet_lite.Property{EPC: echonet_lite.EPCManufacturerCode, EDT: []byte{0x00, 0x00, 0x0b}},
+			echonet_lite.Property{EPC: echonet_lite.EPCProductCode, EDT: productCode}),
+	})
+
+	want := []DeviceVersion{
+		{
+			Target:           "192.168.1.10 0130:1",
+			ClassName:        "Home Air Conditioner",
+			Release:          "R",
+			Revision:         1,
+			StandardVersion:  "Release R Rev.1",
+			ManufacturerCode: "00000B",
+			Manufacturer:     "Panasonic",
+			ManufacturerJa:   "パナソニック",
+			ProductCode:      "RAS-X40",
+		},
+		// A standard version without a release letter is treated as unknown
+		{Target: "192.168.1.20 0291:1", ClassName: "Single Function Lighting"},
+	}
+	if !reflect.DeepEqual(versions, want) {
+		t.Errorf("MakeDeviceVersions() = %+v, want %+v", versions, want)
+	}
+}
+
+func TestParseAvailabilityWindow(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{"24h", 24 * time.Hour},
+		{"90m", 90 * time.Minute},
+		{"7d", 7 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		got, err := ParseAvailabilityWindow(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseAvailabilityWindow(%q) = %v, %v; want %v", tt.input, got, err, tt.want)
+		}
+	}
+	for _, input := range []string{"", "0d", "-1h", "week", "1.5d"} {
+		if _, err := ParseAvailabilityWindow(input); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestParseInitialStateParts(t *testing.T) {
+	if parts, err := ParseInitialStateParts(""); err != nil || parts != nil {
+		t.Errorf("expected all parts for an empty string, got %v, %v", parts, err)
+	}
+	parts, err := ParseInitialStateParts("devices, aliases,devices")
+	if err != nil || len(parts) != 2 || parts[0] != InitialStateDevices || parts[1] != InitialStateAliases {
+		t.Errorf("unexpected parts: %v, %v", parts, err)
+	}
+	if parts, err := ParseInitialStateParts("none"); err != nil || parts == nil || len(parts) != 0 {
+		t.Errorf("expected no parts for none, got %v, %v", parts, err)
+	}
+	if _, err := ParseInitialStateParts("devices,scenes"); err == nil {
+		t.Error("expected an error for an unknown part")
+	}
+}
@@ -0,0 +1,29 @@
+package protocol
+
+import "time"
+
+// TimezoneInfo describes the time zone the server expresses timestamps in
+type TimezoneInfo struct {
+	Name      string `json:"name"`      // IANA name (e.g. "Asia/Tokyo"), or the zone abbreviation when the system zone has no name
+	UTCOffset int    `json:"utcOffset"` // offset from UTC in seconds at the time of the message
+}
+
+// CurrentTimezone returns the server time zone (time.Local) as of now
+func CurrentTimezone(now time.Time) TimezoneInfo {
+	abbr, offset := now.In(time.Local).Zone()
+	name := time.Local.String()
+	if name == "Local" {
+		name = abbr
+	}
+	return TimezoneInfo{Name: name, UTCOffset: offset}
+}
+
+// ServerTime converts a timestamp to the server time zone, so that payloads carry the server's UTC offset
+// (e.g. "2026-10-16T07:00:00+09:00") regardless of how the timestamp was stored.
+// The zero time is returned unchanged.
+func ServerTime(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.In(time.Local)
+}
@@ -0,0 +1,49 @@
+package protocol
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestServerTime(t *testing.T) {
+	saved := time.Local
+	defer func() { time.Local = saved }()
+	time.Local = time.FixedZone("JST", 9*60*60)
+
+	utc := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	data, err := json.Marshal(ServerTime(utc))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if got := string(data); got != `"2026-10-16T09:00:00+09:00"` {
+		t.Errorf("ServerTime = %s, want 2026-10-16T09:00:00+09:00", got)
+	}
+	if !ServerTime(utc).Equal(utc) {
+		t.Error("ServerTime must not change the instant")
+	}
+	if !ServerTime(time.Time{}).IsZero() {
+		t.Error("Zero time should be returned unchanged")
+	}
+}
+
+func TestCurrentTimezone(t *testing.T) {
+	saved := time.Local
+	defer func() { time.Local = saved }()
+
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+	time.Local = loc
+	tz := CurrentTimezone(time.Now())
+	if tz.Name != "Asia/Tokyo" || tz.UTCOffset != 9*60*60 {
+		t.Errorf("CurrentTimezone = %+v", tz)
+	}
+
+	time.Local = time.FixedZone("XYZ", -5*60*60)
+	tz = CurrentTimezone(time.Now())
+	if tz.Name != "XYZ" || tz.UTCOffset != -5*60*60 {
+		t.Errorf("CurrentTimezone = %+v", tz)
+	}
+}
@@ -4,9 +4,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"os/signal"
 	"sync"
-	"syscall"
 )
 
 type LogManager struct {
@@ -14,13 +12,16 @@ type LogManager struct {
 	file        *os.File
 	mu          sync.Mutex
 	transport   WebSocketTransport
-	debug       bool
+	level       slog.Level // Records below this level are not written
+	stream      *LogStream // Recent records and subscribers of subscribe_logs
 }
 
-func NewLogManager(logFilename string, debug bool) (*LogManager, error) {
+// NewLogManager opens the log file and makes it the output of slog.Default() at the given level
+func NewLogManager(logFilename string, level slog.Level) (*LogManager, error) {
 	lm := &LogManager{
 		logFilename: logFilename,
-		debug:       debug,
+		level:       level,
+		stream:      NewLogStream(logBacklogSize),
 	}
 	if err := lm.openAndSetLogger(); err != nil {
 		return nil, err
@@ -41,17 +42,12 @@ func (lm *LogManager) openAndSetLogger() error {
 	}
 
 	// Create text handler for file logging
-	logLevel := slog.LevelInfo
-	if lm.debug {
-		logLevel = slog.LevelDebug
-	}
-	textHandler := slog.NewTextHandler(file, &slog.HandlerOptions{Level: logLevel})
+	textHandler := slog.NewTextHandler(file, &slog.HandlerOptions{Level: lm.level})
 
-	// Wrap with broadcast handler if transport is available
-	var handler slog.Handler = textHandler
-	if lm.transport != nil {
-		handler = NewBroadcastHandler(textHandler, lm.transport, slog.LevelWarn)
-	}
+	// Wrap with broadcast handler; warnings and errors are broadcast once the transport is available,
+	// and every record is kept for subscribe_logs from the start
+	handler := NewBroadcastHandler(textHandler, lm.transport, slog.LevelWarn)
+	handler.stream = lm.stream
 
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
@@ -59,27 +55,9 @@ func (lm *LogManager) openAndSetLogger() error {
 	return nil
 }
 
-// AutoRotateは、SIGHUPシグナルを受信したときにログファイルをローテーションします。
-func (lm *LogManager) AutoRotate() {
-	rotateSignalCh := make(chan os.Signal, 1)
-	signal.Notify(rotateSignalCh, syscall.SIGHUP)
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				_, _ = fmt.Fprintf(os.Stderr, "ログローテーションgoroutineでpanicが発生しました: %v\n", r)
-			}
-		}()
-
-		for range rotateSignalCh {
-			fmt.Fprintln(os.Stderr, "SIGHUPを受信しました。ログファイルをローテーションします...")
-			err := lm.openAndSetLogger()
-			if err != nil {
-				_, _ = fmt.Fprintf(os.Stderr, "ログローテーションエラー: %v\n", err)
-			} else {
-				slog.Info("SIGHUPを受信しました。ログファイルをローテーションしました")
-			}
-		}
-	}()
+// Rotate は、ログファイルを開き直す。外部のツールがログファイルを移動した後に呼ぶ（デーモンモードでは SIGHUP で呼ばれる）
+func (lm *LogManager) Rotate() error {
+	return lm.openAndSetLogger()
 }
 
 // SetTransport sets the WebSocket transport for broadcasting logs
@@ -87,11 +65,17 @@ func (lm *LogManager) SetTransport(transport WebSocketTransport) error {
 	lm.mu.Lock()
 	lm.transport = transport
 	lm.mu.Unlock()
+	lm.stream.SetTransport(transport)
 
 	// Reopen logger to apply the transport
 	return lm.openAndSetLogger()
 }
 
+// Stream returns the log stream that WebSocket clients subscribe to with subscribe_logs
+func (lm *LogManager) Stream() *LogStream {
+	return lm.stream
+}
+
 func (lm *LogManager) Close() error {
 	lm.mu.Lock()
 	defer lm.mu.Unlock()
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"echonet-list/echonet_lite/handler"
+)
+
+// AccessRule grants the clients presenting one token access to a set of devices.
+//
+// Device patterns may be:
+//   - "*" for every device
+//   - "@group" for the devices of a group
+//   - an alias
+//   - a device ID string (e.g. "013001:00000B:ABCDEF0123456789ABCDEF012345")
+//   - "IP EOJ" (e.g. "192.168.0.10 0130:1") or an IP address for every device of a node
+type AccessRule struct {
+	Name    string
+	Token   string
+	Read    []string // devices whose state may be read
+	Control []string // devices whose properties may be set (implies read)
+	Admin   bool     // full access, including aliases, groups, scenes, schedules and discovery
+}
+
+// accessResolver resolves the aliases, groups and IDs used in device patterns.
+// client.ECHONETListClient satisfies it.
+type accessResolver interface {
+	GetIDString(device handler.IPAndEOJ) handler.IDString
+	GetDeviceByAlias(alias string) (handler.IPAndEOJ, bool)
+	GetDevicesByGroup(groupName string) ([]handler.IDString, bool)
+}
+
+// AccessControl authenticates clients by token and holds their access rules
+type AccessControl struct {
+	rules  []*AccessRule
+	byName map[string]*AccessRule
+}
+
+// NewAccessControl validates the rules and creates an AccessControl
+func NewAccessControl(rules []AccessRule) (*AccessControl, error) {
+	a := &AccessControl{byName: make(map[string]*AccessRule)}
+	tokens := make(map[string]bool)
+	for i := range rules {
+		rule := rules[i]
+		if rule.Name == "" {
+			return nil, fmt.Errorf("access rule #%d has no name", i+1)
+		}
+		if rule.Token == "" {
+			return nil, fmt.Errorf("access rule %q has no token", rule.Name)
+		}
+		if _, exists := a.byName[rule.Name]; exists {
+			return nil, fmt.Errorf("duplicate access rule name: %q", rule.Name)
+		}
+		if tokens[rule.Token] {
+			return nil, fmt.Errorf("access rule %q reuses the token of another rule", rule.Name)
+		}
+		tokens[rule.Token] = true
+		a.rules = append(a.rules, &rule)
+		a.byName[rule.Name] = &rule
+	}
+	return a, nil
+}
+
+// tokenFromRequest returns the token of a request, from the Authorization header
+// ("Bearer <token>") or, for browsers that cannot set headers on WebSocket connections, the token query parameter.
+func tokenFromRequest(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return r.URL.Query().Get("token")
+}
+
+// Authenticate returns the rule matching the token of the request
+func (a *AccessControl) Authenticate(r *http.Request) (*AccessRule, bool) {
+	token := tokenFromRequest(r)
+	if token == "" {
+		return nil, false
+	}
+	var found *AccessRule
+	// Compare every token in constant time so that the response time does not reveal a valid prefix
+	for _, rule := range a.rules {
+		if subtle.ConstantTimeCompare([]byte(rule.Token), []byte(token)) == 1 {
+			found = rule
+		}
+	}
+	return found, found != nil
+}
+
+// Rule returns the rule with the given name
+func (a *AccessControl) Rule(name string) *AccessRule {
+	return a.byName[name]
+}
+
+// The methods below treat a nil rule as full access, which is the behavior when access control is disabled.
+
+// IsAdmin reports whether the rule grants full access
+func (r *AccessRule) IsAdmin() bool {
+	return r == nil || r.Admin
+}
+
+// CanReadAll reports whether every device, including ones discovered later, may be read
+func (r *AccessRule) CanReadAll() bool {
+	return r.IsAdmin() || containsPattern(r.Read, "*") || containsPattern(r.Control, "*")
+}
+
+// CanRead reports whether the state of the device may be read
+func (r *AccessRule) CanRead(resolver accessResolver, device handler.IPAndEOJ) bool {
+	return r.CanReadAll() || matchesAnyPattern(resolver, r.Read, device) || matchesAnyPattern(resolver, r.Control, device)
+}
+
+// CanControl reports whether the properties of the device may be set
+func (r *AccessRule) CanControl(resolver accessResolver, device handler.IPAndEOJ) bool {
+	return r.IsAdmin() || matchesAnyPattern(resolver, r.Control, device)
+}
+
+func containsPattern(patterns []string, pattern string) bool {
+	for _, p := range patterns {
+		if p == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnyPattern(resolver accessResolver, patterns []string, device handler.IPAndEOJ) bool {
+	for _, pattern := range patterns {
+		if matchesPattern(resolver, pattern, device) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPattern reports whether a device pattern matches the device
+func matchesPattern(resolver accessResolver, pattern string, device handler.IPAndEOJ) bool {
+	pattern = strings.TrimSpace(pattern)
+	switch {
+	case pattern == "":
+		return false
+	case pattern == "*":
+		return true
+	case strings.HasPrefix(pattern, "@"):
+		if resolver == nil {
+			return false
+		}
+		ids, ok := resolver.GetDevicesByGroup(pattern)
+		if !ok {
+			return false
+		}
+		id := resolver.GetIDString(device)
+		for _, member := range ids {
+			if id != "" && member == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ip := net.ParseIP(pattern); ip != nil {
+		return device.IP.Equal(ip)
+	}
+	if target, err := handler.ParseDeviceIdentifier(pattern); err == nil {
+		return target.Key() == device.Key()
+	}
+	if resolver == nil {
+		return false
+	}
+	if id := resolver.GetIDString(device); id != "" && handler.IDString(pattern) == id {
+		return true
+	}
+	if aliased, ok := resolver.GetDeviceByAlias(pattern); ok {
+		return aliased.Key() == device.Key()
+	}
+	return false
+}
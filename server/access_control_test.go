@@ -0,0 +1,290 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+var (
+	accessAircon = echonet_lite.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	accessLight  = echonet_lite.IPAndEOJ{IP: net.ParseIP("192.168.1.11"), EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)}
+	accessSensor = echonet_lite.IPAndEOJ{IP: net.ParseIP("192.168.1.12"), EOJ: echonet_lite.MakeEOJ(0x0011, 1)}
+)
+
+// accessTestClient resolves aliases, groups and IDs for the access control tests
+type accessTestClient struct {
+	restTestClient
+}
+
+func newAccessTestClient() *accessTestClient {
+	c := &accessTestClient{}
+	for _, device := range []echonet_lite.IPAndEOJ{accessAircon, accessLight, accessSensor} {
+		c.devices = append(c.devices, handler.DeviceAndProperties{Device: device, Properties: echonet_lite.Properties{{EPC: 0x80, EDT: []byte{0x30}}}})
+	}
+	return c
+}
+
+func (c *accessTestClient) GetIDString(device echonet_lite.IPAndEOJ) handler.IDString {
+	return handler.IDString("id-" + device.IP.String())
+}
+
+func (c *accessTestClient) FindDeviceByIDString(id handler.IDString) *echonet_lite.IPAndEOJ {
+	for _, device := range c.devices {
+		if c.GetIDString(device.Device) == id {
+			return &device.Device
+		}
+	}
+	return nil
+}
+
+func (c *accessTestClient) GetDeviceByAlias(alias string) (echonet_lite.IPAndEOJ, bool) {
+	if alias == "thermometer" {
+		return accessSensor, true
+	}
+	return echonet_lite.IPAndEOJ{}, false
+}
+
+func (c *accessTestClient) GetDevicesByGroup(group string) ([]handler.IDString, bool) {
+	if group == "@living" {
+		return []handler.IDString{"id-192.168.1.11"}, true
+	}
+	return nil, false
+}
+
+func newTestAccessControl(t *testing.T) *AccessControl {
+	t.Helper()
+	ac, err := NewAccessControl([]AccessRule{
+		{Name: "admin", Token: "admin-token", Admin: true},
+		{Name: "guest", Token: "guest-token", Read: []string{"@living", "thermometer"}, Control: []string{"192.168.1.11 0291:1"}},
+		{Name: "viewer", Token: "viewer-token", Read: []string{"*"}},
+	})
+	if err != nil {
+		t.Fatalf("NewAccessControl failed: %v", err)
+	}
+	return ac
+}
+
+func TestNewAccessControlValidation(t *testing.T) {
+	cases := [][]AccessRule{
+		{{Name: "", Token: "a"}},
+		{{Name: "a", Token: ""}},
+		{{Name: "a", Token: "x"}, {Name: "a", Token: "y"}},
+		{{Name: "a", Token: "x"}, {Name: "b", Token: "x"}},
+	}
+	for i, rules := range cases {
+		if _, err := NewAccessControl(rules); err == nil {
+			t.Errorf("case %d: expected an error for %+v", i, rules)
+		}
+	}
+}
+
+func TestAccessControlAuthenticate(t *testing.T) {
+	ac := newTestAccessControl(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/ws?token=guest-token", nil)
+	if rule, ok := ac.Authenticate(r); !ok || rule.Name != "guest" {
+		t.Errorf("query token not accepted: %+v %v", rule, ok)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/ws", nil)
+	r.Header.Set("Authorization", "Bearer admin-token")
+	if rule, ok := ac.Authenticate(r); !ok || rule.Name != "admin" {
+		t.Errorf("bearer token not accepted: %+v %v", rule, ok)
+	}
+
+	for _, url := range []string{"/ws", "/ws?token=wrong", "/ws?token=guest"} {
+		if _, ok := ac.Authenticate(httptest.NewRequest(http.MethodGet, url, nil)); ok {
+			t.Errorf("%s should not be authenticated", url)
+		}
+	}
+}
+
+func TestAccessRulePatterns(t *testing.T) {
+	ac := newTestAccessControl(t)
+	c := newAccessTestClient()
+	guest := ac.Rule("guest")
+
+	if guest.CanRead(c, accessAircon) || guest.CanControl(c, accessAircon) {
+		t.Error("guest should not access the air conditioner")
+	}
+	if !guest.CanRead(c, accessLight) || !guest.CanControl(c, accessLight) {
+		t.Error("guest should read and control the light (group and IP EOJ)")
+	}
+	if !guest.CanRead(c, accessSensor) || guest.CanControl(c, accessSensor) {
+		t.Error("guest should only read the sensor (alias)")
+	}
+	if guest.CanReadAll() || guest.IsAdmin() {
+		t.Error("guest should not read all devices")
+	}
+
+	viewer := ac.Rule("viewer")
+	if !viewer.CanReadAll() || viewer.CanControl(c, accessLight) {
+		t.Error("viewer should read but not control every device")
+	}
+
+	// A nil rule means access control is disabled
+	var disabled *AccessRule
+	if !disabled.IsAdmin() || !disabled.CanControl(c, accessAircon) {
+		t.Error("nil rule should allow everything")
+	}
+
+	// Device ID strings and bare IP addresses
+	byID := &AccessRule{Read: []string{"id-192.168.1.10"}, Control: []string{"192.168.1.12"}}
+	if !byID.CanRead(c, accessAircon) || !byID.CanControl(c, accessSensor) || byID.CanRead(c, accessLight) {
+		t.Error("ID string or IP pattern did not match as expected")
+	}
+}
+
+func TestApplyAccessRule(t *testing.T) {
+	ac := newTestAccessControl(t)
+	ws := &WebSocketServer{access: ac, echonetClient: newAccessTestClient()}
+	guest := ac.Rule("guest")
+
+	request := func(msgType protocol.MessageType, payload any) *protocol.Message {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &protocol.Message{Type: msgType, Payload: data}
+	}
+
+	cases := []struct {
+		name    string
+		rule    *AccessRule
+		msg     *protocol.Message
+		allowed bool
+	}{
+		{"get readable", guest, request(protocol.MessageTypeGetProperties, protocol.GetPropertiesPayload{Targets: []string{"192.168.1.12 0011:1"}}), true},
+		{"get hidden", guest, request(protocol.MessageTypeGetProperties, protocol.GetPropertiesPayload{Targets: []string{"192.168.1.12 0011:1", "192.168.1.10 0130:1"}}), false},
+		{"set controllable", guest, request(protocol.MessageTypeSetProperties, protocol.SetPropertiesPayload{Target: "192.168.1.11 0291:1"}), true},
+		{"set read only", guest, request(protocol.MessageTypeSetProperties, protocol.SetPropertiesPayload{Target: "192.168.1.12 0011:1"}), false},
+		{"history hidden", guest, request(protocol.MessageTypeGetDeviceHistory, protocol.GetDeviceHistoryPayload{Target: "192.168.1.10 0130:1"}), false},
+		{"delete read only", guest, request(protocol.MessageTypeDeleteDevice, protocol.DeleteDevicePayload{Target: "192.168.1.12 0011:1"}), false},
+		{"update all", guest, request(protocol.MessageTypeUpdateProperties, protocol.UpdatePropertiesPayload{}), false},
+		{"summary", guest, request(protocol.MessageTypeGetSummary, protocol.GetSummaryPayload{}), false},
+		{"summary viewer", ac.Rule("viewer"), request(protocol.MessageTypeGetSummary, protocol.GetSummaryPayload{}), true},
+		{"group list", guest, request(protocol.MessageTypeManageGroup, protocol.ManageGroupPayload{Action: protocol.GroupActionList}), true},
+		{"group add", guest, request(protocol.MessageTypeManageGroup, protocol.ManageGroupPayload{Action: protocol.GroupActionAdd, Group: "@living"}), false},
+		{"discover", guest, request(protocol.MessageTypeDiscoverDevices, struct{}{}), false},
+		{"discover admin", ac.Rule("admin"), request(protocol.MessageTypeDiscoverDevices, struct{}{}), true},
+		{"get alias", guest, request(protocol.MessageTypeGetProperties, protocol.GetPropertiesPayload{Targets: []string{"thermometer"}}), true},
+		{"get ID string hidden", guest, request(protocol.MessageTypeGetProperties, protocol.GetPropertiesPayload{Targets: []string{"id-192.168.1.10"}}), false},
+		{"get unresolvable", guest, request(protocol.MessageTypeGetProperties, protocol.GetPropertiesPayload{Targets: []string{"192.168.1.12 0011:1", "no-such-device"}}), false},
+		{"set group controllable", guest, request(protocol.MessageTypeSetProperties, protocol.SetPropertiesPayload{Target: "@living"}), true},
+		{"set unknown group", guest, request(protocol.MessageTypeSetProperties, protocol.SetPropertiesPayload{Target: "@kitchen"}), false},
+		{"history without target", guest, request(protocol.MessageTypeGetDeviceHistory, protocol.GetDeviceHistoryPayload{}), false},
+		{"list unresolvable", guest, request(protocol.MessageTypeListDevices, protocol.ListDevicesPayload{Targets: []string{"no-such-device"}}), false},
+		{"aliases", guest, request(protocol.MessageTypeGetAliases, struct{}{}), true},
+		{"unknown type", ac.Rule("viewer"), request(protocol.MessageType("future_request"), struct{}{}), false},
+		{"unknown type admin", ac.Rule("admin"), request(protocol.MessageType("future_request"), struct{}{}), true},
+		{"unknown principal", noAccess, request(protocol.MessageTypeGetProperties, protocol.GetPropertiesPayload{Targets: []string{"192.168.1.11 0291:1"}}), false},
+	}
+	for _, tc := range cases {
+		result, ok := ws.applyAccessRule(tc.rule, tc.msg)
+		if ok != tc.allowed {
+			t.Errorf("%s: allowed = %v, want %v", tc.name, ok, tc.allowed)
+			continue
+		}
+		if !ok && (result.Error == nil || result.Error.Code != protocol.ErrorCodePermissionDenied) {
+			t.Errorf("%s: expected PERMISSION_DENIED, got %+v", tc.name, result)
+		}
+	}
+
+	// list_devices without targets is narrowed to the readable devices
+	msg := request(protocol.MessageTypeListDevices, protocol.ListDevicesPayload{})
+	if _, ok := ws.applyAccessRule(guest, msg); !ok {
+		t.Fatal("list_devices should be allowed")
+	}
+	var narrowed protocol.ListDevicesPayload
+	if err := json.Unmarshal(msg.Payload, &narrowed); err != nil {
+		t.Fatal(err)
+	}
+	if len(narrowed.Targets) != 2 || narrowed.Targets[0] != accessLight.Specifier() || narrowed.Targets[1] != accessSensor.Specifier() {
+		t.Errorf("unexpected narrowed targets: %v", narrowed.Targets)
+	}
+
+	// Without readable devices, the empty list is returned without calling the handler
+	result, ok := ws.applyAccessRule(noAccess, request(protocol.MessageTypeListDevices, protocol.ListDevicesPayload{}))
+	if ok || !result.Success || string(result.Data) != "[]" {
+		t.Errorf("expected an empty list, got %v %+v", ok, result)
+	}
+}
+
+func TestRESTAPI_AccessControl(t *testing.T) {
+	c := newAccessTestClient()
+	api := NewRESTAPIHandler(c)
+	api.access = newTestAccessControl(t)
+	mux := http.NewServeMux()
+	api.Register(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	do := func(method, path, token, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	if resp := do(http.MethodGet, "/api/devices", "", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", resp.StatusCode)
+	}
+
+	resp := do(http.MethodGet, "/api/devices", "guest-token", "")
+	var devices []protocol.Device
+	if err := json.NewDecoder(resp.Body).Decode(&devices); err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 2 {
+		t.Errorf("guest should see 2 devices, got %+v", devices)
+	}
+
+	if resp := do(http.MethodGet, "/api/devices/192.168.1.10/0130:1", "guest-token", ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for a hidden device, got %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodPost, "/api/devices/192.168.1.12/0011:1/properties", "guest-token", `{"80": {"EDT": "MA=="}}`); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for setting a read-only device, got %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodPost, "/api/devices/192.168.1.11/0291:1/properties", "guest-token", `{"80": {"EDT": "MA=="}}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 for setting a controllable device, got %d", resp.StatusCode)
+	}
+}
+
+func TestLogTransport_BroadcastsToClientsReadingAllDevices(t *testing.T) {
+	ac := newTestAccessControl(t)
+	transport := &logStreamTestTransport{}
+	ws := &WebSocketServer{access: ac, transport: transport}
+	ws.clientRules.Store("admin", ac.Rule("admin"))
+	ws.clientRules.Store("viewer", ac.Rule("viewer"))
+	ws.clientRules.Store("guest", ac.Rule("guest"))
+	ws.clientRules.Store("unknown", noAccess)
+
+	handler := NewBroadcastHandler(slog.NewTextHandler(io.Discard, nil), ws.LogTransport(), slog.LevelWarn)
+	slog.New(handler).Warn("Device 192.168.1.10 0130:1 did not respond")
+
+	for connID, want := range map[string]int{"admin": 1, "viewer": 1, "guest": 0, "unknown": 0} {
+		if got := len(transport.messages(connID)); got != want {
+			t.Errorf("%s received %d log notifications, want %d", connID, got, want)
+		}
+	}
+}
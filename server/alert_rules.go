@@ -0,0 +1,342 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// AlertComparator is the comparison of an alert rule between a value and its threshold
+type AlertComparator string
+
+const (
+	AlertAbove        AlertComparator = ">"
+	AlertAboveOrEqual AlertComparator = ">="
+	AlertBelow        AlertComparator = "<"
+	AlertBelowOrEqual AlertComparator = "<="
+)
+
+// alertCheckInterval is how often pending alerts are checked for their duration,
+// as a value that stays above the threshold produces no further property changes.
+const alertCheckInterval = time.Second
+
+// alertWebhookTimeout limits how long a webhook request may take
+const alertWebhookTimeout = 10 * time.Second
+
+// AlertRule raises an alert when a numeric property of a device crosses a threshold,
+// e.g. when the instantaneous power consumption of an air conditioner stays above 1500 W for 5 minutes.
+type AlertRule struct {
+	Name       string
+	Device     string // device pattern: alias, "@group", "IP EOJ", IP address or "*"
+	EPC        echonet_lite.EPCType
+	Comparator AlertComparator
+	Threshold  float64 // in the unit of the property's number value (e.g. W or ℃)
+	Hysteresis float64 // how far back the value must go before the alert is cleared
+	Duration   time.Duration
+	Webhook    string // URL the alert_raised and alert_cleared messages are posted to (empty for none)
+}
+
+// holds reports whether the condition of the rule holds for a value
+func (r *AlertRule) holds(value float64) bool {
+	switch r.Comparator {
+	case AlertAbove:
+		return value > r.Threshold
+	case AlertAboveOrEqual:
+		return value >= r.Threshold
+	case AlertBelow:
+		return value < r.Threshold
+	case AlertBelowOrEqual:
+		return value <= r.Threshold
+	}
+	return false
+}
+
+// clears reports whether a value is far enough back from the threshold to clear a raised alert
+func (r *AlertRule) clears(value float64) bool {
+	if r.Comparator == AlertAbove || r.Comparator == AlertAboveOrEqual {
+		return !r.holds(value + r.Hysteresis)
+	}
+	return !r.holds(value - r.Hysteresis)
+}
+
+// AlertRules holds the validated alert rules
+type AlertRules struct {
+	rules []*AlertRule
+}
+
+// NewAlertRules validates the rules and creates AlertRules
+func NewAlertRules(rules []AlertRule) (*AlertRules, error) {
+	a := &AlertRules{}
+	names := make(map[string]bool)
+	for i := range rules {
+		rule := rules[i]
+		if rule.Name == "" {
+			return nil, fmt.Errorf("alert rule #%d has no name", i+1)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("duplicate alert rule name: %q", rule.Name)
+		}
+		names[rule.Name] = true
+		if strings.TrimSpace(rule.Device) == "" {
+			return nil, fmt.Errorf("alert rule %q has no device", rule.Name)
+		}
+		switch rule.Comparator {
+		case AlertAbove, AlertAboveOrEqual, AlertBelow, AlertBelowOrEqual:
+		default:
+			return nil, fmt.Errorf("alert rule %q has an invalid comparator: %q (>, >=, < or <=)", rule.Name, rule.Comparator)
+		}
+		if math.IsNaN(rule.Threshold) || math.IsInf(rule.Threshold, 0) {
+			return nil, fmt.Errorf("alert rule %q has an invalid threshold: %v", rule.Name, rule.Threshold)
+		}
+		if rule.Hysteresis < 0 || math.IsNaN(rule.Hysteresis) || math.IsInf(rule.Hysteresis, 0) {
+			return nil, fmt.Errorf("alert rule %q has an invalid hysteresis: %v", rule.Name, rule.Hysteresis)
+		}
+		if rule.Duration < 0 {
+			return nil, fmt.Errorf("alert rule %q has a negative duration: %v", rule.Name, rule.Duration)
+		}
+		if rule.Webhook != "" {
+			if u, err := url.Parse(rule.Webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("alert rule %q has an invalid webhook URL: %q", rule.Name, rule.Webhook)
+			}
+		}
+		a.rules = append(a.rules, &rule)
+	}
+	return a, nil
+}
+
+// Len returns the number of rules
+func (a *AlertRules) Len() int {
+	return len(a.rules)
+}
+
+// hasDurations reports whether any rule waits before raising its alert
+func (a *AlertRules) hasDurations() bool {
+	for _, rule := range a.rules {
+		if rule.Duration > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// alertState is the state of one rule for one device
+type alertState struct {
+	value  float64
+	since  time.Time // when the condition started to hold (zero when it does not)
+	raised bool
+}
+
+// alertEvent is an alert that was raised or cleared
+type alertEvent struct {
+	rule   *AlertRule
+	device handler.IPAndEOJ
+	value  float64
+	since  time.Time
+	time   time.Time
+	raised bool
+}
+
+// payload returns the alert_raised or alert_cleared payload of the event
+func (e alertEvent) payload() protocol.AlertPayload {
+	return protocol.AlertPayload{
+		Rule:       e.rule.Name,
+		IP:         e.device.IP.String(),
+		EOJ:        e.device.EOJ.Specifier(),
+		EPC:        fmt.Sprintf("%02X", byte(e.rule.EPC)),
+		Value:      e.value,
+		Comparator: string(e.rule.Comparator),
+		Threshold:  e.rule.Threshold,
+		Since:      e.since,
+		Timestamp:  e.time,
+	}
+}
+
+// messageType returns alert_raised or alert_cleared
+func (e alertEvent) messageType() protocol.MessageType {
+	if e.raised {
+		return protocol.MessageTypeAlertRaised
+	}
+	return protocol.MessageTypeAlertCleared
+}
+
+// alertMonitor evaluates the alert rules against property changes.
+// Property changes are evaluated by listenForNotifications and durations by a ticker, so the state is locked.
+type alertMonitor struct {
+	rules  *AlertRules
+	mu     sync.Mutex
+	states map[*AlertRule]map[string]*alertState // rule -> device.Key() -> state
+	device map[string]handler.IPAndEOJ           // device.Key() -> device
+}
+
+func newAlertMonitor(rules *AlertRules) *alertMonitor {
+	return &alertMonitor{
+		rules:  rules,
+		states: make(map[*AlertRule]map[string]*alertState),
+		device: make(map[string]handler.IPAndEOJ),
+	}
+}
+
+// Evaluate updates the alerts of the rules matching a property change and returns the alerts raised or cleared by it.
+// Values that are not numbers are ignored.
+func (m *alertMonitor) Evaluate(resolver accessResolver, device handler.IPAndEOJ, property echonet_lite.Property, now time.Time) []alertEvent {
+	var matched []*AlertRule
+	for _, rule := range m.rules.rules {
+		if rule.EPC == property.EPC && matchesPattern(resolver, rule.Device, device) {
+			matched = append(matched, rule)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	number := protocol.MakePropertyData(device.EOJ.ClassCode(), property).Number
+	if number == nil {
+		return nil
+	}
+	value := float64(*number)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := device.Key()
+	m.device[key] = device
+	var events []alertEvent
+	for _, rule := range matched {
+		if m.states[rule] == nil {
+			m.states[rule] = make(map[string]*alertState)
+		}
+		state := m.states[rule][key]
+		if state == nil {
+			state = &alertState{}
+			m.states[rule][key] = state
+		}
+		state.value = value
+
+		if state.raised {
+			if rule.clears(value) {
+				events = append(events, alertEvent{rule: rule, device: device, value: value, since: state.since, time: now})
+				state.raised = false
+				state.since = time.Time{}
+			}
+			continue
+		}
+		if !rule.holds(value) {
+			state.since = time.Time{}
+			continue
+		}
+		if state.since.IsZero() {
+			state.since = now
+		}
+		if event, ok := m.raise(rule, key, state, now); ok {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Check raises the pending alerts whose condition has held for their duration
+func (m *alertMonitor) Check(now time.Time) []alertEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var events []alertEvent
+	for _, rule := range m.rules.rules {
+		for key, state := range m.states[rule] {
+			if state.raised || state.since.IsZero() {
+				continue
+			}
+			if event, ok := m.raise(rule, key, state, now); ok {
+				events = append(events, event)
+			}
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].since.Before(events[j].since)
+	})
+	return events
+}
+
+// raise raises the alert of a pending state if its condition has held for the duration of the rule
+func (m *alertMonitor) raise(rule *AlertRule, key string, state *alertState, now time.Time) (alertEvent, bool) {
+	if now.Sub(state.since) < rule.Duration {
+		return alertEvent{}, false
+	}
+	state.raised = true
+	return alertEvent{rule: rule, device: m.device[key], value: state.value, since: state.since, time: now, raised: true}, true
+}
+
+// evaluateAlerts evaluates the alert rules against a property change and publishes the resulting alerts
+func (ws *WebSocketServer) evaluateAlerts(device handler.IPAndEOJ, property echonet_lite.Property) {
+	if ws.alerts == nil {
+		return
+	}
+	ws.publishAlerts(ws.alerts.Evaluate(ws.accessResolver(), device, property, time.Now()))
+}
+
+// runAlertChecks raises the alerts whose condition has held for their duration
+func (ws *WebSocketServer) runAlertChecks() {
+	ticker := time.NewTicker(alertCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			ws.publishAlerts(ws.alerts.Check(now))
+		case <-ws.ctx.Done():
+			return
+		}
+	}
+}
+
+// publishAlerts broadcasts alerts to the clients allowed to read the device and posts them to the webhooks of their rules
+func (ws *WebSocketServer) publishAlerts(events []alertEvent) {
+	for _, event := range events {
+		payload := event.payload()
+		message := "Alert cleared"
+		if event.raised {
+			message = "Alert raised"
+		}
+		slog.Info(message, "rule", payload.Rule, "device", event.device.Specifier(), "epc", payload.EPC, "value", payload.Value, "threshold", payload.Threshold)
+
+		if err := ws.broadcastDeviceMessageToClients(event.device, event.messageType(), payload); err != nil && !isClientDisconnectedError(err) {
+			slog.Error("Failed to broadcast alert", "error", err, "rule", payload.Rule)
+		}
+		if event.rule.Webhook != "" {
+			go ws.postAlertWebhook(event.rule.Webhook, event.messageType(), payload)
+		}
+	}
+}
+
+// postAlertWebhook posts an alert to a webhook in the same format as the WebSocket message
+func (ws *WebSocketServer) postAlertWebhook(webhook string, msgType protocol.MessageType, payload protocol.AlertPayload) {
+	data, err := protocol.CreateMessage(msgType, payload, "")
+	if err != nil {
+		slog.Error("Error creating alert webhook message", "err", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(ws.ctx, alertWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(data))
+	if err != nil {
+		slog.Error("Error creating alert webhook request", "err", err, "rule", payload.Rule)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		slog.Warn("Failed to post alert webhook", "err", err, "rule", payload.Rule)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		slog.Warn("Alert webhook returned an error", "status", resp.StatusCode, "rule", payload.Rule)
+	}
+}
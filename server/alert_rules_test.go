@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// broadcastTransport records the broadcast messages
+type broadcastTransport struct {
+	mockLocationTransport
+	broadcast [][]byte
+}
+
+func (m *broadcastTransport) BroadcastMessage(message []byte) error {
+	m.broadcast = append(m.broadcast, message)
+	return nil
+}
+
+func TestNewAlertRules(t *testing.T) {
+	valid := AlertRule{Name: "hot", Device: "*", EPC: 0xBB, Comparator: AlertAbove, Threshold: 30}
+	if rules, err := NewAlertRules([]AlertRule{valid}); err != nil || rules.Len() != 1 {
+		t.Fatalf("unexpected result: %v %v", rules, err)
+	}
+
+	invalid := map[string]func(r *AlertRule){
+		"no name":       func(r *AlertRule) { r.Name = "" },
+		"no device":     func(r *AlertRule) { r.Device = " " },
+		"comparator":    func(r *AlertRule) { r.Comparator = "==" },
+		"hysteresis":    func(r *AlertRule) { r.Hysteresis = -1 },
+		"duration":      func(r *AlertRule) { r.Duration = -time.Second },
+		"webhook":       func(r *AlertRule) { r.Webhook = "ftp://example.com/" },
+		"relative hook": func(r *AlertRule) { r.Webhook = "/alerts" },
+	}
+	for name, modify := range invalid {
+		rule := valid
+		modify(&rule)
+		if _, err := NewAlertRules([]AlertRule{rule}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := NewAlertRules([]AlertRule{valid, valid}); err == nil {
+		t.Error("expected an error for duplicate names")
+	}
+}
+
+func TestAlertMonitor(t *testing.T) {
+	aircon := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	bedroom := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.11"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	rules, err := NewAlertRules([]AlertRule{
+		{Name: "hot", Device: "192.168.1.10 0130:1", EPC: 0xBB, Comparator: AlertAbove, Threshold: 30, Hysteresis: 2, Duration: 5 * time.Minute},
+		{Name: "cold", Device: "*", EPC: 0xBB, Comparator: AlertBelowOrEqual, Threshold: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	monitor := newAlertMonitor(rules)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	temp := func(device handler.IPAndEOJ, value byte, at time.Time) []alertEvent {
+		return monitor.Evaluate(nil, device, echonet_lite.Property{EPC: 0xBB, EDT: []byte{value}}, at)
+	}
+
+	// Without a duration the alert is raised immediately
+	if events := temp(bedroom, 10, now); len(events) != 1 || !events[0].raised || events[0].rule.Name != "cold" {
+		t.Fatalf("expected the cold alert, got %+v", events)
+	}
+	if events := temp(bedroom, 9, now); len(events) != 0 {
+		t.Errorf("expected no second alert, got %+v", events)
+	}
+	if events := temp(bedroom, 11, now); len(events) != 1 || events[0].raised {
+		t.Errorf("expected the cold alert to clear, got %+v", events)
+	}
+
+	// The condition must hold for the duration, also without further changes
+	if events := temp(aircon, 31, now); len(events) != 0 {
+		t.Fatalf("expected a pending alert, got %+v", events)
+	}
+	if events := monitor.Check(now.Add(4 * time.Minute)); len(events) != 0 {
+		t.Errorf("expected no alert before the duration, got %+v", events)
+	}
+	events := monitor.Check(now.Add(5 * time.Minute))
+	if len(events) != 1 || !events[0].raised || events[0].device.Key() != aircon.Key() || !events[0].since.Equal(now) {
+		t.Fatalf("expected the hot alert after the duration, got %+v", events)
+	}
+	payload := events[0].payload()
+	if payload.Rule != "hot" || payload.EPC != "BB" || payload.Value != 31 || payload.EOJ != "0130:1" {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+
+	// The alert is cleared only below the threshold minus the hysteresis
+	if events := temp(aircon, 29, now.Add(6*time.Minute)); len(events) != 0 {
+		t.Errorf("expected the hysteresis to keep the alert, got %+v", events)
+	}
+	if events := temp(aircon, 28, now.Add(7*time.Minute)); len(events) != 1 || events[0].raised {
+		t.Errorf("expected the hot alert to clear, got %+v", events)
+	}
+
+	// Dropping below the threshold before the duration resets the pending alert
+	temp(aircon, 31, now.Add(10*time.Minute))
+	temp(aircon, 30, now.Add(12*time.Minute))
+	if events := monitor.Check(now.Add(20 * time.Minute)); len(events) != 0 {
+		t.Errorf("expected the pending alert to be reset, got %+v", events)
+	}
+
+	// Values that are not numbers (0x80 is "underflow", not -128) are ignored
+	if events := temp(bedroom, 0x80, now); len(events) != 0 {
+		t.Errorf("expected no alert for an unknown value, got %+v", events)
+	}
+}
+
+func TestPublishAlerts(t *testing.T) {
+	received := make(chan []byte, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- body
+	}))
+	defer hook.Close()
+
+	rules, err := NewAlertRules([]AlertRule{{Name: "hot", Device: "*", EPC: 0xBB, Comparator: AlertAbove, Threshold: 30, Webhook: hook.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &broadcastTransport{}
+	ws := &WebSocketServer{ctx: context.Background(), transport: transport, alerts: newAlertMonitor(rules)}
+
+	aircon := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	ws.evaluateAlerts(aircon, echonet_lite.Property{EPC: 0xBB, EDT: []byte{32}})
+
+	if len(transport.broadcast) != 1 {
+		t.Fatalf("expected one broadcast, got %d", len(transport.broadcast))
+	}
+	select {
+	case body := <-received:
+		var msg protocol.Message
+		var payload protocol.AlertPayload
+		if err := json.Unmarshal(body, &msg); err != nil || msg.Type != protocol.MessageTypeAlertRaised {
+			t.Fatalf("unexpected webhook message: %s", body)
+		}
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil || payload.Rule != "hot" || payload.Value != 32 {
+			t.Errorf("unexpected webhook payload: %s", msg.Payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"log/slog"
+
+	"echonet-list/protocol"
+)
+
+// auditedMessageTypes are the requests that change devices or the server state.
+// Each of them leaves an audit record whether it succeeded or not.
+var auditedMessageTypes = map[protocol.MessageType]bool{
+	protocol.MessageTypeSetProperties:       true,
+	protocol.MessageTypeSetGetProperties:    true,
+	protocol.MessageTypeSetGroupProperties:  true,
+	protocol.MessageTypeManageAlias:         true,
+	protocol.MessageTypeManageGroup:         true,
+	protocol.MessageTypeManageScene:         true,
+	protocol.MessageTypeRunScene:            true,
+	protocol.MessageTypeManageSchedule:      true,
+	protocol.MessageTypeDiscoverDevices:     true,
+	protocol.MessageTypeCleanupDevices:      true,
+	protocol.MessageTypeDeleteDevice:        true,
+	protocol.MessageTypeDebugSetOffline:     true,
+	protocol.MessageTypeManageLocationAlias: true,
+	protocol.MessageTypeSetLocationOrder:    true,
+	protocol.MessageTypeManageMetadata:      true,
+}
+
+// Audit outcomes
+const (
+	auditOutcomeSuccess = "success"
+	auditOutcomeFailure = "failure"
+	auditOutcomeDenied  = "denied"
+)
+
+// ConnectionInfo returns the metadata of the connection an audit record refers to.
+// Connections closed recently can be looked up too.
+func (ws *WebSocketServer) ConnectionInfo(connID string) (ConnectionInfo, bool) {
+	if transport, ok := ws.transport.(*DefaultWebSocketTransport); ok {
+		return transport.ConnectionInfo(connID)
+	}
+	return ConnectionInfo{}, false
+}
+
+// audit writes the audit record of a request. The connID ties the record to the
+// "WebSocket client connected" log and to ConnectionInfo.
+func (ws *WebSocketServer) audit(connID string, msg *protocol.Message, outcome string, detail string) {
+	attrs := []any{"connID", connID, "type", msg.Type, "requestID", msg.RequestID, "outcome", outcome}
+	if info, ok := ws.ConnectionInfo(connID); ok {
+		attrs = append(attrs, "principal", info.Principal, "remote_addr", info.RemoteAddr)
+	}
+	if detail != "" {
+		attrs = append(attrs, "detail", detail)
+	}
+	slog.Info("Audit", attrs...)
+}
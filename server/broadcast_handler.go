@@ -9,10 +9,25 @@ import (
 )
 
 // BroadcastHandler はError/Warnレベルのログをブロードキャストするカスタムハンドラー
+// stream が設定されている場合は、すべてのログを subscribe_logs の購読者にも送る
+// Records with the NoBroadcast() attribute are never broadcast, whatever their level
 type BroadcastHandler struct {
-	inner     slog.Handler
-	transport WebSocketTransport
-	minLevel  slog.Level
+	inner       slog.Handler
+	transport   WebSocketTransport
+	minLevel    slog.Level
+	stream      *LogStream
+	noBroadcast bool // NoBroadcast() was passed to WithAttrs
+}
+
+// noBroadcastKey is the key of the NoBroadcast() attribute; it is removed before the record is written
+const noBroadcastKey = "no_broadcast"
+
+// NoBroadcast returns an attribute that keeps a Warn or Error record from being broadcast to the WebSocket clients.
+// Use it for failures that repeat, or warnings caused by a single client's requests, which would flood every client.
+//
+//	slog.Warn("Failed to write to InfluxDB", "err", err, server.NoBroadcast())
+func NoBroadcast() slog.Attr {
+	return slog.Bool(noBroadcastKey, true)
 }
 
 // NewBroadcastHandler creates a new BroadcastHandler
@@ -31,37 +46,85 @@ func (h *BroadcastHandler) Enabled(ctx context.Context, level slog.Level) bool {
 
 // Handle handles the Record by passing it to the inner handler and broadcasting if needed.
 func (h *BroadcastHandler) Handle(ctx context.Context, r slog.Record) error {
+	broadcast := !h.noBroadcast
+	if hasNoBroadcast(r) {
+		broadcast = false
+		r = withoutNoBroadcast(r)
+	}
+
 	// First, let the inner handler process the record
 	if err := h.inner.Handle(ctx, r); err != nil {
 		return err
 	}
 
 	// Broadcast Error and Warn level logs
-	if r.Level >= slog.LevelWarn && h.transport != nil {
+	if broadcast && r.Level >= slog.LevelWarn && h.transport != nil {
 		h.broadcastLog(r)
 	}
 
+	if h.stream != nil {
+		h.stream.Publish(r)
+	}
+
 	return nil
 }
 
 // WithAttrs returns a new Handler whose attributes consist of both the receiver's attributes and the arguments.
 func (h *BroadcastHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	noBroadcast := h.noBroadcast
+	filtered := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if a.Key == noBroadcastKey {
+			noBroadcast = true
+			continue
+		}
+		filtered = append(filtered, a)
+	}
 	return &BroadcastHandler{
-		inner:     h.inner.WithAttrs(attrs),
-		transport: h.transport,
-		minLevel:  h.minLevel,
+		inner:       h.inner.WithAttrs(filtered),
+		transport:   h.transport,
+		minLevel:    h.minLevel,
+		stream:      h.stream,
+		noBroadcast: noBroadcast,
 	}
 }
 
 // WithGroup returns a new Handler with the given group appended to the receiver's existing groups.
 func (h *BroadcastHandler) WithGroup(name string) slog.Handler {
 	return &BroadcastHandler{
-		inner:     h.inner.WithGroup(name),
-		transport: h.transport,
-		minLevel:  h.minLevel,
+		inner:       h.inner.WithGroup(name),
+		transport:   h.transport,
+		minLevel:    h.minLevel,
+		stream:      h.stream,
+		noBroadcast: h.noBroadcast,
 	}
 }
 
+// hasNoBroadcast reports whether the record has the NoBroadcast() attribute
+func hasNoBroadcast(r slog.Record) bool {
+	found := false
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == noBroadcastKey {
+			found = true
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// withoutNoBroadcast returns a copy of the record without the NoBroadcast() attribute
+func withoutNoBroadcast(r slog.Record) slog.Record {
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key != noBroadcastKey {
+			out.AddAttrs(a)
+		}
+		return true
+	})
+	return out
+}
+
 // formatAttributeValue formats a slog.Value for JSON serialization
 func formatAttributeValue(v slog.Value) interface{} {
 	switch v.Kind() {
@@ -120,22 +183,10 @@ func formatAttributeValue(v slog.Value) interface{} {
 
 // broadcastLog sends the log record to all connected WebSocket clients
 func (h *BroadcastHandler) broadcastLog(r slog.Record) {
-	// Extract attributes
-	attrs := make(map[string]interface{})
-	r.Attrs(func(a slog.Attr) bool {
-		attrs[a.Key] = formatAttributeValue(a.Value)
-		return true
-	})
-
 	// Create log notification payload
 	payload := map[string]interface{}{
-		"type": "log_notification",
-		"payload": map[string]interface{}{
-			"level":      r.Level.String(),
-			"message":    r.Message,
-			"time":       r.Time.Format(time.RFC3339),
-			"attributes": attrs,
-		},
+		"type":    "log_notification",
+		"payload": logEntryFromRecord(r),
 	}
 
 	// Marshal to JSON
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBroadcastHandler_NoBroadcast(t *testing.T) {
+	var out bytes.Buffer
+	transport := new(mockLocationTransport)
+	transport.On("BroadcastMessage", mock.Anything).Return(nil)
+	logger := slog.New(NewBroadcastHandler(slog.NewTextHandler(&out, nil), transport, slog.LevelWarn))
+
+	logger.Warn("broadcast warning")
+	logger.Warn("quiet warning", "err", "boom", NoBroadcast())
+	logger.With(NoBroadcast()).Error("quiet error")
+
+	if len(transport.broadcastMessages) != 1 || !strings.Contains(string(transport.broadcastMessages[0]), "broadcast warning") {
+		t.Fatalf("expected only the first warning to be broadcast, got %d messages", len(transport.broadcastMessages))
+	}
+	// The records are still logged, without the marker attribute
+	logged := out.String()
+	for _, message := range []string{"quiet warning", "err=boom", "quiet error"} {
+		if !strings.Contains(logged, message) {
+			t.Errorf("expected %q in the log, got:\n%s", message, logged)
+		}
+	}
+	if strings.Contains(logged, noBroadcastKey) {
+		t.Errorf("the %s attribute should not be logged:\n%s", noBroadcastKey, logged)
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// ChangeThresholdKey identifies the properties a change threshold applies to
+type ChangeThresholdKey struct {
+	ClassCode echonet_lite.EOJClassCode // 0 applies to every class
+	EPC       echonet_lite.EPCType
+}
+
+// ChangeThresholds maps properties to the minimum change of their numeric value that is broadcast and recorded.
+// The threshold is in the unit of the property's number value (e.g. ℃ or W).
+type ChangeThresholds map[ChangeThresholdKey]float64
+
+// ParseChangeThresholds parses thresholds from the configuration.
+// A key is an EPC ("BB") for every class, or a class code and EPC ("0011:E0") for one class, which takes priority.
+func ParseChangeThresholds(config map[string]float64) (ChangeThresholds, error) {
+	thresholds := make(ChangeThresholds, len(config))
+	for key, threshold := range config {
+		if threshold <= 0 || math.IsNaN(threshold) || math.IsInf(threshold, 0) {
+			return nil, fmt.Errorf("threshold of %s must be greater than zero: %v", key, threshold)
+		}
+		var k ChangeThresholdKey
+		epcStr := key
+		if classStr, rest, ok := strings.Cut(key, ":"); ok {
+			classCode, err := handler.ParseEOJClassCodeString(classStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid change threshold key %s: %w", key, err)
+			}
+			k.ClassCode = classCode
+			epcStr = rest
+		}
+		epc, err := handler.ParseEPCString(epcStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid change threshold key %s: %w", key, err)
+		}
+		k.EPC = epc
+		thresholds[k] = threshold
+	}
+	return thresholds, nil
+}
+
+// lookup returns the threshold for a property of a class, preferring a class-specific entry
+func (t ChangeThresholds) lookup(classCode echonet_lite.EOJClassCode, epc echonet_lite.EPCType) (float64, bool) {
+	if threshold, ok := t[ChangeThresholdKey{ClassCode: classCode, EPC: epc}]; ok {
+		return threshold, true
+	}
+	threshold, ok := t[ChangeThresholdKey{EPC: epc}]
+	return threshold, ok
+}
+
+// changeThresholdFilter drops small changes of numeric properties, e.g. a temperature sensor jittering by 0.1℃.
+// A change is compared with the last value that was let through, not with the previous value,
+// so that a slow drift is still reported once it adds up to the threshold.
+// It is only used from listenForNotifications, so it needs no lock.
+type changeThresholdFilter struct {
+	thresholds ChangeThresholds
+	reported   map[string]int // key: device.Key() + EPC, value: number of the last change let through
+}
+
+func newChangeThresholdFilter(thresholds ChangeThresholds) *changeThresholdFilter {
+	return &changeThresholdFilter{
+		thresholds: thresholds,
+		reported:   make(map[string]int),
+	}
+}
+
+// Allow reports whether a property change is significant enough to be broadcast and recorded.
+// Properties without a threshold, and values that are not numbers, are always allowed.
+func (f *changeThresholdFilter) Allow(device handler.IPAndEOJ, property echonet_lite.Property) bool {
+	classCode := device.EOJ.ClassCode()
+	threshold, ok := f.thresholds.lookup(classCode, property.EPC)
+	if !ok {
+		return true
+	}
+	number := protocol.MakePropertyData(classCode, property).Number
+	if number == nil {
+		return true
+	}
+
+	key := fmt.Sprintf("%s %02X", device.Key(), byte(property.EPC))
+	if last, ok := f.reported[key]; ok && math.Abs(float64(*number-last)) < threshold {
+		return false
+	}
+	f.reported[key] = *number
+	return true
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"net"
+	"testing"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+)
+
+func TestParseChangeThresholds(t *testing.T) {
+	thresholds, err := ParseChangeThresholds(map[string]float64{"84": 10, "0130:BB": 2, "0130:84": 50})
+	if err != nil {
+		t.Fatalf("ParseChangeThresholds returned error: %v", err)
+	}
+	// A class-specific threshold takes priority over the EPC alone
+	if got, ok := thresholds.lookup(echonet_lite.HomeAirConditioner_ClassCode, 0x84); !ok || got != 50 {
+		t.Errorf("expected 50 for 0130:84, got %v %v", got, ok)
+	}
+	if got, ok := thresholds.lookup(echonet_lite.SingleFunctionLighting_ClassCode, 0x84); !ok || got != 10 {
+		t.Errorf("expected 10 for 84 of another class, got %v %v", got, ok)
+	}
+	if _, ok := thresholds.lookup(echonet_lite.SingleFunctionLighting_ClassCode, 0xBB); ok {
+		t.Error("0130:BB should not apply to another class")
+	}
+
+	for _, config := range []map[string]float64{{"8": 1}, {"0130:XY": 1}, {"130:BB": 1}, {"84": 0}, {"84": -1}} {
+		if _, err := ParseChangeThresholds(config); err == nil {
+			t.Errorf("expected an error for %v", config)
+		}
+	}
+}
+
+func TestChangeThresholdFilter(t *testing.T) {
+	thresholds, err := ParseChangeThresholds(map[string]float64{"0130:BB": 2, "80": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter := newChangeThresholdFilter(thresholds)
+	aircon := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	other := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.11"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	temperature := func(value byte) echonet_lite.Property {
+		return echonet_lite.Property{EPC: 0xBB, EDT: []byte{value}}
+	}
+
+	steps := []struct {
+		device handler.IPAndEOJ
+		value  byte
+		want   bool
+	}{
+		{aircon, 20, true},  // The first value is always reported
+		{aircon, 21, false}, // 1℃ is below the threshold
+		{aircon, 22, true},  // 2℃ from the last reported value
+		{aircon, 21, false},
+		{aircon, 23, false}, // Compared with 22, not with the previous 21
+		{aircon, 24, true},  // A slow drift is reported once it adds up to the threshold
+		{other, 25, true},   // Each device is tracked separately
+	}
+	for i, step := range steps {
+		if got := filter.Allow(step.device, temperature(step.value)); got != step.want {
+			t.Errorf("step %d (%d℃): Allow = %v, want %v", i, step.value, got, step.want)
+		}
+	}
+
+	// Values without a number and properties without a threshold are always allowed
+	for i := 0; i < 2; i++ {
+		if !filter.Allow(aircon, echonet_lite.Property{EPC: 0x80, EDT: []byte{0x30}}) {
+			t.Error("non-numeric property was dropped")
+		}
+		if !filter.Allow(aircon, echonet_lite.Property{EPC: 0xB3, EDT: []byte{25}}) {
+			t.Error("property without a threshold was dropped")
+		}
+	}
+}
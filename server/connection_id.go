@@ -0,0 +1,51 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// IDGenerator generates the IDs that identify client connections in logs and audit records.
+// It must return a different ID on every call; tests can inject a deterministic one.
+type IDGenerator func() string
+
+// crockfordBase32 is the alphabet of ULIDs (no I, L, O and U to avoid confusion)
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID returns a ULID: a 48-bit millisecond timestamp followed by 80 random bits,
+// encoded as 26 characters. ULIDs sort in the order they were generated (to the millisecond),
+// so the connections in a log can be ordered by ID.
+func NewULID() string {
+	return newULID(time.Now())
+}
+
+func newULID(t time.Time) string {
+	var id [16]byte
+	ms := uint64(t.UnixMilli())
+	id[0] = byte(ms >> 40)
+	id[1] = byte(ms >> 32)
+	binary.BigEndian.PutUint32(id[2:6], uint32(ms))
+	_, _ = rand.Read(id[6:]) // crypto/rand.Read never returns an error
+
+	// 128 bits are encoded 5 bits at a time from the most significant bit; the first character holds 3 bits
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordBase32[lo&0x1F]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// NewUUID returns a random (version 4) UUID
+func NewUUID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	id[6] = id[6]&0x0F | 0x40 // version 4
+	id[8] = id[8]&0x3F | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestNewULID(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`)
+	id := NewULID()
+	if !pattern.MatchString(id) {
+		t.Fatalf("invalid ULID: %q", id)
+	}
+	if NewULID() == id {
+		t.Error("two ULIDs are equal")
+	}
+
+	// The timestamp part sorts by time
+	earlier := newULID(time.UnixMilli(1_700_000_000_000))
+	later := newULID(time.UnixMilli(1_700_000_000_001))
+	if earlier[:10] >= later[:10] {
+		t.Errorf("ULIDs do not sort by time: %s >= %s", earlier, later)
+	}
+	// 0 ms is all zeros in the timestamp part
+	if got := newULID(time.UnixMilli(0))[:10]; got != "0000000000" {
+		t.Errorf("timestamp of epoch = %q", got)
+	}
+}
+
+func TestNewUUID(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	id := NewUUID()
+	if !pattern.MatchString(id) {
+		t.Fatalf("invalid UUID: %q", id)
+	}
+	if NewUUID() == id {
+		t.Error("two UUIDs are equal")
+	}
+}
+
+// TestConnectionInfo verifies that the injected generator names connections
+// and that their metadata can be looked up while and after they are connected
+func TestConnectionInfo(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	transport := NewDefaultWebSocketTransport(ctx, ":0")
+	transport.SetIDGenerator(func() string { return "conn-1" })
+	connected := make(chan string, 1)
+	disconnected := make(chan string, 1)
+	transport.SetConnectHandler(func(connID string) error {
+		connected <- connID
+		return nil
+	})
+	transport.SetDisconnectHandler(func(connID string) {
+		disconnected <- connID
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(transport.handleWebSocket))
+	defer server.Close()
+
+	header := http.Header{"User-Agent": []string{"echonet-test"}}
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	select {
+	case connID := <-connected:
+		if connID != "conn-1" {
+			t.Fatalf("connID = %q, want the generated ID", connID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Connect handler was not called")
+	}
+
+	info, ok := transport.ConnectionInfo("conn-1")
+	if !ok {
+		t.Fatal("ConnectionInfo not found for a connected client")
+	}
+	if info.UserAgent != "echonet-test" || info.RemoteAddr == "" || info.ConnectedAt.IsZero() || !info.DisconnectedAt.IsZero() {
+		t.Errorf("unexpected connection info: %+v", info)
+	}
+
+	conn.Close()
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("Disconnect handler was not called")
+	}
+
+	info, ok = transport.ConnectionInfo("conn-1")
+	if !ok {
+		t.Fatal("ConnectionInfo not found after the client disconnected")
+	}
+	if info.DisconnectedAt.IsZero() {
+		t.Errorf("DisconnectedAt is not set: %+v", info)
+	}
+	if _, ok := transport.ConnectionInfo("unknown"); ok {
+		t.Error("ConnectionInfo found an unknown connection")
+	}
+}
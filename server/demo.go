@@ -0,0 +1,33 @@
+package server
+
+import (
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/echonet_lite/simulator"
+	"time"
+)
+
+const (
+	// DemoSensorInterval はデモモードでセンサー値を更新する間隔
+	DemoSensorInterval = time.Minute
+	// demoHistoryPeriod はデモモードの起動時に生成する履歴の期間
+	demoHistoryPeriod = 24 * time.Hour
+	// demoHistoryStep は生成する履歴の時間間隔
+	demoHistoryStep = 10 * time.Minute
+)
+
+// seedDemoHistory は、模擬デバイスのセンサー値の過去の推移を履歴に記録する
+func seedDemoHistory(h *handler.ECHONETLiteHandler, demoNetwork *simulator.Network, now time.Time) {
+	data := h.GetDataManagementHandler()
+	if data == nil || data.DeviceHistory == nil {
+		return
+	}
+	for _, sample := range demoNetwork.History(now.Add(-demoHistoryPeriod), now, demoHistoryStep) {
+		data.DeviceHistory.Record(handler.DeviceHistoryEntry{
+			Timestamp: sample.Time.UTC(),
+			Device:    sample.Device,
+			EPC:       sample.Property.EPC,
+			Value:     handler.PropertyValueFromEDT(sample.Property.EDT, sample.Property.EPC, sample.Device.EOJ.ClassCode()),
+			Origin:    handler.HistoryOriginNotification,
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"echonet-list/config"
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+)
+
+func TestNewServer_DemoMode(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	cfg := config.NewConfig()
+	cfg.Demo.Enabled = true
+	cfg.Network.MonitorEnabled = false
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, err := NewServer(ctx, cfg)
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	closed := false
+	defer func() {
+		if !closed {
+			_ = s.Close()
+		}
+	}()
+	h := s.GetHandler()
+
+	aircon := echonet_lite.IPAndEOJ{IP: net.ParseIP("192.0.2.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+
+	// Discovery runs in the background; wait until the simulated devices and their properties are known
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if _, ok := h.GetDataManagementHandler().GetProperty(aircon, echonet_lite.EPC_HAC_CurrentRoomTemperature); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("simulated devices were not discovered: %v", h.ListDevices(handler.FilterCriteria{}))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// Setting a property goes through the simulated network
+	result, err := h.SetProperties(aircon, echonet_lite.Properties{{EPC: echonet_lite.EPC_HAC_TemperatureSetting, EDT: []byte{22}}}, nil)
+	if err != nil || len(result.Properties) != 1 {
+		t.Fatalf("SetProperties failed: %v %+v", err, result)
+	}
+	got, err := h.GetProperties(aircon, []echonet_lite.EPCType{echonet_lite.EPC_HAC_TemperatureSetting}, false)
+	if err != nil || len(got.Properties) != 1 || got.Properties[0].EDT[0] != 22 {
+		t.Fatalf("GetProperties after set = %+v, %v", got, err)
+	}
+
+	// The history is seeded with the past sensor values
+	entries := h.GetDataManagementHandler().DeviceHistory.Query(aircon, handler.HistoryQuery{})
+	if len(entries) == 0 {
+		t.Error("expected generated history for the air conditioner")
+	}
+
+	// No data files are written in demo mode
+	closed = true
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 0 {
+		t.Errorf("demo mode wrote files: %v", files)
+	}
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// eventStreamQueueSize is the number of events queued for each SSE subscriber.
+// A subscriber that falls further behind is disconnected; EventSource clients reconnect by themselves.
+const eventStreamQueueSize = sendQueueSize
+
+// eventPollBufferSize is the number of recent events kept for GET /api/poll.
+const eventPollBufferSize = 1024
+
+// streamEvent is a notification message as broadcast to WebSocket clients.
+type streamEvent struct {
+	seq     uint64
+	msgType protocol.MessageType
+	data    []byte
+	device  *handler.IPAndEOJ // the device the message is about (nil if not about a device)
+}
+
+// eventSubscriber is one GET /api/events connection.
+type eventSubscriber struct {
+	rule  *AccessRule                   // nil when access control is disabled
+	types map[protocol.MessageType]bool // nil means all message types
+	ch    chan streamEvent
+	done  chan struct{} // closed when the subscriber is dropped
+	once  sync.Once
+}
+
+func (s *eventSubscriber) drop() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// eventStream mirrors the WebSocket notification broadcasts to Server-Sent Events subscribers,
+// and keeps the recent events numbered by sequence for long-polling clients.
+type eventStream struct {
+	ctx         context.Context
+	mu          sync.Mutex
+	subscribers map[*eventSubscriber]struct{}
+	seq         uint64        // sequence number of the last event
+	recent      []streamEvent // ring buffer of the last eventPollBufferSize events
+	published   chan struct{} // closed and replaced when an event is published
+}
+
+func newEventStream(ctx context.Context) *eventStream {
+	return &eventStream{
+		ctx:         ctx,
+		subscribers: make(map[*eventSubscriber]struct{}),
+		recent:      make([]streamEvent, 0, eventPollBufferSize),
+		published:   make(chan struct{}),
+	}
+}
+
+func (e *eventStream) subscribe(rule *AccessRule, types map[protocol.MessageType]bool) *eventSubscriber {
+	s := &eventSubscriber{
+		rule:  rule,
+		types: types,
+		ch:    make(chan streamEvent, eventStreamQueueSize),
+		done:  make(chan struct{}),
+	}
+	e.mu.Lock()
+	e.subscribers[s] = struct{}{}
+	e.mu.Unlock()
+	return s
+}
+
+func (e *eventStream) unsubscribe(s *eventSubscriber) {
+	e.mu.Lock()
+	delete(e.subscribers, s)
+	e.mu.Unlock()
+	s.drop()
+}
+
+// publish queues a message for the subscribers that want its type.
+// When device is given, only the subscribers allowed to read the device receive it.
+func (e *eventStream) publish(msgType protocol.MessageType, data []byte, device *handler.IPAndEOJ, resolver accessResolver) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.seq++
+	ev := streamEvent{seq: e.seq, msgType: msgType, data: data, device: device}
+	if len(e.recent) < eventPollBufferSize {
+		e.recent = append(e.recent, ev)
+	} else {
+		e.recent[int((e.seq-1)%eventPollBufferSize)] = ev
+	}
+	close(e.published)
+	e.published = make(chan struct{})
+
+	for s := range e.subscribers {
+		if s.types != nil && !s.types[msgType] {
+			continue
+		}
+		if device != nil && !s.rule.CanRead(resolver, *device) {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			// Same as the disconnect policy of WebSocket clients: a slow subscriber must not hold up the others
+			slog.Warn("Dropping slow event stream subscriber", "queueSize", cap(s.ch))
+			delete(e.subscribers, s)
+			s.drop()
+		}
+	}
+}
+
+// lastSeq returns the sequence number of the last event.
+func (e *eventStream) lastSeq() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.seq
+}
+
+// since returns the events after the sequence number, oldest first, and a channel closed at the next publish.
+// missed is true when events after seq have already been dropped from the buffer.
+func (e *eventStream) since(seq uint64) (events []streamEvent, last uint64, missed bool, published <-chan struct{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if seq > e.seq {
+		// The server restarted since the client's last poll
+		return nil, e.seq, true, e.published
+	}
+	oldest := e.seq - uint64(len(e.recent)) + 1
+	if seq+1 < oldest {
+		missed = true
+		seq = oldest - 1
+	}
+	for n := seq + 1; n <= e.seq; n++ {
+		events = append(events, e.recent[int((n-1)%eventPollBufferSize)])
+	}
+	return events, e.seq, missed, e.published
+}
+
+// parseEventTypes parses the ?types= filter of GET /api/events, e.g. "property_changed,device_online".
+func parseEventTypes(values []string) map[protocol.MessageType]bool {
+	var types map[protocol.MessageType]bool
+	for _, value := range values {
+		for _, t := range strings.Split(value, ",") {
+			if t = strings.TrimSpace(t); t == "" {
+				continue
+			}
+			if types == nil {
+				types = make(map[protocol.MessageType]bool)
+			}
+			types[protocol.MessageType(t)] = true
+		}
+	}
+	return types
+}
+
+// handleEvents streams the WebSocket notifications (property_changed, device_added, server_heartbeat, ...)
+// as Server-Sent Events, for clients behind proxies that do not pass WebSockets and for curl users.
+// Each event is named after the message type and its data is the same JSON message a WebSocket client receives.
+func (a *RESTAPIHandler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	rule, ok := a.authenticate(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeRESTError(w, http.StatusInternalServerError, protocol.ErrorCodeInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	s := a.events.subscribe(rule, parseEventTypes(r.URL.Query()["types"]))
+	defer a.events.unsubscribe(s)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Ask reverse proxies such as nginx not to buffer the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-a.events.ctx.Done():
+			return
+		case <-s.done:
+			return
+		case ev := <-s.ch:
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.seq, ev.msgType, ev.data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"echonet-list/protocol"
+)
+
+// newEventTestServer は、アクセス制御付きでイベントを配信する REST API のテストサーバーを作る
+func newEventTestServer(t *testing.T, ctx context.Context) (*WebSocketServer, *httptest.Server) {
+	t.Helper()
+	c := newAccessTestClient()
+	ws := &WebSocketServer{
+		ctx:           ctx,
+		echonetClient: c,
+		transport:     NewDefaultWebSocketTransport(ctx, ":0"),
+		access:        newTestAccessControl(t),
+		events:        newEventStream(ctx),
+	}
+	api := NewRESTAPIHandler(c)
+	api.access = ws.access
+	api.events = ws.events
+	mux := http.NewServeMux()
+	api.Register(mux)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return ws, srv
+}
+
+func TestRESTAPI_Events(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ws, srv := newEventTestServer(t, ctx)
+
+	resp, err := http.Get(srv.URL + "/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + "/api/events?token=guest-token&types=property_changed,alias_changed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() (string, protocol.Message) {
+		t.Helper()
+		var name string
+		var msg protocol.Message
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("stream ended: %v", err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "" && name != "":
+				return name, msg
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &msg); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+	// 購読の開始を待つ
+	if line, _ := reader.ReadString('\n'); line != ": connected\n" {
+		t.Fatalf("unexpected first line: %q", line)
+	}
+
+	changed := func(ip string) protocol.PropertyChangedPayload {
+		return protocol.PropertyChangedPayload{IP: ip, EPC: "80", Value: protocol.PropertyData{String: "on"}}
+	}
+	// guest は 192.168.1.10 を読めない、server_heartbeat は types に含まれない
+	_ = ws.broadcastDeviceMessageToClients(accessAircon, protocol.MessageTypePropertyChanged, changed("192.168.1.10"))
+	_ = ws.broadcastMessageToClients(protocol.MessageTypeServerHeartbeat, protocol.ServerHeartbeatPayload{})
+	_ = ws.broadcastDeviceMessageToClients(accessLight, protocol.MessageTypePropertyChanged, changed("192.168.1.11"))
+	_ = ws.broadcastMessageToClients(protocol.MessageTypeAliasChanged, protocol.AliasChangedPayload{Alias: "light"})
+
+	name, msg := readEvent()
+	var payload protocol.PropertyChangedPayload
+	if err := protocol.ParsePayload(&msg, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if name != "property_changed" || msg.Type != protocol.MessageTypePropertyChanged || payload.IP != "192.168.1.11" {
+		t.Errorf("unexpected event: %s %+v", name, msg)
+	}
+	if name, msg := readEvent(); name != "alias_changed" || msg.Type != protocol.MessageTypeAliasChanged {
+		t.Errorf("unexpected event: %s %+v", name, msg)
+	}
+
+	// サーバーの停止でストリームも終わる
+	cancel()
+	if _, err := io.ReadAll(reader); err != nil {
+		t.Errorf("stream did not end cleanly: %v", err)
+	}
+}
+
+func TestRESTAPI_Poll(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ws, srv := newEventTestServer(t, ctx)
+
+	poll := func(query string) pollResponse {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/api/poll?token=guest-token&" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body pollResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status %d: %+v", resp.StatusCode, body)
+		}
+		return body
+	}
+	changed := protocol.PropertyChangedPayload{EPC: "80", Value: protocol.PropertyData{String: "on"}}
+
+	// since がなければ現在の番号だけをすぐに返す
+	if got := poll(""); got.Seq != 0 || len(got.Events) != 0 {
+		t.Errorf("unexpected initial poll: %+v", got)
+	}
+
+	// guest は 192.168.1.10 を読めない
+	_ = ws.broadcastDeviceMessageToClients(accessAircon, protocol.MessageTypePropertyChanged, changed)
+	_ = ws.broadcastDeviceMessageToClients(accessLight, protocol.MessageTypePropertyChanged, changed)
+	got := poll("since=0")
+	if got.Seq != 2 || got.Missed || len(got.Events) != 1 || got.Events[0].Seq != 2 || got.Events[0].Type != protocol.MessageTypePropertyChanged {
+		t.Errorf("unexpected poll: %+v", got)
+	}
+
+	// 新しいイベントが来るまで待つ
+	result := make(chan pollResponse, 1)
+	go func() { result <- poll("since=2&timeout=10&types=alias_changed") }()
+	_ = ws.broadcastMessageToClients(protocol.MessageTypeServerHeartbeat, protocol.ServerHeartbeatPayload{})
+	_ = ws.broadcastMessageToClients(protocol.MessageTypeAliasChanged, protocol.AliasChangedPayload{Alias: "light"})
+	select {
+	case got := <-result:
+		if got.Seq != 4 || len(got.Events) != 1 || got.Events[0].Type != protocol.MessageTypeAliasChanged {
+			t.Errorf("unexpected long poll: %+v", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("long poll did not return")
+	}
+
+	// タイムアウトしたら空の結果を返す
+	if got := poll("since=4&timeout=0"); got.Seq != 4 || len(got.Events) != 0 || got.Missed {
+		t.Errorf("unexpected poll after timeout: %+v", got)
+	}
+
+	// 取りこぼしとサーバーの再起動
+	for i := 0; i < eventPollBufferSize; i++ {
+		_ = ws.broadcastMessageToClients(protocol.MessageTypeServerHeartbeat, protocol.ServerHeartbeatPayload{})
+	}
+	if got := poll("since=2&timeout=0"); !got.Missed || len(got.Events) != eventPollBufferSize {
+		t.Errorf("expected missed events, got missed=%v with %d events", got.Missed, len(got.Events))
+	}
+	if got := poll("since=100000&timeout=0"); !got.Missed || got.Seq != 4+eventPollBufferSize {
+		t.Errorf("expected missed after restart, got %+v", got.Missed)
+	}
+
+	resp, err := http.Get(srv.URL + "/api/poll?token=guest-token&since=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid since, got %d", resp.StatusCode)
+	}
+}
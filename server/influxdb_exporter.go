@@ -0,0 +1,294 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+const (
+	// DefaultInfluxDBMeasurement is the measurement name when InfluxDBOptions.Measurement is empty
+	DefaultInfluxDBMeasurement = "echonet"
+	// influxFlushInterval is how often queued points are written
+	influxFlushInterval = time.Second
+	// influxQueueSize limits the points waiting to be written; newer points are dropped while the database is unreachable
+	influxQueueSize = 10000
+	// influxUDPPacketSize keeps UDP packets below a typical MTU
+	influxUDPPacketSize = 1400
+	// influxWriteTimeout limits how long one HTTP write may take
+	influxWriteTimeout = 10 * time.Second
+)
+
+// InfluxDBOptions configures the exporter of numeric property values in InfluxDB line protocol.
+// Points are written to the HTTP API of InfluxDB 2.x (also served by 1.8 and later) when URL is set,
+// and sent as UDP packets (e.g. to Telegraf or the UDP listener of InfluxDB 1.x) when UDPAddr is set.
+type InfluxDBOptions struct {
+	URL         string        // base URL of InfluxDB, e.g. "http://localhost:8086"
+	Org         string        // organization (ignored by InfluxDB 1.x)
+	Bucket      string        // bucket, or "database/retention-policy" for InfluxDB 1.x
+	Token       string        // API token, or "user:password" for InfluxDB 1.x
+	UDPAddr     string        // host:port to send line protocol over UDP
+	Measurement string        // measurement name (empty for DefaultInfluxDBMeasurement)
+	Interval    time.Duration // writes every numeric property of every device at this interval (0 writes changes only)
+}
+
+// Validate checks that the options name at least one destination
+func (o InfluxDBOptions) Validate() error {
+	if o.URL == "" && o.UDPAddr == "" {
+		return fmt.Errorf("influxdb needs url or udp_addr")
+	}
+	if o.URL != "" {
+		if u, err := url.Parse(o.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid influxdb url: %q", o.URL)
+		}
+		if o.Bucket == "" {
+			return fmt.Errorf("influxdb url needs a bucket")
+		}
+	}
+	if o.UDPAddr != "" {
+		if _, _, err := net.SplitHostPort(o.UDPAddr); err != nil {
+			return fmt.Errorf("invalid influxdb udp_addr: %q", o.UDPAddr)
+		}
+	}
+	if o.Interval < 0 {
+		return fmt.Errorf("influxdb interval must not be negative: %v", o.Interval)
+	}
+	return nil
+}
+
+func (o InfluxDBOptions) measurement() string {
+	if o.Measurement == "" {
+		return DefaultInfluxDBMeasurement
+	}
+	return o.Measurement
+}
+
+// influxTagEscaper escapes tag keys and values of the line protocol
+var influxTagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// influxMeasurementEscaper escapes measurement names of the line protocol
+var influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+
+// influxLine formats a numeric property value as a line of the line protocol, e.g.
+// `echonet,ip=192.168.1.10,eoj=0130:1,epc=BB,property=Current\ room\ temperature,alias=living value=25 1700000000000000000`.
+// It returns false when the value is not a number.
+func influxLine(measurement string, device handler.IPAndEOJ, alias string, property echonet_lite.Property, t time.Time) (string, bool) {
+	classCode := device.EOJ.ClassCode()
+	number := protocol.MakePropertyData(classCode, property).Number
+	if number == nil {
+		return "", false
+	}
+
+	var b strings.Builder
+	b.WriteString(influxMeasurementEscaper.Replace(measurement))
+	b.WriteString(",ip=" + influxTagEscaper.Replace(device.IP.String()))
+	b.WriteString(",eoj=" + influxTagEscaper.Replace(device.EOJ.Specifier()))
+	fmt.Fprintf(&b, ",epc=%02X", byte(property.EPC))
+	if desc, ok := echonet_lite.GetPropertyDesc(classCode, property.EPC); ok && desc.Name != "" {
+		b.WriteString(",property=" + influxTagEscaper.Replace(desc.Name))
+	}
+	if alias != "" {
+		b.WriteString(",alias=" + influxTagEscaper.Replace(alias))
+	}
+	b.WriteString(" value=" + strconv.Itoa(*number))
+	b.WriteString(" " + strconv.FormatInt(t.UnixNano(), 10))
+	return b.String(), true
+}
+
+// influxExporter queues points and writes them in batches, so that a slow database does not delay notifications
+type influxExporter struct {
+	opts   InfluxDBOptions
+	client *http.Client
+	queue  chan string
+	mu     sync.Mutex // protects udp
+	udp    net.Conn
+}
+
+func newInfluxExporter(opts InfluxDBOptions) *influxExporter {
+	return &influxExporter{
+		opts:   opts,
+		client: &http.Client{Timeout: influxWriteTimeout},
+		queue:  make(chan string, influxQueueSize),
+	}
+}
+
+// Add queues a point, dropping it when the queue is full
+func (e *influxExporter) Add(line string) {
+	select {
+	case e.queue <- line:
+	default:
+		slog.Debug("InfluxDB queue is full, dropping a point")
+	}
+}
+
+// run writes the queued points every influxFlushInterval until ctx is done
+func (e *influxExporter) run(ctx context.Context) {
+	ticker := time.NewTicker(influxFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.flush(ctx)
+		case <-ctx.Done():
+			e.mu.Lock()
+			if e.udp != nil {
+				e.udp.Close()
+			}
+			e.mu.Unlock()
+			return
+		}
+	}
+}
+
+// flush writes the points queued so far
+func (e *influxExporter) flush(ctx context.Context) {
+	var lines []string
+drain:
+	for len(lines) < influxQueueSize {
+		select {
+		case line := <-e.queue:
+			lines = append(lines, line)
+		default:
+			break drain
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	if e.opts.URL != "" {
+		if err := e.writeHTTP(ctx, lines); err != nil {
+			// Repeats on every flush while InfluxDB is down, so keep it out of the client notifications
+			slog.Warn("Failed to write to InfluxDB", "err", err, "points", len(lines), NoBroadcast())
+		}
+	}
+	if e.opts.UDPAddr != "" {
+		if err := e.writeUDP(lines); err != nil {
+			slog.Warn("Failed to send line protocol over UDP", "err", err, "points", len(lines), NoBroadcast())
+		}
+	}
+}
+
+// writeHTTP writes points with the /api/v2/write endpoint
+func (e *influxExporter) writeHTTP(ctx context.Context, lines []string) error {
+	query := url.Values{"bucket": {e.opts.Bucket}, "precision": {"ns"}}
+	if e.opts.Org != "" {
+		query.Set("org", e.opts.Org)
+	}
+	endpoint := strings.TrimSuffix(e.opts.URL, "/") + "/api/v2/write?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if e.opts.Token != "" {
+		req.Header.Set("Authorization", "Token "+e.opts.Token)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("InfluxDB returned %s", resp.Status)
+	}
+	return nil
+}
+
+// writeUDP sends points in packets of up to influxUDPPacketSize bytes
+func (e *influxExporter) writeUDP(lines []string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.udp == nil {
+		conn, err := net.Dial("udp", e.opts.UDPAddr)
+		if err != nil {
+			return err
+		}
+		e.udp = conn
+	}
+
+	var packet bytes.Buffer
+	send := func() error {
+		if packet.Len() == 0 {
+			return nil
+		}
+		_, err := e.udp.Write(packet.Bytes())
+		packet.Reset()
+		return err
+	}
+	for _, line := range lines {
+		if packet.Len() > 0 && packet.Len()+len(line)+1 > influxUDPPacketSize {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+		packet.WriteString(line)
+		packet.WriteByte('\n')
+	}
+	return send()
+}
+
+// exportPropertyChange queues the value of a changed property for InfluxDB
+func (ws *WebSocketServer) exportPropertyChange(device handler.IPAndEOJ, property echonet_lite.Property) {
+	if ws.influx == nil {
+		return
+	}
+	if line, ok := influxLine(ws.influx.opts.measurement(), device, ws.firstAlias(device), property, time.Now()); ok {
+		ws.influx.Add(line)
+	}
+}
+
+// exportAllProperties queues the numeric properties of every device, so that constant values still appear in dashboards
+func (ws *WebSocketServer) exportAllProperties(now time.Time) {
+	if ws.echonetClient == nil {
+		return
+	}
+	measurement := ws.influx.opts.measurement()
+	for _, device := range ws.echonetClient.ListDevices(handler.FilterCriteria{}) {
+		if ws.handler != nil && ws.handler.IsOffline(device.Device) {
+			continue
+		}
+		alias := ws.firstAlias(device.Device)
+		for _, property := range device.Properties {
+			if line, ok := influxLine(measurement, device.Device, alias, property, now); ok {
+				ws.influx.Add(line)
+			}
+		}
+	}
+}
+
+// runInfluxSnapshots writes every numeric property at the configured interval
+func (ws *WebSocketServer) runInfluxSnapshots(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			ws.exportAllProperties(now)
+		case <-ws.ctx.Done():
+			return
+		}
+	}
+}
+
+// firstAlias returns the first alias of a device, or "" when it has none
+func (ws *WebSocketServer) firstAlias(device handler.IPAndEOJ) string {
+	if ws.echonetClient == nil {
+		return ""
+	}
+	if aliases := ws.echonetClient.GetAliases(device); len(aliases) > 0 {
+		return aliases[0]
+	}
+	return ""
+}
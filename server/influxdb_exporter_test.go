@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+)
+
+func TestInfluxLine(t *testing.T) {
+	aircon := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	ts := time.Unix(1700000000, 0)
+
+	line, ok := influxLine("echonet", aircon, "living room", echonet_lite.Property{EPC: 0xBB, EDT: []byte{25}}, ts)
+	want := `echonet,ip=192.168.1.10,eoj=0130:1,epc=BB,property=Current\ room\ temperature,alias=living\ room value=25 1700000000000000000`
+	if !ok || line != want {
+		t.Errorf("unexpected line:\n got %s\nwant %s", line, want)
+	}
+
+	// Values that are not numbers are not exported
+	if _, ok := influxLine("echonet", aircon, "", echonet_lite.Property{EPC: 0x80, EDT: []byte{0x30}}, ts); ok {
+		t.Error("expected no line for the operation status")
+	}
+}
+
+func TestInfluxDBOptionsValidate(t *testing.T) {
+	for _, opts := range []InfluxDBOptions{
+		{URL: "http://localhost:8086", Bucket: "echonet"},
+		{UDPAddr: "localhost:8089"},
+	} {
+		if err := opts.Validate(); err != nil {
+			t.Errorf("%+v: unexpected error: %v", opts, err)
+		}
+	}
+	for _, opts := range []InfluxDBOptions{
+		{},
+		{URL: "http://localhost:8086"},
+		{URL: "localhost:8086", Bucket: "echonet"},
+		{UDPAddr: "localhost"},
+		{UDPAddr: "localhost:8089", Interval: -time.Second},
+	} {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+}
+
+func TestInfluxExporterFlush(t *testing.T) {
+	var gotQuery, gotAuth, gotBody string
+	db := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Path + "?" + r.URL.RawQuery
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer db.Close()
+
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+
+	e := newInfluxExporter(InfluxDBOptions{URL: db.URL, Org: "home", Bucket: "echonet", Token: "secret", UDPAddr: udp.LocalAddr().String()})
+	e.Add("echonet,epc=BB value=25 1")
+	e.Add("echonet,epc=BA value=50 1")
+	e.flush(context.Background())
+
+	if gotQuery != "/api/v2/write?bucket=echonet&org=home&precision=ns" || gotAuth != "Token secret" {
+		t.Errorf("unexpected request: %s %s", gotQuery, gotAuth)
+	}
+	if gotBody != "echonet,epc=BB value=25 1\nechonet,epc=BA value=50 1" {
+		t.Errorf("unexpected body: %q", gotBody)
+	}
+
+	buf := make([]byte, 2048)
+	_ = udp.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := udp.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "echonet,epc=BB value=25 1\nechonet,epc=BA value=50 1\n" {
+		t.Errorf("unexpected UDP packet: %q", got)
+	}
+	e.udp.Close()
+}
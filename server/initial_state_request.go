@@ -0,0 +1,86 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// initialStateRequest is the part of the initial state a client asked for with query parameters of the WebSocket URL,
+// so that a lightweight client such as a wall panel showing two devices does not receive the whole state:
+// ?initial=devices,aliases selects the parts and ?devices=living-aircon,@panel limits the devices.
+// ?encoding=compact sends the devices in the compact encoding, which is about half the size for large installs.
+type initialStateRequest struct {
+	parts   []protocol.InitialStatePart // nil for all parts
+	devices []string                    // device patterns as in AccessRule, empty for all readable devices
+	compact bool                        // send protocol.CompactInitialStatePayload
+}
+
+// parseInitialStateRequest parses the initial, devices and encoding query parameters
+func parseInitialStateRequest(query url.Values) (initialStateRequest, error) {
+	parts, err := protocol.ParseInitialStateParts(query.Get("initial"))
+	if err != nil {
+		return initialStateRequest{}, err
+	}
+	request := initialStateRequest{parts: parts}
+	switch encoding := query.Get("encoding"); encoding {
+	case "":
+	case protocol.EncodingCompact:
+		request.compact = true
+	default:
+		return initialStateRequest{}, fmt.Errorf("unknown encoding: %q (compact)", encoding)
+	}
+	if devices := query.Get("devices"); devices != "" {
+		for _, pattern := range strings.Split(devices, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				request.devices = append(request.devices, pattern)
+			}
+		}
+		if len(request.devices) == 0 {
+			return initialStateRequest{}, fmt.Errorf("no device in devices parameter: %q", devices)
+		}
+	}
+	return request, nil
+}
+
+// isPartial reports whether some parts are left out
+func (r initialStateRequest) isPartial() bool {
+	return r.parts != nil
+}
+
+// skip reports whether the client asked for no initial_state message
+func (r initialStateRequest) skip() bool {
+	return r.parts != nil && len(r.parts) == 0
+}
+
+// includes reports whether a part is sent
+func (r initialStateRequest) includes(part protocol.InitialStatePart) bool {
+	return r.parts == nil || slices.Contains(r.parts, part)
+}
+
+// includesDevice reports whether a device matches the requested device patterns
+func (r initialStateRequest) includesDevice(resolver accessResolver, device handler.IPAndEOJ) bool {
+	if len(r.devices) == 0 {
+		return true
+	}
+	for _, pattern := range r.devices {
+		if matchesPattern(resolver, pattern, device) {
+			return true
+		}
+	}
+	return false
+}
+
+// initialStateRequestForConnection returns the initial state requested in the URL of a connection
+func (ws *WebSocketServer) initialStateRequestForConnection(connID string) (initialStateRequest, error) {
+	if transport, ok := ws.transport.(*DefaultWebSocketTransport); ok {
+		if query, ok := transport.Query(connID); ok {
+			return parseInitialStateRequest(query)
+		}
+	}
+	return initialStateRequest{}, nil
+}
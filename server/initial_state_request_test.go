@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/url"
+	"testing"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// initialStateTestClient has an air conditioner, a light and one alias
+type initialStateTestClient struct {
+	restTestClient
+}
+
+func (c *initialStateTestClient) AliasList() []handler.AliasIDStringPair {
+	return []handler.AliasIDStringPair{{Alias: "aircon", ID: "013001:00000B:0123"}}
+}
+
+// sentMessageTransport records the messages sent to clients
+type sentMessageTransport struct {
+	mockLocationTransport
+	sent [][]byte
+}
+
+func (m *sentMessageTransport) SendMessage(_ string, message []byte) error {
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+func TestParseInitialStateRequest(t *testing.T) {
+	all, err := parseInitialStateRequest(url.Values{})
+	if err != nil || all.isPartial() || all.skip() || !all.includes(protocol.InitialStateGroups) {
+		t.Errorf("expected all parts without parameters, got %+v %v", all, err)
+	}
+
+	request, err := parseInitialStateRequest(url.Values{"initial": {"devices, aliases"}, "devices": {"192.168.1.10 0130:1, 192.168.1.20"}})
+	if err != nil {
+		t.Fatalf("parseInitialStateRequest returned error: %v", err)
+	}
+	if !request.isPartial() || !request.includes(protocol.InitialStateAliases) || request.includes(protocol.InitialStateGroups) {
+		t.Errorf("unexpected parts: %v", request.parts)
+	}
+	aircon := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	light := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.11"), EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)}
+	if !request.includesDevice(nil, aircon) || request.includesDevice(nil, light) {
+		t.Errorf("unexpected device filter: %v", request.devices)
+	}
+
+	none, err := parseInitialStateRequest(url.Values{"initial": {"none"}})
+	if err != nil || !none.skip() {
+		t.Errorf("expected no initial state for none, got %+v %v", none, err)
+	}
+
+	compact, err := parseInitialStateRequest(url.Values{"encoding": {"compact"}})
+	if err != nil || !compact.compact || compact.isPartial() {
+		t.Errorf("expected the compact encoding of all parts, got %+v %v", compact, err)
+	}
+
+	for _, query := range []url.Values{{"initial": {"devices,scenes"}}, {"devices": {" , "}}, {"encoding": {"msgpack"}}} {
+		if _, err := parseInitialStateRequest(query); err == nil {
+			t.Errorf("expected an error for %v", query)
+		}
+	}
+}
+
+func TestGenerateAndSendInitialState_Partial(t *testing.T) {
+	ctx := context.Background()
+	handlerInstance, err := handler.NewECHONETLiteHandler(ctx, handler.ECHONETLieHandlerOptions{TestMode: true, InMemory: true})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handlerInstance.Close()
+
+	c := &initialStateTestClient{}
+	c.devices = []handler.DeviceAndProperties{
+		{Device: handler.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}},
+		{Device: handler.IPAndEOJ{IP: net.ParseIP("192.168.1.11"), EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)}},
+	}
+	transport := &sentMessageTransport{}
+	ws := &WebSocketServer{ctx: ctx, handler: handlerInstance, transport: transport, echonetClient: c}
+
+	send := func(query url.Values) protocol.InitialStatePayload {
+		t.Helper()
+		request, err := parseInitialStateRequest(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		transport.sent = nil
+		if err := ws.generateAndSendInitialState("conn", request); err != nil {
+			t.Fatalf("generateAndSendInitialState returned error: %v", err)
+		}
+		if len(transport.sent) != 1 {
+			t.Fatalf("expected one message, got %d", len(transport.sent))
+		}
+		var msg protocol.Message
+		var payload protocol.InitialStatePayload
+		if err := json.Unmarshal(transport.sent[0], &msg); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		return payload
+	}
+
+	full := send(url.Values{})
+	if len(full.Devices) != 2 || len(full.Aliases) != 1 || full.Included != nil {
+		t.Errorf("unexpected full initial state: %+v", full)
+	}
+
+	// Only the devices part, limited to the air conditioner
+	partial := send(url.Values{"initial": {"devices"}, "devices": {"192.168.1.10 0130:1"}})
+	if len(partial.Devices) != 1 || len(partial.Aliases) != 0 {
+		t.Errorf("unexpected partial initial state: %+v", partial)
+	}
+	if len(partial.Included) != 1 || partial.Included[0] != protocol.InitialStateDevices {
+		t.Errorf("unexpected included parts: %v", partial.Included)
+	}
+
+	// Aliases can be fetched separately
+	result := ws.handleGetAliasesFromClient(&protocol.Message{Type: protocol.MessageTypeGetAliases})
+	var aliases map[string]handler.IDString
+	if !result.Success || json.Unmarshal(result.Data, &aliases) != nil || aliases["aircon"] != "013001:00000B:0123" {
+		t.Errorf("unexpected get_aliases result: %+v", result)
+	}
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"echonet-list/protocol"
+)
+
+// logBacklogSize is the number of recent log records kept for subscribe_logs
+const logBacklogSize = 1000
+
+// LogStream keeps the recent log records and sends new ones to the WebSocket clients subscribed with subscribe_logs.
+// It outlives log rotation, so that the backlog covers the records since startup.
+type LogStream struct {
+	mu          sync.Mutex
+	backlog     []protocol.LogEntryPayload // ring buffer of the recent records
+	next        int                        // index of the next record in backlog
+	full        bool                       // backlog has wrapped around
+	levels      []slog.Level               // levels of the records in backlog
+	subscribers map[string]slog.Level      // connID -> minimum level
+	transport   WebSocketTransport
+}
+
+// NewLogStream creates a LogStream that keeps the last size records
+func NewLogStream(size int) *LogStream {
+	if size <= 0 {
+		size = logBacklogSize
+	}
+	return &LogStream{
+		backlog:     make([]protocol.LogEntryPayload, size),
+		levels:      make([]slog.Level, size),
+		subscribers: make(map[string]slog.Level),
+	}
+}
+
+// SetTransport sets the transport used to send log_entry messages to the subscribers
+func (s *LogStream) SetTransport(transport WebSocketTransport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transport = transport
+}
+
+// logEntryFromRecord converts a log record to the payload sent to clients
+func logEntryFromRecord(r slog.Record) protocol.LogEntryPayload {
+	attrs := make(map[string]interface{})
+	r.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = formatAttributeValue(a.Value)
+		return true
+	})
+	return protocol.LogEntryPayload{
+		Level:      r.Level.String(),
+		Message:    r.Message,
+		Time:       r.Time.Format(time.RFC3339),
+		Attributes: attrs,
+	}
+}
+
+// Publish stores a record in the backlog and sends it to the subscribers of its level
+func (s *LogStream) Publish(r slog.Record) {
+	entry := logEntryFromRecord(r)
+
+	s.mu.Lock()
+	s.backlog[s.next] = entry
+	s.levels[s.next] = r.Level
+	s.next = (s.next + 1) % len(s.backlog)
+	if s.next == 0 {
+		s.full = true
+	}
+	var targets []string
+	for connID, level := range s.subscribers {
+		if r.Level >= level {
+			targets = append(targets, connID)
+		}
+	}
+	transport := s.transport
+	s.mu.Unlock()
+
+	if len(targets) == 0 || transport == nil {
+		return
+	}
+	data, err := protocol.CreateMessage(protocol.MessageTypeLogEntry, entry, "")
+	if err != nil {
+		// Cannot log here as it might cause infinite loop
+		return
+	}
+	// Send outside the lock: the transport may log, which publishes again
+	for _, connID := range targets {
+		_ = transport.SendMessage(connID, data)
+	}
+}
+
+// Subscribe starts sending the records at level or above to a client, replacing its previous level.
+// It returns up to backlog recent records at level or above, oldest first;
+// records published after the call are sent as log_entry messages.
+func (s *LogStream) Subscribe(connID string, level slog.Level, backlog int) []protocol.LogEntryPayload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers[connID] = level
+	return s.recent(level, backlog)
+}
+
+// Unsubscribe stops sending records to a client
+func (s *LogStream) Unsubscribe(connID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscribers, connID)
+}
+
+// recent returns up to n recent records at level or above, oldest first. The caller must hold s.mu.
+func (s *LogStream) recent(level slog.Level, n int) []protocol.LogEntryPayload {
+	entries := []protocol.LogEntryPayload{}
+	if n <= 0 {
+		return entries
+	}
+	count := s.next
+	if s.full {
+		count = len(s.backlog)
+	}
+	// Walk back from the newest record, then reverse
+	for i := 1; i <= count && len(entries) < n; i++ {
+		index := (s.next - i + len(s.backlog)) % len(s.backlog)
+		if s.levels[index] >= level {
+			entries = append(entries, s.backlog[index])
+		}
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries
+}
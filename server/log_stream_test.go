@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"echonet-list/protocol"
+)
+
+// logStreamTestTransport records the messages sent to each client
+type logStreamTestTransport struct {
+	mockLocationTransport
+	mu   sync.Mutex
+	sent map[string][]protocol.Message
+}
+
+func (m *logStreamTestTransport) SendMessage(connID string, message []byte) error {
+	var msg protocol.Message
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sent == nil {
+		m.sent = make(map[string][]protocol.Message)
+	}
+	m.sent[connID] = append(m.sent[connID], msg)
+	return nil
+}
+
+func (m *logStreamTestTransport) messages(connID string) []protocol.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]protocol.Message(nil), m.sent[connID]...)
+}
+
+func TestLogStream(t *testing.T) {
+	transport := &logStreamTestTransport{}
+	stream := NewLogStream(3)
+	stream.SetTransport(transport)
+	logger := slog.New(&BroadcastHandler{inner: slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}), stream: stream})
+
+	logger.Debug("one")
+	logger.Info("two", "device", "192.168.1.10 0130:1")
+	logger.Warn("three")
+	logger.Info("four")
+
+	// The backlog keeps the last 3 records, filtered by level, oldest first
+	entries := stream.Subscribe("info", slog.LevelInfo, 10)
+	if len(entries) != 3 || entries[0].Message != "two" || entries[2].Message != "four" {
+		t.Fatalf("unexpected backlog: %+v", entries)
+	}
+	if entries[0].Attributes["device"] != "192.168.1.10 0130:1" {
+		t.Errorf("attributes are missing: %+v", entries[0])
+	}
+	if entries := stream.Subscribe("warn", slog.LevelWarn, 10); len(entries) != 1 || entries[0].Message != "three" {
+		t.Errorf("unexpected backlog at warn: %+v", entries)
+	}
+	if entries := stream.Subscribe("none", slog.LevelError, 0); entries == nil || len(entries) != 0 {
+		t.Errorf("backlog must be empty, not nil: %+v", entries)
+	}
+
+	logger.Info("five")
+	logger.Error("six")
+	stream.Unsubscribe("none")
+	logger.Error("seven")
+
+	count := func(connID string) int { return len(transport.messages(connID)) }
+	if n := count("info"); n != 3 {
+		t.Errorf("info subscriber received %d entries, want 3", n)
+	}
+	if n := count("warn"); n != 2 {
+		t.Errorf("warn subscriber received %d entries, want 2", n)
+	}
+	if n := count("none"); n != 1 {
+		t.Errorf("unsubscribed client received %d entries, want 1", n)
+	}
+	var entry protocol.LogEntryPayload
+	msg := transport.messages("info")[0]
+	if err := json.Unmarshal(msg.Payload, &entry); err != nil || msg.Type != protocol.MessageTypeLogEntry || entry.Message != "five" || entry.Level != "INFO" {
+		t.Errorf("unexpected log_entry: %s %s", msg.Type, msg.Payload)
+	}
+}
+
+func TestHandleSubscribeLogsFromClient(t *testing.T) {
+	transport := &logStreamTestTransport{}
+	ws := &WebSocketServer{ctx: context.Background(), transport: transport}
+	request := func(payload protocol.SubscribeLogsPayload) protocol.CommandResultPayload {
+		data, _ := json.Marshal(payload)
+		return ws.handleSubscribeLogsFromClient("client", &protocol.Message{Type: protocol.MessageTypeSubscribeLogs, Payload: data})
+	}
+
+	if result := request(protocol.SubscribeLogsPayload{}); result.Success || result.Error.Code != protocol.ErrorCodeFeatureDisabled {
+		t.Errorf("expected FEATURE_DISABLED without a log stream: %+v", result)
+	}
+
+	stream := NewLogStream(10)
+	stream.SetTransport(transport)
+	ws.SetLogStream(stream)
+	stream.Publish(slog.NewRecord(time.Now(), slog.LevelInfo, "started", 0))
+	stream.Publish(slog.NewRecord(time.Now(), slog.LevelWarn, "no response", 0))
+
+	for _, payload := range []protocol.SubscribeLogsPayload{{Level: "verbose"}, {Backlog: -1}, {Backlog: logBacklogSize + 1}} {
+		if result := request(payload); result.Success {
+			t.Errorf("%+v must be rejected", payload)
+		}
+	}
+
+	result := request(protocol.SubscribeLogsPayload{Level: "warn", Backlog: 5})
+	if !result.Success {
+		t.Fatalf("subscribe_logs failed: %+v", result.Error)
+	}
+	var subscribed protocol.SubscribeLogsResult
+	if err := json.Unmarshal(result.Data, &subscribed); err != nil {
+		t.Fatal(err)
+	}
+	if subscribed.Level != "WARN" || len(subscribed.Entries) != 1 || subscribed.Entries[0].Message != "no response" {
+		t.Errorf("unexpected result: %+v", subscribed)
+	}
+
+	stream.Publish(slog.NewRecord(time.Now(), slog.LevelInfo, "below the level", 0))
+	stream.Publish(slog.NewRecord(time.Now(), slog.LevelError, "failed", 0))
+	if sent := transport.messages("client"); len(sent) != 1 || sent[0].Type != protocol.MessageTypeLogEntry {
+		t.Errorf("unexpected messages: %+v", sent)
+	}
+
+	if result := request(protocol.SubscribeLogsPayload{Unsubscribe: true}); !result.Success {
+		t.Errorf("unsubscribe failed: %+v", result.Error)
+	}
+	stream.Publish(slog.NewRecord(time.Now(), slog.LevelError, "after unsubscribe", 0))
+	if sent := transport.messages("client"); len(sent) != 1 {
+		t.Errorf("received entries after unsubscribing: %+v", sent)
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+)
+
+// PollExclusions lists the device classes and devices that are never polled by the periodic updater,
+// e.g. a chatty solar inverter that is managed by another system.
+// Their INF notifications are still accepted. Entries can be added and removed at runtime.
+type PollExclusions struct {
+	mu      sync.RWMutex
+	classes map[echonet_lite.EOJClassCode]bool
+	// devices holds device patterns as in AccessRule: an alias, "@group", a device ID, "IP EOJ" or an IP address
+	devices map[string]bool
+}
+
+// ParsePollExclusions parses the exclusions from the configuration.
+// An entry of 4 hexadecimal digits is a class code ("0279"); any other entry is a device pattern.
+func ParsePollExclusions(entries []string) (*PollExclusions, error) {
+	p := &PollExclusions{
+		classes: make(map[echonet_lite.EOJClassCode]bool),
+		devices: make(map[string]bool),
+	}
+	for _, entry := range entries {
+		if err := p.Add(entry); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// parsePollExclusion returns the class code of an entry, or the trimmed device pattern
+func parsePollExclusion(entry string) (classCode echonet_lite.EOJClassCode, pattern string, err error) {
+	entry = strings.TrimSpace(entry)
+	if entry == "" {
+		return 0, "", fmt.Errorf("empty poll exclusion")
+	}
+	if len(entry) == 4 {
+		if classCode, err := handler.ParseEOJClassCodeString(entry); err == nil {
+			return classCode, "", nil
+		}
+	}
+	return 0, entry, nil
+}
+
+// Add excludes a class code or device pattern from periodic updates
+func (p *PollExclusions) Add(entry string) error {
+	classCode, pattern, err := parsePollExclusion(entry)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pattern == "" {
+		p.classes[classCode] = true
+	} else {
+		p.devices[pattern] = true
+	}
+	return nil
+}
+
+// Remove polls a class code or device pattern again. It reports whether the entry was excluded.
+func (p *PollExclusions) Remove(entry string) (bool, error) {
+	classCode, pattern, err := parsePollExclusion(entry)
+	if err != nil {
+		return false, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pattern == "" {
+		if !p.classes[classCode] {
+			return false, nil
+		}
+		delete(p.classes, classCode)
+		return true, nil
+	}
+	if !p.devices[pattern] {
+		return false, nil
+	}
+	delete(p.devices, pattern)
+	return true, nil
+}
+
+// List returns the excluded class codes ("0279") and device patterns, sorted
+func (p *PollExclusions) List() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	entries := make([]string, 0, len(p.classes)+len(p.devices))
+	for classCode := range p.classes {
+		entries = append(entries, fmt.Sprintf("%04X", uint16(classCode)))
+	}
+	for pattern := range p.devices {
+		entries = append(entries, pattern)
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// Excludes reports whether a device is excluded from periodic updates by its class or a device pattern
+func (p *PollExclusions) Excludes(resolver accessResolver, device handler.IPAndEOJ) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.classes[device.EOJ.ClassCode()] {
+		return true
+	}
+	for pattern := range p.devices {
+		if matchesPattern(resolver, pattern, device) {
+			return true
+		}
+	}
+	return false
+}
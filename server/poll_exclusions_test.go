@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net"
+	"slices"
+	"testing"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+)
+
+func TestPollExclusions(t *testing.T) {
+	exclusions, err := ParsePollExclusions([]string{"0279", " 192.168.1.50 ", "192.168.1.20 0130:2"})
+	if err != nil {
+		t.Fatalf("ParsePollExclusions returned error: %v", err)
+	}
+	if got, want := exclusions.List(), []string{"0279", "192.168.1.20 0130:2", "192.168.1.50"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	device := func(ip string, classCode echonet_lite.EOJClassCode, instance echonet_lite.EOJInstanceCode) handler.IPAndEOJ {
+		return handler.IPAndEOJ{IP: net.ParseIP(ip), EOJ: echonet_lite.MakeEOJ(classCode, instance)}
+	}
+	tests := []struct {
+		name   string
+		device handler.IPAndEOJ
+		want   bool
+	}{
+		{"class", device("192.168.1.10", 0x0279, 1), true},
+		{"ip", device("192.168.1.50", echonet_lite.SingleFunctionLighting_ClassCode, 1), true},
+		{"device", device("192.168.1.20", echonet_lite.HomeAirConditioner_ClassCode, 2), true},
+		{"other instance", device("192.168.1.20", echonet_lite.HomeAirConditioner_ClassCode, 1), false},
+		{"other class", device("192.168.1.10", echonet_lite.HomeAirConditioner_ClassCode, 1), false},
+	}
+	for _, tt := range tests {
+		if got := exclusions.Excludes(nil, tt.device); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	// Class codes are compared by value, not by spelling
+	if removed, err := exclusions.Remove("0279"); err != nil || !removed {
+		t.Errorf("Remove(0279) = %v, %v", removed, err)
+	}
+	if removed, _ := exclusions.Remove("0279"); removed {
+		t.Error("removed an entry that was not excluded")
+	}
+	if exclusions.Excludes(nil, device("192.168.1.10", 0x0279, 1)) {
+		t.Error("class is still excluded after Remove")
+	}
+
+	if _, err := ParsePollExclusions([]string{" "}); err == nil {
+		t.Error("expected an error for an empty entry")
+	}
+	var none *PollExclusions
+	if none.Excludes(nil, device("192.168.1.10", 0x0279, 1)) {
+		t.Error("nil exclusions exclude a device")
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+)
+
+// PollIntervals overrides the periodic update interval for device classes and devices,
+// so that chatty devices such as a smart meter can be polled often without polling every device as often.
+type PollIntervals struct {
+	Classes map[echonet_lite.EOJClassCode]time.Duration
+	// Devices is keyed by device patterns as in AccessRule: an alias, "@group", a device ID, "IP EOJ" or an IP address
+	Devices map[string]time.Duration
+}
+
+// defaultClassPollIntervals are the poll intervals of device classes whose values change all the time,
+// used while periodic updates are enabled unless the configuration sets the class
+var defaultClassPollIntervals = map[echonet_lite.EOJClassCode]time.Duration{
+	// the instantaneous power of a smart meter is useless at the default interval of minutes
+	echonet_lite.LowVoltageSmartMeter_ClassCode: 30 * time.Second,
+}
+
+// ParsePollIntervals parses intervals from the configuration.
+// A key of 4 hexadecimal digits is a class code ("0288"); any other key is a device pattern.
+// A value of "0" stops periodic updates of the devices.
+func ParsePollIntervals(config map[string]string) (PollIntervals, error) {
+	p := PollIntervals{
+		Classes: make(map[echonet_lite.EOJClassCode]time.Duration),
+		Devices: make(map[string]time.Duration),
+	}
+	for key, value := range config {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval < 0 {
+			return PollIntervals{}, fmt.Errorf("invalid poll interval of %s: %q", key, value)
+		}
+		key = strings.TrimSpace(key)
+		if len(key) == 4 {
+			if classCode, err := handler.ParseEOJClassCodeString(key); err == nil {
+				p.Classes[classCode] = interval
+				continue
+			}
+		}
+		if key == "" {
+			return PollIntervals{}, fmt.Errorf("empty poll interval key")
+		}
+		p.Devices[key] = interval
+	}
+	return p, nil
+}
+
+// WithClassDefaults returns the intervals with the default intervals of the classes that are not configured,
+// such as 30 seconds for a low-voltage smart electric energy meter (class 0x0288)
+func (p PollIntervals) WithClassDefaults() PollIntervals {
+	classes := make(map[echonet_lite.EOJClassCode]time.Duration, len(p.Classes)+len(defaultClassPollIntervals))
+	for classCode, interval := range defaultClassPollIntervals {
+		classes[classCode] = interval
+	}
+	for classCode, interval := range p.Classes {
+		classes[classCode] = interval
+	}
+	return PollIntervals{Classes: classes, Devices: p.Devices}
+}
+
+// IsEmpty reports whether no class or device overrides the default interval
+func (p PollIntervals) IsEmpty() bool {
+	return len(p.Classes) == 0 && len(p.Devices) == 0
+}
+
+// Interval returns the poll interval of a device: the shortest of the matching device patterns,
+// then the interval of its class, then def. Zero means that the device is not polled.
+func (p PollIntervals) Interval(resolver accessResolver, device handler.IPAndEOJ, def time.Duration) time.Duration {
+	matched := false
+	var shortest time.Duration
+	for pattern, interval := range p.Devices {
+		if !matchesPattern(resolver, pattern, device) {
+			continue
+		}
+		if !matched || interval < shortest {
+			shortest = interval
+		}
+		matched = true
+	}
+	if matched {
+		return shortest
+	}
+	if interval, ok := p.Classes[device.EOJ.ClassCode()]; ok {
+		return interval
+	}
+	return def
+}
+
+// Tick returns how often the periodic updater checks for devices to poll: the shortest positive interval.
+// Zero means that no device is polled.
+func (p PollIntervals) Tick(def time.Duration) time.Duration {
+	tick := max(def, 0)
+	consider := func(interval time.Duration) {
+		if interval > 0 && (tick == 0 || interval < tick) {
+			tick = interval
+		}
+	}
+	for _, interval := range p.Classes {
+		consider(interval)
+	}
+	for _, interval := range p.Devices {
+		consider(interval)
+	}
+	return tick
+}
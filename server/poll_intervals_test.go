@@ -0,0 +1,89 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+)
+
+func TestPollIntervals(t *testing.T) {
+	intervals, err := ParsePollIntervals(map[string]string{
+		"0288":                "30s",
+		"0130":                "10m",
+		"192.168.1.20":        "5m",
+		"192.168.1.20 0130:2": "2m",
+		"192.168.1.30 0291:1": "0",
+	})
+	if err != nil {
+		t.Fatalf("ParsePollIntervals returned error: %v", err)
+	}
+	if len(intervals.Classes) != 2 || len(intervals.Devices) != 3 {
+		t.Fatalf("unexpected intervals: %+v", intervals)
+	}
+
+	device := func(ip string, classCode echonet_lite.EOJClassCode, instance echonet_lite.EOJInstanceCode) handler.IPAndEOJ {
+		return handler.IPAndEOJ{IP: net.ParseIP(ip), EOJ: echonet_lite.MakeEOJ(classCode, instance)}
+	}
+	tests := []struct {
+		name   string
+		device handler.IPAndEOJ
+		want   time.Duration
+	}{
+		{"class", device("192.168.1.10", 0x0288, 1), 30 * time.Second},
+		{"default", device("192.168.1.10", echonet_lite.SingleFunctionLighting_ClassCode, 1), time.Minute},
+		// A device pattern takes priority over the class, and the shortest matching pattern wins
+		{"device over class", device("192.168.1.20", echonet_lite.HomeAirConditioner_ClassCode, 1), 5 * time.Minute},
+		{"shortest device", device("192.168.1.20", echonet_lite.HomeAirConditioner_ClassCode, 2), 2 * time.Minute},
+		{"disabled", device("192.168.1.30", echonet_lite.SingleFunctionLighting_ClassCode, 1), 0},
+	}
+	for _, tt := range tests {
+		if got := intervals.Interval(nil, tt.device, time.Minute); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	if got := intervals.Tick(time.Minute); got != 30*time.Second {
+		t.Errorf("expected tick of 30s, got %v", got)
+	}
+	// Overrides keep polling even when the default interval is disabled
+	if got := intervals.Tick(0); got != 30*time.Second {
+		t.Errorf("expected tick of 30s without a default, got %v", got)
+	}
+	if got := (PollIntervals{}).Tick(0); got != 0 {
+		t.Errorf("expected no tick, got %v", got)
+	}
+
+	for _, config := range []map[string]string{{"0288": "soon"}, {"0288": "-1m"}, {" ": "1m"}} {
+		if _, err := ParsePollIntervals(config); err == nil {
+			t.Errorf("expected an error for %v", config)
+		}
+	}
+}
+
+func TestPollIntervals_WithClassDefaults(t *testing.T) {
+	meter := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.LowVoltageSmartMeter_ClassCode, 1)}
+
+	intervals, err := ParsePollIntervals(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := intervals.WithClassDefaults().Interval(nil, meter, time.Minute); got != 30*time.Second {
+		t.Errorf("expected the smart meter profile of 30s, got %v", got)
+	}
+
+	// The configuration takes priority over the profile, including "0"
+	intervals, err = ParsePollIntervals(map[string]string{"0288": "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withDefaults := intervals.WithClassDefaults()
+	if got := withDefaults.Interval(nil, meter, time.Minute); got != 0 {
+		t.Errorf("expected the configured interval, got %v", got)
+	}
+	if _, ok := intervals.Classes[echonet_lite.HomeAirConditioner_ClassCode]; ok || len(intervals.Classes) != 1 {
+		t.Errorf("WithClassDefaults modified the original intervals: %+v", intervals)
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+)
+
+// DefaultPropertyChangeCoalesceWindow is the default time property changes of one device are collected before broadcasting
+const DefaultPropertyChangeCoalesceWindow = 50 * time.Millisecond
+
+// pendingPropertyChanges holds the changes of one device waiting to be flushed
+type pendingPropertyChanges struct {
+	device     handler.IPAndEOJ
+	properties echonet_lite.Properties
+}
+
+// propertyChangeCoalescer collects property changes per device and flushes them together after a short window.
+// When a device changes again within the window, the latest value of each EPC wins.
+type propertyChangeCoalescer struct {
+	ctx          context.Context
+	window       time.Duration
+	timeProvider TimeProvider
+	flush        func(device handler.IPAndEOJ, properties echonet_lite.Properties)
+
+	mu      sync.Mutex
+	pending map[string]*pendingPropertyChanges // key: device.Key()
+}
+
+func newPropertyChangeCoalescer(ctx context.Context, window time.Duration, timeProvider TimeProvider, flush func(handler.IPAndEOJ, echonet_lite.Properties)) *propertyChangeCoalescer {
+	return &propertyChangeCoalescer{
+		ctx:          ctx,
+		window:       window,
+		timeProvider: timeProvider,
+		flush:        flush,
+		pending:      make(map[string]*pendingPropertyChanges),
+	}
+}
+
+// Add queues a property change. The first change of a device starts its window.
+func (c *propertyChangeCoalescer) Add(device handler.IPAndEOJ, property echonet_lite.Property) {
+	key := device.Key()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.pending[key]
+	if !ok {
+		p = &pendingPropertyChanges{device: device}
+		c.pending[key] = p
+		go c.flushAfterWindow(key)
+	}
+	p.properties = p.properties.UpdateProperty(property)
+}
+
+// flushAfterWindow waits for the window to elapse and flushes the changes of the device.
+func (c *propertyChangeCoalescer) flushAfterWindow(key string) {
+	select {
+	case <-c.timeProvider.After(c.window):
+	case <-c.ctx.Done():
+		return
+	}
+
+	c.mu.Lock()
+	p := c.pending[key]
+	delete(c.pending, key)
+	c.mu.Unlock()
+
+	if p != nil && len(p.properties) > 0 {
+		c.flush(p.device, p.properties)
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+)
+
+func TestPropertyChangeCoalescer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type flushed struct {
+		device     handler.IPAndEOJ
+		properties echonet_lite.Properties
+	}
+	flushCh := make(chan flushed, 10)
+	c := newPropertyChangeCoalescer(ctx, 20*time.Millisecond, &RealTimeProvider{}, func(device handler.IPAndEOJ, properties echonet_lite.Properties) {
+		flushCh <- flushed{device: device, properties: properties}
+	})
+
+	device1 := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(0x0130, 1)}
+	device2 := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.11"), EOJ: echonet_lite.MakeEOJ(0x0130, 1)}
+
+	c.Add(device1, echonet_lite.Property{EPC: 0x80, EDT: []byte{0x31}})
+	c.Add(device1, echonet_lite.Property{EPC: 0xB3, EDT: []byte{0x19}})
+	c.Add(device1, echonet_lite.Property{EPC: 0x80, EDT: []byte{0x30}}) // 同じEPCは最新値で上書き
+	c.Add(device2, echonet_lite.Property{EPC: 0x80, EDT: []byte{0x30}})
+
+	got := make(map[string]echonet_lite.Properties)
+	for i := 0; i < 2; i++ {
+		select {
+		case f := <-flushCh:
+			got[f.device.Key()] = f.properties
+		case <-time.After(time.Second):
+			t.Fatal("変化がフラッシュされない")
+		}
+	}
+
+	props1 := got[device1.Key()]
+	if len(props1) != 2 {
+		t.Fatalf("device1 の変化がまとめられていない: %v", props1)
+	}
+	if p, ok := props1.FindEPC(0x80); !ok || p.EDT[0] != 0x30 {
+		t.Errorf("最新値になっていない: %v", props1)
+	}
+	if len(got[device2.Key()]) != 1 {
+		t.Errorf("device2 の変化が不正: %v", got[device2.Key()])
+	}
+
+	// ウィンドウ経過後の変化は新しいバッチになる
+	c.Add(device1, echonet_lite.Property{EPC: 0x80, EDT: []byte{0x31}})
+	select {
+	case f := <-flushCh:
+		if len(f.properties) != 1 {
+			t.Errorf("新しいバッチの変化数が不正: %v", f.properties)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("新しいバッチがフラッシュされない")
+	}
+}
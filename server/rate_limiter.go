@@ -0,0 +1,92 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"echonet-list/protocol"
+)
+
+// RateLimit is the allowed request rate of one message type for one client
+type RateLimit struct {
+	Rate  float64 // requests per second (0 or less means unlimited)
+	Burst int     // requests allowed at once before the rate applies (at least 1)
+}
+
+// RateLimits maps client message types to their limits. Types without an entry are not limited.
+type RateLimits map[protocol.MessageType]RateLimit
+
+// DefaultRateLimits returns the limits for requests that send ECHONET Lite messages to the network
+func DefaultRateLimits() RateLimits {
+	return RateLimits{
+		protocol.MessageTypeSetProperties:      {Rate: 5, Burst: 10},
+		protocol.MessageTypeSetGetProperties:   {Rate: 5, Burst: 10},
+		protocol.MessageTypeSetGroupProperties: {Rate: 1, Burst: 3},
+		protocol.MessageTypeUpdateProperties:   {Rate: 1, Burst: 5},
+		protocol.MessageTypeRunScene:           {Rate: 1, Burst: 3},
+		protocol.MessageTypeDiscoverDevices:    {Rate: 0.1, Burst: 2},
+	}
+}
+
+// tokenBucket holds the remaining requests of one client for one message type
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// requestRateLimiter limits requests per client and message type with token buckets
+type requestRateLimiter struct {
+	limits RateLimits
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]map[protocol.MessageType]*tokenBucket // connID -> message type -> bucket
+}
+
+func newRequestRateLimiter(limits RateLimits, now func() time.Time) *requestRateLimiter {
+	return &requestRateLimiter{
+		limits:  limits,
+		now:     now,
+		buckets: make(map[string]map[protocol.MessageType]*tokenBucket),
+	}
+}
+
+// Allow reports whether the client may send a request of the message type now, consuming one token if so.
+func (l *requestRateLimiter) Allow(connID string, msgType protocol.MessageType) bool {
+	limit, ok := l.limits[msgType]
+	if !ok || limit.Rate <= 0 {
+		return true
+	}
+	burst := float64(max(limit.Burst, 1))
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	clientBuckets, ok := l.buckets[connID]
+	if !ok {
+		clientBuckets = make(map[protocol.MessageType]*tokenBucket)
+		l.buckets[connID] = clientBuckets
+	}
+	bucket, ok := clientBuckets[msgType]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, last: now}
+		clientBuckets[msgType] = bucket
+	} else {
+		bucket.tokens = min(burst, bucket.tokens+now.Sub(bucket.last).Seconds()*limit.Rate)
+		bucket.last = now
+	}
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// Remove forgets the buckets of a disconnected client
+func (l *requestRateLimiter) Remove(connID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.buckets, connID)
+}
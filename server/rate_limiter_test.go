@@ -0,0 +1,63 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"echonet-list/protocol"
+)
+
+func TestRequestRateLimiter(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	limiter := newRequestRateLimiter(RateLimits{
+		protocol.MessageTypeSetProperties: {Rate: 2, Burst: 3},
+	}, func() time.Time { return now })
+
+	// The burst is allowed at once, then requests are rejected
+	for i := 0; i < 3; i++ {
+		if !limiter.Allow("a", protocol.MessageTypeSetProperties) {
+			t.Fatalf("Request %d within the burst was rejected", i)
+		}
+	}
+	if limiter.Allow("a", protocol.MessageTypeSetProperties) {
+		t.Error("Request over the burst was allowed")
+	}
+
+	// Other clients and unlimited message types are not affected
+	if !limiter.Allow("b", protocol.MessageTypeSetProperties) {
+		t.Error("Another client was limited")
+	}
+	for i := 0; i < 100; i++ {
+		if !limiter.Allow("a", protocol.MessageTypeListDevices) {
+			t.Fatal("Unlimited message type was limited")
+		}
+	}
+
+	// Tokens are refilled at the configured rate
+	now = now.Add(500 * time.Millisecond)
+	if !limiter.Allow("a", protocol.MessageTypeSetProperties) {
+		t.Error("Request after refill was rejected")
+	}
+	if limiter.Allow("a", protocol.MessageTypeSetProperties) {
+		t.Error("Only one token should have been refilled")
+	}
+
+	// A reconnected client starts with a full burst
+	limiter.Remove("a")
+	for i := 0; i < 3; i++ {
+		if !limiter.Allow("a", protocol.MessageTypeSetProperties) {
+			t.Fatalf("Request %d after Remove was rejected", i)
+		}
+	}
+}
+
+func TestRequestRateLimiterZeroRateIsUnlimited(t *testing.T) {
+	limiter := newRequestRateLimiter(RateLimits{
+		protocol.MessageTypeDiscoverDevices: {Rate: 0, Burst: 1},
+	}, time.Now)
+	for i := 0; i < 10; i++ {
+		if !limiter.Allow("a", protocol.MessageTypeDiscoverDevices) {
+			t.Fatal("Rate 0 should not limit requests")
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"echonet-list/client"
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// maxRESTRequestBodySize limits the size of request bodies accepted by the REST API.
+const maxRESTRequestBodySize = 1 << 20
+
+// RESTAPIHandler serves a small REST API backed by an ECHONETListClient, so that
+// scripts and curl users can query and control devices without the WebSocket protocol.
+//
+//	GET  /api/devices                           list cached devices (?offline=false excludes offline devices)
+//	GET  /api/devices/{ip}/{eoj}                cached data of a device
+//	GET  /api/devices/{ip}/{eoj}/properties     fetch properties from the device (?epc=80&epc=B0)
+//	POST /api/devices/{ip}/{eoj}/properties     set properties, body: {"80": {"string": "on"}}
+//	GET  /api/export                            devices and history for bug reports, anonymized (?anonymize=false, ?history=50)
+//	POST /api/command                           run a console command such as "set aircon on" and return a spoken summary (?lang=ja)
+//	GET  /api/events                            notifications as Server-Sent Events (?types=property_changed,device_online)
+//	GET  /api/poll                              notifications after a sequence number, long-polling (?since=42, ?timeout=30, ?types=)
+//	GET  /d/{device}/{epc}                      one property as plain text, by device alias or ID (?cached=true)
+//	PUT  /d/{device}/{epc}                      set one property from a plain text body or ?value= (POST is also accepted)
+//	     /elapi/v1/devices...                   ECHONET Lite Web API, if enabled (see rest_api_elwa.go)
+//	     /grafana/...                           JSON datasource for Grafana charts of the history (see rest_api_grafana.go)
+//
+// When access control is enabled, requests must carry a token ("Authorization: Bearer <token>" or ?token=)
+// and only see and control the devices allowed by its access rule.
+type RESTAPIHandler struct {
+	client client.ECHONETListClient
+	// access restricts the devices of each token (nil if disabled).
+	access *AccessControl
+	// onSet is called with the properties about to be set, e.g. to record history.
+	onSet func(device handler.IPAndEOJ, properties echonet_lite.Properties)
+	// anonymizer keeps the pseudonyms of exports stable while the server is running.
+	anonymizer *handler.Anonymizer
+	// events mirrors the WebSocket notifications for GET /api/events (nil if not served).
+	events *eventStream
+	// elwa serves the ECHONET Lite Web API under /elapi/v1/.
+	elwa bool
+	// history is charted by the Grafana datasource under /grafana/ (nil if not served).
+	history handler.DeviceHistoryStore
+}
+
+// NewRESTAPIHandler creates a REST API handler for the given client.
+func NewRESTAPIHandler(c client.ECHONETListClient) *RESTAPIHandler {
+	return &RESTAPIHandler{client: c, anonymizer: handler.NewAnonymizer()}
+}
+
+// Register adds the REST API routes to the mux.
+func (a *RESTAPIHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/devices", a.handleListDevices)
+	mux.HandleFunc("GET /api/devices/{ip}/{eoj}", a.handleGetDevice)
+	mux.HandleFunc("GET /api/devices/{ip}/{eoj}/properties", a.handleGetProperties)
+	mux.HandleFunc("POST /api/devices/{ip}/{eoj}/properties", a.handleSetProperties)
+	mux.HandleFunc("GET /api/export", a.handleExport)
+	mux.HandleFunc("POST /api/command", a.handleCommand)
+	mux.HandleFunc("GET /d/{device}/{epc}", a.handleGatewayGet)
+	mux.HandleFunc("PUT /d/{device}/{epc}", a.handleGatewaySet)
+	mux.HandleFunc("POST /d/{device}/{epc}", a.handleGatewaySet)
+	if a.events != nil {
+		mux.HandleFunc("GET /api/events", a.handleEvents)
+		mux.HandleFunc("GET /api/poll", a.handlePoll)
+	}
+	if a.elwa {
+		a.registerELWA(mux)
+	}
+	if a.history != nil {
+		a.registerGrafana(mux)
+	}
+}
+
+// restError is the body returned for failed REST requests.
+type restError struct {
+	Error protocol.Error `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("Failed to write REST response", "err", err)
+	}
+}
+
+func writeRESTError(w http.ResponseWriter, status int, code protocol.ErrorCode, message string) {
+	writeJSON(w, status, restError{Error: protocol.Error{Code: code, Message: message}})
+}
+
+// deviceFromPath parses the {ip} and {eoj} path values, e.g. /api/devices/192.168.1.10/0130:1
+func deviceFromPath(r *http.Request) (handler.IPAndEOJ, error) {
+	ip := r.PathValue("ip")
+	if net.ParseIP(ip) == nil {
+		return handler.IPAndEOJ{}, errors.New("invalid IP address: " + ip)
+	}
+	return handler.ParseDeviceIdentifier(ip + " " + r.PathValue("eoj"))
+}
+
+// authenticate returns the access rule of the request, writing 401 when the token is missing or unknown.
+// The rule is nil when access control is disabled.
+func (a *RESTAPIHandler) authenticate(w http.ResponseWriter, r *http.Request) (*AccessRule, bool) {
+	if a.access == nil {
+		return nil, true
+	}
+	rule, ok := a.access.Authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeRESTError(w, http.StatusUnauthorized, protocol.ErrorCodePermissionDenied, "Missing or invalid token")
+		return nil, false
+	}
+	return rule, true
+}
+
+func writePermissionDenied(w http.ResponseWriter, what string, device handler.IPAndEOJ) {
+	writeRESTError(w, http.StatusForbidden, protocol.ErrorCodePermissionDenied, "No permission to "+what+" device: "+device.Specifier())
+}
+
+func (a *RESTAPIHandler) toProtocol(device handler.DeviceAndProperties) protocol.Device {
+	return protocol.DeviceToProtocol(device.Device, device.Properties, time.Time{}, a.client.IsOfflineDevice(device.Device))
+}
+
+func (a *RESTAPIHandler) handleListDevices(w http.ResponseWriter, r *http.Request) {
+	rule, ok := a.authenticate(w, r)
+	if !ok {
+		return
+	}
+	excludeOffline := r.URL.Query().Get("offline") == "false"
+	devices := a.client.ListDevices(handler.FilterCriteria{ExcludeOffline: excludeOffline})
+
+	results := make([]protocol.Device, 0, len(devices))
+	for _, device := range devices {
+		if !rule.CanRead(a.client, device.Device) {
+			continue
+		}
+		results = append(results, a.toProtocol(device))
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (a *RESTAPIHandler) handleGetDevice(w http.ResponseWriter, r *http.Request) {
+	rule, ok := a.authenticate(w, r)
+	if !ok {
+		return
+	}
+	device, err := deviceFromPath(r)
+	if err != nil {
+		writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, err.Error())
+		return
+	}
+	if !rule.CanRead(a.client, device) {
+		writePermissionDenied(w, "read", device)
+		return
+	}
+
+	devices := a.client.ListDevices(handler.FilterCriteria{Device: handler.DeviceSpecifierFromIPAndEOJ(device)})
+	if len(devices) == 0 {
+		writeRESTError(w, http.StatusNotFound, protocol.ErrorCodeTargetNotFound, "Device not found: "+device.Specifier())
+		return
+	}
+	writeJSON(w, http.StatusOK, a.toProtocol(devices[0]))
+}
+
+func (a *RESTAPIHandler) handleGetProperties(w http.ResponseWriter, r *http.Request) {
+	rule, ok := a.authenticate(w, r)
+	if !ok {
+		return
+	}
+	device, err := deviceFromPath(r)
+	if err != nil {
+		writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, err.Error())
+		return
+	}
+	if !rule.CanRead(a.client, device) {
+		writePermissionDenied(w, "read", device)
+		return
+	}
+
+	// Accept both ?epc=80&epc=B0 and ?epc=80,B0
+	var epcs []echonet_lite.EPCType
+	for _, value := range r.URL.Query()["epc"] {
+		for _, epcStr := range strings.Split(value, ",") {
+			epc, err := handler.ParseEPCString(strings.TrimSpace(epcStr))
+			if err != nil {
+				writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, "Invalid EPC: "+err.Error())
+				return
+			}
+			epcs = append(epcs, epc)
+		}
+	}
+
+	result, err := a.client.GetProperties(device, epcs, false)
+	if err != nil {
+		writeRESTError(w, http.StatusBadGateway, protocol.ErrorCodeEchonetCommunicationError, "Error getting properties: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, a.toProtocol(result))
+}
+
+func (a *RESTAPIHandler) handleSetProperties(w http.ResponseWriter, r *http.Request) {
+	rule, ok := a.authenticate(w, r)
+	if !ok {
+		return
+	}
+	device, err := deviceFromPath(r)
+	if err != nil {
+		writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, err.Error())
+		return
+	}
+	if !rule.CanControl(a.client, device) {
+		writePermissionDenied(w, "control", device)
+		return
+	}
+
+	var body map[string]protocol.PropertyData
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRESTRequestBodySize)).Decode(&body); err != nil {
+		writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidRequestFormat, "Error parsing request body: "+err.Error())
+		return
+	}
+	if len(body) == 0 {
+		writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, "No properties specified")
+		return
+	}
+
+	properties, err := propertiesFromProtocol(device.EOJ.ClassCode(), body)
+	if err != nil {
+		writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, err.Error())
+		return
+	}
+
+	if a.onSet != nil {
+		a.onSet(device, properties)
+	}
+
+	result, err := a.client.SetProperties(device, properties, nil)
+	if err != nil {
+		writeRESTError(w, http.StatusBadGateway, protocol.ErrorCodeEchonetCommunicationError, "Error setting properties: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, a.toProtocol(result))
+}
@@ -0,0 +1,275 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"echonet-list/console"
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// commandRequest is the JSON body of POST /api/command. A text/plain body is the command itself.
+type commandRequest struct {
+	Command string `json:"command"`
+	Lang    string `json:"lang,omitempty"`
+}
+
+// commandResponse is the body of POST /api/command: a sentence that a voice assistant can read out.
+// Error is also set when the command failed, as in other REST API errors.
+type commandResponse struct {
+	Speech string          `json:"speech"`
+	Error  *protocol.Error `json:"error,omitempty"`
+}
+
+func writeCommandError(w http.ResponseWriter, status int, code protocol.ErrorCode, speech string) {
+	writeJSON(w, status, commandResponse{Speech: speech, Error: &protocol.Error{Code: code, Message: speech}})
+}
+
+// commandLang selects the language of the spoken summary from ?lang=, the request body, Accept-Language or the locale of the server.
+// Only Japanese has its own sentences; other languages get English sentences with translated names if available.
+func commandLang(r *http.Request, requested string) string {
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = requested
+	}
+	if lang == "" {
+		lang = r.Header.Get("Accept-Language")
+	}
+	if lang = echonet_lite.NormalizeLanguage(lang); lang == "" {
+		lang = echonet_lite.DisplayLanguage()
+	}
+	return lang
+}
+
+// commandDeviceName is the name of a device to speak: its first alias, or its class name.
+func (a *RESTAPIHandler) commandDeviceName(device handler.IPAndEOJ, lang string) string {
+	if aliases := a.client.GetAliases(device); len(aliases) > 0 {
+		return aliases[0]
+	}
+	meta, _ := echonet_lite.GetClassMetadata(device.EOJ.ClassCode())
+	if lang == "ja" && meta.NameJa != "" {
+		return meta.NameJa
+	}
+	if meta.Name != "" {
+		return meta.Name
+	}
+	return device.EOJ.ClassCode().String()
+}
+
+// commandPropertyPhrase returns the short name and value of a property to speak, e.g. "temperature setting", "26".
+func (a *RESTAPIHandler) commandPropertyPhrase(classCode echonet_lite.EOJClassCode, prop echonet_lite.Property, lang string) (string, string) {
+	desc, ok := a.client.GetPropertyDesc(classCode, prop.EPC)
+	if !ok {
+		return prop.EPC.String(), fmt.Sprintf("%X", prop.EDT)
+	}
+	value := desc.EDTToString(prop.EDT)
+	if translated, ok := desc.GetAliasTranslations(lang)[value]; ok {
+		value = translated
+	}
+	if value == "" {
+		value = fmt.Sprintf("%X", prop.EDT)
+	}
+	name := desc.GetShortName(lang)
+	if lang != "ja" {
+		name = strings.ToLower(name)
+	}
+	return name, value
+}
+
+// commandSentence joins the property phrases of one device, e.g.
+// "Set aircon operation mode to cooling and temperature setting to 26." or "aircon の運転モードを冷房、温度設定を26にしました。"
+func (a *RESTAPIHandler) commandSentence(device handler.IPAndEOJ, properties echonet_lite.Properties, set bool, lang string) string {
+	classCode := device.EOJ.ClassCode()
+	name := a.commandDeviceName(device, lang)
+	phrases := make([]string, 0, len(properties))
+	for _, prop := range properties {
+		propName, value := a.commandPropertyPhrase(classCode, prop, lang)
+		switch {
+		case lang == "ja" && set:
+			phrases = append(phrases, propName+"を"+value)
+		case lang == "ja":
+			phrases = append(phrases, propName+"は"+value)
+		case set:
+			phrases = append(phrases, propName+" to "+value)
+		default:
+			phrases = append(phrases, propName+" is "+value)
+		}
+	}
+	switch {
+	case lang == "ja" && set:
+		return name + "の" + strings.Join(phrases, "、") + "にしました。"
+	case lang == "ja":
+		return name + "の" + strings.Join(phrases, "、") + "です。"
+	case set:
+		return "Set " + name + " " + strings.Join(phrases, " and ") + "."
+	default:
+		return name + " " + strings.Join(phrases, " and ") + "."
+	}
+}
+
+// commandTargets returns the devices of a parsed command: the members of its @group, or the single matching device.
+func (a *RESTAPIHandler) commandTargets(cmd *console.Command) ([]handler.IPAndEOJ, error) {
+	if cmd.GroupName != nil {
+		ids, _ := a.client.GetDevicesByGroup(*cmd.GroupName)
+		var devices []handler.IPAndEOJ
+		for _, id := range ids {
+			if device := a.client.FindDeviceByIDString(id); device != nil {
+				devices = append(devices, *device)
+			}
+		}
+		if len(devices) == 0 {
+			return nil, fmt.Errorf("group %s has no devices", *cmd.GroupName)
+		}
+		return devices, nil
+	}
+	devices := a.client.GetDevices(cmd.DeviceSpec)
+	switch len(devices) {
+	case 0:
+		return nil, fmt.Errorf("device not found")
+	case 1:
+		return devices, nil
+	default:
+		return nil, fmt.Errorf("%d devices match, please use an alias", len(devices))
+	}
+}
+
+// handleCommand runs one console command given as text, e.g. "set living-aircon on temperature:26",
+// and returns a spoken summary, so that Alexa or Google Home webhooks can be bridged with a single request.
+// The command is parsed by the console parser; set, get and scene run are supported.
+func (a *RESTAPIHandler) handleCommand(w http.ResponseWriter, r *http.Request) {
+	rule, ok := a.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	var req commandRequest
+	body := http.MaxBytesReader(w, r.Body, maxRESTRequestBodySize)
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			writeCommandError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidRequestFormat, "Invalid request body: "+err.Error())
+			return
+		}
+	} else {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			writeCommandError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidRequestFormat, "Error reading request body: "+err.Error())
+			return
+		}
+		req.Command = string(data)
+	}
+	lang := commandLang(r, req.Lang)
+
+	cmd, err := console.NewCommandParser(a.client, a.client, a.client).ParseCommand(strings.TrimSpace(req.Command), false)
+	if err != nil {
+		writeCommandError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, err.Error())
+		return
+	}
+	if cmd == nil {
+		writeCommandError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, "No command specified")
+		return
+	}
+
+	switch cmd.Type {
+	case console.CmdSet, console.CmdGet:
+		a.runDeviceCommand(w, rule, cmd, lang)
+	case console.CmdSceneRun:
+		a.runSceneCommand(w, rule, *cmd.SceneName, lang)
+	default:
+		writeCommandError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, "Unsupported command, use set, get or scene run")
+	}
+}
+
+func (a *RESTAPIHandler) runDeviceCommand(w http.ResponseWriter, rule *AccessRule, cmd *console.Command, lang string) {
+	set := cmd.Type == console.CmdSet
+	devices, err := a.commandTargets(cmd)
+	if err != nil {
+		writeCommandError(w, http.StatusNotFound, protocol.ErrorCodeTargetNotFound, err.Error())
+		return
+	}
+	for _, device := range devices {
+		if set && !rule.CanControl(a.client, device) || !set && !rule.CanRead(a.client, device) {
+			writeCommandError(w, http.StatusForbidden, protocol.ErrorCodePermissionDenied, "No permission for "+a.commandDeviceName(device, lang))
+			return
+		}
+	}
+
+	var sentences []string
+	failed := 0
+	for _, device := range devices {
+		var properties echonet_lite.Properties
+		if set {
+			if a.onSet != nil {
+				a.onSet(device, cmd.Properties)
+			}
+			_, err = a.client.SetProperties(device, cmd.Properties, nil)
+			properties = cmd.Properties
+		} else {
+			var result handler.DeviceAndProperties
+			result, err = a.client.GetProperties(device, cmd.EPCs, false)
+			properties = result.Properties
+		}
+		if err != nil {
+			failed++
+			if lang == "ja" {
+				sentences = append(sentences, a.commandDeviceName(device, lang)+"と通信できませんでした。")
+			} else {
+				sentences = append(sentences, "Could not reach "+a.commandDeviceName(device, lang)+".")
+			}
+			continue
+		}
+		sentences = append(sentences, a.commandSentence(device, properties, set, lang))
+	}
+
+	speech := strings.Join(sentences, " ")
+	if failed == len(devices) {
+		writeCommandError(w, http.StatusBadGateway, protocol.ErrorCodeEchonetCommunicationError, speech)
+		return
+	}
+	writeJSON(w, http.StatusOK, commandResponse{Speech: speech})
+}
+
+func (a *RESTAPIHandler) runSceneCommand(w http.ResponseWriter, rule *AccessRule, name string, lang string) {
+	// Same check as run_scene over WebSocket: every device of the scene must be controllable
+	actions, ok := a.client.GetScene(name)
+	if !ok {
+		writeCommandError(w, http.StatusNotFound, protocol.ErrorCodeTargetNotFound, "Scene not found: "+name)
+		return
+	}
+	for _, action := range actions {
+		device := a.client.FindDeviceByIDString(action.Device)
+		if device != nil && !rule.CanControl(a.client, *device) {
+			writeCommandError(w, http.StatusForbidden, protocol.ErrorCodePermissionDenied, "No permission for scene "+name)
+			return
+		}
+	}
+
+	results, err := a.client.SceneRun(name)
+	if err != nil {
+		writeCommandError(w, http.StatusBadGateway, protocol.ErrorCodeEchonetCommunicationError, err.Error())
+		return
+	}
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	var speech string
+	switch {
+	case lang == "ja" && failed > 0:
+		speech = fmt.Sprintf("シーン %s を実行しましたが、%d 台の機器を操作できませんでした。", name, failed)
+	case lang == "ja":
+		speech = fmt.Sprintf("シーン %s を実行しました。", name)
+	case failed > 0:
+		speech = fmt.Sprintf("Ran scene %s, but %d devices could not be controlled.", name, failed)
+	default:
+		speech = fmt.Sprintf("Ran scene %s.", name)
+	}
+	writeJSON(w, http.StatusOK, commandResponse{Speech: speech})
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// commandTestClient はエイリアス aircon のエアコンとシーン night があるモック
+type commandTestClient struct {
+	gatewayTestClient
+	sceneRun string
+}
+
+func (c *commandTestClient) GetDevices(spec handler.DeviceSpecifier) []echonet_lite.IPAndEOJ {
+	var result []echonet_lite.IPAndEOJ
+	for _, d := range c.ListDevices(handler.FilterCriteria{Device: spec}) {
+		result = append(result, d.Device)
+	}
+	return result
+}
+
+func (c *commandTestClient) GetAliases(echonet_lite.IPAndEOJ) []string { return []string{"aircon"} }
+
+func (c *commandTestClient) GetPropertyDesc(classCode echonet_lite.EOJClassCode, epc echonet_lite.EPCType) (*echonet_lite.PropertyDesc, bool) {
+	return echonet_lite.GetPropertyDesc(classCode, epc)
+}
+
+func (c *commandTestClient) FindPropertyAlias(classCode echonet_lite.EOJClassCode, alias string) (echonet_lite.Property, bool) {
+	return handler.FindPropertyAlias(classCode, alias)
+}
+
+func (c *commandTestClient) GetScene(name string) ([]handler.SceneAction, bool) {
+	return nil, name == "night"
+}
+
+func (c *commandTestClient) SceneRun(name string) ([]handler.SceneRunResult, error) {
+	c.sceneRun = name
+	return []handler.SceneRunResult{{}}, nil
+}
+
+func TestRESTAPI_Command(t *testing.T) {
+	c := &commandTestClient{}
+	c.devices = []handler.DeviceAndProperties{
+		{Device: echonet_lite.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}},
+	}
+	mux := http.NewServeMux()
+	NewRESTAPIHandler(c).Register(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	run := func(contentType, body string, header http.Header) (int, commandResponse) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/command", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var result commandResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, result
+	}
+
+	// コンソールと同じ文法で設定し、読み上げ用の文を返す
+	status, got := run("text/plain", "set aircon on temperature:26", nil)
+	if status != http.StatusOK || got.Error != nil || got.Speech != "Set aircon operation status to on and temperature setting to 26℃." {
+		t.Errorf("unexpected set response: %d %+v", status, got)
+	}
+	if len(c.setRequest) != 2 || c.setRequest[0].EPC != 0x80 || c.setRequest[1].EPC != 0xB3 || c.setRequest[1].EDT[0] != 26 {
+		t.Errorf("unexpected set request: %v", c.setRequest)
+	}
+
+	// JSON の本文と日本語の応答
+	status, got = run("application/json", `{"command": "get aircon 80", "lang": "ja"}`, nil)
+	if status != http.StatusOK || got.Speech != "airconの動作状態はonです。" {
+		t.Errorf("unexpected get response: %d %+v", status, got)
+	}
+	status, got = run("text/plain", "scene run night", http.Header{"Accept-Language": {"ja-JP,ja;q=0.9"}})
+	if status != http.StatusOK || c.sceneRun != "night" || got.Speech != "シーン night を実行しました。" {
+		t.Errorf("unexpected scene response: %d %+v", status, got)
+	}
+
+	tests := []struct {
+		body   string
+		status int
+		code   protocol.ErrorCode
+	}{
+		{"set unknown on", http.StatusBadRequest, protocol.ErrorCodeInvalidParameters},
+		{"devices", http.StatusBadRequest, protocol.ErrorCodeInvalidParameters},
+		{"set 192.168.1.20 0130:1 on", http.StatusNotFound, protocol.ErrorCodeTargetNotFound},
+		{"scene run morning", http.StatusNotFound, protocol.ErrorCodeTargetNotFound},
+		{"", http.StatusBadRequest, protocol.ErrorCodeInvalidParameters},
+	}
+	for _, tt := range tests {
+		status, got := run("text/plain", tt.body, nil)
+		if status != tt.status || got.Error == nil || got.Error.Code != tt.code || got.Speech == "" {
+			t.Errorf("%q: unexpected response %d %+v", tt.body, status, got)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package server
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// The ECHONET Lite Web API (ELWA) routes expose devices in the standardized Web API format,
+// so that ELWA-compatible apps can use them without the custom WebSocket protocol.
+// Only the device part of the guideline is implemented:
+//
+//	GET /elapi/v1/devices                                  device list
+//	GET /elapi/v1/devices/{id}                             device description
+//	GET /elapi/v1/devices/{id}/properties                  cached values of all properties
+//	GET /elapi/v1/devices/{id}/properties/{property}       one property, read from the device
+//	PUT /elapi/v1/devices/{id}/properties/{property}       set one property, body: {"operationStatus": true}
+//
+// Devices are identified by their device ID, or by "<ip>_<eoj>" when the device has no identification number.
+// Properties are named by the lowerCamelCase of their English name (e.g. operationStatus), or by the EPC in hex.
+
+func (a *RESTAPIHandler) registerELWA(mux *http.ServeMux) {
+	mux.HandleFunc("GET /elapi/v1/devices", a.handleELWADevices)
+	mux.HandleFunc("GET /elapi/v1/devices/{id}", a.handleELWADevice)
+	mux.HandleFunc("GET /elapi/v1/devices/{id}/properties", a.handleELWAProperties)
+	mux.HandleFunc("GET /elapi/v1/devices/{id}/properties/{property}", a.handleELWAGetProperty)
+	mux.HandleFunc("PUT /elapi/v1/devices/{id}/properties/{property}", a.handleELWASetProperty)
+}
+
+// elwaDescriptions is the multilingual text used throughout ELWA.
+type elwaDescriptions struct {
+	Ja string `json:"ja,omitempty"`
+	En string `json:"en"`
+}
+
+type elwaManufacturer struct {
+	Code         string           `json:"code"`
+	Descriptions elwaDescriptions `json:"descriptions"`
+}
+
+// elwaDeviceSummary is an entry of GET /elapi/v1/devices.
+type elwaDeviceSummary struct {
+	ID           string            `json:"id"`
+	DeviceType   string            `json:"deviceType"`
+	Protocol     map[string]string `json:"protocol"`
+	Manufacturer *elwaManufacturer `json:"manufacturer,omitempty"`
+}
+
+// elwaPropertyDescription describes one property in the device description.
+type elwaPropertyDescription struct {
+	EPC          string           `json:"epc"`
+	Descriptions elwaDescriptions `json:"descriptions"`
+	Writable     bool             `json:"writable"`
+	Observable   bool             `json:"observable"`
+	Schema       map[string]any   `json:"schema"`
+}
+
+// elwaDeviceDescription is the body of GET /elapi/v1/devices/{id}.
+type elwaDeviceDescription struct {
+	elwaDeviceSummary
+	EOJ          string                             `json:"eoj"`
+	Descriptions elwaDescriptions                   `json:"descriptions"`
+	Properties   map[string]elwaPropertyDescription `json:"properties"`
+}
+
+// lowerCamelCase turns an English name such as "Operation status" into "operationStatus".
+func lowerCamelCase(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for i, word := range words {
+		if i == 0 {
+			b.WriteString(strings.ToLower(word[:1]) + word[1:])
+		} else {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+// elwaDeviceType is the deviceType of a class, e.g. "homeAirConditioner".
+func elwaDeviceType(classCode echonet_lite.EOJClassCode) string {
+	if meta, ok := echonet_lite.GetClassMetadata(classCode); ok && meta.Name != "" {
+		return lowerCamelCase(meta.Name)
+	}
+	return fmt.Sprintf("0x%04X", uint16(classCode))
+}
+
+// elwaPropertyName is the resource name of a property, e.g. "operationStatus" for 0x80.
+func elwaPropertyName(classCode echonet_lite.EOJClassCode, epc echonet_lite.EPCType) string {
+	if desc, ok := echonet_lite.GetPropertyDesc(classCode, epc); ok && desc.Name != "" {
+		if name := lowerCamelCase(desc.Name); name != "" {
+			return name
+		}
+	}
+	return "epc" + epc.String()
+}
+
+// elwaDeviceID is the id of a device: its device ID, or "<ip>_<eoj>" without an identification number.
+func elwaDeviceID(device handler.IPAndEOJ, properties echonet_lite.Properties) string {
+	if id := properties.GetIdentificationNumber(); id != nil {
+		return string(handler.MakeIDString(device.EOJ, *id))
+	}
+	return device.IP.String() + "_" + device.EOJ.Specifier()
+}
+
+// elwaTarget resolves the {id} path value to a cached device.
+func (a *RESTAPIHandler) elwaTarget(r *http.Request) (handler.DeviceAndProperties, bool) {
+	id := r.PathValue("id")
+	var device handler.IPAndEOJ
+	if found := a.client.FindDeviceByIDString(handler.IDString(id)); found != nil {
+		device = *found
+	} else {
+		ip, eoj, ok := strings.Cut(id, "_")
+		if !ok {
+			return handler.DeviceAndProperties{}, false
+		}
+		parsed, err := handler.ParseDeviceIdentifier(ip + " " + eoj)
+		if err != nil {
+			return handler.DeviceAndProperties{}, false
+		}
+		device = parsed
+	}
+	devices := a.client.ListDevices(handler.FilterCriteria{Device: handler.DeviceSpecifierFromIPAndEOJ(device)})
+	if len(devices) == 0 {
+		return handler.DeviceAndProperties{}, false
+	}
+	return devices[0], true
+}
+
+// elwaResolveProperty finds the EPC of a {property} path value: a property name or the EPC in hex.
+func elwaResolveProperty(classCode echonet_lite.EOJClassCode, name string) (echonet_lite.EPCType, bool) {
+	if epc, err := handler.ParseEPCString(strings.TrimPrefix(name, "epc")); err == nil {
+		return epc, true
+	}
+	if table, ok := echonet_lite.PropertyTables[classCode]; ok {
+		for epc := range table.EPCDesc {
+			if elwaPropertyName(classCode, epc) == name {
+				return epc, true
+			}
+		}
+	}
+	for epc := range echonet_lite.ProfileSuperClass_PropertyTable.EPCDesc {
+		if elwaPropertyName(classCode, epc) == name {
+			return epc, true
+		}
+	}
+	return 0, false
+}
+
+// isOnOffProperty reports whether the aliases of a property are exactly "on" and "off", which ELWA represents as a boolean.
+func isOnOffProperty(desc echonet_lite.PropertyDesc) bool {
+	_, on := desc.Aliases["on"]
+	_, off := desc.Aliases["off"]
+	return on && off && len(desc.Aliases) == 2
+}
+
+// elwaValue converts a property to its ELWA value: a boolean for on/off properties,
+// a number for numeric properties, the alias or decoded string, or the EDT in hex.
+func elwaValue(classCode echonet_lite.EOJClassCode, prop echonet_lite.Property) any {
+	data := protocol.MakePropertyData(classCode, prop)
+	desc, ok := echonet_lite.GetPropertyDesc(classCode, prop.EPC)
+	switch {
+	case data.Number != nil:
+		return *data.Number
+	case ok && isOnOffProperty(*desc) && (data.String == "on" || data.String == "off"):
+		return data.String == "on"
+	case data.String != "":
+		return data.String
+	default:
+		return strings.ToUpper(hex.EncodeToString(prop.EDT))
+	}
+}
+
+// elwaProperty converts an ELWA value from a PUT body back to a property.
+func elwaProperty(classCode echonet_lite.EOJClassCode, epc echonet_lite.EPCType, value any) (echonet_lite.Property, error) {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return gatewayProperty(classCode, epc, "on")
+		}
+		return gatewayProperty(classCode, epc, "off")
+	case float64:
+		number := int(v)
+		if float64(number) != v {
+			return echonet_lite.Property{}, fmt.Errorf("value must be an integer: %v", v)
+		}
+		properties, err := propertiesFromProtocol(classCode, map[string]protocol.PropertyData{epc.String(): {Number: &number}})
+		if err != nil {
+			return echonet_lite.Property{}, err
+		}
+		return properties[0], nil
+	case string:
+		return gatewayProperty(classCode, epc, v)
+	default:
+		return echonet_lite.Property{}, fmt.Errorf("unsupported value: %v", value)
+	}
+}
+
+// elwaSchema is a JSON Schema of the values of a property.
+func elwaSchema(desc echonet_lite.PropertyDesc) map[string]any {
+	if isOnOffProperty(desc) {
+		return map[string]any{"type": "boolean"}
+	}
+	if number, ok := desc.Decoder.(echonet_lite.NumberDesc); ok {
+		schema := map[string]any{"type": "number", "minimum": number.Min, "maximum": number.Max}
+		if number.Unit != "" {
+			schema["unit"] = number.Unit
+		}
+		return schema
+	}
+	if len(desc.Aliases) > 0 {
+		values := make([]string, 0, len(desc.Aliases))
+		for alias := range desc.Aliases {
+			values = append(values, alias)
+		}
+		sort.Strings(values)
+		return map[string]any{"type": "string", "enum": values}
+	}
+	return map[string]any{"type": "string"}
+}
+
+func (a *RESTAPIHandler) elwaSummary(device handler.DeviceAndProperties) elwaDeviceSummary {
+	summary := elwaDeviceSummary{
+		ID:         elwaDeviceID(device.Device, device.Properties),
+		DeviceType: elwaDeviceType(device.Device.EOJ.ClassCode()),
+		Protocol:   map[string]string{"type": "ECHONET_Lite"},
+	}
+	if info := device.Properties.GetDeviceInfo(); info.ManufacturerCode != "" {
+		summary.Manufacturer = &elwaManufacturer{
+			Code:         "0x" + info.ManufacturerCode,
+			Descriptions: elwaDescriptions{Ja: info.Manufacturer.NameJa, En: info.Manufacturer.Name},
+		}
+	}
+	return summary
+}
+
+func (a *RESTAPIHandler) handleELWADevices(w http.ResponseWriter, r *http.Request) {
+	rule, ok := a.authenticate(w, r)
+	if !ok {
+		return
+	}
+	devices := a.client.ListDevices(handler.FilterCriteria{})
+	results := make([]elwaDeviceSummary, 0, len(devices))
+	for _, device := range devices {
+		if device.Device.EOJ.ClassCode() == echonet_lite.NodeProfile_ClassCode || !rule.CanRead(a.client, device.Device) {
+			continue
+		}
+		results = append(results, a.elwaSummary(device))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"devices": results, "hasMore": false})
+}
+
+// elwaReadableTarget resolves the device of the request and checks that the token can read it.
+func (a *RESTAPIHandler) elwaReadableTarget(w http.ResponseWriter, r *http.Request) (*AccessRule, handler.DeviceAndProperties, bool) {
+	rule, ok := a.authenticate(w, r)
+	if !ok {
+		return nil, handler.DeviceAndProperties{}, false
+	}
+	device, ok := a.elwaTarget(r)
+	if !ok {
+		writeRESTError(w, http.StatusNotFound, protocol.ErrorCodeTargetNotFound, "Device not found: "+r.PathValue("id"))
+		return nil, handler.DeviceAndProperties{}, false
+	}
+	if !rule.CanRead(a.client, device.Device) {
+		writePermissionDenied(w, "read", device.Device)
+		return nil, handler.DeviceAndProperties{}, false
+	}
+	return rule, device, true
+}
+
+func (a *RESTAPIHandler) handleELWADevice(w http.ResponseWriter, r *http.Request) {
+	_, device, ok := a.elwaReadableTarget(w, r)
+	if !ok {
+		return
+	}
+	classCode := device.Device.EOJ.ClassCode()
+	meta, _ := echonet_lite.GetClassMetadata(classCode)
+	description := elwaDeviceDescription{
+		elwaDeviceSummary: a.elwaSummary(device),
+		EOJ:               "0x" + device.Device.EOJ.IDString(),
+		Descriptions:      elwaDescriptions{Ja: meta.NameJa, En: meta.Name},
+		Properties:        make(map[string]elwaPropertyDescription),
+	}
+
+	var setMap, announceMap echonet_lite.PropertyMap
+	if prop, ok := device.Properties.FindEPC(echonet_lite.EPCSetPropertyMap); ok {
+		setMap = echonet_lite.DecodePropertyMap(prop.EDT)
+	}
+	if prop, ok := device.Properties.FindEPC(echonet_lite.EPCStatusAnnouncementPropertyMap); ok {
+		announceMap = echonet_lite.DecodePropertyMap(prop.EDT)
+	}
+	for _, prop := range device.Properties {
+		desc, _ := echonet_lite.GetPropertyDesc(classCode, prop.EPC)
+		var descriptions elwaDescriptions
+		var schema map[string]any
+		if desc != nil {
+			descriptions = elwaDescriptions{Ja: desc.GetName("ja"), En: desc.Name}
+			schema = elwaSchema(*desc)
+		} else {
+			schema = map[string]any{"type": "string"}
+		}
+		description.Properties[elwaPropertyName(classCode, prop.EPC)] = elwaPropertyDescription{
+			EPC:          "0x" + prop.EPC.String(),
+			Descriptions: descriptions,
+			Writable:     setMap.Has(prop.EPC),
+			Observable:   announceMap.Has(prop.EPC),
+			Schema:       schema,
+		}
+	}
+	writeJSON(w, http.StatusOK, description)
+}
+
+func (a *RESTAPIHandler) handleELWAProperties(w http.ResponseWriter, r *http.Request) {
+	_, device, ok := a.elwaReadableTarget(w, r)
+	if !ok {
+		return
+	}
+	classCode := device.Device.EOJ.ClassCode()
+	values := make(map[string]any, len(device.Properties))
+	for _, prop := range device.Properties {
+		values[elwaPropertyName(classCode, prop.EPC)] = elwaValue(classCode, prop)
+	}
+	writeJSON(w, http.StatusOK, values)
+}
+
+// handleELWAGetProperty reads one property from the device and returns it as {"name": value}.
+func (a *RESTAPIHandler) handleELWAGetProperty(w http.ResponseWriter, r *http.Request) {
+	_, device, ok := a.elwaReadableTarget(w, r)
+	if !ok {
+		return
+	}
+	classCode := device.Device.EOJ.ClassCode()
+	epc, ok := elwaResolveProperty(classCode, r.PathValue("property"))
+	if !ok {
+		writeRESTError(w, http.StatusNotFound, protocol.ErrorCodeTargetNotFound, "Property not found: "+r.PathValue("property"))
+		return
+	}
+	result, err := a.client.GetProperties(device.Device, []echonet_lite.EPCType{epc}, false)
+	if err != nil {
+		writeRESTError(w, http.StatusBadGateway, protocol.ErrorCodeEchonetCommunicationError, "Error getting property: "+err.Error())
+		return
+	}
+	prop, ok := result.Properties.FindEPC(epc)
+	if !ok {
+		writeRESTError(w, http.StatusNotFound, protocol.ErrorCodeTargetNotFound, "Property not found: "+epc.String())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{elwaPropertyName(classCode, epc): elwaValue(classCode, prop)})
+}
+
+// handleELWASetProperty sets one property from a body such as {"operationStatus": true}
+// and returns the value that was set in the same form.
+func (a *RESTAPIHandler) handleELWASetProperty(w http.ResponseWriter, r *http.Request) {
+	rule, device, ok := a.elwaReadableTarget(w, r)
+	if !ok {
+		return
+	}
+	if !rule.CanControl(a.client, device.Device) {
+		writePermissionDenied(w, "control", device.Device)
+		return
+	}
+	classCode := device.Device.EOJ.ClassCode()
+	epc, ok := elwaResolveProperty(classCode, r.PathValue("property"))
+	if !ok {
+		writeRESTError(w, http.StatusNotFound, protocol.ErrorCodeTargetNotFound, "Property not found: "+r.PathValue("property"))
+		return
+	}
+
+	var body map[string]any
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRESTRequestBodySize)).Decode(&body); err != nil {
+		writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidRequestFormat, "Invalid request body: "+err.Error())
+		return
+	}
+	name := elwaPropertyName(classCode, epc)
+	value, ok := body[name]
+	if !ok {
+		writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, "Missing value for "+name)
+		return
+	}
+	prop, err := elwaProperty(classCode, epc, value)
+	if err != nil {
+		writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, err.Error())
+		return
+	}
+	properties := echonet_lite.Properties{prop}
+	if a.onSet != nil {
+		a.onSet(device.Device, properties)
+	}
+
+	result, err := a.client.SetProperties(device.Device, properties, nil)
+	if err != nil {
+		writeRESTError(w, http.StatusBadGateway, protocol.ErrorCodeEchonetCommunicationError, "Error setting property: "+err.Error())
+		return
+	}
+	if set, ok := result.Properties.FindEPC(epc); ok {
+		prop = set
+	}
+	writeJSON(w, http.StatusOK, map[string]any{name: elwaValue(classCode, prop)})
+}
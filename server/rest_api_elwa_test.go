@@ -0,0 +1,146 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+)
+
+func TestLowerCamelCase(t *testing.T) {
+	tests := map[string]string{
+		"Operation status":         "operationStatus",
+		"Home air conditioner":     "homeAirConditioner",
+		"Measured value (current)": "measuredValueCurrent",
+		"":                         "",
+	}
+	for input, want := range tests {
+		if got := lowerCamelCase(input); got != want {
+			t.Errorf("lowerCamelCase(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestRESTAPI_ELWA(t *testing.T) {
+	c := &restTestClient{}
+	c.devices = []handler.DeviceAndProperties{
+		{
+			Device: echonet_lite.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)},
+			Properties: echonet_lite.Properties{
+				{EPC: 0x80, EDT: []byte{0x31}},
+				{EPC: 0xB3, EDT: []byte{25}},
+				{EPC: 0x8A, EDT: []byte{0x00, 0x00, 0x0B}},
+				{EPC: 0x9E, EDT: []byte{2, 0x80, 0xB3}},
+				{EPC: 0x9D, EDT: []byte{1, 0x80}},
+			},
+		},
+	}
+	api := NewRESTAPIHandler(c)
+	api.elwa = true
+	mux := http.NewServeMux()
+	api.Register(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	do := func(method, path, body string, v any) int {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != nil {
+			if err := json.Unmarshal(data, v); err != nil {
+				t.Fatalf("%s %s: invalid JSON %q", method, path, data)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	var list struct {
+		Devices []elwaDeviceSummary `json:"devices"`
+	}
+	if status := do("GET", "/elapi/v1/devices", "", &list); status != http.StatusOK || len(list.Devices) != 1 {
+		t.Fatalf("unexpected device list: %d %+v", status, list)
+	}
+	device := list.Devices[0]
+	if device.ID != "192.168.1.10_0130:1" || device.DeviceType != "homeAirConditioner" || device.Manufacturer == nil || device.Manufacturer.Code != "0x00000B" {
+		t.Errorf("unexpected device: %+v", device)
+	}
+
+	var description elwaDeviceDescription
+	if status := do("GET", "/elapi/v1/devices/"+device.ID, "", &description); status != http.StatusOK {
+		t.Fatalf("unexpected status for the description: %d", status)
+	}
+	status, ok := description.Properties["operationStatus"]
+	if !ok || status.EPC != "0x80" || !status.Writable || !status.Observable || status.Schema["type"] != "boolean" {
+		t.Errorf("unexpected operationStatus description: %+v", status)
+	}
+	if temp := description.Properties["temperatureSetting"]; !temp.Writable || temp.Observable || temp.Schema["type"] != "number" {
+		t.Errorf("unexpected temperatureSetting description: %+v", temp)
+	}
+
+	var values map[string]any
+	do("GET", "/elapi/v1/devices/"+device.ID+"/properties", "", &values)
+	if values["operationStatus"] != false || values["temperatureSetting"] != float64(25) {
+		t.Errorf("unexpected values: %v", values)
+	}
+
+	// 1つのプロパティは機器から読む（モックは 0x80=on を返す）
+	values = nil
+	if code := do("GET", "/elapi/v1/devices/"+device.ID+"/properties/operationStatus", "", &values); code != http.StatusOK || values["operationStatus"] != true {
+		t.Errorf("unexpected property: %d %v", code, values)
+	}
+
+	values = nil
+	if code := do("PUT", "/elapi/v1/devices/"+device.ID+"/properties/temperatureSetting", `{"temperatureSetting": 26}`, &values); code != http.StatusOK || values["temperatureSetting"] != float64(26) {
+		t.Errorf("unexpected set response: %d %v", code, values)
+	}
+	if len(c.setRequest) != 1 || c.setRequest[0].EPC != 0xB3 || c.setRequest[0].EDT[0] != 26 {
+		t.Errorf("unexpected set request: %v", c.setRequest)
+	}
+	do("PUT", "/elapi/v1/devices/"+device.ID+"/properties/80", `{"operationStatus": true}`, nil)
+	if len(c.setRequest) != 1 || c.setRequest[0].EPC != 0x80 || c.setRequest[0].EDT[0] != 0x30 {
+		t.Errorf("unexpected set request: %v", c.setRequest)
+	}
+
+	tests := []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/elapi/v1/devices/192.168.1.20_0130:1", "", http.StatusNotFound},
+		{"GET", "/elapi/v1/devices/unknown", "", http.StatusNotFound},
+		{"GET", "/elapi/v1/devices/" + device.ID + "/properties/unknownProperty", "", http.StatusNotFound},
+		{"PUT", "/elapi/v1/devices/" + device.ID + "/properties/operationStatus", `{"temperatureSetting": 26}`, http.StatusBadRequest},
+		{"PUT", "/elapi/v1/devices/" + device.ID + "/properties/operationStatus", `{"operationStatus": 1.5}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if status := do(tt.method, tt.path, tt.body, nil); status != tt.status {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, status, tt.status)
+		}
+	}
+
+	// 無効な場合はルートを登録しない
+	plain := http.NewServeMux()
+	NewRESTAPIHandler(c).Register(plain)
+	req := httptest.NewRequest("GET", "/elapi/v1/devices", nil)
+	rec := httptest.NewRecorder()
+	plain.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when ELWA is disabled, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/base64"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"echonet-list/client"
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// exportResponse is the body of GET /api/export.
+type exportResponse struct {
+	ExportedAt time.Time      `json:"exportedAt"`
+	Anonymized bool           `json:"anonymized"`
+	Devices    []exportDevice `json:"devices"`
+}
+
+// exportDevice is a device with its recent property history.
+type exportDevice struct {
+	protocol.Device
+	History []protocol.HistoryEntry `json:"history,omitempty"`
+}
+
+// handleExport exports all readable devices and their history as one JSON document,
+// e.g. to attach to a bug report. Unless ?anonymize=false is given, IP addresses,
+// identification numbers, production numbers and facility codes are replaced with
+// pseudonyms that stay the same while the server is running, so that devices in
+// several exports can still be matched with each other.
+func (a *RESTAPIHandler) handleExport(w http.ResponseWriter, r *http.Request) {
+	rule, ok := a.authenticate(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+
+	anonymize := true
+	if value := query.Get("anonymize"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, "Invalid anonymize: "+value)
+			return
+		}
+		anonymize = parsed
+	}
+
+	historyLimit := defaultHistoryLimit
+	if value := query.Get("history"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, "Invalid history limit: "+value)
+			return
+		}
+		historyLimit = parsed
+	}
+
+	result := exportResponse{
+		ExportedAt: protocol.ServerTime(time.Now()),
+		Anonymized: anonymize,
+		Devices:    []exportDevice{},
+	}
+	for _, device := range a.client.ListDevices(handler.FilterCriteria{}) {
+		if !rule.CanRead(a.client, device.Device) {
+			continue
+		}
+
+		var history []client.DeviceHistoryEntry
+		if historyLimit > 0 {
+			settableOnly := false
+			// History may be disabled on the server; export the devices anyway.
+			history, _ = a.client.GetDeviceHistory(device.Device, client.DeviceHistoryOptions{Limit: historyLimit, SettableOnly: &settableOnly})
+		}
+
+		ipAndEOJ, properties := device.Device, device.Properties
+		if anonymize {
+			ipAndEOJ, properties = a.anonymizer.Device(ipAndEOJ), a.anonymizer.Properties(properties)
+		}
+		exported := exportDevice{
+			Device: protocol.DeviceToProtocol(ipAndEOJ, properties, time.Time{}, a.client.IsOfflineDevice(device.Device)),
+		}
+		for _, entry := range history {
+			value := entry.Value
+			if anonymize && slices.Contains(handler.AnonymizedEPCs, entry.EPC) {
+				value = a.anonymizeHistoryValue(device.Device.EOJ.ClassCode(), entry.EPC, value)
+			}
+			epc := ""
+			if entry.EPC != 0 {
+				epc = entry.EPC.String()
+			}
+			exported.History = append(exported.History, protocol.HistoryEntry{
+				Timestamp: protocol.ServerTime(entry.Timestamp),
+				EPC:       epc,
+				Value:     value,
+				Origin:    entry.Origin,
+				Settable:  entry.Settable,
+			})
+		}
+		result.Devices = append(result.Devices, exported)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// anonymizeHistoryValue replaces a sensitive history value with the same pseudonym as the device export.
+// Values that cannot be decoded are dropped rather than exported as is.
+func (a *RESTAPIHandler) anonymizeHistoryValue(classCode echonet_lite.EOJClassCode, epc echonet_lite.EPCType, value protocol.PropertyData) protocol.PropertyData {
+	edt, err := base64.StdEncoding.DecodeString(value.EDT)
+	if err != nil || len(edt) == 0 {
+		return protocol.PropertyData{}
+	}
+	return protocol.MakePropertyData(classCode, a.anonymizer.Property(echonet_lite.Property{EPC: epc, EDT: edt}))
+}
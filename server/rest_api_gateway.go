@@ -0,0 +1,162 @@
+package server
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// maxGatewayValueSize limits the body of PUT /d/{device}/{epc}.
+const maxGatewayValueSize = 1 << 10
+
+// gatewayTarget resolves the {device} and {epc} path values of the /d/ gateway.
+// {device} is a device alias or ID and {epc} is the EPC in hex, e.g. /d/aircon/80
+func (a *RESTAPIHandler) gatewayTarget(r *http.Request) (handler.IPAndEOJ, echonet_lite.EPCType, int, error) {
+	name := r.PathValue("device")
+	device, ok := a.client.GetDeviceByAlias(name)
+	if !ok {
+		found := a.client.FindDeviceByIDString(handler.IDString(name))
+		if found == nil {
+			return handler.IPAndEOJ{}, 0, http.StatusNotFound, fmt.Errorf("device not found: %s", name)
+		}
+		device = *found
+	}
+	epc, err := handler.ParseEPCString(r.PathValue("epc"))
+	if err != nil {
+		return handler.IPAndEOJ{}, 0, http.StatusBadRequest, fmt.Errorf("invalid EPC: %v", err)
+	}
+	return device, epc, http.StatusOK, nil
+}
+
+// gatewayValue formats a property value as plain text: the number if the property is numeric,
+// otherwise the alias or decoded string, otherwise the EDT in hex.
+func gatewayValue(classCode echonet_lite.EOJClassCode, prop echonet_lite.Property) string {
+	data := protocol.MakePropertyData(classCode, prop)
+	switch {
+	case data.Number != nil:
+		return strconv.Itoa(*data.Number)
+	case data.String != "":
+		return data.String
+	default:
+		return strings.ToUpper(hex.EncodeToString(prop.EDT))
+	}
+}
+
+// gatewayProperty converts a plain text value for the gateway: an alias or string such as "on" or "25",
+// or the EDT in hex such as "30".
+func gatewayProperty(classCode echonet_lite.EOJClassCode, epc echonet_lite.EPCType, value string) (echonet_lite.Property, error) {
+	properties, err := propertiesFromProtocol(classCode, map[string]protocol.PropertyData{epc.String(): {String: value}})
+	if err == nil {
+		return properties[0], nil
+	}
+	if edt, hexErr := hex.DecodeString(value); hexErr == nil && len(edt) > 0 {
+		return echonet_lite.Property{EPC: epc, EDT: edt}, nil
+	}
+	return echonet_lite.Property{}, err
+}
+
+func writeGatewayValue(w http.ResponseWriter, value string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, value+"\n")
+}
+
+// handleGatewayGet returns one property of a device as plain text, read from the device
+// (?cached=true returns the cached value without ECHONET Lite communication).
+// The /d/ gateway is meant for IoT buttons and Shortcuts automations that cannot handle JSON.
+func (a *RESTAPIHandler) handleGatewayGet(w http.ResponseWriter, r *http.Request) {
+	rule, ok := a.authenticate(w, r)
+	if !ok {
+		return
+	}
+	device, epc, status, err := a.gatewayTarget(r)
+	if err != nil {
+		writeRESTError(w, status, protocol.ErrorCodeInvalidParameters, err.Error())
+		return
+	}
+	if !rule.CanRead(a.client, device) {
+		writePermissionDenied(w, "read", device)
+		return
+	}
+
+	var properties echonet_lite.Properties
+	if r.URL.Query().Get("cached") == "true" {
+		if devices := a.client.ListDevices(handler.FilterCriteria{Device: handler.DeviceSpecifierFromIPAndEOJ(device)}); len(devices) > 0 {
+			properties = devices[0].Properties
+		}
+	} else {
+		result, err := a.client.GetProperties(device, []echonet_lite.EPCType{epc}, false)
+		if err != nil {
+			writeRESTError(w, http.StatusBadGateway, protocol.ErrorCodeEchonetCommunicationError, "Error getting property: "+err.Error())
+			return
+		}
+		properties = result.Properties
+	}
+
+	prop, ok := properties.FindEPC(epc)
+	if !ok {
+		writeRESTError(w, http.StatusNotFound, protocol.ErrorCodeTargetNotFound, "Property not found: "+epc.String())
+		return
+	}
+	writeGatewayValue(w, gatewayValue(device.EOJ.ClassCode(), prop))
+}
+
+// handleGatewaySet sets one property of a device from a plain text body or ?value=,
+// and returns the value that was set as plain text.
+func (a *RESTAPIHandler) handleGatewaySet(w http.ResponseWriter, r *http.Request) {
+	rule, ok := a.authenticate(w, r)
+	if !ok {
+		return
+	}
+	device, epc, status, err := a.gatewayTarget(r)
+	if err != nil {
+		writeRESTError(w, status, protocol.ErrorCodeInvalidParameters, err.Error())
+		return
+	}
+	if !rule.CanControl(a.client, device) {
+		writePermissionDenied(w, "control", device)
+		return
+	}
+
+	value := r.URL.Query().Get("value")
+	if value == "" {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxGatewayValueSize))
+		if err != nil {
+			writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidRequestFormat, "Error reading request body: "+err.Error())
+			return
+		}
+		value = strings.TrimSpace(string(body))
+	}
+	if value == "" {
+		writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, "No value specified")
+		return
+	}
+
+	classCode := device.EOJ.ClassCode()
+	prop, err := gatewayProperty(classCode, epc, value)
+	if err != nil {
+		writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, err.Error())
+		return
+	}
+	properties := echonet_lite.Properties{prop}
+	if a.onSet != nil {
+		a.onSet(device, properties)
+	}
+
+	result, err := a.client.SetProperties(device, properties, nil)
+	if err != nil {
+		writeRESTError(w, http.StatusBadGateway, protocol.ErrorCodeEchonetCommunicationError, "Error setting property: "+err.Error())
+		return
+	}
+	if set, ok := result.Properties.FindEPC(epc); ok {
+		prop = set
+	}
+	writeGatewayValue(w, gatewayValue(classCode, prop))
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// The /grafana/ routes implement the JSON datasource protocol of Grafana (the SimpleJSON / Infinity
+// "JSON API" plugins), so that the property history of the server can be charted without an
+// intermediate database. Set the URL of the datasource to https://<server>/grafana.
+//
+//	GET  /grafana/         health check
+//	POST /grafana/search   metric names, e.g. "aircon/B3" (body: {"target": "aircon"} filters by substring)
+//	POST /grafana/query    time series of the requested metrics in the requested range
+//
+// A metric is "<device>/<EPC>", where <device> is an alias, a device ID or "IP EOJ".
+// Numeric properties are charted by their number and on/off properties as 1 and 0.
+
+// grafanaSearchRequest is the body of POST /grafana/search.
+type grafanaSearchRequest struct {
+	Target string `json:"target"`
+}
+
+// grafanaQueryRequest is the body of POST /grafana/query.
+type grafanaQueryRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	Targets []struct {
+		Target string `json:"target"`
+	} `json:"targets"`
+	MaxDataPoints int `json:"maxDataPoints"`
+}
+
+// grafanaSeries is one time series of the POST /grafana/query response.
+// Each data point is [value, unix time in milliseconds] as Grafana expects.
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+func (a *RESTAPIHandler) registerGrafana(mux *http.ServeMux) {
+	mux.HandleFunc("GET /grafana/{$}", a.handleGrafanaHealth)
+	mux.HandleFunc("POST /grafana/search", a.handleGrafanaSearch)
+	mux.HandleFunc("POST /grafana/query", a.handleGrafanaQuery)
+}
+
+// grafanaNumber returns the value of a property to chart: its number, or 1 and 0 for on/off.
+func grafanaNumber(value protocol.PropertyData) (float64, bool) {
+	switch {
+	case value.Number != nil:
+		return float64(*value.Number), true
+	case value.String == "on":
+		return 1, true
+	case value.String == "off":
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// grafanaDeviceName is the name of a device in metric names: its first alias, or "IP EOJ".
+func (a *RESTAPIHandler) grafanaDeviceName(device handler.IPAndEOJ) string {
+	if aliases := a.client.GetAliases(device); len(aliases) > 0 {
+		return aliases[0]
+	}
+	return device.Specifier()
+}
+
+// grafanaTarget resolves a metric name such as "aircon/B3" to a device and an EPC.
+func (a *RESTAPIHandler) grafanaTarget(target string) (handler.IPAndEOJ, echonet_lite.EPCType, error) {
+	i := strings.LastIndex(target, "/")
+	if i < 0 {
+		return handler.IPAndEOJ{}, 0, fmt.Errorf("invalid target %q, expected <device>/<EPC>", target)
+	}
+	name := target[:i]
+	epc, err := handler.ParseEPCString(target[i+1:])
+	if err != nil {
+		return handler.IPAndEOJ{}, 0, fmt.Errorf("invalid EPC in target %q: %v", target, err)
+	}
+	if device, ok := a.client.GetDeviceByAlias(name); ok {
+		return device, epc, nil
+	}
+	if device := a.client.FindDeviceByIDString(handler.IDString(name)); device != nil {
+		return *device, epc, nil
+	}
+	device, err := handler.ParseDeviceIdentifier(name)
+	if err != nil {
+		return handler.IPAndEOJ{}, 0, fmt.Errorf("device not found: %s", name)
+	}
+	return device, epc, nil
+}
+
+func (a *RESTAPIHandler) handleGrafanaHealth(w http.ResponseWriter, r *http.Request) {
+	if _, ok := a.authenticate(w, r); !ok {
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleGrafanaSearch lists the properties of readable devices that can be charted.
+func (a *RESTAPIHandler) handleGrafanaSearch(w http.ResponseWriter, r *http.Request) {
+	rule, ok := a.authenticate(w, r)
+	if !ok {
+		return
+	}
+	var req grafanaSearchRequest
+	// Grafana may send an empty body
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRESTRequestBodySize)).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidRequestFormat, "Invalid request body: "+err.Error())
+		return
+	}
+
+	metrics := []string{}
+	for _, device := range a.client.ListDevices(handler.FilterCriteria{}) {
+		if !rule.CanRead(a.client, device.Device) {
+			continue
+		}
+		name := a.grafanaDeviceName(device.Device)
+		for _, prop := range device.Properties {
+			if _, ok := grafanaNumber(protocol.MakePropertyData(device.Device.EOJ.ClassCode(), prop)); !ok {
+				continue
+			}
+			metric := name + "/" + prop.EPC.String()
+			if strings.Contains(metric, req.Target) {
+				metrics = append(metrics, metric)
+			}
+		}
+	}
+	sort.Strings(metrics)
+	writeJSON(w, http.StatusOK, metrics)
+}
+
+// handleGrafanaQuery returns the history of each target within the range, oldest first.
+func (a *RESTAPIHandler) handleGrafanaQuery(w http.ResponseWriter, r *http.Request) {
+	rule, ok := a.authenticate(w, r)
+	if !ok {
+		return
+	}
+	var req grafanaQueryRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRESTRequestBodySize)).Decode(&req); err != nil {
+		writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidRequestFormat, "Invalid request body: "+err.Error())
+		return
+	}
+	if !req.Range.From.IsZero() && !req.Range.To.IsZero() && req.Range.To.Before(req.Range.From) {
+		writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, "range.to must not be before range.from")
+		return
+	}
+
+	results := make([]grafanaSeries, 0, len(req.Targets))
+	for _, target := range req.Targets {
+		if target.Target == "" {
+			continue
+		}
+		device, epc, err := a.grafanaTarget(target.Target)
+		if err != nil {
+			writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, err.Error())
+			return
+		}
+		if !rule.CanRead(a.client, device) {
+			writePermissionDenied(w, "read", device)
+			return
+		}
+
+		series := grafanaSeries{Target: target.Target, Datapoints: [][2]float64{}}
+		entries := a.history.Query(device, handler.HistoryQuery{Since: req.Range.From, Until: req.Range.To})
+		for _, entry := range entries {
+			if entry.EPC != epc {
+				continue
+			}
+			if value, ok := grafanaNumber(protocol.PropertyDataFromHandlerValue(entry.Value)); ok {
+				series.Datapoints = append(series.Datapoints, [2]float64{value, float64(entry.Timestamp.UnixMilli())})
+			}
+		}
+		// The history is newest first; Grafana expects the points in time order
+		slices.Reverse(series.Datapoints)
+		if req.MaxDataPoints > 0 && len(series.Datapoints) > req.MaxDataPoints {
+			series.Datapoints = series.Datapoints[len(series.Datapoints)-req.MaxDataPoints:]
+		}
+		results = append(results, series)
+	}
+	writeJSON(w, http.StatusOK, results)
+}
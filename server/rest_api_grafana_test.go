@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+)
+
+func TestRESTAPI_Grafana(t *testing.T) {
+	aircon := echonet_lite.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	c := &commandTestClient{}
+	c.devices = []handler.DeviceAndProperties{
+		{Device: aircon, Properties: echonet_lite.Properties{
+			{EPC: 0x80, EDT: []byte{0x30}},
+			{EPC: 0xB3, EDT: []byte{25}},
+		}},
+	}
+
+	store := handler.NewMemoryDeviceHistoryStore(handler.HistoryOptions{PerDeviceSettableLimit: 100, PerDeviceNonSettableLimit: 100})
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i, temp := range []int{24, 25, 26} {
+		store.Record(handler.DeviceHistoryEntry{Timestamp: base.Add(time.Duration(i) * time.Minute), Device: aircon, EPC: 0xB3, Value: handler.PropertyValue{Number: &temp}, Settable: true})
+	}
+	store.Record(handler.DeviceHistoryEntry{Timestamp: base.Add(30 * time.Second), Device: aircon, EPC: 0x80, Value: handler.PropertyValue{String: "on"}, Settable: true})
+
+	api := NewRESTAPIHandler(c)
+	api.history = store
+	mux := http.NewServeMux()
+	api.Register(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	post := func(path, body string, v any) int {
+		t.Helper()
+		resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if v != nil && resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	resp, err := http.Get(srv.URL + "/grafana/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected health check status: %d", resp.StatusCode)
+	}
+
+	// 数値と on/off のプロパティだけが候補になる
+	var metrics []string
+	post("/grafana/search", `{"target": ""}`, &metrics)
+	if strings.Join(metrics, ",") != "aircon/80,aircon/B3" {
+		t.Errorf("unexpected metrics: %v", metrics)
+	}
+	metrics = nil
+	post("/grafana/search", `{"target": "B3"}`, &metrics)
+	if len(metrics) != 1 {
+		t.Errorf("unexpected filtered metrics: %v", metrics)
+	}
+
+	var series []grafanaSeries
+	body := `{"range": {"from": "2026-10-01T12:00:30Z", "to": "2026-10-01T12:10:00Z"}, "targets": [{"target": "aircon/B3"}, {"target": "192.168.1.10 0130:1/80"}]}`
+	if status := post("/grafana/query", body, &series); status != http.StatusOK || len(series) != 2 {
+		t.Fatalf("unexpected query response: %d %+v", status, series)
+	}
+	points := series[0].Datapoints
+	if len(points) != 2 || points[0][0] != 25 || points[1][0] != 26 || points[0][1] != float64(base.Add(time.Minute).UnixMilli()) {
+		t.Errorf("unexpected B3 datapoints: %v", points)
+	}
+	if points := series[1].Datapoints; len(points) != 1 || points[0][0] != 1 {
+		t.Errorf("unexpected 80 datapoints: %v", points)
+	}
+
+	// maxDataPoints は新しい点を残す
+	series = nil
+	post("/grafana/query", `{"targets": [{"target": "aircon/B3"}], "maxDataPoints": 1}`, &series)
+	if len(series) != 1 || len(series[0].Datapoints) != 1 || series[0].Datapoints[0][0] != 26 {
+		t.Errorf("unexpected limited datapoints: %+v", series)
+	}
+
+	for _, body := range []string{
+		`{"targets": [{"target": "aircon"}]}`,
+		`{"targets": [{"target": "unknown/B3"}]}`,
+		`{"range": {"from": "2026-10-02T00:00:00Z", "to": "2026-10-01T00:00:00Z"}, "targets": []}`,
+		`not json`,
+	} {
+		if status := post("/grafana/query", body, nil); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, status)
+		}
+	}
+}
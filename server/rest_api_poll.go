@@ -0,0 +1,103 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"echonet-list/protocol"
+)
+
+const (
+	// defaultPollTimeout is how long GET /api/poll waits for an event when ?timeout= is not given.
+	defaultPollTimeout = 30 * time.Second
+	// maxPollTimeout limits ?timeout= so that idle connections are not kept forever.
+	maxPollTimeout = 120 * time.Second
+)
+
+// pollResponse is the body of GET /api/poll.
+type pollResponse struct {
+	// Seq is the sequence number to pass as ?since= in the next poll.
+	Seq uint64 `json:"seq"`
+	// Missed is true when events after ?since= were dropped (or the server restarted);
+	// the client should reload the device list.
+	Missed bool        `json:"missed,omitempty"`
+	Events []pollEvent `json:"events"`
+}
+
+// pollEvent is a notification message as sent to WebSocket clients, with its sequence number.
+type pollEvent struct {
+	Seq uint64 `json:"seq"`
+	protocol.Message
+}
+
+// handlePoll returns the notifications after ?since=, waiting up to ?timeout= seconds until there is one.
+// It is the same stream as GET /api/events for clients without WebSocket or SSE support, such as
+// microcontroller dashboards. Without ?since=, it returns the current sequence number immediately.
+func (a *RESTAPIHandler) handlePoll(w http.ResponseWriter, r *http.Request) {
+	rule, ok := a.authenticate(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+
+	timeout := defaultPollTimeout
+	if value := query.Get("timeout"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 || time.Duration(seconds)*time.Second > maxPollTimeout {
+			writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, "Invalid timeout: "+value)
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	sinceValue := query.Get("since")
+	if sinceValue == "" {
+		writeJSON(w, http.StatusOK, pollResponse{Seq: a.events.lastSeq(), Events: []pollEvent{}})
+		return
+	}
+	since, err := strconv.ParseUint(sinceValue, 10, 64)
+	if err != nil {
+		writeRESTError(w, http.StatusBadRequest, protocol.ErrorCodeInvalidParameters, "Invalid since: "+sinceValue)
+		return
+	}
+	types := parseEventTypes(query["types"])
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	result := pollResponse{Events: []pollEvent{}}
+	for {
+		events, last, missed, published := a.events.since(since)
+		result.Seq = last
+		result.Missed = result.Missed || missed
+		for _, ev := range events {
+			if types != nil && !types[ev.msgType] {
+				continue
+			}
+			if ev.device != nil && !rule.CanRead(a.client, *ev.device) {
+				continue
+			}
+			var msg protocol.Message
+			if err := json.Unmarshal(ev.data, &msg); err != nil {
+				continue
+			}
+			result.Events = append(result.Events, pollEvent{Seq: ev.seq, Message: msg})
+		}
+		if len(result.Events) > 0 || result.Missed {
+			break
+		}
+		// Events that this client does not receive still advance its position
+		since = last
+
+		select {
+		case <-published:
+			continue
+		case <-timer.C:
+		case <-r.Context().Done():
+		case <-a.events.ctx.Done():
+		}
+		break
+	}
+	writeJSON(w, http.StatusOK, result)
+}
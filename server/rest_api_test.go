@@ -0,0 +1,296 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"echonet-list/client"
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// restTestClient は REST API テスト用に一部のメソッドを上書きしたモック
+type restTestClient struct {
+	mockECHONETListClient
+	devices    []handler.DeviceAndProperties
+	getEPCs    []echonet_lite.EPCType
+	setRequest echonet_lite.Properties
+	history    []client.DeviceHistoryEntry
+}
+
+func (c *restTestClient) ListDevices(criteria handler.FilterCriteria) []handler.DeviceAndProperties {
+	var result []handler.DeviceAndProperties
+	for _, d := range c.devices {
+		if criteria.Device.IP != nil && !criteria.Device.IP.Equal(d.Device.IP) {
+			continue
+		}
+		if criteria.Device.ClassCode != nil && *criteria.Device.ClassCode != d.Device.EOJ.ClassCode() {
+			continue
+		}
+		result = append(result, d)
+	}
+	return result
+}
+
+func (c *restTestClient) GetProperties(device echonet_lite.IPAndEOJ, epcs []echonet_lite.EPCType, _ bool) (handler.DeviceAndProperties, error) {
+	c.getEPCs = epcs
+	return handler.DeviceAndProperties{Device: device, Properties: echonet_lite.Properties{{EPC: 0x80, EDT: []byte{0x30}}}}, nil
+}
+
+func (c *restTestClient) SetProperties(device echonet_lite.IPAndEOJ, properties echonet_lite.Properties, _ []echonet_lite.EPCType) (handler.DeviceAndProperties, error) {
+	c.setRequest = properties
+	return handler.DeviceAndProperties{Device: device, Properties: properties}, nil
+}
+
+func (c *restTestClient) GetDeviceHistory(_ echonet_lite.IPAndEOJ, _ client.DeviceHistoryOptions) ([]client.DeviceHistoryEntry, error) {
+	return c.history, nil
+}
+
+func newRESTTestServer(t *testing.T) (*restTestClient, *httptest.Server) {
+	t.Helper()
+	c := &restTestClient{
+		devices: []handler.DeviceAndProperties{
+			{
+				Device:     echonet_lite.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)},
+				Properties: echonet_lite.Properties{{EPC: 0x80, EDT: []byte{0x30}}},
+			},
+		},
+	}
+	mux := http.NewServeMux()
+	NewRESTAPIHandler(c).Register(mux)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return c, srv
+}
+
+func TestRESTAPI_ListAndGetDevice(t *testing.T) {
+	_, srv := newRESTTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/api/devices")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var devices []protocol.Device
+	if err := json.NewDecoder(resp.Body).Decode(&devices); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || len(devices) != 1 || devices[0].EOJ != "0130:1" {
+		t.Fatalf("unexpected list response: %d %+v", resp.StatusCode, devices)
+	}
+
+	resp2, err := http.Get(srv.URL + "/api/devices/192.168.1.10/0130:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp2.Body.Close()
+	var device protocol.Device
+	if err := json.NewDecoder(resp2.Body).Decode(&device); err != nil {
+		t.Fatal(err)
+	}
+	if device.Properties["80"].String != "on" {
+		t.Errorf("expected operation status on, got %+v", device.Properties)
+	}
+
+	resp3, err := http.Get(srv.URL + "/api/devices/192.168.1.99/0130:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp3.Body.Close()
+	if resp3.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown device, got %d", resp3.StatusCode)
+	}
+
+	resp4, err := http.Get(srv.URL + "/api/devices/not-an-ip/0130:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp4.Body.Close()
+	if resp4.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid IP, got %d", resp4.StatusCode)
+	}
+}
+
+func TestRESTAPI_GetAndSetProperties(t *testing.T) {
+	c, srv := newRESTTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/api/devices/192.168.1.10/0130:1/properties?epc=80,B0&epc=B3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	}
+	if len(c.getEPCs) != 3 || c.getEPCs[0] != 0x80 || c.getEPCs[1] != 0xB0 || c.getEPCs[2] != 0xB3 {
+		t.Errorf("unexpected EPCs: %v", c.getEPCs)
+	}
+
+	resp2, err := http.Post(srv.URL+"/api/devices/192.168.1.10/0130:1/properties", "application/json",
+		strings.NewReader(`{"80": {"string": "off"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %d", resp2.StatusCode)
+	}
+	if len(c.setRequest) != 1 || c.setRequest[0].EPC != 0x80 || c.setRequest[0].EDT[0] != 0x31 {
+		t.Errorf("unexpected set request: %+v", c.setRequest)
+	}
+
+	resp3, err := http.Post(srv.URL+"/api/devices/192.168.1.10/0130:1/properties", "application/json",
+		strings.NewReader(`{"80": {"string": "unknown-value"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp3.Body.Close()
+	var body restError
+	if err := json.NewDecoder(resp3.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if resp3.StatusCode != http.StatusBadRequest || body.Error.Code != protocol.ErrorCodeInvalidParameters {
+		t.Errorf("expected invalid parameters error, got %d %+v", resp3.StatusCode, body)
+	}
+}
+
+func TestRESTAPI_Export(t *testing.T) {
+	c, srv := newRESTTestServer(t)
+	serial := []byte("SN-0001234567")
+	c.devices[0].Properties = append(c.devices[0].Properties, echonet_lite.Property{EPC: echonet_lite.EPCProductionNumber, EDT: serial})
+	c.history = []client.DeviceHistoryEntry{
+		{EPC: echonet_lite.EPCProductionNumber, Value: protocol.PropertyData{EDT: base64.StdEncoding.EncodeToString(serial)}},
+		{EPC: 0x80, Value: protocol.PropertyData{EDT: "MA==", String: "on"}},
+	}
+
+	export := func(query string) ([]byte, exportResponse) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/api/export" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body exportResponse
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || len(body.Devices) != 1 {
+			t.Fatalf("unexpected export response: %d %s", resp.StatusCode, data)
+		}
+		return data, body
+	}
+
+	// 既定では IP アドレスと製造番号が仮名になる
+	data, body := export("")
+	if !body.Anonymized || strings.Contains(string(data), "192.168.1.10") || strings.Contains(string(data), base64.StdEncoding.EncodeToString(serial)) {
+		t.Errorf("export is not anonymized: %s", data)
+	}
+	device := body.Devices[0]
+	if !strings.HasPrefix(device.IP, "10.") || device.Properties["80"].String != "on" || len(device.History) != 2 {
+		t.Errorf("unexpected anonymized device: %+v", device)
+	}
+	// 履歴の値もデバイスと同じ仮名になる
+	if device.History[0].Value.EDT != device.Properties["8D"].EDT || device.History[1].Value.String != "on" {
+		t.Errorf("history is not anonymized consistently: %+v", device.History)
+	}
+
+	// 仮名はエクスポートをまたいで変わらない
+	if _, again := export(""); again.Devices[0].IP != device.IP {
+		t.Errorf("pseudonym changed between exports: %s -> %s", device.IP, again.Devices[0].IP)
+	}
+
+	_, raw := export("?anonymize=false&history=0")
+	if raw.Anonymized || raw.Devices[0].IP != "192.168.1.10" || len(raw.Devices[0].History) != 0 {
+		t.Errorf("unexpected raw export: %+v", raw)
+	}
+}
+
+// gatewayTestClient はエアコンにエイリアス aircon がある REST API テスト用のモック
+type gatewayTestClient struct {
+	restTestClient
+}
+
+func (c *gatewayTestClient) GetDeviceByAlias(alias string) (echonet_lite.IPAndEOJ, bool) {
+	if alias == "aircon" {
+		return c.devices[0].Device, true
+	}
+	return echonet_lite.IPAndEOJ{}, false
+}
+
+func TestRESTAPI_Gateway(t *testing.T) {
+	c := &gatewayTestClient{}
+	c.devices = []handler.DeviceAndProperties{
+		{
+			Device:     echonet_lite.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)},
+			Properties: echonet_lite.Properties{{EPC: 0x80, EDT: []byte{0x31}}},
+		},
+	}
+	mux := http.NewServeMux()
+	NewRESTAPIHandler(c).Register(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	do := func(method, path, body string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(data)
+	}
+
+	// 取得はデバイスから、?cached=true はキャッシュから
+	if status, body := do(http.MethodGet, "/d/aircon/80", ""); status != http.StatusOK || body != "on\n" {
+		t.Errorf("unexpected GET: %d %q", status, body)
+	}
+	if status, body := do(http.MethodGet, "/d/aircon/80?cached=true", ""); status != http.StatusOK || body != "off\n" {
+		t.Errorf("unexpected cached GET: %d %q", status, body)
+	}
+
+	// 値はエイリアス・数値・16進数の EDT のいずれでもよい
+	tests := []struct {
+		method, path, body string
+		want               []byte
+		response           string
+	}{
+		{http.MethodPut, "/d/aircon/B3", "25\n", []byte{25}, "25\n"},
+		{http.MethodPost, "/d/aircon/80?value=off", "", []byte{0x31}, "off\n"},
+		{http.MethodPut, "/d/aircon/80", "30", []byte{0x30}, "on\n"},
+	}
+	for _, tt := range tests {
+		status, body := do(tt.method, tt.path, tt.body)
+		if status != http.StatusOK || body != tt.response {
+			t.Errorf("%s %s: unexpected response %d %q", tt.method, tt.path, status, body)
+		}
+		if len(c.setRequest) != 1 || string(c.setRequest[0].EDT) != string(tt.want) {
+			t.Errorf("%s %s: unexpected set request %v", tt.method, tt.path, c.setRequest)
+		}
+	}
+
+	if status, _ := do(http.MethodGet, "/d/unknown/80", ""); status != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown device, got %d", status)
+	}
+	if status, _ := do(http.MethodPut, "/d/aircon/B3", "warm"); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid value, got %d", status)
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"echonet-list/config"
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+)
+
+// ParseRetryOptions converts the [retry] section of the configuration.
+// A key of 4 hexadecimal digits is a class code ("03B7"); any other key must be an IP address.
+func ParseRetryOptions(global config.RetryPolicyConfig, overrides map[string]config.RetryPolicyConfig) (handler.RetryOptions, error) {
+	parse := func(name string, c config.RetryPolicyConfig) (handler.RetryPolicy, error) {
+		policy := handler.RetryPolicy{MaxRetries: c.MaxRetries}
+		if c.MaxRetries < 0 {
+			return policy, fmt.Errorf("invalid max_retries of %s: %d", name, c.MaxRetries)
+		}
+		if c.Interval != "" {
+			interval, err := time.ParseDuration(c.Interval)
+			if err != nil || interval < 0 {
+				return policy, fmt.Errorf("invalid retry interval of %s: %q", name, c.Interval)
+			}
+			policy.RetryInterval = interval
+		}
+		return policy, nil
+	}
+
+	var opts handler.RetryOptions
+	var err error
+	if opts.Default, err = parse("retry", global); err != nil {
+		return handler.RetryOptions{}, err
+	}
+	for key, c := range overrides {
+		key = strings.TrimSpace(key)
+		policy, err := parse("retry.overrides."+key, c)
+		if err != nil {
+			return handler.RetryOptions{}, err
+		}
+		if len(key) == 4 {
+			if classCode, err := handler.ParseEOJClassCodeString(key); err == nil {
+				if opts.Classes == nil {
+					opts.Classes = make(map[echonet_lite.EOJClassCode]handler.RetryPolicy)
+				}
+				opts.Classes[classCode] = policy
+				continue
+			}
+		}
+		ip := net.ParseIP(key)
+		if ip == nil {
+			return handler.RetryOptions{}, fmt.Errorf("invalid retry override key %q: expected a class code or an IP address", key)
+		}
+		if opts.IPs == nil {
+			opts.IPs = make(map[string]handler.RetryPolicy)
+		}
+		opts.IPs[ip.String()] = policy
+	}
+	return opts, nil
+}
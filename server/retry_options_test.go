@@ -0,0 +1,38 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"echonet-list/config"
+	"echonet-list/echonet_lite"
+)
+
+func TestParseRetryOptions(t *testing.T) {
+	opts, err := ParseRetryOptions(config.RetryPolicyConfig{MaxRetries: 7, Interval: "3s"}, map[string]config.RetryPolicyConfig{
+		"03B7":         {MaxRetries: 10, Interval: "10s"},
+		"192.168.1.20": {MaxRetries: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Default.MaxRetries != 7 || opts.Default.RetryInterval != 3*time.Second {
+		t.Errorf("unexpected default policy: %v", opts.Default)
+	}
+	if p := opts.Classes[echonet_lite.Refrigerator_ClassCode]; p.MaxRetries != 10 || p.RetryInterval != 10*time.Second {
+		t.Errorf("unexpected class policy: %v", opts.Classes)
+	}
+	if p := opts.IPs["192.168.1.20"]; p.MaxRetries != 2 || p.RetryInterval != 0 {
+		t.Errorf("unexpected IP policy: %v", opts.IPs)
+	}
+
+	for name, overrides := range map[string]map[string]config.RetryPolicyConfig{
+		"unknown key":      {"refrigerator": {MaxRetries: 3}},
+		"invalid interval": {"0290": {Interval: "soon"}},
+		"negative retries": {"0290": {MaxRetries: -1}},
+	} {
+		if _, err := ParseRetryOptions(config.RetryPolicyConfig{}, overrides); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
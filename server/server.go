@@ -5,7 +5,10 @@ import (
 	"echonet-list/config"
 	"echonet-list/echonet_lite/handler"
 	"echonet-list/echonet_lite/network"
+	"echonet-list/echonet_lite/simulator"
 	"fmt"
+	"net"
+	"time"
 )
 
 type Server struct {
@@ -27,30 +30,260 @@ func NewServer(ctx context.Context, cfg *config.Config) (*Server, error) {
 		options.DevicesFile = cfg.DataFiles.DevicesFile
 		options.AliasesFile = cfg.DataFiles.AliasesFile
 		options.GroupsFile = cfg.DataFiles.GroupsFile
+		options.ScenesFile = cfg.DataFiles.ScenesFile
+		options.SchedulesFile = cfg.DataFiles.SchedulesFile
+		options.LocationSettingsFile = cfg.DataFiles.LocationsFile
+		options.MetadataFile = cfg.DataFiles.MetadataFile
+		options.PreferencesFile = cfg.DataFiles.PreferencesFile
+		options.LocalPropertyMapsFile = cfg.DataFiles.LocalPropertyMapsFile
+		options.StatsFile = cfg.DataFiles.StatsFile
 	}
 
 	// 履歴設定を追加
 	if cfg != nil {
+		retention, err := cfg.HistoryRetention()
+		if err != nil {
+			return nil, err
+		}
 		options.HistoryOptions = handler.HistoryOptions{
 			PerDeviceSettableLimit:    cfg.History.PerDeviceSettableLimit,
 			PerDeviceNonSettableLimit: cfg.History.PerDeviceNonSettableLimit,
 			HistoryFilePath:           cfg.DataFiles.HistoryFile,
+			Disabled:                  !cfg.History.Enabled,
+			Backend:                   handler.HistoryBackend(cfg.History.Backend),
+			JournalFilePath:           cfg.History.JournalFile,
+			Retention:                 retention,
+		}
+	}
+
+	// メモリ上限設定を追加
+	if cfg != nil {
+		checkInterval, err := cfg.MemoryCheckInterval()
+		if err != nil {
+			return nil, err
+		}
+		options.MemoryLimits = handler.MemoryLimits{
+			HistorySoftLimitBytes: int64(cfg.Memory.HistorySoftLimitMB) * 1024 * 1024,
+			DevicesSoftLimitBytes: int64(cfg.Memory.DevicesSoftLimitMB) * 1024 * 1024,
+			EvictOfflineDevices:   cfg.Memory.EvictOfflineDevices,
+			CheckInterval:         checkInterval,
+		}
+	}
+
+	// 保存ファイルの整合性チェック設定を追加
+	if cfg != nil && cfg.Integrity.Enabled {
+		checkInterval, err := cfg.IntegrityCheckInterval()
+		if err != nil {
+			return nil, err
+		}
+		options.Integrity = handler.IntegrityOptions{
+			Enabled:       true,
+			CheckInterval: checkInterval,
+			Backups:       cfg.Integrity.Backups,
+		}
+	}
+
+	// デバイス情報の保存をまとめる間隔と古いデバイスの自動削除の設定を追加
+	if cfg != nil {
+		saveInterval, pruneAfter, err := cfg.PersistenceDurations()
+		if err != nil {
+			return nil, err
+		}
+		options.Persistence = handler.PersistenceOptions{
+			SaveInterval: saveInterval,
+			PruneAfter:   pruneAfter,
+		}
+	}
+
+	// ノードの死活監視設定を追加
+	if cfg != nil && cfg.Liveness.Enabled {
+		interval, maxInterval, err := cfg.LivenessIntervals()
+		if err != nil {
+			return nil, err
+		}
+		options.Liveness = handler.LivenessOptions{
+			Enabled:     true,
+			Interval:    interval,
+			MaxInterval: maxInterval,
+		}
+	}
+
+	// 保存ファイルの暗号化設定を追加
+	if cfg != nil {
+		key, err := cfg.EncryptionKey()
+		if err != nil {
+			return nil, err
+		}
+		if key != nil {
+			cipher, err := handler.NewFileCipher(key)
+			if err != nil {
+				return nil, fmt.Errorf("encryption: %w", err)
+			}
+			options.Cipher = cipher
 		}
 	}
 
 	// ネットワーク監視設定を追加
-	if cfg != nil && cfg.Network.MonitorEnabled {
+	if cfg != nil && (cfg.Network.MonitorEnabled || len(cfg.Network.MulticastInterfaces) > 0) {
 		options.NetworkMonitorConfig = &network.NetworkMonitorConfig{
-			Enabled: cfg.Network.MonitorEnabled,
+			Enabled:    cfg.Network.MonitorEnabled,
+			Interfaces: cfg.Network.MulticastInterfaces,
+		}
+	}
+
+	// 検出対象インターフェースと応答アドレスを追加
+	if cfg != nil {
+		options.DiscoveryInterfaces = cfg.Network.Interfaces
+		options.DiscoveryBroadcast = cfg.Network.DiscoveryBroadcast
+		if cfg.Network.ReplyAddress != "" {
+			replyIP := net.ParseIP(cfg.Network.ReplyAddress)
+			if replyIP == nil || replyIP.To4() == nil {
+				return nil, fmt.Errorf("invalid network.reply_address: %q", cfg.Network.ReplyAddress)
+			}
+			options.ReplyIP = replyIP
+		}
+		ipVersion, err := network.ParseIPVersion(cfg.Network.IPVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network.ip_version: %w", err)
+		}
+		preferIPVersion, err := network.ParseIPVersion(cfg.Network.PreferIPVersion)
+		if err != nil || preferIPVersion == network.DualStack {
+			return nil, fmt.Errorf("invalid network.prefer_ip_version: %q", cfg.Network.PreferIPVersion)
 		}
+		options.IPVersion = ipVersion
+		options.PreferIPVersion = preferIPVersion
+
+		if cfg.Network.MaxPacketsPerSecond < 0 || cfg.Network.PacketBurst < 0 || cfg.Network.MaxInFlight < 0 {
+			return nil, fmt.Errorf("network.max_packets_per_second, network.packet_burst and network.max_in_flight must not be negative")
+		}
+		options.OutboundLimits = handler.OutboundLimits{
+			PacketsPerSecond: cfg.Network.MaxPacketsPerSecond,
+			Burst:            cfg.Network.PacketBurst,
+			MaxInFlight:      cfg.Network.MaxInFlight,
+		}
+		if cfg.Network.MaxPropertiesPerFrame < 0 || cfg.Network.MaxFrameSize < 0 {
+			return nil, fmt.Errorf("network.max_properties_per_frame and network.max_frame_size must not be negative")
+		}
+		options.FrameLimits = handler.FrameLimits{
+			MaxProperties: cfg.Network.MaxPropertiesPerFrame,
+			MaxFrameSize:  cfg.Network.MaxFrameSize,
+		}
+		options.Strict = cfg.Network.Strict
+		if options.Strict {
+			fmt.Println("ECHONET Lite の仕様に厳密に従うモードで動作します。仕様に従わない電文は破棄します。")
+		}
+	}
+
+	// 再送設定を追加
+	if cfg != nil {
+		retry, err := ParseRetryOptions(config.RetryPolicyConfig{MaxRetries: cfg.Retry.MaxRetries, Interval: cfg.Retry.Interval}, cfg.Retry.Overrides)
+		if err != nil {
+			return nil, err
+		}
+		options.Retry = retry
+	}
+
+	// 日の出・日の入りを計算する地点
+	var site *handler.Coordinates
+	if cfg != nil && (cfg.Location.Latitude != nil || cfg.Location.Longitude != nil) {
+		if cfg.Location.Latitude == nil || cfg.Location.Longitude == nil {
+			return nil, fmt.Errorf("location.latitude and location.longitude must be set together")
+		}
+		site = &handler.Coordinates{Latitude: *cfg.Location.Latitude, Longitude: *cfg.Location.Longitude}
+		if err := site.Validate(); err != nil {
+			return nil, err
+		}
+		options.Coordinates = site
+	}
+
+	// 天気の仮想デバイスは、実際のノードと合わせて通信する仮想ノードとして提供する
+	var weatherNode *WeatherNode
+	if cfg != nil && cfg.Weather.Enabled {
+		interval, err := cfg.WeatherInterval()
+		if err != nil {
+			return nil, err
+		}
+		var weatherIP net.IP
+		if cfg.Weather.IP != "" {
+			if weatherIP = net.ParseIP(cfg.Weather.IP); weatherIP == nil {
+				return nil, fmt.Errorf("invalid weather.ip: %q", cfg.Weather.IP)
+			}
+		}
+		weatherNode, err = NewWeatherNode(WeatherOptions{
+			Provider:  cfg.Weather.Provider,
+			Latitude:  cfg.Weather.Latitude,
+			Longitude: cfg.Weather.Longitude,
+			Station:   cfg.Weather.Station,
+			Interval:  interval,
+			IP:        weatherIP,
+			Sun:       site,
+		})
+		if err != nil {
+			return nil, err
+		}
+		options.VirtualNodes = weatherNode.Network()
+	}
+
+	// デモモードでは実際のネットワークの代わりに模擬ネットワークを使い、データファイルを読み書きしない
+	var demoNetwork *simulator.Network
+	if cfg != nil && cfg.Demo.Enabled {
+		demoNetwork = simulator.NewNetwork(simulator.DemoNodes())
+		options.Connection = demoNetwork
+		options.InMemory = true
+		options.NetworkMonitorConfig = nil
+		options.DiscoveryInterfaces = nil
+		options.ReplyIP = nil
+		options.IPVersion = ""
+		options.OutboundLimits = handler.OutboundLimits{}
+		fmt.Println("デモモードで起動します。模擬デバイスを使用し、データファイルの読み書きは行いません。")
+	}
+
+	// Wi-SUN（Bルート）ではシリアル接続の Wi-SUN アダプター経由でスマート電力量メーターと通信する
+	if cfg != nil && cfg.WiSUN.Enabled {
+		if cfg.Demo.Enabled {
+			return nil, fmt.Errorf("demo mode and wisun cannot be enabled at the same time")
+		}
+		fmt.Printf("Wi-SUN アダプター（%s）でスマート電力量メーターに接続しています...\n", cfg.WiSUN.Device)
+		wisunConn, err := network.OpenWiSUNConnection(ctx, network.WiSUNConfig{
+			Device:         cfg.WiSUN.Device,
+			BaudRate:       cfg.WiSUN.BaudRate,
+			RouteBID:       cfg.WiSUN.RouteBID,
+			RouteBPassword: cfg.WiSUN.RouteBPassword,
+			Debug:          cfg.Debug,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect via Wi-SUN: %w", err)
+		}
+		options.Connection = wisunConn
+		options.NetworkMonitorConfig = nil
+		options.DiscoveryInterfaces = nil
+		options.ReplyIP = nil
+		options.IPVersion = ""
 	}
 
 	// ECHONETLiteHandlerの作成
 	liteHandler, err := handler.NewECHONETLiteHandler(ctx, options)
 	if err != nil {
+		if options.Connection != nil {
+			_ = options.Connection.Close()
+		}
+		if options.VirtualNodes != nil {
+			_ = options.VirtualNodes.Close()
+		}
 		return nil, err
 	}
 
+	// 模擬デバイスの過去の値を履歴に入れ、センサー値の変化を開始する
+	if demoNetwork != nil {
+		seedDemoHistory(liteHandler, demoNetwork, time.Now())
+		go demoNetwork.Run(ctx, DemoSensorInterval)
+	}
+
+	// 天気の取得を開始する
+	if weatherNode != nil {
+		go weatherNode.Run(ctx)
+	}
+
 	// メインループの開始
 	liteHandler.StartMainLoop()
 
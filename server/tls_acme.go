@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// defaultACMECacheDir は ACME で取得した証明書とアカウント鍵のデフォルトの保存先
+const defaultACMECacheDir = "certs/acme"
+
+// ACMEOptions は ACME (Let's Encrypt) で TLS 証明書を自動取得・更新する設定
+type ACMEOptions struct {
+	// 証明書を取得するドメイン（これ以外の名前での接続には証明書を発行しない）
+	Domains []string
+	// 証明書の期限切れなどの連絡先メールアドレス（省略可）
+	Email string
+	// 取得した証明書とアカウント鍵の保存先 (空でデフォルトの certs/acme)
+	CacheDir string
+	// HTTP-01 チャレンジに応答する HTTP サーバーのアドレス (例: ":80"、空で TLS-ALPN-01 のみ)
+	HTTPChallengeAddr string
+	// ACME サーバーのディレクトリ URL (空で Let's Encrypt の本番環境、テストにはステージング環境を指定する)
+	DirectoryURL string
+}
+
+// newACMEManager は ACMEOptions から autocert.Manager を作成する
+// 規約 (Terms of Service) には同意したものとして扱う
+func newACMEManager(options *ACMEOptions) (*autocert.Manager, error) {
+	if len(options.Domains) == 0 {
+		return nil, errors.New("ACME requires at least one domain")
+	}
+	cacheDir := options.CacheDir
+	if cacheDir == "" {
+		cacheDir = defaultACMECacheDir
+	}
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(options.Domains...),
+		Cache:      autocert.DirCache(cacheDir),
+		Email:      options.Email,
+	}
+	if options.DirectoryURL != "" {
+		manager.Client = &acme.Client{DirectoryURL: options.DirectoryURL}
+	}
+	return manager, nil
+}
+
+// startACMEChallengeServer は HTTP-01 チャレンジに応答する HTTP サーバーを起動する
+// チャレンジ以外のリクエストは HTTPS にリダイレクトされる
+func startACMEChallengeServer(manager *autocert.Manager, addr string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{Handler: manager.HTTPHandler(nil)}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			slog.Error("ACME HTTP challenge server stopped", "addr", addr, "err", err)
+		}
+	}()
+	slog.Info("ACME HTTP challenge server started", "addr", listener.Addr().String())
+	return server, nil
+}
+
+// stopACMEChallengeServer は HTTP-01 チャレンジ用のサーバーを停止する
+func stopACMEChallengeServer(server *http.Server) {
+	if server == nil {
+		return
+	}
+	if err := server.Shutdown(context.Background()); err != nil {
+		slog.Warn("Error shutting down ACME HTTP challenge server", "err", err)
+	}
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"golang.org/x/crypto/acme"
+)
+
+func TestNewACMEManager(t *testing.T) {
+	if _, err := newACMEManager(&ACMEOptions{}); err == nil {
+		t.Fatal("expected an error without domains")
+	}
+
+	dir := t.TempDir()
+	manager, err := newACMEManager(&ACMEOptions{
+		Domains:      []string{"home.example.com"},
+		Email:        "admin@example.com",
+		CacheDir:     dir,
+		DirectoryURL: "https://acme-staging-v02.api.letsencrypt.org/directory",
+	})
+	if err != nil {
+		t.Fatalf("newACMEManager failed: %v", err)
+	}
+
+	// 指定したドメイン以外には証明書を発行しない
+	if err := manager.HostPolicy(context.Background(), "home.example.com"); err != nil {
+		t.Errorf("expected the configured domain to be allowed: %v", err)
+	}
+	if err := manager.HostPolicy(context.Background(), "other.example.com"); err == nil {
+		t.Error("expected other domains to be rejected")
+	}
+	if manager.Email != "admin@example.com" || manager.Client == nil || manager.Client.DirectoryURL != "https://acme-staging-v02.api.letsencrypt.org/directory" {
+		t.Errorf("unexpected manager settings: %+v", manager)
+	}
+
+	// TLS-ALPN-01 チャレンジに応答できる
+	if !slices.Contains(manager.TLSConfig().NextProtos, acme.ALPNProto) {
+		t.Errorf("expected %s in NextProtos", acme.ALPNProto)
+	}
+
+	// チャレンジ以外の HTTP リクエストは HTTPS にリダイレクトする
+	rec := httptest.NewRecorder()
+	manager.HTTPHandler(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://home.example.com/index.html", nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://home.example.com/index.html" {
+		t.Errorf("unexpected redirect: %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+}
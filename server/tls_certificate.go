@@ -0,0 +1,97 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// certCheckInterval は証明書ファイルの更新を確認する最短の間隔
+const certCheckInterval = 30 * time.Second
+
+// certificateReloader は TLS 証明書を tls.Config.GetCertificate で提供し、
+// certbot などで証明書ファイルが更新されたら再起動せずに読み込み直す
+type certificateReloader struct {
+	certFile      string
+	keyFile       string
+	checkInterval time.Duration
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	certStat  fileStamp // 読み込んだときの証明書ファイルの状態
+	keyStat   fileStamp // 読み込んだときの秘密鍵ファイルの状態
+	lastCheck time.Time
+}
+
+// fileStamp は更新の検出に使うファイルの更新時刻とサイズ
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// newCertificateReloader は証明書を読み込む。読み込めない場合は起動時のエラーとして返す
+func newCertificateReloader(certFile, keyFile string) (*certificateReloader, error) {
+	r := &certificateReloader{certFile: certFile, keyFile: keyFile, checkInterval: certCheckInterval}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load は証明書と秘密鍵を読み込む。mu を保持して呼ぶか、初期化時に呼ぶこと
+func (r *certificateReloader) load() error {
+	certStat, err := statFile(r.certFile)
+	if err != nil {
+		return err
+	}
+	keyStat, err := statFile(r.keyFile)
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	r.cert = &cert
+	r.certStat = certStat
+	r.keyStat = keyStat
+	return nil
+}
+
+// GetCertificate は tls.Config.GetCertificate として使う
+// checkInterval ごとにファイルの更新時刻とサイズを確認し、変わっていれば読み込み直す
+// 読み込みに失敗した場合（証明書と秘密鍵の書き換えの途中など）は、前の証明書を使い続けて次の確認で再試行する
+func (r *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.lastCheck) < r.checkInterval {
+		return r.cert, nil
+	}
+	r.lastCheck = now
+
+	certStat, certErr := statFile(r.certFile)
+	keyStat, keyErr := statFile(r.keyFile)
+	if certErr == nil && keyErr == nil && certStat == r.certStat && keyStat == r.keyStat {
+		return r.cert, nil
+	}
+
+	if err := r.load(); err != nil {
+		slog.Warn("Failed to reload TLS certificate, keeping the current one", "certFile", r.certFile, "err", err)
+		return r.cert, nil
+	}
+	slog.Info("Reloaded TLS certificate", "certFile", r.certFile)
+	return r.cert, nil
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate は自己署名証明書と秘密鍵を書き出す。更新時刻は modTime にする
+func writeTestCertificate(t *testing.T, certFile, keyFile string, serial int64, modTime time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{certFile, keyFile} {
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func certificateSerial(t *testing.T, r *certificateReloader) int64 {
+	t.Helper()
+	cert, err := r.GetCertificate(nil)
+	if err != nil || cert == nil {
+		t.Fatalf("GetCertificate failed: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.SerialNumber.Int64()
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	base := time.Now().Add(-time.Hour)
+
+	if _, err := newCertificateReloader(certFile, keyFile); err == nil {
+		t.Fatal("expected an error for missing certificate files")
+	}
+
+	writeTestCertificate(t, certFile, keyFile, 1, base)
+	reloader, err := newCertificateReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("newCertificateReloader failed: %v", err)
+	}
+	if got := certificateSerial(t, reloader); got != 1 {
+		t.Fatalf("expected serial 1, got %d", got)
+	}
+
+	// 確認間隔の間はファイルを見ない
+	writeTestCertificate(t, certFile, keyFile, 2, base.Add(time.Minute))
+	if got := certificateSerial(t, reloader); got != 1 {
+		t.Errorf("certificate reloaded before the check interval: serial %d", got)
+	}
+
+	// 更新されたファイルは次の確認で読み込み直す
+	reloader.checkInterval = 0
+	if got := certificateSerial(t, reloader); got != 2 {
+		t.Errorf("expected the renewed certificate, got serial %d", got)
+	}
+
+	// 書き換えの途中で読み込めない場合は、前の証明書を使い続ける
+	if err := os.WriteFile(keyFile, []byte("broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got := certificateSerial(t, reloader); got != 2 {
+		t.Errorf("expected the previous certificate while the key is broken, got serial %d", got)
+	}
+	writeTestCertificate(t, certFile, keyFile, 3, base.Add(2*time.Minute))
+	if got := certificateSerial(t, reloader); got != 3 {
+		t.Errorf("expected the certificate to be reloaded after the files were fixed, got serial %d", got)
+	}
+}
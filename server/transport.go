@@ -2,13 +2,17 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -21,8 +25,47 @@ const (
 	pongWait = 60 * time.Second
 	// pingPeriod はPingを送信する間隔（pongWaitより短くする必要がある）
 	pingPeriod = 54 * time.Second
+	// sendQueueSize はクライアントごとの送信キューのデフォルトの長さ
+	sendQueueSize = 256
 )
 
+// SlowClientPolicy は送信キューが一杯になったクライアントの扱いを表す
+type SlowClientPolicy string
+
+const (
+	// SlowClientPolicyDisconnect はキューが一杯になったクライアントを切断する
+	SlowClientPolicyDisconnect SlowClientPolicy = "disconnect"
+	// SlowClientPolicyDropOldest はキューの最も古いメッセージを捨てて新しいメッセージを追加する
+	SlowClientPolicyDropOldest SlowClientPolicy = "drop_oldest"
+)
+
+// ParseSlowClientPolicy は設定値を SlowClientPolicy に変換する（空文字はデフォルトの disconnect）
+func ParseSlowClientPolicy(s string) (SlowClientPolicy, error) {
+	switch SlowClientPolicy(s) {
+	case "", SlowClientPolicyDisconnect:
+		return SlowClientPolicyDisconnect, nil
+	case SlowClientPolicyDropOldest:
+		return SlowClientPolicyDropOldest, nil
+	default:
+		return "", fmt.Errorf("unknown slow client policy: %q (expected %q or %q)", s, SlowClientPolicyDisconnect, SlowClientPolicyDropOldest)
+	}
+}
+
+// SendQueueStats は1つのクライアントの送信キューの状態
+type SendQueueStats struct {
+	ConnID   string
+	Length   int
+	Capacity int
+	Dropped  int64 // drop_oldest で捨てられたメッセージ数
+}
+
+// TransportStats は送信キューと遅いクライアントへの対処の統計
+type TransportStats struct {
+	Queues          []SendQueueStats
+	EvictedClients  int64 // キューが一杯で切断したクライアント数
+	DroppedMessages int64 // drop_oldest で捨てたメッセージの総数
+}
+
 // StartOptions は websocket_server.go で定義されていますが、ここにHTTPサーバー用の設定も追加します
 
 // WebSocketTransport はWebSocketサーバーのネットワーク層を抽象化するインターフェース
@@ -53,11 +96,47 @@ type WebSocketTransport interface {
 	BroadcastMessage(message []byte) error
 }
 
-// clientConnection wraps a WebSocket connection with a mutex for safe concurrent writes
+// clientConnection wraps a WebSocket connection with a mutex for safe concurrent writes.
+// Outbound messages go through sendCh and are written by a single goroutine,
+// so each client receives messages in the order they were queued.
 type clientConnection struct {
 	conn     *websocket.Conn // immutable after creation, safe for concurrent read access
 	mutex    sync.Mutex      // protects write operations on conn
-	pingDone chan struct{}   // ping goroutine停止用
+	pingDone chan struct{}   // ping goroutine停止用（送信goroutineも停止する）
+	sendCh   chan []byte     // 送信キュー
+
+	enqueueMutex sync.Mutex   // キューが一杯の時の「古いものを捨てて追加」を不可分にする
+	dropping     bool         // メッセージを捨てている最中か（ログを1回にまとめるため、enqueueMutex で保護）
+	dropped      atomic.Int64 // 捨てたメッセージ数
+
+	principal string     // 認証されたアクセスルール名（認証が無効な場合は空、作成後は不変）
+	query     url.Values // 接続URLのクエリパラメータ（作成後は不変）
+	info      ConnectionInfo
+}
+
+// ConnectionInfo は接続のメタデータ。ログや監査記録の connID から接続元を調べるために使う
+type ConnectionInfo struct {
+	ConnID         string
+	RemoteAddr     string
+	UserAgent      string
+	Principal      string // 認証されたアクセスルール名（認証が無効な場合は空）
+	ConnectedAt    time.Time
+	DisconnectedAt time.Time // 接続中はゼロ値
+}
+
+// recentConnectionsSize は、切断後も ConnectionInfo で調べられる接続の数
+const recentConnectionsSize = 256
+
+// newClientConnection creates a clientConnection with an empty send queue of the given size
+func newClientConnection(conn *websocket.Conn, queueSize int) *clientConnection {
+	if queueSize <= 0 {
+		queueSize = sendQueueSize
+	}
+	return &clientConnection{
+		conn:     conn,
+		pingDone: make(chan struct{}),
+		sendCh:   make(chan []byte, queueSize),
+	}
 }
 
 // DefaultWebSocketTransport は WebSocketTransport インターフェースのデフォルト実装
@@ -72,6 +151,16 @@ type DefaultWebSocketTransport struct {
 	messageHandler    func(connID string, message []byte) error
 	connectHandler    func(connID string) error
 	disconnectHandler func(connID string)
+	authenticator     func(r *http.Request) (string, bool) // 接続時の認証（nil の場合は認証しない）
+	idGenerator       IDGenerator                          // 接続IDの生成（Start より前に変更すること）
+	challengeServer   *http.Server                         // ACME の HTTP-01 チャレンジ用サーバー（ACME を使わない場合は nil）
+
+	sendQueueSize    int              // クライアントごとの送信キューの長さ（0以下はデフォルト）
+	slowClientPolicy SlowClientPolicy // 送信キューが一杯になった時の扱い
+	evictedClients   atomic.Int64
+	droppedMessages  atomic.Int64
+
+	recentConnections []ConnectionInfo // 切断済みの接続（古い順、clientsMutex で保護）
 }
 
 // NewDefaultWebSocketTransport は DefaultWebSocketTransport の新しいインスタンスを作成する
@@ -87,9 +176,12 @@ func NewDefaultWebSocketTransport(ctx context.Context, addr string) *DefaultWebS
 				return true
 			},
 		},
-		clients:        make(map[string]*clientConnection),
-		clientsReverse: make(map[*websocket.Conn]string),
-		clientsMutex:   sync.RWMutex{},
+		clients:          make(map[string]*clientConnection),
+		clientsReverse:   make(map[*websocket.Conn]string),
+		clientsMutex:     sync.RWMutex{},
+		idGenerator:      NewULID,
+		sendQueueSize:    sendQueueSize,
+		slowClientPolicy: SlowClientPolicyDisconnect,
 	}
 
 	// Create the HTTP server
@@ -127,11 +219,56 @@ func (t *DefaultWebSocketTransport) SetupStaticFileServer(webRoot string) error
 	return nil
 }
 
+// SetupRESTAPI はREST APIのルートを設定する
+func (t *DefaultWebSocketTransport) SetupRESTAPI(api *RESTAPIHandler) {
+	if mux, ok := t.server.Handler.(*http.ServeMux); ok {
+		api.Register(mux)
+		slog.Info("REST API configured", "path", "/api/")
+	}
+}
+
 // Start はWebSocketサーバーを起動する
 func (t *DefaultWebSocketTransport) Start(options StartOptions) error {
+	// 送信キューの設定（接続受付前に反映する）
+	if options.SendQueueSize > 0 {
+		t.sendQueueSize = options.SendQueueSize
+	}
+	if options.SlowClientPolicy != "" {
+		t.slowClientPolicy = options.SlowClientPolicy
+	}
+
+	// ACME が指定されている場合は、証明書ファイルの代わりに自動取得した証明書を使う
+	var tlsConfig *tls.Config
+	if options.ACME != nil {
+		manager, err := newACMEManager(options.ACME)
+		if err != nil {
+			return err
+		}
+		if options.ACME.HTTPChallengeAddr != "" {
+			challengeServer, err := startACMEChallengeServer(manager, options.ACME.HTTPChallengeAddr)
+			if err != nil {
+				return err
+			}
+			t.challengeServer = challengeServer
+		}
+		tlsConfig = manager.TLSConfig()
+		slog.Info("Using TLS with ACME certificates", "domains", options.ACME.Domains)
+	}
+
+	// TLS証明書が指定されている場合は、リスナーより先に読み込んでエラーを返す
+	var certReloader *certificateReloader
+	if tlsConfig == nil && options.CertFile != "" && options.KeyFile != "" {
+		reloader, err := newCertificateReloader(options.CertFile, options.KeyFile)
+		if err != nil {
+			return err
+		}
+		certReloader = reloader
+	}
+
 	// 先にリスナーをバインド
 	listener, err := net.Listen("tcp", t.server.Addr)
 	if err != nil {
+		stopACMEChallengeServer(t.challengeServer)
 		return err
 	}
 	// 待ち受け完了を通知
@@ -140,10 +277,18 @@ func (t *DefaultWebSocketTransport) Start(options StartOptions) error {
 	}
 	slog.Info("WebSocket server starting", "addr", t.server.Addr)
 
+	// ACME で証明書を取得する場合（TLS-ALPN-01 チャレンジにもこのサーバーで応答する）
+	if tlsConfig != nil {
+		t.server.TLSConfig = tlsConfig
+		return t.server.ServeTLS(listener, "", "")
+	}
+
 	// TLS証明書が指定されている場合
-	if options.CertFile != "" && options.KeyFile != "" {
+	// 証明書は GetCertificate で提供し、ファイルが更新されたら再起動せずに読み込み直す
+	if certReloader != nil {
 		slog.Info("Using TLS with certificate", "certFile", options.CertFile)
-		return t.server.ServeTLS(listener, options.CertFile, options.KeyFile)
+		t.server.TLSConfig = &tls.Config{GetCertificate: certReloader.GetCertificate}
+		return t.server.ServeTLS(listener, "", "")
 	}
 
 	// 通常のHTTP (証明書なし)
@@ -154,6 +299,7 @@ func (t *DefaultWebSocketTransport) Start(options StartOptions) error {
 func (t *DefaultWebSocketTransport) Stop() error {
 	slog.Info("Stopping WebSocket server", "addr", t.server.Addr)
 	t.cancel()
+	stopACMEChallengeServer(t.challengeServer)
 	err := t.server.Shutdown(context.Background())
 	if err != nil {
 		slog.Info("Error shutting down WebSocket server", "err", err)
@@ -176,6 +322,55 @@ func (t *DefaultWebSocketTransport) SetDisconnectHandler(handler func(connID str
 	t.disconnectHandler = handler
 }
 
+// SetAuthenticator は接続要求を認証する関数を設定する（Start より前に呼ぶこと）
+// 認証に成功した場合は接続の主体名を返し、失敗した接続は 401 で拒否される
+func (t *DefaultWebSocketTransport) SetAuthenticator(authenticator func(r *http.Request) (string, bool)) {
+	t.authenticator = authenticator
+}
+
+// SetIDGenerator は接続IDを生成する関数を設定する（Start より前に呼ぶこと）
+func (t *DefaultWebSocketTransport) SetIDGenerator(generator IDGenerator) {
+	t.idGenerator = generator
+}
+
+// ConnectionInfo は接続のメタデータを返す
+// 監査記録から接続元を辿れるよう、切断後の接続も直近の recentConnectionsSize 件までは返す
+func (t *DefaultWebSocketTransport) ConnectionInfo(connID string) (ConnectionInfo, bool) {
+	t.clientsMutex.RLock()
+	defer t.clientsMutex.RUnlock()
+	if client, ok := t.clients[connID]; ok {
+		return client.info, true
+	}
+	for i := len(t.recentConnections) - 1; i >= 0; i-- {
+		if t.recentConnections[i].ConnID == connID {
+			return t.recentConnections[i], true
+		}
+	}
+	return ConnectionInfo{}, false
+}
+
+// Principal は接続の認証時に得た主体名を返す
+func (t *DefaultWebSocketTransport) Principal(connID string) (string, bool) {
+	t.clientsMutex.RLock()
+	defer t.clientsMutex.RUnlock()
+	client, ok := t.clients[connID]
+	if !ok {
+		return "", false
+	}
+	return client.principal, true
+}
+
+// Query は接続時のURLのクエリパラメータを返す
+func (t *DefaultWebSocketTransport) Query(connID string) (url.Values, bool) {
+	t.clientsMutex.RLock()
+	defer t.clientsMutex.RUnlock()
+	client, ok := t.clients[connID]
+	if !ok {
+		return nil, false
+	}
+	return client.query, true
+}
+
 // isConnectionClosedError checks if the error indicates a closed connection
 func isConnectionClosedError(err error) bool {
 	return websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseNoStatusReceived) ||
@@ -201,6 +396,14 @@ func (t *DefaultWebSocketTransport) removeClient(connID string) bool {
 		delete(t.clientsReverse, client.conn)
 	}
 
+	// Keep the metadata of recently closed connections for ConnectionInfo
+	info := client.info
+	info.DisconnectedAt = time.Now()
+	if len(t.recentConnections) >= recentConnectionsSize {
+		t.recentConnections = append(t.recentConnections[:0], t.recentConnections[1:]...)
+	}
+	t.recentConnections = append(t.recentConnections, info)
+
 	// Call disconnect handler outside of the mutex lock
 	go func() {
 		select {
@@ -216,7 +419,7 @@ func (t *DefaultWebSocketTransport) removeClient(connID string) bool {
 	return true
 }
 
-// SendMessage は特定のクライアントにメッセージを送信する
+// SendMessage は特定のクライアントの送信キューにメッセージを追加する
 func (t *DefaultWebSocketTransport) SendMessage(connID string, message []byte) error {
 	t.clientsMutex.RLock()
 	client, exists := t.clients[connID]
@@ -226,56 +429,143 @@ func (t *DefaultWebSocketTransport) SendMessage(connID string, message []byte) e
 		return fmt.Errorf("client with ID %s not found", connID)
 	}
 
-	client.mutex.Lock()
-	defer client.mutex.Unlock()
-
-	client.conn.SetWriteDeadline(time.Now().Add(writeWait))
-	err := client.conn.WriteMessage(websocket.TextMessage, message)
-	if err != nil {
-		// Check if this is a connection close error
-		if isConnectionClosedError(err) {
-			// Remove the client using the common method
-			t.removeClient(connID)
-		}
-		return fmt.Errorf("failed to send message to client %s: %w", connID, err)
-	}
-
-	return nil
+	return t.enqueue(connID, client, message)
 }
 
-// BroadcastMessage は接続中の全クライアントにメッセージを送信する
+// BroadcastMessage は接続中の全クライアントの送信キューにメッセージを追加する
+// 送信はクライアントごとのgoroutineで行うため、遅いクライアントが他のクライアントを待たせることはない
 func (t *DefaultWebSocketTransport) BroadcastMessage(message []byte) error {
 	t.clientsMutex.RLock()
-	clients := make(map[string]*clientConnection)
-	clientsReverse := make(map[*websocket.Conn]string)
+	clients := make(map[string]*clientConnection, len(t.clients))
 	for connID, client := range t.clients {
 		clients[connID] = client
-		clientsReverse[client.conn] = connID
 	}
 	t.clientsMutex.RUnlock()
 
-	var disconnectedClients []string
-
 	for connID, client := range clients {
-		client.mutex.Lock()
-		client.conn.SetWriteDeadline(time.Now().Add(writeWait))
-		if err := client.conn.WriteMessage(websocket.TextMessage, message); err != nil {
-			// Check if this is a client disconnection error
-			if isConnectionClosedError(err) {
-				disconnectedClients = append(disconnectedClients, connID)
-			} else {
-				slog.Error("Error broadcasting message to client", "err", err, "connID", connID)
+		_ = t.enqueue(connID, client, message)
+	}
+
+	return nil
+}
+
+// enqueue はクライアントの送信キューにメッセージを追加する
+// キューが一杯の場合は、受信が追いつかないクライアントとして slowClientPolicy に従って処理する
+func (t *DefaultWebSocketTransport) enqueue(connID string, client *clientConnection, message []byte) error {
+	select {
+	case <-client.pingDone:
+		return fmt.Errorf("client with ID %s not found", connID)
+	default:
+	}
+
+	if t.slowClientPolicy == SlowClientPolicyDropOldest {
+		t.enqueueDropOldest(connID, client, message)
+		return nil
+	}
+
+	select {
+	case client.sendCh <- message:
+		return nil
+	default:
+		t.evictSlowClient(connID, client)
+		return fmt.Errorf("failed to send message to client %s: send queue is full", connID)
+	}
+}
+
+// enqueueDropOldest は、キューが一杯なら最も古いメッセージを捨ててから追加する
+func (t *DefaultWebSocketTransport) enqueueDropOldest(connID string, client *clientConnection, message []byte) {
+	client.enqueueMutex.Lock()
+	droppedNow := false
+	for {
+		select {
+		case client.sendCh <- message:
+		default:
+			// 最も古いメッセージを捨てて再試行する
+			select {
+			case <-client.sendCh:
+				client.dropped.Add(1)
+				t.droppedMessages.Add(1)
+				droppedNow = true
+			default:
 			}
+			continue
 		}
-		client.mutex.Unlock()
+		break
 	}
+	// キューが半分まで空くまでは同じクライアントの破棄を1回の出来事として扱う
+	startedDropping := droppedNow && !client.dropping
+	if droppedNow {
+		client.dropping = true
+	} else if len(client.sendCh) < cap(client.sendCh)/2 {
+		client.dropping = false
+	}
+	client.enqueueMutex.Unlock()
 
-	// Clean up disconnected clients using the common method
-	for _, connID := range disconnectedClients {
-		t.removeClient(connID)
+	// ログはブロードキャストされ得るため、ロックを外してから出力する
+	if startedDropping {
+		slog.Warn("Send queue is full, dropping oldest messages for slow client", "connID", connID, "queueSize", cap(client.sendCh))
 	}
+}
 
-	return nil
+// evictSlowClient は送信キューが一杯になったクライアントに理由を通知して切断する
+func (t *DefaultWebSocketTransport) evictSlowClient(connID string, client *clientConnection) {
+	if !t.removeClient(connID) {
+		return
+	}
+	t.evictedClients.Add(1)
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "send queue is full")
+	_ = client.conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(writeWait))
+	client.conn.Close()
+	// 切断後に出力する（ログのブロードキャストがこのクライアントに向かわないように）
+	slog.Warn("Send queue is full, disconnected slow client", "connID", connID, "queueSize", cap(client.sendCh))
+}
+
+// Stats は送信キューと遅いクライアントへの対処の統計を返す
+func (t *DefaultWebSocketTransport) Stats() TransportStats {
+	t.clientsMutex.RLock()
+	queues := make([]SendQueueStats, 0, len(t.clients))
+	for connID, client := range t.clients {
+		queues = append(queues, SendQueueStats{
+			ConnID:   connID,
+			Length:   len(client.sendCh),
+			Capacity: cap(client.sendCh),
+			Dropped:  client.dropped.Load(),
+		})
+	}
+	t.clientsMutex.RUnlock()
+
+	sort.Slice(queues, func(i, j int) bool { return queues[i].ConnID < queues[j].ConnID })
+	return TransportStats{
+		Queues:          queues,
+		EvictedClients:  t.evictedClients.Load(),
+		DroppedMessages: t.droppedMessages.Load(),
+	}
+}
+
+// writePump は送信キューのメッセージを順番にクライアントへ書き込む
+func (t *DefaultWebSocketTransport) writePump(connID string, client *clientConnection) {
+	for {
+		select {
+		case message := <-client.sendCh:
+			client.mutex.Lock()
+			client.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			err := client.conn.WriteMessage(websocket.TextMessage, message)
+			client.mutex.Unlock()
+			if err != nil {
+				if !isConnectionClosedError(err) {
+					slog.Error("Error sending message to client", "err", err, "connID", connID)
+				}
+				// 読み込みループを終了させ、クライアントを削除する
+				client.conn.Close()
+				t.removeClient(connID)
+				return
+			}
+		case <-client.pingDone:
+			return
+		case <-t.ctx.Done():
+			return
+		}
+	}
 }
 
 // handleWebSocket はWebSocket接続を処理する
@@ -288,6 +578,18 @@ func (t *DefaultWebSocketTransport) handleWebSocket(w http.ResponseWriter, r *ht
 		"sec-websocket-key", r.Header.Get("Sec-WebSocket-Key"),
 		"sec-websocket-version", r.Header.Get("Sec-WebSocket-Version"))
 
+	// 認証が有効な場合は Upgrade の前にトークンを確認する
+	var principal string
+	if t.authenticator != nil {
+		name, ok := t.authenticator(r)
+		if !ok {
+			slog.Warn("WebSocket connection rejected: authentication failed", "remote_addr", r.RemoteAddr, NoBroadcast())
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		principal = name
+	}
+
 	// Upgrade the HTTP connection to a WebSocket connection
 	conn, err := t.upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -299,14 +601,22 @@ func (t *DefaultWebSocketTransport) handleWebSocket(w http.ResponseWriter, r *ht
 	defer conn.Close()
 
 	// Generate a unique connection ID
-	connID := fmt.Sprintf("%p", conn)
+	connID := t.idGenerator()
 
 	// Register the client
-	client := &clientConnection{
-		conn:     conn,
-		mutex:    sync.Mutex{},
-		pingDone: make(chan struct{}),
+	client := newClientConnection(conn, t.sendQueueSize)
+	client.principal = principal
+	client.query = r.URL.Query()
+	client.info = ConnectionInfo{
+		ConnID:      connID,
+		RemoteAddr:  r.RemoteAddr,
+		UserAgent:   r.Header.Get("User-Agent"),
+		Principal:   principal,
+		ConnectedAt: time.Now(),
 	}
+	// Info level so that the connID in later log and audit records can be traced back to the client
+	slog.Info("WebSocket client connected", "connID", connID, "remote_addr", r.RemoteAddr,
+		"user_agent", client.info.UserAgent, "principal", principal)
 	t.clientsMutex.Lock()
 	t.clients[connID] = client
 	t.clientsReverse[conn] = connID
@@ -318,6 +628,9 @@ func (t *DefaultWebSocketTransport) handleWebSocket(w http.ResponseWriter, r *ht
 		t.removeClient(connID)
 	}()
 
+	// Start the writer goroutine that delivers queued messages in order
+	go t.writePump(connID, client)
+
 	// Call the connect handler if set
 	if t.connectHandler != nil {
 		if err := t.connectHandler(connID); err != nil {
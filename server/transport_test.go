@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -464,3 +465,274 @@ func TestPingPongMechanism(t *testing.T) {
 		t.Error("Did not receive ping from server")
 	}
 }
+
+// TestBroadcastMessageOrderedDelivery verifies that messages queued for a client are delivered in order
+func TestBroadcastMessageOrderedDelivery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	transport := NewDefaultWebSocketTransport(ctx, ":0")
+	connected := make(chan string, 1)
+	transport.SetConnectHandler(func(connID string) error {
+		connected <- connID
+		return nil
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(transport.handleWebSocket))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	var connID string
+	select {
+	case connID = <-connected:
+	case <-time.After(time.Second):
+		t.Fatal("Connect handler was not called")
+	}
+
+	const count = 100
+	for i := 0; i < count; i++ {
+		msg := []byte(strings.Repeat("x", i%10) + string(rune('A'+i%26)))
+		if i%2 == 0 {
+			if err := transport.BroadcastMessage(msg); err != nil {
+				t.Fatalf("BroadcastMessage failed: %v", err)
+			}
+		} else if err := transport.SendMessage(connID, msg); err != nil {
+			t.Fatalf("SendMessage failed: %v", err)
+		}
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for i := 0; i < count; i++ {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage failed at %d: %v", i, err)
+		}
+		want := strings.Repeat("x", i%10) + string(rune('A'+i%26))
+		if string(msg) != want {
+			t.Fatalf("message %d out of order: got %q, want %q", i, msg, want)
+		}
+	}
+}
+
+// TestSlowClientIsDisconnected verifies that a client whose send queue is full is removed
+func TestSlowClientIsDisconnected(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// Register a client without a writer goroutine so that its queue is never drained
+	transport := NewDefaultWebSocketTransport(ctx, ":0")
+	client := newClientConnection(conn, sendQueueSize)
+	transport.clients["slow"] = client
+	transport.clientsReverse[conn] = "slow"
+
+	for i := 0; i < sendQueueSize; i++ {
+		if err := transport.SendMessage("slow", []byte("msg")); err != nil {
+			t.Fatalf("SendMessage failed before the queue was full: %v", err)
+		}
+	}
+	if err := transport.SendMessage("slow", []byte("msg")); err == nil {
+		t.Fatal("SendMessage should fail when the queue is full")
+	}
+
+	transport.clientsMutex.RLock()
+	_, exists := transport.clients["slow"]
+	transport.clientsMutex.RUnlock()
+	if exists {
+		t.Error("Slow client should have been removed")
+	}
+}
+
+// TestSlowClientDropOldest verifies that the drop_oldest policy keeps the client and discards the oldest messages
+func TestSlowClientDropOldest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// Register a client without a writer goroutine so that its queue is never drained
+	transport := NewDefaultWebSocketTransport(ctx, ":0")
+	transport.slowClientPolicy = SlowClientPolicyDropOldest
+	client := newClientConnection(conn, 4)
+	transport.clients["slow"] = client
+	transport.clientsReverse[conn] = "slow"
+
+	for i := 0; i < 6; i++ {
+		if err := transport.SendMessage("slow", []byte(fmt.Sprintf("msg-%d", i))); err != nil {
+			t.Fatalf("SendMessage failed: %v", err)
+		}
+	}
+
+	stats := transport.Stats()
+	if len(stats.Queues) != 1 || stats.Queues[0].Length != 4 || stats.Queues[0].Dropped != 2 {
+		t.Fatalf("Unexpected queue stats: %+v", stats.Queues)
+	}
+	if stats.DroppedMessages != 2 || stats.EvictedClients != 0 {
+		t.Errorf("Unexpected totals: dropped=%d evicted=%d", stats.DroppedMessages, stats.EvictedClients)
+	}
+
+	// The newest messages remain in order
+	for i := 2; i < 6; i++ {
+		if got := string(<-client.sendCh); got != fmt.Sprintf("msg-%d", i) {
+			t.Errorf("Expected msg-%d, got %s", i, got)
+		}
+	}
+}
+
+// TestSlowClientEvictionIsCounted verifies that evicted clients are counted in the transport stats
+func TestSlowClientEvictionIsCounted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	closeCode := make(chan int, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				if closeErr, ok := err.(*websocket.CloseError); ok {
+					closeCode <- closeErr.Code
+				}
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	transport := NewDefaultWebSocketTransport(ctx, ":0")
+	client := newClientConnection(conn, 1)
+	transport.clients["slow"] = client
+	transport.clientsReverse[conn] = "slow"
+
+	_ = transport.SendMessage("slow", []byte("msg"))
+	if err := transport.SendMessage("slow", []byte("msg")); err == nil {
+		t.Fatal("SendMessage should fail when the queue is full")
+	}
+
+	if stats := transport.Stats(); stats.EvictedClients != 1 || len(stats.Queues) != 0 {
+		t.Errorf("Unexpected stats after eviction: %+v", stats)
+	}
+
+	select {
+	case code := <-closeCode:
+		if code != websocket.CloseTryAgainLater {
+			t.Errorf("Expected close code %d, got %d", websocket.CloseTryAgainLater, code)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Evicted client did not receive a close frame")
+	}
+}
+
+func TestParseSlowClientPolicy(t *testing.T) {
+	for input, want := range map[string]SlowClientPolicy{
+		"":            SlowClientPolicyDisconnect,
+		"disconnect":  SlowClientPolicyDisconnect,
+		"drop_oldest": SlowClientPolicyDropOldest,
+	} {
+		got, err := ParseSlowClientPolicy(input)
+		if err != nil || got != want {
+			t.Errorf("ParseSlowClientPolicy(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseSlowClientPolicy("block"); err == nil {
+		t.Error("ParseSlowClientPolicy should reject unknown policies")
+	}
+}
+
+// TestAuthenticatorRejectsUnauthorized verifies that connections failing authentication are rejected before upgrading
+func TestAuthenticatorRejectsUnauthorized(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	transport := NewDefaultWebSocketTransport(ctx, "localhost:0")
+	transport.SetAuthenticator(func(r *http.Request) (string, bool) {
+		return "guest", r.URL.Query().Get("token") == "secret"
+	})
+	principals := make(chan string, 1)
+	transport.SetConnectHandler(func(connID string) error {
+		principal, _ := transport.Principal(connID)
+		principals <- principal
+		return nil
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(transport.handleWebSocket))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL+"?token=wrong", nil)
+	if err == nil {
+		t.Fatal("Expected the connection to be rejected")
+	}
+	if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected 401, got %v", resp)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?token=secret", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect with a valid token: %v", err)
+	}
+	defer conn.Close()
+
+	select {
+	case principal := <-principals:
+		if principal != "guest" {
+			t.Errorf("Principal = %q, want guest", principal)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Connect handler was not called")
+	}
+}
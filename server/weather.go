@@ -0,0 +1,494 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/echonet_lite/simulator"
+)
+
+const (
+	// WeatherProviderOpenMeteo fetches the current weather at a latitude and longitude from Open-Meteo
+	WeatherProviderOpenMeteo = "open-meteo"
+	// WeatherProviderJMA fetches the latest observation of an AMeDAS station from the Japan Meteorological Agency
+	WeatherProviderJMA = "jma"
+
+	// DefaultWeatherInterval is how often the weather is fetched when WeatherOptions.Interval is 0
+	DefaultWeatherInterval = 10 * time.Minute
+	// DefaultWeatherIP is the address of the virtual weather node when WeatherOptions.IP is nil
+	DefaultWeatherIP = "192.0.2.254"
+
+	openMeteoBaseURL = "https://api.open-meteo.com"
+	jmaBaseURL       = "https://www.jma.go.jp"
+
+	// weatherFetchTimeout limits how long one request to the provider may take
+	weatherFetchTimeout = 30 * time.Second
+	// weatherStaleIntervals is how many fetch intervals the last conditions stay valid when fetching keeps failing
+	weatherStaleIntervals = 3
+	// weatherLocationGarden is the installation location (EPC 0x81) of the virtual node
+	weatherLocationGarden = 0x60
+)
+
+// WeatherConditions is the current weather reported by a provider.
+// Fields the provider does not report are NaN (Condition is empty).
+type WeatherConditions struct {
+	Temperature   float64 // ℃
+	Humidity      float64 // %
+	WindSpeed     float64 // m/s
+	WindDirection float64 // degrees clockwise from north
+	Precipitation float64 // mm in the last hour
+	Condition     string  // alias of the weather condition property ("clear", "rain", ...)
+}
+
+// unknownWeather returns conditions with every field missing
+func unknownWeather() WeatherConditions {
+	nan := math.NaN()
+	return WeatherConditions{Temperature: nan, Humidity: nan, WindSpeed: nan, WindDirection: nan, Precipitation: nan}
+}
+
+// WeatherProvider fetches the current weather
+type WeatherProvider interface {
+	Current(ctx context.Context) (WeatherConditions, error)
+}
+
+// WeatherOptions configures the virtual weather device
+type WeatherOptions struct {
+	Provider  string        // WeatherProviderOpenMeteo or WeatherProviderJMA
+	Latitude  float64       // location for Open-Meteo
+	Longitude float64       // location for Open-Meteo
+	Station   string        // AMeDAS station number for JMA, e.g. "44132" (Tokyo)
+	Interval  time.Duration // how often the weather is fetched (0 for DefaultWeatherInterval)
+	IP        net.IP        // address of the virtual node (nil for DefaultWeatherIP)
+	BaseURL   string        // base URL of the provider (empty for the public service)
+	// Sun is where the sunrise and sunset properties are computed (nil to omit them).
+	// They are computed locally, so they are available with either provider.
+	Sun *handler.Coordinates
+}
+
+// Validate checks the provider and its location
+func (o WeatherOptions) Validate() error {
+	switch o.Provider {
+	case WeatherProviderOpenMeteo:
+		if o.Latitude < -90 || o.Latitude > 90 || o.Longitude < -180 || o.Longitude > 180 {
+			return fmt.Errorf("invalid weather location: latitude %v, longitude %v", o.Latitude, o.Longitude)
+		}
+	case WeatherProviderJMA:
+		if o.Station == "" {
+			return fmt.Errorf("weather provider %q needs a station", WeatherProviderJMA)
+		}
+	default:
+		return fmt.Errorf("unknown weather provider %q (expected %q or %q)", o.Provider, WeatherProviderOpenMeteo, WeatherProviderJMA)
+	}
+	if o.Interval < 0 {
+		return fmt.Errorf("weather interval must not be negative: %v", o.Interval)
+	}
+	if o.IP != nil && o.IP.To4() == nil {
+		return fmt.Errorf("weather ip must be an IPv4 address: %v", o.IP)
+	}
+	if o.Sun != nil {
+		if err := o.Sun.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewWeatherProvider creates the provider named in opts
+func NewWeatherProvider(opts WeatherOptions) (WeatherProvider, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: weatherFetchTimeout}
+	switch opts.Provider {
+	case WeatherProviderJMA:
+		return &jmaProvider{baseURL: baseURLOr(opts.BaseURL, jmaBaseURL), station: opts.Station, client: client}, nil
+	default:
+		return &openMeteoProvider{baseURL: baseURLOr(opts.BaseURL, openMeteoBaseURL), latitude: opts.Latitude, longitude: opts.Longitude, client: client}, nil
+	}
+}
+
+func baseURLOr(baseURL, def string) string {
+	if baseURL == "" {
+		return def
+	}
+	return strings.TrimSuffix(baseURL, "/")
+}
+
+// fetchWeatherJSON fetches endpoint and decodes the JSON response into v
+func fetchWeatherJSON(ctx context.Context, client *http.Client, endpoint string, v any) error {
+	body, err := fetchWeather(ctx, client, endpoint)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decode %s: %w", endpoint, err)
+	}
+	return nil
+}
+
+// fetchWeather fetches endpoint and returns the response body
+func fetchWeather(ctx context.Context, client *http.Client, endpoint string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<24))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("GET %s: %s", endpoint, resp.Status)
+	}
+	return body, nil
+}
+
+// openMeteoProvider fetches the current weather from the forecast API of Open-Meteo
+type openMeteoProvider struct {
+	baseURL   string
+	latitude  float64
+	longitude float64
+	client    *http.Client
+}
+
+func (p *openMeteoProvider) Current(ctx context.Context) (WeatherConditions, error) {
+	query := url.Values{}
+	query.Set("latitude", strconv.FormatFloat(p.latitude, 'f', -1, 64))
+	query.Set("longitude", strconv.FormatFloat(p.longitude, 'f', -1, 64))
+	query.Set("current", "temperature_2m,relative_humidity_2m,wind_speed_10m,wind_direction_10m,precipitation,weather_code")
+	query.Set("wind_speed_unit", "ms")
+
+	var response struct {
+		Current struct {
+			Temperature   *float64 `json:"temperature_2m"`
+			Humidity      *float64 `json:"relative_humidity_2m"`
+			WindSpeed     *float64 `json:"wind_speed_10m"`
+			WindDirection *float64 `json:"wind_direction_10m"`
+			Precipitation *float64 `json:"precipitation"`
+			WeatherCode   *int     `json:"weather_code"`
+		} `json:"current"`
+	}
+	if err := fetchWeatherJSON(ctx, p.client, p.baseURL+"/v1/forecast?"+query.Encode(), &response); err != nil {
+		return WeatherConditions{}, err
+	}
+
+	c := response.Current
+	result := WeatherConditions{
+		Temperature:   valueOrNaN(c.Temperature),
+		Humidity:      valueOrNaN(c.Humidity),
+		WindSpeed:     valueOrNaN(c.WindSpeed),
+		WindDirection: valueOrNaN(c.WindDirection),
+		Precipitation: valueOrNaN(c.Precipitation),
+	}
+	if c.WeatherCode != nil {
+		result.Condition = wmoCondition(*c.WeatherCode)
+	}
+	return result, nil
+}
+
+func valueOrNaN(v *float64) float64 {
+	if v == nil {
+		return math.NaN()
+	}
+	return *v
+}
+
+// wmoCondition maps a WMO weather interpretation code to a weather condition alias
+func wmoCondition(code int) string {
+	switch {
+	case code <= 1:
+		return "clear"
+	case code <= 3:
+		return "cloudy"
+	case code == 45 || code == 48:
+		return "fog"
+	case code >= 51 && code <= 67, code >= 80 && code <= 82:
+		return "rain"
+	case code >= 71 && code <= 77, code == 85 || code == 86:
+		return "snow"
+	case code >= 95 && code <= 99:
+		return "thunderstorm"
+	}
+	return "unknown"
+}
+
+// jmaProvider fetches the latest observation of an AMeDAS station from the Japan Meteorological Agency.
+// AMeDAS does not report the weather itself, so the condition is "rain" while it is raining and unknown otherwise.
+type jmaProvider struct {
+	baseURL string
+	station string
+	client  *http.Client
+}
+
+// jmaLatestTimeLayout is the layout of latest_time.txt, e.g. "2024-05-01T12:30:00+09:00"
+const jmaLatestTimeLayout = time.RFC3339
+
+func (p *jmaProvider) Current(ctx context.Context) (WeatherConditions, error) {
+	latestEndpoint := p.baseURL + "/bosai/amedas/data/latest_time.txt"
+	body, err := fetchWeather(ctx, p.client, latestEndpoint)
+	if err != nil {
+		return WeatherConditions{}, err
+	}
+	latest, err := time.Parse(jmaLatestTimeLayout, strings.TrimSpace(string(body)))
+	if err != nil {
+		return WeatherConditions{}, fmt.Errorf("decode %s: %w", latestEndpoint, err)
+	}
+
+	// Each element is [value, quality flag]; the value is null when it is not observed
+	var stations map[string]map[string][]*float64
+	if err := fetchWeatherJSON(ctx, p.client, p.baseURL+"/bosai/amedas/data/map/"+latest.Format("20060102150405")+".json", &stations); err != nil {
+		return WeatherConditions{}, err
+	}
+	observation, ok := stations[p.station]
+	if !ok {
+		return WeatherConditions{}, fmt.Errorf("AMeDAS station %s is not in the observations at %s", p.station, latest.Format(time.RFC3339))
+	}
+	value := func(key string) float64 {
+		if v := observation[key]; len(v) > 0 {
+			return valueOrNaN(v[0])
+		}
+		return math.NaN()
+	}
+
+	result := WeatherConditions{
+		Temperature:   value("temp"),
+		Humidity:      value("humidity"),
+		WindSpeed:     value("wind"),
+		WindDirection: value("windDirection"),
+		Precipitation: value("precipitation1h"),
+	}
+	// The wind direction is one of 16 points clockwise from NNE (1) to N (16), or 0 when calm
+	if !math.IsNaN(result.WindDirection) {
+		result.WindDirection = math.Mod(result.WindDirection*22.5, 360)
+	}
+	if result.Precipitation > 0 {
+		result.Condition = "rain"
+	}
+	return result, nil
+}
+
+// WeatherNode serves the current weather as a virtual ECHONET Lite node, so that it appears as a device
+// and its properties can be used in alert rules and dashboards like those of a real outdoor sensor
+type WeatherNode struct {
+	provider WeatherProvider
+	interval time.Duration
+	ip       net.IP
+	sun      *handler.Coordinates
+	network  *simulator.Network
+	now      func() time.Time
+
+	mu      sync.RWMutex
+	current WeatherConditions
+	updated time.Time // when current was fetched (zero until the first successful fetch)
+}
+
+// NewWeatherNode creates the virtual weather node; call Run to start fetching
+func NewWeatherNode(opts WeatherOptions) (*WeatherNode, error) {
+	provider, err := NewWeatherProvider(opts)
+	if err != nil {
+		return nil, err
+	}
+	return newWeatherNode(provider, opts), nil
+}
+
+func newWeatherNode(provider WeatherProvider, opts WeatherOptions) *WeatherNode {
+	w := &WeatherNode{
+		provider: provider,
+		interval: opts.Interval,
+		ip:       opts.IP,
+		sun:      opts.Sun,
+		now:      time.Now,
+		current:  unknownWeather(),
+	}
+	if w.interval == 0 {
+		w.interval = DefaultWeatherInterval
+	}
+	if w.ip == nil {
+		w.ip = net.ParseIP(DefaultWeatherIP)
+	}
+
+	sensor := func(epc echonet_lite.EPCType, encode func(WeatherConditions) []byte) simulator.Sensor {
+		return simulator.Sensor{EPC: epc, Value: func(time.Time) []byte { return encode(w.conditions()) }}
+	}
+	epcs := []echonet_lite.EPCType{
+		echonet_lite.EPC_WE_Temperature,
+		echonet_lite.EPC_WE_Humidity,
+		echonet_lite.EPC_WE_WindSpeed,
+		echonet_lite.EPC_WE_WindDirection,
+		echonet_lite.EPC_WE_Precipitation,
+		echonet_lite.EPC_WE_Condition,
+	}
+	sensors := []simulator.Sensor{
+		sensor(echonet_lite.EPC_WE_Temperature, func(c WeatherConditions) []byte {
+			return encodeWeatherValue(echonet_lite.EPC_WE_Temperature, c.Temperature, echonet_lite.WeatherUnknownSigned1)
+		}),
+		sensor(echonet_lite.EPC_WE_Humidity, func(c WeatherConditions) []byte {
+			return encodeWeatherValue(echonet_lite.EPC_WE_Humidity, c.Humidity, echonet_lite.WeatherUnknownUnsigned1)
+		}),
+		sensor(echonet_lite.EPC_WE_WindSpeed, func(c WeatherConditions) []byte {
+			return encodeWeatherValue(echonet_lite.EPC_WE_WindSpeed, c.WindSpeed, echonet_lite.WeatherUnknownUnsigned1)
+		}),
+		sensor(echonet_lite.EPC_WE_WindDirection, func(c WeatherConditions) []byte {
+			return encodeWeatherValue(echonet_lite.EPC_WE_WindDirection, c.WindDirection, echonet_lite.WeatherUnknownUnsigned2)
+		}),
+		sensor(echonet_lite.EPC_WE_Precipitation, func(c WeatherConditions) []byte {
+			return encodeWeatherValue(echonet_lite.EPC_WE_Precipitation, c.Precipitation, echonet_lite.WeatherUnknownUnsigned2)
+		}),
+		sensor(echonet_lite.EPC_WE_Condition, encodeWeatherCondition),
+	}
+	if w.sun != nil {
+		sun := *w.sun
+		epcs = append(epcs, echonet_lite.EPC_WE_Sunrise, echonet_lite.EPC_WE_Sunset, echonet_lite.EPC_WE_Daylight)
+		sensors = append(sensors,
+			simulator.Sensor{EPC: echonet_lite.EPC_WE_Sunrise, Value: func(t time.Time) []byte { return encodeSunEvent(sun, t, handler.Sunrise) }},
+			simulator.Sensor{EPC: echonet_lite.EPC_WE_Sunset, Value: func(t time.Time) []byte { return encodeSunEvent(sun, t, handler.Sunset) }},
+			simulator.Sensor{EPC: echonet_lite.EPC_WE_Daylight, Value: func(t time.Time) []byte { return encodeDaylight(sun, t) }},
+		)
+	}
+	w.network = simulator.NewNetwork([]simulator.NodeSpec{{
+		IP: w.ip,
+		Devices: []simulator.DeviceSpec{{
+			EOJ: echonet_lite.MakeEOJ(echonet_lite.Weather_ClassCode, 1),
+			Properties: echonet_lite.Properties{
+				{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}},
+				{EPC: echonet_lite.EPCInstallationLocation, EDT: []byte{weatherLocationGarden}},
+				{EPC: echonet_lite.EPCFaultStatus, EDT: []byte{0x42}},
+			},
+			Announce: epcs,
+			Sensors:  sensors,
+		}},
+	}})
+	return w
+}
+
+// encodeSunEvent encodes the local time of the sunrise or sunset on the day of t as hour and minute
+func encodeSunEvent(sun handler.Coordinates, t time.Time, event handler.SolarEvent) []byte {
+	at, ok := sun.SunEvent(t, event)
+	if !ok {
+		return echonet_lite.WeatherNoSunEvent
+	}
+	at = at.Round(time.Minute)
+	return []byte{byte(at.Hour()), byte(at.Minute())}
+}
+
+// encodeDaylight encodes whether t is between the sunrise and sunset of its day.
+// On days without them, it is day when the sun is up at noon (midnight sun) and night otherwise.
+func encodeDaylight(sun handler.Coordinates, t time.Time) []byte {
+	day, night := []byte{0x41}, []byte{0x42}
+	sunrise, sunset, ok := sun.SunTimes(t)
+	if !ok {
+		summer := t.Month() >= time.April && t.Month() <= time.September
+		if summer == (sun.Latitude > 0) {
+			return day
+		}
+		return night
+	}
+	if !t.Before(sunrise) && t.Before(sunset) {
+		return day
+	}
+	return night
+}
+
+// encodeWeatherValue encodes a value rounded and clamped to the range of the property, or unknown when it is NaN
+func encodeWeatherValue(epc echonet_lite.EPCType, value float64, unknown []byte) []byte {
+	desc, ok := echonet_lite.GetPropertyDesc(echonet_lite.Weather_ClassCode, epc)
+	if !ok || math.IsNaN(value) {
+		return unknown
+	}
+	number, ok := desc.Decoder.(echonet_lite.NumberDesc)
+	if !ok {
+		return unknown
+	}
+	n := int(math.Round(value))
+	n = max(number.Min, min(number.Max, n))
+	if edt, ok := number.FromInt(n); ok {
+		return edt
+	}
+	return unknown
+}
+
+// encodeWeatherCondition encodes the condition alias, or unknown when the provider did not report one
+func encodeWeatherCondition(c WeatherConditions) []byte {
+	desc, ok := echonet_lite.GetPropertyDesc(echonet_lite.Weather_ClassCode, echonet_lite.EPC_WE_Condition)
+	if !ok {
+		return nil
+	}
+	if edt, ok := desc.Aliases[c.Condition]; ok {
+		return edt
+	}
+	return desc.Aliases["unknown"]
+}
+
+// conditions returns the last fetched conditions, or unknown ones when fetching has failed for too long
+func (w *WeatherNode) conditions() WeatherConditions {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.updated.IsZero() || w.now().Sub(w.updated) > weatherStaleIntervals*w.interval {
+		return unknownWeather()
+	}
+	return w.current
+}
+
+// Network returns the virtual node to pass as ECHONETLieHandlerOptions.VirtualNodes
+func (w *WeatherNode) Network() *simulator.Network {
+	return w.network
+}
+
+// Run fetches the weather every interval and announces changed values until ctx is done.
+// With the sunrise and sunset properties, they are also updated every minute so that the daylight changes on time.
+func (w *WeatherNode) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	var sunTicks <-chan time.Time
+	if w.sun != nil {
+		sunTicker := time.NewTicker(time.Minute)
+		defer sunTicker.Stop()
+		sunTicks = sunTicker.C
+	}
+	w.fetch(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.fetch(ctx)
+		case <-sunTicks:
+			w.network.UpdateSensors()
+		}
+	}
+}
+
+// fetch updates the current conditions from the provider and announces the properties that changed.
+// The properties are also updated when fetching fails, so that stale conditions become unknown.
+func (w *WeatherNode) fetch(ctx context.Context) {
+	defer w.network.UpdateSensors()
+
+	conditions, err := w.provider.Current(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Warn("Failed to fetch the weather", "err", err, NoBroadcast())
+		}
+		return
+	}
+	w.mu.Lock()
+	w.current = conditions
+	w.updated = w.now()
+	w.mu.Unlock()
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/echonet_lite/simulator"
+	"echonet-list/protocol"
+)
+
+func TestOpenMeteoProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/forecast" || r.URL.Query().Get("latitude") != "35.68" || r.URL.Query().Get("wind_speed_unit") != "ms" {
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"current":{"time":"2024-05-01T03:30","temperature_2m":-3.6,"relative_humidity_2m":81,` +
+			`"wind_speed_10m":12.4,"wind_direction_10m":275,"precipitation":1.2,"weather_code":73}}`))
+	}))
+	defer srv.Close()
+
+	provider, err := NewWeatherProvider(WeatherOptions{Provider: WeatherProviderOpenMeteo, Latitude: 35.68, Longitude: 139.76, BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := provider.Current(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := WeatherConditions{Temperature: -3.6, Humidity: 81, WindSpeed: 12.4, WindDirection: 275, Precipitation: 1.2, Condition: "snow"}
+	if got != want {
+		t.Errorf("Current() = %+v, want %+v", got, want)
+	}
+}
+
+func TestJMAProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bosai/amedas/data/latest_time.txt":
+			_, _ = w.Write([]byte("2024-05-01T12:30:00+09:00\n"))
+		case "/bosai/amedas/data/map/20240501123000.json":
+			_, _ = w.Write([]byte(`{"44132":{"temp":[21.3,0],"humidity":[null,1],"wind":[4.1,0],"windDirection":[12,0],"precipitation1h":[0.5,0]}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	provider, err := NewWeatherProvider(WeatherOptions{Provider: WeatherProviderJMA, Station: "44132", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := provider.Current(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Temperature != 21.3 || got.WindSpeed != 4.1 || got.WindDirection != 270 || got.Precipitation != 0.5 || got.Condition != "rain" {
+		t.Errorf("Current() = %+v", got)
+	}
+	if !math.IsNaN(got.Humidity) {
+		t.Errorf("Humidity = %v, want NaN for a missing observation", got.Humidity)
+	}
+
+	// A station missing from the observations is an error
+	provider, _ = NewWeatherProvider(WeatherOptions{Provider: WeatherProviderJMA, Station: "99999", BaseURL: srv.URL})
+	if _, err := provider.Current(context.Background()); err == nil {
+		t.Error("expected an error for an unknown station")
+	}
+}
+
+func TestWeatherOptionsValidate(t *testing.T) {
+	for _, opts := range []WeatherOptions{
+		{Provider: "accuweather"},
+		{Provider: WeatherProviderOpenMeteo, Latitude: 91},
+		{Provider: WeatherProviderJMA},
+		{Provider: WeatherProviderOpenMeteo, Interval: -time.Minute},
+	} {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want an error", opts)
+		}
+	}
+}
+
+// stubWeatherProvider returns fixed conditions or an error
+type stubWeatherProvider struct {
+	conditions WeatherConditions
+	err        error
+}
+
+func (p *stubWeatherProvider) Current(context.Context) (WeatherConditions, error) {
+	return p.conditions, p.err
+}
+
+func TestWeatherNodeEncodesConditions(t *testing.T) {
+	provider := &stubWeatherProvider{conditions: WeatherConditions{
+		Temperature: -4.6, Humidity: 55, WindSpeed: 12.5, WindDirection: 90, Precipitation: 0, Condition: "clear",
+	}}
+	node := newWeatherNode(provider, WeatherOptions{Interval: time.Minute})
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	node.now = func() time.Time { return now }
+
+	number := func(epc echonet_lite.EPCType, encode func(WeatherConditions) []byte) *int {
+		return protocol.MakePropertyData(echonet_lite.Weather_ClassCode, echonet_lite.Property{EPC: epc, EDT: encode(node.conditions())}).Number
+	}
+	temperature := func(c WeatherConditions) []byte {
+		return encodeWeatherValue(echonet_lite.EPC_WE_Temperature, c.Temperature, echonet_lite.WeatherUnknownSigned1)
+	}
+	windSpeed := func(c WeatherConditions) []byte {
+		return encodeWeatherValue(echonet_lite.EPC_WE_WindSpeed, c.WindSpeed, echonet_lite.WeatherUnknownUnsigned1)
+	}
+
+	// Nothing is known before the first fetch
+	if n := number(echonet_lite.EPC_WE_Temperature, temperature); n != nil {
+		t.Errorf("temperature before fetching = %d, want none", *n)
+	}
+
+	node.fetch(context.Background())
+	if n := number(echonet_lite.EPC_WE_Temperature, temperature); n == nil || *n != -5 {
+		t.Errorf("temperature = %v, want -5", n)
+	}
+	if n := number(echonet_lite.EPC_WE_WindSpeed, windSpeed); n == nil || *n != 13 {
+		t.Errorf("wind speed = %v, want 13", n)
+	}
+	if got := protocol.MakePropertyData(echonet_lite.Weather_ClassCode, echonet_lite.Property{EPC: echonet_lite.EPC_WE_Condition, EDT: encodeWeatherCondition(node.conditions())}).String; got != "clear" {
+		t.Errorf("condition = %q, want clear", got)
+	}
+
+	// A failed fetch keeps the last conditions until they become stale
+	provider.err = errors.New("unavailable")
+	now = now.Add(2 * time.Minute)
+	node.fetch(context.Background())
+	if n := number(echonet_lite.EPC_WE_WindSpeed, windSpeed); n == nil || *n != 13 {
+		t.Errorf("wind speed after a failed fetch = %v, want 13", n)
+	}
+	now = now.Add(2 * time.Minute)
+	if n := number(echonet_lite.EPC_WE_WindSpeed, windSpeed); n != nil {
+		t.Errorf("stale wind speed = %d, want none", *n)
+	}
+	if got := encodeWeatherCondition(node.conditions()); string(got) != "\x40" {
+		t.Errorf("stale condition = %X, want unknown", got)
+	}
+}
+
+func TestWeatherNodeEncodesSunTimes(t *testing.T) {
+	tokyo := handler.Coordinates{Latitude: 35.68, Longitude: 139.76}
+	jst := time.FixedZone("JST", 9*60*60)
+	noon := time.Date(2026, 6, 21, 12, 0, 0, 0, jst)
+
+	sunrise := encodeSunEvent(tokyo, noon, handler.Sunrise)
+	if len(sunrise) != 2 || sunrise[0] != 4 {
+		t.Errorf("sunrise = %X, want around 04:25", sunrise)
+	}
+	sunset := encodeSunEvent(tokyo, noon, handler.Sunset)
+	if len(sunset) != 2 || sunset[0] < 18 || sunset[0] > 19 {
+		t.Errorf("sunset = %X, want around 19:00", sunset)
+	}
+	if got := encodeDaylight(tokyo, noon); got[0] != 0x41 {
+		t.Errorf("daylight at noon = %X, want day", got)
+	}
+	if got := encodeDaylight(tokyo, noon.Add(11*time.Hour)); got[0] != 0x42 {
+		t.Errorf("daylight at 23:00 = %X, want night", got)
+	}
+
+	// Midnight sun has neither sunrise nor sunset, but it is day
+	svalbard := handler.Coordinates{Latitude: 78, Longitude: 15}
+	if got := encodeSunEvent(svalbard, noon, handler.Sunrise); string(got) != string(echonet_lite.WeatherNoSunEvent) {
+		t.Errorf("sunrise in midnight sun = %X, want none", got)
+	}
+	if got := encodeDaylight(svalbard, noon); got[0] != 0x41 {
+		t.Errorf("daylight in midnight sun = %X, want day", got)
+	}
+}
+
+func TestWeatherNodeIsDiscovered(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	provider := &stubWeatherProvider{conditions: WeatherConditions{
+		Temperature: 18, Humidity: 60, WindSpeed: 11, WindDirection: 180, Precipitation: 0, Condition: "cloudy",
+	}}
+	node := newWeatherNode(provider, WeatherOptions{Interval: time.Minute})
+	node.fetch(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h, err := handler.NewECHONETLiteHandler(ctx, handler.ECHONETLieHandlerOptions{
+		Connection:   simulator.NewNetwork(simulator.DemoNodes()),
+		VirtualNodes: node.Network(),
+		InMemory:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	h.StartMainLoop()
+	go func() { _ = h.Discover() }()
+
+	weather := echonet_lite.IPAndEOJ{IP: net.ParseIP(DefaultWeatherIP), EOJ: echonet_lite.MakeEOJ(echonet_lite.Weather_ClassCode, 1)}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if p, ok := h.GetDataManagementHandler().GetProperty(weather, echonet_lite.EPC_WE_WindSpeed); ok {
+			if n := protocol.MakePropertyData(echonet_lite.Weather_ClassCode, *p).Number; n == nil || *n != 11 {
+				t.Fatalf("wind speed = %v, want 11", n)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("weather device was not discovered: %v", h.ListDevices(handler.FilterCriteria{}))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
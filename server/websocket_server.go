@@ -52,15 +52,41 @@ type StartOptions struct {
 	CertFile string
 	// TLS秘密鍵ファイルのパス (TLSを使用する場合)
 	KeyFile string
+	// ACME (Let's Encrypt) で証明書を自動取得する設定 (nil で無効、指定した場合は CertFile/KeyFile より優先)
+	ACME *ACMEOptions
 	// 定期的なプロパティ更新の間隔 (0以下で無効)
 	PeriodicUpdateInterval time.Duration
+	// デバイスクラス・デバイスごとの定期更新の間隔 (PeriodicUpdateInterval より優先)
+	PollIntervals PollIntervals
+	// 定期更新しないデバイスクラス・デバイス (nil の場合はなし)。INF 通知は受け付ける。manage_poll_exclusions で実行中に変更できる
+	PollExclusions *PollExclusions
 	// 強制更新の間隔 (0以下で無効、通常30分程度)
 	ForcedUpdateInterval time.Duration
+	// 強制更新を行う時刻 (cron 形式、nil で無効)。指定した場合は ForcedUpdateInterval より優先
+	ForcedUpdateSchedule *handler.CronSpec
 	// サーバーの待ち受け完了を通知するチャネル
 	Ready chan struct{}
 	// HTTPサーバーの設定
 	HTTPEnabled bool
 	HTTPWebRoot string
+	// ECHONET Lite Web API (/elapi/v1/) を REST API と一緒に提供する
+	ELWAEnabled bool
+	// 同一デバイスのプロパティ変化をまとめて通知する時間幅 (0以下で無効、変化ごとに property_changed を送る)
+	PropertyChangeCoalesceWindow time.Duration
+	// クライアントごとの送信キューの長さ (0以下でデフォルトの256)
+	SendQueueSize int
+	// 送信キューが一杯になったクライアントの扱い (空文字でデフォルトの disconnect)
+	SlowClientPolicy SlowClientPolicy
+	// クライアントごと・メッセージタイプごとのリクエスト数の上限 (nil または空で無制限)
+	RateLimits RateLimits
+	// 通知・記録する数値プロパティの変化の最小幅 (nil または空で全ての変化を通知)
+	ChangeThresholds ChangeThresholds
+	// トークンごとのデバイスへのアクセス制御 (nil で無効、全クライアントが全操作を行える)
+	AccessControl *AccessControl
+	// 数値プロパティの閾値によるアラート (nil で無効)
+	AlertRules *AlertRules
+	// 数値プロパティの値を InfluxDB の line protocol で書き出す (nil で無効)
+	InfluxDB *InfluxDBOptions
 }
 
 // setOperationTracker tracks a recent SET operation for duplicate detection.
@@ -86,8 +112,10 @@ type WebSocketServer struct {
 	initialStateInProgress atomic.Int32                      // Counter for ongoing initial state generations
 	lastUpdateTime         atomic.Int64                      // Unix timestamp of last periodic update (for monitoring)
 	lastForcedUpdateTime   atomic.Int64                      // Unix timestamp of last forced update
+	shuttingDown           atomic.Bool                       // Set by BeginShutdown; client requests are rejected
 	updateInterval         time.Duration                     // Expected update interval (for monitoring)
 	forcedUpdateInterval   time.Duration                     // Forced update interval
+	forcedUpdateSchedule   *handler.CronSpec                 // Wall-clock times of forced updates (overrides forcedUpdateInterval)
 	timeProvider           TimeProvider                      // Time provider for testability
 	serverStartupTime      time.Time                         // Server startup timestamp
 	deviceResolver         func(echonet_lite.IPAndEOJ) bool  // Resolves whether a device is known
@@ -95,6 +123,19 @@ type WebSocketServer struct {
 	recentSetOpsMutex      sync.RWMutex                      // Protects recentSetOps
 	cleanupDone            chan bool                         // Channel to stop the cleanup goroutine
 	heartbeatDone          chan bool                         // Channel to stop the heartbeat goroutine
+	propertyCoalescer      *propertyChangeCoalescer          // Coalesces property changes per device (nil if disabled)
+	changeFilter           *changeThresholdFilter            // Drops insignificant numeric changes (nil if disabled)
+	alerts                 *alertMonitor                     // Raises alerts on numeric thresholds (nil if disabled)
+	influx                 *influxExporter                   // Writes numeric property values to InfluxDB (nil if disabled)
+	rateLimiter            *requestRateLimiter               // Limits requests per client and message type (nil if disabled)
+	operations             bulkOperations                    // Bulk operations started with async
+	access                 *AccessControl                    // Per-token device access control (nil if disabled)
+	pollExclusions         *PollExclusions                   // Classes and devices that are not polled periodically
+	clientRules            sync.Map                          // connID -> *AccessRule, only when access control is enabled
+	clientLangs            sync.Map                          // connID -> language given with ?lang= in the WebSocket URL
+	events                 *eventStream                      // Mirrors broadcasts to SSE subscribers of /api/events
+	replays                sync.Map                          // connID -> *replaySession of a running replay_history
+	logs                   *LogStream                        // Log records for subscribe_logs (nil if not available)
 }
 
 // NewWebSocketServer creates a new WebSocket server
@@ -122,6 +163,7 @@ func NewWebSocketServer(ctx context.Context, addr string, echonetClient client.E
 		timeProvider:      &RealTimeProvider{}, // Use real time by default
 		serverStartupTime: startupTime,
 		recentSetOps:      make(map[string]setOperationTracker), // Initialize SET operation tracking map
+		events:            newEventStream(serverCtx),
 	}
 
 	ws.deviceResolver = func(device echonet_lite.IPAndEOJ) bool {
@@ -285,7 +327,7 @@ func (ws *WebSocketServer) isPropertySettable(device handler.IPAndEOJ, epc echon
 	if dataHandler == nil {
 		return false
 	}
-	return dataHandler.HasEPCInPropertyMap(device, handler.SetPropertyMap, epc)
+	return dataHandler.HasEPCInPropertyMap(device, handler.SetPropertyMap, epc) || echonet_lite.IsWriteOnlyEPC(device.EOJ.ClassCode(), epc)
 }
 
 // periodicUpdater runs in a goroutine, triggering property updates at the configured interval
@@ -313,6 +355,11 @@ func (ws *WebSocketServer) periodicUpdater() {
 	for {
 		select {
 		case <-ws.updateTicker.C:
+			// Do not start new polls while in-flight requests are drained on shutdown
+			if ws.shuttingDown.Load() {
+				continue
+			}
+
 			// Check if initial state generation is in progress
 			clientCount := ws.activeClients.Load()
 			initialStateCount := ws.initialStateInProgress.Load()
@@ -345,8 +392,8 @@ func (ws *WebSocketServer) periodicUpdater() {
 						}
 					}()
 
-					// Use an empty FilterCriteria to target all devices
-					err := ws.handler.UpdateProperties(handler.FilterCriteria{}, shouldForce)
+					// Update the devices whose poll interval has passed (all devices without per-device intervals)
+					err := ws.handler.UpdateScheduledProperties(shouldForce)
 					if err != nil {
 						// Log the error but don't stop the ticker
 						if shouldForce {
@@ -377,6 +424,16 @@ func (ws *WebSocketServer) periodicUpdater() {
 
 // shouldPerformForcedUpdate determines if the current update should be forced
 func (ws *WebSocketServer) shouldPerformForcedUpdate(currentTime time.Time) bool {
+	// A schedule forces an update at the first tick after each scheduled time
+	if ws.forcedUpdateSchedule != nil {
+		since := ws.serverStartupTime
+		if lastForcedUpdate := ws.lastForcedUpdateTime.Load(); lastForcedUpdate != 0 {
+			since = time.Unix(0, lastForcedUpdate)
+		}
+		next := ws.forcedUpdateSchedule.Next(since)
+		return !next.IsZero() && !currentTime.Before(next)
+	}
+
 	// If forced update interval is disabled (0 or negative), never force
 	if ws.forcedUpdateInterval <= 0 {
 		return false
@@ -586,6 +643,8 @@ func (ws *WebSocketServer) handleClientConnect(connID string) error {
 	if ws.handler.IsDebug() {
 		slog.Debug("New WebSocket connection established", "connID", connID)
 	}
+	ws.registerClientRule(connID)
+	ws.registerClientLanguage(connID)
 
 	// Increment active client count
 	ws.activeClients.Add(1)
@@ -593,9 +652,23 @@ func (ws *WebSocketServer) handleClientConnect(connID string) error {
 		slog.Debug("Active clients", "count", ws.activeClients.Load())
 	}
 
+	// The client may ask for only some parts of the initial state, or none
+	request, err := ws.initialStateRequestForConnection(connID)
+	if err != nil {
+		slog.Warn("Invalid initial state request", "connID", connID, "err", err, NoBroadcast())
+		errorPayload := protocol.ErrorNotificationPayload{
+			Code:    protocol.ErrorCodeInvalidParameters,
+			Message: fmt.Sprintf("Invalid initial state request: %v", err),
+		}
+		return ws.sendMessageToClient(connID, protocol.MessageTypeErrorNotification, errorPayload, "")
+	}
+	if request.skip() {
+		return nil
+	}
+
 	// Send initial state to the client asynchronously
 	// Don't wait for completion to avoid blocking the connection handler
-	if err := ws.sendInitialStateToClient(connID); err != nil {
+	if err := ws.sendInitialStateToClient(connID, request); err != nil {
 		slog.Error("Failed to start initial state sending", "error", err, "connID", connID)
 		// Don't return error here as connection should still be established
 	}
@@ -625,10 +698,40 @@ func (ws *WebSocketServer) handleClientMessage(connID string, message []byte) er
 		slog.Debug("Parsed message", "connID", connID, "type", msg.Type, "requestID", msg.RequestID)
 	}
 
+	// Reject requests once a graceful shutdown has started
+	if ws.shuttingDown.Load() {
+		result := ErrorResponse(protocol.ErrorCodeShuttingDown, "The server is shutting down")
+		return ws.sendMessageToClient(connID, protocol.MessageTypeCommandResult, result, msg.RequestID)
+	}
+
+	// Reject requests over the per-client rate limit before doing any work
+	if ws.rateLimiter != nil && !ws.rateLimiter.Allow(connID, msg.Type) {
+		limit := ws.rateLimiter.limits[msg.Type]
+		// Broadcasting it to every client would amplify the flood
+		slog.Warn("Request rate limit exceeded", "connID", connID, "type", msg.Type, "rate", limit.Rate, "burst", limit.Burst, NoBroadcast())
+		result := ErrorResponse(protocol.ErrorCodeRateLimited, "Too many %s requests: limit is %g per second (burst %d)", msg.Type, limit.Rate, limit.Burst)
+		return ws.sendMessageToClient(connID, protocol.MessageTypeCommandResult, result, msg.RequestID)
+	}
+
+	// Reject requests the access rule of the client does not allow
+	if ws.access != nil {
+		if result, ok := ws.applyAccessRule(ws.ruleForConnection(connID), msg); !ok {
+			ws.audit(connID, msg, auditOutcomeDenied, result.Error.Message)
+			return ws.sendMessageToClient(connID, protocol.MessageTypeCommandResult, result, msg.RequestID)
+		}
+	}
+
 	handle := func(handler func(msg *protocol.Message) protocol.CommandResultPayload) error {
 		result := handler(msg)
 		if !result.Success {
-			slog.Error("Error for RequestID", "requestID", msg.RequestID, "message", result.Error.Message)
+			slog.Error("Error for RequestID", "connID", connID, "requestID", msg.RequestID, "message", result.Error.Message)
+		}
+		if auditedMessageTypes[msg.Type] {
+			if result.Success {
+				ws.audit(connID, msg, auditOutcomeSuccess, "")
+			} else {
+				ws.audit(connID, msg, auditOutcomeFailure, result.Error.Message)
+			}
 		}
 		return ws.sendMessageToClient(connID, protocol.MessageTypeCommandResult, result, msg.RequestID)
 	}
@@ -639,33 +742,105 @@ func (ws *WebSocketServer) handleClientMessage(connID string, message []byte) er
 		return handle(ws.handleGetPropertiesFromClient)
 	case protocol.MessageTypeSetProperties:
 		return handle(ws.handleSetPropertiesFromClient)
+	case protocol.MessageTypeSetGetProperties:
+		return handle(ws.handleSetGetPropertiesFromClient)
+	case protocol.MessageTypeSetGroupProperties:
+		return handle(func(msg *protocol.Message) protocol.CommandResultPayload {
+			return ws.handleSetGroupPropertiesFromClient(connID, msg)
+		})
 	case protocol.MessageTypeUpdateProperties:
-		return handle(ws.handleUpdatePropertiesFromClient)
+		return handle(func(msg *protocol.Message) protocol.CommandResultPayload {
+			return ws.handleUpdatePropertiesFromClient(connID, msg)
+		})
 	case protocol.MessageTypeListDevices:
-		return handle(ws.handleListDevicesFromClient)
+		return handle(func(msg *protocol.Message) protocol.CommandResultPayload {
+			return ws.handleListDevicesFromClient(connID, msg)
+		})
 	case protocol.MessageTypeManageAlias:
 		return handle(ws.handleManageAliasFromClient)
 	case protocol.MessageTypeManageGroup:
 		return handle(ws.handleManageGroupFromClient)
+	case protocol.MessageTypeManageScene:
+		return handle(ws.handleManageSceneFromClient)
+	case protocol.MessageTypeRunScene:
+		return handle(ws.handleRunSceneFromClient)
+	case protocol.MessageTypeManageSchedule:
+		return handle(ws.handleManageScheduleFromClient)
 	case protocol.MessageTypeDiscoverDevices:
-		return handle(ws.handleDiscoverDevicesFromClient)
+		return handle(func(msg *protocol.Message) protocol.CommandResultPayload {
+			return ws.handleDiscoverDevicesFromClient(connID, msg)
+		})
 	case protocol.MessageTypeGetPropertyDescription:
-		return handle(ws.handleGetPropertyDescriptionFromClient)
+		return handle(func(msg *protocol.Message) protocol.CommandResultPayload {
+			return ws.handleGetPropertyDescriptionFromClient(connID, msg)
+		})
+	case protocol.MessageTypeCleanupDevices:
+		return handle(ws.handleCleanupDevicesFromClient)
 	case protocol.MessageTypeDeleteDevice:
 		return handle(ws.handleDeleteDeviceFromClient)
+	case protocol.MessageTypeRefreshPropertyMap:
+		return handle(ws.handleRefreshPropertyMapFromClient)
 	case protocol.MessageTypeDebugSetOffline:
 		return handle(ws.handleDebugSetOfflineFromClient)
+	case protocol.MessageTypeDebugPendingRequests:
+		return handle(ws.handleDebugPendingRequestsFromClient)
+	case protocol.MessageTypeManageLocalPropertyMaps:
+		return handle(ws.handleManageLocalPropertyMapsFromClient)
+	case protocol.MessageTypeManagePollExclusions:
+		return handle(ws.handleManagePollExclusionsFromClient)
 	case protocol.MessageTypeGetDeviceHistory:
 		return handle(ws.handleGetDeviceHistoryFromClient)
+	case protocol.MessageTypeGetPropertyStatistics:
+		return handle(ws.handleGetPropertyStatisticsFromClient)
+	case protocol.MessageTypeGetSummary:
+		return handle(ws.handleGetSummaryFromClient)
+	case protocol.MessageTypeGetVersions:
+		return handle(ws.handleGetVersionsFromClient)
+	case protocol.MessageTypeGetMemoryUsage:
+		return handle(ws.handleGetMemoryUsageFromClient)
+	case protocol.MessageTypeGetAliases:
+		return handle(ws.handleGetAliasesFromClient)
+	case protocol.MessageTypeGetGroups:
+		return handle(ws.handleGetGroupsFromClient)
+	case protocol.MessageTypeExportCSV:
+		return handle(func(msg *protocol.Message) protocol.CommandResultPayload {
+			return ws.handleExportCSVFromClient(connID, msg)
+		})
+	case protocol.MessageTypeReplayHistory:
+		return handle(func(msg *protocol.Message) protocol.CommandResultPayload {
+			return ws.handleReplayHistoryFromClient(connID, msg)
+		})
+	case protocol.MessageTypeSubscribeLogs:
+		return handle(func(msg *protocol.Message) protocol.CommandResultPayload {
+			return ws.handleSubscribeLogsFromClient(connID, msg)
+		})
 	case protocol.MessageTypeGetLocationSettings:
 		return handle(ws.handleGetLocationSettingsFromClient)
 	case protocol.MessageTypeManageLocationAlias:
 		return handle(ws.handleManageLocationAliasFromClient)
 	case protocol.MessageTypeSetLocationOrder:
 		return handle(ws.handleSetLocationOrderFromClient)
+	case protocol.MessageTypeManageMetadata:
+		return handle(ws.handleManageMetadataFromClient)
+	case protocol.MessageTypeGetPreferences:
+		return handle(func(msg *protocol.Message) protocol.CommandResultPayload {
+			return ws.handleGetPreferencesFromClient(connID, msg)
+		})
+	case protocol.MessageTypeSetPreferences:
+		return handle(func(msg *protocol.Message) protocol.CommandResultPayload {
+			return ws.handleSetPreferencesFromClient(connID, msg)
+		})
+	case protocol.MessageTypeGetOperationStatus:
+		return handle(func(msg *protocol.Message) protocol.CommandResultPayload {
+			return ws.handleGetOperationStatusFromClient(connID, msg)
+		})
+	case protocol.MessageTypeCancelOperation:
+		return handle(func(msg *protocol.Message) protocol.CommandResultPayload {
+			return ws.handleCancelOperationFromClient(connID, msg)
+		})
 
 	default:
-		slog.Error("Unknown message type", "type", msg.Type)
+		slog.Error("Unknown message type", "connID", connID, "type", msg.Type)
 		// エラー応答を送信
 		errorPayload := protocol.ErrorNotificationPayload{
 			Code:    protocol.ErrorCodeInvalidRequestFormat,
@@ -680,6 +855,15 @@ func (ws *WebSocketServer) handleClientDisconnect(connID string) {
 	if ws.handler.IsDebug() {
 		slog.Debug("WebSocket connection closed", "connID", connID)
 	}
+	if ws.rateLimiter != nil {
+		ws.rateLimiter.Remove(connID)
+	}
+	ws.clientRules.Delete(connID)
+	ws.clientLangs.Delete(connID)
+	ws.stopReplay(connID)
+	if ws.logs != nil {
+		ws.logs.Unsubscribe(connID)
+	}
 	// Decrement active client count
 	ws.activeClients.Add(-1)
 	if ws.handler.IsDebug() {
@@ -689,6 +873,58 @@ func (ws *WebSocketServer) handleClientDisconnect(connID string) {
 
 // Start starts the WebSocket server and optionally the periodic updater
 func (ws *WebSocketServer) Start(options StartOptions) error {
+	// Coalesce property changes of the same device into one broadcast
+	if options.PropertyChangeCoalesceWindow > 0 {
+		ws.propertyCoalescer = newPropertyChangeCoalescer(ws.ctx, options.PropertyChangeCoalesceWindow, ws.timeProvider, ws.broadcastPropertyChanges)
+		slog.Info("Property change coalescing enabled", "window", options.PropertyChangeCoalesceWindow)
+	}
+
+	// Drop small changes of jittery sensors before they are recorded and broadcast
+	if len(options.ChangeThresholds) > 0 {
+		ws.changeFilter = newChangeThresholdFilter(options.ChangeThresholds)
+		slog.Info("Property change thresholds enabled", "thresholds", len(options.ChangeThresholds))
+	}
+
+	// Raise alerts when numeric properties cross their thresholds
+	if options.AlertRules != nil && options.AlertRules.Len() > 0 {
+		ws.alerts = newAlertMonitor(options.AlertRules)
+		if options.AlertRules.hasDurations() {
+			go ws.runAlertChecks()
+		}
+		slog.Info("Alert rules enabled", "rules", options.AlertRules.Len())
+	}
+
+	// Export numeric property values to InfluxDB for dashboards
+	if options.InfluxDB != nil {
+		if err := options.InfluxDB.Validate(); err != nil {
+			return err
+		}
+		ws.influx = newInfluxExporter(*options.InfluxDB)
+		go ws.influx.run(ws.ctx)
+		if options.InfluxDB.Interval > 0 {
+			go ws.runInfluxSnapshots(options.InfluxDB.Interval)
+		}
+		slog.Info("InfluxDB export enabled", "url", options.InfluxDB.URL, "udp", options.InfluxDB.UDPAddr, "interval", options.InfluxDB.Interval)
+	}
+
+	// Limit requests per client so that one client cannot flood the ECHONET Lite network
+	if len(options.RateLimits) > 0 {
+		ws.rateLimiter = newRequestRateLimiter(options.RateLimits, time.Now)
+		slog.Info("Request rate limiting enabled", "limits", len(options.RateLimits))
+	}
+
+	// Authenticate clients by token and restrict them to the devices of their access rule
+	if options.AccessControl != nil {
+		ws.setupAccessControl(options.AccessControl)
+		slog.Info("Access control enabled")
+	}
+
+	// Classes and devices that are not polled; an empty list is kept so that entries can be added at runtime
+	ws.pollExclusions = options.PollExclusions
+	if ws.pollExclusions == nil {
+		ws.pollExclusions, _ = ParsePollExclusions(nil)
+	}
+
 	// Start listening for notifications from the ECHONET Lite handler
 	go ws.listenForNotifications()
 
@@ -701,21 +937,57 @@ func (ws *WebSocketServer) Start(options StartOptions) error {
 			if err := transport.SetupStaticFileServer(options.HTTPWebRoot); err != nil {
 				return fmt.Errorf("failed to setup static file server: %v", err)
 			}
+			if ws.echonetClient != nil {
+				api := NewRESTAPIHandler(ws.echonetClient)
+				api.access = ws.access
+				api.events = ws.events
+				api.elwa = options.ELWAEnabled
+				api.history = ws.GetHistoryStore()
+				// Record set operations in history just like set_properties over WebSocket
+				api.onSet = func(device handler.IPAndEOJ, properties echonet_lite.Properties) {
+					for _, prop := range properties {
+						ws.recordSetResult(device, prop.EPC, protocol.MakePropertyData(device.EOJ.ClassCode(), prop))
+					}
+				}
+				transport.SetupRESTAPI(api)
+			}
 		}
 	}
 
-	// Start the periodic updater ticker if interval is positive
-	if options.PeriodicUpdateInterval > 0 {
+	// Start the periodic updater ticker if any device is polled.
+	// The ticker runs at the shortest interval, and each device is polled when its own interval has passed.
+	pollTick := options.PollIntervals.Tick(options.PeriodicUpdateInterval)
+	if pollTick > 0 {
 		// 更新間隔を保存（監視用）
-		ws.updateInterval = options.PeriodicUpdateInterval
+		ws.updateInterval = pollTick
 		ws.forcedUpdateInterval = options.ForcedUpdateInterval
+		ws.forcedUpdateSchedule = options.ForcedUpdateSchedule
 		// 初期時刻は0のまま（実際の更新が開始されるまで監視を無効にするため）
 
-		ws.updateTicker = time.NewTicker(options.PeriodicUpdateInterval)
+		// The schedule is always set so that exclusions added at runtime take effect
+		pollIntervals := options.PollIntervals
+		defaultInterval := options.PeriodicUpdateInterval
+		ws.handler.SetPollSchedule(handler.NewPollSchedule(pollTick, func(device handler.IPAndEOJ) time.Duration {
+			resolver := ws.accessResolver()
+			if ws.pollExclusions.Excludes(resolver, device) {
+				return 0
+			}
+			return pollIntervals.Interval(resolver, device, defaultInterval)
+		}))
+		if !pollIntervals.IsEmpty() {
+			slog.Info("Per-device poll intervals enabled", "classes", len(pollIntervals.Classes), "devices", len(pollIntervals.Devices), "tick", pollTick)
+		}
+		if excluded := ws.pollExclusions.List(); len(excluded) > 0 {
+			slog.Info("Poll exclusions enabled", "excluded", excluded)
+		}
+
+		ws.updateTicker = time.NewTicker(pollTick)
 		go ws.periodicUpdater()
 		go ws.monitorUpdateInterval() // 監視goroutineも開始
 
-		if options.ForcedUpdateInterval > 0 {
+		if options.ForcedUpdateSchedule != nil {
+			slog.Info("Periodic property updater and monitor enabled", "interval", options.PeriodicUpdateInterval, "nextForcedUpdate", options.ForcedUpdateSchedule.Next(time.Now()))
+		} else if options.ForcedUpdateInterval > 0 {
 			slog.Info("Periodic property updater and monitor enabled", "interval", options.PeriodicUpdateInterval, "forcedInterval", options.ForcedUpdateInterval)
 		} else {
 			slog.Info("Periodic property updater and monitor enabled", "interval", options.PeriodicUpdateInterval, "forcedInterval", "disabled")
@@ -732,6 +1004,16 @@ func (ws *WebSocketServer) Start(options StartOptions) error {
 	return ws.transport.Start(options)
 }
 
+// BeginShutdown starts a graceful shutdown. Requests from clients are rejected with SHUTTING_DOWN
+// and no periodic update is started, while requests already in progress are left to finish.
+// Connections stay open until Stop is called.
+func (ws *WebSocketServer) BeginShutdown() {
+	if ws.shuttingDown.Swap(true) {
+		return
+	}
+	slog.Info("Shutting down: no longer accepting client requests")
+}
+
 // Stop stops the WebSocket server and the periodic updater
 func (ws *WebSocketServer) Stop() error {
 	// Signal the periodic updater and monitor to stop if they were started
@@ -755,7 +1037,7 @@ func (ws *WebSocketServer) Stop() error {
 }
 
 // sendInitialStateToClient sends the initial state to a client
-func (ws *WebSocketServer) sendInitialStateToClient(connID string) error {
+func (ws *WebSocketServer) sendInitialStateToClient(connID string, request initialStateRequest) error {
 	if ws.handler.IsDebug() {
 		slog.Debug("Sending initial state to client", "connID", connID)
 	}
@@ -794,7 +1076,7 @@ func (ws *WebSocketServer) sendInitialStateToClient(connID string) error {
 				}
 			}()
 
-			if err := ws.generateAndSendInitialState(connID); err != nil {
+			if err := ws.generateAndSendInitialState(connID, request); err != nil {
 				select {
 				case done <- err:
 				default:
@@ -929,11 +1211,62 @@ func (ws *WebSocketServer) getCachedDeviceList() []handler.DeviceAndProperties {
 }
 
 // generateAndSendInitialState generates and sends the initial state data
-func (ws *WebSocketServer) generateAndSendInitialState(connID string) error {
+func (ws *WebSocketServer) generateAndSendInitialState(connID string, request initialStateRequest) error {
 	if ws.handler.IsDebug() {
 		slog.Debug("Starting initial state generation", "connID", connID)
 	}
+	protoDevices := make(map[string]protocol.Device)
+	aliases := make(map[string]client.IDString)
+	groups := make(map[string][]client.IDString)
 
+	if request.includes(protocol.InitialStateDevices) {
+		if err := ws.collectInitialStateDevices(connID, request, protoDevices); err != nil {
+			return err
+		}
+	}
+	if request.includes(protocol.InitialStateAliases) {
+		ws.collectInitialStateAliases(connID, aliases)
+	}
+	if request.includes(protocol.InitialStateGroups) {
+		ws.collectInitialStateGroups(connID, groups)
+	}
+
+	var locationSettings *protocol.LocationSettingsData
+	if request.includes(protocol.InitialStateLocations) {
+		locationSettings = ws.initialStateLocationSettings()
+	}
+	var metadata map[handler.IDString]handler.DeviceMetadata
+	if request.includes(protocol.InitialStateMetadata) {
+		metadata = ws.readableMetadata(connID)
+	}
+
+	// Create initial state payload
+	payload := protocol.InitialStatePayload{
+		Devices:           protoDevices,
+		Aliases:           aliases,
+		Groups:            groups,
+		LocationSettings:  locationSettings,
+		Metadata:          metadata,
+		ServerStartupTime: protocol.ServerTime(ws.serverStartupTime),
+		ServerTimezone:    protocol.CurrentTimezone(time.Now()),
+	}
+	if request.isPartial() {
+		payload.Included = request.parts
+	}
+
+	if ws.handler.IsDebug() {
+		slog.Debug("Sending initial state message", "connID", connID, "totalDevices", len(protoDevices), "totalAliases", len(aliases), "totalGroups", len(groups))
+	}
+
+	// Send the message
+	if request.compact {
+		return ws.sendMessageToClient(connID, protocol.MessageTypeInitialState, protocol.ToCompactInitialState(payload), "")
+	}
+	return ws.sendMessageToClient(connID, protocol.MessageTypeInitialState, payload, "")
+}
+
+// collectInitialStateDevices adds the devices of the initial state that the client may read and asked for
+func (ws *WebSocketServer) collectInitialStateDevices(connID string, request initialStateRequest, protoDevices map[string]protocol.Device) error {
 	// Get all devices with timeout-aware fetching
 	if ws.handler.IsDebug() {
 		slog.Debug("Fetching device list", "connID", connID)
@@ -1056,8 +1389,9 @@ func (ws *WebSocketServer) generateAndSendInitialState(connID string) error {
 		slog.Debug("Device list processing completed", "connID", connID, "deviceCount", len(devices))
 	}
 
-	// Convert devices to protocol format
-	protoDevices := make(map[string]protocol.Device)
+	// Convert devices to protocol format, leaving out the devices the client may not read
+	rule := ws.ruleForConnection(connID)
+	favorites := ws.favoriteDevices(connID, "")
 	for i, device := range devices {
 		if ws.handler.IsDebug() && i < 5 { // Log first 5 devices to avoid spam
 			slog.Debug("Processing device", "connID", connID, "device", device.Device.Specifier(), "index", i)
@@ -1068,6 +1402,9 @@ func (ws *WebSocketServer) generateAndSendInitialState(connID string) error {
 			slog.Warn("Skipping device with nil IP", "connID", connID, "device", device.Device.Specifier())
 			continue
 		}
+		if !rule.CanRead(ws.accessResolver(), device.Device) || !request.includesDevice(ws.accessResolver(), device.Device) {
+			continue
+		}
 
 		// デバイスの最終更新タイムスタンプを取得
 		lastSeen := ws.handler.GetLastUpdateTime(device.Device)
@@ -1084,6 +1421,7 @@ func (ws *WebSocketServer) generateAndSendInitialState(connID string) error {
 			lastSeen,
 			isOffline,
 		)
+		protoDevice.Favorite = protoDevice.ID != "" && favorites[protoDevice.ID]
 
 		// Add to map with device identifier as key
 		protoDevices[device.Device.Specifier()] = protoDevice
@@ -1092,12 +1430,34 @@ func (ws *WebSocketServer) generateAndSendInitialState(connID string) error {
 	if ws.handler.IsDebug() {
 		slog.Debug("Device conversion completed", "connID", connID, "protoDeviceCount", len(protoDevices))
 	}
+	return nil
+}
+
+// initialStateLocationSettings returns the location settings of the initial state, or nil if none are set
+func (ws *WebSocketServer) initialStateLocationSettings() *protocol.LocationSettingsData {
+	locAliases, locOrder := ws.handler.GetLocationSettings()
+	if locAliases == nil && locOrder == nil {
+		return nil
+	}
+	locationSettings := &protocol.LocationSettingsData{
+		Aliases: locAliases,
+		Order:   locOrder,
+	}
+	if locationSettings.Aliases == nil {
+		locationSettings.Aliases = make(map[string]string)
+	}
+	if locationSettings.Order == nil {
+		locationSettings.Order = []string{}
+	}
+	return locationSettings
+}
 
+// collectInitialStateAliases adds all aliases to the initial state
+func (ws *WebSocketServer) collectInitialStateAliases(connID string, aliases map[string]client.IDString) {
 	// Get all aliases with timeout
 	if ws.handler.IsDebug() {
 		slog.Debug("Fetching alias list", "connID", connID)
 	}
-	aliases := make(map[string]client.IDString)
 	if ws.echonetClient != nil {
 		aliasCh := make(chan []client.AliasIDStringPair, 1)
 		go func() {
@@ -1127,12 +1487,14 @@ func (ws *WebSocketServer) generateAndSendInitialState(connID string) error {
 	if ws.handler.IsDebug() {
 		slog.Debug("Alias list processing completed", "connID", connID, "aliasCount", len(aliases))
 	}
+}
 
+// collectInitialStateGroups adds all groups to the initial state
+func (ws *WebSocketServer) collectInitialStateGroups(connID string, groups map[string][]client.IDString) {
 	// Get all groups with timeout
 	if ws.handler.IsDebug() {
 		slog.Debug("Fetching group list", "connID", connID)
 	}
-	groups := make(map[string][]client.IDString)
 	if ws.echonetClient != nil {
 		groupCh := make(chan []client.GroupDevicePair, 1)
 		go func() {
@@ -1162,37 +1524,6 @@ func (ws *WebSocketServer) generateAndSendInitialState(connID string) error {
 	if ws.handler.IsDebug() {
 		slog.Debug("Group list processing completed", "connID", connID, "groupCount", len(groups))
 	}
-
-	// Get location settings
-	var locationSettings *protocol.LocationSettingsData
-	if locAliases, locOrder := ws.handler.GetLocationSettings(); locAliases != nil || locOrder != nil {
-		locationSettings = &protocol.LocationSettingsData{
-			Aliases: locAliases,
-			Order:   locOrder,
-		}
-		if locationSettings.Aliases == nil {
-			locationSettings.Aliases = make(map[string]string)
-		}
-		if locationSettings.Order == nil {
-			locationSettings.Order = []string{}
-		}
-	}
-
-	// Create initial state payload
-	payload := protocol.InitialStatePayload{
-		Devices:           protoDevices,
-		Aliases:           aliases,
-		Groups:            groups,
-		LocationSettings:  locationSettings,
-		ServerStartupTime: ws.serverStartupTime,
-	}
-
-	if ws.handler.IsDebug() {
-		slog.Debug("Sending initial state message", "connID", connID, "totalDevices", len(protoDevices), "totalAliases", len(aliases), "totalGroups", len(groups))
-	}
-
-	// Send the message
-	return ws.sendMessageToClient(connID, protocol.MessageTypeInitialState, payload, "")
 }
 
 // SuccessResponse はコマンドの成功応答を作成する
@@ -1237,6 +1568,7 @@ func (ws *WebSocketServer) broadcastMessageToClients(msgType protocol.MessageTyp
 	}
 
 	// Send the message to all clients
+	ws.events.publish(msgType, data, nil, nil)
 	return ws.transport.BroadcastMessage(data)
 }
 
@@ -1276,7 +1608,7 @@ func (ws *WebSocketServer) listenForNotifications() {
 				}
 
 				// Broadcast the message
-				if err := ws.broadcastMessageToClients(protocol.MessageTypeDeviceAdded, payload); err != nil {
+				if err := ws.broadcastDeviceMessageToClients(device, protocol.MessageTypeDeviceAdded, payload); err != nil {
 					if !isClientDisconnectedError(err) {
 						slog.Error("Failed to broadcast device added message", "error", err, "device", notification.Device.Specifier())
 					}
@@ -1309,6 +1641,9 @@ func (ws *WebSocketServer) listenForNotifications() {
 					slog.Info("Device deleted message broadcasted", "device", notification.Device.Specifier())
 				}
 
+			case handler.DeviceReconfigured:
+				ws.broadcastDeviceReconfigured(notification.Device, notification.Reconfiguration)
+
 			case handler.DeviceTimeout:
 				slog.Error("Device timeout", "device", notification.Device.Specifier(), "error", notification.Error)
 
@@ -1322,7 +1657,7 @@ func (ws *WebSocketServer) listenForNotifications() {
 				}
 
 				// Broadcast the message
-				_ = ws.broadcastMessageToClients(protocol.MessageTypeTimeoutNotification, payload)
+				_ = ws.broadcastDeviceMessageToClients(device, protocol.MessageTypeTimeoutNotification, payload)
 
 			case handler.DeviceOffline:
 				// Record offline event in history
@@ -1336,7 +1671,7 @@ func (ws *WebSocketServer) listenForNotifications() {
 				}
 
 				// Broadcast the message
-				if err := ws.broadcastMessageToClients(protocol.MessageTypeDeviceOffline, payload); err != nil {
+				if err := ws.broadcastDeviceMessageToClients(device, protocol.MessageTypeDeviceOffline, payload); err != nil {
 					if !isClientDisconnectedError(err) {
 						slog.Error("Failed to broadcast device offline message", "error", err, "device", notification.Device.Specifier())
 					}
@@ -1355,7 +1690,7 @@ func (ws *WebSocketServer) listenForNotifications() {
 				}
 
 				// Broadcast the message
-				if err := ws.broadcastMessageToClients(protocol.MessageTypeDeviceOnline, payload); err != nil {
+				if err := ws.broadcastDeviceMessageToClients(device, protocol.MessageTypeDeviceOnline, payload); err != nil {
 					if !isClientDisconnectedError(err) {
 						slog.Error("Failed to broadcast device online message", "error", err, "device", notification.Device.Specifier())
 					}
@@ -1368,25 +1703,122 @@ func (ws *WebSocketServer) listenForNotifications() {
 				slog.Debug("Property changed", "device", propertyChange.Device.Specifier(), "epc", fmt.Sprintf("%02X", byte(propertyChange.Property.EPC)))
 			}
 
+			// アラートは変化の最小幅で間引く前の値で判定する
+			ws.evaluateAlerts(propertyChange.Device, propertyChange.Property)
+
+			// 閾値に満たない数値の変化は、履歴にも記録せず通知もしない
+			if ws.changeFilter != nil && !ws.changeFilter.Allow(propertyChange.Device, propertyChange.Property) {
+				continue
+			}
+
 			ws.recordPropertyChange(propertyChange)
+			ws.exportPropertyChange(propertyChange.Device, propertyChange.Property)
 
-			// プロパティ変化通知ペイロードを作成
-			payload := protocol.PropertyChangedPayload{
-				IP:    propertyChange.Device.IP.String(),
-				EOJ:   propertyChange.Device.EOJ.Specifier(),
-				EPC:   fmt.Sprintf("%02X", byte(propertyChange.Property.EPC)),
-				Value: protocol.MakePropertyData(propertyChange.Device.EOJ.ClassCode(), propertyChange.Property),
+			if ws.propertyCoalescer != nil {
+				ws.propertyCoalescer.Add(propertyChange.Device, propertyChange.Property)
+			} else {
+				// 送信はクライアントごとのキューで行われるため、通知順を保つようにここで直接ブロードキャストする
+				ws.broadcastPropertyChanges(propertyChange.Device, echonet_lite.Properties{propertyChange.Property})
 			}
+		case reload := <-ws.handler.FileReloadCh:
+			// 外部で編集されたエイリアス・グループファイルの変更を通知する
+			slog.Info("Reloaded externally modified file", "file", reload.File, "aliases", len(reload.Aliases), "groups", len(reload.Groups))
+			ws.broadcastFileReload(reload)
+		case status := <-ws.handler.PersistenceCh:
+			ws.broadcastPersistenceStatus(status)
+		}
+	}
+}
 
-			// メッセージを非同期でブロードキャスト
-			go func() {
-				if err := ws.broadcastMessageToClients(protocol.MessageTypePropertyChanged, payload); err != nil {
-					if !isClientDisconnectedError(err) {
-						slog.Error("Failed to broadcast property change", "error", err, "device", propertyChange.Device.Specifier())
-					}
-					// No logging for client disconnection errors
-				}
-			}()
+// broadcastFileReload broadcasts the alias and group changes of a reloaded file
+// as the same alias_changed and group_changed messages that in-app changes produce.
+func (ws *WebSocketServer) broadcastFileReload(reload handler.ReloadNotification) {
+	for _, change := range reload.Aliases {
+		payload := protocol.AliasChangedPayload{
+			ChangeType: protocol.AliasChangeType(change.Type),
+			Alias:      change.Alias,
+			Target:     change.ID,
+		}
+		if change.Type == handler.FileChangeDeleted {
+			payload.Target = ""
+		}
+		_ = ws.broadcastMessageToClients(protocol.MessageTypeAliasChanged, payload)
+	}
+	for _, change := range reload.Groups {
+		_ = ws.broadcastMessageToClients(protocol.MessageTypeGroupChanged, protocol.GroupChangedPayload{
+			ChangeType: protocol.GroupChangeType(change.Type),
+			Group:      change.Group,
+			Devices:    change.Devices,
+		})
+	}
+}
+
+// broadcastDeviceReconfigured tells the clients that a renumbered instance took over a vanished one,
+// followed by the alias_changed and group_changed messages of the moved aliases and groups.
+func (ws *WebSocketServer) broadcastDeviceReconfigured(device handler.IPAndEOJ, reconfiguration *handler.DeviceReconfiguration) {
+	if reconfiguration == nil {
+		return
+	}
+	slog.Info("Device reconfigured", "previous", reconfiguration.Previous.Specifier(), "device", device.Specifier())
+
+	payload := protocol.DeviceReconfiguredPayload{
+		IP:          device.IP.String(),
+		PreviousEOJ: reconfiguration.Previous.EOJ.Specifier(),
+		EOJ:         device.EOJ.Specifier(),
+		PreviousID:  reconfiguration.PreviousID,
+		ID:          reconfiguration.ID,
+	}
+	for _, change := range reconfiguration.Aliases {
+		payload.Aliases = append(payload.Aliases, change.Alias)
+	}
+	for _, change := range reconfiguration.Groups {
+		payload.Groups = append(payload.Groups, change.Group)
+	}
+	if err := ws.broadcastDeviceMessageToClients(device, protocol.MessageTypeDeviceReconfigured, payload); err != nil && !isClientDisconnectedError(err) {
+		slog.Error("Failed to broadcast device reconfigured message", "error", err, "device", device.Specifier())
+	}
+	ws.broadcastFileReload(handler.ReloadNotification{Aliases: reconfiguration.Aliases, Groups: reconfiguration.Groups})
+}
+
+// broadcastPersistenceStatus tells the clients that saving the device information started failing,
+// once per failure episode, so that data loss on a full disk does not go unnoticed.
+// Recovery is not broadcast; get_summary reports the current state.
+func (ws *WebSocketServer) broadcastPersistenceStatus(status handler.PersistenceStatus) {
+	if status.Healthy {
+		return
+	}
+	_ = ws.broadcastMessageToClients(protocol.MessageTypeErrorNotification, protocol.ErrorNotificationPayload{
+		Code:    protocol.ErrorCodePersistenceFailed,
+		Message: fmt.Sprintf("Failed to save device information (retrying): %s", status.LastError),
+	})
+}
+
+// broadcastPropertyChanges broadcasts property changes of one device.
+// A single change is sent as property_changed and several changes as one properties_changed message.
+func (ws *WebSocketServer) broadcastPropertyChanges(device handler.IPAndEOJ, properties echonet_lite.Properties) {
+	classCode := device.EOJ.ClassCode()
+
+	var err error
+	if len(properties) == 1 {
+		err = ws.broadcastDeviceMessageToClients(device, protocol.MessageTypePropertyChanged, protocol.PropertyChangedPayload{
+			IP:    device.IP.String(),
+			EOJ:   device.EOJ.Specifier(),
+			EPC:   fmt.Sprintf("%02X", byte(properties[0].EPC)),
+			Value: protocol.MakePropertyData(classCode, properties[0]),
+		})
+	} else {
+		props := make(protocol.PropertyMap)
+		for _, prop := range properties {
+			props.Set(prop.EPC, protocol.MakePropertyData(classCode, prop))
 		}
+		err = ws.broadcastDeviceMessageToClients(device, protocol.MessageTypePropertiesChanged, protocol.PropertiesChangedPayload{
+			IP:         device.IP.String(),
+			EOJ:        device.EOJ.Specifier(),
+			Properties: props,
+		})
+	}
+	if err != nil && !isClientDisconnectedError(err) {
+		// No logging for client disconnection errors
+		slog.Error("Failed to broadcast property change", "error", err, "device", device.Specifier())
 	}
 }
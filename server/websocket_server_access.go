@@ -0,0 +1,378 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// noAccess is the rule of a connection whose principal is unknown; it allows nothing
+var noAccess = &AccessRule{}
+
+// setupAccessControl authenticates new connections with the given access control
+func (ws *WebSocketServer) setupAccessControl(access *AccessControl) {
+	ws.access = access
+	if transport, ok := ws.transport.(*DefaultWebSocketTransport); ok {
+		transport.SetAuthenticator(func(r *http.Request) (string, bool) {
+			rule, ok := access.Authenticate(r)
+			if !ok {
+				return "", false
+			}
+			return rule.Name, true
+		})
+	}
+}
+
+// registerClientRule remembers the access rule of a new connection
+func (ws *WebSocketServer) registerClientRule(connID string) {
+	if ws.access == nil {
+		return
+	}
+	rule := noAccess
+	if transport, ok := ws.transport.(*DefaultWebSocketTransport); ok {
+		if name, ok := transport.Principal(connID); ok {
+			if r := ws.access.Rule(name); r != nil {
+				rule = r
+			}
+		}
+	}
+	ws.clientRules.Store(connID, rule)
+}
+
+// ruleForConnection returns the access rule of a connection (nil when access control is disabled)
+func (ws *WebSocketServer) ruleForConnection(connID string) *AccessRule {
+	if ws.access == nil {
+		return nil
+	}
+	if rule, ok := ws.clientRules.Load(connID); ok {
+		return rule.(*AccessRule)
+	}
+	return noAccess
+}
+
+// accessResolver returns the resolver used to match device patterns
+func (ws *WebSocketServer) accessResolver() accessResolver {
+	if ws.echonetClient == nil {
+		return nil
+	}
+	return ws.echonetClient
+}
+
+// permissionDenied creates the response to a request the access rule does not allow.
+// The caller records the denial with audit.
+func permissionDenied(format string, args ...any) (protocol.CommandResultPayload, bool) {
+	return ErrorResponse(protocol.ErrorCodePermissionDenied, format, args...), false
+}
+
+// applyAccessRule checks a request against the access rule of the client.
+// It returns false together with the response to send when the request must not reach its handler.
+// list_devices requests without targets are narrowed to the devices the client may read.
+// Payloads that cannot be parsed are passed through so that the handler reports the error.
+func (ws *WebSocketServer) applyAccessRule(rule *AccessRule, msg *protocol.Message) (protocol.CommandResultPayload, bool) {
+	if rule.IsAdmin() {
+		return protocol.CommandResultPayload{}, true
+	}
+	resolver := ws.accessResolver()
+
+	// checkTargets denies the request if one of the targets is not allowed or cannot be resolved.
+	// An empty target means every device, which the caller allows only for clients that can read all devices.
+	checkTargets := func(targets []string, allowed func(accessResolver, handler.IPAndEOJ) bool, what string) (protocol.CommandResultPayload, bool) {
+		for _, target := range targets {
+			if target == "" && rule.CanReadAll() {
+				continue
+			}
+			devices, ok := ws.resolveAccessTarget(target)
+			if !ok {
+				return permissionDenied("No permission to %s unknown device: %s", what, target)
+			}
+			for _, device := range devices {
+				if !allowed(resolver, device) {
+					return permissionDenied("No permission to %s device: %s", what, target)
+				}
+			}
+		}
+		return protocol.CommandResultPayload{}, true
+	}
+
+	switch msg.Type {
+	case protocol.MessageTypeGetProperties:
+		var payload protocol.GetPropertiesPayload
+		if protocol.ParsePayload(msg, &payload) == nil {
+			return checkTargets(payload.Targets, rule.CanRead, "read")
+		}
+
+	case protocol.MessageTypeUpdateProperties:
+		var payload protocol.UpdatePropertiesPayload
+		if protocol.ParsePayload(msg, &payload) == nil {
+			if len(payload.Targets) == 0 && !rule.CanReadAll() {
+				return permissionDenied("No permission to update all devices")
+			}
+			return checkTargets(payload.Targets, rule.CanRead, "read")
+		}
+
+	case protocol.MessageTypeGetDeviceHistory:
+		var payload protocol.GetDeviceHistoryPayload
+		if protocol.ParsePayload(msg, &payload) == nil {
+			return checkTargets([]string{payload.Target}, rule.CanRead, "read")
+		}
+
+	case protocol.MessageTypeGetPropertyStatistics:
+		var payload protocol.GetPropertyStatisticsPayload
+		if protocol.ParsePayload(msg, &payload) == nil {
+			if payload.Target == "" && !rule.CanReadAll() {
+				return permissionDenied("No permission to read statistics of all devices")
+			}
+			return checkTargets([]string{payload.Target}, rule.CanRead, "read")
+		}
+
+	case protocol.MessageTypeReplayHistory:
+		var payload protocol.ReplayHistoryPayload
+		if protocol.ParsePayload(msg, &payload) == nil && !payload.Stop {
+			if payload.Target == "" && !rule.CanReadAll() {
+				return permissionDenied("No permission to replay all devices")
+			}
+			return checkTargets([]string{payload.Target}, rule.CanRead, "read")
+		}
+
+	case protocol.MessageTypeExportCSV:
+		var payload protocol.ExportCSVPayload
+		if protocol.ParsePayload(msg, &payload) == nil {
+			if payload.Target == "" && !rule.CanReadAll() {
+				return permissionDenied("No permission to export all devices")
+			}
+			return checkTargets([]string{payload.Target}, rule.CanRead, "read")
+		}
+
+	case protocol.MessageTypeGetVersions:
+		var payload protocol.GetVersionsPayload
+		if protocol.ParsePayload(msg, &payload) == nil {
+			if payload.Target == "" && !rule.CanReadAll() {
+				return permissionDenied("No permission to read the versions of all devices")
+			}
+			return checkTargets([]string{payload.Target}, rule.CanRead, "read")
+		}
+
+	case protocol.MessageTypeRefreshPropertyMap:
+		var payload protocol.RefreshPropertyMapPayload
+		if protocol.ParsePayload(msg, &payload) == nil {
+			return checkTargets([]string{payload.Target}, rule.CanRead, "read")
+		}
+
+	case protocol.MessageTypeSubscribeLogs:
+		// The log mentions every device
+		var payload protocol.SubscribeLogsPayload
+		if protocol.ParsePayload(msg, &payload) == nil && !payload.Unsubscribe && !rule.CanReadAll() {
+			return permissionDenied("No permission to read the server log")
+		}
+
+	case protocol.MessageTypeGetSummary:
+		if !rule.CanReadAll() {
+			return permissionDenied("No permission to read the summary of all devices")
+		}
+
+	case protocol.MessageTypeListDevices:
+		return ws.narrowListDevices(rule, msg)
+
+	case protocol.MessageTypeSetProperties:
+		var payload protocol.SetPropertiesPayload
+		if protocol.ParsePayload(msg, &payload) == nil {
+			return checkTargets([]string{payload.Target}, rule.CanControl, "control")
+		}
+
+	case protocol.MessageTypeSetGetProperties:
+		var payload protocol.SetGetPropertiesPayload
+		if protocol.ParsePayload(msg, &payload) == nil {
+			return checkTargets([]string{payload.Target}, rule.CanControl, "control")
+		}
+
+	case protocol.MessageTypeSetGroupProperties:
+		var payload protocol.SetGroupPropertiesPayload
+		if protocol.ParsePayload(msg, &payload) == nil && ws.echonetClient != nil {
+			devices, _, _ := ws.groupPropertiesTargets(payload)
+			for _, device := range devices {
+				if !rule.CanControl(resolver, device) {
+					return permissionDenied("No permission to control device: %s", device.Specifier())
+				}
+			}
+		}
+
+	case protocol.MessageTypeDeleteDevice:
+		var payload protocol.DeleteDevicePayload
+		if protocol.ParsePayload(msg, &payload) == nil {
+			return checkTargets([]string{payload.Target}, rule.CanControl, "control")
+		}
+
+	case protocol.MessageTypeRunScene:
+		var payload protocol.RunScenePayload
+		if protocol.ParsePayload(msg, &payload) == nil && ws.echonetClient != nil {
+			actions, _ := ws.echonetClient.GetScene(payload.Scene)
+			for _, action := range actions {
+				device := ws.echonetClient.FindDeviceByIDString(action.Device)
+				if device != nil && !rule.CanControl(resolver, *device) {
+					return permissionDenied("No permission to control device %s of scene %s", action.Device, payload.Scene)
+				}
+			}
+		}
+
+	case protocol.MessageTypeManageAlias, protocol.MessageTypeManageGroup,
+		protocol.MessageTypeManageScene, protocol.MessageTypeManageSchedule, protocol.MessageTypeManagePollExclusions:
+		// Listing is allowed; changing the definitions requires admin
+		var payload struct {
+			Action string `json:"action"`
+		}
+		if protocol.ParsePayload(msg, &payload) == nil && payload.Action == "list" {
+			return protocol.CommandResultPayload{}, true
+		}
+		return permissionDenied("No permission to %s", msg.Type)
+
+	case protocol.MessageTypeDiscoverDevices, protocol.MessageTypeDebugSetOffline, protocol.MessageTypeCleanupDevices,
+		protocol.MessageTypeManageLocationAlias, protocol.MessageTypeSetLocationOrder, protocol.MessageTypeManageMetadata,
+		protocol.MessageTypeGetMemoryUsage, protocol.MessageTypeDebugPendingRequests, protocol.MessageTypeManageLocalPropertyMaps:
+		return permissionDenied("No permission to %s", msg.Type)
+
+	case protocol.MessageTypeGetPropertyDescription, protocol.MessageTypeGetAliases, protocol.MessageTypeGetGroups,
+		protocol.MessageTypeGetLocationSettings, protocol.MessageTypeGetPreferences, protocol.MessageTypeSetPreferences,
+		protocol.MessageTypeGetOperationStatus, protocol.MessageTypeCancelOperation:
+		// Not about the state of a device; preferences and operations are kept per client
+		return protocol.CommandResultPayload{}, true
+
+	default:
+		// Message types not listed above require admin, so that a new request type is never open by mistake
+		return permissionDenied("No permission to %s", msg.Type)
+	}
+	return protocol.CommandResultPayload{}, true
+}
+
+// resolveAccessTarget resolves a request target to the devices it refers to, in the same forms
+// the device patterns accept: "IP EOJ", a device ID string, an alias or "@group".
+// It returns false when the target does not refer to a known device, so that the request is denied.
+func (ws *WebSocketServer) resolveAccessTarget(target string) ([]handler.IPAndEOJ, bool) {
+	if device, err := handler.ParseDeviceIdentifier(target); err == nil {
+		return []handler.IPAndEOJ{device}, true
+	}
+	if ws.echonetClient == nil {
+		return nil, false
+	}
+	if strings.HasPrefix(target, "@") {
+		ids, ok := ws.echonetClient.GetDevicesByGroup(target)
+		if !ok {
+			return nil, false
+		}
+		devices := make([]handler.IPAndEOJ, 0, len(ids))
+		for _, id := range ids {
+			if device := ws.echonetClient.FindDeviceByIDString(id); device != nil {
+				devices = append(devices, *device)
+			}
+		}
+		return devices, true
+	}
+	if device := ws.echonetClient.FindDeviceByIDString(handler.IDString(target)); device != nil {
+		return []handler.IPAndEOJ{*device}, true
+	}
+	if device, ok := ws.echonetClient.GetDeviceByAlias(target); ok {
+		return []handler.IPAndEOJ{device}, true
+	}
+	return nil, false
+}
+
+// narrowListDevices limits a list_devices request to the devices the client may read
+func (ws *WebSocketServer) narrowListDevices(rule *AccessRule, msg *protocol.Message) (protocol.CommandResultPayload, bool) {
+	if rule.CanReadAll() {
+		return protocol.CommandResultPayload{}, true
+	}
+	var payload protocol.ListDevicesPayload
+	if protocol.ParsePayload(msg, &payload) != nil {
+		return protocol.CommandResultPayload{}, true
+	}
+	resolver := ws.accessResolver()
+
+	if len(payload.Targets) > 0 {
+		for _, target := range payload.Targets {
+			devices, ok := ws.resolveAccessTarget(target)
+			if !ok {
+				return permissionDenied("No permission to read unknown device: %s", target)
+			}
+			for _, device := range devices {
+				if !rule.CanRead(resolver, device) {
+					return permissionDenied("No permission to read device: %s", target)
+				}
+			}
+		}
+		return protocol.CommandResultPayload{}, true
+	}
+
+	// No targets means all online devices: replace them with the readable ones
+	if ws.echonetClient != nil {
+		for _, device := range ws.echonetClient.ListDevices(handler.FilterCriteria{ExcludeOffline: true}) {
+			if rule.CanRead(resolver, device.Device) {
+				payload.Targets = append(payload.Targets, device.Device.Specifier())
+			}
+		}
+	}
+	if len(payload.Targets) == 0 {
+		return SuccessResponse(json.RawMessage("[]")), false
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling list_devices payload: %v", err), false
+	}
+	msg.Payload = data
+	return protocol.CommandResultPayload{}, true
+}
+
+// broadcastDeviceMessageToClients sends a message about a device to the clients allowed to read the device
+func (ws *WebSocketServer) broadcastDeviceMessageToClients(device handler.IPAndEOJ, msgType protocol.MessageType, payload interface{}) error {
+	if ws.access == nil {
+		return ws.broadcastMessageToClients(msgType, payload)
+	}
+
+	data, err := protocol.CreateMessage(msgType, payload, "")
+	if err != nil {
+		slog.Error("Error creating broadcast message", "err", err)
+		return err
+	}
+
+	resolver := ws.accessResolver()
+	ws.events.publish(msgType, data, &device, resolver)
+	ws.clientRules.Range(func(key, value any) bool {
+		connID := key.(string)
+		if value.(*AccessRule).CanRead(resolver, device) {
+			if err := ws.transport.SendMessage(connID, data); err != nil && !isClientDisconnectedError(err) {
+				slog.Warn("Failed to send device message", "connID", connID, "type", msgType, "err", err, NoBroadcast())
+			}
+		}
+		return true
+	})
+	return nil
+}
+
+// LogTransport returns the transport to give to LogManager.SetTransport.
+// When access control is enabled, the broadcast log notifications reach only the clients allowed to read every device,
+// because a log record may mention any device.
+func (ws *WebSocketServer) LogTransport() WebSocketTransport {
+	return &logTransport{WebSocketTransport: ws.transport, ws: ws}
+}
+
+// logTransport is a WebSocketTransport whose broadcasts are limited by the access rules of the clients
+type logTransport struct {
+	WebSocketTransport
+	ws *WebSocketServer
+}
+
+// BroadcastMessage sends the message to the clients whose access rule allows reading every device
+func (t *logTransport) BroadcastMessage(message []byte) error {
+	if t.ws.access == nil {
+		return t.WebSocketTransport.BroadcastMessage(message)
+	}
+	t.ws.clientRules.Range(func(key, value any) bool {
+		if value.(*AccessRule).CanReadAll() {
+			_ = t.WebSocketTransport.SendMessage(key.(string), message)
+		}
+		return true
+	})
+	return nil
+}
@@ -56,10 +56,14 @@ func (m *MockECHONETClientWithForceTracking) GetProperties(device client.IPAndEO
 	return client.DeviceAndProperties{}, nil
 }
 
-func (m *MockECHONETClientWithForceTracking) SetProperties(device client.IPAndEOJ, properties client.Properties) (client.DeviceAndProperties, error) {
+func (m *MockECHONETClientWithForceTracking) SetProperties(device client.IPAndEOJ, properties client.Properties, _ []client.EPCType) (client.DeviceAndProperties, error) {
 	return client.DeviceAndProperties{Device: device, Properties: properties}, nil
 }
 
+func (m *MockECHONETClientWithForceTracking) SetGetProperties(device client.IPAndEOJ, properties client.Properties, _ []client.EPCType) (client.SetGetResult, error) {
+	return client.SetGetResult{Device: device, Set: properties}, nil
+}
+
 func (m *MockECHONETClientWithForceTracking) GetDeviceHistory(device client.IPAndEOJ, opts client.DeviceHistoryOptions) ([]client.DeviceHistoryEntry, error) {
 	return []client.DeviceHistoryEntry{}, nil
 }
@@ -124,6 +128,14 @@ func (m *MockECHONETClientWithForceTracking) SetDebug(debug bool) {
 	// No-op for mock
 }
 
+func (m *MockECHONETClientWithForceTracking) DebugPendingRequests() ([]client.PendingRequest, error) {
+	return nil, nil
+}
+
+func (m *MockECHONETClientWithForceTracking) DebugCancelRequest(id uint64) error {
+	return nil
+}
+
 func (m *MockECHONETClientWithForceTracking) IsOfflineDevice(device client.IPAndEOJ) bool {
 	return false
 }
@@ -145,6 +157,14 @@ func (m *MockECHONETClientWithForceTracking) GetIDString(device client.IPAndEOJ)
 	return ""
 }
 
+func (m *MockECHONETClientWithForceTracking) CleanupDevices(unseenDays int, dryRun bool) ([]client.StaleDevice, error) {
+	return nil, nil
+}
+
+func (m *MockECHONETClientWithForceTracking) RefreshPropertyMap(device client.IPAndEOJ) (client.PropertyMapChange, error) {
+	return client.PropertyMapChange{}, nil
+}
+
 // PropertyDescProvider interface methods
 func (m *MockECHONETClientWithForceTracking) GetAllPropertyAliases() map[string]client.PropertyDescription {
 	return map[string]client.PropertyDescription{}
@@ -183,11 +203,82 @@ func (m *MockECHONETClientWithForceTracking) GetDevicesByGroup(groupName string)
 	return []client.IDString{}, false
 }
 
+// SceneManager interface methods
+func (m *MockECHONETClientWithForceTracking) SceneList(sceneName *string) []client.SceneActionsPair {
+	return []client.SceneActionsPair{}
+}
+
+func (m *MockECHONETClientWithForceTracking) SceneAdd(sceneName string, actions []client.SceneAction) error {
+	return nil
+}
+
+func (m *MockECHONETClientWithForceTracking) SceneDelete(sceneName string) error {
+	return nil
+}
+
+func (m *MockECHONETClientWithForceTracking) GetScene(sceneName string) ([]client.SceneAction, bool) {
+	return nil, false
+}
+
+func (m *MockECHONETClientWithForceTracking) SceneSetSequence(sceneName string, sequence client.SceneSequence) error {
+	return nil
+}
+
+func (m *MockECHONETClientWithForceTracking) GetSceneSequence(sceneName string) client.SceneSequence {
+	return client.SceneSequence{}
+}
+
+func (m *MockECHONETClientWithForceTracking) SceneRun(sceneName string) ([]client.SceneRunResult, error) {
+	return nil, nil
+}
+
+// ScheduleManager methods
+func (m *MockECHONETClientWithForceTracking) ScheduleList() []client.Schedule {
+	return nil
+}
+
+func (m *MockECHONETClientWithForceTracking) ScheduleSet(schedule client.Schedule) error {
+	return nil
+}
+
+func (m *MockECHONETClientWithForceTracking) ScheduleDelete(name string) error {
+	return nil
+}
+
+func (m *MockECHONETClientWithForceTracking) ScheduleSetDisabled(name string, disabled bool) error {
+	return nil
+}
+
+func (m *MockECHONETClientWithForceTracking) LocalPropertyMaps() ([]client.LocalPropertyMap, error) {
+	return nil, nil
+}
+
+func (m *MockECHONETClientWithForceTracking) SetLocalPropertyMap(eoj client.EOJ, mapType client.PropertyMapType, epcs []client.EPCType) error {
+	return nil
+}
+
+func (m *MockECHONETClientWithForceTracking) PollExclusions() ([]string, error) {
+	return nil, nil
+}
+
+func (m *MockECHONETClientWithForceTracking) SetPollExcluded(target string, excluded bool) error {
+	return nil
+}
+
+func (m *MockECHONETClientWithForceTracking) ScheduleNextRun(schedule client.Schedule, after time.Time) time.Time {
+	return time.Time{}
+}
+
 // Close method for main interface
 func (m *MockECHONETClientWithForceTracking) Close() error {
 	return nil
 }
 
+func (m *MockECHONETClientWithForceTracking) WatchPropertyChanges() (<-chan client.PropertyChangeNotification, func()) {
+	ch := make(chan client.PropertyChangeNotification)
+	return ch, func() {}
+}
+
 // LocationSettingsManager interface methods
 func (m *MockECHONETClientWithForceTracking) GetLocationSettings() (map[string]string, []string) {
 	return map[string]string{}, []string{}
@@ -337,6 +428,43 @@ func TestShouldPerformForcedUpdate_RegularInterval(t *testing.T) {
 	}
 }
 
+// TestShouldPerformForcedUpdate_Schedule tests forced updates at wall-clock times
+func TestShouldPerformForcedUpdate_Schedule(t *testing.T) {
+	ws, _, err := createTestServerWithTiming(1*time.Minute, 30*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+	schedule, err := handler.ParseCronSpec("30 3 * * *")
+	if err != nil {
+		t.Fatalf("ParseCronSpec returned error: %v", err)
+	}
+	ws.forcedUpdateSchedule = &schedule
+	ws.serverStartupTime = time.Date(2026, 10, 16, 22, 0, 0, 0, time.Local)
+
+	// The schedule replaces the interval: no forced update 30 minutes after startup
+	if ws.shouldPerformForcedUpdate(ws.serverStartupTime.Add(30 * time.Minute)) {
+		t.Error("expected no forced update before the scheduled time")
+	}
+	scheduled := time.Date(2026, 10, 17, 3, 30, 0, 0, time.Local)
+	if ws.shouldPerformForcedUpdate(scheduled.Add(-time.Second)) {
+		t.Error("expected no forced update just before the scheduled time")
+	}
+	// The first tick after the scheduled time forces the update
+	firstTick := scheduled.Add(40 * time.Second)
+	if !ws.shouldPerformForcedUpdate(firstTick) {
+		t.Error("expected a forced update after the scheduled time")
+	}
+	ws.lastForcedUpdateTime.Store(firstTick.UnixNano())
+
+	// Only once per scheduled time
+	if ws.shouldPerformForcedUpdate(firstTick.Add(time.Minute)) {
+		t.Error("expected no second forced update on the same day")
+	}
+	if !ws.shouldPerformForcedUpdate(scheduled.AddDate(0, 0, 1)) {
+		t.Error("expected a forced update on the next day")
+	}
+}
+
 // TestShouldPerformForcedUpdate_Disabled tests behavior when forced updates are disabled
 func TestShouldPerformForcedUpdate_Disabled(t *testing.T) {
 	ws, _, err := createTestServerWithTiming(1*time.Minute, 0) // disabled
@@ -7,12 +7,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"sort"
 	"strings"
 	"time"
 )
 
-// handleListDevicesFromClient handles a list_devices message from a client
-func (ws *WebSocketServer) handleListDevicesFromClient(msg *protocol.Message) protocol.CommandResultPayload {
+// handleListDevicesFromClient handles a list_devices message from a client.
+// Favorite devices are marked and listed first, keeping the order of the other devices.
+func (ws *WebSocketServer) handleListDevicesFromClient(connID string, msg *protocol.Message) protocol.CommandResultPayload {
 	// 操作追跡を開始
 	operationID := "list_devices_" + time.Now().Format("20060102_150405.000")
 
@@ -22,6 +24,28 @@ func (ws *WebSocketServer) handleListDevicesFromClient(msg *protocol.Message) pr
 		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing list_devices payload: %v", err)
 	}
 
+	switch payload.Mode {
+	case "", protocol.ListDevicesModeFlat, protocol.ListDevicesModeNodes:
+	default:
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid mode: %s", payload.Mode)
+	}
+
+	var availabilityWindow time.Duration
+	if payload.AvailabilityWindow != "" {
+		window, err := protocol.ParseAvailabilityWindow(payload.AvailabilityWindow)
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "%v", err)
+		}
+		availabilityWindow = window
+	}
+
+	if payload.PreferencesKey != "" {
+		if err := handler.ValidatePreferencesKey(payload.PreferencesKey); err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid preferencesKey: %v", err)
+		}
+	}
+	favorites := ws.favoriteDevices(connID, payload.PreferencesKey)
+
 	// ECHONETクライアントからOperationTrackerを取得
 	if tracker := ws.getOperationTracker(); tracker != nil {
 		tracker.StartOperation(operationID, handler.OperationTypeGetProperties,
@@ -86,12 +110,30 @@ func (ws *WebSocketServer) handleListDevicesFromClient(msg *protocol.Message) pr
 			lastSeen,
 			isOffline,
 		)
+		if availabilityWindow > 0 && ws.handler != nil {
+			percent := protocol.AvailabilityPercent(ws.handler.GetDeviceAvailability(device.Device, availabilityWindow))
+			protoDevice.Availability = &percent
+		}
+		protoDevice.Favorite = protoDevice.ID != "" && favorites[protoDevice.ID]
+		if payload.FavoritesOnly && !protoDevice.Favorite {
+			continue
+		}
 		results = append(results, protoDevice)
 	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Favorite && !results[j].Favorite
+	})
 
 	// Marshal the results
 	var resultJSON json.RawMessage
-	if len(results) == 1 {
+	if payload.Mode == protocol.ListDevicesModeNodes {
+		// Node mode - always return an array of nodes, regardless of the device count
+		nodesJSON, err := json.Marshal(protocol.GroupDevicesByNode(results))
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling nodes: %v", err)
+		}
+		resultJSON = nodesJSON
+	} else if len(results) == 1 {
 		// Single device - return the device directly (same format as get_properties)
 		deviceJSON, err := json.Marshal(results[0])
 		if err != nil {
@@ -191,6 +233,75 @@ func (ws *WebSocketServer) handleDeleteDeviceFromClient(msg *protocol.Message) p
 	return SuccessResponse(nil)
 }
 
+// handleCleanupDevicesFromClient handles a cleanup_devices message from a client.
+// Devices not updated for unseenDays days are removed; clients are notified by device_deleted as usual.
+func (ws *WebSocketServer) handleCleanupDevicesFromClient(msg *protocol.Message) protocol.CommandResultPayload {
+	var payload protocol.CleanupDevicesPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing cleanup_devices payload: %v", err)
+	}
+	if payload.UnseenDays <= 0 {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "unseenDays must be a positive number of days")
+	}
+	if ws.handler == nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Handler is not available")
+	}
+
+	devices, err := ws.handler.CleanupStaleDevices(time.Duration(payload.UnseenDays)*24*time.Hour, payload.DryRun)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Failed to clean up devices: %v", err)
+	}
+
+	data, err := json.Marshal(protocol.CleanupDevicesResponse{
+		Devices: protocol.StaleDevicesToProtocol(devices),
+		DryRun:  payload.DryRun,
+	})
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling cleanup result: %v", err)
+	}
+	return SuccessResponse(data)
+}
+
+// handleRefreshPropertyMapFromClient handles a refresh_property_map message from a client.
+// The property maps of the device are read again, then every property of the new Get property map;
+// the changed properties are notified by property_changed as usual.
+func (ws *WebSocketServer) handleRefreshPropertyMapFromClient(msg *protocol.Message) protocol.CommandResultPayload {
+	var payload protocol.RefreshPropertyMapPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing refresh_property_map payload: %v", err)
+	}
+
+	ipAndEOJ, err := handler.ParseDeviceIdentifier(payload.Target)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid target device identifier: %v", err)
+	}
+	if len(ws.echonetClient.ListDevices(handler.FilterCriteria{Device: handler.DeviceSpecifierFromIPAndEOJ(ipAndEOJ)})) == 0 {
+		return ErrorResponse(protocol.ErrorCodeTargetNotFound, "Device not found: %s", payload.Target)
+	}
+
+	change, err := ws.echonetClient.RefreshPropertyMap(ipAndEOJ)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeEchonetCommunicationError, "Failed to refresh property map: %v", err)
+	}
+
+	response := protocol.RefreshPropertyMapResponse{
+		Target:  payload.Target,
+		Added:   []string{},
+		Removed: []string{},
+	}
+	for _, epc := range change.Added {
+		response.Added = append(response.Added, fmt.Sprintf("%02X", byte(epc)))
+	}
+	for _, epc := range change.Removed {
+		response.Removed = append(response.Removed, fmt.Sprintf("%02X", byte(epc)))
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling refresh_property_map result: %v", err)
+	}
+	return SuccessResponse(data)
+}
+
 // handleDebugSetOfflineFromClient handles a debug_set_offline message from a client
 func (ws *WebSocketServer) handleDebugSetOfflineFromClient(msg *protocol.Message) protocol.CommandResultPayload {
 	// Parse the payload
@@ -7,8 +7,21 @@ import (
 	"time"
 )
 
-// handleDiscoverDevicesFromClient handles a discover_devices message from a client
-func (ws *WebSocketServer) handleDiscoverDevicesFromClient(_ *protocol.Message) protocol.CommandResultPayload {
+// handleDiscoverDevicesFromClient handles a discover_devices message from a client.
+// With async, the discovery runs in the background as an operation that cannot be cancelled.
+func (ws *WebSocketServer) handleDiscoverDevicesFromClient(connID string, msg *protocol.Message) protocol.CommandResultPayload {
+	var payload protocol.DiscoverDevicesPayload
+	if len(msg.Payload) > 0 {
+		if err := protocol.ParsePayload(msg, &payload); err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing discover_devices payload: %v", err)
+		}
+	}
+	if payload.Async {
+		return ws.startOperation(connID, protocol.MessageTypeDiscoverDevices, 0, false, func(_ *bulkOperation) error {
+			return ws.echonetClient.Discover()
+		})
+	}
+
 	// 操作追跡を開始
 	operationID := "discover_" + time.Now().Format("20060102_150405.000")
 
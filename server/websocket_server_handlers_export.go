@@ -0,0 +1,118 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// handleExportCSVFromClient handles an export_csv message from a client.
+// It returns the device/property table or the device history as a CSV file in the command result,
+// so that the values can be analyzed in a spreadsheet.
+// Property names are in the lang of the payload, or the language of the connection if it is omitted.
+func (ws *WebSocketServer) handleExportCSVFromClient(connID string, msg *protocol.Message) protocol.CommandResultPayload {
+	if ws.handler == nil || ws.echonetClient == nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Handler is not available")
+	}
+
+	var payload protocol.ExportCSVPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing export_csv payload: %v", err)
+	}
+
+	lang := ws.languageForConnection(connID, payload.Lang)
+
+	devices := ws.echonetClient.ListDevices(handler.FilterCriteria{})
+	if target := strings.TrimSpace(payload.Target); target != "" {
+		device, err := handler.ParseDeviceIdentifier(target)
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid target: %v", err)
+		}
+		devices = ws.echonetClient.ListDevices(handler.FilterCriteria{Device: handler.DeviceSpecifierFromIPAndEOJ(device)})
+		if len(devices) == 0 {
+			return ErrorResponse(protocol.ErrorCodeTargetNotFound, "Unknown device: %s", target)
+		}
+	}
+
+	var buf bytes.Buffer
+	switch payload.Kind {
+	case protocol.CSVExportDevices:
+		protoDevices := make([]protocol.Device, 0, len(devices))
+		aliases := make(map[string]string)
+		for _, device := range devices {
+			protoDevices = append(protoDevices, protocol.DeviceToProtocol(device.Device, device.Properties,
+				ws.handler.GetLastUpdateTime(device.Device), ws.handler.IsOffline(device.Device)))
+			if names := ws.echonetClient.GetAliases(device.Device); len(names) > 0 {
+				aliases[device.Device.Specifier()] = names[0]
+			}
+		}
+		if err := protocol.WriteDevicesCSV(&buf, protoDevices, aliases, lang); err != nil {
+			return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error writing CSV: %v", err)
+		}
+
+	case protocol.CSVExportHistory:
+		store := ws.GetHistoryStore()
+		if store == nil {
+			return ErrorResponse(protocol.ErrorCodeFeatureDisabled, "Device history is disabled on this server")
+		}
+		query := handler.HistoryQuery{}
+		if payload.Since != nil {
+			query.Since = *payload.Since
+		}
+		if payload.Until != nil {
+			query.Until = *payload.Until
+		}
+		if !query.Since.IsZero() && !query.Until.IsZero() && query.Until.Before(query.Since) {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "until must not be before since")
+		}
+
+		var entries []protocol.CSVHistoryEntry
+		for _, device := range devices {
+			csvDevice := protocol.CSVDevice{IP: device.Device.IP.String(), EOJ: device.Device.EOJ.Specifier()}
+			if names := ws.echonetClient.GetAliases(device.Device); len(names) > 0 {
+				csvDevice.Alias = names[0]
+			}
+			for _, entry := range store.Query(device.Device, query) {
+				epc := ""
+				if entry.EPC != 0 {
+					epc = entry.EPC.String()
+				}
+				entries = append(entries, protocol.CSVHistoryEntry{
+					Device: csvDevice,
+					Entry: protocol.HistoryEntry{
+						Timestamp: entry.Timestamp,
+						EPC:       epc,
+						Value:     protocol.PropertyDataFromHandlerValue(entry.Value),
+						Origin:    protocol.HistoryOrigin(entry.Origin),
+						Settable:  entry.Settable,
+					},
+				})
+			}
+		}
+		// Oldest first, as spreadsheets are usually read from the top
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Entry.Timestamp.Before(entries[j].Entry.Timestamp)
+		})
+		if err := protocol.WriteHistoryCSV(&buf, entries, lang); err != nil {
+			return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error writing CSV: %v", err)
+		}
+
+	default:
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Unknown kind: %q (devices or history)", payload.Kind)
+	}
+
+	data, err := json.Marshal(protocol.ExportCSVResult{
+		Filename:    protocol.CSVFilename(payload.Kind, time.Now()),
+		ContentType: protocol.CSVContentType,
+		CSV:         buf.String(),
+	})
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling CSV export: %v", err)
+	}
+	return SuccessResponse(data)
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+func TestHandleExportCSVFromClient(t *testing.T) {
+	ctx := context.Background()
+	liteHandler, err := handler.NewECHONETLiteHandler(ctx, handler.ECHONETLieHandlerOptions{
+		TestMode:       true,
+		InMemory:       true,
+		HistoryOptions: handler.HistoryOptions{PerDeviceSettableLimit: 10, PerDeviceNonSettableLimit: 10},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer liteHandler.Close()
+
+	aircon := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	c := &restTestClient{}
+	c.devices = []handler.DeviceAndProperties{
+		{Device: aircon, Properties: echonet_lite.Properties{{EPC: 0x80, EDT: []byte{0x30}}}},
+	}
+	ws := &WebSocketServer{ctx: ctx, handler: liteHandler, echonetClient: c}
+
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	store := ws.GetHistoryStore()
+	store.Record(handler.DeviceHistoryEntry{Timestamp: base.Add(time.Minute), Device: aircon, EPC: 0x80, Value: handler.PropertyValue{String: "off"}, Origin: handler.HistoryOriginNotification})
+	store.Record(handler.DeviceHistoryEntry{Timestamp: base, Device: aircon, EPC: 0x80, Value: handler.PropertyValue{String: "on"}, Origin: handler.HistoryOriginSet, Settable: true})
+
+	export := func(payload protocol.ExportCSVPayload) (protocol.CommandResultPayload, []string) {
+		t.Helper()
+		data, _ := json.Marshal(payload)
+		result := ws.handleExportCSVFromClient("", &protocol.Message{Type: protocol.MessageTypeExportCSV, Payload: data})
+		if !result.Success {
+			return result, nil
+		}
+		var exported protocol.ExportCSVResult
+		if err := json.Unmarshal(result.Data, &exported); err != nil {
+			t.Fatal(err)
+		}
+		if exported.ContentType != protocol.CSVContentType || !strings.HasSuffix(exported.Filename, ".csv") {
+			t.Errorf("unexpected result: %+v", exported)
+		}
+		return result, strings.Split(strings.TrimSpace(exported.CSV), "\n")
+	}
+
+	if _, lines := export(protocol.ExportCSVPayload{Kind: protocol.CSVExportDevices}); len(lines) != 2 {
+		t.Errorf("expected a header and one property row, got %q", lines)
+	}
+
+	// History is exported oldest first, including read-only changes
+	_, lines := export(protocol.ExportCSVPayload{Kind: protocol.CSVExportHistory, Target: "192.168.1.10 0130:1"})
+	if len(lines) != 3 || !strings.Contains(lines[1], ",on,") || !strings.Contains(lines[2], ",off,") {
+		t.Errorf("unexpected history rows: %q", lines)
+	}
+
+	until := base
+	if _, lines := export(protocol.ExportCSVPayload{Kind: protocol.CSVExportHistory, Until: &until}); len(lines) != 2 {
+		t.Errorf("expected one row until %v, got %q", until, lines)
+	}
+
+	since := base.Add(time.Hour)
+	for _, payload := range []protocol.ExportCSVPayload{
+		{Kind: "scenes"},
+		{Kind: protocol.CSVExportDevices, Target: "192.168.1.99 0130:1"},
+		{Kind: protocol.CSVExportHistory, Since: &since, Until: &until},
+	} {
+		if result, _ := export(payload); result.Success {
+			t.Errorf("expected an error for %+v", payload)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// groupPropertiesTargets resolves the devices of a set_group_properties payload, without duplicates.
+// Group members that are not known (e.g. offline since before the server started) are returned in missing.
+func (ws *WebSocketServer) groupPropertiesTargets(payload protocol.SetGroupPropertiesPayload) (devices []handler.IPAndEOJ, missing []handler.IDString, err error) {
+	seen := make(map[string]bool)
+	add := func(device handler.IPAndEOJ) {
+		if !seen[device.Key()] {
+			seen[device.Key()] = true
+			devices = append(devices, device)
+		}
+	}
+
+	if payload.Group != "" {
+		ids, ok := ws.echonetClient.GetDevicesByGroup(payload.Group)
+		if !ok {
+			return nil, nil, fmt.Errorf("group not found: %s", payload.Group)
+		}
+		for _, id := range ids {
+			if device := ws.echonetClient.FindDeviceByIDString(id); device != nil {
+				add(*device)
+			} else {
+				missing = append(missing, id)
+			}
+		}
+	}
+	for _, target := range payload.Targets {
+		device, err := handler.ParseDeviceIdentifier(target)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid target: %w", err)
+		}
+		add(device)
+	}
+	return devices, missing, nil
+}
+
+// setGroupPropertiesOnDevice sets the properties of a set_group_properties request on one device
+func (ws *WebSocketServer) setGroupPropertiesOnDevice(device handler.IPAndEOJ, values map[string]protocol.PropertyData, skipValidationEPCs []echonet_lite.EPCType) protocol.SetGroupPropertiesDeviceResult {
+	result := protocol.SetGroupPropertiesDeviceResult{
+		Target: device.Specifier(),
+		ID:     ws.echonetClient.GetIDString(device),
+	}
+
+	// Values given as string or number are converted for the class of each device
+	classCode := device.EOJ.ClassCode()
+	properties, err := propertiesFromProtocol(classCode, values)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// Record Set operations before sending, as in set_properties
+	for _, prop := range properties {
+		ws.recordSetResult(device, prop.EPC, protocol.MakePropertyData(classCode, prop))
+	}
+	deviceAndProps, err := ws.echonetClient.SetProperties(device, properties, skipValidationEPCs)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	ws.scheduleTriggerUpdates(device, properties)
+
+	result.Success = true
+	result.Properties = make(protocol.PropertyMap)
+	for _, prop := range deviceAndProps.Properties {
+		result.Properties.Set(prop.EPC, protocol.MakePropertyData(classCode, prop))
+	}
+	return result
+}
+
+// handleSetGroupPropertiesFromClient handles a set_group_properties message from a client.
+// The properties are set on each device concurrently; a failure on one device does not stop the others.
+// With async, the devices are set in the background as a cancellable operation.
+func (ws *WebSocketServer) handleSetGroupPropertiesFromClient(connID string, msg *protocol.Message) protocol.CommandResultPayload {
+	var payload protocol.SetGroupPropertiesPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing set_group_properties payload: %v", err)
+	}
+	if payload.Group == "" && len(payload.Targets) == 0 {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No group or targets specified")
+	}
+	if len(payload.Properties) == 0 {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No properties specified")
+	}
+
+	skipValidationEPCs := make([]echonet_lite.EPCType, 0, len(payload.SkipValidationEPCs))
+	for _, epcStr := range payload.SkipValidationEPCs {
+		epc, err := handler.ParseEPCString(epcStr)
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid EPC in skip_validation_epcs: %v", err)
+		}
+		skipValidationEPCs = append(skipValidationEPCs, epc)
+	}
+
+	devices, missing, err := ws.groupPropertiesTargets(payload)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "%v", err)
+	}
+
+	if payload.Async {
+		return ws.startOperation(connID, protocol.MessageTypeSetGroupProperties, len(devices)+len(missing), true, func(op *bulkOperation) error {
+			for _, id := range missing {
+				op.report(string(id), operationTargetFailed, "Device not found: "+string(id))
+			}
+			batches := make([][]handler.IPAndEOJ, len(devices))
+			for i, device := range devices {
+				batches[i] = []handler.IPAndEOJ{device}
+			}
+			runOperationBatches(op, batches, func(batch []handler.IPAndEOJ) {
+				result := ws.setGroupPropertiesOnDevice(batch[0], payload.Properties, skipValidationEPCs)
+				if result.Success {
+					op.report(result.Target, operationTargetSucceeded, "")
+				} else {
+					op.report(result.Target, operationTargetFailed, result.Error)
+				}
+			})
+			return nil
+		})
+	}
+
+	response := protocol.SetGroupPropertiesResponse{
+		Group:   payload.Group,
+		Results: make([]protocol.SetGroupPropertiesDeviceResult, len(devices), len(devices)+len(missing)),
+	}
+
+	var wg sync.WaitGroup
+	for i, device := range devices {
+		wg.Add(1)
+		go func(result *protocol.SetGroupPropertiesDeviceResult) {
+			defer wg.Done()
+			*result = ws.setGroupPropertiesOnDevice(device, payload.Properties, skipValidationEPCs)
+		}(&response.Results[i])
+	}
+	wg.Wait()
+
+	for _, id := range missing {
+		response.Results = append(response.Results, protocol.SetGroupPropertiesDeviceResult{
+			Target: string(id),
+			ID:     id,
+			Error:  "Device not found: " + string(id),
+		})
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling set_group_properties result: %v", err)
+	}
+	return SuccessResponse(data)
+}
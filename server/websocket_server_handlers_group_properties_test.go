@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// groupSetTestClient はグループの一括設定のテスト用に一部のメソッドを上書きしたモック
+type groupSetTestClient struct {
+	mockECHONETListClient
+	groups  map[string][]handler.IDString
+	devices map[handler.IDString]echonet_lite.IPAndEOJ
+	failIP  string
+
+	mu  sync.Mutex
+	set map[string]echonet_lite.Properties
+}
+
+func (c *groupSetTestClient) GetDevicesByGroup(group string) ([]handler.IDString, bool) {
+	ids, ok := c.groups[group]
+	return ids, ok
+}
+
+func (c *groupSetTestClient) FindDeviceByIDString(id handler.IDString) *echonet_lite.IPAndEOJ {
+	if device, ok := c.devices[id]; ok {
+		return &device
+	}
+	return nil
+}
+
+func (c *groupSetTestClient) SetProperties(device echonet_lite.IPAndEOJ, properties echonet_lite.Properties, _ []echonet_lite.EPCType) (handler.DeviceAndProperties, error) {
+	if device.IP.String() == c.failIP {
+		return handler.DeviceAndProperties{}, errors.New("timeout")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set[device.Specifier()] = properties
+	return handler.DeviceAndProperties{Device: device, Properties: properties}, nil
+}
+
+func TestHandleSetGroupPropertiesFromClient(t *testing.T) {
+	light := func(ip string) echonet_lite.IPAndEOJ {
+		return echonet_lite.IPAndEOJ{IP: net.ParseIP(ip), EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)}
+	}
+	c := &groupSetTestClient{
+		groups: map[string][]handler.IDString{"@lights": {"light1", "light2", "light3"}},
+		devices: map[handler.IDString]echonet_lite.IPAndEOJ{
+			"light1": light("192.168.1.11"),
+			"light2": light("192.168.1.12"),
+			// light3 は見つからない
+		},
+		failIP: "192.168.1.12",
+		set:    make(map[string]echonet_lite.Properties),
+	}
+	ws := &WebSocketServer{
+		ctx:           context.Background(),
+		echonetClient: c,
+		timeProvider:  &RealTimeProvider{},
+	}
+
+	call := func(payload protocol.SetGroupPropertiesPayload) protocol.CommandResultPayload {
+		t.Helper()
+		data, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ws.handleSetGroupPropertiesFromClient("conn-1", &protocol.Message{Type: protocol.MessageTypeSetGroupProperties, Payload: data})
+	}
+
+	// グループのデバイスと、追加で指定したデバイス（重複は1回だけ）を一度に設定する
+	cr := call(protocol.SetGroupPropertiesPayload{
+		Group:      "@lights",
+		Targets:    []string{"192.168.1.13 0291:1", "192.168.1.11 0291:1"},
+		Properties: protocol.PropertyMap{"80": {String: "off"}},
+	})
+	if !cr.Success {
+		t.Fatalf("expected success, got %+v", cr.Error)
+	}
+	var response protocol.SetGroupPropertiesResponse
+	if err := json.Unmarshal(cr.Data, &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Group != "@lights" || len(response.Results) != 4 {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+
+	want := []struct {
+		target  string
+		success bool
+	}{
+		{"192.168.1.11 0291:1", true},
+		{"192.168.1.12 0291:1", false},
+		{"192.168.1.13 0291:1", true},
+		{"light3", false},
+	}
+	for i, w := range want {
+		r := response.Results[i]
+		if r.Target != w.target || r.Success != w.success {
+			t.Errorf("result %d = %+v, want target %s success %v", i, r, w.target, w.success)
+		}
+		if !r.Success && r.Error == "" {
+			t.Errorf("result %d has no error message", i)
+		}
+	}
+	if response.Results[0].Properties["80"].String != "off" {
+		t.Errorf("unexpected properties: %+v", response.Results[0].Properties)
+	}
+	if len(c.set) != 2 || c.set["192.168.1.11 0291:1"][0].EDT[0] != 0x31 {
+		t.Errorf("unexpected set requests: %+v", c.set)
+	}
+
+	// リクエスト全体のエラー
+	for name, payload := range map[string]protocol.SetGroupPropertiesPayload{
+		"no target":      {Properties: protocol.PropertyMap{"80": {String: "off"}}},
+		"no properties":  {Group: "@lights"},
+		"unknown group":  {Group: "@unknown", Properties: protocol.PropertyMap{"80": {String: "off"}}},
+		"invalid target": {Targets: []string{"not-a-device"}, Properties: protocol.PropertyMap{"80": {String: "off"}}},
+	} {
+		if cr := call(payload); cr.Success || cr.Error.Code != protocol.ErrorCodeInvalidParameters {
+			t.Errorf("%s: expected invalid parameters, got %+v", name, cr)
+		}
+	}
+}
@@ -14,7 +14,7 @@ const defaultHistoryLimit = 50
 // handleGetDeviceHistoryFromClient handles a get_device_history message from a client.
 func (ws *WebSocketServer) handleGetDeviceHistoryFromClient(msg *protocol.Message) protocol.CommandResultPayload {
 	if ws.GetHistoryStore() == nil {
-		return ErrorResponse(protocol.ErrorCodeInternalServerError, "History store is not available")
+		return ErrorResponse(protocol.ErrorCodeFeatureDisabled, "Device history is disabled on this server")
 	}
 
 	var payload protocol.GetDeviceHistoryPayload
@@ -61,6 +61,22 @@ func (ws *WebSocketServer) handleGetDeviceHistoryFromClient(msg *protocol.Messag
 		Limit:        limit,
 		SettableOnly: settableOnly,
 	}
+	if payload.Since != nil {
+		query.Since = *payload.Since
+	}
+	if payload.Until != nil {
+		query.Until = *payload.Until
+	}
+	if !query.Since.IsZero() && !query.Until.IsZero() && query.Until.Before(query.Since) {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "until must not be before since")
+	}
+	if payload.EPC != "" {
+		epc, err := handler.ParseEPCString(payload.EPC)
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid EPC: %v", err)
+		}
+		query.EPC = epc
+	}
 
 	history := ws.GetHistoryStore().Query(ipAndEOJ, query)
 	resultEntries := make([]protocol.HistoryEntry, 0, len(history))
@@ -77,7 +93,7 @@ func (ws *WebSocketServer) handleGetDeviceHistoryFromClient(msg *protocol.Messag
 		settable := entry.Settable
 
 		resultEntries = append(resultEntries, protocol.HistoryEntry{
-			Timestamp: entry.Timestamp,
+			Timestamp: protocol.ServerTime(entry.Timestamp),
 			EPC:       epcStr, // Empty string for events, will be omitted in JSON
 			Value:     protocol.PropertyDataFromHandlerValue(entry.Value),
 			Origin:    protocol.HistoryOrigin(entry.Origin),
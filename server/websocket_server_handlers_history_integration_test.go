@@ -180,6 +180,39 @@ func TestHandleGetDeviceHistoryFromClient_Basic(t *testing.T) {
 			t.Errorf("Expected InvalidParameters error, got: %v", result.Error)
 		}
 	})
+
+	// Test 5: EPC filter
+	t.Run("FilterByEPC", func(t *testing.T) {
+		for epc, expected := range map[string]int{"80": 1, "B0": 0} {
+			payloadBytes, err := json.Marshal(protocol.GetDeviceHistoryPayload{Target: testDevice.Specifier(), EPC: epc})
+			if err != nil {
+				t.Fatalf("Failed to marshal payload: %v", err)
+			}
+			result := ws.handleGetDeviceHistoryFromClient(&protocol.Message{
+				Type:    protocol.MessageTypeGetDeviceHistory,
+				Payload: payloadBytes,
+			})
+			if !result.Success {
+				t.Fatalf("Expected success, got error: %v", result.Error)
+			}
+			var response protocol.DeviceHistoryResponse
+			if err := json.Unmarshal(result.Data, &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if len(response.Entries) != expected {
+				t.Errorf("EPC %s: expected %d entries, got %d", epc, expected, len(response.Entries))
+			}
+		}
+
+		payloadBytes, _ := json.Marshal(protocol.GetDeviceHistoryPayload{Target: testDevice.Specifier(), EPC: "XYZ"})
+		result := ws.handleGetDeviceHistoryFromClient(&protocol.Message{
+			Type:    protocol.MessageTypeGetDeviceHistory,
+			Payload: payloadBytes,
+		})
+		if result.Success || result.Error == nil || result.Error.Code != protocol.ErrorCodeInvalidParameters {
+			t.Errorf("Expected InvalidParameters error for an invalid EPC, got: %+v", result)
+		}
+	})
 }
 
 // TestRecordHistory tests the recordHistory function
@@ -653,3 +686,50 @@ func TestRecordSetResult_TrackingBeforeHistory(t *testing.T) {
 		t.Errorf("Expected second entry to be Set, got %s", entries[1].Origin)
 	}
 }
+
+// TestHandleGetDeviceHistoryFromClient_Disabled tests that history queries fail with FEATURE_DISABLED when history is disabled
+func TestHandleGetDeviceHistoryFromClient_Disabled(t *testing.T) {
+	ctx := context.Background()
+
+	liteHandler, err := handler.NewECHONETLiteHandler(ctx, handler.ECHONETLieHandlerOptions{
+		TestMode:       true,
+		HistoryOptions: handler.HistoryOptions{Disabled: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer liteHandler.Close()
+
+	testDevice := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.100"), EOJ: echonet_lite.MakeEOJ(0x0291, 1)}
+	liteHandler.GetDataManagementHandler().RegisterDevice(testDevice)
+
+	ws := &WebSocketServer{
+		ctx:     ctx,
+		handler: liteHandler,
+		deviceResolver: func(d handler.IPAndEOJ) bool {
+			return d.Key() == testDevice.Key()
+		},
+	}
+
+	if ws.GetHistoryStore() != nil {
+		t.Fatal("History store should be nil when history is disabled")
+	}
+
+	// Recording must be a no-op rather than a panic
+	ws.recordHistory(testDevice, echonet_lite.EPCType(0x80), protocol.PropertyData{String: "on"}, handler.HistoryOriginSet)
+
+	payloadBytes, err := json.Marshal(protocol.GetDeviceHistoryPayload{Target: testDevice.Specifier()})
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+	result := ws.handleGetDeviceHistoryFromClient(&protocol.Message{
+		Type:    protocol.MessageTypeGetDeviceHistory,
+		Payload: payloadBytes,
+	})
+	if result.Success {
+		t.Fatal("Expected failure when history is disabled")
+	}
+	if result.Error == nil || result.Error.Code != protocol.ErrorCodeFeatureDisabled {
+		t.Errorf("Expected FEATURE_DISABLED error, got %+v", result.Error)
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// handleManageLocalPropertyMapsFromClient handles a manage_local_property_maps message from a client.
+// "list" returns the property maps of the devices of our own node, "set" replaces one of them and "reset" restores its default.
+func (ws *WebSocketServer) handleManageLocalPropertyMapsFromClient(msg *protocol.Message) protocol.CommandResultPayload {
+	var payload protocol.ManageLocalPropertyMapsPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing manage_local_property_maps payload: %v", err)
+	}
+
+	switch payload.Action {
+	case "", "list":
+		maps, err := ws.echonetClient.LocalPropertyMaps()
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error getting property maps: %v", err)
+		}
+		response := protocol.LocalPropertyMapsResponse{Maps: make([]protocol.LocalPropertyMap, 0, len(maps))}
+		for _, m := range maps {
+			response.Maps = append(response.Maps, protocol.LocalPropertyMapToProtocol(m))
+		}
+		data, err := json.Marshal(response)
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling property maps: %v", err)
+		}
+		return SuccessResponse(data)
+
+	case "set", "reset":
+		eoj, err := handler.ParseEOJString(payload.EOJ)
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid eoj: %v", err)
+		}
+		mapType, err := handler.ParsePropertyMapType(payload.Map)
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid map: %v", err)
+		}
+		var epcs []echonet_lite.EPCType
+		if payload.Action == "set" {
+			epcs = make([]echonet_lite.EPCType, 0, len(payload.EPCs))
+			for _, s := range payload.EPCs {
+				epc, err := handler.ParseEPCString(s)
+				if err != nil {
+					return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid EPC: %v", err)
+				}
+				epcs = append(epcs, epc)
+			}
+		}
+		if err := ws.echonetClient.SetLocalPropertyMap(eoj, mapType, epcs); err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "%v", err)
+		}
+		return SuccessResponse(nil)
+
+	default:
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Unknown action: %s", payload.Action)
+	}
+}
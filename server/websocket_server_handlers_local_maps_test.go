@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"echonet-list/client"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+func TestHandleManageLocalPropertyMapsFromClient(t *testing.T) {
+	ctx := context.Background()
+
+	liteHandler, err := handler.NewECHONETLiteHandler(ctx, handler.ECHONETLieHandlerOptions{TestMode: true})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer liteHandler.Close()
+
+	ws := &WebSocketServer{ctx: ctx, handler: liteHandler, echonetClient: client.NewECHONETListClientProxy(liteHandler)}
+
+	send := func(payload protocol.ManageLocalPropertyMapsPayload) protocol.CommandResultPayload {
+		t.Helper()
+		payloadBytes, _ := json.Marshal(payload)
+		return ws.handleManageLocalPropertyMapsFromClient(&protocol.Message{Type: protocol.MessageTypeManageLocalPropertyMaps, Payload: payloadBytes})
+	}
+	controller := func() protocol.LocalPropertyMap {
+		t.Helper()
+		result := send(protocol.ManageLocalPropertyMapsPayload{})
+		if !result.Success {
+			t.Fatalf("list failed: %+v", result.Error)
+		}
+		var response protocol.LocalPropertyMapsResponse
+		if err := json.Unmarshal(result.Data, &response); err != nil {
+			t.Fatalf("Failed to unmarshal property maps: %v", err)
+		}
+		for _, m := range response.Maps {
+			if m.EOJ == "05FF:1" {
+				return m
+			}
+		}
+		t.Fatalf("controller not found in %+v", response.Maps)
+		return protocol.LocalPropertyMap{}
+	}
+
+	// The controller announces its installation location by default
+	if got := controller(); len(got.Announce) != 1 || got.Announce[0] != "81" || len(got.Overridden) != 0 {
+		t.Errorf("default controller maps = %+v", got)
+	}
+
+	if result := send(protocol.ManageLocalPropertyMapsPayload{Action: "set", EOJ: "05FF:1", Map: "announce", EPCs: []string{"80", "81"}}); !result.Success {
+		t.Fatalf("set failed: %+v", result.Error)
+	}
+	if got := controller(); len(got.Announce) != 2 || len(got.Overridden) != 1 || got.Overridden[0] != "announce" {
+		t.Errorf("controller maps after set = %+v", got)
+	}
+
+	if result := send(protocol.ManageLocalPropertyMapsPayload{Action: "reset", EOJ: "05FF:1", Map: "announce"}); !result.Success {
+		t.Fatalf("reset failed: %+v", result.Error)
+	}
+	if got := controller(); len(got.Announce) != 1 || len(got.Overridden) != 0 {
+		t.Errorf("controller maps after reset = %+v", got)
+	}
+
+	for _, payload := range []protocol.ManageLocalPropertyMapsPayload{
+		{Action: "set", EOJ: "0130:1", Map: "get"},
+		{Action: "set", EOJ: "05FF:1", Map: "inf"},
+		{Action: "set", EOJ: "05FF:1", Map: "get", EPCs: []string{"XY"}},
+		{Action: "unknown"},
+	} {
+		if result := send(payload); result.Success {
+			t.Errorf("expected an error for %+v", payload)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	"echonet-list/protocol"
+)
+
+// SetLogStream enables subscribe_logs with the log stream of the LogManager
+func (ws *WebSocketServer) SetLogStream(stream *LogStream) {
+	ws.logs = stream
+}
+
+// handleSubscribeLogsFromClient handles a subscribe_logs message from a client.
+// It subscribes the client to the log records at the requested level or above and returns the requested backlog,
+// so that a web client can show the server log live.
+func (ws *WebSocketServer) handleSubscribeLogsFromClient(connID string, msg *protocol.Message) protocol.CommandResultPayload {
+	var payload protocol.SubscribeLogsPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing subscribe_logs payload: %v", err)
+	}
+
+	if ws.logs == nil {
+		return ErrorResponse(protocol.ErrorCodeFeatureDisabled, "Log streaming is not available on this server")
+	}
+	if payload.Unsubscribe {
+		ws.logs.Unsubscribe(connID)
+		return SuccessResponse(nil)
+	}
+
+	level := slog.LevelInfo
+	if payload.Level != "" {
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(payload.Level))); err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid level: %q (debug, info, warn or error)", payload.Level)
+		}
+	}
+	if payload.Backlog < 0 || payload.Backlog > logBacklogSize {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "backlog must be between 0 and %d", logBacklogSize)
+	}
+
+	result := protocol.SubscribeLogsResult{
+		Level:   level.String(),
+		Entries: ws.logs.Subscribe(connID, level, payload.Backlog),
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		ws.logs.Unsubscribe(connID)
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling subscribe_logs result: %v", err)
+	}
+	return SuccessResponse(data)
+}
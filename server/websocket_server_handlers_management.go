@@ -214,3 +214,37 @@ func (ws *WebSocketServer) handleManageGroupFromClient(msg *protocol.Message) pr
 		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Unknown group action: %s", payload.Action)
 	}
 }
+
+// handleGetAliasesFromClient handles a get_aliases message from a client.
+// It returns the same alias map as initial_state, for clients that left aliases out of it.
+func (ws *WebSocketServer) handleGetAliasesFromClient(_ *protocol.Message) protocol.CommandResultPayload {
+	aliases := make(map[string]client.IDString)
+	for _, alias := range ws.echonetClient.AliasList() {
+		if alias.Alias != "" && alias.ID != "" {
+			aliases[alias.Alias] = alias.ID
+		}
+	}
+
+	aliasDataJSON, err := json.Marshal(aliases)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling alias data: %v", err)
+	}
+	return SuccessResponse(aliasDataJSON)
+}
+
+// handleGetGroupsFromClient handles a get_groups message from a client.
+// It returns the same group map as initial_state, for clients that left groups out of it.
+func (ws *WebSocketServer) handleGetGroupsFromClient(_ *protocol.Message) protocol.CommandResultPayload {
+	groups := make(map[string][]client.IDString)
+	for _, group := range ws.echonetClient.GroupList(nil) {
+		if group.Group != "" {
+			groups[group.Group] = group.Devices
+		}
+	}
+
+	groupDataJSON, err := json.Marshal(groups)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling group data: %v", err)
+	}
+	return SuccessResponse(groupDataJSON)
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"runtime"
+
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// handleGetMemoryUsageFromClient handles a get_memory_usage message from a client.
+// It reports the approximate memory used by the in-memory stores and notification buffers.
+func (ws *WebSocketServer) handleGetMemoryUsageFromClient(msg *protocol.Message) protocol.CommandResultPayload {
+	if ws.handler == nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Handler is not available")
+	}
+
+	usage := memoryUsageToProtocol(ws.handler.MemoryUsage())
+	if transport, ok := ws.transport.(*DefaultWebSocketTransport); ok {
+		usage.Clients = transportStatsToProtocol(transport.slowClientPolicy, transport.Stats())
+	}
+
+	data, err := json.Marshal(usage)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling memory usage data: %v", err)
+	}
+
+	return SuccessResponse(data)
+}
+
+// memoryUsageToProtocol converts the handler's memory usage and adds Go runtime statistics.
+func memoryUsageToProtocol(usage handler.MemoryUsage) protocol.MemoryUsageResponse {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	buffers := make([]protocol.MemoryUsageBuffer, 0, len(usage.Buffers))
+	for _, buffer := range usage.Buffers {
+		buffers = append(buffers, protocol.MemoryUsageBuffer{
+			Name:     buffer.Name,
+			Length:   buffer.Length,
+			Capacity: buffer.Capacity,
+		})
+	}
+
+	return protocol.MemoryUsageResponse{
+		Devices: protocol.MemoryUsageDevices{
+			Count:       usage.Devices.Devices,
+			Properties:  usage.Devices.Properties,
+			ApproxBytes: usage.Devices.ApproxBytes,
+		},
+		History: protocol.MemoryUsageHistory{
+			Entries:     usage.History.Entries,
+			ApproxBytes: usage.History.ApproxBytes,
+		},
+		Buffers:     buffers,
+		ApproxBytes: usage.ApproxBytes,
+		Runtime: protocol.MemoryUsageRuntime{
+			AllocBytes: memStats.Alloc,
+			SysBytes:   memStats.Sys,
+			Goroutines: runtime.NumGoroutine(),
+			NumGC:      memStats.NumGC,
+		},
+	}
+}
+
+// transportStatsToProtocol converts the send queue statistics of the transport.
+func transportStatsToProtocol(policy SlowClientPolicy, stats TransportStats) *protocol.MemoryUsageClients {
+	queues := make([]protocol.MemoryUsageSendQueue, 0, len(stats.Queues))
+	for _, queue := range stats.Queues {
+		queues = append(queues, protocol.MemoryUsageSendQueue{
+			ConnID:   queue.ConnID,
+			Length:   queue.Length,
+			Capacity: queue.Capacity,
+			Dropped:  queue.Dropped,
+		})
+	}
+	return &protocol.MemoryUsageClients{
+		SlowClientPolicy: string(policy),
+		EvictedClients:   stats.EvictedClients,
+		DroppedMessages:  stats.DroppedMessages,
+		SendQueues:       queues,
+	}
+}
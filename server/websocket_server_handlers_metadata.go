@@ -0,0 +1,85 @@
+package server
+
+import (
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// handleManageMetadataFromClient handles a manage_metadata message from a client.
+// The metadata is stored on the server, so that every client shows the same notes and labels.
+func (ws *WebSocketServer) handleManageMetadataFromClient(msg *protocol.Message) protocol.CommandResultPayload {
+	var payload protocol.ManageMetadataPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing manage_metadata payload: %v", err)
+	}
+
+	if payload.Target == "" {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No target specified")
+	}
+
+	switch payload.Action {
+	case protocol.MetadataActionSet:
+		if payload.Metadata == nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No metadata specified for set action")
+		}
+		if ws.handler.FindDeviceByIDString(payload.Target) == nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid target: %v", payload.Target)
+		}
+		if err := ws.handler.DeviceMetadataSet(payload.Target, *payload.Metadata); err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Error setting metadata: %v", err)
+		}
+
+		// Setting empty metadata deletes it
+		changedPayload := protocol.MetadataChangedPayload{ChangeType: protocol.MetadataChangeTypeDeleted, Target: payload.Target}
+		if !payload.Metadata.IsEmpty() {
+			changedPayload = protocol.MetadataChangedPayload{ChangeType: protocol.MetadataChangeTypeUpdated, Target: payload.Target, Metadata: payload.Metadata}
+		}
+		_ = ws.broadcastMetadataChanged(changedPayload)
+		return SuccessResponse(nil)
+
+	case protocol.MetadataActionDelete:
+		// The device may already be gone, so only the metadata has to exist
+		if err := ws.handler.DeviceMetadataDelete(payload.Target); err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Error deleting metadata: %v", err)
+		}
+		_ = ws.broadcastMetadataChanged(protocol.MetadataChangedPayload{
+			ChangeType: protocol.MetadataChangeTypeDeleted,
+			Target:     payload.Target,
+		})
+		return SuccessResponse(nil)
+
+	default:
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Unknown metadata action: %s", payload.Action)
+	}
+}
+
+// broadcastMetadataChanged sends metadata_changed to the clients allowed to read the device.
+// The metadata of a device that is no longer known only goes to the clients that can read every device.
+func (ws *WebSocketServer) broadcastMetadataChanged(payload protocol.MetadataChangedPayload) error {
+	return ws.broadcastDeviceMessageToClients(ws.deviceOfIDString(payload.Target), protocol.MessageTypeMetadataChanged, payload)
+}
+
+// deviceOfIDString returns the device with the ID string, or the zero value if it is not known
+func (ws *WebSocketServer) deviceOfIDString(id handler.IDString) handler.IPAndEOJ {
+	if device := ws.handler.FindDeviceByIDString(id); device != nil {
+		return *device
+	}
+	return handler.IPAndEOJ{}
+}
+
+// readableMetadata returns the metadata of the devices the client may read
+func (ws *WebSocketServer) readableMetadata(connID string) map[handler.IDString]handler.DeviceMetadata {
+	metadata := ws.handler.GetDeviceMetadata()
+	rule := ws.ruleForConnection(connID)
+	if rule.CanReadAll() {
+		return metadata
+	}
+	resolver := ws.accessResolver()
+	readable := make(map[handler.IDString]handler.DeviceMetadata)
+	for id, m := range metadata {
+		if rule.CanRead(resolver, ws.deviceOfIDString(id)) {
+			readable[id] = m
+		}
+	}
+	return readable
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleManageMetadataFromClient(t *testing.T) {
+	ctx := context.Background()
+	handlerInstance, err := handler.NewECHONETLiteHandler(ctx, handler.ECHONETLieHandlerOptions{TestMode: true, InMemory: true})
+	require.NoError(t, err)
+	defer handlerInstance.Close()
+
+	// 識別番号を持つノードのエアコンを登録する
+	ip := net.ParseIP("192.168.1.10")
+	aircon := handler.IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	idNumber := append([]byte{0xFE, 0x00, 0x00, 0x0B}, make([]byte, 13)...)
+	data := handlerInstance.GetDataManagementHandler()
+	data.RegisterProperties(handler.IPAndEOJ{IP: ip, EOJ: echonet_lite.NodeProfileObject}, handler.Properties{{EPC: echonet_lite.EPC_NPO_IDNumber, EDT: idNumber}})
+	data.RegisterDevice(aircon)
+	id := handlerInstance.GetIDString(aircon)
+	require.NotEmpty(t, id)
+
+	mockTransport := new(mockLocationTransport)
+	mockTransport.On("BroadcastMessage", mock.Anything).Return(nil)
+	ws := &WebSocketServer{ctx: ctx, handler: handlerInstance, transport: mockTransport}
+
+	manage := func(payload protocol.ManageMetadataPayload) protocol.CommandResultPayload {
+		t.Helper()
+		raw, err := json.Marshal(payload)
+		require.NoError(t, err)
+		return ws.handleManageMetadataFromClient(&protocol.Message{Type: protocol.MessageTypeManageMetadata, Payload: raw})
+	}
+	lastChange := func() protocol.MetadataChangedPayload {
+		t.Helper()
+		require.NotEmpty(t, mockTransport.broadcastMessages)
+		var msg protocol.Message
+		require.NoError(t, json.Unmarshal(mockTransport.broadcastMessages[len(mockTransport.broadcastMessages)-1], &msg))
+		assert.Equal(t, protocol.MessageTypeMetadataChanged, msg.Type)
+		var payload protocol.MetadataChangedPayload
+		require.NoError(t, json.Unmarshal(msg.Payload, &payload))
+		return payload
+	}
+
+	// 設定すると保存され、すべてのクライアントに通知される
+	metadata := handler.DeviceMetadata{Notes: "フィルター清掃済み", Room: "リビング", Floor: "1F", Tags: map[string]string{"model": "X"}}
+	result := manage(protocol.ManageMetadataPayload{Action: protocol.MetadataActionSet, Target: id, Metadata: &metadata})
+	require.True(t, result.Success, "%+v", result.Error)
+	assert.Equal(t, "リビング", handlerInstance.GetDeviceMetadata()[id].Room)
+	change := lastChange()
+	assert.Equal(t, protocol.MetadataChangeTypeUpdated, change.ChangeType)
+	assert.Equal(t, id, change.Target)
+	require.NotNil(t, change.Metadata)
+	assert.Equal(t, "X", change.Metadata.Tags["model"])
+
+	// initial_state にも含まれる
+	transport := &sentMessageTransport{}
+	ws.transport = transport
+	require.NoError(t, ws.generateAndSendInitialState("conn", initialStateRequest{}))
+	require.Len(t, transport.sent, 1)
+	var msg protocol.Message
+	var initialState protocol.InitialStatePayload
+	require.NoError(t, json.Unmarshal(transport.sent[0], &msg))
+	require.NoError(t, json.Unmarshal(msg.Payload, &initialState))
+	assert.Equal(t, "フィルター清掃済み", initialState.Metadata[id].Notes)
+	ws.transport = mockTransport
+
+	// 不正な指定はエラーになる
+	for name, payload := range map[string]protocol.ManageMetadataPayload{
+		"不明なデバイス":  {Action: protocol.MetadataActionSet, Target: "013001:000005:FF", Metadata: &metadata},
+		"メタデータなし":  {Action: protocol.MetadataActionSet, Target: id},
+		"対象なし":     {Action: protocol.MetadataActionDelete},
+		"不明なアクション": {Action: "list", Target: id},
+		"不正なタグ":    {Action: protocol.MetadataActionSet, Target: id, Metadata: &handler.DeviceMetadata{Tags: map[string]string{"": "v"}}},
+	} {
+		result := manage(payload)
+		if assert.False(t, result.Success, name) {
+			assert.Equal(t, protocol.ErrorCodeInvalidParameters, result.Error.Code, name)
+		}
+	}
+
+	// 削除すると削除が通知され、2回目はエラーになる
+	result = manage(protocol.ManageMetadataPayload{Action: protocol.MetadataActionDelete, Target: id})
+	require.True(t, result.Success, "%+v", result.Error)
+	assert.Empty(t, handlerInstance.GetDeviceMetadata())
+	assert.Equal(t, protocol.MetadataChangeTypeDeleted, lastChange().ChangeType)
+	assert.False(t, manage(protocol.ManageMetadataPayload{Action: protocol.MetadataActionDelete, Target: id}).Success)
+}
+
+func TestHandleManageMetadataFromClient_AccessControl(t *testing.T) {
+	ctx := context.Background()
+	handlerInstance, err := handler.NewECHONETLiteHandler(ctx, handler.ECHONETLieHandlerOptions{TestMode: true, InMemory: true})
+	require.NoError(t, err)
+	defer handlerInstance.Close()
+
+	ip := net.ParseIP("192.168.1.10")
+	aircon := handler.IPAndEOJ{IP: ip, EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	idNumber := append([]byte{0xFE, 0x00, 0x00, 0x0B}, make([]byte, 13)...)
+	data := handlerInstance.GetDataManagementHandler()
+	data.RegisterProperties(handler.IPAndEOJ{IP: ip, EOJ: echonet_lite.NodeProfileObject}, handler.Properties{{EPC: echonet_lite.EPC_NPO_IDNumber, EDT: idNumber}})
+	data.RegisterDevice(aircon)
+	id := handlerInstance.GetIDString(aircon)
+	require.NotEmpty(t, id)
+
+	ac, err := NewAccessControl([]AccessRule{
+		{Name: "viewer", Token: "viewer-token", Read: []string{"*"}},
+		{Name: "aircon", Token: "aircon-token", Read: []string{"192.168.1.10"}},
+		{Name: "other", Token: "other-token", Read: []string{"192.168.1.99"}},
+	})
+	require.NoError(t, err)
+	transport := &logStreamTestTransport{}
+	ws := &WebSocketServer{ctx: ctx, handler: handlerInstance, transport: transport, access: ac}
+	for _, name := range []string{"viewer", "aircon", "other"} {
+		ws.clientRules.Store(name, ac.Rule(name))
+	}
+
+	metadata := handler.DeviceMetadata{Notes: "フィルター清掃済み"}
+	raw, err := json.Marshal(protocol.ManageMetadataPayload{Action: protocol.MetadataActionSet, Target: id, Metadata: &metadata})
+	require.NoError(t, err)
+	result := ws.handleManageMetadataFromClient(&protocol.Message{Type: protocol.MessageTypeManageMetadata, Payload: raw})
+	require.True(t, result.Success, "%+v", result.Error)
+
+	// デバイスを読めないクライアントには metadata_changed も initial_state のメタデータも送られない
+	for connID, want := range map[string]int{"viewer": 1, "aircon": 1, "other": 0} {
+		assert.Len(t, transport.messages(connID), want, connID)
+		assert.Len(t, ws.readableMetadata(connID), want, connID)
+	}
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"encoding/json"
+
+	"echonet-list/protocol"
+)
+
+// handleDebugPendingRequestsFromClient handles a debug_pending_requests message from a client.
+// "list" returns the Get/Set requests waiting for a device's response, "cancel" stops retrying one of them.
+func (ws *WebSocketServer) handleDebugPendingRequestsFromClient(msg *protocol.Message) protocol.CommandResultPayload {
+	var payload protocol.DebugPendingRequestsPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing debug_pending_requests payload: %v", err)
+	}
+	if ws.handler == nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Handler is not available")
+	}
+
+	switch payload.Action {
+	case "", "list":
+		pending := ws.handler.DebugPendingRequests()
+		response := protocol.PendingRequestsResponse{Requests: make([]protocol.PendingRequest, 0, len(pending))}
+		for _, req := range pending {
+			response.Requests = append(response.Requests, protocol.PendingRequestToProtocol(req))
+		}
+		data, err := json.Marshal(response)
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling pending requests: %v", err)
+		}
+		return SuccessResponse(data)
+
+	case "cancel":
+		if err := ws.handler.DebugCancelRequest(payload.ID); err != nil {
+			return ErrorResponse(protocol.ErrorCodeTargetNotFound, "%v", err)
+		}
+		return SuccessResponse(nil)
+
+	default:
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Unknown action: %s", payload.Action)
+	}
+}
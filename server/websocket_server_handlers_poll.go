@@ -0,0 +1,48 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"echonet-list/protocol"
+)
+
+// handleManagePollExclusionsFromClient handles a manage_poll_exclusions message from a client.
+// "list" returns the excluded classes and devices, "add" stops polling a class or device and "remove" polls it again.
+// Changes last until the server stops; the exclusions of the configuration file are restored at the next start.
+func (ws *WebSocketServer) handleManagePollExclusionsFromClient(msg *protocol.Message) protocol.CommandResultPayload {
+	if ws.pollExclusions == nil {
+		return ErrorResponse(protocol.ErrorCodeFeatureDisabled, "Periodic updates are not available")
+	}
+
+	var payload protocol.ManagePollExclusionsPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing manage_poll_exclusions payload: %v", err)
+	}
+
+	switch payload.Action {
+	case "", "list":
+	case "add":
+		if err := ws.pollExclusions.Add(payload.Target); err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid target: %v", err)
+		}
+		slog.Info("Device excluded from periodic updates", "target", payload.Target)
+	case "remove":
+		removed, err := ws.pollExclusions.Remove(payload.Target)
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid target: %v", err)
+		}
+		if !removed {
+			return ErrorResponse(protocol.ErrorCodeTargetNotFound, "Not excluded from periodic updates: %s", payload.Target)
+		}
+		slog.Info("Device included in periodic updates again", "target", payload.Target)
+	default:
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Unknown action: %s", payload.Action)
+	}
+
+	data, err := json.Marshal(protocol.PollExclusionsResponse{Exclusions: ws.pollExclusions.List()})
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling poll exclusions: %v", err)
+	}
+	return SuccessResponse(data)
+}
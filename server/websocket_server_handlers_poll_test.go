@@ -0,0 +1,55 @@
+package server
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"echonet-list/protocol"
+)
+
+func TestHandleManagePollExclusionsFromClient(t *testing.T) {
+	exclusions, _ := ParsePollExclusions([]string{"0279"})
+	ws := &WebSocketServer{pollExclusions: exclusions}
+
+	send := func(payload protocol.ManagePollExclusionsPayload) protocol.CommandResultPayload {
+		t.Helper()
+		payloadBytes, _ := json.Marshal(payload)
+		return ws.handleManagePollExclusionsFromClient(&protocol.Message{Type: protocol.MessageTypeManagePollExclusions, Payload: payloadBytes})
+	}
+	list := func(result protocol.CommandResultPayload) []string {
+		t.Helper()
+		if !result.Success {
+			t.Fatalf("request failed: %+v", result.Error)
+		}
+		var response protocol.PollExclusionsResponse
+		if err := json.Unmarshal(result.Data, &response); err != nil {
+			t.Fatalf("Failed to unmarshal poll exclusions: %v", err)
+		}
+		return response.Exclusions
+	}
+
+	if got := list(send(protocol.ManagePollExclusionsPayload{})); !slices.Equal(got, []string{"0279"}) {
+		t.Errorf("unexpected list: %v", got)
+	}
+	if got := list(send(protocol.ManagePollExclusionsPayload{Action: "add", Target: "solar"})); !slices.Equal(got, []string{"0279", "solar"}) {
+		t.Errorf("unexpected list after add: %v", got)
+	}
+	if got := list(send(protocol.ManagePollExclusionsPayload{Action: "remove", Target: "0279"})); !slices.Equal(got, []string{"solar"}) {
+		t.Errorf("unexpected list after remove: %v", got)
+	}
+
+	for _, tt := range []struct {
+		payload protocol.ManagePollExclusionsPayload
+		code    protocol.ErrorCode
+	}{
+		{protocol.ManagePollExclusionsPayload{Action: "remove", Target: "0279"}, protocol.ErrorCodeTargetNotFound},
+		{protocol.ManagePollExclusionsPayload{Action: "add"}, protocol.ErrorCodeInvalidParameters},
+		{protocol.ManagePollExclusionsPayload{Action: "clear"}, protocol.ErrorCodeInvalidParameters},
+	} {
+		result := send(tt.payload)
+		if result.Success || result.Error == nil || result.Error.Code != tt.code {
+			t.Errorf("%+v: expected %s, got %+v", tt.payload, tt.code, result)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+	"encoding/json"
+)
+
+// preferencesKey returns the key the preferences of a client are stored under.
+// With access control, the key is prefixed with the name of the access rule,
+// so that clients presenting different tokens cannot read each other's preferences.
+func (ws *WebSocketServer) preferencesKey(connID, key string) string {
+	if rule := ws.ruleForConnection(connID); rule != nil {
+		return rule.Name + "/" + key
+	}
+	return key
+}
+
+// preferencesResponse creates the response holding the preferences of a key
+func preferencesResponse(key string, preferences handler.ClientPreferences, saved bool) protocol.CommandResultPayload {
+	data, err := json.Marshal(protocol.PreferencesData{Key: key, Preferences: preferences, Saved: saved})
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling preferences: %v", err)
+	}
+	return SuccessResponse(data)
+}
+
+// handleGetPreferencesFromClient handles a get_preferences message from a client.
+// A key without saved preferences returns empty preferences, so that a new browser can start from the defaults.
+func (ws *WebSocketServer) handleGetPreferencesFromClient(connID string, msg *protocol.Message) protocol.CommandResultPayload {
+	var payload protocol.GetPreferencesPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing get_preferences payload: %v", err)
+	}
+	if err := handler.ValidatePreferencesKey(payload.Key); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid key: %v", err)
+	}
+
+	preferences, saved := ws.handler.GetClientPreferences(ws.preferencesKey(connID, payload.Key))
+	return preferencesResponse(payload.Key, preferences, saved)
+}
+
+// handleSetPreferencesFromClient handles a set_preferences message from a client.
+// The preferences replace the saved ones of the key and are stored on the server.
+func (ws *WebSocketServer) handleSetPreferencesFromClient(connID string, msg *protocol.Message) protocol.CommandResultPayload {
+	var payload protocol.SetPreferencesPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing set_preferences payload: %v", err)
+	}
+	if err := handler.ValidatePreferencesKey(payload.Key); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid key: %v", err)
+	}
+
+	if err := ws.handler.ClientPreferencesSet(ws.preferencesKey(connID, payload.Key), payload.Preferences); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Error setting preferences: %v", err)
+	}
+	return preferencesResponse(payload.Key, payload.Preferences, !payload.Preferences.IsEmpty())
+}
+
+// favoriteDevices returns the IDs of the favorite devices of a client: the devices pinned in the metadata
+// and, when key is not empty, the favorites saved with set_preferences under the key
+func (ws *WebSocketServer) favoriteDevices(connID, key string) map[handler.IDString]bool {
+	favorites := make(map[handler.IDString]bool)
+	if ws.handler == nil {
+		return favorites
+	}
+	for id, metadata := range ws.handler.GetDeviceMetadata() {
+		if metadata.Pinned {
+			favorites[id] = true
+		}
+	}
+	if key != "" {
+		if preferences, ok := ws.handler.GetClientPreferences(ws.preferencesKey(connID, key)); ok {
+			for _, id := range preferences.Favorites {
+				favorites[id] = true
+			}
+		}
+	}
+	return favorites
+}
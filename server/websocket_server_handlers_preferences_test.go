@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlePreferencesFromClient(t *testing.T) {
+	ctx := context.Background()
+	handlerInstance, err := handler.NewECHONETLiteHandler(ctx, handler.ECHONETLieHandlerOptions{TestMode: true, InMemory: true})
+	require.NoError(t, err)
+	defer handlerInstance.Close()
+
+	ws := &WebSocketServer{ctx: ctx, handler: handlerInstance}
+
+	get := func(connID, key string) protocol.PreferencesData {
+		t.Helper()
+		raw, err := json.Marshal(protocol.GetPreferencesPayload{Key: key})
+		require.NoError(t, err)
+		result := ws.handleGetPreferencesFromClient(connID, &protocol.Message{Type: protocol.MessageTypeGetPreferences, Payload: raw})
+		require.True(t, result.Success, "%+v", result.Error)
+		var data protocol.PreferencesData
+		require.NoError(t, json.Unmarshal(result.Data, &data))
+		return data
+	}
+	set := func(connID string, payload protocol.SetPreferencesPayload) protocol.CommandResultPayload {
+		t.Helper()
+		raw, err := json.Marshal(payload)
+		require.NoError(t, err)
+		return ws.handleSetPreferencesFromClient(connID, &protocol.Message{Type: protocol.MessageTypeSetPreferences, Payload: raw})
+	}
+
+	// 保存していないキーは空の表示設定を返す
+	data := get("conn-1", "tablet")
+	assert.Equal(t, "tablet", data.Key)
+	assert.False(t, data.Saved)
+	assert.True(t, data.Preferences.IsEmpty())
+
+	// 保存した表示設定は別の接続からも読める
+	preferences := handler.ClientPreferences{Layout: "compact", Favorites: []handler.IDString{"013001:000005:01"}, Units: map[string]string{"temperature": "fahrenheit"}}
+	result := set("conn-1", protocol.SetPreferencesPayload{Key: "tablet", Preferences: preferences})
+	require.True(t, result.Success, "%+v", result.Error)
+	data = get("conn-2", "tablet")
+	assert.True(t, data.Saved)
+	assert.Equal(t, preferences, data.Preferences)
+
+	// 不正な指定はエラーになる
+	for name, payload := range map[string]protocol.SetPreferencesPayload{
+		"キーなし":    {Preferences: preferences},
+		"不正な単位設定": {Key: "tablet", Preferences: handler.ClientPreferences{Units: map[string]string{"": "celsius"}}},
+	} {
+		result := set("conn-1", payload)
+		if assert.False(t, result.Success, name) {
+			assert.Equal(t, protocol.ErrorCodeInvalidParameters, result.Error.Code, name)
+		}
+	}
+
+	// アクセス制御が有効な場合、キーはトークンごとに分かれる
+	access, err := NewAccessControl([]AccessRule{
+		{Name: "family", Token: "token-family", Read: []string{"*"}},
+		{Name: "guest", Token: "token-guest", Read: []string{"*"}},
+	})
+	require.NoError(t, err)
+	ws.access = access
+	ws.clientRules.Store("family-conn", access.Rule("family"))
+	ws.clientRules.Store("guest-conn", access.Rule("guest"))
+	result = set("family-conn", protocol.SetPreferencesPayload{Key: "tablet", Preferences: handler.ClientPreferences{Layout: "grid"}})
+	require.True(t, result.Success, "%+v", result.Error)
+	assert.Equal(t, "grid", get("family-conn", "tablet").Preferences.Layout)
+	assert.False(t, get("guest-conn", "tablet").Saved)
+
+	// 空の表示設定を保存すると削除される
+	result = set("family-conn", protocol.SetPreferencesPayload{Key: "tablet"})
+	require.True(t, result.Success, "%+v", result.Error)
+	assert.False(t, get("family-conn", "tablet").Saved)
+}
+
+func TestHandleListDevicesFromClient_Favorites(t *testing.T) {
+	ctx := context.Background()
+	handlerInstance, err := handler.NewECHONETLiteHandler(ctx, handler.ECHONETLieHandlerOptions{TestMode: true, InMemory: true})
+	require.NoError(t, err)
+	defer handlerInstance.Close()
+
+	// 識別番号を持つデバイスを3台用意する
+	var devices []handler.DeviceAndProperties
+	var ids []handler.IDString
+	for i := 1; i <= 3; i++ {
+		edt := append([]byte{0xFE, 0x00, 0x00, 0x0B}, make([]byte, 13)...)
+		edt[16] = byte(i)
+		device := handler.DeviceAndProperties{
+			Device:     echonet_lite.IPAndEOJ{IP: net.ParseIP(fmt.Sprintf("192.168.1.%d", i)), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)},
+			Properties: echonet_lite.Properties{{EPC: echonet_lite.EPCIdentificationNumber, EDT: edt}},
+		}
+		devices = append(devices, device)
+		ids = append(ids, handler.MakeIDString(device.Device.EOJ, *device.Properties.GetIdentificationNumber()))
+	}
+	ws := &WebSocketServer{ctx: ctx, handler: handlerInstance, echonetClient: &restTestClient{devices: devices}}
+
+	list := func(payload protocol.ListDevicesPayload) []protocol.Device {
+		t.Helper()
+		raw, err := json.Marshal(payload)
+		require.NoError(t, err)
+		result := ws.handleListDevicesFromClient("conn-1", &protocol.Message{Type: protocol.MessageTypeListDevices, Payload: raw})
+		require.True(t, result.Success, "%+v", result.Error)
+		var listed []protocol.Device
+		if err := json.Unmarshal(result.Data, &listed); err != nil {
+			// 1台だけの場合はデバイスそのものが返る
+			var device protocol.Device
+			require.NoError(t, json.Unmarshal(result.Data, &device))
+			listed = []protocol.Device{device}
+		}
+		return listed
+	}
+	order := func(listed []protocol.Device) []handler.IDString {
+		var result []handler.IDString
+		for _, device := range listed {
+			result = append(result, device.ID)
+		}
+		return result
+	}
+
+	// お気に入りがなければ元の順序のまま
+	listed := list(protocol.ListDevicesPayload{})
+	assert.Equal(t, ids, order(listed))
+	for _, device := range listed {
+		assert.False(t, device.Favorite)
+	}
+
+	// メタデータで固定したデバイスは全クライアントのお気に入りとして先頭に並ぶ
+	require.NoError(t, handlerInstance.DeviceMetadataSet(ids[2], handler.DeviceMetadata{Pinned: true}))
+	listed = list(protocol.ListDevicesPayload{})
+	assert.Equal(t, []handler.IDString{ids[2], ids[0], ids[1]}, order(listed))
+	assert.True(t, listed[0].Favorite)
+
+	// 表示設定のお気に入りは preferencesKey を指定した場合に加わる
+	require.NoError(t, handlerInstance.ClientPreferencesSet("tablet", handler.ClientPreferences{Favorites: []handler.IDString{ids[1]}}))
+	listed = list(protocol.ListDevicesPayload{PreferencesKey: "tablet"})
+	assert.Equal(t, []handler.IDString{ids[1], ids[2], ids[0]}, order(listed))
+
+	// favoritesOnly ではお気に入りだけを返す
+	listed = list(protocol.ListDevicesPayload{PreferencesKey: "tablet", FavoritesOnly: true})
+	assert.Equal(t, []handler.IDString{ids[1], ids[2]}, order(listed))
+	listed = list(protocol.ListDevicesPayload{FavoritesOnly: true})
+	assert.Equal(t, []handler.IDString{ids[2]}, order(listed))
+
+	// 不正なキーはエラーになる
+	raw, err := json.Marshal(protocol.ListDevicesPayload{PreferencesKey: strings.Repeat("x", 65)})
+	require.NoError(t, err)
+	result := ws.handleListDevicesFromClient("conn-1", &protocol.Message{Type: protocol.MessageTypeListDevices, Payload: raw})
+	assert.False(t, result.Success)
+}
@@ -27,6 +27,12 @@ func (ws *WebSocketServer) handleGetPropertiesFromClient(msg *protocol.Message)
 	if len(payload.Targets) == 0 {
 		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No targets specified")
 	}
+	if payload.All && len(payload.EPCs) > 0 {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "EPCs cannot be specified with all")
+	}
+	if !payload.All && len(payload.EPCs) == 0 {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No EPCs specified")
+	}
 
 	// ECHONETクライアントからOperationTrackerを取得
 	if tracker := ws.getOperationTracker(); tracker != nil {
@@ -148,69 +154,18 @@ func (ws *WebSocketServer) handleSetPropertiesFromClient(msg *protocol.Message)
 	}
 
 	// Parse properties
-	requestedValues := make(map[echonet_lite.EPCType]protocol.PropertyData, len(payload.Properties))
-	properties := make(echonet_lite.Properties, 0, len(payload.Properties))
-	for epcStr, propData := range payload.Properties {
+	properties, err := propertiesFromProtocol(ipAndEOJ.EOJ.ClassCode(), payload.Properties)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "%v", err)
+	}
+
+	skipValidationEPCs := make([]echonet_lite.EPCType, 0, len(payload.SkipValidationEPCs))
+	for _, epcStr := range payload.SkipValidationEPCs {
 		epc, err := handler.ParseEPCString(epcStr)
 		if err != nil {
-			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid EPC: %v", err)
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid EPC in skip_validation_epcs: %v", err)
 		}
-		desc, ok := echonet_lite.GetPropertyDesc(ipAndEOJ.EOJ.ClassCode(), epc)
-		if !ok {
-			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Unknown property EPC: %s", epcStr)
-		}
-
-		var edtBytes []byte
-		var valueBytes []byte
-
-		if propData.EDT != "" {
-			decoded, err := base64.StdEncoding.DecodeString(propData.EDT)
-			if err != nil {
-				return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid EDT: %v", err)
-			}
-			edtBytes = decoded
-		}
-		switch {
-		case propData.String != "" && propData.Number != nil:
-			// StringとNumberの両方があったらエラー
-			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Conflicting string and number for EPC: %s", epcStr)
-		case propData.Number != nil:
-			converter, ok := desc.Decoder.(echonet_lite.PropertyIntConverter)
-			if !ok {
-				// 数値に対応していないEPCに数値が与えられたエラー
-				return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid number field for EPC %s", epcStr)
-			}
-			converted, ok := converter.FromInt(*propData.Number)
-			if !ok {
-				// 装置が範囲外
-				return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid number value for EPC %s", epcStr)
-			}
-			valueBytes = converted
-
-		case propData.String != "":
-			converted, ok := desc.ToEDT(propData.String)
-			if !ok {
-				return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid string value: %s", propData.String)
-			}
-			valueBytes = converted
-		}
-
-		switch {
-		case edtBytes == nil && valueBytes == nil:
-			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No EDT or string specified for EPC: %s", epcStr)
-		case edtBytes != nil && valueBytes != nil:
-			if !bytes.Equal(edtBytes, valueBytes) {
-				return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Conflicting EDT and string for EPC: %s", epcStr)
-			}
-		case edtBytes == nil && valueBytes != nil:
-			edtBytes = valueBytes
-		}
-
-		properties = append(properties, echonet_lite.Property{
-			EPC: epc,
-			EDT: edtBytes,
-		})
-		requestedValues[epc] = propData
+		skipValidationEPCs = append(skipValidationEPCs, epc)
 	}
 
 	// Record Set operations BEFORE sending to device to ensure they are recorded before any notifications arrive
@@ -221,15 +176,121 @@ func (ws *WebSocketServer) handleSetPropertiesFromClient(msg *protocol.Message)
 		ws.recordSetResult(ipAndEOJ, prop.EPC, value)
 	}
 
+	if payload.Transaction {
+		return ws.setPropertiesTransaction(ipAndEOJ, properties, skipValidationEPCs)
+	}
+
 	// Set properties
-	deviceAndProps, err := ws.echonetClient.SetProperties(ipAndEOJ, properties)
+	deviceAndProps, err := ws.echonetClient.SetProperties(ipAndEOJ, properties, skipValidationEPCs)
 	if err != nil {
 		return ErrorResponse(protocol.ErrorCodeEchonetCommunicationError, "Error setting properties: %v", err)
 	}
 
-	// Check if any of the set properties have TriggerUpdate flag
+	ws.scheduleTriggerUpdates(ipAndEOJ, properties)
+
+	// デバイスの最終更新タイムスタンプを取得
+	lastSeen := ws.handler.GetLastUpdateTime(deviceAndProps.Device)
+
+	// Use DeviceToProtocol to convert to protocol format
+	// Check if device is offline
+	var isOffline bool
+	if ws.handler != nil {
+		isOffline = ws.handler.IsOffline(deviceAndProps.Device)
+	}
+	deviceData := protocol.DeviceToProtocol(
+		deviceAndProps.Device,
+		deviceAndProps.Properties,
+		lastSeen,
+		isOffline,
+	)
+
+	// Marshal the device data, with the order the properties were set in
+	deviceDataJSON, err := json.Marshal(protocol.SetPropertiesResult{
+		Device:       deviceData,
+		AppliedOrder: protocol.AppliedOrder(deviceAndProps.Properties),
+	})
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling device data: %v", err)
+	}
+
+	// Send the success response with device data
+	return SuccessResponse(deviceDataJSON)
+}
+
+// handleSetGetPropertiesFromClient handles a set_get_properties message from a client.
+// The properties are set and read in one SetGet frame, for devices that need it to report a consistent state.
+func (ws *WebSocketServer) handleSetGetPropertiesFromClient(msg *protocol.Message) protocol.CommandResultPayload {
+	// Parse the payload
+	var payload protocol.SetGetPropertiesPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing set_get_properties payload: %v", err)
+	}
+
+	// Validate the payload
+	if payload.Target == "" {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No target specified")
+	}
+	if len(payload.Properties) == 0 && len(payload.EPCs) == 0 {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No properties or EPCs specified")
+	}
+
+	// Parse the target
+	ipAndEOJ, err := handler.ParseDeviceIdentifier(payload.Target)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid target: %v", err)
+	}
+	classCode := ipAndEOJ.EOJ.ClassCode()
+
+	// Parse properties and EPCs
+	properties, err := propertiesFromProtocol(classCode, payload.Properties)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "%v", err)
+	}
+	epcs := make([]echonet_lite.EPCType, 0, len(payload.EPCs))
+	for _, epcStr := range payload.EPCs {
+		epc, err := handler.ParseEPCString(epcStr)
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid EPC: %v", err)
+		}
+		epcs = append(epcs, epc)
+	}
+
+	// Record Set operations before sending, as in set_properties
 	for _, prop := range properties {
-		desc, ok := echonet_lite.GetPropertyDesc(ipAndEOJ.EOJ.ClassCode(), prop.EPC)
+		ws.recordSetResult(ipAndEOJ, prop.EPC, protocol.MakePropertyData(classCode, prop))
+	}
+
+	result, err := ws.echonetClient.SetGetProperties(ipAndEOJ, properties, epcs)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeEchonetCommunicationError, "Error setting and getting properties: %v", err)
+	}
+
+	ws.scheduleTriggerUpdates(ipAndEOJ, properties)
+
+	response := protocol.SetGetPropertiesResult{
+		IP:  ipAndEOJ.IP.String(),
+		EOJ: ipAndEOJ.EOJ.Specifier(),
+		Set: make(protocol.PropertyMap),
+		Get: make(protocol.PropertyMap),
+	}
+	for _, prop := range result.Set {
+		response.Set.Set(prop.EPC, protocol.MakePropertyData(classCode, prop))
+	}
+	for _, prop := range result.Get {
+		response.Get.Set(prop.EPC, protocol.MakePropertyData(classCode, prop))
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling set_get_properties result: %v", err)
+	}
+	return SuccessResponse(data)
+}
+
+// scheduleTriggerUpdates schedules a forced property update for each set property that has the TriggerUpdate flag
+func (ws *WebSocketServer) scheduleTriggerUpdates(device handler.IPAndEOJ, properties echonet_lite.Properties) {
+	for _, prop := range properties {
+		desc, ok := echonet_lite.GetPropertyDesc(device.EOJ.ClassCode(), prop.EPC)
 		if ok && desc.TriggerUpdate {
 			// Launch a goroutine to update properties after the specified delay
 			go func(device handler.IPAndEOJ, delay time.Duration, targets []echonet_lite.EPCType) {
@@ -271,34 +332,9 @@ func (ws *WebSocketServer) handleSetPropertiesFromClient(msg *protocol.Message)
 						"device", device.Specifier(),
 						"error", err)
 				}
-			}(ipAndEOJ, desc.UpdateDelay, desc.UpdateTargets)
+			}(device, desc.UpdateDelay, desc.UpdateTargets)
 		}
 	}
-
-	// デバイスの最終更新タイムスタンプを取得
-	lastSeen := ws.handler.GetLastUpdateTime(deviceAndProps.Device)
-
-	// Use DeviceToProtocol to convert to protocol format
-	// Check if device is offline
-	var isOffline bool
-	if ws.handler != nil {
-		isOffline = ws.handler.IsOffline(deviceAndProps.Device)
-	}
-	deviceData := protocol.DeviceToProtocol(
-		deviceAndProps.Device,
-		deviceAndProps.Properties,
-		lastSeen,
-		isOffline,
-	)
-
-	// Marshal the device data
-	deviceDataJSON, err := json.Marshal(deviceData)
-	if err != nil {
-		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling device data: %v", err)
-	}
-
-	// Send the success response with device data
-	return SuccessResponse(deviceDataJSON)
 }
 
 // populateEPCDescriptions converts echonet_lite property descriptions to protocol EPC descriptions
@@ -359,7 +395,7 @@ func populateEPCDescriptions(propTable echonet_lite.PropertyTable, targetMap map
 }
 
 // handleGetPropertyDescriptionFromClient handles a get_property_description message from a client
-func (ws *WebSocketServer) handleGetPropertyDescriptionFromClient(msg *protocol.Message) protocol.CommandResultPayload {
+func (ws *WebSocketServer) handleGetPropertyDescriptionFromClient(connID string, msg *protocol.Message) protocol.CommandResultPayload {
 	// Parse the payload
 	var payload protocol.GetPropertyDescriptionPayload
 	if err := protocol.ParsePayload(msg, &payload); err != nil {
@@ -391,8 +427,8 @@ func (ws *WebSocketServer) handleGetPropertyDescriptionFromClient(msg *protocol.
 	// Convert to protocol format
 	propertiesMap := make(map[string]protocol.EPCDesc)
 
-	// Get language from payload, default to empty string (which will use English)
-	lang := payload.Lang
+	// Get language from payload, defaulting to the language of the connection (empty uses English)
+	lang := ws.languageForConnection(connID, payload.Lang)
 
 	// Populate common properties first
 	populateEPCDescriptions(echonet_lite.ProfileSuperClass_PropertyTable, propertiesMap, lang)
@@ -426,7 +462,9 @@ func (ws *WebSocketServer) handleGetPropertyDescriptionFromClient(msg *protocol.
 }
 
 // handleUpdatePropertiesFromClient handles an update_properties message from a client
-func (ws *WebSocketServer) handleUpdatePropertiesFromClient(msg *protocol.Message) protocol.CommandResultPayload {
+// With progress, each device is reported to the requesting client as it finishes.
+// With async, the update runs in the background and an operation is returned at once.
+func (ws *WebSocketServer) handleUpdatePropertiesFromClient(connID string, msg *protocol.Message) protocol.CommandResultPayload {
 	// Parse the payload
 	var payload protocol.UpdatePropertiesPayload
 	if err := protocol.ParsePayload(msg, &payload); err != nil {
@@ -456,6 +494,13 @@ func (ws *WebSocketServer) handleUpdatePropertiesFromClient(msg *protocol.Messag
 		}
 	}
 
+	if payload.Async {
+		return ws.updatePropertiesAsync(connID, filterCriteriaList, payload.Force)
+	}
+	if payload.Progress {
+		return ws.updatePropertiesWithProgress(connID, msg.RequestID, filterCriteriaList, payload.Force)
+	}
+
 	// 各フィルター基準に基づいてプロパティを更新
 	var firstError error
 	// 操作追跡を開始
@@ -524,3 +569,71 @@ func (ws *WebSocketServer) handleUpdatePropertiesFromClient(msg *protocol.Messag
 	// Send the success response
 	return SuccessResponse(nil)
 }
+
+// propertiesFromProtocol converts the properties of a set request into ECHONET Lite properties.
+// Each value may be given as EDT, string or number; conflicting representations are rejected.
+func propertiesFromProtocol(classCode echonet_lite.EOJClassCode, props map[string]protocol.PropertyData) (echonet_lite.Properties, error) {
+	properties := make(echonet_lite.Properties, 0, len(props))
+	for epcStr, propData := range props {
+		epc, err := handler.ParseEPCString(epcStr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid EPC: %v", err)
+		}
+		desc, ok := echonet_lite.GetPropertyDesc(classCode, epc)
+		if !ok {
+			return nil, fmt.Errorf("Unknown property EPC: %s", epcStr)
+		}
+
+		var edtBytes []byte
+		var valueBytes []byte
+
+		if propData.EDT != "" {
+			decoded, err := base64.StdEncoding.DecodeString(propData.EDT)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid EDT: %v", err)
+			}
+			edtBytes = decoded
+		}
+		switch {
+		case propData.String != "" && propData.Number != nil:
+			// StringとNumberの両方があったらエラー
+			return nil, fmt.Errorf("Conflicting string and number for EPC: %s", epcStr)
+		case propData.Number != nil:
+			converter, ok := desc.Decoder.(echonet_lite.PropertyIntConverter)
+			if !ok {
+				// 数値に対応していないEPCに数値が与えられたエラー
+				return nil, fmt.Errorf("Invalid number field for EPC %s", epcStr)
+			}
+			converted, ok := converter.FromInt(*propData.Number)
+			if !ok {
+				// 装置が範囲外
+				return nil, fmt.Errorf("Invalid number value for EPC %s", epcStr)
+			}
+			valueBytes = converted
+
+		case propData.String != "":
+			converted, ok := desc.ToEDT(propData.String)
+			if !ok {
+				return nil, fmt.Errorf("Invalid string value: %s", propData.String)
+			}
+			valueBytes = converted
+		}
+
+		switch {
+		case edtBytes == nil && valueBytes == nil:
+			return nil, fmt.Errorf("No EDT or string specified for EPC: %s", epcStr)
+		case edtBytes != nil && valueBytes != nil:
+			if !bytes.Equal(edtBytes, valueBytes) {
+				return nil, fmt.Errorf("Conflicting EDT and string for EPC: %s", epcStr)
+			}
+		case edtBytes == nil && valueBytes != nil:
+			edtBytes = valueBytes
+		}
+
+		properties = append(properties, echonet_lite.Property{
+			EPC: epc,
+			EDT: edtBytes,
+		})
+	}
+	return properties, nil
+}
@@ -9,6 +9,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/assert"
@@ -71,10 +72,14 @@ func (m *mockECHONETListClient) GetProperties(_ echonet_lite.IPAndEOJ, _ []echon
 	return handler.DeviceAndProperties{}, nil
 }
 
-func (m *mockECHONETListClient) SetProperties(_ echonet_lite.IPAndEOJ, _ echonet_lite.Properties) (handler.DeviceAndProperties, error) {
+func (m *mockECHONETListClient) SetProperties(_ echonet_lite.IPAndEOJ, _ echonet_lite.Properties, _ []echonet_lite.EPCType) (handler.DeviceAndProperties, error) {
 	return handler.DeviceAndProperties{}, nil
 }
 
+func (m *mockECHONETListClient) SetGetProperties(_ echonet_lite.IPAndEOJ, _ echonet_lite.Properties, _ []echonet_lite.EPCType) (handler.SetGetResult, error) {
+	return handler.SetGetResult{}, nil
+}
+
 func (m *mockECHONETListClient) GetDeviceHistory(device echonet_lite.IPAndEOJ, opts client.DeviceHistoryOptions) ([]client.DeviceHistoryEntry, error) {
 	return []client.DeviceHistoryEntry{}, nil
 }
@@ -140,10 +145,82 @@ func (m *mockECHONETListClient) GetDevicesByGroup(_ string) ([]handler.IDString,
 	return nil, false
 }
 
+func (m *mockECHONETListClient) SceneList(_ *string) []handler.SceneActionsPair {
+	return nil
+}
+
+func (m *mockECHONETListClient) SceneAdd(_ string, _ []handler.SceneAction) error {
+	return nil
+}
+
+func (m *mockECHONETListClient) SceneDelete(_ string) error {
+	return nil
+}
+
+func (m *mockECHONETListClient) GetScene(_ string) ([]handler.SceneAction, bool) {
+	return nil, false
+}
+
+func (m *mockECHONETListClient) SceneSetSequence(_ string, _ handler.SceneSequence) error {
+	return nil
+}
+
+func (m *mockECHONETListClient) GetSceneSequence(_ string) handler.SceneSequence {
+	return handler.SceneSequence{}
+}
+
+func (m *mockECHONETListClient) SceneRun(_ string) ([]handler.SceneRunResult, error) {
+	return nil, nil
+}
+
+func (m *mockECHONETListClient) ScheduleList() []handler.Schedule {
+	return nil
+}
+
+func (m *mockECHONETListClient) ScheduleSet(_ handler.Schedule) error {
+	return nil
+}
+
+func (m *mockECHONETListClient) ScheduleDelete(_ string) error {
+	return nil
+}
+
+func (m *mockECHONETListClient) ScheduleSetDisabled(_ string, _ bool) error {
+	return nil
+}
+
+func (m *mockECHONETListClient) LocalPropertyMaps() ([]client.LocalPropertyMap, error) {
+	return nil, nil
+}
+
+func (m *mockECHONETListClient) SetLocalPropertyMap(_ client.EOJ, _ client.PropertyMapType, _ []client.EPCType) error {
+	return nil
+}
+
+func (m *mockECHONETListClient) PollExclusions() ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockECHONETListClient) SetPollExcluded(_ string, _ bool) error {
+	return nil
+}
+
+func (m *mockECHONETListClient) ScheduleNextRun(schedule client.Schedule, after time.Time) time.Time {
+	return schedule.NextRun(after, nil)
+}
+
 func (m *mockECHONETListClient) DebugSetOffline(_ string, _ bool) error {
 	return nil
 }
 
+func (m *mockECHONETListClient) DebugPendingRequests() ([]client.PendingRequest, error) {
+	return nil, nil
+}
+
+func (m *mockECHONETListClient) DebugCancelRequest(_ uint64) error {
+	return nil
+}
+
 func (m *mockECHONETListClient) IsOfflineDevice(_ echonet_lite.IPAndEOJ) bool {
 	return false
 }
@@ -152,6 +229,11 @@ func (m *mockECHONETListClient) Close() error {
 	return nil
 }
 
+func (m *mockECHONETListClient) WatchPropertyChanges() (<-chan client.PropertyChangeNotification, func()) {
+	ch := make(chan client.PropertyChangeNotification)
+	return ch, func() {}
+}
+
 func (m *mockECHONETListClient) FindDeviceByIDString(_ handler.IDString) *echonet_lite.IPAndEOJ {
 	return nil
 }
@@ -160,6 +242,14 @@ func (m *mockECHONETListClient) GetIDString(_ echonet_lite.IPAndEOJ) handler.IDS
 	return ""
 }
 
+func (m *mockECHONETListClient) CleanupDevices(_ int, _ bool) ([]handler.StaleDevice, error) {
+	return nil, nil
+}
+
+func (m *mockECHONETListClient) RefreshPropertyMap(_ echonet_lite.IPAndEOJ) (handler.PropertyMapChange, error) {
+	return handler.PropertyMapChange{}, nil
+}
+
 func (m *mockECHONETListClient) GetLocationSettings() (map[string]string, []string) {
 	return nil, nil
 }
@@ -340,7 +430,7 @@ func TestHandleGetPropertyDescriptionFromClient(t *testing.T) {
 				RequestID: "test-request-id",
 			}
 
-			responsePayload := ws.handleGetPropertyDescriptionFromClient(msg)
+			responsePayload := ws.handleGetPropertyDescriptionFromClient("", msg)
 
 			// 成功ステータスを確認
 			if responsePayload.Success != tt.wantStatus {
@@ -543,7 +633,7 @@ func TestHandleGetPropertyDescriptionWithShortNames(t *testing.T) {
 				RequestID: "test-request-id",
 			}
 
-			responsePayload := ws.handleGetPropertyDescriptionFromClient(msg)
+			responsePayload := ws.handleGetPropertyDescriptionFromClient("", msg)
 
 			if !responsePayload.Success {
 				t.Fatalf("Request failed: %v", responsePayload.Error)
@@ -573,3 +663,44 @@ func TestHandleGetPropertyDescriptionWithShortNames(t *testing.T) {
 		})
 	}
 }
+
+// getAllTestClient は get_properties で要求された EPC を記録するモック
+type getAllTestClient struct {
+	mockECHONETListClient
+	requested [][]echonet_lite.EPCType
+}
+
+func (c *getAllTestClient) GetProperties(device echonet_lite.IPAndEOJ, epcs []echonet_lite.EPCType, _ bool) (handler.DeviceAndProperties, error) {
+	c.requested = append(c.requested, epcs)
+	return handler.DeviceAndProperties{Device: device}, nil
+}
+
+func TestHandleGetPropertiesFromClientAll(t *testing.T) {
+	c := &getAllTestClient{}
+	ws := &WebSocketServer{ctx: context.Background(), echonetClient: c}
+	call := func(payload protocol.GetPropertiesPayload) protocol.CommandResultPayload {
+		t.Helper()
+		data, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ws.handleGetPropertiesFromClient(&protocol.Message{Type: protocol.MessageTypeGetProperties, Payload: data})
+	}
+
+	// all では EPC を指定せずにクライアントへ渡す（GetPropertyMap のすべてを取得する）
+	if cr := call(protocol.GetPropertiesPayload{Targets: []string{"192.168.1.10 0130:1"}, All: true}); !cr.Success {
+		t.Fatalf("expected success, got %+v", cr.Error)
+	}
+	if len(c.requested) != 1 || len(c.requested[0]) != 0 {
+		t.Errorf("unexpected requested EPCs: %v", c.requested)
+	}
+
+	for name, payload := range map[string]protocol.GetPropertiesPayload{
+		"EPCもallもない": {Targets: []string{"192.168.1.10 0130:1"}},
+		"EPCとall":    {Targets: []string{"192.168.1.10 0130:1"}, EPCs: []string{"80"}, All: true},
+	} {
+		if cr := call(payload); cr.Success || cr.Error.Code != protocol.ErrorCodeInvalidParameters {
+			t.Errorf("%s: expected invalid parameters, got %+v", name, cr)
+		}
+	}
+}
@@ -25,7 +25,7 @@ func (m *MockECHONETClientWithUpdateTracking) UpdateProperties(criteria handler.
 	return nil
 }
 
-func (m *MockECHONETClientWithUpdateTracking) SetProperties(device echonet_lite.IPAndEOJ, props echonet_lite.Properties) (handler.DeviceAndProperties, error) {
+func (m *MockECHONETClientWithUpdateTracking) SetProperties(device echonet_lite.IPAndEOJ, props echonet_lite.Properties, _ []echonet_lite.EPCType) (handler.DeviceAndProperties, error) {
 	return handler.DeviceAndProperties{Device: device, Properties: props}, nil
 }
 
@@ -47,7 +47,7 @@ func createTestSetup(t *testing.T, ctx context.Context) (*WebSocketServer, *Mock
 	}
 
 	// Create handler and WebSocket server
-	handlerInstance, err := handler.NewECHONETLiteHandler(ctx, handler.ECHONETLieHandlerOptions{})
+	handlerInstance, err := handler.NewECHONETLiteHandler(ctx, handler.ECHONETLieHandlerOptions{InMemory: true})
 	if err != nil {
 		t.Fatalf("Failed to create handler: %v", err)
 	}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+const (
+	// defaultReplaySpeed replays an hour of history in a minute
+	defaultReplaySpeed = 60
+	// maxReplaySpeed limits how fast a replay may run
+	maxReplaySpeed = 3600
+	// maxReplayDelay shortens long quiet periods, so that a replay does not seem to stall
+	maxReplayDelay = 5 * time.Second
+	// maxReplayEntries limits the entries sent by one replay
+	maxReplayEntries = 10000
+)
+
+// replaySession is a running replay of a client
+type replaySession struct {
+	cancel context.CancelFunc
+}
+
+// replayEntry is a history entry of a device to replay
+type replayEntry struct {
+	device handler.IPAndEOJ
+	entry  handler.DeviceHistoryEntry
+}
+
+// handleReplayHistoryFromClient handles a replay_history message from a client.
+// It replies with the number of entries and replays them in the background to the requesting client only,
+// so that UI behavior can be tested against real past data without affecting other clients.
+func (ws *WebSocketServer) handleReplayHistoryFromClient(connID string, msg *protocol.Message) protocol.CommandResultPayload {
+	var payload protocol.ReplayHistoryPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing replay_history payload: %v", err)
+	}
+
+	if payload.Stop {
+		ws.stopReplay(connID)
+		return SuccessResponse(nil)
+	}
+
+	store := ws.GetHistoryStore()
+	if store == nil {
+		return ErrorResponse(protocol.ErrorCodeFeatureDisabled, "Device history is disabled on this server")
+	}
+	if payload.Since == nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "since is required")
+	}
+	query := handler.HistoryQuery{Since: *payload.Since, Until: time.Now(), SettableOnly: payload.SettableOnly}
+	if payload.Until != nil {
+		query.Until = *payload.Until
+	}
+	if query.Until.Before(query.Since) {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "until must not be before since")
+	}
+	speed := payload.Speed
+	if speed == 0 {
+		speed = defaultReplaySpeed
+	}
+	if speed < 0 || speed > maxReplaySpeed {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "speed must be greater than 0 and at most %d", maxReplaySpeed)
+	}
+
+	var devices []handler.IPAndEOJ
+	if target := strings.TrimSpace(payload.Target); target != "" {
+		device, err := handler.ParseDeviceIdentifier(target)
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid target: %v", err)
+		}
+		if ws.deviceResolver == nil || !ws.deviceResolver(device) {
+			return ErrorResponse(protocol.ErrorCodeTargetNotFound, "Unknown device: %s", target)
+		}
+		devices = append(devices, device)
+	} else {
+		if ws.echonetClient == nil {
+			return ErrorResponse(protocol.ErrorCodeInternalServerError, "Handler is not available")
+		}
+		for _, device := range ws.echonetClient.ListDevices(handler.FilterCriteria{}) {
+			devices = append(devices, device.Device)
+		}
+	}
+
+	var entries []replayEntry
+	for _, device := range devices {
+		for _, entry := range store.Query(device, query) {
+			entries = append(entries, replayEntry{device: device, entry: entry})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].entry.Timestamp.Before(entries[j].entry.Timestamp)
+	})
+	result := protocol.ReplayHistoryResult{Entries: len(entries)}
+	if len(entries) > maxReplayEntries {
+		entries = entries[:maxReplayEntries]
+		result = protocol.ReplayHistoryResult{Entries: maxReplayEntries, Truncated: true}
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling replay_history result: %v", err)
+	}
+
+	// A client runs one replay at a time
+	ws.stopReplay(connID)
+	ctx, cancel := context.WithCancel(ws.ctx)
+	session := &replaySession{cancel: cancel}
+	ws.replays.Store(connID, session)
+	slog.Info("Replaying history", "connID", connID, "entries", len(entries), "since", query.Since, "until", query.Until, "speed", speed)
+	go ws.runReplay(ctx, session, connID, msg.RequestID, entries, speed)
+
+	return SuccessResponse(data)
+}
+
+// runReplay sends the entries to a client, waiting between them for their original interval divided by speed
+func (ws *WebSocketServer) runReplay(ctx context.Context, session *replaySession, connID, requestID string, entries []replayEntry, speed float64) {
+	defer session.cancel()
+	after := time.After
+	if ws.timeProvider != nil {
+		after = ws.timeProvider.After
+	}
+
+	sent := 0
+	cancelled := false
+	for i, e := range entries {
+		if i > 0 {
+			delay := min(time.Duration(float64(e.entry.Timestamp.Sub(entries[i-1].entry.Timestamp))/speed), maxReplayDelay)
+			select {
+			case <-after(delay):
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			cancelled = true
+			break
+		}
+		if err := ws.sendReplayEntry(connID, e); err != nil {
+			if !isClientDisconnectedError(err) {
+				slog.Error("Failed to send replayed entry", "error", err, "connID", connID)
+			}
+			cancelled = true
+			break
+		}
+		sent++
+	}
+
+	// Only forget the replay if it has not been replaced by a newer one
+	ws.replays.CompareAndDelete(connID, session)
+	if err := ws.sendMessageToClient(connID, protocol.MessageTypeReplayFinished, protocol.ReplayFinishedPayload{Entries: sent, Cancelled: cancelled}, requestID); err != nil && !isClientDisconnectedError(err) {
+		slog.Error("Failed to send replay_finished", "error", err, "connID", connID)
+	}
+}
+
+// sendReplayEntry sends a history entry as the notification it was recorded from
+func (ws *WebSocketServer) sendReplayEntry(connID string, e replayEntry) error {
+	timestamp := protocol.ServerTime(e.entry.Timestamp)
+	marker := protocol.ReplayMarker{Replay: true, Timestamp: &timestamp}
+	ip, eoj := e.device.IP.String(), e.device.EOJ.Specifier()
+	switch e.entry.Origin {
+	case handler.HistoryOriginOffline:
+		return ws.sendMessageToClient(connID, protocol.MessageTypeDeviceOffline, protocol.DeviceOfflinePayload{IP: ip, EOJ: eoj, ReplayMarker: marker}, "")
+	case handler.HistoryOriginOnline:
+		return ws.sendMessageToClient(connID, protocol.MessageTypeDeviceOnline, protocol.DeviceOnlinePayload{IP: ip, EOJ: eoj, ReplayMarker: marker}, "")
+	}
+	return ws.sendMessageToClient(connID, protocol.MessageTypePropertyChanged, protocol.PropertyChangedPayload{
+		IP:           ip,
+		EOJ:          eoj,
+		EPC:          fmt.Sprintf("%02X", byte(e.entry.EPC)),
+		Value:        protocol.PropertyDataFromHandlerValue(e.entry.Value),
+		ReplayMarker: marker,
+	}, "")
+}
+
+// stopReplay cancels the running replay of a client
+func (ws *WebSocketServer) stopReplay(connID string) {
+	if session, ok := ws.replays.LoadAndDelete(connID); ok {
+		session.(*replaySession).cancel()
+	}
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// replayTestTransport records the messages sent to clients from any goroutine
+type replayTestTransport struct {
+	mockLocationTransport
+	mu   sync.Mutex
+	sent []protocol.Message
+}
+
+func (m *replayTestTransport) SendMessage(_ string, message []byte) error {
+	var msg protocol.Message
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// waitFor waits until a message of the type has been sent and returns the messages sent so far
+func (m *replayTestTransport) waitFor(t *testing.T, msgType protocol.MessageType) []protocol.Message {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		m.mu.Lock()
+		sent := append([]protocol.Message(nil), m.sent...)
+		m.mu.Unlock()
+		for _, msg := range sent {
+			if msg.Type == msgType {
+				return sent
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s was not sent", msgType)
+	return nil
+}
+
+func TestHandleReplayHistoryFromClient(t *testing.T) {
+	ctx := context.Background()
+	liteHandler, err := handler.NewECHONETLiteHandler(ctx, handler.ECHONETLieHandlerOptions{
+		TestMode:       true,
+		InMemory:       true,
+		HistoryOptions: handler.HistoryOptions{PerDeviceSettableLimit: 10, PerDeviceNonSettableLimit: 10},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer liteHandler.Close()
+
+	aircon := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	c := &restTestClient{}
+	c.devices = []handler.DeviceAndProperties{{Device: aircon}}
+	transport := &replayTestTransport{}
+	ws := &WebSocketServer{
+		ctx:            ctx,
+		handler:        liteHandler,
+		echonetClient:  c,
+		transport:      transport,
+		deviceResolver: func(d handler.IPAndEOJ) bool { return d.Key() == aircon.Key() },
+	}
+
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	store := ws.GetHistoryStore()
+	store.Record(handler.DeviceHistoryEntry{Timestamp: base, Device: aircon, EPC: 0x80, Value: handler.PropertyValue{String: "on"}, Origin: handler.HistoryOriginSet, Settable: true})
+	store.Record(handler.DeviceHistoryEntry{Timestamp: base.Add(time.Minute), Device: aircon, Origin: handler.HistoryOriginOffline})
+	store.Record(handler.DeviceHistoryEntry{Timestamp: base.Add(2 * time.Minute), Device: aircon, EPC: 0xBB, Value: handler.PropertyValue{Number: new(int)}, Origin: handler.HistoryOriginNotification})
+	store.Record(handler.DeviceHistoryEntry{Timestamp: base.Add(time.Hour), Device: aircon, EPC: 0x80, Value: handler.PropertyValue{String: "off"}, Origin: handler.HistoryOriginSet, Settable: true})
+
+	replay := func(payload protocol.ReplayHistoryPayload) protocol.CommandResultPayload {
+		data, _ := json.Marshal(payload)
+		return ws.handleReplayHistoryFromClient("conn", &protocol.Message{Type: protocol.MessageTypeReplayHistory, Payload: data, RequestID: "req-1"})
+	}
+
+	since, until := base, base.Add(10*time.Minute)
+	result := replay(protocol.ReplayHistoryPayload{Since: &since, Until: &until, Speed: maxReplaySpeed})
+	var replayResult protocol.ReplayHistoryResult
+	if !result.Success || json.Unmarshal(result.Data, &replayResult) != nil || replayResult.Entries != 3 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	// Entries arrive oldest first as the notifications they were recorded from
+	sent := transport.waitFor(t, protocol.MessageTypeReplayFinished)
+	wantTypes := []protocol.MessageType{protocol.MessageTypePropertyChanged, protocol.MessageTypeDeviceOffline, protocol.MessageTypePropertyChanged, protocol.MessageTypeReplayFinished}
+	if len(sent) != len(wantTypes) {
+		t.Fatalf("expected %d messages, got %+v", len(wantTypes), sent)
+	}
+	for i, want := range wantTypes {
+		if sent[i].Type != want {
+			t.Errorf("message %d: expected %s, got %s", i, want, sent[i].Type)
+		}
+	}
+	var changed protocol.PropertyChangedPayload
+	if err := json.Unmarshal(sent[0].Payload, &changed); err != nil || !changed.Replay || changed.Timestamp == nil || !changed.Timestamp.Equal(base) || changed.Value.String != "on" {
+		t.Errorf("unexpected replayed change: %s", sent[0].Payload)
+	}
+	var finished protocol.ReplayFinishedPayload
+	if err := json.Unmarshal(sent[3].Payload, &finished); err != nil || finished.Entries != 3 || finished.Cancelled || sent[3].RequestID != "req-1" {
+		t.Errorf("unexpected replay_finished: %+v %s", sent[3], sent[3].Payload)
+	}
+
+	// A replay can be stopped; long gaps are shortened but still take a while at a low speed
+	transport.mu.Lock()
+	transport.sent = nil
+	transport.mu.Unlock()
+	since = base.Add(2 * time.Minute)
+	until = base.Add(time.Hour)
+	if result := replay(protocol.ReplayHistoryPayload{Since: &since, Until: &until, Speed: 1}); !result.Success {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	transport.waitFor(t, protocol.MessageTypePropertyChanged)
+	if result := replay(protocol.ReplayHistoryPayload{Stop: true}); !result.Success {
+		t.Fatalf("unexpected stop result: %+v", result)
+	}
+	sent = transport.waitFor(t, protocol.MessageTypeReplayFinished)
+	if err := json.Unmarshal(sent[len(sent)-1].Payload, &finished); err != nil || finished.Entries != 1 || !finished.Cancelled {
+		t.Errorf("unexpected replay_finished after stop: %s", sent[len(sent)-1].Payload)
+	}
+
+	for _, payload := range []protocol.ReplayHistoryPayload{
+		{},
+		{Since: &until, Until: &since},
+		{Since: &since, Speed: -1},
+		{Since: &since, Speed: maxReplaySpeed + 1},
+		{Since: &since, Target: "192.168.1.99 0130:1"},
+	} {
+		if result := replay(payload); result.Success {
+			t.Errorf("expected an error for %+v", payload)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"encoding/json"
+	"sort"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// sceneClassCode resolves the class code of a device referenced by a scene
+func (ws *WebSocketServer) sceneClassCode(id handler.IDString) (echonet_lite.EOJClassCode, bool) {
+	device := ws.echonetClient.FindDeviceByIDString(id)
+	if device == nil {
+		return 0, false
+	}
+	return device.EOJ.ClassCode(), true
+}
+
+// sceneActionsFromProtocol converts the actions of a manage_scene payload.
+// Values may be given as EDT, string or number, so every device must be known to resolve its class code.
+func (ws *WebSocketServer) sceneActionsFromProtocol(devices []protocol.SceneDeviceProperties) ([]handler.SceneAction, protocol.CommandResultPayload, bool) {
+	actions := make([]handler.SceneAction, 0)
+	for _, device := range devices {
+		if len(device.Properties) == 0 {
+			return nil, ErrorResponse(protocol.ErrorCodeInvalidParameters, "No properties specified for device: %s", device.Device), false
+		}
+		classCode, ok := ws.sceneClassCode(device.Device)
+		if !ok {
+			return nil, ErrorResponse(protocol.ErrorCodeInvalidParameters, "Device not found: %s", device.Device), false
+		}
+		properties, err := propertiesFromProtocol(classCode, device.Properties)
+		if err != nil {
+			return nil, ErrorResponse(protocol.ErrorCodeInvalidParameters, "%v", err), false
+		}
+		sort.Slice(properties, func(i, j int) bool { return properties[i].EPC < properties[j].EPC })
+		for _, prop := range properties {
+			actions = append(actions, handler.SceneAction{Device: device.Device, EPC: prop.EPC, EDT: prop.EDT})
+		}
+	}
+	return actions, protocol.CommandResultPayload{}, true
+}
+
+// sceneSequencePayload returns the sequence of a scene for notifications, or nil when the devices are set concurrently
+func (ws *WebSocketServer) sceneSequencePayload(sceneName string) *handler.SceneSequence {
+	sequence := ws.echonetClient.GetSceneSequence(sceneName)
+	if sequence.IsZero() {
+		return nil
+	}
+	return &sequence
+}
+
+// handleManageSceneFromClient handles a manage_scene message from a client
+func (ws *WebSocketServer) handleManageSceneFromClient(msg *protocol.Message) protocol.CommandResultPayload {
+	// Parse the payload
+	var payload protocol.ManageScenePayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing manage_scene payload: %v", err)
+	}
+
+	switch payload.Action {
+	case protocol.SceneActionAdd:
+		if payload.Scene == "" {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No scene specified")
+		}
+		if len(payload.Actions) == 0 {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No actions specified for add action")
+		}
+
+		actions, errResult, ok := ws.sceneActionsFromProtocol(payload.Actions)
+		if !ok {
+			return errResult
+		}
+
+		if err := ws.echonetClient.SceneAdd(payload.Scene, actions); err != nil {
+			return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error adding actions to scene: %v", err)
+		}
+
+		// Broadcast scene changed notification
+		updatedActions, _ := ws.echonetClient.GetScene(payload.Scene)
+		_ = ws.broadcastMessageToClients(protocol.MessageTypeSceneChanged, protocol.SceneChangedPayload{
+			ChangeType: protocol.SceneChangeTypeUpdated,
+			Scene:      payload.Scene,
+			Actions:    protocol.SceneActionsToProtocol(updatedActions, ws.sceneClassCode),
+			Sequence:   ws.sceneSequencePayload(payload.Scene),
+		})
+
+		return SuccessResponse(nil)
+
+	case protocol.SceneActionSetSequence:
+		if payload.Scene == "" {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No scene specified")
+		}
+		// A missing sequence clears it, like an empty one
+		var sequence handler.SceneSequence
+		if payload.Sequence != nil {
+			sequence = *payload.Sequence
+		}
+		if err := sequence.Validate(); err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "%v", err)
+		}
+		actions, ok := ws.echonetClient.GetScene(payload.Scene)
+		if !ok {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Scene not found: %s", payload.Scene)
+		}
+
+		if err := ws.echonetClient.SceneSetSequence(payload.Scene, sequence); err != nil {
+			return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error setting scene sequence: %v", err)
+		}
+
+		_ = ws.broadcastMessageToClients(protocol.MessageTypeSceneChanged, protocol.SceneChangedPayload{
+			ChangeType: protocol.SceneChangeTypeUpdated,
+			Scene:      payload.Scene,
+			Actions:    protocol.SceneActionsToProtocol(actions, ws.sceneClassCode),
+			Sequence:   ws.sceneSequencePayload(payload.Scene),
+		})
+
+		return SuccessResponse(nil)
+
+	case protocol.SceneActionGetSequence:
+		if payload.Scene == "" {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No scene specified")
+		}
+		if _, ok := ws.echonetClient.GetScene(payload.Scene); !ok {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Scene not found: %s", payload.Scene)
+		}
+
+		data, err := json.Marshal(ws.echonetClient.GetSceneSequence(payload.Scene))
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling scene sequence: %v", err)
+		}
+		return SuccessResponse(data)
+
+	case protocol.SceneActionDelete:
+		if payload.Scene == "" {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No scene specified")
+		}
+
+		if err := ws.echonetClient.SceneDelete(payload.Scene); err != nil {
+			return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error deleting scene: %v", err)
+		}
+
+		// Broadcast scene deleted notification
+		_ = ws.broadcastMessageToClients(protocol.MessageTypeSceneChanged, protocol.SceneChangedPayload{
+			ChangeType: protocol.SceneChangeTypeDeleted,
+			Scene:      payload.Scene,
+		})
+
+		return SuccessResponse(nil)
+
+	case protocol.SceneActionList:
+		var sceneList []handler.SceneActionsPair
+		if payload.Scene != "" {
+			sceneName := payload.Scene
+			sceneList = ws.echonetClient.SceneList(&sceneName)
+		} else {
+			sceneList = ws.echonetClient.SceneList(nil)
+		}
+
+		// Convert to map for JSON response
+		scenes := make(map[string][]protocol.SceneDeviceProperties)
+		for _, scene := range sceneList {
+			scenes[scene.Scene] = protocol.SceneActionsToProtocol(scene.Actions, ws.sceneClassCode)
+		}
+
+		sceneDataJSON, err := json.Marshal(scenes)
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling scene data: %v", err)
+		}
+		return SuccessResponse(sceneDataJSON)
+
+	default:
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Unknown scene action: %s", payload.Action)
+	}
+}
+
+// handleRunSceneFromClient handles a run_scene message from a client.
+// The properties of each device are set concurrently, or one by one when the scene has a sequence;
+// a failure on one device does not stop the others. Each finished device is broadcast as scene_progress.
+func (ws *WebSocketServer) handleRunSceneFromClient(msg *protocol.Message) protocol.CommandResultPayload {
+	var payload protocol.RunScenePayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing run_scene payload: %v", err)
+	}
+	if payload.Scene == "" {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No scene specified")
+	}
+
+	actions, ok := ws.echonetClient.GetScene(payload.Scene)
+	if !ok {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Scene not found: %s", payload.Scene)
+	}
+
+	results := handler.RunSceneActions(actions, ws.echonetClient.FindDeviceByIDString,
+		func(device handler.IPAndEOJ, properties echonet_lite.Properties) (handler.DeviceAndProperties, error) {
+			// Record Set operations before sending, as in set_properties
+			for _, prop := range properties {
+				ws.recordSetResult(device, prop.EPC, protocol.MakePropertyData(device.EOJ.ClassCode(), prop))
+			}
+			result, err := ws.echonetClient.SetProperties(device, properties, nil)
+			if err == nil {
+				ws.scheduleTriggerUpdates(device, properties)
+			}
+			return result, err
+		},
+		handler.SceneRunOptions{
+			Sequence: ws.echonetClient.GetSceneSequence(payload.Scene),
+			Progress: func(r handler.SceneRunResult, completed, total int) {
+				progress := protocol.SceneProgressPayload{
+					Scene:     payload.Scene,
+					Device:    r.ID,
+					Success:   r.Err == nil,
+					Completed: completed,
+					Total:     total,
+				}
+				if r.Err != nil {
+					progress.Error = r.Err.Error()
+				}
+				// アクセス制御が有効な場合は、デバイスを読めるクライアントにだけ通知する
+				_ = ws.broadcastDeviceMessageToClients(r.Device, protocol.MessageTypeSceneProgress, progress)
+			},
+		})
+
+	response := protocol.RunSceneResponse{
+		Scene:   payload.Scene,
+		Results: make([]protocol.RunSceneDeviceResult, 0, len(results)),
+	}
+	for _, r := range results {
+		deviceResult := protocol.RunSceneDeviceResult{Device: r.ID, Success: r.Err == nil}
+		if r.Err != nil {
+			deviceResult.Error = r.Err.Error()
+		} else {
+			deviceResult.Properties = make(protocol.PropertyMap)
+			for _, prop := range r.Result.Properties {
+				deviceResult.Properties.Set(prop.EPC, protocol.MakePropertyData(r.Device.EOJ.ClassCode(), prop))
+			}
+		}
+		response.Results = append(response.Results, deviceResult)
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling scene result: %v", err)
+	}
+	return SuccessResponse(data)
+}
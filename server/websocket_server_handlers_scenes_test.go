@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// sceneTestClient はシーンのテスト用に、シーンを保持して設定の順序を記録するモック
+type sceneTestClient struct {
+	mockECHONETListClient
+	scenes   *handler.DeviceScenes
+	devices  map[handler.IDString]handler.IPAndEOJ
+	mu       sync.Mutex // 順序を指定しないシーンでは並列に設定される
+	setOrder []string
+}
+
+func (c *sceneTestClient) GetScene(name string) ([]handler.SceneAction, bool) {
+	return c.scenes.GetScene(name)
+}
+
+func (c *sceneTestClient) SceneSetSequence(name string, sequence handler.SceneSequence) error {
+	return c.scenes.SceneSetSequence(name, sequence)
+}
+
+func (c *sceneTestClient) GetSceneSequence(name string) handler.SceneSequence {
+	return c.scenes.GetSceneSequence(name)
+}
+
+func (c *sceneTestClient) FindDeviceByIDString(id handler.IDString) *echonet_lite.IPAndEOJ {
+	if device, ok := c.devices[id]; ok {
+		return &device
+	}
+	return nil
+}
+
+func (c *sceneTestClient) SetProperties(device echonet_lite.IPAndEOJ, properties echonet_lite.Properties, _ []echonet_lite.EPCType) (handler.DeviceAndProperties, error) {
+	c.mu.Lock()
+	c.setOrder = append(c.setOrder, device.IP.String())
+	c.mu.Unlock()
+	return handler.DeviceAndProperties{Device: device, Properties: properties}, nil
+}
+
+func TestSceneSequence(t *testing.T) {
+	const (
+		heater = handler.IDString("027B01:000005:01")
+		aircon = handler.IDString("013001:000005:01")
+	)
+	c := &sceneTestClient{
+		scenes: handler.NewDeviceScenes(),
+		devices: map[handler.IDString]handler.IPAndEOJ{
+			heater: {IP: net.ParseIP("192.168.1.20"), EOJ: echonet_lite.MakeEOJ(echonet_lite.FloorHeating_ClassCode, 1)},
+			aircon: {IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)},
+		},
+	}
+	if err := c.scenes.SceneAdd("morning", []handler.SceneAction{
+		{Device: aircon, EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}},
+		{Device: heater, EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	transport := &broadcastTransport{}
+	ws := &WebSocketServer{ctx: context.Background(), echonetClient: c, transport: transport, timeProvider: &RealTimeProvider{}}
+
+	manage := func(payload protocol.ManageScenePayload) protocol.CommandResultPayload {
+		t.Helper()
+		data, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ws.handleManageSceneFromClient(&protocol.Message{Type: protocol.MessageTypeManageScene, Payload: data})
+	}
+
+	// 不正な間隔や存在しないシーンは拒否される
+	if cr := manage(protocol.ManageScenePayload{Action: protocol.SceneActionSetSequence, Scene: "morning", Sequence: &handler.SceneSequence{DelayMs: -1}}); cr.Success {
+		t.Error("負の間隔が受け付けられた")
+	}
+	if cr := manage(protocol.ManageScenePayload{Action: protocol.SceneActionSetSequence, Scene: "missing", Sequence: &handler.SceneSequence{DelayMs: 10}}); cr.Success {
+		t.Error("存在しないシーンに設定できた")
+	}
+
+	// 床暖房を先に設定する
+	sequence := handler.SceneSequence{Order: []handler.IDString{heater}, DelayMs: 10}
+	if cr := manage(protocol.ManageScenePayload{Action: protocol.SceneActionSetSequence, Scene: "morning", Sequence: &sequence}); !cr.Success {
+		t.Fatalf("set_sequence failed: %+v", cr)
+	}
+	var changed protocol.Message
+	if len(transport.broadcast) != 1 || json.Unmarshal(transport.broadcast[0], &changed) != nil || changed.Type != protocol.MessageTypeSceneChanged {
+		t.Fatalf("scene_changed が通知されていない: %s", transport.broadcast)
+	}
+	var changedPayload protocol.SceneChangedPayload
+	if err := protocol.ParsePayload(&changed, &changedPayload); err != nil || changedPayload.Sequence == nil || changedPayload.Sequence.DelayMs != 10 {
+		t.Errorf("通知の順序が不正: %+v (%v)", changedPayload, err)
+	}
+
+	cr := manage(protocol.ManageScenePayload{Action: protocol.SceneActionGetSequence, Scene: "morning"})
+	var got handler.SceneSequence
+	if !cr.Success || json.Unmarshal(cr.Data, &got) != nil || got.DelayMs != 10 || len(got.Order) != 1 || got.Order[0] != heater {
+		t.Errorf("get_sequence の結果が不正: %+v %s", cr, cr.Data)
+	}
+
+	// 実行すると順序どおりに設定し、デバイスごとに進捗を通知する
+	transport.broadcast = nil
+	data, _ := json.Marshal(protocol.RunScenePayload{Scene: "morning"})
+	if cr := ws.handleRunSceneFromClient(&protocol.Message{Type: protocol.MessageTypeRunScene, Payload: data}); !cr.Success {
+		t.Fatalf("run_scene failed: %+v", cr)
+	}
+	if len(c.setOrder) != 2 || c.setOrder[0] != "192.168.1.20" || c.setOrder[1] != "192.168.1.10" {
+		t.Errorf("設定の順序が不正: %v", c.setOrder)
+	}
+	var progress []protocol.SceneProgressPayload
+	for _, raw := range transport.broadcast {
+		var msg protocol.Message
+		if err := json.Unmarshal(raw, &msg); err != nil || msg.Type != protocol.MessageTypeSceneProgress {
+			continue
+		}
+		var p protocol.SceneProgressPayload
+		if err := protocol.ParsePayload(&msg, &p); err != nil {
+			t.Fatal(err)
+		}
+		progress = append(progress, p)
+	}
+	if len(progress) != 2 || progress[0].Device != heater || progress[0].Completed != 1 || progress[1].Completed != 2 || progress[1].Total != 2 || !progress[1].Success {
+		t.Errorf("進捗の通知が不正: %+v", progress)
+	}
+}
+
+func TestRunScene_ProgressFollowsAccessRules(t *testing.T) {
+	const (
+		heater = handler.IDString("027B01:000005:01")
+		aircon = handler.IDString("013001:000005:01")
+	)
+	c := &sceneTestClient{
+		scenes: handler.NewDeviceScenes(),
+		devices: map[handler.IDString]handler.IPAndEOJ{
+			heater: {IP: net.ParseIP("192.168.1.20"), EOJ: echonet_lite.MakeEOJ(echonet_lite.FloorHeating_ClassCode, 1)},
+			aircon: {IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)},
+		},
+	}
+	if err := c.scenes.SceneAdd("morning", []handler.SceneAction{
+		{Device: aircon, EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}},
+		{Device: heater, EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x30}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	ac, err := NewAccessControl([]AccessRule{
+		{Name: "admin", Token: "admin-token", Admin: true},
+		{Name: "heater", Token: "heater-token", Read: []string{"192.168.1.20"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &logStreamTestTransport{}
+	ws := &WebSocketServer{ctx: context.Background(), echonetClient: c, transport: transport, timeProvider: &RealTimeProvider{}, access: ac}
+	ws.clientRules.Store("admin", ac.Rule("admin"))
+	ws.clientRules.Store("heater", ac.Rule("heater"))
+
+	data, _ := json.Marshal(protocol.RunScenePayload{Scene: "morning"})
+	if cr := ws.handleRunSceneFromClient(&protocol.Message{Type: protocol.MessageTypeRunScene, Payload: data}); !cr.Success {
+		t.Fatalf("run_scene failed: %+v", cr)
+	}
+
+	// 床暖房しか読めないクライアントには、エアコンの進捗は通知されない
+	progressDevices := func(connID string) []handler.IDString {
+		var devices []handler.IDString
+		for _, msg := range transport.messages(connID) {
+			var p protocol.SceneProgressPayload
+			if msg.Type == protocol.MessageTypeSceneProgress && protocol.ParsePayload(&msg, &p) == nil {
+				devices = append(devices, p.Device)
+			}
+		}
+		return devices
+	}
+	if got := progressDevices("admin"); len(got) != 2 {
+		t.Errorf("admin の進捗の通知が不正: %v", got)
+	}
+	if got := progressDevices("heater"); len(got) != 1 || got[0] != heater {
+		t.Errorf("床暖房のみのクライアントへの進捗の通知が不正: %v", got)
+	}
+}
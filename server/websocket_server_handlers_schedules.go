@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"time"
+
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// scheduleToProtocol converts a schedule for clients, including its next run time
+func (ws *WebSocketServer) scheduleToProtocol(schedule handler.Schedule) protocol.ScheduleData {
+	return protocol.ScheduleToProtocol(schedule, ws.sceneClassCode, ws.echonetClient.ScheduleNextRun(schedule, time.Now()))
+}
+
+// broadcastScheduleUpdated notifies clients of the current state of a schedule
+func (ws *WebSocketServer) broadcastScheduleUpdated(name string) {
+	for _, schedule := range ws.echonetClient.ScheduleList() {
+		if schedule.Name != name {
+			continue
+		}
+		data := ws.scheduleToProtocol(schedule)
+		_ = ws.broadcastMessageToClients(protocol.MessageTypeScheduleChanged, protocol.ScheduleChangedPayload{
+			ChangeType: protocol.ScheduleChangeTypeUpdated,
+			Name:       name,
+			Schedule:   &data,
+		})
+		return
+	}
+}
+
+// handleManageScheduleFromClient handles a manage_schedule message from a client
+func (ws *WebSocketServer) handleManageScheduleFromClient(msg *protocol.Message) protocol.CommandResultPayload {
+	// Parse the payload
+	var payload protocol.ManageSchedulePayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing manage_schedule payload: %v", err)
+	}
+
+	switch payload.Action {
+	case protocol.ScheduleActionSet:
+		if payload.Schedule == nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No schedule specified for set action")
+		}
+
+		schedule := handler.Schedule{
+			Name:     payload.Schedule.Name,
+			Cron:     payload.Schedule.Cron,
+			Scene:    payload.Schedule.Scene,
+			Disabled: payload.Schedule.Disabled,
+		}
+		if len(payload.Schedule.Actions) > 0 {
+			actions, errResult, ok := ws.sceneActionsFromProtocol(payload.Schedule.Actions)
+			if !ok {
+				return errResult
+			}
+			schedule.Actions = actions
+		}
+		if schedule.Scene != "" {
+			if _, ok := ws.echonetClient.GetScene(schedule.Scene); !ok {
+				return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Scene not found: %s", schedule.Scene)
+			}
+		}
+		if err := schedule.Validate(); err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "%v", err)
+		}
+
+		if err := ws.echonetClient.ScheduleSet(schedule); err != nil {
+			return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error setting schedule: %v", err)
+		}
+
+		// Broadcast schedule changed notification
+		ws.broadcastScheduleUpdated(schedule.Name)
+		return SuccessResponse(nil)
+
+	case protocol.ScheduleActionEnable, protocol.ScheduleActionDisable:
+		if payload.Name == "" {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No schedule name specified")
+		}
+
+		disabled := payload.Action == protocol.ScheduleActionDisable
+		if err := ws.echonetClient.ScheduleSetDisabled(payload.Name, disabled); err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Error updating schedule: %v", err)
+		}
+
+		ws.broadcastScheduleUpdated(payload.Name)
+		return SuccessResponse(nil)
+
+	case protocol.ScheduleActionDelete:
+		if payload.Name == "" {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "No schedule name specified")
+		}
+
+		if err := ws.echonetClient.ScheduleDelete(payload.Name); err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Error deleting schedule: %v", err)
+		}
+
+		// Broadcast schedule deleted notification
+		_ = ws.broadcastMessageToClients(protocol.MessageTypeScheduleChanged, protocol.ScheduleChangedPayload{
+			ChangeType: protocol.ScheduleChangeTypeDeleted,
+			Name:       payload.Name,
+		})
+		return SuccessResponse(nil)
+
+	case protocol.ScheduleActionList:
+		schedules := ws.echonetClient.ScheduleList()
+		result := make([]protocol.ScheduleData, 0, len(schedules))
+		for _, schedule := range schedules {
+			result = append(result, ws.scheduleToProtocol(schedule))
+		}
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling schedule data: %v", err)
+		}
+		return SuccessResponse(data)
+
+	default:
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Unknown schedule action: %s", payload.Action)
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"slices"
 	"testing"
 
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
 	"echonet-list/protocol"
 )
 
@@ -161,3 +164,41 @@ func TestHandleSetPropertiesFromClient(t *testing.T) {
 		})
 	}
 }
+
+// orderedSetTestClient は、EPC の大きい順に設定したものとして結果を返すモック
+type orderedSetTestClient struct {
+	mockECHONETListClient
+}
+
+func (c *orderedSetTestClient) SetProperties(device echonet_lite.IPAndEOJ, properties echonet_lite.Properties, _ []echonet_lite.EPCType) (handler.DeviceAndProperties, error) {
+	applied := slices.Clone(properties)
+	slices.SortFunc(applied, func(a, b echonet_lite.Property) int { return int(b.EPC) - int(a.EPC) })
+	return handler.DeviceAndProperties{Device: device, Properties: applied}, nil
+}
+
+func TestHandleSetPropertiesFromClientAppliedOrder(t *testing.T) {
+	ws := &WebSocketServer{ctx: context.Background(), echonetClient: &orderedSetTestClient{}, timeProvider: &RealTimeProvider{}}
+	data, err := json.Marshal(protocol.SetPropertiesPayload{
+		Target:     "192.168.1.10 0130:1",
+		Properties: protocol.PropertyMap{"80": {String: "on"}, "B0": {String: "cooling"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr := ws.handleSetPropertiesFromClient(&protocol.Message{Type: protocol.MessageTypeSetProperties, Payload: data})
+	if !cr.Success {
+		t.Fatalf("expected success, got %+v", cr.Error)
+	}
+
+	// 応答はデバイス情報に設定した順序を加えたもの
+	var result protocol.SetPropertiesResult
+	if err := json.Unmarshal(cr.Data, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.IP != "192.168.1.10" || !slices.Equal(result.AppliedOrder, []string{"B0", "80"}) {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if _, ok := result.Properties["80"]; !ok {
+		t.Errorf("device properties are missing: %+v", result.Properties)
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// handleGetPropertyStatisticsFromClient handles a get_property_statistics message from a client.
+// It reports how often each property changed during the last hour and day, most frequent first,
+// and the availability of the devices when an availability window is requested.
+func (ws *WebSocketServer) handleGetPropertyStatisticsFromClient(msg *protocol.Message) protocol.CommandResultPayload {
+	if ws.handler == nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Handler is not available")
+	}
+
+	var payload protocol.GetPropertyStatisticsPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing get_property_statistics payload: %v", err)
+	}
+
+	var device *handler.IPAndEOJ
+	if target := strings.TrimSpace(payload.Target); target != "" {
+		ipAndEOJ, err := handler.ParseDeviceIdentifier(target)
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid target: %v", err)
+		}
+		device = &ipAndEOJ
+	}
+
+	if payload.Limit != nil && *payload.Limit <= 0 {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Limit must be greater than zero")
+	}
+
+	var window time.Duration
+	if payload.AvailabilityWindow != "" {
+		parsed, err := protocol.ParseAvailabilityWindow(payload.AvailabilityWindow)
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "%v", err)
+		}
+		window = parsed
+	}
+
+	stats := ws.handler.GetPropertyChangeStats(device)
+	if payload.Limit != nil && len(stats) > *payload.Limit {
+		stats = stats[:*payload.Limit]
+	}
+
+	entries := make([]protocol.PropertyStatisticsEntry, 0, len(stats))
+	for _, stat := range stats {
+		entries = append(entries, protocol.PropertyStatisticsEntry{
+			Target:     stat.Device.Specifier(),
+			EPC:        fmt.Sprintf("%02X", byte(stat.EPC)),
+			LastHour:   stat.LastHour,
+			LastDay:    stat.LastDay,
+			LastChange: protocol.ServerTime(stat.LastChange),
+		})
+	}
+
+	response := protocol.PropertyStatisticsResponse{Entries: entries}
+	if window > 0 {
+		// Availability is computed from the online/offline events in the device history
+		var availability []handler.DeviceAvailability
+		if device != nil {
+			availability = []handler.DeviceAvailability{ws.handler.GetDeviceAvailability(*device, window)}
+		} else {
+			availability = ws.handler.GetAvailability(window)
+		}
+		if payload.Limit != nil && len(availability) > *payload.Limit {
+			availability = availability[:*payload.Limit]
+		}
+		response.Availability = protocol.AvailabilityToProtocol(availability)
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling statistics data: %v", err)
+	}
+
+	return SuccessResponse(data)
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+const (
+	defaultSummaryEventLimit = 10
+	// faultStatusOccurred is the EDT of EPC 0x88 (fault status) when a fault has occurred.
+	faultStatusOccurred = 0x41
+	// maxValidPowerValue is the largest valid value of EPC 0x84; 0xFFFE and above mean overflow/underflow.
+	maxValidPowerValue = 0xFFFD
+)
+
+// handleGetSummaryFromClient handles a get_summary message from a client.
+// It returns the aggregated information needed by a dashboard in a single response.
+func (ws *WebSocketServer) handleGetSummaryFromClient(msg *protocol.Message) protocol.CommandResultPayload {
+	if ws.handler == nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Handler is not available")
+	}
+
+	var payload protocol.GetSummaryPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing get_summary payload: %v", err)
+	}
+
+	eventLimit := defaultSummaryEventLimit
+	if payload.EventLimit != nil {
+		if *payload.EventLimit < 0 {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "eventLimit must not be negative")
+		}
+		eventLimit = *payload.EventLimit
+	}
+
+	data, err := json.Marshal(ws.buildSummary(eventLimit))
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling summary data: %v", err)
+	}
+
+	return SuccessResponse(data)
+}
+
+// buildSummary aggregates device states, client count and recent history events.
+// Node profile objects are not counted as devices.
+func (ws *WebSocketServer) buildSummary(eventLimit int) protocol.SummaryResponse {
+	summary := protocol.SummaryResponse{
+		FaultDevices:  []string{},
+		ActiveClients: int(ws.activeClients.Load()),
+		RecentEvents:  []protocol.SummaryEvent{},
+		Persistence:   protocol.PersistenceStatusToProtocol(ws.handler.PersistenceStatus()),
+		Startup:       protocol.StartupReportToProtocol(ws.handler.StartupReport()),
+	}
+
+	historyStore := ws.GetHistoryStore()
+	var events []handler.DeviceHistoryEntry
+	totalPower := 0
+	hasPower := false
+
+	for _, device := range ws.handler.ListDevices(handler.FilterCriteria{ExcludeOffline: false}) {
+		if historyStore != nil && eventLimit > 0 {
+			events = append(events, historyStore.Query(device.Device, handler.HistoryQuery{Limit: eventLimit})...)
+		}
+
+		if device.Device.EOJ.ClassCode() == echonet_lite.NodeProfile_ClassCode {
+			continue
+		}
+
+		summary.Devices.Total++
+		if ws.handler.IsOffline(device.Device) {
+			summary.Devices.Offline++
+			continue
+		}
+		summary.Devices.Online++
+
+		if fault, ok := device.Properties.FindEPC(echonet_lite.EPCFaultStatus); ok && len(fault.EDT) == 1 && fault.EDT[0] == faultStatusOccurred {
+			summary.Devices.Faults++
+			summary.FaultDevices = append(summary.FaultDevices, device.Device.Specifier())
+		}
+
+		if power, ok := device.Properties.FindEPC(echonet_lite.EPCMeasuredInstantaneousPowerConsumption); ok && len(power.EDT) == 2 {
+			if value := binary.BigEndian.Uint16(power.EDT); value <= maxValidPowerValue {
+				totalPower += int(value)
+				hasPower = true
+			}
+		}
+	}
+
+	if hasPower {
+		summary.TotalPower = &totalPower
+	}
+
+	// Newest first across all devices
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.After(events[j].Timestamp)
+	})
+	if len(events) > eventLimit {
+		events = events[:eventLimit]
+	}
+	for _, entry := range events {
+		epcStr := ""
+		if entry.EPC != 0 {
+			epcStr = fmt.Sprintf("%02X", byte(entry.EPC))
+		}
+		summary.RecentEvents = append(summary.RecentEvents, protocol.SummaryEvent{
+			Timestamp: protocol.ServerTime(entry.Timestamp),
+			Target:    entry.Device.Specifier(),
+			EPC:       epcStr,
+			Value:     protocol.PropertyDataFromHandlerValue(entry.Value),
+			Origin:    protocol.HistoryOrigin(entry.Origin),
+		})
+	}
+
+	return summary
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+func TestHandleGetSummaryFromClient(t *testing.T) {
+	ctx := context.Background()
+
+	liteHandler, err := handler.NewECHONETLiteHandler(ctx, handler.ECHONETLieHandlerOptions{TestMode: true})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer liteHandler.Close()
+
+	aircon := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	light := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.11"), EOJ: echonet_lite.MakeEOJ(0x0291, 1)}
+	offline := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.12"), EOJ: echonet_lite.MakeEOJ(0x0291, 1)}
+	nodeProfile := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.NodeProfileObject}
+
+	dataHandler := liteHandler.GetDataManagementHandler()
+	for _, d := range []handler.IPAndEOJ{aircon, light, offline, nodeProfile} {
+		dataHandler.RegisterDevice(d)
+	}
+	dataHandler.RegisterProperties(aircon, handler.Properties{
+		{EPC: echonet_lite.EPCFaultStatus, EDT: []byte{0x41}},
+		{EPC: echonet_lite.EPCMeasuredInstantaneousPowerConsumption, EDT: []byte{0x01, 0x00}},
+	})
+	dataHandler.RegisterProperties(light, handler.Properties{
+		{EPC: echonet_lite.EPCFaultStatus, EDT: []byte{0x42}},
+		{EPC: echonet_lite.EPCMeasuredInstantaneousPowerConsumption, EDT: []byte{0x00, 0x0A}},
+	})
+	dataHandler.SetOffline(offline, true)
+
+	ws := &WebSocketServer{ctx: ctx, handler: liteHandler}
+	ws.activeClients.Store(2)
+
+	now := time.Now().UTC()
+	ws.GetHistoryStore().Record(handler.DeviceHistoryEntry{
+		Timestamp: now.Add(-time.Minute),
+		Device:    light,
+		EPC:       echonet_lite.EPCOperationStatus,
+		Value:     handler.PropertyValue{String: "on"},
+		Origin:    handler.HistoryOriginSet,
+		Settable:  true,
+	})
+	ws.GetHistoryStore().Record(handler.DeviceHistoryEntry{
+		Timestamp: now,
+		Device:    offline,
+		Origin:    handler.HistoryOriginOffline,
+	})
+
+	eventLimit := 1
+	payloadBytes, _ := json.Marshal(protocol.GetSummaryPayload{EventLimit: &eventLimit})
+	result := ws.handleGetSummaryFromClient(&protocol.Message{Type: protocol.MessageTypeGetSummary, Payload: payloadBytes})
+	if !result.Success {
+		t.Fatalf("get_summary failed: %+v", result.Error)
+	}
+
+	var summary protocol.SummaryResponse
+	if err := json.Unmarshal(result.Data, &summary); err != nil {
+		t.Fatalf("Failed to unmarshal summary: %v", err)
+	}
+
+	expected := protocol.SummaryDeviceCounts{Total: 3, Online: 2, Offline: 1, Faults: 1}
+	if summary.Devices != expected {
+		t.Errorf("Devices = %+v, want %+v", summary.Devices, expected)
+	}
+	if len(summary.FaultDevices) != 1 || summary.FaultDevices[0] != aircon.Specifier() {
+		t.Errorf("FaultDevices = %v", summary.FaultDevices)
+	}
+	if summary.ActiveClients != 2 {
+		t.Errorf("ActiveClients = %d, want 2", summary.ActiveClients)
+	}
+	if summary.TotalPower == nil || *summary.TotalPower != 266 {
+		t.Errorf("TotalPower = %v, want 266", summary.TotalPower)
+	}
+	if len(summary.RecentEvents) != 1 || summary.RecentEvents[0].Origin != protocol.HistoryOriginOffline {
+		t.Errorf("RecentEvents = %+v, want the offline event only", summary.RecentEvents)
+	}
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// cachedProperties returns the cached values of a device, keyed by EPC
+func (ws *WebSocketServer) cachedProperties(device handler.IPAndEOJ) map[echonet_lite.EPCType]echonet_lite.Property {
+	cached := make(map[echonet_lite.EPCType]echonet_lite.Property)
+	for _, dp := range ws.echonetClient.ListDevices(handler.FilterCriteria{Device: handler.DeviceSpecifierFromIPAndEOJ(device)}) {
+		if dp.Device.Key() != device.Key() {
+			continue
+		}
+		for _, prop := range dp.Properties {
+			cached[prop.EPC] = prop
+		}
+	}
+	return cached
+}
+
+// setPropertiesTransaction sets the properties of a set_properties request with transaction.
+// The properties are sent as usual. If the device rejects some of them,
+// the accepted ones are set back to their cached values before the request (best effort),
+// and the outcome of each EPC is reported in the response.
+func (ws *WebSocketServer) setPropertiesTransaction(device handler.IPAndEOJ, properties echonet_lite.Properties, skipValidationEPCs []echonet_lite.EPCType) protocol.CommandResultPayload {
+	classCode := device.EOJ.ClassCode()
+	previous := ws.cachedProperties(device)
+
+	// A communication error means nothing is known to be applied, so there is nothing to roll back
+	deviceAndProps, err := ws.echonetClient.SetProperties(device, properties, skipValidationEPCs)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeEchonetCommunicationError, "Error setting properties: %v", err)
+	}
+
+	applied := make(map[echonet_lite.EPCType]bool, len(deviceAndProps.Properties))
+	for _, prop := range deviceAndProps.Properties {
+		applied[prop.EPC] = true
+	}
+
+	sorted := make(echonet_lite.Properties, len(properties))
+	copy(sorted, properties)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].EPC < sorted[j].EPC })
+
+	result := protocol.SetPropertiesTransactionResult{
+		Outcomes:     make([]protocol.SetPropertyOutcome, len(sorted)),
+		AppliedOrder: protocol.AppliedOrder(deviceAndProps.Properties),
+	}
+	var failed []string
+	for i, prop := range sorted {
+		outcome := protocol.SetPropertyOutcome{
+			EPC:     prop.EPC.String(),
+			Outcome: protocol.PropertyOutcomeApplied,
+			Value:   protocol.MakePropertyData(classCode, prop),
+		}
+		if prev, ok := previous[prop.EPC]; ok {
+			value := protocol.MakePropertyData(classCode, prev)
+			outcome.Previous = &value
+		}
+		if !applied[prop.EPC] {
+			outcome.Outcome = protocol.PropertyOutcomeFailed
+			failed = append(failed, prop.EPC.String())
+		}
+		result.Outcomes[i] = outcome
+	}
+
+	if len(failed) == 0 {
+		ws.scheduleTriggerUpdates(device, properties)
+
+		lastSeen := ws.handler.GetLastUpdateTime(deviceAndProps.Device)
+		var isOffline bool
+		if ws.handler != nil {
+			isOffline = ws.handler.IsOffline(deviceAndProps.Device)
+		}
+		deviceData := protocol.DeviceToProtocol(deviceAndProps.Device, deviceAndProps.Properties, lastSeen, isOffline)
+		result.Committed = true
+		result.Device = &deviceData
+		return transactionResponse(result)
+	}
+
+	// Set the applied properties back to their previous values
+	var rollback echonet_lite.Properties
+	for i, prop := range sorted {
+		if !applied[prop.EPC] {
+			continue
+		}
+		prev, ok := previous[prop.EPC]
+		if !ok {
+			result.Outcomes[i].Outcome = protocol.PropertyOutcomeNotRolledBack
+			continue
+		}
+		ws.recordSetResult(device, prev.EPC, protocol.MakePropertyData(classCode, prev))
+		rollback = append(rollback, prev)
+	}
+	if len(rollback) > 0 {
+		restored := make(map[echonet_lite.EPCType]bool, len(rollback))
+		rolledBack, err := ws.echonetClient.SetProperties(device, rollback, skipValidationEPCs)
+		if err != nil {
+			slog.Error("Failed to roll back set_properties transaction", "device", device.Specifier(), "err", err)
+		}
+		for _, prop := range rolledBack.Properties {
+			restored[prop.EPC] = true
+		}
+		for i, prop := range sorted {
+			if !applied[prop.EPC] || result.Outcomes[i].Outcome == protocol.PropertyOutcomeNotRolledBack {
+				continue
+			}
+			if restored[prop.EPC] {
+				result.Outcomes[i].Outcome = protocol.PropertyOutcomeRolledBack
+			} else {
+				result.Outcomes[i].Outcome = protocol.PropertyOutcomeRollbackFailed
+			}
+		}
+	}
+
+	response := transactionResponse(result)
+	if response.Success {
+		response.Success = false
+		response.Error = &protocol.Error{
+			Code:    protocol.ErrorCodeEchonetDeviceError,
+			Message: "Transaction rolled back: the device rejected EPC " + strings.Join(failed, ", "),
+		}
+	}
+	return response
+}
+
+// transactionResponse marshals the result of a set_properties transaction into a success response
+func transactionResponse(result protocol.SetPropertiesTransactionResult) protocol.CommandResultPayload {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling transaction result: %v", err)
+	}
+	return SuccessResponse(data)
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// transactionTestClient はトランザクションのテスト用に、一部の EPC の設定を拒否するモック
+type transactionTestClient struct {
+	mockECHONETListClient
+	device handler.IPAndEOJ
+	cached echonet_lite.Properties
+	reject map[echonet_lite.EPCType]bool
+	calls  []echonet_lite.Properties
+
+	rejectRollback bool // 2回目以降の設定（元に戻す設定）をすべて拒否する
+}
+
+func (c *transactionTestClient) ListDevices(_ handler.FilterCriteria) []handler.DeviceAndProperties {
+	return []handler.DeviceAndProperties{{Device: c.device, Properties: c.cached}}
+}
+
+func (c *transactionTestClient) SetProperties(device echonet_lite.IPAndEOJ, properties echonet_lite.Properties, _ []echonet_lite.EPCType) (handler.DeviceAndProperties, error) {
+	c.calls = append(c.calls, properties)
+	var accepted echonet_lite.Properties
+	if c.rejectRollback && len(c.calls) > 1 {
+		return handler.DeviceAndProperties{Device: device}, nil
+	}
+	for _, prop := range properties {
+		if !c.reject[prop.EPC] {
+			accepted = append(accepted, prop)
+		}
+	}
+	return handler.DeviceAndProperties{Device: device, Properties: accepted}, nil
+}
+
+func TestSetPropertiesTransaction(t *testing.T) {
+	aircon := echonet_lite.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	newServer := func(reject ...echonet_lite.EPCType) (*WebSocketServer, *transactionTestClient) {
+		c := &transactionTestClient{
+			device: aircon,
+			// 動作状態と温度設定値はキャッシュにあり、運転モードはない
+			cached: echonet_lite.Properties{
+				{EPC: echonet_lite.EPCOperationStatus, EDT: []byte{0x31}},
+				{EPC: echonet_lite.EPC_HAC_TemperatureSetting, EDT: []byte{20}},
+			},
+			reject: make(map[echonet_lite.EPCType]bool),
+		}
+		for _, epc := range reject {
+			c.reject[epc] = true
+		}
+		ws := &WebSocketServer{ctx: context.Background(), echonetClient: c, timeProvider: &RealTimeProvider{}}
+		return ws, c
+	}
+	call := func(ws *WebSocketServer) (protocol.CommandResultPayload, protocol.SetPropertiesTransactionResult) {
+		t.Helper()
+		data, err := json.Marshal(protocol.SetPropertiesPayload{
+			Target: "192.168.1.10 0130:1",
+			Properties: protocol.PropertyMap{
+				"80": {String: "on"},
+				"B0": {String: "cooling"},
+				"B3": {Number: intPtr(26)},
+			},
+			Transaction: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cr := ws.handleSetPropertiesFromClient(&protocol.Message{Type: protocol.MessageTypeSetProperties, Payload: data})
+		var result protocol.SetPropertiesTransactionResult
+		if err := json.Unmarshal(cr.Data, &result); err != nil {
+			t.Fatalf("transaction result is missing: %v (%+v)", err, cr)
+		}
+		return cr, result
+	}
+	outcomes := func(result protocol.SetPropertiesTransactionResult) map[string]protocol.PropertyOutcome {
+		m := make(map[string]protocol.PropertyOutcome)
+		for _, o := range result.Outcomes {
+			m[o.EPC] = o.Outcome
+		}
+		return m
+	}
+
+	// すべて受け付けられるとコミットされる
+	ws, c := newServer()
+	cr, result := call(ws)
+	if !cr.Success || !result.Committed || result.Device == nil {
+		t.Fatalf("expected committed transaction, got %+v %+v", cr, result)
+	}
+	if len(result.Outcomes) != 3 || result.Outcomes[0].EPC != "80" || result.Outcomes[2].EPC != "B3" {
+		t.Errorf("outcomes must be in EPC order: %+v", result.Outcomes)
+	}
+	for epc, outcome := range outcomes(result) {
+		if outcome != protocol.PropertyOutcomeApplied {
+			t.Errorf("EPC %s: outcome = %s, want applied", epc, outcome)
+		}
+	}
+	if len(c.calls) != 1 {
+		t.Errorf("expected a single set request, got %d", len(c.calls))
+	}
+
+	// 温度設定値が拒否されると、動作状態は元の値に戻され、キャッシュのない運転モードは戻せない
+	ws, c = newServer(echonet_lite.EPC_HAC_TemperatureSetting)
+	cr, result = call(ws)
+	if cr.Success || cr.Error == nil || cr.Error.Code != protocol.ErrorCodeEchonetDeviceError {
+		t.Fatalf("expected device error, got %+v", cr)
+	}
+	if result.Committed || result.Device != nil {
+		t.Errorf("transaction must not be committed: %+v", result)
+	}
+	want := map[string]protocol.PropertyOutcome{
+		"80": protocol.PropertyOutcomeRolledBack,
+		"B0": protocol.PropertyOutcomeNotRolledBack,
+		"B3": protocol.PropertyOutcomeFailed,
+	}
+	if got := outcomes(result); len(got) != len(want) || got["80"] != want["80"] || got["B0"] != want["B0"] || got["B3"] != want["B3"] {
+		t.Errorf("outcomes = %v, want %v", got, want)
+	}
+	if prev := result.Outcomes[0].Previous; prev == nil || prev.String != "off" {
+		t.Errorf("previous value of 80 = %+v", prev)
+	}
+	if len(c.calls) != 2 || len(c.calls[1]) != 1 || c.calls[1][0].EPC != echonet_lite.EPCOperationStatus || c.calls[1][0].EDT[0] != 0x31 {
+		t.Errorf("unexpected rollback request: %+v", c.calls)
+	}
+
+	// 元の値に戻す設定も拒否された場合
+	ws, c = newServer(echonet_lite.EPC_HAC_TemperatureSetting)
+	c.rejectRollback = true
+	_, result = call(ws)
+	if got := outcomes(result); got["80"] != protocol.PropertyOutcomeRollbackFailed || got["B3"] != protocol.PropertyOutcomeFailed {
+		t.Errorf("outcomes = %v", got)
+	}
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// devicesMatching returns the devices matching any of the criteria, each device once
+func (ws *WebSocketServer) devicesMatching(criteriaList []handler.FilterCriteria) []handler.IPAndEOJ {
+	// Several targets may match the same device
+	var devices []handler.IPAndEOJ
+	seen := make(map[string]bool)
+	for _, criteria := range criteriaList {
+		for _, device := range ws.echonetClient.ListDevices(criteria) {
+			if key := device.Device.Key(); !seen[key] {
+				seen[key] = true
+				devices = append(devices, device.Device)
+			}
+		}
+	}
+	return devices
+}
+
+// updatePropertiesWithProgress updates the devices matching the criteria and sends an update_progress notification
+// to the requesting client as each device finishes, so that a refresh button can show real progress.
+// Failures are reported per device, so the result is a success with the counts unless no device matches.
+func (ws *WebSocketServer) updatePropertiesWithProgress(connID, requestID string, criteriaList []handler.FilterCriteria, force bool) protocol.CommandResultPayload {
+	if ws.handler == nil || ws.echonetClient == nil {
+		return ErrorResponse(protocol.ErrorCodeFeatureDisabled, "Progress of update_properties is not available")
+	}
+
+	devices := ws.devicesMatching(criteriaList)
+	if len(devices) == 0 {
+		return ErrorResponse(protocol.ErrorCodeTargetNotFound, "No devices matched the targets")
+	}
+	seen := make(map[string]bool, len(devices))
+	for _, device := range devices {
+		seen[device.Key()] = true
+	}
+
+	var mu sync.Mutex
+	result := protocol.UpdatePropertiesResult{Total: len(devices)}
+	reported := make(map[string]bool, len(devices))
+	completed := 0
+	progress := func(update handler.UpdateResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		key := update.Device.Key()
+		if !seen[key] || reported[key] {
+			return
+		}
+		reported[key] = true
+		completed++
+		switch update.Status {
+		case handler.UpdateStatusSucceeded:
+			result.Succeeded++
+		case handler.UpdateStatusFailed:
+			result.Failed++
+		default:
+			result.Skipped++
+		}
+		// Sent while holding the lock, so that completed increases in the order the client receives it
+		payload := protocol.UpdateProgressPayload{
+			RequestID: requestID,
+			IP:        update.Device.IP.String(),
+			EOJ:       update.Device.EOJ.Specifier(),
+			Status:    string(update.Status),
+			Reason:    update.Reason,
+			Completed: completed,
+			Total:     len(devices),
+		}
+		if err := ws.sendMessageToClient(connID, protocol.MessageTypeUpdateProgress, payload, ""); err != nil && !isClientDisconnectedError(err) {
+			slog.Error("Failed to send update_progress", "error", err, "connID", connID)
+		}
+	}
+
+	operationID := "update_properties_" + time.Now().Format("20060102_150405.000")
+	tracker := ws.getOperationTracker()
+	if tracker != nil {
+		tracker.StartOperation(operationID, handler.OperationTypeUpdateProperties,
+			fmt.Sprintf("Property update of %d devices with progress", len(devices)),
+			map[string]interface{}{
+				"source":       "websocket",
+				"device_count": len(devices),
+				"force":        force,
+			})
+	}
+	err := ws.handler.UpdateDevicesWithProgress(devices, force, progress)
+	if tracker != nil {
+		tracker.CompleteOperation(operationID, err == nil, err)
+	}
+	if err != nil {
+		slog.Debug("Property update with progress completed with errors", "devices", len(devices), "error", err)
+	}
+
+	// Every device is reported once, even one the updater left out without a result
+	for _, device := range devices {
+		progress(handler.UpdateResult{Device: device, Status: handler.UpdateStatusSkipped, Reason: "not updated"})
+	}
+
+	mu.Lock()
+	data, err := json.Marshal(result)
+	mu.Unlock()
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling update_properties result: %v", err)
+	}
+	return SuccessResponse(data)
+}
+
+// updatePropertiesAsync updates the devices matching the criteria as a cancellable operation.
+// The devices of a node are updated together, so cancellation takes effect between nodes.
+func (ws *WebSocketServer) updatePropertiesAsync(connID string, criteriaList []handler.FilterCriteria, force bool) protocol.CommandResultPayload {
+	if ws.handler == nil || ws.echonetClient == nil {
+		return ErrorResponse(protocol.ErrorCodeFeatureDisabled, "Async update_properties is not available")
+	}
+	devices := ws.devicesMatching(criteriaList)
+	if len(devices) == 0 {
+		return ErrorResponse(protocol.ErrorCodeTargetNotFound, "No devices matched the targets")
+	}
+
+	var batches [][]handler.IPAndEOJ
+	nodes := make(map[string]int)
+	for _, device := range devices {
+		ip := device.IP.String()
+		i, ok := nodes[ip]
+		if !ok {
+			i = len(batches)
+			nodes[ip] = i
+			batches = append(batches, nil)
+		}
+		batches[i] = append(batches[i], device)
+	}
+
+	return ws.startOperation(connID, protocol.MessageTypeUpdateProperties, len(devices), true, func(op *bulkOperation) error {
+		runOperationBatches(op, batches, func(batch []handler.IPAndEOJ) {
+			err := ws.handler.UpdateDevicesWithProgress(batch, force, func(update handler.UpdateResult) {
+				op.report(update.Device.Specifier(), string(update.Status), update.Reason)
+			})
+			if err != nil {
+				slog.Debug("Async property update completed with errors", "devices", len(batch), "error", err)
+			}
+			for _, device := range batch {
+				op.report(device.Specifier(), operationTargetSkipped, "not updated")
+			}
+		})
+		return nil
+	})
+}
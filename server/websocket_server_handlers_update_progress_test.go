@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+func TestHandleUpdatePropertiesFromClient_Progress(t *testing.T) {
+	ctx := context.Background()
+	liteHandler, err := handler.NewECHONETLiteHandler(ctx, handler.ECHONETLieHandlerOptions{TestMode: true, InMemory: true})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer liteHandler.Close()
+
+	aircon := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	light := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.11"), EOJ: echonet_lite.MakeEOJ(echonet_lite.GeneralLighting_ClassCode, 1)}
+	c := &restTestClient{}
+	c.devices = []handler.DeviceAndProperties{{Device: aircon}, {Device: light}}
+	transport := &replayTestTransport{}
+	ws := &WebSocketServer{ctx: ctx, handler: liteHandler, echonetClient: c, transport: transport}
+
+	request := func(payload protocol.UpdatePropertiesPayload) protocol.CommandResultPayload {
+		data, _ := json.Marshal(payload)
+		return ws.handleUpdatePropertiesFromClient("conn1", &protocol.Message{Type: protocol.MessageTypeUpdateProperties, Payload: data, RequestID: "req-1"})
+	}
+
+	// The same device given twice is updated and reported once
+	result := request(protocol.UpdatePropertiesPayload{Targets: []string{"192.168.1.10 0130:1", "192.168.1.10 0130:1", "192.168.1.11 0290:1"}, Progress: true})
+	if !result.Success {
+		t.Fatalf("update_properties failed: %+v", result.Error)
+	}
+	var counts protocol.UpdatePropertiesResult
+	if err := json.Unmarshal(result.Data, &counts); err != nil {
+		t.Fatal(err)
+	}
+	// The handler of the test mode does not communicate, so both devices are reported as not updated
+	if counts != (protocol.UpdatePropertiesResult{Total: 2, Skipped: 2}) {
+		t.Errorf("unexpected result: %+v", counts)
+	}
+
+	transport.mu.Lock()
+	sent := append([]protocol.Message(nil), transport.sent...)
+	transport.mu.Unlock()
+	if len(sent) != 2 {
+		t.Fatalf("expected 2 update_progress messages, got %d", len(sent))
+	}
+	for i, msg := range sent {
+		if msg.Type != protocol.MessageTypeUpdateProgress || msg.RequestID != "" {
+			t.Errorf("message #%d: type %s, requestId %q", i, msg.Type, msg.RequestID)
+		}
+		var progress protocol.UpdateProgressPayload
+		if err := json.Unmarshal(msg.Payload, &progress); err != nil {
+			t.Fatal(err)
+		}
+		if progress.RequestID != "req-1" || progress.Completed != i+1 || progress.Total != 2 || progress.Status != string(handler.UpdateStatusSkipped) {
+			t.Errorf("unexpected progress #%d: %+v", i, progress)
+		}
+	}
+
+	// No device matched
+	result = request(protocol.UpdatePropertiesPayload{Targets: []string{"192.168.1.99 0130:1"}, Progress: true})
+	if result.Success || result.Error.Code != protocol.ErrorCodeTargetNotFound {
+		t.Errorf("expected TARGET_NOT_FOUND, got %+v", result)
+	}
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// handleGetVersionsFromClient handles a get_versions message from a client.
+// It reports the standard version, manufacturer code and product code of each device from the cached properties,
+// so that it can be audited which ECHONET release each appliance implements.
+func (ws *WebSocketServer) handleGetVersionsFromClient(msg *protocol.Message) protocol.CommandResultPayload {
+	if ws.echonetClient == nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "ECHONET client is not available")
+	}
+
+	var payload protocol.GetVersionsPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing get_versions payload: %v", err)
+	}
+
+	var criteria handler.FilterCriteria
+	if target := strings.TrimSpace(payload.Target); target != "" {
+		device, err := handler.ParseDeviceIdentifier(target)
+		if err != nil {
+			return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Invalid target: %v", err)
+		}
+		criteria.Device = handler.DeviceSpecifierFromIPAndEOJ(device)
+	}
+
+	data, err := json.Marshal(protocol.VersionsResponse{Devices: protocol.MakeDeviceVersions(ws.echonetClient.ListDevices(criteria))})
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling versions data: %v", err)
+	}
+	return SuccessResponse(data)
+}
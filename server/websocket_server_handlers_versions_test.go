@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"echonet-list/client"
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+func TestHandleGetVersionsFromClient(t *testing.T) {
+	ctx := context.Background()
+
+	liteHandler, err := handler.NewECHONETLiteHandler(ctx, handler.ECHONETLieHandlerOptions{TestMode: true})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer liteHandler.Close()
+
+	aircon := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.MakeEOJ(echonet_lite.HomeAirConditioner_ClassCode, 1)}
+	light := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.11"), EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)}
+	nodeProfile := handler.IPAndEOJ{IP: net.ParseIP("192.168.1.10"), EOJ: echonet_lite.NodeProfileObject}
+
+	dataHandler := liteHandler.GetDataManagementHandler()
+	for _, d := range []handler.IPAndEOJ{aircon, light, nodeProfile} {
+		dataHandler.RegisterDevice(d)
+	}
+	dataHandler.RegisterProperties(aircon, handler.Properties{
+		{EPC: echonet_lite.EPCStandardVersion, EDT: []byte{0, 0, 'R', 1}},
+		{EPC: echonet_lite.EPCManufacturerCode, EDT: []byte{0x00, 0x00, 0x0b}},
+	})
+	dataHandler.RegisterProperties(light, handler.Properties{
+		{EPC: echonet_lite.EPCStandardVersion, EDT: []byte{0, 0, 'J', 0}},
+	})
+	dataHandler.RegisterProperties(nodeProfile, handler.Properties{
+		{EPC: echonet_lite.EPCStandardVersion, EDT: []byte{1, 14, 1, 0}},
+	})
+
+	ws := &WebSocketServer{ctx: ctx, handler: liteHandler, echonetClient: client.NewECHONETListClientProxy(liteHandler)}
+
+	getVersions := func(target string) protocol.VersionsResponse {
+		t.Helper()
+		payloadBytes, _ := json.Marshal(protocol.GetVersionsPayload{Target: target})
+		result := ws.handleGetVersionsFromClient(&protocol.Message{Type: protocol.MessageTypeGetVersions, Payload: payloadBytes})
+		if !result.Success {
+			t.Fatalf("get_versions failed: %+v", result.Error)
+		}
+		var versions protocol.VersionsResponse
+		if err := json.Unmarshal(result.Data, &versions); err != nil {
+			t.Fatalf("Failed to unmarshal versions: %v", err)
+		}
+		return versions
+	}
+
+	// Node profiles are excluded
+	versions := getVersions("")
+	if len(versions.Devices) != 2 {
+		t.Fatalf("Devices = %+v, want the air conditioner and the light", versions.Devices)
+	}
+	if got := versions.Devices[0]; got.Target != aircon.Specifier() || got.Release != "R" || got.Revision != 1 || got.ManufacturerCode != "00000B" {
+		t.Errorf("Devices[0] = %+v", got)
+	}
+	if got := versions.Devices[1]; got.Target != light.Specifier() || got.Release != "J" {
+		t.Errorf("Devices[1] = %+v", got)
+	}
+
+	versions = getVersions(light.Specifier())
+	if len(versions.Devices) != 1 || versions.Devices[0].Target != light.Specifier() {
+		t.Errorf("Devices for %s = %+v", light.Specifier(), versions.Devices)
+	}
+
+	payloadBytes, _ := json.Marshal(protocol.GetVersionsPayload{Target: "not a device"})
+	if result := ws.handleGetVersionsFromClient(&protocol.Message{Type: protocol.MessageTypeGetVersions, Payload: payloadBytes}); result.Success {
+		t.Error("expected an error for an invalid target")
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"log/slog"
+
+	"echonet-list/echonet_lite"
+)
+
+// registerClientLanguage remembers the language a client asked for with ?lang= in the WebSocket URL,
+// e.g. ws://host:8080/ws?lang=ja, so that its requests without a lang field get names in that language.
+func (ws *WebSocketServer) registerClientLanguage(connID string) {
+	transport, ok := ws.transport.(*DefaultWebSocketTransport)
+	if !ok {
+		return
+	}
+	query, ok := transport.Query(connID)
+	if !ok || query.Get("lang") == "" {
+		return
+	}
+	lang, err := echonet_lite.ParseLanguage(query.Get("lang"))
+	if err != nil {
+		// Names fall back to English, so an unknown language is not an error for the connection
+		slog.Info("Ignoring the language of a connection", "connID", connID, "err", err)
+		return
+	}
+	ws.clientLangs.Store(connID, lang)
+}
+
+// languageForConnection returns the language of property and class names sent to a client:
+// the lang field of the request, the ?lang= of the connection, or the locale of the server.
+func (ws *WebSocketServer) languageForConnection(connID, requested string) string {
+	if lang := echonet_lite.NormalizeLanguage(requested); lang != "" {
+		return lang
+	}
+	if lang, ok := ws.clientLangs.Load(connID); ok {
+		return lang.(string)
+	}
+	return echonet_lite.DisplayLanguage()
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/protocol"
+)
+
+func TestLanguageForConnection(t *testing.T) {
+	t.Cleanup(func() { _ = echonet_lite.SetDisplayLanguage("") })
+	ws := &WebSocketServer{ctx: context.Background()}
+	ws.clientLangs.Store("ja-client", "ja")
+
+	if got := ws.languageForConnection("other", ""); got != "" {
+		t.Errorf("expected English without any language, got %q", got)
+	}
+	if got := ws.languageForConnection("ja-client", ""); got != "ja" {
+		t.Errorf("expected the language of the connection, got %q", got)
+	}
+	if got := ws.languageForConnection("ja-client", "en-US"); got != "en" {
+		t.Errorf("expected the language of the request, got %q", got)
+	}
+	if err := echonet_lite.SetDisplayLanguage("ja"); err != nil {
+		t.Fatal(err)
+	}
+	if got := ws.languageForConnection("other", ""); got != "ja" {
+		t.Errorf("expected the locale of the server, got %q", got)
+	}
+
+	// get_property_description without lang uses the language of the connection
+	msg := &protocol.Message{Type: protocol.MessageTypeGetPropertyDescription, Payload: json.RawMessage(`{"classCode":""}`)}
+	result := ws.handleGetPropertyDescriptionFromClient("ja-client", msg)
+	if !result.Success {
+		t.Fatalf("unexpected error: %+v", result.Error)
+	}
+	var data protocol.PropertyDescriptionData
+	if err := json.Unmarshal(result.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if got := data.Properties["80"].Description; got != "動作状態" {
+		t.Errorf("expected the Japanese name of 0x80, got %q", got)
+	}
+}
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+const (
+	// operationRetention is how long a finished operation can still be read with get_operation_status
+	operationRetention = 10 * time.Minute
+	// operationWorkers is how many devices (or nodes) an async operation works on at the same time
+	operationWorkers = 4
+)
+
+// Status of one device of a bulk operation
+const (
+	operationTargetSucceeded = "succeeded"
+	operationTargetFailed    = "failed"
+	operationTargetSkipped   = "skipped"
+	operationTargetCancelled = "cancelled"
+)
+
+// bulkOperation is a long-running request started with async.
+// Cancelling its context stops it from starting more devices; requests already sent are completed.
+type bulkOperation struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	owner  string // access rule of the client that started it, empty without access control
+
+	mu        sync.Mutex
+	data      protocol.OperationStatusData
+	reported  map[string]bool // devices with a result
+	cancelled int             // devices not started because of cancellation
+}
+
+// report records the result of a device. A device is only reported once.
+func (op *bulkOperation) report(target, status, reason string) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	if op.reported[target] {
+		return
+	}
+	op.reported[target] = true
+	if status == operationTargetCancelled {
+		op.cancelled++
+	}
+	op.data.Results = append(op.data.Results, protocol.OperationTargetResult{Target: target, Status: status, Reason: reason})
+	op.data.Completed++
+	if op.data.Total > 0 {
+		op.data.Percent = float64(op.data.Completed) * 100 / float64(op.data.Total)
+	}
+}
+
+// finish records the end of the operation. It is cancelled only if cancellation actually left devices out.
+func (op *bulkOperation) finish(err error) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	now := time.Now()
+	op.data.FinishedAt = &now
+	switch {
+	case op.cancelled > 0:
+		op.data.Status = protocol.OperationStatusCancelled
+	case err != nil:
+		op.data.Status = protocol.OperationStatusFailed
+		op.data.Error = err.Error()
+	default:
+		op.data.Status = protocol.OperationStatusCompleted
+		op.data.Percent = 100
+	}
+}
+
+// finished returns whether the operation has ended and when
+func (op *bulkOperation) finished() (time.Time, bool) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	if op.data.FinishedAt == nil {
+		return time.Time{}, false
+	}
+	return *op.data.FinishedAt, true
+}
+
+// snapshot returns a copy of the state of the operation
+func (op *bulkOperation) snapshot() protocol.OperationStatusData {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	data := op.data
+	data.Results = append([]protocol.OperationTargetResult{}, op.data.Results...)
+	return data
+}
+
+// bulkOperations keeps the operations started with async. The zero value is ready to use.
+type bulkOperations struct {
+	mu  sync.Mutex
+	ops map[string]*bulkOperation
+	seq uint64
+}
+
+// add gives the operation an ID and keeps it, forgetting the operations that finished long ago
+func (b *bulkOperations) add(op *bulkOperation) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ops == nil {
+		b.ops = make(map[string]*bulkOperation)
+	}
+	for id, old := range b.ops {
+		if at, ok := old.finished(); ok && time.Since(at) > operationRetention {
+			delete(b.ops, id)
+		}
+	}
+	b.seq++
+	id := fmt.Sprintf("op-%d-%d", time.Now().Unix(), b.seq)
+	op.data.OperationID = id
+	b.ops[id] = op
+	return id
+}
+
+// get returns an operation started by a client with the same access rule
+func (b *bulkOperations) get(id, owner string) (*bulkOperation, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	op, ok := b.ops[id]
+	if !ok || op.owner != owner {
+		return nil, false
+	}
+	return op, true
+}
+
+// operationOwner returns the access rule name a connection's operations belong to
+func (ws *WebSocketServer) operationOwner(connID string) string {
+	if rule := ws.ruleForConnection(connID); rule != nil {
+		return rule.Name
+	}
+	return ""
+}
+
+// operationResponse creates the response holding the state of an operation
+func operationResponse(data protocol.OperationStatusData) protocol.CommandResultPayload {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return ErrorResponse(protocol.ErrorCodeInternalServerError, "Error marshaling operation status: %v", err)
+	}
+	return SuccessResponse(raw)
+}
+
+// startOperation runs an async request in the background and returns the state of the new operation at once
+func (ws *WebSocketServer) startOperation(connID string, msgType protocol.MessageType, total int, cancellable bool, run func(op *bulkOperation) error) protocol.CommandResultPayload {
+	parent := ws.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	op := &bulkOperation{
+		ctx:      ctx,
+		cancel:   cancel,
+		owner:    ws.operationOwner(connID),
+		reported: make(map[string]bool),
+		data: protocol.OperationStatusData{
+			Type:        msgType,
+			Status:      protocol.OperationStatusRunning,
+			Cancellable: cancellable,
+			Total:       total,
+			Results:     []protocol.OperationTargetResult{},
+			StartedAt:   time.Now(),
+		},
+	}
+	ws.operations.add(op)
+	response := operationResponse(op.snapshot())
+
+	go func() {
+		defer cancel()
+		op.finish(run(op))
+	}()
+	return response
+}
+
+// runOperationBatches runs work on each batch of devices, operationWorkers batches at the same time.
+// After the operation is cancelled, the devices of the batches not started yet are reported as cancelled.
+func runOperationBatches(op *bulkOperation, batches [][]handler.IPAndEOJ, work func(batch []handler.IPAndEOJ)) {
+	sem := make(chan struct{}, operationWorkers)
+	var wg sync.WaitGroup
+	for _, batch := range batches {
+		select {
+		case sem <- struct{}{}:
+		case <-op.ctx.Done():
+		}
+		if op.ctx.Err() != nil {
+			for _, device := range batch {
+				op.report(device.Specifier(), operationTargetCancelled, "")
+			}
+			continue
+		}
+		wg.Add(1)
+		go func(batch []handler.IPAndEOJ) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			work(batch)
+		}(batch)
+	}
+	wg.Wait()
+}
+
+// handleGetOperationStatusFromClient handles a get_operation_status message from a client
+func (ws *WebSocketServer) handleGetOperationStatusFromClient(connID string, msg *protocol.Message) protocol.CommandResultPayload {
+	var payload protocol.OperationPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing get_operation_status payload: %v", err)
+	}
+	op, ok := ws.operations.get(payload.OperationID, ws.operationOwner(connID))
+	if !ok {
+		return ErrorResponse(protocol.ErrorCodeTargetNotFound, "Operation not found: %s", payload.OperationID)
+	}
+	return operationResponse(op.snapshot())
+}
+
+// handleCancelOperationFromClient handles a cancel_operation message from a client.
+// The operation stops starting more devices; its status becomes cancelled when the devices in progress finish.
+func (ws *WebSocketServer) handleCancelOperationFromClient(connID string, msg *protocol.Message) protocol.CommandResultPayload {
+	var payload protocol.OperationPayload
+	if err := protocol.ParsePayload(msg, &payload); err != nil {
+		return ErrorResponse(protocol.ErrorCodeInvalidRequestFormat, "Error parsing cancel_operation payload: %v", err)
+	}
+	op, ok := ws.operations.get(payload.OperationID, ws.operationOwner(connID))
+	if !ok {
+		return ErrorResponse(protocol.ErrorCodeTargetNotFound, "Operation not found: %s", payload.OperationID)
+	}
+	if data := op.snapshot(); !data.Cancellable {
+		return ErrorResponse(protocol.ErrorCodeInvalidParameters, "Operation %s (%s) cannot be cancelled", payload.OperationID, data.Type)
+	}
+	op.cancel()
+	return operationResponse(op.snapshot())
+}
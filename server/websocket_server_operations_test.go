@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"echonet-list/echonet_lite"
+	"echonet-list/echonet_lite/handler"
+	"echonet-list/protocol"
+)
+
+// blockingGroupSetTestClient は release が閉じられるまで SetProperties を待たせるモック
+type blockingGroupSetTestClient struct {
+	*groupSetTestClient
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingGroupSetTestClient) SetProperties(device echonet_lite.IPAndEOJ, properties echonet_lite.Properties, skip []echonet_lite.EPCType) (handler.DeviceAndProperties, error) {
+	c.started <- struct{}{}
+	<-c.release
+	return c.groupSetTestClient.SetProperties(device, properties, skip)
+}
+
+// operationCall は操作系のメッセージを送り、結果の操作の状態を返す
+func operationCall(t *testing.T, handle func(string, *protocol.Message) protocol.CommandResultPayload, msgType protocol.MessageType, payload interface{}) (protocol.OperationStatusData, protocol.CommandResultPayload) {
+	t.Helper()
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr := handle("conn-1", &protocol.Message{Type: msgType, Payload: data})
+	var status protocol.OperationStatusData
+	if cr.Success {
+		if err := json.Unmarshal(cr.Data, &status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return status, cr
+}
+
+// waitOperation は操作が終わるまで get_operation_status で待つ
+func waitOperation(t *testing.T, ws *WebSocketServer, id string) protocol.OperationStatusData {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		status, cr := operationCall(t, ws.handleGetOperationStatusFromClient, protocol.MessageTypeGetOperationStatus, protocol.OperationPayload{OperationID: id})
+		if !cr.Success {
+			t.Fatalf("get_operation_status failed: %+v", cr.Error)
+		}
+		if status.Status != protocol.OperationStatusRunning {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("operation %s did not finish: %+v", id, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAsyncDiscoverDevices(t *testing.T) {
+	ws := &WebSocketServer{ctx: context.Background(), echonetClient: &mockECHONETListClient{}}
+
+	status, cr := operationCall(t, ws.handleDiscoverDevicesFromClient, protocol.MessageTypeDiscoverDevices, protocol.DiscoverDevicesPayload{Async: true})
+	if !cr.Success || status.OperationID == "" || status.Type != protocol.MessageTypeDiscoverDevices || status.Cancellable {
+		t.Fatalf("unexpected response: %+v %+v", status, cr.Error)
+	}
+	status = waitOperation(t, ws, status.OperationID)
+	if status.Status != protocol.OperationStatusCompleted || status.Percent != 100 || status.FinishedAt == nil {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	// 探索は途中で止められない
+	_, cr = operationCall(t, ws.handleCancelOperationFromClient, protocol.MessageTypeCancelOperation, protocol.OperationPayload{OperationID: status.OperationID})
+	if cr.Success || cr.Error.Code != protocol.ErrorCodeInvalidParameters {
+		t.Errorf("expected invalid parameters, got %+v", cr)
+	}
+
+	// 存在しない操作
+	_, cr = operationCall(t, ws.handleGetOperationStatusFromClient, protocol.MessageTypeGetOperationStatus, protocol.OperationPayload{OperationID: "op-unknown"})
+	if cr.Success || cr.Error.Code != protocol.ErrorCodeTargetNotFound {
+		t.Errorf("expected target not found, got %+v", cr)
+	}
+}
+
+func TestAsyncSetGroupProperties(t *testing.T) {
+	light := func(ip string) echonet_lite.IPAndEOJ {
+		return echonet_lite.IPAndEOJ{IP: net.ParseIP(ip), EOJ: echonet_lite.MakeEOJ(echonet_lite.SingleFunctionLighting_ClassCode, 1)}
+	}
+	c := &groupSetTestClient{
+		groups: map[string][]handler.IDString{"@lights": {"light1", "light2", "light3"}},
+		devices: map[handler.IDString]echonet_lite.IPAndEOJ{
+			"light1": light("192.168.1.11"),
+			"light2": light("192.168.1.12"),
+		},
+		failIP: "192.168.1.12",
+		set:    make(map[string]echonet_lite.Properties),
+	}
+	ws := &WebSocketServer{ctx: context.Background(), echonetClient: c, timeProvider: &RealTimeProvider{}}
+	payload := protocol.SetGroupPropertiesPayload{
+		Group:      "@lights",
+		Properties: protocol.PropertyMap{"80": {String: "off"}},
+		Async:      true,
+	}
+
+	status, cr := operationCall(t, ws.handleSetGroupPropertiesFromClient, protocol.MessageTypeSetGroupProperties, payload)
+	if !cr.Success || status.Total != 3 || !status.Cancellable {
+		t.Fatalf("unexpected response: %+v %+v", status, cr.Error)
+	}
+	status = waitOperation(t, ws, status.OperationID)
+	if status.Status != protocol.OperationStatusCompleted || status.Completed != 3 || len(status.Results) != 3 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	results := make(map[string]string)
+	for _, r := range status.Results {
+		results[r.Target] = r.Status
+	}
+	want := map[string]string{
+		"192.168.1.11 0291:1": operationTargetSucceeded,
+		"192.168.1.12 0291:1": operationTargetFailed,
+		"light3":              operationTargetFailed,
+	}
+	for target, s := range want {
+		if results[target] != s {
+			t.Errorf("%s = %q, want %q", target, results[target], s)
+		}
+	}
+
+	// 取消すると、まだ始めていないデバイスは cancelled になる
+	var ids []handler.IDString
+	c.groups["@many"] = nil
+	for i := 0; i < operationWorkers+2; i++ {
+		id := handler.IDString(net.IPv4(192, 168, 2, byte(i+1)).String())
+		c.devices[id] = light(string(id))
+		ids = append(ids, id)
+	}
+	c.groups["@many"] = ids
+	c.failIP = ""
+	blocking := &blockingGroupSetTestClient{groupSetTestClient: c, started: make(chan struct{}, len(ids)), release: make(chan struct{})}
+	ws.echonetClient = blocking
+	payload.Group = "@many"
+	status, cr = operationCall(t, ws.handleSetGroupPropertiesFromClient, protocol.MessageTypeSetGroupProperties, payload)
+	if !cr.Success {
+		t.Fatalf("unexpected response: %+v", cr.Error)
+	}
+	for i := 0; i < operationWorkers; i++ {
+		<-blocking.started
+	}
+	if _, cr := operationCall(t, ws.handleCancelOperationFromClient, protocol.MessageTypeCancelOperation, protocol.OperationPayload{OperationID: status.OperationID}); !cr.Success {
+		t.Fatalf("cancel_operation failed: %+v", cr.Error)
+	}
+	close(blocking.release)
+	status = waitOperation(t, ws, status.OperationID)
+	if status.Status != protocol.OperationStatusCancelled {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	counts := make(map[string]int)
+	for _, r := range status.Results {
+		counts[r.Status]++
+	}
+	if counts[operationTargetSucceeded] != operationWorkers || counts[operationTargetCancelled] != 2 {
+		t.Errorf("unexpected results: %+v", status.Results)
+	}
+}
+
+func TestBulkOperationsOwner(t *testing.T) {
+	var ops bulkOperations
+	op := &bulkOperation{owner: "kids", reported: make(map[string]bool)}
+	id := ops.add(op)
+	if _, ok := ops.get(id, "kids"); !ok {
+		t.Error("expected the operation for its owner")
+	}
+	// 別のアクセスルールのクライアントからは見えない
+	if _, ok := ops.get(id, ""); ok {
+		t.Error("expected no operation for another rule")
+	}
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"echonet-list/protocol"
+)
+
+// sentTransport records the messages sent to each client
+type sentTransport struct {
+	broadcastTransport
+	sent [][]byte
+}
+
+func (m *sentTransport) SendMessage(connID string, message []byte) error {
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+func TestBeginShutdownRejectsRequests(t *testing.T) {
+	c := &sceneTestClient{}
+	transport := &sentTransport{}
+	ws := &WebSocketServer{ctx: context.Background(), echonetClient: c, transport: transport, timeProvider: &RealTimeProvider{}}
+
+	ws.BeginShutdown()
+	ws.BeginShutdown() // calling it twice is harmless
+
+	request := []byte(`{"type":"set_properties","payload":{"target":"192.168.1.10 013001","properties":{"80":{"EDT":"MzA="}}},"requestId":"req-1"}`)
+	if err := ws.handleClientMessage("conn-1", request); err != nil {
+		t.Fatal(err)
+	}
+	if len(transport.sent) != 1 {
+		t.Fatalf("expected one response, got %d", len(transport.sent))
+	}
+	var msg protocol.Message
+	if err := json.Unmarshal(transport.sent[0], &msg); err != nil {
+		t.Fatal(err)
+	}
+	var result protocol.CommandResultPayload
+	if err := protocol.ParsePayload(&msg, &result); err != nil {
+		t.Fatal(err)
+	}
+	if msg.RequestID != "req-1" || result.Success || result.Error == nil || result.Error.Code != protocol.ErrorCodeShuttingDown {
+		t.Errorf("unexpected response: %s", transport.sent[0])
+	}
+}
@@ -63,9 +63,14 @@ type ErrorOperation = keyof typeof ERROR_MESSAGE_TEMPLATES;
 
 function App() {
   // 開発環境と本番環境でWebSocket URLを切り替え
-  const wsUrl = import.meta.env.DEV 
+  const baseWsUrl = import.meta.env.DEV 
     ? (import.meta.env.VITE_WS_URL || 'wss://localhost:8080/ws')  // 開発時は環境変数またはデフォルト値
     : `${window.location.protocol === 'https:' ? 'wss:' : 'ws:'}//${window.location.host}/ws`; // 本番時は現在のホストを使用
+  // アクセス制御が有効なサーバー向けに、ページURLの ?token= をそのままWebSocket接続に渡す
+  const accessToken = new URLSearchParams(window.location.search).get('token');
+  const wsUrl = accessToken
+    ? `${baseWsUrl}${baseWsUrl.includes('?') ? '&' : '?'}token=${encodeURIComponent(accessToken)}`
+    : baseWsUrl;
   
   // Log notification state
   const [logs, setLogs] = useState<LogEntry[]>([]);
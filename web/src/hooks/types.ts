@@ -4,10 +4,20 @@ export type Device = {
   ip: string;
   eoj: string;
   name: string;
+  className?: string; // English class name
+  classNameJa?: string; // Japanese class name
+  icon?: string; // Suggested icon identifier for the class
   id: string | undefined; // Format: EOJ:ManufacturerCode:UniqueIdentifier, undefined when IdentificationNumber (EPC 0x83) is not available
   properties: Record<string, PropertyValue>;
   lastSeen: string; // ISO 8601 format
   isOffline?: boolean; // true when device is offline
+  manufacturerCode?: string; // Manufacturer code (EPC 0x8A) in hex, e.g. "00000B"
+  manufacturer?: string; // Manufacturer name, omitted when the code is unknown to the server
+  manufacturerJa?: string; // Japanese manufacturer name
+  productCode?: string; // Product code (EPC 0x8C)
+  productionDate?: string; // Production date (EPC 0x8E), "YYYY-MM-DD"
+  availability?: number; // Percentage of time online, set when list_devices requests an availabilityWindow
+  favorite?: boolean; // true for devices pinned in the metadata or in the favorites of the preferences
 };
 
 export type PropertyValue = {
@@ -43,6 +53,11 @@ export type ErrorInfo = {
   message: string;
 };
 
+export type ServerTimezone = {
+  name: string; // IANA name (e.g. "Asia/Tokyo") or zone abbreviation
+  utcOffset: number; // Offset from UTC in seconds
+};
+
 // Server -> Client Messages (Notifications)
 export type InitialState = {
   type: 'initial_state';
@@ -52,6 +67,8 @@ export type InitialState = {
     groups: DeviceGroup;
     locationSettings?: LocationSettings;
     serverStartupTime: string; // ISO 8601 format
+    serverTimezone?: ServerTimezone; // Time zone of the timestamps in payloads
+    included?: ('devices' | 'aliases' | 'groups' | 'locations')[]; // Parts sent when ?initial= requested a partial state
   };
 };
 
@@ -81,6 +98,16 @@ export type PropertyChanged = {
   };
 };
 
+// Several property changes of one device coalesced by the server
+export type PropertiesChanged = {
+  type: 'properties_changed';
+  payload: {
+    ip: string;
+    eoj: string;
+    properties: Record<string, PropertyValue>; // key: 2-digit hex EPC
+  };
+};
+
 export type TimeoutNotification = {
   type: 'timeout_notification';
   payload: {
@@ -149,6 +176,19 @@ export type LogNotification = {
   };
 };
 
+// A log record sent to the clients subscribed with subscribe_logs
+export type LogEntryPayload = {
+  level: 'DEBUG' | 'INFO' | 'WARN' | 'ERROR';
+  message: string;
+  time: string; // ISO 8601 format
+  attributes: Record<string, unknown>;
+};
+
+export type LogEntry = {
+  type: 'log_entry';
+  payload: LogEntryPayload;
+};
+
 // Periodic liveness signal pushed by the server so clients can detect a dead
 // (zombie) WebSocket connection even when no device properties are changing.
 export type ServerHeartbeat = {
@@ -163,6 +203,7 @@ export type ServerMessage =
   | DeviceAdded
   | AliasChanged
   | PropertyChanged
+  | PropertiesChanged
   | TimeoutNotification
   | DeviceOffline
   | DeviceOnline
@@ -171,6 +212,7 @@ export type ServerMessage =
   | LocationSettingsChanged
   | ErrorNotification
   | LogNotification
+  | LogEntry
   | ServerHeartbeat;
 
 // Client -> Server Messages (Requests)
@@ -183,6 +225,7 @@ export type BaseRequest<T = Record<string, unknown>> = {
 export type GetPropertiesRequest = BaseRequest<{
   targets: string[]; // device ID strings (IP EOJ format)
   epcs: string[]; // EPC strings
+  all?: boolean; // read every EPC of the Get property map (epcs must be empty)
 }>;
 
 export type SetPropertiesRequest = BaseRequest<{
@@ -190,6 +233,23 @@ export type SetPropertiesRequest = BaseRequest<{
   properties: Record<string, PropertyValue>;
 }>;
 
+export type SetGroupPropertiesRequest = BaseRequest<{
+  group?: string; // group name, e.g. "@lights"
+  targets?: string[]; // device ID strings (IP EOJ format)
+  properties: Record<string, PropertyValue>;
+}>;
+
+export type SetGroupPropertiesResult = {
+  group?: string;
+  results: {
+    target: string;
+    id?: string;
+    success: boolean;
+    error?: string;
+    properties?: Record<string, PropertyValue>;
+  }[];
+};
+
 export type UpdatePropertiesRequest = BaseRequest<{
   targets?: string[]; // device ID strings, if omitted all devices are updated
   force?: boolean; // force update flag
@@ -221,6 +281,7 @@ export type GetDeviceHistoryRequest = BaseRequest<{
   target: string;
   limit?: number;
   since?: string;
+  until?: string;
   settableOnly?: boolean;
 }>;
 
@@ -234,9 +295,21 @@ export type SetLocationOrderRequest = BaseRequest<{
   order: string[];
 }>;
 
+export type SubscribeLogsRequest = BaseRequest<{
+  level?: 'debug' | 'info' | 'warn' | 'error'; // minimum level, defaults to 'info'
+  backlog?: number; // number of recent records returned in the result (0-1000)
+  unsubscribe?: boolean;
+}>;
+
+export type SubscribeLogsResult = {
+  level: string;
+  entries: LogEntryPayload[]; // oldest first
+};
+
 export type ClientMessage =
   | GetPropertiesRequest
   | SetPropertiesRequest
+  | SetGroupPropertiesRequest
   | UpdatePropertiesRequest
   | ManageAliasRequest
   | ManageGroupRequest
@@ -245,7 +318,8 @@ export type ClientMessage =
   | DeleteDeviceRequest
   | GetDeviceHistoryRequest
   | ManageLocationAliasRequest
-  | SetLocationOrderRequest;
+  | SetLocationOrderRequest
+  | SubscribeLogsRequest;
 
 // Command Result Response
 export type CommandResult = {
@@ -224,6 +224,7 @@ export type ECHONETHook = {
   // Device operations
   listDevices: (targets: string[]) => Promise<unknown>;
   setDeviceProperties: (target: string, properties: Record<string, PropertyValue>) => Promise<unknown>;
+  setGroupProperties: (group: string, properties: Record<string, PropertyValue>, targets?: string[]) => Promise<unknown>;
   updateDeviceProperties: (targets?: string[], force?: boolean) => Promise<unknown>;
   discoverDevices: () => Promise<unknown>;
   deleteDevice: (target: string) => Promise<unknown>;
@@ -394,6 +395,20 @@ export function useECHONET(
         });
         break;
 
+      case 'properties_changed':
+        for (const [epc, value] of Object.entries(message.payload.properties)) {
+          dispatch({
+            type: 'UPDATE_PROPERTY',
+            payload: {
+              ip: message.payload.ip,
+              eoj: message.payload.eoj,
+              epc,
+              value,
+            },
+          });
+        }
+        break;
+
       case 'alias_changed':
         dispatch({
           type: 'SET_ALIAS',
@@ -495,6 +510,14 @@ export function useECHONET(
     return response;
   }, [connection]);
 
+  const setGroupProperties = useCallback(async (group: string, properties: Record<string, PropertyValue>, targets?: string[]) => {
+    return connection.sendMessage({
+      type: 'set_group_properties',
+      payload: { group, targets, properties },
+      requestId: '',
+    });
+  }, [connection]);
+
   const updateDeviceProperties = useCallback(async (targets?: string[], force?: boolean) => {
     return connection.sendMessage({
       type: 'update_properties',
@@ -679,6 +702,7 @@ export function useECHONET(
     // Device operations
     listDevices,
     setDeviceProperties,
+    setGroupProperties,
     updateDeviceProperties,
     discoverDevices,
     deleteDevice,
@@ -8,7 +8,8 @@ import {
   Refrigerator,
   Settings,
   Info,
-  CircleHelp
+  CircleHelp,
+  CloudSun
 } from 'lucide-react';
 
 /**
@@ -20,6 +21,9 @@ export const DEFAULT_DEVICE_ICON = CircleHelp;
  * Mapping of ECHONET Lite device class codes to Lucide icons
  */
 export const DEVICE_CLASS_ICONS: Record<string, LucideIcon> = {
+  // Weather (virtual device)
+  '00F0': CloudSun,
+
   // Home Air Conditioner
   '0130': AirVent,
   
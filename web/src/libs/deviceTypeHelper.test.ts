@@ -392,14 +392,22 @@ describe('deviceTypeHelper', () => {
       expect(getDashboardStatusProperties('02A3')).toEqual(['C0']);
     });
 
-    it('should return hot water temperature for Electric Water Heater', () => {
-      expect(getDashboardStatusProperties('026B')).toEqual(['D1']);
+    it('should return remaining hot water for Electric Water Heater', () => {
+      expect(getDashboardStatusProperties('026B')).toEqual(['E1']);
     });
 
     it('should return operation mode for Bath Room Heating', () => {
       expect(getDashboardStatusProperties('0272')).toEqual(['B0']);
     });
 
+    it('should return generation power for Household Solar Power Generation', () => {
+      expect(getDashboardStatusProperties('0279')).toEqual(['E0']);
+    });
+
+    it('should return SOC for Storage Battery', () => {
+      expect(getDashboardStatusProperties('027D')).toEqual(['E4']);
+    });
+
     it('should return door open status for Refrigerator', () => {
       expect(getDashboardStatusProperties('03B7')).toEqual(['B0']);
     });
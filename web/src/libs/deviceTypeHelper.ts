@@ -54,11 +54,17 @@ export const DEVICE_PRIMARY_PROPERTIES: Record<string, string[]> = {
   '02A3': ['C0'], // Scene control
 
   // Electric Water Heater (026B)
-  '026B': ['D1', 'D2'], // Hot water temperature, volume
+  '026B': ['B0', 'C0', 'E1', 'C1', 'D1', 'B6'], // Water heating, daytime reheating, remaining hot water, tank temperature, supply temperature, tank mode
 
   // Bath Room Heating and Air Conditioning (0272)
   '0272': ['B0', 'B3'], // Operation mode, Temperature
 
+  // Household Solar Power Generation (0279)
+  '0279': ['E0', 'E1', 'E3'], // Generation power, cumulative generation, cumulative sold
+
+  // Storage Battery (027D)
+  '027D': ['DA', 'CF', 'E4', 'D3', 'E2'], // Operation mode, working status, SOC, charging/discharging power, remaining energy
+
   // Refrigerator (03b7)
   '03B7': ['89', 'B0', 'B1', 'B2', 'B3'], // Fault description, Door open status, Door open alert status, Refrigerator door open status, Freezer door open status
 };
@@ -175,12 +181,18 @@ export const DEVICE_DASHBOARD_PROPERTIES: Record<string, string[]> = {
   // Lighting System (02A3) - Scene control
   '02A3': ['C0'],
 
-  // Electric Water Heater (026B) - Hot water temperature
-  '026B': ['D1'],
+  // Electric Water Heater (026B) - Remaining hot water
+  '026B': ['E1'],
 
   // Bath Room Heating and Air Conditioning (0272) - Operation mode
   '0272': ['B0'],
 
+  // Household Solar Power Generation (0279) - Generation power
+  '0279': ['E0'],
+
+  // Storage Battery (027D) - SOC
+  '027D': ['E4'],
+
   // Refrigerator (03B7) - Door open status
   '03B7': ['B0'],
 };
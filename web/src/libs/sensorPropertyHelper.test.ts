@@ -29,6 +29,10 @@ describe('sensorPropertyHelper', () => {
       expect(isSensorProperty('027B', 'E3')).toBe(true); // Floor temperature
       expect(isSensorProperty('027B', 'F3')).toBe(true); // Temperature sensor 1
       expect(isSensorProperty('027B', 'F4')).toBe(true); // Temperature sensor 2
+
+      // Weather virtual device
+      expect(isSensorProperty('00F0', 'E0')).toBe(true); // Outside temperature
+      expect(isSensorProperty('00F0', 'E2')).toBe(true); // Wind speed
     });
 
     it('should return false for non-sensor properties', () => {
@@ -83,10 +87,12 @@ describe('sensorPropertyHelper', () => {
       expect(isTemperatureSensor('027B', 'E3')).toBe(true);
       expect(isTemperatureSensor('027B', 'F3')).toBe(true);
       expect(isTemperatureSensor('027B', 'F4')).toBe(true);
+      expect(isTemperatureSensor('00F0', 'E0')).toBe(true);
     });
 
     it('should return false for non-temperature sensors', () => {
       expect(isTemperatureSensor('0130', 'BA')).toBe(false); // Humidity
+      expect(isTemperatureSensor('00F0', 'E2')).toBe(false); // Wind speed
     });
 
     it('should return false for non-sensor properties', () => {
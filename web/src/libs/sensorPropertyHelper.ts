@@ -5,6 +5,8 @@ import {
   ThermometerSun,
   ThermometerSnowflake,
   Droplets,
+  Wind,
+  CloudRain,
   type LucideIcon
 } from 'lucide-react';
 import type { PropertyValue } from '@/hooks/types';
@@ -21,12 +23,19 @@ export const SENSOR_PROPERTY_ICONS: Record<string, LucideIcon> = {
   '027B:E3': Home,               // Floor temperature
   '027B:F3': ThermometerSun,     // Temperature sensor 1 (outgoing water temp)
   '027B:F4': ThermometerSnowflake, // Temperature sensor 2 (return water temp)
+
+  // Weather (00F0) virtual device
+  '00F0:E0': CloudSun,     // Outside temperature
+  '00F0:E1': Droplets,     // Outside humidity
+  '00F0:E2': Wind,         // Wind speed
+  '00F0:E4': CloudRain,    // Precipitation
 };
 
 // Temperature sensor EPCs (for color calculation)
 const TEMPERATURE_SENSOR_EPCS = new Set([
   '0130:BB', '0130:BE',  // Air Conditioner temperature sensors
-  '027B:E2', '027B:E3', '027B:F3', '027B:F4'  // Floor Heating temperature sensors
+  '027B:E2', '027B:E3', '027B:F3', '027B:F4',  // Floor Heating temperature sensors
+  '00F0:E0'  // Weather outside temperature
 ]);
 
 /**